@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/parser"
+	"github.com/spf13/cobra"
+)
+
+func auditCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "audit <plan-id>",
+		Short: "Re-parse a plan's sessions and report inconsistencies",
+		Long: `Walk every session of a plan, re-parse its stored output with the current
+parser, and report where the result disagrees with what was recorded: an
+iteration that now parses as done and approved in a plan that isn't completed,
+a completed plan with no such iteration, a rejection whose feedback was never
+stored, and stored feedback that never reached a developer.
+
+Nothing is changed. Run it after a parser fix to find plans that ended the
+wrong way.
+
+Examples:
+  ralph audit 3f2a9c1e`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			return runAudit(cmd.OutOrStdout(), database, args[0])
+		},
+	}
+}
+
+// auditFinding is one inconsistency between a plan's stored state and its
+// re-parsed sessions.
+type auditFinding struct {
+	Iteration int    // 0 for findings about the plan as a whole
+	SessionID string // Empty for findings about an iteration or the plan
+	Message   string
+}
+
+// auditIteration is the re-parsed verdicts of one iteration's sessions.
+type auditIteration struct {
+	Iteration int
+	DevDone   bool // The iteration's last developer session signaled done
+	Reviews   int  // Reviewer sessions
+	Approvals int  // Reviewer sessions that approved
+	Feedback  bool // A rejecting reviewer left feedback
+}
+
+// runAudit re-parses a plan's sessions and prints the inconsistencies found.
+func runAudit(out io.Writer, database *db.DB, planID string) error {
+	plan, err := database.GetPlan(planID)
+	if errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("plan not found: %s", planID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get plan: %w", err)
+	}
+
+	findings, parsed, err := auditPlan(database, plan)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Audit of plan %s (%s): %d session(s) re-parsed\n", plan.ID, plan.Status, parsed)
+	if len(findings) == 0 {
+		fmt.Fprintln(out, "No inconsistencies found")
+		return nil
+	}
+	fmt.Fprintln(out)
+	for _, f := range findings {
+		where := "plan"
+		if f.Iteration > 0 {
+			where = fmt.Sprintf("iteration %d", f.Iteration)
+		}
+		if f.SessionID != "" {
+			where += ", session " + f.SessionID
+		}
+		fmt.Fprintf(out, "  %s: %s\n", where, f.Message)
+	}
+	fmt.Fprintf(out, "\n%d finding(s)\n", len(findings))
+	return nil
+}
+
+// auditPlan re-parses the developer and reviewer sessions of a plan that
+// weren't superseded and checks the result against the plan's status and
+// stored feedback. It returns the findings and how many sessions it parsed.
+//
+// An iteration counts as agreed for a plan that isn't completed only when
+// every reviewer approved, and as agreed for a completed plan when any did,
+// so a review panel's quorum never produces a false finding either way.
+func auditPlan(database *db.DB, plan *db.Plan) ([]auditFinding, int, error) {
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	feedbackList, err := database.GetReviewerFeedbackByPlan(plan.ID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get reviewer feedback: %w", err)
+	}
+
+	// The loop turns approvals that fail the plan's own checks or acceptance
+	// criteria into rejections; invalid ones already kept the plan from
+	// running
+	checks, _ := agent.PlanChecklist(plan.Content)
+	criteria, _ := agent.AcceptanceCriteria(plan.Content)
+
+	var findings []auditFinding
+	var iterations []*auditIteration
+	byIteration := make(map[int]*auditIteration)
+	iterationOf := make(map[string]int)
+	var developers []*db.PlanSession
+	parsed := 0
+
+	for _, session := range sessions {
+		iterationOf[session.ID] = session.Iteration
+		if session.Superseded {
+			continue
+		}
+		if session.AgentType != db.LoopAgentDeveloper && session.AgentType != db.LoopAgentReviewer {
+			continue
+		}
+		if session.Status != db.PlanSessionCompleted {
+			// Failed and interrupted sessions left no verdict to re-check
+			continue
+		}
+
+		result, fromTool, err := auditParse(database, session)
+		if err != nil {
+			return nil, 0, err
+		}
+		parsed++
+		if result.Malformed && !fromTool {
+			findings = append(findings, auditFinding{session.Iteration, session.ID,
+				fmt.Sprintf("%s output has no Progress or Learnings section", session.AgentType)})
+		}
+
+		iter := byIteration[session.Iteration]
+		if iter == nil {
+			iter = &auditIteration{Iteration: session.Iteration}
+			byIteration[session.Iteration] = iter
+			iterations = append(iterations, iter)
+		}
+		if session.AgentType == db.LoopAgentDeveloper {
+			iter.DevDone = result.DevDone
+			developers = append(developers, session)
+			continue
+		}
+		if len(checks) > 0 && result.ReviewerApproved && iter.DevDone {
+			if failed := parser.FailedChecks(parser.MatchChecklist(checks, result.Checklist)); failed != "" {
+				result.ReviewerApproved = false
+				result.ReviewerFeedback = strings.TrimSpace(result.ReviewerFeedback + "\n\n" + failed)
+			}
+		}
+		if len(criteria) > 0 && result.ReviewerApproved && iter.DevDone {
+			if failed := parser.FailedCriteria(parser.MatchChecklist(criteria, result.Criteria)); failed != "" {
+				result.ReviewerApproved = false
+				result.ReviewerFeedback = strings.TrimSpace(result.ReviewerFeedback + "\n\n" + failed)
+			}
+		}
+		iter.Reviews++
+		if result.ReviewerApproved {
+			iter.Approvals++
+		} else if result.ReviewerFeedback != "" {
+			iter.Feedback = true
+		}
+	}
+
+	completed := plan.Status == db.PlanStatusCompleted
+	agreed := false
+	for _, iter := range iterations {
+		if !iter.DevDone || iter.Reviews == 0 {
+			continue
+		}
+		if iter.Approvals > 0 {
+			agreed = true
+		}
+		if !completed && plan.Status != db.PlanStatusRunning && iter.Approvals == iter.Reviews {
+			findings = append(findings, auditFinding{iter.Iteration, "",
+				fmt.Sprintf("developer signaled done and the reviewer approved, but the plan is %s", plan.Status)})
+		}
+	}
+	if completed && !agreed {
+		findings = append(findings, auditFinding{0, "",
+			"plan is completed but no iteration has both a done signal and an approval"})
+	}
+
+	// Every rejection with feedback should have left feedback for the
+	// next developer
+	stored := make(map[int]bool)
+	for _, f := range feedbackList {
+		if !f.Superseded {
+			stored[iterationOf[f.SessionID]] = true
+		}
+	}
+	for _, iter := range iterations {
+		if iter.Reviews > 0 && iter.Approvals == 0 && iter.Feedback && !stored[iter.Iteration] {
+			findings = append(findings, auditFinding{iter.Iteration, "",
+				"reviewer rejected with feedback, but no feedback was stored"})
+		}
+	}
+
+	for _, f := range feedbackList {
+		if f.Superseded {
+			continue
+		}
+		findings = append(findings, auditFeedback(f, iterationOf[f.SessionID], developers, completed)...)
+	}
+
+	return findings, parsed, nil
+}
+
+// auditParse re-parses a session the way the loop does: from its last
+// valid status tool call when it made one, from its output otherwise. It
+// reports whether the result came from a status tool call.
+func auditParse(database *db.DB, session *db.PlanSession) (*parser.AgentParseResult, bool, error) {
+	messages, err := database.GetTranscriptBySession(session.ID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get transcript for session %s: %w", session.ID, err)
+	}
+	var report *parser.StatusReport
+	for _, m := range messages {
+		if m.Kind != "tool_use" || m.SubAgentID != "" || !parser.IsStatusTool(m.ToolName) {
+			continue
+		}
+		if r, err := parser.ParseStatusReport([]byte(m.Content)); err == nil {
+			report = r
+		}
+	}
+
+	agentType := string(session.AgentType)
+	if report != nil {
+		return report.Result(agentType, session.FinalOutput), true, nil
+	}
+	return parser.ParseAgentOutput(session.FinalOutput, agentType), false, nil
+}
+
+// auditFeedback checks that a stored feedback row reached a later
+// developer session's prompt. Feedback still waiting for the next
+// developer is fine unless it was cleared or the plan already completed.
+func auditFeedback(f *db.ReviewerFeedback, iteration int, developers []*db.PlanSession, completed bool) []auditFinding {
+	content := strings.TrimSpace(f.Content)
+	later := 0
+	for _, dev := range developers {
+		if dev.Iteration <= iteration {
+			continue
+		}
+		later++
+		if strings.Contains(dev.InputPrompt, content) {
+			return nil
+		}
+	}
+
+	switch {
+	case later > 0:
+		return []auditFinding{{iteration, f.SessionID,
+			fmt.Sprintf("feedback was stored but none of the %d later developer prompt(s) included it", later)}}
+	case f.Cleared:
+		return []auditFinding{{iteration, f.SessionID,
+			"feedback was cleared without being given to a developer"}}
+	case completed:
+		return []auditFinding{{iteration, f.SessionID,
+			"feedback was stored but the plan completed before a developer saw it"}}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+const (
+	auditDevDone   = "## Progress\nDone.\n\n## Learnings\nNone.\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"
+	auditDevWork   = "## Progress\nWorking.\n\n## Learnings\nNone."
+	auditApproved  = "## Progress\nReviewed.\n\n## Learnings\nNone.\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!"
+	auditRejection = "## Progress\nReviewed.\n\n## Learnings\nNone.\n\n### Critical Issues\n- Missing error check in loop.go\n\n### Verdict\nChanges needed."
+)
+
+// createAuditSession stores a completed session.
+func createAuditSession(t *testing.T, database *db.DB, id string, iteration int, agentType db.LoopAgentType, prompt, output string) {
+	t.Helper()
+	if err := database.CreatePlanSession(&db.PlanSession{
+		ID:          id,
+		PlanID:      "plan-1",
+		Iteration:   iteration,
+		InputPrompt: prompt,
+		FinalOutput: output,
+		Status:      db.PlanSessionCompleted,
+		AgentType:   agentType,
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newAuditTestDB(t *testing.T, status db.PlanStatus) *db.DB {
+	t.Helper()
+	database := newPlansTestDB(t)
+	if err := database.CreatePlan(&db.Plan{ID: "plan-1", Content: "Build it", Status: status}); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+func TestRunAudit_Consistent(t *testing.T) {
+	database := newAuditTestDB(t, db.PlanStatusCompleted)
+	createAuditSession(t, database, "dev-1", 1, db.LoopAgentDeveloper, "Build it", auditDevWork)
+	createAuditSession(t, database, "rev-1", 1, db.LoopAgentReviewer, "Review", auditRejection)
+	if err := database.CreateReviewerFeedback(&db.ReviewerFeedback{PlanID: "plan-1", SessionID: "rev-1", Content: "Missing error check in loop.go"}); err != nil {
+		t.Fatal(err)
+	}
+	createAuditSession(t, database, "dev-2", 2, db.LoopAgentDeveloper, "Feedback:\nMissing error check in loop.go", auditDevDone)
+	createAuditSession(t, database, "rev-2", 2, db.LoopAgentReviewer, "Review", auditApproved)
+
+	var out bytes.Buffer
+	if err := runAudit(&out, database, "plan-1"); err != nil {
+		t.Fatalf("runAudit() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "4 session(s) re-parsed") || !strings.Contains(out.String(), "No inconsistencies found") {
+		t.Errorf("output = %q, want 4 sessions and no findings", out.String())
+	}
+}
+
+func TestRunAudit_Findings(t *testing.T) {
+	database := newAuditTestDB(t, db.PlanStatusStopped)
+	createAuditSession(t, database, "dev-1", 1, db.LoopAgentDeveloper, "Build it", auditDevWork)
+	createAuditSession(t, database, "rev-1", 1, db.LoopAgentReviewer, "Review", auditRejection)
+	if err := database.CreateReviewerFeedback(&db.ReviewerFeedback{PlanID: "plan-1", SessionID: "rev-1", Content: "Missing error check in loop.go"}); err != nil {
+		t.Fatal(err)
+	}
+	// The next developer never got the feedback, and its rejection wasn't stored
+	createAuditSession(t, database, "dev-2", 2, db.LoopAgentDeveloper, "Build it", "no sections at all")
+	createAuditSession(t, database, "rev-2", 2, db.LoopAgentReviewer, "Review", auditRejection)
+	// The parser now sees this iteration as agreed, but the plan stopped
+	createAuditSession(t, database, "dev-3", 3, db.LoopAgentDeveloper, "Build it", auditDevDone)
+	createAuditSession(t, database, "rev-3", 3, db.LoopAgentReviewer, "Review", auditApproved)
+
+	var out bytes.Buffer
+	if err := runAudit(&out, database, "plan-1"); err != nil {
+		t.Fatalf("runAudit() error = %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"iteration 2, session dev-2: developer output has no Progress or Learnings section",
+		"iteration 3: developer signaled done and the reviewer approved, but the plan is stopped",
+		"iteration 2: reviewer rejected with feedback, but no feedback was stored",
+		"iteration 1, session rev-1: feedback was stored but none of the 2 later developer prompt(s) included it",
+		"4 finding(s)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestRunAudit_CompletedWithoutAgreement(t *testing.T) {
+	database := newAuditTestDB(t, db.PlanStatusCompleted)
+	createAuditSession(t, database, "dev-1", 1, db.LoopAgentDeveloper, "Build it", auditDevDone)
+	createAuditSession(t, database, "rev-1", 1, db.LoopAgentReviewer, "Review", auditRejection)
+	if err := database.CreateReviewerFeedback(&db.ReviewerFeedback{PlanID: "plan-1", SessionID: "rev-1", Content: "Missing error check in loop.go"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runAudit(&out, database, "plan-1"); err != nil {
+		t.Fatalf("runAudit() error = %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"plan: plan is completed but no iteration has both a done signal and an approval",
+		"iteration 1, session rev-1: feedback was stored but the plan completed before a developer saw it",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestRunAudit_PlanNotFound(t *testing.T) {
+	database := newPlansTestDB(t)
+	err := runAudit(&bytes.Buffer{}, database, "missing")
+	if err == nil || !strings.Contains(err.Error(), "plan not found") {
+		t.Errorf("runAudit() error = %v, want plan not found", err)
+	}
+}
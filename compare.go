@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/spf13/cobra"
+)
+
+func compareCmd() *cobra.Command {
+	var stat bool
+
+	cmd := &cobra.Command{
+		Use:   "compare <plan-id> <other-plan-id>",
+		Short: "Show the differences between two forks of a plan",
+		Long: `Print the diff from the latest snapshot of one plan to the latest snapshot of
+another, typically a plan and a fork of it made with ralph fork. With --stat,
+print a per-file summary instead of the full diff.
+
+A plan's latest snapshot is the working copy recorded after its last
+developer session; a fork that hasn't run yet is compared at the change it
+was forked into.
+
+Examples:
+  ralph compare 3f2a9c1e 9b0d4e7a
+  ralph compare 3f2a9c1e 9b0d4e7a --stat`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			workDir, err := planWorkDir(database, args[0])
+			if err != nil {
+				return err
+			}
+
+			return runCompare(cmd.Context(), cmd.OutOrStdout(), database, jj.NewClient(workDir), args[0], args[1], stat)
+		},
+	}
+
+	cmd.Flags().BoolVar(&stat, "stat", false, "Show a per-file summary instead of the full diff")
+
+	return cmd
+}
+
+// runCompare prints the diff between the latest snapshots of two plans.
+func runCompare(ctx context.Context, out io.Writer, database *db.DB, jjClient *jj.Client, planID, otherID string, stat bool) error {
+	var plans [2]*db.Plan
+	var heads [2]string
+	for i, id := range []string{planID, otherID} {
+		plan, err := database.GetPlan(id)
+		if errors.Is(err, db.ErrNotFound) {
+			return fmt.Errorf("plan not found: %s", id)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get plan: %w", err)
+		}
+		if heads[i], err = planHead(database, plan); err != nil {
+			return err
+		}
+		plans[i] = plan
+	}
+	if plans[0].WorkDir != "" && plans[1].WorkDir != "" && plans[0].WorkDir != plans[1].WorkDir {
+		return fmt.Errorf("plans %s and %s run in different repositories", planID, otherID)
+	}
+
+	return printDiff(ctx, out, jjClient, heads[0], heads[1], stat)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+func TestRunCompare(t *testing.T) {
+	database := newDiffTestDB(t, "base")
+	if err := database.ForkPlan(&db.Plan{ID: "fork-1", Content: "c", BaseChangeID: "base", ForkedFrom: "plan-1"}, "forkchange"); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls [][]string
+	jjClient := jj.NewClient("/repo")
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		calls = append(calls, args)
+		return "diff output\n", "", nil
+	})
+
+	var out bytes.Buffer
+	if err := runCompare(context.Background(), &out, database, jjClient, "plan-1", "fork-1", false); err != nil {
+		t.Fatalf("runCompare() error: %v", err)
+	}
+	if want := []string{"diff", "--from", "c2", "--to", "forkchange"}; len(calls) != 1 || !slices.Equal(calls[0], want) {
+		t.Errorf("jj calls = %v, want [%v]", calls, want)
+	}
+	if out.String() != "diff output\n" {
+		t.Errorf("output = %q", out.String())
+	}
+
+	calls = nil
+	if err := runCompare(context.Background(), &out, database, jjClient, "fork-1", "plan-1", true); err != nil {
+		t.Fatalf("runCompare() error: %v", err)
+	}
+	if want := []string{"diff", "--stat", "--from", "forkchange", "--to", "c2"}; len(calls) != 1 || !slices.Equal(calls[0], want) {
+		t.Errorf("jj calls = %v, want [%v]", calls, want)
+	}
+}
+
+func TestRunCompare_Errors(t *testing.T) {
+	jjClient := jj.NewClient("/repo")
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		return "", "", nil
+	})
+
+	database := newDiffTestDB(t, "base")
+	if err := database.CreatePlan(&db.Plan{ID: "plan-2", OriginPath: "plan.md", Content: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := runCompare(context.Background(), &out, database, jjClient, "plan-1", "missing", false); err == nil || !strings.Contains(err.Error(), "plan not found") {
+		t.Errorf("expected plan not found error, got: %v", err)
+	}
+	if err := runCompare(context.Background(), &out, database, jjClient, "plan-1", "plan-2", false); err == nil || !strings.Contains(err.Error(), "no snapshot") {
+		t.Errorf("expected missing snapshot error, got: %v", err)
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/app"
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/loop"
+	"github.com/gerunddev/ralph/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+func dashboardCmd() *cobra.Command {
+	var limit int
+	var interval time.Duration
+	var theme string
+	var accessible bool
+
+	cmd := &cobra.Command{
+		Use:   "dashboard",
+		Short: "Watch all plans and attach to their event streams",
+		Long: `Open a live dashboard of the most recently updated plans, running plans
+first, with each plan's status, current iteration, last event, and Claude cost.
+Select a plan and press Enter to attach to its event stream, which replays the
+plan's history and then follows it live; Esc goes back to the list.
+
+The dashboard reads the plans database, so it works for plans run by any ralph
+process.
+
+Examples:
+  ralph dashboard
+  ralph dashboard --limit 50 --interval 5s`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if limit < 1 {
+				return errors.New("--limit must be at least 1")
+			}
+			if interval <= 0 {
+				return errors.New("--interval must be positive")
+			}
+			if err := config.ValidateTheme(theme); err != nil {
+				return fmt.Errorf("--theme %w", err)
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			if theme == "" {
+				theme = cfg.TUI.Theme
+			}
+			if err := tui.SetTheme(theme, accessible || cfg.TUI.Accessible); err != nil {
+				return err
+			}
+
+			database, err := app.OpenPlansDB(cfg)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			lister := newPlanLister(database, limit)
+			attach := func(ctx context.Context, planID string) <-chan loop.Event {
+				return followPlan(ctx, database, planID, interval)
+			}
+			return tui.RunDashboard(lister.list, attach, interval)
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "n", 20, "Maximum number of plans to list")
+	cmd.Flags().DurationVar(&interval, "interval", tui.DefaultDashboardInterval, "How often to refresh plans and attached event streams")
+	cmd.Flags().StringVar(&theme, "theme", "", "Color theme: dark, light, high-contrast, or no-color (default: tui.theme from config)")
+	cmd.Flags().BoolVar(&accessible, "accessible", false, "Screen-reader-friendly output: plain word prefixes instead of symbols and no box-drawing characters")
+
+	return cmd
+}
+
+// planActivity is what the dashboard has read of a plan's stored events.
+type planActivity struct {
+	lastEventID int64
+	lastEvent   string
+	lastEventAt time.Time
+	costUSD     float64
+}
+
+// planLister lists plans for the dashboard. It reads each plan's events
+// incrementally, so refreshing only costs the events stored since the last
+// refresh.
+type planLister struct {
+	db       *db.DB
+	limit    int
+	activity map[string]*planActivity
+}
+
+// newPlanLister creates a lister for the most recently updated limit plans.
+func newPlanLister(database *db.DB, limit int) *planLister {
+	return &planLister{db: database, limit: limit, activity: make(map[string]*planActivity)}
+}
+
+// list returns the plans, running plans first, then most recently updated.
+func (l *planLister) list() ([]tui.DashboardPlan, error) {
+	plans, err := l.db.ListPlans(l.limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plans: %w", err)
+	}
+
+	result := make([]tui.DashboardPlan, 0, len(plans))
+	for _, plan := range plans {
+		item, err := l.summarize(plan)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+
+	slices.SortStableFunc(result, func(a, b tui.DashboardPlan) int {
+		aRunning, bRunning := a.Status == string(db.PlanStatusRunning), b.Status == string(db.PlanStatusRunning)
+		switch {
+		case aRunning && !bRunning:
+			return -1
+		case bRunning && !aRunning:
+			return 1
+		}
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+	return result, nil
+}
+
+// summarize builds a plan's dashboard entry, reading its new events.
+func (l *planLister) summarize(plan *db.Plan) (tui.DashboardPlan, error) {
+	item := tui.DashboardPlan{
+		ID:        plan.ID,
+		Status:    string(plan.Status),
+		UpdatedAt: plan.UpdatedAt,
+	}
+
+	session, err := l.db.GetLatestPlanSession(plan.ID)
+	if err != nil {
+		return item, fmt.Errorf("failed to get latest session of plan %s: %w", plan.ID, err)
+	}
+	if session != nil {
+		item.Iteration = session.Iteration
+		item.Agent = string(session.AgentType)
+	}
+
+	activity := l.activity[plan.ID]
+	if activity == nil {
+		activity = &planActivity{}
+		l.activity[plan.ID] = activity
+	}
+	events, err := l.db.GetPlanEventsAfter(plan.ID, activity.lastEventID)
+	if err != nil {
+		return item, fmt.Errorf("failed to get events of plan %s: %w", plan.ID, err)
+	}
+	for _, e := range events {
+		activity.lastEventID = e.ID
+		event, err := claude.NewParser(strings.NewReader(e.RawJSON)).Next()
+		if err != nil || event == nil {
+			continue
+		}
+		if event.Result != nil {
+			activity.costUSD += event.Result.CostUSD
+		}
+		if text := describeEvent(event); text != "" {
+			activity.lastEvent = text
+			activity.lastEventAt = e.CreatedAt
+		}
+	}
+
+	item.LastEvent = activity.lastEvent
+	item.CostUSD = activity.costUSD
+	if activity.lastEventAt.After(item.UpdatedAt) {
+		item.UpdatedAt = activity.lastEventAt
+	}
+	return item, nil
+}
+
+// describeEvent summarizes a Claude event for the dashboard's last event
+// column ("" for events not worth showing).
+func describeEvent(event *claude.StreamEvent) string {
+	prefix := ""
+	if event.SubAgentID != "" {
+		prefix = "sub-agent "
+	}
+	switch event.Type {
+	case claude.EventInit:
+		return prefix + "session started"
+	case claude.EventAssistantText, claude.EventMessage:
+		return prefix + "writing"
+	case claude.EventToolUse:
+		if event.ToolUse != nil {
+			return prefix + "tool: " + event.ToolUse.Name
+		}
+	case claude.EventResult:
+		return prefix + "session finished"
+	case claude.EventError:
+		if event.Error != nil {
+			return prefix + "error: " + event.Error.Message
+		}
+	}
+	return ""
+}
+
+// followPlan streams a plan's stored events as loop events, starting from its
+// first event, until ctx is cancelled, polling the database every interval.
+// Sessions starting and the plan reaching a final status are announced the
+// way the loop announces them. The channel is closed once ctx is cancelled.
+func followPlan(ctx context.Context, database *db.DB, planID string, interval time.Duration) <-chan loop.Event {
+	bus := loop.NewBus()
+	sub := bus.Subscribe()
+	f := &planFollower{db: database, planID: planID, publish: bus.Publish}
+
+	go func() {
+		defer bus.Close()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := f.poll(ctx); err != nil {
+				log.Warn("failed to follow plan", "plan", planID, "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return sub.Events()
+}
+
+// planFollower turns a plan's stored events into loop events.
+type planFollower struct {
+	db      *db.DB
+	planID  string
+	publish func(loop.Event)
+
+	lastEventID int64
+	session     string // ID of the session the last event came from
+	iteration   int
+	status      db.PlanStatus
+}
+
+// poll publishes the events stored since the last poll, then the plan's
+// status if it changed. It stops early once ctx is cancelled.
+func (f *planFollower) poll(ctx context.Context) error {
+	// Events are read before sessions so every event's session is known
+	events, err := f.db.GetPlanEventsAfter(f.planID, f.lastEventID)
+	if err != nil {
+		return err
+	}
+	sessions, err := f.db.GetPlanSessionsByPlan(f.planID)
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]*db.PlanSession, len(sessions))
+	for _, s := range sessions {
+		byID[s.ID] = s
+	}
+
+	for _, e := range events {
+		if ctx.Err() != nil {
+			return nil
+		}
+		f.lastEventID = e.ID
+		if session := byID[e.SessionID]; session != nil && session.ID != f.session {
+			f.startSession(session)
+		}
+		event, err := claude.NewParser(strings.NewReader(e.RawJSON)).Next()
+		if err != nil || event == nil {
+			continue
+		}
+		event.SubAgentID = e.SubAgentID
+		f.publish(loop.NewClaudeStreamEvent(f.iteration, 0, event))
+	}
+
+	plan, err := f.db.GetPlan(f.planID)
+	if err != nil {
+		return err
+	}
+	if plan.Status != f.status && ctx.Err() == nil {
+		f.status = plan.Status
+		if event, ok := planStatusEvent(plan, f.iteration); ok {
+			f.publish(event)
+		}
+	}
+	return nil
+}
+
+// startSession announces a session the way the loop does when it starts
+// the session's agent.
+func (f *planFollower) startSession(session *db.PlanSession) {
+	f.session = session.ID
+	if session.AgentType == db.LoopAgentPlanner {
+		f.publish(loop.NewEvent(loop.EventPlanningStart, f.iteration, 0, "Planning tasks"))
+		return
+	}
+
+	if session.Iteration != f.iteration {
+		f.iteration = session.Iteration
+		f.publish(loop.NewEvent(loop.EventIterationStart, f.iteration, 0,
+			fmt.Sprintf("Starting iteration %d", f.iteration)))
+	}
+	switch session.AgentType {
+	case db.LoopAgentReviewer:
+		f.publish(loop.NewEvent(loop.EventReviewerStart, f.iteration, 0, "Starting reviewer agent"))
+	case db.LoopAgentConflictResolver:
+		f.publish(loop.NewEvent(loop.EventConflictResolverStart, f.iteration, 0, "Starting conflict resolver agent"))
+	case db.LoopAgentRebuttalReviewer:
+		f.publish(loop.NewEvent(loop.EventRebuttalStart, f.iteration, 0, "Developer disputed the review feedback, starting re-evaluation"))
+	case db.LoopAgentReformatter:
+		f.publish(loop.NewEvent(loop.EventReformatStart, f.iteration, 0, "Output is missing its required sections, asking for it to be reformatted"))
+	case db.LoopAgentFailureTriager:
+		f.publish(loop.NewEvent(loop.EventFailureTriageStart, f.iteration, 0, "The same failure keeps happening, starting failure triage agent"))
+	case db.LoopAgentDiffSummarizer:
+		f.publish(loop.NewEvent(loop.EventDiffSummaryStart, f.iteration, 0, "Diff is over the reviewer's budget, summarizing it"))
+	default:
+		f.publish(loop.NewEvent(loop.EventDeveloperStart, f.iteration, 0, "Starting developer agent"))
+	}
+}
+
+// planStatusEvent returns the loop event the loop emits when a plan ends
+// with the plan's status, if any.
+func planStatusEvent(plan *db.Plan, iteration int) (loop.Event, bool) {
+	reason := plan.FailureReason
+	if reason == "" {
+		reason = "Plan " + string(plan.Status)
+	}
+
+	switch plan.Status {
+	case db.PlanStatusCompleted:
+		return loop.NewEvent(loop.EventDone, iteration, 0, "Plan completed"), true
+	case db.PlanStatusFailed, db.PlanStatusCancelled, db.PlanStatusAbandoned:
+		return loop.NewEvent(loop.EventFailed, iteration, 0, reason), true
+	case db.PlanStatusStopped:
+		if strings.HasPrefix(reason, "Reached max iterations") {
+			return loop.NewEvent(loop.EventMaxIterations, iteration, 0, reason), true
+		}
+		return loop.NewEvent(loop.EventStallAborted, iteration, 0, reason), true
+	case db.PlanStatusPaused:
+		if strings.HasPrefix(reason, "Reached max duration") {
+			return loop.NewEvent(loop.EventMaxDuration, iteration, 0, reason), true
+		}
+		if strings.HasPrefix(reason, "Reached max cost") {
+			return loop.NewEvent(loop.EventMaxCost, iteration, 0, reason), true
+		}
+		if reason == "Paused by request" || reason == "Stopped by request" {
+			return loop.NewEvent(loop.EventPaused, iteration, 0, reason), true
+		}
+	}
+	return loop.Event{}, false
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+// createDashboardEvent stores a Claude event for a session.
+func createDashboardEvent(t *testing.T, database *db.DB, sessionID, eventType, rawJSON string) {
+	t.Helper()
+	if err := database.CreateEvent(&db.Event{SessionID: sessionID, EventType: eventType, RawJSON: rawJSON}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const (
+	dashboardToolUse = `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Edit","input":{"file_path":"a.go"}}]}}`
+	dashboardResult  = `{"type":"result","total_cost_usd":0.25}`
+)
+
+func TestPlanLister_List(t *testing.T) {
+	database := newPlansTestDB(t)
+	for _, id := range []string{"done", "active"} {
+		if err := database.CreatePlan(&db.Plan{ID: id, Content: "c"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.UpdatePlanStatus("active", db.PlanStatusRunning); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.UpdatePlanStatus("done", db.PlanStatusRunning); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.UpdatePlanStatus("done", db.PlanStatusCompleted); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreatePlanSession(&db.PlanSession{ID: "s1", PlanID: "active", Iteration: 2, InputPrompt: "p", AgentType: db.LoopAgentReviewer}); err != nil {
+		t.Fatal(err)
+	}
+	createDashboardEvent(t, database, "s1", "result", dashboardResult)
+	createDashboardEvent(t, database, "s1", "tool_use", dashboardToolUse)
+
+	lister := newPlanLister(database, 20)
+	plans, err := lister.list()
+	if err != nil {
+		t.Fatalf("list() error: %v", err)
+	}
+	if len(plans) != 2 || plans[0].ID != "active" || plans[1].ID != "done" {
+		t.Fatalf("list() = %+v, want the running plan first", plans)
+	}
+	active := plans[0]
+	if active.Iteration != 2 || active.Agent != "reviewer" || active.LastEvent != "tool: Edit" || active.CostUSD != 0.25 {
+		t.Errorf("active plan = %+v, want iteration 2 reviewer, last event tool: Edit, cost 0.25", active)
+	}
+
+	// Later refreshes only add the new events
+	createDashboardEvent(t, database, "s1", "result", dashboardResult)
+	plans, err = lister.list()
+	if err != nil {
+		t.Fatalf("list() error: %v", err)
+	}
+	if plans[0].CostUSD != 0.5 || plans[0].LastEvent != "session finished" {
+		t.Errorf("refreshed plan = %+v, want cost 0.5 and last event session finished", plans[0])
+	}
+}
+
+func TestFollowPlan(t *testing.T) {
+	database := newPlansTestDB(t)
+	if err := database.CreatePlan(&db.Plan{ID: "plan-1", Content: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreatePlanSession(&db.PlanSession{ID: "d1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p"}); err != nil {
+		t.Fatal(err)
+	}
+	createDashboardEvent(t, database, "d1", "tool_use", dashboardToolUse)
+	if err := database.CreatePlanSession(&db.PlanSession{ID: "r1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p", AgentType: db.LoopAgentReviewer}); err != nil {
+		t.Fatal(err)
+	}
+	createDashboardEvent(t, database, "r1", "result", dashboardResult)
+	if err := database.UpdatePlanStatus("plan-1", db.PlanStatusRunning); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.UpdatePlanStatus("plan-1", db.PlanStatusCompleted); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := followPlan(ctx, database, "plan-1", time.Hour)
+
+	want := []loop.EventType{
+		loop.EventIterationStart,
+		loop.EventDeveloperStart,
+		loop.EventClaudeStream,
+		loop.EventReviewerStart,
+		loop.EventClaudeStream,
+		loop.EventDone,
+	}
+	for i, wantType := range want {
+		select {
+		case event := <-events:
+			if event.Type != wantType {
+				t.Fatalf("event %d type = %s, want %s", i, event.Type, wantType)
+			}
+			if event.Iteration != 1 {
+				t.Errorf("event %d iteration = %d, want 1", i, event.Iteration)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for event %d (%s)", i, wantType)
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected no events after the plan's history")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("events channel not closed after cancel")
+	}
+}
+
+func TestPlanStatusEvent(t *testing.T) {
+	tests := []struct {
+		status db.PlanStatus
+		reason string
+		want   loop.EventType
+		ok     bool
+	}{
+		{db.PlanStatusRunning, "", "", false},
+		{db.PlanStatusCompleted, "", loop.EventDone, true},
+		{db.PlanStatusFailed, "boom", loop.EventFailed, true},
+		{db.PlanStatusStopped, "Reached max iterations (5)", loop.EventMaxIterations, true},
+		{db.PlanStatusStopped, "Stopped after 3 iterations without progress", loop.EventStallAborted, true},
+		{db.PlanStatusPaused, "Reached max duration (1h0m0s)", loop.EventMaxDuration, true},
+		{db.PlanStatusPaused, "Paused by request", loop.EventPaused, true},
+		{db.PlanStatusPaused, "Interrupted", "", false},
+	}
+	for _, tt := range tests {
+		event, ok := planStatusEvent(&db.Plan{Status: tt.status, FailureReason: tt.reason}, 3)
+		if ok != tt.ok || event.Type != tt.want {
+			t.Errorf("planStatusEvent(%s, %q) = %s, %v; want %s, %v", tt.status, tt.reason, event.Type, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import "github.com/spf13/cobra"
+
+// dbCmd creates the db subcommand group.
+func dbCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Database maintenance commands",
+		Long:  `Database maintenance commands for checking the integrity of the plans database.`,
+	}
+
+	cmd.AddCommand(dbAuditCmd())
+
+	return cmd
+}
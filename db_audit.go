@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/spf13/cobra"
+)
+
+func dbAuditCmd() *cobra.Command {
+	var repair bool
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Report rows orphaned by deleted plans",
+		Long: `Report rows of sessions, events, transcripts, progress, learnings, and
+the other plan-owned tables whose plan or session no longer exists, such as
+rows left behind by plans deleted by hand.
+
+With --repair, the orphaned rows are deleted in a single transaction.
+
+Examples:
+  ralph db audit
+  ralph db audit --repair`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			return auditDB(cmd.OutOrStdout(), database, repair)
+		},
+	}
+
+	cmd.Flags().BoolVar(&repair, "repair", false, "Delete the orphaned rows")
+
+	return cmd
+}
+
+// auditDB reports orphaned rows, deleting them when repair is set.
+func auditDB(out io.Writer, database *db.DB, repair bool) error {
+	counts, err := database.AuditOrphans()
+	if err != nil {
+		return err
+	}
+	if len(counts) == 0 {
+		fmt.Fprintln(out, "No orphaned rows")
+		return nil
+	}
+
+	total := 0
+	for _, c := range counts {
+		fmt.Fprintf(out, "  %-22s %d\n", c.Table, c.Rows)
+		total += c.Rows
+	}
+
+	if !repair {
+		fmt.Fprintf(out, "\n%d orphaned row(s); run with --repair to delete them\n", total)
+		return nil
+	}
+
+	deleted, err := database.RepairOrphans()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\nDeleted %d orphaned row(s)\n", deleted)
+	return nil
+}
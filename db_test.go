@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDBCmd_SubcommandGroup(t *testing.T) {
+	cmd := dbCmd()
+	if cmd.Use != "db" {
+		t.Errorf("dbCmd().Use = %q, want %q", cmd.Use, "db")
+	}
+	if sub, _, err := cmd.Find([]string{"audit"}); err != nil || sub.Name() != "audit" {
+		t.Errorf("dbCmd() missing subcommand audit")
+	}
+}
+
+func TestAuditDB_Clean(t *testing.T) {
+	database := newPlansTestDB(t)
+
+	var out bytes.Buffer
+	if err := auditDB(&out, database, true); err != nil {
+		t.Fatalf("auditDB() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "No orphaned rows") {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/spf13/cobra"
+)
+
+func diffCmd() *cobra.Command {
+	var iteration int
+	var stat bool
+
+	cmd := &cobra.Command{
+		Use:   "diff <plan-id>",
+		Short: "Show the changes a plan made",
+		Long: `Print the cumulative diff of a plan, from the change it started on to the
+current working copy (@). With --iteration, print only the change made by that
+iteration's developer session. With --stat, print a per-file summary instead
+of the full diff.
+
+The diff is read from jj in the plan's repository (the current directory for
+plans that didn't record one).
+
+Examples:
+  ralph diff 3f2a9c1e
+  ralph diff 3f2a9c1e --iteration 2
+  ralph diff 3f2a9c1e --stat`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("iteration") && iteration < 1 {
+				return errors.New("--iteration must be at least 1")
+			}
+
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			workDir, err := planWorkDir(database, args[0])
+			if err != nil {
+				return err
+			}
+
+			return runDiff(cmd.Context(), cmd.OutOrStdout(), database, jj.NewClient(workDir), args[0], iteration, stat)
+		},
+	}
+
+	cmd.Flags().IntVarP(&iteration, "iteration", "i", 0, "Show only the change made by this iteration")
+	cmd.Flags().BoolVar(&stat, "stat", false, "Show a per-file summary instead of the full diff")
+
+	return cmd
+}
+
+// runDiff prints the diff of a plan, or of one of its iterations when
+// iteration is positive.
+func runDiff(ctx context.Context, out io.Writer, database *db.DB, jjClient *jj.Client, planID string, iteration int, stat bool) error {
+	plan, err := database.GetPlan(planID)
+	if errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("plan not found: %s", planID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get plan: %w", err)
+	}
+	if plan.BaseChangeID == "" {
+		return fmt.Errorf("plan %s has no recorded base change to diff from", planID)
+	}
+
+	from, to := plan.BaseChangeID, "@"
+	if iteration > 0 {
+		from, to, err = iterationRange(database, plan, iteration)
+		if err != nil {
+			return err
+		}
+	}
+
+	return printDiff(ctx, out, jjClient, from, to, stat)
+}
+
+// printDiff prints the diff between two revisions, or a per-file summary of
+// it when stat is set.
+func printDiff(ctx context.Context, out io.Writer, jjClient *jj.Client, from, to string, stat bool) error {
+	var diff string
+	var err error
+	if stat {
+		diff, err = jjClient.DiffSummary(ctx, from, to)
+	} else {
+		diff, err = jjClient.Diff(ctx, from, to)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to diff %s..%s: %w", from, to, err)
+	}
+
+	if strings.TrimSpace(diff) == "" {
+		fmt.Fprintln(out, "No changes.")
+		return nil
+	}
+	fmt.Fprint(out, diff)
+	if !strings.HasSuffix(diff, "\n") {
+		fmt.Fprintln(out)
+	}
+	return nil
+}
+
+// iterationRange returns the revisions bounding one iteration's change: the
+// working-copy commit recorded after the previous iteration's developer
+// session (or the plan's base change) and the one recorded after this
+// iteration's. Sessions superseded by resuming from an earlier iteration are
+// ignored.
+func iterationRange(database *db.DB, plan *db.Plan, iteration int) (from, to string, err error) {
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	from = plan.BaseChangeID
+	for _, session := range sessions {
+		if session.AgentType == db.LoopAgentPlanner || session.AgentType == db.LoopAgentReviewer || session.AgentType == db.LoopAgentRebuttalReviewer || session.CommitID == "" || session.Superseded {
+			continue
+		}
+		switch {
+		case session.Iteration < iteration:
+			from = session.CommitID
+		case session.Iteration == iteration:
+			to = session.CommitID
+		}
+	}
+
+	if to == "" {
+		return "", "", fmt.Errorf("no snapshot recorded for iteration %d of plan %s", iteration, plan.ID)
+	}
+	return from, to, nil
+}
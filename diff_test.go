@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+// newDiffTestDB stores a plan with two iterations of developer snapshots.
+func newDiffTestDB(t *testing.T, baseChangeID string) *db.DB {
+	t.Helper()
+	database := newPlansTestDB(t)
+	if err := database.CreatePlan(&db.Plan{ID: "plan-1", OriginPath: "plan.md", Content: "c", BaseChangeID: baseChangeID}); err != nil {
+		t.Fatal(err)
+	}
+	result := `{"type":"result"}`
+	createReportSession(t, database, &db.PlanSession{ID: "d1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p", CommitID: "c1"}, result)
+	createReportSession(t, database, &db.PlanSession{ID: "r1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p", AgentType: db.LoopAgentReviewer}, result)
+	createReportSession(t, database, &db.PlanSession{ID: "d2", PlanID: "plan-1", Iteration: 2, InputPrompt: "p", CommitID: "c2"}, result)
+	return database
+}
+
+func TestRunDiff(t *testing.T) {
+	database := newDiffTestDB(t, "base")
+
+	var calls [][]string
+	jjClient := jj.NewClient("/repo")
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		calls = append(calls, args)
+		return "diff output\n", "", nil
+	})
+
+	tests := []struct {
+		name      string
+		iteration int
+		stat      bool
+		want      []string
+	}{
+		{"cumulative", 0, false, []string{"diff", "--from", "base", "--to", "@"}},
+		{"first iteration", 1, false, []string{"diff", "--from", "base", "--to", "c1"}},
+		{"later iteration", 2, false, []string{"diff", "--from", "c1", "--to", "c2"}},
+		{"stat", 0, true, []string{"diff", "--stat", "--from", "base", "--to", "@"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			var out bytes.Buffer
+			if err := runDiff(context.Background(), &out, database, jjClient, "plan-1", tt.iteration, tt.stat); err != nil {
+				t.Fatalf("runDiff() error: %v", err)
+			}
+			if len(calls) != 1 || !slices.Equal(calls[0], tt.want) {
+				t.Errorf("jj calls = %v, want [%v]", calls, tt.want)
+			}
+			if out.String() != "diff output\n" {
+				t.Errorf("output = %q", out.String())
+			}
+		})
+	}
+}
+
+func TestRunDiff_Errors(t *testing.T) {
+	jjClient := jj.NewClient("/repo")
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		return "", "", nil
+	})
+
+	database := newDiffTestDB(t, "base")
+	var out bytes.Buffer
+	if err := runDiff(context.Background(), &out, database, jjClient, "missing", 0, false); err == nil || !strings.Contains(err.Error(), "plan not found") {
+		t.Errorf("expected plan not found error, got: %v", err)
+	}
+	if err := runDiff(context.Background(), &out, database, jjClient, "plan-1", 3, false); err == nil || !strings.Contains(err.Error(), "iteration 3") {
+		t.Errorf("expected missing iteration error, got: %v", err)
+	}
+	if err := runDiff(context.Background(), &out, database, jjClient, "plan-1", 0, false); err != nil || out.String() != "No changes.\n" {
+		t.Errorf("runDiff() with empty diff = %q, %v; want No changes.", out.String(), err)
+	}
+
+	noBase := newDiffTestDB(t, "")
+	if err := runDiff(context.Background(), &out, noBase, jjClient, "plan-1", 0, false); err == nil || !strings.Contains(err.Error(), "base change") {
+		t.Errorf("expected missing base change error, got: %v", err)
+	}
+}
+
+func TestDiffCmd_RejectsInvalidIteration(t *testing.T) {
+	cmd := diffCmd()
+	cmd.SetArgs([]string{"plan-1", "--iteration", "0"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "--iteration") {
+		t.Errorf("expected --iteration error, got: %v", err)
+	}
+}
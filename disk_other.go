@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package main
+
+import "errors"
+
+// diskFree is not implemented on this platform.
+func diskFree(string) (uint64, error) {
+	return 0, errors.New("not supported on this platform")
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/plandiff"
+)
+
+// editorRunner opens a file in the user's editor and waits for it to exit.
+// It can be replaced in tests.
+var editorRunner = defaultEditorRunner
+
+// confirmInput is where confirmation answers are read from.
+// It can be replaced in tests.
+var confirmInput io.Reader = os.Stdin
+
+// errEditCancelled is returned when the user rejects the edited plan.
+var errEditCancelled = errors.New("plan edit cancelled")
+
+// defaultEditorRunner runs $VISUAL or $EDITOR (falling back to vi) on path.
+// The variable may include arguments, e.g. "code --wait".
+func defaultEditorRunner(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	return nil
+}
+
+// editPlan opens a copy of the plan file in the editor, prints a diff between
+// the file and the edited copy, and asks for confirmation. It returns the
+// edited content; the plan file itself is never modified.
+func editPlan(out io.Writer, planPath string) (string, error) {
+	original, err := os.ReadFile(planPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	// Keep the extension so editors highlight structured plans as YAML or JSON
+	ext := filepath.Ext(planPath)
+	if ext == "" {
+		ext = ".md"
+	}
+	tmp, err := os.CreateTemp("", "ralph-plan-*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(original); err != nil {
+		_ = tmp.Close()
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	if err := editorRunner(tmp.Name()); err != nil {
+		return "", err
+	}
+
+	edited, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited plan: %w", err)
+	}
+
+	if string(edited) == string(original) {
+		fmt.Fprintln(out, "Plan unchanged.")
+		return string(edited), nil
+	}
+	if strings.TrimSpace(string(edited)) == "" {
+		return "", errors.New("edited plan is empty")
+	}
+
+	fmt.Fprintf(out, "Changes to %s (stored with the plan; the file is left untouched):\n\n", planPath)
+	fmt.Fprintln(out, plandiff.Diff(string(original), string(edited)))
+	fmt.Fprintln(out)
+	if !confirmYes(out, "Start with the edited plan?") {
+		return "", errEditCancelled
+	}
+	return string(edited), nil
+}
+
+// confirmYes asks a yes/no question defaulting to yes, reading the answer
+// from confirmInput. Anything but an empty answer, y, or Y is a no.
+func confirmYes(out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [Y/n]: ", question)
+
+	response, _ := bufio.NewReader(confirmInput).ReadString('\n')
+	response = strings.TrimSpace(response)
+	return response == "" || response == "y" || response == "Y"
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/app"
+)
+
+// stubEditor replaces the editor and confirmation input for a test.
+func stubEditor(t *testing.T, edited, answer string) {
+	t.Helper()
+	originalEditor, originalInput := editorRunner, confirmInput
+	t.Cleanup(func() { editorRunner, confirmInput = originalEditor, originalInput })
+
+	editorRunner = func(path string) error {
+		return os.WriteFile(path, []byte(edited), 0644)
+	}
+	confirmInput = strings.NewReader(answer)
+}
+
+func writePlan(t *testing.T, content string) string {
+	t.Helper()
+	planPath := filepath.Join(t.TempDir(), "plan.md")
+	if err := os.WriteFile(planPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write plan file: %v", err)
+	}
+	return planPath
+}
+
+func TestEditPlan_Confirmed(t *testing.T) {
+	planPath := writePlan(t, "# Plan\nold step\n")
+	stubEditor(t, "# Plan\nnew step\n", "\n")
+
+	var out bytes.Buffer
+	content, err := editPlan(&out, planPath)
+	if err != nil {
+		t.Fatalf("editPlan() error: %v", err)
+	}
+	if content != "# Plan\nnew step\n" {
+		t.Errorf("editPlan() = %q", content)
+	}
+	if !strings.Contains(out.String(), "-old step") || !strings.Contains(out.String(), "+new step") {
+		t.Errorf("expected diff in output, got:\n%s", out.String())
+	}
+
+	// The plan file is left untouched
+	onDisk, _ := os.ReadFile(planPath)
+	if string(onDisk) != "# Plan\nold step\n" {
+		t.Errorf("plan file was modified: %q", onDisk)
+	}
+}
+
+func TestEditPlan_Rejected(t *testing.T) {
+	planPath := writePlan(t, "# Plan\nold step\n")
+	stubEditor(t, "# Plan\nnew step\n", "n\n")
+
+	_, err := editPlan(&bytes.Buffer{}, planPath)
+	if !errors.Is(err, errEditCancelled) {
+		t.Errorf("editPlan() error = %v, want errEditCancelled", err)
+	}
+}
+
+func TestEditPlan_Unchanged(t *testing.T) {
+	planPath := writePlan(t, "# Plan\n")
+	stubEditor(t, "# Plan\n", "")
+
+	var out bytes.Buffer
+	content, err := editPlan(&out, planPath)
+	if err != nil {
+		t.Fatalf("editPlan() error: %v", err)
+	}
+	if content != "# Plan\n" || !strings.Contains(out.String(), "Plan unchanged") {
+		t.Errorf("editPlan() = %q, output %q", content, out.String())
+	}
+}
+
+func TestEditPlan_EditorError(t *testing.T) {
+	planPath := writePlan(t, "# Plan\n")
+	stubEditor(t, "", "")
+	editorRunner = func(path string) error { return errors.New("editor crashed") }
+
+	if _, err := editPlan(&bytes.Buffer{}, planPath); err == nil || !strings.Contains(err.Error(), "editor crashed") {
+		t.Errorf("editPlan() error = %v, want editor error", err)
+	}
+}
+
+func TestRunNew_EditPassesContentToApp(t *testing.T) {
+	originalFactory := appFactory
+	defer func() { appFactory = originalFactory }()
+
+	planPath := writePlan(t, "# Plan\nold step\n")
+	stubEditor(t, "# Plan\nnew step\n", "y\n")
+
+	var capturedContent string
+	var capturedPath string
+	appFactory = func(cfg app.Config) (App, error) {
+		capturedContent = cfg.PlanContent
+		return &mockAppImpl{
+			runFunc: func(ctx context.Context, path string) error {
+				capturedPath = path
+				return nil
+			},
+		}, nil
+	}
+
+	if err := runNew(context.Background(), planPath, runOptions{}, true); err != nil {
+		t.Fatalf("runNew() error: %v", err)
+	}
+	if capturedContent != "# Plan\nnew step\n" {
+		t.Errorf("PlanContent = %q, want edited plan", capturedContent)
+	}
+	if capturedPath != planPath {
+		t.Errorf("Run() path = %q, want %q", capturedPath, planPath)
+	}
+}
+
+func TestRunNew_EditCancelledDoesNotRun(t *testing.T) {
+	originalFactory := appFactory
+	defer func() { appFactory = originalFactory }()
+
+	planPath := writePlan(t, "# Plan\nold step\n")
+	stubEditor(t, "# Plan\nnew step\n", "n\n")
+
+	appFactory = func(cfg app.Config) (App, error) {
+		t.Error("appFactory should not be called when the edit is cancelled")
+		return nil, nil
+	}
+
+	if err := runNew(context.Background(), planPath, runOptions{}, true); err != nil {
+		t.Errorf("runNew() error: %v", err)
+	}
+}
+
+func TestConfirmYes(t *testing.T) {
+	originalInput := confirmInput
+	defer func() { confirmInput = originalInput }()
+
+	for answer, want := range map[string]bool{"\n": true, "y\n": true, "Y": true, "": true, "n\n": false, "no\n": false} {
+		confirmInput = strings.NewReader(answer)
+		var out bytes.Buffer
+		if got := confirmYes(&out, "Resume it?"); got != want {
+			t.Errorf("confirmYes() with answer %q = %v, want %v", answer, got, want)
+		}
+		if out.String() != "Resume it? [Y/n]: " {
+			t.Errorf("confirmYes() output = %q", out.String())
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/scrollback"
+	"github.com/spf13/cobra"
+)
+
+// pagerRunner shows a file in the user's pager and waits for it to exit.
+// It can be replaced in tests.
+var pagerRunner = defaultPagerRunner
+
+// defaultPagerRunner runs $PAGER (falling back to less) on path. The
+// variable may include arguments, e.g. "less -S".
+func defaultPagerRunner(path string) error {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less"
+	}
+
+	fields := strings.Fields(pager)
+	cmd := exec.Command(fields[0], append(fields[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pager %q failed: %w", pager, err)
+	}
+	return nil
+}
+
+func feedCmd() *cobra.Command {
+	var noPager bool
+
+	cmd := &cobra.Command{
+		Use:   "feed <plan-id>",
+		Short: "Page through the TUI feed of a plan",
+		Long: `Show the TUI feed of a plan as plain text, from the scrollback file kept in
+the plan's .ralph/feeds/ directory while it ran (see tui.scrollback). The
+file is capped by tui.scrollback_max_mb, so the oldest output of a long plan
+may be gone.
+
+The feed opens in $PAGER (default less) when output is a terminal, and is
+printed otherwise.
+
+Examples:
+  ralph feed 3f2a9c1e
+  ralph feed 3f2a9c1e --no-pager | grep ERROR`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			path, err := feedPath(database, args[0])
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if f, ok := out.(*os.File); ok && !noPager {
+				if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+					return pagerRunner(path)
+				}
+			}
+			return printFeed(out, path)
+		},
+	}
+
+	cmd.Flags().BoolVar(&noPager, "no-pager", false, "Print the feed instead of opening it in $PAGER")
+
+	return cmd
+}
+
+// feedPath returns the scrollback file of a plan, checking it exists.
+// Plans created before their directory was recorded are looked up in the
+// current directory.
+func feedPath(database *db.DB, planID string) (string, error) {
+	plan, err := database.GetPlan(planID)
+	if errors.Is(err, db.ErrNotFound) {
+		return "", fmt.Errorf("plan not found: %s", planID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get plan: %w", err)
+	}
+
+	workDir := plan.WorkDir
+	if workDir == "" {
+		if workDir, err = os.Getwd(); err != nil {
+			return "", fmt.Errorf("failed to get working directory: %w", err)
+		}
+	}
+	path := scrollback.Path(filepath.Join(workDir, config.LocalDir), plan.ID)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("no feed recorded for plan %s (expected %s)", plan.ID, path)
+		}
+		return "", fmt.Errorf("failed to read feed: %w", err)
+	}
+	return path, nil
+}
+
+// printFeed copies a scrollback file to out.
+func printFeed(out io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read feed: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := io.Copy(out, f); err != nil {
+		return fmt.Errorf("failed to print feed: %w", err)
+	}
+	return nil
+}
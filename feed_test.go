@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/scrollback"
+)
+
+func TestFeedPath(t *testing.T) {
+	database := newPlansTestDB(t)
+	workDir := t.TempDir()
+	if err := database.CreatePlan(&db.Plan{ID: "plan-1", Content: "c", Status: db.PlanStatusCompleted, WorkDir: workDir}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := feedPath(database, "missing"); err == nil || !strings.Contains(err.Error(), "plan not found") {
+		t.Errorf("expected plan not found error, got: %v", err)
+	}
+	if _, err := feedPath(database, "plan-1"); err == nil || !strings.Contains(err.Error(), "no feed recorded") {
+		t.Errorf("expected no feed error, got: %v", err)
+	}
+
+	w, err := scrollback.Open(scrollback.Path(filepath.Join(workDir, config.LocalDir), "plan-1"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte("\x1b[32m✓ Iteration 1 done\x1b[0m\n"))
+	_ = w.Close()
+
+	path, err := feedPath(database, "plan-1")
+	if err != nil {
+		t.Fatalf("feedPath() error: %v", err)
+	}
+	var out bytes.Buffer
+	if err := printFeed(&out, path); err != nil {
+		t.Fatalf("printFeed() error: %v", err)
+	}
+	if out.String() != "✓ Iteration 1 done\n" {
+		t.Errorf("printFeed() = %q", out.String())
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+func forkCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fork <plan-id>",
+		Short: "Fork a plan to try an alternative approach",
+		Long: `Create a new plan with the content, latest progress, and latest learnings of
+an existing one, working in a new jj change started off the same base. The
+original plan's changes and history are left as they are, so both approaches
+can be run and compared.
+
+The new change becomes the working copy (@); run the fork with
+ralph --resume, and go back to the original's work with jj edit.
+
+Examples:
+  ralph fork 3f2a9c1e
+  ralph --resume <fork-id>
+  ralph compare 3f2a9c1e <fork-id>`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			workDir, err := planWorkDir(database, args[0])
+			if err != nil {
+				return err
+			}
+
+			return forkPlan(cmd.Context(), cmd.OutOrStdout(), database, jj.NewClient(workDir), args[0])
+		},
+	}
+
+	return cmd
+}
+
+// forkPlan forks a plan into a new plan working in a new jj change off the
+// plan's base change.
+func forkPlan(ctx context.Context, out io.Writer, database *db.DB, jjClient *jj.Client, planID string) error {
+	plan, err := database.GetPlan(planID)
+	if errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("plan not found: %s", planID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get plan: %w", err)
+	}
+	if plan.Status == db.PlanStatusRunning {
+		return fmt.Errorf("plan %s is running; stop it before forking", planID)
+	}
+	if plan.BaseChangeID == "" {
+		return fmt.Errorf("plan %s has no recorded base change to fork from", planID)
+	}
+	// Where the original's work is, to tell how to get back to it
+	head, _ := planHead(database, plan)
+
+	forkID := uuid.New().String()
+	if err := jjClient.NewOn(ctx, plan.BaseChangeID, fmt.Sprintf("ralph: fork of plan %s", planID)); err != nil {
+		return fmt.Errorf("failed to start a change for the fork: %w", err)
+	}
+	changeID, err := jjClient.GetCurrentChangeID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the fork's change ID: %w", err)
+	}
+
+	fork := &db.Plan{
+		ID:           forkID,
+		OriginPath:   plan.OriginPath,
+		Content:      plan.RunContent(),
+		BaseChangeID: plan.BaseChangeID,
+		WorkDir:      plan.WorkDir,
+		OriginHash:   plan.OriginHash,
+		ForkedFrom:   plan.ID,
+	}
+	if err := database.ForkPlan(fork, changeID); err != nil {
+		return fmt.Errorf("failed to create fork: %w", err)
+	}
+
+	fmt.Fprintf(out, "Forked plan %s into %s (change %s)\n", planID, forkID, changeID)
+	fmt.Fprintf(out, "Run it with:      ralph --resume %s\n", forkID)
+	fmt.Fprintf(out, "Compare it with:  ralph compare %s %s\n", planID, forkID)
+	if head != "" {
+		fmt.Fprintf(out, "Back to %s with: jj edit %s\n", planID, head)
+	}
+	return nil
+}
+
+// planHead returns the latest working-copy snapshot recorded for a plan: the
+// commit after its last developer session, or the change a fork started in.
+// Snapshots superseded by resuming from an earlier iteration are ignored.
+func planHead(database *db.DB, plan *db.Plan) (string, error) {
+	return planHeadAsOf(database, plan, 0)
+}
+
+// planHeadAsOf returns the latest snapshot recorded by the end of the given
+// iteration (0 = the latest iteration).
+func planHeadAsOf(database *db.DB, plan *db.Plan, iteration int) (string, error) {
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	head := ""
+	for _, session := range sessions {
+		if session.CommitID != "" && !session.Superseded && (iteration == 0 || session.Iteration <= iteration) {
+			head = session.CommitID
+		}
+	}
+	if head == "" {
+		return "", fmt.Errorf("no snapshot recorded for plan %s", plan.ID)
+	}
+	return head, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+func TestForkPlan(t *testing.T) {
+	database := newDiffTestDB(t, "base")
+	if err := database.CreateProgress(&db.Progress{PlanID: "plan-1", SessionID: "d2", Content: "Parser done"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls [][]string
+	jjClient := jj.NewClient("/repo")
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		calls = append(calls, args)
+		if args[0] == "log" {
+			return "forkchange\n", "", nil
+		}
+		return "", "", nil
+	})
+
+	var out bytes.Buffer
+	if err := forkPlan(context.Background(), &out, database, jjClient, "plan-1"); err != nil {
+		t.Fatalf("forkPlan() error: %v", err)
+	}
+	if len(calls) == 0 || !slices.Equal(calls[0], []string{"new", "base", "-m", "ralph: fork of plan plan-1"}) {
+		t.Errorf("jj calls = %v, want a new change off the base first", calls)
+	}
+	if !strings.Contains(out.String(), "jj edit c2") {
+		t.Errorf("expected how to get back to the original's work, got:\n%s", out.String())
+	}
+
+	plans, err := database.ListPlans(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fork *db.Plan
+	for _, p := range plans {
+		if p.ForkedFrom == "plan-1" {
+			fork = p
+		}
+	}
+	if fork == nil || fork.BaseChangeID != "base" {
+		t.Fatalf("expected a fork of plan-1 off the same base, got %+v", plans)
+	}
+	if !strings.Contains(out.String(), "ralph --resume "+fork.ID) {
+		t.Errorf("expected how to run the fork, got:\n%s", out.String())
+	}
+	progress, err := database.GetLatestProgress(fork.ID)
+	if err != nil || progress == nil || progress.Content != "Parser done" {
+		t.Errorf("GetLatestProgress(fork) = %+v, %v; want the original's progress", progress, err)
+	}
+	if head, err := planHead(database, fork); err != nil || head != "forkchange" {
+		t.Errorf("planHead(fork) = %q, %v; want the fork's change", head, err)
+	}
+}
+
+func TestForkPlan_Errors(t *testing.T) {
+	jjClient := jj.NewClient("/repo")
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		t.Errorf("unexpected jj call: %v", args)
+		return "", "", nil
+	})
+
+	var out bytes.Buffer
+	database := newDiffTestDB(t, "base")
+	if err := forkPlan(context.Background(), &out, database, jjClient, "missing"); err == nil || !strings.Contains(err.Error(), "plan not found") {
+		t.Errorf("expected plan not found error, got: %v", err)
+	}
+	if err := database.UpdatePlanStatus("plan-1", db.PlanStatusRunning); err != nil {
+		t.Fatal(err)
+	}
+	if err := forkPlan(context.Background(), &out, database, jjClient, "plan-1"); err == nil || !strings.Contains(err.Error(), "is running") {
+		t.Errorf("expected running plan error, got: %v", err)
+	}
+
+	noBase := newDiffTestDB(t, "")
+	if err := forkPlan(context.Background(), &out, noBase, jjClient, "plan-1"); err == nil || !strings.Contains(err.Error(), "base change") {
+		t.Errorf("expected missing base change error, got: %v", err)
+	}
+}
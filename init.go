@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// lookPath is the function used to locate required binaries.
+// It can be replaced in tests to mock tool detection.
+var lookPath = exec.LookPath
+
+// requiredTools lists the external commands ralph needs, with install hints.
+var requiredTools = []struct {
+	name string
+	hint string
+}{
+	{"jj", "install jujutsu: https://github.com/martinvonz/jj"},
+	{"claude", "install Claude Code: https://docs.anthropic.com/en/docs/claude-code"},
+}
+
+// localConfigTemplate is the starter project-local config written by init.
+// It sets nothing, so the global config applies until settings are added.
+const localConfigTemplate = `{}
+`
+
+// localIgnoreTemplate keeps the local database and logs out of version control.
+// jj honors .gitignore files, so this covers both jj and git.
+const localIgnoreTemplate = `# Local ralph state - do not commit
+*.db
+*.db-shm
+*.db-wal
+logs/
+`
+
+// promptStubTemplate is the header of a prompt override stub, followed by
+// the built-in prompt to start from. Template comments aren't sent.
+const promptStubTemplate = `{{/*
+Custom %s prompt for ralph: a Go text/template with the same fields as the
+built-in prompt below, which it replaces.
+
+To use it, point agents.%s in .ralph/config.json at this file, relative to
+the config file:
+
+  "agents": { "%s": "prompts/%s.md" }
+
+Leave it unreferenced to keep using the built-in prompt.
+*/}}
+`
+
+// starterPlanTemplate is the optional plan file written with --plan.
+const starterPlanTemplate = `# Plan
+
+## Goal
+
+Describe what you want to build.
+
+## Requirements
+
+- First requirement
+- Second requirement
+
+## Acceptance Criteria
+
+- How we know the work is done
+`
+
+func initCmd() *cobra.Command {
+	var withPlan bool
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Set up ralph in the current repository",
+		Long: `Set up a .ralph/ directory in the current repository.
+
+Creates a project-local config file, prompt override stubs, and ignore
+entries for the local database and logs. Verifies that jj and the claude
+CLI are installed.
+
+Examples:
+  ralph init            # Set up .ralph/
+  ralph init --plan     # Also create a starter plan.md
+  ralph init --force    # Overwrite existing files`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workDir, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("failed to get working directory: %w", err)
+			}
+			return runInit(cmd.OutOrStdout(), workDir, withPlan, force)
+		},
+	}
+
+	cmd.Flags().BoolVar(&withPlan, "plan", false, "Also create a starter plan.md template")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing files")
+
+	return cmd
+}
+
+func runInit(out io.Writer, workDir string, withPlan, force bool) error {
+	// Verify required tools first so we don't leave a half-configured repo
+	if err := checkRequiredTools(out); err != nil {
+		return err
+	}
+
+	localDir := filepath.Join(workDir, config.LocalDir)
+	if err := os.MkdirAll(filepath.Join(localDir, "prompts"), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", config.LocalDir, err)
+	}
+
+	files := []struct {
+		path    string
+		content string
+	}{
+		{filepath.Join(localDir, "config.json"), localConfigTemplate},
+		{filepath.Join(localDir, ".gitignore"), localIgnoreTemplate},
+		{filepath.Join(localDir, "prompts", "developer.md"), promptStub("developer", agent.DeveloperPromptTemplate)},
+		{filepath.Join(localDir, "prompts", "reviewer.md"), promptStub("reviewer", agent.ReviewerPromptTemplate)},
+	}
+	if withPlan {
+		files = append(files, struct {
+			path    string
+			content string
+		}{filepath.Join(workDir, "plan.md"), starterPlanTemplate})
+	}
+
+	for _, f := range files {
+		written, err := writeFileIfMissing(f.path, f.content, force)
+		if err != nil {
+			return err
+		}
+		rel, relErr := filepath.Rel(workDir, f.path)
+		if relErr != nil {
+			rel = f.path
+		}
+		if written {
+			fmt.Fprintf(out, "  created %s\n", rel)
+		} else {
+			fmt.Fprintf(out, "  exists  %s (use --force to overwrite)\n", rel)
+		}
+	}
+
+	fmt.Fprintf(out, "\nralph is ready. Start with: ralph %s\n", planHint(withPlan))
+	return nil
+}
+
+// checkRequiredTools verifies that every required binary is on PATH.
+func checkRequiredTools(out io.Writer) error {
+	var missing []string
+	for _, tool := range requiredTools {
+		path, err := lookPath(tool.name)
+		if err != nil {
+			fmt.Fprintf(out, "  missing %s (%s)\n", tool.name, tool.hint)
+			missing = append(missing, tool.name)
+			continue
+		}
+		fmt.Fprintf(out, "  found   %s at %s\n", tool.name, path)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("required tools not found: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// writeFileIfMissing writes content to path unless it already exists.
+// Returns whether the file was written.
+func writeFileIfMissing(path, content string, force bool) (bool, error) {
+	if !force {
+		if _, err := os.Stat(path); err == nil {
+			return false, nil
+		}
+	}
+	if err := os.WriteFile(path, []byte(content), filePermissions); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// promptStub renders the prompt override stub for an agent type.
+func promptStub(agentType, builtIn string) string {
+	return fmt.Sprintf(promptStubTemplate, agentType, agentType, agentType, agentType) + builtIn
+}
+
+// planHint returns the plan argument to suggest after init.
+func planHint(withPlan bool) string {
+	if withPlan {
+		return "plan.md"
+	}
+	return "<plan-file>"
+}
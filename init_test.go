@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/config"
+)
+
+// mockLookPath replaces lookPath for the duration of a test.
+func mockLookPath(t *testing.T, missing ...string) {
+	t.Helper()
+	original := lookPath
+	t.Cleanup(func() { lookPath = original })
+	lookPath = func(name string) (string, error) {
+		for _, m := range missing {
+			if m == name {
+				return "", errors.New("not found")
+			}
+		}
+		return "/usr/bin/" + name, nil
+	}
+}
+
+func TestRunInit_CreatesLocalDir(t *testing.T) {
+	mockLookPath(t)
+	workDir := t.TempDir()
+
+	var out bytes.Buffer
+	if err := runInit(&out, workDir, false, false); err != nil {
+		t.Fatalf("runInit() error: %v", err)
+	}
+
+	for _, rel := range []string{
+		".ralph/config.json",
+		".ralph/.gitignore",
+		".ralph/prompts/developer.md",
+		".ralph/prompts/reviewer.md",
+	} {
+		if _, err := os.Stat(filepath.Join(workDir, rel)); err != nil {
+			t.Errorf("expected %s to exist: %v", rel, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(workDir, "plan.md")); !os.IsNotExist(err) {
+		t.Error("plan.md should not be created without --plan")
+	}
+
+	ignore, _ := os.ReadFile(filepath.Join(workDir, ".ralph/.gitignore"))
+	if !strings.Contains(string(ignore), "*.db") || !strings.Contains(string(ignore), "logs/") {
+		t.Errorf("expected db and logs ignore entries, got:\n%s", ignore)
+	}
+}
+
+func TestRunInit_TemplatesKeepDefaults(t *testing.T) {
+	mockLookPath(t)
+	workDir := t.TempDir()
+
+	if err := runInit(&bytes.Buffer{}, workDir, false, false); err != nil {
+		t.Fatalf("runInit() error: %v", err)
+	}
+
+	// The local config sets nothing
+	cfg, err := config.LoadFromPath(filepath.Join(workDir, ".ralph", "config.json"))
+	if err != nil {
+		t.Fatalf("failed to load the local config: %v", err)
+	}
+	defaults := config.DefaultConfig()
+	if cfg.MaxIterations != defaults.MaxIterations || cfg.Claude.Model != defaults.Claude.Model || cfg.Agents.Developer != "" {
+		t.Errorf("expected the local config to keep the defaults, got %+v", cfg)
+	}
+
+	// The prompt stubs render as the built-in prompts, without their header
+	stub, err := os.ReadFile(filepath.Join(workDir, ".ralph", "prompts", "developer.md"))
+	if err != nil {
+		t.Fatalf("expected the developer prompt stub: %v", err)
+	}
+	devCtx := agent.DeveloperContext{PlanContent: "Build a REST API"}
+	want, err := agent.BuildDeveloperPrompt(devCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	devCtx.Template = string(stub)
+	if got, err := agent.BuildDeveloperPrompt(devCtx); err != nil || strings.TrimSpace(got) != strings.TrimSpace(want) {
+		t.Errorf("expected the stub to render as the built-in prompt, got %v:\n%s", err, got)
+	}
+}
+
+func TestRunInit_WithPlan(t *testing.T) {
+	mockLookPath(t)
+	workDir := t.TempDir()
+
+	var out bytes.Buffer
+	if err := runInit(&out, workDir, true, false); err != nil {
+		t.Fatalf("runInit() error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(workDir, "plan.md"))
+	if err != nil {
+		t.Fatalf("expected plan.md: %v", err)
+	}
+	if !strings.Contains(string(content), "# Plan") {
+		t.Errorf("unexpected plan template: %s", content)
+	}
+	if !strings.Contains(out.String(), "ralph plan.md") {
+		t.Errorf("expected next-step hint, got:\n%s", out.String())
+	}
+}
+
+func TestRunInit_KeepsExistingFiles(t *testing.T) {
+	mockLookPath(t)
+	workDir := t.TempDir()
+	configPath := filepath.Join(workDir, ".ralph", "config.json")
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configPath, []byte(`{"max_iterations": 3}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runInit(&out, workDir, false, false); err != nil {
+		t.Fatalf("runInit() error: %v", err)
+	}
+
+	content, _ := os.ReadFile(configPath)
+	if string(content) != `{"max_iterations": 3}` {
+		t.Errorf("existing config was overwritten: %s", content)
+	}
+	if !strings.Contains(out.String(), "exists") {
+		t.Errorf("expected 'exists' notice, got:\n%s", out.String())
+	}
+
+	// --force overwrites
+	if err := runInit(&out, workDir, false, true); err != nil {
+		t.Fatalf("runInit(force) error: %v", err)
+	}
+	content, _ = os.ReadFile(configPath)
+	if string(content) != localConfigTemplate {
+		t.Errorf("expected config to be overwritten with --force, got: %s", content)
+	}
+}
+
+func TestRunInit_MissingTools(t *testing.T) {
+	mockLookPath(t, "claude")
+	workDir := t.TempDir()
+
+	var out bytes.Buffer
+	err := runInit(&out, workDir, false, false)
+	if err == nil {
+		t.Fatal("expected error when claude is missing")
+	}
+	if !strings.Contains(err.Error(), "claude") {
+		t.Errorf("expected error to mention claude, got: %v", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(workDir, ".ralph")); !os.IsNotExist(statErr) {
+		t.Error(".ralph should not be created when tools are missing")
+	}
+}
+
+func TestInitCmd_Flags(t *testing.T) {
+	cmd := initCmd()
+	if cmd.Flags().Lookup("plan") == nil {
+		t.Error("expected --plan flag")
+	}
+	if cmd.Flags().Lookup("force") == nil {
+		t.Error("expected --force flag")
+	}
+}
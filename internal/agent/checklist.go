@@ -0,0 +1,21 @@
+package agent
+
+import "github.com/gerunddev/ralph/internal/frontmatter"
+
+// PlanChecklist returns the checks in the "review_checklist:" block of a
+// plan's front matter, which the reviewer verifies along with its own
+// checklist before approving a done signal (e.g. "no new dependencies").
+// The block is an indented list of "- check" lines or an inline "[a, b]"
+// list. Other front matter keys are ignored. A plan without front matter or
+// a checklist block has none.
+func PlanChecklist(plan string) ([]string, error) {
+	return frontmatter.List(plan, "review_checklist", "check")
+}
+
+// AcceptanceCriteria returns the criteria in the "acceptance_criteria:"
+// block of a plan's front matter, which the reviewer must mark satisfied,
+// one by one, before a done signal is approved. The block is written like
+// the review checklist; structured plans put their criteria there.
+func AcceptanceCriteria(plan string) ([]string, error) {
+	return frontmatter.List(plan, "acceptance_criteria", "criterion")
+}
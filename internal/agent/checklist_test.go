@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPlanChecklist(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    string
+		want    []string
+		wantErr string
+	}{
+		{"no front matter", "# Plan\n- not a check\n", nil, ""},
+		{"no checklist", "---\nfiles:\n  - \"*.go\"\n---\n# Plan\n", nil, ""},
+		{"checklist", "---\nfiles:\n  - \"*.go\"\nreview_checklist:\n  - verify i18n strings extracted\n  # comment\n  - \"no new deps\"\nother: x\n---\n- not a check\n",
+			[]string{"verify i18n strings extracted", "no new deps"}, ""},
+		{"inline value", "---\nreview_checklist: no new deps\n---\n", nil, "review_checklist must be a block"},
+		{"empty check", "---\nreview_checklist:\n  -\n---\n", nil, "empty review_checklist check"},
+		{"unclosed", "---\nreview_checklist:\n  - no new deps\n", nil, "missing its closing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PlanChecklist(tt.plan)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("PlanChecklist() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PlanChecklist() error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("PlanChecklist() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcceptanceCriteria(t *testing.T) {
+	plan := "---\nreview_checklist:\n  - no new deps\nacceptance_criteria:\n  - export writes a header row\n---\n# Plan\n"
+	got, err := AcceptanceCriteria(plan)
+	if err != nil {
+		t.Fatalf("AcceptanceCriteria() error: %v", err)
+	}
+	if !slices.Equal(got, []string{"export writes a header row"}) {
+		t.Errorf("AcceptanceCriteria() = %q", got)
+	}
+	if _, err := AcceptanceCriteria("---\nacceptance_criteria: works\n---\n"); err == nil ||
+		!strings.Contains(err.Error(), "acceptance_criteria must be a block") {
+		t.Errorf("AcceptanceCriteria() error = %v, want an invalid acceptance_criteria error", err)
+	}
+}
+
+func TestBuildReviewerPrompt_AcceptanceCriteria(t *testing.T) {
+	ctx := ReviewerContext{PlanContent: "Build a REST API", DevSignaledDone: true, AcceptanceCriteria: []string{"GET /users returns 200"}}
+	result, err := BuildReviewerPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "- GET /users returns 200\n") || !strings.Contains(result, "### Acceptance Criteria") {
+		t.Errorf("expected the acceptance criteria in the reviewer prompt:\n%s", result)
+	}
+
+	ctx.AcceptanceCriteria = nil
+	result, err = BuildReviewerPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result, "Acceptance Criteria") {
+		t.Error("expected no acceptance criteria section without criteria")
+	}
+}
+
+func TestBuildReviewerPrompt_Checklist(t *testing.T) {
+	ctx := ReviewerContext{PlanContent: "Build a REST API", DevSignaledDone: true, Checklist: []string{"no new deps"}}
+	result, err := BuildReviewerPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "This plan adds its own checks") || !strings.Contains(result, "- no new deps\n") ||
+		!strings.Contains(result, "### Plan Checklist") || !strings.Contains(result, "A failed plan check is a major issue") {
+		t.Errorf("expected the plan checks in the reviewer prompt:\n%s", result)
+	}
+
+	ctx.Checklist = nil
+	result, err = BuildReviewerPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result, "Plan Checklist") || strings.Contains(result, "plan check") {
+		t.Error("expected no plan checks without a checklist")
+	}
+}
@@ -6,12 +6,23 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"text/template"
+
+	"github.com/gerunddev/ralph/internal/locale"
 )
 
 // ErrEmptyPlanContent is returned when PlanContent is empty or whitespace-only.
 var ErrEmptyPlanContent = errors.New("PlanContent cannot be empty")
 
+// ErrEmptyOutput is returned when the output to reformat is empty or
+// whitespace-only.
+var ErrEmptyOutput = errors.New("output to reformat cannot be empty")
+
+// ErrEmptyDiff is returned when a diff summary prompt is built without a
+// diff.
+var ErrEmptyDiff = errors.New("diff to summarize cannot be empty")
+
 // PromptTemplate is the Go template for building the agent prompt.
 // It includes static instructions and dynamic sections for plan, progress, and learnings.
 const PromptTemplate = `# Instructions
@@ -91,7 +102,25 @@ type DeveloperContext struct {
 	Progress         string // Current progress (empty string if none)
 	Learnings        string // Current learnings (empty string if none)
 	ReviewerFeedback string // Feedback from last review rejection (empty if none)
+	OpenItems        string // Review items raised earlier and not yet verified fixed, formatted (empty if none)
+	RebuttalAllowed  bool   // Whether the developer may dispute ReviewerFeedback with a rebuttal
+	SubPlans         bool   // Whether the developer may propose a sub-plan for a large tangent
+	RunSubPlans      bool   // Whether a proposed sub-plan runs before the plan resumes, rather than being recorded for later
 	TeamMode         bool   // Whether agent teams are enabled
+	Stuck            bool   // Whether recent iterations made no progress
+	GlobalLearnings  string // Relevant repo-wide learnings from other plans (empty if none)
+	Rejected         string // Approaches rejected earlier in the plan, not to repeat (empty if none)
+	CurrentTask      string // Task being worked on when the plan is decomposed (empty if none)
+	PlanUpdate       string // Diff of plan file edits merged since the last iteration (empty if none)
+	OutOfScope       string // Changes outside the plan's scope or files allowlist, and what was done (empty if none)
+	Scope            string // Mono-repo directory the plan is limited to (empty = the whole repository)
+	FailingChecks    string // Output of the checks that failed after the last session (empty if none)
+	UserFeedback     string // Feedback the user sent while the plan ran (empty if none)
+	Conventions      string // The repository's convention files, formatted (empty if none)
+	StatusTool       bool   // Whether to report status with the ralph_status tool
+	StateTools       bool   // Whether the plan's state can be queried with tools
+	Locale           string // Locale code of the language to write in ("" = English)
+	Template         string // Custom prompt template replacing the built-in one (empty = built-in)
 }
 
 // ReviewerContext holds context for reviewer agent prompts.
@@ -102,6 +131,101 @@ type ReviewerContext struct {
 	DiffOutput       string // Output from jj show (the changes to review)
 	DeveloperSummary string // Developer's output text for context
 	DevSignaledDone  bool   // Whether the developer has signaled completion
+	CurrentTask      string // Task being reviewed when the plan is decomposed (empty if none)
+	Findings         string // Output of the configured static analyzers (empty if none)
+	Conventions      string // The repository's convention files, formatted (empty if none)
+	Scope            string // Mono-repo directory the plan is limited to (empty = the whole repository)
+
+	// Checklist is the plan's own checks (see PlanChecklist), verified
+	// with the review checklist when the developer signals done.
+	Checklist []string
+
+	// AcceptanceCriteria is the plan's acceptance criteria (see
+	// AcceptanceCriteria), each of which must be marked satisfied before a
+	// done signal is approved.
+	AcceptanceCriteria []string
+
+	// WithdrawnFeedback is earlier feedback withdrawn after the developer's
+	// rebuttal, which must not be raised again (empty if none).
+	WithdrawnFeedback string
+
+	// ChangedFiles is the current content of the files the diff changes,
+	// formatted, for reading its hunks in context (empty unless the plan's
+	// review context includes files).
+	ChangedFiles string
+
+	// OpenItems is the review items raised earlier and not yet verified
+	// fixed, formatted, some of which the developer claims to have fixed
+	// (empty if none).
+	OpenItems string
+
+	// AuthorTests asks the reviewer of a done signal to add a test of the
+	// change before approving (test-authoring mode).
+	AuthorTests bool
+
+	// Review panel: when several reviewers review the same work, this
+	// reviewer's seat (1-based), the panel size, and the approvals needed
+	// (PanelSize <= 1 = single reviewer).
+	PanelSeat int
+	PanelSize int
+	Quorum    int
+
+	StatusTool bool // Whether to report status with the ralph_status tool
+	StateTools bool // Whether the plan's state can be queried with tools
+
+	Locale   string // Locale code of the language to write in ("" = English)
+	Template string // Custom prompt template replacing the built-in one (empty = built-in)
+}
+
+// PlannerContext holds context for the planner agent prompt.
+type PlannerContext struct {
+	PlanContent string // The full plan text
+	Locale      string // Locale code of the language to write in ("" = English)
+	Template    string // Custom prompt template replacing the built-in one (empty = built-in)
+}
+
+// ConflictResolverContext holds context for the conflict resolver agent
+// prompt.
+type ConflictResolverContext struct {
+	PlanContent string   // The full plan text
+	Conflicts   []string // Paths with unresolved jj conflicts
+	Status      string   // Output of jj status
+	Locale      string   // Locale code of the language to write in ("" = English)
+}
+
+// RebuttalReviewContext holds context for the rebuttal reviewer agent
+// prompt.
+type RebuttalReviewContext struct {
+	PlanContent string // The full plan text
+	Feedback    string // The review feedback the developer disputes
+	Rebuttal    string // The developer's rebuttal
+	DiffOutput  string // The changes under review, as they stand
+	Locale      string // Locale code of the language to write in ("" = English)
+}
+
+// ReformatContext holds context for the prompt asking an agent's malformed
+// output to be restated in the required sections.
+type ReformatContext struct {
+	Reviewer bool   // The output is a reviewer's (otherwise a developer's)
+	Output   string // The malformed output
+	Locale   string // Locale code of the language to write in ("" = English)
+}
+
+// DiffSummaryContext holds context for the prompt summarizing part of a
+// diff too large for the reviewer.
+type DiffSummaryContext struct {
+	Diff   string // Git-format diff of the files to summarize
+	Locale string // Locale code of the language to write in ("" = English)
+}
+
+// FailureTriageContext holds context for the failure triage agent prompt.
+type FailureTriageContext struct {
+	PlanContent string // The full plan text
+	Failure     string // What keeps failing, e.g. "Iteration error" or "Checks failed: tests"
+	Count       int    // Consecutive times it failed
+	Detail      string // The latest failure's error message or output
+	Progress    string // The latest progress (may be empty)
+	Locale      string // Locale code of the language to write in ("" = English)
 }
 
 // BuildPrompt constructs the full agent prompt from the given context.
@@ -134,6 +258,15 @@ func BuildPrompt(ctx PromptContext) (string, error) {
 	return buf.String(), nil
 }
 
+// stateToolsSection lists the tools agents query a plan's state with, for
+// the developer and reviewer prompts.
+const stateToolsSection = `This prompt includes only the latest progress and learnings. Query the rest of the plan's state with these tools when you need it:
+- ` + "`get_progress_history`" + `: every progress entry recorded for the plan
+- ` + "`get_learnings`" + `: every distinct learning recorded for the plan
+- ` + "`get_reviewer_feedback`" + `: the reviewer feedback not yet addressed
+- ` + "`get_plan`" + `: the plan, including edits made since this session started
+`
+
 // DeveloperPromptTemplate is the template for developer agent prompts.
 const DeveloperPromptTemplate = `# Instructions
 
@@ -165,6 +298,13 @@ Always output three sections with these exact headers, separated by horizontal r
 ## Learnings
 [Insights about the codebase, patterns discovered, approaches that didn't work]
 
+Optionally, add a section for learnings that apply to the whole repository
+beyond this plan (build commands, conventions, pitfalls), one per bullet.
+They will be shared with future plans in this repository:
+
+## Global Learnings
+- [Durable, repo-wide learning]
+
 ---
 
 ## Status
@@ -179,12 +319,58 @@ DEV_DONE DEV_DONE DEV_DONE!!!
 Review your changes carefully before signaling done. A reviewer will verify
 your work, and if issues are found, you will need to address them.
 
+Only signal done from a session in which you changed no files. If you
+changed anything (with any tool, including shell commands and code
+generators), keep the status RUNNING and verify those changes in the next
+session; a DEV_DONE from a session that changed files is rejected.
+{{if .StatusTool}}
+## Reporting Status
+
+Instead of writing the sections above, report by calling the ` + "`ralph_status`" + ` tool once, as the last thing you do in the session. Pass your progress, learnings, and any global learnings; set done to signal DEV_DONE, under the same rules; put a rebuttal, when you write one, in rebuttal; and put the numbers of the open review items you fixed in fixed_items{{if .SubPlans}}, and a sub-plan, when you propose one, in sub_plan{{end}}. If the call fails, fix its arguments and call it again. The sections are only read when you make no call.
+{{end}}{{if .StateTools}}
+## Plan State
+
+` + stateToolsSection + `
+Call ` + "`record_progress`" + ` after each significant step to checkpoint your progress. Checkpoints are kept if the session ends unexpectedly.
+{{end}}{{if .Conventions}}
+---
+
+# Repository Conventions
+
+The repository documents these conventions. Follow them in everything you write:
+
+{{.Conventions}}
+{{end}}{{if .Scope}}
+---
+
+# Scope
+
+This repository is a mono-repo, and this plan is limited to ` + "`{{.Scope}}/`" + `. Only change files under that directory: changes anywhere else are reverted, as other plans may be working on other parts of the repository. If the plan cannot be completed without changing files outside it, record that in Learnings instead.
+{{end}}
 ---
 
 # Plan
 
 {{.PlanContent}}
+{{if .PlanUpdate}}
+---
+
+# Plan Updated
 
+The plan file was edited while you were working, and the plan above already includes these changes. Check your progress against the updated plan: pick up anything the edit added and drop work it removed.
+
+` + "```diff" + `
+{{.PlanUpdate}}
+` + "```" + `
+{{end}}{{if .CurrentTask}}
+---
+
+# Current Task
+
+The plan has been broken into tasks that are completed one at a time. Work ONLY on this task; later tasks are handled in later sessions. Signal DEV_DONE when this task, not the whole plan, is complete.
+
+{{.CurrentTask}}
+{{end}}
 ---
 
 # Progress So Far
@@ -196,7 +382,21 @@ your work, and if issues are found, you will need to address them.
 # Learnings So Far
 
 {{if .Learnings}}{{.Learnings}}{{else}}No learnings yet.{{end}}
-{{if .ReviewerFeedback}}
+{{if .GlobalLearnings}}
+---
+
+# Repository Learnings (from previous plans)
+
+{{.GlobalLearnings}}
+{{end}}{{if .Rejected}}
+---
+
+# Previously Rejected Approaches
+
+These approaches were rejected by the reviewer, or kept failing the checks, earlier in this plan. Do not retry them. If one seems unavoidable, record why in Learnings instead:
+
+{{.Rejected}}
+{{end}}{{if .ReviewerFeedback}}
 ---
 
 # Reviewer Feedback (from last review - MUST ADDRESS)
@@ -204,6 +404,78 @@ your work, and if issues are found, you will need to address them.
 The reviewer rejected your previous work. You MUST address all the following issues:
 
 {{.ReviewerFeedback}}
+{{if .RebuttalAllowed}}
+If you are confident that a point above is wrong (for example, it misreads the code or contradicts the plan), you may dispute it once instead of changing the code for it. Address every other point as usual, and explain the dispute in this section of your output:
+
+## Rebuttal
+[The point you dispute and why, with file:line references. If it is one of the open review items, cite its number: #[number]]
+
+The reviewer re-evaluates the disputed point against the code as it stands. If it upholds the feedback, you must address it; there is no second rebuttal.
+{{end}}{{end}}{{if .OpenItems}}
+---
+
+# Open Review Items
+
+These review items are still open: a reviewer raised them and has not yet verified them fixed. Items stay open until a reviewer verifies the fix, so do not assume an item is closed because it is no longer in the latest feedback:
+
+{{.OpenItems}}
+
+When you fix any of them, list their numbers in this section of your output, before the status:
+
+## Fixed Items
+- #[number]
+
+The reviewer checks each item you list, and reopens any it finds not fixed.
+{{end}}{{if .OutOfScope}}
+---
+
+# Out-of-Scope Changes
+
+Your last session changed files the plan does not allow you to touch:
+
+{{.OutOfScope}}
+
+Keep your work within those files. If the plan cannot be completed without changing others, record that in Learnings instead of changing them.
+{{end}}{{if .FailingChecks}}
+---
+
+# Failing Checks
+
+These checks failed after your last session. Their output is below, trimmed to the failures; there is no need to rerun them to find out what failed. Fix these failures before continuing with the plan:
+
+{{.FailingChecks}}
+{{end}}{{if .UserFeedback}}
+---
+
+# User Feedback (MUST ADDRESS)
+
+The user sent this feedback while you were working. It takes precedence over your own plans for this iteration:
+
+{{.UserFeedback}}
+{{end}}{{if .Stuck}}
+---
+
+# You Are Stuck
+
+Your last several iterations produced no new changes and no new progress. Repeating the same approach will not work. You MUST change approach:
+
+1. Re-read the plan and your learnings, and identify what is blocking you
+2. Try a fundamentally different solution rather than tweaking the previous one
+3. If something outside your control blocks the work, record it in Learnings and move on to another part of the plan
+{{end}}{{if .SubPlans}}
+---
+
+# Sub-Plans
+
+If the plan depends on a large tangent that deserves a plan of its own (for example, a refactoring the work needs first), you may propose a sub-plan for it instead of doing it in this session. Write the sub-plan, complete enough to be worked on without this plan, in this section of your output, before the status:
+
+## Sub-Plan
+` + "```markdown" + `
+# [Title]
+[What to do, and how to tell it is done]
+` + "```" + `
+
+{{if .RunSubPlans}}The sub-plan runs to completion on its own before this plan resumes, and its learnings are added to yours.{{else}}The sub-plan is recorded to be run separately; carry on with the rest of this plan without it.{{end}} Propose at most one per session, and only for work too large to do here.
 {{end}}{{if .TeamMode}}
 ---
 
@@ -223,6 +495,7 @@ Important:
 - If a teammate encounters an edit conflict (old_string not found), they should re-read the file and retry
 - When all team work is complete, report the combined progress and learnings in your output
 - Signal DEV_DONE only when ALL teammates have finished their work
+- When reviewer feedback is grouped by teammate, send each group to the teammate that owns those files (spawn a new one for the group if that teammate has finished)
 {{end}}`
 
 // ReviewerPromptTemplate is the template for reviewer agent prompts.
@@ -259,7 +532,15 @@ For EACH file in the diff, check:
 6. **Tests** - Are there tests? Do they cover the happy path AND edge cases?
 7. **Style** - Consistent with the codebase? Clear naming? Appropriate comments?
 8. **Documentation** - Are public APIs documented? Complex logic explained?
-
+{{if .Checklist}}
+This plan adds its own checks, which apply to the work as a whole:
+{{range .Checklist}}- {{.}}
+{{end}}{{end}}{{if .AcceptanceCriteria}}
+## Acceptance Criteria
+
+The plan is only done when the work satisfies every one of its acceptance criteria. Check each one explicitly against the code, not against the developer's summary:
+{{range .AcceptanceCriteria}}- {{.}}
+{{end}}{{end}}
 ## Output Format
 
 Always output three sections with these exact headers:
@@ -282,7 +563,17 @@ Then output your review findings:
 
 ### Minor Issues
 [List each minor issue with file:line reference, or "None"]
+{{if .Checklist}}
+### Plan Checklist
+[One line per plan check, in the order listed above: "- [x] <check>" if the work passes it, or "- [ ] <check>: <why it fails>" if it doesn't]
 
+A failed plan check is a major issue: list it under Major Issues too.
+{{end}}{{if .AcceptanceCriteria}}
+### Acceptance Criteria
+[One line per acceptance criterion, in the order listed above: "- [x] <criterion>" if the work satisfies it, or "- [ ] <criterion>: <what is missing>" if it doesn't]
+
+An unsatisfied acceptance criterion is a major issue: list it under Major Issues too.
+{{end}}
 ### Verdict
 
 If ALL issue lists above are exactly "None":
@@ -313,7 +604,12 @@ If the diff section shows "No code changes to review" then no code was modified
 - Incomplete features that are clearly still in progress per the plan
 - Missing tests for code that isn't finished yet
 - TODOs or placeholder code that the developer is clearly planning to address
+{{if .AcceptanceCriteria}}
+## Acceptance Criteria
 
+The finished work must satisfy these. Flag work that heads away from them, but not criteria that are simply not met yet:
+{{range .AcceptanceCriteria}}- {{.}}
+{{end}}{{end}}
 ## Output Format
 
 Always output three sections with these exact headers:
@@ -344,13 +640,91 @@ REVIEWER_APPROVED REVIEWER_APPROVED!!!
 
 If you spot issues worth addressing now:
 REVIEWER_FEEDBACK: [Summarize what needs to be fixed]
+{{end}}{{if .StatusTool}}
+## Reporting Status
+
+Instead of writing the sections above, report by calling the ` + "`ralph_status`" + ` tool once, as the last thing you do in the session. Pass your progress and learnings; set approved where you would write REVIEWER_APPROVED, and otherwise put what needs to be fixed, with any Suggested Patch block, in feedback.{{if and .DevSignaledDone .Checklist}} Report each plan check in checklist, with a note saying why when it fails.{{end}}{{if and .DevSignaledDone .AcceptanceCriteria}} Report each acceptance criterion in criteria, with a note saying what is missing when it isn't satisfied.{{end}}{{if .OpenItems}} Put the numbers of the review items you verified fixed in verified_items.{{end}} Name any approach not to try again in rejected_approaches. If the call fails, fix its arguments and call it again. The sections are only read when you make no call.
+{{end}}{{if .StateTools}}
+## Plan State
+
+` + stateToolsSection + `{{end}}
+## Suggested Patches
+
+When a fix is small and unambiguous, you MAY attach it to REVIEWER_FEEDBACK as a unified diff, with paths relative to the repository root, in a fenced block under this exact header after the Verdict:
+
+### Suggested Patch
+` + "```diff" + `
+--- a/path/to/file.go
++++ b/path/to/file.go
+@@ -10,3 +10,3 @@
+` + "```" + `
+
+Do not edit files yourself{{if .AuthorTests}}, other than the tests described below{{end}}. Leave the section out when you approve.
+
+## Rejected Approaches
+
+When the developer's approach itself is wrong, not just its details, and must not be tried again, name it with REVIEWER_FEEDBACK under this exact header after the Verdict:
+
+### Rejected Approaches
+- [The approach and why it must not be retried]
+
+Leave the section out when you only want the details fixed. Issues you raise again after the developer tried to fix them are also recorded as rejected approaches.
+{{if .AuthorTests}}
+## Test Authoring
+
+Before you approve, you MUST add at least one new test that exercises the developer's change: it must FAIL without the change and PASS with it. Only add or edit test files; do not touch the code under review. Your edits are recorded as a jj change of their own, attributed to you.
+
+After you approve, ralph runs the test suite with your tests, then again with the developer's change reverted. An approval is discarded if you added no test, if your tests fail with the change, or if they still pass without it. If you reject the work, you may still leave tests that show the problem.
+{{end}}{{if gt .PanelSize 1}}
+## Review Panel
+
+You are reviewer {{.PanelSeat}} of {{.PanelSize}} reviewing this work independently. The plan is complete only when {{.Quorum}} of the {{.PanelSize}} reviewers approve. Judge the work on its own merits; do not assume another reviewer will catch what you skip. Your feedback is merged with the other reviewers', so reference files by their path from the repository root.
+{{end}}{{if .OpenItems}}
+## Open Review Items
+
+These items from earlier reviews are still open. For each one the developer claims to have fixed, check the code to verify the fix, and list the numbers of those you verified in this section after the Verdict:
+
+### Verified Items
+- #[number]
+
+{{.OpenItems}}
+
+A claimed item you do not list is reopened. Approving verifies every claimed item{{if .DevSignaledDone}}, and approving the finished work closes every open item, so do not approve while one is still unfixed: raise it again under the issues above{{end}}. Do not repeat an open item as a new issue unless its fix is wrong or missing.
+{{end}}{{if .WithdrawnFeedback}}
+## Withdrawn Feedback
+
+The developer disputed a point of an earlier review, and it was withdrawn on re-evaluation. Do not raise that point again; the rest of that review still stands:
+
+{{.WithdrawnFeedback}}
+{{end}}{{if .Conventions}}
+---
+
+# Repository Conventions
+
+The repository documents these conventions. Flag changes that break them:
+
+{{.Conventions}}
+{{end}}{{if .Scope}}
+---
+
+# Scope
+
+This repository is a mono-repo, and this plan is limited to ` + "`{{.Scope}}/`" + `. The diff below covers only that directory, and changes outside it are reverted before you see them. Judge the work within the scope; do not ask for changes outside it.
 {{end}}
 ---
 
 # Plan (for context)
 
 {{.PlanContent}}
+{{if .CurrentTask}}
+---
+
+# Current Task
 
+The plan has been broken into tasks that are completed one at a time. Review the work against this task only; later tasks are not expected to be done yet. The diff below covers only this task's changes, and the progress and learnings above are this task's.
+
+{{.CurrentTask}}
+{{end}}
 ---
 
 # Progress So Far
@@ -368,21 +742,435 @@ REVIEWER_FEEDBACK: [Summarize what needs to be fixed]
 # Developer Summary
 
 {{if .DeveloperSummary}}{{.DeveloperSummary}}{{else}}No developer summary available.{{end}}
+{{if .Findings}}
+---
 
+# Automated Findings
+
+Static analyzers were run on the changed files. Verify each finding against the diff: report real problems as issues, and ignore findings in code the developer did not touch.
+
+{{.Findings}}
+{{end}}
 ---
 
 # Diff to Review
 
 {{if .DiffOutput}}` + "```diff" + `
 {{.DiffOutput}}
-` + "```" + `{{else}}No code changes to review. The developer completed analysis/investigation without modifying any files. Review the Developer Summary section above to verify the developer's conclusions are sound.{{end}}`
+` + "```" + `{{else}}No code changes to review. The developer completed analysis/investigation without modifying any files. Review the Developer Summary section above to verify the developer's conclusions are sound.{{end}}{{if .ChangedFiles}}
+
+---
+
+# Changed Files
+
+The current content of the files the diff changes, so you can read each hunk in context. Review the diff; use these files to check how the changed code fits with the code around it.
+
+{{.ChangedFiles}}{{end}}`
+
+// PlannerPromptTemplate is the template for the planner agent prompt, which
+// breaks a plan into ordered tasks before development starts.
+const PlannerPromptTemplate = `# Instructions
+
+You are a senior engineer breaking a plan into tasks for a developer who will complete them one at a time, in order. Each task gets its own review cycle and its own jj change.
+
+Read the plan and explore the codebase as needed, but DO NOT modify any files.
+
+## Guidelines
+- Each task should be a coherent, reviewable unit of work that leaves the codebase working
+- Order tasks so each one builds only on tasks before it
+- Prefer 3-10 tasks; do not split work so finely that tasks cannot be reviewed on their own
+- Put everything the developer needs to know in the description; they will not see the other tasks while working
+
+## Output Format
+
+Output a numbered list under this exact header. The text after each number is the task title; the indented lines below it are the description:
+
+## Tasks
+
+1. [Short task title]
+   [What to build, which files or packages are involved, and how to verify it]
+
+2. [Short task title]
+   [Description]
+
+---
+
+# Plan
+
+{{.PlanContent}}`
+
+// ConflictResolverPromptTemplate is the template for the conflict resolver
+// agent prompt, which resolves jj conflicts in the working copy before
+// development continues.
+const ConflictResolverPromptTemplate = `# Instructions
+
+You are a senior engineer resolving version control conflicts. The working copy of this jj repository has unresolved conflicts, for example after a rebase. Development of the plan below is paused until they are resolved.
+
+## Guidelines
+- Resolve only the conflicts; do not continue the plan's work or make unrelated changes
+- Edit each conflicted file to keep the intent of both sides, and remove every conflict marker
+- Use the plan to decide between sides that cannot both be kept
+- Do not create, abandon, rebase, or squash changes; leave the resolution in the working copy
+- Run ` + "`jj status`" + ` when done and make sure no conflicts are listed
+
+## Conflicted Paths
+{{range .Conflicts}}
+- {{.}}{{end}}
+
+## jj status
+
+` + "```" + `
+{{.Status}}
+` + "```" + `
+
+## Output Format
+
+Finish with a short summary of how each conflict was resolved.
+
+---
+
+# Plan
+
+{{.PlanContent}}`
+
+// RebuttalReviewPromptTemplate is the template for the rebuttal reviewer
+// agent prompt, which re-evaluates review feedback the developer disputed,
+// without new code changes.
+const RebuttalReviewPromptTemplate = `# Instructions
+
+You are the code reviewer who rejected the developer's previous work. The developer disputes a point of your feedback and has written a rebuttal. Re-evaluate the disputed point against the rebuttal and the code as it stands. This is not a new review, and no further code changes will be made before your verdict.
+
+## Guidelines
+- Check each claim in the rebuttal against the code; you MAY use jj commands to inspect it, but DO NOT modify any files
+- Withdraw the point if the rebuttal shows it is wrong, does not apply, or contradicts the plan
+- Uphold it if the problem is real, and explain why the rebuttal does not change that
+- Judge only the disputed point; the rest of your feedback stands either way, and do not raise new issues
+
+## Output Format
+
+## Response
+[Your reasoning, addressed to the developer]
+
+### Verdict
+
+If the rebuttal convinces you to withdraw the disputed point:
+REBUTTAL_ACCEPTED REBUTTAL_ACCEPTED!!!
+
+Otherwise:
+FEEDBACK_UPHELD
+
+---
+
+# Your Feedback
+
+{{.Feedback}}
+
+---
+
+# Developer's Rebuttal
+
+{{.Rebuttal}}
+
+---
+
+# Plan (for context)
+
+{{.PlanContent}}
+
+---
+
+# Diff
+
+{{if .DiffOutput}}` + "```diff" + `
+{{.DiffOutput}}
+` + "```" + `{{else}}No code changes.{{end}}`
+
+// ReformatPromptTemplate is the template for the follow-up asking for an
+// agent's malformed output to be restated in the sections the output
+// parser requires. Nothing is redone; the answer is only reformatted.
+const ReformatPromptTemplate = `# Instructions
+
+The answer below was written for an automated workflow, but it is missing the required section headers, so it can't be processed. Restate it in the required format.
+
+- Reformat only: keep the answer's content and meaning, and do not add new work, findings, or conclusions
+- Do not use any tools or modify any files
+- Output only the reformatted answer
+{{if .Reviewer}}- If the answer does not clearly approve the work, use the REVIEWER_FEEDBACK verdict
+
+## Required Format
+
+## Progress
+[Summary of what was reviewed]
+
+## Learnings
+[Patterns noticed, potential systemic issues]
+
+---
+
+### Critical Issues
+[Each critical issue with file:line reference, or "None"]
+
+### Major Issues
+[Each major issue with file:line reference, or "None"]
+
+### Minor Issues
+[Each minor issue with file:line reference, or "None"]
+
+### Verdict
+REVIEWER_APPROVED REVIEWER_APPROVED!!!
+or
+REVIEWER_FEEDBACK: [Summary of what needs to be fixed]
+{{else}}- Only use the DEV_DONE status if the answer clearly says all work is complete
+
+## Required Format
+
+## Progress
+[What was built or completed, and the current state]
+
+## Learnings
+[Insights about the codebase, approaches that didn't work]
+
+---
+
+## Status
+RUNNING RUNNING RUNNING
+or
+DEV_DONE DEV_DONE DEV_DONE!!!
+{{end}}
+---
+
+# Answer to Reformat
+
+{{.Output}}`
+
+// DeveloperDeltaPromptTemplate is the template for differential developer
+// prompts, sent to a resumed session that already has the full prompt: it
+// holds only what changed since the session last ran.
+const DeveloperDeltaPromptTemplate = `# Continue
+
+You are continuing the plan from your previous session, which reviewed and recorded your work since. The instructions, plan, and output format from the start of this conversation still apply; only what changed is below.
+{{if .PlanUpdate}}
+---
+
+# Plan Updated
+
+The plan file was edited. Check your progress against these changes: pick up anything the edit added and drop work it removed.
+
+` + "```diff" + `
+{{.PlanUpdate}}
+` + "```" + `
+{{end}}{{if .Rejected}}
+---
+
+# Previously Rejected Approaches
+
+These approaches were rejected by the reviewer, or kept failing the checks, earlier in this plan. Do not retry them:
+
+{{.Rejected}}
+{{end}}{{if .ReviewerFeedback}}
+---
+
+# Reviewer Feedback (from last review - MUST ADDRESS)
+
+The reviewer rejected your previous work. You MUST address all the following issues:
+
+{{.ReviewerFeedback}}
+{{if .RebuttalAllowed}}
+If you are confident that a point above is wrong, you may dispute it once in a ## Rebuttal section of your output instead of changing the code for it, as described at the start of this conversation.
+{{end}}{{else}}
+No new reviewer feedback: continue with the plan.
+{{end}}{{if .OpenItems}}
+---
+
+# Open Review Items
+
+These review items are still open until a reviewer verifies them fixed. List the numbers of those you fix in a ## Fixed Items section of your output:
+
+{{.OpenItems}}
+{{end}}{{if .OutOfScope}}
+---
+
+# Out-of-Scope Changes
+
+Your last session changed files the plan does not allow you to touch:
+
+{{.OutOfScope}}
+
+Keep your work within those files.
+{{end}}{{if .FailingChecks}}
+---
+
+# Failing Checks
+
+These checks failed after your last session, with their output trimmed to the failures. Fix these failures before continuing with the plan:
+
+{{.FailingChecks}}
+{{end}}{{if .UserFeedback}}
+---
+
+# User Feedback (MUST ADDRESS)
+
+The user sent this feedback while you were working. It takes precedence over your own plans for this iteration:
+
+{{.UserFeedback}}
+{{end}}{{if .Stuck}}
+---
+
+# You Are Stuck
+
+Your last several iterations produced no new changes and no new progress. You MUST change approach: try a fundamentally different solution, or record what blocks you in Learnings and move on to another part of the plan.
+{{end}}
+---
+
+Finish by reporting {{if .StatusTool}}with the ` + "`ralph_status`" + ` tool{{else}}the Progress, Learnings, and Status sections{{end}} as before. Only signal DEV_DONE from a session in which you changed no files.`
+
+// FailureTriagePromptTemplate is the template for the failure triage agent
+// prompt, which diagnoses why the loop keeps failing the same way.
+const FailureTriagePromptTemplate = `# Instructions
+
+You are a senior engineer triaging an automated development loop. A developer and a reviewer agent take turns working on the plan below, and the same failure has now happened {{.Count}} times in a row. Retrying is not fixing it. Find out why.
+
+## Guidelines
+- Diagnose the systemic cause rather than the latest symptom: a plan that is wrong, ambiguous, or impossible; a missing dependency, tool, or service; an authentication or permission problem; a broken environment or test setup
+- Investigate the repository and environment to confirm your diagnosis; you MAY run read-only commands, but DO NOT modify any files
+- Say plainly whether the loop can recover on its own or needs a person to act
+- Your report is shown to the person running the loop, not to the agents
+
+## Failure
+
+{{.Failure}} ({{.Count}} times in a row)
+
+` + "```" + `
+{{.Detail}}
+` + "```" + `
+{{if .Progress}}
+## Latest Progress
+
+{{.Progress}}
+{{end}}
+## Output Format
+
+Write a short report with these sections:
+
+## Diagnosis
+The most likely cause, and the evidence for it.
+
+## Recommended Action
+What the person running the loop should do, step by step, or why the loop will recover on its own.
+
+---
+
+# Plan
+
+{{.PlanContent}}`
+
+// DiffSummaryPromptTemplate is the template for the prompt summarizing
+// part of a diff too large for the reviewer's prompt.
+const DiffSummaryPromptTemplate = `# Instructions
+
+You are summarizing part of a code change for a reviewer who can't read the whole diff: it is too large for their context. Your summary is all they will see of these files, so keep what matters to a review and drop what doesn't.
+
+## Guidelines
+- Summarize every file in the diff, in the order they appear
+- Name the functions, methods, and types added, changed, and removed, with one line on what changed in each
+- Quote the key hunks verbatim: new logic, changed conditions, error handling, security-sensitive code, and anything that looks wrong
+- Leave out mechanical changes (renames, formatting, moved or generated code), saying that you did
+- Describe the change; do not review it or suggest fixes
+- DO NOT modify any files
+
+## Output Format
+
+For each file:
+
+### path/to/file
+- Added: ` + "`Name`" + ` - what it does
+- Changed: ` + "`Name`" + ` - what changed
+- Removed: ` + "`Name`" + `
+
+` + "```diff" + `
+[Key hunks, verbatim]
+` + "```" + `
+
+Leave out lines with nothing to report.
+
+---
+
+# Diff
+
+` + "```diff" + `
+{{.Diff}}
+` + "```" + `
+`
 
 // developerTemplate is the pre-parsed developer template.
 var developerTemplate = template.Must(template.New("developer-prompt").Parse(DeveloperPromptTemplate))
 
+// developerDeltaTemplate is the pre-parsed differential developer template.
+var developerDeltaTemplate = template.Must(template.New("developer-delta-prompt").Parse(DeveloperDeltaPromptTemplate))
+
 // reviewerTemplate is the pre-parsed reviewer template.
 var reviewerTemplate = template.Must(template.New("reviewer-prompt").Parse(ReviewerPromptTemplate))
 
+// plannerTemplate is the pre-parsed planner template.
+var plannerTemplate = template.Must(template.New("planner-prompt").Parse(PlannerPromptTemplate))
+
+// conflictResolverTemplate is the pre-parsed conflict resolver template.
+var conflictResolverTemplate = template.Must(template.New("conflict-resolver-prompt").Parse(ConflictResolverPromptTemplate))
+
+// rebuttalReviewTemplate is the pre-parsed rebuttal reviewer template.
+var rebuttalReviewTemplate = template.Must(template.New("rebuttal-review-prompt").Parse(RebuttalReviewPromptTemplate))
+
+// reformatTemplate is the pre-parsed reformat template.
+var reformatTemplate = template.Must(template.New("reformat-prompt").Parse(ReformatPromptTemplate))
+
+// failureTriageTemplate is the pre-parsed failure triage template.
+var failureTriageTemplate = template.Must(template.New("failure-triage-prompt").Parse(FailureTriagePromptTemplate))
+
+// diffSummaryTemplate is the pre-parsed diff summary template.
+var diffSummaryTemplate = template.Must(template.New("diff-summary-prompt").Parse(DiffSummaryPromptTemplate))
+
+// localizedTemplates caches the templates rewritten for a locale, keyed by
+// template name and locale code.
+var localizedTemplates sync.Map
+
+// localizedTemplate returns tmpl, parsed from text, as rewritten for the
+// locale with the given code. English returns tmpl itself.
+func localizedTemplate(tmpl *template.Template, text, code string) (*template.Template, error) {
+	l, ok := locale.Get(code)
+	if !ok {
+		return nil, fmt.Errorf("unsupported locale %q", code)
+	}
+	if l.Code == locale.Default {
+		return tmpl, nil
+	}
+
+	key := tmpl.Name() + "/" + l.Code
+	if cached, ok := localizedTemplates.Load(key); ok {
+		return cached.(*template.Template), nil
+	}
+	localized, err := template.New(tmpl.Name()).Parse(l.Localize(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s template for locale %s: %w", tmpl.Name(), l.Code, err)
+	}
+	localizedTemplates.Store(key, localized)
+	return localized, nil
+}
+
+// agentTemplate returns the template for a prompt: custom, the text of a
+// custom prompt file (agents.* in the config), when set, and otherwise tmpl,
+// parsed from text, as localized for the locale with the given code. Custom
+// templates are used as written, in any locale.
+func agentTemplate(tmpl *template.Template, text, custom, code string) (*template.Template, error) {
+	if custom == "" {
+		return localizedTemplate(tmpl, text, code)
+	}
+	t, err := template.New(tmpl.Name()).Parse(custom)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse custom %s template: %w", tmpl.Name(), err)
+	}
+	return t, nil
+}
+
 // BuildDeveloperPrompt constructs the developer agent prompt.
 func BuildDeveloperPrompt(ctx DeveloperContext) (string, error) {
 	if strings.TrimSpace(ctx.PlanContent) == "" {
@@ -399,15 +1187,86 @@ func BuildDeveloperPrompt(ctx DeveloperContext) (string, error) {
 	if strings.TrimSpace(ctx.ReviewerFeedback) == "" {
 		ctx.ReviewerFeedback = ""
 	}
+	if strings.TrimSpace(ctx.OpenItems) == "" {
+		ctx.OpenItems = ""
+	}
+	if strings.TrimSpace(ctx.GlobalLearnings) == "" {
+		ctx.GlobalLearnings = ""
+	}
+	if strings.TrimSpace(ctx.Rejected) == "" {
+		ctx.Rejected = ""
+	}
+	if strings.TrimSpace(ctx.CurrentTask) == "" {
+		ctx.CurrentTask = ""
+	}
+	if strings.TrimSpace(ctx.PlanUpdate) == "" {
+		ctx.PlanUpdate = ""
+	}
+	if strings.TrimSpace(ctx.OutOfScope) == "" {
+		ctx.OutOfScope = ""
+	}
+	if strings.TrimSpace(ctx.FailingChecks) == "" {
+		ctx.FailingChecks = ""
+	}
+	if strings.TrimSpace(ctx.UserFeedback) == "" {
+		ctx.UserFeedback = ""
+	}
+
+	tmpl, err := agentTemplate(developerTemplate, DeveloperPromptTemplate, ctx.Template, ctx.Locale)
+	if err != nil {
+		return "", err
+	}
 
 	var buf bytes.Buffer
-	if err := developerTemplate.Execute(&buf, ctx); err != nil {
+	if err := tmpl.Execute(&buf, ctx); err != nil {
 		return "", fmt.Errorf("failed to execute developer prompt template: %w", err)
 	}
 
 	return buf.String(), nil
 }
 
+// BuildDeveloperDeltaPrompt constructs the differential developer prompt
+// for a resumed session, from the parts of ctx that change between
+// iterations: plan edits, reviewer feedback, open review items, out-of-scope
+// changes, failing checks, user feedback, and the stuck nudge. The plan, progress, learnings,
+// task, and conventions are already in the session.
+func BuildDeveloperDeltaPrompt(ctx DeveloperContext) (string, error) {
+	// Normalize whitespace-only strings to empty to trigger fallbacks
+	if strings.TrimSpace(ctx.ReviewerFeedback) == "" {
+		ctx.ReviewerFeedback = ""
+	}
+	if strings.TrimSpace(ctx.OpenItems) == "" {
+		ctx.OpenItems = ""
+	}
+	if strings.TrimSpace(ctx.PlanUpdate) == "" {
+		ctx.PlanUpdate = ""
+	}
+	if strings.TrimSpace(ctx.Rejected) == "" {
+		ctx.Rejected = ""
+	}
+	if strings.TrimSpace(ctx.OutOfScope) == "" {
+		ctx.OutOfScope = ""
+	}
+	if strings.TrimSpace(ctx.FailingChecks) == "" {
+		ctx.FailingChecks = ""
+	}
+	if strings.TrimSpace(ctx.UserFeedback) == "" {
+		ctx.UserFeedback = ""
+	}
+
+	tmpl, err := localizedTemplate(developerDeltaTemplate, DeveloperDeltaPromptTemplate, ctx.Locale)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, ctx); err != nil {
+		return "", fmt.Errorf("failed to execute developer delta prompt template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
 // BuildReviewerPrompt constructs the reviewer agent prompt.
 func BuildReviewerPrompt(ctx ReviewerContext) (string, error) {
 	if strings.TrimSpace(ctx.PlanContent) == "" {
@@ -427,11 +1286,156 @@ func BuildReviewerPrompt(ctx ReviewerContext) (string, error) {
 	if strings.TrimSpace(ctx.DeveloperSummary) == "" {
 		ctx.DeveloperSummary = ""
 	}
+	if strings.TrimSpace(ctx.CurrentTask) == "" {
+		ctx.CurrentTask = ""
+	}
+	if strings.TrimSpace(ctx.Findings) == "" {
+		ctx.Findings = ""
+	}
+	if strings.TrimSpace(ctx.ChangedFiles) == "" {
+		ctx.ChangedFiles = ""
+	}
+	if strings.TrimSpace(ctx.WithdrawnFeedback) == "" {
+		ctx.WithdrawnFeedback = ""
+	}
+	if strings.TrimSpace(ctx.OpenItems) == "" {
+		ctx.OpenItems = ""
+	}
+
+	tmpl, err := agentTemplate(reviewerTemplate, ReviewerPromptTemplate, ctx.Template, ctx.Locale)
+	if err != nil {
+		return "", err
+	}
 
 	var buf bytes.Buffer
-	if err := reviewerTemplate.Execute(&buf, ctx); err != nil {
+	if err := tmpl.Execute(&buf, ctx); err != nil {
 		return "", fmt.Errorf("failed to execute reviewer prompt template: %w", err)
 	}
 
 	return buf.String(), nil
 }
+
+// BuildPlannerPrompt constructs the planner agent prompt.
+func BuildPlannerPrompt(ctx PlannerContext) (string, error) {
+	if strings.TrimSpace(ctx.PlanContent) == "" {
+		return "", ErrEmptyPlanContent
+	}
+
+	tmpl, err := agentTemplate(plannerTemplate, PlannerPromptTemplate, ctx.Template, ctx.Locale)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, ctx); err != nil {
+		return "", fmt.Errorf("failed to execute planner prompt template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// BuildConflictResolverPrompt constructs the conflict resolver agent prompt.
+func BuildConflictResolverPrompt(ctx ConflictResolverContext) (string, error) {
+	if strings.TrimSpace(ctx.PlanContent) == "" {
+		return "", ErrEmptyPlanContent
+	}
+	ctx.Status = strings.TrimSpace(ctx.Status)
+
+	tmpl, err := localizedTemplate(conflictResolverTemplate, ConflictResolverPromptTemplate, ctx.Locale)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, ctx); err != nil {
+		return "", fmt.Errorf("failed to execute conflict resolver prompt template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// BuildRebuttalReviewPrompt constructs the rebuttal reviewer agent prompt.
+func BuildRebuttalReviewPrompt(ctx RebuttalReviewContext) (string, error) {
+	if strings.TrimSpace(ctx.PlanContent) == "" {
+		return "", ErrEmptyPlanContent
+	}
+	if strings.TrimSpace(ctx.DiffOutput) == "" {
+		ctx.DiffOutput = ""
+	}
+
+	tmpl, err := localizedTemplate(rebuttalReviewTemplate, RebuttalReviewPromptTemplate, ctx.Locale)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, ctx); err != nil {
+		return "", fmt.Errorf("failed to execute rebuttal review prompt template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// BuildReformatPrompt constructs the prompt asking for malformed agent
+// output to be reformatted.
+func BuildReformatPrompt(ctx ReformatContext) (string, error) {
+	if strings.TrimSpace(ctx.Output) == "" {
+		return "", ErrEmptyOutput
+	}
+
+	tmpl, err := localizedTemplate(reformatTemplate, ReformatPromptTemplate, ctx.Locale)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, ctx); err != nil {
+		return "", fmt.Errorf("failed to execute reformat prompt template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// BuildFailureTriagePrompt constructs the failure triage agent prompt.
+func BuildFailureTriagePrompt(ctx FailureTriageContext) (string, error) {
+	if strings.TrimSpace(ctx.PlanContent) == "" {
+		return "", ErrEmptyPlanContent
+	}
+	ctx.Detail = strings.TrimSpace(ctx.Detail)
+	if strings.TrimSpace(ctx.Progress) == "" {
+		ctx.Progress = ""
+	}
+
+	tmpl, err := localizedTemplate(failureTriageTemplate, FailureTriagePromptTemplate, ctx.Locale)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, ctx); err != nil {
+		return "", fmt.Errorf("failed to execute failure triage prompt template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// BuildDiffSummaryPrompt constructs the prompt summarizing part of a diff
+// for the reviewer.
+func BuildDiffSummaryPrompt(ctx DiffSummaryContext) (string, error) {
+	ctx.Diff = strings.Trim(ctx.Diff, "\n")
+	if strings.TrimSpace(ctx.Diff) == "" {
+		return "", ErrEmptyDiff
+	}
+
+	tmpl, err := localizedTemplate(diffSummaryTemplate, DiffSummaryPromptTemplate, ctx.Locale)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, ctx); err != nil {
+		return "", fmt.Errorf("failed to execute diff summary prompt template: %w", err)
+	}
+
+	return buf.String(), nil
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"strings"
 	"testing"
+
+	"github.com/gerunddev/ralph/internal/locale"
 )
 
 func TestBuildPrompt_AllFieldsPopulated(t *testing.T) {
@@ -898,6 +900,117 @@ func TestBuildReviewerPrompt_FinalReviewMode(t *testing.T) {
 	}
 }
 
+func TestBuildReviewerPrompt_SuggestedPatchInBothModes(t *testing.T) {
+	for _, done := range []bool{false, true} {
+		result, err := BuildReviewerPrompt(ReviewerContext{PlanContent: "Build a REST API", DevSignaledDone: done})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(result, "### Suggested Patch") {
+			t.Errorf("DevSignaledDone=%v: missing suggested patch instructions", done)
+		}
+	}
+}
+
+func TestBuildReviewerPrompt_Findings(t *testing.T) {
+	result, err := BuildReviewerPrompt(ReviewerContext{PlanContent: "Build a REST API", DiffOutput: "diff"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result, "# Automated Findings") {
+		t.Error("should omit Automated Findings section without findings")
+	}
+
+	result, err = BuildReviewerPrompt(ReviewerContext{
+		PlanContent: "Build a REST API",
+		DiffOutput:  "diff",
+		Findings:    "## go vet (failed)\n\n```\nmain.go:3: unreachable code\n```",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	findings := strings.Index(result, "# Automated Findings")
+	if findings < 0 || !strings.Contains(result, "main.go:3: unreachable code") {
+		t.Fatal("missing Automated Findings section")
+	}
+	if diff := strings.Index(result, "# Diff to Review"); findings > diff {
+		t.Error("Automated Findings should come before the diff")
+	}
+}
+
+func TestBuildReviewerPrompt_ReviewPanel(t *testing.T) {
+	result, err := BuildReviewerPrompt(ReviewerContext{PlanContent: "Build a REST API", PanelSeat: 1, PanelSize: 1, Quorum: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result, "## Review Panel") {
+		t.Error("should omit Review Panel section for a single reviewer")
+	}
+
+	result, err = BuildReviewerPrompt(ReviewerContext{PlanContent: "Build a REST API", PanelSeat: 2, PanelSize: 3, Quorum: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "You are reviewer 2 of 3") || !strings.Contains(result, "only when 2 of the 3 reviewers approve") {
+		t.Error("missing Review Panel section")
+	}
+}
+
+func TestBuildReviewerPrompt_AuthorTests(t *testing.T) {
+	result, err := BuildReviewerPrompt(ReviewerContext{PlanContent: "Build a REST API", DevSignaledDone: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result, "## Test Authoring") || !strings.Contains(result, "Do not edit files yourself.") {
+		t.Error("should omit Test Authoring section outside test-authoring mode")
+	}
+
+	result, err = BuildReviewerPrompt(ReviewerContext{PlanContent: "Build a REST API", DevSignaledDone: true, AuthorTests: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "## Test Authoring") || !strings.Contains(result, "FAIL without the change and PASS with it") {
+		t.Error("missing Test Authoring section")
+	}
+	if !strings.Contains(result, "Do not edit files yourself, other than the tests described below.") {
+		t.Error("expected the suggested patch instructions to allow test edits")
+	}
+}
+
+func TestBuildPrompts_Conventions(t *testing.T) {
+	const conventions = "## CONTRIBUTING.md\n\n```markdown\nWrap errors with %w.\n```"
+
+	dev, err := BuildDeveloperPrompt(DeveloperContext{PlanContent: "Build a REST API"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rev, err := BuildReviewerPrompt(ReviewerContext{PlanContent: "Build a REST API"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(dev, "# Repository Conventions") || strings.Contains(rev, "# Repository Conventions") {
+		t.Error("should omit Repository Conventions section without convention files")
+	}
+
+	dev, err = BuildDeveloperPrompt(DeveloperContext{PlanContent: "Build a REST API", Conventions: conventions})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rev, err = BuildReviewerPrompt(ReviewerContext{PlanContent: "Build a REST API", Conventions: conventions})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, prompt := range map[string]string{"developer": dev, "reviewer": rev} {
+		section := strings.Index(prompt, "# Repository Conventions")
+		if section < 0 || !strings.Contains(prompt, conventions) {
+			t.Errorf("%s prompt missing Repository Conventions section", name)
+		}
+		if plan := strings.Index(prompt, "Build a REST API"); section > plan {
+			t.Errorf("%s prompt should list conventions before the plan", name)
+		}
+	}
+}
+
 func TestReviewerPromptTemplate_DevSignaledDoneVariable(t *testing.T) {
 	// Verify the template contains the DevSignaledDone conditional
 	if !strings.Contains(ReviewerPromptTemplate, "{{if .DevSignaledDone}}") {
@@ -1024,3 +1137,592 @@ func TestReviewerPromptNoVCSCommands(t *testing.T) {
 		t.Error("prompt should mention jj show for investigation")
 	}
 }
+
+func TestBuildDeveloperPrompt_Stuck(t *testing.T) {
+	ctx := DeveloperContext{PlanContent: "Build a REST API"}
+
+	result, err := BuildDeveloperPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result, "# You Are Stuck") {
+		t.Error("should not show stuck section when Stuck is false")
+	}
+
+	ctx.Stuck = true
+	result, err = BuildDeveloperPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "# You Are Stuck") {
+		t.Error("missing stuck section when Stuck is true")
+	}
+}
+
+func TestBuildDeveloperPrompt_Rejected(t *testing.T) {
+	ctx := DeveloperContext{PlanContent: "Build a REST API", Rejected: "  \n"}
+
+	result, err := BuildDeveloperPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result, "# Previously Rejected Approaches") {
+		t.Error("should not show rejected approaches section when empty")
+	}
+
+	ctx.Rejected = "- (reviewer) Caching in a package-level map is not safe for concurrent use"
+	result, err = BuildDeveloperPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "# Previously Rejected Approaches") || !strings.Contains(result, "package-level map") {
+		t.Error("missing rejected approaches section")
+	}
+}
+
+func TestBuildDeveloperPrompt_GlobalLearnings(t *testing.T) {
+	ctx := DeveloperContext{PlanContent: "Build a REST API", GlobalLearnings: "  \n"}
+
+	result, err := BuildDeveloperPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result, "# Repository Learnings") {
+		t.Error("should not show repository learnings section when empty")
+	}
+
+	ctx.GlobalLearnings = "- Run make generate after editing protos"
+	result, err = BuildDeveloperPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "# Repository Learnings") || !strings.Contains(result, "make generate") {
+		t.Error("missing repository learnings section")
+	}
+	if !strings.Contains(result, "## Global Learnings") {
+		t.Error("developer prompt should explain how to promote global learnings")
+	}
+}
+
+func TestBuildPrompts_CurrentTask(t *testing.T) {
+	task := "Task 2 of 3: Send notifications\n\nPost JSON on loop completion."
+
+	devResult, err := BuildDeveloperPrompt(DeveloperContext{PlanContent: "Build a REST API"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(devResult, "# Current Task") {
+		t.Error("should not show current task section without a task")
+	}
+
+	devResult, err = BuildDeveloperPrompt(DeveloperContext{PlanContent: "Build a REST API", CurrentTask: task})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(devResult, "# Current Task") || !strings.Contains(devResult, task) {
+		t.Error("developer prompt missing current task section")
+	}
+
+	reviewResult, err := BuildReviewerPrompt(ReviewerContext{PlanContent: "Build a REST API", CurrentTask: task})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(reviewResult, "# Current Task") || !strings.Contains(reviewResult, task) {
+		t.Error("reviewer prompt missing current task section")
+	}
+}
+
+func TestBuildPlannerPrompt(t *testing.T) {
+	result, err := BuildPlannerPrompt(PlannerContext{PlanContent: "Build a REST API"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"Build a REST API", "## Tasks", "DO NOT modify any files"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("planner prompt missing %q", want)
+		}
+	}
+
+	if _, err := BuildPlannerPrompt(PlannerContext{PlanContent: "  "}); err != ErrEmptyPlanContent {
+		t.Errorf("expected ErrEmptyPlanContent, got %v", err)
+	}
+}
+
+func TestBuildConflictResolverPrompt(t *testing.T) {
+	result, err := BuildConflictResolverPrompt(ConflictResolverContext{
+		PlanContent: "Build a REST API",
+		Conflicts:   []string{"api/handler.go", "go.mod"},
+		Status:      "There are unresolved conflicts at these paths:\napi/handler.go    2-sided conflict\n",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"Build a REST API", "- api/handler.go\n- go.mod", "api/handler.go    2-sided conflict\n```", "remove every conflict marker"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("conflict resolver prompt missing %q", want)
+		}
+	}
+
+	if _, err := BuildConflictResolverPrompt(ConflictResolverContext{PlanContent: "  "}); err != ErrEmptyPlanContent {
+		t.Errorf("expected ErrEmptyPlanContent, got %v", err)
+	}
+}
+
+func TestBuildFailureTriagePrompt(t *testing.T) {
+	result, err := BuildFailureTriagePrompt(FailureTriageContext{
+		PlanContent: "Build a REST API",
+		Failure:     "Checks failed: tests",
+		Count:       3,
+		Detail:      "\ncannot find package \"github.com/lib/pq\"\n",
+		Progress:    "Added the handlers",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"Build a REST API",
+		"has now happened 3 times in a row",
+		"Checks failed: tests (3 times in a row)",
+		"```\ncannot find package \"github.com/lib/pq\"\n```",
+		"## Latest Progress\n\nAdded the handlers",
+		"DO NOT modify any files",
+		"## Recommended Action",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("failure triage prompt missing %q", want)
+		}
+	}
+
+	result, err = BuildFailureTriagePrompt(FailureTriageContext{PlanContent: "Build a REST API", Progress: "  "})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result, "## Latest Progress") {
+		t.Error("expected no progress section for whitespace-only progress")
+	}
+
+	if _, err := BuildFailureTriagePrompt(FailureTriageContext{PlanContent: "  "}); err != ErrEmptyPlanContent {
+		t.Errorf("expected ErrEmptyPlanContent, got %v", err)
+	}
+}
+
+func TestBuildRebuttalReviewPrompt(t *testing.T) {
+	result, err := BuildRebuttalReviewPrompt(RebuttalReviewContext{
+		PlanContent: "Build a REST API",
+		Feedback:    "Add a nil check in config.go:12",
+		Rebuttal:    "New never returns a nil config",
+		DiffOutput:  "+func New() *Config",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"Build a REST API", "Add a nil check in config.go:12", "New never returns a nil config", "+func New() *Config\n```", "REBUTTAL_ACCEPTED REBUTTAL_ACCEPTED!!!"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("rebuttal review prompt missing %q", want)
+		}
+	}
+
+	if _, err := BuildRebuttalReviewPrompt(RebuttalReviewContext{PlanContent: "  "}); err != ErrEmptyPlanContent {
+		t.Errorf("expected ErrEmptyPlanContent, got %v", err)
+	}
+}
+
+func TestBuildReformatPrompt(t *testing.T) {
+	dev, err := BuildReformatPrompt(ReformatContext{Output: "I fixed the parser."})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"I fixed the parser.", "## Progress", "## Status", "DEV_DONE DEV_DONE DEV_DONE!!!"} {
+		if !strings.Contains(dev, want) {
+			t.Errorf("developer reformat prompt missing %q", want)
+		}
+	}
+	if strings.Contains(dev, "### Verdict") {
+		t.Error("developer reformat prompt should not ask for a verdict")
+	}
+
+	review, err := BuildReformatPrompt(ReformatContext{Reviewer: true, Output: "Looks wrong."})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"Looks wrong.", "### Verdict", "REVIEWER_FEEDBACK:", "REVIEWER_APPROVED REVIEWER_APPROVED!!!"} {
+		if !strings.Contains(review, want) {
+			t.Errorf("reviewer reformat prompt missing %q", want)
+		}
+	}
+
+	if _, err := BuildReformatPrompt(ReformatContext{Output: " \n"}); err != ErrEmptyOutput {
+		t.Errorf("expected ErrEmptyOutput, got %v", err)
+	}
+}
+
+func TestBuildDiffSummaryPrompt(t *testing.T) {
+	diff := "diff --git a/api.go b/api.go\n+func Serve() {}\n"
+	result, err := BuildDiffSummaryPrompt(DiffSummaryContext{Diff: diff})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "```diff\ndiff --git a/api.go b/api.go\n+func Serve() {}\n```") {
+		t.Errorf("expected the diff in a fenced block:\n%s", result)
+	}
+	if !strings.Contains(result, "- Added: `Name`") {
+		t.Error("expected the output format in the diff summary prompt")
+	}
+
+	if _, err := BuildDiffSummaryPrompt(DiffSummaryContext{Diff: "\n \n"}); err != ErrEmptyDiff {
+		t.Errorf("expected ErrEmptyDiff, got %v", err)
+	}
+}
+
+func TestBuildDeveloperPrompt_RebuttalAllowed(t *testing.T) {
+	ctx := DeveloperContext{PlanContent: "Build a REST API", ReviewerFeedback: "Add a nil check"}
+
+	result, err := BuildDeveloperPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result, "## Rebuttal") {
+		t.Error("expected no rebuttal instructions when a rebuttal is not allowed")
+	}
+
+	ctx.RebuttalAllowed = true
+	result, err = BuildDeveloperPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "## Rebuttal") || !strings.Contains(result, "there is no second rebuttal") {
+		t.Error("expected rebuttal instructions with the reviewer feedback")
+	}
+}
+
+func TestBuildReviewerPrompt_WithdrawnFeedback(t *testing.T) {
+	result, err := BuildReviewerPrompt(ReviewerContext{PlanContent: "Build a REST API", WithdrawnFeedback: "Add a nil check"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "## Withdrawn Feedback") || !strings.Contains(result, "Add a nil check") {
+		t.Error("expected the withdrawn feedback in the reviewer prompt")
+	}
+}
+
+func TestBuildPrompts_OpenItems(t *testing.T) {
+	const items = "- #2 [Major] (claimed fixed) Add a nil check"
+
+	dev, err := BuildDeveloperPrompt(DeveloperContext{PlanContent: "Build a REST API", OpenItems: items})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(dev, "# Open Review Items") || !strings.Contains(dev, items) || !strings.Contains(dev, "## Fixed Items") {
+		t.Error("expected the open items and how to claim fixes in the developer prompt")
+	}
+	delta, err := BuildDeveloperDeltaPrompt(DeveloperContext{OpenItems: items})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(delta, items) {
+		t.Error("expected the open items in the developer delta prompt")
+	}
+
+	for _, done := range []bool{false, true} {
+		review, err := BuildReviewerPrompt(ReviewerContext{PlanContent: "Build a REST API", OpenItems: items, DevSignaledDone: done})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(review, "### Verified Items") || !strings.Contains(review, items) {
+			t.Errorf("expected the open items to verify in the reviewer prompt (done %v)", done)
+		}
+		if got := strings.Contains(review, "closes every open item"); got != done {
+			t.Errorf("reviewer prompt mentions closing every item = %v, want %v", got, done)
+		}
+	}
+
+	plain, err := BuildReviewerPrompt(ReviewerContext{PlanContent: "Build a REST API", OpenItems: "  "})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(plain, "Open Review Items") {
+		t.Error("should not show open review items when there are none")
+	}
+}
+
+func TestBuildDeveloperPrompt_SubPlans(t *testing.T) {
+	plain, err := BuildDeveloperPrompt(DeveloperContext{PlanContent: "Build a REST API"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(plain, "## Sub-Plan") {
+		t.Error("should not offer sub-plans unless enabled")
+	}
+
+	for _, run := range []bool{false, true} {
+		prompt, err := BuildDeveloperPrompt(DeveloperContext{PlanContent: "Build a REST API", SubPlans: true, RunSubPlans: run, StatusTool: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(prompt, "# Sub-Plans") || !strings.Contains(prompt, "## Sub-Plan\n```markdown") || !strings.Contains(prompt, "in sub_plan") {
+			t.Errorf("expected how to propose a sub-plan in the developer prompt (run %v)", run)
+		}
+		if got := strings.Contains(prompt, "before this plan resumes"); got != run {
+			t.Errorf("prompt says the sub-plan runs first = %v, want %v", got, run)
+		}
+	}
+}
+
+func TestBuildDeveloperPrompt_UserFeedback(t *testing.T) {
+	ctx := DeveloperContext{PlanContent: "Build a REST API"}
+
+	result, err := BuildDeveloperPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result, "# User Feedback") {
+		t.Error("should not show user feedback section when there is none")
+	}
+
+	ctx.UserFeedback = "Use cursor-based pagination"
+	result, err = BuildDeveloperPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "# User Feedback (MUST ADDRESS)") || !strings.Contains(result, "Use cursor-based pagination") {
+		t.Errorf("missing user feedback section:\n%s", result)
+	}
+}
+
+func TestBuildDeveloperPrompt_PlanUpdate(t *testing.T) {
+	ctx := DeveloperContext{PlanContent: "Build a REST API\nAdd pagination"}
+
+	result, err := BuildDeveloperPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result, "# Plan Updated") {
+		t.Error("should not show plan update section when there is no update")
+	}
+
+	ctx.PlanUpdate = "+Add pagination"
+	result, err = BuildDeveloperPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "# Plan Updated") || !strings.Contains(result, "```diff\n+Add pagination\n```") {
+		t.Errorf("missing plan update section with the diff:\n%s", result)
+	}
+}
+
+func TestBuildDeveloperPrompt_OutOfScope(t *testing.T) {
+	ctx := DeveloperContext{PlanContent: "Build a REST API"}
+
+	result, err := BuildDeveloperPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result, "# Out-of-Scope Changes") {
+		t.Error("should not show out-of-scope section when there are none")
+	}
+
+	ctx.OutOfScope = "- go.sum\n\nThese changes were reverted"
+	result, err = BuildDeveloperPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "# Out-of-Scope Changes") || !strings.Contains(result, "- go.sum") {
+		t.Errorf("missing out-of-scope section:\n%s", result)
+	}
+}
+
+func TestBuildDeveloperPrompt_FailingChecks(t *testing.T) {
+	ctx := DeveloperContext{PlanContent: "Build a REST API", FailingChecks: "  \n"}
+
+	result, err := BuildDeveloperPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result, "# Failing Checks") {
+		t.Error("should not show failing checks section when there are none")
+	}
+
+	ctx.FailingChecks = "## tests\n\n```\n--- FAIL: TestHandler (0.00s)\n```"
+	result, err = BuildDeveloperPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "# Failing Checks") || !strings.Contains(result, "--- FAIL: TestHandler") {
+		t.Errorf("missing failing checks section:\n%s", result)
+	}
+}
+
+func TestBuildPrompts_StatusTool(t *testing.T) {
+	dev, err := BuildDeveloperPrompt(DeveloperContext{PlanContent: "Build a REST API"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(dev, "ralph_status") {
+		t.Error("should not mention the status tool unless it is enabled")
+	}
+
+	dev, err = BuildDeveloperPrompt(DeveloperContext{PlanContent: "Build a REST API", StatusTool: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	review, err := BuildReviewerPrompt(ReviewerContext{PlanContent: "Build a REST API", StatusTool: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, prompt := range map[string]string{"developer": dev, "reviewer": review} {
+		if !strings.Contains(prompt, "## Reporting Status") || !strings.Contains(prompt, "`ralph_status`") {
+			t.Errorf("expected the %s prompt to ask for a ralph_status call:\n%s", name, prompt)
+		}
+		// The markdown format stays as the fallback
+		if !strings.Contains(prompt, "## Progress") {
+			t.Errorf("expected the %s prompt to keep the markdown sections", name)
+		}
+	}
+}
+
+func TestBuildPrompts_StateTools(t *testing.T) {
+	dev, err := BuildDeveloperPrompt(DeveloperContext{PlanContent: "Build a REST API", StateTools: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	review, err := BuildReviewerPrompt(ReviewerContext{PlanContent: "Build a REST API", StateTools: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, prompt := range map[string]string{"developer": dev, "reviewer": review} {
+		if !strings.Contains(prompt, "## Plan State") || !strings.Contains(prompt, "`get_progress_history`") {
+			t.Errorf("expected the %s prompt to list the state tools:\n%s", name, prompt)
+		}
+	}
+	if !strings.Contains(dev, "`record_progress`") || strings.Contains(review, "record_progress") {
+		t.Error("expected only the developer to be asked to checkpoint progress")
+	}
+
+	dev, err = BuildDeveloperPrompt(DeveloperContext{PlanContent: "Build a REST API"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(dev, "## Plan State") {
+		t.Error("should not list the state tools unless they are enabled")
+	}
+}
+
+func TestBuildDeveloperDeltaPrompt(t *testing.T) {
+	ctx := DeveloperContext{
+		PlanContent:   "Build a REST API",
+		Progress:      "Wrote the handler",
+		Conventions:   "Use tabs",
+		FailingChecks: "## tests\n\n```\n--- FAIL: TestHandler (0.00s)\n```",
+		UserFeedback:  "  ",
+		Rejected:      " \n",
+	}
+	result, err := BuildDeveloperDeltaPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"# Continue", "# Failing Checks", "--- FAIL: TestHandler", "No new reviewer feedback", "Progress, Learnings, and Status sections"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in the delta prompt:\n%s", want, result)
+		}
+	}
+	// The session already has everything that doesn't change
+	for _, unwanted := range []string{"Build a REST API", "Wrote the handler", "Use tabs", "# User Feedback", "# Previously Rejected Approaches"} {
+		if strings.Contains(result, unwanted) {
+			t.Errorf("unexpected %q in the delta prompt", unwanted)
+		}
+	}
+
+	ctx.ReviewerFeedback = "Handle the nil body"
+	ctx.RebuttalAllowed = true
+	ctx.StatusTool = true
+	ctx.Rejected = "- (reviewer) Caching in a package-level map"
+	result, err = BuildDeveloperDeltaPrompt(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"# Reviewer Feedback", "Handle the nil body", "## Rebuttal", "`ralph_status`", "# Previously Rejected Approaches", "package-level map"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in the delta prompt:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "No new reviewer feedback") {
+		t.Error("should not say there is no feedback")
+	}
+}
+
+func TestBuildPrompts_Locale(t *testing.T) {
+	dev, err := BuildDeveloperPrompt(DeveloperContext{PlanContent: "## Progress of the API\nBuild it", Locale: "ja"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"# 言語", "## 進捗", "## 学び", "## ステータス", "DEV_DONE DEV_DONE DEV_DONE!!!", "## Progress of the API"} {
+		if !strings.Contains(dev, want) {
+			t.Errorf("expected %q in the developer prompt", want)
+		}
+	}
+
+	rev, err := BuildReviewerPrompt(ReviewerContext{PlanContent: "Build a REST API", DevSignaledDone: true, Locale: "ja"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"### 重大な問題", "### 判定", "REVIEWER_APPROVED REVIEWER_APPROVED!!!", "REVIEWER_FEEDBACK:"} {
+		if !strings.Contains(rev, want) {
+			t.Errorf("expected %q in the reviewer prompt", want)
+		}
+	}
+
+	en, err := BuildDeveloperPrompt(DeveloperContext{PlanContent: "Build a REST API", Locale: "en"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(en, "# 言語") || !strings.Contains(en, "## Progress") {
+		t.Error("expected the English prompt to be unchanged")
+	}
+
+	if _, err := BuildDeveloperPrompt(DeveloperContext{PlanContent: "Build a REST API", Locale: "xx"}); err == nil {
+		t.Error("expected an error for an unsupported locale")
+	}
+}
+
+func TestLocalePhrasesMatchTemplates(t *testing.T) {
+	templates := strings.Join([]string{
+		DeveloperPromptTemplate, ReviewerPromptTemplate, PlannerPromptTemplate,
+		ConflictResolverPromptTemplate, RebuttalReviewPromptTemplate, ReformatPromptTemplate,
+	}, "\n")
+	for _, code := range locale.Codes() {
+		l, _ := locale.Get(code)
+		for _, phrase := range l.Phrases {
+			if !strings.Contains(templates, phrase[0]) {
+				t.Errorf("%s phrase %q no longer appears in any template", code, phrase[0])
+			}
+		}
+	}
+}
+
+func TestBuildPrompts_CustomTemplate(t *testing.T) {
+	result, err := BuildDeveloperPrompt(DeveloperContext{
+		PlanContent: "Build a REST API",
+		Locale:      "es",
+		Template:    "{{/* ignored */}}Plan: {{.PlanContent}}",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "Plan: Build a REST API" {
+		t.Errorf("developer prompt = %q, want the custom template", result)
+	}
+
+	result, err = BuildReviewerPrompt(ReviewerContext{PlanContent: "Build a REST API", DiffOutput: "diff", Template: "Review {{.DiffOutput}}"})
+	if err != nil || result != "Review diff" {
+		t.Errorf("reviewer prompt = %q, %v; want the custom template", result, err)
+	}
+	result, err = BuildPlannerPrompt(PlannerContext{PlanContent: "Build a REST API", Template: "Split {{.PlanContent}}"})
+	if err != nil || result != "Split Build a REST API" {
+		t.Errorf("planner prompt = %q, %v; want the custom template", result, err)
+	}
+
+	if _, err := BuildDeveloperPrompt(DeveloperContext{PlanContent: "x", Template: "{{.Missing"}); err == nil ||
+		!strings.Contains(err.Error(), "custom") {
+		t.Errorf("expected a custom template parse error, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// EstimateTokens estimates how many tokens Claude's tokenizer splits text
+// into: one for every four characters of a word, and one for each
+// punctuation or symbol character. Whitespace is free.
+func EstimateTokens(text string) int {
+	tokens, word := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word++
+			continue
+		case unicode.IsSpace(r):
+		default:
+			tokens++
+		}
+		tokens += (word + 3) / 4
+		word = 0
+	}
+	return tokens + (word+3)/4
+}
+
+// PrunableSections are the parts of a prompt's context that FitPrompt may
+// cut, in the order it cuts them. A nil section is left alone.
+type PrunableSections struct {
+	Files     *string // Cut from the tail
+	Learnings *string // Cut from the oldest (first) lines
+	Progress  *string // Cut from the oldest (first) lines
+	Diff      *string // Cut from the tail
+}
+
+// Omission is content FitPrompt cut from a section of a prompt.
+type Omission struct {
+	Section string // "files", "learnings", "progress", or "diff"
+	Lines   int    // Lines cut
+	Tokens  int    // Estimated tokens cut
+}
+
+// FitPrompt builds a prompt and, while it is estimated at more than budget
+// tokens, cuts the lowest-priority content and builds it again: the tail of
+// the changed files first, then the oldest learnings, then the oldest
+// progress, then the tail of the diff. A cut section says in its place what
+// was omitted. The prompt may still be over budget once nothing is left to
+// cut.
+func FitPrompt(budget int, sections PrunableSections, build func() (string, error)) (string, []Omission, error) {
+	prompt, err := build()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var omitted []Omission
+	for _, p := range []*prunable{
+		newPrunable("files", sections.Files, true),
+		newPrunable("learnings", sections.Learnings, false),
+		newPrunable("progress", sections.Progress, false),
+		newPrunable("diff", sections.Diff, true),
+	} {
+		if p == nil {
+			continue
+		}
+		for {
+			excess := EstimateTokens(prompt) - budget
+			if excess <= 0 || !p.cut(excess) {
+				break
+			}
+			if prompt, err = build(); err != nil {
+				return "", nil, err
+			}
+		}
+		if p.dropped > 0 {
+			omitted = append(omitted, Omission{Section: p.name, Lines: p.dropped, Tokens: p.droppedTokens})
+		}
+	}
+	return prompt, omitted, nil
+}
+
+// prunable is a section of a prompt being cut line by line.
+type prunable struct {
+	name    string
+	text    *string
+	lines   []string
+	fromEnd bool // Cut from the end rather than the start
+
+	dropped       int // Lines cut so far
+	droppedTokens int
+}
+
+// newPrunable returns the section to cut, or nil if there is none.
+func newPrunable(name string, text *string, fromEnd bool) *prunable {
+	if text == nil || strings.TrimSpace(*text) == "" {
+		return nil
+	}
+	return &prunable{name: name, text: text, lines: strings.Split(*text, "\n"), fromEnd: fromEnd}
+}
+
+// cut drops at least one more line, and enough to make up for excess
+// tokens and the note saying what was omitted, then rewrites the section.
+// It returns false when there was nothing left to drop.
+func (p *prunable) cut(excess int) bool {
+	if p.dropped == len(p.lines) {
+		return false
+	}
+	target := excess + EstimateTokens(p.note())
+	for removed := 0; p.dropped < len(p.lines) && removed < target; p.dropped++ {
+		i := p.dropped
+		if p.fromEnd {
+			i = len(p.lines) - 1 - p.dropped
+		}
+		tokens := EstimateTokens(p.lines[i]) + 1
+		removed += tokens
+		p.droppedTokens += tokens
+	}
+
+	if p.fromEnd {
+		*p.text = strings.Join(p.lines[:len(p.lines)-p.dropped], "\n") + "\n\n" + p.note()
+	} else {
+		*p.text = p.note() + "\n" + strings.Join(p.lines[p.dropped:], "\n")
+	}
+	*p.text = strings.TrimSpace(*p.text)
+	return true
+}
+
+// note says what was cut from the section.
+func (p *prunable) note() string {
+	if p.fromEnd {
+		return fmt.Sprintf("... [%s TRUNCATED - %d lines omitted to fit the context window. Review may be incomplete.]",
+			strings.ToUpper(p.name), p.dropped)
+	}
+	return fmt.Sprintf("[%d older %s lines omitted to fit the context window]", p.dropped, p.name)
+}
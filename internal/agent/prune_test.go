@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"   \n\t", 0},
+		{"go", 1},
+		{"hello world", 4},
+		{"x := fmt.Sprintf()", 9},
+		{"internationalization", 5},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFitPrompt(t *testing.T) {
+	lines := func(prefix string, n int) string {
+		var b strings.Builder
+		for i := 1; i <= n; i++ {
+			fmt.Fprintf(&b, "%s %d\n", prefix, i)
+		}
+		return strings.TrimSpace(b.String())
+	}
+
+	t.Run("under budget", func(t *testing.T) {
+		learnings := lines("learning", 10)
+		prompt, omitted, err := FitPrompt(1000, PrunableSections{Learnings: &learnings}, func() (string, error) {
+			return "Plan\n" + learnings, nil
+		})
+		if err != nil || len(omitted) != 0 || prompt != "Plan\n"+lines("learning", 10) {
+			t.Errorf("FitPrompt() = %q, %+v, %v; want the prompt untouched", prompt, omitted, err)
+		}
+	})
+
+	t.Run("cuts in priority order", func(t *testing.T) {
+		learnings, progress, diff := lines("learning", 50), lines("progress", 50), lines("+diff", 50)
+		build := func() (string, error) {
+			return "Plan\n" + learnings + "\n" + progress + "\n" + diff, nil
+		}
+		full, _ := build()
+		// Dropping every learning and some progress fits the budget
+		budget := EstimateTokens(full) - EstimateTokens(lines("learning", 50)) - 100
+		prompt, omitted, err := FitPrompt(budget, PrunableSections{Learnings: &learnings, Progress: &progress, Diff: &diff}, build)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if EstimateTokens(prompt) > budget {
+			t.Errorf("prompt is %d tokens, want at most %d", EstimateTokens(prompt), budget)
+		}
+		if len(omitted) != 2 || omitted[0].Section != "learnings" || omitted[0].Lines != 50 || omitted[1].Section != "progress" {
+			t.Fatalf("omitted = %+v, want all learnings then some progress", omitted)
+		}
+		if learnings != "[50 older learnings lines omitted to fit the context window]" {
+			t.Errorf("learnings = %q, want only the note", learnings)
+		}
+		want := fmt.Sprintf("[%d older progress lines omitted to fit the context window]\nprogress %d", omitted[1].Lines, omitted[1].Lines+1)
+		if !strings.HasPrefix(progress, want) || !strings.HasSuffix(progress, "progress 50") {
+			t.Errorf("progress = %q, want the oldest lines cut", progress)
+		}
+		if diff != lines("+diff", 50) {
+			t.Error("diff was cut before progress ran out")
+		}
+	})
+
+	t.Run("cuts the diff tail", func(t *testing.T) {
+		diff := lines("+diff", 100)
+		build := func() (string, error) { return "Review\n" + diff, nil }
+		prompt, omitted, err := FitPrompt(200, PrunableSections{Diff: &diff}, build)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if EstimateTokens(prompt) > 200 || len(omitted) != 1 || omitted[0].Section != "diff" {
+			t.Fatalf("FitPrompt() = %d tokens, %+v; want the diff cut to fit", EstimateTokens(prompt), omitted)
+		}
+		if !strings.HasPrefix(diff, "+diff 1\n") || !strings.HasSuffix(diff, "... [DIFF TRUNCATED - "+fmt.Sprint(omitted[0].Lines)+" lines omitted to fit the context window. Review may be incomplete.]") {
+			t.Errorf("diff = %q, want the tail cut and noted", diff)
+		}
+	})
+
+	t.Run("cuts changed files first", func(t *testing.T) {
+		learnings, diff, files := lines("learning", 20), lines("+diff", 20), lines("file", 100)
+		build := func() (string, error) { return "Review\n" + learnings + "\n" + diff + "\n" + files, nil }
+		full, _ := build()
+		budget := EstimateTokens(full) - 100
+		prompt, omitted, err := FitPrompt(budget, PrunableSections{Files: &files, Learnings: &learnings, Diff: &diff}, build)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if EstimateTokens(prompt) > budget || len(omitted) != 1 || omitted[0].Section != "files" {
+			t.Fatalf("FitPrompt() = %d tokens, %+v; want only the changed files cut", EstimateTokens(prompt), omitted)
+		}
+		if learnings != lines("learning", 20) || diff != lines("+diff", 20) || !strings.HasPrefix(files, "file 1\n") {
+			t.Error("want only the tail of the changed files cut")
+		}
+	})
+
+	t.Run("nothing left to cut", func(t *testing.T) {
+		learnings := "one"
+		prompt, omitted, err := FitPrompt(1, PrunableSections{Learnings: &learnings}, func() (string, error) {
+			return "A plan far longer than the budget\n" + learnings, nil
+		})
+		if err != nil || len(omitted) != 1 || !strings.Contains(prompt, "[1 older learnings lines omitted") {
+			t.Errorf("FitPrompt() = %q, %+v, %v; want the learnings cut and the prompt left over budget", prompt, omitted, err)
+		}
+	})
+}
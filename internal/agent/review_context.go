@@ -0,0 +1,30 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/gerunddev/ralph/internal/frontmatter"
+)
+
+// What the reviewer is given of the changes under review, besides the
+// plan's own instructions.
+const (
+	ReviewContextDiff  = "diff"  // The cumulative diff alone
+	ReviewContextFiles = "files" // The diff and the current content of the changed files
+)
+
+// ReviewContext returns the "review_context:" value of a plan's front
+// matter, which chooses what the plan's reviewer is given: "diff" or
+// "files" (see ReviewContextDiff and ReviewContextFiles). It returns ""
+// when the plan doesn't say, leaving the choice to the config.
+func ReviewContext(plan string) (string, error) {
+	value, err := frontmatter.Value(plan, "review_context")
+	if err != nil {
+		return "", err
+	}
+	switch value {
+	case "", ReviewContextDiff, ReviewContextFiles:
+		return value, nil
+	}
+	return "", fmt.Errorf("review_context must be %q or %q, got %q", ReviewContextDiff, ReviewContextFiles, value)
+}
@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReviewContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    string
+		want    string
+		wantErr string
+	}{
+		{"no front matter", "# Plan\nreview_context: files\n", "", ""},
+		{"not set", "---\nfiles:\n  - \"*.go\"\n---\n# Plan\n", "", ""},
+		{"files", "---\nfiles:\n  - \"*.go\"\nreview_context: files\n---\n# Plan\n", ReviewContextFiles, ""},
+		{"quoted diff", "---\nreview_context: \"diff\"\n---\n", ReviewContextDiff, ""},
+		{"unknown", "---\nreview_context: everything\n---\n", "", `review_context must be "diff" or "files"`},
+		{"empty", "---\nreview_context:\n---\n", "", "review_context must be a value"},
+		{"unclosed", "---\nreview_context: files\n", "", "missing its closing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReviewContext(tt.plan)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ReviewContext() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReviewContext() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ReviewContext() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildReviewerPrompt_ChangedFiles(t *testing.T) {
+	ctx := ReviewerContext{PlanContent: "Plan", DiffOutput: "+func login() {}"}
+	prompt, err := BuildReviewerPrompt(ctx)
+	if err != nil {
+		t.Fatalf("BuildReviewerPrompt() error: %v", err)
+	}
+	if strings.Contains(prompt, "# Changed Files") {
+		t.Error("expected no changed files section without files")
+	}
+
+	ctx.ChangedFiles = "### web/login.go\n\n```\npackage web\n```"
+	prompt, err = BuildReviewerPrompt(ctx)
+	if err != nil {
+		t.Fatalf("BuildReviewerPrompt() error: %v", err)
+	}
+	if !strings.Contains(prompt, "# Changed Files") || !strings.Contains(prompt, "### web/login.go") {
+		t.Errorf("expected the changed files section:\n%s", prompt)
+	}
+}
@@ -0,0 +1,221 @@
+// Package analyze runs static analyzers over the files a plan changed, so
+// their findings can be handed to the reviewer.
+package analyze
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Placeholders expanded in analyzer commands.
+const (
+	// FilesPlaceholder expands to the changed files the analyzer applies to.
+	FilesPlaceholder = "{files}"
+	// DirsPlaceholder expands to the distinct directories of those files,
+	// as "./dir" package paths.
+	DirsPlaceholder = "{dirs}"
+)
+
+// DefaultTimeout bounds an analyzer run when none is configured.
+const DefaultTimeout = 2 * time.Minute
+
+// maxOutputBytes caps the output kept from a single analyzer.
+const maxOutputBytes = 32 * 1024
+
+// CommandRunner is the function type used to execute commands.
+// It can be replaced in tests to mock command execution.
+type CommandRunner func(ctx context.Context, dir string, name string, args ...string) (string, string, error)
+
+// defaultCommandRunner executes a command using exec.CommandContext.
+func defaultCommandRunner(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	return stdout.String(), stderr.String(), err
+}
+
+// Analyzer is a static analysis command.
+type Analyzer struct {
+	Name string // Label for its findings, e.g. "go vet"
+
+	// Command is the program and its arguments. FilesPlaceholder and
+	// DirsPlaceholder arguments are replaced by the changed files; without
+	// them the command runs as-is whenever a matching file changed.
+	Command []string
+
+	// Extensions restricts the analyzer to files with these extensions,
+	// e.g. ".go" (empty = every changed file).
+	Extensions []string
+
+	Timeout time.Duration // 0 = DefaultTimeout
+}
+
+// Finding is the output of one analyzer run.
+type Finding struct {
+	Analyzer string
+	Output   string // Combined stdout and stderr
+	Failed   bool   // The analyzer exited non-zero or could not be run
+}
+
+// Runner runs a set of analyzers in a repository.
+type Runner struct {
+	workDir       string
+	analyzers     []Analyzer
+	commandRunner CommandRunner
+}
+
+// NewRunner creates a runner for the analyzers, executed in workDir.
+func NewRunner(workDir string, analyzers []Analyzer) *Runner {
+	return &Runner{
+		workDir:       workDir,
+		analyzers:     analyzers,
+		commandRunner: defaultCommandRunner,
+	}
+}
+
+// SetCommandRunner allows setting a custom command runner (for testing).
+func (r *Runner) SetCommandRunner(runner CommandRunner) {
+	r.commandRunner = runner
+}
+
+// Run runs each analyzer that applies to the changed files, in order, and
+// returns the findings of those that reported something. Analyzers that
+// cannot be run are reported as failed findings rather than errors, so one
+// missing tool doesn't hold up the review.
+func (r *Runner) Run(ctx context.Context, files []string) []Finding {
+	files = r.existing(files)
+
+	var findings []Finding
+	for _, a := range r.analyzers {
+		matched := a.matchingFiles(files)
+		if len(matched) == 0 || len(a.Command) == 0 {
+			continue
+		}
+		if finding, ok := r.run(ctx, a, matched); ok {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// run runs one analyzer, reporting false when it passed without output.
+func (r *Runner) run(ctx context.Context, a Analyzer, files []string) (Finding, bool) {
+	timeout := a.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	args := expandArgs(a.Command[1:], files)
+	stdout, stderr, err := r.commandRunner(ctx, r.workDir, a.Command[0], args...)
+	output := strings.TrimSpace(strings.TrimSpace(stdout) + "\n" + strings.TrimSpace(stderr))
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil && output == "":
+		return Finding{}, false
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		output = strings.TrimSpace(fmt.Sprintf("%s\n[timed out after %s]", output, timeout))
+	case err != nil && !errors.As(err, &exitErr):
+		output = fmt.Sprintf("failed to run %s: %v", a.Command[0], err)
+	}
+
+	if len(output) > maxOutputBytes {
+		cut := maxOutputBytes
+		for cut > 0 && !utf8.RuneStart(output[cut]) {
+			cut--
+		}
+		output = output[:cut] + "\n[output truncated]"
+	}
+	return Finding{Analyzer: a.Name, Output: output, Failed: err != nil}, true
+}
+
+// existing drops the files that no longer exist, i.e. were deleted.
+func (r *Runner) existing(files []string) []string {
+	var result []string
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(r.workDir, f)); err == nil {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// matchingFiles returns the files the analyzer applies to.
+func (a Analyzer) matchingFiles(files []string) []string {
+	if len(a.Extensions) == 0 {
+		return files
+	}
+	var matched []string
+	for _, f := range files {
+		if slices.Contains(a.Extensions, path.Ext(f)) {
+			matched = append(matched, f)
+		}
+	}
+	return matched
+}
+
+// expandArgs replaces placeholder arguments with the files or directories.
+func expandArgs(args, files []string) []string {
+	var expanded []string
+	for _, arg := range args {
+		switch arg {
+		case FilesPlaceholder:
+			expanded = append(expanded, files...)
+		case DirsPlaceholder:
+			expanded = append(expanded, dirs(files)...)
+		default:
+			expanded = append(expanded, arg)
+		}
+	}
+	return expanded
+}
+
+// dirs returns the distinct directories of the files as "./dir" paths, in
+// order of first appearance.
+func dirs(files []string) []string {
+	var result []string
+	for _, f := range files {
+		dir := "./" + path.Dir(f)
+		if dir == "./." {
+			dir = "."
+		}
+		if !slices.Contains(result, dir) {
+			result = append(result, dir)
+		}
+	}
+	return result
+}
+
+// Format renders findings as markdown for a prompt, one subsection per
+// analyzer. It returns "" when there are no findings.
+func Format(findings []Finding) string {
+	var b strings.Builder
+	for i, f := range findings {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		status := "reported"
+		if f.Failed {
+			status = "failed"
+		}
+		fmt.Fprintf(&b, "## %s (%s)\n\n```\n%s\n```", f.Analyzer, status, f.Output)
+	}
+	return b.String()
+}
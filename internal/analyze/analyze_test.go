@@ -0,0 +1,118 @@
+package analyze
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// newTestRepo creates a directory holding the given files.
+func newTestRepo(t *testing.T, files ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, f := range files {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRunner_Run(t *testing.T) {
+	dir := newTestRepo(t, "cmd/main.go", "internal/db/db.go", "internal/db/models.go", "web/app.ts")
+
+	var calls [][]string
+	r := NewRunner(dir, []Analyzer{
+		{Name: "go vet", Command: []string{"go", "vet", DirsPlaceholder}, Extensions: []string{".go"}},
+		{Name: "eslint", Command: []string{"eslint", FilesPlaceholder}, Extensions: []string{".ts", ".tsx"}},
+		{Name: "ruff", Command: []string{"ruff", "check", FilesPlaceholder}, Extensions: []string{".py"}},
+	})
+	r.SetCommandRunner(func(ctx context.Context, d string, name string, args ...string) (string, string, error) {
+		calls = append(calls, append([]string{name}, args...))
+		if name == "go" {
+			return "", "", nil // Clean
+		}
+		return "web/app.ts: 1 problem\n", "", &exec.ExitError{}
+	})
+
+	// removed.go was deleted, so it is not analyzed
+	files := []string{"cmd/main.go", "internal/db/db.go", "internal/db/models.go", "web/app.ts", "removed.go"}
+	findings := r.Run(context.Background(), files)
+
+	wantCalls := [][]string{
+		{"go", "vet", "./cmd", "./internal/db"},
+		{"eslint", "web/app.ts"},
+	}
+	if !slices.EqualFunc(calls, wantCalls, slices.Equal[[]string]) {
+		t.Errorf("calls = %v, want %v", calls, wantCalls)
+	}
+	want := []Finding{{Analyzer: "eslint", Output: "web/app.ts: 1 problem", Failed: true}}
+	if !slices.Equal(findings, want) {
+		t.Errorf("findings = %+v, want %+v", findings, want)
+	}
+}
+
+func TestRunner_Run_CommandNotFound(t *testing.T) {
+	dir := newTestRepo(t, "main.go")
+	r := NewRunner(dir, []Analyzer{{Name: "staticcheck", Command: []string{"staticcheck", "./..."}}})
+	r.SetCommandRunner(func(ctx context.Context, d string, name string, args ...string) (string, string, error) {
+		return "", "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+	})
+
+	findings := r.Run(context.Background(), []string{"main.go"})
+	if len(findings) != 1 || !findings[0].Failed || !strings.Contains(findings[0].Output, "failed to run staticcheck") {
+		t.Errorf("findings = %+v, want a failed run", findings)
+	}
+}
+
+func TestRunner_Run_Timeout(t *testing.T) {
+	dir := newTestRepo(t, "main.go")
+	r := NewRunner(dir, []Analyzer{{Name: "slow", Command: []string{"slow"}, Timeout: 1}})
+	r.SetCommandRunner(func(ctx context.Context, d string, name string, args ...string) (string, string, error) {
+		<-ctx.Done()
+		return "partial", "", errors.New("signal: killed")
+	})
+
+	findings := r.Run(context.Background(), []string{"main.go"})
+	if len(findings) != 1 || !strings.Contains(findings[0].Output, "partial\n[timed out after") {
+		t.Errorf("findings = %+v, want a timed out run", findings)
+	}
+}
+
+func TestRunner_Run_TruncatesAtRuneBoundary(t *testing.T) {
+	dir := newTestRepo(t, "main.go")
+	r := NewRunner(dir, []Analyzer{{Name: "lint", Command: []string{"lint"}}})
+	r.SetCommandRunner(func(ctx context.Context, d string, name string, args ...string) (string, string, error) {
+		// Three-byte runes after one byte put the limit mid-rune
+		return "x" + strings.Repeat("€", maxOutputBytes), "", &exec.ExitError{}
+	})
+
+	findings := r.Run(context.Background(), []string{"main.go"})
+	if len(findings) != 1 || !utf8.ValidString(findings[0].Output) || !strings.HasSuffix(findings[0].Output, "[output truncated]") {
+		t.Errorf("expected valid UTF-8 output truncated, got %d findings", len(findings))
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if got := Format(nil); got != "" {
+		t.Errorf("Format(nil) = %q, want empty", got)
+	}
+	got := Format([]Finding{
+		{Analyzer: "go vet", Output: "a.go:1: bad"},
+		{Analyzer: "eslint", Output: "missing", Failed: true},
+	})
+	want := "## go vet (reported)\n\n```\na.go:1: bad\n```\n\n## eslint (failed)\n\n```\nmissing\n```"
+	if got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+}
@@ -4,21 +4,36 @@ package app
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/google/uuid"
 
+	"github.com/gerunddev/ralph/internal/analyze"
 	"github.com/gerunddev/ralph/internal/claude"
 	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/conventions"
 	"github.com/gerunddev/ralph/internal/db"
 	"github.com/gerunddev/ralph/internal/jj"
 	"github.com/gerunddev/ralph/internal/log"
 	"github.com/gerunddev/ralph/internal/loop"
+	"github.com/gerunddev/ralph/internal/notify"
+	"github.com/gerunddev/ralph/internal/planenv"
+	"github.com/gerunddev/ralph/internal/planspec"
+	"github.com/gerunddev/ralph/internal/policy"
+	"github.com/gerunddev/ralph/internal/redact"
+	"github.com/gerunddev/ralph/internal/scrollback"
+	"github.com/gerunddev/ralph/internal/snapshot"
+	"github.com/gerunddev/ralph/internal/tracker"
+	"github.com/gerunddev/ralph/internal/triage"
 	"github.com/gerunddev/ralph/internal/tui"
 )
 
@@ -29,13 +44,54 @@ type App struct {
 	db      *db.DB
 	claude  *claude.Client
 	jj      *jj.Client
+	vcs     loop.VCS // a.jj, or a snapshot client with NoVCS
 	workDir string
 
+	// reviewerClaude carries reviewer-specific CLI options
+	reviewerClaude *claude.Client
+
+	// fixtures records or replays Claude sessions (nil = run the CLI)
+	fixtures *claude.Fixtures
+
+	// mcpConfig serves ralph's tools to sessions (empty = none)
+	mcpConfig string
+
+	// env holds the plan's environment variables (KEY=VALUE), set on the
+	// jj and Claude processes once the plan is loaded
+	env []string
+
+	// planPolicy is the plan's sandbox policy, from the permissions config
+	// and its front matter, set once the plan is loaded
+	planPolicy *policy.Policy
+
 	// plan is set after loading/creating
 	plan *db.Plan
 
-	// loop is set after initialization
-	loop *loop.Loop
+	// workspace is the jj workspace the plan runs in (nil = workDir), and
+	// repoRoot the repository it belongs to
+	workspace *db.PlanWorkspace
+	repoRoot  string
+
+	// loop is set after initialization; loopDone is closed once its Run
+	// returns. Both are guarded by loopMu, as Pause and Stop may be called
+	// from other goroutines.
+	loopMu   sync.Mutex
+	loop     *loop.Loop
+	loopDone chan struct{}
+
+	// redactor masks secrets in events and logs (nil when disabled)
+	redactor *redact.Redactor
+
+	// notifier posts loop milestones to a webhook (nil when not configured)
+	notifier     *notify.Notifier
+	notifierDone chan struct{} // Closed once the notifier's subscription is drained
+
+	// tracker keeps the plan's tracking issue in sync (nil when not configured)
+	tracker     *tracker.Syncer
+	trackerDone chan struct{} // Closed once the tracker's subscription is drained
+
+	// eventHandler receives the loop's events in headless runs (nil = discard)
+	eventHandler func(loop.Event)
 
 	// For testing: allow injecting mock dependencies
 	claudeOverride *claude.Client
@@ -44,42 +100,156 @@ type App struct {
 
 // Config holds configuration for creating a new App.
 type Config struct {
-	// WorkDir is the working directory for jj operations.
+	// WorkDir is the directory the plan runs in: jj operations, Claude
+	// sessions, and the project-local config all use it.
 	// If empty, uses the current working directory.
 	WorkDir string
 
+	// WorkDirOverride marks WorkDir as chosen explicitly (--workdir), which
+	// lets a resumed plan run outside the directory it was created in.
+	WorkDirOverride bool
+
 	// MaxIterationsOverride overrides the max_iterations from config.
 	// If 0, uses the value from config file.
 	MaxIterationsOverride int
 
-	// ExtremeMode enables extreme mode (+3 iterations after both done).
+	// MaxDuration stops the loop between iterations once this much
+	// wall-clock time has passed, leaving the plan paused (0 = unlimited).
+	MaxDuration time.Duration
+
+	// MaxCost stops the loop between iterations once the plan's Claude
+	// sessions have cost this many USD across all of its runs, leaving
+	// the plan paused (0 = unlimited).
+	MaxCost float64
+
+	// IterationsThisRun runs the plan in batch mode: without the TUI, it
+	// works this many iterations, leaves the plan paused, and prints a
+	// summary (0 = run with the TUI until the plan ends).
+	IterationsThisRun int
+
+	// ExtremeMode enables extreme mode (bonus iterations after both done).
 	ExtremeMode bool
 
+	// ExtremeBonus overrides extreme_bonus_iterations from config.
+	// If 0, uses the value from config file.
+	ExtremeBonus int
+
 	// TeamMode enables agent teams for the developer phase.
 	TeamMode bool
+
+	// Decompose runs a planner agent that breaks the plan into tasks
+	// before development starts.
+	Decompose bool
+
+	// AutoApplyReviewPatches applies patches the reviewer suggests as
+	// separate jj changes instead of only passing them to the developer.
+	AutoApplyReviewPatches bool
+
+	// PlanRefresh overrides plan_refresh from config: what to do when the
+	// plan file is edited during the run (empty = use config).
+	PlanRefresh string
+
+	// NoWorkspace runs the plan in the current jj workspace even when
+	// jj.workspaces gives each plan its own.
+	NoWorkspace bool
+
+	// Scope limits the plan to this slash-separated directory of a
+	// mono-repo, relative to the repository root (empty = the whole
+	// repository). Like Env, it applies to this run only.
+	Scope string
+
+	// Theme overrides tui.theme from config (empty = use config).
+	Theme string
+
+	// Models overrides claude.model and claude.fallback_models from config
+	// with a model and its fallbacks (empty = use config).
+	Models []string
+
+	// Accessible turns on the TUI's screen-reader-friendly output, as
+	// tui.accessible does.
+	Accessible bool
+
+	// CreatePR pushes the result to a bookmark and opens a pull request
+	// once the plan completes.
+	CreatePR bool
+
+	// FromIteration, when resuming, rewinds the plan's progress, learnings,
+	// and reviewer feedback to the end of this iteration first (0 = resume
+	// from the latest state).
+	FromIteration int
+
+	// RestoreWorkingCopy, with FromIteration, also restores the working
+	// copy to the snapshot taken after that iteration's developer session.
+	RestoreWorkingCopy bool
+
+	// PlanContent, when set, is stored as the plan instead of the plan
+	// file's content (e.g. after --edit). The file is left untouched.
+	PlanContent string
+
+	// ForceNew starts a new plan from the plan file even when an unfinished
+	// plan was already started from it.
+	ForceNew bool
+
+	// ConfirmResume is asked, when the plan file was already started as a
+	// plan that isn't finished, whether to resume that plan instead of
+	// starting a new one (nil = resume it without asking).
+	ConfirmResume func(plan *db.Plan) bool
+
+	// PromptFromStdin marks the prompt passed to RunWithPrompt as read from
+	// standard input, so the TUI reads keys from the terminal instead.
+	PromptFromStdin bool
+
+	// RecordFixtures, when set, records the raw stream of every Claude
+	// session to numbered files in this directory.
+	RecordFixtures string
+
+	// ReplayFixtures, when set, replays Claude sessions from fixtures
+	// recorded to this directory instead of running the CLI.
+	ReplayFixtures string
+
+	// NoVCS runs the plan in a directory that isn't a jj repository,
+	// tracking its changes with snapshots kept outside it instead.
+	NoVCS bool
+
+	// Env holds NAME=value assignments (--env) set on the jj and Claude
+	// processes, overriding the plan's front matter env. They apply to this
+	// run only and are not stored with the plan.
+	Env []string
 }
 
 // New creates a new App.
 func New(cfg Config) (*App, error) {
-	// Load configuration
-	appConfig, err := config.Load()
-	if err != nil {
-		return nil, fmt.Errorf("failed to load config: %w", err)
-	}
-
 	// Determine working directory
 	workDir := cfg.WorkDir
 	if workDir == "" {
+		var err error
 		workDir, err = os.Getwd()
 		if err != nil {
 			return nil, fmt.Errorf("failed to get working directory: %w", err)
 		}
 	}
+	workDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve working directory: %w", err)
+	}
+
+	// Load configuration, including the working directory's local config
+	appConfig, err := config.LoadForDir(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
 
 	// Apply max iterations override if specified
 	if cfg.MaxIterationsOverride > 0 {
 		appConfig.MaxIterations = cfg.MaxIterationsOverride
 	}
+	if cfg.ExtremeBonus > 0 {
+		appConfig.ExtremeBonusIterations = cfg.ExtremeBonus
+	}
+	if len(cfg.Models) > 0 {
+		appConfig.Claude.Model = cfg.Models[0]
+		appConfig.Claude.FallbackModels = cfg.Models[1:]
+	}
 
 	app := &App{
 		cfg:     appConfig,
@@ -98,12 +268,16 @@ func (a *App) Run(ctx context.Context, planPath string) error {
 	}
 	defer a.cleanup()
 
-	// Create plan from file
-	if err := a.createPlanFromFile(planPath); err != nil {
+	// Resume the plan already started from the file, or create one
+	if err := a.resumeOrCreatePlan(planPath); err != nil {
 		return err
 	}
+	if err := a.enterWorkspace(ctx); err != nil {
+		return err
+	}
+	defer a.releaseWorkspace(ctx)
 
-	return a.runLoop(ctx)
+	return a.runPlan(ctx)
 }
 
 // Resume continues execution of an existing plan.
@@ -118,8 +292,15 @@ func (a *App) Resume(ctx context.Context, planID string) error {
 	if err := a.loadPlan(planID); err != nil {
 		return err
 	}
+	if err := a.enterWorkspace(ctx); err != nil {
+		return err
+	}
+	defer a.releaseWorkspace(ctx)
+	if err := a.rewindPlan(ctx); err != nil {
+		return err
+	}
 
-	return a.runLoop(ctx)
+	return a.runPlan(ctx)
 }
 
 // RunWithPrompt starts execution with a plan from an inline prompt string.
@@ -134,12 +315,70 @@ func (a *App) RunWithPrompt(ctx context.Context, prompt string) error {
 	if err := a.createPlanFromPrompt(prompt); err != nil {
 		return err
 	}
+	if err := a.enterWorkspace(ctx); err != nil {
+		return err
+	}
+	defer a.releaseWorkspace(ctx)
 
-	return a.runLoop(ctx)
+	return a.runPlan(ctx)
+}
+
+// OpenPlansDB opens the centralized plans database on the configured
+// backend, enabling at-rest encryption and secret redaction as configured.
+func OpenPlansDB(cfg *config.Config) (*db.DB, error) {
+	dsn := db.PlansDBPath(cfg.GetProjectsDir())
+	if cfg.Database.Backend == config.DatabaseBackendPostgres {
+		url, err := cfg.Database.ConnectionURL()
+		if err != nil {
+			return nil, err
+		}
+		dsn = url
+	}
+
+	database, err := db.Open(cfg.Database.Backend, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if cfg.Encryption.Enabled {
+		cipher, err := newCipher(cfg.Encryption)
+		if err != nil {
+			if closeErr := database.Close(); closeErr != nil {
+				log.Warn("failed to close database", "error", closeErr)
+			}
+			return nil, fmt.Errorf("failed to enable encryption: %w", err)
+		}
+		database.SetCipher(cipher)
+	}
+
+	redactor, err := cfg.Redaction.Redactor()
+	if err != nil {
+		if closeErr := database.Close(); closeErr != nil {
+			log.Warn("failed to close database", "error", closeErr)
+		}
+		return nil, err
+	}
+	database.SetRedactor(redactor)
+	return database, nil
+}
+
+// newCipher creates the at-rest cipher from the configured key.
+func newCipher(cfg config.EncryptionConfig) (*db.Cipher, error) {
+	key, err := cfg.Key()
+	if err != nil {
+		return nil, err
+	}
+	return db.NewCipher(key)
 }
 
 // initDependencies initializes all required dependencies.
 func (a *App) initDependencies() error {
+	// Check the forge settings before the run, not once the plan completes
+	if a.appCfg.CreatePR {
+		if _, err := a.forgeClient(); err != nil {
+			return err
+		}
+	}
+
 	// Create database directory and initialize
 	dbDir := a.cfg.GetProjectsDir()
 	if err := os.MkdirAll(dbDir, 0755); err != nil {
@@ -147,22 +386,40 @@ func (a *App) initDependencies() error {
 	}
 
 	// Use centralized database
-	dbPath := filepath.Join(dbDir, "ralph.db")
-	database, err := db.New(dbPath)
+	database, err := OpenPlansDB(a.cfg)
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return err
 	}
 	a.db = database
 
-	// Create Claude client (use override if set, for testing)
+	// Mask secrets shown in the TUI, posted to webhooks, and logged
+	a.redactor, err = a.cfg.Redaction.Redactor()
+	if err != nil {
+		return err
+	}
+	if a.redactor != nil {
+		log.SetRedactor(a.redactor.String)
+	}
+
+	// Create Claude clients (use override if set, for testing)
 	if a.claudeOverride != nil {
 		a.claude = a.claudeOverride
+		a.reviewerClaude = a.claudeOverride
 	} else {
-		a.claude = claude.NewClient(claude.ClientConfig{
-			Model:    a.cfg.Claude.Model,
-			MaxTurns: a.cfg.Claude.MaxTurns,
-			Verbose:  a.cfg.Claude.Verbose,
-		})
+		if a.fixtures, err = a.claudeFixtures(); err != nil {
+			return err
+		}
+		a.mcpConfig = a.newMCPConfig()
+		devCfg := a.claudeConfig(a.cfg.Claude.Developer)
+		if err := devCfg.Validate(); err != nil {
+			return fmt.Errorf("invalid claude.developer options: %w", err)
+		}
+		reviewerCfg := a.claudeConfig(a.cfg.Claude.Reviewer)
+		if err := reviewerCfg.Validate(); err != nil {
+			return fmt.Errorf("invalid claude.reviewer options: %w", err)
+		}
+		a.claude = claude.NewClient(devCfg)
+		a.reviewerClaude = claude.NewClient(reviewerCfg)
 	}
 
 	// Create jj client (use override if set, for testing)
@@ -171,6 +428,10 @@ func (a *App) initDependencies() error {
 	} else {
 		a.jj = jj.NewClient(a.workDir)
 	}
+	a.vcs = a.jj
+	if a.appCfg.NoVCS {
+		a.vcs = snapshot.NewClient(a.workDir, snapshotDir(dbDir, a.workDir))
+	}
 
 	return nil
 }
@@ -186,10 +447,24 @@ func (a *App) cleanup() {
 
 // createPlanFromFile reads a plan file and creates it in the database.
 func (a *App) createPlanFromFile(planPath string) error {
-	content, err := os.ReadFile(planPath)
-	if err != nil {
+	fileContent, err := os.ReadFile(planPath)
+	if err != nil && a.appCfg.PlanContent == "" {
 		return fmt.Errorf("failed to read plan file: %w", err)
 	}
+	content := fileContent
+	if a.appCfg.PlanContent != "" {
+		content = []byte(a.appCfg.PlanContent)
+	}
+	planContent, convErr := planspec.Content(planPath, content)
+	if convErr != nil {
+		return convErr
+	}
+
+	// Snapshot the file so edits made while the plan runs can be detected
+	var originHash string
+	if err == nil {
+		originHash = loop.HashPlanFile(string(fileContent))
+	}
 
 	absPath, err := filepath.Abs(planPath)
 	if err != nil {
@@ -199,10 +474,18 @@ func (a *App) createPlanFromFile(planPath string) error {
 	plan := &db.Plan{
 		ID:         uuid.New().String(),
 		OriginPath: absPath,
-		Content:    string(content),
+		Content:    planContent,
 		Status:     db.PlanStatusPending,
+		WorkDir:    a.workDir,
+		OriginHash: originHash,
 	}
 
+	if err := a.applyEnv(plan); err != nil {
+		return err
+	}
+	if err := a.applyPolicy(plan); err != nil {
+		return err
+	}
 	if err := a.db.CreatePlan(plan); err != nil {
 		return fmt.Errorf("failed to create plan: %w", err)
 	}
@@ -211,6 +494,90 @@ func (a *App) createPlanFromFile(planPath string) error {
 	return nil
 }
 
+// resumeOrCreatePlan loads the unfinished plan already started from the
+// plan file, or creates a new plan from it.
+func (a *App) resumeOrCreatePlan(planPath string) error {
+	existing, err := a.unfinishedPlan(planPath)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return a.createPlanFromFile(planPath)
+	}
+	log.Info("resuming unfinished plan from the same file", "plan", existing.ID, "status", existing.Status)
+	return a.loadPlan(existing.ID)
+}
+
+// unfinishedPlan returns the plan to resume instead of creating a new one
+// from the plan file: the latest plan started from the same file, unchanged
+// since, in the same directory, that is neither completed, cancelled, nor
+// running in a live process, if ConfirmResume agrees. Forks aren't resumed.
+// It returns nil to create a new plan, as it does with ForceNew or when the
+// plan was edited before being stored.
+func (a *App) unfinishedPlan(planPath string) (*db.Plan, error) {
+	if a.appCfg.ForceNew {
+		return nil, nil
+	}
+	fileContent, err := os.ReadFile(planPath)
+	if err != nil || (a.appCfg.PlanContent != "" && a.appCfg.PlanContent != string(fileContent)) {
+		return nil, nil
+	}
+	absPath, err := filepath.Abs(planPath)
+	if err != nil {
+		absPath = planPath
+	}
+
+	plans, err := a.db.FindUnfinishedPlans(absPath, loop.HashPlanFile(string(fileContent)), a.workDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look for an unfinished plan: %w", err)
+	}
+	for _, plan := range plans {
+		if plan.Status == db.PlanStatusRunning && a.runLive(plan.ID) {
+			continue
+		}
+		if a.appCfg.ConfirmResume != nil && !a.appCfg.ConfirmResume(plan) {
+			return nil, nil
+		}
+		return plan, nil
+	}
+	return nil, nil
+}
+
+// runLive reports whether a ralph process may still be running the plan:
+// its recorded process is alive, or runs on another machine, where it
+// can't be checked. A running plan with no recorded process has none.
+func (a *App) runLive(planID string) bool {
+	run, err := a.db.GetPlanRun(planID)
+	if err != nil {
+		log.Warn("failed to get plan run", "plan", planID, "error", err)
+		return true
+	}
+	if run == nil {
+		return false
+	}
+	if host, err := os.Hostname(); err != nil || host != run.Host {
+		return true
+	}
+	return processAlive(run.PID)
+}
+
+// startRun records this process as running the plan until the returned
+// function is called.
+func (a *App) startRun() func() {
+	host, err := os.Hostname()
+	if err != nil {
+		log.Warn("failed to get hostname", "error", err)
+	}
+	if err := a.db.StartPlanRun(&db.PlanRun{PlanID: a.plan.ID, Host: host, PID: os.Getpid()}); err != nil {
+		log.Warn("failed to record plan run", "plan", a.plan.ID, "error", err)
+	}
+	return func() {
+		if err := a.db.EndPlanRun(a.plan.ID); err != nil {
+			log.Warn("failed to clear plan run", "plan", a.plan.ID, "error", err)
+		}
+	}
+}
+
 // createPlanFromPrompt creates a plan from an inline prompt string.
 func (a *App) createPlanFromPrompt(prompt string) error {
 	plan := &db.Plan{
@@ -218,8 +585,15 @@ func (a *App) createPlanFromPrompt(prompt string) error {
 		OriginPath: "", // No file origin for inline prompts
 		Content:    prompt,
 		Status:     db.PlanStatusPending,
+		WorkDir:    a.workDir,
 	}
 
+	if err := a.applyEnv(plan); err != nil {
+		return err
+	}
+	if err := a.applyPolicy(plan); err != nil {
+		return err
+	}
 	if err := a.db.CreatePlan(plan); err != nil {
 		return fmt.Errorf("failed to create plan: %w", err)
 	}
@@ -234,40 +608,531 @@ func (a *App) loadPlan(planID string) error {
 	if err != nil {
 		return fmt.Errorf("failed to load plan: %w", err)
 	}
+	if err := a.checkWorkDir(plan); err != nil {
+		return err
+	}
+	if err := a.applyEnv(plan); err != nil {
+		return err
+	}
+	if err := a.applyPolicy(plan); err != nil {
+		return err
+	}
 
 	a.plan = plan
 	return nil
 }
 
+// applyEnv resolves a plan's environment variables, from its front matter
+// and --env, and sets them on the jj and Claude clients.
+func (a *App) applyEnv(plan *db.Plan) error {
+	env, err := planenv.Resolve(plan.RunContent(), a.appCfg.Env, os.LookupEnv)
+	if err != nil {
+		return err
+	}
+	if len(env) == 0 {
+		return nil
+	}
+	log.Info("setting plan environment", "plan", plan.ID, "vars", planenv.Names(env))
+
+	a.env = env
+	a.jj.SetEnv(env)
+	a.claude.AddEnvVars(env...)
+	if a.reviewerClaude != a.claude {
+		a.reviewerClaude.AddEnvVars(env...)
+	}
+	return nil
+}
+
+// applyPolicy resolves a plan's sandbox policy, from the permissions config
+// and its front matter, and sets its disallowed tools on the Claude clients.
+func (a *App) applyPolicy(plan *db.Plan) error {
+	p, err := policy.ForPlan(a.configPolicy(), plan.RunContent())
+	if err != nil {
+		return fmt.Errorf("invalid plan front matter: %w", err)
+	}
+	a.planPolicy = p
+
+	a.claude.SetDisallowedTools(p.DisallowedTools())
+	if a.reviewerClaude != a.claude {
+		a.reviewerClaude.SetDisallowedTools(p.DisallowedTools())
+	}
+	return nil
+}
+
+// checkWorkDir ensures a resumed plan runs in the directory it was created
+// in, unless the working directory was overridden explicitly.
+func (a *App) checkWorkDir(plan *db.Plan) error {
+	if plan.WorkDir == "" || sameDir(plan.WorkDir, a.workDir) {
+		return nil
+	}
+	if a.appCfg.WorkDirOverride {
+		log.Warn("resuming plan outside its original directory",
+			"plan", plan.ID, "original", plan.WorkDir, "workdir", a.workDir)
+		return nil
+	}
+	return fmt.Errorf("plan %s runs in %s, not %s (pass --workdir to override)", plan.ID, plan.WorkDir, a.workDir)
+}
+
+// sameDir reports whether two paths name the same directory, following
+// symlinks where possible.
+func sameDir(a, b string) bool {
+	resolve := func(path string) string {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			return resolved
+		}
+		return filepath.Clean(path)
+	}
+	return resolve(a) == resolve(b)
+}
+
+// snapshotDir returns where a directory's snapshots are kept with NoVCS:
+// under the projects directory, named by a hash of the directory's path, so
+// they stay out of the directory itself.
+func snapshotDir(projectsDir, workDir string) string {
+	sum := sha256.Sum256([]byte(workDir))
+	return filepath.Join(projectsDir, "snapshots", hex.EncodeToString(sum[:8]))
+}
+
 // createLoop creates a new loop instance with the current plan and dependencies.
 func (a *App) createLoop() {
+	a.refreshMCPConfig()
+
 	deps := loop.Deps{
-		DB:     a.db,
-		Claude: a.claude,
-		JJ:     a.jj,
+		DB:             a.db,
+		Claude:         a.claude,
+		ReviewerClaude: a.reviewerClaude,
+		VCS:            a.vcs,
+		Analyzers:      a.analyzers(),
+		Conventions:    a.conventions(),
+		TestGate:       a.testGate(),
+		Checks:         a.checks(),
+		Hooks:          a.hooks(),
 	}
 
 	// In team mode, create a separate Claude client with agent teams env var
 	if a.appCfg.TeamMode {
-		deps.TeamClaude = claude.NewClient(claude.ClientConfig{
-			Model:    a.cfg.Claude.Model,
-			MaxTurns: a.cfg.Claude.MaxTurns,
-			Verbose:  a.cfg.Claude.Verbose,
-			EnvVars:  []string{"CLAUDE_CODE_EXPERIMENTAL_AGENT_TEAMS=1"},
-		})
+		teamCfg := a.claudeConfig(a.cfg.Claude.Developer)
+		teamCfg.EnvVars = append(teamCfg.EnvVars, "CLAUDE_CODE_EXPERIMENTAL_AGENT_TEAMS=1")
+		deps.TeamClaude = claude.NewClient(teamCfg)
 		// If there's a test override, also apply it to the team client
 		if a.claudeOverride != nil {
 			deps.TeamClaude = a.claudeOverride
 		}
 	}
 
-	a.loop = loop.New(loop.Config{
-		PlanID:        a.plan.ID,
-		MaxIterations: a.cfg.MaxIterations,
-		ExtremeMode:   a.appCfg.ExtremeMode,
-		TeamMode:      a.appCfg.TeamMode,
-		WorkDir:       a.workDir,
+	// With downgraded reviews, trivial changes go to a reviewer on a cheaper model
+	if a.cfg.ReviewTriage.Action == config.ReviewTriageDowngrade {
+		lightCfg := a.claudeConfig(a.cfg.Claude.Reviewer)
+		lightCfg.Model = a.cfg.ReviewTriage.Model
+		deps.TrivialReviewClaude = claude.NewClient(lightCfg)
+		if a.claudeOverride != nil {
+			deps.TrivialReviewClaude = a.claudeOverride
+		}
+	}
+
+	// Malformed output is reformatted on a cheaper model when one is set
+	if a.cfg.SelfCheck.Enabled && a.cfg.SelfCheck.Model != "" {
+		reformatCfg := a.claudeConfig(a.cfg.Claude.Reviewer)
+		reformatCfg.Model = a.cfg.SelfCheck.Model
+		deps.ReformatClaude = claude.NewClient(reformatCfg)
+		if a.claudeOverride != nil {
+			deps.ReformatClaude = a.claudeOverride
+		}
+	}
+
+	// Diffs too large for the reviewer are summarized on their own model
+	// when one is set
+	if a.cfg.ReviewDiff.Format == config.ReviewDiffSummary && a.cfg.ReviewDiff.Model != "" {
+		summaryCfg := a.claudeConfig(a.cfg.Claude.Reviewer)
+		summaryCfg.Model = a.cfg.ReviewDiff.Model
+		deps.SummaryClaude = claude.NewClient(summaryCfg)
+		if a.claudeOverride != nil {
+			deps.SummaryClaude = a.claudeOverride
+		}
+	}
+
+	// Failure triage runs on its own model when one is set
+	if a.cfg.FailureTriage.After > 0 && a.cfg.FailureTriage.Model != "" {
+		triageCfg := a.claudeConfig(a.cfg.Claude.Reviewer)
+		triageCfg.Model = a.cfg.FailureTriage.Model
+		deps.TriageClaude = claude.NewClient(triageCfg)
+		if a.claudeOverride != nil {
+			deps.TriageClaude = a.claudeOverride
+		}
+	}
+
+	l := loop.New(loop.Config{
+		PlanID:              a.plan.ID,
+		MaxIterations:       a.cfg.MaxIterations,
+		MaxDuration:         a.appCfg.MaxDuration,
+		MaxCost:             a.appCfg.MaxCost,
+		IterationsThisRun:   a.appCfg.IterationsThisRun,
+		ExtremeMode:         a.appCfg.ExtremeMode,
+		TeamMode:            a.appCfg.TeamMode,
+		WorkDir:             a.planDir(),
+		RepoRoot:            a.repoRoot,
+		Policy:              a.policy(),
+		FlagOutOfScopeFiles: a.cfg.OutOfScopeFiles == config.OutOfScopeFlag,
+		Scope:               a.appCfg.Scope,
+		StallThreshold:      a.cfg.Stall.Threshold,
+		StallAbort:          a.cfg.Stall.Action == config.StallActionAbort,
+		FailureTriageAfter:  a.cfg.FailureTriage.After,
+		Retry: loop.RetryPolicy{
+			MaxAttempts:    a.cfg.Retry.MaxAttempts,
+			InitialBackoff: time.Duration(a.cfg.Retry.InitialBackoffSeconds) * time.Second,
+			MaxBackoff:     time.Duration(a.cfg.Retry.MaxBackoffSeconds) * time.Second,
+
+			MaxRateLimitWait: time.Duration(a.cfg.Retry.MaxRateLimitWaitSeconds) * time.Second,
+		},
+		ModelChain:             a.cfg.Claude.ModelChain(),
+		Throttle:               SessionThrottle(a.cfg),
+		QuietHours:             a.quietHours(),
+		GlobalLearningsLimit:   a.cfg.GlobalLearningsLimit,
+		Decompose:              a.appCfg.Decompose,
+		CommitTrailers:         a.cfg.CommitTrailers,
+		ChangePerIteration:     a.cfg.JJ.ChangePerIteration,
+		SquashOnComplete:       a.cfg.JJ.SquashOnComplete,
+		Bookmark:               a.planBookmark(),
+		DeleteBookmark:         a.cfg.JJ.BookmarkOnComplete == config.BookmarkDelete,
+		MaxSubPlanDepth:        a.cfg.SubPlans.MaxDepth,
+		RunSubPlans:            a.cfg.SubPlans.Run,
+		Reviewers:              a.cfg.Team.Reviewers,
+		ReviewQuorum:           a.cfg.Team.Quorum,
+		AutoApplyReviewPatches: a.appCfg.AutoApplyReviewPatches,
+		ExtremeBonusIterations: a.cfg.ExtremeBonusIterations,
+		ExtremeMaxRounds:       a.cfg.ExtremeMaxRounds,
+		WatchPlan:              a.planRefresh() != config.PlanRefreshOff,
+		MergePlanEdits:         a.planRefresh() == config.PlanRefreshMerge,
+		WaitOnConflicts:        a.cfg.ConflictResolution == config.ConflictResolutionWait || a.cfg.ConflictResolution == config.ConflictResolutionResolve,
+		ResolveConflicts:       a.cfg.ConflictResolution == config.ConflictResolutionResolve,
+		TrivialChanges:         a.trivialChanges(),
+		SummarizeLargeDiffs:    a.cfg.ReviewDiff.Format == config.ReviewDiffSummary,
+		ReviewFiles:            a.cfg.ReviewDiff.Context == config.ReviewContextFiles,
+		ReviewFilesBytes:       a.cfg.ReviewDiff.ContextBytes,
+		SelfCheckAttempts:      a.selfCheckAttempts(),
+		DifferentialPrompts:    a.differentialPrompts(),
+		FullPromptEvery:        a.cfg.DifferentialPrompts.FullRefreshEvery,
+		FastResume:             a.cfg.FastResume.Enabled,
+		ResumeClaudeSession:    a.resumeClaudeSession(),
+		StatusTool:             a.statusTool(),
+		StateTools:             a.stateTools(),
+		Locale:                 a.cfg.Locale,
+		AgentPrompt:            a.cfg.GetAgentPrompt,
+		ClaudeVersion:          a.claudeVersion(),
+		Redactor:               a.redactor,
 	}, deps)
+
+	a.loopMu.Lock()
+	a.loop = l
+	a.loopDone = make(chan struct{})
+	a.loopMu.Unlock()
+
+	a.subscribeNotifier()
+	a.subscribeTracker()
+}
+
+// runCreatedLoop runs the loop made by createLoop, closing loopDone once it
+// returns.
+func (a *App) runCreatedLoop(ctx context.Context) error {
+	defer close(a.loopDone)
+	return a.loop.Run(ctx)
+}
+
+// running returns the loop and its done channel, or nil if no loop was
+// created.
+func (a *App) running() (*loop.Loop, chan struct{}) {
+	a.loopMu.Lock()
+	defer a.loopMu.Unlock()
+	return a.loop, a.loopDone
+}
+
+// claudeVersion returns the claude CLI's version for the sessions'
+// environment records, or "" if it can't be determined. Test overrides of
+// the Claude client skip the check.
+func (a *App) claudeVersion() string {
+	if a.claudeOverride != nil || a.claude == nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	version, err := a.claude.Version(ctx)
+	if err != nil {
+		log.Warn("failed to get claude CLI version", "error", err)
+		return ""
+	}
+	return version
+}
+
+// planRefresh returns the plan refresh mode: the override when set,
+// otherwise the configured one.
+func (a *App) planRefresh() string {
+	if a.appCfg.PlanRefresh != "" {
+		return a.appCfg.PlanRefresh
+	}
+	if a.cfg.PlanRefresh == "" {
+		return config.PlanRefreshOff
+	}
+	return a.cfg.PlanRefresh
+}
+
+// scrollbackPath returns the plan's feed scrollback file.
+func (a *App) scrollbackPath() string {
+	return scrollback.Path(filepath.Join(a.workDir, config.LocalDir), a.plan.ID)
+}
+
+// theme returns the TUI theme: the override when set, otherwise the
+// configured one.
+func (a *App) theme() string {
+	if a.appCfg.Theme != "" {
+		return a.appCfg.Theme
+	}
+	return a.cfg.TUI.Theme
+}
+
+// newNotifier creates the webhook notifier from the notify config. It returns
+// nil when no webhook is configured or the config is invalid; notifications
+// never stop a run.
+func (a *App) newNotifier() *notify.Notifier {
+	cfg := a.cfg.Notify
+	if cfg.WebhookURL == "" {
+		return nil
+	}
+	notifier, err := notify.New(notify.Config{
+		WebhookURL:  cfg.WebhookURL,
+		Provider:    cfg.Provider,
+		Events:      cfg.Events,
+		Template:    cfg.Template,
+		MinInterval: time.Duration(cfg.MinIntervalSeconds) * time.Second,
+		PlanID:      a.plan.ID,
+	})
+	if err != nil {
+		log.Warn("notifications disabled", "error", err)
+		return nil
+	}
+	return notifier
+}
+
+// subscribeNotifier forwards the notifier's milestones from the loop's event
+// bus. Notify never blocks, so the subscription is lossless.
+func (a *App) subscribeNotifier() {
+	a.notifier = a.newNotifier()
+	if a.notifier == nil {
+		return
+	}
+
+	sub := a.loop.Subscribe(loop.WithTypes(a.notifier.EventTypes()...), loop.WithBuffer(100))
+	a.notifierDone = make(chan struct{})
+	go func() {
+		defer close(a.notifierDone)
+		for event := range sub.Events() {
+			a.notifier.Notify(event)
+		}
+	}()
+}
+
+// closeNotifier flushes pending notifications once the loop has finished.
+func (a *App) closeNotifier() {
+	if a.notifier == nil {
+		return
+	}
+	<-a.notifierDone
+	a.notifier.Close()
+}
+
+// claudeConfig builds the Claude client config for an agent role.
+func (a *App) claudeConfig(role config.ClaudeRoleConfig) claude.ClientConfig {
+	allowedTools := role.AllowedTools
+	if a.mcpConfig != "" {
+		allowedTools = append(slices.Clone(allowedTools), mcpToolsRule)
+	}
+	return claude.ClientConfig{
+		Model:           a.cfg.Claude.Model,
+		MaxTurns:        a.cfg.Claude.MaxTurns,
+		Verbose:         a.cfg.Claude.Verbose,
+		EnvVars:         slices.Clone(a.env),
+		WorkDir:         a.planDir(),
+		DisallowedTools: a.policy().DisallowedTools(),
+		AllowedTools:    allowedTools,
+		PermissionMode:  role.PermissionMode,
+		MCPConfig:       a.mcpConfig,
+		ExtraArgs:       role.ExtraArgs,
+		Liveness: claude.Liveness{
+			HeartbeatInterval: time.Duration(a.cfg.Claude.Liveness.HeartbeatSeconds) * time.Second,
+			StalledAfter:      time.Duration(a.cfg.Claude.Liveness.StalledAfterSeconds) * time.Second,
+			IdleTimeout:       time.Duration(a.cfg.Claude.Liveness.IdleTimeoutSeconds) * time.Second,
+		},
+		Fixtures: a.fixtures,
+		Backend:  a.cfg.Claude.Backend,
+		API: claude.APIConfig{
+			Key:       a.cfg.Claude.API.Key(),
+			BaseURL:   a.cfg.Claude.API.BaseURL,
+			MaxTokens: a.cfg.Claude.API.MaxTokens,
+			Timeout:   time.Duration(a.cfg.Claude.API.TimeoutSeconds) * time.Second,
+		},
+	}
+}
+
+// claudeFixtures returns the fixtures Claude sessions are recorded to or
+// replayed from, or nil when neither is requested.
+func (a *App) claudeFixtures() (*claude.Fixtures, error) {
+	switch {
+	case a.appCfg.RecordFixtures != "" && a.appCfg.ReplayFixtures != "":
+		return nil, errors.New("cannot both record and replay fixtures")
+	case a.appCfg.RecordFixtures != "":
+		return claude.NewFixtureRecorder(a.appCfg.RecordFixtures)
+	case a.appCfg.ReplayFixtures != "":
+		return claude.NewFixtureReplayer(a.appCfg.ReplayFixtures)
+	}
+	return nil, nil
+}
+
+// policy returns the sandbox policy: the plan's, once a plan is loaded, or
+// the permissions config's.
+func (a *App) policy() *policy.Policy {
+	if a.planPolicy != nil {
+		return a.planPolicy
+	}
+	return a.configPolicy()
+}
+
+// configPolicy builds the sandbox policy from the permissions config.
+func (a *App) configPolicy() *policy.Policy {
+	perms := a.cfg.Permissions
+	return policy.New(perms.AllowedPaths, perms.ForbiddenCommands, perms.Network)
+}
+
+// analyzers returns the runner for the configured static analyzers, or nil
+// when none are configured.
+func (a *App) analyzers() *analyze.Runner {
+	if len(a.cfg.Analyzers) == 0 {
+		return nil
+	}
+	analyzers := make([]analyze.Analyzer, len(a.cfg.Analyzers))
+	for i, c := range a.cfg.Analyzers {
+		analyzers[i] = analyze.Analyzer{
+			Name:       c.Name,
+			Command:    c.Command,
+			Extensions: c.Extensions,
+			Timeout:    time.Duration(c.TimeoutSeconds) * time.Second,
+		}
+	}
+	return analyze.NewRunner(a.planDir(), analyzers)
+}
+
+// testGate returns the test gate for test-authoring reviews, or nil when
+// reviewer_tests is disabled.
+func (a *App) testGate() loop.TestGate {
+	if !a.cfg.ReviewerTests.Enabled {
+		return nil
+	}
+	return loop.CommandTestGate{
+		Dir:     a.planDir(),
+		Command: a.cfg.ReviewerTests.Command,
+		Timeout: time.Duration(a.cfg.ReviewerTests.TimeoutSeconds) * time.Second,
+	}
+}
+
+// checks returns the checks run after each developer session.
+func (a *App) checks() []loop.Check {
+	checks := make([]loop.Check, len(a.cfg.Checks))
+	for i, c := range a.cfg.Checks {
+		checks[i] = loop.Check{
+			Name: c.Name,
+			Gate: loop.CommandTestGate{
+				Dir:     a.planDir(),
+				Command: c.Command,
+				Timeout: time.Duration(c.TimeoutSeconds) * time.Second,
+			},
+		}
+	}
+	return checks
+}
+
+// hooks returns the commands run at points of the loop's lifecycle.
+func (a *App) hooks() []loop.Hook {
+	hooks := make([]loop.Hook, len(a.cfg.Hooks))
+	for i, h := range a.cfg.Hooks {
+		hooks[i] = loop.Hook{
+			Name:    h.Name,
+			On:      loop.HookPoint(h.On),
+			Dir:     a.planDir(),
+			Command: h.Command,
+			Timeout: time.Duration(h.TimeoutSeconds) * time.Second,
+		}
+	}
+	return hooks
+}
+
+// trivialChanges returns the rules the review of trivial changes is skipped
+// or downgraded by, or nil when every change gets a full review.
+func (a *App) trivialChanges() *triage.Rules {
+	switch a.cfg.ReviewTriage.Action {
+	case config.ReviewTriageSkip, config.ReviewTriageDowngrade:
+		return &triage.Rules{
+			MaxLines:     a.cfg.ReviewTriage.MaxLines,
+			TrivialPaths: a.cfg.ReviewTriage.TrivialPaths,
+		}
+	}
+	return nil
+}
+
+// selfCheckAttempts returns how many follow-ups may reformat a session's
+// malformed output (0 when self-checking is disabled).
+func (a *App) selfCheckAttempts() int {
+	if !a.cfg.SelfCheck.Enabled {
+		return 0
+	}
+	return a.cfg.SelfCheck.MaxAttempts
+}
+
+// differentialPrompts reports whether developer sessions are resumed with
+// differential prompts. API sessions can't be resumed.
+func (a *App) differentialPrompts() bool {
+	if !a.cfg.DifferentialPrompts.Enabled {
+		return false
+	}
+	if a.cfg.Claude.Backend == config.ClaudeBackendAPI {
+		log.Warn("differential_prompts needs the cli backend, sending full prompts")
+		return false
+	}
+	return true
+}
+
+// resumeClaudeSession reports whether an interrupted developer session's
+// Claude session is resumed when its prompt is sent again. API sessions
+// can't be resumed.
+func (a *App) resumeClaudeSession() bool {
+	if !a.cfg.FastResume.Enabled || !a.cfg.FastResume.ClaudeSession {
+		return false
+	}
+	if a.cfg.Claude.Backend == config.ClaudeBackendAPI {
+		log.Warn("fast_resume.claude_session needs the cli backend, starting new sessions")
+		return false
+	}
+	return true
+}
+
+// quietHours returns the configured quiet hours (the zero value when there
+// are none, or they don't parse).
+func (a *App) quietHours() loop.QuietHours {
+	if !a.cfg.QuietHours.Enabled() {
+		return loop.QuietHours{}
+	}
+	start, end, err := a.cfg.QuietHours.Window()
+	if err != nil {
+		log.Warn("ignoring invalid quiet hours", "error", err)
+		return loop.QuietHours{}
+	}
+	return loop.QuietHours{Start: start, End: end}
+}
+
+// conventions returns the provider for the configured convention files, or
+// nil when none are configured.
+func (a *App) conventions() *conventions.Provider {
+	if len(a.cfg.Conventions.Include) == 0 {
+		return nil
+	}
+	return conventions.NewProvider(a.cfg.Conventions.Include, a.cfg.Conventions.Exclude, a.cfg.Conventions.MaxBytes)
 }
 
 // runLoopHeadless runs the loop without TUI and collects the result.
@@ -278,29 +1143,49 @@ func (a *App) runLoopHeadless(ctx context.Context) *Result {
 
 	// Drain events in background to prevent blocking.
 	// This goroutine exits when loop.Run() completes because
-	// Loop.Run() closes the events channel via defer close(l.events).
+	// Loop.Run() closes the event bus, and with it this channel, on return.
+	events := a.loop.Events()
+	drained := make(chan struct{})
 	go func() {
-		for range a.loop.Events() {
-			// Discard events
+		defer close(drained)
+		for event := range events {
+			if a.eventHandler != nil {
+				a.eventHandler(event)
+			}
 		}
 	}()
 
 	// Run loop
-	loopErr := a.loop.Run(ctx)
+	loopErr := a.runCreatedLoop(ctx)
+	<-drained
+	a.closeNotifier()
+	a.closeTracker()
+	a.sendDigest(ctx, loopErr)
 
 	// Get final iteration count
 	iterations := a.loop.CurrentIteration()
 
 	// Check if completed successfully
-	updatedPlan, _ := a.db.GetPlan(a.plan.ID)
-	completed := updatedPlan != nil && updatedPlan.Status == db.PlanStatusCompleted
+	completed := a.planCompleted()
+
+	result := &Result{
+		PlanID:        a.plan.ID,
+		Completed:     completed,
+		Iterations:    iterations,
+		RunIterations: a.loop.IterationsRun(),
+		Outcome:       runOutcome(a.loop.Outcome(), loopErr),
+		Error:         loopErr,
+	}
 
-	return &Result{
-		PlanID:     a.plan.ID,
-		Completed:  completed,
-		Iterations: iterations,
-		Error:      loopErr,
+	if completed && a.appCfg.CreatePR {
+		prURL, err := a.createPullRequest(ctx)
+		if err != nil && result.Error == nil {
+			result.Error = fmt.Errorf("failed to create pull request: %w", err)
+		}
+		result.PRURL = prURL
 	}
+
+	return result
 }
 
 // runLoop creates and runs the loop with the TUI.
@@ -313,11 +1198,30 @@ func (a *App) runLoop(ctx context.Context) error {
 	a.createLoop()
 
 	// Create TUI with event channel
+	if err := tui.SetTheme(a.theme(), a.appCfg.Accessible || a.cfg.TUI.Accessible); err != nil {
+		return err
+	}
 	model := tui.NewModelWithEvents(a.loop.Events())
 
+	// Keep a copy of the feed on disk for `ralph feed`
+	if a.cfg.TUI.Scrollback {
+		feed, err := scrollback.Open(a.scrollbackPath(), int64(a.cfg.TUI.ScrollbackMaxMB)<<20)
+		if err != nil {
+			log.Warn("feed scrollback disabled", "error", err)
+		} else {
+			defer func() { log.CloseError("feed scrollback", feed.Close()) }()
+			model.SetScrollback(feed)
+		}
+	}
+
 	// Set the plan ID in the header
 	model.SetPlanID(a.plan.ID)
 
+	// Enable history search across all plans
+	model.SetSearch(func(query string, limit int) ([]*db.SearchResult, error) {
+		return a.db.Search(query, "", limit)
+	})
+
 	// Set prompt content (truncated for display)
 	promptPreview := a.plan.Content
 	if len(promptPreview) > 2000 {
@@ -325,8 +1229,12 @@ func (a *App) runLoop(ctx context.Context) error {
 	}
 	model.SetPrompt(promptPreview)
 
-	// Create the Bubble Tea program
-	p := tea.NewProgram(model, tea.WithAltScreen())
+	// Create the Bubble Tea program; stdin is spent when the plan was piped in
+	opts := []tea.ProgramOption{tea.WithAltScreen()}
+	if a.appCfg.PromptFromStdin {
+		opts = append(opts, tea.WithInputTTY())
+	}
+	p := tea.NewProgram(model, opts...)
 
 	// Channel for loop completion
 	loopDone := make(chan error, 1)
@@ -336,7 +1244,7 @@ func (a *App) runLoop(ctx context.Context) error {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		err := a.loop.Run(loopCtx)
+		err := a.runCreatedLoop(loopCtx)
 		loopDone <- err
 	}()
 
@@ -348,9 +1256,12 @@ func (a *App) runLoop(ctx context.Context) error {
 
 	// Wait for loop to finish
 	wg.Wait()
+	a.closeNotifier()
+	a.closeTracker()
 
 	// Get loop error (guaranteed to be available after wg.Wait())
 	loopErr := <-loopDone
+	a.sendDigest(ctx, loopErr)
 
 	// Return TUI error first (user quit), then loop error
 	if tuiErr != nil {
@@ -362,6 +1273,15 @@ func (a *App) runLoop(ctx context.Context) error {
 		return loopErr
 	}
 
+	// The TUI has exited, so the pull request URL goes to stdout
+	if a.appCfg.CreatePR && a.planCompleted() {
+		prURL, err := a.createPullRequest(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to create pull request: %w", err)
+		}
+		fmt.Printf("Opened pull request: %s\n", prURL)
+	}
+
 	return nil
 }
 
@@ -375,6 +1295,55 @@ func (a *App) SetJJClient(client *jj.Client) {
 	a.jjOverride = client
 }
 
+// SetEventHandler makes headless runs pass each loop event to handler
+// instead of discarding it. Events are delivered in order from a single
+// goroutine, which handler should not block for long.
+func (a *App) SetEventHandler(handler func(loop.Event)) {
+	a.eventHandler = handler
+}
+
+// SubmitFeedback passes feedback from the user to the developer in its next
+// prompt. It is only valid while the loop runs, i.e. after the first event
+// reached the event handler.
+func (a *App) SubmitFeedback(feedback string) error {
+	l, _ := a.running()
+	if l == nil {
+		return errors.New("plan is not running")
+	}
+	l.AddUserFeedback(feedback)
+	return nil
+}
+
+// Pause asks the running plan to pause once its current iteration
+// finishes. The plan is left paused, so it can be resumed, and the run
+// returns without error.
+func (a *App) Pause() error {
+	l, _ := a.running()
+	if l == nil {
+		return errors.New("plan is not running")
+	}
+	l.Pause()
+	return nil
+}
+
+// Stop interrupts the running plan, abandoning its current iteration, and
+// waits until the plan has been left paused and the run has returned, or
+// until ctx is done. The run returns without error, and the plan can be
+// resumed.
+func (a *App) Stop(ctx context.Context) error {
+	l, done := a.running()
+	if l == nil {
+		return errors.New("plan is not running")
+	}
+	l.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // PlanID returns the current plan ID, or empty string if not set.
 func (a *App) PlanID() string {
 	if a.plan != nil {
@@ -385,10 +1354,13 @@ func (a *App) PlanID() string {
 
 // Result holds the result of a completed execution.
 type Result struct {
-	PlanID     string
-	Completed  bool
-	Iterations int
-	Error      error
+	PlanID        string
+	Completed     bool
+	Iterations    int
+	RunIterations int          // Iterations worked by this run
+	PRURL         string       // Pull request opened after completion (empty if none)
+	Outcome       loop.Outcome // How the run ended
+	Error         error
 }
 
 // RunHeadless runs the loop without TUI, useful for scripting.
@@ -400,10 +1372,34 @@ func (a *App) RunHeadless(ctx context.Context, planPath string) (*Result, error)
 	}
 	defer a.cleanup()
 
-	// Create plan from file
-	if err := a.createPlanFromFile(planPath); err != nil {
+	// Resume the plan already started from the file, or create one
+	if err := a.resumeOrCreatePlan(planPath); err != nil {
+		return nil, err
+	}
+	if err := a.enterWorkspace(ctx); err != nil {
+		return nil, err
+	}
+	defer a.releaseWorkspace(ctx)
+
+	return a.runLoopHeadless(ctx), nil
+}
+
+// RunWithPromptHeadless runs a plan from an inline prompt without TUI.
+func (a *App) RunWithPromptHeadless(ctx context.Context, prompt string) (*Result, error) {
+	// Initialize dependencies
+	if err := a.initDependencies(); err != nil {
+		return nil, err
+	}
+	defer a.cleanup()
+
+	// Create plan from prompt string
+	if err := a.createPlanFromPrompt(prompt); err != nil {
+		return nil, err
+	}
+	if err := a.enterWorkspace(ctx); err != nil {
 		return nil, err
 	}
+	defer a.releaseWorkspace(ctx)
 
 	return a.runLoopHeadless(ctx), nil
 }
@@ -420,6 +1416,13 @@ func (a *App) ResumeHeadless(ctx context.Context, planID string) (*Result, error
 	if err := a.loadPlan(planID); err != nil {
 		return nil, err
 	}
+	if err := a.enterWorkspace(ctx); err != nil {
+		return nil, err
+	}
+	defer a.releaseWorkspace(ctx)
+	if err := a.rewindPlan(ctx); err != nil {
+		return nil, err
+	}
 
 	return a.runLoopHeadless(ctx), nil
 }
@@ -3,13 +3,19 @@ package app
 import (
 	"context"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gerunddev/ralph/internal/claude"
 	"github.com/gerunddev/ralph/internal/db"
 	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/loop"
+	"github.com/gerunddev/ralph/internal/snapshot"
 )
 
 func TestNew(t *testing.T) {
@@ -159,6 +165,46 @@ func TestApp_Resume_PlanNotFound(t *testing.T) {
 	}
 }
 
+func TestApp_LoadPlan_ChecksWorkDir(t *testing.T) {
+	projectsDir := t.TempDir()
+	repoDir := t.TempDir()
+	otherDir := t.TempDir()
+
+	newApp := func(cfg Config) *App {
+		t.Helper()
+		app, err := New(cfg)
+		if err != nil {
+			t.Fatalf("New() error: %v", err)
+		}
+		app.cfg.ProjectsDir = projectsDir
+		if err := app.initDependencies(); err != nil {
+			t.Fatalf("initDependencies() error: %v", err)
+		}
+		t.Cleanup(app.cleanup)
+		return app
+	}
+
+	creator := newApp(Config{WorkDir: repoDir})
+	if err := creator.createPlanFromPrompt("Fix the bug"); err != nil {
+		t.Fatalf("createPlanFromPrompt() error: %v", err)
+	}
+	planID := creator.plan.ID
+	if creator.plan.WorkDir != repoDir {
+		t.Errorf("plan WorkDir = %q, want %q", creator.plan.WorkDir, repoDir)
+	}
+
+	if err := newApp(Config{WorkDir: repoDir}).loadPlan(planID); err != nil {
+		t.Errorf("loadPlan() in the original directory error: %v", err)
+	}
+	err := newApp(Config{WorkDir: otherDir}).loadPlan(planID)
+	if err == nil || !strings.Contains(err.Error(), "--workdir") {
+		t.Errorf("loadPlan() in another directory = %v, want a --workdir error", err)
+	}
+	if err := newApp(Config{WorkDir: otherDir, WorkDirOverride: true}).loadPlan(planID); err != nil {
+		t.Errorf("loadPlan() with an explicit override error: %v", err)
+	}
+}
+
 func TestConfig_DefaultWorkDir(t *testing.T) {
 	cfg := Config{}
 	if cfg.WorkDir != "" {
@@ -235,6 +281,170 @@ func TestApp_InitDependencies_CreatesDatabase(t *testing.T) {
 	}
 }
 
+// TestApp_InitDependencies_NoVCS verifies that NoVCS tracks the working
+// directory with snapshots kept under the projects directory.
+func TestApp_InitDependencies_NoVCS(t *testing.T) {
+	workDir, projectsDir := t.TempDir(), t.TempDir()
+
+	app, err := New(Config{WorkDir: workDir, NoVCS: true})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	app.cfg.ProjectsDir = projectsDir
+	if err := app.initDependencies(); err != nil {
+		t.Fatalf("initDependencies() error: %v", err)
+	}
+	defer app.cleanup()
+
+	if _, ok := app.vcs.(*snapshot.Client); !ok {
+		t.Fatalf("expected a snapshot client, got %T", app.vcs)
+	}
+	if _, err := app.vcs.GetParentChangeID(context.Background()); err != nil {
+		t.Fatalf("GetParentChangeID() error: %v", err)
+	}
+	if entries, err := os.ReadDir(workDir); err != nil || len(entries) != 0 {
+		t.Errorf("expected the working directory untouched, got %v, %v", entries, err)
+	}
+	if _, err := os.Stat(snapshotDir(projectsDir, workDir)); err != nil {
+		t.Errorf("expected snapshots under the projects directory: %v", err)
+	}
+}
+
+// TestApp_InitDependencies_InvalidRoleOptions verifies that conflicting
+// per-role Claude options are rejected before anything runs.
+func TestApp_InitDependencies_InvalidRoleOptions(t *testing.T) {
+	tempDir := t.TempDir()
+
+	app, err := New(Config{WorkDir: tempDir})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	app.cfg.ProjectsDir = tempDir
+	app.cfg.Claude.Reviewer.ExtraArgs = []string{"--output-format", "text"}
+
+	err = app.initDependencies()
+	defer app.cleanup()
+	if err == nil || !strings.Contains(err.Error(), "claude.reviewer") {
+		t.Errorf("expected claude.reviewer validation error, got: %v", err)
+	}
+}
+
+// TestApp_InitDependencies_ReplayFixtures verifies that a missing fixture
+// directory is reported before anything runs, and that the clients replay
+// from an existing one.
+func TestApp_InitDependencies_ReplayFixtures(t *testing.T) {
+	tempDir := t.TempDir()
+
+	app, err := New(Config{WorkDir: tempDir, ReplayFixtures: filepath.Join(tempDir, "missing")})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	app.cfg.ProjectsDir = tempDir
+	err = app.initDependencies()
+	app.cleanup()
+	if err == nil || !strings.Contains(err.Error(), "fixture directory") {
+		t.Errorf("expected a fixture directory error, got: %v", err)
+	}
+
+	app, err = New(Config{WorkDir: tempDir, ReplayFixtures: tempDir})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	app.cfg.ProjectsDir = tempDir
+	if err := app.initDependencies(); err != nil {
+		t.Fatalf("initDependencies() error: %v", err)
+	}
+	defer app.cleanup()
+	if app.fixtures == nil {
+		t.Error("expected the clients to replay fixtures")
+	}
+}
+
+// TestApp_CreatePlanFromFile_Env verifies that the plan's front matter env
+// and --env reach the Claude clients, and that a missing host variable fails
+// before the plan is stored.
+func TestApp_CreatePlanFromFile_Env(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("RALPH_TEST_BILLING_TOKEN", "s3cret")
+	planPath := filepath.Join(tempDir, "plan.md")
+	plan := "---\nenv:\n  API_TOKEN: ${RALPH_TEST_BILLING_TOKEN}\n  API_URL: https://staging.example.com\n---\n# Billing\n"
+	if err := os.WriteFile(planPath, []byte(plan), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	app, err := New(Config{WorkDir: tempDir, Env: []string{"API_URL=http://localhost:8080"}})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	app.cfg.ProjectsDir = tempDir
+	if err := app.initDependencies(); err != nil {
+		t.Fatalf("initDependencies() error: %v", err)
+	}
+	defer app.cleanup()
+
+	if err := app.createPlanFromFile(planPath); err != nil {
+		t.Fatalf("createPlanFromFile() error: %v", err)
+	}
+	want := []string{"API_TOKEN=s3cret", "API_URL=http://localhost:8080"}
+	if got := app.claudeConfig(app.cfg.Claude.Reviewer).EnvVars; !slices.Equal(got, want) {
+		t.Errorf("claude EnvVars = %v, want %v", got, want)
+	}
+	if strings.Contains(app.plan.Content, "s3cret") {
+		t.Error("expected the secret to stay out of the stored plan")
+	}
+
+	missing := filepath.Join(tempDir, "missing.md")
+	if err := os.WriteFile(missing, []byte("---\nenv:\n  TOKEN: $RALPH_TEST_UNSET_VAR\n---\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.createPlanFromFile(missing); err == nil || !strings.Contains(err.Error(), "RALPH_TEST_UNSET_VAR") {
+		t.Errorf("expected an unset host variable error, got: %v", err)
+	}
+	plans, err := app.db.ListPlans(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Errorf("expected only the first plan to be stored, got %d", len(plans))
+	}
+}
+
+// TestApp_CreatePlanFromFile_Policy verifies that the plan's front matter
+// permissions override the permissions config for that plan only.
+func TestApp_CreatePlanFromFile_Policy(t *testing.T) {
+	tempDir := t.TempDir()
+	planPath := filepath.Join(tempDir, "plan.md")
+	plan := "---\nallowed_paths: [internal/billing/]\nnetwork: false\n---\n# Billing\n"
+	if err := os.WriteFile(planPath, []byte(plan), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	app, err := New(Config{WorkDir: tempDir})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	app.cfg.ProjectsDir = tempDir
+	app.cfg.Permissions.ForbiddenCommands = []string{"rm"}
+	if err := app.initDependencies(); err != nil {
+		t.Fatalf("initDependencies() error: %v", err)
+	}
+	defer app.cleanup()
+
+	if err := app.createPlanFromFile(planPath); err != nil {
+		t.Fatalf("createPlanFromFile() error: %v", err)
+	}
+	if got := app.policy().AllowedPaths; !slices.Equal(got, []string{"internal/billing/"}) {
+		t.Errorf("AllowedPaths = %v, want the plan's", got)
+	}
+	want := []string{"Bash(rm:*)", "WebFetch", "WebSearch"}
+	if got := app.claudeConfig(app.cfg.Claude.Reviewer).DisallowedTools; !slices.Equal(got, want) {
+		t.Errorf("claude DisallowedTools = %v, want %v", got, want)
+	}
+	if app.cfg.Permissions.AllowedPaths != nil || !app.cfg.Permissions.Network {
+		t.Errorf("permissions config changed: %+v", app.cfg.Permissions)
+	}
+}
+
 // TestApp_InitDependencies_UsesOverrides verifies that overrides are used when set.
 func TestApp_InitDependencies_UsesOverrides(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "ralph-app-test-*")
@@ -489,6 +699,202 @@ func TestApp_CreatePlanFromFile(t *testing.T) {
 			t.Errorf("Expected absolute origin path for relative input, got %s", app.plan.OriginPath)
 		}
 	})
+
+	t.Run("edited content override", func(t *testing.T) {
+		planPath := filepath.Join(tempDir, "edited-plan.md")
+		if err := os.WriteFile(planPath, []byte("# Original"), 0644); err != nil {
+			t.Fatalf("Failed to write plan file: %v", err)
+		}
+
+		app.appCfg.PlanContent = "# Edited"
+		defer func() { app.appCfg.PlanContent = "" }()
+
+		if err := app.createPlanFromFile(planPath); err != nil {
+			t.Fatalf("createPlanFromFile() error: %v", err)
+		}
+		if app.plan.Content != "# Edited" {
+			t.Errorf("Expected edited content to be stored, got %q", app.plan.Content)
+		}
+		if app.plan.OriginPath != planPath {
+			t.Errorf("Expected origin path %s, got %s", planPath, app.plan.OriginPath)
+		}
+		// The snapshot is of the file, so only later edits to it are reported
+		if app.plan.OriginHash != loop.HashPlanFile("# Original") {
+			t.Errorf("Expected origin hash of the file content, got %q", app.plan.OriginHash)
+		}
+	})
+}
+
+// TestApp_UnfinishedPlan verifies that a plan file already started as a
+// plan that isn't finished is resumed rather than created again.
+func TestApp_UnfinishedPlan(t *testing.T) {
+	tempDir := t.TempDir()
+	app, err := New(Config{WorkDir: tempDir})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	app.cfg.ProjectsDir = tempDir
+	if err := app.initDependencies(); err != nil {
+		t.Fatalf("initDependencies() error: %v", err)
+	}
+	defer app.cleanup()
+
+	planPath := filepath.Join(tempDir, "plan.md")
+	if err := os.WriteFile(planPath, []byte("# Plan"), 0644); err != nil {
+		t.Fatalf("Failed to write plan file: %v", err)
+	}
+
+	if existing, err := app.unfinishedPlan(planPath); err != nil || existing != nil {
+		t.Fatalf("unfinishedPlan() = %v, %v; want none before the plan is created", existing, err)
+	}
+	if err := app.createPlanFromFile(planPath); err != nil {
+		t.Fatalf("createPlanFromFile() error: %v", err)
+	}
+	created := app.plan.ID
+
+	var asked string
+	app.appCfg.ConfirmResume = func(plan *db.Plan) bool {
+		asked = plan.ID
+		return true
+	}
+	if existing, err := app.unfinishedPlan(planPath); err != nil || existing == nil || existing.ID != created {
+		t.Fatalf("unfinishedPlan() = %v, %v; want plan %s", existing, err, created)
+	}
+	if asked != created {
+		t.Errorf("ConfirmResume asked about %q, want %s", asked, created)
+	}
+
+	// Declined, forced, or edited before being stored, a new plan is created
+	app.appCfg.ConfirmResume = func(*db.Plan) bool { return false }
+	if existing, err := app.unfinishedPlan(planPath); err != nil || existing != nil {
+		t.Errorf("unfinishedPlan() = %v, %v; want none when declined", existing, err)
+	}
+	app.appCfg.ConfirmResume = nil
+	app.appCfg.ForceNew = true
+	if existing, err := app.unfinishedPlan(planPath); err != nil || existing != nil {
+		t.Errorf("unfinishedPlan() = %v, %v; want none with ForceNew", existing, err)
+	}
+	app.appCfg.ForceNew = false
+	app.appCfg.PlanContent = "# Edited"
+	if existing, err := app.unfinishedPlan(planPath); err != nil || existing != nil {
+		t.Errorf("unfinishedPlan() = %v, %v; want none for an edited plan", existing, err)
+	}
+	app.appCfg.PlanContent = ""
+
+	// A completed plan, or a changed file, isn't resumed
+	if err := os.WriteFile(planPath, []byte("# Plan, revised"), 0644); err != nil {
+		t.Fatalf("Failed to write plan file: %v", err)
+	}
+	if existing, err := app.unfinishedPlan(planPath); err != nil || existing != nil {
+		t.Errorf("unfinishedPlan() = %v, %v; want none once the file changed", existing, err)
+	}
+	if err := os.WriteFile(planPath, []byte("# Plan"), 0644); err != nil {
+		t.Fatalf("Failed to write plan file: %v", err)
+	}
+
+	// A running plan is resumed only once its process is gone
+	if err := app.db.UpdatePlanStatus(created, db.PlanStatusRunning); err != nil {
+		t.Fatal(err)
+	}
+	endRun := app.startRun()
+	if existing, err := app.unfinishedPlan(planPath); err != nil || existing != nil {
+		t.Errorf("unfinishedPlan() = %v, %v; want none while this process runs it", existing, err)
+	}
+	endRun()
+	if existing, err := app.unfinishedPlan(planPath); err != nil || existing == nil || existing.ID != created {
+		t.Errorf("unfinishedPlan() = %v, %v; want the running plan with no process", existing, err)
+	}
+	host, _ := os.Hostname()
+	if err := app.db.StartPlanRun(&db.PlanRun{PlanID: created, Host: host, PID: deadPID(t)}); err != nil {
+		t.Fatal(err)
+	}
+	if existing, err := app.unfinishedPlan(planPath); err != nil || existing == nil || existing.ID != created {
+		t.Errorf("unfinishedPlan() = %v, %v; want the running plan whose process exited", existing, err)
+	}
+
+	if err := app.db.UpdatePlanStatus(created, db.PlanStatusCompleted); err != nil {
+		t.Fatal(err)
+	}
+	if existing, err := app.unfinishedPlan(planPath); err != nil || existing != nil {
+		t.Errorf("unfinishedPlan() = %v, %v; want none once the plan completed", existing, err)
+	}
+}
+
+// deadPID returns the ID of a process that has exited.
+func deadPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("cannot run a process: %v", err)
+	}
+	return cmd.Process.Pid
+}
+
+// TestApp_Stop verifies that Stop interrupts a headless run and leaves the
+// plan paused.
+func TestApp_Stop(t *testing.T) {
+	tempDir := t.TempDir()
+	planPath := filepath.Join(tempDir, "plan.md")
+	if err := os.WriteFile(planPath, []byte("# Test Plan"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	app, err := New(Config{WorkDir: tempDir})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	app.cfg.ProjectsDir = tempDir
+
+	if err := app.Pause(); err == nil {
+		t.Error("expected Pause to fail before the plan runs")
+	}
+	if err := app.Stop(context.Background()); err == nil {
+		t.Error("expected Stop to fail before the plan runs")
+	}
+
+	// Sessions run until they are interrupted
+	mockClaude := claude.NewClient(claude.ClientConfig{Model: "mock"})
+	mockClaude.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "sleep", "10")
+	})
+	mockJJ := jj.NewClient(tempDir)
+	mockJJ.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		return "", "", nil
+	})
+	app.SetClaudeClient(mockClaude)
+	app.SetJJClient(mockJJ)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stopped := make(chan error, 1)
+	var once sync.Once
+	app.SetEventHandler(func(loop.Event) {
+		once.Do(func() { go func() { stopped <- app.Stop(ctx) }() })
+	})
+
+	result, err := app.RunHeadless(ctx, planPath)
+	if err != nil {
+		t.Fatalf("RunHeadless() error: %v", err)
+	}
+	if err := <-stopped; err != nil {
+		t.Errorf("Stop() error: %v", err)
+	}
+	if result.Error != nil || result.Completed {
+		t.Errorf("expected an incomplete run without error, got %+v", result)
+	}
+
+	database, err := db.New(db.PlansDBPath(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	plan, err := database.GetPlan(result.PlanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Status != db.PlanStatusPaused || plan.FailureReason != "Stopped by request" {
+		t.Errorf("expected the plan paused by the stop, got %s (%q)", plan.Status, plan.FailureReason)
+	}
 }
 
 // TestApp_ContextCancellation verifies that context cancellation is handled.
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+// runPlan runs the loop with the TUI, or without it in batch mode
+// (Config.IterationsThisRun), printing a summary once the run's iterations
+// are done. Either way it ends with the outcome line, and returns an
+// ExitError when the outcome calls for a non-zero exit code.
+func (a *App) runPlan(ctx context.Context) error {
+	defer a.startRun()()
+
+	if a.appCfg.IterationsThisRun <= 0 {
+		err := a.runLoop(ctx)
+		return a.finishRun(os.Stdout, err)
+	}
+
+	result := a.runLoopHeadless(ctx)
+	if result.Error != nil {
+		return a.finishRun(os.Stdout, result.Error)
+	}
+	plan, err := a.db.GetPlan(a.plan.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get plan: %w", err)
+	}
+	writeBatchSummary(os.Stdout, plan, result, a.cfg.MaxIterations, a.appCfg.IterationsThisRun)
+	return a.finishRun(os.Stdout, nil)
+}
+
+// ExitError is returned by a run whose outcome calls for a non-zero exit
+// code (see loop.Outcome.ExitCode). Err is the run's own error, if any.
+type ExitError struct {
+	Outcome loop.Outcome
+	Err     error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("plan ended with outcome %s", e.Outcome)
+}
+
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// ExitCode returns the exit code of the outcome.
+func (e *ExitError) ExitCode() int { return e.Outcome.ExitCode() }
+
+// finishRun writes the outcome line of a run whose loop ran, and returns
+// the run's error, or an ExitError when the outcome calls for a non-zero
+// exit code.
+func (a *App) finishRun(w io.Writer, runErr error) error {
+	l, _ := a.running()
+	if l == nil {
+		return runErr
+	}
+	outcome := runOutcome(l.Outcome(), runErr)
+
+	var reason string
+	if plan, err := a.db.GetPlan(a.plan.ID); err == nil {
+		reason = plan.FailureReason
+	}
+	writeOutcomeLine(w, a.plan.ID, outcome, l.CurrentIteration(), reason)
+
+	if outcome.ExitCode() == 0 {
+		return runErr
+	}
+	return &ExitError{Outcome: outcome, Err: runErr}
+}
+
+// runOutcome returns the outcome of a run, treating a run that failed before
+// the loop recorded one as failed.
+func runOutcome(outcome loop.Outcome, runErr error) loop.Outcome {
+	if outcome == "" {
+		if runErr != nil {
+			return loop.OutcomeFailed
+		}
+		return loop.OutcomePaused
+	}
+	return outcome
+}
+
+// writeOutcomeLine writes the machine-readable summary of how a run ended,
+// as logfmt key=value pairs:
+//
+//	ralph_outcome=max_iterations exit_code=2 plan_id=... iterations=10 reason="Reached max iterations (10)"
+func writeOutcomeLine(w io.Writer, planID string, outcome loop.Outcome, iterations int, reason string) {
+	_, _ = fmt.Fprintf(w, "ralph_outcome=%s exit_code=%d plan_id=%s iterations=%d reason=%q\n",
+		outcome, outcome.ExitCode(), planID, iterations, reason)
+}
+
+// writeBatchSummary reports what a batch run did and how the plan was
+// left, with the command that continues it.
+func writeBatchSummary(w io.Writer, plan *db.Plan, result *Result, maxIterations, perRun int) {
+	_, _ = fmt.Fprintf(w, "Plan %s: %d iterations this run, at iteration %d of %d\n",
+		plan.ID, result.RunIterations, result.Iterations, maxIterations)
+	switch plan.Status {
+	case db.PlanStatusCompleted:
+		_, _ = fmt.Fprintln(w, "Status: completed")
+		if result.PRURL != "" {
+			_, _ = fmt.Fprintf(w, "Opened pull request: %s\n", result.PRURL)
+		}
+	case db.PlanStatusPaused:
+		_, _ = fmt.Fprintln(w, "Status: paused")
+		_, _ = fmt.Fprintf(w, "Continue with: ralph --resume %s --iterations-this-run %d\n", plan.ID, perRun)
+	default:
+		_, _ = fmt.Fprintf(w, "Status: %s", plan.Status)
+		if plan.FailureReason != "" {
+			_, _ = fmt.Fprintf(w, " (%s)", plan.FailureReason)
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+func TestWriteBatchSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		plan *db.Plan
+		want []string
+	}{
+		{
+			name: "paused",
+			plan: &db.Plan{ID: "plan-1", Status: db.PlanStatusPaused},
+			want: []string{"2 iterations this run, at iteration 4 of 10", "Status: paused", "ralph --resume plan-1 --iterations-this-run 2"},
+		},
+		{
+			name: "completed",
+			plan: &db.Plan{ID: "plan-1", Status: db.PlanStatusCompleted},
+			want: []string{"Status: completed", "Opened pull request: https://example.com/pr/1"},
+		},
+		{
+			name: "stopped",
+			plan: &db.Plan{ID: "plan-1", Status: db.PlanStatusStopped, FailureReason: "Reached max iterations (10)"},
+			want: []string{"Status: stopped (Reached max iterations (10))"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &Result{PlanID: "plan-1", Iterations: 4, RunIterations: 2}
+			if tt.plan.Status == db.PlanStatusCompleted {
+				result.PRURL = "https://example.com/pr/1"
+			}
+
+			var out bytes.Buffer
+			writeBatchSummary(&out, tt.plan, result, 10, 2)
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("summary missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestWriteOutcomeLine(t *testing.T) {
+	var out bytes.Buffer
+	writeOutcomeLine(&out, "plan-1", loop.OutcomeMaxIterations, 10, "Reached max iterations (10)")
+	want := "ralph_outcome=max_iterations exit_code=2 plan_id=plan-1 iterations=10 reason=\"Reached max iterations (10)\"\n"
+	if out.String() != want {
+		t.Errorf("outcome line = %q, want %q", out.String(), want)
+	}
+}
+
+func TestRunOutcome(t *testing.T) {
+	if got := runOutcome(loop.OutcomeNotApproved, nil); got != loop.OutcomeNotApproved {
+		t.Errorf("runOutcome() = %q, want the loop's outcome", got)
+	}
+	if got := runOutcome("", errors.New("failed to load plan")); got != loop.OutcomeFailed {
+		t.Errorf("runOutcome() = %q, want failed for a run that never started", got)
+	}
+	if got := runOutcome("", nil); got != loop.OutcomePaused {
+		t.Errorf("runOutcome() = %q, want paused", got)
+	}
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/notify"
+)
+
+// sendDigest emails a digest of the run when notify.email is configured and
+// the plan completed or failed. Failures are logged; a digest never fails a
+// run.
+func (a *App) sendDigest(ctx context.Context, loopErr error) {
+	cfg := a.cfg.Notify.Email
+	if cfg.SMTPHost == "" {
+		return
+	}
+	plan, err := a.db.GetPlan(a.plan.ID)
+	if err != nil {
+		log.Warn("failed to get plan for email digest", "plan", a.plan.ID, "error", err)
+		return
+	}
+	if plan.Status != db.PlanStatusCompleted && plan.Status != db.PlanStatusFailed {
+		return
+	}
+
+	digest, err := a.buildDigest(plan, loopErr)
+	if err != nil {
+		log.Warn("failed to build email digest", "plan", plan.ID, "error", err)
+		return
+	}
+	err = notify.SendDigest(ctx, notify.EmailConfig{
+		Host:      cfg.SMTPHost,
+		Port:      cfg.SMTPPort,
+		Username:  cfg.Username,
+		Password:  cfg.Password(),
+		From:      cfg.From,
+		To:        cfg.To,
+		TLS:       cfg.TLS,
+		ReportURL: cfg.ReportURL,
+	}, digest)
+	if err != nil {
+		log.Warn("failed to send email digest", "plan", plan.ID, "error", err)
+		return
+	}
+	log.Info("sent email digest", "plan", plan.ID, "to", cfg.To)
+}
+
+// buildDigest summarizes a finished plan: its final progress, iterations,
+// and Claude cost.
+func (a *App) buildDigest(plan *db.Plan, loopErr error) (notify.Digest, error) {
+	digest := notify.Digest{
+		PlanID: plan.ID,
+		Status: string(plan.Status),
+	}
+	if a.loop != nil {
+		digest.Iterations = a.loop.CurrentIteration()
+	}
+	if loopErr != nil {
+		digest.Error = loopErr.Error()
+	}
+
+	progress, err := a.db.GetLatestProgress(plan.ID)
+	if err != nil {
+		return digest, fmt.Errorf("failed to get progress: %w", err)
+	}
+	if progress != nil {
+		digest.Progress = progress.Content
+	}
+	if digest.CostUSD, err = planCost(a.db, plan.ID); err != nil {
+		return digest, err
+	}
+
+	// The digest leaves the machine, so secrets are masked as in events
+	if a.redactor != nil {
+		digest.Progress = a.redactor.String(digest.Progress)
+		digest.Error = a.redactor.String(digest.Error)
+	}
+	return digest, nil
+}
+
+// planCost sums the Claude cost reported by the result events of every
+// session of a plan.
+func planCost(database *db.DB, planID string) (float64, error) {
+	sessions, err := database.GetPlanSessionsByPlan(planID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	var total float64
+	for _, session := range sessions {
+		events, err := database.GetEventsBySession(session.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get events for session %s: %w", session.ID, err)
+		}
+		for _, e := range events {
+			if e.EventType != string(claude.EventResult) {
+				continue
+			}
+			event, err := claude.NewParser(strings.NewReader(e.RawJSON)).Next()
+			if err != nil || event.Result == nil {
+				continue
+			}
+			total += event.Result.CostUSD
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/mcp"
+)
+
+// mcpToolsRule is the permission rule allowing every tool of ralph's MCP
+// server without a prompt.
+const mcpToolsRule = "mcp__" + mcp.ServerName
+
+// newMCPConfig returns the --mcp-config that serves ralph's tools to
+// sessions by running `ralph mcp`: the ralph_status tool, and the plan's
+// state tools once the plan is known. It returns "" when neither is
+// enabled. API sessions can't call tools, so they go without.
+func (a *App) newMCPConfig() string {
+	claudeCfg := a.cfg.Claude
+	if claudeCfg.StatusReporting != config.StatusReportingTool && !claudeCfg.StateTools {
+		return ""
+	}
+	if claudeCfg.Backend == config.ClaudeBackendAPI {
+		log.Warn("claude.status_reporting \"tool\" and claude.state_tools need the cli backend, ignoring them")
+		return ""
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		log.Warn("failed to find the ralph executable to serve its tools", "error", err)
+		return ""
+	}
+
+	args := []string{"mcp"}
+	if claudeCfg.StateTools && a.plan != nil {
+		args = append(args, "--plan", a.plan.ID)
+	}
+	data, err := json.Marshal(map[string]any{
+		"mcpServers": map[string]any{
+			mcp.ServerName: map[string]any{"command": executable, "args": args},
+		},
+	})
+	if err != nil {
+		log.Warn("failed to encode the MCP config for ralph's tools", "error", err)
+		return ""
+	}
+	return string(data)
+}
+
+// refreshMCPConfig gives the developer and reviewer clients the plan's
+// state tools once the plan is loaded.
+func (a *App) refreshMCPConfig() {
+	if !a.cfg.Claude.StateTools || a.claudeOverride != nil || a.mcpConfig == "" {
+		return
+	}
+	a.mcpConfig = a.newMCPConfig()
+	a.claude.SetMCPConfig(a.mcpConfig)
+	a.reviewerClaude.SetMCPConfig(a.mcpConfig)
+}
+
+// statusTool reports whether agents report status with the ralph_status
+// tool.
+func (a *App) statusTool() bool {
+	return a.mcpConfig != "" && a.cfg.Claude.StatusReporting == config.StatusReportingTool
+}
+
+// stateTools reports whether sessions have the plan's state tools.
+func (a *App) stateTools() bool {
+	return a.mcpConfig != "" && a.cfg.Claude.StateTools && a.plan != nil
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+func TestApp_MCPConfig(t *testing.T) {
+	app, err := New(Config{WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if got := app.newMCPConfig(); got != "" {
+		t.Errorf("expected no MCP config for markdown status reporting, got %q", got)
+	}
+
+	app.cfg.Claude.StatusReporting = config.StatusReportingTool
+	app.cfg.Claude.Backend = config.ClaudeBackendAPI
+	if got := app.newMCPConfig(); got != "" {
+		t.Errorf("expected no MCP config for the api backend, got %q", got)
+	}
+
+	app.cfg.Claude.Backend = ""
+	app.mcpConfig = app.newMCPConfig()
+	if !strings.Contains(app.mcpConfig, `"mcpServers":{"ralph":{"args":["mcp"]`) {
+		t.Fatalf("unexpected MCP config: %s", app.mcpConfig)
+	}
+	if !app.statusTool() || app.stateTools() {
+		t.Errorf("statusTool() = %v, stateTools() = %v, want only the status tool", app.statusTool(), app.stateTools())
+	}
+
+	role := config.ClaudeRoleConfig{AllowedTools: []string{"Read"}}
+	cfg := app.claudeConfig(role)
+	if cfg.MCPConfig != app.mcpConfig || !slices.Equal(cfg.AllowedTools, []string{"Read", "mcp__ralph"}) {
+		t.Errorf("unexpected client config: MCPConfig %q, AllowedTools %v", cfg.MCPConfig, cfg.AllowedTools)
+	}
+	if len(role.AllowedTools) != 1 {
+		t.Errorf("the role's allowed tools were modified: %v", role.AllowedTools)
+	}
+
+	// The state tools are served for the plan once it is known
+	app.cfg.Claude.StatusReporting = ""
+	app.cfg.Claude.StateTools = true
+	app.plan = &db.Plan{ID: "plan-1"}
+	if got := app.newMCPConfig(); !strings.Contains(got, `"args":["mcp","--plan","plan-1"]`) {
+		t.Errorf("unexpected MCP config with state tools: %s", got)
+	}
+	if app.statusTool() || !app.stateTools() {
+		t.Errorf("statusTool() = %v, stateTools() = %v, want only the state tools", app.statusTool(), app.stateTools())
+	}
+}
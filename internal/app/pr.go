@@ -0,0 +1,180 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/forge"
+)
+
+// prBookmarkPrefix namespaces the bookmarks pushed for pull requests.
+const prBookmarkPrefix = "ralph/"
+
+// maxBookmarkSlugLength caps the part of a plan bookmark taken from the
+// plan's title.
+const maxBookmarkSlugLength = 40
+
+// maxPRPlanLength caps the plan text included in a pull request body.
+const maxPRPlanLength = 4000
+
+// createPullRequest pushes the completed plan's change to a bookmark and
+// opens a pull request for it, returning the pull request URL.
+func (a *App) createPullRequest(ctx context.Context) (string, error) {
+	client, err := a.forgeClient()
+	if err != nil {
+		return "", err
+	}
+
+	title := prTitle(a.plan)
+
+	// The loop can leave an empty working-copy change on top of the work
+	revision := "@"
+	if empty, err := a.jj.IsEmpty(ctx); err == nil && empty {
+		revision = "@-"
+	}
+
+	// jj refuses to push changes without a description
+	description, err := a.jj.GetDescription(ctx, revision)
+	if err != nil {
+		return "", fmt.Errorf("failed to read change description: %w", err)
+	}
+	if strings.TrimSpace(description) == "" {
+		if err := a.jj.DescribeRevision(ctx, revision, title); err != nil {
+			return "", fmt.Errorf("failed to describe change: %w", err)
+		}
+	}
+
+	bookmark := a.planBookmark()
+	if bookmark == "" {
+		bookmark = prBookmark(a.plan.ID)
+	}
+	if err := a.jj.GitPush(ctx, bookmark, revision); err != nil {
+		return "", fmt.Errorf("failed to push bookmark %s: %w", bookmark, err)
+	}
+
+	body, err := a.prBody()
+	if err != nil {
+		return "", err
+	}
+
+	return client.CreatePullRequest(ctx, forge.PullRequest{
+		Title: title,
+		Body:  body,
+		Head:  bookmark,
+		Base:  a.cfg.Forge.BaseBranch,
+	})
+}
+
+// forgeClient builds the client that opens pull requests, failing when the
+// forge settings lack the repository or the token.
+func (a *App) forgeClient() (*forge.Client, error) {
+	forgeCfg := a.cfg.Forge
+	client, err := forge.NewClient(forge.Config{
+		Provider: forge.Provider(forgeCfg.Provider),
+		Repo:     forgeCfg.Repo,
+		Token:    forgeCfg.Token(),
+		APIURL:   forgeCfg.APIURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid forge config: %w", err)
+	}
+	return client, nil
+}
+
+// planCompleted reports whether the current plan finished successfully.
+func (a *App) planCompleted() bool {
+	plan, err := a.db.GetPlan(a.plan.ID)
+	return err == nil && plan.Status == db.PlanStatusCompleted
+}
+
+// prBookmark returns the bookmark a plan's pull request is pushed to.
+func prBookmark(planID string) string {
+	if len(planID) > 8 {
+		planID = planID[:8]
+	}
+	return prBookmarkPrefix + planID
+}
+
+// planBookmark returns the bookmark kept on the plan's work with the jj
+// bookmark option, e.g. "ralph/add-login-page-1a2b3c4d", or "" without it.
+func (a *App) planBookmark() string {
+	if !a.cfg.JJ.Bookmark || a.plan == nil {
+		return ""
+	}
+	id := a.plan.ID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	slug := bookmarkSlug(prTitle(a.plan))
+	if slug == "" {
+		return a.cfg.JJ.BookmarkPrefix + id
+	}
+	return a.cfg.JJ.BookmarkPrefix + slug + "-" + id
+}
+
+// bookmarkSlug lowercases the title and joins its letters and digits with
+// dashes, so it can be used in a bookmark name.
+func bookmarkSlug(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+		if b.Len() >= maxBookmarkSlugLength {
+			break
+		}
+	}
+	return b.String()
+}
+
+// prTitle uses the plan's first non-empty line, stripped of markdown
+// heading markers, as the pull request title.
+func prTitle(plan *db.Plan) string {
+	for _, line := range strings.Split(plan.Content, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(line, "# "))
+		if line != "" {
+			return line
+		}
+	}
+	return "Ralph plan " + plan.ID
+}
+
+// prBody summarizes the plan for the pull request description: the plan
+// itself and the latest recorded progress.
+func (a *App) prBody() (string, error) {
+	progress, err := a.db.GetLatestProgress(a.plan.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get progress: %w", err)
+	}
+
+	planText := strings.TrimSpace(a.plan.Content)
+	if len(planText) > maxPRPlanLength {
+		cut := maxPRPlanLength
+		for cut > 0 && !utf8.RuneStart(planText[cut]) {
+			cut--
+		}
+		planText = planText[:cut] + "\n\n... (truncated)"
+	}
+
+	var b strings.Builder
+	b.WriteString("## Plan\n\n")
+	b.WriteString(planText)
+	b.WriteString("\n")
+	if progress != nil && strings.TrimSpace(progress.Content) != "" {
+		b.WriteString("\n## Progress\n\n")
+		b.WriteString(strings.TrimSpace(progress.Content))
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "\n---\nOpened by ralph. Plan-ID: `%s`\n", a.plan.ID)
+	return b.String(), nil
+}
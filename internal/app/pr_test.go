@@ -0,0 +1,185 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+func TestPRTitle(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"# Add login page\n\nDetails", "Add login page"},
+		{"\n\n  Fix the parser  \nmore", "Fix the parser"},
+		{"", "Ralph plan plan-1"},
+	}
+	for _, tt := range tests {
+		if got := prTitle(&db.Plan{ID: "plan-1", Content: tt.content}); got != tt.want {
+			t.Errorf("prTitle(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestPRBookmark(t *testing.T) {
+	if got := prBookmark("3f2a9c1e-0000-4000-8000-000000000000"); got != "ralph/3f2a9c1e" {
+		t.Errorf("prBookmark() = %q, want ralph/3f2a9c1e", got)
+	}
+}
+
+func TestPlanBookmark(t *testing.T) {
+	app := &App{cfg: config.DefaultConfig(), plan: &db.Plan{
+		ID:      "3f2a9c1e-0000-4000-8000-000000000000",
+		Content: "# Add the login page (v2)!\n\nDetails",
+	}}
+	if got := app.planBookmark(); got != "" {
+		t.Errorf("planBookmark() = %q without the bookmark option, want none", got)
+	}
+
+	app.cfg.JJ.Bookmark = true
+	if got := app.planBookmark(); got != "ralph/add-the-login-page-v2-3f2a9c1e" {
+		t.Errorf("planBookmark() = %q", got)
+	}
+
+	app.plan.Content = "## ???"
+	if got := app.planBookmark(); got != "ralph/3f2a9c1e" {
+		t.Errorf("planBookmark() = %q for a title without a slug", got)
+	}
+}
+
+func TestApp_CreatePullRequest(t *testing.T) {
+	var gotBody map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"html_url":"https://github.com/acme/app/pull/1"}`))
+	}))
+	defer server.Close()
+
+	tempDir := t.TempDir()
+	app, err := New(Config{WorkDir: tempDir, CreatePR: true})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	app.cfg.ProjectsDir = tempDir
+	app.cfg.Forge.Repo = "acme/app"
+	app.cfg.Forge.APIURL = server.URL
+	app.cfg.Forge.TokenEnv = "RALPH_TEST_PR_TOKEN"
+	t.Setenv("RALPH_TEST_PR_TOKEN", "secret")
+
+	// Every jj command succeeds with no output: the working copy has no diff,
+	// so the work lives in @-, which has no description yet
+	var jjCalls [][]string
+	jjClient := jj.NewClient(tempDir)
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		jjCalls = append(jjCalls, args)
+		return "", "", nil
+	})
+	app.SetJJClient(jjClient)
+
+	if err := app.initDependencies(); err != nil {
+		t.Fatalf("initDependencies() error: %v", err)
+	}
+	defer app.cleanup()
+
+	if err := app.createPlanFromPrompt("# Add login page\n\nBuild it."); err != nil {
+		t.Fatalf("createPlanFromPrompt() error: %v", err)
+	}
+
+	prURL, err := app.createPullRequest(context.Background())
+	if err != nil {
+		t.Fatalf("createPullRequest() error: %v", err)
+	}
+	if prURL != "https://github.com/acme/app/pull/1" {
+		t.Errorf("createPullRequest() = %q", prURL)
+	}
+
+	bookmark := prBookmark(app.plan.ID)
+	wantCalls := [][]string{
+		{"describe", "-r", "@-", "-m", "Add login page"},
+		{"bookmark", "set", bookmark, "-r", "@-", "--allow-backwards"},
+		{"git", "push", "--bookmark", bookmark, "--allow-new"},
+	}
+	for _, want := range wantCalls {
+		if !slices.ContainsFunc(jjCalls, func(call []string) bool { return slices.Equal(call, want) }) {
+			t.Errorf("missing jj call %v in %v", want, jjCalls)
+		}
+	}
+
+	if gotBody["title"] != "Add login page" || gotBody["head"] != bookmark || gotBody["base"] != "main" {
+		t.Errorf("unexpected pull request payload: %v", gotBody)
+	}
+	if !strings.Contains(gotBody["body"], "Build it.") || !strings.Contains(gotBody["body"], app.plan.ID) {
+		t.Errorf("pull request body missing plan summary: %q", gotBody["body"])
+	}
+}
+
+func TestApp_CreatePullRequest_ChecksForgeAtStartup(t *testing.T) {
+	tests := []struct {
+		name    string
+		repo    string
+		wantErr string
+	}{
+		{"missing token", "acme/app", "forge token"},
+		{"missing repo", "", "forge repository"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			app, err := New(Config{WorkDir: tempDir, CreatePR: true})
+			if err != nil {
+				t.Fatalf("New() error: %v", err)
+			}
+			app.cfg.ProjectsDir = tempDir
+			app.cfg.Forge.Repo = tt.repo
+			app.cfg.Forge.TokenEnv = "RALPH_TEST_PR_TOKEN_UNSET"
+			if tt.repo == "" {
+				app.cfg.Forge.TokenEnv = "RALPH_TEST_PR_TOKEN"
+				t.Setenv("RALPH_TEST_PR_TOKEN", "secret")
+			}
+			app.SetJJClient(jj.NewClient(tempDir))
+
+			err = app.initDependencies()
+			defer app.cleanup()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("initDependencies() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApp_PRBody_TruncatesAtRuneBoundary(t *testing.T) {
+	tempDir := t.TempDir()
+	app, err := New(Config{WorkDir: tempDir})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	app.cfg.ProjectsDir = tempDir
+	app.SetJJClient(jj.NewClient(tempDir))
+	if err := app.initDependencies(); err != nil {
+		t.Fatalf("initDependencies() error: %v", err)
+	}
+	defer app.cleanup()
+
+	// 3-byte runes put the length cap in the middle of one
+	if err := app.createPlanFromPrompt(strings.Repeat("€", maxPRPlanLength)); err != nil {
+		t.Fatalf("createPlanFromPrompt() error: %v", err)
+	}
+	body, err := app.prBody()
+	if err != nil {
+		t.Fatalf("prBody() error: %v", err)
+	}
+	if !utf8.ValidString(body) || !strings.Contains(body, "... (truncated)") {
+		t.Errorf("prBody() is not a valid truncated body:\n%.200q", body)
+	}
+}
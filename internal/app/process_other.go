@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package app
+
+import "os"
+
+// processAlive reports whether a process with the given ID exists on this
+// machine.
+func processAlive(pid int) bool {
+	_, err := os.FindProcess(pid)
+	return err == nil
+}
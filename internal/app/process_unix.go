@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package app
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive reports whether a process with the given ID exists on this
+// machine.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
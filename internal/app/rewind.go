@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// rewindPlan rewinds a resumed plan to the end of FromIteration, if set:
+// later iterations' records are superseded and, with RestoreWorkingCopy,
+// the working copy goes back to that iteration's snapshot.
+func (a *App) rewindPlan(ctx context.Context) error {
+	iteration := a.appCfg.FromIteration
+	if iteration == 0 {
+		return nil
+	}
+
+	// Task statuses aren't versioned, so a rewind would leave them ahead
+	tasks, err := a.db.GetTasksByProject(a.plan.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get plan tasks: %w", err)
+	}
+	if len(tasks) > 0 {
+		return fmt.Errorf("cannot resume plan %s from an earlier iteration: it was decomposed into tasks", a.plan.ID)
+	}
+
+	// Restore the working copy first, so a failure leaves the plan as it was
+	if a.appCfg.RestoreWorkingCopy {
+		commitID, err := a.iterationSnapshot(iteration)
+		if err != nil {
+			return err
+		}
+		if err := a.vcs.Restore(ctx, commitID); err != nil {
+			return fmt.Errorf("failed to restore working copy to iteration %d: %w", iteration, err)
+		}
+		log.Info("restored working copy", "plan", a.plan.ID, "iteration", iteration, "commit", commitID)
+	}
+
+	if err := a.db.RewindPlan(a.plan.ID, iteration); err != nil {
+		return fmt.Errorf("failed to rewind plan: %w", err)
+	}
+	log.Info("rewound plan", "plan", a.plan.ID, "iteration", iteration)
+	return nil
+}
+
+// iterationSnapshot returns the working-copy commit recorded after the
+// developer session of the given iteration.
+func (a *App) iterationSnapshot(iteration int) (string, error) {
+	sessions, err := a.db.GetPlanSessionsByPlan(a.plan.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	commitID := ""
+	for _, session := range sessions {
+		if session.Iteration == iteration && session.AgentType == db.LoopAgentDeveloper &&
+			!session.Superseded && session.CommitID != "" {
+			commitID = session.CommitID
+		}
+	}
+	if commitID == "" {
+		return "", fmt.Errorf("no working copy snapshot recorded for iteration %d of plan %s", iteration, a.plan.ID)
+	}
+	return commitID, nil
+}
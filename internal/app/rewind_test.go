@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+func TestApp_RewindPlan(t *testing.T) {
+	tempDir := t.TempDir()
+	app, err := New(Config{WorkDir: tempDir, FromIteration: 1, RestoreWorkingCopy: true})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	app.cfg.ProjectsDir = tempDir
+
+	var jjCalls [][]string
+	jjClient := jj.NewClient(tempDir)
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		jjCalls = append(jjCalls, args)
+		return "", "", nil
+	})
+	app.SetJJClient(jjClient)
+
+	if err := app.initDependencies(); err != nil {
+		t.Fatalf("initDependencies() error: %v", err)
+	}
+	defer app.cleanup()
+
+	if err := app.createPlanFromPrompt("Fix the bug"); err != nil {
+		t.Fatalf("createPlanFromPrompt() error: %v", err)
+	}
+	for i := 1; i <= 2; i++ {
+		session := &db.PlanSession{ID: fmt.Sprintf("dev-%d", i), PlanID: app.plan.ID, Iteration: i, InputPrompt: "p"}
+		if err := app.db.CreatePlanSession(session); err != nil {
+			t.Fatalf("CreatePlanSession() error: %v", err)
+		}
+		if err := app.db.UpdatePlanSessionCommitID(session.ID, fmt.Sprintf("commit-%d", i)); err != nil {
+			t.Fatalf("UpdatePlanSessionCommitID() error: %v", err)
+		}
+		if err := app.db.CreateProgress(&db.Progress{PlanID: app.plan.ID, SessionID: session.ID, Content: fmt.Sprintf("progress %d", i)}); err != nil {
+			t.Fatalf("CreateProgress() error: %v", err)
+		}
+	}
+
+	if err := app.rewindPlan(context.Background()); err != nil {
+		t.Fatalf("rewindPlan() error: %v", err)
+	}
+
+	want := []string{"restore", "--from", "commit-1"}
+	if !slices.ContainsFunc(jjCalls, func(call []string) bool { return slices.Equal(call, want) }) {
+		t.Errorf("missing jj call %v in %v", want, jjCalls)
+	}
+	progress, err := app.db.GetLatestProgress(app.plan.ID)
+	if err != nil || progress == nil || progress.Content != "progress 1" {
+		t.Errorf("GetLatestProgress() = %+v, %v; want progress 1", progress, err)
+	}
+}
+
+func TestApp_RewindPlan_DecomposedPlan(t *testing.T) {
+	tempDir := t.TempDir()
+	app, err := New(Config{WorkDir: tempDir, FromIteration: 1})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	app.cfg.ProjectsDir = tempDir
+	if err := app.initDependencies(); err != nil {
+		t.Fatalf("initDependencies() error: %v", err)
+	}
+	defer app.cleanup()
+
+	if err := app.createPlanFromPrompt("Fix the bug"); err != nil {
+		t.Fatalf("createPlanFromPrompt() error: %v", err)
+	}
+	tasks := []*db.Task{{ID: "task-1", Sequence: 1, Title: "Task", Status: db.TaskPending}}
+	if err := app.db.CreatePlanTasks(app.plan, tasks); err != nil {
+		t.Fatalf("CreatePlanTasks() error: %v", err)
+	}
+
+	err = app.rewindPlan(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "decomposed") {
+		t.Errorf("rewindPlan() = %v, want a decomposed plan error", err)
+	}
+}
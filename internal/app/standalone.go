@@ -0,0 +1,31 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+// NewRoleClient builds the Claude client for an agent role run outside a
+// plan's loop, as by ralph once and ralph review, the way the loop's
+// clients are built: with the role's options, the liveness and backend
+// settings, and the disallowed tools of the permissions config's policy.
+func NewRoleClient(cfg *config.Config, role config.ClaudeRoleConfig, workDir string) (*claude.Client, error) {
+	a := &App{cfg: cfg, workDir: workDir}
+	clientCfg := a.claudeConfig(role)
+	if err := clientCfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid claude options: %w", err)
+	}
+	return claude.NewClient(clientCfg), nil
+}
+
+// SessionThrottle returns the configured limits on how fast Claude sessions
+// start, shared by every run through the plans database.
+func SessionThrottle(cfg *config.Config) loop.Throttle {
+	return loop.Throttle{
+		MaxSessionsPerHour: cfg.Throttle.MaxSessionsPerHour,
+		MaxCostPerHour:     cfg.Throttle.MaxCostPerHour,
+	}
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/loop"
+	"github.com/gerunddev/ralph/internal/tracker"
+)
+
+// newTracker creates the issue-tracker syncer from the tracker config. It
+// returns nil when no tracker is configured or the config is invalid; issue
+// sync never stops a run.
+func (a *App) newTracker() *tracker.Syncer {
+	cfg := a.cfg.Tracker
+	if cfg.Provider == "" {
+		return nil
+	}
+	client, err := tracker.NewClient(tracker.Config{
+		Provider:  cfg.Provider,
+		Repo:      cfg.Repo,
+		Project:   cfg.Project,
+		IssueType: cfg.IssueType,
+		APIURL:    cfg.APIURL,
+		User:      cfg.User,
+		Token:     cfg.Token(),
+	})
+	if err != nil {
+		log.Warn("issue tracker sync disabled", "error", err)
+		return nil
+	}
+	syncer, err := tracker.NewSyncer(client, a.db, a.plan, tracker.Templates{
+		Title:   cfg.Templates.Title,
+		Body:    cfg.Templates.Body,
+		Comment: cfg.Templates.Comment,
+		Close:   cfg.Templates.Close,
+	})
+	if err != nil {
+		log.Warn("issue tracker sync disabled", "error", err)
+		return nil
+	}
+	return syncer
+}
+
+// subscribeTracker keeps the plan's tracking issue in step with the loop's
+// event bus. Tracker requests are slow, so the subscription buffers enough
+// events that a run never waits on them.
+func (a *App) subscribeTracker() {
+	a.tracker = a.newTracker()
+	if a.tracker == nil {
+		return
+	}
+
+	sub := a.loop.Subscribe(loop.WithTypes(a.tracker.EventTypes()...), loop.WithBuffer(100))
+	a.trackerDone = make(chan struct{})
+	go func() {
+		defer close(a.trackerDone)
+		for event := range sub.Events() {
+			if err := a.tracker.Sync(context.Background(), event); err != nil {
+				log.Warn("failed to sync tracking issue", "plan", a.plan.ID, "event", event.Type, "error", err)
+			}
+		}
+	}()
+}
+
+// closeTracker waits for pending tracker updates once the loop has finished.
+func (a *App) closeTracker() {
+	if a.tracker == nil {
+		return
+	}
+	<-a.trackerDone
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/config"
+)
+
+func TestApp_NewTracker(t *testing.T) {
+	tempDir := t.TempDir()
+	app, err := New(Config{WorkDir: tempDir})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	app.cfg.ProjectsDir = tempDir
+	if err := app.initDependencies(); err != nil {
+		t.Fatalf("initDependencies() error: %v", err)
+	}
+	defer app.cleanup()
+	if err := app.createPlanFromPrompt("Fix the bug"); err != nil {
+		t.Fatalf("createPlanFromPrompt() error: %v", err)
+	}
+
+	if app.newTracker() != nil {
+		t.Error("expected no tracker when none is configured")
+	}
+
+	t.Setenv("RALPH_TEST_GITHUB_TOKEN", "")
+	app.cfg.Tracker = config.TrackerConfig{Provider: config.TrackerProviderGitHub, Repo: "acme/app", TokenEnv: "RALPH_TEST_GITHUB_TOKEN"}
+	if app.newTracker() != nil {
+		t.Error("expected no tracker without a token")
+	}
+
+	t.Setenv("RALPH_TEST_GITHUB_TOKEN", "secret")
+	app.cfg.Tracker.Templates.Title = "{{"
+	if app.newTracker() != nil {
+		t.Error("expected no tracker with an invalid template")
+	}
+
+	app.cfg.Tracker.Templates.Title = ""
+	if app.newTracker() == nil {
+		t.Error("expected a tracker")
+	}
+}
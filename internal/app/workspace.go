@@ -0,0 +1,142 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// workspacePrefix namespaces the jj workspaces created for plans.
+const workspacePrefix = "ralph-"
+
+// workspaceName returns the jj workspace name of a plan.
+func workspaceName(planID string) string {
+	if len(planID) > 8 {
+		planID = planID[:8]
+	}
+	return workspacePrefix + planID
+}
+
+// workspacesDir returns where plans' jj workspaces are created: under the
+// projects directory, outside the repository.
+func (a *App) workspacesDir() string {
+	return filepath.Join(a.cfg.GetProjectsDir(), "workspaces")
+}
+
+// useWorkspace reports whether the plan runs in its own jj workspace.
+func (a *App) useWorkspace() bool {
+	return a.cfg.JJ.Workspaces && !a.appCfg.NoWorkspace && !a.appCfg.NoVCS
+}
+
+// planDir returns the directory the plan's work happens in: its jj
+// workspace, or the working directory.
+func (a *App) planDir() string {
+	if a.workspace != nil {
+		return a.workspace.Path
+	}
+	return a.workDir
+}
+
+// enterWorkspace moves the run into the plan's jj workspace, creating it on
+// first use, so plans sharing a repository don't trample each other's
+// working copy. Plans that already ran in the default workspace stay there.
+// A recorded workspace whose directory is gone is an error rather than
+// being started over, since the plan's working copy went with it.
+func (a *App) enterWorkspace(ctx context.Context) error {
+	if !a.useWorkspace() {
+		return nil
+	}
+
+	ws, err := a.db.GetPlanWorkspace(a.plan.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get plan workspace: %w", err)
+	}
+	if ws != nil {
+		if _, err := os.Stat(ws.Path); err != nil {
+			// Deleted, or imported from another machine
+			return fmt.Errorf("plan workspace %s is missing (%w): restore it, or pass --no-workspace to resume in the current working copy", ws.Name, err)
+		}
+	}
+	if ws == nil {
+		latest, err := a.db.GetLatestPlanSession(a.plan.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get latest session: %w", err)
+		}
+		if latest != nil {
+			log.Info("plan started in the default workspace, staying there", "plan", a.plan.ID)
+			return nil
+		}
+		if ws, err = a.addWorkspace(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Global learnings stay keyed by the repository, not the workspace
+	if root, err := a.jj.Root(ctx); err == nil {
+		a.repoRoot = root
+	}
+
+	a.workspace = ws
+	if a.jjOverride == nil {
+		a.jj = jj.NewClient(ws.Path)
+		a.jj.SetEnv(a.env)
+		a.vcs = a.jj
+	}
+	if a.claudeOverride == nil {
+		a.claude = claude.NewClient(a.claudeConfig(a.cfg.Claude.Developer))
+		a.reviewerClaude = claude.NewClient(a.claudeConfig(a.cfg.Claude.Reviewer))
+	}
+	return nil
+}
+
+// addWorkspace creates and records a jj workspace for the plan.
+func (a *App) addWorkspace(ctx context.Context) (*db.PlanWorkspace, error) {
+	ws := &db.PlanWorkspace{
+		PlanID: a.plan.ID,
+		Name:   workspaceName(a.plan.ID),
+		Path:   filepath.Join(a.workspacesDir(), a.plan.ID),
+	}
+	if err := os.MkdirAll(ws.Path, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create workspace directory: %w", err)
+	}
+	if err := a.jj.WorkspaceAdd(ctx, ws.Name, ws.Path); err != nil {
+		_ = os.Remove(ws.Path)
+		return nil, fmt.Errorf("failed to create jj workspace (pass --no-workspace to run in the current one): %w", err)
+	}
+	if err := a.db.CreatePlanWorkspace(ws); err != nil {
+		return nil, fmt.Errorf("failed to record plan workspace: %w", err)
+	}
+	log.Info("created jj workspace", "plan", a.plan.ID, "workspace", ws.Name, "path", ws.Path)
+	return ws, nil
+}
+
+// releaseWorkspace forgets and deletes the plan's jj workspace once the
+// plan has completed. Its changes stay in the repository. Workspaces of
+// unfinished plans are kept for resuming.
+func (a *App) releaseWorkspace(ctx context.Context) {
+	if a.workspace == nil || !a.planCompleted() {
+		return
+	}
+	ws := a.workspace
+
+	if err := a.jj.WorkspaceForget(ctx, ws.Name); err != nil {
+		log.Warn("failed to forget plan workspace", "workspace", ws.Name, "error", err)
+		return
+	}
+	if strings.HasPrefix(ws.Path, a.workspacesDir()+string(filepath.Separator)) {
+		if err := os.RemoveAll(ws.Path); err != nil {
+			log.Warn("failed to delete plan workspace", "path", ws.Path, "error", err)
+		}
+	}
+	if err := a.db.DeletePlanWorkspace(ws.PlanID); err != nil {
+		log.Warn("failed to delete plan workspace record", "plan", ws.PlanID, "error", err)
+	}
+	log.Info("removed jj workspace", "plan", ws.PlanID, "workspace", ws.Name)
+}
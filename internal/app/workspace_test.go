@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+func TestWorkspaceName(t *testing.T) {
+	if got := workspaceName("3f2a9c1e-0000-4000-8000-000000000000"); got != "ralph-3f2a9c1e" {
+		t.Errorf("workspaceName() = %q, want ralph-3f2a9c1e", got)
+	}
+}
+
+func TestApp_Workspace(t *testing.T) {
+	tempDir := t.TempDir()
+	app, err := New(Config{WorkDir: tempDir})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	app.cfg.ProjectsDir = tempDir
+
+	var jjCalls [][]string
+	jjClient := jj.NewClient(tempDir)
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		jjCalls = append(jjCalls, args)
+		if args[0] == "root" {
+			return tempDir + "\n", "", nil
+		}
+		return "", "", nil
+	})
+	app.SetJJClient(jjClient)
+
+	if err := app.initDependencies(); err != nil {
+		t.Fatalf("initDependencies() error: %v", err)
+	}
+	defer app.cleanup()
+	if err := app.createPlanFromPrompt("Build it."); err != nil {
+		t.Fatalf("createPlanFromPrompt() error: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := app.enterWorkspace(ctx); err != nil {
+		t.Fatalf("enterWorkspace() error: %v", err)
+	}
+	name := workspaceName(app.plan.ID)
+	path := filepath.Join(tempDir, "workspaces", app.plan.ID)
+	if !slices.Equal(jjCalls[0], []string{"workspace", "add", "--name", name, path}) {
+		t.Errorf("expected jj workspace add, got %v", jjCalls)
+	}
+	if app.planDir() != path || app.repoRoot != tempDir {
+		t.Errorf("planDir() = %s, repoRoot = %s", app.planDir(), app.repoRoot)
+	}
+	if ws, err := app.db.GetPlanWorkspace(app.plan.ID); err != nil || ws == nil || ws.Path != path {
+		t.Fatalf("GetPlanWorkspace() = %+v, %v", ws, err)
+	}
+
+	// An unfinished plan keeps its workspace
+	app.releaseWorkspace(ctx)
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the workspace kept for resuming: %v", err)
+	}
+
+	// A workspace that has gone missing isn't silently replaced
+	if err := os.Rename(path, path+".moved"); err != nil {
+		t.Fatal(err)
+	}
+	calls := len(jjCalls)
+	if err := app.enterWorkspace(ctx); err == nil || !strings.Contains(err.Error(), "--no-workspace") {
+		t.Errorf("enterWorkspace() with a missing workspace error = %v", err)
+	}
+	if len(jjCalls) != calls {
+		t.Errorf("expected no jj calls for a missing workspace, got %v", jjCalls[calls:])
+	}
+	if ws, err := app.db.GetPlanWorkspace(app.plan.ID); err != nil || ws == nil {
+		t.Errorf("expected the missing workspace kept on record, got %+v, %v", ws, err)
+	}
+	if err := os.Rename(path+".moved", path); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, status := range []db.PlanStatus{db.PlanStatusRunning, db.PlanStatusCompleted} {
+		if err := app.db.UpdatePlanStatus(app.plan.ID, status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app.releaseWorkspace(ctx)
+	if !slices.Equal(jjCalls[len(jjCalls)-1], []string{"workspace", "forget", name}) {
+		t.Errorf("expected jj workspace forget, got %v", jjCalls[len(jjCalls)-1])
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the workspace deleted, got %v", err)
+	}
+	if ws, err := app.db.GetPlanWorkspace(app.plan.ID); err != nil || ws != nil {
+		t.Errorf("GetPlanWorkspace() after completion = %+v, %v", ws, err)
+	}
+}
+
+func TestApp_WorkspaceOptOut(t *testing.T) {
+	tempDir := t.TempDir()
+	app, err := New(Config{WorkDir: tempDir, NoWorkspace: true})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	app.cfg.ProjectsDir = tempDir
+
+	jjClient := jj.NewClient(tempDir)
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		t.Errorf("unexpected jj call: %v", args)
+		return "", "", nil
+	})
+	app.SetJJClient(jjClient)
+
+	if err := app.initDependencies(); err != nil {
+		t.Fatalf("initDependencies() error: %v", err)
+	}
+	defer app.cleanup()
+	if err := app.createPlanFromPrompt("Build it."); err != nil {
+		t.Fatalf("createPlanFromPrompt() error: %v", err)
+	}
+	if err := app.enterWorkspace(context.Background()); err != nil {
+		t.Fatalf("enterWorkspace() error: %v", err)
+	}
+	if app.planDir() != tempDir {
+		t.Errorf("planDir() = %s, want %s", app.planDir(), tempDir)
+	}
+}
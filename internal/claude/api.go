@@ -0,0 +1,284 @@
+package claude
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Backends a client can run sessions with.
+const (
+	BackendCLI = "cli" // The claude CLI, with tools (default)
+	BackendAPI = "api" // The Anthropic Messages API, text only
+)
+
+// DefaultAPIBaseURL is the Anthropic API endpoint used when none is set.
+const DefaultAPIBaseURL = "https://api.anthropic.com"
+
+// DefaultAPIMaxTokens caps a response when APIConfig.MaxTokens is unset.
+const DefaultAPIMaxTokens = 16000
+
+// DefaultAPITimeout bounds a request when APIConfig.Timeout is unset.
+const DefaultAPITimeout = 10 * time.Minute
+
+// anthropicVersion is sent as the anthropic-version header.
+const anthropicVersion = "2023-06-01"
+
+// ErrMissingAPIKey is returned when the API backend has no key.
+var ErrMissingAPIKey = errors.New("anthropic API key is empty")
+
+// ErrAPITimeout is returned when a Messages API request takes longer than
+// its timeout. It is transient: the session can be retried.
+var ErrAPITimeout = errors.New("anthropic API request timed out")
+
+// APIConfig configures the BackendAPI backend.
+type APIConfig struct {
+	Key       string
+	BaseURL   string // Empty = DefaultAPIBaseURL
+	MaxTokens int    // Empty = DefaultAPIMaxTokens
+
+	// Timeout bounds each request (zero = DefaultAPITimeout). The response
+	// arrives whole, so the session's stream is silent until then and the
+	// liveness idle timeout, if any, can't tell a slow answer from a hung
+	// one.
+	Timeout time.Duration
+
+	// HTTPClient overrides the client requests are sent with (for testing).
+	HTTPClient *http.Client
+}
+
+// modelAliases maps the CLI's model aliases to API model IDs, so the same
+// claude.model works with either backend.
+var modelAliases = map[string]string{
+	"opus":   "claude-opus-4-1",
+	"sonnet": "claude-sonnet-4-5",
+	"haiku":  "claude-haiku-4-5",
+}
+
+// apiModel returns the API model ID for a configured model.
+func apiModel(model string) string {
+	if id, ok := modelAliases[model]; ok {
+		return id
+	}
+	return model
+}
+
+// modelPrice is the USD cost per million tokens for models with a prefix.
+type modelPrice struct {
+	prefix string
+	input  float64
+	output float64
+}
+
+// modelPrices lists API prices, most specific prefix first. The CLI reports
+// cost itself; API sessions are priced from their usage.
+var modelPrices = []modelPrice{
+	{"claude-opus-4-5", 5, 25},
+	{"claude-opus-4", 15, 75},
+	{"claude-sonnet-4", 3, 15},
+	{"claude-3-7-sonnet", 3, 15},
+	{"claude-3-5-sonnet", 3, 15},
+	{"claude-haiku-4", 1, 5},
+	{"claude-3-5-haiku", 0.8, 4},
+	{"claude-3-haiku", 0.25, 1.25},
+}
+
+// apiCost prices usage for a model, or returns 0 for an unknown model.
+// Cache writes cost 1.25x and cache reads 0.1x the input price.
+func apiCost(model string, usage Usage) float64 {
+	for _, p := range modelPrices {
+		if strings.HasPrefix(model, p.prefix) {
+			input := float64(usage.InputTokens) + 1.25*float64(usage.CacheCreate) + 0.1*float64(usage.CacheRead)
+			return (input*p.input + float64(usage.OutputTokens)*p.output) / 1e6
+		}
+	}
+	return 0
+}
+
+// apiRequest is the body of a Messages API request.
+type apiRequest struct {
+	Model     string       `json:"model"`
+	MaxTokens int          `json:"max_tokens"`
+	Messages  []apiMessage `json:"messages"`
+}
+
+type apiMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// apiResponse is the body of a Messages API response.
+type apiResponse struct {
+	ID         string       `json:"id"`
+	Role       string       `json:"role"`
+	Model      string       `json:"model"`
+	StopReason string       `json:"stop_reason"`
+	Usage      Usage        `json:"usage"`
+	Content    []rawContent `json:"content"`
+}
+
+// apiError is the body of a Messages API error response.
+type apiError struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// runAPI starts a session that sends the prompt to the Messages API. The
+// response is written to the session's stream as the CLI's stream-JSON
+// (an init, an assistant message, and a result), so sessions are parsed,
+// recorded, and stored the same way with either backend.
+func (c *Client) runAPI(ctx context.Context, prompt string) (*Session, error) {
+	if c.api.Key == "" {
+		return nil, ErrMissingAPIKey
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stdout, stream := io.Pipe()
+	session := c.newSession(ctx, cancel, nil, stdout)
+	if c.fixtures != nil {
+		if err := c.record(session, c.fixtures.nextPath()); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	// A canceled session stops reading; unblock any write to the stream
+	stop := context.AfterFunc(ctx, func() { _ = stream.CloseWithError(ctx.Err()) })
+	go func() {
+		defer stop()
+		if err := c.callAPI(ctx, prompt, stream); err != nil {
+			// Set before closing so the error is in place once the stream ends
+			session.setError(err)
+		}
+		_ = stream.Close()
+	}()
+	go session.streamEvents()
+
+	return session, nil
+}
+
+// callAPI sends one Messages API request and writes the response to w as
+// stream-JSON lines. The request gets its own deadline, APIConfig.Timeout.
+func (c *Client) callAPI(ctx context.Context, prompt string, w io.Writer) error {
+	timeout := c.api.Timeout
+	if timeout <= 0 {
+		timeout = DefaultAPITimeout
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	maxTokens := c.api.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = DefaultAPIMaxTokens
+	}
+	body, err := json.Marshal(apiRequest{
+		Model:     apiModel(c.model),
+		MaxTokens: maxTokens,
+		Messages:  []apiMessage{{Role: "user", Content: prompt}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode API request: %w", err)
+	}
+
+	baseURL := c.api.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultAPIBaseURL
+	}
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create API request: %w", err)
+	}
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("x-api-key", c.api.Key)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	httpClient := c.api.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if timedOut(ctx, reqCtx) {
+			return fmt.Errorf("%w after %s", ErrAPITimeout, timeout)
+		}
+		return fmt.Errorf("anthropic API request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if timedOut(ctx, reqCtx) {
+			return fmt.Errorf("%w after %s", ErrAPITimeout, timeout)
+		}
+		return fmt.Errorf("failed to read API response: %w", err)
+	}
+	elapsed := time.Since(start).Milliseconds()
+
+	if resp.StatusCode != http.StatusOK {
+		return apiStatusError(resp, data)
+	}
+	var msg apiResponse
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to parse API response: %w", err)
+	}
+
+	var text strings.Builder
+	for _, block := range msg.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	// One text block, so the message's text matches the result's
+	msg.Content = []rawContent{{Type: "text", Text: text.String()}}
+	lines := []any{
+		map[string]any{"type": "init", "session_id": msg.ID, "model": msg.Model, "cwd": c.workDir, "tools": 0},
+		map[string]any{"type": "assistant", "session_id": msg.ID, "message": msg},
+		map[string]any{
+			"type":            "result",
+			"session_id":      msg.ID,
+			"total_cost_usd":  apiCost(msg.Model, msg.Usage),
+			"duration_ms":     elapsed,
+			"duration_api_ms": elapsed,
+			"num_turns":       1,
+			"usage":           msg.Usage,
+			"result":          text.String(),
+		},
+	}
+	enc := json.NewEncoder(w)
+	for _, line := range lines {
+		if err := enc.Encode(line); err != nil {
+			return fmt.Errorf("failed to write API response: %w", err)
+		}
+	}
+	return nil
+}
+
+// timedOut reports whether a request's own deadline, rather than the
+// session's context, ended it.
+func timedOut(ctx, reqCtx context.Context) bool {
+	return ctx.Err() == nil && errors.Is(reqCtx.Err(), context.DeadlineExceeded)
+}
+
+// apiStatusError describes a failed API response. The status code and error
+// type are kept in the message so ClassifyError and ParseRateLimit treat
+// rate limits and overloads as transient, as they do for the CLI.
+func apiStatusError(resp *http.Response, data []byte) error {
+	detail := strings.TrimSpace(string(data))
+	var body apiError
+	if err := json.Unmarshal(data, &body); err == nil && body.Error.Message != "" {
+		detail = body.Error.Type + ": " + body.Error.Message
+	}
+	msg := fmt.Sprintf("anthropic API returned %d: %s", resp.StatusCode, detail)
+	if retryAfter := resp.Header.Get("retry-after"); retryAfter != "" {
+		msg += fmt.Sprintf(" (retry after %s seconds)", retryAfter)
+	}
+	return errors.New(msg)
+}
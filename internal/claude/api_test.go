@@ -0,0 +1,132 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newAPIClient(url string) *Client {
+	return NewClient(ClientConfig{
+		Model:   "sonnet",
+		Backend: BackendAPI,
+		API:     APIConfig{Key: "sk-test", BaseURL: url},
+	})
+}
+
+func TestClient_RunAPI(t *testing.T) {
+	var got apiRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "sk-test" || r.Header.Get("anthropic-version") == "" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		_, _ = w.Write([]byte(`{"id":"msg_1","role":"assistant","model":"claude-sonnet-4-5",
+			"stop_reason":"end_turn","usage":{"input_tokens":1000000,"output_tokens":100000},
+			"content":[{"type":"text","text":"Done. "},{"type":"text","text":"All good."}]}`))
+	}))
+	defer server.Close()
+
+	session, err := newAPIClient(server.URL).Run(context.Background(), "Fix the bug")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	var events []StreamEvent
+	for event := range session.Events() {
+		events = append(events, event)
+	}
+	if err := session.Wait(); err != nil {
+		t.Fatalf("Wait() error: %v", err)
+	}
+
+	if got.Model != "claude-sonnet-4-5" || got.MaxTokens != DefaultAPIMaxTokens ||
+		len(got.Messages) != 1 || got.Messages[0].Content != "Fix the bug" {
+		t.Errorf("unexpected request %+v", got)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %+v", len(events), events)
+	}
+	if events[0].Type != EventInit || events[0].Init.Model != "claude-sonnet-4-5" {
+		t.Errorf("unexpected init event %+v", events[0])
+	}
+	if events[1].Type != EventMessage || events[1].Message.Text != "Done. All good." {
+		t.Errorf("unexpected message event %+v", events[1].Message)
+	}
+	result := events[2].Result
+	if events[2].Type != EventResult || result == nil || result.Result != "Done. All good." {
+		t.Fatalf("unexpected result event %+v", events[2])
+	}
+	// $3 per million input tokens plus $15 per million output tokens
+	if result.CostUSD != 4.5 || result.TotalUsage.OutputTokens != 100000 {
+		t.Errorf("unexpected result cost %v, usage %+v", result.CostUSD, result.TotalUsage)
+	}
+}
+
+func TestClient_RunAPIRateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("retry-after", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"rate_limit_error","message":"Slow down"}}`))
+	}))
+	defer server.Close()
+
+	session, err := newAPIClient(server.URL).Run(context.Background(), "prompt")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	for range session.Events() {
+	}
+	err = session.Wait()
+	if !IsTransient(err) {
+		t.Fatalf("expected a transient error, got: %v", err)
+	}
+	now := time.Now()
+	limit, ok := ParseRateLimit(err, now)
+	if !ok || !limit.ResetAt.Equal(now.Add(30*time.Second)) {
+		t.Errorf("ParseRateLimit() = %+v, %v", limit, ok)
+	}
+}
+
+func TestClient_RunAPIMissingKey(t *testing.T) {
+	client := NewClient(ClientConfig{Backend: BackendAPI})
+	if _, err := client.Run(context.Background(), "prompt"); !errors.Is(err, ErrMissingAPIKey) {
+		t.Errorf("Run() error = %v, want ErrMissingAPIKey", err)
+	}
+	if err := (ClientConfig{Backend: BackendAPI}).Validate(); !errors.Is(err, ErrMissingAPIKey) {
+		t.Errorf("Validate() error = %v, want ErrMissingAPIKey", err)
+	}
+	if err := (ClientConfig{Backend: "sdk"}).Validate(); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+}
+
+func TestClient_RunAPITimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient(ClientConfig{
+		Model:   "sonnet",
+		Backend: BackendAPI,
+		API:     APIConfig{Key: "sk-test", BaseURL: server.URL, Timeout: 50 * time.Millisecond},
+	})
+	session, err := client.Run(context.Background(), "prompt")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	for range session.Events() {
+	}
+	err = session.Wait()
+	if !errors.Is(err, ErrAPITimeout) || !IsTransient(err) {
+		t.Errorf("Wait() error = %v, want a transient ErrAPITimeout", err)
+	}
+}
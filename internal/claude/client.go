@@ -9,8 +9,11 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // Error types for Claude operations.
@@ -27,6 +30,92 @@ type ClientConfig struct {
 	MaxTurns int
 	Verbose  bool     // Enable verbose output from Claude CLI
 	EnvVars  []string // Additional environment variables (KEY=VALUE format)
+	WorkDir  string   // Directory the CLI runs in (empty = current directory)
+
+	// DisallowedTools are Claude CLI permission rules the session may not use
+	// (e.g. "WebFetch", "Bash(rm:*)").
+	DisallowedTools []string
+
+	// AllowedTools are permission rules the session may use without prompting
+	// (e.g. "Read", "Bash(go test:*)").
+	AllowedTools []string
+
+	// PermissionMode is passed as --permission-mode (empty = CLI default).
+	PermissionMode string
+
+	// MCPConfig is an MCP server configuration (JSON or a file path) passed
+	// as --mcp-config, alongside any servers the user configured.
+	MCPConfig string
+
+	// ExtraArgs are appended to the CLI invocation before the prompt.
+	// Flags ralph manages itself are rejected by Validate.
+	ExtraArgs []string
+
+	// Liveness monitors sessions that go silent (zero = not monitored).
+	Liveness Liveness
+
+	// Fixtures, when set, records each session's stream, or replays
+	// recorded streams instead of running the CLI.
+	Fixtures *Fixtures
+
+	// Backend selects how sessions run: BackendCLI (empty) or BackendAPI.
+	// API sessions are text only; the tool and CLI options are ignored.
+	Backend string
+
+	// API configures the BackendAPI backend.
+	API APIConfig
+}
+
+// permissionModes lists the values accepted by --permission-mode.
+var permissionModes = []string{"default", "acceptEdits", "plan", "bypassPermissions"}
+
+// managedFlags are flags ralph sets itself; passing them via ExtraArgs would
+// break output parsing or conflict with dedicated ClientConfig fields.
+var managedFlags = []string{
+	"-p", "--print",
+	"--output-format", "--input-format",
+	"--verbose", "--include-partial-messages",
+	"--model", "--max-turns",
+	"--resume",
+	"--permission-mode",
+	"--allowedTools", "--allowed-tools",
+	"--disallowedTools", "--disallowed-tools",
+}
+
+// Validate checks for options that are unknown or conflict with each other.
+func (c ClientConfig) Validate() error {
+	var errs []error
+
+	switch c.Backend {
+	case "", BackendCLI:
+	case BackendAPI:
+		if c.API.Key == "" {
+			errs = append(errs, ErrMissingAPIKey)
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown backend %q (valid: %s, %s)", c.Backend, BackendCLI, BackendAPI))
+	}
+
+	if c.PermissionMode != "" && !slices.Contains(permissionModes, c.PermissionMode) {
+		errs = append(errs, fmt.Errorf("unknown permission mode %q (valid: %s)",
+			c.PermissionMode, strings.Join(permissionModes, ", ")))
+	}
+
+	for _, arg := range c.ExtraArgs {
+		for _, flag := range managedFlags {
+			if arg == flag || strings.HasPrefix(arg, flag+"=") {
+				errs = append(errs, fmt.Errorf("extra argument %q is managed by ralph", arg))
+			}
+		}
+	}
+
+	for _, tool := range c.AllowedTools {
+		if slices.Contains(c.DisallowedTools, tool) {
+			errs = append(errs, fmt.Errorf("tool %q is both allowed and disallowed", tool))
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
 // Client wraps the Claude CLI for executing agent sessions.
@@ -35,6 +124,17 @@ type Client struct {
 	maxTurns int
 	verbose  bool
 	envVars  []string // Additional environment variables
+	workDir  string
+
+	disallowedTools []string
+	allowedTools    []string
+	permissionMode  string
+	mcpConfig       string
+	extraArgs       []string
+	liveness        Liveness
+	fixtures        *Fixtures
+	backend         string
+	api             APIConfig
 
 	// CommandRunner allows overriding command creation for testing.
 	// When set, it's called to create the exec.Cmd instead of the default.
@@ -57,15 +157,78 @@ func NewClient(cfg ClientConfig) *Client {
 		maxTurns:       cfg.MaxTurns,
 		verbose:        cfg.Verbose,
 		envVars:        cfg.EnvVars,
+		workDir:        cfg.WorkDir,
 		commandCreator: defaultCommandCreator,
+
+		disallowedTools: cfg.DisallowedTools,
+		allowedTools:    cfg.AllowedTools,
+		permissionMode:  cfg.PermissionMode,
+		mcpConfig:       cfg.MCPConfig,
+		extraArgs:       cfg.ExtraArgs,
+		liveness:        cfg.Liveness,
+		fixtures:        cfg.Fixtures,
+		backend:         cfg.Backend,
+		api:             cfg.API,
 	}
 }
 
+// Model returns the model the client's sessions use (empty = the CLI's
+// default).
+func (c *Client) Model() string {
+	return c.model
+}
+
+// WithModel returns a copy of the client whose sessions use model.
+func (c *Client) WithModel(model string) *Client {
+	clone := *c
+	clone.model = model
+	return &clone
+}
+
 // SetCommandCreator sets a custom command creator (for testing).
 func (c *Client) SetCommandCreator(creator CommandCreator) {
 	c.commandCreator = creator
 }
 
+// AddEnvVars adds environment variables (KEY=VALUE format) to the sessions
+// the client runs. Later variables override earlier ones of the same name.
+func (c *Client) AddEnvVars(envVars ...string) {
+	c.envVars = append(slices.Clip(c.envVars), envVars...)
+}
+
+// SetDisallowedTools replaces the permission rules the sessions the client
+// runs may not use.
+func (c *Client) SetDisallowedTools(tools []string) {
+	c.disallowedTools = tools
+}
+
+// SetMCPConfig replaces the MCP server configuration passed to the sessions
+// the client runs (empty = none).
+func (c *Client) SetMCPConfig(mcpConfig string) {
+	c.mcpConfig = mcpConfig
+}
+
+// Version returns the claude CLI's version, as printed by claude --version.
+// For the API backend it names the API and model instead.
+func (c *Client) Version(ctx context.Context) (string, error) {
+	if c.backend == BackendAPI {
+		return "anthropic-api " + apiModel(c.model), nil
+	}
+	cmd := c.commandCreator(ctx, "claude", "--version")
+	if c.workDir != "" {
+		cmd.Dir = c.workDir
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var execErr *exec.Error
+		if errors.As(err, &execErr) && errors.Is(execErr.Err, exec.ErrNotFound) {
+			return "", ErrCommandNotFound
+		}
+		return "", fmt.Errorf("claude --version failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
 // Session represents an active Claude session.
 type Session struct {
 	cmd    *exec.Cmd
@@ -73,6 +236,9 @@ type Session struct {
 	stderr *bytes.Buffer
 	parser *Parser
 
+	liveness Liveness
+	fixture  io.Closer // Fixture file being recorded or replayed, if any
+
 	ctx    context.Context
 	events chan StreamEvent
 	done   chan struct{}
@@ -85,6 +251,31 @@ type Session struct {
 // Run executes a Claude session with the given prompt.
 // It returns a Session handle for streaming events.
 func (c *Client) Run(ctx context.Context, prompt string) (*Session, error) {
+	return c.run(ctx, prompt, "")
+}
+
+// Resume continues the CLI session with the given ID (as reported by its
+// init event), sending prompt as the next user turn. The API backend keeps
+// no sessions to resume.
+func (c *Client) Resume(ctx context.Context, sessionID, prompt string) (*Session, error) {
+	if sessionID == "" {
+		return nil, errors.New("no session to resume")
+	}
+	if c.backend == BackendAPI {
+		return nil, errors.New("the api backend cannot resume sessions")
+	}
+	return c.run(ctx, prompt, sessionID)
+}
+
+// run executes a session, resuming the one with the given ID if not empty.
+func (c *Client) run(ctx context.Context, prompt, resume string) (*Session, error) {
+	if c.fixtures != nil && c.fixtures.replay {
+		return c.replay(ctx)
+	}
+	if c.backend == BackendAPI {
+		return c.runAPI(ctx, prompt)
+	}
+
 	// Create a cancelable context
 	ctx, cancel := context.WithCancel(ctx)
 
@@ -105,6 +296,30 @@ func (c *Client) Run(ctx context.Context, prompt string) (*Session, error) {
 		args = append(args, "--max-turns", strconv.Itoa(c.maxTurns))
 	}
 
+	if resume != "" {
+		args = append(args, "--resume", resume)
+	}
+
+	// The CLI's tool lists are variadic, so use the --flag=value form to keep
+	// the prompt from being consumed as another tool name.
+	if len(c.disallowedTools) > 0 {
+		args = append(args, "--disallowedTools="+strings.Join(c.disallowedTools, ","))
+	}
+	if len(c.allowedTools) > 0 {
+		args = append(args, "--allowedTools="+strings.Join(c.allowedTools, ","))
+	}
+
+	if c.permissionMode != "" {
+		args = append(args, "--permission-mode", c.permissionMode)
+	}
+
+	if c.mcpConfig != "" {
+		// Variadic like the tool lists
+		args = append(args, "--mcp-config="+c.mcpConfig)
+	}
+
+	args = append(args, c.extraArgs...)
+
 	// Add the prompt as the final argument
 	args = append(args, prompt)
 
@@ -115,6 +330,9 @@ func (c *Client) Run(ctx context.Context, prompt string) (*Session, error) {
 	if len(c.envVars) > 0 {
 		cmd.Env = append(os.Environ(), c.envVars...)
 	}
+	if c.workDir != "" {
+		cmd.Dir = c.workDir
+	}
 
 	// Set up stdout pipe for streaming
 	stdout, err := cmd.StdoutPipe()
@@ -138,15 +356,14 @@ func (c *Client) Run(ctx context.Context, prompt string) (*Session, error) {
 		return nil, fmt.Errorf("failed to start claude: %w", err)
 	}
 
-	session := &Session{
-		cmd:    cmd,
-		stdout: stdout,
-		stderr: stderr,
-		parser: NewParser(stdout),
-		ctx:    ctx,
-		events: make(chan StreamEvent, 1000),
-		done:   make(chan struct{}),
-		cancel: cancel,
+	session := c.newSession(ctx, cancel, cmd, stdout)
+	session.stderr = stderr
+	if c.fixtures != nil {
+		if err := c.record(session, c.fixtures.nextPath()); err != nil {
+			cancel()
+			_ = cmd.Wait()
+			return nil, err
+		}
 	}
 
 	// Start the event streaming goroutine
@@ -155,28 +372,102 @@ func (c *Client) Run(ctx context.Context, prompt string) (*Session, error) {
 	return session, nil
 }
 
-// streamEvents reads events from the parser and sends them to the events channel.
+// newSession creates a session reading events from stdout. cmd is nil for
+// a replayed session.
+func (c *Client) newSession(ctx context.Context, cancel context.CancelFunc, cmd *exec.Cmd, stdout io.ReadCloser) *Session {
+	return &Session{
+		cmd:      cmd,
+		stdout:   stdout,
+		stderr:   &bytes.Buffer{},
+		parser:   NewParser(stdout),
+		liveness: c.liveness,
+		ctx:      ctx,
+		events:   make(chan StreamEvent, 1000),
+		done:     make(chan struct{}),
+		cancel:   cancel,
+	}
+}
+
+// parsed is the result of reading one event from the stream.
+type parsed struct {
+	event *StreamEvent
+	err   error
+}
+
+// streamEvents reads events from the parser and sends them to the events
+// channel, with heartbeat and stalled events while the stream is silent.
 func (s *Session) streamEvents() {
 	defer close(s.done)
 	defer close(s.events)
+	if s.fixture != nil {
+		defer func() { _ = s.fixture.Close() }()
+	}
 
-	for {
-		event, err := s.parser.Next()
-		if err != nil {
-			if err == io.EOF {
-				// Normal end of stream
-				break
+	// Read in the background so silence can be noticed while a read blocks
+	results := make(chan parsed)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			event, err := s.parser.Next()
+			select {
+			case results <- parsed{event, err}:
+			case <-stop:
+				return
+			}
+			if err != nil {
+				return
 			}
-			s.setError(fmt.Errorf("parse error: %w", err))
-			break
 		}
+	}()
+
+	var ticks <-chan time.Time
+	monitor := newLivenessMonitor(s.liveness, time.Now())
+	if s.liveness.enabled() {
+		ticker := time.NewTicker(s.liveness.checkInterval())
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
 
-		// Send the event, checking for context cancellation
+	for done := false; !done; {
+		var pending []StreamEvent
 		select {
-		case s.events <- *event:
-		case <-s.ctx.Done():
-			return
+		case result := <-results:
+			if result.err != nil {
+				if result.err != io.EOF {
+					s.setError(fmt.Errorf("parse error: %w", result.err))
+				}
+				// io.EOF is the normal end of stream
+				done = true
+				continue
+			}
+			monitor.observe(time.Now())
+			pending = append(pending, *result.event)
+		case now := <-ticks:
+			events, idle := monitor.check(now)
+			if idle {
+				// Kill the process; the stream then ends and it is waited for
+				s.setError(fmt.Errorf("%w for %s", ErrSessionIdle, s.liveness.IdleTimeout))
+				ticks = nil
+				s.cancel()
+				continue
+			}
+			pending = events
 		}
+
+		// Send the events, checking for context cancellation
+		for _, event := range pending {
+			select {
+			case s.events <- event:
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}
+
+	if s.cmd == nil {
+		// Replayed session
+		return
 	}
 
 	// Wait for the command to complete
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -169,6 +170,127 @@ func TestClient_RunOmitsOptionalArguments(t *testing.T) {
 	}
 }
 
+func TestClient_RunAddsDisallowedTools(t *testing.T) {
+	client := NewClient(ClientConfig{
+		DisallowedTools: []string{"WebFetch", "Bash(rm:*)"},
+	})
+
+	creator, calls := mockCommandCreator(`{"type":"init","session_id":"test"}`)
+	client.SetCommandCreator(creator)
+
+	session, err := client.Run(context.Background(), "test prompt")
+	if err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+	for range session.Events() {
+	}
+	_ = session.Wait()
+
+	args := (*calls)[0]
+	found := false
+	for _, arg := range args {
+		if arg == "--disallowedTools=WebFetch,Bash(rm:*)" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected --disallowedTools flag, got: %v", args)
+	}
+	// The prompt must remain the final argument
+	if args[len(args)-1] != "test prompt" {
+		t.Errorf("expected prompt last, got: %v", args)
+	}
+}
+
+func TestClient_RunAddsRoleOptions(t *testing.T) {
+	client := NewClient(ClientConfig{
+		AllowedTools:   []string{"Read", "Grep"},
+		PermissionMode: "plan",
+		MCPConfig:      `{"mcpServers":{}}`,
+		ExtraArgs:      []string{"--append-system-prompt=Be brief"},
+	})
+
+	creator, calls := mockCommandCreator(`{"type":"init","session_id":"test"}`)
+	client.SetCommandCreator(creator)
+
+	session, err := client.Run(context.Background(), "test prompt")
+	if err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+	for range session.Events() {
+	}
+	_ = session.Wait()
+
+	args := strings.Join((*calls)[0], " ")
+	for _, want := range []string{
+		"--allowedTools=Read,Grep",
+		"--permission-mode plan",
+		`--mcp-config={"mcpServers":{}} --append-system-prompt=Be brief test prompt`,
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("expected %q in args, got: %s", want, args)
+		}
+	}
+}
+
+func TestClient_Resume(t *testing.T) {
+	client := NewClient(ClientConfig{})
+	creator, calls := mockCommandCreator(`{"type":"init","session_id":"test"}`)
+	client.SetCommandCreator(creator)
+
+	if _, err := client.Resume(context.Background(), "", "test prompt"); err == nil {
+		t.Error("expected an error without a session to resume")
+	}
+
+	session, err := client.Resume(context.Background(), "abc-123", "test prompt")
+	if err != nil {
+		t.Fatalf("Resume() returned error: %v", err)
+	}
+	for range session.Events() {
+	}
+	_ = session.Wait()
+
+	args := strings.Join((*calls)[0], " ")
+	if !strings.Contains(args, "--resume abc-123") || !strings.HasSuffix(args, "test prompt") {
+		t.Errorf("expected the session to be resumed with the prompt last, got: %s", args)
+	}
+
+	api := NewClient(ClientConfig{Backend: BackendAPI, API: APIConfig{Key: "key"}})
+	if _, err := api.Resume(context.Background(), "abc-123", "test prompt"); err == nil {
+		t.Error("expected the api backend to refuse to resume")
+	}
+}
+
+func TestClientConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ClientConfig
+		wantErr string
+	}{
+		{"empty", ClientConfig{}, ""},
+		{"valid", ClientConfig{PermissionMode: "acceptEdits", AllowedTools: []string{"Read"}, ExtraArgs: []string{"--add-dir=../shared"}}, ""},
+		{"unknown permission mode", ClientConfig{PermissionMode: "yolo"}, "unknown permission mode"},
+		{"managed flag", ClientConfig{ExtraArgs: []string{"--output-format", "text"}}, "managed by ralph"},
+		{"managed flag with value", ClientConfig{ExtraArgs: []string{"--model=sonnet"}}, "managed by ralph"},
+		{"allowed and disallowed", ClientConfig{AllowedTools: []string{"WebFetch"}, DisallowedTools: []string{"WebFetch"}}, "both allowed and disallowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 // =============================================================================
 // Client Tests - Session Events
 // =============================================================================
@@ -395,6 +517,34 @@ func hasClaude() bool {
 	return err == nil
 }
 
+func TestClient_Version(t *testing.T) {
+	client := NewClient(ClientConfig{Model: "opus"})
+	creator, calls := mockCommandCreator("2.0.1 (Claude Code)\n")
+	client.SetCommandCreator(creator)
+
+	version, err := client.Version(context.Background())
+	if err != nil {
+		t.Fatalf("Version() returned error: %v", err)
+	}
+	if version != "2.0.1 (Claude Code)" {
+		t.Errorf("Version() = %q, want 2.0.1 (Claude Code)", version)
+	}
+	if len(*calls) != 1 || strings.Join((*calls)[0], " ") != "claude --version" {
+		t.Errorf("calls = %v, want [claude --version]", *calls)
+	}
+}
+
+func TestClient_VersionCommandNotFound(t *testing.T) {
+	client := NewClient(ClientConfig{})
+	client.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "ralph-nonexistent-claude-binary")
+	})
+
+	if _, err := client.Version(context.Background()); err != ErrCommandNotFound {
+		t.Errorf("Version() error = %v, want ErrCommandNotFound", err)
+	}
+}
+
 // =============================================================================
 // Client Tests - Environment Variables
 // =============================================================================
@@ -477,6 +627,24 @@ func TestClient_RunSetsEnvVars(t *testing.T) {
 	}
 }
 
+func TestClient_AddEnvVars(t *testing.T) {
+	shared := make([]string, 1, 4)
+	shared[0] = "CLAUDE_CODE_EXPERIMENTAL_AGENT_TEAMS=1"
+	client := NewClient(ClientConfig{EnvVars: shared})
+	other := NewClient(ClientConfig{EnvVars: shared})
+
+	client.AddEnvVars("GOPATH=/opt/go", "API_URL=http://localhost")
+	other.AddEnvVars("DEBUG=1")
+
+	want := []string{"CLAUDE_CODE_EXPERIMENTAL_AGENT_TEAMS=1", "GOPATH=/opt/go", "API_URL=http://localhost"}
+	if !slices.Equal(client.envVars, want) {
+		t.Errorf("client.envVars = %v, want %v", client.envVars, want)
+	}
+	if !slices.Equal(other.envVars, []string{"CLAUDE_CODE_EXPERIMENTAL_AGENT_TEAMS=1", "DEBUG=1"}) {
+		t.Errorf("other.envVars = %v, want its own copy", other.envVars)
+	}
+}
+
 func TestClient_RunDoesNotSetEnvVarsWhenEmpty(t *testing.T) {
 	cfg := ClientConfig{}
 	client := NewClient(cfg)
@@ -560,3 +728,23 @@ func TestIntegration_BasicRun(t *testing.T) {
 		t.Error("Missing result event")
 	}
 }
+
+func TestClient_RunSetsWorkDir(t *testing.T) {
+	workDir := t.TempDir()
+	client := NewClient(ClientConfig{WorkDir: workDir})
+	client.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "echo", "-n", `{"type":"init","session_id":"test"}`)
+	})
+
+	session, err := client.Run(context.Background(), "test prompt")
+	if err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+	for range session.Events() {
+	}
+	_ = session.Wait()
+
+	if session.cmd.Dir != workDir {
+		t.Errorf("cmd.Dir = %q, want %q", session.cmd.Dir, workDir)
+	}
+}
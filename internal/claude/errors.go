@@ -0,0 +1,139 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrorClass categorizes a Claude failure for retry decisions.
+type ErrorClass int
+
+const (
+	// ErrorFatal means retrying the same invocation won't help.
+	ErrorFatal ErrorClass = iota
+	// ErrorTransient covers rate limits, API overload, and network failures.
+	ErrorTransient
+)
+
+// String returns a human-readable name for the class.
+func (c ErrorClass) String() string {
+	if c == ErrorTransient {
+		return "transient"
+	}
+	return "fatal"
+}
+
+// transientMarkers are substrings (lowercase) that identify transient failures
+// in CLI stderr output and stream error events.
+var transientMarkers = []string{
+	"rate limit",
+	"rate_limit",
+	"too many requests",
+	"usage limit",
+	"overloaded",
+	"service unavailable",
+	"bad gateway",
+	"gateway timeout",
+	"temporarily unavailable",
+	"timed out",
+	"connection reset",
+	"connection refused",
+	"econnreset",
+	"econnrefused",
+	"etimedout",
+	"network error",
+	"socket hang up",
+}
+
+// transientStatuses are the HTTP status codes of transient failures: rate
+// limited, bad gateway, unavailable, gateway timeout, and overloaded.
+var transientStatuses = []int{429, 502, 503, 504, 529}
+
+// statusPattern matches an HTTP status code where the CLI and API report
+// one: "API Error: 529", "HTTP 503", "HTTP/1.1 502", or "status code: 429".
+// Bare numbers, such as in file names or line numbers, don't count.
+var statusPattern = regexp.MustCompile(`(?i)\b(?:api error|http(?:/[\d.]+)?|status(?: code)?)[:=\s]+(\d{3})\b`)
+
+// statusCode returns the HTTP status code reported in msg (0 = none).
+func statusCode(msg string) int {
+	m := statusPattern.FindStringSubmatch(msg)
+	if m == nil {
+		return 0
+	}
+	code, _ := strconv.Atoi(m[1])
+	return code
+}
+
+// matchesError reports whether msg (lowercase) contains one of markers or
+// reports one of statuses.
+func matchesError(msg string, markers []string, statuses []int) bool {
+	for _, marker := range markers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	code := statusCode(msg)
+	for _, status := range statuses {
+		if code == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ClassifyError reports whether err is worth retrying.
+// Cancellation and a missing claude binary are always fatal; a session
+// ended for producing no output, or an API request that timed out, is
+// always transient.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorFatal
+	}
+	if errors.Is(err, ErrSessionIdle) || errors.Is(err, ErrAPITimeout) {
+		return ErrorTransient
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrSessionCanceled) || errors.Is(err, ErrCommandNotFound) {
+		return ErrorFatal
+	}
+
+	if matchesError(strings.ToLower(err.Error()), transientMarkers, transientStatuses) {
+		return ErrorTransient
+	}
+	return ErrorFatal
+}
+
+// IsTransient reports whether err is a transient failure worth retrying.
+func IsTransient(err error) bool {
+	return ClassifyError(err) == ErrorTransient
+}
+
+// capacityMarkers are substrings (lowercase) that identify a model lacking
+// capacity for the request: overloaded, or a rate, usage, or quota limit
+// reached.
+var capacityMarkers = []string{
+	"overloaded",
+	"rate limit",
+	"rate_limit",
+	"too many requests",
+	"usage limit",
+	"quota",
+}
+
+// capacityStatuses are the HTTP status codes of a model lacking capacity:
+// rate limited and overloaded.
+var capacityStatuses = []int{429, 529}
+
+// IsCapacityError reports whether err means the model couldn't take the
+// request, because it is overloaded or a limit was reached, so another
+// model might.
+func IsCapacityError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrSessionCanceled) {
+		return false
+	}
+	return matchesError(strings.ToLower(err.Error()), capacityMarkers, capacityStatuses)
+}
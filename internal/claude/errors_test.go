@@ -0,0 +1,101 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{"nil", nil, ErrorFatal},
+		{"rate limit", errors.New("claude exited with error: API Error: 429 rate_limit_error"), ErrorTransient},
+		{"overloaded", errors.New("claude error overloaded_error: Overloaded"), ErrorTransient},
+		{"network", errors.New("claude exited with error: connect ECONNREFUSED 127.0.0.1:443"), ErrorTransient},
+		{"timeout", errors.New("request timed out"), ErrorTransient},
+		{"status code", errors.New("claude exited with error: API Error: 502"), ErrorTransient},
+		{"http status", errors.New("HTTP/1.1 504 upstream"), ErrorTransient},
+		{"bare number", errors.New("claude exited with error: cannot open fixtures/503.json"), ErrorFatal},
+		{"timeout flag", errors.New("claude exited with error: unknown option --timeout"), ErrorFatal},
+		{"auth", errors.New("claude exited with error: Invalid API key"), ErrorFatal},
+		{"exit code", errors.New("claude exited with code 1"), ErrorFatal},
+		{"command not found", ErrCommandNotFound, ErrorFatal},
+		{"canceled", fmt.Errorf("wrapped: %w", context.Canceled), ErrorFatal},
+		{"idle", fmt.Errorf("%w for 5m0s", ErrSessionIdle), ErrorTransient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.want {
+				t.Errorf("ClassifyError(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+			if got := IsTransient(tt.err); got != (tt.want == ErrorTransient) {
+				t.Errorf("IsTransient(%v) = %v", tt.err, got)
+			}
+		})
+	}
+}
+
+func TestIsCapacityError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"overloaded", errors.New("claude error overloaded_error: Overloaded"), true},
+		{"rate limit", errors.New("claude exited with error: API Error: 429 rate_limit_error"), true},
+		{"quota", errors.New("API Error: You exceeded your current quota"), true},
+		{"overloaded status", errors.New("claude exited with error: API Error: 529"), true},
+		{"bare number", errors.New("claude exited with error: syntax error on line 429"), false},
+		{"network", errors.New("claude exited with error: connect ECONNREFUSED 127.0.0.1:443"), false},
+		{"auth", errors.New("claude exited with error: Invalid API key"), false},
+		{"canceled", fmt.Errorf("overloaded: %w", context.Canceled), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsCapacityError(tt.err); got != tt.want {
+				t.Errorf("IsCapacityError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		err         error
+		wantLimited bool
+		wantReset   time.Time
+	}{
+		{"nil", nil, false, time.Time{}},
+		{"overloaded", errors.New("claude error overloaded_error: Overloaded"), false, time.Time{}},
+		{"auth", errors.New("claude exited with error: Invalid API key"), false, time.Time{}},
+		{"no reset", errors.New("claude error rate_limit_error: Number of requests exceeded"), true, time.Time{}},
+		{"usage limit", errors.New("claude exited with error: Claude AI usage limit reached|1748782800"), true, time.Unix(1748782800, 0)},
+		{"reset time", errors.New(`claude error rate_limit_error: limit resets at 2025-06-01T13:30:00Z`), true, time.Date(2025, 6, 1, 13, 30, 0, 0, time.UTC)},
+		{"retry-after header", errors.New("API Error: 429 Too Many Requests (retry-after: 30)"), true, now.Add(30 * time.Second)},
+		{"retry after minutes", errors.New("rate limit exceeded, please retry after 2 minutes"), true, now.Add(2 * time.Minute)},
+		{"try again in", errors.New("rate_limit_error: try again in 45s"), true, now.Add(45 * time.Second)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, limited := ParseRateLimit(tt.err, now)
+			if limited != tt.wantLimited {
+				t.Fatalf("ParseRateLimit(%v) limited = %v, want %v", tt.err, limited, tt.wantLimited)
+			}
+			if !limit.ResetAt.Equal(tt.wantReset) {
+				t.Errorf("ParseRateLimit(%v) reset = %v, want %v", tt.err, limit.ResetAt, tt.wantReset)
+			}
+		})
+	}
+}
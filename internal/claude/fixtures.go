@@ -0,0 +1,93 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrFixtureNotFound is returned when replaying a session that has no
+// recorded fixture.
+var ErrFixtureNotFound = errors.New("no fixture recorded for session")
+
+// Fixtures records the raw stream-JSON of each session to a numbered file
+// in a directory, or replays sessions from those files instead of running
+// the CLI. Clients sharing one Fixtures number their sessions together, so
+// a run replays in the order it was recorded.
+type Fixtures struct {
+	dir    string
+	replay bool
+
+	mu   sync.Mutex
+	next int
+}
+
+// NewFixtureRecorder creates fixtures that record sessions to dir, creating
+// it if needed.
+func NewFixtureRecorder(dir string) (*Fixtures, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	return &Fixtures{dir: dir}, nil
+}
+
+// NewFixtureReplayer creates fixtures that replay sessions from dir.
+func NewFixtureReplayer(dir string) (*Fixtures, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fixture directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("fixture path is not a directory: %s", dir)
+	}
+	return &Fixtures{dir: dir, replay: true}, nil
+}
+
+// FixturePath returns the path of the fixture for the nth session (from 1).
+func FixturePath(dir string, n int) string {
+	return filepath.Join(dir, fmt.Sprintf("session-%04d.jsonl", n))
+}
+
+// nextPath returns the path of the fixture for the next session.
+func (f *Fixtures) nextPath() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.next++
+	return FixturePath(f.dir, f.next)
+}
+
+// replay starts a session that streams the next fixture instead of running
+// the CLI.
+func (c *Client) replay(ctx context.Context) (*Session, error) {
+	path := c.fixtures.nextPath()
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrFixtureNotFound, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fixture: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	session := c.newSession(ctx, cancel, nil, file)
+	session.fixture = file
+	go session.streamEvents()
+	return session, nil
+}
+
+// record starts copying a session's stream to the next fixture as it is
+// read.
+func (c *Client) record(session *Session, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create fixture: %w", err)
+	}
+	session.parser = NewParser(io.TeeReader(session.stdout, file))
+	session.fixture = file
+	return nil
+}
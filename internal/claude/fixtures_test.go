@@ -0,0 +1,111 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+const fixtureStream = `{"type":"init","session_id":"s1","model":"opus"}
+{"type":"result","result":"done"}
+`
+
+// collectTypes drains a session and returns its event types.
+func collectTypes(t *testing.T, session *Session) []EventType {
+	t.Helper()
+	var types []EventType
+	for event := range session.Events() {
+		types = append(types, event.Type)
+	}
+	if err := session.Wait(); err != nil {
+		t.Fatalf("Wait() returned error: %v", err)
+	}
+	return types
+}
+
+func TestFixtures_RecordAndReplay(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "fixtures")
+	recorder, err := NewFixtureRecorder(dir)
+	if err != nil {
+		t.Fatalf("NewFixtureRecorder() error: %v", err)
+	}
+
+	// Two clients share the numbering
+	developer := NewClient(ClientConfig{Fixtures: recorder})
+	reviewer := NewClient(ClientConfig{Fixtures: recorder})
+	for _, c := range []*Client{developer, reviewer} {
+		c.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+			return exec.CommandContext(ctx, "printf", "%s", fixtureStream)
+		})
+	}
+	for _, c := range []*Client{developer, reviewer} {
+		session, err := c.Run(context.Background(), "test")
+		if err != nil {
+			t.Fatalf("Run() returned error: %v", err)
+		}
+		collectTypes(t, session)
+	}
+
+	for n := 1; n <= 2; n++ {
+		data, err := os.ReadFile(FixturePath(dir, n))
+		if err != nil {
+			t.Fatalf("expected fixture %d: %v", n, err)
+		}
+		if string(data) != fixtureStream {
+			t.Errorf("fixture %d = %q, want the raw stream", n, data)
+		}
+	}
+
+	replayer, err := NewFixtureReplayer(dir)
+	if err != nil {
+		t.Fatalf("NewFixtureReplayer() error: %v", err)
+	}
+	client := NewClient(ClientConfig{Fixtures: replayer})
+	client.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		t.Error("expected replay not to run the CLI")
+		return exec.CommandContext(ctx, "false")
+	})
+	for n := 1; n <= 2; n++ {
+		session, err := client.Run(context.Background(), "test")
+		if err != nil {
+			t.Fatalf("Run() returned error: %v", err)
+		}
+		if got := collectTypes(t, session); len(got) != 2 || got[0] != EventInit || got[1] != EventResult {
+			t.Errorf("replayed session %d events = %v, want init and result", n, got)
+		}
+	}
+
+	if _, err := client.Run(context.Background(), "test"); !errors.Is(err, ErrFixtureNotFound) {
+		t.Errorf("Run() past the last fixture = %v, want ErrFixtureNotFound", err)
+	}
+}
+
+func TestFixtures_ReplayParseError(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(FixturePath(dir, 1), []byte("{not json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	replayer, err := NewFixtureReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := NewClient(ClientConfig{Fixtures: replayer}).Run(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+	for range session.Events() {
+	}
+	if err := session.Wait(); err == nil {
+		t.Error("expected the replayed parse error")
+	}
+}
+
+func TestNewFixtureReplayer_MissingDir(t *testing.T) {
+	if _, err := NewFixtureReplayer(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error for a missing fixture directory")
+	}
+}
@@ -0,0 +1,98 @@
+package claude
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrSessionIdle is returned when a session is ended because its stream was
+// silent for longer than the idle timeout. It is transient: the session can
+// be retried.
+var ErrSessionIdle = errors.New("session produced no output")
+
+// livenessCheckInterval is how often a session's stream is checked for
+// silence (variable for tests).
+var livenessCheckInterval = time.Second
+
+// Liveness configures monitoring of a session's output stream. A zero
+// threshold disables its check.
+type Liveness struct {
+	// HeartbeatInterval emits an EventHeartbeat for every interval the
+	// stream stays silent.
+	HeartbeatInterval time.Duration
+
+	// StalledAfter emits one EventStalled once the stream has been silent
+	// this long.
+	StalledAfter time.Duration
+
+	// IdleTimeout ends a session whose stream has been silent this long,
+	// with ErrSessionIdle.
+	IdleTimeout time.Duration
+}
+
+// enabled reports whether any check is configured.
+func (l Liveness) enabled() bool {
+	return l.HeartbeatInterval > 0 || l.StalledAfter > 0 || l.IdleTimeout > 0
+}
+
+// checkInterval returns how often to check the stream: every
+// livenessCheckInterval, or more often for thresholds shorter than that.
+func (l Liveness) checkInterval() time.Duration {
+	interval := livenessCheckInterval
+	for _, d := range []time.Duration{l.HeartbeatInterval, l.StalledAfter, l.IdleTimeout} {
+		if d > 0 && d < interval {
+			interval = d
+		}
+	}
+	return interval
+}
+
+// livenessMonitor tracks the silence of one session's stream.
+type livenessMonitor struct {
+	cfg       Liveness
+	lastEvent time.Time // When the stream last produced an event
+	lastBeat  time.Time // When the stream last produced an event or heartbeat
+	stalled   bool      // EventStalled was emitted for the current silence
+}
+
+func newLivenessMonitor(cfg Liveness, now time.Time) *livenessMonitor {
+	return &livenessMonitor{cfg: cfg, lastEvent: now, lastBeat: now}
+}
+
+// observe records that the stream produced an event.
+func (m *livenessMonitor) observe(now time.Time) {
+	m.lastEvent = now
+	m.lastBeat = now
+	m.stalled = false
+}
+
+// check returns the liveness events due at now, and whether the session
+// has been silent past the idle timeout.
+func (m *livenessMonitor) check(now time.Time) (events []StreamEvent, idle bool) {
+	silent := now.Sub(m.lastEvent)
+	if m.cfg.IdleTimeout > 0 && silent >= m.cfg.IdleTimeout {
+		return nil, true
+	}
+	if m.cfg.StalledAfter > 0 && !m.stalled && silent >= m.cfg.StalledAfter {
+		m.stalled = true
+		m.lastBeat = now
+		events = append(events, newLivenessEvent(EventStalled, silent))
+	} else if m.cfg.HeartbeatInterval > 0 && now.Sub(m.lastBeat) >= m.cfg.HeartbeatInterval {
+		m.lastBeat = now
+		events = append(events, newLivenessEvent(EventHeartbeat, silent))
+	}
+	return events, false
+}
+
+// newLivenessEvent creates a heartbeat or stalled event for a stream that
+// has been silent for the given time.
+func newLivenessEvent(eventType EventType, silent time.Duration) StreamEvent {
+	silent = silent.Round(time.Second)
+	raw, _ := json.Marshal(map[string]any{"type": eventType, "silent_seconds": int(silent.Seconds())})
+	return StreamEvent{
+		Type:     eventType,
+		Raw:      raw,
+		Liveness: &LivenessContent{Silent: silent},
+	}
+}
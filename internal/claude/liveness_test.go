@@ -0,0 +1,136 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestLivenessMonitor_Check(t *testing.T) {
+	start := time.Now()
+	m := newLivenessMonitor(Liveness{
+		HeartbeatInterval: 10 * time.Second,
+		StalledAfter:      25 * time.Second,
+		IdleTimeout:       time.Minute,
+	}, start)
+
+	at := func(d time.Duration) []EventType {
+		events, idle := m.check(start.Add(d))
+		if idle {
+			t.Fatalf("check(%s) reported idle", d)
+		}
+		var types []EventType
+		for _, e := range events {
+			types = append(types, e.Type)
+		}
+		return types
+	}
+
+	if got := at(5 * time.Second); len(got) != 0 {
+		t.Errorf("check(5s) = %v, want nothing", got)
+	}
+	if got := at(10 * time.Second); len(got) != 1 || got[0] != EventHeartbeat {
+		t.Errorf("check(10s) = %v, want a heartbeat", got)
+	}
+	if got := at(15 * time.Second); len(got) != 0 {
+		t.Errorf("check(15s) = %v, want nothing until the next interval", got)
+	}
+	if got := at(25 * time.Second); len(got) != 1 || got[0] != EventStalled {
+		t.Errorf("check(25s) = %v, want stalled", got)
+	}
+	// Stalled is reported once; heartbeats continue
+	if got := at(35 * time.Second); len(got) != 1 || got[0] != EventHeartbeat {
+		t.Errorf("check(35s) = %v, want a heartbeat", got)
+	}
+
+	// Output resets the silence
+	m.observe(start.Add(40 * time.Second))
+	if got := at(45 * time.Second); len(got) != 0 {
+		t.Errorf("check(45s) = %v, want nothing after output", got)
+	}
+	if got := at(65 * time.Second); len(got) != 1 || got[0] != EventStalled {
+		t.Errorf("check(65s) = %v, want stalled again", got)
+	}
+
+	if _, idle := m.check(start.Add(100 * time.Second)); !idle {
+		t.Error("expected idle after a minute without output")
+	}
+}
+
+func TestNewLivenessEvent(t *testing.T) {
+	e := newLivenessEvent(EventStalled, 90*time.Second+300*time.Millisecond)
+	if e.Liveness == nil || e.Liveness.Silent != 90*time.Second {
+		t.Errorf("Liveness = %+v, want 1m30s", e.Liveness)
+	}
+	if string(e.Raw) != `{"silent_seconds":90,"type":"stalled"}` {
+		t.Errorf("Raw = %s", e.Raw)
+	}
+}
+
+func TestSession_LivenessEvents(t *testing.T) {
+	oldInterval := livenessCheckInterval
+	livenessCheckInterval = 10 * time.Millisecond
+	t.Cleanup(func() { livenessCheckInterval = oldInterval })
+
+	client := NewClient(ClientConfig{Liveness: Liveness{
+		HeartbeatInterval: 50 * time.Millisecond,
+		StalledAfter:      120 * time.Millisecond,
+	}})
+	client.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "sh", "-c", `sleep 0.3; echo '{"type":"result","result":"done"}'`)
+	})
+
+	session, err := client.Run(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+
+	counts := map[EventType]int{}
+	for event := range session.Events() {
+		counts[event.Type]++
+	}
+	if err := session.Wait(); err != nil {
+		t.Fatalf("Wait() returned error: %v", err)
+	}
+
+	if counts[EventHeartbeat] == 0 {
+		t.Error("expected heartbeats while the session was silent")
+	}
+	if counts[EventStalled] != 1 {
+		t.Errorf("got %d stalled events, want 1", counts[EventStalled])
+	}
+	if counts[EventResult] != 1 {
+		t.Errorf("got %d result events, want 1", counts[EventResult])
+	}
+}
+
+func TestSession_IdleTimeout(t *testing.T) {
+	oldInterval := livenessCheckInterval
+	livenessCheckInterval = 10 * time.Millisecond
+	t.Cleanup(func() { livenessCheckInterval = oldInterval })
+
+	client := NewClient(ClientConfig{Liveness: Liveness{IdleTimeout: 100 * time.Millisecond}})
+	client.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "sleep", "10")
+	})
+
+	session, err := client.Run(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+
+	select {
+	case <-session.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Session did not end after the idle timeout")
+	}
+	err = session.Wait()
+	if !errors.Is(err, ErrSessionIdle) {
+		t.Fatalf("Wait() = %v, want ErrSessionIdle", err)
+	}
+	if !IsTransient(err) {
+		t.Error("expected an idle session to be retryable")
+	}
+}
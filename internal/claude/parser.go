@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 )
 
 // Parser parses Claude's stream-JSON output format.
@@ -108,7 +109,8 @@ func (p *Parser) parseLine(line []byte) (*StreamEvent, error) {
 	}
 
 	event := &StreamEvent{
-		Raw: append([]byte(nil), line...), // Copy the raw bytes
+		Raw:        append([]byte(nil), line...), // Copy the raw bytes
+		SubAgentID: raw.ParentToolUseID,
 	}
 
 	// Determine event type based on content
@@ -153,9 +155,13 @@ func (p *Parser) parseLine(line []byte) (*StreamEvent, error) {
 		if raw.TotalUsage != nil {
 			usage = *raw.TotalUsage
 		}
+		cost := raw.CostUSD
+		if cost == 0 {
+			cost = raw.TotalCostUSD
+		}
 		event.Result = &ResultContent{
 			SessionID:   raw.SessionID,
-			CostUSD:     raw.CostUSD,
+			CostUSD:     cost,
 			DurationMS:  raw.DurationMS,
 			DurationAPI: raw.DurationAPI,
 			NumTurns:    raw.NumTurns,
@@ -212,8 +218,9 @@ func (p *Parser) parseMessageEvent(event *StreamEvent, raw *rawEvent) (*StreamEv
 			}
 		case "tool_result":
 			toolResult = &ToolResultContent{
-				ToolUseID: content.ID,
-				Content:   content.Text,
+				ToolUseID: content.ToolUseID,
+				Content:   toolResultText(content.Content),
+				IsError:   content.IsError,
 			}
 		}
 	}
@@ -261,3 +268,30 @@ func (p *Parser) parseMessageEvent(event *StreamEvent, raw *rawEvent) (*StreamEv
 
 	return event, nil
 }
+
+// toolResultText extracts the text of a tool_result content field, which can
+// be a plain string or an array of content blocks.
+func toolResultText(data json.RawMessage) string {
+	if len(data) == 0 || string(data) == "null" {
+		return ""
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		return text
+	}
+
+	var blocks []rawContent
+	if err := json.Unmarshal(data, &blocks); err == nil {
+		var parts []string
+		for _, block := range blocks {
+			if block.Type == "text" {
+				parts = append(parts, block.Text)
+			}
+		}
+		return strings.Join(parts, "\n")
+	}
+
+	// Unknown format - keep the raw JSON
+	return string(data)
+}
@@ -179,6 +179,63 @@ func TestParser_ToolUseEvent(t *testing.T) {
 	}
 }
 
+func TestParser_SubAgentEvents(t *testing.T) {
+	input := `{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"task_1","name":"Task","input":{"description":"Find callers","subagent_type":"Explore","prompt":"..."}}]},"parent_tool_use_id":null}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"tool_2","name":"Grep","input":{"pattern":"Run"}}]},"parent_tool_use_id":"task_1"}`
+
+	parser := NewParser(strings.NewReader(input))
+	spawn, err := parser.Next()
+	if err != nil {
+		t.Fatalf("Next() returned error: %v", err)
+	}
+	if spawn.SubAgentID != "" {
+		t.Errorf("SubAgentID = %q for the agent's own event, want none", spawn.SubAgentID)
+	}
+	if !IsSubAgentSpawn(spawn.ToolUse) {
+		t.Error("expected the Task call to spawn a sub-agent")
+	}
+	if desc, agentType := SubAgentTask(spawn.ToolUse); desc != "Find callers" || agentType != "Explore" {
+		t.Errorf("SubAgentTask() = %q, %q; want Find callers, Explore", desc, agentType)
+	}
+
+	nested, err := parser.Next()
+	if err != nil {
+		t.Fatalf("Next() returned error: %v", err)
+	}
+	if nested.SubAgentID != "task_1" {
+		t.Errorf("SubAgentID = %q, want task_1", nested.SubAgentID)
+	}
+	if IsSubAgentSpawn(nested.ToolUse) {
+		t.Error("expected Grep not to spawn a sub-agent")
+	}
+	if entries := nested.TranscriptEntries(); len(entries) != 1 || entries[0].SubAgentID != "task_1" {
+		t.Errorf("TranscriptEntries() = %+v, want one entry tagged task_1", entries)
+	}
+}
+
+func TestParser_ToolResultEvent(t *testing.T) {
+	input := `{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"tool_456","content":[{"type":"text","text":"line one"},{"type":"text","text":"line two"}],"is_error":true}]}}`
+
+	parser := NewParser(strings.NewReader(input))
+	event, err := parser.Next()
+	if err != nil {
+		t.Fatalf("Next() returned error: %v", err)
+	}
+
+	if event.Type != EventToolResult {
+		t.Fatalf("event.Type = %v, want %v", event.Type, EventToolResult)
+	}
+	if event.ToolResult.ToolUseID != "tool_456" {
+		t.Errorf("ToolResult.ToolUseID = %q, want %q", event.ToolResult.ToolUseID, "tool_456")
+	}
+	if event.ToolResult.Content != "line one\nline two" {
+		t.Errorf("ToolResult.Content = %q, want %q", event.ToolResult.Content, "line one\nline two")
+	}
+	if !event.ToolResult.IsError {
+		t.Error("ToolResult.IsError = false, want true")
+	}
+}
+
 // =============================================================================
 // Parser Tests - Result Event
 // =============================================================================
@@ -223,6 +280,19 @@ func TestParser_ResultEvent(t *testing.T) {
 	}
 }
 
+func TestParser_ResultEvent_TotalCostUSD(t *testing.T) {
+	input := `{"type":"result","session_id":"abc123","total_cost_usd":0.25,"usage":{"input_tokens":10,"output_tokens":5}}`
+
+	parser := NewParser(strings.NewReader(input))
+	event, err := parser.Next()
+	if err != nil {
+		t.Fatalf("Next() returned error: %v", err)
+	}
+	if event.Result == nil || event.Result.CostUSD != 0.25 {
+		t.Errorf("Result = %+v, want CostUSD 0.25", event.Result)
+	}
+}
+
 // =============================================================================
 // Parser Tests - Error Event
 // =============================================================================
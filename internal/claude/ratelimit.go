@@ -0,0 +1,74 @@
+package claude
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit is what Claude reported about a rate limit it hit.
+type RateLimit struct {
+	ResetAt time.Time // When the limit resets (zero = not reported)
+}
+
+// rateLimitMarkers are substrings (lowercase) that identify a rate limit,
+// as opposed to other transient failures.
+var rateLimitMarkers = []string{
+	"rate limit",
+	"rate_limit",
+	"too many requests",
+	"usage limit",
+}
+
+var (
+	// "Claude AI usage limit reached|1760000000"
+	resetUnixPattern = regexp.MustCompile(`\|(\d{10})\b`)
+	// "resets at 2025-06-01T15:00:00Z"
+	resetTimePattern = regexp.MustCompile(`(?i)resets?(?:\s+at)?[:=]?\s*"?(\d{4}-\d{2}-\d{2}T[^\s,"]+)`)
+	// "retry-after: 30", "retry after 2 minutes", "try again in 45s"
+	retryAfterPattern = regexp.MustCompile(`(?i)(?:retry[- _]after|try again in)["':=\s]*(\d+)\s*(seconds?|secs?|s|minutes?|mins?|m|hours?|h)?\b`)
+)
+
+// ParseRateLimit reports whether err is a rate limit and, when the message
+// says, when it resets. now anchors relative waits such as "retry after 30
+// seconds".
+func ParseRateLimit(err error, now time.Time) (RateLimit, bool) {
+	if err == nil || ClassifyError(err) != ErrorTransient {
+		return RateLimit{}, false
+	}
+	msg := err.Error()
+	if !matchesError(strings.ToLower(msg), rateLimitMarkers, []int{429}) {
+		return RateLimit{}, false
+	}
+
+	if m := resetUnixPattern.FindStringSubmatch(msg); m != nil {
+		if secs, err := strconv.ParseInt(m[1], 10, 64); err == nil {
+			return RateLimit{ResetAt: time.Unix(secs, 0)}, true
+		}
+	}
+	if m := resetTimePattern.FindStringSubmatch(msg); m != nil {
+		if t, err := time.Parse(time.RFC3339, m[1]); err == nil {
+			return RateLimit{ResetAt: t}, true
+		}
+	}
+	if m := retryAfterPattern.FindStringSubmatch(msg); m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil {
+			return RateLimit{ResetAt: now.Add(time.Duration(n) * retryAfterUnit(m[2]))}, true
+		}
+	}
+	return RateLimit{}, true
+}
+
+// retryAfterUnit returns the duration a retry-after unit stands for;
+// a missing unit means seconds.
+func retryAfterUnit(unit string) time.Duration {
+	switch strings.ToLower(unit) {
+	case "m", "min", "mins", "minute", "minutes":
+		return time.Minute
+	case "h", "hour", "hours":
+		return time.Hour
+	default:
+		return time.Second
+	}
+}
@@ -0,0 +1,33 @@
+// Package claude provides a wrapper for the Claude CLI and handles streaming output.
+package claude
+
+import "encoding/json"
+
+// subAgentTools are the tools through which Claude spawns sub-agents. The
+// CLI has called it both "Task" and "Agent".
+var subAgentTools = map[string]bool{
+	"Task":  true,
+	"Agent": true,
+}
+
+// IsSubAgentSpawn reports whether a tool call spawns a sub-agent. The
+// sub-agent's events carry the call's ID as their SubAgentID.
+func IsSubAgentSpawn(tool *ToolUseContent) bool {
+	return tool != nil && subAgentTools[tool.Name]
+}
+
+// SubAgentTask returns the short description and agent type of the task a
+// sub-agent was spawned for. Either may be empty.
+func SubAgentTask(tool *ToolUseContent) (description, agentType string) {
+	if tool == nil || len(tool.Input) == 0 {
+		return "", ""
+	}
+	var input struct {
+		Description  string `json:"description"`
+		SubagentType string `json:"subagent_type"`
+	}
+	if err := json.Unmarshal(tool.Input, &input); err != nil {
+		return "", ""
+	}
+	return input.Description, input.SubagentType
+}
@@ -0,0 +1,81 @@
+// Package claude provides a wrapper for the Claude CLI and handles streaming output.
+package claude
+
+import "encoding/json"
+
+// TranscriptKind identifies what a transcript entry holds.
+type TranscriptKind string
+
+const (
+	// TranscriptText is message text from the assistant or user.
+	TranscriptText TranscriptKind = "text"
+	// TranscriptToolUse is a tool call with its JSON input.
+	TranscriptToolUse TranscriptKind = "tool_use"
+	// TranscriptToolResult is the output of a tool call.
+	TranscriptToolResult TranscriptKind = "tool_result"
+)
+
+// TranscriptEntry is one content block of a conversation, kept verbatim.
+type TranscriptEntry struct {
+	Role       string // "assistant" or "user"
+	Kind       TranscriptKind
+	ToolName   string // For tool_use entries
+	ToolUseID  string // For tool_use and tool_result entries
+	Content    string // Text, tool input JSON, or tool output
+	IsError    bool   // For tool_result entries
+	SubAgentID string // Task tool call that spawned the sub-agent, "" for the agent itself
+}
+
+// TranscriptEntries returns every content block of a message event in order.
+// Unlike the parsed event fields, which keep only the first tool call or
+// result of a message, nothing is dropped. Returns nil for events that do not
+// carry a complete message (including streaming text deltas).
+func (e *StreamEvent) TranscriptEntries() []TranscriptEntry {
+	switch e.Type {
+	case EventMessage, EventToolUse, EventToolResult:
+	default:
+		return nil
+	}
+
+	var raw struct {
+		Message *rawMessage `json:"message"`
+	}
+	if err := json.Unmarshal(e.Raw, &raw); err != nil || raw.Message == nil {
+		return nil
+	}
+
+	role := raw.Message.Role
+	if role == "" {
+		role = "assistant"
+	}
+
+	var entries []TranscriptEntry
+	for _, block := range raw.Message.Content {
+		switch block.Type {
+		case "text":
+			if block.Text == "" {
+				continue
+			}
+			entries = append(entries, TranscriptEntry{Role: role, Kind: TranscriptText, Content: block.Text, SubAgentID: e.SubAgentID})
+		case "tool_use":
+			entries = append(entries, TranscriptEntry{
+				Role:       role,
+				Kind:       TranscriptToolUse,
+				ToolName:   block.Name,
+				ToolUseID:  block.ID,
+				Content:    string(block.Input),
+				SubAgentID: e.SubAgentID,
+			})
+		case "tool_result":
+			entries = append(entries, TranscriptEntry{
+				Role:       role,
+				Kind:       TranscriptToolResult,
+				ToolUseID:  block.ToolUseID,
+				Content:    toolResultText(block.Content),
+				IsError:    block.IsError,
+				SubAgentID: e.SubAgentID,
+			})
+		}
+	}
+	return entries
+}
@@ -0,0 +1,38 @@
+package claude
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStreamEvent_TranscriptEntries(t *testing.T) {
+	input := `{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Reading files"},{"type":"tool_use","id":"t1","name":"Read","input":{"path":"a.go"}},{"type":"tool_use","id":"t2","name":"Read","input":{"path":"b.go"}}]}}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"package a"},{"type":"tool_result","tool_use_id":"t2","content":"no such file","is_error":true}]}}
+{"type":"content_block_delta","delta":{"type":"text_delta","text":"partial"}}`
+
+	parser := NewParser(strings.NewReader(input))
+	var entries []TranscriptEntry
+	for {
+		event, err := parser.Next()
+		if err != nil {
+			break
+		}
+		entries = append(entries, event.TranscriptEntries()...)
+	}
+
+	want := []TranscriptEntry{
+		{Role: "assistant", Kind: TranscriptText, Content: "Reading files"},
+		{Role: "assistant", Kind: TranscriptToolUse, ToolName: "Read", ToolUseID: "t1", Content: `{"path":"a.go"}`},
+		{Role: "assistant", Kind: TranscriptToolUse, ToolName: "Read", ToolUseID: "t2", Content: `{"path":"b.go"}`},
+		{Role: "user", Kind: TranscriptToolResult, ToolUseID: "t1", Content: "package a"},
+		{Role: "user", Kind: TranscriptToolResult, ToolUseID: "t2", Content: "no such file", IsError: true},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
@@ -1,7 +1,10 @@
 // Package claude provides a wrapper for the Claude CLI and handles streaming output.
 package claude
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 // EventType represents the type of a stream event.
 type EventType string
@@ -23,6 +26,12 @@ const (
 	EventError EventType = "error"
 	// EventSystem is for system-level events.
 	EventSystem EventType = "system"
+	// EventHeartbeat is emitted by the client while a session's stream is
+	// silent, to show the session is still being waited on.
+	EventHeartbeat EventType = "heartbeat"
+	// EventStalled is emitted by the client once a session's stream has
+	// been silent long enough to look stuck.
+	EventStalled EventType = "stalled"
 )
 
 // StreamEvent represents a parsed event from Claude's stream-JSON output.
@@ -36,7 +45,12 @@ type StreamEvent struct {
 	ToolResult    *ToolResultContent
 	Result        *ResultContent // For result events
 	Error         *ErrorContent
-	System        *SystemContent // For system events
+	System        *SystemContent   // For system events
+	Liveness      *LivenessContent // For heartbeat and stalled events
+
+	// SubAgentID is the ID of the Task tool call that spawned the sub-agent
+	// this event came from, or "" for the agent's own events.
+	SubAgentID string
 }
 
 // InitContent contains initialization information for a session.
@@ -145,11 +159,19 @@ type SystemContent struct {
 	Message string `json:"message"`
 }
 
+// LivenessContent describes the silence behind a heartbeat or stalled event.
+type LivenessContent struct {
+	Silent time.Duration // Time since the stream's last event
+}
+
 // rawEvent is used for initial JSON parsing to determine event type.
 type rawEvent struct {
 	// Top-level type field (for init, result, error, system events)
 	Type string `json:"type"`
 
+	// Set on events from a sub-agent to the tool call that spawned it
+	ParentToolUseID string `json:"parent_tool_use_id"`
+
 	// Init event fields
 	SessionID  string          `json:"session_id"`
 	Model      string          `json:"model"`
@@ -164,13 +186,14 @@ type rawEvent struct {
 	ContentBlockDelta *rawContentBlockDelta `json:"content_block_delta"`
 
 	// Result event fields
-	CostUSD     float64 `json:"cost_usd"`
-	DurationMS  int64   `json:"duration_ms"`
-	DurationAPI int64   `json:"duration_api_ms"`
-	NumTurns    int     `json:"num_turns"`
-	TotalUsage  *Usage  `json:"usage"`
-	Result      string  `json:"result"`
-	SubAgent    bool    `json:"is_sub_agent"`
+	CostUSD      float64 `json:"cost_usd"`
+	TotalCostUSD float64 `json:"total_cost_usd"` // Newer CLI versions report cost under this name
+	DurationMS   int64   `json:"duration_ms"`
+	DurationAPI  int64   `json:"duration_api_ms"`
+	NumTurns     int     `json:"num_turns"`
+	TotalUsage   *Usage  `json:"usage"`
+	Result       string  `json:"result"`
+	SubAgent     bool    `json:"is_sub_agent"`
 
 	// Error event fields - can be string or ErrorContent object
 	Error json.RawMessage `json:"error"`
@@ -201,9 +224,12 @@ type rawMessage struct {
 
 // rawContent represents a content block within a message.
 type rawContent struct {
-	Type  string          `json:"type"`
-	Text  string          `json:"text"`  // For text content
-	ID    string          `json:"id"`    // For tool_use
-	Name  string          `json:"name"`  // For tool_use
-	Input json.RawMessage `json:"input"` // For tool_use
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`        // For text content
+	ID        string          `json:"id"`          // For tool_use
+	Name      string          `json:"name"`        // For tool_use
+	Input     json.RawMessage `json:"input"`       // For tool_use
+	ToolUseID string          `json:"tool_use_id"` // For tool_result
+	Content   json.RawMessage `json:"content"`     // For tool_result - string or array of text blocks
+	IsError   bool            `json:"is_error"`    // For tool_result
 }
@@ -6,23 +6,118 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
 	"strings"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/conventions"
+	"github.com/gerunddev/ralph/internal/locale"
+	"github.com/gerunddev/ralph/internal/loop"
+	"github.com/gerunddev/ralph/internal/notify"
+	"github.com/gerunddev/ralph/internal/planlint"
+	"github.com/gerunddev/ralph/internal/redact"
+	"github.com/gerunddev/ralph/internal/triage"
 )
 
 // Standard config file location.
 const defaultConfigPath = "~/.config/ralph/config.json"
 
+// LocalDir is the project-local ralph directory, relative to the repo root.
+const LocalDir = ".ralph"
+
+// localConfigFile is the name of the project-local config file inside LocalDir.
+const localConfigFile = "config.json"
+
 // Config holds all Ralph configuration settings.
 type Config struct {
-	DatabasePath        string       `json:"database_path"`      // Deprecated: Use ProjectsDir instead
-	ProjectsDir         string       `json:"projects_dir"`       // Base directory for per-project databases
-	MaxIterations       int          `json:"max_iterations"`     // Max review iterations (new name)
-	MaxReviewIterations int          `json:"max_review_iterations"` // Deprecated: use max_iterations
-	MaxTaskAttempts     int          `json:"max_task_attempts"`
-	DefaultPauseMode    bool         `json:"default_pause_mode"` // Whether to pause between tasks by default
-	Claude              ClaudeConfig `json:"claude"`
-	Agents              AgentConfig  `json:"agents"`
+	DatabasePath        string                    `json:"database_path"`         // Deprecated: Use ProjectsDir instead
+	ProjectsDir         string                    `json:"projects_dir"`          // Base directory for per-project databases
+	MaxIterations       int                       `json:"max_iterations"`        // Max review iterations (new name)
+	MaxReviewIterations int                       `json:"max_review_iterations"` // Deprecated: use max_iterations
+	MaxTaskAttempts     int                       `json:"max_task_attempts"`
+	DefaultPauseMode    bool                      `json:"default_pause_mode"` // Whether to pause between tasks by default
+	Claude              ClaudeConfig              `json:"claude"`
+	Agents              AgentConfig               `json:"agents"`
+	Permissions         PermissionsConfig         `json:"permissions"`
+	Stall               StallConfig               `json:"stall"`
+	FailureTriage       FailureTriageConfig       `json:"failure_triage"`
+	Retry               RetryConfig               `json:"retry"`
+	Throttle            ThrottleConfig            `json:"throttle"`
+	QuietHours          QuietHoursConfig          `json:"quiet_hours"`
+	Forge               ForgeConfig               `json:"forge"`
+	Tracker             TrackerConfig             `json:"tracker"`
+	Notify              NotifyConfig              `json:"notify"`
+	Encryption          EncryptionConfig          `json:"encryption"`
+	Redaction           RedactionConfig           `json:"redaction"`
+	Database            DatabaseConfig            `json:"database"`
+	JJ                  JJConfig                  `json:"jj"`
+	Team                TeamConfig                `json:"team"`
+	SubPlans            SubPlansConfig            `json:"sub_plans"`
+	Conventions         ConventionsConfig         `json:"conventions"`
+	ReviewTriage        ReviewTriageConfig        `json:"review_triage"`
+	ReviewDiff          ReviewDiffConfig          `json:"review_diff"`
+	SelfCheck           SelfCheckConfig           `json:"self_check"`
+	DifferentialPrompts DifferentialPromptsConfig `json:"differential_prompts"`
+	FastResume          FastResumeConfig          `json:"fast_resume"`
+	ReviewerTests       ReviewerTestsConfig       `json:"reviewer_tests"`
+	Lint                LintConfig                `json:"lint"`
+	TUI                 TUIConfig                 `json:"tui"`
+
+	// GlobalLearningsLimit is the max number of repo-wide learnings from
+	// previous plans included in prompts (0 = disabled).
+	GlobalLearningsLimit int `json:"global_learnings_limit"`
+
+	// ExtremeBonusIterations is how many iterations extreme mode adds each
+	// time the developer and reviewer agree the plan is done, for at most
+	// ExtremeMaxRounds such rounds; later agreements are ignored. Zero
+	// means the defaults, 3 iterations and 1 round.
+	ExtremeBonusIterations int `json:"extreme_bonus_iterations"`
+	ExtremeMaxRounds       int `json:"extreme_max_rounds"`
+
+	// CommitTrailers adds Reviewed-by, Iterations, and Plan-ID trailers to
+	// the jj change description once the reviewer approves.
+	CommitTrailers bool `json:"commit_trailers"`
+
+	// PlanRefresh controls what happens when the plan file is edited while
+	// a plan runs: "off", "detect" (report the diff), or "merge" (also
+	// update the plan the agents work from).
+	PlanRefresh string `json:"plan_refresh"`
+
+	// ConflictResolution controls what happens when the working copy has
+	// jj conflicts at the start of an iteration or after the developer's
+	// session: "off" (carry on), "wait" (pause until they are resolved by
+	// hand), or "resolve" (run a conflict resolver agent, then wait if any
+	// remain).
+	ConflictResolution string `json:"conflict_resolution"`
+
+	// OutOfScopeFiles controls what happens when the developer changes
+	// files outside the "files:" globs of the plan's front matter: "revert"
+	// (restore them and tell the developer) or "flag" (only tell the
+	// developer).
+	OutOfScopeFiles string `json:"out_of_scope_files"`
+
+	// Analyzers are static analysis commands run on the changed files
+	// before each review; their findings are added to the reviewer prompt.
+	Analyzers []AnalyzerConfig `json:"analyzers"`
+
+	// Checks are test and verification commands run after each developer
+	// session; the output of failing ones is added to the next developer
+	// prompt.
+	Checks []CheckConfig `json:"checks"`
+
+	// Hooks are commands run at points of the loop's lifecycle, given the
+	// plan's state as JSON on stdin.
+	Hooks []HookConfig `json:"hooks"`
+
+	// Locale is the language agents are asked to write in: "en" (default)
+	// or "ja". Prompt headers and instructions are localized; the status
+	// markers are not.
+	Locale string `json:"locale"`
 
 	// expandedPaths tracks whether ExpandPaths has been called.
 	expandedPaths bool
@@ -30,9 +125,579 @@ type Config struct {
 
 // ClaudeConfig holds Claude-specific configuration.
 type ClaudeConfig struct {
-	Model    string `json:"model"`
-	MaxTurns int    `json:"max_turns"`
-	Verbose  bool   `json:"verbose"`
+	Model     string           `json:"model"`
+	MaxTurns  int              `json:"max_turns"`
+	Verbose   bool             `json:"verbose"`
+	Developer ClaudeRoleConfig `json:"developer"` // CLI options for developer sessions
+	Reviewer  ClaudeRoleConfig `json:"reviewer"`  // CLI options for reviewer sessions
+	Liveness  LivenessConfig   `json:"liveness"`  // Monitoring of sessions that go silent
+	Backend   string           `json:"backend"`   // "cli" (default) or "api"
+	API       ClaudeAPIConfig  `json:"api"`       // Settings for the "api" backend
+
+	// StatusReporting is how agents report progress, learnings, and status:
+	// "markdown" (default) sections and markers in their output, or "tool"
+	// calls to the ralph_status MCP tool. Tool reporting needs the CLI
+	// backend; markdown output is still read when no call is made.
+	StatusReporting string `json:"status_reporting"`
+
+	// StateTools gives sessions MCP tools to query the plan's state on
+	// demand (its progress history, every learning, reviewer feedback) and
+	// to checkpoint progress mid-session. Needs the CLI backend.
+	StateTools bool `json:"state_tools"`
+
+	// FallbackModels are tried in order when a session fails because Model
+	// is overloaded or out of quota, retrying the same prompt on each.
+	FallbackModels []string `json:"fallback_models"`
+}
+
+// Status reporting modes.
+const (
+	StatusReportingMarkdown = "markdown"
+	StatusReportingTool     = "tool"
+)
+
+// Claude backends.
+const (
+	ClaudeBackendCLI = "cli" // Run sessions with the claude CLI
+	ClaudeBackendAPI = "api" // Call the Anthropic Messages API directly, text only
+)
+
+// DefaultAnthropicAPIKeyEnv is the environment variable holding the API key
+// when claude.api.api_key_env is unset.
+const DefaultAnthropicAPIKeyEnv = "ANTHROPIC_API_KEY"
+
+// ClaudeAPIConfig configures the "api" backend.
+type ClaudeAPIConfig struct {
+	APIKeyEnv string `json:"api_key_env"` // Environment variable holding the API key (empty = ANTHROPIC_API_KEY)
+	BaseURL   string `json:"base_url"`    // API endpoint (empty = https://api.anthropic.com)
+	MaxTokens int    `json:"max_tokens"`  // Response token cap (0 = 16000)
+
+	TimeoutSeconds int `json:"timeout_seconds"` // Deadline for each request (0 = 600)
+}
+
+// KeyEnv returns the environment variable the API key is read from.
+func (c ClaudeAPIConfig) KeyEnv() string {
+	if c.APIKeyEnv == "" {
+		return DefaultAnthropicAPIKeyEnv
+	}
+	return c.APIKeyEnv
+}
+
+// Key returns the API key from the configured environment variable.
+func (c ClaudeAPIConfig) Key() string {
+	return os.Getenv(c.KeyEnv())
+}
+
+// LivenessConfig controls monitoring of Claude sessions that stop producing
+// output without failing.
+type LivenessConfig struct {
+	HeartbeatSeconds    int `json:"heartbeat_seconds"`     // Report the session as alive for every interval it is silent (0 = never)
+	StalledAfterSeconds int `json:"stalled_after_seconds"` // Warn once a session has been silent this long (0 = never)
+	IdleTimeoutSeconds  int `json:"idle_timeout_seconds"`  // End and retry a session silent this long (0 = never)
+}
+
+// ClaudeRoleConfig holds Claude CLI options applied to a single agent role.
+type ClaudeRoleConfig struct {
+	PermissionMode string   `json:"permission_mode"` // Passed as --permission-mode
+	AllowedTools   []string `json:"allowed_tools"`   // Passed as --allowedTools
+	ExtraArgs      []string `json:"extra_args"`      // Appended to the claude invocation as-is
+}
+
+// PermissionsConfig restricts what agent sessions may touch.
+type PermissionsConfig struct {
+	AllowedPaths      []string `json:"allowed_paths"`      // Repo-relative paths/globs the agent may modify (empty = all)
+	ForbiddenCommands []string `json:"forbidden_commands"` // Bash command prefixes the agent may not run
+	Network           bool     `json:"network"`            // Allow network tools (WebFetch, WebSearch)
+}
+
+// JJConfig controls how a plan's work is recorded in jj changes.
+type JJConfig struct {
+	ChangePerIteration bool `json:"change_per_iteration"` // Start a new change each iteration instead of amending one working change
+	SquashOnComplete   bool `json:"squash_on_complete"`   // Squash the plan's changes into one, described from the plan, when it completes
+	Workspaces         bool `json:"workspaces"`           // Run each plan in its own jj workspace, forgotten once the plan completes
+
+	// Bookmark keeps a bookmark, BookmarkPrefix followed by a slug of the
+	// plan's title, on the plan's work: created at the base change when the
+	// plan starts and advanced after each completed iteration. When the plan
+	// completes it is kept for pushing or deleted, per BookmarkOnComplete.
+	Bookmark           bool   `json:"bookmark"`
+	BookmarkPrefix     string `json:"bookmark_prefix"`      // e.g. "ralph/"
+	BookmarkOnComplete string `json:"bookmark_on_complete"` // "keep" or "delete"
+}
+
+// What happens to a plan's bookmark when the plan completes.
+const (
+	BookmarkKeep   = "keep"   // Leave it on the completed work, e.g. for pushing
+	BookmarkDelete = "delete" // Delete it
+)
+
+// TeamConfig controls reviewing in team mode.
+type TeamConfig struct {
+	Reviewers int `json:"reviewers"` // Reviewers that review each iteration independently (0 or 1 = a single reviewer)
+	Quorum    int `json:"quorum"`    // Approvals needed before the plan is done (0 = all reviewers)
+}
+
+// SubPlansConfig controls the sub-plans a developer may propose for large
+// tangents.
+type SubPlansConfig struct {
+	MaxDepth int  `json:"max_depth"` // How deeply sub-plans may nest (0 = developers can't propose them)
+	Run      bool `json:"run"`       // Run a sub-plan before the plan that proposed it resumes, instead of only recording it
+}
+
+// ConventionsConfig controls which repository convention files are included
+// in the developer and reviewer prompts.
+type ConventionsConfig struct {
+	Include  []string `json:"include"`   // Repo-relative globs of the files to include, in order (empty = none)
+	Exclude  []string `json:"exclude"`   // Repo-relative globs of matched files to leave out
+	MaxBytes int      `json:"max_bytes"` // Budget for the files' combined content; the rest is cut (0 = no limit)
+}
+
+// LintConfig controls the checks run on a plan before it starts.
+type LintConfig struct {
+	Strict   bool `json:"strict"`    // Refuse to start plans with findings instead of warning
+	MaxBytes int  `json:"max_bytes"` // Size above which a plan is reported as too large (0 = no limit)
+}
+
+// Review triage actions for iterations whose change is trivial.
+const (
+	ReviewTriageOff       = "off"       // Review every change
+	ReviewTriageSkip      = "skip"      // Skip the review
+	ReviewTriageDowngrade = "downgrade" // Review with a cheaper model
+)
+
+// ReviewTriageConfig controls the review of iterations whose change is
+// trivial: whitespace-only, comment-only, or only to trivial paths. A
+// developer's done signal is always reviewed in full.
+type ReviewTriageConfig struct {
+	Action       string   `json:"action"`        // "off" (default), "skip", or "downgrade"
+	MaxLines     int      `json:"max_lines"`     // Changed lines above which a change is never trivial (0 = no limit)
+	TrivialPaths []string `json:"trivial_paths"` // Repo-relative globs of files whose changes are always trivial, e.g. "*.md"
+	Model        string   `json:"model"`         // Reviewer model for trivial changes with "downgrade"
+}
+
+// Formats of the diff given to the reviewer.
+const (
+	ReviewDiffRaw     = "raw"     // The diff as-is, truncated past the reviewer's byte budget
+	ReviewDiffSummary = "summary" // Diffs past the budget are summarized file by file
+)
+
+// What the reviewer is given of the changes under review.
+const (
+	ReviewContextDiff  = "diff"  // The cumulative diff alone
+	ReviewContextFiles = "files" // The diff and the current content of the changed files
+)
+
+// ReviewDiffConfig controls the cumulative diff in the reviewer's prompt.
+// With the "summary" format, a diff too large for the prompt is summarized
+// by a cheaper model, file by file (the functions added, changed, and
+// removed, and the key hunks), in place of being truncated. With the
+// "files" context, the reviewer also gets the current content of the
+// changed files, up to ContextBytes; a plan's "review_context:" front
+// matter overrides Context.
+type ReviewDiffConfig struct {
+	Format string `json:"format"` // "raw" (default) or "summary"
+	Model  string `json:"model"`  // Model for the summaries (empty = the reviewer's model)
+
+	Context      string `json:"context"`       // "diff" (default) or "files"
+	ContextBytes int    `json:"context_bytes"` // Budget for the changed files' content with "files"
+}
+
+// SelfCheckConfig controls the follow-up asking for malformed developer or
+// reviewer output, missing its required sections, to be reformatted before
+// it is stored.
+type SelfCheckConfig struct {
+	Enabled     bool   `json:"enabled"`
+	MaxAttempts int    `json:"max_attempts"` // Reformat follow-ups per session before the output is taken as-is
+	Model       string `json:"model"`        // Model for the follow-ups (empty = the session's model)
+}
+
+// DifferentialPromptsConfig controls differential developer prompts: each
+// developer session resumes the previous one's Claude session and is sent
+// only what changed since (feedback, failing checks, plan edits), with the
+// full prompt sent again every FullRefreshEvery iterations.
+type DifferentialPromptsConfig struct {
+	Enabled          bool `json:"enabled"`
+	FullRefreshEvery int  `json:"full_refresh_every"` // Iterations between full prompts, counting the full one
+}
+
+// FastResumeConfig controls resuming a plan whose last developer session
+// was interrupted mid-stream: the session's stored prompt is sent again
+// instead of being rebuilt, when nothing it was built from changed since.
+// With ClaudeSession, the interrupted Claude session is resumed as well.
+type FastResumeConfig struct {
+	Enabled       bool `json:"enabled"`
+	ClaudeSession bool `json:"claude_session"`
+}
+
+// ReviewerTestsConfig controls test-authoring reviews: before approving a
+// done signal, the reviewer must add a test that fails without the
+// developer's change and passes with it, checked by running Command.
+type ReviewerTestsConfig struct {
+	Enabled        bool     `json:"enabled"`
+	Command        []string `json:"command"`         // Test gate, e.g. ["go", "test", "./..."]; passes when it exits zero
+	TimeoutSeconds int      `json:"timeout_seconds"` // 0 = 600
+}
+
+// TUIConfig controls how the TUI and dashboard look.
+type TUIConfig struct {
+	Theme      string `json:"theme"`      // "dark" (default), "light", "high-contrast", or "no-color"
+	Accessible bool   `json:"accessible"` // Screen-reader-friendly output: no box-drawing characters, word prefixes instead of symbols
+
+	// Scrollback keeps a plain-text copy of each plan's feed in
+	// .ralph/feeds/, capped at ScrollbackMaxMB, for `ralph feed`.
+	Scrollback      bool `json:"scrollback"`
+	ScrollbackMaxMB int  `json:"scrollback_max_mb"`
+}
+
+// TUI themes.
+const (
+	ThemeDark         = "dark"
+	ThemeLight        = "light"
+	ThemeHighContrast = "high-contrast"
+	ThemeNoColor      = "no-color"
+)
+
+// AnalyzerConfig is a static analyzer run before each review.
+type AnalyzerConfig struct {
+	Name           string   `json:"name"`            // Label for its findings, e.g. "go vet"
+	Command        []string `json:"command"`         // Program and arguments; "{files}" and "{dirs}" expand to the changed files and their directories
+	Extensions     []string `json:"extensions"`      // Only run when files with these extensions changed, e.g. [".go"] (empty = any file)
+	TimeoutSeconds int      `json:"timeout_seconds"` // 0 = 120
+}
+
+// CheckConfig is a test or verification command run after each developer
+// session. It fails when it exits non-zero.
+type CheckConfig struct {
+	Name           string   `json:"name"`            // Label for its output, e.g. "tests"
+	Command        []string `json:"command"`         // Program and arguments, e.g. ["go", "test", "./..."]
+	TimeoutSeconds int      `json:"timeout_seconds"` // 0 = 600
+}
+
+// Lifecycle points hooks run at.
+const (
+	HookPreIteration  = "pre_iteration"  // Before each iteration
+	HookPostIteration = "post_iteration" // After each iteration
+	HookOnDone        = "on_done"        // Once the plan completes
+)
+
+// HookConfig is a command run at a point of the loop's lifecycle. It is
+// given the plan ID, iteration, status, and the path of a file holding the
+// plan's diff as JSON on stdin.
+type HookConfig struct {
+	Name           string   `json:"name"`            // Label for failures, e.g. "warm cache"
+	On             string   `json:"on"`              // "pre_iteration", "post_iteration", or "on_done"
+	Command        []string `json:"command"`         // Program and arguments, e.g. ["sh", "-c", "make warm"]
+	TimeoutSeconds int      `json:"timeout_seconds"` // 0 = 60
+}
+
+// Plan refresh modes for edits made to the plan file during a run.
+const (
+	PlanRefreshOff    = "off"    // Ignore edits
+	PlanRefreshDetect = "detect" // Report edits with a diff
+	PlanRefreshMerge  = "merge"  // Report edits and merge them into the stored plan
+)
+
+// Conflict resolution modes for jj conflicts in the working copy.
+const (
+	ConflictResolutionOff     = "off"     // Carry on with the conflicts
+	ConflictResolutionWait    = "wait"    // Pause until they are resolved by hand
+	ConflictResolutionResolve = "resolve" // Run a conflict resolver agent, then wait if any remain
+)
+
+// Actions taken on changes outside a plan's files allowlist.
+const (
+	OutOfScopeRevert = "revert" // Restore the files from the plan's base change
+	OutOfScopeFlag   = "flag"   // Keep the changes and ask the developer to undo them
+)
+
+// Stall actions taken once the stall threshold is reached.
+const (
+	StallActionNudge = "nudge" // Tell the developer it is stuck and must change approach
+	StallActionAbort = "abort" // Stop the loop
+)
+
+// StallConfig controls detection of iterations that stop making progress.
+type StallConfig struct {
+	Threshold int    `json:"threshold"` // Consecutive stalled iterations before acting (0 = disabled)
+	Action    string `json:"action"`    // "nudge" (default) or "abort"
+}
+
+// FailureTriageConfig controls the failure triage agent, which diagnoses
+// why the same iteration error or the same failing checks keep recurring.
+type FailureTriageConfig struct {
+	After int    `json:"after"` // Consecutive identical failures before triaging (0 = disabled)
+	Model string `json:"model"` // Model for the triage (empty = the developer's model)
+}
+
+// RetryConfig controls retries of Claude sessions that fail transiently
+// (rate limits, API overload, network errors).
+type RetryConfig struct {
+	MaxAttempts           int `json:"max_attempts"`            // Total attempts per session (1 = no retries)
+	InitialBackoffSeconds int `json:"initial_backoff_seconds"` // Delay before the first retry, doubled each attempt
+	MaxBackoffSeconds     int `json:"max_backoff_seconds"`     // Upper bound on the delay between attempts
+
+	// MaxRateLimitWaitSeconds is the longest rate-limit reset the loop waits
+	// out, without using up attempts (0 = treat rate limits like other
+	// transient errors).
+	MaxRateLimitWaitSeconds int `json:"max_rate_limit_wait_seconds"`
+}
+
+// ThrottleConfig limits how fast Claude sessions are started, across every
+// run sharing the plans database. Each limit is a token bucket that refills
+// over the hour, so short bursts up to the limit are allowed.
+type ThrottleConfig struct {
+	MaxSessionsPerHour int     `json:"max_sessions_per_hour"` // Claude sessions started per hour (0 = unlimited)
+	MaxCostPerHour     float64 `json:"max_cost_per_hour"`     // Claude spend in USD per hour (0 = unlimited)
+}
+
+// QuietHoursConfig sets a daily window, in local time, during which the
+// loop pauses between iterations and resumes once it ends. A window whose
+// end is before its start wraps past midnight.
+type QuietHoursConfig struct {
+	Start string `json:"start"` // "HH:MM" (empty = no quiet hours)
+	End   string `json:"end"`   // "HH:MM"
+}
+
+// Enabled reports whether quiet hours are configured.
+func (q QuietHoursConfig) Enabled() bool {
+	return q.Start != "" || q.End != ""
+}
+
+// Window returns the start and end of the quiet hours as offsets from
+// midnight.
+func (q QuietHoursConfig) Window() (start, end time.Duration, err error) {
+	if start, err = parseClock(q.Start); err != nil {
+		return 0, 0, fmt.Errorf("quiet_hours.start: %w", err)
+	}
+	if end, err = parseClock(q.End); err != nil {
+		return 0, 0, fmt.Errorf("quiet_hours.end: %w", err)
+	}
+	if start == end {
+		return 0, 0, errors.New("quiet_hours.start and quiet_hours.end must differ")
+	}
+	return start, end, nil
+}
+
+// parseClock parses an "HH:MM" time of day as an offset from midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("must be a time of day like 07:30, got %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Forge providers supported for pull request creation.
+const (
+	ForgeProviderGitHub = "github"
+	ForgeProviderGitLab = "gitlab"
+)
+
+// ForgeConfig controls the pull request opened by --create-pr.
+type ForgeConfig struct {
+	Provider   string `json:"provider"`    // "github" (default) or "gitlab"
+	Repo       string `json:"repo"`        // "owner/name" or GitLab project path
+	BaseBranch string `json:"base_branch"` // Branch the pull request targets
+	APIURL     string `json:"api_url"`     // API base URL for self-hosted forges (empty = provider default)
+	TokenEnv   string `json:"token_env"`   // Environment variable holding the API token (empty = GITHUB_TOKEN or GITLAB_TOKEN)
+}
+
+// Token returns the API token from the configured environment variable,
+// falling back to the provider's conventional variable.
+func (f ForgeConfig) Token() string {
+	name := f.TokenEnv
+	if name == "" {
+		name = "GITHUB_TOKEN"
+		if f.Provider == ForgeProviderGitLab {
+			name = "GITLAB_TOKEN"
+		}
+	}
+	return os.Getenv(name)
+}
+
+// Issue trackers supported for plan tracking issues.
+const (
+	TrackerProviderGitHub = "github"
+	TrackerProviderJira   = "jira"
+)
+
+// TrackerConfig controls the issue-tracker issue that follows each plan:
+// opened when the plan starts, commented on after each iteration, and closed
+// when the plan completes.
+type TrackerConfig struct {
+	Provider  string           `json:"provider"`   // "github" or "jira" (empty = disabled)
+	Repo      string           `json:"repo"`       // GitHub "owner/name"
+	Project   string           `json:"project"`    // Jira project key
+	IssueType string           `json:"issue_type"` // Jira issue type (empty = Task)
+	APIURL    string           `json:"api_url"`    // API base URL; the site URL on Jira (empty = api.github.com on GitHub)
+	User      string           `json:"user"`       // Jira account email for basic auth (empty = bearer token)
+	TokenEnv  string           `json:"token_env"`  // Environment variable holding the API token (empty = GITHUB_TOKEN or JIRA_API_TOKEN)
+	Templates TrackerTemplates `json:"templates"`
+}
+
+// TrackerTemplates are Go text/templates for the tracking issue (empty =
+// built-in).
+type TrackerTemplates struct {
+	Title   string `json:"title"`   // Issue title
+	Body    string `json:"body"`    // Issue description
+	Comment string `json:"comment"` // Comment summarizing each iteration
+	Close   string `json:"close"`   // Comment posted when the plan completes
+}
+
+// Token returns the API token from the configured environment variable,
+// falling back to the provider's conventional variable.
+func (t TrackerConfig) Token() string {
+	name := t.TokenEnv
+	if name == "" {
+		name = "GITHUB_TOKEN"
+		if t.Provider == TrackerProviderJira {
+			name = "JIRA_API_TOKEN"
+		}
+	}
+	return os.Getenv(name)
+}
+
+// Notify providers supported for webhook notifications.
+const (
+	NotifyProviderSlack   = "slack"
+	NotifyProviderDiscord = "discord"
+)
+
+// NotifyConfig controls posting loop milestones to a chat webhook.
+type NotifyConfig struct {
+	WebhookURL         string   `json:"webhook_url"`          // Slack or Discord incoming webhook (empty = disabled)
+	Provider           string   `json:"provider"`             // "slack" or "discord" (empty = detect from the URL)
+	Events             []string `json:"events"`               // Loop event types to post (empty = notify.DefaultEvents)
+	Template           string   `json:"template"`             // Go text/template for each message (empty = built-in)
+	MinIntervalSeconds int      `json:"min_interval_seconds"` // Minimum time between posts; milestones in between are batched
+
+	// Email sends a digest when a plan completes or fails
+	Email EmailConfig `json:"email"`
+}
+
+// DefaultSMTPPasswordEnv is the environment variable holding the SMTP
+// password when notify.email.password_env is unset.
+const DefaultSMTPPasswordEnv = "RALPH_SMTP_PASSWORD"
+
+// EmailConfig controls the digest emailed when a plan completes or fails.
+type EmailConfig struct {
+	SMTPHost    string   `json:"smtp_host"`    // SMTP server (empty = disabled)
+	SMTPPort    int      `json:"smtp_port"`    // 0 = 587, or 465 with tls "tls"
+	Username    string   `json:"username"`     // SMTP login (empty = no authentication)
+	PasswordEnv string   `json:"password_env"` // Environment variable holding the password (empty = RALPH_SMTP_PASSWORD)
+	From        string   `json:"from"`
+	To          []string `json:"to"`
+	TLS         string   `json:"tls"`        // "starttls" (default), "tls" (implicit, SMTPS), or "none"
+	ReportURL   string   `json:"report_url"` // Go text/template linking the plan's report, e.g. with {{.PlanID}} (empty = the ralph report command)
+}
+
+// Password returns the SMTP password from the configured environment
+// variable.
+func (e EmailConfig) Password() string {
+	name := e.PasswordEnv
+	if name == "" {
+		name = DefaultSMTPPasswordEnv
+	}
+	return os.Getenv(name)
+}
+
+// DefaultEncryptionKeyEnv is the environment variable holding the at-rest
+// encryption key when encryption.key_env is unset.
+const DefaultEncryptionKeyEnv = "RALPH_ENCRYPTION_KEY"
+
+// EncryptionConfig controls at-rest encryption of prompts and outputs in the
+// plans database.
+type EncryptionConfig struct {
+	Enabled         bool   `json:"enabled"`
+	KeyEnv          string `json:"key_env"`          // Environment variable holding the key (empty = RALPH_ENCRYPTION_KEY)
+	KeychainService string `json:"keychain_service"` // OS keychain service consulted when the variable is unset (empty = skip)
+}
+
+// keychainLookup reads a secret from the OS keychain. Tests replace it.
+var keychainLookup = func(service string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-w")
+	} else {
+		cmd = exec.Command("secret-tool", "lookup", "service", service)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Key returns the encryption key from the configured environment variable,
+// falling back to the OS keychain (macOS Keychain or the Secret Service via
+// secret-tool) when a keychain service is set.
+func (e EncryptionConfig) Key() (string, error) {
+	name := e.KeyEnv
+	if name == "" {
+		name = DefaultEncryptionKeyEnv
+	}
+	if key := os.Getenv(name); key != "" {
+		return key, nil
+	}
+	if e.KeychainService == "" {
+		return "", fmt.Errorf("encryption key not found: set %s", name)
+	}
+	key, err := keychainLookup(e.KeychainService)
+	if err != nil || key == "" {
+		return "", fmt.Errorf("encryption key not found: set %s or add it to the keychain as service %q", name, e.KeychainService)
+	}
+	return key, nil
+}
+
+// Database backends for the plans database.
+const (
+	DatabaseBackendSQLite   = "sqlite"
+	DatabaseBackendPostgres = "postgres"
+)
+
+// DefaultDatabaseURLEnv is the environment variable holding the Postgres
+// connection string when database.url_env is unset.
+const DefaultDatabaseURLEnv = "RALPH_DATABASE_URL"
+
+// DatabaseConfig selects where the plans database lives. Per-project task
+// databases always stay in SQLite under projects_dir.
+type DatabaseConfig struct {
+	Backend string `json:"backend"` // "sqlite" (default, projects_dir/ralph.db) or "postgres"
+	URL     string `json:"url"`     // Postgres connection string
+	URLEnv  string `json:"url_env"` // Environment variable overriding url (empty = RALPH_DATABASE_URL)
+}
+
+// ConnectionURL returns the Postgres connection string, preferring the
+// configured environment variable over database.url so that credentials
+// can stay out of config files.
+func (d DatabaseConfig) ConnectionURL() (string, error) {
+	name := d.URLEnv
+	if name == "" {
+		name = DefaultDatabaseURLEnv
+	}
+	if url := os.Getenv(name); url != "" {
+		return url, nil
+	}
+	if d.URL != "" {
+		return d.URL, nil
+	}
+	return "", fmt.Errorf("database connection string not found: set database.url or %s", name)
+}
+
+// RedactionConfig controls masking of secrets in stored prompts, output, and
+// events, and in what the TUI, webhooks, and logs display.
+type RedactionConfig struct {
+	Enabled  bool     `json:"enabled"`
+	Patterns []string `json:"patterns"` // Extra regular expressions to mask, on top of the built-in secret patterns
+}
+
+// Redactor returns the configured redactor, or nil when redaction is
+// disabled.
+func (r RedactionConfig) Redactor() (*redact.Redactor, error) {
+	if !r.Enabled {
+		return nil, nil
+	}
+	return redact.New(r.Patterns)
 }
 
 // AgentConfig holds paths to custom agent prompts.
@@ -55,143 +720,924 @@ func DefaultConfig() *Config {
 			Model:    "opus",
 			MaxTurns: 50,
 			Verbose:  true,
+			Liveness: LivenessConfig{
+				HeartbeatSeconds:    60,
+				StalledAfterSeconds: 5 * 60,
+			},
 		},
 		Agents: AgentConfig{},
+		Permissions: PermissionsConfig{
+			Network: true,
+		},
+		Stall: StallConfig{
+			Threshold: 3,
+			Action:    StallActionNudge,
+		},
+		FailureTriage: FailureTriageConfig{
+			After: 3,
+		},
+		Retry: RetryConfig{
+			MaxAttempts:           3,
+			InitialBackoffSeconds: 5,
+			MaxBackoffSeconds:     60,
+
+			MaxRateLimitWaitSeconds: 6 * 60 * 60,
+		},
+		Forge: ForgeConfig{
+			Provider:   ForgeProviderGitHub,
+			BaseBranch: "main",
+		},
+		Notify: NotifyConfig{
+			MinIntervalSeconds: 10,
+		},
+		Redaction: RedactionConfig{
+			Enabled: true,
+		},
+		Conventions: ConventionsConfig{
+			Include:  conventions.DefaultInclude,
+			MaxBytes: conventions.DefaultMaxBytes,
+		},
+		Lint: LintConfig{
+			MaxBytes: planlint.DefaultMaxBytes,
+		},
+		ReviewTriage: ReviewTriageConfig{
+			Action:   ReviewTriageOff,
+			MaxLines: triage.DefaultMaxLines,
+			Model:    "haiku",
+		},
+		ReviewDiff: ReviewDiffConfig{
+			Format:       ReviewDiffRaw,
+			Model:        "haiku",
+			Context:      ReviewContextDiff,
+			ContextBytes: 64 * 1024,
+		},
+		SelfCheck: SelfCheckConfig{
+			MaxAttempts: 1,
+			Model:       "haiku",
+		},
+		DifferentialPrompts: DifferentialPromptsConfig{
+			FullRefreshEvery: 5,
+		},
+		FastResume: FastResumeConfig{
+			Enabled: true,
+		},
+		SubPlans: SubPlansConfig{
+			Run: true,
+		},
+		JJ: JJConfig{
+			Workspaces:         true,
+			BookmarkPrefix:     "ralph/",
+			BookmarkOnComplete: BookmarkKeep,
+		},
+		TUI: TUIConfig{
+			Theme:           ThemeDark,
+			Scrollback:      true,
+			ScrollbackMaxMB: 10,
+		},
+		GlobalLearningsLimit: 10,
+		CommitTrailers:       true,
+		PlanRefresh:          PlanRefreshDetect,
+		ConflictResolution:   ConflictResolutionResolve,
+		OutOfScopeFiles:      OutOfScopeRevert,
+		Locale:               locale.Default,
+
+		ExtremeBonusIterations: 3,
+		ExtremeMaxRounds:       1,
 	}
 }
 
 // Load reads config from the standard location (~/.config/ralph/config.json),
-// falling back to defaults if the file doesn't exist.
+// then overlays the project-local config (.ralph/config.json) if present.
+// Falls back to defaults if neither file exists.
 // Missing fields use default values (not zero values).
 func Load() (*Config, error) {
+	return LoadForDir("")
+}
+
+// LoadForDir is like Load, but reads the project-local config from workDir
+// instead of the current directory.
+func LoadForDir(workDir string) (*Config, error) {
 	configPath, err := expandPath(defaultConfigPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to expand config path: %w", err)
 	}
-	return LoadFromPath(configPath)
+	return LoadFromPaths(configPath, filepath.Join(workDir, LocalConfigPath()))
 }
 
 // LoadFromPath reads config from a specific path.
 // If the file doesn't exist, returns default config.
 // If the file exists but is invalid, returns an error.
 func LoadFromPath(path string) (*Config, error) {
+	return LoadFromPaths(path)
+}
+
+// LoadFromPaths reads config from each path in order, merging later files
+// over earlier ones. Paths that don't exist are skipped.
+// Validation only runs if at least one file was loaded.
+func LoadFromPaths(paths ...string) (*Config, error) {
 	// Start with default config.
 	cfg := DefaultConfig()
 
-	// Check if config file exists.
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		// No config file - use all defaults.
-		if err := cfg.ExpandPaths(); err != nil {
-			return nil, fmt.Errorf("failed to expand paths: %w", err)
+	loaded := false
+	for _, path := range paths {
+		// Check if config file exists.
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+
+		// Read the config file.
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+
+		// Parse JSON into a temporary struct for merging.
+		var fileCfg fileConfig
+		if err := json.Unmarshal(data, &fileCfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		if fileCfg.Agents != nil {
+			fileCfg.Agents.resolve(filepath.Dir(path))
+		}
+
+		// Merge file values over what we have so far (only non-zero values).
+		mergeConfig(cfg, &fileCfg)
+		loaded = true
+	}
+
+	// Expand paths.
+	if err := cfg.ExpandPaths(); err != nil {
+		return nil, fmt.Errorf("failed to expand paths: %w", err)
+	}
+
+	// No config file - use all defaults.
+	if !loaded {
+		return cfg, nil
+	}
+
+	// Validate the merged config.
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// fileConfig is used for parsing JSON with pointer fields to detect what was set.
+type fileConfig struct {
+	DatabasePath        *string                        `json:"database_path"`
+	ProjectsDir         *string                        `json:"projects_dir"`
+	MaxIterations       *int                           `json:"max_iterations"`
+	MaxReviewIterations *int                           `json:"max_review_iterations"`
+	MaxTaskAttempts     *int                           `json:"max_task_attempts"`
+	DefaultPauseMode    *bool                          `json:"default_pause_mode"`
+	Claude              *fileClaudeConfig              `json:"claude"`
+	Agents              *fileAgentConfig               `json:"agents"`
+	Permissions         *filePermissionsConfig         `json:"permissions"`
+	Stall               *fileStallConfig               `json:"stall"`
+	FailureTriage       *fileFailureTriageConfig       `json:"failure_triage"`
+	Retry               *fileRetryConfig               `json:"retry"`
+	Throttle            *fileThrottleConfig            `json:"throttle"`
+	QuietHours          *fileQuietHoursConfig          `json:"quiet_hours"`
+	Forge               *fileForgeConfig               `json:"forge"`
+	Tracker             *fileTrackerConfig             `json:"tracker"`
+	Notify              *fileNotifyConfig              `json:"notify"`
+	Encryption          *fileEncryptionConfig          `json:"encryption"`
+	Redaction           *fileRedactionConfig           `json:"redaction"`
+	Database            *fileDatabaseConfig            `json:"database"`
+	JJ                  *fileJJConfig                  `json:"jj"`
+	Team                *fileTeamConfig                `json:"team"`
+	SubPlans            *fileSubPlansConfig            `json:"sub_plans"`
+	Conventions         *fileConventionsConfig         `json:"conventions"`
+	ReviewTriage        *fileReviewTriageConfig        `json:"review_triage"`
+	ReviewDiff          *fileReviewDiffConfig          `json:"review_diff"`
+	SelfCheck           *fileSelfCheckConfig           `json:"self_check"`
+	DifferentialPrompts *fileDifferentialPromptsConfig `json:"differential_prompts"`
+	FastResume          *fileFastResumeConfig          `json:"fast_resume"`
+	ReviewerTests       *fileReviewerTestsConfig       `json:"reviewer_tests"`
+	Lint                *fileLintConfig                `json:"lint"`
+	TUI                 *fileTUIConfig                 `json:"tui"`
+
+	GlobalLearningsLimit *int    `json:"global_learnings_limit"`
+	CommitTrailers       *bool   `json:"commit_trailers"`
+	PlanRefresh          *string `json:"plan_refresh"`
+	ConflictResolution   *string `json:"conflict_resolution"`
+	OutOfScopeFiles      *string `json:"out_of_scope_files"`
+	Locale               *string `json:"locale"`
+
+	ExtremeBonusIterations *int `json:"extreme_bonus_iterations"`
+	ExtremeMaxRounds       *int `json:"extreme_max_rounds"`
+
+	Analyzers []AnalyzerConfig `json:"analyzers"`
+	Checks    []CheckConfig    `json:"checks"`
+	Hooks     []HookConfig     `json:"hooks"`
+}
+
+type fileClaudeConfig struct {
+	Model     *string               `json:"model"`
+	MaxTurns  *int                  `json:"max_turns"`
+	Verbose   *bool                 `json:"verbose"`
+	Developer *fileClaudeRoleConfig `json:"developer"`
+	Reviewer  *fileClaudeRoleConfig `json:"reviewer"`
+	Liveness  *fileLivenessConfig   `json:"liveness"`
+	Backend   *string               `json:"backend"`
+	API       *fileClaudeAPIConfig  `json:"api"`
+
+	StatusReporting *string `json:"status_reporting"`
+	StateTools      *bool   `json:"state_tools"`
+
+	FallbackModels *[]string `json:"fallback_models"`
+}
+
+type fileClaudeAPIConfig struct {
+	APIKeyEnv *string `json:"api_key_env"`
+	BaseURL   *string `json:"base_url"`
+	MaxTokens *int    `json:"max_tokens"`
+
+	TimeoutSeconds *int `json:"timeout_seconds"`
+}
+
+type fileLivenessConfig struct {
+	HeartbeatSeconds    *int `json:"heartbeat_seconds"`
+	StalledAfterSeconds *int `json:"stalled_after_seconds"`
+	IdleTimeoutSeconds  *int `json:"idle_timeout_seconds"`
+}
+
+type fileClaudeRoleConfig struct {
+	PermissionMode *string  `json:"permission_mode"`
+	AllowedTools   []string `json:"allowed_tools"`
+	ExtraArgs      []string `json:"extra_args"`
+}
+
+type fileAgentConfig struct {
+	Developer  *string `json:"developer"`
+	Reviewer   *string `json:"reviewer"`
+	Planner    *string `json:"planner"`
+	Documenter *string `json:"documenter"`
+}
+
+// resolve makes the relative prompt paths of a config file relative to
+// dir, the file's directory, so they don't depend on where ralph is run.
+// Paths starting with "~" are left for ExpandPaths.
+func (a *fileAgentConfig) resolve(dir string) {
+	for _, path := range []*string{a.Developer, a.Reviewer, a.Planner, a.Documenter} {
+		if path == nil || *path == "" || filepath.IsAbs(*path) || strings.HasPrefix(*path, "~") {
+			continue
+		}
+		*path = filepath.Join(dir, *path)
+	}
+}
+
+type filePermissionsConfig struct {
+	AllowedPaths      []string `json:"allowed_paths"`
+	ForbiddenCommands []string `json:"forbidden_commands"`
+	Network           *bool    `json:"network"`
+}
+
+type fileStallConfig struct {
+	Threshold *int    `json:"threshold"`
+	Action    *string `json:"action"`
+}
+
+type fileFailureTriageConfig struct {
+	After *int    `json:"after"`
+	Model *string `json:"model"`
+}
+
+type fileRetryConfig struct {
+	MaxAttempts           *int `json:"max_attempts"`
+	InitialBackoffSeconds *int `json:"initial_backoff_seconds"`
+	MaxBackoffSeconds     *int `json:"max_backoff_seconds"`
+
+	MaxRateLimitWaitSeconds *int `json:"max_rate_limit_wait_seconds"`
+}
+
+type fileQuietHoursConfig struct {
+	Start *string `json:"start"`
+	End   *string `json:"end"`
+}
+
+type fileThrottleConfig struct {
+	MaxSessionsPerHour *int     `json:"max_sessions_per_hour"`
+	MaxCostPerHour     *float64 `json:"max_cost_per_hour"`
+}
+
+type fileForgeConfig struct {
+	Provider   *string `json:"provider"`
+	Repo       *string `json:"repo"`
+	BaseBranch *string `json:"base_branch"`
+	APIURL     *string `json:"api_url"`
+	TokenEnv   *string `json:"token_env"`
+}
+
+type fileTrackerConfig struct {
+	Provider  *string               `json:"provider"`
+	Repo      *string               `json:"repo"`
+	Project   *string               `json:"project"`
+	IssueType *string               `json:"issue_type"`
+	APIURL    *string               `json:"api_url"`
+	User      *string               `json:"user"`
+	TokenEnv  *string               `json:"token_env"`
+	Templates *fileTrackerTemplates `json:"templates"`
+}
+
+type fileTrackerTemplates struct {
+	Title   *string `json:"title"`
+	Body    *string `json:"body"`
+	Comment *string `json:"comment"`
+	Close   *string `json:"close"`
+}
+
+type fileNotifyConfig struct {
+	WebhookURL         *string          `json:"webhook_url"`
+	Provider           *string          `json:"provider"`
+	Events             []string         `json:"events"`
+	Template           *string          `json:"template"`
+	MinIntervalSeconds *int             `json:"min_interval_seconds"`
+	Email              *fileEmailConfig `json:"email"`
+}
+
+type fileEmailConfig struct {
+	SMTPHost    *string  `json:"smtp_host"`
+	SMTPPort    *int     `json:"smtp_port"`
+	Username    *string  `json:"username"`
+	PasswordEnv *string  `json:"password_env"`
+	From        *string  `json:"from"`
+	To          []string `json:"to"`
+	TLS         *string  `json:"tls"`
+	ReportURL   *string  `json:"report_url"`
+}
+
+type fileEncryptionConfig struct {
+	Enabled         *bool   `json:"enabled"`
+	KeyEnv          *string `json:"key_env"`
+	KeychainService *string `json:"keychain_service"`
+}
+
+type fileRedactionConfig struct {
+	Enabled  *bool    `json:"enabled"`
+	Patterns []string `json:"patterns"`
+}
+
+type fileJJConfig struct {
+	ChangePerIteration *bool `json:"change_per_iteration"`
+	SquashOnComplete   *bool `json:"squash_on_complete"`
+	Workspaces         *bool `json:"workspaces"`
+
+	Bookmark           *bool   `json:"bookmark"`
+	BookmarkPrefix     *string `json:"bookmark_prefix"`
+	BookmarkOnComplete *string `json:"bookmark_on_complete"`
+}
+
+type fileTeamConfig struct {
+	Reviewers *int `json:"reviewers"`
+	Quorum    *int `json:"quorum"`
+}
+
+type fileSubPlansConfig struct {
+	MaxDepth *int  `json:"max_depth"`
+	Run      *bool `json:"run"`
+}
+
+type fileConventionsConfig struct {
+	Include  []string `json:"include"`
+	Exclude  []string `json:"exclude"`
+	MaxBytes *int     `json:"max_bytes"`
+}
+
+type fileLintConfig struct {
+	Strict   *bool `json:"strict"`
+	MaxBytes *int  `json:"max_bytes"`
+}
+
+type fileReviewTriageConfig struct {
+	Action       *string  `json:"action"`
+	MaxLines     *int     `json:"max_lines"`
+	TrivialPaths []string `json:"trivial_paths"`
+	Model        *string  `json:"model"`
+}
+
+type fileReviewDiffConfig struct {
+	Format       *string `json:"format"`
+	Model        *string `json:"model"`
+	Context      *string `json:"context"`
+	ContextBytes *int    `json:"context_bytes"`
+}
+
+type fileSelfCheckConfig struct {
+	Enabled     *bool   `json:"enabled"`
+	MaxAttempts *int    `json:"max_attempts"`
+	Model       *string `json:"model"`
+}
+
+type fileDifferentialPromptsConfig struct {
+	Enabled          *bool `json:"enabled"`
+	FullRefreshEvery *int  `json:"full_refresh_every"`
+}
+
+type fileFastResumeConfig struct {
+	Enabled       *bool `json:"enabled"`
+	ClaudeSession *bool `json:"claude_session"`
+}
+
+type fileReviewerTestsConfig struct {
+	Enabled        *bool    `json:"enabled"`
+	Command        []string `json:"command"`
+	TimeoutSeconds *int     `json:"timeout_seconds"`
+}
+
+type fileTUIConfig struct {
+	Theme           *string `json:"theme"`
+	Accessible      *bool   `json:"accessible"`
+	Scrollback      *bool   `json:"scrollback"`
+	ScrollbackMaxMB *int    `json:"scrollback_max_mb"`
+}
+
+type fileDatabaseConfig struct {
+	Backend *string `json:"backend"`
+	URL     *string `json:"url"`
+	URLEnv  *string `json:"url_env"`
+}
+
+// mergeConfig merges file config values into the default config.
+// Only non-nil values from the file config are applied.
+func mergeConfig(cfg *Config, fileCfg *fileConfig) {
+	if fileCfg.DatabasePath != nil {
+		cfg.DatabasePath = *fileCfg.DatabasePath
+	}
+	if fileCfg.ProjectsDir != nil {
+		cfg.ProjectsDir = *fileCfg.ProjectsDir
+	}
+	if fileCfg.MaxIterations != nil {
+		cfg.MaxIterations = *fileCfg.MaxIterations
+		cfg.MaxReviewIterations = *fileCfg.MaxIterations // Keep in sync
+	}
+	if fileCfg.MaxReviewIterations != nil {
+		cfg.MaxReviewIterations = *fileCfg.MaxReviewIterations
+		cfg.MaxIterations = *fileCfg.MaxReviewIterations // Keep in sync
+	}
+	if fileCfg.MaxTaskAttempts != nil {
+		cfg.MaxTaskAttempts = *fileCfg.MaxTaskAttempts
+	}
+	if fileCfg.DefaultPauseMode != nil {
+		cfg.DefaultPauseMode = *fileCfg.DefaultPauseMode
+	}
+	if fileCfg.GlobalLearningsLimit != nil {
+		cfg.GlobalLearningsLimit = *fileCfg.GlobalLearningsLimit
+	}
+	if fileCfg.CommitTrailers != nil {
+		cfg.CommitTrailers = *fileCfg.CommitTrailers
+	}
+	if fileCfg.ExtremeBonusIterations != nil {
+		cfg.ExtremeBonusIterations = *fileCfg.ExtremeBonusIterations
+	}
+	if fileCfg.ExtremeMaxRounds != nil {
+		cfg.ExtremeMaxRounds = *fileCfg.ExtremeMaxRounds
+	}
+	if fileCfg.PlanRefresh != nil {
+		cfg.PlanRefresh = *fileCfg.PlanRefresh
+	}
+	if fileCfg.ConflictResolution != nil {
+		cfg.ConflictResolution = *fileCfg.ConflictResolution
+	}
+	if fileCfg.OutOfScopeFiles != nil {
+		cfg.OutOfScopeFiles = *fileCfg.OutOfScopeFiles
+	}
+	if fileCfg.Locale != nil {
+		cfg.Locale = *fileCfg.Locale
+	}
+	if fileCfg.Analyzers != nil {
+		cfg.Analyzers = fileCfg.Analyzers
+	}
+	if fileCfg.Checks != nil {
+		cfg.Checks = fileCfg.Checks
+	}
+	if fileCfg.Hooks != nil {
+		cfg.Hooks = fileCfg.Hooks
+	}
+
+	if fileCfg.Claude != nil {
+		if fileCfg.Claude.Model != nil {
+			cfg.Claude.Model = *fileCfg.Claude.Model
+		}
+		if fileCfg.Claude.FallbackModels != nil {
+			cfg.Claude.FallbackModels = *fileCfg.Claude.FallbackModels
+		}
+		if fileCfg.Claude.MaxTurns != nil {
+			cfg.Claude.MaxTurns = *fileCfg.Claude.MaxTurns
+		}
+		if fileCfg.Claude.Verbose != nil {
+			cfg.Claude.Verbose = *fileCfg.Claude.Verbose
+		}
+		mergeClaudeRoleConfig(&cfg.Claude.Developer, fileCfg.Claude.Developer)
+		mergeClaudeRoleConfig(&cfg.Claude.Reviewer, fileCfg.Claude.Reviewer)
+		if l := fileCfg.Claude.Liveness; l != nil {
+			if l.HeartbeatSeconds != nil {
+				cfg.Claude.Liveness.HeartbeatSeconds = *l.HeartbeatSeconds
+			}
+			if l.StalledAfterSeconds != nil {
+				cfg.Claude.Liveness.StalledAfterSeconds = *l.StalledAfterSeconds
+			}
+			if l.IdleTimeoutSeconds != nil {
+				cfg.Claude.Liveness.IdleTimeoutSeconds = *l.IdleTimeoutSeconds
+			}
+		}
+		if fileCfg.Claude.Backend != nil {
+			cfg.Claude.Backend = *fileCfg.Claude.Backend
+		}
+		if fileCfg.Claude.StatusReporting != nil {
+			cfg.Claude.StatusReporting = *fileCfg.Claude.StatusReporting
+		}
+		if fileCfg.Claude.StateTools != nil {
+			cfg.Claude.StateTools = *fileCfg.Claude.StateTools
+		}
+		if api := fileCfg.Claude.API; api != nil {
+			if api.APIKeyEnv != nil {
+				cfg.Claude.API.APIKeyEnv = *api.APIKeyEnv
+			}
+			if api.BaseURL != nil {
+				cfg.Claude.API.BaseURL = *api.BaseURL
+			}
+			if api.MaxTokens != nil {
+				cfg.Claude.API.MaxTokens = *api.MaxTokens
+			}
+			if api.TimeoutSeconds != nil {
+				cfg.Claude.API.TimeoutSeconds = *api.TimeoutSeconds
+			}
+		}
+	}
+
+	if fileCfg.Agents != nil {
+		if fileCfg.Agents.Developer != nil {
+			cfg.Agents.Developer = *fileCfg.Agents.Developer
+		}
+		if fileCfg.Agents.Reviewer != nil {
+			cfg.Agents.Reviewer = *fileCfg.Agents.Reviewer
+		}
+		if fileCfg.Agents.Planner != nil {
+			cfg.Agents.Planner = *fileCfg.Agents.Planner
+		}
+		if fileCfg.Agents.Documenter != nil {
+			cfg.Agents.Documenter = *fileCfg.Agents.Documenter
+		}
+	}
+
+	if fileCfg.Permissions != nil {
+		if fileCfg.Permissions.AllowedPaths != nil {
+			cfg.Permissions.AllowedPaths = fileCfg.Permissions.AllowedPaths
+		}
+		if fileCfg.Permissions.ForbiddenCommands != nil {
+			cfg.Permissions.ForbiddenCommands = fileCfg.Permissions.ForbiddenCommands
+		}
+		if fileCfg.Permissions.Network != nil {
+			cfg.Permissions.Network = *fileCfg.Permissions.Network
+		}
+	}
+
+	if fileCfg.Stall != nil {
+		if fileCfg.Stall.Threshold != nil {
+			cfg.Stall.Threshold = *fileCfg.Stall.Threshold
+		}
+		if fileCfg.Stall.Action != nil {
+			cfg.Stall.Action = *fileCfg.Stall.Action
+		}
+	}
+
+	if fileCfg.FailureTriage != nil {
+		if fileCfg.FailureTriage.After != nil {
+			cfg.FailureTriage.After = *fileCfg.FailureTriage.After
+		}
+		if fileCfg.FailureTriage.Model != nil {
+			cfg.FailureTriage.Model = *fileCfg.FailureTriage.Model
+		}
+	}
+
+	if fileCfg.Retry != nil {
+		if fileCfg.Retry.MaxAttempts != nil {
+			cfg.Retry.MaxAttempts = *fileCfg.Retry.MaxAttempts
+		}
+		if fileCfg.Retry.InitialBackoffSeconds != nil {
+			cfg.Retry.InitialBackoffSeconds = *fileCfg.Retry.InitialBackoffSeconds
+		}
+		if fileCfg.Retry.MaxBackoffSeconds != nil {
+			cfg.Retry.MaxBackoffSeconds = *fileCfg.Retry.MaxBackoffSeconds
+		}
+		if fileCfg.Retry.MaxRateLimitWaitSeconds != nil {
+			cfg.Retry.MaxRateLimitWaitSeconds = *fileCfg.Retry.MaxRateLimitWaitSeconds
+		}
+	}
+
+	if fileCfg.Throttle != nil {
+		if fileCfg.Throttle.MaxSessionsPerHour != nil {
+			cfg.Throttle.MaxSessionsPerHour = *fileCfg.Throttle.MaxSessionsPerHour
+		}
+		if fileCfg.Throttle.MaxCostPerHour != nil {
+			cfg.Throttle.MaxCostPerHour = *fileCfg.Throttle.MaxCostPerHour
+		}
+	}
+
+	if fileCfg.QuietHours != nil {
+		if fileCfg.QuietHours.Start != nil {
+			cfg.QuietHours.Start = *fileCfg.QuietHours.Start
+		}
+		if fileCfg.QuietHours.End != nil {
+			cfg.QuietHours.End = *fileCfg.QuietHours.End
+		}
+	}
+
+	if fileCfg.Forge != nil {
+		if fileCfg.Forge.Provider != nil {
+			cfg.Forge.Provider = *fileCfg.Forge.Provider
+		}
+		if fileCfg.Forge.Repo != nil {
+			cfg.Forge.Repo = *fileCfg.Forge.Repo
+		}
+		if fileCfg.Forge.BaseBranch != nil {
+			cfg.Forge.BaseBranch = *fileCfg.Forge.BaseBranch
+		}
+		if fileCfg.Forge.APIURL != nil {
+			cfg.Forge.APIURL = *fileCfg.Forge.APIURL
+		}
+		if fileCfg.Forge.TokenEnv != nil {
+			cfg.Forge.TokenEnv = *fileCfg.Forge.TokenEnv
+		}
+	}
+
+	if fileCfg.Tracker != nil {
+		t := fileCfg.Tracker
+		for _, f := range []struct {
+			src *string
+			dst *string
+		}{
+			{t.Provider, &cfg.Tracker.Provider},
+			{t.Repo, &cfg.Tracker.Repo},
+			{t.Project, &cfg.Tracker.Project},
+			{t.IssueType, &cfg.Tracker.IssueType},
+			{t.APIURL, &cfg.Tracker.APIURL},
+			{t.User, &cfg.Tracker.User},
+			{t.TokenEnv, &cfg.Tracker.TokenEnv},
+		} {
+			if f.src != nil {
+				*f.dst = *f.src
+			}
+		}
+		if t.Templates != nil {
+			if t.Templates.Title != nil {
+				cfg.Tracker.Templates.Title = *t.Templates.Title
+			}
+			if t.Templates.Body != nil {
+				cfg.Tracker.Templates.Body = *t.Templates.Body
+			}
+			if t.Templates.Comment != nil {
+				cfg.Tracker.Templates.Comment = *t.Templates.Comment
+			}
+			if t.Templates.Close != nil {
+				cfg.Tracker.Templates.Close = *t.Templates.Close
+			}
 		}
-		return cfg, nil
 	}
 
-	// Read the config file.
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+	if fileCfg.Notify != nil {
+		if fileCfg.Notify.WebhookURL != nil {
+			cfg.Notify.WebhookURL = *fileCfg.Notify.WebhookURL
+		}
+		if fileCfg.Notify.Provider != nil {
+			cfg.Notify.Provider = *fileCfg.Notify.Provider
+		}
+		if fileCfg.Notify.Events != nil {
+			cfg.Notify.Events = fileCfg.Notify.Events
+		}
+		if fileCfg.Notify.Template != nil {
+			cfg.Notify.Template = *fileCfg.Notify.Template
+		}
+		if fileCfg.Notify.MinIntervalSeconds != nil {
+			cfg.Notify.MinIntervalSeconds = *fileCfg.Notify.MinIntervalSeconds
+		}
+		if email := fileCfg.Notify.Email; email != nil {
+			if email.SMTPHost != nil {
+				cfg.Notify.Email.SMTPHost = *email.SMTPHost
+			}
+			if email.SMTPPort != nil {
+				cfg.Notify.Email.SMTPPort = *email.SMTPPort
+			}
+			if email.Username != nil {
+				cfg.Notify.Email.Username = *email.Username
+			}
+			if email.PasswordEnv != nil {
+				cfg.Notify.Email.PasswordEnv = *email.PasswordEnv
+			}
+			if email.From != nil {
+				cfg.Notify.Email.From = *email.From
+			}
+			if email.To != nil {
+				cfg.Notify.Email.To = email.To
+			}
+			if email.TLS != nil {
+				cfg.Notify.Email.TLS = *email.TLS
+			}
+			if email.ReportURL != nil {
+				cfg.Notify.Email.ReportURL = *email.ReportURL
+			}
+		}
 	}
 
-	// Parse JSON into a temporary struct for merging.
-	var fileCfg fileConfig
-	if err := json.Unmarshal(data, &fileCfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	if fileCfg.Encryption != nil {
+		if fileCfg.Encryption.Enabled != nil {
+			cfg.Encryption.Enabled = *fileCfg.Encryption.Enabled
+		}
+		if fileCfg.Encryption.KeyEnv != nil {
+			cfg.Encryption.KeyEnv = *fileCfg.Encryption.KeyEnv
+		}
+		if fileCfg.Encryption.KeychainService != nil {
+			cfg.Encryption.KeychainService = *fileCfg.Encryption.KeychainService
+		}
 	}
 
-	// Merge file values over defaults (only non-zero values).
-	mergeConfig(cfg, &fileCfg)
-
-	// Expand paths.
-	if err := cfg.ExpandPaths(); err != nil {
-		return nil, fmt.Errorf("failed to expand paths: %w", err)
+	if fileCfg.Redaction != nil {
+		if fileCfg.Redaction.Enabled != nil {
+			cfg.Redaction.Enabled = *fileCfg.Redaction.Enabled
+		}
+		if fileCfg.Redaction.Patterns != nil {
+			cfg.Redaction.Patterns = fileCfg.Redaction.Patterns
+		}
 	}
 
-	// Validate the merged config.
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
+	if fileCfg.Database != nil {
+		if fileCfg.Database.Backend != nil {
+			cfg.Database.Backend = *fileCfg.Database.Backend
+		}
+		if fileCfg.Database.URL != nil {
+			cfg.Database.URL = *fileCfg.Database.URL
+		}
+		if fileCfg.Database.URLEnv != nil {
+			cfg.Database.URLEnv = *fileCfg.Database.URLEnv
+		}
 	}
 
-	return cfg, nil
-}
+	if fileCfg.JJ != nil {
+		if fileCfg.JJ.ChangePerIteration != nil {
+			cfg.JJ.ChangePerIteration = *fileCfg.JJ.ChangePerIteration
+		}
+		if fileCfg.JJ.SquashOnComplete != nil {
+			cfg.JJ.SquashOnComplete = *fileCfg.JJ.SquashOnComplete
+		}
+		if fileCfg.JJ.Workspaces != nil {
+			cfg.JJ.Workspaces = *fileCfg.JJ.Workspaces
+		}
+		if fileCfg.JJ.Bookmark != nil {
+			cfg.JJ.Bookmark = *fileCfg.JJ.Bookmark
+		}
+		if fileCfg.JJ.BookmarkPrefix != nil {
+			cfg.JJ.BookmarkPrefix = *fileCfg.JJ.BookmarkPrefix
+		}
+		if fileCfg.JJ.BookmarkOnComplete != nil {
+			cfg.JJ.BookmarkOnComplete = *fileCfg.JJ.BookmarkOnComplete
+		}
+	}
 
-// fileConfig is used for parsing JSON with pointer fields to detect what was set.
-type fileConfig struct {
-	DatabasePath        *string           `json:"database_path"`
-	ProjectsDir         *string           `json:"projects_dir"`
-	MaxIterations       *int              `json:"max_iterations"`
-	MaxReviewIterations *int              `json:"max_review_iterations"`
-	MaxTaskAttempts     *int              `json:"max_task_attempts"`
-	DefaultPauseMode    *bool             `json:"default_pause_mode"`
-	Claude              *fileClaudeConfig `json:"claude"`
-	Agents              *fileAgentConfig  `json:"agents"`
-}
+	if fileCfg.Team != nil {
+		if fileCfg.Team.Reviewers != nil {
+			cfg.Team.Reviewers = *fileCfg.Team.Reviewers
+		}
+		if fileCfg.Team.Quorum != nil {
+			cfg.Team.Quorum = *fileCfg.Team.Quorum
+		}
+	}
 
-type fileClaudeConfig struct {
-	Model    *string `json:"model"`
-	MaxTurns *int    `json:"max_turns"`
-	Verbose  *bool   `json:"verbose"`
-}
+	if fileCfg.SubPlans != nil {
+		if fileCfg.SubPlans.MaxDepth != nil {
+			cfg.SubPlans.MaxDepth = *fileCfg.SubPlans.MaxDepth
+		}
+		if fileCfg.SubPlans.Run != nil {
+			cfg.SubPlans.Run = *fileCfg.SubPlans.Run
+		}
+	}
 
-type fileAgentConfig struct {
-	Developer  *string `json:"developer"`
-	Reviewer   *string `json:"reviewer"`
-	Planner    *string `json:"planner"`
-	Documenter *string `json:"documenter"`
-}
+	if fileCfg.Conventions != nil {
+		if fileCfg.Conventions.Include != nil {
+			cfg.Conventions.Include = fileCfg.Conventions.Include
+		}
+		if fileCfg.Conventions.Exclude != nil {
+			cfg.Conventions.Exclude = fileCfg.Conventions.Exclude
+		}
+		if fileCfg.Conventions.MaxBytes != nil {
+			cfg.Conventions.MaxBytes = *fileCfg.Conventions.MaxBytes
+		}
+	}
 
-// mergeConfig merges file config values into the default config.
-// Only non-nil values from the file config are applied.
-func mergeConfig(cfg *Config, fileCfg *fileConfig) {
-	if fileCfg.DatabasePath != nil {
-		cfg.DatabasePath = *fileCfg.DatabasePath
+	if fileCfg.Lint != nil {
+		if fileCfg.Lint.Strict != nil {
+			cfg.Lint.Strict = *fileCfg.Lint.Strict
+		}
+		if fileCfg.Lint.MaxBytes != nil {
+			cfg.Lint.MaxBytes = *fileCfg.Lint.MaxBytes
+		}
 	}
-	if fileCfg.ProjectsDir != nil {
-		cfg.ProjectsDir = *fileCfg.ProjectsDir
+
+	if fileCfg.ReviewTriage != nil {
+		if fileCfg.ReviewTriage.Action != nil {
+			cfg.ReviewTriage.Action = *fileCfg.ReviewTriage.Action
+		}
+		if fileCfg.ReviewTriage.MaxLines != nil {
+			cfg.ReviewTriage.MaxLines = *fileCfg.ReviewTriage.MaxLines
+		}
+		if fileCfg.ReviewTriage.TrivialPaths != nil {
+			cfg.ReviewTriage.TrivialPaths = fileCfg.ReviewTriage.TrivialPaths
+		}
+		if fileCfg.ReviewTriage.Model != nil {
+			cfg.ReviewTriage.Model = *fileCfg.ReviewTriage.Model
+		}
 	}
-	if fileCfg.MaxIterations != nil {
-		cfg.MaxIterations = *fileCfg.MaxIterations
-		cfg.MaxReviewIterations = *fileCfg.MaxIterations // Keep in sync
+
+	if fileCfg.ReviewDiff != nil {
+		if fileCfg.ReviewDiff.Format != nil {
+			cfg.ReviewDiff.Format = *fileCfg.ReviewDiff.Format
+		}
+		if fileCfg.ReviewDiff.Model != nil {
+			cfg.ReviewDiff.Model = *fileCfg.ReviewDiff.Model
+		}
+		if fileCfg.ReviewDiff.Context != nil {
+			cfg.ReviewDiff.Context = *fileCfg.ReviewDiff.Context
+		}
+		if fileCfg.ReviewDiff.ContextBytes != nil {
+			cfg.ReviewDiff.ContextBytes = *fileCfg.ReviewDiff.ContextBytes
+		}
 	}
-	if fileCfg.MaxReviewIterations != nil {
-		cfg.MaxReviewIterations = *fileCfg.MaxReviewIterations
-		cfg.MaxIterations = *fileCfg.MaxReviewIterations // Keep in sync
+
+	if fileCfg.SelfCheck != nil {
+		if fileCfg.SelfCheck.Enabled != nil {
+			cfg.SelfCheck.Enabled = *fileCfg.SelfCheck.Enabled
+		}
+		if fileCfg.SelfCheck.MaxAttempts != nil {
+			cfg.SelfCheck.MaxAttempts = *fileCfg.SelfCheck.MaxAttempts
+		}
+		if fileCfg.SelfCheck.Model != nil {
+			cfg.SelfCheck.Model = *fileCfg.SelfCheck.Model
+		}
 	}
-	if fileCfg.MaxTaskAttempts != nil {
-		cfg.MaxTaskAttempts = *fileCfg.MaxTaskAttempts
+
+	if fileCfg.DifferentialPrompts != nil {
+		if fileCfg.DifferentialPrompts.Enabled != nil {
+			cfg.DifferentialPrompts.Enabled = *fileCfg.DifferentialPrompts.Enabled
+		}
+		if fileCfg.DifferentialPrompts.FullRefreshEvery != nil {
+			cfg.DifferentialPrompts.FullRefreshEvery = *fileCfg.DifferentialPrompts.FullRefreshEvery
+		}
 	}
-	if fileCfg.DefaultPauseMode != nil {
-		cfg.DefaultPauseMode = *fileCfg.DefaultPauseMode
+
+	if fileCfg.FastResume != nil {
+		if fileCfg.FastResume.Enabled != nil {
+			cfg.FastResume.Enabled = *fileCfg.FastResume.Enabled
+		}
+		if fileCfg.FastResume.ClaudeSession != nil {
+			cfg.FastResume.ClaudeSession = *fileCfg.FastResume.ClaudeSession
+		}
 	}
 
-	if fileCfg.Claude != nil {
-		if fileCfg.Claude.Model != nil {
-			cfg.Claude.Model = *fileCfg.Claude.Model
+	if fileCfg.ReviewerTests != nil {
+		if fileCfg.ReviewerTests.Enabled != nil {
+			cfg.ReviewerTests.Enabled = *fileCfg.ReviewerTests.Enabled
 		}
-		if fileCfg.Claude.MaxTurns != nil {
-			cfg.Claude.MaxTurns = *fileCfg.Claude.MaxTurns
+		if fileCfg.ReviewerTests.Command != nil {
+			cfg.ReviewerTests.Command = fileCfg.ReviewerTests.Command
 		}
-		if fileCfg.Claude.Verbose != nil {
-			cfg.Claude.Verbose = *fileCfg.Claude.Verbose
+		if fileCfg.ReviewerTests.TimeoutSeconds != nil {
+			cfg.ReviewerTests.TimeoutSeconds = *fileCfg.ReviewerTests.TimeoutSeconds
 		}
 	}
 
-	if fileCfg.Agents != nil {
-		if fileCfg.Agents.Developer != nil {
-			cfg.Agents.Developer = *fileCfg.Agents.Developer
+	if fileCfg.TUI != nil {
+		if fileCfg.TUI.Theme != nil {
+			cfg.TUI.Theme = *fileCfg.TUI.Theme
 		}
-		if fileCfg.Agents.Reviewer != nil {
-			cfg.Agents.Reviewer = *fileCfg.Agents.Reviewer
+		if fileCfg.TUI.Accessible != nil {
+			cfg.TUI.Accessible = *fileCfg.TUI.Accessible
 		}
-		if fileCfg.Agents.Planner != nil {
-			cfg.Agents.Planner = *fileCfg.Agents.Planner
+		if fileCfg.TUI.Scrollback != nil {
+			cfg.TUI.Scrollback = *fileCfg.TUI.Scrollback
 		}
-		if fileCfg.Agents.Documenter != nil {
-			cfg.Agents.Documenter = *fileCfg.Agents.Documenter
+		if fileCfg.TUI.ScrollbackMaxMB != nil {
+			cfg.TUI.ScrollbackMaxMB = *fileCfg.TUI.ScrollbackMaxMB
 		}
 	}
 }
 
+// mergeClaudeRoleConfig merges a role's file config over the current values.
+func mergeClaudeRoleConfig(role *ClaudeRoleConfig, fileRole *fileClaudeRoleConfig) {
+	if fileRole == nil {
+		return
+	}
+	if fileRole.PermissionMode != nil {
+		role.PermissionMode = *fileRole.PermissionMode
+	}
+	if fileRole.AllowedTools != nil {
+		role.AllowedTools = fileRole.AllowedTools
+	}
+	if fileRole.ExtraArgs != nil {
+		role.ExtraArgs = fileRole.ExtraArgs
+	}
+}
+
 // Validate checks that all config values are valid.
 func (c *Config) Validate() error {
 	var errs []error
@@ -207,11 +1653,45 @@ func (c *Config) Validate() error {
 	if c.Claude.Model == "" {
 		errs = append(errs, errors.New("claude.model must be non-empty"))
 	}
+	for i, model := range c.Claude.FallbackModels {
+		if model == "" {
+			errs = append(errs, fmt.Errorf("claude.fallback_models[%d] must be non-empty", i))
+		} else if slices.Contains(c.Claude.ModelChain()[:i+1], model) {
+			errs = append(errs, fmt.Errorf("claude.fallback_models[%d]: %q is already in the chain", i, model))
+		}
+	}
 
 	if c.Claude.MaxTurns < 1 {
 		errs = append(errs, errors.New("claude.max_turns must be >= 1"))
 	}
 
+	if c.Claude.Liveness.HeartbeatSeconds < 0 {
+		errs = append(errs, errors.New("claude.liveness.heartbeat_seconds must be >= 0"))
+	}
+	if c.Claude.Liveness.StalledAfterSeconds < 0 {
+		errs = append(errs, errors.New("claude.liveness.stalled_after_seconds must be >= 0"))
+	}
+	if c.Claude.Liveness.IdleTimeoutSeconds < 0 {
+		errs = append(errs, errors.New("claude.liveness.idle_timeout_seconds must be >= 0"))
+	}
+	switch c.Claude.Backend {
+	case "", ClaudeBackendCLI, ClaudeBackendAPI:
+	default:
+		errs = append(errs, fmt.Errorf("claude.backend must be %q or %q, got %q", ClaudeBackendCLI, ClaudeBackendAPI, c.Claude.Backend))
+	}
+	if c.Claude.API.MaxTokens < 0 {
+		errs = append(errs, errors.New("claude.api.max_tokens must be >= 0"))
+	}
+	if c.Claude.API.TimeoutSeconds < 0 {
+		errs = append(errs, errors.New("claude.api.timeout_seconds must be >= 0"))
+	}
+	switch c.Claude.StatusReporting {
+	case "", StatusReportingMarkdown, StatusReportingTool:
+	default:
+		errs = append(errs, fmt.Errorf("claude.status_reporting must be %q or %q, got %q",
+			StatusReportingMarkdown, StatusReportingTool, c.Claude.StatusReporting))
+	}
+
 	// Validate agent prompt paths if set.
 	if c.Agents.Developer != "" {
 		if _, err := os.Stat(c.Agents.Developer); os.IsNotExist(err) {
@@ -237,6 +1717,282 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	for _, p := range c.Permissions.AllowedPaths {
+		if filepath.IsAbs(p) || strings.HasPrefix(filepath.Clean(p), "..") {
+			errs = append(errs, fmt.Errorf("permissions.allowed_paths must be repo-relative: %s", p))
+		}
+	}
+
+	if c.Stall.Threshold < 0 {
+		errs = append(errs, errors.New("stall.threshold must be >= 0"))
+	}
+
+	switch c.Stall.Action {
+	case "", StallActionNudge, StallActionAbort:
+	default:
+		errs = append(errs, fmt.Errorf("stall.action must be %q or %q, got %q",
+			StallActionNudge, StallActionAbort, c.Stall.Action))
+	}
+
+	if c.FailureTriage.After < 0 {
+		errs = append(errs, errors.New("failure_triage.after must be >= 0"))
+	}
+
+	if c.GlobalLearningsLimit < 0 {
+		errs = append(errs, errors.New("global_learnings_limit must be >= 0"))
+	}
+
+	if c.ExtremeBonusIterations < 0 {
+		errs = append(errs, errors.New("extreme_bonus_iterations must be >= 0"))
+	}
+	if c.ExtremeMaxRounds < 0 {
+		errs = append(errs, errors.New("extreme_max_rounds must be >= 0"))
+	}
+
+	if c.Retry.MaxAttempts < 0 {
+		errs = append(errs, errors.New("retry.max_attempts must be >= 0"))
+	}
+
+	if c.Retry.MaxRateLimitWaitSeconds < 0 {
+		errs = append(errs, errors.New("retry.max_rate_limit_wait_seconds must be >= 0"))
+	}
+
+	if c.Retry.InitialBackoffSeconds < 0 || c.Retry.MaxBackoffSeconds < 0 {
+		errs = append(errs, errors.New("retry backoff seconds must be >= 0"))
+	}
+
+	if c.Retry.MaxBackoffSeconds > 0 && c.Retry.InitialBackoffSeconds > c.Retry.MaxBackoffSeconds {
+		errs = append(errs, errors.New("retry.initial_backoff_seconds must be <= retry.max_backoff_seconds"))
+	}
+
+	if c.Throttle.MaxSessionsPerHour < 0 || c.Throttle.MaxCostPerHour < 0 {
+		errs = append(errs, errors.New("throttle limits must be >= 0"))
+	}
+
+	if c.QuietHours.Enabled() {
+		if _, _, err := c.QuietHours.Window(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	switch c.Forge.Provider {
+	case "", ForgeProviderGitHub, ForgeProviderGitLab:
+	default:
+		errs = append(errs, fmt.Errorf("forge.provider must be %q or %q, got %q",
+			ForgeProviderGitHub, ForgeProviderGitLab, c.Forge.Provider))
+	}
+
+	switch c.Tracker.Provider {
+	case "":
+	case TrackerProviderGitHub:
+		if c.Tracker.Repo == "" {
+			errs = append(errs, errors.New("tracker.repo must be set for the github tracker"))
+		}
+	case TrackerProviderJira:
+		if c.Tracker.APIURL == "" || c.Tracker.Project == "" {
+			errs = append(errs, errors.New("tracker.api_url and tracker.project must be set for the jira tracker"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracker.provider must be %q or %q, got %q",
+			TrackerProviderGitHub, TrackerProviderJira, c.Tracker.Provider))
+	}
+
+	switch c.Notify.Provider {
+	case "", NotifyProviderSlack, NotifyProviderDiscord:
+	default:
+		errs = append(errs, fmt.Errorf("notify.provider must be %q or %q, got %q",
+			NotifyProviderSlack, NotifyProviderDiscord, c.Notify.Provider))
+	}
+
+	for _, event := range c.Notify.Events {
+		if !slices.Contains(loop.EventTypes, loop.EventType(event)) {
+			errs = append(errs, fmt.Errorf("notify.events has an unknown event %q", event))
+		}
+	}
+
+	if c.Notify.MinIntervalSeconds < 0 {
+		errs = append(errs, errors.New("notify.min_interval_seconds must be >= 0"))
+	}
+
+	email := c.Notify.Email
+	switch email.TLS {
+	case "", notify.EmailTLSStartTLS, notify.EmailTLSImplicit, notify.EmailTLSNone:
+	default:
+		errs = append(errs, fmt.Errorf("notify.email.tls must be %q, %q, or %q, got %q",
+			notify.EmailTLSStartTLS, notify.EmailTLSImplicit, notify.EmailTLSNone, email.TLS))
+	}
+	if err := notify.CheckReportURL(email.ReportURL); err != nil {
+		errs = append(errs, fmt.Errorf("notify.email.report_url: %w", err))
+	}
+	if email.SMTPPort < 0 || email.SMTPPort > 65535 {
+		errs = append(errs, errors.New("notify.email.smtp_port must be between 0 and 65535"))
+	}
+	if email.SMTPHost != "" && (email.From == "" || len(email.To) == 0) {
+		errs = append(errs, errors.New("notify.email needs from and to when smtp_host is set"))
+	}
+
+	if _, ok := locale.Get(c.Locale); !ok {
+		errs = append(errs, fmt.Errorf("locale must be one of %s, got %q", strings.Join(locale.Codes(), ", "), c.Locale))
+	}
+
+	if err := ValidatePlanRefresh(c.PlanRefresh); err != nil {
+		errs = append(errs, fmt.Errorf("plan_refresh: %w", err))
+	}
+	switch c.ConflictResolution {
+	case "", ConflictResolutionOff, ConflictResolutionWait, ConflictResolutionResolve:
+	default:
+		errs = append(errs, fmt.Errorf("conflict_resolution must be %q, %q, or %q, got %q",
+			ConflictResolutionOff, ConflictResolutionWait, ConflictResolutionResolve, c.ConflictResolution))
+	}
+	switch c.OutOfScopeFiles {
+	case "", OutOfScopeRevert, OutOfScopeFlag:
+	default:
+		errs = append(errs, fmt.Errorf("out_of_scope_files must be %q or %q, got %q",
+			OutOfScopeRevert, OutOfScopeFlag, c.OutOfScopeFiles))
+	}
+
+	for i, a := range c.Analyzers {
+		if a.Name == "" || len(a.Command) == 0 {
+			errs = append(errs, fmt.Errorf("analyzers[%d] must have a name and a command", i))
+		}
+		if a.TimeoutSeconds < 0 {
+			errs = append(errs, fmt.Errorf("analyzers[%d].timeout_seconds must be >= 0", i))
+		}
+	}
+	for i, c := range c.Checks {
+		if c.Name == "" || len(c.Command) == 0 {
+			errs = append(errs, fmt.Errorf("checks[%d] must have a name and a command", i))
+		}
+		if c.TimeoutSeconds < 0 {
+			errs = append(errs, fmt.Errorf("checks[%d].timeout_seconds must be >= 0", i))
+		}
+	}
+	for i, h := range c.Hooks {
+		if h.Name == "" || len(h.Command) == 0 {
+			errs = append(errs, fmt.Errorf("hooks[%d] must have a name and a command", i))
+		}
+		switch h.On {
+		case HookPreIteration, HookPostIteration, HookOnDone:
+		default:
+			errs = append(errs, fmt.Errorf("hooks[%d].on must be %q, %q, or %q, got %q",
+				i, HookPreIteration, HookPostIteration, HookOnDone, h.On))
+		}
+		if h.TimeoutSeconds < 0 {
+			errs = append(errs, fmt.Errorf("hooks[%d].timeout_seconds must be >= 0", i))
+		}
+	}
+
+	switch c.JJ.BookmarkOnComplete {
+	case "", BookmarkKeep, BookmarkDelete:
+	default:
+		errs = append(errs, fmt.Errorf("jj.bookmark_on_complete must be %q or %q, got %q",
+			BookmarkKeep, BookmarkDelete, c.JJ.BookmarkOnComplete))
+	}
+	if c.JJ.Bookmark && strings.TrimSpace(c.JJ.BookmarkPrefix) == "" {
+		errs = append(errs, errors.New("jj.bookmark_prefix must be set when jj.bookmark is enabled"))
+	}
+
+	switch c.Database.Backend {
+	case "", DatabaseBackendSQLite, DatabaseBackendPostgres:
+	default:
+		errs = append(errs, fmt.Errorf("database.backend must be %q or %q, got %q",
+			DatabaseBackendSQLite, DatabaseBackendPostgres, c.Database.Backend))
+	}
+
+	if c.Team.Reviewers < 0 {
+		errs = append(errs, errors.New("team.reviewers must be >= 0"))
+	}
+
+	if c.Team.Quorum < 0 {
+		errs = append(errs, errors.New("team.quorum must be >= 0"))
+	} else if c.Team.Quorum > max(c.Team.Reviewers, 1) {
+		errs = append(errs, errors.New("team.quorum must be <= team.reviewers"))
+	}
+
+	if c.SubPlans.MaxDepth < 0 {
+		errs = append(errs, errors.New("sub_plans.max_depth must be >= 0"))
+	}
+
+	for _, list := range []struct {
+		name  string
+		globs []string
+	}{{"conventions.include", c.Conventions.Include}, {"conventions.exclude", c.Conventions.Exclude}} {
+		for _, g := range list.globs {
+			if _, err := path.Match(g, ""); err != nil || path.IsAbs(g) || strings.HasPrefix(path.Clean(g), "..") {
+				errs = append(errs, fmt.Errorf("%s must be valid repo-relative globs: %s", list.name, g))
+			}
+		}
+	}
+
+	if c.Conventions.MaxBytes < 0 {
+		errs = append(errs, errors.New("conventions.max_bytes must be >= 0"))
+	}
+	if c.Lint.MaxBytes < 0 {
+		errs = append(errs, errors.New("lint.max_bytes must be >= 0"))
+	}
+
+	switch c.ReviewTriage.Action {
+	case "", ReviewTriageOff, ReviewTriageSkip:
+	case ReviewTriageDowngrade:
+		if c.ReviewTriage.Model == "" {
+			errs = append(errs, errors.New("review_triage.model must be non-empty with action \"downgrade\""))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("review_triage.action must be %q, %q, or %q, got %q",
+			ReviewTriageOff, ReviewTriageSkip, ReviewTriageDowngrade, c.ReviewTriage.Action))
+	}
+
+	if c.ReviewTriage.MaxLines < 0 {
+		errs = append(errs, errors.New("review_triage.max_lines must be >= 0"))
+	}
+
+	for _, p := range c.ReviewTriage.TrivialPaths {
+		if filepath.IsAbs(p) || strings.HasPrefix(filepath.Clean(p), "..") {
+			errs = append(errs, fmt.Errorf("review_triage.trivial_paths must be repo-relative: %s", p))
+		}
+	}
+
+	switch c.ReviewDiff.Format {
+	case "", ReviewDiffRaw, ReviewDiffSummary:
+	default:
+		errs = append(errs, fmt.Errorf("review_diff.format must be %q or %q, got %q",
+			ReviewDiffRaw, ReviewDiffSummary, c.ReviewDiff.Format))
+	}
+	switch c.ReviewDiff.Context {
+	case "", ReviewContextDiff, ReviewContextFiles:
+	default:
+		errs = append(errs, fmt.Errorf("review_diff.context must be %q or %q, got %q",
+			ReviewContextDiff, ReviewContextFiles, c.ReviewDiff.Context))
+	}
+	if c.ReviewDiff.ContextBytes < 0 {
+		errs = append(errs, errors.New("review_diff.context_bytes must be >= 0"))
+	}
+
+	if c.SelfCheck.Enabled && c.SelfCheck.MaxAttempts < 1 {
+		errs = append(errs, errors.New("self_check.max_attempts must be >= 1 when self_check is enabled"))
+	}
+	if c.DifferentialPrompts.Enabled && c.DifferentialPrompts.FullRefreshEvery < 1 {
+		errs = append(errs, errors.New("differential_prompts.full_refresh_every must be >= 1 when differential_prompts is enabled"))
+	}
+	if c.ReviewerTests.Enabled && len(c.ReviewerTests.Command) == 0 {
+		errs = append(errs, errors.New("reviewer_tests.command must be set when reviewer_tests is enabled"))
+	}
+	if c.ReviewerTests.TimeoutSeconds < 0 {
+		errs = append(errs, errors.New("reviewer_tests.timeout_seconds must be >= 0"))
+	}
+	if err := ValidateTheme(c.TUI.Theme); err != nil {
+		errs = append(errs, fmt.Errorf("tui.theme: %w", err))
+	}
+	if c.TUI.Scrollback && c.TUI.ScrollbackMaxMB < 1 {
+		errs = append(errs, errors.New("tui.scrollback_max_mb must be >= 1 when tui.scrollback is enabled"))
+	}
+
+	for _, p := range c.Redaction.Patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			errs = append(errs, fmt.Errorf("redaction.patterns has an invalid regular expression %q: %w", p, err))
+		}
+	}
+
 	if len(errs) > 0 {
 		return errors.Join(errs...)
 	}
@@ -244,6 +2000,47 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// ValidateTheme checks that name is a TUI theme.
+func ValidateTheme(name string) error {
+	switch name {
+	case "", ThemeDark, ThemeLight, ThemeHighContrast, ThemeNoColor:
+		return nil
+	}
+	return fmt.Errorf("must be %q, %q, %q, or %q, got %q", ThemeDark, ThemeLight, ThemeHighContrast, ThemeNoColor, name)
+}
+
+// ModelChain returns the models sessions try in order: Model, then its
+// fallbacks.
+func (c ClaudeConfig) ModelChain() []string {
+	return append([]string{c.Model}, c.FallbackModels...)
+}
+
+// ParseModelChain parses a model with its fallbacks, separated by commas
+// (e.g. "opus,sonnet,haiku"), as --model takes them.
+func ParseModelChain(chain string) ([]string, error) {
+	var models []string
+	for _, model := range strings.Split(chain, ",") {
+		model = strings.TrimSpace(model)
+		if model == "" {
+			return nil, fmt.Errorf("empty model in %q", chain)
+		}
+		if slices.Contains(models, model) {
+			return nil, fmt.Errorf("%q appears twice in %q", model, chain)
+		}
+		models = append(models, model)
+	}
+	return models, nil
+}
+
+// ValidatePlanRefresh checks that mode is a plan refresh mode.
+func ValidatePlanRefresh(mode string) error {
+	switch mode {
+	case "", PlanRefreshOff, PlanRefreshDetect, PlanRefreshMerge:
+		return nil
+	}
+	return fmt.Errorf("must be %q, %q, or %q, got %q", PlanRefreshOff, PlanRefreshDetect, PlanRefreshMerge, mode)
+}
+
 // ExpandPaths expands ~ to home directory in all path fields.
 func (c *Config) ExpandPaths() error {
 	if c.expandedPaths {
@@ -353,6 +2150,12 @@ func EnsureConfigDir() (string, error) {
 	return configDir, nil
 }
 
+// LocalConfigPath returns the project-local config path relative to the
+// current working directory.
+func LocalConfigPath() string {
+	return filepath.Join(LocalDir, localConfigFile)
+}
+
 // GetConfigPath returns the default config file path (expanded).
 func GetConfigPath() (string, error) {
 	return expandPath(defaultConfigPath)
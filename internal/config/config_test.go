@@ -1,10 +1,16 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/notify"
+	"github.com/gerunddev/ralph/internal/planlint"
 )
 
 func TestLoadFromPath_MissingFile(t *testing.T) {
@@ -484,6 +490,35 @@ func TestGetAgentPrompt_CustomPath(t *testing.T) {
 	}
 }
 
+func TestGetAgentPrompt_RelativeToConfigFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	configDir := filepath.Join(tmpDir, ".ralph")
+	if err := os.MkdirAll(filepath.Join(configDir, "prompts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	customPrompt := "Custom reviewer prompt content"
+	if err := os.WriteFile(filepath.Join(configDir, "prompts", "reviewer.md"), []byte(customPrompt), 0644); err != nil {
+		t.Fatalf("failed to write custom prompt: %v", err)
+	}
+	configPath := filepath.Join(configDir, "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"agents": {"reviewer": "prompts/reviewer.md"}}`), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	// The test runs in the package directory, not next to the config
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	prompt, err := cfg.GetAgentPrompt("reviewer")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prompt != customPrompt {
+		t.Errorf("expected '%s', got '%s'", customPrompt, prompt)
+	}
+}
+
 func TestEnsureConfigDir(t *testing.T) {
 	// This test verifies EnsureConfigDir creates the directory.
 	// We can't easily test the actual ~/.config/ralph without side effects,
@@ -681,3 +716,1331 @@ func TestExpandPaths_OnlyOnce(t *testing.T) {
 		t.Errorf("expected ProjectsDir to remain ~/different/path (unexpanded), got %s", cfg.ProjectsDir)
 	}
 }
+
+func TestLoadFromPaths_LaterOverridesEarlier(t *testing.T) {
+	tmpDir := t.TempDir()
+	globalPath := filepath.Join(tmpDir, "global.json")
+	localPath := filepath.Join(tmpDir, "local.json")
+
+	if err := os.WriteFile(globalPath, []byte(`{"max_iterations": 20, "claude": {"model": "sonnet"}}`), 0644); err != nil {
+		t.Fatalf("failed to write global config: %v", err)
+	}
+	if err := os.WriteFile(localPath, []byte(`{"max_iterations": 5}`), 0644); err != nil {
+		t.Fatalf("failed to write local config: %v", err)
+	}
+
+	cfg, err := LoadFromPaths(globalPath, localPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.MaxIterations != 5 {
+		t.Errorf("expected local max_iterations=5, got %d", cfg.MaxIterations)
+	}
+	if cfg.Claude.Model != "sonnet" {
+		t.Errorf("expected global model=sonnet to survive, got %s", cfg.Claude.Model)
+	}
+}
+
+func TestLoadFromPaths_SkipsMissing(t *testing.T) {
+	tmpDir := t.TempDir()
+	localPath := filepath.Join(tmpDir, "local.json")
+	if err := os.WriteFile(localPath, []byte(`{"max_iterations": 7}`), 0644); err != nil {
+		t.Fatalf("failed to write local config: %v", err)
+	}
+
+	cfg, err := LoadFromPaths(filepath.Join(tmpDir, "missing.json"), localPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.MaxIterations != 7 {
+		t.Errorf("expected max_iterations=7, got %d", cfg.MaxIterations)
+	}
+}
+
+func TestLoadForDir(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	workDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workDir, LocalDir), 0755); err != nil {
+		t.Fatalf("failed to create local dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, LocalConfigPath()), []byte(`{"max_iterations": 9}`), 0644); err != nil {
+		t.Fatalf("failed to write local config: %v", err)
+	}
+
+	cfg, err := LoadForDir(workDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.MaxIterations != 9 {
+		t.Errorf("expected max_iterations=9 from the work dir's local config, got %d", cfg.MaxIterations)
+	}
+}
+
+func TestLocalConfigPath(t *testing.T) {
+	if got := LocalConfigPath(); got != filepath.Join(".ralph", "config.json") {
+		t.Errorf("unexpected local config path: %s", got)
+	}
+}
+
+func TestLoadFromPath_Permissions(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.json")
+
+	configJSON := `{"permissions": {"allowed_paths": ["internal/"], "forbidden_commands": ["rm"], "network": false}}`
+	if err := os.WriteFile(configPath, []byte(configJSON), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(cfg.Permissions.AllowedPaths) != 1 || cfg.Permissions.AllowedPaths[0] != "internal/" {
+		t.Errorf("unexpected allowed_paths: %v", cfg.Permissions.AllowedPaths)
+	}
+	if len(cfg.Permissions.ForbiddenCommands) != 1 || cfg.Permissions.ForbiddenCommands[0] != "rm" {
+		t.Errorf("unexpected forbidden_commands: %v", cfg.Permissions.ForbiddenCommands)
+	}
+	if cfg.Permissions.Network {
+		t.Error("expected network=false")
+	}
+}
+
+func TestDefaultConfig_NetworkAllowed(t *testing.T) {
+	if !DefaultConfig().Permissions.Network {
+		t.Error("expected network to be allowed by default")
+	}
+}
+
+func TestValidate_AbsoluteAllowedPath(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Permissions.AllowedPaths = []string{"/etc"}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "allowed_paths") {
+		t.Errorf("expected allowed_paths error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_Stall(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"stall": {"threshold": 5, "action": "abort"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Stall.Threshold != 5 {
+		t.Errorf("expected threshold 5, got %d", cfg.Stall.Threshold)
+	}
+	if cfg.Stall.Action != StallActionAbort {
+		t.Errorf("expected action abort, got %q", cfg.Stall.Action)
+	}
+}
+
+func TestValidate_InvalidStall(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Stall.Threshold = -1
+	cfg.Stall.Action = "panic"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	if !strings.Contains(err.Error(), "stall.threshold") || !strings.Contains(err.Error(), "stall.action") {
+		t.Errorf("expected stall errors, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_FailureTriage(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.FailureTriage.After != 3 {
+		t.Errorf("expected default failure_triage.after 3, got %d", cfg.FailureTriage.After)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"failure_triage": {"after": 5, "model": "haiku"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.FailureTriage.After != 5 || cfg.FailureTriage.Model != "haiku" {
+		t.Errorf("unexpected failure_triage: %+v", cfg.FailureTriage)
+	}
+
+	cfg.FailureTriage.After = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "failure_triage.after") {
+		t.Errorf("expected failure_triage.after error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_QuietHours(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"quiet_hours": {"start": "23:30", "end": "07:00"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	start, end, err := cfg.QuietHours.Window()
+	if err != nil {
+		t.Fatalf("Window() error: %v", err)
+	}
+	if start != 23*time.Hour+30*time.Minute || end != 7*time.Hour {
+		t.Errorf("Window() = %s, %s; want 23h30m, 7h", start, end)
+	}
+
+	for _, q := range []QuietHoursConfig{{Start: "1am", End: "07:00"}, {Start: "01:00"}, {Start: "07:00", End: "07:00"}} {
+		cfg.QuietHours = q
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "quiet_hours") {
+			t.Errorf("Validate() with %+v = %v, want a quiet_hours error", q, err)
+		}
+	}
+}
+
+func TestLoadFromPath_Retry(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"retry": {"max_attempts": 5, "initial_backoff_seconds": 1, "max_rate_limit_wait_seconds": 600}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Retry.MaxAttempts != 5 {
+		t.Errorf("expected max_attempts 5, got %d", cfg.Retry.MaxAttempts)
+	}
+	if cfg.Retry.InitialBackoffSeconds != 1 {
+		t.Errorf("expected initial_backoff_seconds 1, got %d", cfg.Retry.InitialBackoffSeconds)
+	}
+	if cfg.Retry.MaxBackoffSeconds != 60 {
+		t.Errorf("expected default max_backoff_seconds 60, got %d", cfg.Retry.MaxBackoffSeconds)
+	}
+	if cfg.Retry.MaxRateLimitWaitSeconds != 600 {
+		t.Errorf("expected max_rate_limit_wait_seconds 600, got %d", cfg.Retry.MaxRateLimitWaitSeconds)
+	}
+}
+
+func TestValidate_InvalidRetry(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Retry.InitialBackoffSeconds = 120
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "retry.initial_backoff_seconds") {
+		t.Errorf("expected retry backoff error, got: %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Retry.MaxRateLimitWaitSeconds = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "retry.max_rate_limit_wait_seconds") {
+		t.Errorf("expected rate limit wait error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_ClaudeLiveness(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"claude": {"liveness": {"heartbeat_seconds": 0, "idle_timeout_seconds": 900}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Claude.Liveness.HeartbeatSeconds != 0 {
+		t.Errorf("expected heartbeat_seconds 0, got %d", cfg.Claude.Liveness.HeartbeatSeconds)
+	}
+	if cfg.Claude.Liveness.StalledAfterSeconds != 300 {
+		t.Errorf("expected default stalled_after_seconds 300, got %d", cfg.Claude.Liveness.StalledAfterSeconds)
+	}
+	if cfg.Claude.Liveness.IdleTimeoutSeconds != 900 {
+		t.Errorf("expected idle_timeout_seconds 900, got %d", cfg.Claude.Liveness.IdleTimeoutSeconds)
+	}
+	if cfg.Claude.Model != "opus" {
+		t.Errorf("expected default model to be kept, got %q", cfg.Claude.Model)
+	}
+}
+
+func TestValidate_InvalidClaudeLiveness(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Claude.Liveness.IdleTimeoutSeconds = -1
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "claude.liveness.idle_timeout_seconds") {
+		t.Errorf("expected idle timeout error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_ClaudeFallbackModels(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"claude": {"fallback_models": ["sonnet", "haiku"]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if chain := cfg.Claude.ModelChain(); !slices.Equal(chain, []string{"opus", "sonnet", "haiku"}) {
+		t.Errorf("ModelChain() = %q", chain)
+	}
+
+	cfg.Claude.FallbackModels = []string{"sonnet", "opus"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `"opus" is already in the chain`) {
+		t.Errorf("expected repeated model error, got: %v", err)
+	}
+}
+
+func TestParseModelChain(t *testing.T) {
+	if chain, err := ParseModelChain("opus, sonnet,haiku"); err != nil || !slices.Equal(chain, []string{"opus", "sonnet", "haiku"}) {
+		t.Errorf("ParseModelChain() = %q, %v", chain, err)
+	}
+	for _, chain := range []string{"", "opus,,haiku", "opus,opus"} {
+		if _, err := ParseModelChain(chain); err == nil {
+			t.Errorf("ParseModelChain(%q) should fail", chain)
+		}
+	}
+}
+
+func TestLoadFromPath_ClaudeAPIBackend(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	content := `{"claude": {"backend": "api", "api": {"api_key_env": "RALPH_TEST_KEY", "max_tokens": 8000}}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("RALPH_TEST_KEY", "sk-test")
+
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Claude.Backend != ClaudeBackendAPI || cfg.Claude.API.MaxTokens != 8000 || cfg.Claude.API.Key() != "sk-test" {
+		t.Errorf("unexpected claude config %+v", cfg.Claude)
+	}
+
+	cfg.Claude.Backend = "sdk"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "claude.backend") {
+		t.Errorf("expected backend error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_SelfCheck(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"self_check": {"enabled": true, "max_attempts": 2}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.SelfCheck.Enabled || cfg.SelfCheck.MaxAttempts != 2 || cfg.SelfCheck.Model != "haiku" {
+		t.Errorf("unexpected self_check config %+v", cfg.SelfCheck)
+	}
+
+	cfg.SelfCheck.MaxAttempts = 0
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "self_check.max_attempts") {
+		t.Errorf("expected max attempts error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_ReviewerTests(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"reviewer_tests": {"enabled": true, "command": ["go", "test", "./..."]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.ReviewerTests.Enabled || len(cfg.ReviewerTests.Command) != 3 || cfg.ReviewerTests.TimeoutSeconds != 0 {
+		t.Errorf("unexpected reviewer_tests config %+v", cfg.ReviewerTests)
+	}
+
+	cfg.ReviewerTests.Command = nil
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "reviewer_tests.command") {
+		t.Errorf("expected command error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_ClaudeRoleOptions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	content := `{
+		"claude": {
+			"reviewer": {"permission_mode": "plan", "allowed_tools": ["Read", "Grep"]},
+			"developer": {"extra_args": ["--add-dir=../shared"]}
+		}
+	}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Claude.Model != "opus" {
+		t.Errorf("expected default model to be kept, got %q", cfg.Claude.Model)
+	}
+	if cfg.Claude.Reviewer.PermissionMode != "plan" {
+		t.Errorf("expected reviewer permission mode plan, got %q", cfg.Claude.Reviewer.PermissionMode)
+	}
+	if len(cfg.Claude.Reviewer.AllowedTools) != 2 {
+		t.Errorf("expected 2 reviewer allowed tools, got %v", cfg.Claude.Reviewer.AllowedTools)
+	}
+	if len(cfg.Claude.Developer.ExtraArgs) != 1 || cfg.Claude.Developer.ExtraArgs[0] != "--add-dir=../shared" {
+		t.Errorf("unexpected developer extra args: %v", cfg.Claude.Developer.ExtraArgs)
+	}
+	if cfg.Claude.Developer.PermissionMode != "" {
+		t.Errorf("expected empty developer permission mode, got %q", cfg.Claude.Developer.PermissionMode)
+	}
+}
+
+func TestValidate_NegativeGlobalLearningsLimit(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.GlobalLearningsLimit = -1
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "global_learnings_limit") {
+		t.Errorf("expected global_learnings_limit error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_CommitTrailers(t *testing.T) {
+	if !DefaultConfig().CommitTrailers {
+		t.Error("expected commit trailers to be enabled by default")
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"commit_trailers": false}`), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.CommitTrailers {
+		t.Error("expected commit_trailers=false")
+	}
+}
+
+func TestLoadFromPath_JJ(t *testing.T) {
+	defaults := DefaultConfig().JJ
+	if defaults.ChangePerIteration || defaults.SquashOnComplete || !defaults.Workspaces {
+		t.Errorf("expected only workspaces on by default, got %+v", defaults)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{"jj": {"change_per_iteration": true, "squash_on_complete": true, "workspaces": false}}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.JJ.ChangePerIteration || !cfg.JJ.SquashOnComplete || cfg.JJ.Workspaces {
+		t.Errorf("expected the jj options from the file, got %+v", cfg.JJ)
+	}
+}
+
+func TestLoadFromPath_JJBookmark(t *testing.T) {
+	defaults := DefaultConfig().JJ
+	if defaults.Bookmark || defaults.BookmarkPrefix != "ralph/" || defaults.BookmarkOnComplete != BookmarkKeep {
+		t.Errorf("unexpected bookmark defaults: %+v", defaults)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{"jj": {"bookmark": true, "bookmark_prefix": "wip/", "bookmark_on_complete": "delete"}}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.JJ.Bookmark || cfg.JJ.BookmarkPrefix != "wip/" || cfg.JJ.BookmarkOnComplete != BookmarkDelete {
+		t.Errorf("expected the bookmark options from the file, got %+v", cfg.JJ)
+	}
+
+	cfg.JJ.BookmarkPrefix = ""
+	cfg.JJ.BookmarkOnComplete = "push"
+	err = cfg.Validate()
+	for _, want := range []string{"jj.bookmark_on_complete must be", "jj.bookmark_prefix must be set"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q error, got: %v", want, err)
+		}
+	}
+}
+
+func TestLoadFromPath_SubPlans(t *testing.T) {
+	defaults := DefaultConfig().SubPlans
+	if defaults.MaxDepth != 0 || !defaults.Run {
+		t.Errorf("expected sub-plans off, and run once enabled, by default: %+v", defaults)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"sub_plans": {"max_depth": 2, "run": false}}`), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.SubPlans.MaxDepth != 2 || cfg.SubPlans.Run {
+		t.Errorf("expected the sub_plans options from the file, got %+v", cfg.SubPlans)
+	}
+
+	cfg.SubPlans.MaxDepth = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "sub_plans.max_depth") {
+		t.Errorf("expected sub_plans.max_depth error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_Extreme(t *testing.T) {
+	defaults := DefaultConfig()
+	if defaults.ExtremeBonusIterations != 3 || defaults.ExtremeMaxRounds != 1 {
+		t.Errorf("expected one round of 3 bonus iterations by default, got %d rounds of %d",
+			defaults.ExtremeMaxRounds, defaults.ExtremeBonusIterations)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"extreme_bonus_iterations": 5, "extreme_max_rounds": 2}`), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.ExtremeBonusIterations != 5 || cfg.ExtremeMaxRounds != 2 {
+		t.Errorf("expected the extreme mode options from the file, got %d rounds of %d",
+			cfg.ExtremeMaxRounds, cfg.ExtremeBonusIterations)
+	}
+
+	cfg.ExtremeBonusIterations = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "extreme_bonus_iterations") {
+		t.Errorf("expected extreme_bonus_iterations error, got: %v", err)
+	}
+	cfg.ExtremeBonusIterations = 5
+	cfg.ExtremeMaxRounds = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "extreme_max_rounds") {
+		t.Errorf("expected extreme_max_rounds error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_FastResume(t *testing.T) {
+	defaults := DefaultConfig().FastResume
+	if !defaults.Enabled || defaults.ClaudeSession {
+		t.Errorf("expected fast resume on, without resuming Claude sessions, by default: %+v", defaults)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"fast_resume": {"enabled": false, "claude_session": true}}`), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.FastResume.Enabled || !cfg.FastResume.ClaudeSession {
+		t.Errorf("expected the fast_resume options from the file, got %+v", cfg.FastResume)
+	}
+}
+
+func TestLoadFromPath_Team(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"team": {"reviewers": 3, "quorum": 2}}`), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Team.Reviewers != 3 || cfg.Team.Quorum != 2 {
+		t.Errorf("unexpected team config: %+v", cfg.Team)
+	}
+}
+
+func TestValidate_InvalidTeam(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Team.Reviewers = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "team.reviewers") {
+		t.Errorf("expected a team.reviewers error, got: %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Team.Reviewers = 2
+	cfg.Team.Quorum = 3
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "team.quorum") {
+		t.Errorf("expected a team.quorum error, got: %v", err)
+	}
+
+	cfg.Team.Quorum = 2
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadFromPath_Conventions(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{"conventions": {"include": ["CLAUDE.md", "docs/*.md"], "exclude": ["docs/CHANGELOG.md"]}}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(cfg.Conventions.Include, ",") != "CLAUDE.md,docs/*.md" || strings.Join(cfg.Conventions.Exclude, ",") != "docs/CHANGELOG.md" {
+		t.Errorf("unexpected conventions config: %+v", cfg.Conventions)
+	}
+	if cfg.Conventions.MaxBytes != DefaultConfig().Conventions.MaxBytes {
+		t.Errorf("expected the default max_bytes, got %d", cfg.Conventions.MaxBytes)
+	}
+}
+
+func TestValidate_InvalidConventions(t *testing.T) {
+	for _, include := range []string{"[CLAUDE.md", "/etc/motd", "../CLAUDE.md"} {
+		cfg := DefaultConfig()
+		cfg.Conventions.Include = []string{include}
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "conventions.include") {
+			t.Errorf("include %q: expected a conventions.include error, got: %v", include, err)
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.Conventions.MaxBytes = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "conventions.max_bytes") {
+		t.Errorf("expected a conventions.max_bytes error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_Forge(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{"forge": {"provider": "gitlab", "repo": "group/app", "token_env": "RALPH_TEST_FORGE_TOKEN"}}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Forge.Provider != ForgeProviderGitLab || cfg.Forge.Repo != "group/app" {
+		t.Errorf("unexpected forge config: %+v", cfg.Forge)
+	}
+	if cfg.Forge.BaseBranch != "main" {
+		t.Errorf("expected default base branch main, got %q", cfg.Forge.BaseBranch)
+	}
+
+	t.Setenv("RALPH_TEST_FORGE_TOKEN", "secret")
+	if got := cfg.Forge.Token(); got != "secret" {
+		t.Errorf("Token() = %q, want secret", got)
+	}
+}
+
+func TestForgeConfig_TokenDefaultsToProviderVariable(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "gh")
+	t.Setenv("GITLAB_TOKEN", "gl")
+
+	if got := (ForgeConfig{Provider: ForgeProviderGitHub}).Token(); got != "gh" {
+		t.Errorf("github Token() = %q, want gh", got)
+	}
+	if got := (ForgeConfig{Provider: ForgeProviderGitLab}).Token(); got != "gl" {
+		t.Errorf("gitlab Token() = %q, want gl", got)
+	}
+}
+
+func TestValidate_InvalidForgeProvider(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Forge.Provider = "bitbucket"
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "forge.provider") {
+		t.Errorf("expected forge.provider error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_Notify(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{"notify": {"webhook_url": "https://hooks.slack.com/services/x", "events": ["done"], "template": "{{.Message}}"}}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Notify.WebhookURL != "https://hooks.slack.com/services/x" {
+		t.Errorf("unexpected webhook_url %q", cfg.Notify.WebhookURL)
+	}
+	if len(cfg.Notify.Events) != 1 || cfg.Notify.Events[0] != "done" {
+		t.Errorf("unexpected events %v", cfg.Notify.Events)
+	}
+	if cfg.Notify.Template != "{{.Message}}" {
+		t.Errorf("unexpected template %q", cfg.Notify.Template)
+	}
+	if cfg.Notify.MinIntervalSeconds != 10 {
+		t.Errorf("expected default min_interval_seconds 10, got %d", cfg.Notify.MinIntervalSeconds)
+	}
+}
+
+func TestValidate_InvalidNotify(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notify.Provider = "teams"
+	cfg.Notify.MinIntervalSeconds = -1
+	cfg.Notify.Events = []string{"done", "finished"}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "notify.provider") || !strings.Contains(err.Error(), "notify.min_interval_seconds") {
+		t.Errorf("expected notify errors, got: %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), `unknown event "finished"`) || strings.Contains(err.Error(), `"done"`) {
+		t.Errorf("expected only the unknown event to be rejected, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_NotifyEmail(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{"notify": {"email": {"smtp_host": "smtp.example.com", "smtp_port": 465, "username": "ralph", "password_env": "MAIL_PASS", "from": "ralph@example.com", "to": ["dev@example.com"], "tls": "tls"}}}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	t.Setenv("MAIL_PASS", "hunter2")
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	email := cfg.Notify.Email
+	if email.SMTPHost != "smtp.example.com" || email.SMTPPort != 465 || email.TLS != notify.EmailTLSImplicit {
+		t.Errorf("unexpected email config %+v", email)
+	}
+	if len(email.To) != 1 || email.To[0] != "dev@example.com" || email.From != "ralph@example.com" {
+		t.Errorf("unexpected email addresses %+v", email)
+	}
+	if email.Password() != "hunter2" {
+		t.Errorf("expected the password from MAIL_PASS, got %q", email.Password())
+	}
+}
+
+func TestValidate_InvalidNotifyEmail(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notify.Email = EmailConfig{SMTPHost: "smtp.example.com", SMTPPort: 70000, TLS: "ssl", ReportURL: "https://ci.example.com/{{.PlanID"}
+
+	err := cfg.Validate()
+	for _, want := range []string{"notify.email.tls", "notify.email.smtp_port", "needs from and to", "notify.email.report_url"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q error, got: %v", want, err)
+		}
+	}
+}
+
+func TestLoadFromPath_Encryption(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{"encryption": {"enabled": true, "keychain_service": "ralph"}}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Encryption.Enabled || cfg.Encryption.KeychainService != "ralph" {
+		t.Errorf("unexpected encryption config %+v", cfg.Encryption)
+	}
+}
+
+func TestEncryptionConfig_Key(t *testing.T) {
+	original := keychainLookup
+	t.Cleanup(func() { keychainLookup = original })
+	keychainLookup = func(service string) (string, error) {
+		if service == "ralph" {
+			return "from-keychain", nil
+		}
+		return "", errors.New("not found")
+	}
+
+	t.Setenv(DefaultEncryptionKeyEnv, "from-env")
+	if key, err := (EncryptionConfig{KeychainService: "ralph"}).Key(); err != nil || key != "from-env" {
+		t.Errorf("Key() = %q, %v; want the environment variable", key, err)
+	}
+
+	t.Setenv(DefaultEncryptionKeyEnv, "")
+	if key, err := (EncryptionConfig{KeychainService: "ralph"}).Key(); err != nil || key != "from-keychain" {
+		t.Errorf("Key() = %q, %v; want the keychain", key, err)
+	}
+
+	t.Setenv("CUSTOM_KEY", "custom")
+	if key, err := (EncryptionConfig{KeyEnv: "CUSTOM_KEY"}).Key(); err != nil || key != "custom" {
+		t.Errorf("Key() = %q, %v; want the custom variable", key, err)
+	}
+
+	if _, err := (EncryptionConfig{}).Key(); err == nil || !strings.Contains(err.Error(), DefaultEncryptionKeyEnv) {
+		t.Errorf("expected missing key error naming %s, got: %v", DefaultEncryptionKeyEnv, err)
+	}
+	if _, err := (EncryptionConfig{KeychainService: "missing"}).Key(); err == nil {
+		t.Error("expected error when the keychain has no entry")
+	}
+}
+
+func TestRedactionConfig(t *testing.T) {
+	if !DefaultConfig().Redaction.Enabled {
+		t.Error("expected redaction enabled by default")
+	}
+
+	r, err := (RedactionConfig{Enabled: true, Patterns: []string{`acme-\d+`}}).Redactor()
+	if err != nil {
+		t.Fatalf("Redactor() error: %v", err)
+	}
+	if got := r.String("id acme-42"); strings.Contains(got, "acme-42") {
+		t.Errorf("custom pattern not masked: %q", got)
+	}
+
+	if r, err := (RedactionConfig{}).Redactor(); r != nil || err != nil {
+		t.Errorf("disabled Redactor() = %v, %v; want nil", r, err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Redaction.Patterns = []string{"("}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "redaction.patterns") {
+		t.Errorf("expected redaction.patterns error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_Database(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{"database": {"backend": "postgres", "url": "postgres://localhost/ralph", "url_env": "TEAM_DB"}}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := DatabaseConfig{Backend: DatabaseBackendPostgres, URL: "postgres://localhost/ralph", URLEnv: "TEAM_DB"}
+	if cfg.Database != want {
+		t.Errorf("Database = %+v, want %+v", cfg.Database, want)
+	}
+}
+
+func TestDatabaseConfig_ConnectionURL(t *testing.T) {
+	t.Setenv(DefaultDatabaseURLEnv, "")
+	if url, err := (DatabaseConfig{URL: "postgres://file"}).ConnectionURL(); err != nil || url != "postgres://file" {
+		t.Errorf("ConnectionURL() = %q, %v; want the configured url", url, err)
+	}
+
+	t.Setenv(DefaultDatabaseURLEnv, "postgres://env")
+	if url, err := (DatabaseConfig{URL: "postgres://file"}).ConnectionURL(); err != nil || url != "postgres://env" {
+		t.Errorf("ConnectionURL() = %q, %v; want the environment variable", url, err)
+	}
+
+	t.Setenv("TEAM_DB", "postgres://custom")
+	if url, err := (DatabaseConfig{URLEnv: "TEAM_DB"}).ConnectionURL(); err != nil || url != "postgres://custom" {
+		t.Errorf("ConnectionURL() = %q, %v; want the custom variable", url, err)
+	}
+
+	t.Setenv(DefaultDatabaseURLEnv, "")
+	if _, err := (DatabaseConfig{}).ConnectionURL(); err == nil || !strings.Contains(err.Error(), DefaultDatabaseURLEnv) {
+		t.Errorf("expected missing url error naming %s, got: %v", DefaultDatabaseURLEnv, err)
+	}
+}
+
+func TestValidate_InvalidDatabaseBackend(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Database.Backend = "mysql"
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "database.backend") {
+		t.Errorf("expected database.backend error, got: %v", err)
+	}
+}
+
+func TestPlanRefresh(t *testing.T) {
+	if got := DefaultConfig().PlanRefresh; got != PlanRefreshDetect {
+		t.Errorf("default plan_refresh = %q, want %q", got, PlanRefreshDetect)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"plan_refresh": "merge"}`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.PlanRefresh != PlanRefreshMerge {
+		t.Errorf("plan_refresh = %q, want %q", cfg.PlanRefresh, PlanRefreshMerge)
+	}
+
+	cfg.PlanRefresh = "sometimes"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "plan_refresh") {
+		t.Errorf("expected plan_refresh error, got: %v", err)
+	}
+}
+
+func TestConflictResolution(t *testing.T) {
+	if got := DefaultConfig().ConflictResolution; got != ConflictResolutionResolve {
+		t.Errorf("default conflict_resolution = %q, want %q", got, ConflictResolutionResolve)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"conflict_resolution": "wait"}`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.ConflictResolution != ConflictResolutionWait {
+		t.Errorf("conflict_resolution = %q, want %q", cfg.ConflictResolution, ConflictResolutionWait)
+	}
+
+	cfg.ConflictResolution = "merge"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "conflict_resolution") {
+		t.Errorf("expected conflict_resolution error, got: %v", err)
+	}
+}
+
+func TestOutOfScopeFiles(t *testing.T) {
+	if got := DefaultConfig().OutOfScopeFiles; got != OutOfScopeRevert {
+		t.Errorf("default out_of_scope_files = %q, want %q", got, OutOfScopeRevert)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"out_of_scope_files": "flag"}`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.OutOfScopeFiles != OutOfScopeFlag {
+		t.Errorf("out_of_scope_files = %q, want %q", cfg.OutOfScopeFiles, OutOfScopeFlag)
+	}
+
+	cfg.OutOfScopeFiles = "ignore"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "out_of_scope_files") {
+		t.Errorf("expected out_of_scope_files error, got: %v", err)
+	}
+}
+
+func TestAnalyzers(t *testing.T) {
+	if len(DefaultConfig().Analyzers) != 0 {
+		t.Error("expected no analyzers by default")
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{"analyzers": [{"name": "go vet", "command": ["go", "vet", "{dirs}"], "extensions": [".go"], "timeout_seconds": 60}]}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Analyzers) != 1 || cfg.Analyzers[0].Name != "go vet" || len(cfg.Analyzers[0].Command) != 3 ||
+		cfg.Analyzers[0].TimeoutSeconds != 60 {
+		t.Errorf("analyzers = %+v", cfg.Analyzers)
+	}
+
+	cfg.Analyzers = []AnalyzerConfig{{Name: "eslint"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "analyzers[0]") {
+		t.Errorf("expected analyzers[0] error, got: %v", err)
+	}
+}
+
+func TestChecks(t *testing.T) {
+	if len(DefaultConfig().Checks) != 0 {
+		t.Error("expected no checks by default")
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{"checks": [{"name": "tests", "command": ["go", "test", "./..."], "timeout_seconds": 300}]}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Checks) != 1 || cfg.Checks[0].Name != "tests" || len(cfg.Checks[0].Command) != 3 ||
+		cfg.Checks[0].TimeoutSeconds != 300 {
+		t.Errorf("checks = %+v", cfg.Checks)
+	}
+
+	cfg.Checks = []CheckConfig{{Name: "lint"}, {Name: "tests", Command: []string{"make"}, TimeoutSeconds: -1}}
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "checks[0]") || !strings.Contains(err.Error(), "checks[1].timeout_seconds") {
+		t.Errorf("expected checks errors, got: %v", err)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	if len(DefaultConfig().Hooks) != 0 {
+		t.Error("expected no hooks by default")
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{"hooks": [{"name": "deploy", "on": "on_done", "command": ["./deploy.sh"], "timeout_seconds": 30}]}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Hooks) != 1 || cfg.Hooks[0].On != HookOnDone || cfg.Hooks[0].Command[0] != "./deploy.sh" ||
+		cfg.Hooks[0].TimeoutSeconds != 30 {
+		t.Errorf("hooks = %+v", cfg.Hooks)
+	}
+
+	cfg.Hooks = []HookConfig{
+		{Name: "warm", On: HookPreIteration},
+		{Name: "notify", On: "on_failure", Command: []string{"notify"}, TimeoutSeconds: -1},
+	}
+	err = cfg.Validate()
+	for _, want := range []string{"hooks[0] must have", `hooks[1].on must be`, "hooks[1].timeout_seconds"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q error, got: %v", want, err)
+		}
+	}
+}
+
+func TestLoadFromPath_ReviewTriage(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{"review_triage": {"action": "downgrade", "trivial_paths": ["*.md", "docs/"]}}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.ReviewTriage.Action != ReviewTriageDowngrade || strings.Join(cfg.ReviewTriage.TrivialPaths, ",") != "*.md,docs/" {
+		t.Errorf("unexpected review_triage config: %+v", cfg.ReviewTriage)
+	}
+	if cfg.ReviewTriage.MaxLines != 20 || cfg.ReviewTriage.Model != "haiku" {
+		t.Errorf("expected the default max_lines and model, got %+v", cfg.ReviewTriage)
+	}
+}
+
+func TestLoadFromPath_ReviewDiff(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"review_diff": {"format": "summary"}}`), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.ReviewDiff.Format != ReviewDiffSummary || cfg.ReviewDiff.Model != "haiku" {
+		t.Errorf("unexpected review_diff config: %+v", cfg.ReviewDiff)
+	}
+
+	cfg.ReviewDiff.Format = "compact"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "review_diff.format") {
+		t.Errorf("expected a review_diff.format error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_ReviewDiffContext(t *testing.T) {
+	if defaults := DefaultConfig().ReviewDiff; defaults.Context != ReviewContextDiff || defaults.ContextBytes != 64*1024 {
+		t.Errorf("expected the diff alone by default, got %+v", defaults)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"review_diff": {"context": "files", "context_bytes": 32768}}`), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.ReviewDiff.Context != ReviewContextFiles || cfg.ReviewDiff.ContextBytes != 32768 || cfg.ReviewDiff.Format != ReviewDiffRaw {
+		t.Errorf("unexpected review_diff config: %+v", cfg.ReviewDiff)
+	}
+
+	cfg.ReviewDiff.Context = "everything"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "review_diff.context") {
+		t.Errorf("expected a review_diff.context error, got: %v", err)
+	}
+	cfg.ReviewDiff.Context = ReviewContextFiles
+	cfg.ReviewDiff.ContextBytes = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "review_diff.context_bytes") {
+		t.Errorf("expected a review_diff.context_bytes error, got: %v", err)
+	}
+}
+
+func TestValidate_InvalidReviewTriage(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ReviewTriage.Action = "ignore"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "review_triage.action") {
+		t.Errorf("expected a review_triage.action error, got: %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.ReviewTriage.Action = ReviewTriageDowngrade
+	cfg.ReviewTriage.Model = ""
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "review_triage.model") {
+		t.Errorf("expected a review_triage.model error, got: %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.ReviewTriage.MaxLines = -1
+	cfg.ReviewTriage.TrivialPaths = []string{"../docs"}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "review_triage.max_lines") || !strings.Contains(err.Error(), "review_triage.trivial_paths") {
+		t.Errorf("expected max_lines and trivial_paths errors, got: %v", err)
+	}
+}
+
+func TestTUIConfig(t *testing.T) {
+	if got := DefaultConfig().TUI; got.Theme != ThemeDark || got.Accessible {
+		t.Errorf("default tui = %+v, want the dark theme", got)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"tui": {"theme": "high-contrast", "accessible": true}}`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.TUI.Theme != ThemeHighContrast || !cfg.TUI.Accessible {
+		t.Errorf("tui = %+v, want high-contrast and accessible", cfg.TUI)
+	}
+
+	cfg.TUI.Theme = "solarized"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "tui.theme") {
+		t.Errorf("expected tui.theme error, got: %v", err)
+	}
+}
+
+func TestTUIScrollback(t *testing.T) {
+	if got := DefaultConfig().TUI; !got.Scrollback || got.ScrollbackMaxMB != 10 {
+		t.Errorf("default tui = %+v, want scrollback capped at 10 MB", got)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"tui": {"scrollback": false, "scrollback_max_mb": 2}}`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.TUI.Scrollback || cfg.TUI.ScrollbackMaxMB != 2 {
+		t.Errorf("tui = %+v, want scrollback off with a 2 MB cap", cfg.TUI)
+	}
+
+	cfg.TUI.Scrollback = true
+	cfg.TUI.ScrollbackMaxMB = 0
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "tui.scrollback_max_mb") {
+		t.Errorf("expected tui.scrollback_max_mb error, got: %v", err)
+	}
+	cfg.TUI.Scrollback = false
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("disabled scrollback should not need a cap, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_Locale(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"locale": "ja"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Locale != "ja" {
+		t.Errorf("expected locale ja, got %q", cfg.Locale)
+	}
+	if DefaultConfig().Locale != "en" {
+		t.Errorf("expected default locale en, got %q", DefaultConfig().Locale)
+	}
+}
+
+func TestValidate_InvalidLocale(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Locale = "xx"
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "locale must be one of en, ja") {
+		t.Errorf("expected a locale error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_Throttle(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"throttle": {"max_sessions_per_hour": 30, "max_cost_per_hour": 12.5}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Throttle.MaxSessionsPerHour != 30 || cfg.Throttle.MaxCostPerHour != 12.5 {
+		t.Errorf("unexpected throttle: %+v", cfg.Throttle)
+	}
+
+	cfg.Throttle.MaxCostPerHour = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "throttle limits") {
+		t.Errorf("expected a throttle error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_Lint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"lint": {"strict": true, "max_bytes": 4096}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Lint.Strict || cfg.Lint.MaxBytes != 4096 {
+		t.Errorf("unexpected lint: %+v", cfg.Lint)
+	}
+	if DefaultConfig().Lint.MaxBytes != planlint.DefaultMaxBytes {
+		t.Errorf("expected the default lint.max_bytes to be %d", planlint.DefaultMaxBytes)
+	}
+
+	cfg.Lint.MaxBytes = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "lint.max_bytes") {
+		t.Errorf("expected a lint error, got: %v", err)
+	}
+}
+
+func TestLoadFromPath_Tracker(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	data := `{"tracker": {"provider": "jira", "api_url": "https://acme.atlassian.net", "project": "PROJ",
+		"user": "me@example.com", "token_env": "RALPH_TEST_JIRA_TOKEN", "templates": {"comment": "{{.Progress}}"}}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("RALPH_TEST_JIRA_TOKEN", "secret")
+
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Tracker.Provider != TrackerProviderJira || cfg.Tracker.Project != "PROJ" || cfg.Tracker.User != "me@example.com" {
+		t.Errorf("unexpected tracker: %+v", cfg.Tracker)
+	}
+	if cfg.Tracker.Templates.Comment != "{{.Progress}}" || cfg.Tracker.Templates.Title != "" {
+		t.Errorf("unexpected templates: %+v", cfg.Tracker.Templates)
+	}
+	if cfg.Tracker.Token() != "secret" {
+		t.Errorf("Token() = %q, want the token_env variable", cfg.Tracker.Token())
+	}
+
+	t.Setenv("GITHUB_TOKEN", "gh-secret")
+	if got := (TrackerConfig{Provider: TrackerProviderGitHub}).Token(); got != "gh-secret" {
+		t.Errorf("Token() = %q, want GITHUB_TOKEN", got)
+	}
+
+	for tracker, want := range map[TrackerConfig]string{
+		{Provider: "linear"}:                                        "tracker.provider",
+		{Provider: TrackerProviderGitHub}:                           "tracker.repo",
+		{Provider: TrackerProviderJira, APIURL: cfg.Tracker.APIURL}: "tracker.project",
+	} {
+		cfg := DefaultConfig()
+		cfg.Tracker = tracker
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate(%+v) = %v, want a %s error", tracker, err, want)
+		}
+	}
+}
+
+func TestLoadFromPath_StatusReporting(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"claude": {"status_reporting": "tool", "state_tools": true}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Claude.StatusReporting != StatusReportingTool {
+		t.Errorf("StatusReporting = %q, want %q", cfg.Claude.StatusReporting, StatusReportingTool)
+	}
+	if !cfg.Claude.StateTools {
+		t.Error("expected StateTools to be set")
+	}
+
+	cfg.Claude.StatusReporting = "xml"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "claude.status_reporting") {
+		t.Errorf("expected a claude.status_reporting error, got %v", err)
+	}
+}
+
+func TestLoadFromPath_DifferentialPrompts(t *testing.T) {
+	if got := DefaultConfig().DifferentialPrompts; got.Enabled || got.FullRefreshEvery != 5 {
+		t.Errorf("unexpected defaults: %+v", got)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"differential_prompts": {"enabled": true, "full_refresh_every": 3}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (DifferentialPromptsConfig{Enabled: true, FullRefreshEvery: 3}); cfg.DifferentialPrompts != want {
+		t.Errorf("DifferentialPrompts = %+v, want %+v", cfg.DifferentialPrompts, want)
+	}
+
+	cfg.DifferentialPrompts.FullRefreshEvery = 0
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "differential_prompts.full_refresh_every") {
+		t.Errorf("expected a full_refresh_every error, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+// Package conventions finds a repository's convention files, such as
+// CLAUDE.md and CONTRIBUTING.md, so they can be included in agent prompts.
+package conventions
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultInclude lists the convention files looked for when none are
+// configured, in the order they are included.
+var DefaultInclude = []string{
+	"CLAUDE.md",
+	"CONTRIBUTING.md",
+	"ARCHITECTURE.md",
+	"docs/CONTRIBUTING.md",
+	"docs/ARCHITECTURE.md",
+	".github/CONTRIBUTING.md",
+}
+
+// DefaultMaxBytes is the default budget for the combined content of the
+// included files.
+const DefaultMaxBytes = 16 * 1024
+
+// truncatedNote is appended to content cut to fit the budget.
+const truncatedNote = "\n[... truncated]"
+
+// File is a convention file read from a repository.
+type File struct {
+	Path      string // Slash-separated path relative to the repository root
+	Content   string
+	Truncated bool // Content was cut to fit the budget
+}
+
+// Provider finds convention files in a repository.
+type Provider struct {
+	include  []string
+	exclude  []string
+	maxBytes int
+}
+
+// NewProvider creates a provider for the files matching the include globs
+// but none of the exclude globs. Globs are slash-separated, relative to the
+// repository root, and use path.Match syntax. maxBytes bounds the combined
+// content of the files (0 = no limit).
+func NewProvider(include, exclude []string, maxBytes int) *Provider {
+	return &Provider{include: include, exclude: exclude, maxBytes: maxBytes}
+}
+
+// Load returns the convention files in the repository at root, in the order
+// of the include globs; a file matched by several globs is included once.
+// With dirs, the globs are also matched in those slash-separated
+// directories under root, after root itself, so a mono-repo sub-project's
+// own convention files are included. Content past the budget is cut, and
+// files that no longer fit are left out.
+func (p *Provider) Load(root string, dirs ...string) ([]File, error) {
+	paths, err := p.match(root, dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	remaining := p.maxBytes
+	for _, rel := range paths {
+		if p.maxBytes > 0 && remaining <= 0 {
+			break
+		}
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		content := strings.TrimSpace(string(data))
+		if content == "" {
+			continue
+		}
+
+		file := File{Path: rel, Content: content}
+		if p.maxBytes > 0 {
+			if len(content) > remaining {
+				// The budget is used up by this file
+				file.Content = truncate(content, remaining)
+				file.Truncated = true
+				remaining = 0
+			} else {
+				remaining -= len(content)
+			}
+		}
+		if file.Content != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// match returns the regular files under root matching the provider's
+// globs in root and then in each of dirs, in include order, as paths
+// relative to root.
+func (p *Provider) match(root string, dirs []string) ([]string, error) {
+	var paths []string
+	for _, dir := range append([]string{""}, dirs...) {
+		base := filepath.Join(root, filepath.FromSlash(dir))
+		for _, pattern := range p.include {
+			matches, err := filepath.Glob(filepath.Join(base, filepath.FromSlash(pattern)))
+			if err != nil {
+				return nil, fmt.Errorf("invalid include glob %q: %w", pattern, err)
+			}
+			for _, match := range matches {
+				info, err := os.Stat(match)
+				if err != nil || !info.Mode().IsRegular() {
+					continue
+				}
+				rel, err := filepath.Rel(root, match)
+				if err != nil {
+					continue
+				}
+				rel = filepath.ToSlash(rel)
+				if slices.Contains(paths, rel) || p.excluded(rel) {
+					continue
+				}
+				paths = append(paths, rel)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// excluded reports whether rel matches an exclude glob.
+func (p *Provider) excluded(rel string) bool {
+	for _, pattern := range p.exclude {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate cuts s to at most n bytes, at a line break when there is one in
+// the second half, without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	s = s[:n]
+	if i := strings.LastIndexByte(s, '\n'); i > n/2 {
+		s = s[:i]
+	}
+	return s
+}
+
+// Format renders files as prompt sections, each headed by its path with
+// the content fenced.
+func Format(files []File) string {
+	var b strings.Builder
+	for i, f := range files {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		content := f.Content
+		if f.Truncated {
+			content += truncatedNote
+		}
+		fence := fenceFor(content)
+		fmt.Fprintf(&b, "## %s\n\n%smarkdown\n%s\n%s", f.Path, fence, content, fence)
+	}
+	return b.String()
+}
+
+// fenceFor returns a code fence longer than any run of backticks in s, so
+// fenced blocks inside convention files don't end the fence early.
+func fenceFor(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
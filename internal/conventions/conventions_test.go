@@ -0,0 +1,116 @@
+package conventions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRepo creates a directory holding the given files and contents.
+func newTestRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// paths returns the paths of files.
+func paths(files []File) string {
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Path)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestProvider_Load(t *testing.T) {
+	dir := newTestRepo(t, map[string]string{
+		"CLAUDE.md":               "Use table-driven tests.\n",
+		"CONTRIBUTING.md":         "Run make lint.",
+		"docs/ARCHITECTURE.md":    "Layers: cmd, internal.",
+		"docs/adr/0001-sqlite.md": "We use SQLite.",
+		"docs/adr/draft.md":       "Not decided.",
+		"ARCHITECTURE.md":         "   \n",
+	})
+
+	p := NewProvider([]string{"CLAUDE.md", "ARCHITECTURE.md", "docs/*.md", "docs/adr/*.md", "CLAUDE.md", "CONTRIBUTING.md"},
+		[]string{"docs/adr/draft.md"}, 0)
+	files, err := p.Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	// Include order, each file once, empty and excluded files left out
+	if got, want := paths(files), "CLAUDE.md,docs/ARCHITECTURE.md,docs/adr/0001-sqlite.md,CONTRIBUTING.md"; got != want {
+		t.Errorf("Load() paths = %s, want %s", got, want)
+	}
+	if files[0].Content != "Use table-driven tests." || files[0].Truncated {
+		t.Errorf("Load() first file = %+v", files[0])
+	}
+}
+
+func TestProvider_Load_Dirs(t *testing.T) {
+	dir := newTestRepo(t, map[string]string{
+		"CLAUDE.md":              "Repo-wide.",
+		"services/api/CLAUDE.md": "API conventions.",
+		"services/web/CLAUDE.md": "Web conventions.",
+	})
+
+	files, err := NewProvider([]string{"CLAUDE.md"}, nil, 0).Load(dir, "services/api")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if got, want := paths(files), "CLAUDE.md,services/api/CLAUDE.md"; got != want {
+		t.Errorf("Load() paths = %s, want %s", got, want)
+	}
+}
+
+func TestProvider_Load_Budget(t *testing.T) {
+	dir := newTestRepo(t, map[string]string{
+		"CLAUDE.md":       strings.Repeat("a", 30),
+		"CONTRIBUTING.md": strings.Repeat("line\n", 10),
+		"ARCHITECTURE.md": "Never reached.",
+	})
+
+	files, err := NewProvider(DefaultInclude, nil, 50).Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if got, want := paths(files), "CLAUDE.md,CONTRIBUTING.md"; got != want {
+		t.Fatalf("Load() paths = %s, want %s", got, want)
+	}
+	if files[0].Truncated {
+		t.Error("expected the first file to fit the budget")
+	}
+	// 20 bytes left: cut at the last line break
+	if !files[1].Truncated || files[1].Content != "line\nline\nline\nline" {
+		t.Errorf("Load() second file = %+v, want it cut at a line break", files[1])
+	}
+}
+
+func TestProvider_Load_InvalidGlob(t *testing.T) {
+	if _, err := NewProvider([]string{"[CLAUDE.md"}, nil, 0).Load(t.TempDir()); err == nil {
+		t.Error("expected an error for an invalid include glob")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	got := Format([]File{
+		{Path: "CLAUDE.md", Content: "Use jj."},
+		{Path: "CONTRIBUTING.md", Content: "Run:\n```\nmake test\n```", Truncated: true},
+	})
+	want := "## CLAUDE.md\n\n```markdown\nUse jj.\n```\n\n" +
+		"## CONTRIBUTING.md\n\n````markdown\nRun:\n```\nmake test\n```\n[... truncated]\n````"
+	if got != want {
+		t.Errorf("Format() =\n%s\n\nwant:\n%s", got, want)
+	}
+}
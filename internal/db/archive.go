@@ -0,0 +1,228 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// planTable is a table owned by a plan.
+type planTable struct {
+	name   string
+	filter string
+	autoID bool
+}
+
+// planTables lists the tables owned by a plan, parents first.
+// Each filter selects the rows belonging to the plans bound to its placeholders.
+// Tables with autoID number their rows with an id generated by the database,
+// which nothing references.
+var planTables = []planTable{
+	{"plans", "id IN (%s)", false},
+	{"plan_sessions", "plan_id IN (%s)", false},
+	{"events", "session_id IN (SELECT id FROM main.plan_sessions WHERE plan_id IN (%s))", true},
+	{"transcript_messages", "session_id IN (SELECT id FROM main.plan_sessions WHERE plan_id IN (%s))", true},
+	{"session_sequences", "session_id IN (SELECT id FROM main.plan_sessions WHERE plan_id IN (%s))", false},
+	{"tool_usage", "plan_id IN (%s)", true},
+	{"progress", "plan_id IN (%s)", true},
+	{"learnings", "plan_id IN (%s)", true},
+	{"reviewer_feedback", "plan_id IN (%s)", true},
+	{"feedback_items", "plan_id IN (%s)", true},
+	{"analyzer_findings", "plan_id IN (%s)", true},
+	{"check_failures", "plan_id IN (%s)", true},
+	{"session_environments", "plan_id IN (%s)", false},
+	{"review_skips", "plan_id IN (%s)", true},
+	{"rebuttals", "plan_id IN (%s)", true},
+	{"rejected_approaches", "plan_id IN (%s)", true},
+	{"iteration_operations", "plan_id IN (%s)", false},
+	{"plan_workspaces", "plan_id IN (%s)", false},
+	{"plan_issues", "plan_id IN (%s)", false},
+	{"plan_runs", "plan_id IN (%s)", false},
+	{"projects", "id IN (%s)", false},
+	{"tasks", "project_id IN (%s)", false},
+}
+
+// ListCompletedPlansBefore returns completed plans last updated before cutoff,
+// oldest first.
+func (d *DB) ListCompletedPlansBefore(cutoff time.Time) ([]*Plan, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, origin_path, content, status, base_change_id, work_dir, failure_reason, origin_hash, forked_from, parent_plan_id, created_at, updated_at
+		FROM plans WHERE status = ? ORDER BY updated_at ASC`, PlanStatusCompleted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "error", closeErr)
+		}
+	}()
+
+	var plans []*Plan
+	for rows.Next() {
+		plan := &Plan{}
+		if err := rows.Scan(
+			&plan.ID, &plan.OriginPath, &plan.Content, &plan.Status, &plan.BaseChangeID, &plan.WorkDir,
+			&plan.FailureReason, &plan.OriginHash, &plan.ForkedFrom, &plan.ParentPlanID, &plan.CreatedAt, &plan.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if err := d.unsealAll(&plan.Content); err != nil {
+			return nil, err
+		}
+		if plan.UpdatedAt.Before(cutoff) {
+			plans = append(plans, plan)
+		}
+	}
+	return plans, rows.Err()
+}
+
+// ArchivePlans moves the given plans, with their sessions, events, transcripts,
+// progress, learnings, reviewer feedback, and tasks, into the SQLite database at archivePath
+// and deletes them from this database. The copy and delete happen in a single
+// transaction, so a failure leaves both databases unchanged.
+func (d *DB) ArchivePlans(archivePath string, planIDs []string) error {
+	if len(planIDs) == 0 {
+		return nil
+	}
+	if d.Backend() != BackendSQLite {
+		return errors.New("archiving is only supported with the sqlite backend")
+	}
+
+	ctx := context.Background()
+
+	// ATTACH is per-connection, so pin one connection for the whole operation
+	conn, err := d.conn.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer func() {
+		if closeErr := conn.Close(); closeErr != nil {
+			log.Warn("failed to release connection", "error", closeErr)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `ATTACH DATABASE ? AS archive`, archivePath); err != nil {
+		return fmt.Errorf("failed to attach archive %s: %w", archivePath, err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, `DETACH DATABASE archive`); err != nil {
+			log.Warn("failed to detach archive", "error", err)
+		}
+	}()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Warn("failed to rollback archive transaction", "error", err)
+		}
+	}()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(planIDs)), ",")
+	args := make([]any, len(planIDs))
+	for i, id := range planIDs {
+		args[i] = id
+	}
+
+	// Copy parents first
+	for _, table := range planTables {
+		columns, err := prepareArchiveTable(ctx, tx, table.name)
+		if err != nil {
+			return err
+		}
+		cols := strings.Join(columns, ", ")
+		query := fmt.Sprintf(`INSERT INTO archive.%s (%s) SELECT %s FROM main.%s WHERE %s`,
+			table.name, cols, cols, table.name, fmt.Sprintf(table.filter, placeholders))
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", table.name, err)
+		}
+	}
+
+	// Delete children first to satisfy foreign keys
+	for i := len(planTables) - 1; i >= 0; i-- {
+		table := planTables[i]
+		query := fmt.Sprintf(`DELETE FROM main.%s WHERE %s`,
+			table.name, fmt.Sprintf(table.filter, placeholders))
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", table.name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit archive: %w", err)
+	}
+	return nil
+}
+
+// prepareArchiveTable creates the archive copy of a table if needed, adds
+// any columns the live table gained since, and returns the live column names.
+func prepareArchiveTable(ctx context.Context, tx *sql.Tx, table string) ([]string, error) {
+	// Reuse the live table definition so keys and constraints carry over
+	var ddl string
+	if err := tx.QueryRowContext(ctx,
+		`SELECT sql FROM main.sqlite_master WHERE type = 'table' AND name = ?`, table,
+	).Scan(&ddl); err != nil {
+		return nil, fmt.Errorf("failed to read schema of %s: %w", table, err)
+	}
+	prefix := "CREATE TABLE " + table
+	if !strings.HasPrefix(ddl, prefix) {
+		return nil, fmt.Errorf("unexpected schema for %s: %s", table, ddl)
+	}
+	create := "CREATE TABLE IF NOT EXISTS archive." + table + strings.TrimPrefix(ddl, prefix)
+	if _, err := tx.ExecContext(ctx, create); err != nil {
+		return nil, fmt.Errorf("failed to create archive table %s: %w", table, err)
+	}
+
+	columns, err := tableColumns(ctx, tx, "main", table)
+	if err != nil {
+		return nil, err
+	}
+	archived, err := tableColumns(ctx, tx, "archive", table)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, col := range columns {
+		if slices.Contains(archived, col) {
+			continue
+		}
+		alter := fmt.Sprintf(`ALTER TABLE archive.%s ADD COLUMN %s`, table, col)
+		if _, err := tx.ExecContext(ctx, alter); err != nil {
+			return nil, fmt.Errorf("failed to add column %s to archive table %s: %w", col, table, err)
+		}
+	}
+
+	return columns, nil
+}
+
+// tableColumns returns the column names of a table in the given schema.
+func tableColumns(ctx context.Context, tx *sql.Tx, schema, table string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`SELECT name FROM %s.pragma_table_info(?)`, schema), table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s.%s: %w", schema, table, err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "error", closeErr)
+		}
+	}()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
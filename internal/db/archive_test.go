@@ -0,0 +1,162 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newArchiveTestDB creates a file-backed database with one plan and a full
+// set of child rows. File-backed so ATTACH sees the same data as the pool.
+func newArchiveTestDB(t *testing.T, planIDs ...string) *DB {
+	t.Helper()
+	db, err := New(filepath.Join(t.TempDir(), "ralph.db"))
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	for _, id := range planIDs {
+		if err := db.CreatePlan(&Plan{ID: id, OriginPath: "plan.md", Content: "content", Status: PlanStatusCompleted}); err != nil {
+			t.Fatalf("CreatePlan() error: %v", err)
+		}
+		sessionID := id + "-session"
+		if err := db.CreatePlanSession(&PlanSession{ID: sessionID, PlanID: id, Iteration: 1, InputPrompt: "prompt"}); err != nil {
+			t.Fatalf("CreatePlanSession() error: %v", err)
+		}
+		if err := db.CreateEvent(&Event{SessionID: sessionID, Sequence: 0, EventType: "init", RawJSON: "{}"}); err != nil {
+			t.Fatalf("CreateEvent() error: %v", err)
+		}
+		if err := db.CreateTranscriptMessage(&TranscriptMessage{SessionID: sessionID, Role: "assistant", Kind: "text", Content: "hello"}); err != nil {
+			t.Fatalf("CreateTranscriptMessage() error: %v", err)
+		}
+		if err := db.CreateToolUsage(&ToolUsage{PlanID: id, SessionID: sessionID, Iteration: 1, ToolName: "Read", Calls: 1}); err != nil {
+			t.Fatalf("CreateToolUsage() error: %v", err)
+		}
+		if err := db.CreateProgress(&Progress{PlanID: id, SessionID: sessionID, Content: "progress"}); err != nil {
+			t.Fatalf("CreateProgress() error: %v", err)
+		}
+		if err := db.CreateLearnings(&Learnings{PlanID: id, SessionID: sessionID, Content: "learnings"}); err != nil {
+			t.Fatalf("CreateLearnings() error: %v", err)
+		}
+		if err := db.CreateReviewerFeedback(&ReviewerFeedback{PlanID: id, SessionID: sessionID, Content: "feedback"}); err != nil {
+			t.Fatalf("CreateReviewerFeedback() error: %v", err)
+		}
+		if err := db.CreateFeedbackItem(&FeedbackItem{PlanID: id, SessionID: sessionID, Content: "item", IssuedIteration: 1}); err != nil {
+			t.Fatalf("CreateFeedbackItem() error: %v", err)
+		}
+		if err := db.CreateAnalyzerFinding(&AnalyzerFinding{PlanID: id, SessionID: sessionID, Analyzer: "go vet", Output: "vet: x"}); err != nil {
+			t.Fatalf("CreateAnalyzerFinding() error: %v", err)
+		}
+		if err := db.CreateCheckFailure(&CheckFailure{PlanID: id, SessionID: sessionID, Name: "tests", Output: "--- FAIL: TestX"}); err != nil {
+			t.Fatalf("CreateCheckFailure() error: %v", err)
+		}
+		if err := db.CreateSessionEnvironment(&SessionEnvironment{SessionID: sessionID, PlanID: id, ClaudeVersion: "2.0.1"}); err != nil {
+			t.Fatalf("CreateSessionEnvironment() error: %v", err)
+		}
+		if err := db.CreateReviewSkip(&ReviewSkip{PlanID: id, Iteration: 1, Action: ReviewSkipActionSkip, Reason: "comment-only"}); err != nil {
+			t.Fatalf("CreateReviewSkip() error: %v", err)
+		}
+		if err := db.CreateRebuttal(&Rebuttal{PlanID: id, Iteration: 1, FeedbackSessionID: sessionID, SessionID: sessionID, Feedback: "feedback", Rebuttal: "rebuttal"}); err != nil {
+			t.Fatalf("CreateRebuttal() error: %v", err)
+		}
+		if err := db.CreateRejectedApproach(&RejectedApproach{PlanID: id, SessionID: sessionID, Source: RejectedByChecks, Content: "approach"}); err != nil {
+			t.Fatalf("CreateRejectedApproach() error: %v", err)
+		}
+		if err := db.RecordIterationOperation(id, 1, "op-"+id); err != nil {
+			t.Fatalf("RecordIterationOperation() error: %v", err)
+		}
+		if err := db.CreatePlanWorkspace(&PlanWorkspace{PlanID: id, Name: "ralph-" + id, Path: "/data/workspaces/" + id}); err != nil {
+			t.Fatalf("CreatePlanWorkspace() error: %v", err)
+		}
+		if err := db.CreatePlanIssue(&PlanIssue{PlanID: id, Provider: "github", Key: "42", URL: "https://github.com/o/r/issues/42"}); err != nil {
+			t.Fatalf("CreatePlanIssue() error: %v", err)
+		}
+		if err := db.StartPlanRun(&PlanRun{PlanID: id, Host: "build-1", PID: 100}); err != nil {
+			t.Fatalf("StartPlanRun() error: %v", err)
+		}
+		plan := &Plan{ID: id, OriginPath: "plan.md", Content: "content"}
+		if err := db.CreatePlanTasks(plan, []*Task{{ID: id + "-task", Sequence: 1, Title: "task", Description: "do it"}}); err != nil {
+			t.Fatalf("CreatePlanTasks() error: %v", err)
+		}
+	}
+	return db
+}
+
+// countRows counts rows in a table of the given database.
+func countRows(t *testing.T, db *DB, table string) int {
+	t.Helper()
+	var n int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+		t.Fatalf("failed to count %s: %v", table, err)
+	}
+	return n
+}
+
+func TestArchivePlans(t *testing.T) {
+	db := newArchiveTestDB(t, "plan-1", "plan-2")
+	archivePath := filepath.Join(t.TempDir(), "archive.db")
+
+	if err := db.ArchivePlans(archivePath, []string{"plan-1"}); err != nil {
+		t.Fatalf("ArchivePlans() error: %v", err)
+	}
+
+	// Archived plan is gone from the live db, the other remains
+	if _, err := db.GetPlan("plan-1"); err != ErrNotFound {
+		t.Errorf("expected archived plan to be deleted, got err: %v", err)
+	}
+	if _, err := db.GetPlan("plan-2"); err != nil {
+		t.Errorf("expected other plan to remain, got err: %v", err)
+	}
+	for _, table := range planTables {
+		if got := countRows(t, db, table.name); got != 1 {
+			t.Errorf("live %s: expected 1 row, got %d", table.name, got)
+		}
+	}
+
+	// Archive holds the moved rows
+	archive, err := New(archivePath)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer func() { _ = archive.Close() }()
+	for _, table := range planTables {
+		if got := countRows(t, archive, table.name); got != 1 {
+			t.Errorf("archive %s: expected 1 row, got %d", table.name, got)
+		}
+	}
+
+	// Archiving into an existing archive appends
+	if err := db.ArchivePlans(archivePath, []string{"plan-2"}); err != nil {
+		t.Fatalf("second ArchivePlans() error: %v", err)
+	}
+	if got := countRows(t, archive, "plans"); got != 2 {
+		t.Errorf("expected 2 archived plans, got %d", got)
+	}
+}
+
+func TestArchivePlans_Empty(t *testing.T) {
+	db := newArchiveTestDB(t)
+	if err := db.ArchivePlans(filepath.Join(t.TempDir(), "archive.db"), nil); err != nil {
+		t.Errorf("ArchivePlans() with no plans returned error: %v", err)
+	}
+}
+
+func TestListCompletedPlansBefore(t *testing.T) {
+	db := newArchiveTestDB(t, "old", "new", "running")
+	if err := db.UpdatePlanStatus("running", PlanStatusRunning); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.conn.Exec(`UPDATE plans SET updated_at = ? WHERE id IN ('old', 'running')`,
+		time.Now().Add(-48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	plans, err := db.ListCompletedPlansBefore(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("ListCompletedPlansBefore() error: %v", err)
+	}
+	if len(plans) != 1 || plans[0].ID != "old" {
+		t.Errorf("expected only plan 'old', got %v", plans)
+	}
+}
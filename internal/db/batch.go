@@ -0,0 +1,229 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// DefaultBatchInterval is how often a BatchWriter flushes what it has
+// collected.
+const DefaultBatchInterval = 100 * time.Millisecond
+
+// Batch is events and transcript messages to store in one transaction, in
+// the order they were added.
+type Batch struct {
+	items []batchItem
+}
+
+// batchItem is one record of a batch: an event or a transcript message.
+type batchItem struct {
+	event   *Event
+	message *TranscriptMessage
+}
+
+// AddEvent adds an event to the batch.
+func (b *Batch) AddEvent(event *Event) {
+	b.items = append(b.items, batchItem{event: event})
+}
+
+// AddTranscriptMessage adds a transcript message to the batch.
+func (b *Batch) AddTranscriptMessage(msg *TranscriptMessage) {
+	b.items = append(b.items, batchItem{message: msg})
+}
+
+// Len returns the number of records in the batch.
+func (b *Batch) Len() int {
+	return len(b.items)
+}
+
+// WriteBatch stores a batch's records in one transaction, numbering them by
+// NextSequence in the order they were added, with each insert prepared once.
+// The records' IDs, sequence numbers, and creation times are set as they are
+// inserted; if any insert fails, none of the batch is stored.
+func (d *DB) WriteBatch(b *Batch) error {
+	if b.Len() == 0 {
+		return nil
+	}
+
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Warn("failed to rollback batch transaction", "error", err)
+		}
+	}()
+
+	events, err := tx.prepareInsert(insertEventQuery)
+	if err != nil {
+		return fmt.Errorf("failed to prepare event insert: %w", err)
+	}
+	defer closeStmt(events.stmt)
+	messages, err := tx.prepareInsert(insertTranscriptMessageQuery)
+	if err != nil {
+		return fmt.Errorf("failed to prepare transcript insert: %w", err)
+	}
+	defer closeStmt(messages.stmt)
+
+	for _, item := range b.items {
+		if item.event != nil {
+			err = d.insertBatchEvent(tx, events, item.event)
+		} else {
+			err = d.insertBatchMessage(tx, messages, item.message)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit batch: %w", err)
+	}
+	return nil
+}
+
+// insertBatchEvent inserts an event of a batch, as CreateEvent does.
+func (d *DB) insertBatchEvent(tx *tx, insert *preparedInsert, event *Event) error {
+	rawJSON, err := d.seal(event.RawJSON)
+	if err != nil {
+		return err
+	}
+	sequence, err := nextSequence(tx, event.SessionID)
+	if err != nil {
+		return err
+	}
+	event.Sequence = sequence
+	event.CreatedAt = time.Now()
+
+	id, err := insert.insert(
+		event.SessionID, event.Sequence, event.EventType, rawJSON, event.SubAgentID, event.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert event: %w", err)
+	}
+	event.ID = id
+	return nil
+}
+
+// insertBatchMessage inserts a transcript message of a batch, as
+// CreateTranscriptMessage does.
+func (d *DB) insertBatchMessage(tx *tx, insert *preparedInsert, msg *TranscriptMessage) error {
+	content, err := d.seal(msg.Content)
+	if err != nil {
+		return err
+	}
+	sequence, err := nextSequence(tx, msg.SessionID)
+	if err != nil {
+		return err
+	}
+	msg.Sequence = sequence
+	msg.CreatedAt = time.Now()
+
+	id, err := insert.insert(
+		msg.SessionID, msg.Sequence, msg.Role, msg.Kind, msg.ToolName, msg.ToolUseID,
+		content, msg.IsError, msg.SubAgentID, msg.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert transcript message: %w", err)
+	}
+	msg.ID = id
+	return nil
+}
+
+// closeStmt closes a prepared statement, logging any error.
+func closeStmt(stmt *sql.Stmt) {
+	if err := stmt.Close(); err != nil {
+		log.Warn("failed to close prepared statement", "error", err)
+	}
+}
+
+// BatchWriter collects events and transcript messages as they stream in and
+// stores them with WriteBatch every interval, so rapid streams cost one
+// transaction per interval instead of one per record. Records are stored in
+// the order they were added. Close flushes what is left.
+type BatchWriter struct {
+	db *DB
+
+	mu      sync.Mutex
+	pending *Batch
+
+	// flushMu keeps batches from being written out of order
+	flushMu sync.Mutex
+
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewBatchWriter returns a BatchWriter for d that flushes every interval.
+func NewBatchWriter(d *DB, interval time.Duration) *BatchWriter {
+	w := &BatchWriter{
+		db:      d,
+		pending: &Batch{},
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go w.run(interval)
+	return w
+}
+
+// run flushes every interval until Close.
+func (w *BatchWriter) run(interval time.Duration) {
+	defer close(w.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			if err := w.Flush(); err != nil {
+				log.Warn("failed to store batch", "error", err)
+			}
+		}
+	}
+}
+
+// AddEvent queues an event for the next flush.
+func (w *BatchWriter) AddEvent(event *Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending.AddEvent(event)
+}
+
+// AddTranscriptMessage queues a transcript message for the next flush.
+func (w *BatchWriter) AddTranscriptMessage(msg *TranscriptMessage) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending.AddTranscriptMessage(msg)
+}
+
+// Flush stores the records queued so far. A batch that fails to store is
+// dropped, as a failed CreateEvent would be.
+func (w *BatchWriter) Flush() error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	batch := w.pending
+	w.pending = &Batch{}
+	w.mu.Unlock()
+
+	if err := w.db.WriteBatch(batch); err != nil {
+		return fmt.Errorf("failed to store %d record(s): %w", batch.Len(), err)
+	}
+	return nil
+}
+
+// Close stops the periodic flushes and stores what is left.
+func (w *BatchWriter) Close() error {
+	w.once.Do(func() { close(w.stop) })
+	<-w.stopped
+	return w.Flush()
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newBatchTestDB creates a file-backed database with plan-1 and session s1,
+// the way the loop stores records.
+func newBatchTestDB(tb testing.TB) *DB {
+	tb.Helper()
+	db, err := New(filepath.Join(tb.TempDir(), "ralph.db"))
+	if err != nil {
+		tb.Fatalf("New() error: %v", err)
+	}
+	tb.Cleanup(func() { _ = db.Close() })
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		tb.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "s1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p"}); err != nil {
+		tb.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+	return db
+}
+
+func TestWriteBatch(t *testing.T) {
+	db := newBatchTestDB(t)
+	if err := db.CreateEvent(&Event{SessionID: "s1", EventType: "init", RawJSON: "{}"}); err != nil {
+		t.Fatalf("CreateEvent() returned error: %v", err)
+	}
+
+	var b Batch
+	first := &Event{SessionID: "s1", EventType: "message", RawJSON: `{"n":1}`}
+	msg := &TranscriptMessage{SessionID: "s1", Role: "assistant", Kind: "text", Content: "hello"}
+	last := &Event{SessionID: "s1", EventType: "result", RawJSON: `{"n":2}`}
+	b.AddEvent(first)
+	b.AddTranscriptMessage(msg)
+	b.AddEvent(last)
+	if err := db.WriteBatch(&b); err != nil {
+		t.Fatalf("WriteBatch() returned error: %v", err)
+	}
+
+	if first.Sequence != 1 || msg.Sequence != 2 || last.Sequence != 3 {
+		t.Errorf("sequences = %d, %d, %d; want 1, 2, 3 after the stored event", first.Sequence, msg.Sequence, last.Sequence)
+	}
+	if first.ID == 0 || msg.ID == 0 || last.ID == 0 {
+		t.Error("WriteBatch() did not set IDs")
+	}
+	events, err := db.GetEventsBySession("s1")
+	if err != nil || len(events) != 3 || events[2].RawJSON != `{"n":2}` {
+		t.Errorf("GetEventsBySession() = %+v, %v; want the stored event and both batched", events, err)
+	}
+	messages, err := db.GetTranscriptBySession("s1")
+	if err != nil || len(messages) != 1 || messages[0].Content != "hello" {
+		t.Errorf("GetTranscriptBySession() = %+v, %v; want the batched message", messages, err)
+	}
+}
+
+func TestWriteBatch_RollsBackOnError(t *testing.T) {
+	db := newBatchTestDB(t)
+
+	var b Batch
+	b.AddEvent(&Event{SessionID: "s1", EventType: "message", RawJSON: "{}"})
+	b.AddEvent(&Event{SessionID: "missing", EventType: "message", RawJSON: "{}"})
+	if err := db.WriteBatch(&b); err == nil {
+		t.Fatal("WriteBatch() with an unknown session returned nil error")
+	}
+	if events, err := db.GetEventsBySession("s1"); err != nil || len(events) != 0 {
+		t.Errorf("GetEventsBySession() = %d events, %v; want none stored", len(events), err)
+	}
+}
+
+func TestBatchWriter(t *testing.T) {
+	db := newBatchTestDB(t)
+
+	w := NewBatchWriter(db, 10*time.Millisecond)
+	w.AddEvent(&Event{SessionID: "s1", EventType: "message", RawJSON: "{}"})
+	w.AddTranscriptMessage(&TranscriptMessage{SessionID: "s1", Role: "assistant", Kind: "text", Content: "hi"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		events, err := db.GetEventsBySession("s1")
+		if err != nil {
+			t.Fatalf("GetEventsBySession() returned error: %v", err)
+		}
+		if len(events) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("BatchWriter did not flush on its interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	w.AddEvent(&Event{SessionID: "s1", EventType: "result", RawJSON: "{}"})
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+	events, err := db.GetEventsBySession("s1")
+	if err != nil || len(events) != 2 || events[1].EventType != "result" || events[1].Sequence != 2 {
+		t.Errorf("GetEventsBySession() = %+v, %v; want the event added before Close stored last", events, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close() returned error: %v", err)
+	}
+}
+
+func TestCacheable(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"\n\t\tSELECT id FROM plans", true},
+		{"insert into events (id) values (?)", true},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", true},
+		{"PRAGMA user_version = 3", false},
+		{"ALTER TABLE plans ADD COLUMN x TEXT", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := cacheable(tt.query); got != tt.want {
+			t.Errorf("cacheable(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestStmtCache(t *testing.T) {
+	db := newBatchTestDB(t)
+	if db.conn.stmt(`SELECT id FROM plans WHERE id = ?`) == nil {
+		t.Fatal("stmt() = nil, want a prepared statement")
+	}
+	cached := len(db.conn.stmts)
+
+	// IN lists of every length bypass the cache
+	for _, numbers := range [][]int{{1}, {1, 2}, {1, 2, 3}} {
+		if err := db.ClaimFeedbackItems("plan-1", numbers, 1); err != nil {
+			t.Fatalf("ClaimFeedbackItems() returned error: %v", err)
+		}
+	}
+	if len(db.conn.stmts) != cached {
+		t.Errorf("cache grew from %d to %d statements", cached, len(db.conn.stmts))
+	}
+
+	// A failed prepare runs unprepared and is retried rather than cached
+	if err := db.conn.DB.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+	query := `SELECT id FROM plans WHERE status = ?`
+	if stmt := db.conn.stmt(query); stmt != nil {
+		t.Errorf("stmt() = %v, want nil when the prepare fails", stmt)
+	}
+	if _, ok := db.conn.stmts[query]; ok {
+		t.Error("failed prepare was cached")
+	}
+}
+
+// BenchmarkCreateEvent stores events one transaction each, as the loop did
+// before batching.
+func BenchmarkCreateEvent(b *testing.B) {
+	db := newBatchTestDB(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := db.CreateEvent(&Event{SessionID: "s1", EventType: "message", RawJSON: `{"type":"assistant"}`}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkWriteBatch stores the same events in batches of 100, about what
+// a fast stream produces in a flush interval.
+func BenchmarkWriteBatch(b *testing.B) {
+	db := newBatchTestDB(b)
+	const size = 100
+	b.ResetTimer()
+	for i := 0; i < b.N; i += size {
+		var batch Batch
+		for j := 0; j < size && i+j < b.N; j++ {
+			batch.AddEvent(&Event{SessionID: "s1", EventType: "message", RawJSON: `{"type":"assistant"}`})
+		}
+		if err := db.WriteBatch(&batch); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
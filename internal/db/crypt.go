@@ -0,0 +1,239 @@
+package db
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// encryptedPrefix marks a column value encrypted by a Cipher.
+const encryptedPrefix = "enc:v1:"
+
+// ErrEncrypted is returned when reading an encrypted value without a cipher.
+var ErrEncrypted = errors.New("value is encrypted; configure the encryption key to read it")
+
+// sensitiveColumns lists the columns encrypted at rest when a cipher is set:
+// plan content, prompts, and Claude's output. Progress, learnings, and
+// feedback stay in plaintext so they can be searched. All of them are
+// redacted except the raw columns, which keep the unredacted plan and
+// prompts for later runs and are only ever stored encrypted.
+var sensitiveColumns = []struct {
+	table  string
+	column string
+	raw    bool
+}{
+	{"plans", "content", false},
+	{"plans", "raw_content", true},
+	{"plan_sessions", "input_prompt", false},
+	{"plan_sessions", "raw_input_prompt", true},
+	{"plan_sessions", "final_output", false},
+	{"events", "raw_json", false},
+	{"transcript_messages", "content", false},
+}
+
+// Cipher encrypts column values with AES-256-GCM.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a cipher whose AES-256 key is the SHA-256 of key. Use a
+// long random key, e.g. from `openssl rand -base64 32`.
+func NewCipher(key string) (*Cipher, error) {
+	if key == "" {
+		return nil, errors.New("encryption key is empty")
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt returns the prefixed, base64-encoded nonce and ciphertext of
+// plaintext.
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt. Values without the encrypted prefix are returned
+// unchanged.
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt value (wrong encryption key?)")
+	}
+	return string(plaintext), nil
+}
+
+// IsEncrypted reports whether a stored value was encrypted by a Cipher.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// SetCipher enables at-rest encryption: sensitive columns are encrypted on
+// write and decrypted on read. Values stored before encryption was enabled
+// are still read as-is; EncryptExisting converts them.
+func (d *DB) SetCipher(c *Cipher) {
+	d.cipher = c
+}
+
+// seal prepares a sensitive column value for storage: secrets are masked when
+// a redactor is set, then the value is encrypted when a cipher is set. Empty
+// values are stored as-is.
+func (d *DB) seal(value string) (string, error) {
+	value = d.redactor.String(value)
+	if d.cipher == nil || value == "" {
+		return value, nil
+	}
+	return d.cipher.Encrypt(value)
+}
+
+// sealRaw prepares the unredacted value of a raw column for storage: it is
+// only kept, encrypted, when a cipher is set.
+func (d *DB) sealRaw(value string) (string, error) {
+	if d.cipher == nil || value == "" {
+		return "", nil
+	}
+	return d.cipher.Encrypt(value)
+}
+
+// sealInput seals a plan or prompt, which later runs execute: the stored
+// value is redacted, and when that masked a secret the unredacted value is
+// returned too, for its raw column.
+func (d *DB) sealInput(value string) (sealed, raw string, err error) {
+	if sealed, err = d.seal(value); err != nil {
+		return "", "", err
+	}
+	if d.redactor.String(value) != value {
+		if raw, err = d.sealRaw(value); err != nil {
+			return "", "", err
+		}
+	}
+	return sealed, raw, nil
+}
+
+// unseal decrypts a sensitive column value read from the database.
+func (d *DB) unseal(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if d.cipher == nil {
+		return "", ErrEncrypted
+	}
+	return d.cipher.Decrypt(value)
+}
+
+// unsealAll decrypts several column values in place, stopping at the first
+// error.
+func (d *DB) unsealAll(values ...*string) error {
+	for _, v := range values {
+		plaintext, err := d.unseal(*v)
+		if err != nil {
+			return err
+		}
+		*v = plaintext
+	}
+	return nil
+}
+
+// EncryptExisting encrypts the sensitive column values still stored in
+// plaintext and returns how many were encrypted. It requires a cipher.
+func (d *DB) EncryptExisting(ctx context.Context) (int, error) {
+	if d.cipher == nil {
+		return 0, errors.New("no encryption key configured")
+	}
+
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Warn("failed to rollback encryption transaction", "error", err)
+		}
+	}()
+
+	total := 0
+	for _, col := range sensitiveColumns {
+		n, err := d.encryptColumn(ctx, tx, col.table, col.column)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encrypt %s.%s: %w", col.table, col.column, err)
+		}
+		total += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit encryption: %w", err)
+	}
+	return total, nil
+}
+
+// encryptColumn encrypts the plaintext values of one column.
+func (d *DB) encryptColumn(ctx context.Context, tx *tx, table, column string) (int, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(
+		`SELECT id, %[1]s FROM %[2]s WHERE %[1]s IS NOT NULL AND %[1]s != '' AND %[1]s NOT LIKE ?`,
+		column, table), encryptedPrefix+"%")
+	if err != nil {
+		return 0, err
+	}
+
+	// Collect first so the updates don't run while the query is open
+	type row struct {
+		id    string // TEXT or INTEGER, depending on the table
+		value string
+	}
+	var plaintext []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.value); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		plaintext = append(plaintext, r)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	update := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE id = ?`, table, column)
+	for _, r := range plaintext {
+		sealed, err := d.cipher.Encrypt(r.value)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, update, sealed, r.id); err != nil {
+			return 0, err
+		}
+	}
+	return len(plaintext), nil
+}
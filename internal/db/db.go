@@ -3,26 +3,36 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	_ "modernc.org/sqlite"
 
 	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/redact"
 )
 
+// sqliteMaxIdleConns is how many idle connections a SQLite database keeps
+// open for reuse.
+const sqliteMaxIdleConns = 4
+
 // ErrNotFound is returned when a requested record is not found.
 var ErrNotFound = errors.New("record not found")
 
 // DB holds the database connection and provides methods for data access.
 type DB struct {
-	conn *sql.DB
+	conn     *conn
+	cipher   *Cipher          // Encrypts sensitive columns at rest (nil = plaintext)
+	redactor *redact.Redactor // Masks secrets in stored text (nil = store as-is)
 }
 
-// New creates a new database connection.
+// New creates a new SQLite database connection.
 // If the path is ":memory:", an in-memory database is created.
 // Otherwise, the parent directory is created if it doesn't exist.
 func New(path string) (*DB, error) {
@@ -38,24 +48,56 @@ func New(path string) (*DB, error) {
 	// - foreign_keys: enforce referential integrity
 	// - journal_mode=WAL: Write-Ahead Logging for better concurrent read/write
 	// - busy_timeout=5000: wait up to 5 seconds for locks instead of failing immediately
-	conn, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	db, err := open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", sqliteDialect{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep connections, and the statements prepared on them, open between
+	// bursts of writes. An in-memory database lives and dies with its
+	// connection, so it gets exactly one.
+	if path == ":memory:" {
+		db.conn.SetMaxOpenConns(1)
+	} else {
+		db.conn.SetMaxIdleConns(sqliteMaxIdleConns)
+	}
+	return db, nil
+}
+
+// Open opens the plans database on the given backend. For SQLite, dsn is
+// the database file path; for Postgres, it is a connection string.
+func Open(backend, dsn string) (*DB, error) {
+	switch backend {
+	case "", BackendSQLite:
+		return New(dsn)
+	case BackendPostgres:
+		return NewPostgres(dsn)
+	default:
+		return nil, fmt.Errorf("unknown database backend %q", backend)
+	}
+}
+
+// open connects with the given driver, verifies the connection, and runs
+// migrations.
+func open(driver, dsn string, dialect dialect) (*DB, error) {
+	sqlDB, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	// Verify connection
-	if err := conn.Ping(); err != nil {
-		if closeErr := conn.Close(); closeErr != nil {
+	if err := sqlDB.Ping(); err != nil {
+		if closeErr := sqlDB.Close(); closeErr != nil {
 			log.Warn("failed to close connection after ping failure", "error", closeErr)
 		}
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	db := &DB{conn: conn}
+	db := &DB{conn: &conn{DB: sqlDB, dialect: dialect}}
 
 	// Run migrations automatically
 	if err := db.Migrate(); err != nil {
-		if closeErr := conn.Close(); closeErr != nil {
+		if closeErr := sqlDB.Close(); closeErr != nil {
 			log.Warn("failed to close connection after migration failure", "error", closeErr)
 		}
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
@@ -64,6 +106,11 @@ func New(path string) (*DB, error) {
 	return db, nil
 }
 
+// Backend returns the database's backend (BackendSQLite or BackendPostgres).
+func (d *DB) Backend() string {
+	return d.conn.dialect.backend()
+}
+
 // Close closes the database connection.
 func (d *DB) Close() error {
 	if d.conn != nil {
@@ -528,15 +575,63 @@ func (d *DB) GetLatestSessionForTask(taskID string) (*Session, error) {
 	return session, nil
 }
 
+// =============================================================================
+// Sequence Methods
+// =============================================================================
+
+// NextSequence returns the next sequence number of a session, which numbers
+// the session's messages, events, and transcript messages in the order they
+// were written. A session's first number is 0. The counter is advanced
+// atomically in the database, so concurrent writers never get the same
+// number; it never falls behind the rows a session already has, such as
+// rows imported from exported plan state.
+func (d *DB) NextSequence(sessionID string) (int, error) {
+	return nextSequence(d.conn, sessionID)
+}
+
+// nextSequence assigns a session's next sequence number through e, so that
+// batches can number their records inside their transaction.
+func nextSequence(e execer, sessionID string) (int, error) {
+	var sequence int
+	err := e.QueryRow(`
+		INSERT INTO session_sequences (session_id, last_sequence)
+		VALUES (?, (
+			SELECT COALESCE(MAX(sequence), -1) + 1 FROM (
+				SELECT MAX(sequence) AS sequence FROM messages WHERE session_id = ?
+				UNION ALL
+				SELECT MAX(sequence) FROM events WHERE session_id = ?
+				UNION ALL
+				SELECT MAX(sequence) FROM transcript_messages WHERE session_id = ?
+			) AS existing
+		))
+		ON CONFLICT (session_id) DO UPDATE SET last_sequence = CASE
+			WHEN excluded.last_sequence > session_sequences.last_sequence THEN excluded.last_sequence
+			ELSE session_sequences.last_sequence + 1
+		END
+		RETURNING last_sequence`,
+		sessionID, sessionID, sessionID, sessionID,
+	).Scan(&sequence)
+	if err != nil {
+		return 0, fmt.Errorf("failed to assign sequence number: %w", err)
+	}
+	return sequence, nil
+}
+
 // =============================================================================
 // Message Methods
 // =============================================================================
 
-// CreateMessage inserts a new message into the database.
+// CreateMessage inserts a new message into the database, numbered by
+// NextSequence.
 func (d *DB) CreateMessage(message *Message) error {
+	sequence, err := d.NextSequence(message.SessionID)
+	if err != nil {
+		return err
+	}
+	message.Sequence = sequence
 	message.CreatedAt = time.Now()
 
-	result, err := d.conn.Exec(`
+	id, err := d.conn.insert(`
 		INSERT INTO messages (session_id, sequence, message_type, content, created_at)
 		VALUES (?, ?, ?, ?, ?)`,
 		message.SessionID, message.Sequence, message.MessageType,
@@ -545,11 +640,6 @@ func (d *DB) CreateMessage(message *Message) error {
 	if err != nil {
 		return err
 	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return err
-	}
 	message.ID = id
 	return nil
 }
@@ -590,7 +680,7 @@ func (d *DB) GetMessagesBySession(sessionID string) ([]*Message, error) {
 func (d *DB) CreateFeedback(feedback *Feedback) error {
 	feedback.CreatedAt = time.Now()
 
-	result, err := d.conn.Exec(`
+	id, err := d.conn.insert(`
 		INSERT INTO feedback (session_id, feedback_type, content, created_at)
 		VALUES (?, ?, ?, ?)`,
 		feedback.SessionID, feedback.FeedbackType, feedback.Content, feedback.CreatedAt,
@@ -598,11 +688,6 @@ func (d *DB) CreateFeedback(feedback *Feedback) error {
 	if err != nil {
 		return err
 	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return err
-	}
 	feedback.ID = id
 	return nil
 }
@@ -713,11 +798,16 @@ func (d *DB) CreatePlan(plan *Plan) error {
 	if plan.Status == "" {
 		plan.Status = PlanStatusPending
 	}
+	content, rawContent, err := d.sealInput(plan.Content)
+	if err != nil {
+		return err
+	}
 
-	_, err := d.conn.Exec(`
-		INSERT INTO plans (id, origin_path, content, status, base_change_id, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		plan.ID, plan.OriginPath, plan.Content, plan.Status, plan.BaseChangeID, plan.CreatedAt, plan.UpdatedAt,
+	_, err = d.conn.Exec(`
+		INSERT INTO plans (id, origin_path, content, raw_content, status, base_change_id, work_dir, origin_hash, forked_from, parent_plan_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		plan.ID, plan.OriginPath, content, rawContent, plan.Status, plan.BaseChangeID, plan.WorkDir, plan.OriginHash,
+		plan.ForkedFrom, plan.ParentPlanID, plan.CreatedAt, plan.UpdatedAt,
 	)
 	return err
 }
@@ -726,11 +816,11 @@ func (d *DB) CreatePlan(plan *Plan) error {
 func (d *DB) GetPlan(id string) (*Plan, error) {
 	plan := &Plan{}
 	err := d.conn.QueryRow(`
-		SELECT id, origin_path, content, status, base_change_id, created_at, updated_at
+		SELECT id, origin_path, content, raw_content, status, base_change_id, work_dir, failure_reason, origin_hash, forked_from, parent_plan_id, created_at, updated_at
 		FROM plans WHERE id = ?`, id,
 	).Scan(
-		&plan.ID, &plan.OriginPath, &plan.Content, &plan.Status, &plan.BaseChangeID,
-		&plan.CreatedAt, &plan.UpdatedAt,
+		&plan.ID, &plan.OriginPath, &plan.Content, &plan.RawContent, &plan.Status, &plan.BaseChangeID, &plan.WorkDir,
+		&plan.FailureReason, &plan.OriginHash, &plan.ForkedFrom, &plan.ParentPlanID, &plan.CreatedAt, &plan.UpdatedAt,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
@@ -738,14 +828,170 @@ func (d *DB) GetPlan(id string) (*Plan, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := d.unsealAll(&plan.Content, &plan.RawContent); err != nil {
+		return nil, err
+	}
 	return plan, nil
 }
 
-// UpdatePlanStatus updates a plan's status and updated_at timestamp.
+// ListPlans returns up to limit plans, most recently updated first. Plan
+// content is not loaded.
+func (d *DB) ListPlans(limit int) ([]*Plan, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, origin_path, status, base_change_id, work_dir, failure_reason, forked_from, parent_plan_id, created_at, updated_at
+		FROM plans ORDER BY updated_at DESC LIMIT ?`, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "error", closeErr)
+		}
+	}()
+
+	var plans []*Plan
+	for rows.Next() {
+		plan := &Plan{}
+		if err := rows.Scan(
+			&plan.ID, &plan.OriginPath, &plan.Status, &plan.BaseChangeID, &plan.WorkDir,
+			&plan.FailureReason, &plan.ForkedFrom, &plan.ParentPlanID, &plan.CreatedAt, &plan.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	return plans, rows.Err()
+}
+
+// GetSubPlans returns the sub-plans proposed by a plan's developer, oldest
+// first. Plan content is not loaded.
+func (d *DB) GetSubPlans(parentID string) ([]*Plan, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, origin_path, status, base_change_id, work_dir, failure_reason, forked_from, parent_plan_id, created_at, updated_at
+		FROM plans WHERE parent_plan_id = ? ORDER BY created_at ASC`, parentID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "error", closeErr)
+		}
+	}()
+
+	var plans []*Plan
+	for rows.Next() {
+		plan := &Plan{}
+		if err := rows.Scan(
+			&plan.ID, &plan.OriginPath, &plan.Status, &plan.BaseChangeID, &plan.WorkDir,
+			&plan.FailureReason, &plan.ForkedFrom, &plan.ParentPlanID, &plan.CreatedAt, &plan.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	return plans, rows.Err()
+}
+
+// GetPlansByStatus returns the plans with a status, most recently updated
+// first. Plan content is not loaded.
+func (d *DB) GetPlansByStatus(status PlanStatus) ([]*Plan, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, origin_path, status, base_change_id, work_dir, failure_reason, forked_from, parent_plan_id, created_at, updated_at
+		FROM plans WHERE status = ? ORDER BY updated_at DESC`, status,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "error", closeErr)
+		}
+	}()
+
+	var plans []*Plan
+	for rows.Next() {
+		plan := &Plan{}
+		if err := rows.Scan(
+			&plan.ID, &plan.OriginPath, &plan.Status, &plan.BaseChangeID, &plan.WorkDir,
+			&plan.FailureReason, &plan.ForkedFrom, &plan.ParentPlanID, &plan.CreatedAt, &plan.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	return plans, rows.Err()
+}
+
+// FindUnfinishedPlans returns the plans created from the file at
+// originPath, as last seen with originHash, to run in workDir, that are
+// neither completed nor cancelled, most recently updated first. Running
+// plans are included: whether their process is still alive is up to the
+// caller (see GetPlanRun). Forks, which carry their original's origin, are
+// left out. Plan content is not loaded.
+func (d *DB) FindUnfinishedPlans(originPath, originHash, workDir string) ([]*Plan, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, origin_path, status, base_change_id, work_dir, failure_reason, origin_hash, forked_from, parent_plan_id, created_at, updated_at
+		FROM plans
+		WHERE origin_path = ? AND origin_hash = ? AND work_dir = ? AND forked_from = ''
+			AND status NOT IN (?, ?)
+		ORDER BY updated_at DESC`,
+		originPath, originHash, workDir, PlanStatusCompleted, PlanStatusCancelled,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "error", closeErr)
+		}
+	}()
+
+	var plans []*Plan
+	for rows.Next() {
+		plan := &Plan{}
+		if err := rows.Scan(
+			&plan.ID, &plan.OriginPath, &plan.Status, &plan.BaseChangeID, &plan.WorkDir,
+			&plan.FailureReason, &plan.OriginHash, &plan.ForkedFrom, &plan.ParentPlanID, &plan.CreatedAt, &plan.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	return plans, rows.Err()
+}
+
+// ErrInvalidTransition is returned when a plan can't move to the requested
+// status from its current one.
+var ErrInvalidTransition = errors.New("invalid plan status transition")
+
+// UpdatePlanStatus updates a plan's status and updated_at timestamp, clearing
+// its failure reason.
 func (d *DB) UpdatePlanStatus(id string, status PlanStatus) error {
+	return d.UpdatePlanStatusWithReason(id, status, "")
+}
+
+// UpdatePlanStatusWithReason updates a plan's status, failure reason, and
+// updated_at timestamp. It returns ErrInvalidTransition if the plan can't
+// move to status from its current one.
+func (d *DB) UpdatePlanStatusWithReason(id string, status PlanStatus, reason string) error {
+	var current PlanStatus
+	err := d.conn.QueryRow(`SELECT status FROM plans WHERE id = ?`, id).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !current.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, current, status)
+	}
+
+	// Only update if nothing changed the status since it was read
 	result, err := d.conn.Exec(`
-		UPDATE plans SET status = ?, updated_at = ? WHERE id = ?`,
-		status, time.Now(), id,
+		UPDATE plans SET status = ?, failure_reason = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		status, d.redactor.String(reason), time.Now(), id, current,
 	)
 	if err != nil {
 		return err
@@ -755,7 +1001,7 @@ func (d *DB) UpdatePlanStatus(id string, status PlanStatus) error {
 		return err
 	}
 	if rows == 0 {
-		return ErrNotFound
+		return fmt.Errorf("plan %s status changed concurrently", id)
 	}
 	return nil
 }
@@ -781,6 +1027,72 @@ func (d *DB) UpdatePlanBaseChangeID(id string, baseChangeID string) error {
 	return nil
 }
 
+// UpdatePlanOriginHash records the hash of the plan file as last seen.
+func (d *DB) UpdatePlanOriginHash(id, hash string) error {
+	result, err := d.conn.Exec(`
+		UPDATE plans SET origin_hash = ?, updated_at = ? WHERE id = ?`,
+		hash, time.Now(), id,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdatePlanContent replaces a plan's content with edits made to its plan
+// file, recording the file's hash.
+func (d *DB) UpdatePlanContent(id, content, hash string) error {
+	sealed, raw, err := d.sealInput(content)
+	if err != nil {
+		return err
+	}
+	result, err := d.conn.Exec(`
+		UPDATE plans SET content = ?, raw_content = ?, origin_hash = ?, updated_at = ? WHERE id = ?`,
+		sealed, raw, hash, time.Now(), id,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreatePlanTasks stores the tasks a plan was decomposed into. Tasks belong
+// to a project sharing the plan's ID, which is created if it doesn't exist.
+func (d *DB) CreatePlanTasks(plan *Plan, tasks []*Task) error {
+	if _, err := d.GetProject(plan.ID); errors.Is(err, ErrNotFound) {
+		project := &Project{
+			ID:       plan.ID,
+			Name:     filepath.Base(plan.OriginPath),
+			PlanText: plan.Content,
+			Status:   ProjectInProgress,
+		}
+		if err := d.CreateProject(project); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		task.ProjectID = plan.ID
+	}
+	return d.CreateTasks(tasks)
+}
+
 // =============================================================================
 // Plan Session Methods
 // =============================================================================
@@ -794,12 +1106,20 @@ func (d *DB) CreatePlanSession(session *PlanSession) error {
 	if session.AgentType == "" {
 		session.AgentType = LoopAgentDeveloper
 	}
+	prompt, rawPrompt, err := d.sealInput(session.InputPrompt)
+	if err != nil {
+		return err
+	}
+	output, err := d.seal(session.FinalOutput)
+	if err != nil {
+		return err
+	}
 
-	_, err := d.conn.Exec(`
-		INSERT INTO plan_sessions (id, plan_id, iteration, input_prompt, final_output, status, agent_type, created_at, completed_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		session.ID, session.PlanID, session.Iteration, session.InputPrompt,
-		session.FinalOutput, session.Status, session.AgentType, session.CreatedAt, session.CompletedAt,
+	_, err = d.conn.Exec(`
+		INSERT INTO plan_sessions (id, plan_id, iteration, input_prompt, raw_input_prompt, final_output, status, agent_type, model, created_at, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		session.ID, session.PlanID, session.Iteration, prompt, rawPrompt,
+		output, session.Status, session.AgentType, session.Model, session.CreatedAt, session.CompletedAt,
 	)
 	return err
 }
@@ -808,11 +1128,14 @@ func (d *DB) CreatePlanSession(session *PlanSession) error {
 func (d *DB) GetPlanSession(id string) (*PlanSession, error) {
 	session := &PlanSession{}
 	err := d.conn.QueryRow(`
-		SELECT id, plan_id, iteration, input_prompt, final_output, status, agent_type, created_at, completed_at
+		SELECT id, plan_id, iteration, input_prompt, raw_input_prompt, final_output, status, agent_type, commit_id, model, claude_session_id, superseded, prompt_ms, claude_ms, parse_ms, jj_ms, reformat_attempts, created_at, completed_at
 		FROM plan_sessions WHERE id = ?`, id,
 	).Scan(
-		&session.ID, &session.PlanID, &session.Iteration, &session.InputPrompt,
-		&session.FinalOutput, &session.Status, &session.AgentType, &session.CreatedAt, &session.CompletedAt,
+		&session.ID, &session.PlanID, &session.Iteration, &session.InputPrompt, &session.RawInputPrompt,
+		&session.FinalOutput, &session.Status, &session.AgentType, &session.CommitID, &session.Model,
+		&session.ClaudeSessionID, &session.Superseded, millis{&session.Timings.Prompt}, millis{&session.Timings.Claude},
+		millis{&session.Timings.Parse}, millis{&session.Timings.JJ}, &session.ReformatAttempts,
+		&session.CreatedAt, &session.CompletedAt,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
@@ -820,12 +1143,19 @@ func (d *DB) GetPlanSession(id string) (*PlanSession, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := d.unsealAll(&session.InputPrompt, &session.RawInputPrompt, &session.FinalOutput); err != nil {
+		return nil, err
+	}
 	return session, nil
 }
 
 // CompletePlanSession marks a plan session as completed with the given status and output.
 func (d *DB) CompletePlanSession(id string, status PlanSessionStatus, finalOutput string) error {
 	now := time.Now()
+	finalOutput, err := d.seal(finalOutput)
+	if err != nil {
+		return err
+	}
 	result, err := d.conn.Exec(`
 		UPDATE plan_sessions SET status = ?, final_output = ?, completed_at = ? WHERE id = ?`,
 		status, finalOutput, now, id,
@@ -843,294 +1173,1783 @@ func (d *DB) CompletePlanSession(id string, status PlanSessionStatus, finalOutpu
 	return nil
 }
 
-// GetPlanSessionsByPlan returns all sessions for a plan ordered by iteration.
-func (d *DB) GetPlanSessionsByPlan(planID string) ([]*PlanSession, error) {
-	rows, err := d.conn.Query(`
-		SELECT id, plan_id, iteration, input_prompt, final_output, status, agent_type, created_at, completed_at
-		FROM plan_sessions WHERE plan_id = ? ORDER BY iteration`, planID)
+// UpdatePlanSessionCommitID records the jj commit ID of the working copy at
+// the end of a session.
+func (d *DB) UpdatePlanSessionCommitID(id, commitID string) error {
+	result, err := d.conn.Exec(`UPDATE plan_sessions SET commit_id = ? WHERE id = ?`, commitID, id)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer func() {
-		if closeErr := rows.Close(); closeErr != nil {
-			log.Warn("failed to close rows", "operation", "GetPlanSessionsByPlan", "error", closeErr)
-		}
-	}()
-
-	var sessions []*PlanSession
-	for rows.Next() {
-		s := &PlanSession{}
-		if err := rows.Scan(
-			&s.ID, &s.PlanID, &s.Iteration, &s.InputPrompt,
-			&s.FinalOutput, &s.Status, &s.AgentType, &s.CreatedAt, &s.CompletedAt,
-		); err != nil {
-			return nil, err
-		}
-		sessions = append(sessions, s)
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
 	}
-	return sessions, rows.Err()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
-// GetLatestPlanSession returns the most recent session for a plan.
-func (d *DB) GetLatestPlanSession(planID string) (*PlanSession, error) {
-	session := &PlanSession{}
-	err := d.conn.QueryRow(`
-		SELECT id, plan_id, iteration, input_prompt, final_output, status, agent_type, created_at, completed_at
-		FROM plan_sessions WHERE plan_id = ? ORDER BY iteration DESC LIMIT 1`, planID,
-	).Scan(
-		&session.ID, &session.PlanID, &session.Iteration, &session.InputPrompt,
-		&session.FinalOutput, &session.Status, &session.AgentType, &session.CreatedAt, &session.CompletedAt,
-	)
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil // Return nil, not error, when no records exist
+// UpdatePlanSessionClaudeSession records the ID Claude gave a session, so
+// that an interrupted session can be resumed where it stopped.
+func (d *DB) UpdatePlanSessionClaudeSession(id, claudeSessionID string) error {
+	result, err := d.conn.Exec(`UPDATE plan_sessions SET claude_session_id = ? WHERE id = ?`, claudeSessionID, id)
+	if err != nil {
+		return err
 	}
+	rows, err := result.RowsAffected()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return session, nil
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
-// =============================================================================
-// Event Methods
-// =============================================================================
-
-// CreateEvent inserts a new event into the database.
-func (d *DB) CreateEvent(event *Event) error {
-	event.CreatedAt = time.Now()
+// UpdatePlanSessionModel records the model that served a session.
+func (d *DB) UpdatePlanSessionModel(id, model string) error {
+	result, err := d.conn.Exec(`UPDATE plan_sessions SET model = ? WHERE id = ?`, model, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
 
+// UpdatePlanSessionTimings records how long the stages of a session took.
+func (d *DB) UpdatePlanSessionTimings(id string, timings SessionTimings) error {
 	result, err := d.conn.Exec(`
-		INSERT INTO events (session_id, sequence, event_type, raw_json, created_at)
-		VALUES (?, ?, ?, ?, ?)`,
-		event.SessionID, event.Sequence, event.EventType, event.RawJSON, event.CreatedAt,
+		UPDATE plan_sessions SET prompt_ms = ?, claude_ms = ?, parse_ms = ?, jj_ms = ? WHERE id = ?`,
+		timings.Prompt.Milliseconds(), timings.Claude.Milliseconds(),
+		timings.Parse.Milliseconds(), timings.JJ.Milliseconds(), id,
 	)
 	if err != nil {
 		return err
 	}
-
-	id, err := result.LastInsertId()
+	rows, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-	event.ID = id
+	if rows == 0 {
+		return ErrNotFound
+	}
 	return nil
 }
 
-// GetEventsBySession returns all events for a session ordered by sequence.
-func (d *DB) GetEventsBySession(sessionID string) ([]*Event, error) {
-	rows, err := d.conn.Query(`
-		SELECT id, session_id, sequence, event_type, raw_json, created_at
-		FROM events WHERE session_id = ? ORDER BY sequence`, sessionID)
+// IncrementReformatAttempts counts a request for a plan session's output to
+// be reformatted into the required sections.
+func (d *DB) IncrementReformatAttempts(id string) error {
+	result, err := d.conn.Exec(`
+		UPDATE plan_sessions SET reformat_attempts = reformat_attempts + 1 WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// millis scans an integer column of milliseconds into a duration.
+type millis struct{ d *time.Duration }
+
+// Scan implements sql.Scanner.
+func (m millis) Scan(src any) error {
+	var ms sql.NullInt64
+	if err := ms.Scan(src); err != nil {
+		return err
+	}
+	*m.d = time.Duration(ms.Int64) * time.Millisecond
+	return nil
+}
+
+// GetPlanSessionsByPlan returns all sessions for a plan ordered by iteration,
+// including sessions superseded by resuming from an earlier iteration.
+func (d *DB) GetPlanSessionsByPlan(planID string) ([]*PlanSession, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, plan_id, iteration, input_prompt, raw_input_prompt, final_output, status, agent_type, commit_id, model, claude_session_id, superseded, prompt_ms, claude_ms, parse_ms, jj_ms, reformat_attempts, created_at, completed_at
+		FROM plan_sessions WHERE plan_id = ? ORDER BY iteration, created_at`, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "operation", "GetPlanSessionsByPlan", "error", closeErr)
+		}
+	}()
+
+	var sessions []*PlanSession
+	for rows.Next() {
+		s := &PlanSession{}
+		if err := rows.Scan(
+			&s.ID, &s.PlanID, &s.Iteration, &s.InputPrompt, &s.RawInputPrompt,
+			&s.FinalOutput, &s.Status, &s.AgentType, &s.CommitID, &s.Model, &s.ClaudeSessionID, &s.Superseded,
+			millis{&s.Timings.Prompt}, millis{&s.Timings.Claude}, millis{&s.Timings.Parse}, millis{&s.Timings.JJ},
+			&s.ReformatAttempts, &s.CreatedAt, &s.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		if err := d.unsealAll(&s.InputPrompt, &s.RawInputPrompt, &s.FinalOutput); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// GetLatestPlanSession returns the most recent session for a plan that
+// hasn't been superseded.
+func (d *DB) GetLatestPlanSession(planID string) (*PlanSession, error) {
+	session := &PlanSession{}
+	err := d.conn.QueryRow(`
+		SELECT id, plan_id, iteration, input_prompt, raw_input_prompt, final_output, status, agent_type, commit_id, model, claude_session_id, superseded, prompt_ms, claude_ms, parse_ms, jj_ms, reformat_attempts, created_at, completed_at
+		FROM plan_sessions WHERE plan_id = ? AND NOT superseded ORDER BY iteration DESC, created_at DESC LIMIT 1`, planID,
+	).Scan(
+		&session.ID, &session.PlanID, &session.Iteration, &session.InputPrompt, &session.RawInputPrompt,
+		&session.FinalOutput, &session.Status, &session.AgentType, &session.CommitID, &session.Model,
+		&session.ClaudeSessionID, &session.Superseded, millis{&session.Timings.Prompt}, millis{&session.Timings.Claude},
+		millis{&session.Timings.Parse}, millis{&session.Timings.JJ}, &session.ReformatAttempts,
+		&session.CreatedAt, &session.CompletedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil // Return nil, not error, when no records exist
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := d.unsealAll(&session.InputPrompt, &session.RawInputPrompt, &session.FinalOutput); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// RewindPlan rewinds a plan's stored context to the end of the given
+// iteration, so that resuming continues from there. Sessions of later
+// iterations, and the progress, learnings, and reviewer feedback they
+// recorded, are marked superseded rather than deleted. Feedback left by the
+// iteration's reviewer is restored for the next developer.
+func (d *DB) RewindPlan(planID string, iteration int) error {
+	if err := d.CheckIteration(planID, iteration); err != nil {
+		return err
+	}
+	return d.rewindPlan(planID, iteration)
+}
+
+// UndoIteration rewinds a plan to the end of the iteration before its
+// latest, superseding the latest iteration's records the way RewindPlan
+// does. Undoing a plan's only iteration leaves it with none. It returns the
+// iteration undone.
+func (d *DB) UndoIteration(planID string) (int, error) {
+	latest, err := d.LatestIteration(planID)
+	if err != nil {
+		return 0, err
+	}
+	if latest == 0 {
+		return 0, fmt.Errorf("plan %s has no iterations to undo", planID)
+	}
+	return latest, d.rewindPlan(planID, latest-1)
+}
+
+// rewindPlan supersedes the records of the plan's iterations after the
+// given one.
+func (d *DB) rewindPlan(planID string, iteration int) error {
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && rbErr != sql.ErrTxDone {
+			log.Warn("failed to rollback transaction", "operation", "RewindPlan", "error", rbErr)
+		}
+	}()
+
+	for _, table := range []string{"progress", "learnings", "reviewer_feedback", "feedback_items"} {
+		if _, err := tx.Exec(`
+			UPDATE `+table+` SET superseded = ?
+			WHERE plan_id = ? AND NOT superseded
+			  AND session_id IN (SELECT id FROM plan_sessions WHERE plan_id = ? AND iteration > ?)`,
+			true, planID, planID, iteration,
+		); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`
+		UPDATE plan_sessions SET superseded = ? WHERE plan_id = ? AND iteration > ? AND NOT superseded`,
+		true, planID, iteration,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		UPDATE reviewer_feedback SET cleared = ?
+		WHERE plan_id = ? AND NOT superseded
+		  AND session_id IN (SELECT id FROM plan_sessions WHERE plan_id = ? AND iteration = ? AND NOT superseded)`,
+		false, planID, planID, iteration,
+	); err != nil {
+		return err
+	}
+	// Feedback items raised earlier go back to how they stood after the iteration
+	for _, column := range []string{"acknowledged_iteration", "claimed_iteration", "verified_iteration", "withdrawn_iteration"} {
+		if _, err := tx.Exec(`
+			UPDATE feedback_items SET `+column+` = 0 WHERE plan_id = ? AND `+column+` > ?`,
+			planID, iteration,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CheckIteration returns an error unless the plan has run the given
+// iteration, counting only sessions that haven't been superseded.
+func (d *DB) CheckIteration(planID string, iteration int) error {
+	latest, err := d.LatestIteration(planID)
+	if err != nil {
+		return err
+	}
+	if iteration < 1 || iteration > latest {
+		return fmt.Errorf("plan %s has no iteration %d (latest is %d)", planID, iteration, latest)
+	}
+	return nil
+}
+
+// LatestIteration returns the last iteration the plan ran, counting only
+// sessions that haven't been superseded, or 0 if it has run none.
+func (d *DB) LatestIteration(planID string) (int, error) {
+	var latest int
+	err := d.conn.QueryRow(`
+		SELECT COALESCE(MAX(iteration), 0) FROM plan_sessions WHERE plan_id = ? AND NOT superseded`, planID,
+	).Scan(&latest)
+	return latest, err
+}
+
+// ForkPlan stores fork, a new plan forked from fork.ForkedFrom, and copies
+// the source plan's latest progress and learnings into it. The copies are
+// attached to a completed planner session at iteration 0 whose commit ID is
+// changeID, the jj change the fork starts in, so the fork doesn't reference
+// the source plan's sessions.
+func (d *DB) ForkPlan(fork *Plan, changeID string) error {
+	if fork.ForkedFrom == "" {
+		return errors.New("fork has no source plan")
+	}
+	progress, err := d.GetLatestProgress(fork.ForkedFrom)
+	if err != nil {
+		return err
+	}
+	learnings, err := d.GetLatestLearnings(fork.ForkedFrom)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	fork.CreatedAt = now
+	fork.UpdatedAt = now
+	if fork.Status == "" {
+		fork.Status = PlanStatusPending
+	}
+	content, rawContent, err := d.sealInput(fork.Content)
+	if err != nil {
+		return err
+	}
+	prompt, err := d.seal(fmt.Sprintf("Forked from plan %s", fork.ForkedFrom))
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && rbErr != sql.ErrTxDone {
+			log.Warn("failed to rollback transaction", "operation", "ForkPlan", "error", rbErr)
+		}
+	}()
+
+	if _, err := tx.Exec(`
+		INSERT INTO plans (id, origin_path, content, raw_content, status, base_change_id, work_dir, origin_hash, forked_from, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		fork.ID, fork.OriginPath, content, rawContent, fork.Status, fork.BaseChangeID, fork.WorkDir, fork.OriginHash,
+		fork.ForkedFrom, fork.CreatedAt, fork.UpdatedAt,
+	); err != nil {
+		return err
+	}
+
+	sessionID := uuid.New().String()
+	if _, err := tx.Exec(`
+		INSERT INTO plan_sessions (id, plan_id, iteration, input_prompt, final_output, status, agent_type, commit_id, created_at, completed_at)
+		VALUES (?, ?, 0, ?, '', ?, ?, ?, ?, ?)`,
+		sessionID, fork.ID, prompt, PlanSessionCompleted, LoopAgentPlanner, changeID, now, now,
+	); err != nil {
+		return err
+	}
+
+	if progress != nil {
+		if _, err := tx.insert(`
+			INSERT INTO progress (plan_id, session_id, content, created_at)
+			VALUES (?, ?, ?, ?)`,
+			fork.ID, sessionID, progress.Content, now,
+		); err != nil {
+			return err
+		}
+	}
+	if learnings != nil {
+		if _, err := tx.insert(`
+			INSERT INTO learnings (plan_id, session_id, content, created_at)
+			VALUES (?, ?, ?, ?)`,
+			fork.ID, sessionID, learnings.Content, now,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// =============================================================================
+// Event Methods
+// =============================================================================
+
+// insertEventQuery inserts an event.
+const insertEventQuery = `
+		INSERT INTO events (session_id, sequence, event_type, raw_json, sub_agent_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+// CreateEvent inserts a new event into the database, numbered by
+// NextSequence.
+func (d *DB) CreateEvent(event *Event) error {
+	rawJSON, err := d.seal(event.RawJSON)
+	if err != nil {
+		return err
+	}
+	sequence, err := d.NextSequence(event.SessionID)
+	if err != nil {
+		return err
+	}
+	event.Sequence = sequence
+	event.CreatedAt = time.Now()
+
+	id, err := d.conn.insert(insertEventQuery,
+		event.SessionID, event.Sequence, event.EventType, rawJSON, event.SubAgentID, event.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	event.ID = id
+	return nil
+}
+
+// GetEventsBySession returns all events for a session ordered by sequence.
+func (d *DB) GetEventsBySession(sessionID string) ([]*Event, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, session_id, sequence, event_type, raw_json, sub_agent_id, created_at
+		FROM events WHERE session_id = ? ORDER BY sequence`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "operation", "GetEventsBySession", "error", closeErr)
+		}
+	}()
+
+	var events []*Event
+	for rows.Next() {
+		e := &Event{}
+		if err := rows.Scan(
+			&e.ID, &e.SessionID, &e.Sequence, &e.EventType,
+			&e.RawJSON, &e.SubAgentID, &e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if err := d.unsealAll(&e.RawJSON); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// GetPlanEventsAfter returns the events of all of a plan's sessions with IDs
+// greater than afterID, in the order they were stored. Pass 0 for every event.
+func (d *DB) GetPlanEventsAfter(planID string, afterID int64) ([]*Event, error) {
+	rows, err := d.conn.Query(`
+		SELECT e.id, e.session_id, e.sequence, e.event_type, e.raw_json, e.sub_agent_id, e.created_at
+		FROM events e JOIN plan_sessions s ON s.id = e.session_id
+		WHERE s.plan_id = ? AND e.id > ? ORDER BY e.id`, planID, afterID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "operation", "GetPlanEventsAfter", "error", closeErr)
+		}
+	}()
+
+	var events []*Event
+	for rows.Next() {
+		e := &Event{}
+		if err := rows.Scan(
+			&e.ID, &e.SessionID, &e.Sequence, &e.EventType,
+			&e.RawJSON, &e.SubAgentID, &e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if err := d.unsealAll(&e.RawJSON); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// =============================================================================
+// Transcript Methods
+// =============================================================================
+
+// insertTranscriptMessageQuery inserts a transcript message.
+const insertTranscriptMessageQuery = `
+		INSERT INTO transcript_messages (session_id, sequence, role, kind, tool_name, tool_use_id, content, is_error, sub_agent_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// CreateTranscriptMessage inserts a new transcript message into the
+// database, numbered by NextSequence.
+func (d *DB) CreateTranscriptMessage(msg *TranscriptMessage) error {
+	content, err := d.seal(msg.Content)
+	if err != nil {
+		return err
+	}
+	sequence, err := d.NextSequence(msg.SessionID)
+	if err != nil {
+		return err
+	}
+	msg.Sequence = sequence
+	msg.CreatedAt = time.Now()
+
+	id, err := d.conn.insert(insertTranscriptMessageQuery,
+		msg.SessionID, msg.Sequence, msg.Role, msg.Kind, msg.ToolName, msg.ToolUseID,
+		content, msg.IsError, msg.SubAgentID, msg.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	msg.ID = id
+	return nil
+}
+
+// GetTranscriptBySession returns a session's transcript messages ordered by sequence.
+func (d *DB) GetTranscriptBySession(sessionID string) ([]*TranscriptMessage, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, session_id, sequence, role, kind, tool_name, tool_use_id, content, is_error, sub_agent_id, created_at
+		FROM transcript_messages WHERE session_id = ? ORDER BY sequence`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "operation", "GetTranscriptBySession", "error", closeErr)
+		}
+	}()
+
+	var messages []*TranscriptMessage
+	for rows.Next() {
+		m := &TranscriptMessage{}
+		if err := rows.Scan(
+			&m.ID, &m.SessionID, &m.Sequence, &m.Role, &m.Kind, &m.ToolName,
+			&m.ToolUseID, &m.Content, &m.IsError, &m.SubAgentID, &m.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if err := d.unsealAll(&m.Content); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// =============================================================================
+// Tool Usage Methods
+// =============================================================================
+
+// CreateToolUsage inserts a tool usage summary into the database.
+func (d *DB) CreateToolUsage(usage *ToolUsage) error {
+	usage.CreatedAt = time.Now()
+
+	// Commands can span lines, so lists are stored as JSON arrays
+	files, err := json.Marshal(usage.Files)
+	if err != nil {
+		return err
+	}
+	commands, err := json.Marshal(d.redactAll(usage.Commands))
+	if err != nil {
+		return err
+	}
+
+	id, err := d.conn.insert(`
+		INSERT INTO tool_usage (plan_id, session_id, iteration, tool_name, calls, files, commands, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		usage.PlanID, usage.SessionID, usage.Iteration, usage.ToolName, usage.Calls,
+		string(files), string(commands), usage.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	usage.ID = id
+	return nil
+}
+
+// GetToolUsageByPlan returns a plan's tool usage summaries ordered by
+// iteration, then by insertion.
+func (d *DB) GetToolUsageByPlan(planID string) ([]*ToolUsage, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, plan_id, session_id, iteration, tool_name, calls, files, commands, created_at
+		FROM tool_usage WHERE plan_id = ? ORDER BY iteration, id`, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "operation", "GetToolUsageByPlan", "error", closeErr)
+		}
+	}()
+
+	var usages []*ToolUsage
+	for rows.Next() {
+		u := &ToolUsage{}
+		var files, commands string
+		if err := rows.Scan(
+			&u.ID, &u.PlanID, &u.SessionID, &u.Iteration, &u.ToolName, &u.Calls,
+			&files, &commands, &u.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(files), &u.Files); err != nil {
+			return nil, fmt.Errorf("invalid files for tool usage %d: %w", u.ID, err)
+		}
+		if err := json.Unmarshal([]byte(commands), &u.Commands); err != nil {
+			return nil, fmt.Errorf("invalid commands for tool usage %d: %w", u.ID, err)
+		}
+		usages = append(usages, u)
+	}
+	return usages, rows.Err()
+}
+
+// =============================================================================
+// Progress Methods
+// =============================================================================
+
+// CreateProgress inserts a new progress record into the database.
+func (d *DB) CreateProgress(progress *Progress) error {
+	progress.CreatedAt = time.Now()
+
+	id, err := d.conn.insert(`
+		INSERT INTO progress (plan_id, session_id, task_id, content, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		progress.PlanID, progress.SessionID, progress.TaskID, d.redactor.String(progress.Content), progress.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	progress.ID = id
+	return nil
+}
+
+// GetLatestProgress returns the most recent progress for a plan, skipping
+// superseded records.
+func (d *DB) GetLatestProgress(planID string) (*Progress, error) {
+	progress := &Progress{}
+	err := d.conn.QueryRow(`
+		SELECT id, plan_id, session_id, task_id, content, created_at
+		FROM progress WHERE plan_id = ? AND NOT superseded ORDER BY created_at DESC LIMIT 1`, planID,
+	).Scan(
+		&progress.ID, &progress.PlanID, &progress.SessionID, &progress.TaskID,
+		&progress.Content, &progress.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil // Return nil, not error, when no records exist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// GetLatestTaskProgress returns the most recent progress recorded for a task of a plan.
+func (d *DB) GetLatestTaskProgress(planID, taskID string) (*Progress, error) {
+	progress := &Progress{}
+	err := d.conn.QueryRow(`
+		SELECT id, plan_id, session_id, task_id, content, created_at
+		FROM progress WHERE plan_id = ? AND task_id = ? AND NOT superseded ORDER BY created_at DESC LIMIT 1`, planID, taskID,
+	).Scan(
+		&progress.ID, &progress.PlanID, &progress.SessionID, &progress.TaskID,
+		&progress.Content, &progress.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil // Return nil, not error, when no records exist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// GetProgressHistory returns all progress records for a plan ordered by created_at.
+func (d *DB) GetProgressHistory(planID string) ([]*Progress, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, plan_id, session_id, task_id, content, created_at
+		FROM progress WHERE plan_id = ? ORDER BY created_at`, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "operation", "GetProgressHistory", "error", closeErr)
+		}
+	}()
+
+	var progressList []*Progress
+	for rows.Next() {
+		p := &Progress{}
+		if err := rows.Scan(
+			&p.ID, &p.PlanID, &p.SessionID, &p.TaskID, &p.Content, &p.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		progressList = append(progressList, p)
+	}
+	return progressList, rows.Err()
+}
+
+// =============================================================================
+// Learnings Methods
+// =============================================================================
+
+// CreateLearnings inserts a new learnings record into the database.
+func (d *DB) CreateLearnings(learnings *Learnings) error {
+	learnings.CreatedAt = time.Now()
+
+	id, err := d.conn.insert(`
+		INSERT INTO learnings (plan_id, session_id, task_id, content, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		learnings.PlanID, learnings.SessionID, learnings.TaskID, d.redactor.String(learnings.Content), learnings.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	learnings.ID = id
+	return nil
+}
+
+// GetLatestLearnings returns the most recent learnings for a plan, skipping
+// superseded records.
+func (d *DB) GetLatestLearnings(planID string) (*Learnings, error) {
+	learnings := &Learnings{}
+	err := d.conn.QueryRow(`
+		SELECT id, plan_id, session_id, task_id, content, created_at
+		FROM learnings WHERE plan_id = ? AND NOT superseded ORDER BY created_at DESC LIMIT 1`, planID,
+	).Scan(
+		&learnings.ID, &learnings.PlanID, &learnings.SessionID, &learnings.TaskID,
+		&learnings.Content, &learnings.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil // Return nil, not error, when no records exist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return learnings, nil
+}
+
+// GetLatestTaskLearnings returns the most recent learnings recorded for a task of a plan.
+func (d *DB) GetLatestTaskLearnings(planID, taskID string) (*Learnings, error) {
+	learnings := &Learnings{}
+	err := d.conn.QueryRow(`
+		SELECT id, plan_id, session_id, task_id, content, created_at
+		FROM learnings WHERE plan_id = ? AND task_id = ? AND NOT superseded ORDER BY created_at DESC LIMIT 1`, planID, taskID,
+	).Scan(
+		&learnings.ID, &learnings.PlanID, &learnings.SessionID, &learnings.TaskID,
+		&learnings.Content, &learnings.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil // Return nil, not error, when no records exist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return learnings, nil
+}
+
+// GetLearningsHistory returns all learnings records for a plan ordered by
+// created_at, skipping superseded records.
+func (d *DB) GetLearningsHistory(planID string) ([]*Learnings, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, plan_id, session_id, task_id, content, created_at
+		FROM learnings WHERE plan_id = ? AND NOT superseded ORDER BY created_at`, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "operation", "GetLearningsHistory", "error", closeErr)
+		}
+	}()
+
+	var learningsList []*Learnings
+	for rows.Next() {
+		l := &Learnings{}
+		if err := rows.Scan(
+			&l.ID, &l.PlanID, &l.SessionID, &l.TaskID, &l.Content, &l.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		learningsList = append(learningsList, l)
+	}
+	return learningsList, rows.Err()
+}
+
+// =============================================================================
+// Reviewer Feedback Methods
+// =============================================================================
+
+// CreateReviewerFeedback inserts a new reviewer feedback record into the database.
+func (d *DB) CreateReviewerFeedback(feedback *ReviewerFeedback) error {
+	feedback.CreatedAt = time.Now()
+
+	id, err := d.conn.insert(`
+		INSERT INTO reviewer_feedback (plan_id, session_id, content, created_at)
+		VALUES (?, ?, ?, ?)`,
+		feedback.PlanID, feedback.SessionID, d.redactor.String(feedback.Content), feedback.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	feedback.ID = id
+	return nil
+}
+
+// GetLatestReviewerFeedback returns the most recent reviewer feedback for a
+// plan that is neither cleared nor superseded.
+func (d *DB) GetLatestReviewerFeedback(planID string) (*ReviewerFeedback, error) {
+	feedback := &ReviewerFeedback{}
+	err := d.conn.QueryRow(`
+		SELECT id, plan_id, session_id, content, cleared, superseded, created_at
+		FROM reviewer_feedback WHERE plan_id = ? AND NOT cleared AND NOT superseded ORDER BY created_at DESC LIMIT 1`, planID,
+	).Scan(
+		&feedback.ID, &feedback.PlanID, &feedback.SessionID,
+		&feedback.Content, &feedback.Cleared, &feedback.Superseded, &feedback.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil // Return nil, not error, when no records exist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return feedback, nil
+}
+
+// GetReviewerFeedbackByPlan returns all of a plan's reviewer feedback,
+// cleared and superseded included, oldest first.
+func (d *DB) GetReviewerFeedbackByPlan(planID string) ([]*ReviewerFeedback, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, plan_id, session_id, content, cleared, superseded, created_at
+		FROM reviewer_feedback WHERE plan_id = ? ORDER BY created_at, id`, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "operation", "GetReviewerFeedbackByPlan", "error", closeErr)
+		}
+	}()
+
+	var feedbackList []*ReviewerFeedback
+	for rows.Next() {
+		f := &ReviewerFeedback{}
+		if err := rows.Scan(
+			&f.ID, &f.PlanID, &f.SessionID,
+			&f.Content, &f.Cleared, &f.Superseded, &f.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		feedbackList = append(feedbackList, f)
+	}
+	return feedbackList, rows.Err()
+}
+
+// ClearReviewerFeedback marks all reviewer feedback for a plan as addressed
+// (used after developer addresses it). Cleared feedback is kept so that
+// RewindPlan can bring it back.
+func (d *DB) ClearReviewerFeedback(planID string) error {
+	_, err := d.conn.Exec(`UPDATE reviewer_feedback SET cleared = ? WHERE plan_id = ? AND NOT cleared`, true, planID)
+	return err
+}
+
+// CreateFeedbackItem inserts a feedback item, numbering it after the plan's
+// earlier items.
+func (d *DB) CreateFeedbackItem(item *FeedbackItem) error {
+	item.CreatedAt = time.Now()
+
+	// Superseded items keep their numbers, so a number is never reused
+	if err := d.conn.QueryRow(`
+		SELECT COALESCE(MAX(number), 0) + 1 FROM feedback_items WHERE plan_id = ?`, item.PlanID,
+	).Scan(&item.Number); err != nil {
+		return err
+	}
+	id, err := d.conn.insert(`
+		INSERT INTO feedback_items (plan_id, session_id, number, severity, content, issued_iteration, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		item.PlanID, item.SessionID, item.Number, item.Severity, d.redactor.String(item.Content),
+		item.IssuedIteration, item.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	item.ID = id
+	return nil
+}
+
+// GetFeedbackItemsByPlan returns a plan's feedback items that aren't
+// superseded, closed ones included, in number order.
+func (d *DB) GetFeedbackItemsByPlan(planID string) ([]*FeedbackItem, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, plan_id, session_id, number, severity, content, issued_iteration,
+		       acknowledged_iteration, claimed_iteration, verified_iteration, withdrawn_iteration,
+		       superseded, created_at
+		FROM feedback_items WHERE plan_id = ? AND NOT superseded ORDER BY number`, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "operation", "GetFeedbackItemsByPlan", "error", closeErr)
+		}
+	}()
+
+	var items []*FeedbackItem
+	for rows.Next() {
+		i := &FeedbackItem{}
+		if err := rows.Scan(
+			&i.ID, &i.PlanID, &i.SessionID, &i.Number, &i.Severity, &i.Content, &i.IssuedIteration,
+			&i.AcknowledgedIteration, &i.ClaimedIteration, &i.VerifiedIteration, &i.WithdrawnIteration,
+			&i.Superseded, &i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
+
+// GetOpenFeedbackItems returns a plan's feedback items that are neither
+// verified nor withdrawn, in number order.
+func (d *DB) GetOpenFeedbackItems(planID string) ([]*FeedbackItem, error) {
+	items, err := d.GetFeedbackItemsByPlan(planID)
+	if err != nil {
+		return nil, err
+	}
+	var open []*FeedbackItem
+	for _, item := range items {
+		if item.Open() {
+			open = append(open, item)
+		}
+	}
+	return open, nil
+}
+
+// AcknowledgeFeedbackItems records that the developer session of the given
+// iteration was shown the plan's open items.
+func (d *DB) AcknowledgeFeedbackItems(planID string, iteration int) error {
+	_, err := d.conn.Exec(`
+		UPDATE feedback_items SET acknowledged_iteration = ?
+		WHERE plan_id = ? AND NOT superseded AND acknowledged_iteration = 0
+		  AND verified_iteration = 0 AND withdrawn_iteration = 0`,
+		iteration, planID,
+	)
+	return err
+}
+
+// ClaimFeedbackItems records the developer's claim that the open items with
+// the given numbers are fixed. Numbers of closed or unknown items are
+// ignored.
+func (d *DB) ClaimFeedbackItems(planID string, numbers []int, iteration int) error {
+	if len(numbers) == 0 {
+		return nil
+	}
+	placeholders, args := numberArgs(numbers)
+	_, err := d.conn.execUnprepared(`
+		UPDATE feedback_items SET claimed_iteration = ?
+		WHERE plan_id = ? AND NOT superseded AND verified_iteration = 0 AND withdrawn_iteration = 0
+		  AND number IN (`+placeholders+`)`,
+		append([]any{iteration, planID}, args...)...,
+	)
+	return err
+}
+
+// VerifyFeedbackItems closes the claimed-fixed items with the given numbers
+// and reopens the plan's other claimed-fixed items, which the reviewer
+// didn't accept as fixed.
+func (d *DB) VerifyFeedbackItems(planID string, numbers []int, iteration int) error {
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && rbErr != sql.ErrTxDone {
+			log.Warn("failed to rollback transaction", "operation", "VerifyFeedbackItems", "error", rbErr)
+		}
+	}()
+
+	const claimed = `plan_id = ? AND NOT superseded AND claimed_iteration > 0
+		  AND verified_iteration = 0 AND withdrawn_iteration = 0`
+	if len(numbers) > 0 {
+		placeholders, args := numberArgs(numbers)
+		if _, err := tx.Exec(`
+			UPDATE feedback_items SET verified_iteration = ?
+			WHERE `+claimed+` AND number IN (`+placeholders+`)`,
+			append([]any{iteration, planID}, args...)...,
+		); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE feedback_items SET claimed_iteration = 0 WHERE `+claimed, planID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CloseFeedbackItems verifies all of the plan's open items, for a reviewer's
+// approval of the finished plan.
+func (d *DB) CloseFeedbackItems(planID string, iteration int) error {
+	_, err := d.conn.Exec(`
+		UPDATE feedback_items SET verified_iteration = ?
+		WHERE plan_id = ? AND NOT superseded AND verified_iteration = 0 AND withdrawn_iteration = 0`,
+		iteration, planID,
+	)
+	return err
+}
+
+// WithdrawFeedbackItems closes the open items with the given numbers raised
+// by a reviewer session, for when the developer's rebuttal of them was
+// accepted. The session's other items stay open.
+func (d *DB) WithdrawFeedbackItems(planID, sessionID string, numbers []int, iteration int) error {
+	if len(numbers) == 0 {
+		return nil
+	}
+	placeholders, args := numberArgs(numbers)
+	_, err := d.conn.execUnprepared(`
+		UPDATE feedback_items SET withdrawn_iteration = ?
+		WHERE plan_id = ? AND session_id = ? AND NOT superseded
+		  AND verified_iteration = 0 AND withdrawn_iteration = 0
+		  AND number IN (`+placeholders+`)`,
+		append([]any{iteration, planID, sessionID}, args...)...,
+	)
+	return err
+}
+
+// numberArgs returns the placeholders and arguments for an IN list of
+// feedback item numbers.
+func numberArgs(numbers []int) (string, []any) {
+	args := make([]any, len(numbers))
+	for i, n := range numbers {
+		args[i] = n
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", len(numbers)), ","), args
+}
+
+// CreateAnalyzerFinding stores a static analyzer's findings.
+func (d *DB) CreateAnalyzerFinding(finding *AnalyzerFinding) error {
+	finding.CreatedAt = time.Now()
+
+	id, err := d.conn.insert(`
+		INSERT INTO analyzer_findings (plan_id, session_id, analyzer, output, failed, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		finding.PlanID, finding.SessionID, finding.Analyzer, d.redactor.String(finding.Output),
+		finding.Failed, finding.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	finding.ID = id
+	return nil
+}
+
+// GetAnalyzerFindingsBySession returns the analyzer findings given to a
+// reviewer session, in the order they were stored.
+func (d *DB) GetAnalyzerFindingsBySession(sessionID string) ([]*AnalyzerFinding, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, plan_id, session_id, analyzer, output, failed, created_at
+		FROM analyzer_findings WHERE session_id = ? ORDER BY id`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "operation", "GetAnalyzerFindingsBySession", "error", closeErr)
+		}
+	}()
+
+	var findings []*AnalyzerFinding
+	for rows.Next() {
+		f := &AnalyzerFinding{}
+		if err := rows.Scan(
+			&f.ID, &f.PlanID, &f.SessionID, &f.Analyzer, &f.Output, &f.Failed, &f.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		findings = append(findings, f)
+	}
+	return findings, rows.Err()
+}
+
+// CreateCheckFailure stores the output of a failing check.
+func (d *DB) CreateCheckFailure(failure *CheckFailure) error {
+	failure.CreatedAt = time.Now()
+
+	id, err := d.conn.insert(`
+		INSERT INTO check_failures (plan_id, session_id, name, output, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		failure.PlanID, failure.SessionID, failure.Name, d.redactor.String(failure.Output), failure.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	failure.ID = id
+	return nil
+}
+
+// GetCheckFailuresBySession returns the checks a developer session's changes
+// failed, in the order they were stored.
+func (d *DB) GetCheckFailuresBySession(sessionID string) ([]*CheckFailure, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, plan_id, session_id, name, output, created_at
+		FROM check_failures WHERE session_id = ? ORDER BY id`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "operation", "GetCheckFailuresBySession", "error", closeErr)
+		}
+	}()
+
+	var failures []*CheckFailure
+	for rows.Next() {
+		f := &CheckFailure{}
+		if err := rows.Scan(&f.ID, &f.PlanID, &f.SessionID, &f.Name, &f.Output, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		failures = append(failures, f)
+	}
+	return failures, rows.Err()
+}
+
+// =============================================================================
+// As-Of Methods
+// =============================================================================
+
+// IterationState is a plan's context as it stood at the end of an
+// iteration: what the prompts of the next iteration were built from.
+type IterationState struct {
+	Iteration int
+	Progress  *Progress         // nil if none was recorded yet
+	Learnings *Learnings        // nil if none were recorded yet
+	Feedback  *ReviewerFeedback // Feedback left for the next developer (nil if the reviewer left none)
+}
+
+// GetStateAsOf returns a plan's progress, learnings, and reviewer feedback
+// as they stood at the end of the given iteration, leaving out records of
+// later iterations and superseded ones. It returns an error if the plan
+// hasn't run the iteration.
+func (d *DB) GetStateAsOf(planID string, iteration int) (*IterationState, error) {
+	if err := d.CheckIteration(planID, iteration); err != nil {
+		return nil, err
+	}
+	state := &IterationState{Iteration: iteration}
+	var err error
+	if state.Progress, err = d.GetProgressAsOf(planID, iteration); err != nil {
+		return nil, fmt.Errorf("failed to get progress: %w", err)
+	}
+	if state.Learnings, err = d.GetLearningsAsOf(planID, iteration); err != nil {
+		return nil, fmt.Errorf("failed to get learnings: %w", err)
+	}
+	if state.Feedback, err = d.GetReviewerFeedbackAsOf(planID, iteration); err != nil {
+		return nil, fmt.Errorf("failed to get reviewer feedback: %w", err)
+	}
+	return state, nil
+}
+
+// GetProgressAsOf returns the most recent progress recorded by the end of the
+// given iteration, skipping superseded records.
+func (d *DB) GetProgressAsOf(planID string, iteration int) (*Progress, error) {
+	progress := &Progress{}
+	err := d.conn.QueryRow(`
+		SELECT id, plan_id, session_id, task_id, content, created_at
+		FROM progress WHERE plan_id = ? AND NOT superseded
+		  AND session_id IN (SELECT id FROM plan_sessions WHERE plan_id = ? AND iteration <= ? AND NOT superseded)
+		ORDER BY created_at DESC LIMIT 1`, planID, planID, iteration,
+	).Scan(
+		&progress.ID, &progress.PlanID, &progress.SessionID, &progress.TaskID,
+		&progress.Content, &progress.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil // Return nil, not error, when no records exist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// GetLearningsAsOf returns the most recent learnings recorded by the end of
+// the given iteration, skipping superseded records.
+func (d *DB) GetLearningsAsOf(planID string, iteration int) (*Learnings, error) {
+	learnings := &Learnings{}
+	err := d.conn.QueryRow(`
+		SELECT id, plan_id, session_id, task_id, content, created_at
+		FROM learnings WHERE plan_id = ? AND NOT superseded
+		  AND session_id IN (SELECT id FROM plan_sessions WHERE plan_id = ? AND iteration <= ? AND NOT superseded)
+		ORDER BY created_at DESC LIMIT 1`, planID, planID, iteration,
+	).Scan(
+		&learnings.ID, &learnings.PlanID, &learnings.SessionID, &learnings.TaskID,
+		&learnings.Content, &learnings.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil // Return nil, not error, when no records exist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return learnings, nil
+}
+
+// GetLearningsHistoryAsOf returns the learnings records of a plan up to the
+// end of the given iteration, ordered by created_at, skipping superseded
+// records.
+func (d *DB) GetLearningsHistoryAsOf(planID string, iteration int) ([]*Learnings, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, plan_id, session_id, task_id, content, created_at
+		FROM learnings WHERE plan_id = ? AND NOT superseded
+		  AND session_id IN (SELECT id FROM plan_sessions WHERE plan_id = ? AND iteration <= ? AND NOT superseded)
+		ORDER BY created_at`, planID, planID, iteration)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "operation", "GetLearningsHistoryAsOf", "error", closeErr)
+		}
+	}()
+
+	var learningsList []*Learnings
+	for rows.Next() {
+		l := &Learnings{}
+		if err := rows.Scan(
+			&l.ID, &l.PlanID, &l.SessionID, &l.TaskID, &l.Content, &l.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		learningsList = append(learningsList, l)
+	}
+	return learningsList, rows.Err()
+}
+
+// GetReviewerFeedbackAsOf returns the reviewer feedback pending at the end of
+// the given iteration: the most recent feedback left by that iteration's
+// reviewers, whether or not a later developer has cleared it. Feedback of
+// earlier iterations was addressed by the iteration's developer.
+func (d *DB) GetReviewerFeedbackAsOf(planID string, iteration int) (*ReviewerFeedback, error) {
+	feedback := &ReviewerFeedback{}
+	err := d.conn.QueryRow(`
+		SELECT id, plan_id, session_id, content, cleared, superseded, created_at
+		FROM reviewer_feedback WHERE plan_id = ? AND NOT superseded
+		  AND session_id IN (SELECT id FROM plan_sessions WHERE plan_id = ? AND iteration = ? AND NOT superseded)
+		ORDER BY created_at DESC LIMIT 1`, planID, planID, iteration,
+	).Scan(
+		&feedback.ID, &feedback.PlanID, &feedback.SessionID,
+		&feedback.Content, &feedback.Cleared, &feedback.Superseded, &feedback.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil // Return nil, not error, when no records exist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return feedback, nil
+}
+
+// =============================================================================
+// Review Skip Methods
+// =============================================================================
+
+// CreateReviewSkip records a review skipped or downgraded for a trivial
+// change.
+func (d *DB) CreateReviewSkip(skip *ReviewSkip) error {
+	skip.CreatedAt = time.Now()
+
+	id, err := d.conn.insert(`
+		INSERT INTO review_skips (plan_id, iteration, action, reason, session_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		skip.PlanID, skip.Iteration, skip.Action, skip.Reason, skip.SessionID, skip.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	skip.ID = id
+	return nil
+}
+
+// GetReviewSkipsByPlan returns the reviews skipped or downgraded in a plan,
+// in the order they were recorded.
+func (d *DB) GetReviewSkipsByPlan(planID string) ([]*ReviewSkip, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, plan_id, iteration, action, reason, session_id, created_at
+		FROM review_skips WHERE plan_id = ? ORDER BY id`, planID)
 	if err != nil {
 		return nil, err
 	}
 	defer func() {
 		if closeErr := rows.Close(); closeErr != nil {
-			log.Warn("failed to close rows", "operation", "GetEventsBySession", "error", closeErr)
+			log.Warn("failed to close rows", "operation", "GetReviewSkipsByPlan", "error", closeErr)
 		}
 	}()
 
-	var events []*Event
+	var skips []*ReviewSkip
 	for rows.Next() {
-		e := &Event{}
+		s := &ReviewSkip{}
 		if err := rows.Scan(
-			&e.ID, &e.SessionID, &e.Sequence, &e.EventType,
-			&e.RawJSON, &e.CreatedAt,
+			&s.ID, &s.PlanID, &s.Iteration, &s.Action, &s.Reason, &s.SessionID, &s.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
-		events = append(events, e)
+		skips = append(skips, s)
 	}
-	return events, rows.Err()
+	return skips, rows.Err()
 }
 
 // =============================================================================
-// Progress Methods
+// Rebuttal Methods
 // =============================================================================
 
-// CreateProgress inserts a new progress record into the database.
-func (d *DB) CreateProgress(progress *Progress) error {
-	progress.CreatedAt = time.Now()
+// CreateRebuttal records a rebuttal and the reviewer's verdict on it.
+func (d *DB) CreateRebuttal(rebuttal *Rebuttal) error {
+	rebuttal.CreatedAt = time.Now()
 
-	result, err := d.conn.Exec(`
-		INSERT INTO progress (plan_id, session_id, content, created_at)
-		VALUES (?, ?, ?, ?)`,
-		progress.PlanID, progress.SessionID, progress.Content, progress.CreatedAt,
+	id, err := d.conn.insert(`
+		INSERT INTO rebuttals (plan_id, iteration, feedback_session_id, session_id, feedback, rebuttal, accepted, response, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rebuttal.PlanID, rebuttal.Iteration, rebuttal.FeedbackSessionID, rebuttal.SessionID,
+		d.redactor.String(rebuttal.Feedback), d.redactor.String(rebuttal.Rebuttal), rebuttal.Accepted,
+		d.redactor.String(rebuttal.Response), rebuttal.CreatedAt,
 	)
 	if err != nil {
 		return err
 	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return err
-	}
-	progress.ID = id
+	rebuttal.ID = id
 	return nil
 }
 
-// GetLatestProgress returns the most recent progress for a plan.
-func (d *DB) GetLatestProgress(planID string) (*Progress, error) {
-	progress := &Progress{}
+// GetRebuttalBySession returns the rebuttal re-evaluated by a rebuttal
+// reviewer session, or nil if the session is not one.
+func (d *DB) GetRebuttalBySession(sessionID string) (*Rebuttal, error) {
+	r := &Rebuttal{}
 	err := d.conn.QueryRow(`
-		SELECT id, plan_id, session_id, content, created_at
-		FROM progress WHERE plan_id = ? ORDER BY created_at DESC LIMIT 1`, planID,
+		SELECT id, plan_id, iteration, feedback_session_id, session_id, feedback, rebuttal, accepted, response, created_at
+		FROM rebuttals WHERE session_id = ?`, sessionID,
 	).Scan(
-		&progress.ID, &progress.PlanID, &progress.SessionID,
-		&progress.Content, &progress.CreatedAt,
+		&r.ID, &r.PlanID, &r.Iteration, &r.FeedbackSessionID, &r.SessionID,
+		&r.Feedback, &r.Rebuttal, &r.Accepted, &r.Response, &r.CreatedAt,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil // Return nil, not error, when no records exist
+		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	return progress, nil
+	return r, nil
 }
 
-// GetProgressHistory returns all progress records for a plan ordered by created_at.
-func (d *DB) GetProgressHistory(planID string) ([]*Progress, error) {
+// GetRebuttalsByPlan returns the rebuttals of a plan, in the order they
+// were recorded.
+func (d *DB) GetRebuttalsByPlan(planID string) ([]*Rebuttal, error) {
 	rows, err := d.conn.Query(`
-		SELECT id, plan_id, session_id, content, created_at
-		FROM progress WHERE plan_id = ? ORDER BY created_at`, planID)
+		SELECT id, plan_id, iteration, feedback_session_id, session_id, feedback, rebuttal, accepted, response, created_at
+		FROM rebuttals WHERE plan_id = ? ORDER BY id`, planID)
 	if err != nil {
 		return nil, err
 	}
 	defer func() {
 		if closeErr := rows.Close(); closeErr != nil {
-			log.Warn("failed to close rows", "operation", "GetProgressHistory", "error", closeErr)
+			log.Warn("failed to close rows", "operation", "GetRebuttalsByPlan", "error", closeErr)
 		}
 	}()
 
-	var progressList []*Progress
+	var rebuttals []*Rebuttal
 	for rows.Next() {
-		p := &Progress{}
+		r := &Rebuttal{}
 		if err := rows.Scan(
-			&p.ID, &p.PlanID, &p.SessionID, &p.Content, &p.CreatedAt,
+			&r.ID, &r.PlanID, &r.Iteration, &r.FeedbackSessionID, &r.SessionID,
+			&r.Feedback, &r.Rebuttal, &r.Accepted, &r.Response, &r.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
-		progressList = append(progressList, p)
+		rebuttals = append(rebuttals, r)
 	}
-	return progressList, rows.Err()
+	return rebuttals, rows.Err()
 }
 
 // =============================================================================
-// Learnings Methods
+// Rejected Approach Methods
 // =============================================================================
 
-// CreateLearnings inserts a new learnings record into the database.
-func (d *DB) CreateLearnings(learnings *Learnings) error {
-	learnings.CreatedAt = time.Now()
+// CreateRejectedApproach records an approach that was rejected.
+func (d *DB) CreateRejectedApproach(approach *RejectedApproach) error {
+	approach.CreatedAt = time.Now()
 
-	result, err := d.conn.Exec(`
-		INSERT INTO learnings (plan_id, session_id, content, created_at)
-		VALUES (?, ?, ?, ?)`,
-		learnings.PlanID, learnings.SessionID, learnings.Content, learnings.CreatedAt,
+	id, err := d.conn.insert(`
+		INSERT INTO rejected_approaches (plan_id, session_id, source, content, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		approach.PlanID, approach.SessionID, string(approach.Source), d.redactor.String(approach.Content), approach.CreatedAt,
 	)
 	if err != nil {
 		return err
 	}
+	approach.ID = id
+	return nil
+}
 
-	id, err := result.LastInsertId()
+// GetRejectedApproaches returns the approaches rejected in a plan, in the
+// order they were recorded. Those recorded by superseded sessions, or from
+// review feedback the reviewer withdrew after a rebuttal, are skipped.
+func (d *DB) GetRejectedApproaches(planID string) ([]*RejectedApproach, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, plan_id, session_id, source, content, created_at
+		FROM rejected_approaches
+		WHERE plan_id = ?
+		  AND session_id IN (SELECT id FROM plan_sessions WHERE plan_id = ? AND NOT superseded)
+		  AND session_id NOT IN (SELECT feedback_session_id FROM rebuttals WHERE plan_id = ? AND accepted)
+		ORDER BY id`, planID, planID, planID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	learnings.ID = id
-	return nil
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "operation", "GetRejectedApproaches", "error", closeErr)
+		}
+	}()
+
+	var approaches []*RejectedApproach
+	for rows.Next() {
+		a := &RejectedApproach{}
+		var source string
+		if err := rows.Scan(&a.ID, &a.PlanID, &a.SessionID, &source, &a.Content, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		a.Source = RejectedApproachSource(source)
+		approaches = append(approaches, a)
+	}
+	return approaches, rows.Err()
 }
 
-// GetLatestLearnings returns the most recent learnings for a plan.
-func (d *DB) GetLatestLearnings(planID string) (*Learnings, error) {
-	learnings := &Learnings{}
-	err := d.conn.QueryRow(`
-		SELECT id, plan_id, session_id, content, created_at
-		FROM learnings WHERE plan_id = ? ORDER BY created_at DESC LIMIT 1`, planID,
-	).Scan(
-		&learnings.ID, &learnings.PlanID, &learnings.SessionID,
-		&learnings.Content, &learnings.CreatedAt,
+// =============================================================================
+// Iteration Operation Methods
+// =============================================================================
+
+// RecordIterationOperation records the jj operation the repository was at
+// when an iteration of a plan started, replacing the one recorded for an
+// earlier run of the same iteration (e.g. before a rewind).
+func (d *DB) RecordIterationOperation(planID string, iteration int, operationID string) error {
+	_, err := d.conn.Exec(`
+		INSERT INTO iteration_operations (plan_id, iteration, operation_id, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (plan_id, iteration) DO UPDATE SET operation_id = excluded.operation_id, created_at = excluded.created_at`,
+		planID, iteration, operationID, time.Now(),
 	)
+	return err
+}
+
+// GetIterationOperation returns the jj operation recorded when an iteration
+// of a plan started, or ErrNotFound if none was.
+func (d *DB) GetIterationOperation(planID string, iteration int) (string, error) {
+	var operationID string
+	err := d.conn.QueryRow(`
+		SELECT operation_id FROM iteration_operations WHERE plan_id = ? AND iteration = ?`, planID, iteration,
+	).Scan(&operationID)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil // Return nil, not error, when no records exist
+		return "", ErrNotFound
 	}
-	if err != nil {
-		return nil, err
-	}
-	return learnings, nil
+	return operationID, err
 }
 
-// GetLearningsHistory returns all learnings records for a plan ordered by created_at.
-func (d *DB) GetLearningsHistory(planID string) ([]*Learnings, error) {
+// =============================================================================
+// Session Environment Methods
+// =============================================================================
+
+// CreateSessionEnvironment records the environment a plan session runs in.
+func (d *DB) CreateSessionEnvironment(env *SessionEnvironment) error {
+	env.CreatedAt = time.Now()
+
+	_, err := d.conn.Exec(`
+		INSERT INTO session_environments (session_id, plan_id, claude_version, jj_revision, go_version, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		env.SessionID, env.PlanID, env.ClaudeVersion, env.JJRevision, env.GoVersion, env.CreatedAt,
+	)
+	return err
+}
+
+// GetSessionEnvironmentsByPlan returns the environments of a plan's
+// sessions, in the order the sessions started. The model is the one
+// recorded with the session (see UpdatePlanSessionModel).
+func (d *DB) GetSessionEnvironmentsByPlan(planID string) ([]*SessionEnvironment, error) {
 	rows, err := d.conn.Query(`
-		SELECT id, plan_id, session_id, content, created_at
-		FROM learnings WHERE plan_id = ? ORDER BY created_at`, planID)
+		SELECT e.session_id, e.plan_id, e.claude_version, COALESCE(s.model, ''), e.jj_revision, e.go_version, e.created_at
+		FROM session_environments e LEFT JOIN plan_sessions s ON s.id = e.session_id
+		WHERE e.plan_id = ? ORDER BY e.created_at, e.session_id`, planID)
 	if err != nil {
 		return nil, err
 	}
 	defer func() {
 		if closeErr := rows.Close(); closeErr != nil {
-			log.Warn("failed to close rows", "operation", "GetLearningsHistory", "error", closeErr)
+			log.Warn("failed to close rows", "operation", "GetSessionEnvironmentsByPlan", "error", closeErr)
 		}
 	}()
 
-	var learningsList []*Learnings
+	var envs []*SessionEnvironment
 	for rows.Next() {
-		l := &Learnings{}
+		e := &SessionEnvironment{}
 		if err := rows.Scan(
-			&l.ID, &l.PlanID, &l.SessionID, &l.Content, &l.CreatedAt,
+			&e.SessionID, &e.PlanID, &e.ClaudeVersion, &e.Model, &e.JJRevision, &e.GoVersion, &e.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
-		learningsList = append(learningsList, l)
+		envs = append(envs, e)
 	}
-	return learningsList, rows.Err()
+	return envs, rows.Err()
 }
 
 // =============================================================================
-// Reviewer Feedback Methods
+// Rate Limit Methods
 // =============================================================================
 
-// CreateReviewerFeedback inserts a new reviewer feedback record into the database.
-func (d *DB) CreateReviewerFeedback(feedback *ReviewerFeedback) error {
-	feedback.CreatedAt = time.Now()
+// SetRateLimitCooldown records a cooldown for the provider. A cooldown
+// already in place that lasts longer is kept.
+func (d *DB) SetRateLimitCooldown(cooldown *RateLimitCooldown) error {
+	cooldown.ResumeAt = cooldown.ResumeAt.UTC()
+	cooldown.UpdatedAt = time.Now().UTC()
 
-	result, err := d.conn.Exec(`
-		INSERT INTO reviewer_feedback (plan_id, session_id, content, created_at)
-		VALUES (?, ?, ?, ?)`,
-		feedback.PlanID, feedback.SessionID, feedback.Content, feedback.CreatedAt,
-	)
+	tx, err := d.conn.Begin()
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && rbErr != sql.ErrTxDone {
+			log.Warn("failed to rollback transaction", "operation", "SetRateLimitCooldown", "error", rbErr)
+		}
+	}()
+
+	var existing time.Time
+	err = tx.QueryRow(`SELECT resume_at FROM rate_limit_cooldowns WHERE provider = ?`, cooldown.Provider).Scan(&existing)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return err
+	case existing.After(cooldown.ResumeAt):
+		return nil
+	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
+	if _, err := tx.Exec(`DELETE FROM rate_limit_cooldowns WHERE provider = ?`, cooldown.Provider); err != nil {
 		return err
 	}
-	feedback.ID = id
-	return nil
+	if _, err := tx.Exec(`
+		INSERT INTO rate_limit_cooldowns (provider, resume_at, reason, updated_at)
+		VALUES (?, ?, ?, ?)`,
+		cooldown.Provider, cooldown.ResumeAt, cooldown.Reason, cooldown.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// GetLatestReviewerFeedback returns the most recent reviewer feedback for a plan.
-func (d *DB) GetLatestReviewerFeedback(planID string) (*ReviewerFeedback, error) {
-	feedback := &ReviewerFeedback{}
+// GetRateLimitCooldown returns the provider's latest cooldown, which may
+// already have ended, or nil if it never had one.
+func (d *DB) GetRateLimitCooldown(provider string) (*RateLimitCooldown, error) {
+	c := &RateLimitCooldown{}
 	err := d.conn.QueryRow(`
-		SELECT id, plan_id, session_id, content, created_at
-		FROM reviewer_feedback WHERE plan_id = ? ORDER BY created_at DESC LIMIT 1`, planID,
-	).Scan(
-		&feedback.ID, &feedback.PlanID, &feedback.SessionID,
-		&feedback.Content, &feedback.CreatedAt,
+		SELECT provider, resume_at, reason, updated_at
+		FROM rate_limit_cooldowns WHERE provider = ?`, provider,
+	).Scan(&c.Provider, &c.ResumeAt, &c.Reason, &c.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// =============================================================================
+// Throttle Methods
+// =============================================================================
+
+// TakeThrottleTokens takes amount tokens from the named token bucket, which
+// holds up to capacity tokens and refills at capacity per hour. A bucket
+// starts full. When the bucket holds fewer than amount tokens, none are
+// taken and the time until it will hold enough is returned instead.
+func (d *DB) TakeThrottleTokens(name string, capacity, amount float64) (time.Duration, error) {
+	return d.drawThrottle(name, capacity, amount, false)
+}
+
+// SpendThrottleTokens takes amount tokens from the named token bucket (see
+// TakeThrottleTokens) however many it holds, leaving it in debt that later
+// takes must wait out.
+func (d *DB) SpendThrottleTokens(name string, capacity, amount float64) error {
+	_, err := d.drawThrottle(name, capacity, amount, true)
+	return err
+}
+
+// drawThrottle refills a token bucket for the time since it was last
+// drawn from, then takes amount tokens if it holds enough or force is set.
+// The bucket's row is locked for the draw, so concurrent runs drawing from
+// the same bucket take turns instead of overwriting each other's draws.
+func (d *DB) drawThrottle(name string, capacity, amount float64, force bool) (time.Duration, error) {
+	if capacity <= 0 {
+		return 0, fmt.Errorf("throttle %s: capacity must be positive", name)
+	}
+	now := time.Now().UTC()
+	perSecond := capacity / time.Hour.Seconds()
+
+	// A bucket starts full
+	if _, err := d.conn.Exec(`
+		INSERT INTO throttle_buckets (name, tokens, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO NOTHING`, name, capacity, now,
+	); err != nil {
+		return 0, err
+	}
+
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && rbErr != sql.ErrTxDone {
+			log.Warn("failed to rollback transaction", "operation", "drawThrottle", "error", rbErr)
+		}
+	}()
+
+	// Writing first takes the row's lock (SQLite's write lock) before the
+	// bucket is read, waiting out another run's draw
+	if _, err := tx.Exec(`UPDATE throttle_buckets SET name = name WHERE name = ?`, name); err != nil {
+		return 0, err
+	}
+	var tokens float64
+	var updatedAt time.Time
+	if err := tx.QueryRow(`SELECT tokens, updated_at FROM throttle_buckets WHERE name = ?`, name).Scan(&tokens, &updatedAt); err != nil {
+		return 0, err
+	}
+	if elapsed := now.Sub(updatedAt); elapsed > 0 {
+		tokens += elapsed.Seconds() * perSecond
+	}
+	tokens = min(tokens, capacity)
+
+	if tokens < amount && !force {
+		return time.Duration((amount - tokens) / perSecond * float64(time.Second)), nil
+	}
+	tokens -= amount
+
+	if _, err := tx.Exec(`UPDATE throttle_buckets SET tokens = ?, updated_at = ? WHERE name = ?`, tokens, now, name); err != nil {
+		return 0, err
+	}
+	return 0, tx.Commit()
+}
+
+// =============================================================================
+// Plan Workspace Methods
+// =============================================================================
+
+// CreatePlanWorkspace records the jj workspace a plan runs in.
+func (d *DB) CreatePlanWorkspace(ws *PlanWorkspace) error {
+	ws.CreatedAt = time.Now()
+	_, err := d.conn.Exec(`
+		INSERT INTO plan_workspaces (plan_id, name, path, created_at)
+		VALUES (?, ?, ?, ?)`,
+		ws.PlanID, ws.Name, ws.Path, ws.CreatedAt,
 	)
+	return err
+}
+
+// GetPlanWorkspace returns the jj workspace a plan runs in, or nil if it
+// has none.
+func (d *DB) GetPlanWorkspace(planID string) (*PlanWorkspace, error) {
+	ws := &PlanWorkspace{}
+	err := d.conn.QueryRow(`
+		SELECT plan_id, name, path, created_at
+		FROM plan_workspaces WHERE plan_id = ?`, planID,
+	).Scan(&ws.PlanID, &ws.Name, &ws.Path, &ws.CreatedAt)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil // Return nil, not error, when no records exist
+		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	return feedback, nil
+	return ws, nil
 }
 
-// ClearReviewerFeedback removes all reviewer feedback for a plan (used after developer addresses it).
-func (d *DB) ClearReviewerFeedback(planID string) error {
-	_, err := d.conn.Exec(`DELETE FROM reviewer_feedback WHERE plan_id = ?`, planID)
+// DeletePlanWorkspace removes the record of a plan's jj workspace.
+func (d *DB) DeletePlanWorkspace(planID string) error {
+	_, err := d.conn.Exec(`DELETE FROM plan_workspaces WHERE plan_id = ?`, planID)
+	return err
+}
+
+// =============================================================================
+// Plan Issue Methods
+// =============================================================================
+
+// CreatePlanIssue records the issue-tracker issue that follows a plan.
+func (d *DB) CreatePlanIssue(issue *PlanIssue) error {
+	issue.CreatedAt = time.Now()
+	_, err := d.conn.Exec(`
+		INSERT INTO plan_issues (plan_id, provider, issue_key, url, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		issue.PlanID, issue.Provider, issue.Key, issue.URL, issue.CreatedAt,
+	)
+	return err
+}
+
+// GetPlanIssue returns the issue that follows a plan, or nil if it has none.
+func (d *DB) GetPlanIssue(planID string) (*PlanIssue, error) {
+	issue := &PlanIssue{}
+	err := d.conn.QueryRow(`
+		SELECT plan_id, provider, issue_key, url, created_at
+		FROM plan_issues WHERE plan_id = ?`, planID,
+	).Scan(&issue.PlanID, &issue.Provider, &issue.Key, &issue.URL, &issue.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return issue, nil
+}
+
+// =============================================================================
+// Plan Run Methods
+// =============================================================================
+
+// StartPlanRun records the process running a plan, replacing any earlier
+// run's record.
+func (d *DB) StartPlanRun(run *PlanRun) error {
+	run.StartedAt = time.Now()
+	_, err := d.conn.Exec(`
+		INSERT INTO plan_runs (plan_id, host, pid, started_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (plan_id) DO UPDATE SET host = excluded.host, pid = excluded.pid, started_at = excluded.started_at`,
+		run.PlanID, run.Host, run.PID, run.StartedAt,
+	)
+	return err
+}
+
+// GetPlanRun returns the process last recorded running a plan, or nil if
+// none is.
+func (d *DB) GetPlanRun(planID string) (*PlanRun, error) {
+	run := &PlanRun{}
+	err := d.conn.QueryRow(`
+		SELECT plan_id, host, pid, started_at
+		FROM plan_runs WHERE plan_id = ?`, planID,
+	).Scan(&run.PlanID, &run.Host, &run.PID, &run.StartedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// EndPlanRun removes the record of the process running a plan.
+func (d *DB) EndPlanRun(planID string) error {
+	_, err := d.conn.Exec(`DELETE FROM plan_runs WHERE plan_id = ?`, planID)
 	return err
 }
+
+// =============================================================================
+// Global Learnings Methods
+// =============================================================================
+
+// CreateGlobalLearning stores a repo-wide learning unless the same content
+// already exists for the repo. Returns whether a new row was inserted.
+func (d *DB) CreateGlobalLearning(learning *GlobalLearning) (bool, error) {
+	learning.CreatedAt = time.Now()
+
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && rbErr != sql.ErrTxDone {
+			log.Warn("failed to rollback transaction", "operation", "CreateGlobalLearning", "error", rbErr)
+		}
+	}()
+
+	var existing int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM global_learnings WHERE repo_root = ? AND content = ?`,
+		learning.RepoRoot, learning.Content,
+	).Scan(&existing); err != nil {
+		return false, err
+	}
+	if existing > 0 {
+		return false, nil
+	}
+
+	id, err := tx.insert(`
+		INSERT INTO global_learnings (repo_root, plan_id, content, created_at)
+		VALUES (?, ?, ?, ?)`,
+		learning.RepoRoot, learning.PlanID, learning.Content, learning.CreatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	learning.ID = id
+	return true, nil
+}
+
+// GetGlobalLearnings retrieves all learnings for a repo, newest first.
+func (d *DB) GetGlobalLearnings(repoRoot string) ([]*GlobalLearning, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, repo_root, plan_id, content, created_at
+		FROM global_learnings WHERE repo_root = ? ORDER BY id DESC`, repoRoot,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "error", closeErr)
+		}
+	}()
+
+	var learnings []*GlobalLearning
+	for rows.Next() {
+		learning := &GlobalLearning{}
+		if err := rows.Scan(&learning.ID, &learning.RepoRoot, &learning.PlanID,
+			&learning.Content, &learning.CreatedAt); err != nil {
+			return nil, err
+		}
+		learnings = append(learnings, learning)
+	}
+	return learnings, rows.Err()
+}
@@ -1,7 +1,12 @@
 package db
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 )
@@ -725,15 +730,15 @@ func TestGetMessagesBySession(t *testing.T) {
 		t.Fatalf("CreateSession() returned error: %v", err)
 	}
 
-	// Create messages out of order
-	if err := db.CreateMessage(&Message{SessionID: "sess-1", Sequence: 3, MessageType: "text", Content: "C3"}); err != nil {
-		t.Fatalf("CreateMessage() returned error: %v", err)
-	}
-	if err := db.CreateMessage(&Message{SessionID: "sess-1", Sequence: 1, MessageType: "text", Content: "C1"}); err != nil {
-		t.Fatalf("CreateMessage() returned error: %v", err)
-	}
-	if err := db.CreateMessage(&Message{SessionID: "sess-1", Sequence: 2, MessageType: "text", Content: "C2"}); err != nil {
-		t.Fatalf("CreateMessage() returned error: %v", err)
+	// Sequences given by the caller are replaced by the session's next ones
+	for i, content := range []string{"C1", "C2", "C3"} {
+		message := &Message{SessionID: "sess-1", Sequence: 3 - i, MessageType: "text", Content: content}
+		if err := db.CreateMessage(message); err != nil {
+			t.Fatalf("CreateMessage() returned error: %v", err)
+		}
+		if message.Sequence != i {
+			t.Errorf("CreateMessage() assigned sequence %d, want %d", message.Sequence, i)
+		}
 	}
 
 	messages, err := db.GetMessagesBySession("sess-1")
@@ -742,11 +747,11 @@ func TestGetMessagesBySession(t *testing.T) {
 	}
 
 	if len(messages) != 3 {
-		t.Errorf("GetMessagesBySession() returned %d messages, want 3", len(messages))
+		t.Fatalf("GetMessagesBySession() returned %d messages, want 3", len(messages))
 	}
 
 	// Should be ordered by sequence
-	if messages[0].Sequence != 1 || messages[1].Sequence != 2 || messages[2].Sequence != 3 {
+	if messages[0].Content != "C1" || messages[1].Content != "C2" || messages[2].Content != "C3" {
 		t.Error("GetMessagesBySession() messages not ordered by sequence")
 	}
 }
@@ -1279,6 +1284,30 @@ func TestCreatePlan_WithBaseChangeID(t *testing.T) {
 	}
 }
 
+func TestCreatePlan_WithWorkDir(t *testing.T) {
+	db := newTestDB(t)
+
+	plan := &Plan{
+		ID:         "plan-1",
+		OriginPath: "/repo/plan.md",
+		Content:    "Build the feature",
+		WorkDir:    "/repo",
+	}
+
+	if err := db.CreatePlan(plan); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+
+	got, err := db.GetPlan("plan-1")
+	if err != nil {
+		t.Fatalf("GetPlan() returned error: %v", err)
+	}
+
+	if got.WorkDir != "/repo" {
+		t.Errorf("CreatePlan().WorkDir = %v, want /repo", got.WorkDir)
+	}
+}
+
 func TestGetPlan(t *testing.T) {
 	db := newTestDB(t)
 
@@ -1332,8 +1361,10 @@ func TestUpdatePlanStatus(t *testing.T) {
 		t.Fatalf("CreatePlan() returned error: %v", err)
 	}
 
-	if err := db.UpdatePlanStatus("plan-1", PlanStatusCompleted); err != nil {
-		t.Fatalf("UpdatePlanStatus() returned error: %v", err)
+	for _, status := range []PlanStatus{PlanStatusRunning, PlanStatusCompleted} {
+		if err := db.UpdatePlanStatus("plan-1", status); err != nil {
+			t.Fatalf("UpdatePlanStatus(%s) returned error: %v", status, err)
+		}
 	}
 
 	got, err := db.GetPlan("plan-1")
@@ -1345,6 +1376,234 @@ func TestUpdatePlanStatus(t *testing.T) {
 	}
 }
 
+func TestUpdatePlanStatusWithReason(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.CreatePlan(&Plan{ID: "plan-1", Content: "c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.UpdatePlanStatus("plan-1", PlanStatusRunning); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdatePlanStatusWithReason("plan-1", PlanStatusFailed, "claude exited"); err != nil {
+		t.Fatalf("UpdatePlanStatusWithReason() error: %v", err)
+	}
+	got, err := db.GetPlan("plan-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != PlanStatusFailed || got.FailureReason != "claude exited" {
+		t.Errorf("plan = %s (%q), want failed (claude exited)", got.Status, got.FailureReason)
+	}
+
+	// Resuming clears the reason
+	if err := db.UpdatePlanStatus("plan-1", PlanStatusRunning); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := db.GetPlan("plan-1"); got.FailureReason != "" {
+		t.Errorf("FailureReason = %q after resuming, want empty", got.FailureReason)
+	}
+}
+
+func TestUpdatePlanStatus_InvalidTransition(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.CreatePlan(&Plan{ID: "plan-1", Content: "c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, status := range []PlanStatus{PlanStatusCompleted, PlanStatusPaused, PlanStatusAbandoned} {
+		if err := db.UpdatePlanStatus("plan-1", status); !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("pending -> %s error = %v, want ErrInvalidTransition", status, err)
+		}
+	}
+	if got, _ := db.GetPlan("plan-1"); got.Status != PlanStatusPending {
+		t.Errorf("status = %s after rejected transitions, want pending", got.Status)
+	}
+}
+
+func TestPlanStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to PlanStatus
+		want     bool
+	}{
+		{PlanStatusPending, PlanStatusRunning, true},
+		{PlanStatusRunning, PlanStatusRunning, true},
+		{PlanStatusRunning, PlanStatusPaused, true},
+		{PlanStatusRunning, PlanStatusCancelled, false},
+		{PlanStatusPaused, PlanStatusRunning, true},
+		{PlanStatusPaused, PlanStatusCompleted, false},
+		{PlanStatusFailed, PlanStatusCancelled, true},
+		{PlanStatusCompleted, PlanStatusCancelled, false},
+		{PlanStatusAbandoned, PlanStatusRunning, true},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%s.CanTransitionTo(%s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestListPlans(t *testing.T) {
+	db := newTestDB(t)
+	for _, id := range []string{"old", "new"} {
+		if err := db.CreatePlan(&Plan{ID: id, Content: "secret content"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.conn.Exec(`UPDATE plans SET updated_at = ? WHERE id = 'old'`, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	plans, err := db.ListPlans(10)
+	if err != nil {
+		t.Fatalf("ListPlans() error: %v", err)
+	}
+	if len(plans) != 2 || plans[0].ID != "new" || plans[1].ID != "old" {
+		t.Fatalf("ListPlans() = %v, want new then old", plans)
+	}
+	if plans[0].Content != "" {
+		t.Errorf("ListPlans() loaded content %q, want it left out", plans[0].Content)
+	}
+
+	if plans, err := db.ListPlans(1); err != nil || len(plans) != 1 {
+		t.Errorf("ListPlans(1) = %v, %v; want one plan", plans, err)
+	}
+}
+
+func TestGetSubPlans(t *testing.T) {
+	db := newTestDB(t)
+	plans := []*Plan{
+		{ID: "parent", Content: "Parent"},
+		{ID: "child-1", Content: "First", ParentPlanID: "parent"},
+		{ID: "other", Content: "Other"},
+		{ID: "child-2", Content: "Second", ParentPlanID: "parent"},
+	}
+	for _, plan := range plans {
+		if err := db.CreatePlan(plan); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	subPlans, err := db.GetSubPlans("parent")
+	if err != nil {
+		t.Fatalf("GetSubPlans() error: %v", err)
+	}
+	if len(subPlans) != 2 || subPlans[0].ID != "child-1" || subPlans[1].ID != "child-2" {
+		t.Fatalf("GetSubPlans() = %v, want child-1 then child-2", subPlans)
+	}
+	if got, err := db.GetPlan("child-1"); err != nil || got.ParentPlanID != "parent" {
+		t.Errorf("GetPlan() = %+v, %v; want parent plan recorded", got, err)
+	}
+	if subPlans, err := db.GetSubPlans("other"); err != nil || len(subPlans) != 0 {
+		t.Errorf("GetSubPlans(other) = %v, %v; want none", subPlans, err)
+	}
+}
+
+func TestGetPlansByStatus(t *testing.T) {
+	db := newTestDB(t)
+	for _, id := range []string{"a", "b", "c"} {
+		if err := db.CreatePlan(&Plan{ID: id, Content: "Plan"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"a", "c"} {
+		if err := db.UpdatePlanStatus(id, PlanStatusRunning); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	running, err := db.GetPlansByStatus(PlanStatusRunning)
+	if err != nil {
+		t.Fatalf("GetPlansByStatus() error: %v", err)
+	}
+	if len(running) != 2 || running[0].ID != "c" || running[1].ID != "a" {
+		t.Errorf("GetPlansByStatus(running) = %v, want c then a", running)
+	}
+}
+
+func TestFindUnfinishedPlans(t *testing.T) {
+	db := newTestDB(t)
+	plans := []*Plan{
+		{ID: "old", OriginPath: "/repo/plan.md", OriginHash: "hash-1", WorkDir: "/repo", Content: "Plan"},
+		{ID: "new", OriginPath: "/repo/plan.md", OriginHash: "hash-1", WorkDir: "/repo", Content: "Plan"},
+		{ID: "done", OriginPath: "/repo/plan.md", OriginHash: "hash-1", WorkDir: "/repo", Content: "Plan"},
+		{ID: "edited", OriginPath: "/repo/plan.md", OriginHash: "hash-2", WorkDir: "/repo", Content: "Edited plan"},
+		{ID: "elsewhere", OriginPath: "/repo/plan.md", OriginHash: "hash-1", WorkDir: "/other", Content: "Plan"},
+		{ID: "fork", OriginPath: "/repo/plan.md", OriginHash: "hash-1", WorkDir: "/repo", Content: "Plan", ForkedFrom: "old"},
+		{ID: "busy", OriginPath: "/repo/plan.md", OriginHash: "hash-1", WorkDir: "/repo", Content: "Plan"},
+	}
+	for _, plan := range plans {
+		if err := db.CreatePlan(plan); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.conn.Exec(`UPDATE plans SET updated_at = ? WHERE id = 'old'`, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range []PlanStatus{PlanStatusRunning, PlanStatusCompleted} {
+		if err := db.UpdatePlanStatus("done", status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Running in another process, and updated last
+	if err := db.UpdatePlanStatus("busy", PlanStatusRunning); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.FindUnfinishedPlans("/repo/plan.md", "hash-1", "/repo")
+	if err != nil {
+		t.Fatalf("FindUnfinishedPlans() error: %v", err)
+	}
+	if ids := planIDs(got); !slices.Equal(ids, []string{"busy", "new", "old"}) {
+		t.Errorf("FindUnfinishedPlans() = %v, want the unfinished plans that aren't forks, running ones included, most recent first", ids)
+	}
+
+	if err := db.UpdatePlanStatus("new", PlanStatusCancelled); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := db.FindUnfinishedPlans("/repo/plan.md", "hash-1", "/repo"); err != nil || !slices.Equal(planIDs(got), []string{"busy", "old"}) {
+		t.Errorf("FindUnfinishedPlans() = %v, %v; want busy and old once new is cancelled", planIDs(got), err)
+	}
+	if got, err := db.FindUnfinishedPlans("/repo/plan.md", "hash-3", "/repo"); err != nil || len(got) != 0 {
+		t.Errorf("FindUnfinishedPlans() = %v, %v; want none", planIDs(got), err)
+	}
+}
+
+// planIDs returns the IDs of plans, in order.
+func planIDs(plans []*Plan) []string {
+	ids := make([]string, len(plans))
+	for i, plan := range plans {
+		ids[i] = plan.ID
+	}
+	return ids
+}
+
+func TestPlanRun(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.CreatePlan(&Plan{ID: "p1", OriginPath: "/repo/plan.md", Content: "Plan"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if run, err := db.GetPlanRun("p1"); err != nil || run != nil {
+		t.Fatalf("GetPlanRun() = %+v, %v; want none before a run starts", run, err)
+	}
+	for _, pid := range []int{100, 200} {
+		if err := db.StartPlanRun(&PlanRun{PlanID: "p1", Host: "build-1", PID: pid}); err != nil {
+			t.Fatalf("StartPlanRun() error: %v", err)
+		}
+	}
+	run, err := db.GetPlanRun("p1")
+	if err != nil || run == nil || run.Host != "build-1" || run.PID != 200 {
+		t.Fatalf("GetPlanRun() = %+v, %v; want the latest run", run, err)
+	}
+	if err := db.EndPlanRun("p1"); err != nil {
+		t.Fatalf("EndPlanRun() error: %v", err)
+	}
+	if run, err := db.GetPlanRun("p1"); err != nil || run != nil {
+		t.Errorf("GetPlanRun() = %+v, %v; want none once the run ended", run, err)
+	}
+}
+
 func TestUpdatePlanStatus_NotFound(t *testing.T) {
 	db := newTestDB(t)
 
@@ -1398,35 +1657,1387 @@ func TestUpdatePlanBaseChangeID_NotFound(t *testing.T) {
 	}
 }
 
-func TestUpdatePlanBaseChangeID_UpdatesTimestamp(t *testing.T) {
+func TestUpdatePlanContent(t *testing.T) {
 	db := newTestDB(t)
 
-	plan := &Plan{
-		ID:         "plan-1",
-		OriginPath: "/path/to/plan.md",
-		Content:    "Plan content",
-	}
+	plan := &Plan{ID: "plan-1", OriginPath: "/path/to/plan.md", Content: "Plan content"}
 	if err := db.CreatePlan(plan); err != nil {
 		t.Fatalf("CreatePlan() returned error: %v", err)
 	}
 
-	original, err := db.GetPlan("plan-1")
+	if err := db.UpdatePlanOriginHash("plan-1", "hash-1"); err != nil {
+		t.Fatalf("UpdatePlanOriginHash() returned error: %v", err)
+	}
+	got, err := db.GetPlan("plan-1")
 	if err != nil {
 		t.Fatalf("GetPlan() returned error: %v", err)
 	}
-	originalUpdatedAt := original.UpdatedAt
+	if got.OriginHash != "hash-1" || got.Content != "Plan content" {
+		t.Errorf("plan = %+v, want hash-1 with the original content", got)
+	}
 
-	time.Sleep(10 * time.Millisecond)
+	if err := db.UpdatePlanContent("plan-1", "Edited plan", "hash-2"); err != nil {
+		t.Fatalf("UpdatePlanContent() returned error: %v", err)
+	}
+	got, err = db.GetPlan("plan-1")
+	if err != nil {
+		t.Fatalf("GetPlan() returned error: %v", err)
+	}
+	if got.OriginHash != "hash-2" || got.Content != "Edited plan" {
+		t.Errorf("plan = %+v, want hash-2 with the edited content", got)
+	}
 
-	if err := db.UpdatePlanBaseChangeID("plan-1", "abc123"); err != nil {
-		t.Fatalf("UpdatePlanBaseChangeID() returned error: %v", err)
+	if err := db.UpdatePlanContent("nonexistent", "x", "y"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdatePlanContent() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCreatePlanTasks(t *testing.T) {
+	db := newTestDB(t)
+
+	plan := &Plan{ID: "plan-1", OriginPath: "/path/to/plan.md", Content: "Plan content"}
+	if err := db.CreatePlan(plan); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
 	}
 
-	updated, err := db.GetPlan("plan-1")
+	tasks := []*Task{
+		{ID: "t1", Sequence: 1, Title: "First", Description: "one"},
+		{ID: "t2", Sequence: 2, Title: "Second", Description: "two"},
+	}
+	if err := db.CreatePlanTasks(plan, tasks); err != nil {
+		t.Fatalf("CreatePlanTasks() returned error: %v", err)
+	}
+
+	project, err := db.GetProject("plan-1")
 	if err != nil {
-		t.Fatalf("GetPlan() returned error: %v", err)
+		t.Fatalf("GetProject() returned error: %v", err)
 	}
-	if !updated.UpdatedAt.After(originalUpdatedAt) {
-		t.Error("UpdatePlanBaseChangeID() did not update UpdatedAt timestamp")
+	if project.Name != "plan.md" || project.PlanText != "Plan content" {
+		t.Errorf("unexpected project: %+v", project)
+	}
+
+	got, err := db.GetTasksByProject("plan-1")
+	if err != nil {
+		t.Fatalf("GetTasksByProject() returned error: %v", err)
+	}
+	if len(got) != 2 || got[0].Title != "First" || got[1].Status != TaskPending {
+		t.Errorf("unexpected tasks: %+v", got)
+	}
+
+	// Adding more tasks reuses the existing project
+	if err := db.CreatePlanTasks(plan, []*Task{{ID: "t3", Sequence: 3, Title: "Third", Description: "three"}}); err != nil {
+		t.Fatalf("second CreatePlanTasks() returned error: %v", err)
+	}
+}
+
+func TestGetLatestTaskProgressAndLearnings(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "s1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p"}); err != nil {
+		t.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+	for _, taskID := range []string{"t1", "t2"} {
+		if err := db.CreateProgress(&Progress{PlanID: "plan-1", SessionID: "s1", TaskID: taskID, Content: "progress " + taskID}); err != nil {
+			t.Fatalf("CreateProgress() returned error: %v", err)
+		}
+		if err := db.CreateLearnings(&Learnings{PlanID: "plan-1", SessionID: "s1", TaskID: taskID, Content: "learnings " + taskID}); err != nil {
+			t.Fatalf("CreateLearnings() returned error: %v", err)
+		}
+	}
+
+	progress, err := db.GetLatestTaskProgress("plan-1", "t1")
+	if err != nil {
+		t.Fatalf("GetLatestTaskProgress() returned error: %v", err)
+	}
+	if progress == nil || progress.Content != "progress t1" || progress.TaskID != "t1" {
+		t.Errorf("GetLatestTaskProgress() = %+v, want progress t1", progress)
+	}
+
+	learnings, err := db.GetLatestTaskLearnings("plan-1", "t2")
+	if err != nil {
+		t.Fatalf("GetLatestTaskLearnings() returned error: %v", err)
+	}
+	if learnings == nil || learnings.Content != "learnings t2" {
+		t.Errorf("GetLatestTaskLearnings() = %+v, want learnings t2", learnings)
+	}
+
+	progress, err = db.GetLatestTaskProgress("plan-1", "t3")
+	if err != nil || progress != nil {
+		t.Errorf("GetLatestTaskProgress() for task without entries = %+v, %v; want nil, nil", progress, err)
+	}
+}
+
+func TestRewindPlan(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	// Three iterations, each with a developer and a reviewer session; every
+	// developer addresses the previous reviewer's feedback
+	for i := 1; i <= 3; i++ {
+		dev := fmt.Sprintf("dev-%d", i)
+		rev := fmt.Sprintf("rev-%d", i)
+		if err := db.CreatePlanSession(&PlanSession{ID: dev, PlanID: "plan-1", Iteration: i, InputPrompt: "p"}); err != nil {
+			t.Fatalf("CreatePlanSession() returned error: %v", err)
+		}
+		if err := db.ClearReviewerFeedback("plan-1"); err != nil {
+			t.Fatalf("ClearReviewerFeedback() returned error: %v", err)
+		}
+		if err := db.CreateProgress(&Progress{PlanID: "plan-1", SessionID: dev, Content: fmt.Sprintf("progress %d", i)}); err != nil {
+			t.Fatalf("CreateProgress() returned error: %v", err)
+		}
+		if err := db.CreateLearnings(&Learnings{PlanID: "plan-1", SessionID: dev, Content: fmt.Sprintf("learnings %d", i)}); err != nil {
+			t.Fatalf("CreateLearnings() returned error: %v", err)
+		}
+		if err := db.CreatePlanSession(&PlanSession{ID: rev, PlanID: "plan-1", Iteration: i, InputPrompt: "p", AgentType: LoopAgentReviewer}); err != nil {
+			t.Fatalf("CreatePlanSession() returned error: %v", err)
+		}
+		if err := db.CreateReviewerFeedback(&ReviewerFeedback{PlanID: "plan-1", SessionID: rev, Content: fmt.Sprintf("feedback %d", i)}); err != nil {
+			t.Fatalf("CreateReviewerFeedback() returned error: %v", err)
+		}
+		time.Sleep(time.Millisecond) // Keep created_at ordering stable
+	}
+
+	if err := db.RewindPlan("plan-1", 4); err == nil {
+		t.Error("RewindPlan() to a future iteration should fail")
+	}
+	if err := db.RewindPlan("plan-1", 1); err != nil {
+		t.Fatalf("RewindPlan() returned error: %v", err)
+	}
+
+	latest, err := db.GetLatestPlanSession("plan-1")
+	if err != nil || latest == nil || latest.Iteration != 1 {
+		t.Fatalf("GetLatestPlanSession() = %+v, %v; want iteration 1", latest, err)
+	}
+	if progress, err := db.GetLatestProgress("plan-1"); err != nil || progress == nil || progress.Content != "progress 1" {
+		t.Errorf("GetLatestProgress() = %+v, %v; want progress 1", progress, err)
+	}
+	if learnings, err := db.GetLatestLearnings("plan-1"); err != nil || learnings == nil || learnings.Content != "learnings 1" {
+		t.Errorf("GetLatestLearnings() = %+v, %v; want learnings 1", learnings, err)
+	}
+	// Iteration 2's developer had cleared it; rewinding restores it
+	if feedback, err := db.GetLatestReviewerFeedback("plan-1"); err != nil || feedback == nil || feedback.Content != "feedback 1" {
+		t.Errorf("GetLatestReviewerFeedback() = %+v, %v; want feedback 1", feedback, err)
+	}
+
+	// Later records are kept, marked superseded
+	sessions, err := db.GetPlanSessionsByPlan("plan-1")
+	if err != nil || len(sessions) != 6 {
+		t.Fatalf("GetPlanSessionsByPlan() = %d sessions, %v; want all 6", len(sessions), err)
+	}
+	for _, session := range sessions {
+		if want := session.Iteration > 1; session.Superseded != want {
+			t.Errorf("session %s Superseded = %v, want %v", session.ID, session.Superseded, want)
+		}
+	}
+	history, err := db.GetProgressHistory("plan-1")
+	if err != nil || len(history) != 3 {
+		t.Errorf("GetProgressHistory() = %d records, %v; want all 3", len(history), err)
+	}
+}
+
+func TestFeedbackItems(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	for i, id := range []string{"rev-1", "rev-2"} {
+		if err := db.CreatePlanSession(&PlanSession{ID: id, PlanID: "plan-1", Iteration: i + 1, InputPrompt: "p", AgentType: LoopAgentReviewer}); err != nil {
+			t.Fatalf("CreatePlanSession() returned error: %v", err)
+		}
+	}
+	for _, content := range []string{"nil check", "missing test", "typo"} {
+		if err := db.CreateFeedbackItem(&FeedbackItem{PlanID: "plan-1", SessionID: "rev-1", Severity: "Major", Content: content, IssuedIteration: 1}); err != nil {
+			t.Fatalf("CreateFeedbackItem() returned error: %v", err)
+		}
+	}
+
+	states := func() []FeedbackItemState {
+		t.Helper()
+		items, err := db.GetFeedbackItemsByPlan("plan-1")
+		if err != nil {
+			t.Fatalf("GetFeedbackItemsByPlan() returned error: %v", err)
+		}
+		var got []FeedbackItemState
+		for i, item := range items {
+			if item.Number != i+1 {
+				t.Errorf("item %q Number = %d, want %d", item.Content, item.Number, i+1)
+			}
+			got = append(got, item.State())
+		}
+		return got
+	}
+
+	if err := db.AcknowledgeFeedbackItems("plan-1", 2); err != nil {
+		t.Fatalf("AcknowledgeFeedbackItems() returned error: %v", err)
+	}
+	if err := db.ClaimFeedbackItems("plan-1", []int{1, 2, 9}, 2); err != nil {
+		t.Fatalf("ClaimFeedbackItems() returned error: %v", err)
+	}
+	want := []FeedbackItemState{FeedbackItemClaimed, FeedbackItemClaimed, FeedbackItemAcknowledged}
+	if got := states(); !slices.Equal(got, want) {
+		t.Errorf("after claiming, states = %v, want %v", got, want)
+	}
+
+	// Only the verified claim closes; the other is reopened
+	if err := db.VerifyFeedbackItems("plan-1", []int{1, 3}, 2); err != nil {
+		t.Fatalf("VerifyFeedbackItems() returned error: %v", err)
+	}
+	want = []FeedbackItemState{FeedbackItemVerified, FeedbackItemAcknowledged, FeedbackItemAcknowledged}
+	if got := states(); !slices.Equal(got, want) {
+		t.Errorf("after verifying, states = %v, want %v", got, want)
+	}
+	if open, err := db.GetOpenFeedbackItems("plan-1"); err != nil || len(open) != 2 || open[0].Number != 2 {
+		t.Errorf("GetOpenFeedbackItems() = %d items, %v; want #2 and #3", len(open), err)
+	}
+
+	if err := db.WithdrawFeedbackItems("plan-1", "rev-1", []int{2, 3}, 2); err != nil {
+		t.Fatalf("WithdrawFeedbackItems() returned error: %v", err)
+	}
+	want = []FeedbackItemState{FeedbackItemVerified, FeedbackItemWithdrawn, FeedbackItemWithdrawn}
+	if got := states(); !slices.Equal(got, want) {
+		t.Errorf("after withdrawing, states = %v, want %v", got, want)
+	}
+
+	// Rewinding to iteration 1 reopens them all as issued
+	if err := db.RewindPlan("plan-1", 1); err != nil {
+		t.Fatalf("RewindPlan() returned error: %v", err)
+	}
+	want = []FeedbackItemState{FeedbackItemIssued, FeedbackItemIssued, FeedbackItemIssued}
+	if got := states(); !slices.Equal(got, want) {
+		t.Errorf("after rewinding, states = %v, want %v", got, want)
+	}
+
+	if err := db.CloseFeedbackItems("plan-1", 2); err != nil {
+		t.Fatalf("CloseFeedbackItems() returned error: %v", err)
+	}
+	if open, err := db.GetOpenFeedbackItems("plan-1"); err != nil || len(open) != 0 {
+		t.Errorf("GetOpenFeedbackItems() after closing = %d items, %v; want none", len(open), err)
+	}
+}
+
+func TestWithdrawFeedbackItems(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "rev-1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p", AgentType: LoopAgentReviewer}); err != nil {
+		t.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+	for _, content := range []string{"nil check", "missing test", "typo"} {
+		if err := db.CreateFeedbackItem(&FeedbackItem{PlanID: "plan-1", SessionID: "rev-1", Severity: "Major", Content: content, IssuedIteration: 1}); err != nil {
+			t.Fatalf("CreateFeedbackItem() returned error: %v", err)
+		}
+	}
+
+	// Only the disputed item is withdrawn; the rest of the review stands
+	if err := db.WithdrawFeedbackItems("plan-1", "rev-1", []int{2}, 2); err != nil {
+		t.Fatalf("WithdrawFeedbackItems() returned error: %v", err)
+	}
+	open, err := db.GetOpenFeedbackItems("plan-1")
+	if err != nil {
+		t.Fatalf("GetOpenFeedbackItems() returned error: %v", err)
+	}
+	if len(open) != 2 || open[0].Number != 1 || open[1].Number != 3 {
+		t.Errorf("GetOpenFeedbackItems() = %+v, want #1 and #3", open)
+	}
+
+	// A rebuttal that cites no item withdraws none
+	if err := db.WithdrawFeedbackItems("plan-1", "rev-1", nil, 2); err != nil {
+		t.Fatalf("WithdrawFeedbackItems() returned error: %v", err)
+	}
+	if open, err := db.GetOpenFeedbackItems("plan-1"); err != nil || len(open) != 2 {
+		t.Errorf("GetOpenFeedbackItems() = %d items, %v; want 2", len(open), err)
+	}
+}
+
+func TestUndoIteration(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if _, err := db.UndoIteration("plan-1"); err == nil {
+		t.Error("UndoIteration() of a plan without iterations should fail")
+	}
+	for i := 1; i <= 2; i++ {
+		if err := db.CreatePlanSession(&PlanSession{ID: fmt.Sprintf("dev-%d", i), PlanID: "plan-1", Iteration: i, InputPrompt: "p"}); err != nil {
+			t.Fatalf("CreatePlanSession() returned error: %v", err)
+		}
+		if err := db.RecordIterationOperation("plan-1", i, fmt.Sprintf("op-%d", i)); err != nil {
+			t.Fatalf("RecordIterationOperation() returned error: %v", err)
+		}
+	}
+	// A rerun of an iteration replaces its operation
+	if err := db.RecordIterationOperation("plan-1", 2, "op-2b"); err != nil {
+		t.Fatalf("RecordIterationOperation() returned error: %v", err)
+	}
+	if op, err := db.GetIterationOperation("plan-1", 2); err != nil || op != "op-2b" {
+		t.Errorf("GetIterationOperation() = %q, %v; want op-2b", op, err)
+	}
+	if _, err := db.GetIterationOperation("plan-1", 3); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetIterationOperation() of an unrecorded iteration error = %v, want ErrNotFound", err)
+	}
+
+	for want := 2; want >= 1; want-- {
+		undone, err := db.UndoIteration("plan-1")
+		if err != nil || undone != want {
+			t.Fatalf("UndoIteration() = %d, %v; want %d", undone, err, want)
+		}
+		if latest, err := db.LatestIteration("plan-1"); err != nil || latest != want-1 {
+			t.Errorf("LatestIteration() = %d, %v; want %d", latest, err, want-1)
+		}
+	}
+}
+
+func TestGetStateAsOf(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	// Three iterations; the second reviewer approves without feedback
+	for i := 1; i <= 3; i++ {
+		dev := fmt.Sprintf("dev-%d", i)
+		rev := fmt.Sprintf("rev-%d", i)
+		if err := db.CreatePlanSession(&PlanSession{ID: dev, PlanID: "plan-1", Iteration: i, InputPrompt: "p"}); err != nil {
+			t.Fatalf("CreatePlanSession() returned error: %v", err)
+		}
+		if err := db.ClearReviewerFeedback("plan-1"); err != nil {
+			t.Fatalf("ClearReviewerFeedback() returned error: %v", err)
+		}
+		if err := db.CreateProgress(&Progress{PlanID: "plan-1", SessionID: dev, Content: fmt.Sprintf("progress %d", i)}); err != nil {
+			t.Fatalf("CreateProgress() returned error: %v", err)
+		}
+		if i != 2 {
+			if err := db.CreateLearnings(&Learnings{PlanID: "plan-1", SessionID: dev, Content: fmt.Sprintf("learnings %d", i)}); err != nil {
+				t.Fatalf("CreateLearnings() returned error: %v", err)
+			}
+		}
+		if err := db.CreatePlanSession(&PlanSession{ID: rev, PlanID: "plan-1", Iteration: i, InputPrompt: "p", AgentType: LoopAgentReviewer}); err != nil {
+			t.Fatalf("CreatePlanSession() returned error: %v", err)
+		}
+		if i != 2 {
+			if err := db.CreateReviewerFeedback(&ReviewerFeedback{PlanID: "plan-1", SessionID: rev, Content: fmt.Sprintf("feedback %d", i)}); err != nil {
+				t.Fatalf("CreateReviewerFeedback() returned error: %v", err)
+			}
+		}
+		time.Sleep(time.Millisecond) // Keep created_at ordering stable
+	}
+
+	state, err := db.GetStateAsOf("plan-1", 1)
+	if err != nil {
+		t.Fatalf("GetStateAsOf() returned error: %v", err)
+	}
+	if state.Progress == nil || state.Progress.Content != "progress 1" {
+		t.Errorf("Progress as of 1 = %+v, want progress 1", state.Progress)
+	}
+	if state.Learnings == nil || state.Learnings.Content != "learnings 1" {
+		t.Errorf("Learnings as of 1 = %+v, want learnings 1", state.Learnings)
+	}
+	// Cleared by iteration 2's developer, but pending at the end of iteration 1
+	if state.Feedback == nil || state.Feedback.Content != "feedback 1" {
+		t.Errorf("Feedback as of 1 = %+v, want feedback 1", state.Feedback)
+	}
+
+	state, err = db.GetStateAsOf("plan-1", 2)
+	if err != nil {
+		t.Fatalf("GetStateAsOf() returned error: %v", err)
+	}
+	if state.Progress == nil || state.Progress.Content != "progress 2" {
+		t.Errorf("Progress as of 2 = %+v, want progress 2", state.Progress)
+	}
+	if state.Learnings == nil || state.Learnings.Content != "learnings 1" {
+		t.Errorf("Learnings as of 2 = %+v, want the earlier learnings 1", state.Learnings)
+	}
+	if state.Feedback != nil {
+		t.Errorf("Feedback as of 2 = %+v, want none", state.Feedback)
+	}
+
+	history, err := db.GetLearningsHistoryAsOf("plan-1", 2)
+	if err != nil || len(history) != 1 {
+		t.Errorf("GetLearningsHistoryAsOf() = %d records, %v; want 1", len(history), err)
+	}
+
+	// Records of a rewound timeline are left out
+	if err := db.RewindPlan("plan-1", 1); err != nil {
+		t.Fatalf("RewindPlan() returned error: %v", err)
+	}
+	if _, err := db.GetStateAsOf("plan-1", 2); err == nil {
+		t.Error("GetStateAsOf() for a superseded iteration should fail")
+	}
+	if _, err := db.GetStateAsOf("plan-1", 0); err == nil {
+		t.Error("GetStateAsOf() for iteration 0 should fail")
+	}
+}
+
+func TestForkPlan(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content", BaseChangeID: "base"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "dev-1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p"}); err != nil {
+		t.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+	if err := db.CreateProgress(&Progress{PlanID: "plan-1", SessionID: "dev-1", Content: "progress 1"}); err != nil {
+		t.Fatalf("CreateProgress() returned error: %v", err)
+	}
+	if err := db.CreateLearnings(&Learnings{PlanID: "plan-1", SessionID: "dev-1", Content: "learnings 1"}); err != nil {
+		t.Fatalf("CreateLearnings() returned error: %v", err)
+	}
+
+	if err := db.ForkPlan(&Plan{ID: "fork-1", Content: "Plan content"}, "change-1"); err == nil {
+		t.Error("ForkPlan() without a source plan should fail")
+	}
+	fork := &Plan{ID: "fork-1", OriginPath: "plan.md", Content: "Plan content", BaseChangeID: "base", ForkedFrom: "plan-1"}
+	if err := db.ForkPlan(fork, "change-1"); err != nil {
+		t.Fatalf("ForkPlan() returned error: %v", err)
+	}
+
+	got, err := db.GetPlan("fork-1")
+	if err != nil {
+		t.Fatalf("GetPlan() returned error: %v", err)
+	}
+	if got.ForkedFrom != "plan-1" || got.Content != "Plan content" || got.BaseChangeID != "base" || got.Status != PlanStatusPending {
+		t.Errorf("GetPlan() = %+v", got)
+	}
+
+	// The fork starts before its first iteration, in its own change
+	latest, err := db.GetLatestPlanSession("fork-1")
+	if err != nil || latest == nil || latest.Iteration != 0 || latest.CommitID != "change-1" || latest.AgentType != LoopAgentPlanner {
+		t.Fatalf("GetLatestPlanSession() = %+v, %v; want the fork session", latest, err)
+	}
+	progress, err := db.GetLatestProgress("fork-1")
+	if err != nil || progress == nil || progress.Content != "progress 1" || progress.SessionID != latest.ID {
+		t.Errorf("GetLatestProgress() = %+v, %v; want progress 1 from the fork session", progress, err)
+	}
+	if learnings, err := db.GetLatestLearnings("fork-1"); err != nil || learnings == nil || learnings.Content != "learnings 1" {
+		t.Errorf("GetLatestLearnings() = %+v, %v; want learnings 1", learnings, err)
+	}
+
+	// The source plan is untouched
+	if progress, err := db.GetLatestProgress("plan-1"); err != nil || progress == nil || progress.SessionID != "dev-1" {
+		t.Errorf("source GetLatestProgress() = %+v, %v", progress, err)
+	}
+}
+
+func TestTranscriptMessages(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "s1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p"}); err != nil {
+		t.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+
+	messages := []*TranscriptMessage{
+		{SessionID: "s1", Role: "assistant", Kind: "tool_use", ToolName: "Read", ToolUseID: "t1", Content: `{"path":"a.go"}`},
+		{SessionID: "s1", Role: "user", Kind: "tool_result", ToolUseID: "t1", Content: "not found", IsError: true, SubAgentID: "task-1"},
+	}
+	for _, m := range messages {
+		if err := db.CreateTranscriptMessage(m); err != nil {
+			t.Fatalf("CreateTranscriptMessage() returned error: %v", err)
+		}
+		if m.ID == 0 {
+			t.Error("CreateTranscriptMessage() did not set ID")
+		}
+	}
+
+	got, err := db.GetTranscriptBySession("s1")
+	if err != nil {
+		t.Fatalf("GetTranscriptBySession() returned error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("GetTranscriptBySession() returned %d messages, want 2", len(got))
+	}
+	if got[0].Kind != "tool_use" || got[0].ToolName != "Read" || got[0].Content != `{"path":"a.go"}` {
+		t.Errorf("first message = %+v, want the tool_use", got[0])
+	}
+	if got[1].Kind != "tool_result" || !got[1].IsError || got[1].ToolUseID != "t1" || got[1].SubAgentID != "task-1" {
+		t.Errorf("second message = %+v, want the sub-agent's failed tool_result", got[1])
+	}
+	if got[0].SubAgentID != "" {
+		t.Errorf("first message SubAgentID = %q, want none", got[0].SubAgentID)
+	}
+
+	if err := db.CreateEvent(&Event{SessionID: "s1", EventType: "message", RawJSON: "{}", SubAgentID: "task-1"}); err != nil {
+		t.Fatalf("CreateEvent() returned error: %v", err)
+	}
+	events, err := db.GetEventsBySession("s1")
+	if err != nil || len(events) != 1 || events[0].SubAgentID != "task-1" {
+		t.Errorf("GetEventsBySession() = %+v, %v; want one event tagged task-1", events, err)
+	}
+}
+
+func TestNextSequence_Concurrent(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "ralph.db"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "s1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p"}); err != nil {
+		t.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+
+	const writers, perWriter = 8, 25
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				var err error
+				if i%2 == 0 {
+					err = db.CreateEvent(&Event{SessionID: "s1", EventType: "message", RawJSON: "{}"})
+				} else {
+					err = db.CreateTranscriptMessage(&TranscriptMessage{SessionID: "s1", Role: "assistant", Kind: "text", Content: "hi"})
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent write failed: %v", err)
+	}
+
+	events, err := db.GetEventsBySession("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript, err := db.GetTranscriptBySession("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[int]bool)
+	for _, e := range events {
+		seen[e.Sequence] = true
+	}
+	for _, m := range transcript {
+		seen[m.Sequence] = true
+	}
+	if len(seen) != writers*perWriter || len(events)+len(transcript) != writers*perWriter {
+		t.Errorf("expected %d unique sequences, got %d for %d rows", writers*perWriter, len(seen), len(events)+len(transcript))
+	}
+	for i := 0; i < writers*perWriter; i++ {
+		if !seen[i] {
+			t.Errorf("sequence %d was never assigned", i)
+		}
+	}
+}
+
+func TestNextSequence_SkipsExistingRows(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "s1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p"}); err != nil {
+		t.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+
+	if got, err := db.NextSequence("s1"); err != nil || got != 0 {
+		t.Fatalf("NextSequence() = %d, %v; want 0", got, err)
+	}
+	// Rows written without the counter, as importing plan state does
+	if _, err := db.conn.Exec(`
+		INSERT INTO events (session_id, sequence, event_type, raw_json, created_at)
+		VALUES ('s1', 7, 'message', '{}', ?)`, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := db.NextSequence("s1"); err != nil || got != 8 {
+		t.Errorf("NextSequence() = %d, %v; want 8", got, err)
+	}
+	if got, err := db.NextSequence("s2"); err != nil || got != 0 {
+		t.Errorf("NextSequence() of another session = %d, %v; want 0", got, err)
+	}
+}
+
+func TestMigrate_RenumbersDuplicateSequences(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "s1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p"}); err != nil {
+		t.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+
+	// Simulate a database written by concurrent writers before the unique index
+	if _, err := db.conn.Exec(`DROP INDEX idx_events_session_sequence; PRAGMA user_version = 16`); err != nil {
+		t.Fatal(err)
+	}
+	for _, sequence := range []int{0, 1, 1, 0} {
+		if _, err := db.conn.Exec(`
+			INSERT INTO events (session_id, sequence, event_type, raw_json, created_at)
+			VALUES ('s1', ?, ?, '{}', ?)`, sequence, fmt.Sprintf("e%d", sequence), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate() error: %v", err)
+	}
+	events, err := db.GetEventsBySession("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, fmt.Sprintf("%d:%s", e.Sequence, e.EventType))
+	}
+	if want := "[0:e0 1:e0 2:e1 3:e1]"; fmt.Sprint(got) != want {
+		t.Errorf("events = %v, want %s", got, want)
+	}
+	if _, err := db.conn.Exec(`
+		INSERT INTO events (session_id, sequence, event_type, raw_json, created_at)
+		VALUES ('s1', 0, 'message', '{}', ?)`, time.Now()); err == nil {
+		t.Error("expected the unique index to reject a duplicate sequence")
+	}
+}
+
+func TestMigrate_MovesEnvironmentModelToSessions(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "s1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p"}); err != nil {
+		t.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+
+	// Simulate a database that kept the model with the environment too
+	if _, err := db.conn.Exec(`ALTER TABLE session_environments ADD COLUMN model TEXT NOT NULL DEFAULT ''`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.conn.Exec(`
+		INSERT INTO session_environments (session_id, plan_id, model, created_at)
+		VALUES ('s1', 'plan-1', 'claude-sonnet-4', ?)`, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate() error: %v", err)
+	}
+	if exists, err := db.columnExists("session_environments", "model"); err != nil || exists {
+		t.Errorf("columnExists(session_environments, model) = %v, %v; want the column dropped", exists, err)
+	}
+	session, err := db.GetPlanSession("s1")
+	if err != nil || session.Model != "claude-sonnet-4" {
+		t.Errorf("session model = %+v, %v; want claude-sonnet-4", session, err)
+	}
+}
+
+func TestGetPlanEventsAfter(t *testing.T) {
+	db := newTestDB(t)
+
+	for _, planID := range []string{"plan-1", "plan-2"} {
+		if err := db.CreatePlan(&Plan{ID: planID, OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+			t.Fatalf("CreatePlan() returned error: %v", err)
+		}
+	}
+	sessions := map[string]string{"s1": "plan-1", "s2": "plan-1", "other": "plan-2"}
+	for _, id := range []string{"s1", "s2", "other"} {
+		if err := db.CreatePlanSession(&PlanSession{ID: id, PlanID: sessions[id], Iteration: 1, InputPrompt: "p"}); err != nil {
+			t.Fatalf("CreatePlanSession() returned error: %v", err)
+		}
+	}
+
+	var ids []int64
+	for _, sessionID := range []string{"s1", "other", "s2", "s2"} {
+		e := &Event{SessionID: sessionID, EventType: "message", RawJSON: "{}"}
+		if err := db.CreateEvent(e); err != nil {
+			t.Fatalf("CreateEvent() returned error: %v", err)
+		}
+		ids = append(ids, e.ID)
+	}
+
+	events, err := db.GetPlanEventsAfter("plan-1", 0)
+	if err != nil {
+		t.Fatalf("GetPlanEventsAfter() returned error: %v", err)
+	}
+	if len(events) != 3 || events[0].ID != ids[0] || events[1].ID != ids[2] || events[2].ID != ids[3] {
+		t.Errorf("GetPlanEventsAfter(0) = %+v, want plan-1's three events in order", events)
+	}
+
+	events, err = db.GetPlanEventsAfter("plan-1", ids[2])
+	if err != nil {
+		t.Fatalf("GetPlanEventsAfter() returned error: %v", err)
+	}
+	if len(events) != 1 || events[0].ID != ids[3] {
+		t.Errorf("GetPlanEventsAfter(%d) = %+v, want only the last event", ids[2], events)
+	}
+}
+
+func TestToolUsage(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	for i, id := range []string{"s1", "s2"} {
+		if err := db.CreatePlanSession(&PlanSession{ID: id, PlanID: "plan-1", Iteration: i + 1, InputPrompt: "p"}); err != nil {
+			t.Fatalf("CreatePlanSession() returned error: %v", err)
+		}
+	}
+
+	usages := []*ToolUsage{
+		{PlanID: "plan-1", SessionID: "s2", Iteration: 2, ToolName: "Read", Calls: 3},
+		{PlanID: "plan-1", SessionID: "s1", Iteration: 1, ToolName: "Edit", Calls: 2, Files: []string{"a.go", "b.go"}},
+		{PlanID: "plan-1", SessionID: "s1", Iteration: 1, ToolName: "Bash", Calls: 1, Commands: []string{"go test ./...\ngo vet ./..."}},
+	}
+	for _, u := range usages {
+		if err := db.CreateToolUsage(u); err != nil {
+			t.Fatalf("CreateToolUsage() returned error: %v", err)
+		}
+		if u.ID == 0 {
+			t.Error("CreateToolUsage() did not set ID")
+		}
+	}
+
+	got, err := db.GetToolUsageByPlan("plan-1")
+	if err != nil {
+		t.Fatalf("GetToolUsageByPlan() returned error: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("GetToolUsageByPlan() returned %d rows, want 3", len(got))
+	}
+	if got[0].ToolName != "Edit" || got[0].Calls != 2 || len(got[0].Files) != 2 || got[0].Files[1] != "b.go" {
+		t.Errorf("first row = %+v, want the Edit usage of iteration 1", got[0])
+	}
+	if got[1].ToolName != "Bash" || len(got[1].Commands) != 1 || got[1].Commands[0] != "go test ./...\ngo vet ./..." {
+		t.Errorf("second row = %+v, want the Bash usage with its multi-line command", got[1])
+	}
+	if got[2].ToolName != "Read" || got[2].Files != nil || got[2].Commands != nil {
+		t.Errorf("third row = %+v, want the Read usage without files or commands", got[2])
+	}
+}
+
+func TestUpdatePlanSessionCommitID(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "s1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p"}); err != nil {
+		t.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+
+	if err := db.UpdatePlanSessionCommitID("s1", "commit123"); err != nil {
+		t.Fatalf("UpdatePlanSessionCommitID() returned error: %v", err)
+	}
+	session, err := db.GetPlanSession("s1")
+	if err != nil {
+		t.Fatalf("GetPlanSession() returned error: %v", err)
+	}
+	if session.CommitID != "commit123" {
+		t.Errorf("CommitID = %q, want %q", session.CommitID, "commit123")
+	}
+
+	if err := db.UpdatePlanSessionCommitID("missing", "x"); err != ErrNotFound {
+		t.Errorf("UpdatePlanSessionCommitID() for unknown session error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUpdatePlanSessionClaudeSession(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "s1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p"}); err != nil {
+		t.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+
+	if err := db.UpdatePlanSessionClaudeSession("s1", "claude-abc"); err != nil {
+		t.Fatalf("UpdatePlanSessionClaudeSession() returned error: %v", err)
+	}
+	session, err := db.GetLatestPlanSession("plan-1")
+	if err != nil {
+		t.Fatalf("GetLatestPlanSession() returned error: %v", err)
+	}
+	if session.ClaudeSessionID != "claude-abc" {
+		t.Errorf("ClaudeSessionID = %q, want %q", session.ClaudeSessionID, "claude-abc")
+	}
+
+	if err := db.UpdatePlanSessionClaudeSession("missing", "x"); err != ErrNotFound {
+		t.Errorf("UpdatePlanSessionClaudeSession() for unknown session error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUpdatePlanSessionTimings(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "s1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p"}); err != nil {
+		t.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+
+	timings := SessionTimings{
+		Prompt: 12 * time.Millisecond,
+		Claude: 95 * time.Second,
+		Parse:  1500 * time.Microsecond,
+		JJ:     2 * time.Second,
+	}
+	if err := db.UpdatePlanSessionTimings("s1", timings); err != nil {
+		t.Fatalf("UpdatePlanSessionTimings() returned error: %v", err)
+	}
+	sessions, err := db.GetPlanSessionsByPlan("plan-1")
+	if err != nil {
+		t.Fatalf("GetPlanSessionsByPlan() returned error: %v", err)
+	}
+	// Stored with millisecond precision
+	want := SessionTimings{Prompt: 12 * time.Millisecond, Claude: 95 * time.Second, Parse: time.Millisecond, JJ: 2 * time.Second}
+	if len(sessions) != 1 || sessions[0].Timings != want {
+		t.Fatalf("Timings = %+v, want %+v", sessions[0].Timings, want)
+	}
+	if total := sessions[0].Timings.Total(); total != 97013*time.Millisecond {
+		t.Errorf("Total() = %v", total)
+	}
+
+	if err := db.UpdatePlanSessionTimings("missing", timings); err != ErrNotFound {
+		t.Errorf("UpdatePlanSessionTimings() for unknown session error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestIncrementReformatAttempts(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "s1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p"}); err != nil {
+		t.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+	for range 2 {
+		if err := db.IncrementReformatAttempts("s1"); err != nil {
+			t.Fatalf("IncrementReformatAttempts() returned error: %v", err)
+		}
+	}
+	session, err := db.GetPlanSession("s1")
+	if err != nil {
+		t.Fatalf("GetPlanSession() returned error: %v", err)
+	}
+	if session.ReformatAttempts != 2 {
+		t.Errorf("ReformatAttempts = %d, want 2", session.ReformatAttempts)
+	}
+
+	if err := db.IncrementReformatAttempts("missing"); err != ErrNotFound {
+		t.Errorf("IncrementReformatAttempts() for unknown session error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUpdatePlanBaseChangeID_UpdatesTimestamp(t *testing.T) {
+	db := newTestDB(t)
+
+	plan := &Plan{
+		ID:         "plan-1",
+		OriginPath: "/path/to/plan.md",
+		Content:    "Plan content",
+	}
+	if err := db.CreatePlan(plan); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+
+	original, err := db.GetPlan("plan-1")
+	if err != nil {
+		t.Fatalf("GetPlan() returned error: %v", err)
+	}
+	originalUpdatedAt := original.UpdatedAt
+
+	time.Sleep(10 * time.Millisecond)
+
+	if err := db.UpdatePlanBaseChangeID("plan-1", "abc123"); err != nil {
+		t.Fatalf("UpdatePlanBaseChangeID() returned error: %v", err)
+	}
+
+	updated, err := db.GetPlan("plan-1")
+	if err != nil {
+		t.Fatalf("GetPlan() returned error: %v", err)
+	}
+	if !updated.UpdatedAt.After(originalUpdatedAt) {
+		t.Error("UpdatePlanBaseChangeID() did not update UpdatedAt timestamp")
+	}
+}
+
+// =============================================================================
+// Global Learnings Tests
+// =============================================================================
+
+func TestCreateGlobalLearning_Dedupes(t *testing.T) {
+	db := newTestDB(t)
+
+	learning := &GlobalLearning{RepoRoot: "/repo", PlanID: "plan-1", Content: "Run make generate after editing protos"}
+	inserted, err := db.CreateGlobalLearning(learning)
+	if err != nil {
+		t.Fatalf("CreateGlobalLearning() error: %v", err)
+	}
+	if !inserted || learning.ID == 0 {
+		t.Errorf("expected first learning to be inserted with an ID, got inserted=%v id=%d", inserted, learning.ID)
+	}
+
+	// Same content in the same repo is skipped
+	inserted, err = db.CreateGlobalLearning(&GlobalLearning{RepoRoot: "/repo", PlanID: "plan-2", Content: learning.Content})
+	if err != nil {
+		t.Fatalf("CreateGlobalLearning() duplicate error: %v", err)
+	}
+	if inserted {
+		t.Error("expected duplicate learning to be skipped")
+	}
+
+	// Same content in another repo is kept
+	inserted, err = db.CreateGlobalLearning(&GlobalLearning{RepoRoot: "/other", PlanID: "plan-3", Content: learning.Content})
+	if err != nil {
+		t.Fatalf("CreateGlobalLearning() other repo error: %v", err)
+	}
+	if !inserted {
+		t.Error("expected learning for another repo to be inserted")
+	}
+}
+
+func TestGetGlobalLearnings(t *testing.T) {
+	db := newTestDB(t)
+
+	for _, content := range []string{"first", "second"} {
+		if _, err := db.CreateGlobalLearning(&GlobalLearning{RepoRoot: "/repo", PlanID: "p", Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.CreateGlobalLearning(&GlobalLearning{RepoRoot: "/other", PlanID: "p", Content: "elsewhere"}); err != nil {
+		t.Fatal(err)
+	}
+
+	learnings, err := db.GetGlobalLearnings("/repo")
+	if err != nil {
+		t.Fatalf("GetGlobalLearnings() error: %v", err)
+	}
+	if len(learnings) != 2 {
+		t.Fatalf("expected 2 learnings, got %d", len(learnings))
+	}
+	if learnings[0].Content != "second" {
+		t.Errorf("expected newest first, got %q", learnings[0].Content)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ralph.db")
+	d, err := New(path)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	health, err := Inspect(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Inspect() error: %v", err)
+	}
+	if health.SchemaVersion != SchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", health.SchemaVersion, SchemaVersion)
+	}
+	if len(health.Problems) != 0 {
+		t.Errorf("Problems = %v, want none", health.Problems)
+	}
+
+	if _, err := Inspect(context.Background(), filepath.Join(t.TempDir(), "missing.db")); err == nil {
+		t.Error("expected error inspecting a missing database")
+	}
+}
+
+func TestAnalyzerFindings(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "rev-1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p", AgentType: LoopAgentReviewer}); err != nil {
+		t.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+	for _, f := range []*AnalyzerFinding{
+		{PlanID: "plan-1", SessionID: "rev-1", Analyzer: "go vet", Output: "main.go:3: unreachable code", Failed: true},
+		{PlanID: "plan-1", SessionID: "rev-1", Analyzer: "eslint", Output: "failed to run eslint: not found", Failed: true},
+	} {
+		if err := db.CreateAnalyzerFinding(f); err != nil {
+			t.Fatalf("CreateAnalyzerFinding() returned error: %v", err)
+		}
+		if f.ID == 0 {
+			t.Error("CreateAnalyzerFinding() did not set ID")
+		}
+	}
+
+	findings, err := db.GetAnalyzerFindingsBySession("rev-1")
+	if err != nil {
+		t.Fatalf("GetAnalyzerFindingsBySession() returned error: %v", err)
+	}
+	if len(findings) != 2 || findings[0].Analyzer != "go vet" || findings[1].Analyzer != "eslint" {
+		t.Fatalf("GetAnalyzerFindingsBySession() = %+v", findings)
+	}
+	if findings[0].Output != "main.go:3: unreachable code" || !findings[0].Failed {
+		t.Errorf("finding = %+v", findings[0])
+	}
+}
+
+func TestCheckFailures(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "dev-1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p", AgentType: LoopAgentDeveloper}); err != nil {
+		t.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+	for _, f := range []*CheckFailure{
+		{PlanID: "plan-1", SessionID: "dev-1", Name: "tests", Output: "--- FAIL: TestParse"},
+		{PlanID: "plan-1", SessionID: "dev-1", Name: "lint", Output: "main.go:3: unused"},
+	} {
+		if err := db.CreateCheckFailure(f); err != nil {
+			t.Fatalf("CreateCheckFailure() returned error: %v", err)
+		}
+		if f.ID == 0 {
+			t.Error("CreateCheckFailure() did not set ID")
+		}
+	}
+
+	failures, err := db.GetCheckFailuresBySession("dev-1")
+	if err != nil {
+		t.Fatalf("GetCheckFailuresBySession() returned error: %v", err)
+	}
+	if len(failures) != 2 || failures[0].Name != "tests" || failures[1].Output != "main.go:3: unused" {
+		t.Fatalf("GetCheckFailuresBySession() = %+v", failures)
+	}
+	if failures, err := db.GetCheckFailuresBySession("dev-2"); err != nil || len(failures) != 0 {
+		t.Errorf("GetCheckFailuresBySession() for another session = %+v, %v", failures, err)
+	}
+}
+
+func TestReviewSkips(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	for _, s := range []*ReviewSkip{
+		{PlanID: "plan-1", Iteration: 2, Action: ReviewSkipActionSkip, Reason: "comment-only changes (2 lines in 1 files)"},
+		{PlanID: "plan-1", Iteration: 4, Action: ReviewSkipActionDowngrade, Reason: "whitespace-only changes (1 lines in 1 files)", SessionID: "rev-4"},
+	} {
+		if err := db.CreateReviewSkip(s); err != nil {
+			t.Fatalf("CreateReviewSkip() returned error: %v", err)
+		}
+		if s.ID == 0 {
+			t.Error("CreateReviewSkip() did not set ID")
+		}
+	}
+
+	skips, err := db.GetReviewSkipsByPlan("plan-1")
+	if err != nil {
+		t.Fatalf("GetReviewSkipsByPlan() returned error: %v", err)
+	}
+	if len(skips) != 2 || skips[0].Iteration != 2 || skips[1].Action != ReviewSkipActionDowngrade {
+		t.Fatalf("GetReviewSkipsByPlan() = %+v", skips)
+	}
+	if skips[1].SessionID != "rev-4" || skips[0].Reason == "" {
+		t.Errorf("skip = %+v", skips[1])
+	}
+}
+
+func TestRebuttals(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "reb-2", PlanID: "plan-1", Iteration: 2, InputPrompt: "p", AgentType: LoopAgentRebuttalReviewer}); err != nil {
+		t.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+	rebuttal := &Rebuttal{
+		PlanID:            "plan-1",
+		Iteration:         2,
+		FeedbackSessionID: "rev-1",
+		SessionID:         "reb-2",
+		Feedback:          "Handle the nil config",
+		Rebuttal:          "Config is never nil: New rejects it",
+		Accepted:          true,
+		Response:          "Agreed, withdrawn",
+	}
+	if err := db.CreateRebuttal(rebuttal); err != nil {
+		t.Fatalf("CreateRebuttal() returned error: %v", err)
+	}
+	if rebuttal.ID == 0 {
+		t.Error("CreateRebuttal() did not set ID")
+	}
+
+	got, err := db.GetRebuttalBySession("reb-2")
+	if err != nil {
+		t.Fatalf("GetRebuttalBySession() returned error: %v", err)
+	}
+	if got == nil || got.FeedbackSessionID != "rev-1" || !got.Accepted || got.Response != "Agreed, withdrawn" {
+		t.Fatalf("GetRebuttalBySession() = %+v", got)
+	}
+	if got, err := db.GetRebuttalBySession("rev-1"); err != nil || got != nil {
+		t.Errorf("GetRebuttalBySession() of a reviewer session = %+v, %v; want nil", got, err)
+	}
+
+	rebuttals, err := db.GetRebuttalsByPlan("plan-1")
+	if err != nil {
+		t.Fatalf("GetRebuttalsByPlan() returned error: %v", err)
+	}
+	if len(rebuttals) != 1 || rebuttals[0].Rebuttal != "Config is never nil: New rejects it" {
+		t.Errorf("GetRebuttalsByPlan() = %+v", rebuttals)
+	}
+}
+
+func TestRejectedApproaches(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		rev := fmt.Sprintf("rev-%d", i)
+		if err := db.CreatePlanSession(&PlanSession{ID: rev, PlanID: "plan-1", Iteration: i, InputPrompt: "p", AgentType: LoopAgentReviewer}); err != nil {
+			t.Fatalf("CreatePlanSession() returned error: %v", err)
+		}
+		approach := &RejectedApproach{PlanID: "plan-1", SessionID: rev, Source: RejectedByReviewer, Content: fmt.Sprintf("approach %d", i)}
+		if err := db.CreateRejectedApproach(approach); err != nil {
+			t.Fatalf("CreateRejectedApproach() returned error: %v", err)
+		}
+		if approach.ID == 0 {
+			t.Error("CreateRejectedApproach() did not set ID")
+		}
+	}
+
+	// The reviewer of iteration 2 withdrew its feedback, and iteration 3 is
+	// rewound away
+	if err := db.CreatePlanSession(&PlanSession{ID: "reb-2", PlanID: "plan-1", Iteration: 2, InputPrompt: "p", AgentType: LoopAgentRebuttalReviewer}); err != nil {
+		t.Fatalf("CreatePlanSession() returned error: %v", err)
+	}
+	if err := db.CreateRebuttal(&Rebuttal{PlanID: "plan-1", Iteration: 2, FeedbackSessionID: "rev-2", SessionID: "reb-2", Accepted: true}); err != nil {
+		t.Fatalf("CreateRebuttal() returned error: %v", err)
+	}
+	if err := db.RewindPlan("plan-1", 2); err != nil {
+		t.Fatalf("RewindPlan() returned error: %v", err)
+	}
+
+	approaches, err := db.GetRejectedApproaches("plan-1")
+	if err != nil {
+		t.Fatalf("GetRejectedApproaches() returned error: %v", err)
+	}
+	if len(approaches) != 1 || approaches[0].Content != "approach 1" || approaches[0].Source != RejectedByReviewer {
+		t.Errorf("GetRejectedApproaches() = %+v; want only the first approach", approaches)
+	}
+}
+
+func TestSessionEnvironments(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	for _, id := range []string{"dev-1", "rev-1"} {
+		if err := db.CreatePlanSession(&PlanSession{ID: id, PlanID: "plan-1", Iteration: 1, InputPrompt: "p"}); err != nil {
+			t.Fatalf("CreatePlanSession() returned error: %v", err)
+		}
+		env := &SessionEnvironment{SessionID: id, PlanID: "plan-1", ClaudeVersion: "2.0.1 (Claude Code)", JJRevision: "abc123", GoVersion: "1.22"}
+		if err := db.CreateSessionEnvironment(env); err != nil {
+			t.Fatalf("CreateSessionEnvironment() returned error: %v", err)
+		}
+	}
+
+	// The model is the session's own
+	if err := db.UpdatePlanSessionModel("rev-1", "claude-sonnet-4"); err != nil {
+		t.Fatalf("UpdatePlanSessionModel() returned error: %v", err)
+	}
+
+	envs, err := db.GetSessionEnvironmentsByPlan("plan-1")
+	if err != nil {
+		t.Fatalf("GetSessionEnvironmentsByPlan() returned error: %v", err)
+	}
+	if len(envs) != 2 || envs[0].SessionID != "dev-1" || envs[1].SessionID != "rev-1" {
+		t.Fatalf("GetSessionEnvironmentsByPlan() = %+v", envs)
+	}
+	if envs[0].Model != "" || envs[1].Model != "claude-sonnet-4" {
+		t.Errorf("models = %q, %q, want \"\" and claude-sonnet-4", envs[0].Model, envs[1].Model)
+	}
+	if envs[1].ClaudeVersion != "2.0.1 (Claude Code)" || envs[1].JJRevision != "abc123" || envs[1].GoVersion != "1.22" {
+		t.Errorf("environment = %+v", envs[1])
+	}
+}
+
+func TestPlanWorkspaces(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if ws, err := db.GetPlanWorkspace("plan-1"); err != nil || ws != nil {
+		t.Fatalf("GetPlanWorkspace() with none = %+v, %v", ws, err)
+	}
+
+	if err := db.CreatePlanWorkspace(&PlanWorkspace{PlanID: "plan-1", Name: "ralph-plan-1", Path: "/data/workspaces/plan-1"}); err != nil {
+		t.Fatalf("CreatePlanWorkspace() returned error: %v", err)
+	}
+	ws, err := db.GetPlanWorkspace("plan-1")
+	if err != nil {
+		t.Fatalf("GetPlanWorkspace() returned error: %v", err)
+	}
+	if ws == nil || ws.Name != "ralph-plan-1" || ws.Path != "/data/workspaces/plan-1" || ws.CreatedAt.IsZero() {
+		t.Fatalf("GetPlanWorkspace() = %+v", ws)
+	}
+
+	if err := db.DeletePlanWorkspace("plan-1"); err != nil {
+		t.Fatalf("DeletePlanWorkspace() returned error: %v", err)
+	}
+	if ws, err := db.GetPlanWorkspace("plan-1"); err != nil || ws != nil {
+		t.Errorf("GetPlanWorkspace() after delete = %+v, %v", ws, err)
+	}
+}
+
+func TestPlanIssues(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content"}); err != nil {
+		t.Fatalf("CreatePlan() returned error: %v", err)
+	}
+	if issue, err := db.GetPlanIssue("plan-1"); err != nil || issue != nil {
+		t.Fatalf("GetPlanIssue() with none = %+v, %v", issue, err)
+	}
+
+	if err := db.CreatePlanIssue(&PlanIssue{PlanID: "plan-1", Provider: "jira", Key: "PROJ-7", URL: "https://example.atlassian.net/browse/PROJ-7"}); err != nil {
+		t.Fatalf("CreatePlanIssue() returned error: %v", err)
+	}
+	issue, err := db.GetPlanIssue("plan-1")
+	if err != nil {
+		t.Fatalf("GetPlanIssue() returned error: %v", err)
+	}
+	if issue == nil || issue.Provider != "jira" || issue.Key != "PROJ-7" || issue.URL != "https://example.atlassian.net/browse/PROJ-7" || issue.CreatedAt.IsZero() {
+		t.Fatalf("GetPlanIssue() = %+v", issue)
+	}
+	if err := db.CreatePlanIssue(&PlanIssue{PlanID: "plan-1", Provider: "jira", Key: "PROJ-8"}); err == nil {
+		t.Error("expected a second issue for the plan to be rejected")
+	}
+}
+
+func TestRateLimitCooldown(t *testing.T) {
+	db := newTestDB(t)
+
+	if c, err := db.GetRateLimitCooldown("claude"); err != nil || c != nil {
+		t.Fatalf("GetRateLimitCooldown() with none = %+v, %v", c, err)
+	}
+
+	later := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := db.SetRateLimitCooldown(&RateLimitCooldown{Provider: "claude", ResumeAt: later, Reason: "usage limit"}); err != nil {
+		t.Fatalf("SetRateLimitCooldown() returned error: %v", err)
+	}
+	// A shorter cooldown doesn't cut the current one short
+	if err := db.SetRateLimitCooldown(&RateLimitCooldown{Provider: "claude", ResumeAt: later.Add(-30 * time.Minute), Reason: "429"}); err != nil {
+		t.Fatalf("SetRateLimitCooldown() returned error: %v", err)
+	}
+
+	c, err := db.GetRateLimitCooldown("claude")
+	if err != nil {
+		t.Fatalf("GetRateLimitCooldown() returned error: %v", err)
+	}
+	if c == nil || !c.ResumeAt.Equal(later) || c.Reason != "usage limit" {
+		t.Fatalf("GetRateLimitCooldown() = %+v, want resume at %v", c, later)
+	}
+
+	longer := later.Add(time.Hour)
+	if err := db.SetRateLimitCooldown(&RateLimitCooldown{Provider: "claude", ResumeAt: longer, Reason: "429"}); err != nil {
+		t.Fatalf("SetRateLimitCooldown() returned error: %v", err)
+	}
+	if c, err := db.GetRateLimitCooldown("claude"); err != nil || !c.ResumeAt.Equal(longer) || c.Reason != "429" {
+		t.Errorf("GetRateLimitCooldown() after extending = %+v, %v", c, err)
+	}
+}
+
+func TestThrottleTokens(t *testing.T) {
+	db := newTestDB(t)
+
+	// A new bucket starts full
+	for i := 0; i < 2; i++ {
+		wait, err := db.TakeThrottleTokens(ThrottleSessions, 2, 1)
+		if err != nil {
+			t.Fatalf("TakeThrottleTokens() error: %v", err)
+		}
+		if wait != 0 {
+			t.Fatalf("take %d: expected no wait, got %s", i+1, wait)
+		}
+	}
+
+	// Empty: the next token refills in about half an hour
+	wait, err := db.TakeThrottleTokens(ThrottleSessions, 2, 1)
+	if err != nil {
+		t.Fatalf("TakeThrottleTokens() error: %v", err)
+	}
+	if wait < 29*time.Minute || wait > 30*time.Minute {
+		t.Errorf("expected a wait of about 30m, got %s", wait)
+	}
+
+	// Spending goes into debt, which a zero take must wait out
+	if err := db.SpendThrottleTokens(ThrottleCost, 10, 15); err != nil {
+		t.Fatalf("SpendThrottleTokens() error: %v", err)
+	}
+	wait, err = db.TakeThrottleTokens(ThrottleCost, 10, 0)
+	if err != nil {
+		t.Fatalf("TakeThrottleTokens() error: %v", err)
+	}
+	if wait < 29*time.Minute || wait > 30*time.Minute {
+		t.Errorf("expected $5 of debt to take about 30m at $10/hour, got %s", wait)
+	}
+}
+
+func TestThrottleTokens_Concurrent(t *testing.T) {
+	// Two runs, each with its own connection to the database
+	path := filepath.Join(t.TempDir(), "plans.db")
+	var runs []*DB
+	for i := 0; i < 2; i++ {
+		db, err := New(path)
+		if err != nil {
+			t.Fatalf("New() error: %v", err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+		runs = append(runs, db)
+	}
+
+	const capacity = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	taken := 0
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(db *DB) {
+			defer wg.Done()
+			wait, err := db.TakeThrottleTokens(ThrottleSessions, capacity, 1)
+			if err != nil {
+				t.Errorf("TakeThrottleTokens() error: %v", err)
+				return
+			}
+			if wait == 0 {
+				mu.Lock()
+				taken++
+				mu.Unlock()
+			}
+		}(runs[i%2])
+	}
+	wg.Wait()
+
+	if taken != capacity {
+		t.Errorf("concurrent takes got %d sessions, want exactly %d", taken, capacity)
 	}
 }
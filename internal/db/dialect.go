@@ -0,0 +1,424 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// Database backends for the plans database.
+const (
+	BackendSQLite   = "sqlite"
+	BackendPostgres = "postgres"
+)
+
+// dialect adapts the package's queries, which are written for SQLite with
+// ? placeholders, to a database backend.
+type dialect interface {
+	// backend returns the backend's name (BackendSQLite or BackendPostgres).
+	backend() string
+	// rebind rewrites ? placeholders into the backend's syntax.
+	rebind(query string) string
+	// schema returns the statements creating every table, index, and trigger.
+	schema() string
+	// lockMigrations serializes migrations across processes sharing the
+	// database. The returned function releases the lock.
+	lockMigrations(ctx context.Context, db *sql.DB) (func(), error)
+	// columnExists reports whether table has column.
+	columnExists(c *conn, table, column string) (bool, error)
+	// schemaVersion returns the version recorded by the last migration.
+	schemaVersion(c *conn) (int, error)
+	// setSchemaVersion records the migrated schema version.
+	setSchemaVersion(c *conn, version int) error
+	// returningID reports whether inserts report their row ID through
+	// RETURNING rather than LastInsertId.
+	returningID() bool
+	// searchQuery returns the full-text search query, taking the match
+	// expression, plan ID (twice), and limit as arguments.
+	searchQuery() string
+	// searchMatch converts a user query into the match expression, or ""
+	// if there is nothing to search for.
+	searchMatch(query string) string
+}
+
+// conn is the connection pool, with queries rewritten for its dialect.
+// Data statements are prepared once and reused from a cache.
+type conn struct {
+	*sql.DB
+	dialect dialect
+
+	stmtsMu sync.Mutex
+	stmts   map[string]*sql.Stmt // Keyed by the rebound query
+}
+
+// stmt returns the cached prepared statement for a rebound query, preparing
+// it on first use. Schema changes, pragmas, and statements the driver can't
+// prepare (such as several statements in one query) return nil and run
+// unprepared. A failed prepare isn't cached, so it's retried on next use.
+func (c *conn) stmt(query string) *sql.Stmt {
+	if !cacheable(query) {
+		return nil
+	}
+	c.stmtsMu.Lock()
+	defer c.stmtsMu.Unlock()
+	if stmt, ok := c.stmts[query]; ok {
+		return stmt
+	}
+	stmt, err := c.DB.Prepare(query)
+	if err != nil {
+		log.Debug("running statement unprepared", "error", err)
+		return nil
+	}
+	if c.stmts == nil {
+		c.stmts = make(map[string]*sql.Stmt)
+	}
+	c.stmts[query] = stmt
+	return stmt
+}
+
+// cacheable reports whether a query is a data statement worth preparing
+// once: a SELECT, INSERT, UPDATE, DELETE, or WITH.
+func cacheable(query string) bool {
+	word, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	switch strings.ToUpper(strings.TrimSpace(word)) {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH":
+		return true
+	}
+	return false
+}
+
+// Exec executes a query without returning any rows.
+func (c *conn) Exec(query string, args ...any) (sql.Result, error) {
+	query = c.dialect.rebind(query)
+	if stmt := c.stmt(query); stmt != nil {
+		return stmt.Exec(args...)
+	}
+	return c.DB.Exec(query, args...)
+}
+
+// execUnprepared executes a query without returning any rows, bypassing the
+// statement cache. It's for queries built per call, such as those with an IN
+// list of variable length, which would add a cached statement per length.
+func (c *conn) execUnprepared(query string, args ...any) (sql.Result, error) {
+	return c.DB.Exec(c.dialect.rebind(query), args...)
+}
+
+// Query executes a query that returns rows.
+func (c *conn) Query(query string, args ...any) (*sql.Rows, error) {
+	query = c.dialect.rebind(query)
+	if stmt := c.stmt(query); stmt != nil {
+		return stmt.Query(args...)
+	}
+	return c.DB.Query(query, args...)
+}
+
+// QueryRow executes a query that returns at most one row.
+func (c *conn) QueryRow(query string, args ...any) *sql.Row {
+	query = c.dialect.rebind(query)
+	if stmt := c.stmt(query); stmt != nil {
+		return stmt.QueryRow(args...)
+	}
+	return c.DB.QueryRow(query, args...)
+}
+
+// Close closes the cached statements and the connection pool.
+func (c *conn) Close() error {
+	c.stmtsMu.Lock()
+	for query, stmt := range c.stmts {
+		if err := stmt.Close(); err != nil {
+			log.Warn("failed to close prepared statement", "error", err)
+		}
+		delete(c.stmts, query)
+	}
+	c.stmtsMu.Unlock()
+	return c.DB.Close()
+}
+
+// Begin starts a transaction.
+func (c *conn) Begin() (*tx, error) {
+	return c.BeginTx(context.Background(), nil)
+}
+
+// BeginTx starts a transaction with the given options.
+func (c *conn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*tx, error) {
+	t, err := c.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &tx{Tx: t, dialect: c.dialect}, nil
+}
+
+// insert executes an INSERT and returns the ID of the new row.
+func (c *conn) insert(query string, args ...any) (int64, error) {
+	return insertID(c, c.dialect, query, args...)
+}
+
+// tx is a transaction, with queries rewritten for its dialect.
+type tx struct {
+	*sql.Tx
+	dialect dialect
+}
+
+// Exec executes a query without returning any rows.
+func (t *tx) Exec(query string, args ...any) (sql.Result, error) {
+	return t.Tx.Exec(t.dialect.rebind(query), args...)
+}
+
+// ExecContext executes a query without returning any rows.
+func (t *tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.Tx.ExecContext(ctx, t.dialect.rebind(query), args...)
+}
+
+// Query executes a query that returns rows.
+func (t *tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return t.Tx.Query(t.dialect.rebind(query), args...)
+}
+
+// QueryContext executes a query that returns rows.
+func (t *tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return t.Tx.QueryContext(ctx, t.dialect.rebind(query), args...)
+}
+
+// QueryRow executes a query that returns at most one row.
+func (t *tx) QueryRow(query string, args ...any) *sql.Row {
+	return t.Tx.QueryRow(t.dialect.rebind(query), args...)
+}
+
+// Prepare creates a prepared statement for use within the transaction.
+func (t *tx) Prepare(query string) (*sql.Stmt, error) {
+	return t.Tx.Prepare(t.dialect.rebind(query))
+}
+
+// insert executes an INSERT and returns the ID of the new row.
+func (t *tx) insert(query string, args ...any) (int64, error) {
+	return insertID(t, t.dialect, query, args...)
+}
+
+// preparedInsert is an INSERT prepared once for a transaction's repeated
+// inserts.
+type preparedInsert struct {
+	stmt      *sql.Stmt
+	returning bool // Reports the row ID through RETURNING
+}
+
+// prepareInsert prepares an INSERT within the transaction.
+func (t *tx) prepareInsert(query string) (*preparedInsert, error) {
+	returning := t.dialect.returningID()
+	if returning {
+		query += " RETURNING id"
+	}
+	stmt, err := t.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &preparedInsert{stmt: stmt, returning: returning}, nil
+}
+
+// insert executes the INSERT and returns the ID of the new row.
+func (p *preparedInsert) insert(args ...any) (int64, error) {
+	if p.returning {
+		var id int64
+		err := p.stmt.QueryRow(args...).Scan(&id)
+		return id, err
+	}
+	result, err := p.stmt.Exec(args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// execer is implemented by conn and tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// insertID executes an INSERT and returns the ID of the new row, using
+// RETURNING on backends without LastInsertId.
+func insertID(e execer, d dialect, query string, args ...any) (int64, error) {
+	if d.returningID() {
+		var id int64
+		err := e.QueryRow(query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	result, err := e.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// =============================================================================
+// SQLite
+// =============================================================================
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) backend() string { return BackendSQLite }
+
+func (sqliteDialect) rebind(query string) string { return query }
+
+func (sqliteDialect) schema() string { return schema }
+
+// lockMigrations is a no-op: SQLite serializes writers itself, and
+// busy_timeout makes concurrent openers wait their turn.
+func (sqliteDialect) lockMigrations(context.Context, *sql.DB) (func(), error) {
+	return func() {}, nil
+}
+
+func (sqliteDialect) columnExists(c *conn, table, column string) (bool, error) {
+	rows, err := c.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "operation", "columnExists", "error", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var cid int
+		var name, ctype string
+		var notnull, pk int
+		var dfltValue interface{}
+		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dfltValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+func (sqliteDialect) schemaVersion(c *conn) (int, error) {
+	return schemaVersion(c.DB)
+}
+
+func (sqliteDialect) setSchemaVersion(c *conn, version int) error {
+	_, err := c.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version))
+	return err
+}
+
+func (sqliteDialect) returningID() bool { return false }
+
+func (sqliteDialect) searchQuery() string {
+	return `
+		SELECT s.kind, s.plan_id, s.session_id, COALESCE(ps.iteration, 0),
+		       snippet(search_index, 3, '[', ']', '…', 12)
+		FROM search_index s
+		LEFT JOIN plan_sessions ps ON ps.id = s.session_id
+		WHERE search_index MATCH ? AND (? = '' OR s.plan_id = ?)
+		ORDER BY rank
+		LIMIT ?`
+}
+
+func (sqliteDialect) searchMatch(query string) string { return ftsQuery(query) }
+
+// =============================================================================
+// Postgres
+// =============================================================================
+
+// postgresMigrationLock is the advisory lock key held while migrating.
+const postgresMigrationLock = 0x72616c7068 // "ralph"
+
+type postgresDialect struct{}
+
+func (postgresDialect) backend() string { return BackendPostgres }
+
+func (postgresDialect) rebind(query string) string { return rebindDollar(query) }
+
+func (postgresDialect) schema() string { return postgresSchema }
+
+// lockMigrations takes a session-level advisory lock on a dedicated
+// connection, so that ralph processes starting together don't race to
+// create the same tables.
+func (postgresDialect) lockMigrations(ctx context.Context, db *sql.DB) (func(), error) {
+	c, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, postgresMigrationLock); err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	return func() {
+		if _, err := c.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, postgresMigrationLock); err != nil {
+			log.Warn("failed to unlock migrations", "error", err)
+		}
+		if err := c.Close(); err != nil {
+			log.Warn("failed to release connection", "error", err)
+		}
+	}, nil
+}
+
+func (postgresDialect) columnExists(c *conn, table, column string) (bool, error) {
+	var n int
+	err := c.QueryRow(`
+		SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`,
+		table, column,
+	).Scan(&n)
+	return n > 0, err
+}
+
+func (postgresDialect) schemaVersion(c *conn) (int, error) {
+	var version int
+	err := c.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM ralph_schema_version`).Scan(&version)
+	return version, err
+}
+
+func (postgresDialect) setSchemaVersion(c *conn, version int) error {
+	_, err := c.Exec(fmt.Sprintf(
+		`DELETE FROM ralph_schema_version; INSERT INTO ralph_schema_version (version) VALUES (%d)`, version))
+	return err
+}
+
+func (postgresDialect) returningID() bool { return true }
+
+func (postgresDialect) searchQuery() string {
+	return `
+		SELECT s.kind, s.plan_id, s.session_id, COALESCE(ps.iteration, 0),
+		       ts_headline('simple', s.content, q,
+		                   'StartSel=[, StopSel=], MaxWords=12, MinWords=4, MaxFragments=1, FragmentDelimiter=…')
+		FROM search_index s
+		CROSS JOIN plainto_tsquery('simple', ?) q
+		LEFT JOIN plan_sessions ps ON ps.id = s.session_id
+		WHERE s.document @@ q AND (? = '' OR s.plan_id = ?)
+		ORDER BY ts_rank(s.document, q) DESC
+		LIMIT ?`
+}
+
+// searchMatch passes the query through: plainto_tsquery already ignores
+// punctuation and requires every term.
+func (postgresDialect) searchMatch(query string) string { return strings.TrimSpace(query) }
+
+// rebindDollar rewrites ? placeholders as $1, $2, ..., leaving question
+// marks inside quoted string literals alone.
+func rebindDollar(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+	n := 0
+	quoted := false
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case ch == '\'':
+			quoted = !quoted
+		case ch == '?' && !quoted:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRebindDollar(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"SELECT * FROM plans WHERE id = ?", "SELECT * FROM plans WHERE id = $1"},
+		{"INSERT INTO t (a, b) VALUES (?, ?)", "INSERT INTO t (a, b) VALUES ($1, $2)"},
+		{"WHERE content = '?' AND id = ?", "WHERE content = '?' AND id = $1"},
+		{"WHERE content = 'it''s?' AND (? = '' OR plan_id = ?)", "WHERE content = 'it''s?' AND ($1 = '' OR plan_id = $2)"},
+	}
+	for _, tt := range tests {
+		if got := rebindDollar(tt.query); got != tt.want {
+			t.Errorf("rebindDollar(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	database, err := Open(BackendSQLite, filepath.Join(t.TempDir(), "ralph.db"))
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer database.Close()
+	if got := database.Backend(); got != BackendSQLite {
+		t.Errorf("Backend() = %q, want %q", got, BackendSQLite)
+	}
+
+	if _, err := Open("mysql", "ralph"); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+}
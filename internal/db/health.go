@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// Health describes the state of a database file as found on disk.
+type Health struct {
+	SchemaVersion int      // PRAGMA user_version (0 = never migrated by a versioned release)
+	Problems      []string // Problems reported by PRAGMA integrity_check (empty = ok)
+}
+
+// Inspect opens the database at path read-only, without running migrations,
+// and reports its schema version and integrity.
+func Inspect(ctx context.Context, path string) (*Health, error) {
+	conn, err := sql.Open("sqlite", "file:"+path+"?mode=ro&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if closeErr := conn.Close(); closeErr != nil {
+			log.Warn("failed to close database", "error", closeErr)
+		}
+	}()
+
+	health := &Health{}
+	if health.SchemaVersion, err = schemaVersion(conn); err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `PRAGMA integrity_check`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check integrity: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return nil, err
+		}
+		if result != "ok" {
+			health.Problems = append(health.Problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check integrity: %w", err)
+	}
+	return health, nil
+}
+
+// schemaVersion returns the database's PRAGMA user_version.
+func schemaVersion(conn *sql.DB) (int, error) {
+	var version int
+	err := conn.QueryRow(`PRAGMA user_version`).Scan(&version)
+	return version, err
+}
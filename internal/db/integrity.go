@@ -0,0 +1,146 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// DeletePlans deletes the given plans and everything they own (see
+// planTables) in a single transaction, so no orphaned rows are left behind.
+// Plans that don't exist are skipped.
+func (d *DB) DeletePlans(planIDs []string) error {
+	if len(planIDs) == 0 {
+		return nil
+	}
+
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Warn("failed to rollback delete transaction", "error", err)
+		}
+	}()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(planIDs)), ",")
+	args := make([]any, len(planIDs))
+	for i, id := range planIDs {
+		args[i] = id
+	}
+
+	// Delete children first to satisfy foreign keys
+	for i := len(planTables) - 1; i >= 0; i-- {
+		table := planTables[i]
+		// The filters name the main schema for archiving, which only SQLite has
+		filter := strings.ReplaceAll(fmt.Sprintf(table.filter, placeholders), "main.", "")
+		if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE %s`, table.name, filter), args...); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", table.name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit delete: %w", err)
+	}
+	return nil
+}
+
+// orphanCheck selects the rows of a table whose plan or session no longer
+// exists.
+type orphanCheck struct {
+	table string
+	where string
+}
+
+// Conditions for rows whose plan is gone, and for rows whose session is gone
+// or belongs to a plan that is. Counting a session's rows as orphaned along
+// with it lets a repair delete them before the session.
+const (
+	missingPlan    = "plan_id NOT IN (SELECT id FROM plans)"
+	missingSession = "session_id NOT IN (SELECT id FROM plan_sessions WHERE plan_id IN (SELECT id FROM plans))"
+)
+
+// orphanChecks lists the checks of the plan-owned tables, parents first.
+var orphanChecks = []orphanCheck{
+	{"plan_sessions", missingPlan},
+	{"events", missingSession},
+	{"transcript_messages", missingSession},
+	{"session_sequences", missingSession},
+	{"tool_usage", missingPlan + " OR " + missingSession},
+	{"progress", missingPlan + " OR " + missingSession},
+	{"learnings", missingPlan + " OR " + missingSession},
+	{"reviewer_feedback", missingPlan + " OR " + missingSession},
+	{"feedback_items", missingPlan + " OR " + missingSession},
+	{"analyzer_findings", missingPlan + " OR " + missingSession},
+	{"check_failures", missingPlan + " OR " + missingSession},
+	{"session_environments", missingPlan + " OR " + missingSession},
+	{"review_skips", missingPlan},
+	{"rebuttals", missingPlan + " OR " + missingSession},
+	{"rejected_approaches", missingPlan + " OR " + missingSession},
+	{"iteration_operations", missingPlan},
+	{"plan_workspaces", missingPlan},
+	{"plan_issues", missingPlan},
+	{"plan_runs", missingPlan},
+	{"search_index", missingPlan},
+}
+
+// OrphanCount is the number of orphaned rows found in a table.
+type OrphanCount struct {
+	Table string
+	Rows  int
+}
+
+// AuditOrphans counts the rows of plan-owned tables whose plan or session
+// no longer exists, such as rows left behind by plans deleted by hand.
+// Only tables with orphans are returned, parents first.
+func (d *DB) AuditOrphans() ([]OrphanCount, error) {
+	var counts []OrphanCount
+	for _, check := range orphanChecks {
+		var n int
+		query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s`, check.table, check.where)
+		if err := d.conn.QueryRow(query).Scan(&n); err != nil {
+			return nil, fmt.Errorf("failed to audit %s: %w", check.table, err)
+		}
+		if n > 0 {
+			counts = append(counts, OrphanCount{Table: check.table, Rows: n})
+		}
+	}
+	return counts, nil
+}
+
+// RepairOrphans deletes the rows AuditOrphans reports, children first, in a
+// single transaction. It returns the number of rows deleted.
+func (d *DB) RepairOrphans() (int64, error) {
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Warn("failed to rollback repair transaction", "error", err)
+		}
+	}()
+
+	var deleted int64
+	for i := len(orphanChecks) - 1; i >= 0; i-- {
+		check := orphanChecks[i]
+		result, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE %s`, check.table, check.where))
+		if err != nil {
+			return 0, fmt.Errorf("failed to repair %s: %w", check.table, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to repair %s: %w", check.table, err)
+		}
+		deleted += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit repair: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+// orphanPlan deletes a plan's row with foreign keys disabled, leaving
+// everything it owns behind.
+func orphanPlan(t *testing.T, db *DB, planID string) {
+	t.Helper()
+	ctx := context.Background()
+	conn, err := db.conn.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(ctx, `DELETE FROM plans WHERE id = ?`, planID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeletePlans(t *testing.T) {
+	db := newArchiveTestDB(t, "plan-1", "plan-2")
+
+	if err := db.DeletePlans([]string{"plan-1"}); err != nil {
+		t.Fatalf("DeletePlans() error: %v", err)
+	}
+
+	for _, table := range planTables {
+		if got := countRows(t, db, table.name); got != 1 {
+			t.Errorf("%s: expected only plan-2's row left, got %d", table.name, got)
+		}
+	}
+	if got := countRows(t, db, "search_index"); got != 3 {
+		t.Errorf("expected plan-2's 3 search rows left, got %d", got)
+	}
+	counts, err := db.AuditOrphans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 0 {
+		t.Errorf("expected no orphans after delete, got %+v", counts)
+	}
+}
+
+func TestAuditOrphans_Repair(t *testing.T) {
+	db := newArchiveTestDB(t, "plan-1", "plan-2")
+	orphanPlan(t, db, "plan-1")
+	// The plans trigger clears the search index, so leave a stale row by hand
+	if _, err := db.conn.Exec(`INSERT INTO search_index (kind, plan_id, session_id, content) VALUES ('progress', 'gone', 'gone-session', 'stale')`); err != nil {
+		t.Fatal(err)
+	}
+
+	counts, err := db.AuditOrphans()
+	if err != nil {
+		t.Fatalf("AuditOrphans() error: %v", err)
+	}
+	found := make(map[string]int)
+	for _, c := range counts {
+		found[c.Table] = c.Rows
+	}
+	for _, table := range []string{"plan_sessions", "events", "transcript_messages", "session_sequences", "progress", "rebuttals", "plan_workspaces", "search_index"} {
+		if found[table] == 0 {
+			t.Errorf("expected orphans in %s, got %+v", table, counts)
+		}
+	}
+
+	deleted, err := db.RepairOrphans()
+	if err != nil {
+		t.Fatalf("RepairOrphans() error: %v", err)
+	}
+	if deleted == 0 {
+		t.Error("expected rows to be deleted")
+	}
+	if counts, err := db.AuditOrphans(); err != nil || len(counts) != 0 {
+		t.Errorf("expected no orphans after repair, got %+v (%v)", counts, err)
+	}
+	if got := countRows(t, db, "plan_sessions"); got != 1 {
+		t.Errorf("expected plan-2's session to be kept, got %d sessions", got)
+	}
+}
@@ -1,9 +1,16 @@
 // Package db provides database connectivity and operations for Ralph.
 package db
 
-import "github.com/gerunddev/ralph/internal/log"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 
-// schema is the SQL schema for the Ralph database.
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// schema is the SQL schema for the Ralph database on SQLite.
 const schema = `
 -- Projects table
 CREATE TABLE IF NOT EXISTS projects (
@@ -80,6 +87,12 @@ CREATE TABLE IF NOT EXISTS plans (
     content TEXT NOT NULL,
     status TEXT NOT NULL DEFAULT 'pending',
     base_change_id TEXT NOT NULL DEFAULT '',
+    work_dir TEXT NOT NULL DEFAULT '',
+    failure_reason TEXT NOT NULL DEFAULT '',
+    origin_hash TEXT NOT NULL DEFAULT '',
+    raw_content TEXT NOT NULL DEFAULT '',
+    forked_from TEXT NOT NULL DEFAULT '',
+    parent_plan_id TEXT NOT NULL DEFAULT '',
     created_at DATETIME NOT NULL,
     updated_at DATETIME NOT NULL
 );
@@ -93,6 +106,16 @@ CREATE TABLE IF NOT EXISTS plan_sessions (
     final_output TEXT,
     status TEXT NOT NULL DEFAULT 'running',
     agent_type TEXT NOT NULL DEFAULT 'developer',
+    commit_id TEXT NOT NULL DEFAULT '',
+    model TEXT NOT NULL DEFAULT '',
+    claude_session_id TEXT NOT NULL DEFAULT '',
+    raw_input_prompt TEXT NOT NULL DEFAULT '',
+    superseded BOOLEAN NOT NULL DEFAULT 0,
+    prompt_ms INTEGER NOT NULL DEFAULT 0,
+    claude_ms INTEGER NOT NULL DEFAULT 0,
+    parse_ms INTEGER NOT NULL DEFAULT 0,
+    jj_ms INTEGER NOT NULL DEFAULT 0,
+    reformat_attempts INTEGER NOT NULL DEFAULT 0,
     created_at DATETIME NOT NULL,
     completed_at DATETIME,
     FOREIGN KEY (plan_id) REFERENCES plans(id)
@@ -105,16 +128,57 @@ CREATE TABLE IF NOT EXISTS events (
     sequence INTEGER NOT NULL,
     event_type TEXT NOT NULL,
     raw_json TEXT NOT NULL,
+    sub_agent_id TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (session_id) REFERENCES plan_sessions(id)
+);
+
+-- Transcript messages table (every content block of a plan session's conversation, verbatim)
+CREATE TABLE IF NOT EXISTS transcript_messages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id TEXT NOT NULL,
+    sequence INTEGER NOT NULL,
+    role TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    tool_name TEXT NOT NULL DEFAULT '',
+    tool_use_id TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    is_error BOOLEAN NOT NULL DEFAULT 0,
+    sub_agent_id TEXT NOT NULL DEFAULT '',
     created_at DATETIME NOT NULL,
     FOREIGN KEY (session_id) REFERENCES plan_sessions(id)
 );
 
+-- Session sequences table (the last sequence number assigned in each session;
+-- see NextSequence)
+CREATE TABLE IF NOT EXISTS session_sequences (
+    session_id TEXT PRIMARY KEY,
+    last_sequence INTEGER NOT NULL
+);
+
+-- Tool usage table (per-session summary of the tools Claude called)
+CREATE TABLE IF NOT EXISTS tool_usage (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    plan_id TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    iteration INTEGER NOT NULL,
+    tool_name TEXT NOT NULL,
+    calls INTEGER NOT NULL,
+    files TEXT NOT NULL DEFAULT '[]',
+    commands TEXT NOT NULL DEFAULT '[]',
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (plan_id) REFERENCES plans(id),
+    FOREIGN KEY (session_id) REFERENCES plan_sessions(id)
+);
+
 -- Progress tracking table
 CREATE TABLE IF NOT EXISTS progress (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     plan_id TEXT NOT NULL,
     session_id TEXT NOT NULL,
+    task_id TEXT NOT NULL DEFAULT '',
     content TEXT NOT NULL,
+    superseded BOOLEAN NOT NULL DEFAULT 0,
     created_at DATETIME NOT NULL,
     FOREIGN KEY (plan_id) REFERENCES plans(id),
     FOREIGN KEY (session_id) REFERENCES plan_sessions(id)
@@ -125,7 +189,9 @@ CREATE TABLE IF NOT EXISTS learnings (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     plan_id TEXT NOT NULL,
     session_id TEXT NOT NULL,
+    task_id TEXT NOT NULL DEFAULT '',
     content TEXT NOT NULL,
+    superseded BOOLEAN NOT NULL DEFAULT 0,
     created_at DATETIME NOT NULL,
     FOREIGN KEY (plan_id) REFERENCES plans(id),
     FOREIGN KEY (session_id) REFERENCES plan_sessions(id)
@@ -137,28 +203,249 @@ CREATE TABLE IF NOT EXISTS reviewer_feedback (
     plan_id TEXT NOT NULL,
     session_id TEXT NOT NULL,
     content TEXT NOT NULL,
+    superseded BOOLEAN NOT NULL DEFAULT 0,
+    cleared BOOLEAN NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (plan_id) REFERENCES plans(id),
+    FOREIGN KEY (session_id) REFERENCES plan_sessions(id)
+);
+
+-- Issues raised in reviewer feedback, tracked until the reviewer verifies their fix
+CREATE TABLE IF NOT EXISTS feedback_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    plan_id TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    number INTEGER NOT NULL,
+    severity TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    issued_iteration INTEGER NOT NULL,
+    acknowledged_iteration INTEGER NOT NULL DEFAULT 0,
+    claimed_iteration INTEGER NOT NULL DEFAULT 0,
+    verified_iteration INTEGER NOT NULL DEFAULT 0,
+    withdrawn_iteration INTEGER NOT NULL DEFAULT 0,
+    superseded BOOLEAN NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (plan_id) REFERENCES plans(id),
+    FOREIGN KEY (session_id) REFERENCES plan_sessions(id)
+);
+
+-- Static analyzer output given to a reviewer session
+CREATE TABLE IF NOT EXISTS analyzer_findings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    plan_id TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    analyzer TEXT NOT NULL,
+    output TEXT NOT NULL,
+    failed BOOLEAN NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (plan_id) REFERENCES plans(id),
+    FOREIGN KEY (session_id) REFERENCES plan_sessions(id)
+);
+
+-- Failing output of the checks run after a developer session
+CREATE TABLE IF NOT EXISTS check_failures (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    plan_id TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    output TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (plan_id) REFERENCES plans(id),
+    FOREIGN KEY (session_id) REFERENCES plan_sessions(id)
+);
+
+-- Environment a plan session ran in, to correlate behavior with environment drift
+CREATE TABLE IF NOT EXISTS session_environments (
+    session_id TEXT PRIMARY KEY,
+    plan_id TEXT NOT NULL,
+    claude_version TEXT NOT NULL DEFAULT '',
+    jj_revision TEXT NOT NULL DEFAULT '',
+    go_version TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (plan_id) REFERENCES plans(id),
+    FOREIGN KEY (session_id) REFERENCES plan_sessions(id)
+);
+
+-- Reviews skipped or downgraded because the iteration's change was trivial
+CREATE TABLE IF NOT EXISTS review_skips (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    plan_id TEXT NOT NULL,
+    iteration INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    session_id TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (plan_id) REFERENCES plans(id)
+);
+
+-- Rebuttals: the developer disputed review feedback and a reviewer re-evaluated it
+CREATE TABLE IF NOT EXISTS rebuttals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    plan_id TEXT NOT NULL,
+    iteration INTEGER NOT NULL,
+    feedback_session_id TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    feedback TEXT NOT NULL,
+    rebuttal TEXT NOT NULL,
+    accepted BOOLEAN NOT NULL DEFAULT 0,
+    response TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (plan_id) REFERENCES plans(id),
+    FOREIGN KEY (session_id) REFERENCES plan_sessions(id)
+);
+
+-- Approaches the reviewer rejected or the checks kept failing, so later developers don't retry them
+CREATE TABLE IF NOT EXISTS rejected_approaches (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    plan_id TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    source TEXT NOT NULL,
+    content TEXT NOT NULL,
     created_at DATETIME NOT NULL,
     FOREIGN KEY (plan_id) REFERENCES plans(id),
     FOREIGN KEY (session_id) REFERENCES plan_sessions(id)
 );
 
+-- jj operations repositories were at when iterations started, for undoing an iteration
+CREATE TABLE IF NOT EXISTS iteration_operations (
+    plan_id TEXT NOT NULL,
+    iteration INTEGER NOT NULL,
+    operation_id TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (plan_id, iteration),
+    FOREIGN KEY (plan_id) REFERENCES plans(id)
+);
+
+-- jj workspaces plans run in, apart from the repository's default working copy
+CREATE TABLE IF NOT EXISTS plan_workspaces (
+    plan_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    path TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (plan_id) REFERENCES plans(id)
+);
+
+-- Issue-tracker issues that follow plans
+CREATE TABLE IF NOT EXISTS plan_issues (
+    plan_id TEXT PRIMARY KEY,
+    provider TEXT NOT NULL,
+    issue_key TEXT NOT NULL,
+    url TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (plan_id) REFERENCES plans(id)
+);
+
+-- ralph processes running plans, to tell a live run from a dead one
+CREATE TABLE IF NOT EXISTS plan_runs (
+    plan_id TEXT PRIMARY KEY,
+    host TEXT NOT NULL,
+    pid INTEGER NOT NULL,
+    started_at DATETIME NOT NULL,
+    FOREIGN KEY (plan_id) REFERENCES plans(id)
+);
+
 -- Plan-related indexes
 CREATE INDEX IF NOT EXISTS idx_plan_sessions_plan ON plan_sessions(plan_id);
 CREATE INDEX IF NOT EXISTS idx_events_session ON events(session_id);
+CREATE INDEX IF NOT EXISTS idx_transcript_messages_session ON transcript_messages(session_id);
+CREATE INDEX IF NOT EXISTS idx_tool_usage_plan ON tool_usage(plan_id);
 CREATE INDEX IF NOT EXISTS idx_progress_plan ON progress(plan_id);
 CREATE INDEX IF NOT EXISTS idx_learnings_plan ON learnings(plan_id);
 CREATE INDEX IF NOT EXISTS idx_reviewer_feedback_plan ON reviewer_feedback(plan_id);
+CREATE INDEX IF NOT EXISTS idx_feedback_items_plan ON feedback_items(plan_id);
+CREATE INDEX IF NOT EXISTS idx_analyzer_findings_session ON analyzer_findings(session_id);
+CREATE INDEX IF NOT EXISTS idx_check_failures_session ON check_failures(session_id);
+CREATE INDEX IF NOT EXISTS idx_session_environments_plan ON session_environments(plan_id);
+CREATE INDEX IF NOT EXISTS idx_review_skips_plan ON review_skips(plan_id);
+CREATE INDEX IF NOT EXISTS idx_rebuttals_plan ON rebuttals(plan_id);
+CREATE INDEX IF NOT EXISTS idx_rejected_approaches_plan ON rejected_approaches(plan_id);
+
+-- Repo-wide learnings promoted from plans (outlive the plans that produced them)
+CREATE TABLE IF NOT EXISTS global_learnings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    repo_root TEXT NOT NULL,
+    plan_id TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_global_learnings_repo ON global_learnings(repo_root);
+
+-- Rate-limit cooldowns: Claude sessions wait until resume_at before starting
+CREATE TABLE IF NOT EXISTS rate_limit_cooldowns (
+    provider TEXT PRIMARY KEY,
+    resume_at DATETIME NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    updated_at DATETIME NOT NULL
+);
+
+-- Throttle token buckets shared by every run against the database
+CREATE TABLE IF NOT EXISTS throttle_buckets (
+    name TEXT PRIMARY KEY,
+    tokens REAL NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+-- Full-text index over progress, learnings, and reviewer feedback history.
+-- Rows are added by triggers and kept after feedback is cleared, so old
+-- review comments stay searchable until their plan is deleted.
+CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+    kind UNINDEXED,
+    plan_id UNINDEXED,
+    session_id UNINDEXED,
+    content
+);
+
+CREATE TRIGGER IF NOT EXISTS trg_progress_search AFTER INSERT ON progress BEGIN
+    INSERT INTO search_index (kind, plan_id, session_id, content)
+    VALUES ('progress', new.plan_id, new.session_id, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_learnings_search AFTER INSERT ON learnings BEGIN
+    INSERT INTO search_index (kind, plan_id, session_id, content)
+    VALUES ('learnings', new.plan_id, new.session_id, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_reviewer_feedback_search AFTER INSERT ON reviewer_feedback BEGIN
+    INSERT INTO search_index (kind, plan_id, session_id, content)
+    VALUES ('feedback', new.plan_id, new.session_id, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_plans_search_delete AFTER DELETE ON plans BEGIN
+    DELETE FROM search_index WHERE plan_id = old.id;
+END;
 `
 
+// SchemaVersion is recorded once migrations have run (in PRAGMA user_version
+// on SQLite, the ralph_schema_version table on Postgres), so tools like
+// `ralph doctor` can tell which release last migrated a database. Bump it
+// whenever the schema or a migration changes.
+const SchemaVersion = 24
+
 // Migrate runs all database migrations to ensure the schema is up to date.
 func (d *DB) Migrate() error {
+	unlock, err := d.conn.dialect.lockMigrations(context.Background(), d.conn.DB)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// Create tables if they don't exist
-	if _, err := d.conn.Exec(schema); err != nil {
+	if _, err := d.conn.Exec(d.conn.dialect.schema()); err != nil {
 		return err
 	}
 
 	// Run incremental migrations for existing databases
-	return d.runMigrations()
+	if err := d.runMigrations(); err != nil {
+		return err
+	}
+
+	// Never downgrade the version recorded by a newer release
+	version, err := d.conn.dialect.schemaVersion(d.conn)
+	if err != nil || version >= SchemaVersion {
+		return err
+	}
+	return d.conn.dialect.setSchemaVersion(d.conn, SchemaVersion)
 }
 
 // runMigrations applies incremental schema changes for existing databases.
@@ -207,32 +494,290 @@ func (d *DB) runMigrations() error {
 		}
 	}
 
+	// Migration: Add task_id columns so progress and learnings can be scoped to a task
+	for _, table := range []string{"progress", "learnings"} {
+		if exists, err := d.columnExists(table, "task_id"); err != nil {
+			return err
+		} else if !exists {
+			if _, err := d.conn.Exec(`
+				ALTER TABLE ` + table + ` ADD COLUMN task_id TEXT NOT NULL DEFAULT '';
+			`); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Migration: Add commit_id column to plan_sessions for per-iteration diff churn
+	if exists, err := d.columnExists("plan_sessions", "commit_id"); err != nil {
+		return err
+	} else if !exists {
+		if _, err := d.conn.Exec(`
+			ALTER TABLE plan_sessions ADD COLUMN commit_id TEXT NOT NULL DEFAULT '';
+		`); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Add work_dir column to plans so resumed plans run in their original repository
+	if exists, err := d.columnExists("plans", "work_dir"); err != nil {
+		return err
+	} else if !exists {
+		if _, err := d.conn.Exec(`
+			ALTER TABLE plans ADD COLUMN work_dir TEXT NOT NULL DEFAULT '';
+		`); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Add failure_reason column to plans so stopped and failed plans say why
+	if exists, err := d.columnExists("plans", "failure_reason"); err != nil {
+		return err
+	} else if !exists {
+		if _, err := d.conn.Exec(`
+			ALTER TABLE plans ADD COLUMN failure_reason TEXT NOT NULL DEFAULT '';
+		`); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Add origin_hash column to plans so edits to the plan file during a run are detected
+	if exists, err := d.columnExists("plans", "origin_hash"); err != nil {
+		return err
+	} else if !exists {
+		if _, err := d.conn.Exec(`
+			ALTER TABLE plans ADD COLUMN origin_hash TEXT NOT NULL DEFAULT '';
+		`); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Add forked_from column to plans to link forks to the plan they were forked from
+	if exists, err := d.columnExists("plans", "forked_from"); err != nil {
+		return err
+	} else if !exists {
+		if _, err := d.conn.Exec(`
+			ALTER TABLE plans ADD COLUMN forked_from TEXT NOT NULL DEFAULT '';
+		`); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Add parent_plan_id column to plans to link sub-plans to the
+	// plan that proposed them
+	if exists, err := d.columnExists("plans", "parent_plan_id"); err != nil {
+		return err
+	} else if !exists {
+		if _, err := d.conn.Exec(`
+			ALTER TABLE plans ADD COLUMN parent_plan_id TEXT NOT NULL DEFAULT '';
+		`); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Add sub_agent_id columns to events and transcript_messages to
+	// tag activity of sub-agents spawned through the Task tool
+	for _, table := range []string{"events", "transcript_messages"} {
+		if exists, err := d.columnExists(table, "sub_agent_id"); err != nil {
+			return err
+		} else if !exists {
+			if _, err := d.conn.Exec(`ALTER TABLE ` + table + ` ADD COLUMN sub_agent_id TEXT NOT NULL DEFAULT ''`); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Migration: Add superseded columns so resuming from an earlier
+	// iteration keeps later records, and a cleared column so addressed
+	// reviewer feedback is kept rather than deleted
+	for _, col := range []struct{ table, column string }{
+		{"plan_sessions", "superseded"},
+		{"progress", "superseded"},
+		{"learnings", "superseded"},
+		{"reviewer_feedback", "superseded"},
+		{"reviewer_feedback", "cleared"},
+	} {
+		if exists, err := d.columnExists(col.table, col.column); err != nil {
+			return err
+		} else if !exists {
+			if _, err := d.conn.Exec(`ALTER TABLE ` + col.table + ` ADD COLUMN ` + col.column + ` BOOLEAN NOT NULL DEFAULT FALSE`); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Migration: Add stage duration columns to plan_sessions
+	for _, column := range []string{"prompt_ms", "claude_ms", "parse_ms", "jj_ms"} {
+		if exists, err := d.columnExists("plan_sessions", column); err != nil {
+			return err
+		} else if !exists {
+			if _, err := d.conn.Exec(`ALTER TABLE plan_sessions ADD COLUMN ` + column + ` INTEGER NOT NULL DEFAULT 0`); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Migration: Add the reformat attempt counter to plan_sessions
+	if exists, err := d.columnExists("plan_sessions", "reformat_attempts"); err != nil {
+		return err
+	} else if !exists {
+		if _, err := d.conn.Exec(`ALTER TABLE plan_sessions ADD COLUMN reformat_attempts INTEGER NOT NULL DEFAULT 0`); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Add the model that served each session to plan_sessions
+	if exists, err := d.columnExists("plan_sessions", "model"); err != nil {
+		return err
+	} else if !exists {
+		if _, err := d.conn.Exec(`ALTER TABLE plan_sessions ADD COLUMN model TEXT NOT NULL DEFAULT ''`); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Add the ID Claude gave each session to plan_sessions
+	if exists, err := d.columnExists("plan_sessions", "claude_session_id"); err != nil {
+		return err
+	} else if !exists {
+		if _, err := d.conn.Exec(`ALTER TABLE plan_sessions ADD COLUMN claude_session_id TEXT NOT NULL DEFAULT ''`); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Keep the model each session ran on in plan_sessions alone,
+	// moving the one session_environments recorded from the init event there
+	if exists, err := d.columnExists("session_environments", "model"); err != nil {
+		return err
+	} else if exists {
+		if _, err := d.conn.Exec(`
+			UPDATE plan_sessions SET model = (
+				SELECT e.model FROM session_environments e WHERE e.session_id = plan_sessions.id
+			) WHERE id IN (SELECT session_id FROM session_environments WHERE model <> '')
+		`); err != nil {
+			return err
+		}
+		if _, err := d.conn.Exec(`ALTER TABLE session_environments DROP COLUMN model`); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Keep the unredacted plan and prompts, encrypted, beside the
+	// redacted ones
+	for _, col := range []struct{ table, column string }{
+		{"plans", "raw_content"},
+		{"plan_sessions", "raw_input_prompt"},
+	} {
+		if exists, err := d.columnExists(col.table, col.column); err != nil {
+			return err
+		} else if !exists {
+			if _, err := d.conn.Exec(`ALTER TABLE ` + col.table + ` ADD COLUMN ` + col.column + ` TEXT NOT NULL DEFAULT ''`); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Migration: Number events and messages uniquely within their sessions.
+	// Writers used to pick sequence numbers themselves, so databases written
+	// before NextSequence may hold duplicates, renumbered before the unique
+	// indexes can be created.
+	version, err := d.conn.dialect.schemaVersion(d.conn)
+	if err != nil {
+		return err
+	}
+	for _, table := range sequencedTables {
+		if version < 17 {
+			if err := d.renumberDuplicateSequences(table); err != nil {
+				return fmt.Errorf("failed to renumber %s: %w", table, err)
+			}
+		}
+		if _, err := d.conn.Exec(fmt.Sprintf(
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_%s_session_sequence ON %s(session_id, sequence)`, table, table,
+		)); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Backfill the search index for databases created before it existed
+	var indexed int
+	if err := d.conn.QueryRow(`SELECT COUNT(*) FROM (SELECT 1 FROM search_index LIMIT 1) AS s`).Scan(&indexed); err != nil {
+		return err
+	}
+	if indexed == 0 {
+		if _, err := d.conn.Exec(`
+			INSERT INTO search_index (kind, plan_id, session_id, content)
+			SELECT 'progress', plan_id, session_id, content FROM progress
+			UNION ALL
+			SELECT 'learnings', plan_id, session_id, content FROM learnings
+			UNION ALL
+			SELECT 'feedback', plan_id, session_id, content FROM reviewer_feedback;
+		`); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-// columnExists checks if a column exists in the specified table.
-func (d *DB) columnExists(table, column string) (bool, error) {
-	rows, err := d.conn.Query("PRAGMA table_info(" + table + ")")
+// sequencedTables lists the tables whose rows are numbered per session by
+// NextSequence.
+var sequencedTables = []string{"messages", "events", "transcript_messages"}
+
+// renumberDuplicateSequences renumbers the rows of every session of a table
+// that has duplicate sequence numbers from 0, in their existing order.
+func (d *DB) renumberDuplicateSequences(table string) error {
+	rows, err := d.conn.Query(fmt.Sprintf(`
+		SELECT id, session_id FROM %[1]s
+		WHERE session_id IN (
+			SELECT session_id FROM %[1]s GROUP BY session_id, sequence HAVING COUNT(*) > 1
+		)
+		ORDER BY session_id, sequence, id`, table))
 	if err != nil {
-		return false, err
+		return err
 	}
-	defer func() {
-		if closeErr := rows.Close(); closeErr != nil {
-			log.Warn("failed to close rows", "operation", "columnExists", "error", closeErr)
+	type numbered struct {
+		id       int64
+		sequence int
+	}
+	var renumbered []numbered
+	var session string
+	next := 0
+	for rows.Next() {
+		var id int64
+		var sessionID string
+		if err := rows.Scan(&id, &sessionID); err != nil {
+			_ = rows.Close()
+			return err
 		}
-	}()
+		if sessionID != session {
+			session, next = sessionID, 0
+		}
+		renumbered = append(renumbered, numbered{id, next})
+		next++
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if err := rows.Err(); err != nil || len(renumbered) == 0 {
+		return err
+	}
 
-	for rows.Next() {
-		var cid int
-		var name, ctype string
-		var notnull, pk int
-		var dfltValue interface{}
-		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dfltValue, &pk); err != nil {
-			return false, err
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Warn("failed to rollback renumber transaction", "error", err)
 		}
-		if name == column {
-			return true, nil
+	}()
+	for _, row := range renumbered {
+		if _, err := tx.Exec(fmt.Sprintf(`UPDATE %s SET sequence = ? WHERE id = ?`, table), row.sequence, row.id); err != nil {
+			return err
 		}
 	}
-	return false, rows.Err()
+	return tx.Commit()
+}
+
+// columnExists checks if a column exists in the specified table.
+func (d *DB) columnExists(table, column string) (bool, error) {
+	return d.conn.dialect.columnExists(d.conn, table, column)
 }
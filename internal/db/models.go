@@ -1,7 +1,10 @@
 // Package db provides database connectivity and operations for Ralph.
 package db
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 // ProjectStatus represents the status of a project.
 type ProjectStatus string
@@ -133,11 +136,33 @@ type PlanStatus string
 const (
 	PlanStatusPending   PlanStatus = "pending"
 	PlanStatusRunning   PlanStatus = "running"
-	PlanStatusCompleted PlanStatus = "completed"
-	PlanStatusFailed    PlanStatus = "failed"
-	PlanStatusStopped   PlanStatus = "stopped"
+	PlanStatusPaused    PlanStatus = "paused"    // Run interrupted (e.g. Ctrl+C); resume with --resume
+	PlanStatusCompleted PlanStatus = "completed" // Developer and reviewer agreed the plan is done
+	PlanStatusFailed    PlanStatus = "failed"    // Run ended with an error
+	PlanStatusStopped   PlanStatus = "stopped"   // Hit a limit (max iterations, no progress)
+	PlanStatusCancelled PlanStatus = "cancelled" // Given up on by the user
+	PlanStatusAbandoned PlanStatus = "abandoned" // Its run exited without recording an outcome
 )
 
+// planTransitions lists the statuses each plan status may move to. Any
+// finished plan can be resumed, which moves it back to running.
+var planTransitions = map[PlanStatus][]PlanStatus{
+	PlanStatusPending:   {PlanStatusRunning, PlanStatusCancelled},
+	PlanStatusRunning:   {PlanStatusPaused, PlanStatusCompleted, PlanStatusFailed, PlanStatusStopped, PlanStatusAbandoned},
+	PlanStatusPaused:    {PlanStatusRunning, PlanStatusCancelled},
+	PlanStatusCompleted: {PlanStatusRunning},
+	PlanStatusFailed:    {PlanStatusRunning, PlanStatusCancelled},
+	PlanStatusStopped:   {PlanStatusRunning, PlanStatusCancelled},
+	PlanStatusCancelled: {PlanStatusRunning},
+	PlanStatusAbandoned: {PlanStatusRunning},
+}
+
+// CanTransitionTo reports whether a plan may move from s to next. Staying in
+// the same status is always allowed.
+func (s PlanStatus) CanTransitionTo(next PlanStatus) bool {
+	return s == next || slices.Contains(planTransitions[s], next)
+}
+
 // PlanSessionStatus represents the status of a plan session.
 type PlanSessionStatus string
 
@@ -151,19 +176,44 @@ const (
 type LoopAgentType string
 
 const (
-	LoopAgentDeveloper LoopAgentType = "developer"
-	LoopAgentReviewer  LoopAgentType = "reviewer"
+	LoopAgentDeveloper        LoopAgentType = "developer"
+	LoopAgentReviewer         LoopAgentType = "reviewer"
+	LoopAgentPlanner          LoopAgentType = "planner"
+	LoopAgentConflictResolver LoopAgentType = "conflict_resolver"
+	LoopAgentRebuttalReviewer LoopAgentType = "rebuttal_reviewer"
+	LoopAgentReformatter      LoopAgentType = "reformatter"
+	LoopAgentFailureTriager   LoopAgentType = "failure_triager"
+	LoopAgentDiffSummarizer   LoopAgentType = "diff_summarizer"
 )
 
 // Plan represents a plan to be executed.
 type Plan struct {
-	ID           string
-	OriginPath   string
-	Content      string
-	Status       PlanStatus
-	BaseChangeID string // jj change ID captured at plan start, used for cumulative reviewer diffs
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	ID            string
+	OriginPath    string
+	Content       string
+	Status        PlanStatus
+	BaseChangeID  string // jj change ID captured at plan start, used for cumulative reviewer diffs
+	WorkDir       string // Absolute directory the plan runs in (empty for plans created before it was stored)
+	FailureReason string // Why the plan was paused, failed, stopped, cancelled, or abandoned
+	OriginHash    string // SHA-256 of the plan file as last seen, for detecting edits made during a run
+	ForkedFrom    string // ID of the plan this one was forked from (empty if not a fork)
+	ParentPlanID  string // ID of the plan whose developer proposed this one as a sub-plan (empty if not a sub-plan)
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+
+	// RawContent is the content before secrets were masked, kept encrypted
+	// only when a cipher is set and masking changed it (empty otherwise).
+	// Only GetPlan reads it.
+	RawContent string
+}
+
+// RunContent returns the content to run the plan with: as written when it
+// was kept, otherwise as stored.
+func (p *Plan) RunContent() string {
+	if p.RawContent != "" {
+		return p.RawContent
+	}
+	return p.Content
 }
 
 // PlanSession represents a Claude session linked to a plan.
@@ -175,17 +225,87 @@ type PlanSession struct {
 	FinalOutput string
 	Status      PlanSessionStatus
 	AgentType   LoopAgentType // "developer" or "reviewer"
+	CommitID    string        // jj commit ID of the working copy when a developer session ended (empty if not recorded)
+	Model       string        // Model that served the session, after any fallback (empty if not recorded or the CLI's default)
+	Superseded  bool          // Replaced by resuming the plan from an earlier iteration
+	Timings     SessionTimings
+
+	// ClaudeSessionID is the ID Claude gave the session (empty until its
+	// init event arrives).
+	ClaudeSessionID string
+
+	// ReformatAttempts counts the follow-ups asking for the session's
+	// malformed output to be reformatted into the required sections.
+	ReformatAttempts int
+
+	// RawInputPrompt is the prompt before secrets were masked, kept like
+	// Plan.RawContent.
+	RawInputPrompt string
+
 	CreatedAt   time.Time
 	CompletedAt *time.Time
 }
 
+// RunInputPrompt returns the prompt the session was run with: as built when
+// it was kept, otherwise as stored.
+func (s *PlanSession) RunInputPrompt() string {
+	if s.RawInputPrompt != "" {
+		return s.RawInputPrompt
+	}
+	return s.InputPrompt
+}
+
+// SessionTimings are how long the stages of a plan session took (zero
+// until recorded).
+type SessionTimings struct {
+	Prompt time.Duration // Building the prompt
+	Claude time.Duration // Claude's wall time, including retries
+	Parse  time.Duration // Parsing the output
+	JJ     time.Duration // jj operations while the session ran
+}
+
+// Total returns the time spent in all stages.
+func (t SessionTimings) Total() time.Duration {
+	return t.Prompt + t.Claude + t.Parse + t.JJ
+}
+
 // Event represents a stream event from Claude.
 type Event struct {
+	ID         int64
+	SessionID  string
+	Sequence   int
+	EventType  string
+	RawJSON    string
+	SubAgentID string // Task tool call that spawned the sub-agent, "" for the agent itself
+	CreatedAt  time.Time
+}
+
+// TranscriptMessage is one content block of a plan session's conversation:
+// message text, a tool call, or a tool result.
+type TranscriptMessage struct {
+	ID         int64
+	SessionID  string
+	Sequence   int
+	Role       string // "assistant" or "user"
+	Kind       string // "text", "tool_use", or "tool_result"
+	ToolName   string // Tool called (tool_use only)
+	ToolUseID  string // Tool call the entry belongs to (tool_use and tool_result)
+	Content    string // Text, tool input JSON, or tool output
+	IsError    bool   // Tool reported an error (tool_result only)
+	SubAgentID string // Task tool call that spawned the sub-agent, "" for the agent itself
+	CreatedAt  time.Time
+}
+
+// ToolUsage summarizes one tool's calls during a plan session.
+type ToolUsage struct {
 	ID        int64
+	PlanID    string
 	SessionID string
-	Sequence  int
-	EventType string
-	RawJSON   string
+	Iteration int
+	ToolName  string
+	Calls     int
+	Files     []string // Files the tool touched, in first-use order
+	Commands  []string // Shell commands run (Bash only)
 	CreatedAt time.Time
 }
 
@@ -194,6 +314,7 @@ type Progress struct {
 	ID        int64
 	PlanID    string
 	SessionID string
+	TaskID    string // Task the entry belongs to (empty when the plan has no tasks)
 	Content   string
 	CreatedAt time.Time
 }
@@ -203,15 +324,216 @@ type Learnings struct {
 	ID        int64
 	PlanID    string
 	SessionID string
+	TaskID    string // Task the entry belongs to (empty when the plan has no tasks)
+	Content   string
+	CreatedAt time.Time
+}
+
+// GlobalLearning is a learning promoted from a plan to apply repo-wide.
+type GlobalLearning struct {
+	ID        int64
+	RepoRoot  string // Absolute path of the repository root
+	PlanID    string // Plan that promoted the learning
 	Content   string
 	CreatedAt time.Time
 }
 
+// RateLimitCooldown is a window during which Claude sessions wait for a
+// provider's rate limit to reset.
+type RateLimitCooldown struct {
+	Provider  string // Rate-limited provider, e.g. "claude"
+	ResumeAt  time.Time
+	Reason    string // Error that started the cooldown
+	UpdatedAt time.Time
+}
+
+// Throttle buckets shared by every run against a database.
+const (
+	ThrottleSessions = "claude_sessions" // Claude sessions started
+	ThrottleCost     = "claude_cost"     // Claude spend in USD
+)
+
+// SearchResult is a progress, learnings, or reviewer feedback entry that
+// matched a full-text search.
+type SearchResult struct {
+	Kind      string // "progress", "learnings", or "feedback"
+	PlanID    string
+	SessionID string
+	Iteration int    // Iteration of the session that produced the entry (0 if unknown)
+	Snippet   string // Matching excerpt with hits wrapped in [ ]
+}
+
 // ReviewerFeedback represents feedback from a reviewer rejection.
 type ReviewerFeedback struct {
+	ID         int64
+	PlanID     string
+	SessionID  string // The reviewer session that generated the feedback
+	Content    string
+	Cleared    bool // Addressed by a later developer session
+	Superseded bool // Replaced by resuming the plan from an earlier iteration
+	CreatedAt  time.Time
+}
+
+// FeedbackItemState is where a feedback item is in its lifecycle.
+type FeedbackItemState string
+
+const (
+	FeedbackItemIssued       FeedbackItemState = "issued"        // Raised by a reviewer
+	FeedbackItemAcknowledged FeedbackItemState = "acknowledged"  // Shown to a developer session
+	FeedbackItemClaimed      FeedbackItemState = "claimed-fixed" // The developer says it is fixed
+	FeedbackItemVerified     FeedbackItemState = "verified"      // A reviewer verified the fix; closed
+	FeedbackItemWithdrawn    FeedbackItemState = "withdrawn"     // Withdrawn after the developer's rebuttal; closed
+)
+
+// FeedbackItem is one issue raised in reviewer feedback, tracked across
+// iterations until a reviewer verifies its fix. Each step of its lifecycle
+// records the iteration it happened in (0 = not yet), so rewinding the plan
+// can take it back.
+type FeedbackItem struct {
+	ID                    int64
+	PlanID                string
+	SessionID             string // The reviewer session that raised the item
+	Number                int    // The item's number within the plan, which agents refer to it by
+	Severity              string // "Critical", "Major", or "Minor"; empty when the reviewer gave none
+	Content               string
+	IssuedIteration       int
+	AcknowledgedIteration int
+	ClaimedIteration      int
+	VerifiedIteration     int
+	WithdrawnIteration    int
+	Superseded            bool // Replaced by resuming the plan from an earlier iteration
+	CreatedAt             time.Time
+}
+
+// State returns the furthest step of the item's lifecycle.
+func (i *FeedbackItem) State() FeedbackItemState {
+	switch {
+	case i.WithdrawnIteration > 0:
+		return FeedbackItemWithdrawn
+	case i.VerifiedIteration > 0:
+		return FeedbackItemVerified
+	case i.ClaimedIteration > 0:
+		return FeedbackItemClaimed
+	case i.AcknowledgedIteration > 0:
+		return FeedbackItemAcknowledged
+	}
+	return FeedbackItemIssued
+}
+
+// Open reports whether the item still needs work or verification.
+func (i *FeedbackItem) Open() bool {
+	return i.VerifiedIteration == 0 && i.WithdrawnIteration == 0
+}
+
+// AnalyzerFinding is the output of a static analyzer given to a reviewer.
+type AnalyzerFinding struct {
+	ID        int64
+	PlanID    string
+	SessionID string // The reviewer session the findings were given to
+	Analyzer  string
+	Output    string
+	Failed    bool // The analyzer exited non-zero or could not be run
+	CreatedAt time.Time
+}
+
+// CheckFailure is the output of a check that failed after a developer
+// session, given to the next developer session.
+type CheckFailure struct {
+	ID        int64
+	PlanID    string
+	SessionID string // The developer session whose changes failed the check
+	Name      string
+	Output    string // Trimmed to the failures
+	CreatedAt time.Time
+}
+
+// Actions taken on the review of an iteration whose change was trivial.
+const (
+	ReviewSkipActionSkip      = "skip"      // The review was not run
+	ReviewSkipActionDowngrade = "downgrade" // A cheaper reviewer reviewed the change
+)
+
+// ReviewSkip records a review skipped or downgraded because the iteration's
+// change was trivial.
+type ReviewSkip struct {
 	ID        int64
 	PlanID    string
-	SessionID string // The reviewer session that generated the feedback
+	Iteration int
+	Action    string // ReviewSkipActionSkip or ReviewSkipActionDowngrade
+	Reason    string // Why the change was trivial
+	SessionID string // The downgraded reviewer session ("" when skipped)
+	CreatedAt time.Time
+}
+
+// PlanWorkspace is the jj workspace a plan runs in, so plans sharing a
+// repository don't share a working copy.
+type PlanWorkspace struct {
+	PlanID    string
+	Name      string // jj workspace name
+	Path      string // Absolute directory of the workspace's working copy
+	CreatedAt time.Time
+}
+
+// PlanIssue is the issue-tracker issue that follows a plan.
+type PlanIssue struct {
+	PlanID    string
+	Provider  string // Tracker the issue is in, e.g. "github"
+	Key       string // Issue number or key, e.g. "42" or "PROJ-42"
+	URL       string // Web URL of the issue
+	CreatedAt time.Time
+}
+
+// PlanRun is the ralph process running a plan.
+type PlanRun struct {
+	PlanID    string
+	Host      string // Hostname of the machine the process runs on
+	PID       int
+	StartedAt time.Time
+}
+
+// Rebuttal records the developer disputing review feedback and a reviewer
+// re-evaluating it.
+type Rebuttal struct {
+	ID                int64
+	PlanID            string
+	Iteration         int
+	FeedbackSessionID string // The reviewer session whose feedback was disputed
+	SessionID         string // The rebuttal reviewer session that re-evaluated it
+	Feedback          string
+	Rebuttal          string
+	Accepted          bool   // The reviewer withdrew the feedback
+	Response          string // The reviewer's reasoning
+	CreatedAt         time.Time
+}
+
+// RejectedApproachSource is what rejected an approach.
+type RejectedApproachSource string
+
+// Rejected approach sources.
+const (
+	RejectedByReviewer RejectedApproachSource = "reviewer" // An issue the reviewer raised
+	RejectedByChecks   RejectedApproachSource = "checks"   // Checks that kept failing the same way
+)
+
+// RejectedApproach is an approach a developer took that was rejected, kept
+// so later developer sessions don't retry it.
+type RejectedApproach struct {
+	ID        int64
+	PlanID    string
+	SessionID string // The session that rejected it: a reviewer's, or the developer's whose changes failed the checks
+	Source    RejectedApproachSource
 	Content   string
 	CreatedAt time.Time
 }
+
+// SessionEnvironment is the environment a plan session ran in. Fields that
+// could not be detected are empty.
+type SessionEnvironment struct {
+	SessionID     string
+	PlanID        string
+	ClaudeVersion string // Output of claude --version
+	Model         string // Model that served the session, from plan_sessions
+	JJRevision    string // jj commit ID of the working copy when the session started
+	GoVersion     string // Go version of the target repository's go.mod
+	CreatedAt     time.Time
+}
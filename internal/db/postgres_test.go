@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// newPostgresTestDB connects to the Postgres database named by
+// RALPH_TEST_POSTGRES_URL, skipping the test when it is unset.
+func newPostgresTestDB(t *testing.T) (*DB, string) {
+	t.Helper()
+	url := os.Getenv("RALPH_TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("RALPH_TEST_POSTGRES_URL not set, skipping Postgres test")
+	}
+	db, err := NewPostgres(url)
+	if err != nil {
+		t.Fatalf("NewPostgres() error: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("failed to close test database: %v", err)
+		}
+	})
+	return db, url
+}
+
+func TestPostgres(t *testing.T) {
+	db, url := newPostgresTestDB(t)
+	if got := db.Backend(); got != BackendPostgres {
+		t.Errorf("Backend() = %q, want %q", got, BackendPostgres)
+	}
+
+	planID := "pg-" + uuid.New().String()[:8]
+	sessionID := planID + "-s1"
+	if err := db.CreatePlan(&Plan{ID: planID, OriginPath: "plan.md", Content: "content"}); err != nil {
+		t.Fatalf("CreatePlan() error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: sessionID, PlanID: planID, Iteration: 2, InputPrompt: "prompt"}); err != nil {
+		t.Fatalf("CreatePlanSession() error: %v", err)
+	}
+	progress := &Progress{PlanID: planID, SessionID: sessionID, Content: "Wired the webhook retry queue"}
+	if err := db.CreateProgress(progress); err != nil {
+		t.Fatalf("CreateProgress() error: %v", err)
+	}
+	if progress.ID == 0 {
+		t.Error("expected CreateProgress to set the ID")
+	}
+	if err := db.UpdatePlanStatus(planID, PlanStatusRunning); err != nil {
+		t.Fatalf("UpdatePlanStatus() error: %v", err)
+	}
+	if err := db.UpdatePlanStatusWithReason(planID, PlanStatusFailed, "boom"); err != nil {
+		t.Fatalf("UpdatePlanStatusWithReason() error: %v", err)
+	}
+
+	plan, err := db.GetPlan(planID)
+	if err != nil {
+		t.Fatalf("GetPlan() error: %v", err)
+	}
+	if plan.Status != PlanStatusFailed || plan.FailureReason != "boom" {
+		t.Errorf("plan = %+v, want failed with reason", plan)
+	}
+
+	results, err := db.Search("retry queue", planID, 10)
+	if err != nil {
+		t.Fatalf("Search() error: %v", err)
+	}
+	if len(results) != 1 || results[0].Iteration != 2 {
+		t.Fatalf("Search() = %+v, want the progress entry from iteration 2", results)
+	}
+
+	learning := &GlobalLearning{RepoRoot: "/repo/" + planID, PlanID: planID, Content: "Run make gen"}
+	if created, err := db.CreateGlobalLearning(learning); err != nil || !created {
+		t.Fatalf("CreateGlobalLearning() = %v, %v; want created", created, err)
+	}
+	if created, err := db.CreateGlobalLearning(&GlobalLearning{RepoRoot: learning.RepoRoot, PlanID: planID, Content: "Run make gen"}); err != nil || created {
+		t.Errorf("CreateGlobalLearning(duplicate) = %v, %v; want skipped", created, err)
+	}
+
+	health, err := InspectPostgres(context.Background(), url)
+	if err != nil {
+		t.Fatalf("InspectPostgres() error: %v", err)
+	}
+	if health.SchemaVersion != SchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", health.SchemaVersion, SchemaVersion)
+	}
+
+	if err := db.ArchivePlans("archive.db", []string{planID}); err == nil {
+		t.Error("expected archiving to be unsupported on Postgres")
+	}
+}
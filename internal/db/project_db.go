@@ -8,6 +8,17 @@ import (
 	"github.com/google/uuid"
 )
 
+// PlansDBPath returns the path of the centralized plans database.
+func PlansDBPath(projectsDir string) string {
+	return filepath.Join(projectsDir, "ralph.db")
+}
+
+// ArchiveDBPath returns the default path of the archive database that
+// archived plans are moved to.
+func ArchiveDBPath(projectsDir string) string {
+	return filepath.Join(projectsDir, "archive.db")
+}
+
 // ProjectDBPath returns the database path for a given project ID.
 func ProjectDBPath(projectsDir, projectID string) string {
 	return filepath.Join(projectsDir, projectID, "ralph.db")
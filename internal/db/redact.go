@@ -0,0 +1,23 @@
+package db
+
+import "github.com/gerunddev/ralph/internal/redact"
+
+// SetRedactor masks secrets in plans, prompts, output, events, transcripts,
+// progress, learnings, feedback, and tool commands before they are stored.
+// With a cipher, plans and prompts are also kept unredacted and encrypted,
+// for later runs to execute. Values already stored are not rewritten.
+func (d *DB) SetRedactor(r *redact.Redactor) {
+	d.redactor = r
+}
+
+// redactAll returns a copy of values with secrets masked.
+func (d *DB) redactAll(values []string) []string {
+	if d.redactor == nil {
+		return values
+	}
+	redacted := make([]string, len(values))
+	for i, v := range values {
+		redacted[i] = d.redactor.String(v)
+	}
+	return redacted
+}
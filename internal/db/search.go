@@ -0,0 +1,49 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// Search returns history entries matching query, best match first. Each
+// whitespace-separated term must appear; search operators are not interpreted.
+// A non-empty planID restricts the search to that plan.
+func (d *DB) Search(query, planID string, limit int) ([]*SearchResult, error) {
+	match := d.conn.dialect.searchMatch(query)
+	if match == "" {
+		return nil, nil
+	}
+
+	rows, err := d.conn.Query(d.conn.dialect.searchQuery(), match, planID, planID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "error", closeErr)
+		}
+	}()
+
+	var results []*SearchResult
+	for rows.Next() {
+		result := &SearchResult{}
+		if err := rows.Scan(&result.Kind, &result.PlanID, &result.SessionID,
+			&result.Iteration, &result.Snippet); err != nil {
+			return nil, err
+		}
+		result.Snippet = strings.Join(strings.Fields(result.Snippet), " ")
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// ftsQuery quotes each term of a user query so punctuation such as "-" or
+// ":" is matched literally instead of parsed as FTS5 syntax.
+func ftsQuery(query string) string {
+	terms := strings.Fields(query)
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(terms, " ")
+}
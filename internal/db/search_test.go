@@ -0,0 +1,126 @@
+package db
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "content"}); err != nil {
+		t.Fatalf("CreatePlan() error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "s1", PlanID: "plan-1", Iteration: 3, InputPrompt: "prompt"}); err != nil {
+		t.Fatalf("CreatePlanSession() error: %v", err)
+	}
+	if err := db.CreateProgress(&Progress{PlanID: "plan-1", SessionID: "s1", Content: "Wired the webhook retry queue"}); err != nil {
+		t.Fatalf("CreateProgress() error: %v", err)
+	}
+	if err := db.CreateLearnings(&Learnings{PlanID: "plan-1", SessionID: "s1", Content: "The retry queue needs a mutex"}); err != nil {
+		t.Fatalf("CreateLearnings() error: %v", err)
+	}
+	if err := db.CreateReviewerFeedback(&ReviewerFeedback{PlanID: "plan-1", SessionID: "s1", Content: "Missing tests for retry-after"}); err != nil {
+		t.Fatalf("CreateReviewerFeedback() error: %v", err)
+	}
+	// Feedback stays searchable after the developer clears it
+	if err := db.ClearReviewerFeedback("plan-1"); err != nil {
+		t.Fatalf("ClearReviewerFeedback() error: %v", err)
+	}
+
+	results, err := db.Search("retry", "", 10)
+	if err != nil {
+		t.Fatalf("Search() error: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	kinds := map[string]bool{}
+	for _, r := range results {
+		kinds[r.Kind] = true
+		if r.PlanID != "plan-1" || r.Iteration != 3 {
+			t.Errorf("unexpected reference: plan %q iteration %d", r.PlanID, r.Iteration)
+		}
+		if !strings.Contains(r.Snippet, "[retry") {
+			t.Errorf("snippet %q does not highlight the match", r.Snippet)
+		}
+	}
+	for _, kind := range []string{"progress", "learnings", "feedback"} {
+		if !kinds[kind] {
+			t.Errorf("missing %s result", kind)
+		}
+	}
+
+	// All terms must match
+	results, err = db.Search("queue mutex", "", 10)
+	if err != nil {
+		t.Fatalf("Search() error: %v", err)
+	}
+	if len(results) != 1 || results[0].Kind != "learnings" {
+		t.Errorf("expected only the learnings entry, got %+v", results)
+	}
+
+	// Punctuation is matched literally rather than parsed as FTS syntax
+	if _, err := db.Search("retry-after: \"x", "", 10); err != nil {
+		t.Errorf("Search() with punctuation error: %v", err)
+	}
+
+	results, err = db.Search("retry", "other-plan", 10)
+	if err != nil {
+		t.Fatalf("Search() error: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected no results for other plan, got %d", len(results))
+	}
+
+	results, err = db.Search("   ", "", 10)
+	if err != nil || results != nil {
+		t.Errorf("Search() with blank query = %v, %v; want nil, nil", results, err)
+	}
+}
+
+func TestSearch_ArchivedPlansRemoved(t *testing.T) {
+	db := newArchiveTestDB(t, "plan-1", "plan-2")
+
+	if err := db.ArchivePlans(filepath.Join(t.TempDir(), "archive.db"), []string{"plan-1"}); err != nil {
+		t.Fatalf("ArchivePlans() error: %v", err)
+	}
+
+	results, err := db.Search("progress", "", 10)
+	if err != nil {
+		t.Fatalf("Search() error: %v", err)
+	}
+	if len(results) != 1 || results[0].PlanID != "plan-2" {
+		t.Errorf("expected only plan-2 to remain indexed, got %+v", results)
+	}
+}
+
+func TestMigrate_BackfillsSearchIndex(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "content"}); err != nil {
+		t.Fatalf("CreatePlan() error: %v", err)
+	}
+	if err := db.CreatePlanSession(&PlanSession{ID: "s1", PlanID: "plan-1", Iteration: 1, InputPrompt: "prompt"}); err != nil {
+		t.Fatalf("CreatePlanSession() error: %v", err)
+	}
+	if err := db.CreateProgress(&Progress{PlanID: "plan-1", SessionID: "s1", Content: "legacy entry"}); err != nil {
+		t.Fatalf("CreateProgress() error: %v", err)
+	}
+	// Simulate a database that predates the index
+	if _, err := db.conn.Exec(`DELETE FROM search_index`); err != nil {
+		t.Fatalf("failed to clear index: %v", err)
+	}
+
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate() error: %v", err)
+	}
+	results, err := db.Search("legacy", "", 10)
+	if err != nil {
+		t.Fatalf("Search() error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("expected backfilled entry, got %d results", len(results))
+	}
+}
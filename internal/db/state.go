@@ -0,0 +1,425 @@
+package db
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// PlanStateVersion is the format version of exported plan state. Bump it
+// when PlanState changes incompatibly.
+const PlanStateVersion = 1
+
+// columnPattern matches the column names an import may write.
+var columnPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// PlanState is a plan's rows from every table it owns, exported so the plan
+// can be resumed from another database. Sensitive columns are exported
+// decrypted, so the importing database can encrypt them with its own key.
+type PlanState struct {
+	Version       int          `json:"version"`
+	SchemaVersion int          `json:"schema_version"` // SchemaVersion of the exporting database
+	PlanID        string       `json:"plan_id"`
+	ExportedAt    time.Time    `json:"exported_at"`
+	Tables        []StateTable `json:"tables"`
+}
+
+// StateTable holds one table's rows of exported plan state.
+type StateTable struct {
+	Name    string         `json:"name"`
+	Columns []string       `json:"columns"`
+	Rows    [][]StateValue `json:"rows"`
+}
+
+// StateValue is a column value of exported plan state. Times and bytes are
+// tagged in JSON so they keep their types through an export and import.
+type StateValue struct {
+	Value any // nil, bool, int64, float64, string, []byte, or time.Time
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v StateValue) MarshalJSON() ([]byte, error) {
+	switch value := v.Value.(type) {
+	case time.Time:
+		return json.Marshal(map[string]string{"time": value.Format(time.RFC3339Nano)})
+	case []byte:
+		return json.Marshal(map[string]string{"bytes": base64.StdEncoding.EncodeToString(value)})
+	default:
+		return json.Marshal(value)
+	}
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *StateValue) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var raw any
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+
+	var err error
+	switch value := raw.(type) {
+	case json.Number:
+		if v.Value, err = value.Int64(); err != nil {
+			v.Value, err = value.Float64()
+		}
+	case map[string]any:
+		if t, ok := value["time"].(string); ok {
+			v.Value, err = time.Parse(time.RFC3339Nano, t)
+		} else if b, ok := value["bytes"].(string); ok {
+			v.Value, err = base64.StdEncoding.DecodeString(b)
+		} else {
+			err = fmt.Errorf("unknown state value %s", data)
+		}
+	case []any:
+		err = fmt.Errorf("unknown state value %s", data)
+	default:
+		v.Value = value
+	}
+	return err
+}
+
+// ExportPlanState returns the rows of a plan and everything it owns:
+// sessions, events, transcripts, progress, learnings, reviewer feedback,
+// and tasks.
+func (d *DB) ExportPlanState(planID string) (*PlanState, error) {
+	if _, err := d.GetPlan(planID); err != nil {
+		return nil, err
+	}
+
+	state := &PlanState{
+		Version:       PlanStateVersion,
+		SchemaVersion: SchemaVersion,
+		PlanID:        planID,
+		ExportedAt:    time.Now().UTC(),
+	}
+	for _, table := range planTables {
+		// The filters name the main schema for archiving, which only SQLite has
+		filter := strings.ReplaceAll(fmt.Sprintf(table.filter, "?"), "main.", "")
+		query := fmt.Sprintf(`SELECT * FROM %s WHERE %s`, table.name, filter)
+		if table.autoID {
+			query += " ORDER BY id"
+		}
+		exported, err := d.exportTable(table.name, query, planID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", table.name, err)
+		}
+		state.Tables = append(state.Tables, *exported)
+	}
+	return state, nil
+}
+
+// ExportPlanStateAsOf returns the state ExportPlanState would, rewound to the
+// end of the given iteration as RewindPlan would rewind it: later
+// iterations' sessions, and the progress, learnings, and reviewer feedback
+// they recorded, are marked superseded, and the iteration's feedback is
+// pending again. The plan is marked paused, so importing it resumes from
+// there. The database's own copy is left as it is. Plans decomposed into
+// tasks can't be rewound.
+func (d *DB) ExportPlanStateAsOf(planID string, iteration int) (*PlanState, error) {
+	state, err := d.ExportPlanState(planID)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.CheckIteration(planID, iteration); err != nil {
+		return nil, err
+	}
+	if tasks := state.table("tasks"); tasks != nil && len(tasks.Rows) > 0 {
+		return nil, fmt.Errorf("plan %s was decomposed into tasks and can't be exported as of an earlier iteration", planID)
+	}
+
+	// Iterations of the sessions on the plan's current timeline
+	iterations := make(map[any]int64)
+	if sessions := state.table("plan_sessions"); sessions != nil {
+		for _, row := range sessions.Rows {
+			if sessions.truthy(row, "superseded") {
+				continue
+			}
+			n, _ := sessions.value(row, "iteration").(int64)
+			iterations[sessions.value(row, "id")] = n
+			if n > int64(iteration) {
+				sessions.set(row, "superseded", true)
+			}
+		}
+	}
+	for _, name := range []string{"progress", "learnings", "reviewer_feedback"} {
+		t := state.table(name)
+		if t == nil {
+			continue
+		}
+		for _, row := range t.Rows {
+			if t.truthy(row, "superseded") {
+				continue
+			}
+			n, ok := iterations[t.value(row, "session_id")]
+			switch {
+			case ok && n > int64(iteration):
+				t.set(row, "superseded", true)
+			case ok && n == int64(iteration) && name == "reviewer_feedback":
+				t.set(row, "cleared", false)
+			}
+		}
+	}
+	if plans := state.table("plans"); plans != nil {
+		for _, row := range plans.Rows {
+			plans.set(row, "status", string(PlanStatusPaused))
+			plans.set(row, "failure_reason", fmt.Sprintf("Exported as of iteration %d", iteration))
+		}
+	}
+	return state, nil
+}
+
+// table returns the exported table with the given name (nil if absent).
+func (s *PlanState) table(name string) *StateTable {
+	for i := range s.Tables {
+		if s.Tables[i].Name == name {
+			return &s.Tables[i]
+		}
+	}
+	return nil
+}
+
+// value returns a row's value of the named column (nil if there is no such
+// column).
+func (t *StateTable) value(row []StateValue, column string) any {
+	if i := slices.Index(t.Columns, column); i >= 0 {
+		return row[i].Value
+	}
+	return nil
+}
+
+// truthy reports whether a row's boolean column is set, whichever way the
+// database driver returned it.
+func (t *StateTable) truthy(row []StateValue, column string) bool {
+	switch v := t.value(row, column).(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	}
+	return false
+}
+
+// set sets a row's value of the named column, if there is such a column.
+func (t *StateTable) set(row []StateValue, column string, value any) {
+	if i := slices.Index(t.Columns, column); i >= 0 {
+		row[i].Value = value
+	}
+}
+
+// exportTable returns the rows of a table selected by query, with sensitive
+// columns decrypted.
+func (d *DB) exportTable(name, query string, args ...any) (*StateTable, error) {
+	rows, err := d.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "error", closeErr)
+		}
+	}()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	sensitive := sensitiveColumnIndexes(name, columns)
+
+	table := &StateTable{Name: name, Columns: columns, Rows: [][]StateValue{}}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		row := make([]StateValue, len(columns))
+		for i, value := range values {
+			if s, ok := value.(string); ok && slices.Contains(sensitive, i) {
+				// Unredacted plans and prompts stay out of exports
+				if unredactedColumn(name, columns[i]) {
+					s = ""
+				}
+				if value, err = d.unseal(s); err != nil {
+					return nil, err
+				}
+			}
+			row[i] = StateValue{Value: value}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, rows.Err()
+}
+
+// sensitiveColumnIndexes returns the indexes of a table's columns that are
+// encrypted at rest.
+func sensitiveColumnIndexes(table string, columns []string) []int {
+	var indexes []int
+	for _, col := range sensitiveColumns {
+		if col.table != table {
+			continue
+		}
+		if i := slices.Index(columns, col.column); i >= 0 {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// unredactedColumn reports whether a sensitive column holds an unredacted plan or
+// prompt, which is only stored encrypted.
+func unredactedColumn(table, column string) bool {
+	for _, col := range sensitiveColumns {
+		if col.table == table && col.column == column {
+			return col.raw
+		}
+	}
+	return false
+}
+
+// ImportPlanState inserts exported plan state, with the plan's working
+// directory set to workDir, in a single transaction. Generated row IDs are
+// assigned anew. The plan must not exist yet.
+func (d *DB) ImportPlanState(state *PlanState, workDir string) error {
+	if state.Version != PlanStateVersion {
+		return fmt.Errorf("unsupported plan state version %d (supported: %d)", state.Version, PlanStateVersion)
+	}
+	if state.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("plan state was exported by a newer version of ralph (schema %d, this version has %d); upgrade to import it",
+			state.SchemaVersion, SchemaVersion)
+	}
+	for _, t := range state.Tables {
+		if err := checkStateTable(state.PlanID, &t); err != nil {
+			return err
+		}
+	}
+	if _, err := d.GetPlan(state.PlanID); err == nil {
+		return fmt.Errorf("plan %s already exists", state.PlanID)
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Warn("failed to rollback import transaction", "error", err)
+		}
+	}()
+
+	// Insert parents first
+	for _, table := range planTables {
+		for i := range state.Tables {
+			if state.Tables[i].Name != table.name {
+				continue
+			}
+			if err := d.importTable(tx, &state.Tables[i], table.autoID, workDir); err != nil {
+				return fmt.Errorf("failed to import %s: %w", table.name, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
+	}
+	return nil
+}
+
+// checkStateTable verifies that a table of exported plan state is one a
+// plan owns, is well-formed, and only holds rows of the plan.
+func checkStateTable(planID string, t *StateTable) error {
+	if !slices.ContainsFunc(planTables, func(table planTable) bool { return table.name == t.Name }) {
+		return fmt.Errorf("unknown table %q in plan state", t.Name)
+	}
+
+	// Columns naming the plan that owns the row
+	owners := []string{"plan_id", "project_id"}
+	if t.Name == "plans" || t.Name == "projects" {
+		owners = append(owners, "id")
+	}
+	var ownerIndexes []int
+	for i, col := range t.Columns {
+		if !columnPattern.MatchString(col) {
+			return fmt.Errorf("invalid column %q in plan state table %s", col, t.Name)
+		}
+		if slices.Contains(owners, col) {
+			ownerIndexes = append(ownerIndexes, i)
+		}
+	}
+
+	for _, row := range t.Rows {
+		if len(row) != len(t.Columns) {
+			return fmt.Errorf("plan state table %s has a row of %d values for %d columns", t.Name, len(row), len(t.Columns))
+		}
+		for _, i := range ownerIndexes {
+			if row[i].Value != planID {
+				return fmt.Errorf("plan state table %s has a row of another plan (%s %v)", t.Name, t.Columns[i], row[i].Value)
+			}
+		}
+	}
+	return nil
+}
+
+// importTable inserts the rows of one table of exported plan state, leaving
+// out generated IDs, encrypting sensitive columns, and setting the plan's
+// working directory.
+func (d *DB) importTable(tx *tx, t *StateTable, autoID bool, workDir string) error {
+	var columns []string
+	var indexes []int
+	for i, col := range t.Columns {
+		if autoID && col == "id" {
+			continue
+		}
+		columns = append(columns, col)
+		indexes = append(indexes, i)
+	}
+	sensitive := sensitiveColumnIndexes(t.Name, t.Columns)
+	workDirIndex := -1
+	if t.Name == "plans" {
+		workDirIndex = slices.Index(t.Columns, "work_dir")
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s)`, t.Name, strings.Join(columns, ", "),
+		strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+	for _, row := range t.Rows {
+		args := make([]any, len(indexes))
+		for j, i := range indexes {
+			value := row[i].Value
+			if s, ok := value.(string); ok && slices.Contains(sensitive, i) {
+				seal := d.seal
+				if unredactedColumn(t.Name, t.Columns[i]) {
+					seal = d.sealRaw
+				}
+				sealed, err := seal(s)
+				if err != nil {
+					return err
+				}
+				value = sealed
+			}
+			if i == workDirIndex {
+				value = workDir
+			}
+			args[j] = value
+		}
+		if _, err := tx.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
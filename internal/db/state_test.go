@@ -0,0 +1,192 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTripState exports a plan and decodes the JSON of its state, as an
+// import on another machine would.
+func roundTripState(t *testing.T, db *DB, planID string) *PlanState {
+	t.Helper()
+	state, err := db.ExportPlanState(planID)
+	if err != nil {
+		t.Fatalf("ExportPlanState() error: %v", err)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatalf("failed to encode state: %v", err)
+	}
+	var decoded PlanState
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to decode state: %v", err)
+	}
+	return &decoded
+}
+
+func TestPlanState_ExportImport(t *testing.T) {
+	source := newArchiveTestDB(t, "plan-1")
+	state := roundTripState(t, source, "plan-1")
+	if len(state.Tables) != len(planTables) {
+		t.Errorf("expected %d tables, got %d", len(planTables), len(state.Tables))
+	}
+
+	// The target's generated IDs overlap the source's
+	target := newArchiveTestDB(t, "plan-2")
+	if err := target.ImportPlanState(state, "/home/colleague/repo"); err != nil {
+		t.Fatalf("ImportPlanState() error: %v", err)
+	}
+	for _, table := range planTables {
+		if got := countRows(t, target, table.name); got != 2 {
+			t.Errorf("%s: expected 2 rows, got %d", table.name, got)
+		}
+	}
+
+	plan, err := target.GetPlan("plan-1")
+	if err != nil {
+		t.Fatalf("GetPlan() error: %v", err)
+	}
+	if plan.Content != "content" || plan.WorkDir != "/home/colleague/repo" || plan.Status != PlanStatusCompleted {
+		t.Errorf("unexpected imported plan: %+v", plan)
+	}
+	if plan.CreatedAt.IsZero() || time.Since(plan.CreatedAt) > time.Hour {
+		t.Errorf("expected the creation time to carry over, got %v", plan.CreatedAt)
+	}
+	learnings, err := target.GetLatestLearnings("plan-1")
+	if err != nil || learnings == nil || learnings.Content != "learnings" {
+		t.Errorf("expected imported learnings, got %+v (%v)", learnings, err)
+	}
+
+	if err := target.ImportPlanState(state, "/repo"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected importing an existing plan to fail, got: %v", err)
+	}
+}
+
+func TestPlanState_Encrypted(t *testing.T) {
+	source := newArchiveTestDB(t)
+	sourceCipher, err := NewCipher("source-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source.SetCipher(sourceCipher)
+	if err := source.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "secret plan"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Exported decrypted, imported encrypted with the target's key
+	state := roundTripState(t, source, "plan-1")
+	if got := state.Tables[0].Rows[0][2].Value; got != "secret plan" {
+		t.Errorf("expected decrypted content in the state, got %v", got)
+	}
+
+	target := newArchiveTestDB(t)
+	targetCipher, err := NewCipher("target-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target.SetCipher(targetCipher)
+	if err := target.ImportPlanState(state, "/repo"); err != nil {
+		t.Fatalf("ImportPlanState() error: %v", err)
+	}
+	var stored string
+	if err := target.conn.QueryRow(`SELECT content FROM plans WHERE id = ?`, "plan-1").Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(stored) {
+		t.Errorf("expected content encrypted at rest, got %q", stored)
+	}
+	if plan, err := target.GetPlan("plan-1"); err != nil || plan.Content != "secret plan" {
+		t.Errorf("expected readable content, got %+v (%v)", plan, err)
+	}
+}
+
+func TestPlanState_ImportRejects(t *testing.T) {
+	source := newArchiveTestDB(t, "plan-1")
+
+	tests := []struct {
+		name   string
+		modify func(state *PlanState)
+		want   string
+	}{
+		{"newer format", func(s *PlanState) { s.Version = PlanStateVersion + 1 }, "unsupported plan state version"},
+		{"newer schema", func(s *PlanState) { s.SchemaVersion = SchemaVersion + 1 }, "newer version of ralph"},
+		{"unknown table", func(s *PlanState) { s.Tables[0].Name = "global_learnings" }, "unknown table"},
+		{"invalid column", func(s *PlanState) { s.Tables[1].Columns[0] = "id) --" }, "invalid column"},
+		{"other plan's row", func(s *PlanState) { s.Tables[5].Rows[0][1] = StateValue{Value: "plan-2"} }, "another plan"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := roundTripState(t, source, "plan-1")
+			tt.modify(state)
+			target := newArchiveTestDB(t)
+			err := target.ImportPlanState(state, "/repo")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got: %v", tt.want, err)
+			}
+			if got := countRows(t, target, "plans"); got != 0 {
+				t.Errorf("expected nothing imported, got %d plans", got)
+			}
+		})
+	}
+}
+
+func TestPlanState_ExportAsOf(t *testing.T) {
+	source := newTestDB(t)
+	if err := source.CreatePlan(&Plan{ID: "plan-1", OriginPath: "plan.md", Content: "Plan content", Status: PlanStatusCompleted}); err != nil {
+		t.Fatalf("CreatePlan() error: %v", err)
+	}
+	for i := 1; i <= 2; i++ {
+		dev := fmt.Sprintf("dev-%d", i)
+		rev := fmt.Sprintf("rev-%d", i)
+		if err := source.CreatePlanSession(&PlanSession{ID: dev, PlanID: "plan-1", Iteration: i, InputPrompt: "p"}); err != nil {
+			t.Fatalf("CreatePlanSession() error: %v", err)
+		}
+		if err := source.ClearReviewerFeedback("plan-1"); err != nil {
+			t.Fatalf("ClearReviewerFeedback() error: %v", err)
+		}
+		if err := source.CreateProgress(&Progress{PlanID: "plan-1", SessionID: dev, Content: fmt.Sprintf("progress %d", i)}); err != nil {
+			t.Fatalf("CreateProgress() error: %v", err)
+		}
+		if err := source.CreatePlanSession(&PlanSession{ID: rev, PlanID: "plan-1", Iteration: i, InputPrompt: "p", AgentType: LoopAgentReviewer}); err != nil {
+			t.Fatalf("CreatePlanSession() error: %v", err)
+		}
+		if err := source.CreateReviewerFeedback(&ReviewerFeedback{PlanID: "plan-1", SessionID: rev, Content: fmt.Sprintf("feedback %d", i)}); err != nil {
+			t.Fatalf("CreateReviewerFeedback() error: %v", err)
+		}
+		time.Sleep(time.Millisecond) // Keep created_at ordering stable
+	}
+
+	if _, err := source.ExportPlanStateAsOf("plan-1", 3); err == nil {
+		t.Error("ExportPlanStateAsOf() for a future iteration should fail")
+	}
+	state, err := source.ExportPlanStateAsOf("plan-1", 1)
+	if err != nil {
+		t.Fatalf("ExportPlanStateAsOf() error: %v", err)
+	}
+
+	target := newTestDB(t)
+	if err := target.ImportPlanState(state, "/repo"); err != nil {
+		t.Fatalf("ImportPlanState() error: %v", err)
+	}
+	plan, err := target.GetPlan("plan-1")
+	if err != nil || plan.Status != PlanStatusPaused || plan.FailureReason != "Exported as of iteration 1" {
+		t.Errorf("imported plan = %+v, %v; want paused as of iteration 1", plan, err)
+	}
+	if session, err := target.GetLatestPlanSession("plan-1"); err != nil || session == nil || session.Iteration != 1 {
+		t.Errorf("GetLatestPlanSession() = %+v, %v; want iteration 1", session, err)
+	}
+	if progress, err := target.GetLatestProgress("plan-1"); err != nil || progress == nil || progress.Content != "progress 1" {
+		t.Errorf("GetLatestProgress() = %+v, %v; want progress 1", progress, err)
+	}
+	if feedback, err := target.GetLatestReviewerFeedback("plan-1"); err != nil || feedback == nil || feedback.Content != "feedback 1" {
+		t.Errorf("GetLatestReviewerFeedback() = %+v, %v; want feedback 1", feedback, err)
+	}
+
+	// The source is left as it was
+	if progress, err := source.GetLatestProgress("plan-1"); err != nil || progress == nil || progress.Content != "progress 2" {
+		t.Errorf("source GetLatestProgress() = %+v, %v; want progress 2", progress, err)
+	}
+}
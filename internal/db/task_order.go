@@ -0,0 +1,228 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// ErrTaskInProgress is returned when a change would move, split, or merge a
+// task that is being worked on.
+var ErrTaskInProgress = errors.New("task is in progress")
+
+// ReorderTasks renumbers a project's tasks in the order of ids, which must
+// list each of the project's tasks once. An in-progress task keeps its
+// sequence number.
+func (d *DB) ReorderTasks(projectID string, ids []string) error {
+	return d.rewriteTasks(projectID, "ReorderTasks", func(tasks []*Task) ([]*Task, error) {
+		if len(ids) != len(tasks) {
+			return nil, fmt.Errorf("new order lists %d tasks, project %s has %d", len(ids), projectID, len(tasks))
+		}
+		byID := make(map[string]*Task, len(tasks))
+		for _, t := range tasks {
+			byID[t.ID] = t
+		}
+		ordered := make([]*Task, len(ids))
+		for i, id := range ids {
+			t, ok := byID[id]
+			if !ok {
+				return nil, fmt.Errorf("task %s is not in project %s or is listed twice", id, projectID)
+			}
+			delete(byID, id)
+			if t.Status == TaskInProgress && t.Sequence != i+1 {
+				return nil, fmt.Errorf("cannot move task %d: %w", t.Sequence, ErrTaskInProgress)
+			}
+			ordered[i] = t
+		}
+		return ordered, nil
+	})
+}
+
+// SplitTask replaces a task with subtasks, in its place in the project's
+// order. The first subtask keeps the task's ID, so the task's history stays
+// with it. The task must not be in progress or completed.
+func (d *DB) SplitTask(taskID string, subtasks []*Task) error {
+	if len(subtasks) < 2 {
+		return fmt.Errorf("a task must be split into at least 2 subtasks, got %d", len(subtasks))
+	}
+	task, err := d.GetTask(taskID)
+	if err != nil {
+		return err
+	}
+	return d.rewriteTasks(task.ProjectID, "SplitTask", func(tasks []*Task) ([]*Task, error) {
+		var split []*Task
+		for _, t := range tasks {
+			if t.ID != taskID {
+				split = append(split, t)
+				continue
+			}
+			if err := checkTaskEditable(t, "split"); err != nil {
+				return nil, err
+			}
+			first := *t
+			first.Title, first.Description = subtasks[0].Title, subtasks[0].Description
+			split = append(split, &first)
+			for _, sub := range subtasks[1:] {
+				sub.ProjectID = t.ProjectID
+				if sub.Status == "" {
+					sub.Status = TaskPending
+				}
+				split = append(split, sub)
+			}
+		}
+		return split, nil
+	})
+}
+
+// MergeTasks merges a project's task with sequence number second into the
+// one with sequence number first: the first task's description gets the
+// second's title and description appended, and the second is removed.
+// Neither task may be in progress or completed.
+func (d *DB) MergeTasks(projectID string, first, second int) error {
+	if first == second {
+		return fmt.Errorf("cannot merge task %d with itself", first)
+	}
+	return d.rewriteTasks(projectID, "MergeTasks", func(tasks []*Task) ([]*Task, error) {
+		var into, from *Task
+		for _, t := range tasks {
+			switch t.Sequence {
+			case first:
+				into = t
+			case second:
+				from = t
+			}
+		}
+		if into == nil || from == nil {
+			return nil, ErrNotFound
+		}
+		for _, t := range []*Task{into, from} {
+			if err := checkTaskEditable(t, "merge"); err != nil {
+				return nil, err
+			}
+		}
+		into.Description = fmt.Sprintf("%s\n\n## %s\n\n%s", into.Description, from.Title, from.Description)
+
+		merged := make([]*Task, 0, len(tasks)-1)
+		for _, t := range tasks {
+			if t != from {
+				merged = append(merged, t)
+			}
+		}
+		return merged, nil
+	})
+}
+
+// checkTaskEditable returns an error if a task can't be split or merged.
+func checkTaskEditable(t *Task, action string) error {
+	switch t.Status {
+	case TaskInProgress:
+		return fmt.Errorf("cannot %s task %d: %w", action, t.Sequence, ErrTaskInProgress)
+	case TaskCompleted:
+		return fmt.Errorf("cannot %s completed task %d", action, t.Sequence)
+	}
+	return nil
+}
+
+// rewriteTasks replaces a project's tasks, in one transaction, with the
+// ones edit returns for them in sequence order: tasks left out are deleted,
+// new ones are inserted, and all are renumbered from 1 in their new order.
+func (d *DB) rewriteTasks(projectID, operation string, edit func([]*Task) ([]*Task, error)) error {
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && rbErr != sql.ErrTxDone {
+			log.Warn("failed to rollback transaction", "operation", operation, "error", rbErr)
+		}
+	}()
+
+	tasks, err := queryTasks(tx, projectID)
+	if err != nil {
+		return err
+	}
+	if len(tasks) == 0 {
+		return ErrNotFound
+	}
+	existing := make(map[string]bool, len(tasks))
+	for _, t := range tasks {
+		existing[t.ID] = true
+	}
+
+	edited, err := edit(tasks)
+	if err != nil {
+		return err
+	}
+
+	kept := make(map[string]bool, len(edited))
+	for _, t := range edited {
+		kept[t.ID] = true
+	}
+	for _, t := range tasks {
+		if kept[t.ID] {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM tasks WHERE id = ?`, t.ID); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for i, t := range edited {
+		t.Sequence = i + 1
+		t.UpdatedAt = now
+		if existing[t.ID] {
+			_, err = tx.Exec(`
+				UPDATE tasks SET sequence = ?, title = ?, description = ?, updated_at = ? WHERE id = ?`,
+				t.Sequence, t.Title, t.Description, t.UpdatedAt, t.ID,
+			)
+		} else {
+			t.CreatedAt = now
+			_, err = tx.Exec(`
+				INSERT INTO tasks (id, project_id, sequence, title, description, status, jj_change_id, iteration_count, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				t.ID, t.ProjectID, t.Sequence, t.Title, t.Description,
+				t.Status, t.JJChangeID, t.IterationCount,
+				t.CreatedAt, t.UpdatedAt,
+			)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// queryTasks returns a project's tasks in sequence order within a
+// transaction.
+func queryTasks(tx *tx, projectID string) ([]*Task, error) {
+	rows, err := tx.Query(`
+		SELECT id, project_id, sequence, title, description, status, jj_change_id, iteration_count, created_at, updated_at
+		FROM tasks WHERE project_id = ? ORDER BY sequence`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Warn("failed to close rows", "operation", "queryTasks", "error", closeErr)
+		}
+	}()
+
+	var tasks []*Task
+	for rows.Next() {
+		t := &Task{}
+		if err := rows.Scan(
+			&t.ID, &t.ProjectID, &t.Sequence, &t.Title, &t.Description,
+			&t.Status, &t.JJChangeID, &t.IterationCount,
+			&t.CreatedAt, &t.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// newTaskOrderDB returns a database with a project of tasks titled "T1"
+// through "Tn", with IDs "task-1" through "task-n".
+func newTaskOrderDB(t *testing.T, n int) *DB {
+	t.Helper()
+	db := newTestDB(t)
+	if err := db.CreateProject(&Project{ID: "proj-1", Name: "Project", PlanText: "Plan"}); err != nil {
+		t.Fatalf("CreateProject() returned error: %v", err)
+	}
+	for i := 1; i <= n; i++ {
+		task := &Task{ID: fmt.Sprintf("task-%d", i), ProjectID: "proj-1", Sequence: i, Title: fmt.Sprintf("T%d", i), Description: fmt.Sprintf("Desc %d", i)}
+		if err := db.CreateTask(task); err != nil {
+			t.Fatalf("CreateTask() returned error: %v", err)
+		}
+	}
+	return db
+}
+
+// taskOrder returns the project's task IDs in sequence order, checking they
+// are numbered from 1.
+func taskOrder(t *testing.T, db *DB) string {
+	t.Helper()
+	tasks, err := db.GetTasksByProject("proj-1")
+	if err != nil {
+		t.Fatalf("GetTasksByProject() returned error: %v", err)
+	}
+	var ids []string
+	for i, task := range tasks {
+		if task.Sequence != i+1 {
+			t.Errorf("task %s has sequence %d, want %d", task.ID, task.Sequence, i+1)
+		}
+		ids = append(ids, task.ID)
+	}
+	return strings.Join(ids, " ")
+}
+
+func TestReorderTasks(t *testing.T) {
+	db := newTaskOrderDB(t, 3)
+	if err := db.UpdateTaskStatus("task-1", TaskInProgress); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.ReorderTasks("proj-1", []string{"task-1", "task-3", "task-2"}); err != nil {
+		t.Fatalf("ReorderTasks() returned error: %v", err)
+	}
+	if got := taskOrder(t, db); got != "task-1 task-3 task-2" {
+		t.Errorf("order = %q", got)
+	}
+
+	err := db.ReorderTasks("proj-1", []string{"task-3", "task-1", "task-2"})
+	if !errors.Is(err, ErrTaskInProgress) {
+		t.Errorf("moving an in-progress task: error = %v, want ErrTaskInProgress", err)
+	}
+	if err := db.ReorderTasks("proj-1", []string{"task-1", "task-3", "task-3"}); err == nil {
+		t.Error("expected an error for a task listed twice")
+	}
+	if err := db.ReorderTasks("proj-1", []string{"task-1", "task-3"}); err == nil {
+		t.Error("expected an error for a missing task")
+	}
+	if got := taskOrder(t, db); got != "task-1 task-3 task-2" {
+		t.Errorf("failed reorders changed the order to %q", got)
+	}
+}
+
+func TestSplitTask(t *testing.T) {
+	db := newTaskOrderDB(t, 3)
+
+	subtasks := []*Task{
+		{ID: "sub-a", Title: "A", Description: "Desc A"},
+		{ID: "sub-b", Title: "B", Description: "Desc B"},
+	}
+	if err := db.SplitTask("task-2", subtasks); err != nil {
+		t.Fatalf("SplitTask() returned error: %v", err)
+	}
+	if got := taskOrder(t, db); got != "task-1 task-2 sub-b task-3" {
+		t.Errorf("order = %q", got)
+	}
+	first, err := db.GetTask("task-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Title != "A" || first.Description != "Desc A" {
+		t.Errorf("first subtask = %+v, want the task replaced with A", first)
+	}
+	second, err := db.GetTask("sub-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ProjectID != "proj-1" || second.Status != TaskPending || second.Sequence != 3 {
+		t.Errorf("second subtask = %+v", second)
+	}
+
+	if err := db.UpdateTaskStatus("task-3", TaskCompleted); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SplitTask("task-3", []*Task{{ID: "x"}, {ID: "y"}}); err == nil || !strings.Contains(err.Error(), "completed") {
+		t.Errorf("splitting a completed task: error = %v", err)
+	}
+	if err := db.SplitTask("task-1", subtasks[:1]); err == nil {
+		t.Error("expected an error for a single subtask")
+	}
+}
+
+func TestMergeTasks(t *testing.T) {
+	db := newTaskOrderDB(t, 4)
+
+	if err := db.MergeTasks("proj-1", 3, 2); err != nil {
+		t.Fatalf("MergeTasks() returned error: %v", err)
+	}
+	if got := taskOrder(t, db); got != "task-1 task-3 task-4" {
+		t.Errorf("order = %q", got)
+	}
+	merged, err := db.GetTask("task-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Title != "T3" || merged.Description != "Desc 3\n\n## T2\n\nDesc 2" {
+		t.Errorf("merged task = %+v", merged)
+	}
+	if _, err := db.GetTask("task-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("merged-in task: error = %v, want ErrNotFound", err)
+	}
+
+	if err := db.UpdateTaskStatus("task-1", TaskInProgress); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.MergeTasks("proj-1", 2, 1); !errors.Is(err, ErrTaskInProgress) {
+		t.Errorf("merging an in-progress task: error = %v, want ErrTaskInProgress", err)
+	}
+	if err := db.MergeTasks("proj-1", 2, 9); !errors.Is(err, ErrNotFound) {
+		t.Errorf("merging a missing task: error = %v, want ErrNotFound", err)
+	}
+	if err := db.MergeTasks("proj-1", 2, 2); err == nil {
+		t.Error("expected an error merging a task with itself")
+	}
+}
@@ -0,0 +1,150 @@
+// Package forge opens pull requests on code hosting services (GitHub, GitLab).
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Provider identifies a code hosting service.
+type Provider string
+
+const (
+	ProviderGitHub Provider = "github"
+	ProviderGitLab Provider = "gitlab"
+)
+
+// Default API endpoints for each provider.
+const (
+	DefaultGitHubAPIURL = "https://api.github.com"
+	DefaultGitLabAPIURL = "https://gitlab.com/api/v4"
+)
+
+// Error types for forge operations.
+var (
+	ErrUnsupportedProvider = errors.New("unsupported forge provider")
+	ErrMissingToken        = errors.New("forge token is empty")
+	ErrMissingRepo         = errors.New("forge repository is empty")
+)
+
+// Config holds the settings for a forge client.
+type Config struct {
+	Provider Provider
+	Repo     string // "owner/name" on GitHub, project path ("group/project") on GitLab
+	Token    string // API token sent with every request
+	APIURL   string // API base URL (empty = provider default)
+}
+
+// PullRequest describes a pull (or merge) request to open.
+type PullRequest struct {
+	Title string
+	Body  string
+	Head  string // Branch with the changes
+	Base  string // Branch to merge into
+}
+
+// Client opens pull requests through a forge's REST API.
+type Client struct {
+	cfg        Config
+	httpClient *http.Client
+}
+
+// NewClient creates a forge client, validating the configuration.
+func NewClient(cfg Config) (*Client, error) {
+	switch cfg.Provider {
+	case ProviderGitHub:
+		if cfg.APIURL == "" {
+			cfg.APIURL = DefaultGitHubAPIURL
+		}
+	case ProviderGitLab:
+		if cfg.APIURL == "" {
+			cfg.APIURL = DefaultGitLabAPIURL
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedProvider, cfg.Provider)
+	}
+	if cfg.Token == "" {
+		return nil, ErrMissingToken
+	}
+	if cfg.Repo == "" {
+		return nil, ErrMissingRepo
+	}
+	cfg.APIURL = strings.TrimRight(cfg.APIURL, "/")
+
+	return &Client{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// SetHTTPClient allows setting a custom HTTP client (for testing).
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.httpClient = httpClient
+}
+
+// CreatePullRequest opens a pull request (a merge request on GitLab) and
+// returns its web URL.
+func (c *Client) CreatePullRequest(ctx context.Context, pr PullRequest) (string, error) {
+	var endpoint string
+	var payload map[string]string
+	headers := map[string]string{"Content-Type": "application/json"}
+
+	switch c.cfg.Provider {
+	case ProviderGitHub:
+		endpoint = c.cfg.APIURL + "/repos/" + c.cfg.Repo + "/pulls"
+		payload = map[string]string{"title": pr.Title, "body": pr.Body, "head": pr.Head, "base": pr.Base}
+		headers["Accept"] = "application/vnd.github+json"
+		headers["Authorization"] = "Bearer " + c.cfg.Token
+	default:
+		endpoint = c.cfg.APIURL + "/projects/" + url.PathEscape(c.cfg.Repo) + "/merge_requests"
+		payload = map[string]string{"title": pr.Title, "description": pr.Body, "source_branch": pr.Head, "target_branch": pr.Base}
+		headers["PRIVATE-TOKEN"] = c.cfg.Token
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to build request: %w", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%s request failed: %w", c.cfg.Provider, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s response: %w", c.cfg.Provider, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%s returned %s: %s", c.cfg.Provider, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"` // GitHub
+		WebURL  string `json:"web_url"`  // GitLab
+	}
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return "", fmt.Errorf("failed to parse %s response: %w", c.cfg.Provider, err)
+	}
+	if created.HTMLURL != "" {
+		return created.HTMLURL, nil
+	}
+	return created.WebURL, nil
+}
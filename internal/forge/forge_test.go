@@ -0,0 +1,118 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewClient_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr error
+	}{
+		{"unknown provider", Config{Provider: "bitbucket", Repo: "a/b", Token: "t"}, ErrUnsupportedProvider},
+		{"missing token", Config{Provider: ProviderGitHub, Repo: "a/b"}, ErrMissingToken},
+		{"missing repo", Config{Provider: ProviderGitLab, Token: "t"}, ErrMissingRepo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewClient(tt.cfg); !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewClient() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreatePullRequest_GitHub(t *testing.T) {
+	var gotPath, gotAuth string
+	var gotBody map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"html_url":"https://github.com/acme/app/pull/7"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{Provider: ProviderGitHub, Repo: "acme/app", Token: "secret", APIURL: server.URL + "/"})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	prURL, err := client.CreatePullRequest(context.Background(), PullRequest{Title: "Add parser", Body: "Summary", Head: "ralph/abc", Base: "main"})
+	if err != nil {
+		t.Fatalf("CreatePullRequest() error = %v", err)
+	}
+	if prURL != "https://github.com/acme/app/pull/7" {
+		t.Errorf("CreatePullRequest() = %q", prURL)
+	}
+	if gotPath != "/repos/acme/app/pulls" {
+		t.Errorf("path = %q, want /repos/acme/app/pulls", gotPath)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want Bearer secret", gotAuth)
+	}
+	if gotBody["head"] != "ralph/abc" || gotBody["base"] != "main" || gotBody["body"] != "Summary" {
+		t.Errorf("body = %v", gotBody)
+	}
+}
+
+func TestCreatePullRequest_GitLab(t *testing.T) {
+	var gotPath, gotToken string
+	var gotBody map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotToken = r.Header.Get("PRIVATE-TOKEN")
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"web_url":"https://gitlab.com/acme/app/-/merge_requests/3"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{Provider: ProviderGitLab, Repo: "acme/app", Token: "secret", APIURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	prURL, err := client.CreatePullRequest(context.Background(), PullRequest{Title: "Add parser", Body: "Summary", Head: "ralph/abc", Base: "main"})
+	if err != nil {
+		t.Fatalf("CreatePullRequest() error = %v", err)
+	}
+	if prURL != "https://gitlab.com/acme/app/-/merge_requests/3" {
+		t.Errorf("CreatePullRequest() = %q", prURL)
+	}
+	if gotPath != "/projects/acme%2Fapp/merge_requests" {
+		t.Errorf("path = %q, want /projects/acme%%2Fapp/merge_requests", gotPath)
+	}
+	if gotToken != "secret" {
+		t.Errorf("PRIVATE-TOKEN = %q, want secret", gotToken)
+	}
+	if gotBody["source_branch"] != "ralph/abc" || gotBody["target_branch"] != "main" || gotBody["description"] != "Summary" {
+		t.Errorf("body = %v", gotBody)
+	}
+}
+
+func TestCreatePullRequest_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"message":"A pull request already exists"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{Provider: ProviderGitHub, Repo: "acme/app", Token: "t", APIURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	_, err = client.CreatePullRequest(context.Background(), PullRequest{Title: "x", Head: "h", Base: "main"})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("CreatePullRequest() error = %v, want the API message", err)
+	}
+}
@@ -0,0 +1,144 @@
+// Package frontmatter reads the front matter of a plan: a block at the very
+// start of the plan between "---" lines, holding unindented "key: value"
+// lines. A key's value is either written inline or as a block of the
+// indented (or "- item") lines that follow it. Comments and blank lines are
+// skipped.
+package frontmatter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Delimiter opens and closes a plan's front matter.
+const Delimiter = "---"
+
+// Entry is a top-level key of a plan's front matter.
+type Entry struct {
+	Key   string
+	Value string // The inline value, trimmed ("" for a block)
+	Line  int    // The key's line number in the plan
+	Block []Line // The lines of the key's block, if any
+}
+
+// Line is a line of a key's block, trimmed.
+type Line struct {
+	Num  int
+	Text string
+}
+
+// Parse returns the top-level keys of a plan's front matter, in order. A
+// plan without front matter has none (nil).
+func Parse(plan string) ([]Entry, error) {
+	lines := strings.Split(strings.ReplaceAll(plan, "\r\n", "\n"), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != Delimiter {
+		return nil, nil
+	}
+
+	var entries []Entry
+	for i, line := range lines[1:] {
+		lineNum := i + 2
+		trimmed := strings.TrimSpace(line)
+		if trimmed == Delimiter {
+			return entries, nil
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") || strings.HasPrefix(trimmed, "-") {
+			// Lines before the first key belong to no block
+			if len(entries) > 0 {
+				last := &entries[len(entries)-1]
+				last.Block = append(last.Block, Line{Num: lineNum, Text: trimmed})
+			}
+			continue
+		}
+		key, value, _ := strings.Cut(trimmed, ":")
+		entries = append(entries, Entry{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value), Line: lineNum})
+	}
+	return nil, fmt.Errorf("front matter is missing its closing %q", Delimiter)
+}
+
+// Len returns the number of lines of a plan's front matter, its delimiters
+// included: the index of the first line of the plan's body. A plan without
+// front matter, or whose front matter isn't closed, has none (0).
+func Len(plan string) int {
+	lines := strings.Split(strings.ReplaceAll(plan, "\r\n", "\n"), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != Delimiter {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == Delimiter {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// List returns the items of the key of a plan's front matter: a block of
+// "- item" lines or an inline "[a, b]" list. noun names an item in errors.
+// A plan without the key has no items (nil).
+func List(plan, key, noun string) ([]string, error) {
+	entries, err := Parse(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []string
+	for _, e := range entries {
+		if e.Key != key {
+			continue
+		}
+		if e.Value != "" {
+			if !strings.HasPrefix(e.Value, "[") || !strings.HasSuffix(e.Value, "]") {
+				return nil, fmt.Errorf("front matter line %d: %s must be a block of - %s lines or a [%s, ...] list", e.Line, key, noun, noun)
+			}
+			for _, item := range strings.Split(e.Value[1:len(e.Value)-1], ",") {
+				if item = Unquote(strings.TrimSpace(item)); item != "" {
+					items = append(items, item)
+				}
+			}
+			continue
+		}
+		for _, line := range e.Block {
+			text, ok := strings.CutPrefix(line.Text, "-")
+			if !ok {
+				return nil, fmt.Errorf("front matter line %d: expected - %s, got %q", line.Num, noun, line.Text)
+			}
+			item := Unquote(strings.TrimSpace(text))
+			if item == "" {
+				return nil, fmt.Errorf("front matter line %d: empty %s %s", line.Num, key, noun)
+			}
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// Value returns the inline value of the key of a plan's front matter, a
+// "key: value" line ("" if the key isn't there). The last one wins.
+func Value(plan, key string) (string, error) {
+	entries, err := Parse(plan)
+	if err != nil {
+		return "", err
+	}
+
+	value := ""
+	for _, e := range entries {
+		if e.Key != key {
+			continue
+		}
+		if value = Unquote(e.Value); value == "" {
+			return "", fmt.Errorf("front matter line %d: %s must be a value", e.Line, key)
+		}
+	}
+	return value, nil
+}
+
+// Unquote strips one pair of matching single or double quotes.
+func Unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,96 @@
+package frontmatter
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	plan := "---\ntitle: \"Billing\"\n# comment\nenv:\n  A: b\n\nfiles:\n  - a.go\n- b.go\n---\n# Plan\nother: x\n"
+	entries, err := Parse(plan)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Parse() = %+v, want 3 entries", entries)
+	}
+	if e := entries[0]; e.Key != "title" || e.Value != `"Billing"` || e.Line != 2 || e.Block != nil {
+		t.Errorf("entries[0] = %+v", e)
+	}
+	if e := entries[1]; e.Key != "env" || !slices.Equal(e.Block, []Line{{Num: 5, Text: "A: b"}}) {
+		t.Errorf("entries[1] = %+v", e)
+	}
+	if e := entries[2]; !slices.Equal(e.Block, []Line{{Num: 8, Text: "- a.go"}, {Num: 9, Text: "- b.go"}}) {
+		t.Errorf("entries[2] = %+v", e)
+	}
+
+	for _, plan := range []string{"", "# Plan\n---\ntitle: x\n---\n"} {
+		if entries, err := Parse(plan); err != nil || entries != nil {
+			t.Errorf("Parse(%q) = %v, %v; want no front matter", plan, entries, err)
+		}
+	}
+	if _, err := Parse("---\ntitle: x\n# Plan\n"); err == nil || !strings.Contains(err.Error(), "missing its closing") {
+		t.Errorf("Parse() error = %v, want a missing delimiter error", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    string
+		want    []string
+		wantErr string
+	}{
+		{"missing", "---\ntitle: x\n---\n", nil, ""},
+		{"block", "---\nfiles:\n  - a.go\n  - 'b c'\ntitle: x\n---\n", []string{"a.go", "b c"}, ""},
+		{"inline", "---\nfiles: [a.go, \"b.go\", ]\n---\n", []string{"a.go", "b.go"}, ""},
+		{"inline value", "---\nfiles: a.go\n---\n", nil, "files must be a block of - glob lines"},
+		{"not an item", "---\nfiles:\n  a.go\n---\n", nil, "expected - glob"},
+		{"empty item", "---\nfiles:\n  -\n---\n", nil, "empty files glob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := List(tt.plan, "files", "glob")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("List() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("List() error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("List() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLen(t *testing.T) {
+	tests := map[string]int{
+		"# Plan\n":                       0,
+		"---\nmode: fast\n---\n# Plan\n": 3,
+		"---\r\nmode: fast\r\n---\r\n":   3,
+		"---\nmode: fast\n# Plan\n":      0,
+	}
+	for plan, want := range tests {
+		if got := Len(plan); got != want {
+			t.Errorf("Len(%q) = %d, want %d", plan, got, want)
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	got, err := Value("---\nmode: 'fast'\nfiles:\n  - mode: slow\n---\n", "mode")
+	if err != nil || got != "fast" {
+		t.Errorf("Value() = %q, %v; want fast", got, err)
+	}
+	if got, err := Value("---\ntitle: x\n---\n", "mode"); err != nil || got != "" {
+		t.Errorf("Value() = %q, %v; want none", got, err)
+	}
+	if _, err := Value("---\nmode:\n---\n", "mode"); err == nil || !strings.Contains(err.Error(), "mode must be a value") {
+		t.Errorf("Value() error = %v, want a missing value error", err)
+	}
+}
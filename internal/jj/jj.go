@@ -6,7 +6,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"regexp"
 	"strings"
 )
 
@@ -22,10 +24,14 @@ var (
 // It can be replaced in tests to mock command execution.
 type CommandRunner func(ctx context.Context, dir string, name string, args ...string) (string, string, error)
 
-// defaultCommandRunner executes a command using exec.CommandContext.
-func defaultCommandRunner(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+// execCommand executes a command using exec.CommandContext, with the
+// client's additional environment variables.
+func (c *Client) execCommand(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
 	cmd := exec.CommandContext(ctx, name, args...)
 	cmd.Dir = dir
+	if len(c.env) > 0 {
+		cmd.Env = append(os.Environ(), c.env...)
+	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -38,15 +44,15 @@ func defaultCommandRunner(ctx context.Context, dir string, name string, args ...
 // Client wraps the jj CLI for version control operations.
 type Client struct {
 	workDir       string
+	env           []string // Additional environment variables (KEY=VALUE format)
 	commandRunner CommandRunner
 }
 
 // NewClient creates a new jj CLI client bound to the specified working directory.
 func NewClient(workDir string) *Client {
-	return &Client{
-		workDir:       workDir,
-		commandRunner: defaultCommandRunner,
-	}
+	c := &Client{workDir: workDir}
+	c.commandRunner = c.execCommand
+	return c
 }
 
 // SetCommandRunner allows setting a custom command runner (for testing).
@@ -54,6 +60,12 @@ func (c *Client) SetCommandRunner(runner CommandRunner) {
 	c.commandRunner = runner
 }
 
+// SetEnv sets additional environment variables (KEY=VALUE format) for the
+// jj commands the client runs.
+func (c *Client) SetEnv(env []string) {
+	c.env = env
+}
+
 // runCommand executes a jj command and returns the output.
 func (c *Client) runCommand(ctx context.Context, args ...string) (string, error) {
 	stdout, stderr, err := c.commandRunner(ctx, c.workDir, "jj", args...)
@@ -105,6 +117,41 @@ func (c *Client) Status(ctx context.Context) (string, error) {
 	return c.runCommand(ctx, "status")
 }
 
+// conflictLine matches a path in the conflict list of jj status, e.g.
+// "src/main.go    2-sided conflict".
+var conflictLine = regexp.MustCompile(`^(\S.*?)\s+\d+-sided conflict`)
+
+// Conflicts returns the paths with unresolved conflicts in the working
+// copy, as listed by jj status (empty when there are none).
+func (c *Client) Conflicts(ctx context.Context) ([]string, error) {
+	output, err := c.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseConflicts(output), nil
+}
+
+// parseConflicts extracts the conflicted paths from jj status output.
+func parseConflicts(status string) []string {
+	var paths []string
+	inList := false
+	for _, line := range strings.Split(status, "\n") {
+		if strings.Contains(strings.ToLower(line), "unresolved conflicts at these paths") {
+			inList = true
+			continue
+		}
+		if !inList {
+			continue
+		}
+		match := conflictLine.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			break
+		}
+		paths = append(paths, match[1])
+	}
+	return paths
+}
+
 // Log returns the log output with the specified revset and template.
 // If revset is empty, the default revset is used.
 // If template is empty, the default template is used.
@@ -132,15 +179,136 @@ func (c *Client) IsEmpty(ctx context.Context) (bool, error) {
 // Diff returns the diff between two revisions.
 // If from is empty, it diffs from the parent of 'to'.
 // If to is empty, it defaults to "@" (current change).
-func (c *Client) Diff(ctx context.Context, from, to string) (string, error) {
-	args := []string{"diff"}
+// With paths, only changes under those repo-relative paths are included.
+func (c *Client) Diff(ctx context.Context, from, to string, paths ...string) (string, error) {
+	return c.runCommand(ctx, diffArgs([]string{"diff"}, from, to, paths)...)
+}
+
+// diffArgs appends the revision and path arguments of a diff to args.
+func diffArgs(args []string, from, to string, paths []string) []string {
 	if from != "" {
 		args = append(args, "--from", from)
 	}
 	if to != "" {
 		args = append(args, "--to", to)
 	}
-	return c.runCommand(ctx, args...)
+	if len(paths) > 0 {
+		args = append(args, "--")
+		for _, path := range paths {
+			args = append(args, fmt.Sprintf("root:%q", path))
+		}
+	}
+	return args
+}
+
+// ChangedFiles returns the paths of files modified between two revisions.
+// Arguments follow the same defaults as Diff.
+func (c *Client) ChangedFiles(ctx context.Context, from, to string, paths ...string) ([]string, error) {
+	output, err := c.runCommand(ctx, diffArgs([]string{"diff", "--name-only"}, from, to, paths)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// New starts a new empty change on top of the current one with the given description.
+func (c *Client) New(ctx context.Context, message string) error {
+	_, err := c.runCommand(ctx, "new", "-m", message)
+	return err
+}
+
+// NewOn starts a new empty change on top of revision, rather than the
+// current change, with the given description.
+func (c *Client) NewOn(ctx context.Context, revision, message string) error {
+	_, err := c.runCommand(ctx, "new", revision, "-m", message)
+	return err
+}
+
+// Describe sets the description of the current change.
+func (c *Client) Describe(ctx context.Context, message string) error {
+	_, err := c.runCommand(ctx, "describe", "-m", message)
+	return err
+}
+
+// DescribeRevision sets the description of the given revision.
+func (c *Client) DescribeRevision(ctx context.Context, revision, message string) error {
+	_, err := c.runCommand(ctx, "describe", "-r", revision, "-m", message)
+	return err
+}
+
+// GetDescription returns the full description of the given revision.
+func (c *Client) GetDescription(ctx context.Context, revision string) (string, error) {
+	return c.runCommand(ctx, "log", "-r", revision, "-T", "description", "--no-graph")
+}
+
+// Trailer is a git-style "Key: value" line at the end of a change description.
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+// AddTrailers appends trailers to the description of the given revision.
+// Existing trailers with the same keys are replaced, so repeated calls keep
+// the description current instead of piling up duplicates.
+func (c *Client) AddTrailers(ctx context.Context, revision string, trailers []Trailer) error {
+	description, err := c.GetDescription(ctx, revision)
+	if err != nil {
+		return err
+	}
+	return c.DescribeRevision(ctx, revision, AppendTrailers(description, trailers))
+}
+
+// AppendTrailers returns description with trailers added to its trailer
+// block, starting one (separated by a blank line) if it has none.
+func AppendTrailers(description string, trailers []Trailer) string {
+	body := strings.TrimRight(description, "\n \t")
+
+	// The last paragraph is the trailer block if every line is "Key: value".
+	// A lone one-line paragraph is the subject, never trailers.
+	var block []string
+	i := max(strings.LastIndex(body, "\n\n"), 0)
+	if lines := strings.Split(strings.TrimLeft(body[i:], "\n"), "\n"); isTrailerBlock(lines) && (i > 0 || len(lines) > 1) {
+		block = lines
+		body = strings.TrimRight(body[:i], "\n")
+	}
+
+	keys := make(map[string]bool, len(trailers))
+	for _, t := range trailers {
+		keys[strings.ToLower(t.Key)] = true
+	}
+	var kept []string
+	for _, line := range block {
+		key, _, _ := strings.Cut(line, ":")
+		if !keys[strings.ToLower(strings.TrimSpace(key))] {
+			kept = append(kept, line)
+		}
+	}
+	for _, t := range trailers {
+		kept = append(kept, t.Key+": "+t.Value)
+	}
+
+	if body == "" {
+		return strings.Join(kept, "\n") + "\n"
+	}
+	return body + "\n\n" + strings.Join(kept, "\n") + "\n"
+}
+
+// isTrailerBlock reports whether every line is a "Key: value" trailer.
+func isTrailerBlock(lines []string) bool {
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok || key == "" || value == "" || strings.ContainsAny(key, " \t") {
+			return false
+		}
+	}
+	return len(lines) > 0
 }
 
 // GetCurrentChangeID returns the change ID of the current revision (@).
@@ -152,6 +320,105 @@ func (c *Client) GetCurrentChangeID(ctx context.Context) (string, error) {
 	return strings.TrimSpace(output), nil
 }
 
+// GetCurrentCommitID returns the commit ID of the current revision (@).
+// Unlike the change ID it changes with every working-copy snapshot, so it
+// pins the exact state of the change at that moment.
+func (c *Client) GetCurrentCommitID(ctx context.Context) (string, error) {
+	output, err := c.runCommand(ctx, "log", "-r", "@", "-T", "commit_id", "--no-graph")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// GitDiff returns the diff between two revisions in git's unified format.
+// Arguments follow the same defaults as Diff.
+func (c *Client) GitDiff(ctx context.Context, from, to string, paths ...string) (string, error) {
+	return c.runCommand(ctx, diffArgs([]string{"diff", "--git"}, from, to, paths)...)
+}
+
+// DiffStat returns the number of lines added and removed between two
+// revisions. Arguments follow the same defaults as Diff.
+func (c *Client) DiffStat(ctx context.Context, from, to string) (added, removed int, err error) {
+	output, err := c.GitDiff(ctx, from, to)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// A file's header lines, from "diff --git" to its first hunk, include
+	// the "--- a/..." and "+++ b/..." lines; within a hunk, a removed
+	// "-- comment" line starts with "---" too
+	inHeader := false
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			inHeader = true
+		case strings.HasPrefix(line, "@@"):
+			inHeader = false
+		case inHeader:
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed, nil
+}
+
+// DiffSummary returns jj's per-file summary of changes between two revisions
+// (jj diff --stat). Arguments follow the same defaults as Diff.
+func (c *Client) DiffSummary(ctx context.Context, from, to string) (string, error) {
+	args := []string{"diff", "--stat"}
+	if from != "" {
+		args = append(args, "--from", from)
+	}
+	if to != "" {
+		args = append(args, "--to", to)
+	}
+	return c.runCommand(ctx, args...)
+}
+
+// SetBookmark creates the bookmark at the given revision, or moves it there
+// if it already exists, even backwards.
+func (c *Client) SetBookmark(ctx context.Context, bookmark, revision string) error {
+	_, err := c.runCommand(ctx, "bookmark", "set", bookmark, "-r", revision, "--allow-backwards")
+	return err
+}
+
+// DeleteBookmark deletes the bookmark.
+func (c *Client) DeleteBookmark(ctx context.Context, bookmark string) error {
+	_, err := c.runCommand(ctx, "bookmark", "delete", bookmark)
+	return err
+}
+
+// GitPush points the bookmark at the given revision and pushes it to the
+// git remote, creating the remote branch if needed.
+func (c *Client) GitPush(ctx context.Context, bookmark, revision string) error {
+	if err := c.SetBookmark(ctx, bookmark, revision); err != nil {
+		return err
+	}
+	_, err := c.runCommand(ctx, "git", "push", "--bookmark", bookmark, "--allow-new")
+	return err
+}
+
+// Version returns the output of `jj --version`, e.g. "jj 0.25.0".
+func (c *Client) Version(ctx context.Context) (string, error) {
+	output, err := c.runCommand(ctx, "--version")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// Root returns the absolute path of the repository root.
+func (c *Client) Root(ctx context.Context) (string, error) {
+	output, err := c.runCommand(ctx, "root")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
 // GetParentChangeID returns the change ID of the parent of the current revision (@-).
 // Returns empty string if there is no parent (root commit).
 func (c *Client) GetParentChangeID(ctx context.Context) (string, error) {
@@ -165,3 +432,128 @@ func (c *Client) GetParentChangeID(ctx context.Context) (string, error) {
 	}
 	return strings.TrimSpace(output), nil
 }
+
+// CheckPatch reports whether a unified diff applies cleanly to the working
+// copy, without changing any files.
+func (c *Client) CheckPatch(ctx context.Context, patch string) error {
+	return c.gitApply(ctx, patch, "--check")
+}
+
+// ApplyPatch applies a unified diff to the working copy. jj has no patch
+// command, so it uses `git apply`, which also works outside git repositories.
+// Paths are relative to the repository root. Nothing is changed if any hunk
+// fails to apply.
+func (c *Client) ApplyPatch(ctx context.Context, patch string) error {
+	return c.gitApply(ctx, patch)
+}
+
+// gitApply runs `git apply` from the repository root on patch, which is
+// passed through a temporary file.
+func (c *Client) gitApply(ctx context.Context, patch string, flags ...string) error {
+	root, err := c.Root(ctx)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp("", "ralph-patch-*.diff")
+	if err != nil {
+		return fmt.Errorf("failed to create patch file: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.WriteString(patch); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write patch file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write patch file: %w", err)
+	}
+
+	args := append([]string{"apply", "--whitespace=nowarn"}, flags...)
+	_, stderr, err := c.commandRunner(ctx, root, "git", append(args, f.Name())...)
+	if err != nil {
+		var execErr *exec.Error
+		if errors.As(err, &execErr) && errors.Is(execErr.Err, exec.ErrNotFound) {
+			return errors.New("git command not found (needed to apply patches)")
+		}
+		return fmt.Errorf("git apply failed: %s: %w", strings.TrimSpace(stderr), err)
+	}
+	return nil
+}
+
+// Restore replaces the working copy's contents with those of the given
+// revision, keeping the working-copy change itself. With paths, only those
+// repo-relative files are restored.
+func (c *Client) Restore(ctx context.Context, from string, paths ...string) error {
+	args := []string{"restore", "--from", from}
+	if len(paths) > 0 {
+		args = append(args, "--")
+		for _, path := range paths {
+			args = append(args, fmt.Sprintf("root-file:%q", path))
+		}
+	}
+	_, err := c.runCommand(ctx, args...)
+	return err
+}
+
+// ChangeIDs returns the change IDs of the revisions in a revset, newest first.
+func (c *Client) ChangeIDs(ctx context.Context, revset string) ([]string, error) {
+	output, err := c.runCommand(ctx, "log", "-r", revset, "-T", `change_id ++ "\n"`, "--no-graph")
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			ids = append(ids, line)
+		}
+	}
+	return ids, nil
+}
+
+// Squash moves the changes of the revisions in the from revset into the
+// into revision and sets its description to message. Revisions left empty
+// are abandoned.
+func (c *Client) Squash(ctx context.Context, from, into, message string) error {
+	_, err := c.runCommand(ctx, "squash", "--from", from, "--into", into, "-m", message)
+	return err
+}
+
+// Abandon abandons the given revision. Abandoning the working-copy change
+// starts a new empty one on its parent.
+func (c *Client) Abandon(ctx context.Context, revision string) error {
+	_, err := c.runCommand(ctx, "abandon", revision)
+	return err
+}
+
+// WorkspaceAdd creates a workspace named name at path, with its own working
+// copy on top of the current working copy's parents.
+func (c *Client) WorkspaceAdd(ctx context.Context, name, path string) error {
+	_, err := c.runCommand(ctx, "workspace", "add", "--name", name, path)
+	return err
+}
+
+// WorkspaceForget stops tracking the workspace named name. Its directory is
+// left on disk and its changes stay in the repository.
+func (c *Client) WorkspaceForget(ctx context.Context, name string) error {
+	_, err := c.runCommand(ctx, "workspace", "forget", name)
+	return err
+}
+
+// CurrentOperation returns the ID of the repository's latest operation,
+// after snapshotting the working copy.
+func (c *Client) CurrentOperation(ctx context.Context) (string, error) {
+	output, err := c.runCommand(ctx, "op", "log", "--no-graph", "--limit", "1", "-T", "id")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// RestoreOperation restores the repository, and the working copy, to the
+// state it was in at the given operation. Later operations stay in the
+// operation log, so a restore can itself be undone.
+func (c *Client) RestoreOperation(ctx context.Context, operationID string) error {
+	_, err := c.runCommand(ctx, "op", "restore", operationID)
+	return err
+}
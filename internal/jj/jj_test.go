@@ -88,6 +88,55 @@ func TestNewClient_EmptyPath(t *testing.T) {
 	}
 }
 
+func TestClient_SetEnv(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	client := NewClient(t.TempDir())
+	client.SetEnv([]string{"RALPH_JJ_TEST_VAR=from-plan"})
+
+	stdout, _, err := client.commandRunner(context.Background(), client.workDir, "sh", "-c", `printf %s "$RALPH_JJ_TEST_VAR"`)
+	if err != nil {
+		t.Fatalf("command error: %v", err)
+	}
+	if stdout != "from-plan" {
+		t.Errorf("RALPH_JJ_TEST_VAR = %q, want from-plan", stdout)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse(`Working copy changes:
+C api/handler.go
+M go.mod
+There are unresolved conflicts at these paths:
+api/handler.go    2-sided conflict
+docs/api guide.md    3-sided conflict including 1 deletion
+Working copy : qpvuntsm 1f5e0a2c (conflict) ralph: iteration 4
+Parent commit: rlvkpnrz 7d9e3b1a main
+`, "", nil)
+	mock.addResponse("The working copy is clean\nWorking copy : qpvuntsm 1f5e0a2c (empty) (no description set)\n", "", nil)
+
+	client := NewClient("/test/dir")
+	client.SetCommandRunner(mock.run)
+
+	paths, err := client.Conflicts(context.Background())
+	if err != nil {
+		t.Fatalf("Conflicts() error: %v", err)
+	}
+	if want := []string{"api/handler.go", "docs/api guide.md"}; !slices.Equal(paths, want) {
+		t.Errorf("Conflicts() = %v, want %v", paths, want)
+	}
+	if !slices.Equal(mock.calls[0].args, []string{"status"}) {
+		t.Errorf("Conflicts() ran jj %v, want jj status", mock.calls[0].args)
+	}
+
+	paths, err = client.Conflicts(context.Background())
+	if err != nil || len(paths) != 0 {
+		t.Errorf("Conflicts() on a clean working copy = %v, %v; want none", paths, err)
+	}
+}
+
 // =============================================================================
 // Unit Tests - Show
 // =============================================================================
@@ -447,6 +496,26 @@ func TestDiff(t *testing.T) {
 	}
 }
 
+func TestGitDiff(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("diff --git a/x b/x", "", nil)
+
+	client := NewClient("/test/dir")
+	client.SetCommandRunner(mock.run)
+
+	output, err := client.GitDiff(context.Background(), "abc123", "@")
+	if err != nil {
+		t.Fatalf("GitDiff() error = %v", err)
+	}
+	if output != "diff --git a/x b/x" {
+		t.Errorf("GitDiff() output = %q", output)
+	}
+	expectedArgs := []string{"diff", "--git", "--from", "abc123", "--to", "@"}
+	if !slices.Equal(mock.calls[0].args, expectedArgs) {
+		t.Errorf("command args = %v, want %v", mock.calls[0].args, expectedArgs)
+	}
+}
+
 func TestDiff_NoFrom(t *testing.T) {
 	mock := newMockRunner()
 	mock.addResponse("diff output", "", nil)
@@ -487,6 +556,57 @@ func TestDiff_NoTo(t *testing.T) {
 	}
 }
 
+func TestChangedFiles(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("internal/a.go\n\nREADME.md\n", "", nil)
+
+	client := NewClient("/test/dir")
+	client.SetCommandRunner(mock.run)
+
+	files, err := client.ChangedFiles(context.Background(), "abc123", "@")
+	if err != nil {
+		t.Fatalf("ChangedFiles() error = %v", err)
+	}
+
+	expectedFiles := []string{"internal/a.go", "README.md"}
+	if !slices.Equal(files, expectedFiles) {
+		t.Errorf("ChangedFiles() = %v, want %v", files, expectedFiles)
+	}
+
+	expectedArgs := []string{"diff", "--name-only", "--from", "abc123", "--to", "@"}
+	if !slices.Equal(mock.calls[0].args, expectedArgs) {
+		t.Errorf("command args = %v, want %v", mock.calls[0].args, expectedArgs)
+	}
+}
+
+func TestChangedFilesPaths(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("services/api/main.go\n", "", nil)
+
+	client := NewClient("/test/dir")
+	client.SetCommandRunner(mock.run)
+
+	if _, err := client.ChangedFiles(context.Background(), "abc123", "@", "services/api"); err != nil {
+		t.Fatalf("ChangedFiles() error = %v", err)
+	}
+	want := []string{"diff", "--name-only", "--from", "abc123", "--to", "@", "--", `root:"services/api"`}
+	if !slices.Equal(mock.calls[0].args, want) {
+		t.Errorf("command args = %v, want %v", mock.calls[0].args, want)
+	}
+}
+
+func TestChangedFiles_Error(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("", "boom", errors.New("exit status 1"))
+
+	client := NewClient("/test/dir")
+	client.SetCommandRunner(mock.run)
+
+	if _, err := client.ChangedFiles(context.Background(), "", ""); err == nil {
+		t.Fatal("ChangedFiles() should return error")
+	}
+}
+
 func TestDiff_Error(t *testing.T) {
 	mock := newMockRunner()
 	mock.addResponse("", "error message", errors.New("exit status 1"))
@@ -804,3 +924,443 @@ func TestIntegration_ContextTimeout(t *testing.T) {
 		t.Logf("Status() with timeout: %v", err)
 	}
 }
+
+func TestRoot(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("/home/user/repo\n", "", nil)
+
+	client := NewClient("/home/user/repo/sub")
+	client.SetCommandRunner(mock.run)
+
+	root, err := client.Root(context.Background())
+	if err != nil {
+		t.Fatalf("Root() error = %v", err)
+	}
+	if root != "/home/user/repo" {
+		t.Errorf("Root() = %q, want %q", root, "/home/user/repo")
+	}
+	if len(mock.calls) != 1 || !slices.Equal(mock.calls[0].args, []string{"root"}) {
+		t.Errorf("Root() calls = %v, want [root]", mock.calls)
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	var gotDir, gotName, gotPatch string
+	var gotArgs []string
+	client := NewClient("/home/user/repo/sub")
+	client.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		if name == "jj" {
+			return "/home/user/repo\n", "", nil
+		}
+		gotDir, gotName, gotArgs = dir, name, args
+		data, err := os.ReadFile(args[len(args)-1])
+		if err != nil {
+			t.Fatalf("failed to read patch file: %v", err)
+		}
+		gotPatch = string(data)
+		return "", "", nil
+	})
+
+	patch := "--- a/x.go\n+++ b/x.go\n@@ -1 +1 @@\n-a\n+b\n"
+	if err := client.ApplyPatch(context.Background(), patch); err != nil {
+		t.Fatalf("ApplyPatch() error = %v", err)
+	}
+	if gotDir != "/home/user/repo" || gotName != "git" {
+		t.Errorf("ApplyPatch() ran %s in %s, want git in the repository root", gotName, gotDir)
+	}
+	if !slices.Equal(gotArgs[:2], []string{"apply", "--whitespace=nowarn"}) || len(gotArgs) != 3 {
+		t.Errorf("ApplyPatch() args = %v", gotArgs)
+	}
+	if gotPatch != patch {
+		t.Errorf("patch file = %q, want %q", gotPatch, patch)
+	}
+
+	if err := client.CheckPatch(context.Background(), patch); err != nil {
+		t.Fatalf("CheckPatch() error = %v", err)
+	}
+	if !slices.Contains(gotArgs, "--check") {
+		t.Errorf("CheckPatch() args = %v, want --check", gotArgs)
+	}
+}
+
+func TestApplyPatch_Error(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("/repo\n", "", nil)
+	mock.addResponse("", "error: patch failed: x.go:1\n", errors.New("exit status 1"))
+
+	client := NewClient("/repo")
+	client.SetCommandRunner(mock.run)
+
+	err := client.ApplyPatch(context.Background(), "--- a/x.go\n+++ b/x.go\n")
+	if err == nil || !strings.Contains(err.Error(), "patch failed: x.go:1") {
+		t.Errorf("ApplyPatch() error = %v, want git's message", err)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("jj 0.25.0\n", "", nil)
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	version, err := client.Version(context.Background())
+	if err != nil {
+		t.Fatalf("Version() error = %v", err)
+	}
+	if version != "jj 0.25.0" {
+		t.Errorf("Version() = %q, want %q", version, "jj 0.25.0")
+	}
+	if len(mock.calls) != 1 || !slices.Equal(mock.calls[0].args, []string{"--version"}) {
+		t.Errorf("Version() calls = %v, want [--version]", mock.calls)
+	}
+}
+
+func TestNew(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("", "", nil)
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	if err := client.New(context.Background(), "Add parser"); err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if len(mock.calls) != 1 || !slices.Equal(mock.calls[0].args, []string{"new", "-m", "Add parser"}) {
+		t.Errorf("New() calls = %v, want [new -m Add parser]", mock.calls)
+	}
+}
+
+func TestNewOn(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("", "", nil)
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	if err := client.NewOn(context.Background(), "base123", "Try a parser generator"); err != nil {
+		t.Fatalf("NewOn() error = %v", err)
+	}
+	if len(mock.calls) != 1 || !slices.Equal(mock.calls[0].args, []string{"new", "base123", "-m", "Try a parser generator"}) {
+		t.Errorf("NewOn() calls = %v, want [new base123 -m Try a parser generator]", mock.calls)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("", "", nil)
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	if err := client.Describe(context.Background(), "Add parser"); err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+	if len(mock.calls) != 1 || !slices.Equal(mock.calls[0].args, []string{"describe", "-m", "Add parser"}) {
+		t.Errorf("Describe() calls = %v, want [describe -m Add parser]", mock.calls)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("", "", nil)
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	if err := client.Restore(context.Background(), "abc123"); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if len(mock.calls) != 1 || !slices.Equal(mock.calls[0].args, []string{"restore", "--from", "abc123"}) {
+		t.Errorf("Restore() calls = %v, want [restore --from abc123]", mock.calls)
+	}
+}
+
+func TestRestorePaths(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("", "", nil)
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	if err := client.Restore(context.Background(), "abc123", "go.mod", "docs/a b.md"); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	want := []string{"restore", "--from", "abc123", "--", `root-file:"go.mod"`, `root-file:"docs/a b.md"`}
+	if len(mock.calls) != 1 || !slices.Equal(mock.calls[0].args, want) {
+		t.Errorf("Restore() calls = %v, want %v", mock.calls, want)
+	}
+}
+
+func TestWorkspaces(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("", "", nil)
+	mock.addResponse("", "", nil)
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	ctx := context.Background()
+	if err := client.WorkspaceAdd(ctx, "ralph-abc", "/data/workspaces/abc"); err != nil {
+		t.Fatalf("WorkspaceAdd() error = %v", err)
+	}
+	if err := client.WorkspaceForget(ctx, "ralph-abc"); err != nil {
+		t.Fatalf("WorkspaceForget() error = %v", err)
+	}
+	want := [][]string{
+		{"workspace", "add", "--name", "ralph-abc", "/data/workspaces/abc"},
+		{"workspace", "forget", "ralph-abc"},
+	}
+	if len(mock.calls) != 2 || !slices.Equal(mock.calls[0].args, want[0]) || !slices.Equal(mock.calls[1].args, want[1]) {
+		t.Errorf("calls = %v, want %v", mock.calls, want)
+	}
+}
+
+func TestOperations(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("a1b2c3d4\n", "", nil)
+	mock.addResponse("", "", nil)
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	ctx := context.Background()
+	id, err := client.CurrentOperation(ctx)
+	if err != nil {
+		t.Fatalf("CurrentOperation() error = %v", err)
+	}
+	if id != "a1b2c3d4" {
+		t.Errorf("CurrentOperation() = %q, want a1b2c3d4", id)
+	}
+	if err := client.RestoreOperation(ctx, id); err != nil {
+		t.Fatalf("RestoreOperation() error = %v", err)
+	}
+	want := [][]string{
+		{"op", "log", "--no-graph", "--limit", "1", "-T", "id"},
+		{"op", "restore", "a1b2c3d4"},
+	}
+	if len(mock.calls) != 2 || !slices.Equal(mock.calls[0].args, want[0]) || !slices.Equal(mock.calls[1].args, want[1]) {
+		t.Errorf("calls = %v, want %v", mock.calls, want)
+	}
+}
+
+func TestAppendTrailers(t *testing.T) {
+	trailers := []Trailer{{Key: "Reviewed-by", Value: "ralph-reviewer"}, {Key: "Iterations", Value: "3"}}
+
+	tests := []struct {
+		name        string
+		description string
+		want        string
+	}{
+		{
+			name:        "empty description",
+			description: "",
+			want:        "Reviewed-by: ralph-reviewer\nIterations: 3\n",
+		},
+		{
+			name:        "subject only",
+			description: "Fix: handle nil config\n",
+			want:        "Fix: handle nil config\n\nReviewed-by: ralph-reviewer\nIterations: 3\n",
+		},
+		{
+			name:        "existing trailer block is extended",
+			description: "Add parser\n\nBody text.\n\nSigned-off-by: Dev <dev@example.com>\n",
+			want:        "Add parser\n\nBody text.\n\nSigned-off-by: Dev <dev@example.com>\nReviewed-by: ralph-reviewer\nIterations: 3\n",
+		},
+		{
+			name:        "same keys are replaced",
+			description: "Add parser\n\nIterations: 1\nReviewed-by: ralph-reviewer\n",
+			want:        "Add parser\n\nReviewed-by: ralph-reviewer\nIterations: 3\n",
+		},
+		{
+			name:        "trailers only are replaced",
+			description: "Reviewed-by: ralph-reviewer\nIterations: 2\n",
+			want:        "Reviewed-by: ralph-reviewer\nIterations: 3\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AppendTrailers(tt.description, trailers); got != tt.want {
+				t.Errorf("AppendTrailers() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddTrailers(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("Add parser\n", "", nil)
+	mock.addResponse("", "", nil)
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	if err := client.AddTrailers(context.Background(), "@", []Trailer{{Key: "Plan-ID", Value: "abc"}}); err != nil {
+		t.Fatalf("AddTrailers() error = %v", err)
+	}
+	if len(mock.calls) != 2 {
+		t.Fatalf("AddTrailers() made %d calls, want 2", len(mock.calls))
+	}
+	if !slices.Equal(mock.calls[0].args, []string{"log", "-r", "@", "-T", "description", "--no-graph"}) {
+		t.Errorf("first call = %v, want description lookup", mock.calls[0].args)
+	}
+	want := []string{"describe", "-r", "@", "-m", "Add parser\n\nPlan-ID: abc\n"}
+	if !slices.Equal(mock.calls[1].args, want) {
+		t.Errorf("second call = %q, want %q", mock.calls[1].args, want)
+	}
+}
+
+func TestGetCurrentCommitID(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("f00dcafe\n", "", nil)
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	commitID, err := client.GetCurrentCommitID(context.Background())
+	if err != nil {
+		t.Fatalf("GetCurrentCommitID() error = %v", err)
+	}
+	if commitID != "f00dcafe" {
+		t.Errorf("GetCurrentCommitID() = %q, want %q", commitID, "f00dcafe")
+	}
+	if !slices.Equal(mock.calls[0].args, []string{"log", "-r", "@", "-T", "commit_id", "--no-graph"}) {
+		t.Errorf("GetCurrentCommitID() args = %v", mock.calls[0].args)
+	}
+}
+
+func TestDiffStat(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,3 @@\n-old\n+new\n+more\n context\n"+
+		"diff --git a/q.sql b/q.sql\n--- a/q.sql\n+++ b/q.sql\n@@ -1,2 +1,1 @@\n--- old comment\n-select 1;\n++++ counter\n", "", nil)
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	added, removed, err := client.DiffStat(context.Background(), "abc", "def")
+	if err != nil {
+		t.Fatalf("DiffStat() error = %v", err)
+	}
+	if added != 3 || removed != 3 {
+		t.Errorf("DiffStat() = +%d -%d, want +3 -3", added, removed)
+	}
+	if !slices.Equal(mock.calls[0].args, []string{"diff", "--git", "--from", "abc", "--to", "def"}) {
+		t.Errorf("DiffStat() args = %v", mock.calls[0].args)
+	}
+}
+
+func TestDiffSummary(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("a.go | 3 ++-\n1 file changed, 2 insertions(+), 1 deletion(-)\n", "", nil)
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	summary, err := client.DiffSummary(context.Background(), "abc", "")
+	if err != nil {
+		t.Fatalf("DiffSummary() error = %v", err)
+	}
+	if !strings.Contains(summary, "1 file changed") {
+		t.Errorf("DiffSummary() = %q", summary)
+	}
+	if !slices.Equal(mock.calls[0].args, []string{"diff", "--stat", "--from", "abc"}) {
+		t.Errorf("DiffSummary() args = %v", mock.calls[0].args)
+	}
+}
+
+func TestGitPush(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("", "", nil)
+	mock.addResponse("", "", nil)
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	if err := client.GitPush(context.Background(), "ralph/abc", "@-"); err != nil {
+		t.Fatalf("GitPush() error = %v", err)
+	}
+	if len(mock.calls) != 2 {
+		t.Fatalf("GitPush() made %d calls, want 2", len(mock.calls))
+	}
+	if !slices.Equal(mock.calls[0].args, []string{"bookmark", "set", "ralph/abc", "-r", "@-", "--allow-backwards"}) {
+		t.Errorf("bookmark args = %v", mock.calls[0].args)
+	}
+	if !slices.Equal(mock.calls[1].args, []string{"git", "push", "--bookmark", "ralph/abc", "--allow-new"}) {
+		t.Errorf("push args = %v", mock.calls[1].args)
+	}
+}
+
+func TestGitPush_BookmarkError(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("", "Error: no such revision", errors.New("exit status 1"))
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	if err := client.GitPush(context.Background(), "ralph/abc", "@"); err == nil {
+		t.Fatal("GitPush() expected error")
+	}
+	if len(mock.calls) != 1 {
+		t.Errorf("GitPush() should not push after a bookmark failure, made %d calls", len(mock.calls))
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("", "", nil)
+	mock.addResponse("", "", nil)
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	ctx := context.Background()
+	if err := client.SetBookmark(ctx, "ralph/add-login-abc", "@"); err != nil {
+		t.Fatalf("SetBookmark() error = %v", err)
+	}
+	if err := client.DeleteBookmark(ctx, "ralph/add-login-abc"); err != nil {
+		t.Fatalf("DeleteBookmark() error = %v", err)
+	}
+	if !slices.Equal(mock.calls[0].args, []string{"bookmark", "set", "ralph/add-login-abc", "-r", "@", "--allow-backwards"}) {
+		t.Errorf("SetBookmark() args = %v", mock.calls[0].args)
+	}
+	if !slices.Equal(mock.calls[1].args, []string{"bookmark", "delete", "ralph/add-login-abc"}) {
+		t.Errorf("DeleteBookmark() args = %v", mock.calls[1].args)
+	}
+}
+
+func TestChangeIDs(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("abc\ndef\n", "", nil)
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	ids, err := client.ChangeIDs(context.Background(), "base..@-")
+	if err != nil {
+		t.Fatalf("ChangeIDs() error = %v", err)
+	}
+	if !slices.Equal(ids, []string{"abc", "def"}) {
+		t.Errorf("ChangeIDs() = %v, want [abc def]", ids)
+	}
+	want := []string{"log", "-r", "base..@-", "-T", `change_id ++ "\n"`, "--no-graph"}
+	if !slices.Equal(mock.calls[0].args, want) {
+		t.Errorf("ChangeIDs() args = %v, want %v", mock.calls[0].args, want)
+	}
+}
+
+func TestSquash(t *testing.T) {
+	mock := newMockRunner()
+	mock.addResponse("", "", nil)
+
+	client := NewClient("/test/repo")
+	client.SetCommandRunner(mock.run)
+
+	if err := client.Squash(context.Background(), "base..@-", "@", "Add login"); err != nil {
+		t.Fatalf("Squash() error = %v", err)
+	}
+	want := []string{"squash", "--from", "base..@-", "--into", "@", "-m", "Add login"}
+	if len(mock.calls) != 1 || !slices.Equal(mock.calls[0].args, want) {
+		t.Errorf("Squash() calls = %v, want %v", mock.calls, want)
+	}
+}
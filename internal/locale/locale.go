@@ -0,0 +1,168 @@
+// Package locale localizes the section headers and instructions of agent
+// prompts, so teams can have agents write in their own language. The status
+// markers agents signal with (DEV_DONE and the like) are never localized:
+// the loop matches them verbatim.
+package locale
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Default is the locale used when none is configured.
+const Default = "en"
+
+// Locale describes how prompts are written for one language.
+type Locale struct {
+	Code     string // Config value, e.g. "ja"
+	Language string // Name of the language, e.g. "Japanese"
+
+	// Headers maps the English section headers agents write, without their
+	// leading #s, to their localized equivalents. Headers without an entry
+	// stay in English.
+	Headers map[string]string
+
+	// Phrases pairs instruction sentences of the English prompts with their
+	// translations, replaced in order.
+	Phrases [][2]string
+
+	// None is the localized word for an empty issue list, accepted
+	// alongside "None".
+	None string
+
+	// Note is a section prepended to every prompt asking agents to write in
+	// the language while keeping the status markers verbatim.
+	Note string
+}
+
+// locales are the supported locales by code. English is the prompts as
+// written.
+var locales = map[string]Locale{
+	"en": {Code: "en", Language: "English"},
+	"ja": {
+		Code:     "ja",
+		Language: "Japanese",
+		Headers: map[string]string{
+			"Progress":         "進捗",
+			"Learnings":        "学び",
+			"Global Learnings": "全体の学び",
+			"Status":           "ステータス",
+			"Rebuttal":         "反論",
+			"Critical Issues":  "重大な問題",
+			"Major Issues":     "主要な問題",
+			"Minor Issues":     "軽微な問題",
+			"Verdict":          "判定",
+			"Response":         "回答",
+			"Tasks":            "タスク",
+		},
+		Phrases: [][2]string{
+			{
+				"Always output three sections with these exact headers, separated by horizontal rules:",
+				"次の見出しをそのまま使い、水平線で区切って3つのセクションを必ず出力してください:",
+			},
+			{
+				"Always output three sections with these exact headers:",
+				"次の見出しをそのまま使い、3つのセクションを必ず出力してください:",
+			},
+			{
+				"When you believe ALL work from the plan is complete and your implementation\nis correct, change the Status section to:",
+				"計画のすべての作業が完了し、実装が正しいと確信したら、ステータスセクションを次のように変更してください:",
+			},
+			{
+				"Review your changes carefully before signaling done. A reviewer will verify\nyour work, and if issues are found, you will need to address them.",
+				"完了を知らせる前に変更を慎重に見直してください。レビュアーが作業を検証し、問題が見つかれば対応が必要になります。",
+			},
+			{
+				"If ALL issue lists above are exactly \"None\":",
+				"上記の問題リストがすべて「None」の場合:",
+			},
+			{"Otherwise:", "それ以外の場合:"},
+		},
+		None: "なし",
+		Note: "# 言語\n\n" +
+			"このチームは日本語で作業しています。進捗、学び、レビューの指摘など、すべての文章を日本語で書いてください。" +
+			"セクションの見出しは、以下の出力形式に示された日本語の見出しをそのまま使ってください。\n\n" +
+			"ただし、次のマーカーはプログラムが読み取るため、翻訳も変更もせず、英語のまま正確に出力してください:\n" +
+			"- `RUNNING RUNNING RUNNING`\n" +
+			"- `DEV_DONE DEV_DONE DEV_DONE!!!`\n" +
+			"- `REVIEWER_APPROVED REVIEWER_APPROVED!!!`\n" +
+			"- `REVIEWER_FEEDBACK:`\n" +
+			"- `REBUTTAL_ACCEPTED REBUTTAL_ACCEPTED!!!`\n" +
+			"- `### Suggested Patch`\n\n",
+	},
+}
+
+// Get returns the locale for code, treating "" as Default.
+func Get(code string) (Locale, bool) {
+	if code == "" {
+		code = Default
+	}
+	l, ok := locales[code]
+	return l, ok
+}
+
+// Codes returns the supported locale codes, sorted.
+func Codes() []string {
+	codes := make([]string, 0, len(locales))
+	for code := range locales {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// HeaderAliases returns the localized equivalents of a markdown header such
+// as "## Progress" in every supported locale, keeping its #s. Agent output
+// is parsed with these whatever the configured locale, so a change of locale
+// never strands output already written.
+func HeaderAliases(header string) []string {
+	hashes, name, ok := strings.Cut(header, " ")
+	if !ok {
+		return nil
+	}
+	var aliases []string
+	for _, code := range Codes() {
+		if localized, ok := locales[code].Headers[name]; ok {
+			aliases = append(aliases, hashes+" "+localized)
+		}
+	}
+	return aliases
+}
+
+// IsNone reports whether s is "None", in any case, or its equivalent in a
+// supported locale.
+func IsNone(s string) bool {
+	if strings.EqualFold(s, "none") {
+		return true
+	}
+	for _, l := range locales {
+		if l.None != "" && s == l.None {
+			return true
+		}
+	}
+	return false
+}
+
+// headerLine matches a markdown section header line of a prompt template.
+var headerLine = regexp.MustCompile(`(?m)^(#{2,3}) (.+)$`)
+
+// Localize rewrites the text of an English prompt template for the locale:
+// its section headers and instruction phrases are translated and the
+// locale's note is prepended. Markers are left as they are.
+func (l Locale) Localize(text string) string {
+	if len(l.Headers) == 0 && len(l.Phrases) == 0 && l.Note == "" {
+		return text
+	}
+	text = headerLine.ReplaceAllStringFunc(text, func(line string) string {
+		hashes, name, _ := strings.Cut(line, " ")
+		if localized, ok := l.Headers[name]; ok {
+			return hashes + " " + localized
+		}
+		return line
+	})
+	for _, phrase := range l.Phrases {
+		text = strings.ReplaceAll(text, phrase[0], phrase[1])
+	}
+	return l.Note + text
+}
@@ -0,0 +1,62 @@
+package locale
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	if l, ok := Get(""); !ok || l.Code != Default {
+		t.Errorf("Get(\"\") = %+v, %v; want the default locale", l, ok)
+	}
+	if _, ok := Get("ja"); !ok {
+		t.Error("expected ja to be supported")
+	}
+	if _, ok := Get("xx"); ok {
+		t.Error("expected xx to be unsupported")
+	}
+}
+
+func TestHeaderAliases(t *testing.T) {
+	if got := HeaderAliases("## Progress"); !slices.Equal(got, []string{"## 進捗"}) {
+		t.Errorf("HeaderAliases(## Progress) = %q", got)
+	}
+	if got := HeaderAliases("### Verdict"); !slices.Equal(got, []string{"### 判定"}) {
+		t.Errorf("HeaderAliases(### Verdict) = %q", got)
+	}
+	if got := HeaderAliases("### Suggested Patch"); got != nil {
+		t.Errorf("expected no aliases for a marker header, got %q", got)
+	}
+}
+
+func TestIsNone(t *testing.T) {
+	for _, s := range []string{"None", "none", "なし"} {
+		if !IsNone(s) {
+			t.Errorf("IsNone(%q) = false", s)
+		}
+	}
+	if IsNone("- main.go:3 leaks a file") {
+		t.Error("expected an issue not to be none")
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	const text = "# Instructions\n\n## Progress\n[What you did]\n\nOtherwise:\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!\n\n### Suggested Patch\n"
+
+	en, _ := Get("en")
+	if got := en.Localize(text); got != text {
+		t.Errorf("expected English to be unchanged, got %q", got)
+	}
+
+	ja, _ := Get("ja")
+	got := ja.Localize(text)
+	for _, want := range []string{"# 言語", "## 進捗", "## ステータス", "それ以外の場合:", "DEV_DONE DEV_DONE DEV_DONE!!!\n", "### Suggested Patch\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in localized text:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "## Progress") || strings.Contains(got, "## Status") {
+		t.Errorf("expected the headers to be translated:\n%s", got)
+	}
+}
@@ -3,6 +3,7 @@ package log
 
 import (
 	"os"
+	"sync/atomic"
 
 	"github.com/charmbracelet/log"
 )
@@ -18,33 +19,73 @@ func init() {
 	})
 }
 
+// redactor masks secrets in logged messages and values (nil = log as-is).
+var redactor atomic.Pointer[func(string) string]
+
 // SetLevel sets the logging level.
 func SetLevel(level log.Level) {
 	Logger.SetLevel(level)
 }
 
+// SetRedactor masks secrets in every logged message and in string and error
+// values. Pass nil to log text as-is.
+func SetRedactor(redact func(string) string) {
+	if redact == nil {
+		redactor.Store(nil)
+		return
+	}
+	redactor.Store(&redact)
+}
+
+// scrub applies the redactor to a message and its key-value pairs.
+func scrub(msg interface{}, keyvals []interface{}) (interface{}, []interface{}) {
+	redact := redactor.Load()
+	if redact == nil {
+		return msg, keyvals
+	}
+	value := func(v interface{}) interface{} {
+		switch x := v.(type) {
+		case string:
+			return (*redact)(x)
+		case error:
+			return (*redact)(x.Error())
+		}
+		return v
+	}
+	scrubbed := make([]interface{}, len(keyvals))
+	for i, kv := range keyvals {
+		scrubbed[i] = value(kv)
+	}
+	return value(msg), scrubbed
+}
+
 // Debug logs a debug message.
 func Debug(msg interface{}, keyvals ...interface{}) {
+	msg, keyvals = scrub(msg, keyvals)
 	Logger.Debug(msg, keyvals...)
 }
 
 // Info logs an info message.
 func Info(msg interface{}, keyvals ...interface{}) {
+	msg, keyvals = scrub(msg, keyvals)
 	Logger.Info(msg, keyvals...)
 }
 
 // Warn logs a warning message.
 func Warn(msg interface{}, keyvals ...interface{}) {
+	msg, keyvals = scrub(msg, keyvals)
 	Logger.Warn(msg, keyvals...)
 }
 
 // Error logs an error message.
 func Error(msg interface{}, keyvals ...interface{}) {
+	msg, keyvals = scrub(msg, keyvals)
 	Logger.Error(msg, keyvals...)
 }
 
 // Fatal logs a fatal message and exits.
 func Fatal(msg interface{}, keyvals ...interface{}) {
+	msg, keyvals = scrub(msg, keyvals)
 	Logger.Fatal(msg, keyvals...)
 }
 
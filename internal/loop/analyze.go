@@ -0,0 +1,53 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/analyze"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// runAnalyzers runs the configured static analyzers on the files changed
+// since the review base and emits EventAnalyzerFindings when any of them
+// reported something.
+func (l *Loop) runAnalyzers(ctx context.Context) []analyze.Finding {
+	if l.deps.Analyzers == nil {
+		return nil
+	}
+
+	files, err := l.deps.VCS.ChangedFiles(ctx, l.reviewBaseChangeID(), "@", l.scopePaths()...)
+	if err != nil {
+		log.Warn("failed to list changed files for analyzers", "error", err)
+		return nil
+	}
+	findings := l.deps.Analyzers.Run(ctx, files)
+	if len(findings) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(findings))
+	for i, f := range findings {
+		names[i] = f.Analyzer
+	}
+	l.emit(NewEvent(EventAnalyzerFindings, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Static analysis findings from %s", strings.Join(names, ", "))))
+	return findings
+}
+
+// storeAnalyzerFindings stores the findings given to a reviewer session.
+func (l *Loop) storeAnalyzerFindings(sessionID string, findings []analyze.Finding) {
+	for _, f := range findings {
+		if err := l.deps.DB.CreateAnalyzerFinding(&db.AnalyzerFinding{
+			PlanID:    l.cfg.PlanID,
+			SessionID: sessionID,
+			Analyzer:  f.Analyzer,
+			Output:    f.Output,
+			Failed:    f.Failed,
+		}); err != nil {
+			log.Warn("failed to store analyzer findings", "analyzer", f.Analyzer, "error", err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package loop
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/analyze"
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+func TestLoop_AnalyzerFindingsReachReviewer(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	devClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	devClient.SetCommandCreator(mockClaudeCreator("## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"))
+	reviewerClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	reviewerClient.SetCommandCreator(mockClaudeCreator("## Progress\nReviewed\n\nREVIEWER_FEEDBACK: Fix the vet error"))
+
+	jjClient := jj.NewClient(workDir)
+	jjClient.SetCommandRunner(func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		if args[0] == "diff" && slices.Contains(args, "--name-only") {
+			return "main.go\nREADME.md\n", "", nil
+		}
+		return mockJJRunner()(ctx, dir, name, args...)
+	})
+
+	var analyzerArgs []string
+	analyzers := analyze.NewRunner(workDir, []analyze.Analyzer{
+		{Name: "go vet", Command: []string{"go", "vet", analyze.DirsPlaceholder}, Extensions: []string{".go"}},
+	})
+	analyzers.SetCommandRunner(func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		analyzerArgs = append([]string{name}, args...)
+		return "", "./main.go:3:2: unreachable code", nil
+	})
+
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 1, WorkDir: workDir}, Deps{
+		DB:             database,
+		Claude:         devClient,
+		ReviewerClaude: reviewerClient,
+		VCS:            jjClient,
+		Analyzers:      analyzers,
+	})
+
+	events := runLoop(t, loop)
+
+	if !slices.Equal(analyzerArgs, []string{"go", "vet", "."}) {
+		t.Errorf("analyzer ran as %v, want [go vet .]", analyzerArgs)
+	}
+
+	var reviewerPrompt string
+	sawFindings := false
+	for _, e := range events {
+		if e.Type == EventPromptBuilt && strings.Contains(e.Prompt, "# Diff to Review") {
+			reviewerPrompt = e.Prompt
+		}
+		if e.Type == EventAnalyzerFindings && strings.Contains(e.Message, "go vet") {
+			sawFindings = true
+		}
+	}
+	if !sawFindings {
+		t.Error("expected EventAnalyzerFindings")
+	}
+	if !strings.Contains(reviewerPrompt, "# Automated Findings") || !strings.Contains(reviewerPrompt, "unreachable code") {
+		t.Errorf("reviewer prompt missing findings:\n%s", reviewerPrompt)
+	}
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reviewed := false
+	for _, s := range sessions {
+		if s.AgentType != db.LoopAgentReviewer {
+			continue
+		}
+		reviewed = true
+		findings, err := database.GetAnalyzerFindingsBySession(s.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(findings) != 1 || findings[0].Analyzer != "go vet" || findings[0].Failed {
+			t.Errorf("stored findings = %+v", findings)
+		}
+	}
+	if !reviewed {
+		t.Error("expected a reviewer session")
+	}
+}
@@ -0,0 +1,56 @@
+package loop
+
+import (
+	"context"
+
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// setBookmark points the plan's bookmark at the revision, creating it if
+// needed. Failures are logged; the bookmark is a convenience.
+func (l *Loop) setBookmark(ctx context.Context, revision string) {
+	bookmarks, ok := l.deps.VCS.(Bookmarks)
+	if !ok || l.cfg.Bookmark == "" || revision == "" {
+		return
+	}
+	if err := bookmarks.SetBookmark(ctx, l.cfg.Bookmark, revision); err != nil {
+		log.Warn("failed to set plan bookmark", "bookmark", l.cfg.Bookmark, "revision", revision, "error", err)
+	}
+}
+
+// advanceBookmark moves the plan's bookmark to the work of the iteration
+// that just completed.
+func (l *Loop) advanceBookmark(ctx context.Context) {
+	if l.cfg.Bookmark == "" {
+		return
+	}
+	l.setBookmark(ctx, l.workRevision(ctx))
+}
+
+// finishBookmark leaves the plan's bookmark on its completed work, or
+// deletes it when DeleteBookmark is set.
+func (l *Loop) finishBookmark(ctx context.Context) {
+	if l.cfg.Bookmark == "" {
+		return
+	}
+	if !l.cfg.DeleteBookmark {
+		l.advanceBookmark(ctx)
+		return
+	}
+	bookmarks, ok := l.deps.VCS.(Bookmarks)
+	if !ok {
+		return
+	}
+	if err := bookmarks.DeleteBookmark(ctx, l.cfg.Bookmark); err != nil {
+		log.Warn("failed to delete plan bookmark", "bookmark", l.cfg.Bookmark, "error", err)
+	}
+}
+
+// workRevision is the change holding the plan's latest work: the working
+// copy, or its parent when the working copy is still empty.
+func (l *Loop) workRevision(ctx context.Context) string {
+	if empty, err := l.deps.VCS.IsEmpty(ctx); err == nil && empty {
+		return "@-"
+	}
+	return "@"
+}
@@ -0,0 +1,67 @@
+package loop
+
+import (
+	"context"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoop_Bookmark(t *testing.T) {
+	tests := []struct {
+		name           string
+		deleteBookmark bool
+		want           []string
+	}{
+		{
+			name: "kept",
+			want: []string{"set ralph/test -r base123", "set ralph/test -r @", "set ralph/test -r @"},
+		},
+		{
+			name:           "deleted",
+			deleteBookmark: true,
+			want:           []string{"set ralph/test -r base123", "set ralph/test -r @", "delete ralph/test"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := setupTestDB(t)
+			plan := createTestPlan(t, database, "Test plan content")
+
+			// The developer signals done and the reviewer approves it
+			callCount := 0
+			creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+				callCount++
+				output := "## Progress\nDone\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"
+				if callCount > 1 {
+					output = "## Progress\nReviewed\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!"
+				}
+				return exec.CommandContext(ctx, "echo", createMockClaudeOutputWithToolUse(output, "Read"))
+			}
+
+			var got []string
+			diffRunner := mockJJRunnerWithDiff("base123", "diff --git a/main.go b/main.go\n+func feature() {}\n")
+			runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+				if len(args) > 1 && args[0] == "bookmark" {
+					call := strings.Join(args[1:], " ")
+					got = append(got, strings.TrimSuffix(call, " --allow-backwards"))
+				}
+				return diffRunner(ctx, dir, name, args...)
+			}
+
+			loop := New(Config{
+				PlanID:         plan.ID,
+				MaxIterations:  5,
+				Bookmark:       "ralph/test",
+				DeleteBookmark: tt.deleteBookmark,
+			}, testDeps(database, creator, runner))
+			runLoop(t, loop)
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("bookmark calls = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
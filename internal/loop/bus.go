@@ -0,0 +1,216 @@
+package loop
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// defaultSubscriptionBuffer is the channel buffer of a subscription created
+// without WithBuffer.
+const defaultSubscriptionBuffer = 1000
+
+// Bus fans loop events out to any number of subscribers (TUI, persistence,
+// webhooks, metrics). Each subscriber gets its own channel, optionally
+// filtered by event type. Subscription channels are closed when the bus is.
+type Bus struct {
+	mu     sync.Mutex // Guards subs and closed; never held while sending
+	subs   []*Subscription
+	closed bool
+
+	// publishMu keeps concurrent publishes from interleaving, so every
+	// subscriber sees events in the same order
+	publishMu sync.Mutex
+}
+
+// Subscription receives the events a subscriber registered for.
+type Subscription struct {
+	ch      chan Event
+	types   map[EventType]bool // nil = all event types
+	lossy   bool
+	dropped atomic.Int64
+
+	// sendMu is read-held while an event is sent on ch and write-held to
+	// close it, so ch is never closed under a send. removed is closed on
+	// Unsubscribe to abandon a send blocked on a subscriber that stopped
+	// reading.
+	sendMu    sync.RWMutex
+	closed    bool
+	removed   chan struct{}
+	closeOnce sync.Once
+}
+
+// SubscribeOption configures a subscription.
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	buffer int
+	types  []EventType
+	lossy  bool
+}
+
+// WithTypes delivers only events of the given types.
+func WithTypes(types ...EventType) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.types = append(o.types, types...)
+	}
+}
+
+// WithBuffer sets the subscription's channel buffer size.
+func WithBuffer(size int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.buffer = size
+	}
+}
+
+// Lossy drops events when the subscription's buffer is full instead of
+// blocking the publisher. Use it for consumers, like the TUI, that must never
+// slow the loop down.
+func Lossy() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.lossy = true
+	}
+}
+
+// NewBus creates an event bus with no subscribers.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a subscriber. Events published before Subscribe are not
+// delivered to it. Subscribing to a closed bus returns a closed subscription.
+func (b *Bus) Subscribe(opts ...SubscribeOption) *Subscription {
+	o := subscribeOptions{buffer: defaultSubscriptionBuffer}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	sub := &Subscription{
+		ch:      make(chan Event, max(o.buffer, 0)),
+		lossy:   o.lossy,
+		removed: make(chan struct{}),
+	}
+	if len(o.types) > 0 {
+		sub.types = make(map[EventType]bool, len(o.types))
+		for _, t := range o.types {
+			sub.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.close()
+		return sub
+	}
+	b.subs = append(b.subs, sub)
+	return sub
+}
+
+// Unsubscribe removes a subscriber and closes its channel. An event being
+// sent to it is dropped rather than waiting for it to be read.
+func (b *Bus) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	found := false
+	for i, s := range b.subs {
+		if s == sub {
+			b.subs = append(b.subs[:i], b.subs[i+1:]...)
+			found = true
+			break
+		}
+	}
+	b.mu.Unlock()
+
+	if found {
+		sub.closeOnce.Do(func() { close(sub.removed) })
+		sub.close()
+	}
+}
+
+// Publish delivers an event to every subscriber whose filter matches.
+// Lossy subscribers drop the event when full; others block until there is
+// room, so a lossless subscriber must keep reading until its channel closes.
+// The bus isn't locked while sending, so subscribing and unsubscribing never
+// wait on a slow subscriber. Publishing to a closed bus does nothing.
+func (b *Bus) Publish(event Event) {
+	b.publishMu.Lock()
+	defer b.publishMu.Unlock()
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	subs := append([]*Subscription(nil), b.subs...)
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		if sub.types != nil && !sub.types[event.Type] {
+			continue
+		}
+		sub.send(event)
+	}
+}
+
+// Close closes every subscription channel. Further publishes are ignored.
+// It waits for an event being sent to a lossless subscriber to be read.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	subs := b.subs
+	b.subs = nil
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.close()
+	}
+}
+
+// send delivers an event unless the subscription was closed.
+func (s *Subscription) send(event Event) {
+	s.sendMu.RLock()
+	defer s.sendMu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	if !s.lossy {
+		select {
+		case s.ch <- event:
+		case <-s.removed:
+		}
+		return
+	}
+	select {
+	case s.ch <- event:
+	default:
+		s.dropped.Add(1)
+		log.Warn("event subscriber full, dropping event", "type", event.Type)
+	}
+}
+
+// close closes the subscription's channel once no send is in progress.
+func (s *Subscription) close() {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+// Events returns the channel the subscription's events arrive on. It is
+// closed when the bus closes or the subscription is removed.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Dropped returns how many events a lossy subscription has dropped.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
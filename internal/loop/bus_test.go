@@ -0,0 +1,143 @@
+package loop
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBus_FansOutToSubscribers(t *testing.T) {
+	bus := NewBus()
+	a := bus.Subscribe()
+	b := bus.Subscribe()
+
+	bus.Publish(NewEvent(EventStarted, 1, 5, "Loop started"))
+	bus.Close()
+
+	for name, sub := range map[string]*Subscription{"a": a, "b": b} {
+		var count int
+		for range sub.Events() {
+			count++
+		}
+		if count != 1 {
+			t.Errorf("subscriber %s received %d events, want 1", name, count)
+		}
+	}
+}
+
+func TestBus_TypeFilter(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(WithTypes(EventDone, EventError))
+
+	bus.Publish(NewEvent(EventStarted, 1, 5, "Loop started"))
+	bus.Publish(NewEvent(EventDone, 1, 5, "done"))
+	bus.Close()
+
+	var got []EventType
+	for event := range sub.Events() {
+		got = append(got, event.Type)
+	}
+	if len(got) != 1 || got[0] != EventDone {
+		t.Errorf("filtered events = %v, want [done]", got)
+	}
+}
+
+func TestBus_LossyDropsWhenFull(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(WithBuffer(1), Lossy())
+
+	// Must not block even though nobody reads
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			bus.Publish(NewEvent(EventClaudeStream, 1, 5, ""))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Publish blocked on a full lossy subscriber")
+	}
+
+	if got := sub.Dropped(); got != 2 {
+		t.Errorf("Dropped() = %d, want 2", got)
+	}
+}
+
+func TestBus_BlockingSubscriberReceivesEverything(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(WithBuffer(0))
+
+	received := make(chan int)
+	go func() {
+		count := 0
+		for range sub.Events() {
+			count++
+		}
+		received <- count
+	}()
+
+	for i := 0; i < 50; i++ {
+		bus.Publish(NewEvent(EventClaudeStream, 1, 5, ""))
+	}
+	bus.Close()
+
+	if got := <-received; got != 50 {
+		t.Errorf("blocking subscriber received %d events, want 50", got)
+	}
+}
+
+func TestBus_UnsubscribeClosesChannel(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe()
+	bus.Unsubscribe(sub)
+
+	if _, ok := <-sub.Events(); ok {
+		t.Error("expected closed channel after Unsubscribe")
+	}
+
+	// Publishing afterwards must not panic on the closed channel
+	bus.Publish(NewEvent(EventDone, 1, 5, "done"))
+	bus.Close()
+}
+
+func TestBus_SubscribeAfterClose(t *testing.T) {
+	bus := NewBus()
+	bus.Close()
+	bus.Publish(NewEvent(EventDone, 1, 5, "done"))
+
+	if _, ok := <-bus.Subscribe().Events(); ok {
+		t.Error("expected closed channel when subscribing to a closed bus")
+	}
+}
+
+func TestBus_SlowSubscriberDoesNotBlockUnsubscribe(t *testing.T) {
+	bus := NewBus()
+	stuck := bus.Subscribe(WithBuffer(0))
+
+	// Publish blocks on the subscriber that never reads
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(NewEvent(EventStarted, 1, 5, "Loop started"))
+		close(published)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		other := bus.Subscribe()
+		bus.Unsubscribe(other)
+		bus.Unsubscribe(stuck)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Subscribe or Unsubscribe blocked behind a send to a slow subscriber")
+	}
+	select {
+	case <-published:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Publish still blocked after its subscriber was removed")
+	}
+}
@@ -0,0 +1,132 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// recordIterationOperation records the jj operation the repository is at
+// as the iteration starts, before any of its changes, so ralph
+// undo-iteration can restore it.
+func (l *Loop) recordIterationOperation(ctx context.Context) {
+	ops, ok := l.deps.VCS.(OperationLog)
+	if !ok {
+		return
+	}
+	operationID, err := ops.CurrentOperation(ctx)
+	if err != nil {
+		log.Warn("failed to get jj operation for iteration", "iteration", l.iteration, "error", err)
+		return
+	}
+	if operationID == "" {
+		return
+	}
+	if err := l.deps.DB.RecordIterationOperation(l.cfg.PlanID, l.iteration, operationID); err != nil {
+		log.Warn("failed to store iteration operation", "error", err)
+	}
+}
+
+// startIterationChange moves the iteration onto a fresh jj change when
+// ChangePerIteration is set. An empty working-copy change is reused, keeping
+// any description it already has. On failure the iteration amends the
+// current change.
+func (l *Loop) startIterationChange(ctx context.Context) {
+	if !l.cfg.ChangePerIteration {
+		return
+	}
+
+	message := fmt.Sprintf("Iteration %d", l.iteration)
+	if l.task != nil {
+		message = fmt.Sprintf("Task %d: %s (iteration %d)", l.task.Sequence, l.task.Title, l.iteration)
+	}
+
+	empty, err := l.deps.VCS.IsEmpty(ctx)
+	if err == nil {
+		if !empty {
+			err = l.deps.VCS.New(ctx, message)
+		} else if description, descErr := l.deps.VCS.GetDescription(ctx, "@"); descErr != nil {
+			err = descErr
+		} else if strings.TrimSpace(description) == "" {
+			err = l.deps.VCS.Describe(ctx, message)
+		}
+	}
+	if err != nil {
+		log.Warn("failed to start jj change for iteration", "iteration", l.iteration, "error", err)
+	}
+}
+
+// squashPlanChanges folds every change made since the plan started into the
+// working-copy change and describes it from the plan when SquashOnComplete
+// is set, so a completed plan lands as a single change. Failures are logged
+// and leave the changes as they are.
+func (l *Loop) squashPlanChanges(ctx context.Context) {
+	if !l.cfg.SquashOnComplete {
+		return
+	}
+	if l.baseChangeID == "" {
+		log.Warn("cannot squash plan changes without a base change")
+		return
+	}
+
+	from := l.baseChangeID + "..@-"
+	ids, err := l.deps.VCS.ChangeIDs(ctx, from)
+	if err != nil {
+		log.Warn("failed to list plan changes", "error", err)
+		return
+	}
+
+	message := l.squashDescription()
+	if len(ids) == 0 {
+		err = l.deps.VCS.Describe(ctx, message)
+	} else {
+		err = l.deps.VCS.Squash(ctx, from, "@", message)
+	}
+	if err != nil {
+		log.Warn("failed to squash plan changes", "error", err)
+		return
+	}
+	log.Info("squashed plan changes", "changes", len(ids)+1)
+
+	// The new description replaced any review trailers
+	l.addReviewTrailers(ctx, "@")
+}
+
+// planTitle returns a plan's first non-empty line, stripped of markdown
+// heading markers, or "" for an empty plan.
+func planTitle(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(strings.TrimLeft(line, "# ")); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// squashDescription describes a squashed plan: its first non-empty line as
+// the title, followed by the completed tasks, if any.
+func (l *Loop) squashDescription() string {
+	title := "Plan " + l.cfg.PlanID
+	if l.plan != nil {
+		if first := planTitle(l.plan.Content); first != "" {
+			title = first
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(title)
+	iterations := "iterations"
+	if l.iteration == 1 {
+		iterations = "iteration"
+	}
+	fmt.Fprintf(&b, "\n\nCompleted by ralph in %d %s (plan %s).", l.iteration, iterations, l.cfg.PlanID)
+	if len(l.tasks) > 0 {
+		b.WriteString("\n")
+		for _, task := range l.tasks {
+			fmt.Fprintf(&b, "\n- %s", task.Title)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,69 @@
+package loop
+
+import (
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/parser"
+)
+
+// planChecklist returns the checks of the "review_checklist:" block of the
+// plan's front matter.
+func (l *Loop) planChecklist() []string {
+	checks, err := agent.PlanChecklist(l.plan.Content)
+	if err != nil {
+		// A merged plan edit broke the front matter
+		log.Warn("invalid plan front matter, not checking the review checklist", "error", err)
+		return nil
+	}
+	return checks
+}
+
+// planAcceptanceCriteria returns the criteria of the "acceptance_criteria:"
+// block of the plan's front matter. They describe the finished plan, so in
+// task mode they are only verified with the last task.
+func (l *Loop) planAcceptanceCriteria() []string {
+	if l.task != nil && l.task != l.tasks[len(l.tasks)-1] {
+		return nil
+	}
+	criteria, err := agent.AcceptanceCriteria(l.plan.Content)
+	if err != nil {
+		log.Warn("invalid plan front matter, not checking the acceptance criteria", "error", err)
+		return nil
+	}
+	return criteria
+}
+
+// checkChecklist holds a review of a done signal to the plan's review
+// checklist and acceptance criteria. The reviewer's verdicts are matched to
+// the plan's checks and criteria, and any that failed, or that the reviewer
+// didn't report, are added to the feedback and turn an approval into a
+// rejection.
+func (l *Loop) checkChecklist(review *parser.AgentParseResult, devDone bool) {
+	if !devDone {
+		return
+	}
+	var failed []string
+	if checks := l.planChecklist(); len(checks) > 0 {
+		review.Checklist = parser.MatchChecklist(checks, review.Checklist)
+		if f := parser.FailedChecks(review.Checklist); f != "" {
+			failed = append(failed, f)
+		}
+	}
+	if criteria := l.planAcceptanceCriteria(); len(criteria) > 0 {
+		review.Criteria = parser.MatchChecklist(criteria, review.Criteria)
+		if f := parser.FailedCriteria(review.Criteria); f != "" {
+			failed = append(failed, f)
+		}
+	}
+	if len(failed) == 0 {
+		return
+	}
+	if review.ReviewerApproved {
+		log.Info("reviewer approved with failed plan checks, treating as a rejection")
+		review.ReviewerApproved = false
+		review.ReviewerFeedback = ""
+	}
+	review.ReviewerFeedback = strings.TrimSpace(review.ReviewerFeedback + "\n\n" + strings.Join(failed, "\n\n"))
+}
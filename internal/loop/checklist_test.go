@@ -0,0 +1,121 @@
+package loop
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+func TestLoop_PlanChecklist(t *testing.T) {
+	const planContent = "---\nreview_checklist:\n  - no new deps\n  - strings extracted\n---\n# Plan\n"
+	tests := []struct {
+		name      string
+		checklist string
+		completed bool
+		feedback  string
+	}{
+		{"all pass", "- [x] no new deps\n- [x] strings extracted", true, ""},
+		{"one fails", "- [x] no new deps\n- [ ] strings extracted: the error message is hardcoded", false,
+			"Failed plan checks:\n- strings extracted: the error message is hardcoded"},
+		{"not reported", "- [x] no new deps", false, "- strings extracted: not reported by the reviewer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := setupTestDB(t)
+			plan := createTestPlan(t, database, planContent)
+
+			var mu sync.Mutex
+			calls := 0
+			creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+				mu.Lock()
+				defer mu.Unlock()
+				calls++
+				output := "## Progress\nDone\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"
+				if calls%2 == 0 {
+					output = "## Progress\nReviewed\n\n### Plan Checklist\n" + tt.checklist +
+						"\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!"
+				}
+				return exec.CommandContext(ctx, "echo", createMockClaudeOutput(output))
+			}
+
+			loop := New(Config{PlanID: plan.ID, MaxIterations: 1, WorkDir: "/tmp"}, testDeps(database, creator, mockJJRunner()))
+			events := runLoop(t, loop)
+
+			if _, completed := doneRejections(events); completed != tt.completed {
+				t.Errorf("completed = %v, want %v", completed, tt.completed)
+			}
+			feedback, err := database.GetLatestReviewerFeedback(plan.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.feedback == "" {
+				if feedback != nil {
+					t.Errorf("expected no feedback, got %q", feedback.Content)
+				}
+			} else if feedback == nil || !strings.Contains(feedback.Content, tt.feedback) {
+				t.Errorf("expected feedback containing %q, got %+v", tt.feedback, feedback)
+			}
+		})
+	}
+}
+
+func TestLoop_AcceptanceCriteria(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "---\nacceptance_criteria:\n  - header row written\n  - commas quoted\n---\n# Plan\n")
+
+	var mu sync.Mutex
+	calls := 0
+	var reviewerPrompt string
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		output := "## Progress\nDone\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"
+		if calls%2 == 0 {
+			reviewerPrompt = args[len(args)-1]
+			output = "## Progress\nReviewed\n\n### Acceptance Criteria\n- [x] header row written\n- [ ] commas quoted: a,b is split\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!"
+		}
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput(output))
+	}
+
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 1, WorkDir: "/tmp"}, testDeps(database, creator, mockJJRunner()))
+	events := runLoop(t, loop)
+
+	if _, completed := doneRejections(events); completed {
+		t.Error("expected an unsatisfied criterion to reject the done signal")
+	}
+	feedback, err := database.GetLatestReviewerFeedback(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feedback == nil || !strings.Contains(feedback.Content, "Unsatisfied acceptance criteria:\n- commas quoted: a,b is split") {
+		t.Errorf("expected the unsatisfied criterion in the feedback, got %+v", feedback)
+	}
+	if !strings.Contains(reviewerPrompt, "- commas quoted\n") {
+		t.Error("expected the acceptance criteria in the reviewer prompt")
+	}
+}
+
+func TestLoop_InvalidPlanChecklist(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "---\nreview_checklist: no new deps\n---\n# Plan\n")
+
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 1, WorkDir: "/tmp"}, Deps{
+		DB:     database,
+		Claude: claude.NewClient(claude.ClientConfig{Model: "test"}),
+		VCS:    jj.NewClient("/tmp"),
+	})
+	go func() {
+		for range loop.Events() {
+		}
+	}()
+	err := loop.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "review_checklist must be a block") {
+		t.Errorf("Run() error = %v, want an invalid review_checklist error", err)
+	}
+}
@@ -0,0 +1,148 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// maxCheckOutputBytes caps the output of a failing check passed to the
+// developer.
+const maxCheckOutputBytes = 16 * 1024
+
+// Check is a test or verification command run after each developer session
+// (see Deps.Checks).
+type Check struct {
+	Name string // Label for its output, e.g. "tests"
+	Gate TestGate
+}
+
+// Lines of test runner output: go test -v's lines for a test starting,
+// passing or being skipped, and failing, which end the test's log; lines
+// summarizing a package, which end its tests; and the lines of other
+// runners for a passing test (jest's PASS, pytest's PASSED, ✓ marks).
+var (
+	testStartLine   = regexp.MustCompile(`^\s*=== (RUN|PAUSE|CONT|NAME)\s`)
+	testPassLine    = regexp.MustCompile(`^\s*--- (PASS|SKIP):`)
+	testFailLine    = regexp.MustCompile(`^\s*--- FAIL:|^FAIL(\s|$)`)
+	packagePassLine = regexp.MustCompile(`^(ok\s+\S+|\?\s+\S+\s+\[no test files\]|PASS$)`)
+	runnerPassLine  = regexp.MustCompile(`^\s*(PASS\s|✓|√)|\sPASSED(\s|\[|$)`)
+)
+
+// runChecks runs the configured checks on the developer session's changes.
+// The output of failing ones is trimmed to the failures, stored with the
+// session, and kept for the next developer prompt.
+func (l *Loop) runChecks(ctx context.Context, sessionID string) {
+	l.failingChecks = ""
+	if len(l.deps.Checks) == 0 {
+		return
+	}
+
+	var b strings.Builder
+	var failed []string
+	var firstOutput string
+	for _, check := range l.deps.Checks {
+		output, passed := check.Gate.Run(ctx)
+		if passed {
+			continue
+		}
+		output = trimTestOutput(output, maxCheckOutputBytes)
+		if err := l.deps.DB.CreateCheckFailure(&db.CheckFailure{
+			PlanID:    l.cfg.PlanID,
+			SessionID: sessionID,
+			Name:      check.Name,
+			Output:    output,
+		}); err != nil {
+			log.Warn("failed to store check failure", "check", check.Name, "error", err)
+		}
+
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "## %s\n\n```\n%s\n```", check.Name, output)
+		if len(failed) == 0 {
+			firstOutput = output
+		}
+		failed = append(failed, check.Name)
+	}
+	l.observeCheckFailures(sessionID, failed, firstOutput)
+	if len(failed) == 0 {
+		return
+	}
+
+	l.failingChecks = b.String()
+	if l.iterationFailure == "" {
+		l.iterationFailure = OutcomeGateFailures
+	}
+	l.recordFailure("Checks failed", strings.Join(failed, ", "), l.failingChecks)
+	l.emit(NewEvent(EventChecksFailed, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Checks failed: %s", strings.Join(failed, ", "))))
+}
+
+// takeFailingChecks returns the output of the checks that failed after the
+// last developer session, for the next developer prompt, and clears it.
+func (l *Loop) takeFailingChecks() string {
+	failing := l.failingChecks
+	l.failingChecks = ""
+	return failing
+}
+
+// trimTestOutput trims a failing test run's output to its failures: lines
+// of tests that passed, were skipped, or merely started are dropped, along
+// with what a passing go test -v test logged. Output that is still longer
+// than maxBytes keeps its beginning and end, where runners print the first
+// failure and the summary. Output with nothing but passes is kept whole.
+func trimTestOutput(output string, maxBytes int) string {
+	// pending holds what go test -v logged since a test started, dropped
+	// if the test passes
+	var kept, pending []string
+	inTest := false
+	for _, line := range strings.Split(strings.Trim(output, "\n"), "\n") {
+		switch {
+		case testStartLine.MatchString(line):
+			// A test starting ends the previous test's log, which is kept:
+			// it may belong to a parent test that fails later
+			kept = append(kept, pending...)
+			pending = pending[:0]
+			inTest = true
+		case testFailLine.MatchString(line):
+			kept = append(append(kept, pending...), line)
+			pending = pending[:0]
+		case testPassLine.MatchString(line):
+			pending = pending[:0]
+		case packagePassLine.MatchString(line):
+			kept = append(kept, pending...)
+			pending = pending[:0]
+			inTest = false
+		case runnerPassLine.MatchString(line):
+		case inTest:
+			pending = append(pending, line)
+		default:
+			kept = append(kept, line)
+		}
+	}
+	kept = append(kept, pending...)
+
+	trimmed := strings.Trim(strings.Join(kept, "\n"), "\n")
+	if strings.TrimSpace(trimmed) == "" {
+		trimmed = strings.TrimSpace(output)
+	}
+	if len(trimmed) <= maxBytes {
+		return trimmed
+	}
+
+	half := maxBytes / 2
+	head, tail := runeCut(trimmed, half), runeTail(trimmed, half)
+	if i := strings.LastIndexByte(head, '\n'); i > 0 {
+		head = head[:i]
+	}
+	if i := strings.IndexByte(tail, '\n'); i >= 0 {
+		tail = tail[i+1:]
+	}
+	omitted := strings.Count(trimmed, "\n") - strings.Count(head, "\n") - strings.Count(tail, "\n") - 1
+	return fmt.Sprintf("%s\n[... %d lines omitted ...]\n%s", head, omitted, tail)
+}
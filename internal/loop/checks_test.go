@@ -0,0 +1,131 @@
+package loop
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+func TestLoop_FailingChecksReachDeveloper(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	reviewerClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	reviewerClient.SetCommandCreator(mockClaudeCreator("## Progress\nReviewed\n\nREVIEWER_FEEDBACK: Keep going"))
+
+	// The tests fail after the first session and pass after the second
+	tests := &fakeTestGate{passes: []bool{false, true, true}}
+	deps := testDeps(database, mockClaudeCreator("## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"), mockJJRunner())
+	deps.ReviewerClaude = reviewerClient
+	deps.Checks = []Check{{Name: "tests", Gate: tests}}
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 3, WorkDir: "/tmp"}, deps)
+
+	var devPrompts []string
+	sawFailure := false
+	events, _ := runLoopErr(loop)
+	for _, event := range events {
+		if event.Type == EventPromptBuilt && !strings.Contains(event.Prompt, "# Diff to Review") {
+			devPrompts = append(devPrompts, event.Prompt)
+		}
+		if event.Type == EventChecksFailed && strings.Contains(event.Message, "tests") {
+			sawFailure = true
+		}
+	}
+
+	if !sawFailure {
+		t.Error("expected EventChecksFailed")
+	}
+	if len(devPrompts) != 3 {
+		t.Fatalf("expected 3 developer prompts, got %d", len(devPrompts))
+	}
+	if strings.Contains(devPrompts[0], "# Failing Checks") {
+		t.Error("first developer prompt should not have failing checks")
+	}
+	if !strings.Contains(devPrompts[1], "# Failing Checks") || !strings.Contains(devPrompts[1], "## tests\n\n```\nrun 1\n```") {
+		t.Errorf("second developer prompt missing the failing checks:\n%s", devPrompts[1])
+	}
+	if strings.Contains(devPrompts[2], "# Failing Checks") {
+		t.Error("checks that passed again should leave the prompt")
+	}
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored []*db.CheckFailure
+	for _, s := range sessions {
+		failures, err := database.GetCheckFailuresBySession(s.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored = append(stored, failures...)
+	}
+	if len(stored) != 1 || stored[0].Name != "tests" || stored[0].Output != "run 1" {
+		t.Errorf("stored failures = %+v", stored)
+	}
+}
+
+func TestTrimTestOutput(t *testing.T) {
+	goTest := `=== RUN   TestParse
+--- PASS: TestParse (0.00s)
+=== RUN   TestLex
+    lex_test.go:12: lexing "a+b"
+--- PASS: TestLex (0.00s)
+=== RUN   TestEval
+=== RUN   TestEval/add
+    eval_test.go:30: got 3, want 4
+=== RUN   TestEval/sub
+    eval_test.go:31: subtracting
+--- FAIL: TestEval (0.00s)
+    --- FAIL: TestEval/add (0.00s)
+    --- PASS: TestEval/sub (0.00s)
+FAIL
+FAIL	example.com/calc	0.01s
+ok  	example.com/calc/lex	0.01s
+?   	example.com/calc/cmd	[no test files]`
+	want := `    eval_test.go:30: got 3, want 4
+    eval_test.go:31: subtracting
+--- FAIL: TestEval (0.00s)
+    --- FAIL: TestEval/add (0.00s)
+FAIL
+FAIL	example.com/calc	0.01s`
+	if got := trimTestOutput(goTest, 4096); got != want {
+		t.Errorf("go test output trimmed to:\n%s\nwant:\n%s", got, want)
+	}
+
+	pytest := `test_calc.py::test_parse PASSED [ 50%]
+test_calc.py::test_eval FAILED [100%]
+E   assert 3 == 4`
+	if got := trimTestOutput(pytest, 4096); got != "test_calc.py::test_eval FAILED [100%]\nE   assert 3 == 4" {
+		t.Errorf("pytest output trimmed to %q", got)
+	}
+
+	if got := trimTestOutput("--- PASS: TestA (0.00s)\nok  \tpkg", 4096); got != "--- PASS: TestA (0.00s)\nok  \tpkg" {
+		t.Errorf("output with only passes should be kept, got %q", got)
+	}
+
+	var long strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&long, "error %03d\n", i)
+	}
+	got := trimTestOutput(long.String(), 100)
+	if !strings.HasPrefix(got, "error 001\n") || !strings.HasSuffix(got, "error 100") || !strings.Contains(got, "lines omitted") {
+		t.Errorf("long output trimmed to:\n%s", got)
+	}
+	if len(got) > 130 {
+		t.Errorf("long output kept %d bytes", len(got))
+	}
+}
+
+func TestLoop_TakeFailingChecks(t *testing.T) {
+	l := &Loop{failingChecks: "## tests"}
+	if got := l.takeFailingChecks(); got != "## tests" {
+		t.Errorf("takeFailingChecks() = %q", got)
+	}
+	if got := l.takeFailingChecks(); got != "" {
+		t.Errorf("second takeFailingChecks() = %q, want empty", got)
+	}
+}
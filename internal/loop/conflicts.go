@@ -0,0 +1,134 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// conflictPollInterval is how often jj status is checked while waiting for
+// conflicts to be resolved by hand (variable for tests).
+var conflictPollInterval = 5 * time.Second
+
+// handleConflicts checks the working copy for jj conflicts and, when there
+// are any, emits EventConflictDetected and holds the loop until they are
+// gone: a conflict resolver agent tries first if enabled, then the loop
+// waits for the rest to be resolved by hand. It returns early only if ctx
+// is done.
+func (l *Loop) handleConflicts(ctx context.Context) error {
+	if !l.cfg.WaitOnConflicts {
+		return nil
+	}
+	conflicts, err := l.deps.VCS.Conflicts(ctx)
+	if err != nil {
+		log.Warn("failed to check the working copy for conflicts", "error", err)
+		return nil
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	l.emit(NewEvent(EventConflictDetected, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("jj reports conflicts in %s", strings.Join(conflicts, ", "))))
+
+	if l.cfg.ResolveConflicts {
+		if conflicts, err = l.runConflictResolver(ctx, conflicts); err != nil {
+			return err
+		}
+		if len(conflicts) == 0 {
+			l.emit(NewEvent(EventConflictResolved, l.iteration, l.effectiveMaxIter(),
+				"Conflict resolver resolved the conflicts"))
+			return nil
+		}
+	}
+
+	return l.waitForConflicts(ctx, conflicts)
+}
+
+// runConflictResolver runs a conflict resolver agent session and returns
+// the conflicts left afterwards. A failed session leaves them for manual
+// resolution.
+func (l *Loop) runConflictResolver(ctx context.Context, conflicts []string) ([]string, error) {
+	l.startSessionTimer()
+	status, err := l.deps.VCS.Status(ctx)
+	if err != nil {
+		log.Warn("failed to get jj status for the conflict resolver", "error", err)
+	}
+	prompt, err := agent.BuildConflictResolverPrompt(agent.ConflictResolverContext{
+		PlanContent: l.plan.Content,
+		Conflicts:   conflicts,
+		Status:      status,
+		Locale:      l.cfg.Locale,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build conflict resolver prompt: %w", err)
+	}
+	l.promptBuilt()
+
+	l.emit(NewEvent(EventConflictResolverStart, l.iteration, l.effectiveMaxIter(), "Starting conflict resolver agent"))
+	l.emit(NewPromptBuiltEvent(l.iteration, l.effectiveMaxIter(), prompt))
+
+	sessionID := uuid.New().String()
+	session := &db.PlanSession{
+		ID:          sessionID,
+		PlanID:      l.cfg.PlanID,
+		Iteration:   l.iteration,
+		InputPrompt: prompt,
+		Status:      db.PlanSessionRunning,
+		AgentType:   db.LoopAgentConflictResolver,
+	}
+	if err := l.deps.DB.CreatePlanSession(session); err != nil {
+		return nil, fmt.Errorf("failed to create conflict resolver session: %w", err)
+	}
+
+	_, err = l.runClaudeSession(ctx, sessionID, prompt, l.deps.Claude)
+	l.finishSessionTimer(sessionID, 0)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Warn("conflict resolver agent failed", "error", err)
+		l.emit(NewErrorEvent(l.iteration, l.effectiveMaxIter(), fmt.Errorf("conflict resolver agent failed: %w", err)))
+		return conflicts, nil
+	}
+
+	remaining, err := l.deps.VCS.Conflicts(ctx)
+	if err != nil {
+		log.Warn("failed to check the working copy for conflicts", "error", err)
+		return conflicts, nil
+	}
+	return remaining, nil
+}
+
+// waitForConflicts pauses the plan until jj reports no conflicts, checking
+// every conflictPollInterval. It returns early only if ctx is done.
+func (l *Loop) waitForConflicts(ctx context.Context, conflicts []string) error {
+	reason := fmt.Sprintf("Waiting for conflicts to be resolved in %s", strings.Join(conflicts, ", "))
+	l.pausePlan(reason)
+	l.emit(NewEvent(EventConflictDetected, l.iteration, l.effectiveMaxIter(),
+		reason+"; resolve them with jj and ralph continues"))
+
+	for len(conflicts) > 0 {
+		if err := sleepContext(ctx, conflictPollInterval); err != nil {
+			return err
+		}
+		remaining, err := l.deps.VCS.Conflicts(ctx)
+		if err != nil {
+			log.Warn("failed to check the working copy for conflicts", "error", err)
+			continue
+		}
+		conflicts = remaining
+	}
+
+	if err := l.deps.DB.UpdatePlanStatus(l.cfg.PlanID, db.PlanStatusRunning); err != nil {
+		log.Warn("failed to update plan status", "error", err)
+	}
+	l.emit(NewEvent(EventConflictResolved, l.iteration, l.effectiveMaxIter(), "Conflicts resolved, continuing"))
+	return nil
+}
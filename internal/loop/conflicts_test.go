@@ -0,0 +1,128 @@
+package loop
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+const conflictedStatus = "Working copy changes:\nC api/handler.go\n" +
+	"There are unresolved conflicts at these paths:\napi/handler.go    2-sided conflict\n" +
+	"Working copy : qpvuntsm 1f5e0a2c (conflict) (no description set)\n"
+
+// conflictRun is the outcome of a one-iteration loop that starts with a
+// conflicted working copy.
+type conflictRun struct {
+	database *db.DB
+	plan     *db.Plan
+	events   []Event
+	sessions int // Claude sessions run
+}
+
+// runConflictLoop runs one iteration whose working copy is conflicted until
+// resolved, called on each jj status, reports true.
+func runConflictLoop(t *testing.T, resolve bool, resolved func(run *conflictRun, statusCalls int) bool) *conflictRun {
+	t.Helper()
+	run := &conflictRun{database: setupTestDB(t)}
+	run.plan = createTestPlan(t, run.database, "Test plan content")
+
+	var mu sync.Mutex
+	output := createMockClaudeOutput("## Progress\nWorking\n\n## Status\nRUNNING")
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		mu.Lock()
+		defer mu.Unlock()
+		run.sessions++
+		return exec.CommandContext(ctx, "echo", output)
+	}
+
+	statusCalls := 0
+	runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		if len(args) > 0 && args[0] == "status" {
+			mu.Lock()
+			defer mu.Unlock()
+			statusCalls++
+			if !resolved(run, statusCalls) {
+				return conflictedStatus, "", nil
+			}
+			return "The working copy is clean\n", "", nil
+		}
+		return "", "", nil
+	}
+
+	loop := New(Config{
+		PlanID:           run.plan.ID,
+		MaxIterations:    1,
+		WorkDir:          "/tmp",
+		WaitOnConflicts:  true,
+		ResolveConflicts: resolve,
+	}, testDeps(run.database, creator, runner))
+
+	run.events = runLoop(t, loop)
+	return run
+}
+
+// count returns how many events of the type the run emitted.
+func (r *conflictRun) count(eventType EventType) int {
+	n := 0
+	for _, e := range r.events {
+		if e.Type == eventType {
+			n++
+		}
+	}
+	return n
+}
+
+func TestLoop_ConflictResolverResolvesConflicts(t *testing.T) {
+	// Conflicted until the resolver's session has run
+	run := runConflictLoop(t, true, func(run *conflictRun, _ int) bool { return run.sessions > 0 })
+
+	if run.count(EventConflictDetected) != 1 || run.count(EventConflictResolverStart) != 1 || run.count(EventConflictResolved) != 1 {
+		t.Errorf("expected one detection, resolver start, and resolution, got events %+v", run.events)
+	}
+	// The resolver, then the developer and reviewer
+	if run.sessions != 3 {
+		t.Errorf("expected 3 sessions, got %d", run.sessions)
+	}
+
+	sessions, err := run.database.GetPlanSessionsByPlan(run.plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) == 0 || sessions[0].AgentType != db.LoopAgentConflictResolver || sessions[0].Iteration != 1 {
+		t.Errorf("expected a conflict resolver session first, got %+v", sessions)
+	}
+}
+
+func TestLoop_WaitsForManualConflictResolution(t *testing.T) {
+	orig := conflictPollInterval
+	conflictPollInterval = time.Millisecond
+	t.Cleanup(func() { conflictPollInterval = orig })
+
+	var statusDuringWait db.PlanStatus
+	run := runConflictLoop(t, false, func(run *conflictRun, statusCalls int) bool {
+		if statusCalls == 2 {
+			if plan, err := run.database.GetPlan(run.plan.ID); err == nil {
+				statusDuringWait = plan.Status
+			}
+		}
+		return statusCalls > 3
+	})
+
+	if run.count(EventConflictResolverStart) != 0 {
+		t.Error("expected no conflict resolver in wait mode")
+	}
+	if run.count(EventConflictDetected) != 2 || run.count(EventConflictResolved) != 1 {
+		t.Errorf("expected detection, a wait, and resolution, got events %+v", run.events)
+	}
+	// Only the developer and reviewer, once the conflicts were gone
+	if run.sessions != 2 {
+		t.Errorf("expected 2 sessions, got %d", run.sessions)
+	}
+	if statusDuringWait != db.PlanStatusPaused {
+		t.Errorf("plan status while waiting = %q, want paused", statusDuringWait)
+	}
+}
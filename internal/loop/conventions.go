@@ -0,0 +1,36 @@
+package loop
+
+import (
+	"context"
+
+	"github.com/gerunddev/ralph/internal/conventions"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// loadConventions returns the repository's convention files formatted for
+// the developer and reviewer prompts, or "" when there are none. A scoped
+// plan also gets the convention files of its scope's directory.
+func (l *Loop) loadConventions(ctx context.Context) string {
+	if l.deps.Conventions == nil {
+		return ""
+	}
+
+	root := l.repoRoot
+	if root == "" {
+		var err error
+		if root, err = l.deps.VCS.Root(ctx); err != nil || root == "" {
+			log.Warn("failed to resolve repo root, using work dir for convention files", "error", err)
+			root = l.cfg.WorkDir
+		}
+	}
+
+	files, err := l.deps.Conventions.Load(root, l.scopePaths()...)
+	if err != nil {
+		log.Warn("failed to load convention files", "error", err)
+		return ""
+	}
+	for _, f := range files {
+		log.Debug("including convention file in prompts", "path", f.Path, "truncated", f.Truncated)
+	}
+	return conventions.Format(files)
+}
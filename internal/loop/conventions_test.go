@@ -0,0 +1,52 @@
+package loop
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/conventions"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+func TestLoop_IncludesConventionFiles(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "CONTRIBUTING.md"), []byte("Wrap errors with %w."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	claudeClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	claudeClient.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput("## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"))
+	})
+	jjClient := jj.NewClient(workDir)
+	jjClient.SetCommandRunner(mockJJRunnerEmpty())
+
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 1, WorkDir: workDir}, Deps{
+		DB:          database,
+		Claude:      claudeClient,
+		VCS:         jjClient,
+		Conventions: conventions.NewProvider(conventions.DefaultInclude, nil, 0),
+	})
+	runLoop(t, loop)
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected a developer and a reviewer session, got %d", len(sessions))
+	}
+	for _, s := range sessions {
+		if !strings.Contains(s.InputPrompt, "## CONTRIBUTING.md") || !strings.Contains(s.InputPrompt, "Wrap errors with %w.") {
+			t.Errorf("expected the %s prompt to include CONTRIBUTING.md", s.AgentType)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package loop
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// costWarnFraction is the share of Config.MaxCost past which the plan's
+// cost is shown as a warning.
+const costWarnFraction = 0.8
+
+// PlanUsage is the Claude cost and token usage of a plan so far, across
+// runs.
+type PlanUsage struct {
+	CostUSD    float64
+	Tokens     int     // Input (including cache reads and writes) and output tokens
+	MaxCostUSD float64 // Config.MaxCost (0 = no budget)
+}
+
+// Fraction returns the share of the cost budget spent, or 0 without one.
+func (u PlanUsage) Fraction() float64 {
+	if u.MaxCostUSD <= 0 {
+		return 0
+	}
+	return u.CostUSD / u.MaxCostUSD
+}
+
+// OverBudget reports whether the cost budget is spent.
+func (u PlanUsage) OverBudget() bool {
+	return u.MaxCostUSD > 0 && u.CostUSD >= u.MaxCostUSD
+}
+
+// NearBudget reports whether the cost is past the warning share of the
+// budget.
+func (u PlanUsage) NearBudget() bool {
+	return u.MaxCostUSD > 0 && u.Fraction() >= costWarnFraction
+}
+
+// add accumulates a session's result into the usage.
+func (u *PlanUsage) add(result *claude.ResultContent) {
+	tokens := result.TotalUsage
+	u.CostUSD += result.CostUSD
+	u.Tokens += tokens.InputTokens + tokens.CacheRead + tokens.CacheCreate + tokens.OutputTokens
+}
+
+// loadUsage sums the cost and tokens reported by the result events of the
+// earlier sessions of the plan and its sub-plans, so the budget covers every
+// run of the plan.
+func (l *Loop) loadUsage() {
+	l.usage = PlanUsage{MaxCostUSD: l.cfg.MaxCost}
+	planIDs := []string{l.cfg.PlanID}
+	for i := 0; i < len(planIDs); i++ {
+		subPlans, err := l.deps.DB.GetSubPlans(planIDs[i])
+		if err != nil {
+			log.Warn("failed to load sub-plans for their cost", "error", err)
+		}
+		for _, sub := range subPlans {
+			planIDs = append(planIDs, sub.ID)
+		}
+		l.loadSessionUsage(planIDs[i])
+	}
+}
+
+// loadSessionUsage adds the cost and tokens reported by the result events of
+// one plan's sessions to the usage.
+func (l *Loop) loadSessionUsage(planID string) {
+	sessions, err := l.deps.DB.GetPlanSessionsByPlan(planID)
+	if err != nil {
+		log.Warn("failed to load plan sessions for its cost", "error", err)
+		return
+	}
+	for _, session := range sessions {
+		events, err := l.deps.DB.GetEventsBySession(session.ID)
+		if err != nil {
+			log.Warn("failed to load session events for its cost", "session", session.ID, "error", err)
+			continue
+		}
+		for _, e := range events {
+			if e.EventType != string(claude.EventResult) || e.SubAgentID != "" {
+				continue
+			}
+			event, err := claude.NewParser(strings.NewReader(e.RawJSON)).Next()
+			if err != nil || event.Result == nil {
+				continue
+			}
+			l.usage.add(event.Result)
+		}
+	}
+}
+
+// recordUsage adds a finished session's cost and tokens to the plan's and
+// emits EventCost with the new totals.
+func (l *Loop) recordUsage(result *claude.ResultContent) {
+	l.usage.add(result)
+	l.emitUsage()
+}
+
+// emitUsage emits EventCost with the plan's cost and tokens so far.
+func (l *Loop) emitUsage() {
+	usage := l.usage
+	event := NewEvent(EventCost, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Plan cost: $%.2f, %d tokens", usage.CostUSD, usage.Tokens))
+	event.Usage = &usage
+	l.emit(event)
+}
+
+// overBudget returns why the loop should stop for its cost budget, or ""
+// while there is budget left.
+func (l *Loop) overBudget() string {
+	if !l.usage.OverBudget() {
+		return ""
+	}
+	return fmt.Sprintf("Reached max cost ($%.2f spent of $%.2f)", l.usage.CostUSD, l.usage.MaxCostUSD)
+}
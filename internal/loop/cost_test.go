@@ -0,0 +1,92 @@
+package loop
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+// runCostLoop runs the plan with a cost budget and returns the events the
+// run emitted. Each mock session costs $0.001.
+func runCostLoop(t *testing.T, database *db.DB, plan *db.Plan, maxCost float64) []Event {
+	t.Helper()
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 5, WorkDir: "/tmp", MaxCost: maxCost},
+		testDeps(database, mockClaudeCreator("## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"), mockJJRunnerEmpty()))
+	return runLoop(t, loop)
+}
+
+func TestLoopMaxCost(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	// The second iteration's session spends the budget
+	events := runCostLoop(t, database, plan, 0.0015)
+
+	var usages []PlanUsage
+	var maxCost *Event
+	for i := range events {
+		switch events[i].Type {
+		case EventCost:
+			usages = append(usages, *events[i].Usage)
+		case EventMaxCost:
+			maxCost = &events[i]
+		}
+	}
+	if len(usages) != 3 {
+		t.Fatalf("expected 3 cost events (start and one per session), got %d", len(usages))
+	}
+	if usages[0].CostUSD != 0 || usages[0].MaxCostUSD != 0.0015 {
+		t.Errorf("starting usage = %+v", usages[0])
+	}
+	if last := usages[2]; last.CostUSD < 0.0019 || !last.OverBudget() {
+		t.Errorf("final usage = %+v, want the budget spent", last)
+	}
+	if maxCost == nil {
+		t.Fatal("expected EventMaxCost event")
+	}
+	if !strings.Contains(maxCost.Message, "ralph --resume "+plan.ID) {
+		t.Errorf("expected resume instructions in message, got: %s", maxCost.Message)
+	}
+
+	updatedPlan, err := database.GetPlan(plan.ID)
+	if err != nil {
+		t.Fatalf("failed to get plan: %v", err)
+	}
+	if updatedPlan.Status != db.PlanStatusPaused || !strings.Contains(updatedPlan.FailureReason, "max cost") {
+		t.Errorf("plan = %s (%q), want paused at max cost", updatedPlan.Status, updatedPlan.FailureReason)
+	}
+
+	// Resuming counts the earlier runs' cost against the budget
+	events = runCostLoop(t, database, plan, 0.0015)
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Errorf("expected the resumed run to stop before any session, got %d sessions", len(sessions))
+	}
+	if events[len(events)-1].Type != EventMaxCost {
+		t.Errorf("last event = %s, want %s", events[len(events)-1].Type, EventMaxCost)
+	}
+}
+
+func TestPlanUsage_Budget(t *testing.T) {
+	tests := []struct {
+		usage      PlanUsage
+		near, over bool
+	}{
+		{PlanUsage{CostUSD: 100}, false, false},
+		{PlanUsage{CostUSD: 7.99, MaxCostUSD: 10}, false, false},
+		{PlanUsage{CostUSD: 8, MaxCostUSD: 10}, true, false},
+		{PlanUsage{CostUSD: 10, MaxCostUSD: 10}, true, true},
+	}
+	for _, tt := range tests {
+		if got := tt.usage.NearBudget(); got != tt.near {
+			t.Errorf("%+v NearBudget() = %v, want %v", tt.usage, got, tt.near)
+		}
+		if got := tt.usage.OverBudget(); got != tt.over {
+			t.Errorf("%+v OverBudget() = %v, want %v", tt.usage, got, tt.over)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// maxSummaryChunkBytes caps the diff given to each diff summary session.
+// Files are batched up to it, and a single file's diff over it is
+// truncated.
+const maxSummaryChunkBytes = 128 * 1024
+
+// fileDiff is one file's part of a git-format diff.
+type fileDiff struct {
+	path string
+	text string
+}
+
+// splitGitDiff splits a git-format diff into its files' parts. Anything
+// before the first file header is dropped.
+func splitGitDiff(diff string) []fileDiff {
+	var files []fileDiff
+	var current *strings.Builder
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			files = append(files, fileDiff{path: gitDiffPath(strings.TrimRight(line, "\n"))})
+			current = &strings.Builder{}
+		}
+		if current == nil {
+			continue
+		}
+		current.WriteString(line)
+		files[len(files)-1].text = current.String()
+	}
+	return files
+}
+
+// gitDiffPath returns the new path from a "diff --git a/old b/new" line.
+func gitDiffPath(line string) string {
+	rest := strings.TrimPrefix(line, "diff --git ")
+	if i := strings.LastIndex(rest, " b/"); i >= 0 {
+		return rest[i+len(" b/"):]
+	}
+	return rest
+}
+
+// batchFileDiffs groups files, in order, into diffs of at most maxBytes
+// each. A file over maxBytes is truncated at a line boundary and gets a
+// batch of its own.
+func batchFileDiffs(files []fileDiff, maxBytes int) []string {
+	var batches []string
+	var b strings.Builder
+	for _, f := range files {
+		text := f.text
+		if len(text) > maxBytes {
+			cut := runeCut(text, maxBytes)
+			if i := strings.LastIndex(cut, "\n"); i > 0 {
+				cut = cut[:i+1]
+			}
+			text = cut + fmt.Sprintf("... [%s truncated: %d more bytes]\n", f.path, len(f.text)-len(cut))
+		}
+		if b.Len() > 0 && b.Len()+len(text) > maxBytes {
+			batches = append(batches, b.String())
+			b.Reset()
+		}
+		b.WriteString(text)
+	}
+	if b.Len() > 0 {
+		batches = append(batches, b.String())
+	}
+	return batches
+}
+
+// summarizeDiff returns a file-by-file summary of the cumulative git-format
+// diff for the reviewer, made by diff summary sessions on batches of files,
+// when Config.SummarizeLargeDiffs is set and diffBytes is over the
+// reviewer's budget. It returns "" when the diff isn't summarized or a
+// summary session fails, for the diff to be truncated instead.
+func (l *Loop) summarizeDiff(ctx context.Context, diffBytes int) string {
+	if !l.cfg.SummarizeLargeDiffs || diffBytes <= maxDiffBytes {
+		return ""
+	}
+	gitDiff, err := l.deps.VCS.GitDiff(ctx, l.reviewBaseChangeID(), "@", l.scopePaths()...)
+	if err != nil {
+		log.Warn("failed to get git diff to summarize", "error", err)
+		return ""
+	}
+	files := splitGitDiff(gitDiff)
+	if len(files) == 0 {
+		return ""
+	}
+	batches := batchFileDiffs(files, maxSummaryChunkBytes)
+
+	l.emit(NewEvent(EventDiffSummaryStart, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Diff of %d KB is over the reviewer's %d KB budget, summarizing its %d files in %d parts",
+			diffBytes/1024, maxDiffBytes/1024, len(files), len(batches))))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Note: The diff of %d files (%d bytes) is too large for this prompt, so it was summarized file by file: "+
+		"the functions added, changed, and removed, and the key hunks verbatim. Read the changed files where the summary "+
+		"isn't enough to judge a change.]\n\n", len(files), diffBytes)
+	for i, batch := range batches {
+		summary, err := l.runDiffSummary(ctx, batch)
+		if err != nil {
+			log.Warn("diff summary failed, truncating the diff", "part", i+1, "error", err)
+			return ""
+		}
+		b.WriteString(strings.TrimSpace(summary) + "\n\n")
+	}
+
+	summary := strings.TrimSpace(b.String())
+	if len(summary) > maxDiffBytes {
+		summary = truncateDiff(summary)
+	}
+	l.emit(NewEvent(EventDiffSummarized, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Summarized the diff for the reviewer in %d KB", len(summary)/1024)))
+	return summary
+}
+
+// runDiffSummary runs a diff summary session on a batch of files' diffs and
+// returns the summary.
+func (l *Loop) runDiffSummary(ctx context.Context, diff string) (string, error) {
+	prompt, err := agent.BuildDiffSummaryPrompt(agent.DiffSummaryContext{Diff: diff, Locale: l.cfg.Locale})
+	if err != nil {
+		return "", fmt.Errorf("failed to build diff summary prompt: %w", err)
+	}
+
+	sessionID := uuid.New().String()
+	session := &db.PlanSession{
+		ID:          sessionID,
+		PlanID:      l.cfg.PlanID,
+		Iteration:   l.iteration,
+		InputPrompt: prompt,
+		Status:      db.PlanSessionRunning,
+		AgentType:   db.LoopAgentDiffSummarizer,
+	}
+	if err := l.deps.DB.CreatePlanSession(session); err != nil {
+		return "", fmt.Errorf("failed to create diff summary session: %w", err)
+	}
+
+	output, err := l.runClaudeSession(ctx, sessionID, prompt, l.summaryClient())
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(output) == "" {
+		return "", fmt.Errorf("diff summary session %s produced no summary", sessionID)
+	}
+	return output, nil
+}
+
+// summaryClient returns the Claude client for diff summary sessions.
+func (l *Loop) summaryClient() *claude.Client {
+	if l.deps.SummaryClaude != nil {
+		return l.deps.SummaryClaude
+	}
+	return l.reviewerClient()
+}
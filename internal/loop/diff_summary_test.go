@@ -0,0 +1,103 @@
+package loop
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/claude"
+)
+
+func TestSplitAndBatchGitDiff(t *testing.T) {
+	diff := "preamble\ndiff --git a/a.go b/a.go\n+a\ndiff --git a/old.go b/new.go\n+b\n+c\ndiff --git a/big.go b/big.go\n" +
+		strings.Repeat("+big\n", 10)
+	files := splitGitDiff(diff)
+	if len(files) != 3 || files[0].path != "a.go" || files[1].path != "new.go" || files[2].path != "big.go" {
+		t.Fatalf("splitGitDiff() = %+v", files)
+	}
+	if files[1].text != "diff --git a/old.go b/new.go\n+b\n+c\n" {
+		t.Errorf("file text = %q", files[1].text)
+	}
+
+	batches := batchFileDiffs(files, 70)
+	if len(batches) != 2 {
+		t.Fatalf("batchFileDiffs() = %q, want 2 batches", batches)
+	}
+	if batches[0] != files[0].text+files[1].text {
+		t.Errorf("first batch = %q, want the two small files", batches[0])
+	}
+	if !strings.HasPrefix(batches[1], "diff --git a/big.go b/big.go\n+big\n") || !strings.HasSuffix(batches[1], "... [big.go truncated: 10 more bytes]\n") {
+		t.Errorf("second batch = %q, want big.go truncated", batches[1])
+	}
+}
+
+func TestLoop_SummarizeLargeDiff(t *testing.T) {
+	fileDiff := func(path string) string {
+		return "diff --git a/" + path + " b/" + path + "\n" + strings.Repeat("+line of code\n", maxSummaryChunkBytes/14+100)
+	}
+	largeDiff := fileDiff("a.go") + fileDiff("b.go")
+
+	tests := []struct {
+		name       string
+		summary    string
+		wantPrompt string
+	}{
+		{"summarized", "### a.go\n- Added: `Feature`", "- Added: `Feature`"},
+		{"summary fails", "", "DIFF TRUNCATED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := setupTestDB(t)
+			plan := createTestPlan(t, database, "Test plan content")
+
+			var mu sync.Mutex
+			calls := 0
+			var reviewerPrompt string
+			creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+				mu.Lock()
+				defer mu.Unlock()
+				calls++
+				output := "## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"
+				if calls == 2 {
+					reviewerPrompt = args[len(args)-1]
+					output = "## Progress\nReviewed\n\n### Major Issues\nAdd tests"
+				}
+				return exec.CommandContext(ctx, "echo", createMockClaudeOutput(output))
+			}
+
+			summaries := 0
+			summaryClient := claude.NewClient(claude.ClientConfig{Model: "haiku", MaxTurns: 1})
+			summaryClient.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+				mu.Lock()
+				defer mu.Unlock()
+				summaries++
+				return exec.CommandContext(ctx, "echo", createMockClaudeOutput(tt.summary))
+			})
+
+			deps := testDeps(database, creator, mockJJRunnerWithDiff("base123", largeDiff))
+			deps.SummaryClaude = summaryClient
+			loop := New(Config{PlanID: plan.ID, MaxIterations: 1, WorkDir: "/tmp", SummarizeLargeDiffs: true}, deps)
+			events := runLoop(t, loop)
+
+			if !strings.Contains(reviewerPrompt, tt.wantPrompt) {
+				t.Errorf("reviewer prompt is missing %q", tt.wantPrompt)
+			}
+			summarized := false
+			for _, e := range events {
+				summarized = summarized || e.Type == EventDiffSummarized
+			}
+			if tt.summary != "" {
+				if summaries != 2 || !summarized || !strings.Contains(reviewerPrompt, "summarized file by file") {
+					t.Errorf("expected both files summarized in 2 sessions, got %d sessions (summarized event %v)", summaries, summarized)
+				}
+				if strings.Contains(reviewerPrompt, "+line of code") {
+					t.Error("expected the summary in place of the diff")
+				}
+			} else if summaries != 1 || summarized {
+				t.Errorf("expected the first failed summary to stop summarizing, got %d sessions (summarized event %v)", summaries, summarized)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package loop
+
+// resumableDevSession returns the CLI session the next developer session
+// should resume with a differential prompt, or "" when it needs the full
+// prompt: differential prompts are off or the loop is in team mode, there
+// is no session to resume, the task changed, the last session stopped at
+// the context limit, or a full refresh is due.
+func (l *Loop) resumableDevSession(task string) string {
+	if !l.cfg.DifferentialPrompts || l.cfg.TeamMode || l.devClaudeSession == "" {
+		return ""
+	}
+	if task != l.devSessionTask || l.deltaPrompts+1 >= l.cfg.FullPromptEvery {
+		return ""
+	}
+	return l.devClaudeSession
+}
+
+// devSessionRan records the developer session that just ran, for the next
+// one to resume.
+func (l *Loop) devSessionRan(task string, run claudeRun, resumed bool) {
+	if !l.cfg.DifferentialPrompts {
+		return
+	}
+	l.devClaudeSession = run.sessionID
+	if run.contextLimit {
+		// A full context can't take more turns
+		l.devClaudeSession = ""
+	}
+	l.devSessionTask = task
+	if resumed {
+		l.deltaPrompts++
+	} else {
+		l.deltaPrompts = 0
+	}
+}
@@ -0,0 +1,76 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// workingCopySnapshot returns the working copy's commit ID, to compare the
+// tree against after a session. It returns "" if jj can't report it.
+func (l *Loop) workingCopySnapshot(ctx context.Context) string {
+	commitID, err := l.deps.VCS.GetCurrentCommitID(ctx)
+	if err != nil {
+		log.Warn("failed to snapshot the working copy", "error", err)
+		return ""
+	}
+	return commitID
+}
+
+// changedSince returns the files that differ between the snapshot and the
+// working copy, whichever tool changed them (Edit, or a Bash command such as
+// sed -i or go generate). It returns nil when the snapshot is unknown or the
+// tree is unchanged.
+func (l *Loop) changedSince(ctx context.Context, snapshot string) []string {
+	if snapshot == "" {
+		return nil
+	}
+	// jj snapshots the working copy on every command, so an unchanged
+	// commit ID means an unchanged tree
+	current := l.workingCopySnapshot(ctx)
+	if current == "" || current == snapshot {
+		return nil
+	}
+	files, err := l.deps.VCS.ChangedFiles(ctx, snapshot, "@", l.scopePaths()...)
+	if err != nil {
+		log.Warn("failed to compare the working copy with its snapshot", "error", err)
+		return nil
+	}
+	return files
+}
+
+// verifyDevDone rejects a DEV_DONE signal from a developer session that
+// changed the tree: done must come from a session that found nothing left
+// to change, so the last edits get a review cycle of their own. It returns
+// the note for the next developer prompt, or "" if the signal stands.
+func (l *Loop) verifyDevDone(ctx context.Context, snapshot string) string {
+	files := l.changedSince(ctx, snapshot)
+	if len(files) == 0 {
+		return ""
+	}
+
+	l.emit(NewEvent(EventDoneRejected, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Developer signaled DEV_DONE but changed files in the same session: %s", strings.Join(files, ", "))))
+	return fmt.Sprintf("DEV_DONE REJECTED: you signaled DEV_DONE in a session that changed files (%s). "+
+		"Signal DEV_DONE only from a session that makes no changes: verify your last changes now, "+
+		"and signal it again if nothing is left to do.", strings.Join(files, ", "))
+}
+
+// verifyApproval discards the reviewer's approval if the working copy
+// changed during the review: approved work must be the work that was
+// reviewed. It returns the feedback for the developer, or "" if the
+// approval stands.
+func (l *Loop) verifyApproval(ctx context.Context, snapshot string) string {
+	files := l.changedSince(ctx, snapshot)
+	if len(files) == 0 {
+		return ""
+	}
+
+	l.emit(NewEvent(EventDoneRejected, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Reviewer approved but changed files during the review: %s", strings.Join(files, ", "))))
+	return fmt.Sprintf("The reviewer approved, but the working copy changed during the review, so the approval was discarded. "+
+		"Changed files: %s. Check these changes, keep them only if they are correct, and signal DEV_DONE again for a fresh review.",
+		strings.Join(files, ", "))
+}
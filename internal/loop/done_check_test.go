@@ -0,0 +1,126 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+// runDoneLoop runs up to two iterations in which the developer signals
+// DEV_DONE and the reviewer approves. The working copy's commit changes
+// whenever a session of an agent that edits starts, whichever tool it
+// would use.
+func runDoneLoop(t *testing.T, devEdits, reviewerEdits bool) (*db.DB, *db.Plan, []Event) {
+	t.Helper()
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	var mu sync.Mutex
+	calls, edits := 0, 0
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		output := "## Progress\nDone\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"
+		reviewer := calls%2 == 0
+		if reviewer {
+			output = "## Progress\nReviewed\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!"
+		}
+		if (reviewer && reviewerEdits) || (!reviewer && devEdits) {
+			edits++
+		}
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput(output))
+	}
+
+	runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		if len(args) >= 5 && args[0] == "log" && args[4] == "commit_id" {
+			mu.Lock()
+			defer mu.Unlock()
+			return fmt.Sprintf("commit-%d\n", edits), "", nil
+		}
+		if len(args) >= 2 && args[0] == "diff" && args[1] == "--name-only" {
+			return "gen/api.go\n", "", nil
+		}
+		return "", "", nil
+	}
+
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 2, WorkDir: "/tmp"}, testDeps(database, creator, runner))
+	events := runLoop(t, loop)
+	return database, plan, events
+}
+
+// doneRejections returns the messages of the EventDoneRejected events and
+// whether the plan completed.
+func doneRejections(events []Event) (rejections []string, completed bool) {
+	for _, e := range events {
+		switch e.Type {
+		case EventDoneRejected:
+			rejections = append(rejections, e.Message)
+		case EventBothDone:
+			completed = true
+		}
+	}
+	return rejections, completed
+}
+
+func TestLoop_DevDoneRejectedWhenSessionChangesTree(t *testing.T) {
+	database, plan, events := runDoneLoop(t, true, false)
+
+	rejections, completed := doneRejections(events)
+	if completed {
+		t.Error("expected DEV_DONE from editing sessions not to complete the plan")
+	}
+	if len(rejections) != 2 || !strings.Contains(rejections[0], "Developer signaled DEV_DONE") || !strings.Contains(rejections[0], "gen/api.go") {
+		t.Errorf("unexpected rejections: %q", rejections)
+	}
+	for _, e := range events {
+		if e.Type == EventDeveloperDone {
+			t.Error("a rejected DEV_DONE should not trigger the final review")
+		}
+	}
+
+	feedback, err := database.GetLatestReviewerFeedback(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feedback == nil || !strings.HasPrefix(feedback.Content, "DEV_DONE REJECTED") {
+		t.Errorf("expected feedback about the rejected DEV_DONE, got %+v", feedback)
+	}
+}
+
+func TestLoop_ApprovalDiscardedWhenReviewChangesTree(t *testing.T) {
+	database, plan, events := runDoneLoop(t, false, true)
+
+	rejections, completed := doneRejections(events)
+	if completed {
+		t.Error("expected approvals from editing reviews not to complete the plan")
+	}
+	if len(rejections) != 2 || !strings.Contains(rejections[0], "Reviewer approved") {
+		t.Errorf("unexpected rejections: %q", rejections)
+	}
+
+	feedback, err := database.GetLatestReviewerFeedback(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feedback == nil || !strings.Contains(feedback.Content, "approval was discarded") {
+		t.Errorf("expected feedback about the discarded approval, got %+v", feedback)
+	}
+}
+
+func TestLoop_DoneAcceptedWhenTreeUnchanged(t *testing.T) {
+	_, _, events := runDoneLoop(t, false, false)
+
+	rejections, completed := doneRejections(events)
+	if len(rejections) > 0 {
+		t.Errorf("unexpected rejections: %q", rejections)
+	}
+	if !completed {
+		t.Error("expected the plan to complete")
+	}
+}
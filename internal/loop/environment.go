@@ -0,0 +1,89 @@
+package loop
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// recordEnvironment stores the environment a session starts in: the claude
+// CLI version, the working copy's jj commit, and the target repository's Go
+// version. The model is stored with the session itself (see recordModel).
+func (l *Loop) recordEnvironment(ctx context.Context, sessionID string) {
+	env := &db.SessionEnvironment{
+		SessionID:     sessionID,
+		PlanID:        l.cfg.PlanID,
+		ClaudeVersion: l.cfg.ClaudeVersion,
+		GoVersion:     l.goVersion,
+	}
+	if commitID, err := l.deps.VCS.GetCurrentCommitID(ctx); err != nil {
+		log.Debug("failed to get commit ID for session environment", "error", err)
+	} else {
+		env.JJRevision = commitID
+	}
+	if err := l.deps.DB.CreateSessionEnvironment(env); err != nil {
+		log.Warn("failed to store session environment", "error", err)
+	}
+}
+
+// recordModel stores the model that served a session.
+func (l *Loop) recordModel(sessionID, model string) {
+	if model == "" {
+		return
+	}
+	if err := l.deps.DB.UpdatePlanSessionModel(sessionID, model); err != nil {
+		log.Warn("failed to store session model", "error", err)
+	}
+}
+
+// recordClaudeSession stores the ID Claude gave a session, which lets a
+// session interrupted mid-stream be resumed on the next run.
+func (l *Loop) recordClaudeSession(sessionID, claudeSessionID string) {
+	if claudeSessionID == "" {
+		return
+	}
+	if err := l.deps.DB.UpdatePlanSessionClaudeSession(sessionID, claudeSessionID); err != nil {
+		log.Warn("failed to store Claude session ID", "error", err)
+	}
+}
+
+// detectGoVersion returns the Go version a go.mod in one of dirs declares,
+// followed by its toolchain if it names one (e.g. "1.22 (go1.22.4)"). It
+// returns "" when none of the directories is a Go module.
+func detectGoVersion(dirs ...string) string {
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			continue
+		}
+
+		var version, toolchain string
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				continue
+			}
+			switch fields[0] {
+			case "go":
+				version = fields[1]
+			case "toolchain":
+				toolchain = fields[1]
+			}
+		}
+		if version == "" {
+			continue
+		}
+		if toolchain != "" {
+			return version + " (" + toolchain + ")"
+		}
+		return version
+	}
+	return ""
+}
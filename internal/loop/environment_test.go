@@ -0,0 +1,91 @@
+package loop
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+func TestDetectGoVersion(t *testing.T) {
+	tests := []struct {
+		name  string
+		gomod string // "" = no go.mod
+		want  string
+	}{
+		{"no go.mod", "", ""},
+		{"go directive", "module example.com/x\n\ngo 1.22\n", "1.22"},
+		{"toolchain", "module example.com/x\n\ngo 1.22.0\n\ntoolchain go1.23.2\n", "1.22.0 (go1.23.2)"},
+		{"no go directive", "module example.com/x\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.gomod != "" {
+				if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(tt.gomod), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := detectGoVersion(dir); got != tt.want {
+				t.Errorf("detectGoVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Later directories are tried when earlier ones have no go.mod
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module x\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := detectGoVersion(t.TempDir(), "", root); got != "1.21" {
+		t.Errorf("detectGoVersion() with a fallback = %q, want 1.21", got)
+	}
+}
+
+func TestLoop_RecordsSessionEnvironment(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "go.mod"), []byte("module x\n\ngo 1.22\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	claudeClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	claudeClient.SetCommandCreator(mockClaudeCreator("## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"))
+
+	jjClient := jj.NewClient(workDir)
+	jjClient.SetCommandRunner(func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		if len(args) >= 5 && args[0] == "log" && args[4] == "commit_id" {
+			return "c0ffee\n", "", nil
+		}
+		return mockJJRunner()(ctx, dir, name, args...)
+	})
+
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 1, WorkDir: workDir, ClaudeVersion: "2.0.1 (Claude Code)"}, Deps{
+		DB:     database,
+		Claude: claudeClient,
+		VCS:    jjClient,
+	})
+	runLoop(t, loop)
+
+	envs, err := database.GetSessionEnvironmentsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(envs) == 0 || len(envs) != len(sessions) {
+		t.Fatalf("got %d environments for %d sessions", len(envs), len(sessions))
+	}
+	for _, env := range envs {
+		if env.ClaudeVersion != "2.0.1 (Claude Code)" || env.Model != "test-model" || env.JJRevision != "c0ffee" || env.GoVersion != "1.22" {
+			t.Errorf("environment = %+v", env)
+		}
+	}
+}
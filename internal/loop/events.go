@@ -1,7 +1,12 @@
 // Package loop provides the main execution loop for Ralph.
 package loop
 
-import "github.com/gerunddev/ralph/internal/claude"
+import (
+	"time"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+)
 
 // EventType represents the type of a loop event.
 type EventType string
@@ -29,8 +34,20 @@ const (
 	EventDone EventType = "done"
 	// EventMaxIterations is emitted when max iterations is reached.
 	EventMaxIterations EventType = "max_iterations"
+	// EventMaxDuration is emitted when the loop stops because its wall-clock budget ran out.
+	EventMaxDuration EventType = "max_duration"
+	// EventMaxCost is emitted when the loop stops because the plan's cost budget ran out.
+	EventMaxCost EventType = "max_cost"
+	// EventCost is emitted with the plan's cost and tokens so far, when the
+	// loop starts and after each Claude session.
+	EventCost EventType = "cost"
+	// EventPaused is emitted when the loop pauses the plan, or stops, on
+	// request.
+	EventPaused EventType = "paused"
 	// EventError is emitted when an error occurs.
 	EventError EventType = "error"
+	// EventFailed is emitted when the loop stops on an error; the plan is marked failed.
+	EventFailed EventType = "failed"
 
 	// EventDeveloperStart is emitted when the developer agent starts.
 	EventDeveloperStart EventType = "developer_start"
@@ -46,14 +63,204 @@ const (
 	EventReviewerApproved EventType = "reviewer_approved"
 	// EventReviewerFeedback is emitted when the reviewer provides feedback (rejection).
 	EventReviewerFeedback EventType = "reviewer_feedback"
+	// EventDoneRejected is emitted when a developer signaled DEV_DONE, or a
+	// reviewer approved, in a session that changed the working copy.
+	EventDoneRejected EventType = "done_rejected"
+	// EventReviewerTests is emitted when the tests the reviewer added in
+	// test-authoring mode failed without the change and passed with it.
+	EventReviewerTests EventType = "reviewer_tests"
+	// EventReviewQuorum is emitted when a review panel's verdicts have been
+	// counted against the quorum.
+	EventReviewQuorum EventType = "review_quorum"
+	// EventReviewPatchApplied is emitted when the reviewer's suggested patch was applied as its own change.
+	EventReviewPatchApplied EventType = "review_patch_applied"
 	// EventBothDone is emitted when both developer and reviewer signal done.
 	EventBothDone EventType = "both_done"
 	// EventContextLimit is emitted when the context window usage exceeds the limit.
 	EventContextLimit EventType = "context_limit"
-	// EventExtremeModeTriggered is emitted when extreme mode activates +3 iterations.
+	// EventContextPruned is emitted when a prompt was over the context budget
+	// and its oldest learnings, oldest progress, or diff tail were cut.
+	EventContextPruned EventType = "context_pruned"
+	// EventExtremeModeTriggered is emitted each time extreme mode starts a
+	// round of bonus iterations.
 	EventExtremeModeTriggered EventType = "extreme_mode_triggered"
+	// EventPolicyViolation is emitted when the developer touches paths outside the permissions policy.
+	EventPolicyViolation EventType = "policy_violation"
+	// EventFilesOutOfScope is emitted when the developer changes files outside the plan's files allowlist.
+	EventFilesOutOfScope EventType = "files_out_of_scope"
+	// EventClaudeRetry is emitted when a Claude session failed transiently and will be retried.
+	EventClaudeRetry EventType = "claude_retry"
+	// EventStallDetected is emitted when iterations stop making progress for the stall threshold.
+	EventStallDetected EventType = "stall_detected"
+	// EventStallAborted is emitted when the loop stops because it stalled.
+	EventStallAborted EventType = "stall_aborted"
+	// EventPlanningStart is emitted when the planner agent starts breaking the plan into tasks.
+	EventPlanningStart EventType = "planning_start"
+	// EventTasksPlanned is emitted when the planner's tasks have been stored.
+	EventTasksPlanned EventType = "tasks_planned"
+	// EventTaskStarted is emitted when work on a task begins (or resumes).
+	EventTaskStarted EventType = "task_started"
+	// EventTaskCompleted is emitted when the developer and reviewer approve a task.
+	EventTaskCompleted EventType = "task_completed"
+	// EventPlanChanged is emitted when the plan file was edited during the run, with the changes in Diff.
+	EventPlanChanged EventType = "plan_changed"
+	// EventToolActivity is emitted after each tool call with the iteration's tool usage summary.
+	EventToolActivity EventType = "tool_activity"
+	// EventAnalyzerFindings is emitted when static analyzers reported findings for the reviewer.
+	EventAnalyzerFindings EventType = "analyzer_findings"
+	// EventChecksFailed is emitted when checks failed after a developer
+	// session; their output goes in the next developer prompt.
+	EventChecksFailed EventType = "checks_failed"
+	// EventUserFeedback is emitted when feedback sent by the user is added to a developer prompt.
+	EventUserFeedback EventType = "user_feedback"
+	// EventRateLimitWait is emitted while the loop waits for a rate limit to
+	// reset, as a countdown, and once more when the wait is over.
+	EventRateLimitWait EventType = "rate_limit_wait"
+	// EventThrottled is emitted while the loop waits for the Claude throttle
+	// (Config.Throttle) to let a session start, as a countdown, and once
+	// more when the wait is over.
+	EventThrottled EventType = "throttled"
+	// EventQuietHours is emitted while the loop waits out quiet hours
+	// (Config.QuietHours), as a countdown, and once more when they are
+	// over.
+	EventQuietHours EventType = "quiet_hours"
+	// EventClaudeHeartbeat is emitted while a Claude session is silent, to
+	// show it is still being waited on.
+	EventClaudeHeartbeat EventType = "claude_heartbeat"
+	// EventClaudeStalled is emitted once a Claude session has been silent
+	// long enough to look stuck.
+	EventClaudeStalled EventType = "claude_stalled"
+	// EventReviewSkipped is emitted when an iteration's review is skipped,
+	// or downgraded to a lightweight reviewer, because its change was trivial.
+	EventReviewSkipped EventType = "review_skipped"
+	// EventConflictDetected is emitted when jj reports conflicts in the
+	// working copy, and again when the loop waits for them to be resolved
+	// by hand.
+	EventConflictDetected EventType = "conflict_detected"
+	// EventConflictResolverStart is emitted when the conflict resolver
+	// agent starts.
+	EventConflictResolverStart EventType = "conflict_resolver_start"
+	// EventConflictResolved is emitted once the working copy's conflicts
+	// are resolved and the loop continues.
+	EventConflictResolved EventType = "conflict_resolved"
+	// EventRebuttalStart is emitted when the developer disputes the last
+	// review feedback and a reviewer starts re-evaluating it.
+	EventRebuttalStart EventType = "rebuttal_start"
+	// EventRebuttalAccepted is emitted when the reviewer withdraws the
+	// disputed feedback and the review continues.
+	EventRebuttalAccepted EventType = "rebuttal_accepted"
+	// EventRebuttalRejected is emitted when the reviewer upholds the
+	// disputed feedback, which the developer must then address.
+	EventRebuttalRejected EventType = "rebuttal_rejected"
+	// EventReformatStart is emitted when an agent's output is missing its
+	// required sections and a follow-up asks for it to be reformatted.
+	EventReformatStart EventType = "reformat_start"
+	// EventReformatted is emitted when a follow-up produced output with
+	// the required sections, which is used in place of the original.
+	EventReformatted EventType = "reformatted"
+	// EventReformatFailed is emitted when no follow-up produced the
+	// required sections and the original output is used as-is.
+	EventReformatFailed EventType = "reformat_failed"
+	// EventFailureTriageStart is emitted when the same failure keeps
+	// happening and a failure triage agent starts diagnosing it.
+	EventFailureTriageStart EventType = "failure_triage_start"
+	// EventFailureTriage is emitted with the failure triage agent's
+	// report, in Output, for the person running the loop.
+	EventFailureTriage EventType = "failure_triage"
+	// EventDiffSummaryStart is emitted when the diff is too large for the
+	// reviewer's prompt and is being summarized file by file.
+	EventDiffSummaryStart EventType = "diff_summary_start"
+	// EventDiffSummarized is emitted when the reviewer is given the diff's
+	// summary in place of the diff.
+	EventDiffSummarized EventType = "diff_summarized"
+	// EventHookFailed is emitted when a lifecycle hook exits non-zero,
+	// times out, or can't be started; the loop carries on.
+	EventHookFailed EventType = "hook_failed"
+	// EventSubPlanCreated is emitted when the developer proposed a sub-plan
+	// and it was recorded, and when it starts running.
+	EventSubPlanCreated EventType = "sub_plan_created"
+	// EventSubPlanFinished is emitted when a sub-plan's run ended and the
+	// plan resumes.
+	EventSubPlanFinished EventType = "sub_plan_finished"
+	// EventSessionResubmitted is emitted when a developer session
+	// interrupted in the previous run is sent its prompt again.
+	EventSessionResubmitted EventType = "session_resubmitted"
 )
 
+// EventTypes lists every event type, so configured event names can be
+// checked.
+var EventTypes = []EventType{
+	EventStarted,
+	EventIterationStart,
+	EventPromptBuilt,
+	EventClaudeStart,
+	EventClaudeStream,
+	EventClaudeOutput,
+	EventClaudeEnd,
+	EventParsed,
+	EventIterationEnd,
+	EventDone,
+	EventMaxIterations,
+	EventMaxDuration,
+	EventMaxCost,
+	EventCost,
+	EventPaused,
+	EventError,
+	EventFailed,
+	EventDeveloperStart,
+	EventDeveloperEnd,
+	EventDeveloperDone,
+	EventReviewerStart,
+	EventReviewerEnd,
+	EventReviewerApproved,
+	EventReviewerFeedback,
+	EventDoneRejected,
+	EventReviewerTests,
+	EventReviewQuorum,
+	EventReviewPatchApplied,
+	EventBothDone,
+	EventContextLimit,
+	EventContextPruned,
+	EventExtremeModeTriggered,
+	EventPolicyViolation,
+	EventFilesOutOfScope,
+	EventClaudeRetry,
+	EventStallDetected,
+	EventStallAborted,
+	EventPlanningStart,
+	EventTasksPlanned,
+	EventTaskStarted,
+	EventTaskCompleted,
+	EventPlanChanged,
+	EventToolActivity,
+	EventAnalyzerFindings,
+	EventChecksFailed,
+	EventUserFeedback,
+	EventRateLimitWait,
+	EventThrottled,
+	EventQuietHours,
+	EventClaudeHeartbeat,
+	EventClaudeStalled,
+	EventReviewSkipped,
+	EventConflictDetected,
+	EventConflictResolverStart,
+	EventConflictResolved,
+	EventRebuttalStart,
+	EventRebuttalAccepted,
+	EventRebuttalRejected,
+	EventReformatStart,
+	EventReformatted,
+	EventReformatFailed,
+	EventFailureTriageStart,
+	EventFailureTriage,
+	EventDiffSummaryStart,
+	EventDiffSummarized,
+	EventHookFailed,
+	EventSubPlanCreated,
+	EventSubPlanFinished,
+	EventSessionResubmitted,
+}
+
 // Event represents an event emitted by the loop.
 type Event struct {
 	Type        EventType
@@ -61,10 +268,27 @@ type Event struct {
 	MaxIter     int
 	Message     string
 	Prompt      string              // For EventPromptBuilt events (full prompt content)
-	Output      string              // For EventClaudeOutput events (final collected output)
+	Output      string              // For EventClaudeOutput events (final collected output) and EventFailureTriage events (the report)
+	Diff        string              // For EventPlanChanged events (edits to the plan file)
 	ClaudeEvent *claude.StreamEvent // For EventClaudeStream events
 	Error       error
-	TeamMode    bool // Whether team mode is active (for EventDeveloperStart)
+	TeamMode    bool      // Whether team mode is active (for EventDeveloperStart)
+	Until       time.Time // For EventRateLimitWait, EventThrottled, and EventQuietHours events (when the wait ends; zero once it is over)
+
+	// Time is when the event was emitted, with a monotonic clock reading
+	// so durations between events are immune to wall clock changes.
+	Time time.Time
+	// Duration is how long the stage an EventDeveloperEnd, EventReviewerEnd,
+	// or EventIterationEnd event ends took.
+	Duration time.Duration
+	// Timings are the iteration's stage totals (for EventIterationEnd).
+	Timings *db.SessionTimings
+	// Usage is the plan's cost and tokens so far (for EventCost).
+	Usage *PlanUsage
+	// Round is the extreme mode round an EventExtremeModeTriggered event
+	// starts, from 1, of Rounds at most.
+	Round  int
+	Rounds int
 }
 
 // NewEvent creates a new loop event with the given type and message.
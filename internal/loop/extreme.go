@@ -0,0 +1,44 @@
+package loop
+
+import "fmt"
+
+// Extreme mode defaults, used when the config leaves them unset.
+const (
+	DefaultExtremeBonusIterations = 3
+	DefaultExtremeMaxRounds       = 1
+)
+
+// extremeBonus returns how many iterations a round of extreme mode adds.
+func (l *Loop) extremeBonus() int {
+	if l.cfg.ExtremeBonusIterations > 0 {
+		return l.cfg.ExtremeBonusIterations
+	}
+	return DefaultExtremeBonusIterations
+}
+
+// extremeMaxRounds returns how many rounds of bonus iterations extreme mode
+// may trigger.
+func (l *Loop) extremeMaxRounds() int {
+	if l.cfg.ExtremeMaxRounds > 0 {
+		return l.cfg.ExtremeMaxRounds
+	}
+	return DefaultExtremeMaxRounds
+}
+
+// startExtremeRound starts the next round of extreme mode once the
+// developer and reviewer agree the plan is done in iteration iter: the max
+// iterations becomes iter plus the bonus, and EventExtremeModeTriggered
+// reports the round. It does nothing once the rounds have run out.
+func (l *Loop) startExtremeRound(iter int) {
+	if l.extremeRounds >= l.extremeMaxRounds() {
+		return
+	}
+	l.extremeRounds++
+	l.cfg.MaxIterations = iter + l.extremeBonus()
+
+	event := NewEvent(EventExtremeModeTriggered, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("+%d iterations (max now %d)", l.extremeBonus(), l.cfg.MaxIterations))
+	event.Round = l.extremeRounds
+	event.Rounds = l.extremeMaxRounds()
+	l.emit(event)
+}
@@ -0,0 +1,148 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// maxTriageDetailBytes caps the failure output passed to the failure triage
+// agent.
+const maxTriageDetailBytes = 16 * 1024
+
+// failureStreak tracks how many times in a row the loop failed the same way.
+// Failures are the same when their kind and signature match: an iteration
+// error's message, or the names of the checks that failed.
+type failureStreak struct {
+	kind      string // What failed, e.g. "Iteration error"
+	signature string
+	detail    string // The latest failure's error message or output
+	count     int    // Consecutive failures with this kind and signature
+}
+
+// observe records a failure and returns the number of consecutive times it
+// happened, including this one.
+func (f *failureStreak) observe(kind, signature, detail string) int {
+	if f.count > 0 && kind == f.kind && signature == f.signature {
+		f.count++
+	} else {
+		f.kind, f.signature, f.count = kind, signature, 1
+	}
+	f.detail = detail
+	return f.count
+}
+
+// reset ends the streak.
+func (f *failureStreak) reset() {
+	*f = failureStreak{}
+}
+
+// recordFailure feeds a failure to the failure streak. Once the same failure
+// has happened Config.FailureTriageAfter times in a row, a failure triage
+// runs after the iteration.
+func (l *Loop) recordFailure(kind, signature, detail string) {
+	if l.cfg.FailureTriageAfter <= 0 {
+		return
+	}
+	l.failures.observe(kind, signature, detail)
+}
+
+// triageDue reports whether the failure streak has reached the triage
+// threshold.
+func (l *Loop) triageDue() bool {
+	return l.cfg.FailureTriageAfter > 0 && l.failures.count >= l.cfg.FailureTriageAfter
+}
+
+// runFailureTriage runs a failure triage agent session once the same failure
+// has happened Config.FailureTriageAfter times in a row, and emits its report
+// as EventFailureTriage. The streak starts over afterwards, so a failure
+// that persists is triaged again after as many more. A failed triage
+// session emits the failure itself as the report; it returns an error only
+// if ctx is done.
+func (l *Loop) runFailureTriage(ctx context.Context) error {
+	if !l.triageDue() {
+		return nil
+	}
+	streak := l.failures
+	l.failures.reset()
+
+	l.startSessionTimer()
+	progress, _, _, err := l.loadState()
+	if err != nil {
+		log.Warn("failed to load progress for the failure triage", "error", err)
+	}
+	prompt, err := agent.BuildFailureTriagePrompt(agent.FailureTriageContext{
+		PlanContent: l.plan.Content,
+		Failure:     streak.kind,
+		Count:       streak.count,
+		Detail:      trimTestOutput(streak.detail, maxTriageDetailBytes),
+		Progress:    progress,
+		Locale:      l.cfg.Locale,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build failure triage prompt: %w", err)
+	}
+	l.promptBuilt()
+
+	l.emit(NewEvent(EventFailureTriageStart, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("%s %d times in a row, starting failure triage agent", streak.kind, streak.count)))
+	l.emit(NewPromptBuiltEvent(l.iteration, l.effectiveMaxIter(), prompt))
+
+	sessionID := uuid.New().String()
+	session := &db.PlanSession{
+		ID:          sessionID,
+		PlanID:      l.cfg.PlanID,
+		Iteration:   l.iteration,
+		InputPrompt: prompt,
+		Status:      db.PlanSessionRunning,
+		AgentType:   db.LoopAgentFailureTriager,
+	}
+	if err := l.deps.DB.CreatePlanSession(session); err != nil {
+		log.Warn("failed to create failure triage session", "error", err)
+		l.emitTriageReport(streak, "")
+		return nil
+	}
+
+	output, err := l.runClaudeSession(ctx, sessionID, prompt, l.triageClient())
+	l.finishSessionTimer(sessionID, 0)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Warn("failure triage agent failed", "error", err)
+		output = ""
+	}
+	l.emitTriageReport(streak, output)
+	return nil
+}
+
+// emitTriageReport emits EventFailureTriage for the streak with the triage
+// agent's report, or with the failure itself when there is no report.
+func (l *Loop) emitTriageReport(streak failureStreak, report string) {
+	report = strings.TrimSpace(report)
+	if report == "" {
+		report = fmt.Sprintf("The failure triage agent produced no report. The latest failure:\n\n%s",
+			truncateString(strings.TrimSpace(streak.detail), 2000))
+	}
+	event := NewEvent(EventFailureTriage, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("%s %d times in a row; the loop needs attention", streak.kind, streak.count))
+	event.Output = report
+	l.emit(event)
+}
+
+// triageClient returns the Claude client for failure triage sessions. The
+// triage only reads, so it runs with the reviewer's CLI options rather than
+// the developer's.
+func (l *Loop) triageClient() *claude.Client {
+	if l.deps.TriageClaude != nil {
+		return l.deps.TriageClaude
+	}
+	return l.reviewerClient()
+}
@@ -0,0 +1,170 @@
+package loop
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+func TestFailureStreak_Observe(t *testing.T) {
+	var f failureStreak
+	steps := []struct {
+		kind, signature string
+		want            int
+	}{
+		{"Checks failed", "tests", 1},
+		{"Checks failed", "tests", 2},
+		{"Checks failed", "tests, lint", 1},
+		{"Iteration error", "tests, lint", 1},
+		{"Iteration error", "tests, lint", 2},
+	}
+	for i, step := range steps {
+		if got := f.observe(step.kind, step.signature, "detail"); got != step.want {
+			t.Errorf("step %d: observe() = %d, want %d", i, got, step.want)
+		}
+	}
+
+	f.reset()
+	if got := f.observe("Iteration error", "tests, lint", "detail"); got != 1 {
+		t.Errorf("observe() after reset = %d, want 1", got)
+	}
+}
+
+func TestLoop_TriagesRepeatedFailures(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	reviewerClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	reviewerClient.SetCommandCreator(mockClaudeCreator("## Progress\nReviewed\n\nREVIEWER_FEEDBACK: Keep going"))
+	triageClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	triageClient.SetCommandCreator(mockClaudeCreator("## Diagnosis\nThe lib/pq module is missing from go.mod"))
+
+	// The tests fail after every session
+	tests := &fakeTestGate{passes: []bool{false, false, false, false}}
+	deps := testDeps(database, mockClaudeCreator("## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"), mockJJRunner())
+	deps.ReviewerClaude = reviewerClient
+	deps.TriageClaude = triageClient
+	deps.Checks = []Check{{Name: "tests", Gate: tests}}
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 4, WorkDir: "/tmp", FailureTriageAfter: 2}, deps)
+
+	var triages []Event
+	var triagePrompt string
+	events, _ := runLoopErr(loop)
+	for _, event := range events {
+		switch {
+		case event.Type == EventFailureTriage:
+			triages = append(triages, event)
+		case event.Type == EventPromptBuilt && strings.Contains(event.Prompt, "triaging an automated development loop"):
+			triagePrompt = event.Prompt
+		}
+	}
+
+	// The streak starts over after each triage
+	if len(triages) != 2 {
+		t.Fatalf("expected 2 failure triages, got %d", len(triages))
+	}
+	if triages[0].Iteration != 2 || triages[1].Iteration != 4 {
+		t.Errorf("expected triages after iterations 2 and 4, got %d and %d", triages[0].Iteration, triages[1].Iteration)
+	}
+	if !strings.Contains(triages[0].Message, "Checks failed 2 times in a row") {
+		t.Errorf("unexpected triage message: %q", triages[0].Message)
+	}
+	if !strings.Contains(triages[0].Output, "lib/pq module is missing") {
+		t.Errorf("expected the triage agent's report, got %q", triages[0].Output)
+	}
+	if !strings.Contains(triagePrompt, "Checks failed (2 times in a row)") || !strings.Contains(triagePrompt, "## tests") {
+		t.Errorf("triage prompt missing the failure:\n%s", triagePrompt)
+	}
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	triageSessions := 0
+	for _, s := range sessions {
+		if s.AgentType == db.LoopAgentFailureTriager {
+			triageSessions++
+		}
+	}
+	if triageSessions != 2 {
+		t.Errorf("expected 2 failure triage sessions, got %d", triageSessions)
+	}
+}
+
+func TestLoop_FailureTriageUsesReviewerClient(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	developerClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	developerClient.SetCommandCreator(mockClaudeCreator("## Diagnosis\nFrom the developer"))
+	reviewerClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	reviewerClient.SetCommandCreator(mockClaudeCreator("## Diagnosis\nFrom the reviewer"))
+	jjClient := jj.NewClient("/tmp")
+	jjClient.SetCommandRunner(mockJJRunner())
+
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 3, WorkDir: "/tmp", FailureTriageAfter: 1}, Deps{
+		DB:             database,
+		Claude:         developerClient,
+		ReviewerClaude: reviewerClient,
+		VCS:            jjClient,
+	})
+	loop.plan = plan
+
+	sub := loop.Subscribe(WithTypes(EventFailureTriage))
+	loop.recordFailure("Iteration error", "auth failed", "claude: 401 invalid x-api-key")
+	if err := loop.runFailureTriage(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	loop.bus.Close()
+
+	var triage *Event
+	for event := range sub.Events() {
+		triage = &event
+	}
+	if triage == nil || !strings.Contains(triage.Output, "From the reviewer") {
+		t.Errorf("expected the reviewer's client to triage, got %+v", triage)
+	}
+}
+
+func TestLoop_FailureTriageReportsFailureWhenAgentFails(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	triageClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	triageClient.SetCommandCreator(mockClaudeCreator(""))
+	jjClient := jj.NewClient("/tmp")
+	jjClient.SetCommandRunner(mockJJRunner())
+
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 3, WorkDir: "/tmp", FailureTriageAfter: 2}, Deps{
+		DB:           database,
+		TriageClaude: triageClient,
+		VCS:          jjClient,
+	})
+	loop.plan = plan
+
+	sub := loop.Subscribe(WithTypes(EventFailureTriage))
+	loop.recordFailure("Iteration error", "auth failed", "claude: 401 invalid x-api-key")
+	if err := loop.runFailureTriage(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	loop.recordFailure("Iteration error", "auth failed", "claude: 401 invalid x-api-key")
+	if err := loop.runFailureTriage(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	loop.bus.Close()
+
+	var triage *Event
+	for event := range sub.Events() {
+		triage = &event
+	}
+	if triage == nil {
+		t.Fatal("expected EventFailureTriage")
+	}
+	if !strings.Contains(triage.Output, "401 invalid x-api-key") {
+		t.Errorf("expected the failure in place of the missing report, got %q", triage.Output)
+	}
+}
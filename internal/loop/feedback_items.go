@@ -0,0 +1,115 @@
+package loop
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/parser"
+)
+
+// openFeedbackItems returns the plan's review items not yet verified or
+// withdrawn.
+func (l *Loop) openFeedbackItems() []*db.FeedbackItem {
+	items, err := l.deps.DB.GetOpenFeedbackItems(l.cfg.PlanID)
+	if err != nil {
+		log.Warn("failed to load open feedback items", "error", err)
+		return nil
+	}
+	return items
+}
+
+// formatOpenItems formats the plan's open review items for a prompt, one
+// per line with their number, severity, and whether a fix is claimed.
+func (l *Loop) formatOpenItems() string {
+	var b strings.Builder
+	for _, item := range l.openFeedbackItems() {
+		fmt.Fprintf(&b, "- #%d ", item.Number)
+		if item.Severity != "" {
+			fmt.Fprintf(&b, "[%s] ", item.Severity)
+		}
+		if item.State() == db.FeedbackItemClaimed {
+			b.WriteString("(claimed fixed) ")
+		}
+		b.WriteString(item.Content)
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// claimFeedbackItems records that the developer session saw the open items,
+// and its claim to have fixed those with the given numbers.
+func (l *Loop) claimFeedbackItems(fixed []int) {
+	if err := l.deps.DB.AcknowledgeFeedbackItems(l.cfg.PlanID, l.iteration); err != nil {
+		log.Warn("failed to acknowledge feedback items", "error", err)
+	}
+	if err := l.deps.DB.ClaimFeedbackItems(l.cfg.PlanID, fixed, l.iteration); err != nil {
+		log.Warn("failed to record fixed feedback items", "error", err)
+	}
+}
+
+// verifyFeedbackItems applies a review's verdict to the claimed fixes: an
+// approval verifies them all and an approval of a done signal closes every
+// open item; otherwise only the fixes the reviewer listed are verified, and
+// the other claims are reopened.
+func (l *Loop) verifyFeedbackItems(review *parser.AgentParseResult, devDone bool) {
+	var err error
+	switch {
+	case devDone && review.ReviewerApproved:
+		err = l.deps.DB.CloseFeedbackItems(l.cfg.PlanID, l.iteration)
+	case review.ReviewerApproved:
+		var claimed []int
+		for _, item := range l.openFeedbackItems() {
+			if item.State() == db.FeedbackItemClaimed {
+				claimed = append(claimed, item.Number)
+			}
+		}
+		err = l.deps.DB.VerifyFeedbackItems(l.cfg.PlanID, claimed, l.iteration)
+	default:
+		err = l.deps.DB.VerifyFeedbackItems(l.cfg.PlanID, review.VerifiedItems, l.iteration)
+	}
+	if err != nil {
+		log.Warn("failed to verify feedback items", "error", err)
+	}
+}
+
+// issueFeedbackItems tracks the issues in a review's feedback as review
+// items, leaving out those that repeat an open item.
+func (l *Loop) issueFeedbackItems(sessionID, feedback string) {
+	open := l.openFeedbackItems()
+	known := make([]string, len(open))
+	for i, item := range open {
+		known[i] = item.Content
+	}
+	// A review panel's verdict count isn't an issue
+	if strings.HasPrefix(feedback, reviewPanelSummary) {
+		_, feedback, _ = strings.Cut(feedback, "\n")
+	}
+	for _, issue := range parser.NewFeedback(known, []string{feedback}) {
+		if err := l.deps.DB.CreateFeedbackItem(&db.FeedbackItem{
+			PlanID:          l.cfg.PlanID,
+			SessionID:       sessionID,
+			Severity:        issue.Severity,
+			Content:         issue.Text,
+			IssuedIteration: l.iteration,
+		}); err != nil {
+			log.Warn("failed to store feedback item", "error", err)
+		}
+	}
+}
+
+// panelVerifiedItems returns the claimed fixes a review panel verified: those
+// the quorum of its reviewers verified, counting each approval as verifying
+// every claim.
+func panelVerifiedItems(verified map[int]int, approvals, quorum int) []int {
+	var numbers []int
+	for n, count := range verified {
+		if count+approvals >= quorum {
+			numbers = append(numbers, n)
+		}
+	}
+	slices.Sort(numbers)
+	return numbers
+}
@@ -0,0 +1,186 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// DefaultHookTimeout bounds a hook run when none is configured.
+const DefaultHookTimeout = time.Minute
+
+// maxHookOutputLen caps the hook output quoted in EventHookFailed.
+const maxHookOutputLen = 500
+
+// HookPoint is a point of the loop's lifecycle hooks run at.
+type HookPoint string
+
+const (
+	HookPreIteration  HookPoint = "pre_iteration"  // Before each iteration's developer session
+	HookPostIteration HookPoint = "post_iteration" // After each iteration, whatever its result
+	HookOnDone        HookPoint = "on_done"        // Once the plan completes
+)
+
+// Hook statuses, describing the plan or the iteration that just ran.
+const (
+	HookStatusRunning   = "running"   // The iteration is starting, or ended with more work to do
+	HookStatusApproved  = "approved"  // The iteration ended with the reviewer approving a done signal
+	HookStatusError     = "error"     // The iteration ended with an error
+	HookStatusCompleted = "completed" // The plan completed
+)
+
+// Hook is a command run at a point of the loop's lifecycle (see Deps.Hooks),
+// with a HookPayload as JSON on stdin. A hook that fails or times out is
+// reported with EventHookFailed; the loop carries on.
+type Hook struct {
+	Name    string // Label for events and logs, e.g. "warm cache"
+	On      HookPoint
+	Dir     string        // Directory the command runs in
+	Command []string      // Program and arguments
+	Timeout time.Duration // 0 = DefaultHookTimeout
+}
+
+// HookPayload describes the plan to a hook.
+type HookPayload struct {
+	Hook          HookPoint `json:"hook"`
+	PlanID        string    `json:"plan_id"`
+	Iteration     int       `json:"iteration"`
+	MaxIterations int       `json:"max_iterations"`
+	Status        string    `json:"status"`
+	WorkDir       string    `json:"work_dir"`
+
+	// DiffPath is a file holding the plan's changes so far as a git-format
+	// diff, removed once the hook exits (empty when there is no diff).
+	DiffPath string `json:"diff_path"`
+}
+
+// runHooks runs the hooks for a lifecycle point in order, describing the
+// plan with the given status.
+func (l *Loop) runHooks(ctx context.Context, point HookPoint, status string) {
+	var hooks []Hook
+	for _, hook := range l.deps.Hooks {
+		if hook.On == point {
+			hooks = append(hooks, hook)
+		}
+	}
+	if len(hooks) == 0 || ctx.Err() != nil {
+		return
+	}
+
+	payload := HookPayload{
+		Hook:          point,
+		PlanID:        l.cfg.PlanID,
+		Iteration:     l.iteration,
+		MaxIterations: l.effectiveMaxIter(),
+		Status:        status,
+		WorkDir:       l.cfg.WorkDir,
+	}
+	if path := l.writeHookDiff(ctx); path != "" {
+		payload.DiffPath = path
+		defer func() {
+			if err := os.Remove(path); err != nil {
+				log.Warn("failed to remove hook diff", "path", path, "error", err)
+			}
+		}()
+	}
+	input, err := json.Marshal(payload)
+	if err != nil {
+		log.Warn("failed to encode hook payload", "error", err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if output, err := hook.run(ctx, input); err != nil {
+			log.Warn("hook failed", "hook", hook.Name, "on", point, "error", err, "output", output)
+			message := fmt.Sprintf("Hook %s (%s) failed: %v", hook.Name, point, err)
+			if output != "" {
+				message += "\n" + truncateString(output, maxHookOutputLen)
+			}
+			l.emit(NewEvent(EventHookFailed, l.iteration, l.effectiveMaxIter(), message))
+		}
+	}
+}
+
+// iterationHookStatus returns the status post-iteration hooks are given for
+// an iteration's result.
+func iterationHookStatus(done bool, err error) string {
+	switch {
+	case err != nil:
+		return HookStatusError
+	case done:
+		return HookStatusApproved
+	default:
+		return HookStatusRunning
+	}
+}
+
+// writeHookDiff writes the plan's changes since its base change to a
+// temporary file for hooks, returning its path, or "" without a base change
+// or when there are no changes.
+func (l *Loop) writeHookDiff(ctx context.Context) string {
+	if l.baseChangeID == "" {
+		return ""
+	}
+	diff, err := l.deps.VCS.GitDiff(ctx, l.baseChangeID, "@", l.scopePaths()...)
+	if err != nil {
+		log.Warn("failed to get diff for hooks", "error", err)
+		return ""
+	}
+	if strings.TrimSpace(diff) == "" {
+		return ""
+	}
+
+	f, err := os.CreateTemp("", "ralph-hook-*.diff")
+	if err != nil {
+		log.Warn("failed to create hook diff file", "error", err)
+		return ""
+	}
+	_, err = f.WriteString(diff)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Warn("failed to write hook diff file", "error", err)
+		if err := os.Remove(f.Name()); err != nil {
+			log.Warn("failed to remove hook diff", "path", f.Name(), "error", err)
+		}
+		return ""
+	}
+	return f.Name()
+}
+
+// run runs the hook's command with input on stdin, returning its combined
+// output.
+func (h Hook) run(ctx context.Context, input []byte) (string, error) {
+	if len(h.Command) == 0 {
+		return "", errors.New("no command configured")
+	}
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...)
+	cmd.Dir = h.Dir
+	cmd.Stdin = bytes.NewReader(input)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+
+	output := strings.TrimSpace(out.String())
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return output, fmt.Errorf("timed out after %s", timeout)
+	}
+	return output, err
+}
@@ -0,0 +1,93 @@
+package loop
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoop_Hooks(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	// The developer signals done and the reviewer approves it
+	callCount := 0
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		callCount++
+		output := "## Progress\nDone\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"
+		if callCount > 1 {
+			output = "## Progress\nReviewed\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!"
+		}
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutputWithToolUse(output, "Read"))
+	}
+	const diff = "diff --git a/main.go b/main.go\n+func feature() {}\n"
+
+	// Each hook appends its payload to a file; one also copies the diff
+	dir := t.TempDir()
+	payloads := filepath.Join(dir, "payloads")
+	copied := filepath.Join(dir, "copied.diff")
+	record := []string{"sh", "-c", `cat >> "$1"; echo >> "$1"`, "sh", payloads}
+	deps := testDeps(database, creator, mockJJRunnerWithDiff("base123", diff))
+	deps.Hooks = []Hook{
+		{Name: "record", On: HookPreIteration, Dir: dir, Command: record},
+		{Name: "broken", On: HookPreIteration, Dir: dir, Command: []string{"sh", "-c", "echo boom; exit 3"}},
+		{Name: "record", On: HookPostIteration, Dir: dir, Command: record},
+		{Name: "copy diff", On: HookOnDone, Dir: dir, Command: []string{"sh", "-c",
+			`sed -n 's/.*"diff_path":"\([^"]*\)".*/\1/p' | xargs -I{} cp {} "$1"`, "sh", copied}},
+		{Name: "record", On: HookOnDone, Dir: dir, Command: record},
+	}
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 5, WorkDir: "/tmp"}, deps)
+
+	var failures []string
+	events := runLoop(t, loop)
+	for _, event := range events {
+		if event.Type == EventHookFailed {
+			failures = append(failures, event.Message)
+		}
+	}
+
+	data, err := os.ReadFile(payloads)
+	if err != nil {
+		t.Fatalf("hooks recorded nothing: %v", err)
+	}
+	var got []HookPayload
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var payload HookPayload
+		if err := json.Unmarshal([]byte(line), &payload); err != nil {
+			t.Fatalf("invalid payload %q: %v", line, err)
+		}
+		got = append(got, payload)
+	}
+	want := []struct {
+		hook   HookPoint
+		status string
+	}{
+		{HookPreIteration, HookStatusRunning},
+		{HookPostIteration, HookStatusApproved},
+		{HookOnDone, HookStatusCompleted},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("payloads = %+v, want %d", got, len(want))
+	}
+	for i, w := range want {
+		if got[i].Hook != w.hook || got[i].Status != w.status || got[i].PlanID != plan.ID || got[i].Iteration != 1 {
+			t.Errorf("payload %d = %+v, want %s with status %s", i, got[i], w.hook, w.status)
+		}
+		if got[i].DiffPath == "" {
+			t.Errorf("payload %d has no diff path", i)
+		} else if _, err := os.Stat(got[i].DiffPath); !os.IsNotExist(err) {
+			t.Errorf("diff %s should be removed after the hooks ran", got[i].DiffPath)
+		}
+	}
+	if copiedDiff, err := os.ReadFile(copied); err != nil || string(copiedDiff) != diff {
+		t.Errorf("hook read diff %q, %v; want %q", copiedDiff, err, diff)
+	}
+
+	if len(failures) != 1 || !strings.Contains(failures[0], "Hook broken (pre_iteration) failed") || !strings.Contains(failures[0], "boom") {
+		t.Errorf("hook failures = %q, want the broken hook with its output", failures)
+	}
+}
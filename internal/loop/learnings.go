@@ -0,0 +1,120 @@
+package loop
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// minKeywordLen filters out short words (articles, prepositions) when
+// matching learnings against the plan.
+const minKeywordLen = 4
+
+// loadGlobalLearnings resolves the repo root and returns the most relevant
+// repo-wide learnings for the plan, formatted as a markdown list.
+func (l *Loop) loadGlobalLearnings(ctx context.Context) string {
+	if l.cfg.GlobalLearningsLimit <= 0 {
+		return ""
+	}
+
+	root := l.cfg.RepoRoot
+	if root == "" {
+		var err error
+		root, err = l.deps.VCS.Root(ctx)
+		if err != nil || root == "" {
+			log.Warn("failed to resolve repo root, using work dir for global learnings", "error", err)
+			root = l.cfg.WorkDir
+		}
+	}
+	l.repoRoot = root
+
+	learnings, err := l.deps.DB.GetGlobalLearnings(root)
+	if err != nil {
+		log.Warn("failed to load global learnings", "error", err)
+		return ""
+	}
+
+	return FormatGlobalLearnings(l.plan.Content, learnings, l.cfg.GlobalLearningsLimit)
+}
+
+// FormatGlobalLearnings returns up to n of learnings, the most relevant to
+// planContent first, formatted as a markdown list, or "" when there are none.
+func FormatGlobalLearnings(planContent string, learnings []*db.GlobalLearning, n int) string {
+	ranked := rankGlobalLearnings(planContent, learnings, n)
+	if len(ranked) == 0 {
+		return ""
+	}
+	return "- " + strings.Join(ranked, "\n- ")
+}
+
+// promoteGlobalLearnings stores learnings the agent marked as repo-wide.
+func (l *Loop) promoteGlobalLearnings(contents []string) {
+	if l.cfg.GlobalLearningsLimit <= 0 || l.repoRoot == "" {
+		return
+	}
+	for _, content := range contents {
+		content = sanitizeDevDoneMarker(sanitizeDoneMarker(content))
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		if _, err := l.deps.DB.CreateGlobalLearning(&db.GlobalLearning{
+			RepoRoot: l.repoRoot,
+			PlanID:   l.cfg.PlanID,
+			Content:  content,
+		}); err != nil {
+			log.Warn("failed to store global learning", "error", err)
+		}
+	}
+}
+
+// rankGlobalLearnings returns the content of up to n learnings, ordered by
+// how many distinct keywords they share with the plan. Ties keep the input
+// order (newest first).
+func rankGlobalLearnings(planContent string, learnings []*db.GlobalLearning, n int) []string {
+	planWords := keywords(planContent)
+
+	type scored struct {
+		content string
+		score   int
+	}
+	candidates := make([]scored, len(learnings))
+	for i, learning := range learnings {
+		score := 0
+		for word := range keywords(learning.Content) {
+			if planWords[word] {
+				score++
+			}
+		}
+		candidates[i] = scored{learning.Content, score}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	result := make([]string, len(candidates))
+	for i, c := range candidates {
+		result[i] = c.content
+	}
+	return result
+}
+
+// keywords returns the distinct lowercase words in s of at least minKeywordLen.
+func keywords(s string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) >= minKeywordLen {
+			words[word] = true
+		}
+	}
+	return words
+}
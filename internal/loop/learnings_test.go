@@ -0,0 +1,88 @@
+package loop
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+func TestRankGlobalLearnings(t *testing.T) {
+	learnings := []*db.GlobalLearning{
+		{Content: "Frontend uses pnpm, not npm"},
+		{Content: "Database migrations live in internal/db/migrations.go"},
+		{Content: "Run the database tests with -race"},
+	}
+
+	got := rankGlobalLearnings("Add a column to the database migrations", learnings, 2)
+	want := []string{
+		"Database migrations live in internal/db/migrations.go",
+		"Run the database tests with -race",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("rankGlobalLearnings() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("rankGlobalLearnings()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRankGlobalLearnings_TiesKeepNewestFirst(t *testing.T) {
+	learnings := []*db.GlobalLearning{{Content: "newest"}, {Content: "older"}, {Content: "oldest"}}
+
+	got := rankGlobalLearnings("unrelated plan", learnings, 2)
+	if len(got) != 2 || got[0] != "newest" || got[1] != "older" {
+		t.Errorf("rankGlobalLearnings() = %v, want [newest older]", got)
+	}
+}
+
+func TestLoop_GlobalLearningsSharedAcrossPlans(t *testing.T) {
+	database := setupTestDB(t)
+
+	runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		if len(args) == 1 && args[0] == "root" {
+			return "/repo\n", "", nil
+		}
+		return "", "", nil
+	}
+
+	runPlan := func(output string) []Event {
+		plan := createTestPlan(t, database, "Speed up the protobuf build")
+
+		loop := New(Config{
+			PlanID:               plan.ID,
+			MaxIterations:        1,
+			WorkDir:              "/tmp",
+			GlobalLearningsLimit: 5,
+		}, testDeps(database, mockClaudeCreator(output), runner))
+
+		events := runLoop(t, loop)
+		return events
+	}
+
+	// First plan promotes a learning
+	runPlan("## Progress\nWorking\n\n## Learnings\nLocal\n\n## Global Learnings\n- Regenerate protobuf code with make proto\n\n## Status\nRUNNING RUNNING RUNNING")
+
+	stored, err := database.GetGlobalLearnings("/repo")
+	if err != nil {
+		t.Fatalf("GetGlobalLearnings() error: %v", err)
+	}
+	if len(stored) != 1 || stored[0].Content != "Regenerate protobuf code with make proto" {
+		t.Fatalf("expected promoted learning to be stored, got %v", stored)
+	}
+
+	// Second plan sees it in the developer prompt
+	events := runPlan("## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING")
+	var found bool
+	for _, e := range events {
+		if e.Type == EventPromptBuilt && strings.Contains(e.Prompt, "Regenerate protobuf code with make proto") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected global learning in the next plan's developer prompt")
+	}
+}
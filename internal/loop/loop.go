@@ -5,17 +5,26 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 
 	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/analyze"
 	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/conventions"
 	"github.com/gerunddev/ralph/internal/db"
 	"github.com/gerunddev/ralph/internal/jj"
 	"github.com/gerunddev/ralph/internal/log"
 	"github.com/gerunddev/ralph/internal/parser"
+	"github.com/gerunddev/ralph/internal/policy"
+	"github.com/gerunddev/ralph/internal/redact"
+	"github.com/gerunddev/ralph/internal/triage"
 )
 
 // maxDiffBytes is the maximum size of diff to include in reviewer prompt.
@@ -31,7 +40,7 @@ func truncateDiff(diff string) string {
 		return diff
 	}
 
-	truncated := diff[:maxDiffBytes]
+	truncated := runeCut(diff, maxDiffBytes)
 	// Try to truncate at a line boundary for cleaner output
 	if lastNewline := strings.LastIndex(truncated, "\n"); lastNewline > maxDiffBytes/2 {
 		truncated = truncated[:lastNewline]
@@ -57,18 +66,294 @@ func sanitizeDevDoneMarker(s string) string {
 type Config struct {
 	PlanID          string
 	MaxIterations   int
-	ExtremeMode     bool   // Enable extreme mode (+3 iterations after both done)
+	ExtremeMode     bool   // Enable extreme mode (bonus iterations after both done)
 	TeamMode        bool   // Enable agent teams for developer phase
 	WorkDir         string // For jj operations
+	RepoRoot        string // Repository global learnings are keyed by, when WorkDir is a jj workspace outside it (empty = jj root)
 	EventBufferSize int    // Size of event channel buffer (default: 1000)
+
+	// ExtremeBonusIterations is how many iterations extreme mode adds each
+	// time the developer and reviewer agree the plan is done (0 = 3), for
+	// at most ExtremeMaxRounds rounds (0 = 1); later agreements are ignored.
+	ExtremeBonusIterations int
+	ExtremeMaxRounds       int
+
+	// MaxDuration is the wall-clock budget for this run. It is checked
+	// between iterations, so the current iteration always finishes
+	// (0 = unlimited).
+	MaxDuration time.Duration
+
+	// MaxCost is the plan's Claude spend budget in USD, across all of its
+	// runs. Like MaxDuration it is checked between iterations, so the
+	// iteration that spends it finishes first (0 = unlimited).
+	MaxCost float64
+
+	// IterationsThisRun pauses the plan once this run has worked this many
+	// iterations, for batch runs driven by an external scheduler
+	// (0 = unlimited).
+	IterationsThisRun int
+
+	// Policy restricts which paths the developer may modify (nil = unrestricted).
+	Policy *policy.Policy
+
+	// FlagOutOfScopeFiles keeps the developer's changes to files outside
+	// the plan's "files:" allowlist and only asks for them to be undone,
+	// instead of restoring them from the plan's base change.
+	FlagOutOfScopeFiles bool
+
+	// Scope restricts the plan to a directory of a mono-repo, relative to
+	// the repository root and slash-separated: diffs, static analysis, and
+	// convention files cover only it, prompts name it, and changes outside
+	// it are treated like changes outside the "files:" allowlist
+	// (empty = the whole repository).
+	Scope string
+
+	// Stall handling: after StallThreshold consecutive iterations without
+	// progress, either nudge the developer or abort (0 = disabled).
+	StallThreshold int
+	StallAbort     bool
+
+	// FailureTriageAfter runs a failure triage agent, which diagnoses the
+	// systemic cause and reports it as EventFailureTriage, once the same
+	// iteration error or the same failing checks happened this many times
+	// in a row (0 = never).
+	FailureTriageAfter int
+
+	// Retry controls retries of Claude sessions that fail transiently
+	// (zero value = no retries).
+	Retry RetryPolicy
+
+	// ModelChain is the configured model followed by its fallbacks. A
+	// session whose model is overloaded or out of quota is retried at once
+	// on the next model of the chain; sessions of clients configured with
+	// a model outside it aren't (nil = no fallbacks).
+	ModelChain []string
+
+	// Throttle limits how fast Claude sessions start, across every run
+	// sharing the database (zero value = unlimited).
+	Throttle Throttle
+
+	// QuietHours pauses the loop between iterations during a daily window
+	// and resumes it once the window ends (zero value = never).
+	QuietHours QuietHours
+
+	// GlobalLearningsLimit is the max number of repo-wide learnings included
+	// in developer prompts (0 = disable global learnings).
+	GlobalLearningsLimit int
+
+	// Decompose runs a planner agent that breaks the plan into ordered tasks,
+	// which are then developed and reviewed one at a time. Plans that already
+	// have tasks are always worked as tasks.
+	Decompose bool
+
+	// WatchPlan checks the plan file for edits before each iteration and
+	// reports them. With MergePlanEdits, the edited file replaces the stored
+	// plan and the next developer prompt notes what changed.
+	WatchPlan      bool
+	MergePlanEdits bool
+
+	// WaitOnConflicts holds the loop while jj reports conflicts in the
+	// working copy, checked at the start of each iteration and after the
+	// developer's session, until they are resolved by hand. With
+	// ResolveConflicts, a conflict resolver agent tries to resolve them
+	// first.
+	WaitOnConflicts  bool
+	ResolveConflicts bool
+
+	// AutoApplyReviewPatches applies patches the reviewer suggests with its
+	// feedback as separate jj changes. Otherwise they are only passed to the
+	// developer in the next prompt.
+	AutoApplyReviewPatches bool
+
+	// CommitTrailers adds review trailers to the jj change description when
+	// the reviewer approves.
+	CommitTrailers bool
+
+	// ChangePerIteration starts a new jj change for each iteration instead
+	// of amending one working change.
+	ChangePerIteration bool
+
+	// SquashOnComplete squashes the plan's changes into a single change,
+	// described from the plan, when the plan completes.
+	SquashOnComplete bool
+
+	// Bookmark names a jj bookmark kept on the plan's work ("" = none). It
+	// is created at the base change when the plan starts and advanced after
+	// each completed iteration. When the plan completes it is left on the
+	// work for pushing, or deleted with DeleteBookmark.
+	Bookmark       string
+	DeleteBookmark bool
+
+	// MaxSubPlanDepth lets the developer propose a sub-plan for a large
+	// tangent, recorded as a plan of its own linked to this one, with
+	// sub-plans nested up to this depth (0 = no sub-plans). SubPlanDepth is
+	// how deep this plan is nested (0 = not a sub-plan). With RunSubPlans
+	// a proposed sub-plan runs after the iteration that proposed it, before
+	// the plan resumes, and its learnings are added to the plan's.
+	MaxSubPlanDepth int
+	SubPlanDepth    int
+	RunSubPlans     bool
+
+	// Reviewers is the size of the review panel in team mode: each reviewer
+	// reviews the iteration independently, and the plan is done only when
+	// ReviewQuorum of them approve (0 = all). Outside team mode, and with
+	// fewer than 2 reviewers, a single reviewer decides.
+	Reviewers    int
+	ReviewQuorum int
+
+	// TrivialChanges classifies the change each developer session made. The
+	// review of a trivial change is skipped, or downgraded to
+	// Deps.TrivialReviewClaude when set. A done signal is always reviewed in
+	// full (nil = review every change).
+	TrivialChanges *triage.Rules
+
+	// SummarizeLargeDiffs has a cumulative diff too large for the reviewer's
+	// prompt summarized file by file, by Deps.SummaryClaude, in place of
+	// being truncated.
+	SummarizeLargeDiffs bool
+
+	// ReviewFiles gives the reviewer the current content of the files the
+	// cumulative diff changes, up to ReviewFilesBytes bytes (0 = 64 KiB),
+	// besides the diff. The "review_context:" key of the plan's front
+	// matter overrides it.
+	ReviewFiles      bool
+	ReviewFilesBytes int
+
+	// StatusTool is whether agents are asked to report their status with a
+	// ralph_status tool call, read from the session's stream in place of
+	// the markdown sections (which are still read when no call is made).
+	StatusTool bool
+
+	// StateTools is whether sessions have ralph's MCP tools for querying
+	// the plan's state, whose record_progress checkpoints are stored as
+	// they stream in.
+	StateTools bool
+
+	// DifferentialPrompts resumes the previous developer session's Claude
+	// session with a prompt holding only what changed since, instead of the
+	// full prompt. The full prompt is sent in a new session every
+	// FullPromptEvery developer sessions, when the task changes, and when
+	// the previous session can't be resumed. Ignored in team mode.
+	DifferentialPrompts bool
+	FullPromptEvery     int
+
+	// FastResume re-runs a developer session interrupted mid-stream (by a
+	// crash or a kill) with the prompt it was sent, stored with the session,
+	// instead of rebuilding the prompt, as long as nothing the prompt was
+	// built from changed since. With ResumeClaudeSession the interrupted
+	// Claude session is resumed too, when its ID was recorded.
+	FastResume          bool
+	ResumeClaudeSession bool
+
+	// SelfCheckAttempts is how many follow-ups may ask for a developer or
+	// reviewer session's output to be reformatted when it is missing the
+	// required sections, before it is taken as-is (0 = never ask).
+	SelfCheckAttempts int
+
+	// Locale is the locale code of the language agents are asked to write
+	// in (empty = English).
+	Locale string
+
+	// AgentPrompt returns the custom prompt template replacing the built-in
+	// one for an agent type ("developer", "reviewer", or "planner"), or ""
+	// for the built-in prompt. It is called each time a prompt is built
+	// (nil = built-in prompts).
+	AgentPrompt func(agentType string) (string, error)
+
+	// ClaudeVersion is the claude CLI's version, recorded with each
+	// session's environment (empty = unknown).
+	ClaudeVersion string
+
+	// Redactor masks secrets in published events before subscribers (TUI,
+	// webhooks) see them (nil = publish as-is).
+	Redactor *redact.Redactor
+}
+
+// VCS records and diffs the work of each iteration. It is the jj client's
+// interface, which a snapshot client mirrors for directories without a
+// repository (see snapshot.Client).
+type VCS interface {
+	Abandon(ctx context.Context, revision string) error
+	AddTrailers(ctx context.Context, revision string, trailers []jj.Trailer) error
+	ApplyPatch(ctx context.Context, patch string) error
+	ChangeIDs(ctx context.Context, revset string) ([]string, error)
+	ChangedFiles(ctx context.Context, from, to string, paths ...string) ([]string, error)
+	CheckPatch(ctx context.Context, patch string) error
+	Conflicts(ctx context.Context) ([]string, error)
+	Describe(ctx context.Context, message string) error
+	Diff(ctx context.Context, from, to string, paths ...string) (string, error)
+	GetCurrentChangeID(ctx context.Context) (string, error)
+	GetCurrentCommitID(ctx context.Context) (string, error)
+	GetDescription(ctx context.Context, revision string) (string, error)
+	GetParentChangeID(ctx context.Context) (string, error)
+	GitDiff(ctx context.Context, from, to string, paths ...string) (string, error)
+	IsEmpty(ctx context.Context) (bool, error)
+	New(ctx context.Context, message string) error
+	Restore(ctx context.Context, from string, paths ...string) error
+	Root(ctx context.Context) (string, error)
+	Show(ctx context.Context) (string, error)
+	Squash(ctx context.Context, from, into, message string) error
+	Status(ctx context.Context) (string, error)
+}
+
+// OperationLog is implemented by VCS clients that keep an operation log,
+// like the jj client, so an iteration's effects on the repository can be
+// undone by restoring the operation it started from. Directory snapshots
+// have none.
+type OperationLog interface {
+	CurrentOperation(ctx context.Context) (string, error)
+}
+
+// Bookmarks is implemented by VCS clients with named bookmarks, like the jj
+// client, so the plan's work can be kept on one (see Config.Bookmark).
+type Bookmarks interface {
+	SetBookmark(ctx context.Context, bookmark, revision string) error
+	DeleteBookmark(ctx context.Context, bookmark string) error
 }
 
 // Deps holds dependencies for the loop.
 type Deps struct {
-	DB         *db.DB
-	Claude     *claude.Client // Default Claude client (used for reviewer, and developer when not in team mode)
-	TeamClaude *claude.Client // Claude client with team env vars (used for developer in team mode; nil when not in team mode)
-	JJ         *jj.Client
+	DB             *db.DB
+	Claude         *claude.Client // Default Claude client (used for developer when not in team mode, and reviewer when ReviewerClaude is nil)
+	TeamClaude     *claude.Client // Claude client with team env vars (used for developer in team mode; nil when not in team mode)
+	ReviewerClaude *claude.Client // Claude client with reviewer-specific CLI options (nil = use Claude)
+
+	// TrivialReviewClaude reviews trivial changes in place of the full
+	// reviewer (nil = skip their review; see Config.TrivialChanges)
+	TrivialReviewClaude *claude.Client
+
+	// ReformatClaude runs the follow-ups that reformat malformed output
+	// (nil = the reviewer's client; see Config.SelfCheckAttempts)
+	ReformatClaude *claude.Client
+
+	// TriageClaude runs failure triage sessions (nil = the reviewer's
+	// client; see Config.FailureTriageAfter)
+	TriageClaude *claude.Client
+
+	// SummaryClaude summarizes diffs too large for the reviewer (nil = the
+	// reviewer's client; see Config.SummarizeLargeDiffs)
+	SummaryClaude *claude.Client
+
+	VCS       VCS             // jj repository, or directory snapshots without one
+	Analyzers *analyze.Runner // Static analyzers run before each review (nil = none)
+
+	// Conventions finds the repository's convention files for the
+	// developer and reviewer prompts (nil = none)
+	Conventions *conventions.Provider
+
+	// TestGate runs the tests for test-authoring reviews: the reviewer of
+	// a done signal must add a test, in a jj change of its own, that fails
+	// without the developer's change and passes with it before its
+	// approval counts. A review panel doesn't author tests (nil = off).
+	TestGate TestGate
+
+	// Checks run after each developer session; the output of failing ones
+	// goes in the next developer prompt (empty = none)
+	Checks []Check
+
+	// Hooks run at points of the loop's lifecycle, for integrations such as
+	// cache warming or deployment (empty = none)
+	Hooks []Hook
 }
 
 // Loop orchestrates the main execution loop for Ralph.
@@ -76,17 +361,126 @@ type Loop struct {
 	cfg  Config
 	deps Deps
 
-	events      chan Event
-	eventsMu    sync.Mutex
+	bus         *Bus
+	events      *Subscription // Default lossy subscription returned by Events()
 	iterationMu sync.RWMutex
 	iteration   int
+	ranThisRun  int     // Iterations started by this run
+	outcome     Outcome // How the run ended (guarded by iterationMu)
+
+	// What failed in the current iteration (OutcomeProviderError,
+	// OutcomeGateFailures, or "" for nothing), and whether the reviewer
+	// rejected a done signal during the run, for classifying a run a
+	// limit stops
+	iterationFailure Outcome
+	doneRejected     bool
 
 	// For tracking state
 	plan         *db.Plan
-	baseChangeID string // jj change ID at the start of the loop, used for reviewer diffs
+	baseChangeID string    // jj change ID at the start of the loop, used for reviewer diffs
+	deadline     time.Time // When MaxDuration runs out (zero = no limit)
+	usage        PlanUsage // The plan's Claude cost and tokens so far
 
 	// Extreme mode state
-	extremeModeTriggered bool // Whether +3 has been triggered
+	extremeRounds int // Rounds of bonus iterations triggered so far
+
+	// Stall detection state
+	stall   stallDetector
+	stalled bool // Whether the next developer prompt should include the stuck nudge
+
+	// Repeated failures, for the failure triage
+	failures failureStreak
+
+	// Sessions in a row that failed the same checks, for rejected approaches
+	checkFailures failureStreak
+
+	// Repo-wide learnings state
+	repoRoot        string // Repository root that global learnings are keyed by
+	globalLearnings string // Relevant global learnings, loaded once at start
+
+	// The repository's convention files, formatted for prompts and loaded
+	// once at start
+	conventions string
+
+	// Go version of the target repository, recorded with each session's environment
+	goVersion string
+
+	// Task mode state
+	tasks []*db.Task // Tasks the plan was decomposed into (empty = not in task mode)
+	task  *db.Task   // Task currently being worked on
+
+	// Plan file edits merged since the last developer prompt
+	planUpdate     string // Diff shown to the developer (empty = none)
+	planBeforeEdit string // Plan content the developer last saw
+
+	// Changes outside the plan's scope or files allowlist, for the next
+	// developer prompt
+	outOfScope string
+
+	// Output of the checks that failed after the last developer session,
+	// for the next developer prompt
+	failingChecks string
+
+	// The last valid status tool call of each session, by session ID
+	statusReports map[string]*parser.StatusReport
+
+	// Differential prompt state: the CLI session of the last developer
+	// session (empty = none to resume), the task it worked on, and the
+	// differential prompts sent since the last full one
+	devClaudeSession string
+	devSessionTask   string
+	deltaPrompts     int
+
+	// Feedback sent by the user while the loop runs, for the next developer prompt
+	userFeedbackMu sync.Mutex
+	userFeedback   []string
+
+	// Rebuttal state: the session of the review feedback given to the
+	// developer, whether it may be disputed, and feedback withdrawn this
+	// iteration after a rebuttal (for the reviewer prompt)
+	feedbackSessionID string
+	rebuttalAllowed   bool
+	withdrawnFeedback string
+
+	// Current content of the changed files for this iteration's reviewers
+	// (empty = not included)
+	reviewFiles string
+
+	// The jj change the reviewer adds its tests in during a test-authoring
+	// review (empty = the review doesn't author tests)
+	reviewerTestChange string
+
+	// Tool calls of the current iteration, for the live activity summary
+	activity *toolUsage
+
+	// Stage timing state: the session being timed, the current iteration's
+	// start and stage totals, and the time spent in jj operations
+	timer       *sessionTimer
+	iterStart   time.Time
+	iterJJStart time.Duration
+	iterTimings db.SessionTimings
+	jjTime      atomic.Int64 // Nanoseconds
+
+	// Rate-limit cooldown this run started, in case it couldn't be stored
+	cooldownUntil time.Time
+
+	// Teammates of the current team-mode developer session, for routing
+	// review panel feedback (nil outside team mode)
+	workers *teamWorkers
+
+	// Sub-plan the developer proposed this iteration, run or recorded once
+	// the iteration ends (nil if none)
+	subPlan *proposedSubPlan
+
+	// Developer session interrupted in the previous run, whose prompt the
+	// first iteration re-submits (nil if none)
+	interrupted *db.PlanSession
+
+	// Pause and Stop requests from embedding programs
+	controlMu      sync.Mutex
+	pauseRequested bool
+	stopRequested  bool
+	cancelRun      context.CancelFunc // Cancels the running Run (nil when not running)
 }
 
 // New creates a new Loop with the given configuration and dependencies.
@@ -95,23 +489,39 @@ func New(cfg Config, deps Deps) *Loop {
 	if bufferSize <= 0 {
 		bufferSize = 10000 // Default buffer size - needs to be large for Claude streaming events
 	}
-	return &Loop{
-		cfg:    cfg,
-		deps:   deps,
-		events: make(chan Event, bufferSize),
+	bus := NewBus()
+	l := &Loop{
+		cfg:      cfg,
+		bus:      bus,
+		events:   bus.Subscribe(WithBuffer(bufferSize), Lossy()),
+		activity: newToolUsage(),
+	}
+	// jj operations are timed for the iteration and session timings
+	if deps.VCS != nil {
+		deps.VCS = timedVCS{vcs: deps.VCS, elapsed: &l.jjTime}
 	}
+	l.deps = deps
+	return l
 }
 
-// Events returns the channel for receiving loop events.
-// The channel is closed when the loop completes.
+// Events returns the channel for receiving all loop events. It is a lossy
+// subscription: events are dropped rather than blocking the loop when the
+// buffer is full. The channel is closed when the loop completes.
 func (l *Loop) Events() <-chan Event {
-	return l.events
+	return l.events.Events()
+}
+
+// Subscribe registers an additional event subscriber, e.g. for persistence,
+// webhooks, or metrics. Subscribe before Run to receive every event; the
+// subscription is closed when the loop completes.
+func (l *Loop) Subscribe(opts ...SubscribeOption) *Subscription {
+	return l.bus.Subscribe(opts...)
 }
 
 // effectiveMaxIter returns the max iterations to use in events.
 // In extreme mode before trigger, returns 0 (signals "X" to UI).
 func (l *Loop) effectiveMaxIter() int {
-	if l.cfg.ExtremeMode && !l.extremeModeTriggered {
+	if l.cfg.ExtremeMode && l.extremeRounds == 0 {
 		return 0
 	}
 	return l.cfg.MaxIterations
@@ -125,16 +535,114 @@ func (l *Loop) CurrentIteration() int {
 	return l.iteration
 }
 
+// IterationsRun returns the number of iterations this run has started.
+func (l *Loop) IterationsRun() int {
+	l.iterationMu.RLock()
+	defer l.iterationMu.RUnlock()
+	return l.ranThisRun
+}
+
+// AddUserFeedback queues feedback from the user for the next developer
+// prompt. It is safe to call while the loop runs.
+func (l *Loop) AddUserFeedback(feedback string) {
+	feedback = strings.TrimSpace(feedback)
+	if feedback == "" {
+		return
+	}
+	l.userFeedbackMu.Lock()
+	defer l.userFeedbackMu.Unlock()
+	l.userFeedback = append(l.userFeedback, feedback)
+}
+
+// takeUserFeedback returns the user feedback queued since the last
+// developer prompt, oldest first, and emits EventUserFeedback if there was any.
+func (l *Loop) takeUserFeedback() string {
+	l.userFeedbackMu.Lock()
+	queued := l.userFeedback
+	l.userFeedback = nil
+	l.userFeedbackMu.Unlock()
+
+	if len(queued) == 0 {
+		return ""
+	}
+	feedback := strings.Join(queued, "\n\n---\n\n")
+	l.emit(NewEvent(EventUserFeedback, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Sending user feedback to the developer: %s", truncateString(feedback, 100))))
+	return feedback
+}
+
+// Pause asks the loop to pause the plan once the current iteration
+// finishes, so it can be resumed later. It is safe to call while the loop
+// runs.
+func (l *Loop) Pause() {
+	l.controlMu.Lock()
+	defer l.controlMu.Unlock()
+	l.pauseRequested = true
+}
+
+// Stop interrupts the loop, abandoning the current iteration, and leaves the
+// plan paused so it can be resumed; Run then returns nil. It is safe to call
+// while the loop runs, and a Stop before Run makes Run return at once.
+func (l *Loop) Stop() {
+	l.controlMu.Lock()
+	defer l.controlMu.Unlock()
+	l.stopRequested = true
+	if l.cancelRun != nil {
+		l.cancelRun()
+	}
+}
+
+// requested returns the pending pause and stop requests.
+func (l *Loop) requested() (pause, stop bool) {
+	l.controlMu.Lock()
+	defer l.controlMu.Unlock()
+	return l.pauseRequested, l.stopRequested
+}
+
 // Run executes the main loop until completion, max iterations, or cancellation.
-func (l *Loop) Run(ctx context.Context) error {
-	defer close(l.events)
+func (l *Loop) Run(ctx context.Context) (err error) {
+	defer l.bus.Close()
+
+	// Stop cancels the run
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	l.controlMu.Lock()
+	l.cancelRun = cancel
+	if l.stopRequested {
+		cancel()
+	}
+	l.controlMu.Unlock()
+	defer func() {
+		l.controlMu.Lock()
+		l.cancelRun = nil
+		l.controlMu.Unlock()
+	}()
+
+	if l.cfg.MaxDuration > 0 {
+		l.deadline = time.Now().Add(l.cfg.MaxDuration)
+	}
 
 	// Load the plan
 	plan, err := l.deps.DB.GetPlan(l.cfg.PlanID)
 	if err != nil {
 		return fmt.Errorf("failed to load plan: %w", err)
 	}
+	// Run the plan as written, not with its secrets masked
+	plan.Content = plan.RunContent()
 	l.plan = plan
+	l.snapshotPlanFile()
+	if _, err := policy.PlanFiles(plan.Content); err != nil {
+		return fmt.Errorf("invalid plan front matter: %w", err)
+	}
+	if _, err := agent.PlanChecklist(plan.Content); err != nil {
+		return fmt.Errorf("invalid plan front matter: %w", err)
+	}
+	if _, err := agent.AcceptanceCriteria(plan.Content); err != nil {
+		return fmt.Errorf("invalid plan front matter: %w", err)
+	}
+	if _, err := agent.ReviewContext(plan.Content); err != nil {
+		return fmt.Errorf("invalid plan front matter: %w", err)
+	}
 
 	// Determine starting iteration (for resume support)
 	latestSession, err := l.deps.DB.GetLatestPlanSession(l.cfg.PlanID)
@@ -144,6 +652,11 @@ func (l *Loop) Run(ctx context.Context) error {
 	if latestSession != nil {
 		l.iterationMu.Lock()
 		l.iteration = latestSession.Iteration
+		if l.interruptedSession(latestSession) {
+			// The interrupted iteration runs again
+			l.interrupted = latestSession
+			l.iteration--
+		}
 		l.iterationMu.Unlock()
 	}
 
@@ -151,6 +664,14 @@ func (l *Loop) Run(ctx context.Context) error {
 	if err := l.deps.DB.UpdatePlanStatus(l.cfg.PlanID, db.PlanStatusRunning); err != nil {
 		log.Warn("failed to update plan status", "error", err)
 	}
+	defer func() {
+		if _, stop := l.requested(); stop && errors.Is(err, context.Canceled) {
+			err = nil
+			l.pauseOnRequest("Stopped by request")
+			return
+		}
+		l.recordExit(err)
+	}()
 
 	// Load or capture base change ID for reviewer diffs.
 	// On first run, we capture @- (parent of current working copy) and persist it.
@@ -161,7 +682,7 @@ func (l *Loop) Run(ctx context.Context) error {
 		log.Debug("using persisted base change ID for reviewer diffs", "changeID", plan.BaseChangeID)
 	} else {
 		// First run: capture and persist the parent change ID
-		baseChangeID, err := l.deps.JJ.GetParentChangeID(ctx)
+		baseChangeID, err := l.deps.VCS.GetParentChangeID(ctx)
 		if err != nil {
 			log.Warn("failed to get parent change ID", "error", err)
 		} else if baseChangeID != "" {
@@ -172,13 +693,33 @@ func (l *Loop) Run(ctx context.Context) error {
 			} else {
 				log.Debug("captured and persisted parent change ID for reviewer diffs", "changeID", baseChangeID)
 			}
+			l.setBookmark(ctx, baseChangeID)
 		} else {
 			log.Debug("no parent change ID (root commit), will use jj show fallback")
 		}
 	}
 
+	// Load repo-wide learnings from previous plans
+	l.globalLearnings = l.loadGlobalLearnings(ctx)
+	l.conventions = l.loadConventions(ctx)
+	l.goVersion = detectGoVersion(l.cfg.WorkDir, l.repoRoot)
+
 	// Emit started event
 	l.emit(NewEvent(EventStarted, l.iteration, l.effectiveMaxIter(), "Loop started"))
+	l.loadUsage()
+	l.emitUsage()
+
+	// Break the plan into tasks, or pick up tasks from an earlier run
+	if err := l.loadTasks(ctx); err != nil {
+		return err
+	}
+	if len(l.tasks) > 0 && !l.startNextTask(ctx) {
+		l.squashPlanChanges(ctx)
+		l.finishBookmark(ctx)
+		l.completePlan("All tasks completed")
+		l.runHooks(ctx, HookOnDone, HookStatusCompleted)
+		return nil
+	}
 
 	// Main loop
 	for {
@@ -189,6 +730,40 @@ func (l *Loop) Run(ctx context.Context) error {
 		default:
 		}
 
+		// Pause between iterations when asked to
+		if pause, _ := l.requested(); pause {
+			l.pauseOnRequest("Paused by request")
+			return nil
+		}
+
+		// Hold off the next iteration during quiet hours
+		if err := l.waitForQuietHours(ctx); err != nil {
+			return err
+		}
+
+		// Stop between iterations once the wall-clock budget is spent
+		if !l.deadline.IsZero() && !time.Now().Before(l.deadline) {
+			reason := fmt.Sprintf("Reached max duration (%s)", l.cfg.MaxDuration)
+			l.pausePlan(reason)
+			l.emit(NewEvent(EventMaxDuration, l.iteration, l.effectiveMaxIter(),
+				fmt.Sprintf("%s; resume with: ralph --resume %s", reason, l.cfg.PlanID)))
+			return nil
+		}
+
+		// Stop between iterations once the cost budget is spent
+		if reason := l.overBudget(); reason != "" {
+			l.pausePlan(reason)
+			l.emit(NewEvent(EventMaxCost, l.iteration, l.effectiveMaxIter(),
+				fmt.Sprintf("%s; resume with: ralph --resume %s --max-cost <usd>", reason, l.cfg.PlanID)))
+			return nil
+		}
+
+		// Stop between iterations once this run's share of them is done
+		if l.cfg.IterationsThisRun > 0 && l.IterationsRun() >= l.cfg.IterationsThisRun {
+			l.pauseOnRequest(fmt.Sprintf("Ran %d iterations this run", l.cfg.IterationsThisRun))
+			return nil
+		}
+
 		// Increment iteration
 		l.iterationMu.Lock()
 		l.iteration++
@@ -196,63 +771,161 @@ func (l *Loop) Run(ctx context.Context) error {
 		l.iterationMu.Unlock()
 
 		// Check max iterations (skip in extreme mode until triggered)
-		if !l.cfg.ExtremeMode || l.extremeModeTriggered {
+		if !l.cfg.ExtremeMode || l.extremeRounds > 0 {
 			if currentIter > l.cfg.MaxIterations {
-				if err := l.deps.DB.UpdatePlanStatus(l.cfg.PlanID, db.PlanStatusStopped); err != nil {
-					log.Warn("failed to update plan status to stopped", "error", err)
-				}
-				l.emit(NewEvent(EventMaxIterations, l.iteration-1, l.effectiveMaxIter(),
-					fmt.Sprintf("Reached max iterations (%d)", l.cfg.MaxIterations)))
+				reason := fmt.Sprintf("Reached max iterations (%d)", l.cfg.MaxIterations)
+				l.stopPlan(reason)
+				l.emit(NewEvent(EventMaxIterations, l.iteration-1, l.effectiveMaxIter(), reason))
 				return nil
 			}
 		}
 
 		// Run one iteration
+		l.iterationMu.Lock()
+		l.ranThisRun++
+		l.iterationMu.Unlock()
+		l.iterationFailure = ""
+		l.runHooks(ctx, HookPreIteration, HookStatusRunning)
 		done, err := l.runIteration(ctx)
+		l.runHooks(ctx, HookPostIteration, iterationHookStatus(done, err))
+		if errors.Is(err, errStalled) {
+			reason := fmt.Sprintf("Stopped after %d iterations without progress", l.stall.count)
+			l.stopPlan(reason)
+			l.emit(NewEvent(EventStallAborted, l.iteration, l.effectiveMaxIter(), reason))
+			return nil
+		}
 		if err != nil {
 			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 				return err
 			}
+			// Policy violations already emitted their own event
+			if errors.Is(err, policy.ErrViolation) {
+				log.Warn("iteration aborted", "iteration", l.iteration, "error", err)
+				continue
+			}
 			// Log error but continue - be resilient
 			log.Error("iteration error", "iteration", l.iteration, "error", err)
 			l.emit(NewErrorEvent(l.iteration, l.effectiveMaxIter(), err))
+			var providerErr *ProviderError
+			if errors.As(err, &providerErr) {
+				l.iterationFailure = OutcomeProviderError
+			}
+			l.recordFailure("Iteration error", err.Error(), err.Error())
+			if err := l.runFailureTriage(ctx); err != nil {
+				return err
+			}
 			continue
 		}
 
+		l.advanceBookmark(ctx)
+		if err := l.runSubPlan(ctx); err != nil {
+			return err
+		}
+
+		// An iteration without errors ends a streak of them; failing checks
+		// were recorded by runChecks
+		if l.failingChecks == "" {
+			l.failures.reset()
+		}
+		if err := l.runFailureTriage(ctx); err != nil {
+			return err
+		}
+
 		if done {
-			if l.cfg.ExtremeMode {
-				if !l.extremeModeTriggered {
-					// First "both done" in extreme mode - trigger +3
-					l.extremeModeTriggered = true
-					l.cfg.MaxIterations = currentIter + 3
-					l.emit(NewEvent(EventExtremeModeTriggered, l.iteration, l.effectiveMaxIter(),
-						fmt.Sprintf("+3 iterations (max now %d)", l.cfg.MaxIterations)))
+			// In task mode approval completes the current task; the plan is
+			// done once no tasks remain
+			if l.task != nil {
+				l.completeTask()
+				if l.startNextTask(ctx) {
 					continue
 				}
-				// Already triggered - ignore done, keep going
+			}
+			if l.cfg.ExtremeMode {
+				// "Both done" starts a round of bonus iterations, until
+				// the rounds run out; then it is ignored
+				l.startExtremeRound(currentIter)
 				continue
 			}
 			// Normal mode - exit
-			if err := l.deps.DB.UpdatePlanStatus(l.cfg.PlanID, db.PlanStatusCompleted); err != nil {
-				log.Warn("failed to mark plan complete", "error", err)
-			}
-			l.emit(NewEvent(EventDone, l.iteration, l.effectiveMaxIter(), "Agent completed"))
+			l.squashPlanChanges(ctx)
+			l.finishBookmark(ctx)
+			l.completePlan("Agent completed")
+			l.runHooks(ctx, HookOnDone, HookStatusCompleted)
 			return nil
 		}
 	}
 }
 
-// emit sends an event to the events channel if it's not full.
-func (l *Loop) emit(event Event) {
-	l.eventsMu.Lock()
-	defer l.eventsMu.Unlock()
+// stopPlan marks the plan stopped at a limit, recording why.
+func (l *Loop) stopPlan(reason string) {
+	l.setOutcome(l.limitOutcome())
+	if err := l.deps.DB.UpdatePlanStatusWithReason(l.cfg.PlanID, db.PlanStatusStopped, reason); err != nil {
+		log.Warn("failed to update plan status to stopped", "error", err)
+	}
+}
+
+// pausePlan marks the plan paused so it can be resumed later, recording why.
+func (l *Loop) pausePlan(reason string) {
+	l.setOutcome(OutcomePaused)
+	if err := l.deps.DB.UpdatePlanStatusWithReason(l.cfg.PlanID, db.PlanStatusPaused, reason); err != nil {
+		log.Warn("failed to update plan status to paused", "error", err)
+	}
+}
+
+// pauseOnRequest pauses the plan on a Pause or Stop request and emits
+// EventPaused.
+func (l *Loop) pauseOnRequest(reason string) {
+	l.pausePlan(reason)
+	l.emit(NewEvent(EventPaused, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("%s; resume with: ralph --resume %s", reason, l.cfg.PlanID)))
+}
+
+// recordExit records why a run ended with an error: interrupted runs are
+// paused so they can be resumed, anything else fails the plan and emits
+// EventFailed.
+func (l *Loop) recordExit(err error) {
+	if err == nil {
+		return
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		l.pausePlan("Interrupted")
+		return
+	}
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		l.setOutcome(OutcomeProviderError)
+	} else {
+		l.setOutcome(OutcomeFailed)
+	}
+	reason := err.Error()
+	if err := l.deps.DB.UpdatePlanStatusWithReason(l.cfg.PlanID, db.PlanStatusFailed, reason); err != nil {
+		log.Warn("failed to update plan status to failed", "error", err)
+	}
+	l.emit(NewEvent(EventFailed, l.iteration, l.effectiveMaxIter(), reason))
+}
 
-	select {
-	case l.events <- event:
-	default:
-		// Channel full, log and drop
-		log.Warn("event channel full, dropping event", "type", event.Type)
+// completePlan marks the plan (and its task project, if any) completed and
+// emits EventDone.
+func (l *Loop) completePlan(message string) {
+	l.setOutcome(OutcomeCompleted)
+	if err := l.deps.DB.UpdatePlanStatus(l.cfg.PlanID, db.PlanStatusCompleted); err != nil {
+		log.Warn("failed to mark plan complete", "error", err)
 	}
+	if len(l.tasks) > 0 {
+		if err := l.deps.DB.UpdateProjectStatus(l.cfg.PlanID, db.ProjectCompleted); err != nil {
+			log.Warn("failed to mark task project complete", "error", err)
+		}
+	}
+	l.emit(NewEvent(EventDone, l.iteration, l.effectiveMaxIter(), message))
+}
+
+// emit publishes an event to all subscribers.
+func (l *Loop) emit(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	l.bus.Publish(redactEvent(l.cfg.Redactor, event))
 }
 
 // runIteration runs a single iteration with developer and reviewer.
@@ -260,6 +933,24 @@ func (l *Loop) emit(event Event) {
 func (l *Loop) runIteration(ctx context.Context) (bool, error) {
 	l.emit(NewEvent(EventIterationStart, l.iteration, l.effectiveMaxIter(),
 		fmt.Sprintf("Starting iteration %d", l.iteration)))
+	l.startIterationTimer()
+	l.activity = newToolUsage()
+	l.withdrawnFeedback = ""
+	l.checkPlanFile()
+
+	if l.task != nil {
+		if err := l.deps.DB.IncrementTaskIteration(l.task.ID); err != nil {
+			log.Warn("failed to increment task iteration", "task", l.task.ID, "error", err)
+		}
+	}
+
+	l.recordIterationOperation(ctx)
+	l.startIterationChange(ctx)
+
+	// 0. Conflicts left in the working copy are resolved before any work
+	if err := l.handleConflicts(ctx); err != nil {
+		return false, err
+	}
 
 	// 1. Load state
 	progress, learnings, feedback, err := l.loadState()
@@ -267,7 +958,8 @@ func (l *Loop) runIteration(ctx context.Context) (bool, error) {
 		return false, err
 	}
 
-	// 2. Run developer agent
+	// 2. Run developer agent, from a snapshot its done signal is checked against
+	devSnapshot := l.workingCopySnapshot(ctx)
 	devStartEvent := NewEvent(EventDeveloperStart, l.iteration, l.effectiveMaxIter(), "Starting developer agent")
 	devStartEvent.TeamMode = l.cfg.TeamMode
 	l.emit(devStartEvent)
@@ -277,20 +969,57 @@ func (l *Loop) runIteration(ctx context.Context) (bool, error) {
 		return false, fmt.Errorf("developer agent failed: %w", err)
 	}
 
-	l.emit(NewEvent(EventDeveloperEnd, l.iteration, l.effectiveMaxIter(), "Developer agent ended"))
+	devEndEvent := NewEvent(EventDeveloperEnd, l.iteration, l.effectiveMaxIter(), "Developer agent ended")
+	devEndEvent.Duration = l.sessionElapsed()
+	l.emit(devEndEvent)
 
-	// 3. Parse developer output
-	devResult := parser.ParseAgentOutput(devOutput, "developer")
+	// 3. Parse developer output; DEV_DONE only counts from a session that
+	// left the tree unchanged
+	parseStart := time.Now()
+	devResult := l.parseOutput(devSessionID, devOutput, "developer")
+	l.finishSessionTimer(devSessionID, time.Since(parseStart))
+	var doneRejection string
+	if devResult.DevDone {
+		if doneRejection = l.verifyDevDone(ctx, devSnapshot); doneRejection != "" {
+			devResult.DevDone = false
+		}
+	}
 
 	// 4. Store developer progress/learnings
 	l.storeProgressLearnings(devSessionID, devResult.Progress, devResult.Learnings)
+	l.promoteGlobalLearnings(devResult.GlobalLearnings)
+	l.proposeSubPlan(devSessionID, devResult.SubPlan)
+	l.recordSnapshot(ctx, devSessionID)
 
-	// 5. Clear any previous reviewer feedback (developer has now seen and addressed it)
+	// 5. Clear any previous reviewer feedback (developer has now seen it);
+	// its items stay open until a reviewer verifies their fixes
 	if feedback != "" {
 		if err := l.deps.DB.ClearReviewerFeedback(l.cfg.PlanID); err != nil {
 			log.Warn("failed to clear reviewer feedback", "error", err)
 		}
 	}
+	l.claimFeedbackItems(devResult.FixedItems)
+
+	// 5b. Enforce the plan's scope, its files allowlist, and the path
+	// restrictions before the reviewer sees anything
+	if err := l.checkScope(ctx); err != nil {
+		return false, err
+	}
+	if err := l.checkPlanFiles(ctx); err != nil {
+		return false, err
+	}
+	if err := l.checkPolicy(ctx, devSessionID); err != nil {
+		return false, err
+	}
+
+	// 5c. Conflicts the developer's session left (e.g. after a rebase in
+	// team mode) are resolved before the review
+	if err := l.handleConflicts(ctx); err != nil {
+		return false, err
+	}
+
+	// 5d. Run the checks, whose failures go in the next developer prompt
+	l.runChecks(ctx, devSessionID)
 
 	// 6. Emit developer done event if applicable (for UI)
 	if devResult.DevDone {
@@ -299,18 +1028,21 @@ func (l *Loop) runIteration(ctx context.Context) (bool, error) {
 	}
 
 	// 7. Get diff for reviewer - use cumulative diff from base change
-	var diff string
-	if l.baseChangeID != "" {
-		log.Debug("getting cumulative diff for reviewer", "baseChangeID", l.baseChangeID)
-		diff, err = l.deps.JJ.Diff(ctx, l.baseChangeID, "@")
+	// (in task mode, from the start of the current task). changes keeps
+	// the diff without the notes added for the reviewer.
+	var diff, changes string
+	if baseChangeID := l.reviewBaseChangeID(); baseChangeID != "" {
+		log.Debug("getting cumulative diff for reviewer", "baseChangeID", baseChangeID)
+		diff, err = l.deps.VCS.Diff(ctx, baseChangeID, "@", l.scopePaths()...)
+		changes = diff
 		if err != nil {
 			log.Warn("failed to get cumulative diff for reviewer", "error", err)
 			diff = ""
 		} else if strings.TrimSpace(diff) == "" {
 			log.Warn("cumulative diff is empty despite having baseChangeID",
-				"baseChangeID", l.baseChangeID,
+				"baseChangeID", baseChangeID,
 				"hint", "changes may have been squashed, rebased, or committed before loop started")
-			diff = "[Note: Cumulative diff from base change " + l.baseChangeID +
+			diff = "[Note: Cumulative diff from base change " + baseChangeID +
 				" is empty. This may occur if changes were squashed/rebased, or if all work " +
 				"was committed before the loop captured the base change. Review the Developer " +
 				"Summary section for context on what was accomplished.]"
@@ -320,7 +1052,12 @@ func (l *Loop) runIteration(ctx context.Context) (bool, error) {
 	} else {
 		log.Warn("no baseChangeID available, falling back to jj show (single change only)",
 			"limitation", "review will only include current change, not cumulative session work")
-		diff, err = l.deps.JJ.Show(ctx)
+		if l.cfg.Scope != "" {
+			diff, err = l.deps.VCS.Diff(ctx, "", "@", l.scopePaths()...)
+		} else {
+			diff, err = l.deps.VCS.Show(ctx)
+		}
+		changes = diff
 		if err != nil {
 			log.Warn("failed to get diff for reviewer", "error", err)
 			diff = ""
@@ -330,57 +1067,310 @@ func (l *Loop) runIteration(ctx context.Context) (bool, error) {
 		}
 	}
 
-	// Truncate large diffs to prevent context window exhaustion
-	if len(diff) > maxDiffBytes {
-		log.Warn("diff exceeds size limit, truncating",
-			"originalSize", len(diff),
-			"maxSize", maxDiffBytes)
-		diff = truncateDiff(diff)
+	// 7a. A rebuttal of the last feedback is re-evaluated before the
+	// review; upheld feedback ends the iteration
+	if l.rebuttalAllowed && devResult.Rebuttal != "" {
+		upheld, err := l.handleRebuttal(ctx, feedback, devResult.Rebuttal, devResult.DisputedItems, diff)
+		if err != nil {
+			return false, err
+		}
+		if upheld {
+			l.emit(l.newIterationEndEvent())
+			return false, nil
+		}
 	}
 
-	// 8. Run reviewer agent (always — pass devDone flag for prompt mode)
-	l.emit(NewEvent(EventReviewerStart, l.iteration, l.effectiveMaxIter(), "Starting reviewer agent"))
+	// 7b. Check whether the loop is making progress
+	if err := l.checkStall(changes, devResult.Progress); err != nil {
+		return false, err
+	}
 
-	reviewOutput, reviewSessionID, err := l.runReviewer(ctx, progress, learnings, diff, devOutput, devResult.DevDone)
-	if err != nil {
-		return false, fmt.Errorf("reviewer agent failed: %w", err)
+	// Summarize or truncate large diffs to prevent context window exhaustion
+	if len(diff) > maxDiffBytes {
+		if summary := l.summarizeDiff(ctx, len(diff)); summary != "" {
+			diff = summary
+		} else {
+			log.Warn("diff exceeds size limit, truncating",
+				"originalSize", len(diff),
+				"maxSize", maxDiffBytes)
+			diff = truncateDiff(diff)
+		}
 	}
 
-	l.emit(NewEvent(EventReviewerEnd, l.iteration, l.effectiveMaxIter(), "Reviewer agent ended"))
+	// 7c. A trivial change skips the review, or gets a lightweight one
+	trivialReason := l.trivialChange(ctx, devSnapshot, devResult.DevDone || doneRejection != "")
+	if trivialReason != "" && l.deps.TrivialReviewClaude == nil {
+		l.recordReviewSkip(db.ReviewSkipActionSkip, trivialReason, "")
+		l.emit(l.newIterationEndEvent())
+		return false, nil
+	}
+
+	// 7d. Run static analyzers on the changed files for the reviewer, and
+	// read the files if the reviewer gets them too
+	findings := l.runAnalyzers(ctx)
+	l.reviewFiles = l.changedFilesContext(ctx)
+
+	// 7e. Snapshot the tree so an approval can be checked against it; in
+	// test-authoring mode the reviewer's tests go in a change of their own
+	testChangeID := l.startReviewerTestChange(ctx, devResult.DevDone && trivialReason == "" && l.reviewPanelSize() <= 1)
+	reviewSnapshot := l.workingCopySnapshot(ctx)
+
+	// 8-9. Run the reviewer agent (pass devDone flag for prompt mode) and
+	// parse its output; a review panel's verdicts are aggregated
+	var reviewResult *parser.AgentParseResult
+	var reviewSessionID string
+	if trivialReason != "" {
+		l.emit(NewEvent(EventReviewerStart, l.iteration, l.effectiveMaxIter(), "Starting lightweight reviewer agent"))
+
+		var reviewOutput string
+		reviewOutput, reviewSessionID, err = l.runReviewer(ctx, l.deps.TrivialReviewClaude, 1, progress, learnings, diff, devOutput, devResult.DevDone, findings)
+		if err != nil {
+			return false, fmt.Errorf("reviewer agent failed: %w", err)
+		}
+		l.recordReviewSkip(db.ReviewSkipActionDowngrade, trivialReason, reviewSessionID)
+
+		reviewEndEvent := NewEvent(EventReviewerEnd, l.iteration, l.effectiveMaxIter(), "Reviewer agent ended")
+		reviewEndEvent.Duration = l.sessionElapsed()
+		l.emit(reviewEndEvent)
+
+		parseStart := time.Now()
+		reviewResult = l.parseOutput(reviewSessionID, reviewOutput, "reviewer")
+		l.checkChecklist(reviewResult, devResult.DevDone)
+		l.finishSessionTimer(reviewSessionID, time.Since(parseStart))
+	} else if l.reviewPanelSize() > 1 {
+		reviewResult, reviewSessionID, err = l.runReviewPanel(ctx, progress, learnings, diff, devOutput, devResult.DevDone, findings)
+		if err != nil {
+			return false, fmt.Errorf("review panel failed: %w", err)
+		}
+	} else {
+		l.emit(NewEvent(EventReviewerStart, l.iteration, l.effectiveMaxIter(), "Starting reviewer agent"))
+
+		var reviewOutput string
+		reviewOutput, reviewSessionID, err = l.runReviewer(ctx, l.reviewerClient(), 1, progress, learnings, diff, devOutput, devResult.DevDone, findings)
+		if err != nil {
+			return false, fmt.Errorf("reviewer agent failed: %w", err)
+		}
+
+		reviewEndEvent := NewEvent(EventReviewerEnd, l.iteration, l.effectiveMaxIter(), "Reviewer agent ended")
+		reviewEndEvent.Duration = l.sessionElapsed()
+		l.emit(reviewEndEvent)
 
-	// 9. Parse reviewer output
-	reviewResult := parser.ParseAgentOutput(reviewOutput, "reviewer")
+		parseStart := time.Now()
+		reviewResult = l.parseOutput(reviewSessionID, reviewOutput, "reviewer")
+		l.checkChecklist(reviewResult, devResult.DevDone)
+		l.finishSessionTimer(reviewSessionID, time.Since(parseStart))
+	}
 
 	// 10. Store reviewer progress/learnings
 	l.storeProgressLearnings(reviewSessionID, reviewResult.Progress, reviewResult.Learnings)
 
+	// 10b. An approval only counts if the review left the tree unchanged,
+	// or in test-authoring mode added tests that need the developer's change
+	if testChangeID != "" {
+		feedback, err := l.verifyReviewerTests(ctx, reviewSnapshot, reviewResult.ReviewerApproved)
+		if err != nil {
+			return false, err
+		}
+		if feedback != "" {
+			reviewResult.ReviewerApproved = false
+			reviewResult.ReviewerFeedback = feedback
+		}
+	} else if devResult.DevDone && reviewResult.ReviewerApproved {
+		if feedback := l.verifyApproval(ctx, reviewSnapshot); feedback != "" {
+			reviewResult.ReviewerApproved = false
+			reviewResult.ReviewerFeedback = feedback
+		}
+	}
+
+	// 10c. The review verifies the developer's claimed fixes
+	l.verifyFeedbackItems(reviewResult, devResult.DevDone)
+
 	// 11. Check: if DEV_DONE && REVIEWER_APPROVED → done
 	if devResult.DevDone && reviewResult.ReviewerApproved {
 		l.emit(NewEvent(EventReviewerApproved, l.iteration, l.effectiveMaxIter(),
 			"Reviewer approved - implementation complete"))
+		// The reviewer's tests sit below a fresh working-copy change
+		trailerRevision := "@"
+		if testChangeID != "" {
+			trailerRevision = "@-"
+		}
+		l.addReviewTrailers(ctx, trailerRevision)
 		l.emit(NewEvent(EventBothDone, l.iteration, l.effectiveMaxIter(),
 			"Both developer and reviewer approved"))
 		return true, nil
 	}
 
-	// 12. If reviewer has feedback, store for next iteration
+	if devResult.DevDone {
+		l.doneRejected = true
+	}
+
+	// 12. If reviewer has feedback, store for next iteration (with any
+	// suggested patch, applied first when enabled) after a rejected DEV_DONE,
+	// and track its issues as review items
+	var nextFeedback []string
+	if doneRejection != "" {
+		nextFeedback = append(nextFeedback, doneRejection)
+	}
 	if reviewResult.ReviewerFeedback != "" {
+		l.recordReviewerRejection(reviewSessionID, reviewResult.ReviewerFeedback, reviewResult.RejectedApproaches)
+		l.issueFeedbackItems(reviewSessionID, reviewResult.ReviewerFeedback)
 		l.emit(NewEvent(EventReviewerFeedback, l.iteration, l.effectiveMaxIter(),
 			fmt.Sprintf("Reviewer feedback: %s", truncateString(reviewResult.ReviewerFeedback, 100))))
-		if err := l.storeReviewerFeedback(reviewSessionID, reviewResult.ReviewerFeedback); err != nil {
+		nextFeedback = append(nextFeedback, l.reviewerFeedbackWithPatch(ctx, reviewResult.ReviewerFeedback, reviewResult.ReviewerPatch))
+	}
+	if len(nextFeedback) > 0 {
+		if err := l.storeReviewerFeedback(reviewSessionID, strings.Join(nextFeedback, "\n\n")); err != nil {
 			log.Warn("failed to store reviewer feedback", "error", err)
 		}
 	}
 
-	l.emit(NewEvent(EventIterationEnd, l.iteration, l.effectiveMaxIter(),
-		fmt.Sprintf("iteration %d complete", l.iteration)))
+	l.emit(l.newIterationEndEvent())
 
 	return false, nil
 }
 
+// checkPolicy verifies the developer's changes stay within the allowed paths.
+// On breach it emits EventPolicyViolation, records the violation as reviewer
+// feedback so the next developer session sees it, and returns policy.ErrViolation.
+func (l *Loop) checkPolicy(ctx context.Context, sessionID string) error {
+	if !l.cfg.Policy.HasPathRestrictions() {
+		return nil
+	}
+
+	files, err := l.deps.VCS.ChangedFiles(ctx, l.baseChangeID, "@")
+	if err != nil {
+		return fmt.Errorf("failed to list changed files for policy check: %w", err)
+	}
+
+	violations := l.cfg.Policy.CheckPaths(files)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	l.emit(NewEvent(EventPolicyViolation, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Changes outside allowed paths: %s", strings.Join(violations, ", "))))
+
+	feedback := fmt.Sprintf("POLICY VIOLATION: you modified files outside the allowed paths (%s). "+
+		"Revert your changes to: %s",
+		strings.Join(l.cfg.Policy.AllowedPaths, ", "), strings.Join(violations, ", "))
+	if err := l.storeReviewerFeedback(sessionID, feedback); err != nil {
+		log.Warn("failed to store policy violation feedback", "error", err)
+	}
+
+	return fmt.Errorf("%w: %s", policy.ErrViolation, strings.Join(violations, ", "))
+}
+
+// checkPlanFiles finds the developer's changes to files outside the "files:"
+// allowlist of the plan's front matter. They are restored from the plan's
+// base change, or with FlagOutOfScopeFiles kept, and the next developer
+// prompt lists them.
+func (l *Loop) checkPlanFiles(ctx context.Context) error {
+	globs, err := policy.PlanFiles(l.plan.Content)
+	if err != nil {
+		// A merged plan edit broke the front matter
+		log.Warn("invalid plan front matter, not checking files", "error", err)
+		return nil
+	}
+	if len(globs) == 0 || l.baseChangeID == "" {
+		return nil
+	}
+
+	files, err := l.deps.VCS.ChangedFiles(ctx, l.baseChangeID, "@")
+	if err != nil {
+		return fmt.Errorf("failed to list changed files for files check: %w", err)
+	}
+	outOfScope := policy.New(globs, nil, true).CheckPaths(files)
+	if len(outOfScope) == 0 {
+		return nil
+	}
+
+	l.handleOutOfScope(ctx, outOfScope, "the plan's files",
+		"the plan only allows changes to: "+strings.Join(globs, ", "))
+	return nil
+}
+
+// handleOutOfScope restores the out-of-scope files from the plan's base
+// change, or with FlagOutOfScopeFiles keeps them, emits
+// EventFilesOutOfScope, and adds them to the next developer prompt. what
+// names the limit the files broke in the event, and rule states it in the
+// prompt.
+func (l *Loop) handleOutOfScope(ctx context.Context, outOfScope []string, what, rule string) {
+	reverted := false
+	if !l.cfg.FlagOutOfScopeFiles {
+		if err := l.deps.VCS.Restore(ctx, l.baseChangeID, outOfScope...); err != nil {
+			log.Warn("failed to revert out-of-scope files", "files", outOfScope, "error", err)
+		} else {
+			reverted = true
+		}
+	}
+
+	action := "flagged"
+	if reverted {
+		action = "reverted"
+	}
+	l.emit(NewEvent(EventFilesOutOfScope, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Changes outside %s %s: %s", what, action, strings.Join(outOfScope, ", "))))
+
+	var b strings.Builder
+	if l.outOfScope != "" {
+		b.WriteString(l.outOfScope + "\n\n")
+	}
+	for _, file := range outOfScope {
+		fmt.Fprintf(&b, "- %s\n", file)
+	}
+	if reverted {
+		b.WriteString("\nThese changes were reverted because ")
+	} else {
+		b.WriteString("\nThese changes were kept for now, but you must undo them: ")
+	}
+	b.WriteString(rule)
+	l.outOfScope = b.String()
+}
+
+// takeOutOfScope returns the out-of-scope changes found since the last
+// developer prompt, and clears them.
+func (l *Loop) takeOutOfScope() string {
+	outOfScope := l.outOfScope
+	l.outOfScope = ""
+	return outOfScope
+}
+
+// errStalled signals that the loop should stop because it stopped making progress.
+var errStalled = errors.New("loop stalled")
+
+// checkStall feeds the iteration's diff and progress to the stall detector.
+// Once the threshold is reached it emits EventStallDetected and either arms the
+// stuck nudge for the next developer prompt or returns errStalled to abort.
+func (l *Loop) checkStall(diff, progress string) error {
+	if l.cfg.StallThreshold <= 0 {
+		return nil
+	}
+
+	count := l.stall.observe(diff, progress)
+	if count < l.cfg.StallThreshold {
+		l.stalled = false
+		return nil
+	}
+
+	l.emit(NewEvent(EventStallDetected, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("No progress for %d iterations", count)))
+
+	if l.cfg.StallAbort {
+		return errStalled
+	}
+	l.stalled = true
+	return nil
+}
+
 // loadState loads progress, learnings, and reviewer feedback.
+// In task mode progress and learnings are those of the current task.
 func (l *Loop) loadState() (progress, learnings, feedback string, err error) {
-	progressRecord, err := l.deps.DB.GetLatestProgress(l.cfg.PlanID)
+	var progressRecord *db.Progress
+	if l.task != nil {
+		progressRecord, err = l.deps.DB.GetLatestTaskProgress(l.cfg.PlanID, l.task.ID)
+	} else {
+		progressRecord, err = l.deps.DB.GetLatestProgress(l.cfg.PlanID)
+	}
 	if err != nil {
 		return "", "", "", fmt.Errorf("failed to get latest progress: %w", err)
 	}
@@ -388,20 +1378,29 @@ func (l *Loop) loadState() (progress, learnings, feedback string, err error) {
 		progress = progressRecord.Content
 	}
 
-	learningsRecord, err := l.deps.DB.GetLatestLearnings(l.cfg.PlanID)
+	var learningsRecord *db.Learnings
+	if l.task != nil {
+		learningsRecord, err = l.deps.DB.GetLatestTaskLearnings(l.cfg.PlanID, l.task.ID)
+	} else {
+		learningsRecord, err = l.deps.DB.GetLatestLearnings(l.cfg.PlanID)
+	}
 	if err != nil {
 		return "", "", "", fmt.Errorf("failed to get latest learnings: %w", err)
 	}
 	if learningsRecord != nil {
-		learnings = learningsRecord.Content
+		// Agents restate earlier learnings; keep each one once
+		learnings = parser.DedupeLearnings(learningsRecord.Content)
 	}
 
 	feedbackRecord, err := l.deps.DB.GetLatestReviewerFeedback(l.cfg.PlanID)
 	if err != nil {
 		return "", "", "", fmt.Errorf("failed to get latest reviewer feedback: %w", err)
 	}
+	l.feedbackSessionID, l.rebuttalAllowed = "", false
 	if feedbackRecord != nil {
 		feedback = feedbackRecord.Content
+		l.feedbackSessionID = feedbackRecord.SessionID
+		l.rebuttalAllowed = l.canRebut(feedbackRecord.SessionID)
 	}
 
 	return progress, learnings, feedback, nil
@@ -409,18 +1408,89 @@ func (l *Loop) loadState() (progress, learnings, feedback string, err error) {
 
 // runDeveloper runs the developer agent and returns output and session ID.
 func (l *Loop) runDeveloper(ctx context.Context, progress, learnings, feedback string) (output string, sessionID string, err error) {
-	// Build developer prompt
-	prompt, err := agent.BuildDeveloperPrompt(agent.DeveloperContext{
+	l.startSessionTimer()
+
+	// Select Claude client: use team client for developer in team mode
+	devClient := l.deps.Claude
+	if l.cfg.TeamMode && l.deps.TeamClaude != nil {
+		devClient = l.deps.TeamClaude
+	}
+
+	// A session interrupted in the previous run is sent its prompt again
+	if output, sessionID, ok, err := l.resubmitInterrupted(ctx, devClient); ok {
+		return output, sessionID, err
+	}
+
+	customPrompt, err := l.agentPrompt("developer")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build developer prompt: %w", err)
+	}
+
+	// Build developer prompt: only what changed when the previous
+	// developer session is resumed
+	promptCtx := agent.DeveloperContext{
 		PlanContent:      l.plan.Content,
 		Progress:         progress,
 		Learnings:        learnings,
 		ReviewerFeedback: feedback,
+		OpenItems:        l.formatOpenItems(),
+		RebuttalAllowed:  l.rebuttalAllowed,
+		SubPlans:         l.subPlansAllowed(),
+		RunSubPlans:      l.cfg.RunSubPlans,
 		TeamMode:         l.cfg.TeamMode,
-	})
+		Stuck:            l.stalled,
+		GlobalLearnings:  l.globalLearnings,
+		Rejected:         l.loadRejectedApproaches(),
+		CurrentTask:      l.currentTaskPrompt(),
+		PlanUpdate:       l.takePlanUpdate(),
+		OutOfScope:       l.takeOutOfScope(),
+		FailingChecks:    l.takeFailingChecks(),
+		UserFeedback:     l.takeUserFeedback(),
+		Conventions:      l.conventions,
+		Scope:            l.cfg.Scope,
+		StatusTool:       l.cfg.StatusTool,
+		StateTools:       l.cfg.StateTools,
+		Locale:           l.cfg.Locale,
+		Template:         customPrompt,
+	}
+	resume := l.resumableDevSession(promptCtx.CurrentTask)
+
+	// Old learnings, then old progress, are cut if the prompt is too large
+	sections := agent.PrunableSections{Learnings: &promptCtx.Learnings, Progress: &promptCtx.Progress}
+	buildPrompt := func() (string, error) {
+		if resume != "" {
+			return agent.BuildDeveloperDeltaPrompt(promptCtx)
+		}
+		return agent.BuildDeveloperPrompt(promptCtx)
+	}
+	prompt, err := l.fitPrompt(devClient, sections, buildPrompt)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to build developer prompt: %w", err)
 	}
+	l.promptBuilt()
+
+	output, sessionID, run, err := l.startDeveloperSession(ctx, prompt, devClient, resume)
+	if err == nil && resume != "" && run.sessionID == "" {
+		// The CLI couldn't resume the session (e.g. its history was
+		// removed), so start over with the full prompt
+		log.Warn("failed to resume the developer session, sending the full prompt", "claudeSession", resume)
+		resume = ""
+		if prompt, err = l.fitPrompt(devClient, sections, buildPrompt); err != nil {
+			return "", "", fmt.Errorf("failed to build developer prompt: %w", err)
+		}
+		output, sessionID, run, err = l.startDeveloperSession(ctx, prompt, devClient, "")
+	}
+	if err != nil {
+		return "", sessionID, err
+	}
+	l.devSessionRan(promptCtx.CurrentTask, run, resume != "")
+
+	return l.selfCheck(ctx, sessionID, db.LoopAgentDeveloper, output), sessionID, nil
+}
 
+// startDeveloperSession records and runs a developer session with the
+// given prompt, resuming the CLI session resume when it isn't empty.
+func (l *Loop) startDeveloperSession(ctx context.Context, prompt string, devClient *claude.Client, resume string) (output, sessionID string, run claudeRun, err error) {
 	l.emit(NewPromptBuiltEvent(l.iteration, l.effectiveMaxIter(), prompt))
 
 	// Create session in DB
@@ -434,38 +1504,78 @@ func (l *Loop) runDeveloper(ctx context.Context, progress, learnings, feedback s
 		AgentType:   db.LoopAgentDeveloper,
 	}
 	if err := l.deps.DB.CreatePlanSession(session); err != nil {
-		return "", "", fmt.Errorf("failed to create developer session: %w", err)
+		return "", "", claudeRun{}, fmt.Errorf("failed to create developer session: %w", err)
 	}
 
-	// Select Claude client: use team client for developer in team mode
-	devClient := l.deps.Claude
-	if l.cfg.TeamMode && l.deps.TeamClaude != nil {
-		devClient = l.deps.TeamClaude
+	// Record the teammates the team client spawns to route review feedback to
+	if l.cfg.TeamMode {
+		l.workers = newTeamWorkers(sessionID)
 	}
 
 	// Run Claude session
-	output, err = l.runClaudeSession(ctx, sessionID, prompt, devClient)
-	if err != nil {
-		return "", sessionID, err
-	}
+	output, run, err = l.resumeClaudeSession(ctx, sessionID, prompt, devClient, resume)
+	return output, sessionID, run, err
+}
 
-	return output, sessionID, nil
+// agentPrompt returns the custom prompt template for an agent type ("" for
+// the built-in prompt).
+func (l *Loop) agentPrompt(agentType string) (string, error) {
+	if l.cfg.AgentPrompt == nil {
+		return "", nil
+	}
+	return l.cfg.AgentPrompt(agentType)
 }
 
 // runReviewer runs the reviewer agent and returns output and session ID.
-func (l *Loop) runReviewer(ctx context.Context, progress, learnings, diff, devSummary string, devDone bool) (output string, sessionID string, err error) {
+// seat is the reviewer's seat on the review panel (1 for a single reviewer).
+func (l *Loop) runReviewer(ctx context.Context, client *claude.Client, seat int, progress, learnings, diff, devSummary string, devDone bool, findings []analyze.Finding) (output string, sessionID string, err error) {
+	l.startSessionTimer()
+
+	customPrompt, err := l.agentPrompt("reviewer")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build reviewer prompt: %w", err)
+	}
+
 	// Build reviewer prompt
-	prompt, err := agent.BuildReviewerPrompt(agent.ReviewerContext{
-		PlanContent:      l.plan.Content,
-		Progress:         progress,
-		Learnings:        learnings,
-		DiffOutput:       diff,
-		DeveloperSummary: devSummary,
-		DevSignaledDone:  devDone,
+	reviewCtx := agent.ReviewerContext{
+		PlanContent:        l.plan.Content,
+		Progress:           progress,
+		Learnings:          learnings,
+		DiffOutput:         diff,
+		DeveloperSummary:   devSummary,
+		DevSignaledDone:    devDone,
+		CurrentTask:        l.currentTaskPrompt(),
+		Findings:           analyze.Format(findings),
+		Conventions:        l.conventions,
+		Scope:              l.cfg.Scope,
+		Checklist:          l.planChecklist(),
+		AcceptanceCriteria: l.planAcceptanceCriteria(),
+		WithdrawnFeedback:  l.withdrawnFeedback,
+		ChangedFiles:       l.reviewFiles,
+		OpenItems:          l.formatOpenItems(),
+		AuthorTests:        l.reviewerTestChange != "",
+		PanelSeat:          seat,
+		PanelSize:          l.reviewPanelSize(),
+		Quorum:             l.reviewQuorum(),
+		StatusTool:         l.cfg.StatusTool,
+		StateTools:         l.cfg.StateTools,
+		Locale:             l.cfg.Locale,
+		Template:           customPrompt,
+	}
+	// The changed files' tail, old learnings, old progress, then the diff's
+	// tail are cut if the prompt is too large
+	prompt, err := l.fitPrompt(client, agent.PrunableSections{
+		Files:     &reviewCtx.ChangedFiles,
+		Learnings: &reviewCtx.Learnings,
+		Progress:  &reviewCtx.Progress,
+		Diff:      &reviewCtx.DiffOutput,
+	}, func() (string, error) {
+		return agent.BuildReviewerPrompt(reviewCtx)
 	})
 	if err != nil {
 		return "", "", fmt.Errorf("failed to build reviewer prompt: %w", err)
 	}
+	l.promptBuilt()
 
 	l.emit(NewPromptBuiltEvent(l.iteration, l.effectiveMaxIter(), prompt))
 
@@ -482,50 +1592,230 @@ func (l *Loop) runReviewer(ctx context.Context, progress, learnings, diff, devSu
 	if err := l.deps.DB.CreatePlanSession(session); err != nil {
 		return "", "", fmt.Errorf("failed to create reviewer session: %w", err)
 	}
+	l.storeAnalyzerFindings(sessionID, findings)
 
-	// Run Claude session (reviewer always uses the default client, never team client)
-	output, err = l.runClaudeSession(ctx, sessionID, prompt, l.deps.Claude)
+	output, err = l.runClaudeSession(ctx, sessionID, prompt, client)
 	if err != nil {
 		return "", sessionID, err
 	}
 
-	return output, sessionID, nil
+	return l.selfCheck(ctx, sessionID, db.LoopAgentReviewer, output), sessionID, nil
+}
+
+// reviewerClient returns the Claude client for full reviews (the reviewer
+// never uses the team client).
+func (l *Loop) reviewerClient() *claude.Client {
+	if l.deps.ReviewerClaude != nil {
+		return l.deps.ReviewerClaude
+	}
+	return l.deps.Claude
 }
 
 // runClaudeSession runs a Claude session and returns the output.
+// Transient failures (rate limits, overload, network errors) are retried
+// with backoff according to the retry policy.
 func (l *Loop) runClaudeSession(ctx context.Context, sessionID, prompt string, client *claude.Client) (output string, err error) {
+	output, _, err = l.resumeClaudeSession(ctx, sessionID, prompt, client, "")
+	return output, err
+}
+
+// resumeClaudeSession runs a Claude session like runClaudeSession,
+// continuing the CLI session resume when it isn't empty, and returns what
+// the session reported about itself.
+func (l *Loop) resumeClaudeSession(ctx context.Context, sessionID, prompt string, client *claude.Client, resume string) (output string, run claudeRun, err error) {
+	maxAttempts := l.cfg.Retry.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	l.recordEnvironment(ctx, sessionID)
+	if timer := l.timer; timer != nil {
+		defer func(start time.Time) { timer.timings.Claude += time.Since(start) }(time.Now())
+	}
+
+	// State continues across attempts so stored events and transcripts stay ordered
+	seq := sessionState{tools: newToolUsage(), resume: resume}
+	defer l.storeToolUsage(sessionID, seq.tools)
+
+	rateLimitWaits := 0
+	for attempt := 1; ; attempt++ {
+		// Wait out a rate limit this or another run hit
+		if err := l.waitForCooldown(ctx); err != nil {
+			l.failSession(sessionID)
+			return "", claudeRun{}, err
+		}
+		if err := l.waitForThrottle(ctx); err != nil {
+			l.failSession(sessionID)
+			return "", claudeRun{}, err
+		}
+
+		output, err = l.runClaudeAttempt(ctx, sessionID, prompt, client, &seq)
+		if err == nil {
+			break
+		}
+
+		// A model without capacity hands the prompt to the next one, without
+		// using up an attempt
+		if next := l.fallbackModel(client.Model(), err); next != "" && ctx.Err() == nil {
+			log.Warn("model lacks capacity, falling back", "model", client.Model(), "fallback", next, "error", err)
+			l.emit(NewEvent(EventClaudeRetry, l.iteration, l.effectiveMaxIter(),
+				fmt.Sprintf("%s is unavailable, retrying on %s: %s", client.Model(), next, truncateString(err.Error(), 200))))
+			client = client.WithModel(next)
+			attempt--
+			continue
+		}
+
+		// A rate limit that reports its reset is waited out without using
+		// up an attempt
+		if resumeAt, ok := l.rateLimitReset(err); ok && rateLimitWaits < maxRateLimitWaits && ctx.Err() == nil {
+			rateLimitWaits++
+			attempt--
+			l.startCooldown(resumeAt, err)
+			continue
+		}
+
+		if attempt >= maxAttempts || !claude.IsTransient(err) || ctx.Err() != nil {
+			l.failSession(sessionID)
+			if ctx.Err() == nil {
+				err = &ProviderError{Err: err}
+			}
+			return "", claudeRun{}, err
+		}
+
+		delay := l.cfg.Retry.backoff(attempt)
+		log.Warn("transient Claude failure, retrying", "attempt", attempt, "delay", delay, "error", err)
+		l.emit(NewEvent(EventClaudeRetry, l.iteration, l.effectiveMaxIter(),
+			fmt.Sprintf("Transient Claude error, retrying in %s (attempt %d/%d): %s",
+				delay.Round(time.Second), attempt+1, maxAttempts, truncateString(err.Error(), 200))))
+
+		// Rate limits hold off other runs too; the next attempt waits
+		if _, ok := claude.ParseRateLimit(err, time.Now()); ok {
+			l.startCooldown(time.Now().Add(delay), err)
+			continue
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			l.failSession(sessionID)
+			return "", claudeRun{}, err
+		}
+	}
+
+	// Mark session complete
+	if err := l.deps.DB.CompletePlanSession(sessionID, db.PlanSessionCompleted, output); err != nil {
+		log.Warn("failed to complete session", "error", err)
+	}
+	// The model the init event reported, or the one the session was run on
+	if seq.run.model == "" {
+		l.recordModel(sessionID, client.Model())
+	}
+
+	return output, seq.run, nil
+}
+
+// fallbackModel returns the model of the chain after model when err means
+// model lacks capacity, or "" when there is none to fall back to.
+func (l *Loop) fallbackModel(model string, err error) string {
+	if !claude.IsCapacityError(err) {
+		return ""
+	}
+	i := slices.Index(l.cfg.ModelChain, model)
+	if i < 0 || i+1 >= len(l.cfg.ModelChain) {
+		return ""
+	}
+	return l.cfg.ModelChain[i+1]
+}
+
+// failSession marks a plan session as failed.
+func (l *Loop) failSession(sessionID string) {
+	if err := l.deps.DB.CompletePlanSession(sessionID, db.PlanSessionFailed, ""); err != nil {
+		log.Warn("failed to mark session as failed", "error", err)
+	}
+}
+
+// sessionState aggregates the tool calls of a session and tracks its
+// attempts.
+type sessionState struct {
+	tools *toolUsage
+
+	resume string    // CLI session the attempts resume (empty = a new session)
+	run    claudeRun // What the last attempt reported about itself
+}
+
+// claudeRun is what a Claude session reported about itself.
+type claudeRun struct {
+	sessionID    string // The CLI's ID of the session (empty = it never started)
+	model        string // The model its init event reported (empty = none)
+	contextLimit bool   // It was stopped at the context limit
+}
+
+// runClaudeAttempt runs a single Claude invocation, streaming and storing its
+// events and transcript. It returns an error if Claude failed to start or
+// failed transiently; other session errors are logged and the collected output
+// is returned.
+func (l *Loop) runClaudeAttempt(ctx context.Context, sessionID, prompt string, client *claude.Client, seq *sessionState) (string, error) {
 	l.emit(NewEvent(EventClaudeStart, l.iteration, l.effectiveMaxIter(), "Starting Claude session"))
 
-	claudeSession, err := client.Run(ctx, prompt)
+	var claudeSession *claude.Session
+	var err error
+	if seq.resume != "" {
+		claudeSession, err = client.Resume(ctx, seq.resume, prompt)
+	} else {
+		claudeSession, err = client.Run(ctx, prompt)
+	}
 	if err != nil {
-		if dbErr := l.deps.DB.CompletePlanSession(sessionID, db.PlanSessionFailed, ""); dbErr != nil {
-			log.Warn("failed to mark session as failed", "error", dbErr)
-		}
 		return "", fmt.Errorf("failed to start Claude: %w", err)
 	}
+	seq.run = claudeRun{}
+
+	// Events and transcript messages are stored in batches as they stream
+	records := db.NewBatchWriter(l.deps.DB, db.DefaultBatchInterval)
+	defer func() {
+		if err := records.Close(); err != nil {
+			log.Warn("failed to store events", "error", err)
+		}
+	}()
 
 	// Stream events and collect output
 	var outputBuilder strings.Builder
-	sequence := 0
+	var streamErr error
+
+	// Streamed text not yet covered by a complete message
+	var pendingText strings.Builder
 
 	// Context window tracking
 	maxContext := claude.DefaultContextWindow
 	contextLimitReached := false
 
 	for claudeEvent := range claudeSession.Events() {
-		// Get max context from init event
+		// Liveness events are reported, not part of the session's record
+		if claudeEvent.Type == claude.EventHeartbeat || claudeEvent.Type == claude.EventStalled {
+			l.emitLiveness(claudeEvent)
+			continue
+		}
+
+		// Get max context and the model actually used from the init event
 		if claudeEvent.Type == claude.EventInit && claudeEvent.Init != nil {
 			maxContext = claude.GetContextWindowForModel(claudeEvent.Init.Model)
 			log.Debug("context window determined", "model", claudeEvent.Init.Model, "maxContext", maxContext)
+			if claudeEvent.SubAgentID == "" {
+				l.recordModel(sessionID, claudeEvent.Init.Model)
+				seq.run.model = claudeEvent.Init.Model
+				l.recordClaudeSession(sessionID, claudeEvent.Init.SessionID)
+				seq.run.sessionID = claudeEvent.Init.SessionID
+			}
 		}
 
+		// Sub-agents spawned through the Task tool have their own context,
+		// and their text isn't the agent's output
+		subAgent := claudeEvent.SubAgentID != ""
+
 		// Track token usage from message events and check context limit
-		if !contextLimitReached && claudeEvent.Type == claude.EventMessage && claudeEvent.Message != nil {
+		if !contextLimitReached && !subAgent && claudeEvent.Type == claude.EventMessage && claudeEvent.Message != nil {
 			totalTokens := claudeEvent.Message.Usage.InputTokens + claudeEvent.Message.Usage.OutputTokens
 			percentage := float64(totalTokens) / float64(maxContext) * 100.0
 
 			if percentage >= claude.ContextLimitPercent {
 				contextLimitReached = true
+				seq.run.contextLimit = true
 				log.Info("context limit reached, stopping session",
 					"percentage", fmt.Sprintf("%.1f%%", percentage),
 					"totalTokens", totalTokens,
@@ -541,19 +1831,34 @@ func (l *Loop) runClaudeSession(ctx context.Context, sessionID, prompt string, c
 		eventCopy := claudeEvent
 		l.emit(NewClaudeStreamEvent(l.iteration, l.effectiveMaxIter(), &eventCopy))
 
-		// Store event in DB
-		dbEvent := &db.Event{
-			SessionID: sessionID,
-			Sequence:  sequence,
-			EventType: string(claudeEvent.Type),
-			RawJSON:   string(claudeEvent.Raw),
+		// Store the event and its transcript; complete messages supersede
+		// streamed text
+		RecordStreamEvent(records, sessionID, claudeEvent)
+		if entries := claudeEvent.TranscriptEntries(); entries != nil {
+			if !subAgent {
+				pendingText.Reset()
+			}
+			l.recordToolCalls(sessionID, seq.tools, entries)
+			l.recordToolReports(sessionID, entries)
+		} else if !subAgent && claudeEvent.Type == claude.EventAssistantText && claudeEvent.AssistantText != nil {
+			pendingText.WriteString(claudeEvent.AssistantText.Text)
 		}
-		if err := l.deps.DB.CreateEvent(dbEvent); err != nil {
-			log.Warn("failed to store event", "error", err)
+
+		// Spend the session's cost from the throttle's and plan's budgets
+		if !subAgent && claudeEvent.Type == claude.EventResult && claudeEvent.Result != nil {
+			l.spendThrottleCost(claudeEvent.Result.CostUSD)
+			l.recordUsage(claudeEvent.Result)
+		}
+
+		// Remember API errors reported in the stream (e.g. rate limits)
+		if claudeEvent.Type == claude.EventError && claudeEvent.Error != nil && streamErr == nil {
+			streamErr = fmt.Errorf("claude error %s: %s", claudeEvent.Error.Code, claudeEvent.Error.Message)
 		}
-		sequence++
 
 		// Collect text
+		if subAgent {
+			continue
+		}
 		if claudeEvent.Type == claude.EventAssistantText && claudeEvent.AssistantText != nil {
 			outputBuilder.WriteString(claudeEvent.AssistantText.Text)
 		} else if claudeEvent.Type == claude.EventMessage && claudeEvent.Message != nil {
@@ -561,22 +1866,140 @@ func (l *Loop) runClaudeSession(ctx context.Context, sessionID, prompt string, c
 		}
 	}
 
-	if err := claudeSession.Wait(); err != nil {
-		log.Warn("Claude session error", "error", err)
+	// Keep text from a message that never completed (e.g. canceled mid-stream)
+	if pendingText.Len() > 0 {
+		storeTranscript(records, sessionID, []claude.TranscriptEntry{
+			{Role: "assistant", Kind: claude.TranscriptText, Content: pendingText.String()},
+		})
 	}
 
-	output = outputBuilder.String()
+	sessionErr := claudeSession.Wait()
+	if sessionErr == nil {
+		sessionErr = streamErr
+	}
+
+	output := outputBuilder.String()
 	l.emit(NewClaudeOutputEvent(l.iteration, l.effectiveMaxIter(), output))
 	l.emit(NewEvent(EventClaudeEnd, l.iteration, l.effectiveMaxIter(), "Claude session ended"))
 
-	// Mark session complete
-	if err := l.deps.DB.CompletePlanSession(sessionID, db.PlanSessionCompleted, output); err != nil {
-		log.Warn("failed to complete session", "error", err)
+	if sessionErr != nil {
+		// A canceled session after hitting the context limit is expected
+		if !contextLimitReached && claude.IsTransient(sessionErr) {
+			return output, sessionErr
+		}
+		log.Warn("Claude session error", "error", sessionErr)
+		// A session that failed without output counts as the provider's
+		// failure when classifying how the run ends
+		if strings.TrimSpace(output) == "" {
+			l.iterationFailure = OutcomeProviderError
+		}
 	}
 
 	return output, nil
 }
 
+// recordSnapshot stores the working copy's commit ID on the developer session
+// so reports can measure each iteration's diff churn.
+func (l *Loop) recordSnapshot(ctx context.Context, sessionID string) {
+	commitID, err := l.deps.VCS.GetCurrentCommitID(ctx)
+	if err != nil {
+		log.Warn("failed to get commit ID for session", "error", err)
+		return
+	}
+	if err := l.deps.DB.UpdatePlanSessionCommitID(sessionID, commitID); err != nil {
+		log.Warn("failed to store session commit ID", "error", err)
+	}
+}
+
+// recordToolCalls adds the tool calls among transcript entries to the
+// session's and iteration's usage, and emits the updated activity summary.
+func (l *Loop) recordToolCalls(sessionID string, session *toolUsage, entries []claude.TranscriptEntry) {
+	if l.workers != nil && l.workers.sessionID == sessionID {
+		l.workers.add(entries)
+	}
+	recorded := false
+	for _, entry := range entries {
+		if entry.Kind != claude.TranscriptToolUse {
+			continue
+		}
+		session.add(entry.ToolName, entry.Content)
+		l.activity.add(entry.ToolName, entry.Content)
+		recorded = true
+	}
+	if recorded {
+		l.emit(NewEvent(EventToolActivity, l.iteration, l.effectiveMaxIter(), l.activity.summary()))
+	}
+}
+
+// storeToolUsage stores one summary row per tool the session called.
+func (l *Loop) storeToolUsage(sessionID string, usage *toolUsage) {
+	for _, name := range usage.order {
+		row := &db.ToolUsage{
+			PlanID:    l.plan.ID,
+			SessionID: sessionID,
+			Iteration: l.iteration,
+			ToolName:  name,
+			Calls:     usage.calls[name],
+			Files:     usage.files[name],
+			Commands:  usage.commands[name],
+		}
+		if err := l.deps.DB.CreateToolUsage(row); err != nil {
+			log.Warn("failed to store tool usage", "tool", name, "error", err)
+		}
+	}
+}
+
+// emitLiveness reports a heartbeat or stalled event from a silent Claude
+// session.
+func (l *Loop) emitLiveness(claudeEvent claude.StreamEvent) {
+	var silent time.Duration
+	if claudeEvent.Liveness != nil {
+		silent = claudeEvent.Liveness.Silent
+	}
+	if claudeEvent.Type == claude.EventStalled {
+		log.Warn("claude session stalled", "silent", silent)
+		l.emit(NewEvent(EventClaudeStalled, l.iteration, l.effectiveMaxIter(),
+			fmt.Sprintf("Claude has produced no output for %s", silent)))
+		return
+	}
+	l.emit(NewEvent(EventClaudeHeartbeat, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Waiting on Claude (silent for %s)", silent)))
+}
+
+// RecordStreamEvent queues a Claude session's event, and its transcript
+// entries, for storage by records, as the loop stores its sessions. Stored
+// events are redacted and sealed by the database. Liveness events aren't
+// part of a session's record and are skipped.
+func RecordStreamEvent(records *db.BatchWriter, sessionID string, event claude.StreamEvent) {
+	if event.Type == claude.EventHeartbeat || event.Type == claude.EventStalled {
+		return
+	}
+	records.AddEvent(&db.Event{
+		SessionID:  sessionID,
+		EventType:  string(event.Type),
+		RawJSON:    string(event.Raw),
+		SubAgentID: event.SubAgentID,
+	})
+	storeTranscript(records, sessionID, event.TranscriptEntries())
+}
+
+// storeTranscript queues transcript entries of a session for storage, in
+// order.
+func storeTranscript(records *db.BatchWriter, sessionID string, entries []claude.TranscriptEntry) {
+	for _, entry := range entries {
+		records.AddTranscriptMessage(&db.TranscriptMessage{
+			SessionID:  sessionID,
+			Role:       entry.Role,
+			Kind:       string(entry.Kind),
+			ToolName:   entry.ToolName,
+			ToolUseID:  entry.ToolUseID,
+			Content:    entry.Content,
+			IsError:    entry.IsError,
+			SubAgentID: entry.SubAgentID,
+		})
+	}
+}
+
 // storeProgressLearnings stores progress and learnings from an agent session.
 func (l *Loop) storeProgressLearnings(sessionID, progress, learnings string) {
 	if progress != "" {
@@ -585,6 +2008,7 @@ func (l *Loop) storeProgressLearnings(sessionID, progress, learnings string) {
 		progressRecord := &db.Progress{
 			PlanID:    l.cfg.PlanID,
 			SessionID: sessionID,
+			TaskID:    l.currentTaskID(),
 			Content:   progress,
 		}
 		if err := l.deps.DB.CreateProgress(progressRecord); err != nil {
@@ -598,6 +2022,7 @@ func (l *Loop) storeProgressLearnings(sessionID, progress, learnings string) {
 		learningsRecord := &db.Learnings{
 			PlanID:    l.cfg.PlanID,
 			SessionID: sessionID,
+			TaskID:    l.currentTaskID(),
 			Content:   learnings,
 		}
 		if err := l.deps.DB.CreateLearnings(learningsRecord); err != nil {
@@ -620,10 +2045,36 @@ func (l *Loop) storeReviewerFeedback(sessionID, feedback string) error {
 	return l.deps.DB.CreateReviewerFeedback(feedbackRecord)
 }
 
-// truncateString truncates a string to maxLen, adding "..." if truncated.
+// truncateString truncates a string to maxLen bytes, adding "..." if
+// truncated.
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
 	}
-	return s[:maxLen-3] + "..."
+	return runeCut(s, maxLen-3) + "..."
+}
+
+// runeCut returns the longest prefix of s of at most n bytes that doesn't
+// split a UTF-8 sequence.
+func runeCut(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// runeTail returns the longest suffix of s of at most n bytes that doesn't
+// split a UTF-8 sequence.
+func runeTail(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	start := len(s) - n
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return s[start:]
 }
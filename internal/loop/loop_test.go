@@ -4,17 +4,21 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os/exec"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 
 	"github.com/gerunddev/ralph/internal/claude"
 	"github.com/gerunddev/ralph/internal/db"
 	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/policy"
 )
 
 // setupTestDB creates an in-memory database for testing.
@@ -118,6 +122,45 @@ func mockJJRunnerEmpty() jj.CommandRunner {
 	}
 }
 
+// testDeps returns loop dependencies whose Claude sessions run creator's
+// commands and whose jj commands go to runner.
+func testDeps(database *db.DB, creator claude.CommandCreator, runner jj.CommandRunner) Deps {
+	claudeClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	claudeClient.SetCommandCreator(creator)
+	jjClient := jj.NewClient("/tmp")
+	jjClient.SetCommandRunner(runner)
+	return Deps{DB: database, Claude: claudeClient, VCS: jjClient}
+}
+
+// runLoop runs loop until it stops, within a timeout, and returns the
+// events it emitted. A Run error fails the test.
+func runLoop(t *testing.T, loop *Loop) []Event {
+	t.Helper()
+	events, err := runLoopErr(loop)
+	if err != nil {
+		t.Fatalf("loop.Run() error: %v", err)
+	}
+	return events
+}
+
+// runLoopErr is like runLoop, but returns Run's error.
+func runLoopErr(loop *Loop) ([]Event, error) {
+	var events []Event
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range loop.Events() {
+			events = append(events, event)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := loop.Run(ctx)
+	<-done
+	return events, err
+}
+
 func TestLoopBasicIteration(t *testing.T) {
 	database := setupTestDB(t)
 	plan := createTestPlan(t, database, "Test plan content")
@@ -141,7 +184,7 @@ func TestLoopBasicIteration(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	// Run with timeout
@@ -253,7 +296,7 @@ func TestLoopDoneMarker(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	// Run with timeout
@@ -333,7 +376,7 @@ func TestLoopContextCancellation(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	// Run with very short timeout
@@ -354,6 +397,211 @@ func TestLoopContextCancellation(t *testing.T) {
 	if !errors.Is(err, context.DeadlineExceeded) {
 		t.Errorf("expected context.DeadlineExceeded (possibly wrapped), got: %v", err)
 	}
+
+	// An interrupted plan is paused so it can be resumed
+	updatedPlan, err := database.GetPlan(plan.ID)
+	if err != nil {
+		t.Fatalf("failed to get plan: %v", err)
+	}
+	if updatedPlan.Status != db.PlanStatusPaused {
+		t.Errorf("expected plan status 'paused' after cancellation, got: %s", updatedPlan.Status)
+	}
+	if updatedPlan.FailureReason != "Interrupted" {
+		t.Errorf("expected failure reason 'Interrupted', got: %q", updatedPlan.FailureReason)
+	}
+}
+
+func TestLoopMaxDuration(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	// The budget is spent before the first iteration starts
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 5,
+		MaxDuration:   time.Nanosecond,
+		WorkDir:       "/tmp",
+	}, testDeps(database, mockClaudeCreator("## Progress\nDid some work"), mockJJRunner()))
+
+	events := runLoop(t, loop)
+
+	var maxDuration *Event
+	for i := range events {
+		if events[i].Type == EventMaxDuration {
+			maxDuration = &events[i]
+		}
+	}
+	if maxDuration == nil {
+		t.Fatal("expected EventMaxDuration event")
+	}
+	if !strings.Contains(maxDuration.Message, "ralph --resume "+plan.ID) {
+		t.Errorf("expected resume instructions in message, got: %s", maxDuration.Message)
+	}
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatalf("failed to get sessions: %v", err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("expected no iterations to run, got %d sessions", len(sessions))
+	}
+
+	updatedPlan, err := database.GetPlan(plan.ID)
+	if err != nil {
+		t.Fatalf("failed to get plan: %v", err)
+	}
+	if updatedPlan.Status != db.PlanStatusPaused {
+		t.Errorf("expected plan status 'paused', got: %s", updatedPlan.Status)
+	}
+	if !strings.Contains(updatedPlan.FailureReason, "max duration") {
+		t.Errorf("expected max duration reason, got: %q", updatedPlan.FailureReason)
+	}
+}
+
+func TestLoopIterationsThisRun(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	run := func() (*Loop, []Event) {
+		loop := New(Config{
+			PlanID:            plan.ID,
+			MaxIterations:     5,
+			IterationsThisRun: 2,
+			WorkDir:           "/tmp",
+		}, testDeps(database, mockClaudeCreator("## Progress\nDid some work"), mockJJRunner()))
+
+		events := runLoop(t, loop)
+		return loop, events
+	}
+
+	// Each run works two iterations and leaves the plan paused
+	for _, wantIter := range []int{2, 4} {
+		loop, events := run()
+		if loop.IterationsRun() != 2 || loop.CurrentIteration() != wantIter {
+			t.Errorf("ran %d iterations up to %d, want 2 up to %d", loop.IterationsRun(), loop.CurrentIteration(), wantIter)
+		}
+		if last := events[len(events)-1]; last.Type != EventPaused || !strings.Contains(last.Message, "Ran 2 iterations this run") {
+			t.Errorf("expected a paused event last, got %s: %s", last.Type, last.Message)
+		}
+
+		updatedPlan, err := database.GetPlan(plan.ID)
+		if err != nil {
+			t.Fatalf("failed to get plan: %v", err)
+		}
+		if updatedPlan.Status != db.PlanStatusPaused {
+			t.Errorf("expected plan status 'paused', got: %s", updatedPlan.Status)
+		}
+	}
+}
+
+// runControlledLoop runs a plan of up to five iterations, calling control
+// with the loop when the first Claude session starts.
+func runControlledLoop(t *testing.T, control func(loop *Loop)) (*db.DB, *db.Plan, []Event) {
+	t.Helper()
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	var loop *Loop
+	var once sync.Once
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		once.Do(func() { control(loop) })
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput("## Progress\nDid some work"))
+	}
+
+	loop = New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 5,
+		WorkDir:       "/tmp",
+	}, testDeps(database, creator, mockJJRunner()))
+
+	events := runLoop(t, loop)
+	return database, plan, events
+}
+
+func TestLoopPause(t *testing.T) {
+	database, plan, events := runControlledLoop(t, (*Loop).Pause)
+
+	var paused *Event
+	for i := range events {
+		if events[i].Type == EventPaused {
+			paused = &events[i]
+		}
+	}
+	if paused == nil {
+		t.Fatal("expected EventPaused event")
+	}
+	if !strings.Contains(paused.Message, "ralph --resume "+plan.ID) {
+		t.Errorf("expected resume instructions in message, got: %s", paused.Message)
+	}
+
+	// The current iteration finishes before the loop pauses
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatalf("failed to get sessions: %v", err)
+	}
+	for _, session := range sessions {
+		if session.Iteration != 1 {
+			t.Errorf("expected only iteration 1 to run, got a session in iteration %d", session.Iteration)
+		}
+		if session.Status != db.PlanSessionCompleted {
+			t.Errorf("expected completed sessions, got %s", session.Status)
+		}
+	}
+
+	updatedPlan, err := database.GetPlan(plan.ID)
+	if err != nil {
+		t.Fatalf("failed to get plan: %v", err)
+	}
+	if updatedPlan.Status != db.PlanStatusPaused {
+		t.Errorf("expected plan status 'paused', got: %s", updatedPlan.Status)
+	}
+	if updatedPlan.FailureReason != "Paused by request" {
+		t.Errorf("expected failure reason 'Paused by request', got: %q", updatedPlan.FailureReason)
+	}
+}
+
+func TestLoopStop(t *testing.T) {
+	database, plan, events := runControlledLoop(t, (*Loop).Stop)
+
+	found := false
+	for _, event := range events {
+		found = found || event.Type == EventPaused
+		if event.Type == EventFailed {
+			t.Errorf("expected no EventFailed, got: %s", event.Message)
+		}
+	}
+	if !found {
+		t.Error("expected EventPaused event")
+	}
+
+	updatedPlan, err := database.GetPlan(plan.ID)
+	if err != nil {
+		t.Fatalf("failed to get plan: %v", err)
+	}
+	if updatedPlan.Status != db.PlanStatusPaused {
+		t.Errorf("expected plan status 'paused', got: %s", updatedPlan.Status)
+	}
+	if updatedPlan.FailureReason != "Stopped by request" {
+		t.Errorf("expected failure reason 'Stopped by request', got: %q", updatedPlan.FailureReason)
+	}
+}
+
+func TestLoopStopBeforeRun(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 5, WorkDir: "/tmp"},
+		testDeps(database, mockClaudeCreator("## Progress\nDid some work"), mockJJRunner()))
+	loop.Stop()
+	runLoop(t, loop)
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatalf("failed to get sessions: %v", err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("expected no iterations to run, got %d sessions", len(sessions))
+	}
 }
 
 func TestLoopResume(t *testing.T) {
@@ -414,7 +662,7 @@ func TestLoopResume(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	// Run
@@ -474,7 +722,7 @@ func TestLoopEventTypes(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	// Run
@@ -530,6 +778,32 @@ func TestLoopEventTypes(t *testing.T) {
 	}
 }
 
+func TestLoop_SubscribeReceivesFilteredEvents(t *testing.T) {
+	l := New(Config{PlanID: "plan-1", MaxIterations: 5}, Deps{})
+	sub := l.Subscribe(WithTypes(EventDone))
+
+	l.emit(NewEvent(EventIterationStart, 1, 5, "Starting iteration 1"))
+	l.emit(NewEvent(EventDone, 2, 5, "Agent completed"))
+	l.bus.Close()
+
+	var got []EventType
+	for event := range sub.Events() {
+		got = append(got, event.Type)
+	}
+	if len(got) != 1 || got[0] != EventDone {
+		t.Errorf("subscriber events = %v, want [done]", got)
+	}
+
+	// The default subscription still receives everything
+	var all []EventType
+	for event := range l.Events() {
+		all = append(all, event.Type)
+	}
+	if len(all) != 2 {
+		t.Errorf("Events() = %v, want 2 events", all)
+	}
+}
+
 func TestNewEvent(t *testing.T) {
 	event := NewEvent(EventStarted, 1, 10, "test message")
 
@@ -727,7 +1001,7 @@ func TestLoop_DevDonePlusReviewerApproved(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -804,7 +1078,7 @@ func TestDevDoneMarkerSanitizedFromProgress(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	// Run with timeout
@@ -854,7 +1128,6 @@ func TestDevDoneMarkerSanitizedFromProgress(t *testing.T) {
 	}
 }
 
-
 func TestSanitizeDoneMarker(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -923,7 +1196,7 @@ func TestLoop_DevRunningReviewerAlsoRuns(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -1017,7 +1290,7 @@ func TestLoop_DeveloperDoneTriggersReviewer(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -1120,7 +1393,7 @@ func TestLoop_ReviewerRejects(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -1243,7 +1516,7 @@ func TestLoop_FeedbackIncludedInNextIteration(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -1368,7 +1641,7 @@ func TestLoop_ReviewerReceivesCumulativeDiff(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -1464,7 +1737,7 @@ func TestLoop_AgentTypeStoredInSession(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -1553,7 +1826,7 @@ func TestLoop_ExtremeMode_DoesNotExitOnFirstBothDone(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	// Run with timeout
@@ -1623,6 +1896,10 @@ func TestLoop_ExtremeMode_DoesNotExitOnFirstBothDone(t *testing.T) {
 	if updatedPlan.Status != db.PlanStatusStopped {
 		t.Errorf("expected plan status 'stopped' in extreme mode (exits via max iterations), got: %s", updatedPlan.Status)
 	}
+	// The developer and reviewer agreed, so the run still completed
+	if loop.Outcome() != OutcomeCompleted {
+		t.Errorf("expected outcome %s once extreme mode triggered, got: %s", OutcomeCompleted, loop.Outcome())
+	}
 
 	// Loop iteration counter is 5 because: trigger at iter 1, max becomes 1+3=4,
 	// runs iters 2,3,4, then increments to 5 and checks 5>4 which exits.
@@ -1666,7 +1943,7 @@ func TestLoop_ExtremeMode_TriggersPlus3(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -1773,7 +2050,7 @@ func TestLoop_ExtremeMode_SubsequentDoneIgnored(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -1820,6 +2097,57 @@ func TestLoop_ExtremeMode_SubsequentDoneIgnored(t *testing.T) {
 	}
 }
 
+func TestLoop_ExtremeMode_Rounds(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	callCount := 0
+
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		callCount++
+		// Every iteration ends with both done
+		output := "## Progress\nCompleted\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"
+		if callCount%2 == 0 {
+			output = "## Progress\nReviewed\n\n### Critical Issues\nNone\n\n### Major Issues\nNone\n\n### Minor Issues\nNone\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!"
+		}
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput(output))
+	}
+
+	loop := New(Config{
+		PlanID:                 plan.ID,
+		MaxIterations:          100,
+		ExtremeMode:            true,
+		ExtremeBonusIterations: 2,
+		ExtremeMaxRounds:       2,
+		WorkDir:                "/tmp",
+	}, testDeps(database, creator, mockJJRunnerEmpty()))
+
+	events := runLoop(t, loop)
+
+	// Iteration 1 starts round 1 (max 3), iteration 2 round 2 (max 4); the
+	// done signals of iterations 3 and 4 are ignored
+	var rounds []Event
+	for _, e := range events {
+		if e.Type == EventExtremeModeTriggered {
+			rounds = append(rounds, e)
+		}
+	}
+	if len(rounds) != 2 {
+		t.Fatalf("expected 2 EventExtremeModeTriggered, got %d", len(rounds))
+	}
+	for i, e := range rounds {
+		if e.Round != i+1 || e.Rounds != 2 {
+			t.Errorf("event %d round = %d/%d, want %d/2", i, e.Round, e.Rounds, i+1)
+		}
+		if e.MaxIter != 3+i || !strings.Contains(e.Message, "+2 iterations") {
+			t.Errorf("event %d = max %d, %q; want max %d, +2 iterations", i, e.MaxIter, e.Message, 3+i)
+		}
+	}
+	if loop.CurrentIteration() != 5 {
+		t.Errorf("expected iteration 5, got: %d", loop.CurrentIteration())
+	}
+}
+
 func TestLoop_ExtremeMode_EffectiveMaxIter(t *testing.T) {
 	database := setupTestDB(t)
 	plan := createTestPlan(t, database, "Test plan content")
@@ -1855,7 +2183,7 @@ func TestLoop_ExtremeMode_EffectiveMaxIter(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -1942,7 +2270,7 @@ func TestLoop_ExtremeMode_TriggerOnIteration1(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -1991,7 +2319,7 @@ func TestLoop_TeamMode_DeveloperStartEventHasTeamMode(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -2050,7 +2378,7 @@ func TestLoop_TeamMode_DeveloperStartEventNoTeamMode(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -2129,6 +2457,26 @@ func TestTruncateDiff(t *testing.T) {
 	}
 }
 
+func TestTruncation_KeepsRunesWhole(t *testing.T) {
+	s := strings.Repeat("é", 10) // 2 bytes each
+	for _, got := range []string{
+		truncateString(s, 8),
+		runeCut(s, 7),
+		runeTail(s, 7),
+		truncateDiff(strings.Repeat("é", maxDiffBytes)),
+	} {
+		if !utf8.ValidString(got) {
+			t.Errorf("truncated to invalid UTF-8: %q", got)
+		}
+	}
+	if got := truncateString(s, 8); got != "éé..." {
+		t.Errorf("truncateString() = %q, want éé...", got)
+	}
+	if got := runeTail(s, 7); got != "ééé" {
+		t.Errorf("runeTail() = %q, want ééé", got)
+	}
+}
+
 // =============================================================================
 // Always-Review Model Tests
 // =============================================================================
@@ -2170,7 +2518,7 @@ func TestLoop_MidWorkReviewFeedback(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -2262,7 +2610,7 @@ func TestLoop_MidWorkReviewApproves(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -2351,7 +2699,7 @@ func TestLoop_EditsWithDevDoneNoGuard(t *testing.T) {
 	}, Deps{
 		DB:     database,
 		Claude: claudeClient,
-		JJ:     jjClient,
+		VCS:    jjClient,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -2393,3 +2741,1301 @@ func TestLoop_EditsWithDevDoneNoGuard(t *testing.T) {
 		t.Errorf("expected plan status 'completed', got: %s", updatedPlan.Status)
 	}
 }
+
+func TestLoop_PolicyViolationAbortsIteration(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	callCount := 0
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		callCount++
+		output := "## Progress\nCompleted work\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput(output))
+	}
+
+	runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		if len(args) >= 2 && args[0] == "diff" && args[1] == "--name-only" {
+			return "src/main.go\nsecrets.txt\n", "", nil
+		}
+		return "", "", nil
+	}
+
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 1,
+		WorkDir:       "/tmp",
+		Policy:        policy.New([]string{"src/"}, nil, true),
+	}, testDeps(database, creator, runner))
+
+	events := runLoop(t, loop)
+
+	var violation *Event
+	for i, e := range events {
+		if e.Type == EventPolicyViolation {
+			violation = &events[i]
+		}
+		if e.Type == EventReviewerStart {
+			t.Error("reviewer should not run after a policy violation")
+		}
+		if e.Type == EventError {
+			t.Errorf("unexpected error event: %s", e.Message)
+		}
+	}
+	if violation == nil {
+		t.Fatal("expected EventPolicyViolation")
+	}
+	if !strings.Contains(violation.Message, "secrets.txt") || strings.Contains(violation.Message, "src/main.go") {
+		t.Errorf("violation should list only secrets.txt, got: %s", violation.Message)
+	}
+	if callCount != 1 {
+		t.Errorf("expected only the developer to run, got %d claude calls", callCount)
+	}
+
+	// Violation is fed back to the developer for the next iteration
+	feedback, err := database.GetLatestReviewerFeedback(plan.ID)
+	if err != nil {
+		t.Fatalf("failed to get feedback: %v", err)
+	}
+	if feedback == nil || !strings.Contains(feedback.Content, "POLICY VIOLATION") {
+		t.Errorf("expected policy violation feedback to be stored, got: %+v", feedback)
+	}
+}
+
+// runPlanFilesLoop runs a two-iteration loop on a plan that only allows
+// changes under src/, whose developer also changes go.sum. It returns the
+// sessions, the restore commands run, and the events.
+func runPlanFilesLoop(t *testing.T, flag bool) ([]*db.PlanSession, [][]string, []Event) {
+	t.Helper()
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "---\nfiles:\n  - src/\n---\n# Plan\nFix the bug")
+
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		output := "## Progress\nWorking\n\n## Learnings\nNone\n\n### Verdict\nNEEDS_WORK\n\nKeep going"
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput(output))
+	}
+
+	var mu sync.Mutex
+	var restores [][]string
+	runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		switch {
+		case len(args) >= 3 && args[0] == "log" && args[2] == "@-":
+			return "base123", "", nil
+		case len(args) >= 2 && args[0] == "diff" && args[1] == "--name-only":
+			return "src/main.go\ngo.sum\n", "", nil
+		case len(args) > 0 && args[0] == "restore":
+			mu.Lock()
+			restores = append(restores, args)
+			mu.Unlock()
+		}
+		return "", "", nil
+	}
+
+	loop := New(Config{
+		PlanID:              plan.ID,
+		MaxIterations:       2,
+		WorkDir:             "/tmp",
+		FlagOutOfScopeFiles: flag,
+	}, testDeps(database, creator, runner))
+
+	events := runLoop(t, loop)
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sessions, restores, events
+}
+
+func TestLoop_PlanFilesRevertsOutOfScopeChanges(t *testing.T) {
+	sessions, restores, events := runPlanFilesLoop(t, false)
+
+	want := []string{"restore", "--from", "base123", "--", `root-file:"go.sum"`}
+	if len(restores) == 0 || !slices.Equal(restores[0], want) {
+		t.Errorf("restores = %v, want %v", restores, want)
+	}
+	var flagged *Event
+	for i, e := range events {
+		if e.Type == EventFilesOutOfScope {
+			flagged = &events[i]
+		}
+	}
+	if flagged == nil || !strings.Contains(flagged.Message, "reverted: go.sum") {
+		t.Fatalf("expected a files out of scope event for go.sum, got %+v", flagged)
+	}
+
+	var devPrompts []string
+	for _, s := range sessions {
+		if s.AgentType == db.LoopAgentDeveloper {
+			devPrompts = append(devPrompts, s.InputPrompt)
+		}
+	}
+	if len(devPrompts) != 2 {
+		t.Fatalf("expected 2 developer sessions, got %d", len(devPrompts))
+	}
+	if strings.Contains(devPrompts[0], "# Out-of-Scope Changes") {
+		t.Error("first developer prompt should not list out-of-scope changes")
+	}
+	if !strings.Contains(devPrompts[1], "# Out-of-Scope Changes") || !strings.Contains(devPrompts[1], "- go.sum") ||
+		!strings.Contains(devPrompts[1], "were reverted") {
+		t.Errorf("second developer prompt should list the reverted change:\n%s", devPrompts[1])
+	}
+}
+
+func TestLoop_PlanFilesFlagsOutOfScopeChanges(t *testing.T) {
+	sessions, restores, _ := runPlanFilesLoop(t, true)
+
+	if len(restores) != 0 {
+		t.Errorf("flagging should not restore anything, got %v", restores)
+	}
+	last := sessions[len(sessions)-1]
+	for _, s := range sessions {
+		if s.AgentType == db.LoopAgentDeveloper {
+			last = s
+		}
+	}
+	if !strings.Contains(last.InputPrompt, "you must undo them") {
+		t.Errorf("developer prompt should ask for the flagged change to be undone:\n%s", last.InputPrompt)
+	}
+}
+
+func TestLoop_StallAbort(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	// Developer keeps reporting the same progress and the diff never changes
+	creator := mockClaudeCreator(
+		"## Progress\nStill working\n\n## Status\nRUNNING RUNNING RUNNING")
+
+	loop := New(Config{
+		PlanID:         plan.ID,
+		MaxIterations:  10,
+		WorkDir:        "/tmp",
+		StallThreshold: 2,
+		StallAbort:     true,
+	}, testDeps(database, creator, mockJJRunner()))
+
+	events := runLoop(t, loop)
+
+	var detected, aborted bool
+	for _, e := range events {
+		switch e.Type {
+		case EventStallDetected:
+			detected = true
+		case EventStallAborted:
+			aborted = true
+		case EventMaxIterations:
+			t.Error("should stop on stall before max iterations")
+		}
+	}
+	if !detected {
+		t.Error("expected EventStallDetected")
+	}
+	if !aborted {
+		t.Error("expected EventStallAborted")
+	}
+
+	// Stalls on iterations 2 and 3, aborting on the third
+	if got := loop.CurrentIteration(); got != 3 {
+		t.Errorf("expected abort on iteration 3, got %d", got)
+	}
+
+	updated, err := database.GetPlan(plan.ID)
+	if err != nil {
+		t.Fatalf("failed to get plan: %v", err)
+	}
+	if updated.Status != db.PlanStatusStopped {
+		t.Errorf("expected plan status stopped, got %s", updated.Status)
+	}
+}
+
+func TestLoop_StallAbortAtThreshold(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	// Developer never changes anything or reports progress
+	creator := mockClaudeCreator("## Progress\n\n## Status\nRUNNING RUNNING RUNNING")
+
+	const threshold = 3
+	loop := New(Config{
+		PlanID:         plan.ID,
+		MaxIterations:  10,
+		WorkDir:        "/tmp",
+		StallThreshold: threshold,
+		StallAbort:     true,
+	}, testDeps(database, creator, mockJJRunnerWithDiff("base123", "")))
+
+	events := runLoop(t, loop)
+
+	// Every iteration stalls, so the loop stops on exactly the threshold'th
+	var aborted bool
+	for _, e := range events {
+		if e.Type == EventStallAborted {
+			aborted = true
+		}
+		if e.Type == EventStallDetected && e.Iteration != threshold {
+			t.Errorf("unexpected stall detected in iteration %d", e.Iteration)
+		}
+	}
+	if !aborted {
+		t.Error("expected EventStallAborted")
+	}
+	if got := loop.CurrentIteration(); got != threshold {
+		t.Errorf("expected abort on iteration %d, got %d", threshold, got)
+	}
+}
+
+func TestLoop_StallNudgesDeveloper(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	creator := mockClaudeCreator(
+		"## Progress\nStill working\n\n## Status\nRUNNING RUNNING RUNNING")
+
+	loop := New(Config{
+		PlanID:         plan.ID,
+		MaxIterations:  3,
+		WorkDir:        "/tmp",
+		StallThreshold: 1,
+	}, testDeps(database, creator, mockJJRunner()))
+
+	events := runLoop(t, loop)
+
+	// Iteration 2 stalls, so the iteration 3 developer prompt carries the nudge
+	var nudged bool
+	for _, e := range events {
+		if e.Type == EventStallAborted {
+			t.Error("nudge mode should not abort")
+		}
+		if e.Type == EventPromptBuilt && e.Iteration == 3 && strings.Contains(e.Prompt, "# You Are Stuck") {
+			nudged = true
+		}
+		if e.Type == EventPromptBuilt && e.Iteration < 3 && strings.Contains(e.Prompt, "# You Are Stuck") {
+			t.Errorf("unexpected stuck nudge in iteration %d", e.Iteration)
+		}
+	}
+	if !nudged {
+		t.Error("expected stuck nudge in iteration 3 developer prompt")
+	}
+}
+
+func TestLoop_RetriesTransientClaudeFailure(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	callCount := 0
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		callCount++
+		if callCount == 1 {
+			// First developer attempt is rate limited
+			return exec.CommandContext(ctx, "sh", "-c", "echo 'API Error: 429 rate_limit_error' >&2; exit 1")
+		}
+		output := "## Progress\nDone\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"
+		if callCount == 3 {
+			output = "## Progress\nReviewed\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED REVIEWER_APPROVED!!!"
+		}
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput(output))
+	}
+
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 1,
+		WorkDir:       "/tmp",
+		Retry: RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+		},
+	}, testDeps(database, creator, mockJJRunnerEmpty()))
+
+	events := runLoop(t, loop)
+
+	var retried, done bool
+	for _, e := range events {
+		switch e.Type {
+		case EventClaudeRetry:
+			retried = true
+		case EventError:
+			t.Errorf("unexpected error event: %s", e.Message)
+		case EventDone:
+			done = true
+		}
+	}
+	if !retried {
+		t.Error("expected EventClaudeRetry")
+	}
+	if !done {
+		t.Error("expected loop to complete after retry")
+	}
+	if callCount != 3 {
+		t.Errorf("expected 3 claude calls (failed dev, dev retry, reviewer), got %d", callCount)
+	}
+}
+
+func TestLoop_NoRetryForFatalClaudeFailure(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	callCount := 0
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		callCount++
+		return exec.CommandContext(ctx, "sh", "-c", "echo 'Invalid API key' >&2; exit 1")
+	}
+
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 1,
+		WorkDir:       "/tmp",
+		Retry: RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+		},
+	}, testDeps(database, creator, mockJJRunnerEmpty()))
+
+	events := runLoop(t, loop)
+
+	for _, e := range events {
+		if e.Type == EventClaudeRetry {
+			t.Error("fatal errors should not be retried")
+		}
+	}
+	// Developer and reviewer each run once
+	if callCount != 2 {
+		t.Errorf("expected 2 claude calls, got %d", callCount)
+	}
+}
+
+func TestLoop_ReviewerUsesReviewerClient(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	devCalls, reviewerCalls := 0, 0
+	devCreator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		devCalls++
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput("## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"))
+	}
+	reviewerClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	reviewerClient.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		reviewerCalls++
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput("## Progress\nReviewed"))
+	})
+
+	deps := testDeps(database, devCreator, mockJJRunnerEmpty())
+	deps.ReviewerClaude = reviewerClient
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 1,
+		WorkDir:       "/tmp",
+	}, deps)
+
+	runLoop(t, loop)
+
+	if devCalls != 1 || reviewerCalls != 1 {
+		t.Errorf("expected 1 developer and 1 reviewer call, got %d and %d", devCalls, reviewerCalls)
+	}
+}
+
+// plannerOutput is mock planner output with two tasks.
+const plannerOutput = "## Tasks\n\n1. First task\n   Do the first thing\n2. Second task\n   Do the second thing"
+
+func TestLoop_DecomposeRunsTasksInOrder(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	var devPrompts []string
+	devCreator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		devPrompts = append(devPrompts, args[len(args)-1])
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput(
+			"## Progress\nDone\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"))
+	}
+	// The planner runs with the reviewer's options
+	plannerCalls := 0
+	reviewerClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	reviewerClient.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		if strings.Contains(args[len(args)-1], "breaking a plan into tasks") {
+			plannerCalls++
+			return exec.CommandContext(ctx, "echo", createMockClaudeOutput(plannerOutput))
+		}
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput("## Progress\nLooks good\n\nREVIEWER_APPROVED REVIEWER_APPROVED!!!"))
+	})
+
+	var jjNewCalls [][]string
+	changes := 0
+	runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		switch {
+		case args[0] == "new":
+			jjNewCalls = append(jjNewCalls, args)
+			changes++
+		case args[0] == "diff":
+			return "diff --git a/file.go b/file.go\n+// new code", "", nil
+		case args[0] == "log" && args[2] == "@":
+			return fmt.Sprintf("change%d", changes), "", nil
+		}
+		return "", "", nil
+	}
+
+	deps := testDeps(database, devCreator, runner)
+	deps.ReviewerClaude = reviewerClient
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 10,
+		WorkDir:       "/tmp",
+		Decompose:     true,
+	}, deps)
+
+	events := runLoop(t, loop)
+
+	if plannerCalls != 1 {
+		t.Errorf("expected 1 planner call, got %d", plannerCalls)
+	}
+	if len(devPrompts) != 2 ||
+		!strings.Contains(devPrompts[0], "Task 1 of 2: First task") ||
+		!strings.Contains(devPrompts[1], "Task 2 of 2: Second task") {
+		t.Errorf("expected one developer session per task in order, got %d prompts", len(devPrompts))
+	}
+	if len(jjNewCalls) != 2 || jjNewCalls[1][2] != "Task 2: Second task" {
+		t.Errorf("expected a jj change per task, got %v", jjNewCalls)
+	}
+
+	var started, completed int
+	var done bool
+	for _, e := range events {
+		switch e.Type {
+		case EventTaskStarted:
+			started++
+		case EventTaskCompleted:
+			completed++
+		case EventDone:
+			done = true
+		}
+	}
+	if started != 2 || completed != 2 || !done {
+		t.Errorf("expected 2 started, 2 completed, and done; got %d, %d, %v", started, completed, done)
+	}
+
+	tasks, err := database.GetTasksByProject(plan.ID)
+	if err != nil {
+		t.Fatalf("GetTasksByProject() error: %v", err)
+	}
+	for i, task := range tasks {
+		if task.Status != db.TaskCompleted {
+			t.Errorf("task %d status = %s, want completed", i+1, task.Status)
+		}
+		if task.JJChangeID == nil || *task.JJChangeID != fmt.Sprintf("change%d", i+1) {
+			t.Errorf("task %d change ID = %v, want change%d", i+1, task.JJChangeID, i+1)
+		}
+	}
+	updatedPlan, err := database.GetPlan(plan.ID)
+	if err != nil {
+		t.Fatalf("GetPlan() error: %v", err)
+	}
+	if updatedPlan.Status != db.PlanStatusCompleted {
+		t.Errorf("plan status = %s, want completed", updatedPlan.Status)
+	}
+}
+
+func TestLoop_ResumesExistingTasks(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+	if err := database.CreatePlanTasks(plan, []*db.Task{
+		{ID: "t1", Sequence: 1, Title: "First task", Status: db.TaskCompleted},
+		{ID: "t2", Sequence: 2, Title: "Second task"},
+	}); err != nil {
+		t.Fatalf("CreatePlanTasks() error: %v", err)
+	}
+
+	var devPrompts []string
+	devCreator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		devPrompts = append(devPrompts, args[len(args)-1])
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput(
+			"## Progress\nDone\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"))
+	}
+	reviewerClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	reviewerClient.SetCommandCreator(mockClaudeCreator("REVIEWER_APPROVED REVIEWER_APPROVED!!!"))
+
+	// Decompose is off: existing tasks alone put the loop in task mode
+	deps := testDeps(database, devCreator, mockJJRunner())
+	deps.ReviewerClaude = reviewerClient
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 10,
+		WorkDir:       "/tmp",
+	}, deps)
+
+	runLoop(t, loop)
+
+	if len(devPrompts) != 1 || !strings.Contains(devPrompts[0], "Task 2 of 2: Second task") {
+		t.Errorf("expected only the second task to run, got %d prompts", len(devPrompts))
+	}
+	task, err := database.GetTask("t2")
+	if err != nil {
+		t.Fatalf("GetTask() error: %v", err)
+	}
+	if task.Status != db.TaskCompleted || task.IterationCount != 1 {
+		t.Errorf("task t2 = %s after %d iterations, want completed after 1", task.Status, task.IterationCount)
+	}
+}
+
+func TestLoop_DecomposeFailsWithoutTasks(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 10,
+		WorkDir:       "/tmp",
+		Decompose:     true,
+	}, testDeps(database, mockClaudeCreator("I could not find anything to do."), mockJJRunner()))
+
+	if _, err := runLoopErr(loop); !errors.Is(err, errNoTasks) {
+		t.Errorf("loop.Run() error = %v, want errNoTasks", err)
+	}
+}
+
+func TestLoop_TaskModeScopesProgressAndDiff(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+	if err := database.CreatePlanTasks(plan, []*db.Task{
+		{ID: "t1", Sequence: 1, Title: "First task"},
+		{ID: "t2", Sequence: 2, Title: "Second task"},
+	}); err != nil {
+		t.Fatalf("CreatePlanTasks() error: %v", err)
+	}
+
+	var devPrompts []string
+	devCreator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		prompt := args[len(args)-1]
+		devPrompts = append(devPrompts, prompt)
+		step := "step-alpha"
+		if strings.Contains(prompt, "Task 2 of 2") {
+			step = "step-beta"
+		}
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput(
+			"## Progress\nFinished "+step+"\n\n## Learnings\nLearned "+step+"\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"))
+	}
+	reviewerClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	reviewerClient.SetCommandCreator(mockClaudeCreator("REVIEWER_APPROVED REVIEWER_APPROVED!!!"))
+
+	var diffFroms []string
+	changes := 0
+	runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		switch {
+		case args[0] == "new":
+			changes++
+		case args[0] == "diff" && len(args) > 2 && args[1] == "--from":
+			diffFroms = append(diffFroms, args[2])
+			return "diff --git a/file.go b/file.go\n+// new code", "", nil
+		case args[0] == "diff":
+			return "diff --git a/file.go b/file.go\n+// new code", "", nil
+		case args[0] == "log" && args[2] == "@":
+			return fmt.Sprintf("change%d", changes), "", nil
+		case args[0] == "log" && args[2] == "@-":
+			return "planbase", "", nil
+		}
+		return "", "", nil
+	}
+
+	deps := testDeps(database, devCreator, runner)
+	deps.ReviewerClaude = reviewerClient
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 10,
+		WorkDir:       "/tmp",
+	}, deps)
+
+	runLoop(t, loop)
+
+	if len(devPrompts) != 2 {
+		t.Fatalf("expected 2 developer sessions, got %d", len(devPrompts))
+	}
+	if strings.Contains(devPrompts[1], "step-alpha") {
+		t.Error("second task's prompt should not include the first task's progress or learnings")
+	}
+	if !slices.Equal(diffFroms, []string{"change1-", "change2-"}) {
+		t.Errorf("reviewer diffs from %v, want each task's own base", diffFroms)
+	}
+
+	history, err := database.GetProgressHistory(plan.ID)
+	if err != nil {
+		t.Fatalf("GetProgressHistory() error: %v", err)
+	}
+	found := false
+	for _, p := range history {
+		if p.TaskID == "t1" && strings.Contains(p.Content, "step-alpha") {
+			found = true
+		}
+		if p.TaskID == "t2" && strings.Contains(p.Content, "step-alpha") {
+			t.Errorf("task t2 progress contains the first task's work: %q", p.Content)
+		}
+	}
+	if !found {
+		t.Error("expected task t1 progress to be recorded under t1")
+	}
+	project, err := database.GetProject(plan.ID)
+	if err != nil {
+		t.Fatalf("GetProject() error: %v", err)
+	}
+	if project.Status != db.ProjectCompleted {
+		t.Errorf("project status = %s, want completed", project.Status)
+	}
+}
+
+func TestLoop_StoresSessionTranscript(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	creator := mockClaudeCreatorWithToolUse(
+		"## Progress\nEdited the file\n\n## Status\nRUNNING RUNNING RUNNING", "Write")
+
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 1,
+		WorkDir:       "/tmp",
+	}, testDeps(database, creator, mockJJRunner()))
+
+	runLoop(t, loop)
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil || len(sessions) == 0 {
+		t.Fatalf("expected a stored session, got %d (err %v)", len(sessions), err)
+	}
+	transcript, err := database.GetTranscriptBySession(sessions[0].ID)
+	if err != nil {
+		t.Fatalf("GetTranscriptBySession() error: %v", err)
+	}
+	if len(transcript) != 2 {
+		t.Fatalf("expected 2 transcript messages, got %d: %+v", len(transcript), transcript)
+	}
+	if transcript[0].Kind != "tool_use" || transcript[0].ToolName != "Write" {
+		t.Errorf("first message = %+v, want the Write tool call", transcript[0])
+	}
+	if transcript[1].Kind != "text" || !strings.Contains(transcript[1].Content, "Edited the file") {
+		t.Errorf("second message = %+v, want the assistant text", transcript[1])
+	}
+	if transcript[0].Sequence >= transcript[1].Sequence {
+		t.Errorf("sequences %d, %d are out of order", transcript[0].Sequence, transcript[1].Sequence)
+	}
+}
+
+func TestLoop_TagsSubAgentEvents(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	lines := []string{
+		`{"type":"init","session_id":"s","model":"test-model"}`,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"task_1","name":"Task","input":{"description":"Find callers"}}]}}`,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Sub-agent notes"}]},"parent_tool_use_id":"task_1"}`,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"## Progress\nDone\n\n## Status\nRUNNING RUNNING RUNNING"}]}}`,
+		`{"type":"result","result":"ok"}`,
+	}
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "echo", strings.Join(lines, "\n"))
+	}
+
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 1,
+		WorkDir:       "/tmp",
+	}, testDeps(database, creator, mockJJRunner()))
+
+	runLoop(t, loop)
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil || len(sessions) == 0 {
+		t.Fatalf("expected a stored session, got %d (err %v)", len(sessions), err)
+	}
+	if strings.Contains(sessions[0].FinalOutput, "Sub-agent notes") {
+		t.Errorf("expected sub-agent text to be left out of the session output, got %q", sessions[0].FinalOutput)
+	}
+
+	events, err := database.GetEventsBySession(sessions[0].ID)
+	if err != nil {
+		t.Fatalf("GetEventsBySession() error: %v", err)
+	}
+	tagged := 0
+	for _, e := range events {
+		if e.SubAgentID == "task_1" {
+			tagged++
+		}
+	}
+	if tagged != 1 {
+		t.Errorf("expected 1 event tagged with the sub-agent, got %d", tagged)
+	}
+
+	transcript, err := database.GetTranscriptBySession(sessions[0].ID)
+	if err != nil {
+		t.Fatalf("GetTranscriptBySession() error: %v", err)
+	}
+	if len(transcript) != 3 || transcript[1].SubAgentID != "task_1" || transcript[1].Content != "Sub-agent notes" {
+		t.Errorf("transcript = %+v, want the sub-agent text tagged task_1", transcript)
+	}
+}
+
+func TestLoop_RecordsToolUsage(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	creator := mockClaudeCreatorWithToolUse(
+		"## Progress\nEdited the file\n\n## Status\nRUNNING RUNNING RUNNING", "Read", "Edit", "Read")
+
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 1,
+		WorkDir:       "/tmp",
+	}, testDeps(database, creator, mockJJRunner()))
+
+	var activity []string
+	events := runLoop(t, loop)
+	for _, event := range events {
+		if event.Type == EventToolActivity {
+			activity = append(activity, event.Message)
+		}
+	}
+
+	// The developer and reviewer sessions each call Read, Edit, Read; the
+	// activity summary covers the whole iteration
+	if len(activity) != 6 || activity[2] != "Read×2 Edit×1" || activity[5] != "Read×4 Edit×2" {
+		t.Errorf("activity = %q, want 6 summaries ending in %q", activity, "Read×4 Edit×2")
+	}
+
+	usages, err := database.GetToolUsageByPlan(plan.ID)
+	if err != nil {
+		t.Fatalf("GetToolUsageByPlan() error: %v", err)
+	}
+	if len(usages) != 4 {
+		t.Fatalf("expected 4 tool usage rows (2 per session), got %d", len(usages))
+	}
+	for _, u := range usages {
+		if u.Iteration != 1 {
+			t.Errorf("row %+v: want iteration 1", u)
+		}
+		if (u.ToolName == "Read" && u.Calls != 2) || (u.ToolName == "Edit" && u.Calls != 1) {
+			t.Errorf("row %+v: want Read×2 and Edit×1 per session", u)
+		}
+	}
+	if usages[0].SessionID == usages[2].SessionID {
+		t.Error("expected the rows to belong to two sessions")
+	}
+}
+
+func TestLoop_AddsReviewTrailersOnApproval(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	reviewerClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	reviewerClient.SetCommandCreator(mockClaudeCreator("REVIEWER_APPROVED REVIEWER_APPROVED!!!"))
+
+	var described []string
+	runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		switch {
+		case args[0] == "diff":
+			return "diff --git a/file.go b/file.go\n+// new code", "", nil
+		case args[0] == "log" && slices.Contains(args, "description"):
+			return "Add feature\n", "", nil
+		case args[0] == "describe":
+			described = append(described, args[len(args)-1])
+		}
+		return "", "", nil
+	}
+
+	deps := testDeps(database, mockClaudeCreator("## Progress\nDone\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"), runner)
+	deps.ReviewerClaude = reviewerClient
+	loop := New(Config{
+		PlanID:         plan.ID,
+		MaxIterations:  5,
+		WorkDir:        "/tmp",
+		CommitTrailers: true,
+	}, deps)
+
+	runLoop(t, loop)
+
+	want := "Add feature\n\nReviewed-by: ralph-reviewer\nIterations: 1\nPlan-ID: " + plan.ID + "\n"
+	if len(described) != 1 || described[0] != want {
+		t.Errorf("described = %q, want [%q]", described, want)
+	}
+}
+
+func TestLoop_RecordsDeveloperCommitID(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		if args[0] == "log" && slices.Contains(args, "commit_id") {
+			return "snapshot1\n", "", nil
+		}
+		return mockJJRunner()(ctx, dir, name, args...)
+	}
+
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 1, WorkDir: "/tmp"}, testDeps(database, mockClaudeCreator("## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"), runner))
+
+	runLoop(t, loop)
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatalf("GetPlanSessionsByPlan() error: %v", err)
+	}
+	for _, s := range sessions {
+		want := ""
+		if s.AgentType == db.LoopAgentDeveloper {
+			want = "snapshot1"
+		}
+		if s.CommitID != want {
+			t.Errorf("%s session CommitID = %q, want %q", s.AgentType, s.CommitID, want)
+		}
+	}
+}
+
+func TestLoop_ChangePerIteration(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	var created []string
+	runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		switch args[0] {
+		case "diff":
+			return "diff --git a/file.go b/file.go\n+// new code", "", nil
+		case "new":
+			created = append(created, args[len(args)-1])
+		}
+		return "", "", nil
+	}
+
+	loop := New(Config{
+		PlanID:             plan.ID,
+		MaxIterations:      2,
+		WorkDir:            "/tmp",
+		ChangePerIteration: true,
+	}, testDeps(database, mockClaudeCreator("## Progress\nWorking on it"), runner))
+
+	runLoop(t, loop)
+
+	want := []string{"Iteration 1", "Iteration 2"}
+	if !slices.Equal(created, want) {
+		t.Errorf("new changes = %q, want %q", created, want)
+	}
+}
+
+func TestLoop_RecordsIterationOperations(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	// Each iteration's operation is taken before its change is created
+	var calls []string
+	runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		switch args[0] {
+		case "diff":
+			return "diff --git a/file.go b/file.go\n+// new code", "", nil
+		case "op", "new":
+			calls = append(calls, args[0])
+			return fmt.Sprintf("op-%d\n", len(calls)), "", nil
+		}
+		return "", "", nil
+	}
+
+	loop := New(Config{
+		PlanID:             plan.ID,
+		MaxIterations:      2,
+		WorkDir:            "/tmp",
+		ChangePerIteration: true,
+	}, testDeps(database, mockClaudeCreator("## Progress\nWorking on it"), runner))
+	runLoop(t, loop)
+
+	if want := []string{"op", "new", "op", "new"}; !slices.Equal(calls, want) {
+		t.Errorf("jj calls = %q, want %q", calls, want)
+	}
+	for iteration, want := range map[int]string{1: "op-1", 2: "op-3"} {
+		if got, err := database.GetIterationOperation(plan.ID, iteration); err != nil || got != want {
+			t.Errorf("GetIterationOperation(%d) = %q, %v; want %q", iteration, got, err, want)
+		}
+	}
+}
+
+func TestLoop_SquashOnComplete(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "# Add login\n\nDetails")
+
+	reviewerClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	reviewerClient.SetCommandCreator(mockClaudeCreator("REVIEWER_APPROVED REVIEWER_APPROVED!!!"))
+
+	var squashed [][]string
+	runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		switch {
+		case args[0] == "diff":
+			return "diff --git a/file.go b/file.go\n+// new code", "", nil
+		case args[0] == "log" && args[2] == "@-":
+			return "base", "", nil
+		case args[0] == "log" && args[2] == "base..@-":
+			return "abc\ndef\n", "", nil
+		case args[0] == "squash":
+			squashed = append(squashed, args)
+		}
+		return "", "", nil
+	}
+
+	deps := testDeps(database, mockClaudeCreator("## Progress\nDone\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"), runner)
+	deps.ReviewerClaude = reviewerClient
+	loop := New(Config{
+		PlanID:           plan.ID,
+		MaxIterations:    5,
+		WorkDir:          "/tmp",
+		SquashOnComplete: true,
+	}, deps)
+
+	runLoop(t, loop)
+
+	message := "Add login\n\nCompleted by ralph in 1 iteration (plan " + plan.ID + ")."
+	want := []string{"squash", "--from", "base..@-", "--into", "@", "-m", message}
+	if len(squashed) != 1 || !slices.Equal(squashed[0], want) {
+		t.Errorf("squash calls = %q, want [%q]", squashed, want)
+	}
+}
+
+func TestLoop_UserFeedback(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 2,
+		WorkDir:       "/tmp",
+	}, testDeps(database, mockClaudeCreator("## Progress\nWorking on it"), mockJJRunner()))
+	loop.AddUserFeedback("  ")
+	loop.AddUserFeedback("Use cursor pagination")
+	loop.AddUserFeedback("Keep the v1 endpoints")
+
+	var prompts []string
+	var feedbackEvents int
+	events := runLoop(t, loop)
+	for _, event := range events {
+		switch event.Type {
+		case EventPromptBuilt:
+			prompts = append(prompts, event.Prompt)
+		case EventUserFeedback:
+			feedbackEvents++
+		}
+	}
+
+	if len(prompts) < 2 {
+		t.Fatalf("got %d prompts, want at least 2", len(prompts))
+	}
+	if !strings.Contains(prompts[0], "Use cursor pagination\n\n---\n\nKeep the v1 endpoints") {
+		t.Errorf("first developer prompt missing the queued feedback:\n%s", prompts[0])
+	}
+	for _, prompt := range prompts[1:] {
+		if strings.Contains(prompt, "# User Feedback") {
+			t.Error("expected user feedback in only one developer prompt")
+		}
+	}
+	if feedbackEvents != 1 {
+		t.Errorf("got %d user feedback events, want 1", feedbackEvents)
+	}
+}
+
+func TestLoop_ReportsSilentClaudeSessions(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	claudeClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1, Liveness: claude.Liveness{
+		HeartbeatInterval: 50 * time.Millisecond,
+		StalledAfter:      150 * time.Millisecond,
+	}})
+	output := createMockClaudeOutput("## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING")
+	claudeClient.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "sh", "-c", `sleep 0.3; printf '%s\n' "$1"`, "sh", output)
+	})
+	jjClient := jj.NewClient("/tmp")
+	jjClient.SetCommandRunner(mockJJRunnerEmpty())
+
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 1, WorkDir: "/tmp"}, Deps{
+		DB:     database,
+		Claude: claudeClient,
+		VCS:    jjClient,
+	})
+
+	counts := map[EventType]int{}
+	events := runLoop(t, loop)
+	for _, event := range events {
+		counts[event.Type]++
+		if event.Type == EventClaudeStream && event.ClaudeEvent.Type == claude.EventHeartbeat {
+			t.Error("expected heartbeats not to be forwarded as stream events")
+		}
+	}
+
+	if counts[EventClaudeHeartbeat] == 0 {
+		t.Error("expected heartbeat events while Claude was silent")
+	}
+	// Once for the developer, once for the reviewer
+	if counts[EventClaudeStalled] != 2 {
+		t.Errorf("got %d stalled events, want 2", counts[EventClaudeStalled])
+	}
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range sessions {
+		events, err := database.GetEventsBySession(s.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range events {
+			if e.EventType == string(claude.EventHeartbeat) || e.EventType == string(claude.EventStalled) {
+				t.Errorf("expected liveness events not to be stored, got %s", e.EventType)
+			}
+		}
+	}
+}
+
+func TestLoop_ReadsStatusToolCalls(t *testing.T) {
+	for _, statusTool := range []bool{true, false} {
+		t.Run(fmt.Sprintf("status tool %v", statusTool), func(t *testing.T) {
+			database := setupTestDB(t)
+			plan := createTestPlan(t, database, "Test plan content")
+
+			// Both agents report done and approved through the tool, and
+			// write nothing the markdown parser would take as a signal
+			lines := []string{
+				`{"type":"init","session_id":"s","model":"test-model"}`,
+				`{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"mcp__ralph__ralph_status","input":{"learnings":"no progress"}}]}}`,
+				`{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"t2","name":"mcp__ralph__ralph_status","input":{"progress":"Reported by tool","done":true,"approved":true}}]}}`,
+				`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"All finished."}]}}`,
+				`{"type":"result","result":"ok"}`,
+			}
+			creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+				return exec.CommandContext(ctx, "echo", strings.Join(lines, "\n"))
+			}
+
+			loop := New(Config{
+				PlanID:        plan.ID,
+				MaxIterations: 1,
+				WorkDir:       "/tmp",
+				StatusTool:    statusTool,
+			}, testDeps(database, creator, mockJJRunner()))
+
+			runLoop(t, loop)
+
+			updatedPlan, err := database.GetPlan(plan.ID)
+			if err != nil {
+				t.Fatalf("failed to get plan: %v", err)
+			}
+			if completed := updatedPlan.Status == db.PlanStatusCompleted; completed != statusTool {
+				t.Errorf("plan status = %s, want completed only when the status tool is read", updatedPlan.Status)
+			}
+
+			progress, err := database.GetLatestProgress(plan.ID)
+			if statusTool && (err != nil || progress.Content != "Reported by tool") {
+				t.Errorf("latest progress = %+v (err %v), want the tool call's", progress, err)
+			}
+		})
+	}
+}
+
+func TestLoop_StoresProgressCheckpoints(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	lines := []string{
+		`{"type":"init","session_id":"s","model":"test-model"}`,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"mcp__ralph__record_progress","input":{"progress":"Checkpoint one"}}]}}`,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"t2","name":"mcp__ralph__record_progress","input":{"progress":""}}]}}`,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"## Progress\nFinal progress\n\n## Status\nRUNNING RUNNING RUNNING"}]}}`,
+		`{"type":"result","result":"ok"}`,
+	}
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "echo", strings.Join(lines, "\n"))
+	}
+
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 1,
+		WorkDir:       "/tmp",
+		StateTools:    true,
+	}, testDeps(database, creator, mockJJRunner()))
+
+	runLoop(t, loop)
+
+	history, err := database.GetProgressHistory(plan.ID)
+	if err != nil {
+		t.Fatalf("GetProgressHistory() error: %v", err)
+	}
+	if len(history) < 2 || history[0].Content != "Checkpoint one" || history[1].Content != "Final progress" {
+		t.Fatalf("expected the checkpoint before the session's progress, got %+v", history)
+	}
+	if history[0].SessionID != history[1].SessionID {
+		t.Errorf("expected the checkpoint to belong to the developer session")
+	}
+}
+
+func TestLoop_DifferentialPrompts(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	// Developer sessions are told apart from reviews by their prompt, and
+	// the CLI reports a new session ID for each run
+	var mu sync.Mutex
+	var devCalls [][]string
+	runs := 0
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		mu.Lock()
+		defer mu.Unlock()
+		prompt := args[len(args)-1]
+		if strings.HasPrefix(prompt, "# Continue") || strings.Contains(prompt, "experienced software developer") {
+			devCalls = append(devCalls, args)
+		}
+		runs++
+		lines := []string{
+			fmt.Sprintf(`{"type":"init","session_id":"claude-%d","model":"test-model"}`, runs),
+			`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"## Progress\nWorking\n\n## Learnings\nNone\n\n## Status\nRUNNING RUNNING RUNNING"}]}}`,
+			`{"type":"result","result":"ok"}`,
+		}
+		return exec.CommandContext(ctx, "echo", strings.Join(lines, "\n"))
+	}
+
+	loop := New(Config{
+		PlanID:              plan.ID,
+		MaxIterations:       3,
+		WorkDir:             "/tmp",
+		DifferentialPrompts: true,
+		FullPromptEvery:     2,
+	}, testDeps(database, creator, mockJJRunner()))
+
+	_, _ = runLoopErr(loop)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(devCalls) != 3 {
+		t.Fatalf("expected 3 developer sessions, got %d", len(devCalls))
+	}
+	resumed := func(args []string) string {
+		for i, arg := range args {
+			if arg == "--resume" && i+1 < len(args) {
+				return args[i+1]
+			}
+		}
+		return ""
+	}
+	// Full, then differential in the first developer session's CLI session,
+	// then a full refresh
+	if got := resumed(devCalls[0]); got != "" {
+		t.Errorf("first session resumed %q, want a new session", got)
+	}
+	if got := resumed(devCalls[1]); got != "claude-1" {
+		t.Errorf("second session resumed %q, want claude-1", got)
+	}
+	if prompt := devCalls[1][len(devCalls[1])-1]; !strings.HasPrefix(prompt, "# Continue") || strings.Contains(prompt, "Test plan content") {
+		t.Errorf("expected a differential prompt without the plan, got:\n%s", prompt)
+	}
+	if got := resumed(devCalls[2]); got != "" {
+		t.Errorf("third session resumed %q, want a full refresh", got)
+	}
+}
+
+func TestLoop_DifferentialPromptsFallBackWhenResumeFails(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		if slices.Contains(args, "--resume") {
+			// The CLI reports nothing when the session is gone
+			return exec.CommandContext(ctx, "echo", `{"type":"result","result":"No conversation found"}`)
+		}
+		lines := []string{
+			`{"type":"init","session_id":"claude-1","model":"test-model"}`,
+			`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"}]}}`,
+			`{"type":"result","result":"ok"}`,
+		}
+		return exec.CommandContext(ctx, "echo", strings.Join(lines, "\n"))
+	}
+
+	loop := New(Config{
+		PlanID:              plan.ID,
+		MaxIterations:       2,
+		WorkDir:             "/tmp",
+		DifferentialPrompts: true,
+		FullPromptEvery:     5,
+	}, testDeps(database, creator, mockJJRunner()))
+
+	_, _ = runLoopErr(loop)
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var prompts []string
+	for _, s := range sessions {
+		if s.AgentType == db.LoopAgentDeveloper && s.Iteration == 2 {
+			prompts = append(prompts, s.InputPrompt)
+		}
+	}
+	if len(prompts) != 2 || !strings.HasPrefix(prompts[0], "# Continue") || !strings.Contains(prompts[1], "Test plan content") {
+		t.Errorf("expected a failed differential session followed by a full one in iteration 2, got %d sessions", len(prompts))
+	}
+}
+
+func TestLoop_FallsBackToNextModel(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	var models []string
+	claudeClient := claude.NewClient(claude.ClientConfig{
+		Model:    "primary",
+		MaxTurns: 1,
+	})
+	claudeClient.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		model := args[slices.Index(args, "--model")+1]
+		models = append(models, model)
+		if model == "primary" {
+			return exec.CommandContext(ctx, "sh", "-c", "echo 'API Error: 529 overloaded_error' >&2; exit 1")
+		}
+		output := "## Progress\nDone\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"
+		if len(models) > 2 {
+			output = "## Progress\nReviewed\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED REVIEWER_APPROVED!!!"
+		}
+		// The init event reports the model that served the session
+		return exec.CommandContext(ctx, "echo", strings.Replace(createMockClaudeOutput(output), "test-model", model, 1))
+	})
+
+	jjClient := jj.NewClient("/tmp")
+	jjClient.SetCommandRunner(mockJJRunnerEmpty())
+
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 1,
+		WorkDir:       "/tmp",
+		ModelChain:    []string{"primary", "secondary"},
+		// A single attempt: the fallback must not count as a retry
+		Retry: RetryPolicy{MaxAttempts: 1},
+	}, Deps{
+		DB:     database,
+		Claude: claudeClient,
+		VCS:    jjClient,
+	})
+
+	events := runLoop(t, loop)
+
+	var fellBack bool
+	for _, e := range events {
+		if e.Type == EventClaudeRetry && strings.Contains(e.Message, "primary is unavailable, retrying on secondary") {
+			fellBack = true
+		}
+		if e.Type == EventError {
+			t.Errorf("unexpected error event: %s", e.Message)
+		}
+	}
+	if !fellBack {
+		t.Error("expected a fallback retry event")
+	}
+
+	// Developer on primary, then secondary; reviewer on primary, then secondary
+	if want := []string{"primary", "secondary", "primary", "secondary"}; !slices.Equal(models, want) {
+		t.Errorf("models = %v, want %v", models, want)
+	}
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range sessions {
+		if s.Model != "secondary" {
+			t.Errorf("%s session model = %q, want secondary", s.AgentType, s.Model)
+		}
+	}
+}
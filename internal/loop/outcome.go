@@ -0,0 +1,80 @@
+package loop
+
+// Outcome classifies how a run ended, so scripts and CI can branch on it
+// (see Loop.Outcome).
+type Outcome string
+
+const (
+	OutcomeCompleted     Outcome = "completed"      // The plan completed
+	OutcomePaused        Outcome = "paused"         // The plan was left paused to be resumed (a request, a budget, an interruption)
+	OutcomeMaxIterations Outcome = "max_iterations" // A limit stopped the plan before the developer signaled done
+	OutcomeNotApproved   Outcome = "not_approved"   // A limit stopped the plan while the reviewer kept rejecting the developer's done signals
+	OutcomeGateFailures  Outcome = "gate_failures"  // A limit stopped the plan with its checks or test gates failing
+	OutcomeProviderError Outcome = "provider_error" // Claude sessions kept failing
+	OutcomeFailed        Outcome = "failed"         // The run ended with any other error
+)
+
+// ExitCode returns the process exit code for the outcome. The codes are a
+// stable contract for scripts: 0 completed, 1 failed, 2 max iterations
+// without done, 3 reviewer never approved, 4 gate failures, 5 provider
+// errors, 6 paused.
+func (o Outcome) ExitCode() int {
+	switch o {
+	case OutcomeCompleted:
+		return 0
+	case OutcomeMaxIterations:
+		return 2
+	case OutcomeNotApproved:
+		return 3
+	case OutcomeGateFailures:
+		return 4
+	case OutcomeProviderError:
+		return 5
+	case OutcomePaused:
+		return 6
+	default:
+		return 1
+	}
+}
+
+// ProviderError is a Claude session failure that retries didn't overcome:
+// the provider failed, rather than the loop.
+type ProviderError struct {
+	Err error
+}
+
+func (e *ProviderError) Error() string { return e.Err.Error() }
+
+func (e *ProviderError) Unwrap() error { return e.Err }
+
+// Outcome returns how the last run ended ("" if it hasn't ended, or failed
+// before it started).
+func (l *Loop) Outcome() Outcome {
+	l.iterationMu.RLock()
+	defer l.iterationMu.RUnlock()
+	return l.outcome
+}
+
+// setOutcome records how the run ended.
+func (l *Loop) setOutcome(o Outcome) {
+	l.iterationMu.Lock()
+	l.outcome = o
+	l.iterationMu.Unlock()
+}
+
+// limitOutcome classifies a run a limit stopped: an extreme mode run whose
+// developer and reviewer agreed the plan is done completed it, with the
+// bonus iterations on top; otherwise by what failed in its last iteration,
+// then by whether the reviewer ever rejected a done signal.
+func (l *Loop) limitOutcome() Outcome {
+	switch {
+	case l.extremeRounds > 0:
+		return OutcomeCompleted
+	case l.iterationFailure != "":
+		return l.iterationFailure
+	case l.doneRejected:
+		return OutcomeNotApproved
+	default:
+		return OutcomeMaxIterations
+	}
+}
@@ -0,0 +1,88 @@
+package loop
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+func TestOutcome_ExitCode(t *testing.T) {
+	tests := []struct {
+		outcome Outcome
+		want    int
+	}{
+		{OutcomeCompleted, 0},
+		{OutcomeFailed, 1},
+		{OutcomeMaxIterations, 2},
+		{OutcomeNotApproved, 3},
+		{OutcomeGateFailures, 4},
+		{OutcomeProviderError, 5},
+		{OutcomePaused, 6},
+	}
+	for _, tt := range tests {
+		if got := tt.outcome.ExitCode(); got != tt.want {
+			t.Errorf("%s.ExitCode() = %d, want %d", tt.outcome, got, tt.want)
+		}
+	}
+}
+
+func TestLoop_Outcome(t *testing.T) {
+	const (
+		running  = "## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"
+		done     = "## Progress\nCompleted\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"
+		approved = "## Progress\nReviewed\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!"
+		rejected = "## Progress\nReviewed\n\n### Verdict\nNEEDS_WORK\n\nAdd the missing test"
+	)
+
+	tests := []struct {
+		name      string
+		developer string // "" = the claude CLI fails
+		reviewer  string
+		checks    []Check
+		want      Outcome
+	}{
+		{name: "completed", developer: done, reviewer: approved, want: OutcomeCompleted},
+		{name: "max iterations", developer: running, reviewer: rejected, want: OutcomeMaxIterations},
+		{name: "reviewer never approved", developer: done, reviewer: rejected, want: OutcomeNotApproved},
+		{
+			name: "gate failures", developer: done, reviewer: rejected,
+			checks: []Check{{Name: "tests", Gate: &fakeTestGate{}}},
+			want:   OutcomeGateFailures,
+		},
+		{name: "provider error", want: OutcomeProviderError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := setupTestDB(t)
+			plan := createTestPlan(t, database, "Test plan content")
+
+			devClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+			if tt.developer == "" {
+				devClient.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+					return exec.CommandContext(ctx, "false")
+				})
+			} else {
+				devClient.SetCommandCreator(mockClaudeCreator(tt.developer))
+			}
+			reviewerClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+			reviewerClient.SetCommandCreator(mockClaudeCreator(tt.reviewer))
+			jjClient := jj.NewClient("/tmp")
+			jjClient.SetCommandRunner(mockJJRunnerEmpty())
+
+			loop := New(Config{PlanID: plan.ID, MaxIterations: 2, WorkDir: "/tmp"}, Deps{
+				DB:             database,
+				Claude:         devClient,
+				ReviewerClaude: reviewerClient,
+				VCS:            jjClient,
+				Checks:         tt.checks,
+			})
+			runLoop(t, loop)
+			if got := loop.Outcome(); got != tt.want {
+				t.Errorf("Outcome() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package loop
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/plandiff"
+	"github.com/gerunddev/ralph/internal/planspec"
+)
+
+// HashPlanFile returns the hash recorded for a plan file's content, used to
+// detect edits made to the file while a plan runs.
+func HashPlanFile(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// snapshotPlanFile records the plan file's hash for plans created before
+// snapshots were taken, so only edits made from now on are reported.
+func (l *Loop) snapshotPlanFile() {
+	if !l.cfg.WatchPlan || l.plan.OriginPath == "" || l.plan.OriginHash != "" {
+		return
+	}
+	data, err := os.ReadFile(l.plan.OriginPath)
+	if err != nil {
+		log.Debug("plan file not readable, not watching it", "path", l.plan.OriginPath, "error", err)
+		return
+	}
+	l.plan.OriginHash = HashPlanFile(string(data))
+	if err := l.deps.DB.UpdatePlanOriginHash(l.cfg.PlanID, l.plan.OriginHash); err != nil {
+		log.Warn("failed to record plan file hash", "error", err)
+	}
+}
+
+// checkPlanFile compares the plan file with its last snapshot. When it was
+// edited, it emits EventPlanChanged with a diff against the plan the agents
+// work from and, with MergePlanEdits, replaces the stored plan and notes the
+// change in the next developer prompt.
+func (l *Loop) checkPlanFile() {
+	if !l.cfg.WatchPlan || l.plan.OriginPath == "" || l.plan.OriginHash == "" {
+		return
+	}
+	data, err := os.ReadFile(l.plan.OriginPath)
+	if err != nil {
+		log.Debug("failed to read plan file", "path", l.plan.OriginPath, "error", err)
+		return
+	}
+	hash := HashPlanFile(string(data))
+	if hash == l.plan.OriginHash {
+		return
+	}
+	content, err := planspec.Content(l.plan.OriginPath, data)
+	if err != nil {
+		// Mid-edit files are often briefly invalid; wait for the next save
+		log.Warn("edited plan file is invalid, not comparing it", "path", l.plan.OriginPath, "error", err)
+		return
+	}
+
+	diff := plandiff.Diff(l.plan.Content, content)
+	if diff == "" || !l.cfg.MergePlanEdits {
+		if err := l.deps.DB.UpdatePlanOriginHash(l.cfg.PlanID, hash); err != nil {
+			log.Warn("failed to record plan file hash", "error", err)
+			return
+		}
+		l.plan.OriginHash = hash
+		if diff == "" {
+			// Edited back to the plan the agents already have
+			return
+		}
+		event := NewEvent(EventPlanChanged, l.iteration, l.effectiveMaxIter(),
+			fmt.Sprintf("Plan file %s was edited; not merged (set plan_refresh to merge to apply edits)", l.plan.OriginPath))
+		event.Diff = diff
+		l.emit(event)
+		return
+	}
+
+	if err := l.deps.DB.UpdatePlanContent(l.cfg.PlanID, content, hash); err != nil {
+		log.Warn("failed to merge plan file edits", "error", err)
+		return
+	}
+	// Diff against the plan the developer last saw, in case an earlier
+	// merge hasn't reached a developer prompt yet
+	if l.planBeforeEdit == "" {
+		l.planBeforeEdit = l.plan.Content
+	}
+	l.plan.Content = content
+	l.plan.OriginHash = hash
+	l.planUpdate = plandiff.Diff(l.planBeforeEdit, content)
+
+	event := NewEvent(EventPlanChanged, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Plan file %s was edited; merged into the plan", l.plan.OriginPath))
+	event.Diff = diff
+	l.emit(event)
+}
+
+// takePlanUpdate returns the diff of plan edits merged since the last
+// developer prompt, and clears it.
+func (l *Loop) takePlanUpdate() string {
+	update := l.planUpdate
+	l.planUpdate = ""
+	l.planBeforeEdit = ""
+	return update
+}
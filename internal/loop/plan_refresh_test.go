@@ -0,0 +1,128 @@
+package loop
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newPlanRefreshLoop returns a loop over a plan whose origin is a temporary
+// plan file holding content.
+func newPlanRefreshLoop(t *testing.T, cfg Config, content string) (*Loop, string) {
+	t.Helper()
+	database := setupTestDB(t)
+	path := filepath.Join(t.TempDir(), "plan.md")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plan := createTestPlan(t, database, content)
+	plan.OriginPath = path
+
+	cfg.PlanID = plan.ID
+	cfg.WatchPlan = true
+	l := New(cfg, Deps{DB: database})
+	l.plan = plan
+	l.snapshotPlanFile()
+	return l, path
+}
+
+// planChangedEvents drains the loop's buffered events and returns the
+// EventPlanChanged ones.
+func planChangedEvents(l *Loop) []Event {
+	var changed []Event
+	for {
+		select {
+		case e := <-l.Events():
+			if e.Type == EventPlanChanged {
+				changed = append(changed, e)
+			}
+		default:
+			return changed
+		}
+	}
+}
+
+func TestCheckPlanFile_Detect(t *testing.T) {
+	l, path := newPlanRefreshLoop(t, Config{}, "# Plan\nAdd login\n")
+
+	l.checkPlanFile()
+	if events := planChangedEvents(l); len(events) != 0 {
+		t.Fatalf("expected no event for an unchanged file, got %+v", events)
+	}
+
+	if err := os.WriteFile(path, []byte("# Plan\nAdd login\nAdd logout\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l.checkPlanFile()
+	events := planChangedEvents(l)
+	if len(events) != 1 || !strings.Contains(events[0].Diff, "+Add logout") || !strings.Contains(events[0].Message, "not merged") {
+		t.Fatalf("expected one unmerged plan change with the diff, got %+v", events)
+	}
+
+	// Reported once, and the stored plan is untouched
+	l.checkPlanFile()
+	if events := planChangedEvents(l); len(events) != 0 {
+		t.Errorf("expected the edit to be reported once, got %+v", events)
+	}
+	stored, err := l.deps.DB.GetPlan(l.cfg.PlanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Content != "# Plan\nAdd login\n" {
+		t.Errorf("stored plan should be unchanged, got %q", stored.Content)
+	}
+	if l.takePlanUpdate() != "" {
+		t.Error("developer should not be told about unmerged edits")
+	}
+}
+
+func TestCheckPlanFile_Merge(t *testing.T) {
+	l, path := newPlanRefreshLoop(t, Config{MergePlanEdits: true}, "# Plan\nAdd login\n")
+
+	if err := os.WriteFile(path, []byte("# Plan\nAdd login\nAdd logout\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l.checkPlanFile()
+	if err := os.WriteFile(path, []byte("# Plan\nAdd login\nAdd logout\nAdd signup\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l.checkPlanFile()
+
+	if events := planChangedEvents(l); len(events) != 2 || !strings.Contains(events[1].Message, "merged into the plan") {
+		t.Fatalf("expected two merged plan changes, got %+v", events)
+	}
+	stored, err := l.deps.DB.GetPlan(l.cfg.PlanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Plan\nAdd login\nAdd logout\nAdd signup\n"
+	if stored.Content != want || l.plan.Content != want {
+		t.Errorf("plan content = %q (stored %q), want %q", l.plan.Content, stored.Content, want)
+	}
+	if stored.OriginHash != HashPlanFile(want) {
+		t.Error("expected the stored hash to match the merged file")
+	}
+
+	// Both edits reach the next developer prompt, once
+	update := l.takePlanUpdate()
+	if !strings.Contains(update, "+Add logout") || !strings.Contains(update, "+Add signup") {
+		t.Errorf("plan update should cover both edits, got %q", update)
+	}
+	if l.takePlanUpdate() != "" {
+		t.Error("plan update should be cleared once taken")
+	}
+}
+
+func TestSnapshotPlanFile_KeepsExistingHash(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "content")
+	plan.OriginHash = "recorded"
+
+	l := New(Config{PlanID: plan.ID, WatchPlan: true}, Deps{DB: database})
+	l.plan = plan
+	l.snapshotPlanFile()
+	if l.plan.OriginHash != "recorded" {
+		t.Errorf("OriginHash = %q, want the recorded hash kept", l.plan.OriginHash)
+	}
+}
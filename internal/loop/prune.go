@@ -0,0 +1,38 @@
+package loop
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// PromptBudget is the most tokens a prompt for model may take: a session is
+// stopped once it uses ContextLimitPercent of the context window, so a
+// larger prompt would be stopped on its first message.
+func PromptBudget(model string) int {
+	return int(float64(claude.GetContextWindowForModel(model)) * claude.ContextLimitPercent / 100)
+}
+
+// fitPrompt builds a prompt with build, cutting sections to fit the
+// client's prompt budget as agent.FitPrompt does, and warns with
+// EventContextPruned when anything was cut.
+func (l *Loop) fitPrompt(client *claude.Client, sections agent.PrunableSections, build func() (string, error)) (string, error) {
+	budget := PromptBudget(client.Model())
+	prompt, omitted, err := agent.FitPrompt(budget, sections, build)
+	if err != nil || len(omitted) == 0 {
+		return prompt, err
+	}
+
+	parts := make([]string, len(omitted))
+	for i, o := range omitted {
+		parts[i] = fmt.Sprintf("%d %s lines (~%d tokens)", o.Lines, o.Section, o.Tokens)
+	}
+	log.Warn("prompt exceeded the context budget, pruned it",
+		"budget", budget, "tokens", agent.EstimateTokens(prompt), "omitted", strings.Join(parts, ", "))
+	l.emit(NewEvent(EventContextPruned, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Prompt over the %d-token context budget: omitted %s", budget, strings.Join(parts, ", "))))
+	return prompt, nil
+}
@@ -0,0 +1,40 @@
+package loop
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/claude"
+)
+
+func TestLoop_FitPrompt(t *testing.T) {
+	client := claude.NewClient(claude.ClientConfig{Model: "claude-sonnet-4-5", MaxTurns: 1})
+	if got := PromptBudget(client.Model()); got != 100000 {
+		t.Fatalf("PromptBudget() = %d, want half the 200k window", got)
+	}
+
+	loop := New(Config{PlanID: "plan-1", MaxIterations: 1}, Deps{DB: setupTestDB(t), Claude: client})
+	events := loop.Events()
+
+	learnings := strings.Repeat("- remember to run the linter before committing\n", 20000)
+	prompt, err := loop.fitPrompt(client, agent.PrunableSections{Learnings: &learnings}, func() (string, error) {
+		return "## Learnings\n" + learnings, nil
+	})
+	if err != nil {
+		t.Fatalf("fitPrompt() error: %v", err)
+	}
+	if agent.EstimateTokens(prompt) > 100000 || !strings.Contains(prompt, "older learnings lines omitted to fit the context window") {
+		t.Errorf("prompt is %d tokens, want it cut to the budget with a note", agent.EstimateTokens(prompt))
+	}
+
+	select {
+	case event := <-events:
+		if event.Type != EventContextPruned || !strings.Contains(event.Message, "learnings lines") {
+			t.Errorf("event = %+v, want %s naming the learnings", event, EventContextPruned)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event emitted")
+	}
+}
@@ -0,0 +1,90 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// quietHoursTick is how often countdown events are emitted during quiet
+// hours (variable for tests).
+var quietHoursTick = time.Minute
+
+// QuietHours is a daily window, in local time, during which the loop
+// doesn't start iterations. A window whose End is before its Start wraps
+// past midnight; one whose Start and End are equal is disabled.
+type QuietHours struct {
+	Start time.Duration // Offset from midnight the window starts at
+	End   time.Duration // Offset from midnight the window ends at
+}
+
+// Until returns when the quiet hours that t falls in end, or the zero time
+// if t is outside them. Start and End are wall-clock times, so the window
+// keeps its hours on days a DST change makes longer or shorter.
+func (q QuietHours) Until(t time.Time) time.Time {
+	if q.Start == q.End {
+		return time.Time{}
+	}
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+
+	switch {
+	case q.Start < q.End:
+		if offset >= q.Start && offset < q.End {
+			return wallClock(t, 0, q.End)
+		}
+	case offset >= q.Start:
+		return wallClock(t, 1, q.End)
+	case offset < q.End:
+		return wallClock(t, 0, q.End)
+	}
+	return time.Time{}
+}
+
+// wallClock returns the time at offset from midnight, as read on a clock,
+// days after t's day, in t's location.
+func wallClock(t time.Time, days int, offset time.Duration) time.Time {
+	h, m, s := int(offset/time.Hour), int(offset%time.Hour/time.Minute), int(offset%time.Minute/time.Second)
+	return time.Date(t.Year(), t.Month(), t.Day()+days, h, m, s, 0, t.Location())
+}
+
+// waitForQuietHours holds the loop between iterations while it is quiet
+// hours, emitting EventQuietHours countdown events. The plan is recorded as
+// paused for the wait, so a run that dies during it leaves the plan to be
+// resumed rather than abandoned, and as running again once it ends. Time
+// spent waiting doesn't count against MaxDuration. It returns early only if
+// ctx is done.
+func (l *Loop) waitForQuietHours(ctx context.Context) error {
+	until := l.cfg.QuietHours.Until(time.Now())
+	if until.IsZero() {
+		return nil
+	}
+
+	start := time.Now()
+	reason := fmt.Sprintf("Quiet hours until %s", until.Format("15:04"))
+	l.pausePlan(reason)
+	log.Info("pausing for quiet hours", "until", until)
+
+	for remaining := time.Until(until); remaining > 0; remaining = time.Until(until) {
+		event := NewEvent(EventQuietHours, l.iteration, l.effectiveMaxIter(),
+			fmt.Sprintf("%s, resuming in %s", reason, remaining.Round(time.Second)))
+		event.Until = until
+		l.emit(event)
+
+		if err := sleepContext(ctx, min(remaining, quietHoursTick)); err != nil {
+			return err
+		}
+	}
+
+	if err := l.deps.DB.UpdatePlanStatus(l.cfg.PlanID, db.PlanStatusRunning); err != nil {
+		log.Warn("failed to update plan status", "error", err)
+	}
+	if !l.deadline.IsZero() {
+		l.deadline = l.deadline.Add(time.Since(start))
+	}
+	l.emit(NewEvent(EventQuietHours, l.iteration, l.effectiveMaxIter(), "Quiet hours over, resuming"))
+	return nil
+}
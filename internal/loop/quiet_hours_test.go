@@ -0,0 +1,126 @@
+package loop
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+func TestQuietHours_Until(t *testing.T) {
+	day := func(hour, minute int) time.Time {
+		return time.Date(2026, 3, 10, hour, minute, 0, 0, time.Local)
+	}
+	overnight := QuietHours{Start: 23 * time.Hour, End: 7 * time.Hour}
+	daytime := QuietHours{Start: 12 * time.Hour, End: 13*time.Hour + 30*time.Minute}
+
+	tests := []struct {
+		name  string
+		quiet QuietHours
+		at    time.Time
+		want  time.Time
+	}{
+		{"before an overnight window", overnight, day(22, 59), time.Time{}},
+		{"start of an overnight window", overnight, day(23, 0), day(7, 0).AddDate(0, 0, 1)},
+		{"after midnight", overnight, day(3, 15), day(7, 0)},
+		{"end of an overnight window", overnight, day(7, 0), time.Time{}},
+		{"inside a daytime window", daytime, day(12, 45), day(13, 30)},
+		{"after a daytime window", daytime, day(13, 30), time.Time{}},
+		{"disabled", QuietHours{}, day(0, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.quiet.Until(tt.at); !got.Equal(tt.want) {
+				t.Errorf("Until(%s) = %s, want %s", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuietHours_UntilAcrossDSTChange(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	overnight := QuietHours{Start: 23 * time.Hour, End: 7 * time.Hour}
+
+	// Clocks go forward at 2:00 on March 8, 2026: the night is an hour short
+	got := overnight.Until(time.Date(2026, 3, 7, 23, 30, 0, 0, loc))
+	if want := time.Date(2026, 3, 8, 7, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("Until() = %s, want %s", got, want)
+	}
+	// Clocks go back at 2:00 on November 1, 2026: the night is an hour long
+	got = overnight.Until(time.Date(2026, 11, 1, 6, 30, 0, 0, loc))
+	if want := time.Date(2026, 11, 1, 7, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("Until() = %s, want %s", got, want)
+	}
+}
+
+func TestLoop_WaitsOutQuietHours(t *testing.T) {
+	oldTick := quietHoursTick
+	quietHoursTick = 100 * time.Millisecond
+	t.Cleanup(func() { quietHoursTick = oldTick })
+
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput("## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"))
+	}
+
+	// Quiet hours from a second ago to a second from now
+	now := time.Now()
+	offset := now.Sub(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+	const day = 24 * time.Hour
+	quiet := QuietHours{Start: (offset - time.Second + day) % day, End: (offset + time.Second) % day}
+
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 1, WorkDir: "/tmp", QuietHours: quiet}, testDeps(database, creator, mockJJRunnerEmpty()))
+
+	var events []Event
+	var pausedStatus db.PlanStatus
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for event := range loop.Events() {
+			if event.Type == EventQuietHours && !event.Until.IsZero() && pausedStatus == "" {
+				if p, err := database.GetPlan(plan.ID); err == nil {
+					pausedStatus = p.Status
+				}
+			}
+			events = append(events, event)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := loop.Run(ctx); err != nil {
+		t.Fatalf("loop.Run() error: %v", err)
+	}
+	wg.Wait()
+
+	waited, over := false, false
+	for _, e := range events {
+		switch e.Type {
+		case EventQuietHours:
+			if e.Until.IsZero() {
+				over = true
+			} else {
+				waited = true
+			}
+		case EventClaudeStart:
+			if !over {
+				t.Fatal("expected the iteration to start once quiet hours were over")
+			}
+		}
+	}
+	if !waited || !over {
+		t.Errorf("expected quiet hours countdown and end events, got waited=%v over=%v", waited, over)
+	}
+	if pausedStatus != db.PlanStatusPaused {
+		t.Errorf("expected the plan to be paused during quiet hours, got %q", pausedStatus)
+	}
+}
@@ -0,0 +1,80 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// rateLimitProvider keys the cooldowns of Claude's rate limits.
+const rateLimitProvider = "claude"
+
+// maxRateLimitWaits bounds the rate-limit resets a session waits out
+// without using up an attempt.
+const maxRateLimitWaits = 10
+
+// rateLimitTick is how often countdown events are emitted while waiting for
+// a rate limit to reset (variable for tests).
+var rateLimitTick = 10 * time.Second
+
+// rateLimitReset returns when the rate limit err reports resets, if it does
+// so within the retry policy's MaxRateLimitWait.
+func (l *Loop) rateLimitReset(err error) (time.Time, bool) {
+	if l.cfg.Retry.MaxRateLimitWait <= 0 {
+		return time.Time{}, false
+	}
+	limit, ok := claude.ParseRateLimit(err, time.Now())
+	if !ok || limit.ResetAt.IsZero() || time.Until(limit.ResetAt) > l.cfg.Retry.MaxRateLimitWait {
+		return time.Time{}, false
+	}
+	return limit.ResetAt, true
+}
+
+// startCooldown holds off Claude sessions until resumeAt. The cooldown is
+// stored so other runs against the same database wait too.
+func (l *Loop) startCooldown(resumeAt time.Time, cause error) {
+	log.Warn("Claude rate limit hit, cooling down", "resumeAt", resumeAt, "error", cause)
+	if resumeAt.After(l.cooldownUntil) {
+		l.cooldownUntil = resumeAt
+	}
+	err := l.deps.DB.SetRateLimitCooldown(&db.RateLimitCooldown{
+		Provider: rateLimitProvider,
+		ResumeAt: resumeAt,
+		Reason:   truncateString(cause.Error(), 500),
+	})
+	if err != nil {
+		log.Warn("failed to store rate limit cooldown", "error", err)
+	}
+}
+
+// waitForCooldown waits until any rate-limit cooldown is over, emitting
+// EventRateLimitWait countdown events. It returns early only if ctx is done.
+func (l *Loop) waitForCooldown(ctx context.Context) error {
+	resumeAt := l.cooldownUntil
+	if cooldown, err := l.deps.DB.GetRateLimitCooldown(rateLimitProvider); err != nil {
+		log.Warn("failed to get rate limit cooldown", "error", err)
+	} else if cooldown != nil && cooldown.ResumeAt.After(resumeAt) {
+		resumeAt = cooldown.ResumeAt
+	}
+	if !time.Now().Before(resumeAt) {
+		return nil
+	}
+
+	for remaining := time.Until(resumeAt); remaining > 0; remaining = time.Until(resumeAt) {
+		event := NewEvent(EventRateLimitWait, l.iteration, l.effectiveMaxIter(),
+			fmt.Sprintf("Rate limited, resuming in %s", remaining.Round(time.Second)))
+		event.Until = resumeAt
+		l.emit(event)
+
+		if err := sleepContext(ctx, min(remaining, rateLimitTick)); err != nil {
+			return err
+		}
+	}
+
+	l.emit(NewEvent(EventRateLimitWait, l.iteration, l.effectiveMaxIter(), "Rate limit reset, resuming"))
+	return nil
+}
@@ -0,0 +1,132 @@
+package loop
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+// runRateLimitLoop runs one iteration in which the developer's first
+// attempt fails with the given stderr (none if empty), and returns the
+// events it emitted.
+func runRateLimitLoop(t *testing.T, database *db.DB, plan *db.Plan, stderr string, retry RetryPolicy) []Event {
+	t.Helper()
+	oldTick := rateLimitTick
+	rateLimitTick = 100 * time.Millisecond
+	t.Cleanup(func() { rateLimitTick = oldTick })
+
+	callCount := 0
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		callCount++
+		if callCount == 1 && stderr != "" {
+			return exec.CommandContext(ctx, "sh", "-c", "echo '"+stderr+"' >&2; exit 1")
+		}
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput("## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"))
+	}
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 1, WorkDir: "/tmp", Retry: retry}, testDeps(database, creator, mockJJRunnerEmpty()))
+	return runLoop(t, loop)
+}
+
+// rateLimitWaits returns the EventRateLimitWait events.
+func rateLimitWaits(events []Event) []Event {
+	var waits []Event
+	for _, e := range events {
+		if e.Type == EventRateLimitWait {
+			waits = append(waits, e)
+		}
+	}
+	return waits
+}
+
+func TestLoop_WaitsOutRateLimitReset(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	// No retries: a reported reset is waited out without using an attempt
+	start := time.Now()
+	events := runRateLimitLoop(t, database, plan, "API Error: 429 rate_limit_error (retry-after: 1)",
+		RetryPolicy{MaxAttempts: 1, MaxRateLimitWait: time.Minute})
+
+	for _, e := range events {
+		if e.Type == EventError || e.Type == EventFailed {
+			t.Fatalf("unexpected %s event: %s", e.Type, e.Message)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("expected the loop to wait for the reset, finished in %s", elapsed)
+	}
+
+	waits := rateLimitWaits(events)
+	if len(waits) < 2 {
+		t.Fatalf("expected countdown events, got %d", len(waits))
+	}
+	if waits[0].Until.IsZero() {
+		t.Error("expected countdown events to carry the resume time")
+	}
+	if last := waits[len(waits)-1]; !last.Until.IsZero() {
+		t.Errorf("expected a final event once the wait is over, got %q", last.Message)
+	}
+
+	cooldown, err := database.GetRateLimitCooldown(rateLimitProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cooldown == nil || cooldown.Reason == "" {
+		t.Errorf("expected the cooldown to be stored, got %+v", cooldown)
+	}
+}
+
+func TestLoop_RateLimitResetBeyondMaxWaitFailsIteration(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	events := runRateLimitLoop(t, database, plan, "Claude AI usage limit reached|4102444800",
+		RetryPolicy{MaxAttempts: 1, MaxRateLimitWait: time.Hour})
+
+	if len(rateLimitWaits(events)) != 0 {
+		t.Error("expected no wait for a reset beyond the max wait")
+	}
+	failed := false
+	for _, e := range events {
+		if e.Type == EventError {
+			failed = true
+		}
+	}
+	if !failed {
+		t.Error("expected the iteration to fail")
+	}
+}
+
+func TestLoop_WaitsForStoredCooldown(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	// Another run hit the rate limit
+	if err := database.SetRateLimitCooldown(&db.RateLimitCooldown{
+		Provider: rateLimitProvider,
+		ResumeAt: time.Now().Add(300 * time.Millisecond),
+		Reason:   "429",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	events := runRateLimitLoop(t, database, plan, "", RetryPolicy{})
+
+	waited := false
+	for _, e := range events {
+		switch e.Type {
+		case EventRateLimitWait:
+			waited = true
+		case EventClaudeStart:
+			if !waited {
+				t.Fatal("expected the wait before the first Claude session")
+			}
+		}
+	}
+	if !waited {
+		t.Error("expected the loop to wait for the stored cooldown")
+	}
+}
@@ -0,0 +1,107 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// canRebut reports whether the review feedback from sessionID may be
+// disputed: feedback upheld on re-evaluation of a rebuttal is final.
+func (l *Loop) canRebut(sessionID string) bool {
+	rebuttal, err := l.deps.DB.GetRebuttalBySession(sessionID)
+	if err != nil {
+		log.Warn("failed to check feedback for an earlier rebuttal", "session", sessionID, "error", err)
+		return false
+	}
+	return rebuttal == nil
+}
+
+// handleRebuttal has a reviewer re-evaluate the point of its feedback the
+// developer disputed, against the code as it stands, and records the
+// exchange. A withdrawn point, as the rebuttal states it, is passed on to
+// this iteration's review, the rest of the feedback still standing; upheld
+// feedback is stored, final, for the next developer session, and
+// handleRebuttal reports it so the iteration ends without a review.
+func (l *Loop) handleRebuttal(ctx context.Context, feedback, rebuttal string, disputed []int, diff string) (upheld bool, err error) {
+	if len(diff) > maxDiffBytes {
+		diff = truncateDiff(diff)
+	}
+	l.startSessionTimer()
+	prompt, err := agent.BuildRebuttalReviewPrompt(agent.RebuttalReviewContext{
+		PlanContent: l.plan.Content,
+		Feedback:    feedback,
+		Rebuttal:    rebuttal,
+		Locale:      l.cfg.Locale,
+		DiffOutput:  diff,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to build rebuttal review prompt: %w", err)
+	}
+	l.promptBuilt()
+
+	l.emit(NewEvent(EventRebuttalStart, l.iteration, l.effectiveMaxIter(),
+		"Developer disputed the review feedback, starting re-evaluation"))
+	l.emit(NewPromptBuiltEvent(l.iteration, l.effectiveMaxIter(), prompt))
+
+	sessionID := uuid.New().String()
+	session := &db.PlanSession{
+		ID:          sessionID,
+		PlanID:      l.cfg.PlanID,
+		Iteration:   l.iteration,
+		InputPrompt: prompt,
+		Status:      db.PlanSessionRunning,
+		AgentType:   db.LoopAgentRebuttalReviewer,
+	}
+	if err := l.deps.DB.CreatePlanSession(session); err != nil {
+		return false, fmt.Errorf("failed to create rebuttal reviewer session: %w", err)
+	}
+
+	output, err := l.runClaudeSession(ctx, sessionID, prompt, l.reviewerClient())
+	if err != nil {
+		return false, fmt.Errorf("rebuttal reviewer agent failed: %w", err)
+	}
+	parseStart := time.Now()
+	result := l.parseOutput(sessionID, output, string(db.LoopAgentRebuttalReviewer))
+	l.finishSessionTimer(sessionID, time.Since(parseStart))
+
+	record := &db.Rebuttal{
+		PlanID:            l.cfg.PlanID,
+		Iteration:         l.iteration,
+		FeedbackSessionID: l.feedbackSessionID,
+		SessionID:         sessionID,
+		Feedback:          feedback,
+		Rebuttal:          rebuttal,
+		Accepted:          result.RebuttalAccepted,
+		Response:          result.RebuttalResponse,
+	}
+	if err := l.deps.DB.CreateRebuttal(record); err != nil {
+		log.Warn("failed to record rebuttal", "error", err)
+	}
+
+	if result.RebuttalAccepted {
+		if err := l.deps.DB.WithdrawFeedbackItems(l.cfg.PlanID, l.feedbackSessionID, disputed, l.iteration); err != nil {
+			log.Warn("failed to withdraw feedback items", "error", err)
+		}
+		l.withdrawnFeedback = fmt.Sprintf("Disputed point:\n%s\n\nRe-evaluation:\n%s", rebuttal, result.RebuttalResponse)
+		l.emit(NewEvent(EventRebuttalAccepted, l.iteration, l.effectiveMaxIter(),
+			"Reviewer withdrew the disputed point"))
+		return false, nil
+	}
+
+	final := fmt.Sprintf("The reviewer re-evaluated your rebuttal and upheld its feedback. "+
+		"This is final: address the feedback below; it cannot be disputed again.\n\n"+
+		"Feedback:\n%s\n\nReviewer's response to your rebuttal:\n%s", feedback, result.RebuttalResponse)
+	if err := l.storeReviewerFeedback(sessionID, final); err != nil {
+		log.Warn("failed to store upheld reviewer feedback", "error", err)
+	}
+	l.emit(NewEvent(EventRebuttalRejected, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Reviewer upheld its feedback: %s", truncateString(result.RebuttalResponse, 100))))
+	return true, nil
+}
@@ -0,0 +1,150 @@
+package loop
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+const rebuttalDevOutput = "## Progress\nWorking\n\n## Learnings\nNone\n\n" +
+	"## Rebuttal\nThe nil check is unneeded: New never returns nil.\n\n---\n\n## Status\nRUNNING"
+
+// rebuttalRun is the outcome of a loop that starts with stored reviewer
+// feedback, whose Claude sessions print outputs in turn (the last one
+// repeats).
+type rebuttalRun struct {
+	database *db.DB
+	plan     *db.Plan
+	events   []Event
+}
+
+func runRebuttalLoop(t *testing.T, maxIterations int, outputs ...string) *rebuttalRun {
+	t.Helper()
+	run := &rebuttalRun{database: setupTestDB(t)}
+	run.plan = createTestPlan(t, run.database, "Test plan content")
+
+	if err := run.database.CreatePlanSession(&db.PlanSession{ID: "rev-0", PlanID: run.plan.ID, InputPrompt: "p", AgentType: db.LoopAgentReviewer}); err != nil {
+		t.Fatal(err)
+	}
+	if err := run.database.CreateReviewerFeedback(&db.ReviewerFeedback{PlanID: run.plan.ID, SessionID: "rev-0", Content: "1. Add a nil check in New\n2. Add tests for Close"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	calls := 0
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		mu.Lock()
+		defer mu.Unlock()
+		output := outputs[min(calls, len(outputs)-1)]
+		calls++
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput(output))
+	}
+
+	runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		return "", "", nil
+	}
+
+	loop := New(Config{
+		PlanID:        run.plan.ID,
+		MaxIterations: maxIterations,
+		WorkDir:       "/tmp",
+	}, testDeps(run.database, creator, runner))
+
+	run.events = runLoop(t, loop)
+	return run
+}
+
+// sessions returns the run's sessions, in order.
+func (r *rebuttalRun) sessions(t *testing.T) []*db.PlanSession {
+	t.Helper()
+	sessions, err := r.database.GetPlanSessionsByPlan(r.plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sessions[1:] // Without the seeded reviewer session
+}
+
+// has reports whether the run emitted an event of the type.
+func (r *rebuttalRun) has(eventType EventType) bool {
+	for _, e := range r.events {
+		if e.Type == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLoop_RebuttalUpheld(t *testing.T) {
+	upheld := "## Response\nNew returns nil when the config fails to load.\n\n### Verdict\nFEEDBACK_UPHELD"
+	run := runRebuttalLoop(t, 2, rebuttalDevOutput, upheld, rebuttalDevOutput)
+
+	sessions := run.sessions(t)
+	var types []db.LoopAgentType
+	for _, s := range sessions {
+		types = append(types, s.AgentType)
+	}
+	// Upheld feedback ends the first iteration without a review, and is
+	// final: the second developer's rebuttal goes to the normal review
+	want := []db.LoopAgentType{db.LoopAgentDeveloper, db.LoopAgentRebuttalReviewer, db.LoopAgentDeveloper, db.LoopAgentReviewer}
+	if len(types) != len(want) {
+		t.Fatalf("session types = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("session types = %v, want %v", types, want)
+		}
+	}
+	if !run.has(EventRebuttalStart) || !run.has(EventRebuttalRejected) || run.has(EventRebuttalAccepted) {
+		t.Errorf("expected a rejected rebuttal, got events %+v", run.events)
+	}
+
+	if !strings.Contains(sessions[0].InputPrompt, "## Rebuttal") {
+		t.Error("expected the first developer prompt to offer a rebuttal")
+	}
+	second := sessions[2].InputPrompt
+	if strings.Contains(second, "## Rebuttal") || !strings.Contains(second, "upheld its feedback") ||
+		!strings.Contains(second, "New returns nil when the config fails to load.") {
+		t.Errorf("expected the second developer prompt to carry the final feedback without a rebuttal offer:\n%s", second)
+	}
+
+	rebuttals, err := run.database.GetRebuttalsByPlan(run.plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rebuttals) != 1 || rebuttals[0].Accepted || rebuttals[0].FeedbackSessionID != "rev-0" ||
+		rebuttals[0].SessionID != sessions[1].ID || rebuttals[0].Rebuttal != "The nil check is unneeded: New never returns nil." {
+		t.Errorf("unexpected rebuttals %+v", rebuttals)
+	}
+}
+
+func TestLoop_RebuttalAccepted(t *testing.T) {
+	accepted := "## Response\nRight, New validates it.\n\n### Verdict\nREBUTTAL_ACCEPTED REBUTTAL_ACCEPTED!!!"
+	run := runRebuttalLoop(t, 1, rebuttalDevOutput, accepted, "## Progress\nReviewed\n\n### Verdict\nREVIEWER_FEEDBACK: Add tests")
+
+	sessions := run.sessions(t)
+	if len(sessions) != 3 || sessions[1].AgentType != db.LoopAgentRebuttalReviewer || sessions[2].AgentType != db.LoopAgentReviewer {
+		t.Fatalf("expected developer, rebuttal reviewer, and reviewer sessions, got %+v", sessions)
+	}
+	if !run.has(EventRebuttalAccepted) || run.has(EventRebuttalRejected) {
+		t.Errorf("expected an accepted rebuttal, got events %+v", run.events)
+	}
+	_, withdrawn, ok := strings.Cut(sessions[2].InputPrompt, "## Withdrawn Feedback")
+	if !ok || !strings.Contains(withdrawn, "New never returns nil") {
+		t.Errorf("expected the disputed point in the review prompt:\n%s", sessions[2].InputPrompt)
+	}
+	if strings.Contains(withdrawn, "Add tests for Close") {
+		t.Errorf("expected only the disputed point to be withdrawn:\n%s", withdrawn)
+	}
+
+	rebuttals, err := run.database.GetRebuttalsByPlan(run.plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rebuttals) != 1 || !rebuttals[0].Accepted || rebuttals[0].Response != "Right, New validates it." {
+		t.Errorf("unexpected rebuttals %+v", rebuttals)
+	}
+}
@@ -0,0 +1,67 @@
+package loop
+
+import (
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/redact"
+)
+
+// redactEvent returns a copy of event with secrets masked in its text and in
+// the Claude stream event it wraps. The loop keeps working with the
+// unredacted originals.
+func redactEvent(r *redact.Redactor, event Event) Event {
+	if r == nil {
+		return event
+	}
+
+	event.Message = r.String(event.Message)
+	event.Prompt = r.String(event.Prompt)
+	event.Output = r.String(event.Output)
+	event.Diff = r.String(event.Diff)
+	if event.ClaudeEvent != nil {
+		event.ClaudeEvent = redactStreamEvent(r, event.ClaudeEvent)
+	}
+	return event
+}
+
+// redactStreamEvent returns a copy of a Claude stream event with secrets
+// masked in every text field.
+func redactStreamEvent(r *redact.Redactor, e *claude.StreamEvent) *claude.StreamEvent {
+	c := *e
+	c.Raw = r.Bytes(e.Raw)
+	if e.Message != nil {
+		m := *e.Message
+		m.Text = r.String(m.Text)
+		c.Message = &m
+	}
+	if e.AssistantText != nil {
+		t := *e.AssistantText
+		t.Text = r.String(t.Text)
+		c.AssistantText = &t
+	}
+	if e.ToolUse != nil {
+		u := *e.ToolUse
+		u.Input = r.Bytes(u.Input)
+		c.ToolUse = &u
+	}
+	if e.ToolResult != nil {
+		res := *e.ToolResult
+		res.Content = r.String(res.Content)
+		c.ToolResult = &res
+	}
+	if e.Result != nil {
+		res := *e.Result
+		res.Result = r.String(res.Result)
+		c.Result = &res
+	}
+	if e.Error != nil {
+		er := *e.Error
+		er.Message = r.String(er.Message)
+		c.Error = &er
+	}
+	if e.System != nil {
+		sys := *e.System
+		sys.Message = r.String(sys.Message)
+		c.System = &sys
+	}
+	return &c
+}
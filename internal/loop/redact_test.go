@@ -0,0 +1,53 @@
+package loop
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/redact"
+)
+
+const testSecret = "sk-ant-REDACTED"
+
+func TestRedactEvent(t *testing.T) {
+	r, err := redact.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original := &claude.StreamEvent{
+		Type:       claude.EventToolResult,
+		Raw:        []byte(`{"content":"` + testSecret + `"}`),
+		ToolUse:    &claude.ToolUseContent{Name: "Bash", Input: json.RawMessage(`{"command":"export API_KEY=hunter2"}`)},
+		ToolResult: &claude.ToolResultContent{Content: "key: " + testSecret},
+	}
+	event := NewClaudeStreamEvent(1, 5, original)
+	event.Message = "using " + testSecret
+
+	got := redactEvent(r, event)
+
+	for name, text := range map[string]string{
+		"message":     got.Message,
+		"raw":         string(got.ClaudeEvent.Raw),
+		"tool input":  string(got.ClaudeEvent.ToolUse.Input),
+		"tool result": got.ClaudeEvent.ToolResult.Content,
+	} {
+		if strings.Contains(text, testSecret) || strings.Contains(text, "hunter2") {
+			t.Errorf("%s not redacted: %q", name, text)
+		}
+	}
+
+	// The loop's own copy is untouched
+	if original.ToolResult.Content != "key: "+testSecret || !strings.Contains(string(original.Raw), testSecret) {
+		t.Error("redactEvent modified the original stream event")
+	}
+}
+
+func TestRedactEvent_NilRedactor(t *testing.T) {
+	event := NewEvent(EventStarted, 1, 5, testSecret)
+	if got := redactEvent(nil, event); got.Message != testSecret {
+		t.Errorf("Message = %q, want it unchanged", got.Message)
+	}
+}
@@ -0,0 +1,87 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/parser"
+)
+
+// selfCheck returns a developer or reviewer session's output, reformatted
+// into the required sections when it is missing them and self-checking is
+// enabled. Each follow-up is counted against the session and runs as a
+// reformatter session of its own; when none produces the sections, the
+// original output is returned to be parsed leniently. Output that comes
+// with a status tool call is never reformatted.
+func (l *Loop) selfCheck(ctx context.Context, sessionID string, agentType db.LoopAgentType, output string) string {
+	if l.cfg.SelfCheckAttempts < 1 || l.statusReports[sessionID] != nil || !parser.ParseAgentOutput(output, string(agentType)).Malformed {
+		return output
+	}
+
+	prompt, err := agent.BuildReformatPrompt(agent.ReformatContext{
+		Reviewer: agentType == db.LoopAgentReviewer,
+		Output:   output,
+		Locale:   l.cfg.Locale,
+	})
+	if err != nil {
+		log.Warn("failed to build reformat prompt", "session", sessionID, "error", err)
+		return output
+	}
+
+	for attempt := 1; attempt <= l.cfg.SelfCheckAttempts; attempt++ {
+		if err := l.deps.DB.IncrementReformatAttempts(sessionID); err != nil {
+			log.Warn("failed to count reformat attempt", "session", sessionID, "error", err)
+		}
+		l.emit(NewEvent(EventReformatStart, l.iteration, l.effectiveMaxIter(),
+			fmt.Sprintf("The %s's output is missing its required sections, asking for it to be reformatted (attempt %d of %d)",
+				agentType, attempt, l.cfg.SelfCheckAttempts)))
+
+		reformatID := uuid.New().String()
+		session := &db.PlanSession{
+			ID:          reformatID,
+			PlanID:      l.cfg.PlanID,
+			Iteration:   l.iteration,
+			InputPrompt: prompt,
+			Status:      db.PlanSessionRunning,
+			AgentType:   db.LoopAgentReformatter,
+		}
+		if err := l.deps.DB.CreatePlanSession(session); err != nil {
+			log.Warn("failed to create reformatter session", "error", err)
+			break
+		}
+
+		reformatted, err := l.runClaudeSession(ctx, reformatID, prompt, l.reformatClient())
+		if err != nil {
+			log.Warn("reformat follow-up failed", "session", sessionID, "attempt", attempt, "error", err)
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		if !parser.ParseAgentOutput(reformatted, string(agentType)).Malformed {
+			l.emit(NewEvent(EventReformatted, l.iteration, l.effectiveMaxIter(),
+				fmt.Sprintf("Reformatted the %s's output", agentType)))
+			return reformatted
+		}
+	}
+
+	l.emit(NewEvent(EventReformatFailed, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Could not reformat the %s's output, using it as-is", agentType)))
+	return output
+}
+
+// reformatClient returns the Claude client for reformat follow-ups. A
+// follow-up only restates an answer, so it runs with the reviewer's CLI
+// options, never the developer's.
+func (l *Loop) reformatClient() *claude.Client {
+	if l.deps.ReformatClaude != nil {
+		return l.deps.ReformatClaude
+	}
+	return l.reviewerClient()
+}
@@ -0,0 +1,151 @@
+package loop
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/parser"
+)
+
+// runSelfCheckLoop runs a one-iteration loop with self-checking, whose
+// Claude sessions print outputs in turn (the last one repeats).
+func runSelfCheckLoop(t *testing.T, attempts int, outputs ...string) (*db.DB, *db.Plan, []Event) {
+	t.Helper()
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	var mu sync.Mutex
+	calls := 0
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		mu.Lock()
+		defer mu.Unlock()
+		output := outputs[min(calls, len(outputs)-1)]
+		calls++
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput(output))
+	}
+
+	loop := New(Config{
+		PlanID:            plan.ID,
+		MaxIterations:     1,
+		WorkDir:           "/tmp",
+		SelfCheckAttempts: attempts,
+	}, testDeps(database, creator, mockJJRunnerEmpty()))
+	events := runLoop(t, loop)
+	return database, plan, events
+}
+
+func TestLoop_SelfCheckReformatsMalformedOutput(t *testing.T) {
+	reformatted := "## Progress\nAdded the endpoint\n\n## Learnings\nNone\n\n---\n\n## Status\nRUNNING"
+	review := "## Progress\nReviewed\n\n## Learnings\nNone\n\n### Verdict\n" + parser.ReviewerApprovedMarker
+	database, plan, events := runSelfCheckLoop(t, 2, "I added the endpoint.", reformatted, review)
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []db.LoopAgentType{db.LoopAgentDeveloper, db.LoopAgentReformatter, db.LoopAgentReviewer}
+	if len(sessions) != len(want) {
+		t.Fatalf("got %d sessions, want %v", len(sessions), want)
+	}
+	for i, s := range sessions {
+		if s.AgentType != want[i] {
+			t.Errorf("session %d type = %s, want %s", i, s.AgentType, want[i])
+		}
+	}
+	if sessions[0].ReformatAttempts != 1 || sessions[2].ReformatAttempts != 0 {
+		t.Errorf("reformat attempts = %d, %d, want 1, 0", sessions[0].ReformatAttempts, sessions[2].ReformatAttempts)
+	}
+
+	history, err := database.GetProgressHistory(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, p := range history {
+		found = found || (p.SessionID == sessions[0].ID && p.Content == "Added the endpoint")
+	}
+	if !found {
+		t.Errorf("expected the reformatted developer progress to be stored, got %+v", history)
+	}
+
+	var started, reformattedEvent bool
+	for _, e := range events {
+		started = started || e.Type == EventReformatStart
+		reformattedEvent = reformattedEvent || e.Type == EventReformatted
+	}
+	if !started || !reformattedEvent {
+		t.Errorf("expected reformat start and reformatted events, got %+v", events)
+	}
+}
+
+func TestLoop_SelfCheckGivesUp(t *testing.T) {
+	database, plan, events := runSelfCheckLoop(t, 2, "I added the endpoint.", "Still no headers.",
+		"Still no headers.", "## Progress\nReviewed\n\n## Learnings\nNone\n\n### Verdict\n"+parser.ReviewerApprovedMarker)
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 4 || sessions[0].ReformatAttempts != 2 {
+		t.Fatalf("expected a developer with 2 reformat attempts, then a review, got %d sessions", len(sessions))
+	}
+
+	failed := false
+	for _, e := range events {
+		failed = failed || e.Type == EventReformatFailed
+	}
+	if !failed {
+		t.Errorf("expected a reformat failed event, got %+v", events)
+	}
+}
+
+func TestLoop_SelfCheckDisabled(t *testing.T) {
+	database, plan, _ := runSelfCheckLoop(t, 0, "I added the endpoint.",
+		"## Progress\nReviewed\n\n## Learnings\nNone\n\n### Verdict\n"+parser.ReviewerApprovedMarker)
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 || sessions[0].ReformatAttempts != 0 {
+		t.Errorf("expected no reformat follow-ups, got %d sessions", len(sessions))
+	}
+}
+
+func TestLoop_SelfCheckReformatsWithReviewerClient(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	developer := mockClaudeCreator("I added the endpoint.")
+	var reviewerPrompts []string
+	reviewerClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	reviewerClient.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		reviewerPrompts = append(reviewerPrompts, args[len(args)-1])
+		if len(reviewerPrompts) == 1 {
+			return exec.CommandContext(ctx, "echo", createMockClaudeOutput("## Progress\nAdded the endpoint\n\n## Learnings\nNone\n\n---\n\n## Status\nRUNNING"))
+		}
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput("## Progress\nReviewed\n\n## Learnings\nNone\n\n### Verdict\n"+parser.ReviewerApprovedMarker))
+	})
+
+	deps := testDeps(database, developer, mockJJRunnerEmpty())
+	deps.ReviewerClaude = reviewerClient
+	loop := New(Config{
+		PlanID:            plan.ID,
+		MaxIterations:     1,
+		WorkDir:           "/tmp",
+		SelfCheckAttempts: 1,
+	}, deps)
+	events := runLoop(t, loop)
+
+	reformatted := false
+	for _, e := range events {
+		reformatted = reformatted || e.Type == EventReformatted
+	}
+	if !reformatted || len(reviewerPrompts) != 2 {
+		t.Errorf("expected the reviewer's client to reformat, then review; got %d reviewer sessions", len(reviewerPrompts))
+	}
+}
@@ -0,0 +1,127 @@
+package loop
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/parser"
+)
+
+// maxRejectedApproaches caps the rejected approaches listed in a developer
+// prompt; the most recent are kept.
+const maxRejectedApproaches = 10
+
+// maxRejectedApproachLen caps the length of each listed approach.
+const maxRejectedApproachLen = 300
+
+// checksRejectAfter is how many developer sessions in a row must fail the
+// same checks before their approach is recorded as rejected.
+const checksRejectAfter = 2
+
+// recordReviewerRejection records as approaches not to repeat those the
+// reviewer rejected outright, and the critical and major issues of its
+// feedback that recur: they repeat a review item still open from an
+// earlier iteration. It must run before the feedback's new issues are
+// issued as review items. Minor issues are nitpicks, not approaches.
+func (l *Loop) recordReviewerRejection(sessionID, feedback string, rejected []string) {
+	for _, approach := range rejected {
+		l.recordRejectedApproach(sessionID, db.RejectedByReviewer, approach)
+	}
+
+	open := l.openFeedbackItems()
+	if len(open) == 0 {
+		return
+	}
+	known := make([]string, len(open))
+	for i, item := range open {
+		known[i] = item.Content
+	}
+	fresh := make(map[string]bool)
+	for _, item := range parser.NewFeedback(known, []string{feedback}) {
+		fresh[item.Text] = true
+	}
+	for _, item := range parser.MergeFeedback([]string{feedback}) {
+		if (item.Severity == "Critical" || item.Severity == "Major") && !fresh[item.Text] {
+			l.recordRejectedApproach(sessionID, db.RejectedByReviewer, item.Text)
+		}
+	}
+}
+
+// observeCheckFailures feeds the checks a developer session's changes failed
+// to the check failure streak, and records the approach as rejected once
+// the same checks have failed checksRejectAfter sessions in a row. No
+// failures end the streak.
+func (l *Loop) observeCheckFailures(sessionID string, failed []string, output string) {
+	if len(failed) == 0 {
+		l.checkFailures.reset()
+		return
+	}
+	if l.checkFailures.observe("Checks failed", strings.Join(failed, ", "), output) != checksRejectAfter {
+		return
+	}
+	l.recordRejectedApproach(sessionID, db.RejectedByChecks,
+		fmt.Sprintf("%d sessions in a row failed the %s checks: %s",
+			checksRejectAfter, strings.Join(failed, ", "), firstFailureLine(output)))
+}
+
+// recordRejectedApproach stores an approach not to repeat.
+func (l *Loop) recordRejectedApproach(sessionID string, source db.RejectedApproachSource, content string) {
+	if err := l.deps.DB.CreateRejectedApproach(&db.RejectedApproach{
+		PlanID:    l.cfg.PlanID,
+		SessionID: sessionID,
+		Source:    source,
+		Content:   content,
+	}); err != nil {
+		log.Warn("failed to store rejected approach", "error", err)
+	}
+}
+
+// loadRejectedApproaches returns the most recent approaches rejected in the
+// plan, each once and on one line, formatted as a markdown list.
+func (l *Loop) loadRejectedApproaches() string {
+	approaches, err := l.deps.DB.GetRejectedApproaches(l.cfg.PlanID)
+	if err != nil {
+		log.Warn("failed to load rejected approaches", "error", err)
+		return ""
+	}
+	return formatRejectedApproaches(approaches, maxRejectedApproaches)
+}
+
+// formatRejectedApproaches lists up to n approaches, most recent last,
+// keeping the latest of any recorded more than once.
+func formatRejectedApproaches(approaches []*db.RejectedApproach, n int) string {
+	seen := make(map[string]bool)
+	var lines []string
+	for i := len(approaches) - 1; i >= 0 && len(lines) < n; i-- {
+		content := truncateString(strings.Join(strings.Fields(approaches[i].Content), " "), maxRejectedApproachLen)
+		if content == "" || seen[content] {
+			continue
+		}
+		seen[content] = true
+		lines = append(lines, fmt.Sprintf("- (%s) %s", approaches[i].Source, content))
+	}
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// firstFailureLine returns the line of a failing check's output that names
+// its first failure, or its first line when no line looks like one.
+func firstFailureLine(output string) string {
+	first := ""
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if testFailLine.MatchString(line) {
+			return strings.TrimSpace(line)
+		}
+		if first == "" {
+			first = strings.TrimSpace(line)
+		}
+	}
+	return first
+}
@@ -0,0 +1,91 @@
+package loop
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+func TestLoop_RejectedApproachesReachDeveloper(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	reviewerClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	reviewerClient.SetCommandCreator(mockClaudeCreator("## Progress\nReviewed\n\n### Critical Issues\n- Caching in a global map races\n\n### Minor Issues\n- Typo in a comment\n\n### Verdict\nChanges needed.\n\n### Rejected Approaches\n- Retrying the request forever"))
+
+	deps := testDeps(database, mockClaudeCreator("## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"), mockJJRunner())
+	deps.ReviewerClaude = reviewerClient
+	deps.Checks = []Check{{Name: "tests", Gate: &fakeTestGate{}}}
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 3, WorkDir: "/tmp"}, deps)
+
+	var devPrompts []string
+	events, _ := runLoopErr(loop)
+	for _, event := range events {
+		if event.Type == EventPromptBuilt && !strings.Contains(event.Prompt, "# Diff to Review") {
+			devPrompts = append(devPrompts, event.Prompt)
+		}
+	}
+
+	if len(devPrompts) != 3 {
+		t.Fatalf("expected 3 developer prompts, got %d", len(devPrompts))
+	}
+	if strings.Contains(devPrompts[0], "# Previously Rejected Approaches") {
+		t.Error("first developer prompt should not have rejected approaches")
+	}
+	if !strings.Contains(devPrompts[1], "- (reviewer) Retrying the request forever") {
+		t.Errorf("second developer prompt missing the rejected approach:\n%s", devPrompts[1])
+	}
+	if strings.Contains(devPrompts[1], "(reviewer) Caching") {
+		t.Error("an issue raised once should not be recorded as a rejected approach")
+	}
+	if strings.Contains(devPrompts[1], "(checks)") {
+		t.Error("checks that failed once should not be recorded as a rejected approach")
+	}
+	if strings.Count(devPrompts[2], "(reviewer) Retrying the request forever\n") != 1 {
+		t.Error("third developer prompt should list the repeated rejection once")
+	}
+	if !strings.Contains(devPrompts[2], "- (reviewer) Caching in a global map races") {
+		t.Errorf("third developer prompt missing the recurring issue:\n%s", devPrompts[2])
+	}
+	if !strings.Contains(devPrompts[2], "- (checks) 2 sessions in a row failed the tests checks: run 2") {
+		t.Errorf("third developer prompt missing the checks that kept failing:\n%s", devPrompts[2])
+	}
+	if strings.Contains(devPrompts[2], "(reviewer) Typo") {
+		t.Error("minor issues should not be recorded as rejected approaches")
+	}
+}
+
+func TestFormatRejectedApproaches(t *testing.T) {
+	approaches := []*db.RejectedApproach{
+		{Source: db.RejectedByReviewer, Content: "Use a global map"},
+		{Source: db.RejectedByChecks, Content: "Skip   the\nmigration"},
+		{Source: db.RejectedByReviewer, Content: "Use a global map"},
+		{Source: db.RejectedByReviewer, Content: "Retry forever"},
+	}
+
+	got := formatRejectedApproaches(approaches, 10)
+	want := "- (checks) Skip the migration\n- (reviewer) Use a global map\n- (reviewer) Retry forever"
+	if got != want {
+		t.Errorf("formatRejectedApproaches() =\n%s\nwant\n%s", got, want)
+	}
+	if got := formatRejectedApproaches(approaches, 1); got != "- (reviewer) Retry forever" {
+		t.Errorf("formatRejectedApproaches() with a limit of 1 = %q, want the most recent", got)
+	}
+}
+
+func TestFirstFailureLine(t *testing.T) {
+	tests := []struct {
+		output, want string
+	}{
+		{"\nok  \tpkg/a\n--- FAIL: TestFetch (0.01s)\nFAIL\tpkg/b", "--- FAIL: TestFetch (0.01s)"},
+		{"\n  lint: 3 problems\nmore", "lint: 3 problems"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := firstFailureLine(tt.output); got != tt.want {
+			t.Errorf("firstFailureLine(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// interruptedSession reports whether session, the plan's latest, is a
+// developer session the previous run was interrupted in, whose full prompt
+// can be sent again: it never finished, and its prompt holds the plan as it
+// is now. Differential prompts don't hold the plan and are rebuilt.
+func (l *Loop) interruptedSession(session *db.PlanSession) bool {
+	if !l.cfg.FastResume || session.AgentType != db.LoopAgentDeveloper {
+		return false
+	}
+	if session.Status != db.PlanSessionRunning && session.Status != db.PlanSessionFailed {
+		return false
+	}
+	return session.FinalOutput == "" && strings.Contains(session.RunInputPrompt(), l.plan.Content)
+}
+
+// resubmitInterrupted runs a developer session with the prompt of the
+// session the previous run was interrupted in, skipping the prompt's
+// rebuild. It reports false, running nothing, when there is no such
+// session or something its prompt was built from changed since: the plan
+// or the task, or there are plan edits, out-of-scope changes, failing
+// checks, or user feedback for the developer to be told about.
+func (l *Loop) resubmitInterrupted(ctx context.Context, devClient *claude.Client) (output, sessionID string, ok bool, err error) {
+	session := l.interrupted
+	if session == nil {
+		return "", "", false, nil
+	}
+	l.interrupted = nil
+
+	l.userFeedbackMu.Lock()
+	queued := len(l.userFeedback)
+	l.userFeedbackMu.Unlock()
+	task := l.currentTaskPrompt()
+	if queued > 0 || l.planUpdate != "" || l.outOfScope != "" || l.failingChecks != "" ||
+		!strings.Contains(session.RunInputPrompt(), l.plan.Content) || !strings.Contains(session.RunInputPrompt(), task) {
+		log.Debug("state changed since the interrupted session, rebuilding the prompt", "session", session.ID)
+		return "", "", false, nil
+	}
+
+	if session.Status == db.PlanSessionRunning {
+		l.failSession(session.ID)
+	}
+	resume := ""
+	if l.cfg.ResumeClaudeSession {
+		resume = session.ClaudeSessionID
+	}
+	message := fmt.Sprintf("Re-sending the prompt of the developer session interrupted in iteration %d", session.Iteration)
+	if resume != "" {
+		message += ", resuming its Claude session"
+	}
+	l.emit(NewEvent(EventSessionResubmitted, l.iteration, l.effectiveMaxIter(), message))
+	l.promptBuilt()
+
+	output, sessionID, run, err := l.startDeveloperSession(ctx, session.RunInputPrompt(), devClient, resume)
+	if err == nil && resume != "" && run.sessionID == "" {
+		// The CLI couldn't resume the session, so start a new one
+		log.Warn("failed to resume the interrupted developer session, starting a new one", "claudeSession", resume)
+		output, sessionID, run, err = l.startDeveloperSession(ctx, session.RunInputPrompt(), devClient, "")
+	}
+	if err != nil {
+		return "", sessionID, true, err
+	}
+	l.devSessionRan(task, run, false)
+
+	return l.selfCheck(ctx, sessionID, db.LoopAgentDeveloper, output), sessionID, true, nil
+}
@@ -0,0 +1,117 @@
+package loop
+
+import (
+	"context"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+func TestLoop_FastResume(t *testing.T) {
+	tests := []struct {
+		name       string
+		prompt     func(plan *db.Plan) string // Prompt of the interrupted session
+		resubmit   bool
+		resumeFrom string // Claude session the first session should resume
+	}{
+		{
+			name:       "unchanged plan",
+			prompt:     func(plan *db.Plan) string { return "# Instructions\n\n" + plan.Content + "\n\n# Progress\nHalfway" },
+			resubmit:   true,
+			resumeFrom: "claude-old",
+		},
+		{
+			name:   "plan edited since",
+			prompt: func(*db.Plan) string { return "# Instructions\n\nAdd signup\n\n# Progress\nHalfway" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := setupTestDB(t)
+			plan := createTestPlan(t, database, "Add login")
+			interrupted := &db.PlanSession{
+				ID:          "interrupted",
+				PlanID:      plan.ID,
+				Iteration:   2,
+				InputPrompt: tt.prompt(plan),
+				Status:      db.PlanSessionRunning,
+				AgentType:   db.LoopAgentDeveloper,
+			}
+			if err := database.CreatePlanSession(interrupted); err != nil {
+				t.Fatalf("CreatePlanSession() error: %v", err)
+			}
+			if err := database.UpdatePlanSessionClaudeSession(interrupted.ID, "claude-old"); err != nil {
+				t.Fatalf("UpdatePlanSessionClaudeSession() error: %v", err)
+			}
+
+			outputs := []string{
+				"## Progress\nDone\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!",
+				"## Progress\nReviewed\n\n### Critical Issues\nNone\n\n### Major Issues\nNone\n\n### Minor Issues\nNone\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!",
+			}
+			var calls [][]string
+			creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+				calls = append(calls, args)
+				output := outputs[min(len(calls), len(outputs))-1]
+				return exec.CommandContext(ctx, "echo", createMockClaudeOutputWithToolUse(output, "Read"))
+			}
+
+			loop := New(Config{
+				PlanID:              plan.ID,
+				MaxIterations:       5,
+				WorkDir:             "/tmp",
+				FastResume:          true,
+				ResumeClaudeSession: true,
+			}, testDeps(database, creator, mockJJRunnerWithDiff("base123", "diff --git a/login.go b/login.go\n+func login() {}\n")))
+
+			var resubmitted bool
+			events := runLoop(t, loop)
+			for _, event := range events {
+				if event.Type == EventSessionResubmitted {
+					resubmitted = true
+				}
+			}
+
+			if len(calls) == 0 {
+				t.Fatal("expected Claude sessions to run")
+			}
+			devArgs := calls[0]
+			if got := devArgs[len(devArgs)-1] == interrupted.InputPrompt; got != tt.resubmit {
+				t.Errorf("developer prompt re-sent = %v, want %v:\n%s", got, tt.resubmit, devArgs[len(devArgs)-1])
+			}
+			if resubmitted != tt.resubmit {
+				t.Errorf("EventSessionResubmitted emitted = %v, want %v", resubmitted, tt.resubmit)
+			}
+			if i := slices.Index(devArgs, "--resume"); (i >= 0) != (tt.resumeFrom != "") ||
+				(i >= 0 && devArgs[i+1] != tt.resumeFrom) {
+				t.Errorf("developer args = %v, want resume of %q", devArgs, tt.resumeFrom)
+			}
+
+			sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+			if err != nil {
+				t.Fatalf("GetPlanSessionsByPlan() error: %v", err)
+			}
+			wantIteration := 3
+			if tt.resubmit {
+				wantIteration = 2
+			}
+			for _, s := range sessions {
+				if s.ID == interrupted.ID {
+					if tt.resubmit && s.Status != db.PlanSessionFailed {
+						t.Errorf("interrupted session status = %s, want failed", s.Status)
+					}
+					continue
+				}
+				if s.Iteration != wantIteration {
+					t.Errorf("session %s ran in iteration %d, want %d", s.ID, s.Iteration, wantIteration)
+				}
+				if !strings.HasPrefix(s.ClaudeSessionID, "test-session") {
+					t.Errorf("session %s Claude session ID = %q, want it recorded", s.ID, s.ClaudeSessionID)
+				}
+			}
+		})
+	}
+}
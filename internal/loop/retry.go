@@ -0,0 +1,49 @@
+package loop
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls retries of Claude sessions that fail transiently.
+type RetryPolicy struct {
+	MaxAttempts    int           // Total attempts including the first (<= 1 disables retries)
+	InitialBackoff time.Duration // Delay before the first retry
+	MaxBackoff     time.Duration // Upper bound on the delay between attempts
+
+	// MaxRateLimitWait is the longest rate-limit reset waited out without
+	// using up an attempt (0 = rate limits use the backoff like other
+	// transient errors).
+	MaxRateLimitWait time.Duration
+}
+
+// backoff returns the delay before retrying after the given failed attempt
+// (1-based). The delay doubles each attempt up to MaxBackoff, and the upper
+// half is randomized so concurrent runs don't retry in lockstep.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,44 @@
+package loop
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 2 * time.Second,
+		MaxBackoff:     10 * time.Second,
+	}
+
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{1, 1 * time.Second, 2 * time.Second},
+		{2, 2 * time.Second, 4 * time.Second},
+		{3, 4 * time.Second, 8 * time.Second},
+		{4, 5 * time.Second, 10 * time.Second}, // capped
+		{10, 5 * time.Second, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			d := p.backoff(tt.attempt)
+			if d < tt.min || d > tt.max {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", tt.attempt, d, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestSleepContext_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := sleepContext(ctx, time.Hour); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
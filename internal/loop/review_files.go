@@ -0,0 +1,85 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// defaultReviewFilesBytes is the byte budget for the changed files given to
+// the reviewer when the config doesn't set one.
+const defaultReviewFilesBytes = 64 * 1024
+
+// reviewFilesIncluded reports whether the reviewer is given the content of
+// the changed files: as the "review_context:" key of the plan's front
+// matter says, or as configured when it doesn't.
+func (l *Loop) reviewFilesIncluded() bool {
+	reviewContext, err := agent.ReviewContext(l.plan.Content)
+	if err != nil {
+		// A merged plan edit broke the front matter
+		log.Warn("invalid plan front matter, using the configured review context", "error", err)
+		return l.cfg.ReviewFiles
+	}
+	switch reviewContext {
+	case agent.ReviewContextFiles:
+		return true
+	case agent.ReviewContextDiff:
+		return false
+	}
+	return l.cfg.ReviewFiles
+}
+
+// changedFilesContext returns the current content of the files changed
+// since the review base, formatted for the reviewer prompt, or "" when the
+// reviewer isn't given them. Files are added in the order jj lists them
+// while they fit the byte budget; binary files and those past the budget
+// are listed as left out, and deleted files are left to the diff.
+func (l *Loop) changedFilesContext(ctx context.Context) string {
+	if !l.reviewFilesIncluded() {
+		return ""
+	}
+	files, err := l.deps.VCS.ChangedFiles(ctx, l.reviewBaseChangeID(), "@", l.scopePaths()...)
+	if err != nil {
+		log.Warn("failed to list changed files for the reviewer", "error", err)
+		return ""
+	}
+	budget := l.cfg.ReviewFilesBytes
+	if budget <= 0 {
+		budget = defaultReviewFilesBytes
+	}
+
+	var b strings.Builder
+	var binary, overBudget []string
+	used := 0
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(l.cfg.WorkDir, file))
+		if err != nil {
+			// Deleted by the change
+			continue
+		}
+		if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+			binary = append(binary, file)
+			continue
+		}
+		if used+len(data) > budget {
+			overBudget = append(overBudget, file)
+			continue
+		}
+		used += len(data)
+		fmt.Fprintf(&b, "### %s\n\n```\n%s\n```\n\n", file, strings.TrimRight(string(data), "\n"))
+	}
+	if len(overBudget) > 0 {
+		fmt.Fprintf(&b, "Left out to stay within %d bytes: %s\n", budget, strings.Join(overBudget, ", "))
+	}
+	if len(binary) > 0 {
+		fmt.Fprintf(&b, "Left out as binary: %s\n", strings.Join(binary, ", "))
+	}
+	return strings.TrimSpace(b.String())
+}
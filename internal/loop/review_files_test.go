@@ -0,0 +1,90 @@
+package loop
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+func TestLoop_ReviewFiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		plan        string
+		reviewFiles bool
+		want        bool
+	}{
+		{"configured", "Add login", true, true},
+		{"off", "Add login", false, false},
+		{"plan asks for files", "---\nreview_context: files\n---\nAdd login", false, true},
+		{"plan asks for the diff alone", "---\nreview_context: diff\n---\nAdd login", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := setupTestDB(t)
+			plan := createTestPlan(t, database, tt.plan)
+
+			workDir := t.TempDir()
+			for name, content := range map[string]string{
+				"login.go": "package web\n\nfunc login() {}\n",
+				"big.go":   "package web\n\n// " + strings.Repeat("x", 200) + "\n",
+				"logo.png": "\x89PNG\x00\x01",
+			} {
+				if err := os.WriteFile(filepath.Join(workDir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			devClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+			devClient.SetCommandCreator(mockClaudeCreator("## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"))
+			reviewerClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+			reviewerClient.SetCommandCreator(mockClaudeCreator("## Progress\nReviewed\n\nREVIEWER_FEEDBACK: Handle errors"))
+
+			jjClient := jj.NewClient(workDir)
+			jjClient.SetCommandRunner(func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+				if args[0] == "diff" && slices.Contains(args, "--name-only") {
+					return "login.go\nbig.go\nlogo.png\nremoved.go\n", "", nil
+				}
+				return mockJJRunner()(ctx, dir, name, args...)
+			})
+
+			loop := New(Config{
+				PlanID:           plan.ID,
+				MaxIterations:    1,
+				WorkDir:          workDir,
+				ReviewFiles:      tt.reviewFiles,
+				ReviewFilesBytes: 100,
+			}, Deps{DB: database, Claude: devClient, ReviewerClaude: reviewerClient, VCS: jjClient})
+
+			var reviewerPrompt string
+			events := runLoop(t, loop)
+			for _, e := range events {
+				if e.Type == EventPromptBuilt && strings.Contains(e.Prompt, "# Diff to Review") {
+					reviewerPrompt = e.Prompt
+				}
+			}
+
+			if got := strings.Contains(reviewerPrompt, "# Changed Files"); got != tt.want {
+				t.Fatalf("changed files in the reviewer prompt = %v, want %v:\n%s", got, tt.want, reviewerPrompt)
+			}
+			if !tt.want {
+				return
+			}
+			if !strings.Contains(reviewerPrompt, "### login.go\n\n```\npackage web\n\nfunc login() {}\n```") {
+				t.Errorf("expected login.go's content in the reviewer prompt:\n%s", reviewerPrompt)
+			}
+			if !strings.Contains(reviewerPrompt, "Left out to stay within 100 bytes: big.go") ||
+				!strings.Contains(reviewerPrompt, "Left out as binary: logo.png") {
+				t.Errorf("expected big.go and logo.png listed as left out:\n%s", reviewerPrompt)
+			}
+			if strings.Contains(reviewerPrompt, "removed.go") {
+				t.Error("a deleted file should be left to the diff")
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package loop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/analyze"
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/parser"
+)
+
+// editTools are the tools that modify the file named in their input.
+var editTools = map[string]bool{
+	"Edit":         true,
+	"MultiEdit":    true,
+	"Write":        true,
+	"NotebookEdit": true,
+}
+
+// teamWorkers records the teammates a team-mode developer session spawned
+// and the files each one edited, so review feedback can be routed to them.
+type teamWorkers struct {
+	sessionID string
+	order     []string            // Spawning tool call IDs, in spawn order
+	names     map[string]string   // Task description per worker
+	files     map[string][]string // Files edited per worker, in first-edit order
+}
+
+// newTeamWorkers creates an empty record for a developer session.
+func newTeamWorkers(sessionID string) *teamWorkers {
+	return &teamWorkers{
+		sessionID: sessionID,
+		names:     make(map[string]string),
+		files:     make(map[string][]string),
+	}
+}
+
+// add records the spawns and edits among transcript entries.
+func (w *teamWorkers) add(entries []claude.TranscriptEntry) {
+	for _, entry := range entries {
+		if entry.Kind != claude.TranscriptToolUse {
+			continue
+		}
+		tool := &claude.ToolUseContent{ID: entry.ToolUseID, Name: entry.ToolName, Input: json.RawMessage(entry.Content)}
+		if claude.IsSubAgentSpawn(tool) && entry.SubAgentID == "" {
+			description, _ := claude.SubAgentTask(tool)
+			w.worker(entry.ToolUseID)
+			w.names[entry.ToolUseID] = description
+			continue
+		}
+		if entry.SubAgentID == "" || !editTools[entry.ToolName] {
+			continue
+		}
+		var params map[string]any
+		if err := json.Unmarshal([]byte(entry.Content), &params); err != nil {
+			continue
+		}
+		for _, key := range fileParams {
+			if path, ok := params[key].(string); ok && path != "" {
+				w.worker(entry.SubAgentID)
+				if !slices.Contains(w.files[entry.SubAgentID], path) {
+					w.files[entry.SubAgentID] = append(w.files[entry.SubAgentID], path)
+				}
+				break
+			}
+		}
+	}
+}
+
+// worker registers a worker the first time it is seen.
+func (w *teamWorkers) worker(id string) {
+	if _, ok := w.names[id]; !ok {
+		w.order = append(w.order, id)
+		w.names[id] = ""
+	}
+}
+
+// owner returns the worker whose edited files the text mentions most
+// specifically, matching on the path relative to workDir or the file name.
+// It returns "" when the text mentions none of them.
+func (w *teamWorkers) owner(text, workDir string) string {
+	best, bestLen := "", 0
+	for _, id := range w.order {
+		for _, path := range w.files[id] {
+			for _, name := range pathNames(path, workDir) {
+				if len(name) > bestLen && strings.Contains(text, name) {
+					best, bestLen = id, len(name)
+				}
+			}
+		}
+	}
+	return best
+}
+
+// label names a worker by its task and the files it edited.
+func (w *teamWorkers) label(id, workDir string) string {
+	name := w.names[id]
+	if name == "" {
+		name = "teammate " + id
+	}
+	var files []string
+	for _, path := range w.files[id] {
+		names := pathNames(path, workDir)
+		files = append(files, names[0])
+	}
+	if len(files) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, strings.Join(files, ", "))
+}
+
+// pathNames returns the names feedback may use for a file: its path
+// relative to workDir when it is inside it, and its base name.
+func pathNames(path, workDir string) []string {
+	var names []string
+	if workDir != "" && filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(workDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+	}
+	names = append(names, filepath.ToSlash(path))
+	if base := filepath.Base(path); base != names[0] {
+		names = append(names, base)
+	}
+	return names
+}
+
+// reviewPanelSummary starts the panel's feedback, which first counts its
+// verdicts.
+const reviewPanelSummary = "Review panel:"
+
+// reviewPanelSize returns how many reviewers review each iteration. A
+// panel is only used in team mode.
+func (l *Loop) reviewPanelSize() int {
+	if !l.cfg.TeamMode || l.cfg.Reviewers < 2 {
+		return 1
+	}
+	return l.cfg.Reviewers
+}
+
+// reviewQuorum returns how many of the panel's reviewers must approve.
+func (l *Loop) reviewQuorum() int {
+	size := l.reviewPanelSize()
+	if l.cfg.ReviewQuorum <= 0 || l.cfg.ReviewQuorum > size {
+		return size
+	}
+	return l.cfg.ReviewQuorum
+}
+
+// runReviewPanel runs each reviewer of the panel on the same work and
+// aggregates their verdicts into a single result: approved once the quorum
+// approves, otherwise with the rejecting reviewers' feedback merged,
+// deduplicated, and grouped by the teammate whose files it concerns, and
+// the claimed fixes the quorum verified. The
+// result's progress is the last reviewer's and its learnings are everyone's.
+// It returns the last reviewer's session ID.
+func (l *Loop) runReviewPanel(ctx context.Context, progress, learnings, diff, devSummary string, devDone bool, findings []analyze.Finding) (*parser.AgentParseResult, string, error) {
+	size, quorum := l.reviewPanelSize(), l.reviewQuorum()
+
+	result := &parser.AgentParseResult{}
+	var sessionID string
+	var allLearnings, feedbacks []string
+	approvals := 0
+	verified := make(map[int]int) // Reviewers that verified each claimed fix
+	for seat := 1; seat <= size; seat++ {
+		l.emit(NewEvent(EventReviewerStart, l.iteration, l.effectiveMaxIter(),
+			fmt.Sprintf("Starting reviewer %d of %d", seat, size)))
+
+		output, id, err := l.runReviewer(ctx, l.reviewerClient(), seat, progress, learnings, diff, devSummary, devDone, findings)
+		if err != nil {
+			return nil, "", fmt.Errorf("reviewer %d of %d failed: %w", seat, size, err)
+		}
+		sessionID = id
+
+		reviewEnd := l.sessionElapsed()
+		parseStart := time.Now()
+		review := l.parseOutput(id, output, "reviewer")
+		l.checkChecklist(review, devDone)
+		l.finishSessionTimer(id, time.Since(parseStart))
+		endEvent := NewEvent(EventReviewerEnd, l.iteration, l.effectiveMaxIter(),
+			fmt.Sprintf("Reviewer %d of %d ended (%s)", seat, size, verdictName(review.ReviewerApproved)))
+		endEvent.Duration = reviewEnd
+		l.emit(endEvent)
+
+		result.Progress = review.Progress
+		if review.Learnings != "" {
+			allLearnings = append(allLearnings, review.Learnings)
+		}
+		if review.ReviewerApproved {
+			approvals++
+			continue
+		}
+		for _, n := range review.VerifiedItems {
+			verified[n]++
+		}
+		if review.ReviewerFeedback != "" {
+			feedbacks = append(feedbacks, review.ReviewerFeedback)
+		}
+		if result.ReviewerPatch == "" {
+			result.ReviewerPatch = review.ReviewerPatch
+		}
+		result.RejectedApproaches = append(result.RejectedApproaches, review.RejectedApproaches...)
+	}
+
+	if items := parser.UniqueLearnings(allLearnings); len(items) > 0 {
+		result.Learnings = "- " + strings.Join(items, "\n- ")
+	}
+
+	l.emit(NewEvent(EventReviewQuorum, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("%d of %d reviewers approved (%d needed)", approvals, size, quorum)))
+
+	if approvals >= quorum {
+		result.ReviewerApproved = true
+		result.ReviewerPatch = ""
+		result.RejectedApproaches = nil
+		return result, sessionID, nil
+	}
+	result.ReviewerFeedback = l.routeFeedback(parser.MergeFeedback(feedbacks), approvals, size, quorum)
+	result.VerifiedItems = panelVerifiedItems(verified, approvals, quorum)
+	return result, sessionID, nil
+}
+
+// verdictName describes a reviewer's verdict for events.
+func verdictName(approved bool) string {
+	if approved {
+		return "approved"
+	}
+	return "changes requested"
+}
+
+// routeFeedback formats the panel's merged feedback for the developer,
+// grouped by the teammate whose files each issue mentions. Issues that
+// mention no teammate's files are left to the lead.
+func (l *Loop) routeFeedback(items []parser.FeedbackItem, approvals, size, quorum int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %d of %d reviewers approved (%d needed).", reviewPanelSummary, approvals, size, quorum)
+	if len(items) == 0 {
+		b.WriteString(" The other reviewers gave no specific feedback; re-check the work against the plan.")
+		return b.String()
+	}
+
+	workers := l.workers
+	groups := make(map[string][]parser.FeedbackItem)
+	for _, item := range items {
+		owner := ""
+		if workers != nil {
+			owner = workers.owner(item.Text, l.cfg.WorkDir)
+		}
+		groups[owner] = append(groups[owner], item)
+	}
+	if len(groups[""]) == len(items) {
+		b.WriteString(" Merged feedback from the reviewers:\n\n")
+		writeFeedbackItems(&b, items)
+		return strings.TrimRight(b.String(), "\n")
+	}
+
+	b.WriteString(" Merged feedback from the reviewers, grouped by the teammate whose files it concerns:\n")
+	for _, id := range workers.order {
+		if len(groups[id]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### For teammate: %s\n\n", workers.label(id, l.cfg.WorkDir))
+		writeFeedbackItems(&b, groups[id])
+	}
+	if len(groups[""]) > 0 {
+		b.WriteString("\n### For the lead (no single teammate)\n\n")
+		writeFeedbackItems(&b, groups[""])
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// writeFeedbackItems writes feedback items as a list, tagged with their
+// severity.
+func writeFeedbackItems(b *strings.Builder, items []parser.FeedbackItem) {
+	for _, item := range items {
+		if item.Severity != "" {
+			fmt.Fprintf(b, "- [%s] %s\n", item.Severity, item.Text)
+		} else {
+			fmt.Fprintf(b, "- %s\n", item.Text)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package loop
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/parser"
+)
+
+func TestReviewPanel_SizeAndQuorum(t *testing.T) {
+	tests := []struct {
+		name               string
+		cfg                Config
+		wantSize, wantNeed int
+	}{
+		{"not team mode", Config{Reviewers: 3}, 1, 1},
+		{"single reviewer", Config{TeamMode: true, Reviewers: 1}, 1, 1},
+		{"all must approve", Config{TeamMode: true, Reviewers: 3}, 3, 3},
+		{"quorum", Config{TeamMode: true, Reviewers: 3, ReviewQuorum: 2}, 3, 2},
+		{"quorum above size", Config{TeamMode: true, Reviewers: 2, ReviewQuorum: 5}, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Loop{cfg: tt.cfg}
+			if got := l.reviewPanelSize(); got != tt.wantSize {
+				t.Errorf("reviewPanelSize() = %d, want %d", got, tt.wantSize)
+			}
+			if got := l.reviewQuorum(); got != tt.wantNeed {
+				t.Errorf("reviewQuorum() = %d, want %d", got, tt.wantNeed)
+			}
+		})
+	}
+}
+
+func TestTeamWorkers_RouteFeedback(t *testing.T) {
+	workers := newTeamWorkers("dev-session")
+	workers.add([]claude.TranscriptEntry{
+		{Kind: claude.TranscriptToolUse, ToolName: "Task", ToolUseID: "task-1", Content: `{"description":"Build the parser"}`},
+		{Kind: claude.TranscriptToolUse, ToolName: "Task", ToolUseID: "task-2", Content: `{"description":"Write the docs"}`},
+		{Kind: claude.TranscriptToolUse, ToolName: "Edit", SubAgentID: "task-1", Content: `{"file_path":"/repo/internal/parser/lexer.go"}`},
+		{Kind: claude.TranscriptToolUse, ToolName: "Write", SubAgentID: "task-2", Content: `{"file_path":"/repo/README.md"}`},
+		// Reads and the lead's own edits don't make a file a teammate's
+		{Kind: claude.TranscriptToolUse, ToolName: "Read", SubAgentID: "task-2", Content: `{"file_path":"/repo/main.go"}`},
+		{Kind: claude.TranscriptToolUse, ToolName: "Edit", Content: `{"file_path":"/repo/main.go"}`},
+	})
+
+	l := &Loop{cfg: Config{WorkDir: "/repo"}, workers: workers}
+	got := l.routeFeedback([]parser.FeedbackItem{
+		{Severity: "Critical", Text: "internal/parser/lexer.go:12 panics on empty input"},
+		{Severity: "Minor", Text: "README.md has a typo in the install section"},
+		{Text: "main.go ignores the error from Run"},
+	}, 1, 3, 2)
+
+	want := `Review panel: 1 of 3 reviewers approved (2 needed). Merged feedback from the reviewers, grouped by the teammate whose files it concerns:
+
+### For teammate: Build the parser (internal/parser/lexer.go)
+
+- [Critical] internal/parser/lexer.go:12 panics on empty input
+
+### For teammate: Write the docs (README.md)
+
+- [Minor] README.md has a typo in the install section
+
+### For the lead (no single teammate)
+
+- main.go ignores the error from Run`
+	if got != want {
+		t.Errorf("routeFeedback() =\n%s\n\nwant:\n%s", got, want)
+	}
+}
+
+func TestRouteFeedback_WithoutWorkers(t *testing.T) {
+	l := &Loop{cfg: Config{WorkDir: "/repo"}}
+	got := l.routeFeedback([]parser.FeedbackItem{{Text: "Add tests"}}, 0, 2, 2)
+	want := "Review panel: 0 of 2 reviewers approved (2 needed). Merged feedback from the reviewers:\n\n- Add tests"
+	if got != want {
+		t.Errorf("routeFeedback() = %q, want %q", got, want)
+	}
+}
+
+func TestLoop_ReviewPanel_Quorum(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	const (
+		devDone  = "## Progress\nImplemented\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"
+		approve  = "## Progress\nReviewed\n\n## Learnings\n- The parser is table driven\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!"
+		rejectA  = "## Progress\nReviewed\n\n### Critical Issues\n- lexer.go panics on empty input\n\n### Major Issues\nNone\n\n### Minor Issues\nNone"
+		rejectAB = "## Progress\nReviewed\n\n## Learnings\n- The parser is table-driven\n\n### Critical Issues\n- lexer.go panics on empty input\n\n### Major Issues\n- Missing tests for the lexer\n\n### Minor Issues\nNone"
+	)
+	// Iteration 1: 1 of 3 approve; iteration 2: 2 of 3 approve
+	outputs := []string{devDone, approve, rejectA, rejectAB, devDone, approve, rejectA, approve}
+	var mu sync.Mutex
+	calls := 0
+
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		mu.Lock()
+		defer mu.Unlock()
+		output := "## Progress\nUnexpected session"
+		if calls < len(outputs) {
+			output = outputs[calls]
+		}
+		calls++
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput(output))
+	}
+
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 5,
+		TeamMode:      true,
+		Reviewers:     3,
+		ReviewQuorum:  2,
+		WorkDir:       "/tmp",
+	}, testDeps(database, creator, mockJJRunnerEmpty()))
+
+	events := runLoop(t, loop)
+
+	if calls != len(outputs) {
+		t.Errorf("expected %d Claude sessions, got %d", len(outputs), calls)
+	}
+
+	var quorums []string
+	for _, e := range events {
+		if e.Type == EventReviewQuorum {
+			quorums = append(quorums, e.Message)
+		}
+	}
+	wantQuorums := []string{"1 of 3 reviewers approved (2 needed)", "2 of 3 reviewers approved (2 needed)"}
+	if strings.Join(quorums, "|") != strings.Join(wantQuorums, "|") {
+		t.Errorf("quorum events = %q, want %q", quorums, wantQuorums)
+	}
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reviewerPrompt, secondDevPrompt string
+	for _, s := range sessions {
+		switch {
+		case s.AgentType == db.LoopAgentReviewer && s.Iteration == 1 && reviewerPrompt == "":
+			reviewerPrompt = s.InputPrompt
+		case s.AgentType == db.LoopAgentDeveloper && s.Iteration == 2:
+			secondDevPrompt = s.InputPrompt
+		}
+	}
+	if !strings.Contains(reviewerPrompt, "You are reviewer 1 of 3") {
+		t.Error("expected the reviewer prompt to describe the review panel")
+	}
+
+	// Both rejections' feedback, with the shared issue once
+	wantFeedback := "Review panel: 1 of 3 reviewers approved (2 needed). Merged feedback from the reviewers:\n\n" +
+		"- [Critical] lexer.go panics on empty input\n- [Major] Missing tests for the lexer"
+	if !strings.Contains(secondDevPrompt, wantFeedback) {
+		t.Errorf("expected merged feedback in the second developer prompt, got:\n%s", secondDevPrompt)
+	}
+
+	learnings, err := database.GetLatestLearnings(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if learnings == nil || strings.Count(learnings.Content, "table") != 1 {
+		t.Errorf("expected the reviewers' learnings merged, got %+v", learnings)
+	}
+}
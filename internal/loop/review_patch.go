@@ -0,0 +1,113 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// reviewerFeedbackWithPatch returns the reviewer feedback to store for the
+// next developer session. A suggested patch is always included verbatim; with
+// AutoApplyReviewPatches it is first applied as its own jj change, and the
+// developer is told so instead of being asked to apply it.
+func (l *Loop) reviewerFeedbackWithPatch(ctx context.Context, feedback, patch string) string {
+	if patch == "" {
+		return feedback
+	}
+
+	intro := "The reviewer suggested this patch. Apply it, or an equivalent fix:"
+	if l.cfg.AutoApplyReviewPatches {
+		changeID, err := l.applyReviewPatch(ctx, patch)
+		if err != nil {
+			log.Warn("failed to apply reviewer patch", "error", err)
+			intro = fmt.Sprintf("The reviewer suggested this patch, but it could not be applied automatically (%s). "+
+				"Apply it, or an equivalent fix:", err)
+		} else {
+			l.emit(NewEvent(EventReviewPatchApplied, l.iteration, l.effectiveMaxIter(),
+				fmt.Sprintf("Applied reviewer's suggested patch as change %s", changeID)))
+			intro = fmt.Sprintf("The reviewer's suggested patch below has ALREADY been applied as jj change %s. "+
+				"Do not apply it again; build on it and fix anything it got wrong:", changeID)
+		}
+	}
+
+	return fmt.Sprintf("%s\n\n%s\n\n```diff\n%s```", feedback, intro, patch)
+}
+
+// applyReviewPatch applies the reviewer's patch as a separate jj change on
+// top of the developer's work, attributed to the reviewer with trailers, then
+// starts a fresh change for the developer to continue in. It returns the
+// reviewer change's ID. Nothing is changed if the patch doesn't apply cleanly
+// or touches paths outside the permissions policy.
+func (l *Loop) applyReviewPatch(ctx context.Context, patch string) (string, error) {
+	if l.cfg.Policy.HasPathRestrictions() {
+		if violations := l.cfg.Policy.CheckPaths(patchPaths(patch)); len(violations) > 0 {
+			return "", fmt.Errorf("patch modifies files outside the allowed paths: %s", strings.Join(violations, ", "))
+		}
+	}
+	if err := l.deps.VCS.CheckPatch(ctx, patch); err != nil {
+		return "", err
+	}
+
+	description := jj.AppendTrailers(
+		fmt.Sprintf("Apply reviewer's suggested patch (iteration %d)", l.iteration),
+		[]jj.Trailer{
+			{Key: "Suggested-by", Value: reviewerTrailerName},
+			{Key: "Plan-ID", Value: l.cfg.PlanID},
+		},
+	)
+	if err := l.deps.VCS.New(ctx, description); err != nil {
+		return "", err
+	}
+	if err := l.deps.VCS.ApplyPatch(ctx, patch); err != nil {
+		if abandonErr := l.deps.VCS.Abandon(ctx, "@"); abandonErr != nil {
+			log.Warn("failed to abandon reviewer patch change", "error", abandonErr)
+		}
+		return "", err
+	}
+
+	changeID, err := l.deps.VCS.GetCurrentChangeID(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := l.deps.VCS.New(ctx, ""); err != nil {
+		return "", err
+	}
+	return changeID, nil
+}
+
+// patchPaths returns the files a unified diff modifies, from its "--- " and
+// "+++ " header pairs (without the a/ and b/ prefixes).
+func patchPaths(patch string) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	lines := strings.Split(patch, "\n")
+	for i := 0; i+1 < len(lines); i++ {
+		// A header pair; a lone "--- " line is a removed "-- " line
+		if !strings.HasPrefix(lines[i], "--- ") || !strings.HasPrefix(lines[i+1], "+++ ") {
+			continue
+		}
+		for _, header := range lines[i : i+2] {
+			path := header[4:]
+			// Drop a trailing timestamp, then the a/ or b/ prefix
+			if tab := strings.IndexByte(path, '\t'); tab != -1 {
+				path = path[:tab]
+			}
+			path = strings.TrimSpace(path)
+			if path == "/dev/null" {
+				continue
+			}
+			if strings.HasPrefix(path, "a/") || strings.HasPrefix(path, "b/") {
+				path = path[2:]
+			}
+			if path != "" && !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+		i++
+	}
+	return paths
+}
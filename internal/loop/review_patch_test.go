@@ -0,0 +1,139 @@
+package loop
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/policy"
+)
+
+const testReviewPatch = "--- a/auth.go\n+++ b/auth.go\n@@ -1 +1 @@\n-return nil\n+return err\n"
+
+// reviewerWithPatch is reviewer output that rejects with a suggested patch.
+var reviewerWithPatch = "## Progress\nReviewed\n\n### Verdict\nREVIEWER_FEEDBACK: Return the error\n\n### Suggested Patch\n```diff\n" +
+	testReviewPatch + "```\n"
+
+// runReviewPatchLoop runs one iteration whose reviewer suggests
+// testReviewPatch, recording every jj and git command, and returns the
+// stored reviewer feedback.
+func runReviewPatchLoop(t *testing.T, cfg Config) (feedback string, commands [][]string, events []Event) {
+	t.Helper()
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	reviewerClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	reviewerClient.SetCommandCreator(mockClaudeCreator(reviewerWithPatch))
+
+	runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		commands = append(commands, append([]string{name}, args...))
+		switch {
+		case name == "jj" && args[0] == "diff" && slices.Contains(args, "--name-only"):
+			return "docs/guide.md\n", "", nil
+		case name == "jj" && args[0] == "root":
+			return "/tmp\n", "", nil
+		case name == "jj" && args[0] == "log" && slices.Contains(args, "change_id") && slices.Contains(args, "@"):
+			return "rvwchange\n", "", nil
+		}
+		return mockJJRunner()(ctx, dir, name, args...)
+	}
+
+	cfg.PlanID = plan.ID
+	cfg.MaxIterations = 1
+	cfg.WorkDir = "/tmp"
+	deps := testDeps(database, mockClaudeCreator("## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"), runner)
+	deps.ReviewerClaude = reviewerClient
+	loop := New(cfg, deps)
+
+	events = runLoop(t, loop)
+
+	record, err := database.GetLatestReviewerFeedback(plan.ID)
+	if err != nil || record == nil {
+		t.Fatalf("expected stored reviewer feedback, got %v, %v", record, err)
+	}
+	return record.Content, commands, events
+}
+
+// gitCommands returns the git invocations among commands.
+func gitCommands(commands [][]string) [][]string {
+	var git [][]string
+	for _, c := range commands {
+		if c[0] == "git" {
+			git = append(git, c)
+		}
+	}
+	return git
+}
+
+func TestLoop_ReviewerPatchPassedToDeveloper(t *testing.T) {
+	feedback, commands, _ := runReviewPatchLoop(t, Config{})
+
+	if !strings.HasPrefix(feedback, "Return the error") {
+		t.Errorf("feedback should start with the reviewer's summary, got: %q", feedback)
+	}
+	if !strings.Contains(feedback, "```diff\n"+testReviewPatch+"```") {
+		t.Errorf("feedback should include the patch verbatim, got: %q", feedback)
+	}
+	if git := gitCommands(commands); len(git) != 0 {
+		t.Errorf("patch should not be applied without auto-apply, got: %v", git)
+	}
+}
+
+func TestLoop_AutoAppliesReviewerPatch(t *testing.T) {
+	feedback, commands, events := runReviewPatchLoop(t, Config{AutoApplyReviewPatches: true})
+
+	git := gitCommands(commands)
+	if len(git) != 2 || !slices.Contains(git[0], "--check") || slices.Contains(git[1], "--check") {
+		t.Fatalf("expected git apply --check then git apply, got: %v", git)
+	}
+
+	var newChanges []string
+	for _, c := range commands {
+		if c[0] == "jj" && c[1] == "new" {
+			newChanges = append(newChanges, c[len(c)-1])
+		}
+	}
+	if len(newChanges) != 2 || !strings.Contains(newChanges[0], "Suggested-by: ralph-reviewer") || newChanges[1] != "" {
+		t.Errorf("expected a reviewer change then a fresh change, got: %q", newChanges)
+	}
+
+	if !strings.Contains(feedback, "ALREADY been applied as jj change rvwchange") ||
+		!strings.Contains(feedback, testReviewPatch) {
+		t.Errorf("feedback should say the patch was applied and include it, got: %q", feedback)
+	}
+
+	var applied bool
+	for _, e := range events {
+		applied = applied || e.Type == EventReviewPatchApplied
+	}
+	if !applied {
+		t.Error("expected EventReviewPatchApplied")
+	}
+}
+
+func TestLoop_AutoApplySkipsPatchOutsidePolicy(t *testing.T) {
+	feedback, commands, _ := runReviewPatchLoop(t, Config{
+		AutoApplyReviewPatches: true,
+		Policy:                 policy.New([]string{"docs/"}, nil, true),
+	})
+
+	if git := gitCommands(commands); len(git) != 0 {
+		t.Errorf("patch outside the allowed paths should not be applied, got: %v", git)
+	}
+	if !strings.Contains(feedback, "could not be applied automatically") || !strings.Contains(feedback, testReviewPatch) {
+		t.Errorf("feedback should explain the patch wasn't applied, got: %q", feedback)
+	}
+}
+
+func TestPatchPaths(t *testing.T) {
+	patch := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,2 +1 @@\n--- SQL comment\n-x\n" +
+		"--- /dev/null\n+++ b/new/file.go\t2024-01-01 00:00:00\n@@ -0,0 +1 @@\n+package new\n"
+
+	got := patchPaths(patch)
+	want := []string{"a.go", "new/file.go"}
+	if !slices.Equal(got, want) {
+		t.Errorf("patchPaths() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,203 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// DefaultTestGateTimeout bounds a test gate run when none is configured.
+const DefaultTestGateTimeout = 10 * time.Minute
+
+// maxTestGateOutputBytes caps the test output passed to the developer.
+const maxTestGateOutputBytes = 16 * 1024
+
+// TestGate runs the repository's tests for test-authoring reviews (see
+// Deps.TestGate).
+type TestGate interface {
+	// Run runs the tests in the working copy as it is, returning their
+	// output and whether they passed.
+	Run(ctx context.Context) (output string, passed bool)
+}
+
+// CommandTestGate is a TestGate that runs a command in a directory. The
+// tests pass when it exits zero.
+type CommandTestGate struct {
+	Dir     string
+	Command []string      // Program and arguments, e.g. go test ./...
+	Timeout time.Duration // 0 = DefaultTestGateTimeout
+}
+
+// Run runs the command, reporting a command that can't be started or times
+// out as failed tests.
+func (g CommandTestGate) Run(ctx context.Context) (string, bool) {
+	if len(g.Command) == 0 {
+		return "no test command configured", false
+	}
+	timeout := g.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTestGateTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, g.Command[0], g.Command[1:]...)
+	cmd.Dir = g.Dir
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+
+	output := strings.TrimSpace(out.String())
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		output = strings.TrimSpace(fmt.Sprintf("%s\n[timed out after %s]", output, timeout))
+	case err != nil && !errors.As(err, &exitErr):
+		output = fmt.Sprintf("failed to run %s: %v", g.Command[0], err)
+	}
+	return output, err == nil
+}
+
+// startReviewerTestChange moves the working copy onto a new jj change for
+// the reviewer's tests before the final review of a done signal, when a test
+// gate is configured. The change is attributed to the reviewer with
+// trailers, so its edits stay apart from the developer's. It records and
+// returns the change's ID, or "" when the review doesn't author tests.
+func (l *Loop) startReviewerTestChange(ctx context.Context, finalReview bool) string {
+	l.reviewerTestChange = ""
+	if l.deps.TestGate == nil || !finalReview {
+		return ""
+	}
+
+	description := jj.AppendTrailers(
+		fmt.Sprintf("Add reviewer's tests (iteration %d)", l.iteration),
+		[]jj.Trailer{
+			{Key: "Authored-by", Value: reviewerTrailerName},
+			{Key: "Plan-ID", Value: l.cfg.PlanID},
+		},
+	)
+	if err := l.deps.VCS.New(ctx, description); err != nil {
+		log.Warn("failed to start a change for the reviewer's tests", "error", err)
+		return ""
+	}
+	changeID, err := l.deps.VCS.GetCurrentChangeID(ctx)
+	if err != nil {
+		log.Warn("failed to get the reviewer's test change", "error", err)
+		return ""
+	}
+	l.reviewerTestChange = changeID
+	return changeID
+}
+
+// verifyReviewerTests checks the tests the reviewer added in its own change
+// since snapshot, taken when the change was started. An approval stands only
+// if the reviewer added tests that pass with the developer's change and fail
+// without it. A change the reviewer left empty is abandoned; tests the
+// reviewer kept are left in their change, and the developer continues in a
+// new one. It returns the feedback for the developer, or "" if the approval
+// stands or there was none.
+func (l *Loop) verifyReviewerTests(ctx context.Context, snapshot string, approved bool) (string, error) {
+	changeID := l.reviewerTestChange
+	l.reviewerTestChange = ""
+
+	files := l.changedSince(ctx, snapshot)
+	if len(files) == 0 {
+		l.abandonReviewerTestChange(ctx)
+		if !approved {
+			return "", nil
+		}
+		l.emit(NewEvent(EventDoneRejected, l.iteration, l.effectiveMaxIter(),
+			"Reviewer approved without adding a test of the change"))
+		return "The reviewer approved without adding a test of your change, so the approval was discarded. " +
+			"Nothing needs to change: signal DEV_DONE again for a fresh review.", nil
+	}
+	if !approved {
+		l.leaveReviewerTestChange(ctx)
+		return "", nil
+	}
+
+	output, passed := l.deps.TestGate.Run(ctx)
+	if !passed {
+		l.leaveReviewerTestChange(ctx)
+		l.emit(NewEvent(EventDoneRejected, l.iteration, l.effectiveMaxIter(),
+			fmt.Sprintf("Reviewer approved, but its tests in change %s fail", changeID)))
+		return fmt.Sprintf("The reviewer approved and added tests in jj change %s (%s), but the tests fail with your change, "+
+			"so the approval was discarded. Fix the code, or the tests if they are wrong, and signal DEV_DONE again:\n\n```\n%s\n```",
+			changeID, strings.Join(files, ", "), trimTestOutput(output, maxTestGateOutputBytes)), nil
+	}
+
+	failsWithout, err := l.failsWithoutChange(ctx, snapshot, files)
+	if err != nil {
+		return "", err
+	}
+	if !failsWithout {
+		l.abandonReviewerTestChange(ctx)
+		l.emit(NewEvent(EventDoneRejected, l.iteration, l.effectiveMaxIter(),
+			"Reviewer approved, but its tests also pass without the change"))
+		return "The reviewer approved, but its new tests also pass without your change, so they were dropped and the approval was discarded. " +
+			"Nothing needs to change: signal DEV_DONE again for a fresh review.", nil
+	}
+
+	l.leaveReviewerTestChange(ctx)
+	l.emit(NewEvent(EventReviewerTests, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Reviewer's tests in change %s fail without the change and pass with it: %s", changeID, strings.Join(files, ", "))))
+	return "", nil
+}
+
+// failsWithoutChange runs the test gate with the developer's changes since
+// the review base reverted, keeping the reviewer's test files, and restores
+// them afterwards. Files both changed keep their reviewed content. When the
+// developer changed nothing else, there is nothing to revert and the tests
+// count as failing without the change.
+func (l *Loop) failsWithoutChange(ctx context.Context, snapshot string, testFiles []string) (bool, error) {
+	base := l.reviewBaseChangeID()
+	if base == "" {
+		log.Warn("no base change to run the reviewer's tests without the change")
+		return true, nil
+	}
+	devFiles, err := l.deps.VCS.ChangedFiles(ctx, base, snapshot)
+	if err != nil {
+		return false, fmt.Errorf("failed to list the developer's changed files: %w", err)
+	}
+	devFiles = slices.DeleteFunc(devFiles, func(f string) bool { return slices.Contains(testFiles, f) })
+	if len(devFiles) == 0 {
+		return true, nil
+	}
+
+	reviewed := l.workingCopySnapshot(ctx)
+	if reviewed == "" {
+		return false, errors.New("failed to snapshot the reviewer's tests")
+	}
+	if err := l.deps.VCS.Restore(ctx, base, devFiles...); err != nil {
+		return false, fmt.Errorf("failed to revert the developer's change for the reviewer's tests: %w", err)
+	}
+	_, passed := l.deps.TestGate.Run(ctx)
+	if err := l.deps.VCS.Restore(ctx, reviewed, devFiles...); err != nil {
+		return false, fmt.Errorf("failed to restore the developer's change after the reviewer's tests: %w", err)
+	}
+	return !passed, nil
+}
+
+// abandonReviewerTestChange drops the reviewer's test change and its edits.
+func (l *Loop) abandonReviewerTestChange(ctx context.Context) {
+	if err := l.deps.VCS.Abandon(ctx, "@"); err != nil {
+		log.Warn("failed to abandon the reviewer's test change", "error", err)
+	}
+}
+
+// leaveReviewerTestChange starts a fresh change on top of the reviewer's
+// tests for the developer to continue in.
+func (l *Loop) leaveReviewerTestChange(ctx context.Context) {
+	if err := l.deps.VCS.New(ctx, ""); err != nil {
+		log.Warn("failed to start a change after the reviewer's tests", "error", err)
+	}
+}
@@ -0,0 +1,168 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+// fakeTestGate reports the next result of passes on each run.
+type fakeTestGate struct {
+	mu     sync.Mutex
+	passes []bool
+	runs   int
+}
+
+func (g *fakeTestGate) Run(ctx context.Context) (string, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	passed := g.runs < len(g.passes) && g.passes[g.runs]
+	g.runs++
+	return fmt.Sprintf("run %d", g.runs), passed
+}
+
+// runReviewerTestsLoop runs one iteration in which the developer signals
+// DEV_DONE without edits and the reviewer approves, adding a test file when
+// reviewerEdits is set. It returns the jj commands run besides log and diff.
+func runReviewerTestsLoop(t *testing.T, gate *fakeTestGate, reviewerEdits bool) (*db.DB, *db.Plan, []Event, []string) {
+	t.Helper()
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	var mu sync.Mutex
+	calls, edits := 0, 0
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		output := "## Progress\nDone\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!"
+		if calls%2 == 0 {
+			output = "## Progress\nReviewed\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!"
+			if reviewerEdits {
+				edits++
+			}
+		}
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput(output))
+	}
+
+	var commands []string
+	runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case len(args) >= 5 && args[0] == "log" && args[2] == "@-":
+			return "base\n", "", nil
+		case len(args) >= 5 && args[0] == "log" && args[4] == "commit_id":
+			return fmt.Sprintf("commit-%d\n", edits), "", nil
+		case len(args) >= 5 && args[0] == "log" && args[4] == "change_id":
+			return "reviewtests\n", "", nil
+		case len(args) >= 6 && args[0] == "diff" && args[1] == "--name-only":
+			// The developer's change, then the reviewer's test
+			if args[3] == "base" {
+				return "api.go\n", "", nil
+			}
+			return "api_test.go\n", "", nil
+		case args[0] == "log" || args[0] == "diff":
+			return "", "", nil
+		}
+		commands = append(commands, strings.Join(args, " "))
+		return "", "", nil
+	}
+
+	deps := testDeps(database, creator, runner)
+	deps.TestGate = gate
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 1, WorkDir: "/tmp"}, deps)
+
+	events := runLoop(t, loop)
+	return database, plan, events, commands
+}
+
+// hasCommand reports whether a jj command starting with prefix was run.
+func hasCommand(commands []string, prefix string) bool {
+	return slices.ContainsFunc(commands, func(c string) bool { return strings.HasPrefix(c, prefix) })
+}
+
+func TestLoop_ReviewerTestsAccepted(t *testing.T) {
+	gate := &fakeTestGate{passes: []bool{true, false}}
+	_, _, events, commands := runReviewerTestsLoop(t, gate, true)
+
+	rejections, completed := doneRejections(events)
+	if len(rejections) > 0 || !completed {
+		t.Fatalf("expected the plan to complete, got rejections %q", rejections)
+	}
+	if gate.runs != 2 {
+		t.Errorf("expected the tests to run with and without the change, ran %d times", gate.runs)
+	}
+	if !slices.ContainsFunc(events, func(e Event) bool { return e.Type == EventReviewerTests }) {
+		t.Error("expected an EventReviewerTests event")
+	}
+
+	if !hasCommand(commands, "new -m Add reviewer's tests (iteration 1)") ||
+		!slices.ContainsFunc(commands, func(c string) bool { return strings.Contains(c, "Authored-by: ralph-reviewer") }) {
+		t.Errorf("expected a change attributed to the reviewer, got %q", commands)
+	}
+	if !hasCommand(commands, `restore --from base -- root-file:"api.go"`) ||
+		!hasCommand(commands, `restore --from commit-1 -- root-file:"api.go"`) {
+		t.Errorf("expected the developer's change to be reverted and restored, got %q", commands)
+	}
+	if hasCommand(commands, "abandon") {
+		t.Errorf("expected the reviewer's tests to be kept, got %q", commands)
+	}
+}
+
+func TestLoop_ReviewerTestsRejected(t *testing.T) {
+	tests := []struct {
+		name          string
+		passes        []bool
+		reviewerEdits bool
+		rejection     string
+		feedback      string
+		abandoned     bool
+	}{
+		{"no test", nil, false, "without adding a test", "without adding a test", true},
+		{"fails with the change", []bool{false}, true, "fail", "fail with your change", false},
+		{"passes without the change", []bool{true, true}, true, "also pass without the change", "also pass without your change", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, plan, events, commands := runReviewerTestsLoop(t, &fakeTestGate{passes: tt.passes}, tt.reviewerEdits)
+
+			rejections, completed := doneRejections(events)
+			if completed {
+				t.Error("expected the approval to be discarded")
+			}
+			if len(rejections) != 1 || !strings.Contains(rejections[0], tt.rejection) {
+				t.Errorf("unexpected rejections: %q", rejections)
+			}
+			if got := hasCommand(commands, "abandon @"); got != tt.abandoned {
+				t.Errorf("abandoned = %v, want %v (commands %q)", got, tt.abandoned, commands)
+			}
+
+			feedback, err := database.GetLatestReviewerFeedback(plan.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if feedback == nil || !strings.Contains(feedback.Content, tt.feedback) {
+				t.Errorf("expected feedback containing %q, got %+v", tt.feedback, feedback)
+			}
+		})
+	}
+}
+
+func TestCommandTestGate(t *testing.T) {
+	if output, passed := (CommandTestGate{Command: []string{"sh", "-c", "echo ok"}}).Run(context.Background()); !passed || output != "ok" {
+		t.Errorf("Run() = %q, %v; want ok, true", output, passed)
+	}
+	if output, passed := (CommandTestGate{Command: []string{"sh", "-c", "echo FAIL; exit 1"}}).Run(context.Background()); passed || output != "FAIL" {
+		t.Errorf("Run() = %q, %v; want FAIL, false", output, passed)
+	}
+	if output, passed := (CommandTestGate{Command: []string{"ralph-no-such-command"}}).Run(context.Background()); passed || !strings.Contains(output, "failed to run") {
+		t.Errorf("Run() = %q, %v; want a failure to run", output, passed)
+	}
+}
@@ -0,0 +1,40 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gerunddev/ralph/internal/policy"
+)
+
+// scopePaths returns the paths diffs are limited to: the plan's scope, or
+// none for the whole repository.
+func (l *Loop) scopePaths() []string {
+	if l.cfg.Scope == "" {
+		return nil
+	}
+	return []string{l.cfg.Scope}
+}
+
+// checkScope finds the developer's changes to files outside the plan's
+// scope, which are handled like changes outside the "files:" allowlist:
+// restored from the plan's base change, or with FlagOutOfScopeFiles kept,
+// and listed in the next developer prompt.
+func (l *Loop) checkScope(ctx context.Context) error {
+	if l.cfg.Scope == "" || l.baseChangeID == "" {
+		return nil
+	}
+
+	files, err := l.deps.VCS.ChangedFiles(ctx, l.baseChangeID, "@")
+	if err != nil {
+		return fmt.Errorf("failed to list changed files for scope check: %w", err)
+	}
+	outOfScope := policy.New([]string{l.cfg.Scope + "/"}, nil, true).CheckPaths(files)
+	if len(outOfScope) == 0 {
+		return nil
+	}
+
+	l.handleOutOfScope(ctx, outOfScope, "the plan's scope",
+		"the plan is scoped to "+l.cfg.Scope+"/")
+	return nil
+}
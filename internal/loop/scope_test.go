@@ -0,0 +1,105 @@
+package loop
+
+import (
+	"context"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+func TestLoop_ScopeLimitsDiffsAndRevertsChangesOutsideIt(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "# Plan\nAdd the endpoint")
+
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		output := "## Progress\nWorking\n\n## Learnings\nNone\n\n### Verdict\nNEEDS_WORK\n\nKeep going"
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutput(output))
+	}
+
+	var mu sync.Mutex
+	var restores, diffs [][]string
+	runner := func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		scoped := slices.Contains(args, `root:"services/api"`)
+		switch {
+		case len(args) >= 3 && args[0] == "log" && args[2] == "@-":
+			return "base123", "", nil
+		case len(args) >= 2 && args[0] == "diff" && args[1] == "--name-only":
+			if scoped {
+				return "services/api/main.go\n", "", nil
+			}
+			return "services/api/main.go\nservices/web/app.ts\n", "", nil
+		case len(args) > 0 && args[0] == "diff":
+			mu.Lock()
+			diffs = append(diffs, args)
+			mu.Unlock()
+			return "diff of services/api/main.go", "", nil
+		case len(args) > 0 && args[0] == "restore":
+			mu.Lock()
+			restores = append(restores, args)
+			mu.Unlock()
+		}
+		return "", "", nil
+	}
+
+	loop := New(Config{
+		PlanID:        plan.ID,
+		MaxIterations: 2,
+		WorkDir:       "/tmp",
+		Scope:         "services/api",
+	}, testDeps(database, creator, runner))
+
+	var outOfScope []Event
+	events := runLoop(t, loop)
+	for _, event := range events {
+		if event.Type == EventFilesOutOfScope {
+			outOfScope = append(outOfScope, event)
+		}
+	}
+
+	want := []string{"restore", "--from", "base123", "--", `root-file:"services/web/app.ts"`}
+	if len(restores) == 0 || !slices.Equal(restores[0], want) {
+		t.Errorf("restores = %v, want %v", restores, want)
+	}
+	if len(outOfScope) == 0 || !strings.Contains(outOfScope[0].Message, "outside the plan's scope reverted: services/web/app.ts") {
+		t.Errorf("expected a scope event for services/web/app.ts, got %+v", outOfScope)
+	}
+	if len(diffs) == 0 {
+		t.Fatal("expected a reviewer diff")
+	}
+	for _, args := range diffs {
+		if !slices.Contains(args, `root:"services/api"`) {
+			t.Errorf("diff not limited to the scope: %v", args)
+		}
+	}
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var devPrompts []string
+	var reviewerPrompt string
+	for _, s := range sessions {
+		switch s.AgentType {
+		case db.LoopAgentDeveloper:
+			devPrompts = append(devPrompts, s.InputPrompt)
+		case db.LoopAgentReviewer:
+			reviewerPrompt = s.InputPrompt
+		}
+	}
+	if len(devPrompts) != 2 {
+		t.Fatalf("expected 2 developer sessions, got %d", len(devPrompts))
+	}
+	if !strings.Contains(devPrompts[0], "# Scope") || !strings.Contains(devPrompts[0], "`services/api/`") {
+		t.Errorf("developer prompt should name the scope:\n%s", devPrompts[0])
+	}
+	if !strings.Contains(devPrompts[1], "- services/web/app.ts") || !strings.Contains(devPrompts[1], "the plan is scoped to services/api/") {
+		t.Errorf("second developer prompt should list the reverted change:\n%s", devPrompts[1])
+	}
+	if !strings.Contains(reviewerPrompt, "# Scope") {
+		t.Errorf("reviewer prompt should name the scope:\n%s", reviewerPrompt)
+	}
+}
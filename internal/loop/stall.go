@@ -0,0 +1,45 @@
+package loop
+
+import (
+	"crypto/sha256"
+	"strings"
+)
+
+// stallDetector tracks whether successive iterations are making progress.
+// An iteration is stalled when the cumulative diff is unchanged from the
+// previous iteration and the developer reported no new progress. The first
+// iteration is compared with an empty diff and no progress, so it counts
+// too when it changes nothing.
+type stallDetector struct {
+	diffHash     [sha256.Size]byte
+	progressHash [sha256.Size]byte
+	seen         bool
+	count        int // Consecutive stalled iterations
+}
+
+// emptyHash is the hash of an empty diff or progress.
+var emptyHash = sha256.Sum256(nil)
+
+// observe records an iteration's diff and progress and returns the number of
+// consecutive stalled iterations (0 if this iteration made progress).
+func (s *stallDetector) observe(diff, progress string) int {
+	diffHash := sha256.Sum256([]byte(strings.TrimSpace(diff)))
+	progress = strings.TrimSpace(progress)
+	progressHash := sha256.Sum256([]byte(progress))
+
+	if !s.seen {
+		s.diffHash, s.progressHash = emptyHash, emptyHash
+	}
+	if diffHash == s.diffHash && (progress == "" || progressHash == s.progressHash) {
+		s.count++
+	} else {
+		s.count = 0
+	}
+
+	s.diffHash = diffHash
+	if progress != "" {
+		s.progressHash = progressHash
+	}
+	s.seen = true
+	return s.count
+}
@@ -0,0 +1,37 @@
+package loop
+
+import "testing"
+
+func TestStallDetector_Observe(t *testing.T) {
+	tests := []struct {
+		name     string
+		diff     string
+		progress string
+		want     int
+	}{
+		{"first iteration", "diff A", "started", 0},
+		{"same diff and progress", "diff A", "started", 1},
+		{"same diff, empty progress", "diff A", "", 2},
+		{"same diff, whitespace only changes", "  diff A\n", "started", 3},
+		{"same diff, new progress", "diff A", "tried something else", 0},
+		{"same diff and progress again", "diff A", "tried something else", 1},
+		{"new diff", "diff B", "tried something else", 0},
+	}
+
+	var s stallDetector
+	for _, tt := range tests {
+		if got := s.observe(tt.diff, tt.progress); got != tt.want {
+			t.Errorf("%s: observe() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStallDetector_FirstIterationWithoutChanges(t *testing.T) {
+	var s stallDetector
+	if got := s.observe("", ""); got != 1 {
+		t.Errorf("observe() = %d, want the first iteration counted as stalled", got)
+	}
+	if got := s.observe("", ""); got != 2 {
+		t.Errorf("observe() = %d, want 2", got)
+	}
+}
@@ -0,0 +1,55 @@
+package loop
+
+import (
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/mcp"
+	"github.com/gerunddev/ralph/internal/parser"
+)
+
+// recordToolReports handles the calls to ralph's own tools among a
+// session's transcript entries: it keeps the last valid ralph_status call
+// and stores record_progress checkpoints as they arrive. Calls made by
+// sub-agents aren't the session's.
+func (l *Loop) recordToolReports(sessionID string, entries []claude.TranscriptEntry) {
+	for _, entry := range entries {
+		if entry.Kind != claude.TranscriptToolUse || entry.SubAgentID != "" {
+			continue
+		}
+		switch {
+		case l.cfg.StatusTool && parser.IsStatusTool(entry.ToolName):
+			l.recordStatusReport(sessionID, entry.Content)
+		case l.cfg.StateTools && mcp.IsTool(entry.ToolName, mcp.RecordProgressToolName):
+			progress, err := mcp.ParseRecordProgress([]byte(entry.Content))
+			if err != nil {
+				log.Debug("ignoring invalid progress checkpoint", "session", sessionID, "error", err)
+				continue
+			}
+			l.storeProgressLearnings(sessionID, progress, "")
+		}
+	}
+}
+
+// recordStatusReport keeps a session's status tool call if it is valid.
+func (l *Loop) recordStatusReport(sessionID, input string) {
+	report, err := parser.ParseStatusReport([]byte(input))
+	if err != nil {
+		// The tool told the agent; a corrected call may follow
+		log.Debug("ignoring invalid status report", "session", sessionID, "error", err)
+		return
+	}
+	if l.statusReports == nil {
+		l.statusReports = make(map[string]*parser.StatusReport)
+	}
+	l.statusReports[sessionID] = report
+}
+
+// parseOutput parses a session's output for the given agent type, from its
+// status tool call when it made one and from the markdown sections
+// otherwise.
+func (l *Loop) parseOutput(sessionID, output, agentType string) *parser.AgentParseResult {
+	if report := l.statusReports[sessionID]; report != nil {
+		return report.Result(agentType, output)
+	}
+	return parser.ParseAgentOutput(output, agentType)
+}
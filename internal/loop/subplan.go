@@ -0,0 +1,216 @@
+package loop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/google/uuid"
+)
+
+// proposedSubPlan is a sub-plan a developer session proposed.
+type proposedSubPlan struct {
+	content   string
+	sessionID string // The developer session that proposed it
+}
+
+// subPlansAllowed reports whether this plan's developer may propose
+// sub-plans: whether they would still be within MaxSubPlanDepth.
+func (l *Loop) subPlansAllowed() bool {
+	return l.cfg.SubPlanDepth < l.cfg.MaxSubPlanDepth
+}
+
+// proposeSubPlan keeps the sub-plan a developer session proposed until the
+// iteration ends. Proposals beyond the depth limit are ignored.
+func (l *Loop) proposeSubPlan(sessionID, content string) {
+	if content == "" {
+		return
+	}
+	if !l.subPlansAllowed() {
+		log.Warn("ignoring sub-plan beyond the depth limit", "depth", l.cfg.SubPlanDepth)
+		return
+	}
+	l.subPlan = &proposedSubPlan{content: content, sessionID: sessionID}
+}
+
+// subPlanEvents are the events of a sub-plan's loop that are about its run
+// as a whole, which aren't forwarded to this loop's subscribers: they would
+// read as this plan's.
+var subPlanEvents = map[EventType]bool{
+	EventStarted:        true,
+	EventIterationStart: true,
+	EventIterationEnd:   true,
+	EventCost:           true,
+	EventBothDone:       true,
+	EventDone:           true,
+	EventMaxIterations:  true,
+	EventMaxDuration:    true,
+	EventMaxCost:        true,
+	EventPaused:         true,
+	EventFailed:         true,
+}
+
+// runSubPlan records the sub-plan proposed this iteration, if any, as a
+// plan linked to this one. With RunSubPlans it then runs the sub-plan to
+// its end, within what is left of this plan's iterations, time, and cost,
+// forwarding its events, and adds its cost and learnings to this plan's.
+// Only cancellation is returned; a sub-plan that fails leaves this plan to
+// carry on.
+func (l *Loop) runSubPlan(ctx context.Context) error {
+	proposal := l.subPlan
+	l.subPlan = nil
+	if proposal == nil {
+		return nil
+	}
+
+	child := &db.Plan{
+		ID:           uuid.New().String(),
+		Content:      proposal.content,
+		WorkDir:      l.plan.WorkDir,
+		ParentPlanID: l.cfg.PlanID,
+	}
+	if err := l.deps.DB.CreatePlan(child); err != nil {
+		log.Warn("failed to create sub-plan", "error", err)
+		return nil
+	}
+	title := planTitle(child.Content)
+
+	if !l.cfg.RunSubPlans {
+		l.emit(NewEvent(EventSubPlanCreated, l.iteration, l.effectiveMaxIter(),
+			fmt.Sprintf("Recorded sub-plan %q; run it with ralph --resume %s", title, child.ID)))
+		return nil
+	}
+	cfg, reason := l.subPlanConfig(child.ID)
+	if reason != "" {
+		l.emit(NewEvent(EventSubPlanCreated, l.iteration, l.effectiveMaxIter(),
+			fmt.Sprintf("Recorded sub-plan %q without running it (%s); run it with ralph --resume %s", title, reason, child.ID)))
+		return nil
+	}
+
+	l.emit(NewEvent(EventSubPlanCreated, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Running sub-plan %q (%s)", title, child.ID)))
+	subLoop := New(cfg, l.subPlanDeps())
+	forwarded := l.forwardEvents(subLoop)
+	err := subLoop.Run(ctx)
+	<-forwarded
+	l.usage.CostUSD += subLoop.usage.CostUSD
+	l.usage.Tokens += subLoop.usage.Tokens
+	l.emitUsage()
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		log.Warn("sub-plan failed", "plan", child.ID, "error", err)
+	}
+
+	status := "ended"
+	if finished, getErr := l.deps.DB.GetPlan(child.ID); getErr == nil {
+		status = string(finished.Status)
+	}
+	l.rollUpLearnings(child.ID, title, status, proposal.sessionID)
+	l.emit(NewEvent(EventSubPlanFinished, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Sub-plan %q %s; resuming the plan", title, status)))
+	return nil
+}
+
+// subPlanConfig is the configuration a sub-plan runs with: this plan's,
+// one level deeper, limited to what is left of this plan's iterations,
+// time, and cost, without the options that belong to the top-level plan
+// (its plan file, bookmark, squash, and extreme mode). It also returns why
+// the sub-plan can't run when nothing is left of one of them.
+func (l *Loop) subPlanConfig(planID string) (Config, string) {
+	cfg := l.cfg
+	cfg.PlanID = planID
+	cfg.SubPlanDepth++
+	cfg.ExtremeMode = false
+	cfg.IterationsThisRun = 0
+	cfg.WatchPlan = false
+	cfg.MergePlanEdits = false
+	cfg.SquashOnComplete = false
+	cfg.Bookmark = ""
+	cfg.DeleteBookmark = false
+
+	// Iterations are only limited once extreme mode has triggered
+	if !l.cfg.ExtremeMode || l.extremeRounds > 0 {
+		cfg.MaxIterations = l.cfg.MaxIterations - l.iteration
+		if cfg.MaxIterations <= 0 {
+			return cfg, "no iterations left"
+		}
+	}
+	if !l.deadline.IsZero() {
+		cfg.MaxDuration = time.Until(l.deadline)
+		if cfg.MaxDuration <= 0 {
+			return cfg, "no time left"
+		}
+	}
+	if l.cfg.MaxCost > 0 {
+		cfg.MaxCost = l.cfg.MaxCost - l.usage.CostUSD
+		if cfg.MaxCost <= 0 {
+			return cfg, "no budget left"
+		}
+	}
+	return cfg, ""
+}
+
+// forwardEvents publishes a sub-plan loop's events, but for those about its
+// run as a whole, to this loop's subscribers under the current iteration.
+// The returned channel is closed once the sub-plan's loop has completed
+// and every event was forwarded.
+func (l *Loop) forwardEvents(sub *Loop) <-chan struct{} {
+	events := sub.Subscribe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range events.Events() {
+			if subPlanEvents[event.Type] {
+				continue
+			}
+			event.Iteration = l.CurrentIteration()
+			event.MaxIter = l.effectiveMaxIter()
+			l.emit(event)
+		}
+	}()
+	return done
+}
+
+// subPlanDeps are this loop's dependencies, with the jj client it was
+// given rather than the timed wrapper, which the sub-plan's loop adds.
+func (l *Loop) subPlanDeps() Deps {
+	deps := l.deps
+	if timed, ok := deps.VCS.(timedVCS); ok {
+		deps.VCS = timed.vcs
+	}
+	return deps
+}
+
+// rollUpLearnings adds a finished sub-plan's learnings to this plan's
+// latest learnings, where the next developer prompt picks them up.
+func (l *Loop) rollUpLearnings(childID, title, status, sessionID string) {
+	childLearnings, err := l.deps.DB.GetLatestLearnings(childID)
+	if err != nil || childLearnings == nil || strings.TrimSpace(childLearnings.Content) == "" {
+		return
+	}
+
+	var current *db.Learnings
+	if l.task != nil {
+		current, err = l.deps.DB.GetLatestTaskLearnings(l.cfg.PlanID, l.task.ID)
+	} else {
+		current, err = l.deps.DB.GetLatestLearnings(l.cfg.PlanID)
+	}
+	if err != nil {
+		log.Warn("failed to get learnings for sub-plan roll-up", "error", err)
+		return
+	}
+
+	var b strings.Builder
+	if current != nil && strings.TrimSpace(current.Content) != "" {
+		b.WriteString(strings.TrimSpace(current.Content))
+		b.WriteString("\n\n")
+	}
+	fmt.Fprintf(&b, "From sub-plan %q (%s):\n%s", title, status, strings.TrimSpace(childLearnings.Content))
+	l.storeProgressLearnings(sessionID, "", b.String())
+}
@@ -0,0 +1,151 @@
+package loop
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+// runSubPlanLoop runs a plan whose first developer session proposes a
+// sub-plan. Sessions answer in order: the plan's developer and reviewer,
+// then, when the sub-plan runs, its developer and reviewer, then the plan's
+// developer and reviewer again. It returns the prompts of every session.
+func runSubPlanLoop(t *testing.T, run bool, maxIterations int) (*db.DB, *db.Plan, []string, []Event) {
+	t.Helper()
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Add login")
+
+	const approved = "## Progress\nReviewed\n\n### Critical Issues\nNone\n\n### Major Issues\nNone\n\n### Minor Issues\nNone\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!"
+	outputs := []string{
+		"## Progress\nLogin needs the auth rewrite\n\n## Learnings\nLogin lives in web/\n\n" +
+			"## Sub-Plan\n```markdown\n# Rewrite auth\n\nSplit tokens from sessions\n```\n\n## Status\nRUNNING RUNNING RUNNING",
+		"## Progress\nReviewed\n\n### Critical Issues\nNone\n\n### Major Issues\n- Finish the login form\n\n### Minor Issues\nNone\n\n### Verdict\nREVIEWER_FEEDBACK: Finish the login form",
+	}
+	if run {
+		outputs = append(outputs,
+			"## Progress\nRewrote auth\n\n## Learnings\nTokens live in auth/token.go\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!",
+			approved)
+	}
+	outputs = append(outputs, "## Progress\nDone\n\n## Status\nDEV_DONE DEV_DONE DEV_DONE!!!", approved)
+
+	var prompts []string
+	creator := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		prompts = append(prompts, args[len(args)-1])
+		output := outputs[min(len(prompts), len(outputs))-1]
+		return exec.CommandContext(ctx, "echo", createMockClaudeOutputWithToolUse(output, "Read"))
+	}
+	runner := mockJJRunnerWithDiff("base123", "diff --git a/web/login.go b/web/login.go\n+func login() {}\n")
+
+	loop := New(Config{
+		PlanID:          plan.ID,
+		MaxIterations:   maxIterations,
+		WorkDir:         "/tmp",
+		MaxSubPlanDepth: 1,
+		RunSubPlans:     run,
+	}, testDeps(database, creator, runner))
+	events := runLoop(t, loop)
+	return database, plan, prompts, events
+}
+
+func TestLoop_SubPlan(t *testing.T) {
+	database, plan, prompts, events := runSubPlanLoop(t, true, 5)
+
+	if len(prompts) != 6 {
+		t.Fatalf("expected 6 sessions, got %d", len(prompts))
+	}
+	if !strings.Contains(prompts[0], "## Sub-Plan") {
+		t.Error("the plan's developer should be offered sub-plans")
+	}
+	if !strings.Contains(prompts[2], "Split tokens from sessions") || strings.Contains(prompts[2], "# Sub-Plans") {
+		t.Errorf("the sub-plan's developer should work on the sub-plan, without nesting further:\n%s", prompts[2])
+	}
+	if !strings.Contains(prompts[4], "Tokens live in auth/token.go") || !strings.Contains(prompts[4], "Login lives in web/") {
+		t.Errorf("the plan's next developer prompt should have its own and the sub-plan's learnings:\n%s", prompts[4])
+	}
+
+	subPlans, err := database.GetSubPlans(plan.ID)
+	if err != nil || len(subPlans) != 1 {
+		t.Fatalf("GetSubPlans() = %v, %v; want one sub-plan", subPlans, err)
+	}
+	if subPlans[0].Status != db.PlanStatusCompleted {
+		t.Errorf("sub-plan status = %s, want completed", subPlans[0].Status)
+	}
+	if got, err := database.GetPlan(plan.ID); err != nil || got.Status != db.PlanStatusCompleted {
+		t.Errorf("plan = %+v, %v; want it completed after the sub-plan", got, err)
+	}
+
+	// The sub-plan's sessions are forwarded, and cost the plan
+	var created, finished bool
+	var developers int
+	var usage *PlanUsage
+	for _, event := range events {
+		switch event.Type {
+		case EventDeveloperStart:
+			developers++
+		case EventCost:
+			usage = event.Usage
+		case EventSubPlanCreated:
+			created = strings.Contains(event.Message, `Running sub-plan "Rewrite auth"`)
+		case EventSubPlanFinished:
+			finished = strings.Contains(event.Message, "completed")
+		case EventDone:
+			if event.Message != "Agent completed" {
+				t.Errorf("unexpected done event from the sub-plan: %q", event.Message)
+			}
+		}
+	}
+	if !created || !finished {
+		t.Errorf("expected sub-plan created and finished events (created %v, finished %v)", created, finished)
+	}
+	if developers != 3 {
+		t.Errorf("expected 3 developer start events with the sub-plan's, got %d", developers)
+	}
+	if usage == nil || usage.CostUSD < 0.0059 || usage.CostUSD > 0.0061 {
+		t.Errorf("plan usage = %+v, want the cost of all 6 sessions", usage)
+	}
+}
+
+func TestLoop_SubPlanWithoutIterationsLeft(t *testing.T) {
+	database, plan, prompts, events := runSubPlanLoop(t, true, 1)
+
+	if len(prompts) != 2 {
+		t.Fatalf("expected the sub-plan not to run on the last iteration, got %d sessions", len(prompts))
+	}
+	subPlans, err := database.GetSubPlans(plan.ID)
+	if err != nil || len(subPlans) != 1 || subPlans[0].Status != db.PlanStatusPending {
+		t.Fatalf("GetSubPlans() = %v, %v; want one pending sub-plan", subPlans, err)
+	}
+	recorded := false
+	for _, event := range events {
+		if event.Type == EventSubPlanCreated && strings.Contains(event.Message, "without running it (no iterations left)") {
+			recorded = true
+		}
+	}
+	if !recorded {
+		t.Error("expected an event saying the sub-plan was recorded for lack of iterations")
+	}
+}
+
+func TestLoop_SubPlanRecorded(t *testing.T) {
+	database, plan, prompts, events := runSubPlanLoop(t, false, 5)
+
+	if len(prompts) != 4 {
+		t.Fatalf("expected 4 sessions without running the sub-plan, got %d", len(prompts))
+	}
+	subPlans, err := database.GetSubPlans(plan.ID)
+	if err != nil || len(subPlans) != 1 || subPlans[0].Status != db.PlanStatusPending {
+		t.Fatalf("GetSubPlans() = %v, %v; want one pending sub-plan", subPlans, err)
+	}
+	recorded := false
+	for _, event := range events {
+		if event.Type == EventSubPlanCreated && strings.Contains(event.Message, "ralph --resume "+subPlans[0].ID) {
+			recorded = true
+		}
+	}
+	if !recorded {
+		t.Error("expected an event telling how to run the recorded sub-plan")
+	}
+}
@@ -0,0 +1,206 @@
+package loop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/parser"
+)
+
+// errNoTasks is returned when the planner output contains no task list.
+var errNoTasks = errors.New("planner produced no tasks")
+
+// loadTasks loads the plan's tasks from an earlier run or, when decomposition
+// is enabled and there are none yet, runs the planner agent to create them.
+// Leaves l.tasks empty when the plan is not worked as tasks.
+func (l *Loop) loadTasks(ctx context.Context) error {
+	tasks, err := l.deps.DB.GetTasksByProject(l.cfg.PlanID)
+	if err != nil {
+		return fmt.Errorf("failed to load tasks: %w", err)
+	}
+
+	if len(tasks) == 0 && l.cfg.Decompose {
+		tasks, err = l.planTasks(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	l.tasks = tasks
+	return nil
+}
+
+// planTasks runs the planner agent and stores the tasks it produces.
+func (l *Loop) planTasks(ctx context.Context) ([]*db.Task, error) {
+	l.emit(NewEvent(EventPlanningStart, l.iteration, l.effectiveMaxIter(), "Breaking plan into tasks"))
+
+	l.startSessionTimer()
+	customPrompt, err := l.agentPrompt("planner")
+	if err != nil {
+		return nil, fmt.Errorf("failed to build planner prompt: %w", err)
+	}
+	prompt, err := agent.BuildPlannerPrompt(agent.PlannerContext{PlanContent: l.plan.Content, Locale: l.cfg.Locale, Template: customPrompt})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build planner prompt: %w", err)
+	}
+	l.promptBuilt()
+
+	l.emit(NewPromptBuiltEvent(l.iteration, l.effectiveMaxIter(), prompt))
+
+	sessionID := uuid.New().String()
+	session := &db.PlanSession{
+		ID:          sessionID,
+		PlanID:      l.cfg.PlanID,
+		Iteration:   l.iteration,
+		InputPrompt: prompt,
+		Status:      db.PlanSessionRunning,
+		AgentType:   db.LoopAgentPlanner,
+	}
+	if err := l.deps.DB.CreatePlanSession(session); err != nil {
+		return nil, fmt.Errorf("failed to create planner session: %w", err)
+	}
+
+	// The planner only reads the plan and the repository, so it runs with
+	// the reviewer's CLI options rather than the developer's
+	output, err := l.runClaudeSession(ctx, sessionID, prompt, l.reviewerClient())
+	if err != nil {
+		return nil, fmt.Errorf("planner agent failed: %w", err)
+	}
+
+	parseStart := time.Now()
+	planned := parser.ParseTasks(output)
+	l.finishSessionTimer(sessionID, time.Since(parseStart))
+	if len(planned) == 0 {
+		return nil, errNoTasks
+	}
+
+	tasks := make([]*db.Task, len(planned))
+	for i, p := range planned {
+		tasks[i] = &db.Task{
+			ID:          uuid.New().String(),
+			Sequence:    i + 1,
+			Title:       p.Title,
+			Description: p.Description,
+			Status:      db.TaskPending,
+		}
+	}
+	if err := l.deps.DB.CreatePlanTasks(l.plan, tasks); err != nil {
+		return nil, fmt.Errorf("failed to store tasks: %w", err)
+	}
+
+	l.emit(NewEvent(EventTasksPlanned, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Planned %d tasks", len(tasks))))
+	return tasks, nil
+}
+
+// startNextTask makes the first unfinished task current, giving it its own jj
+// change unless it was already started by an earlier run. Returns false when
+// every task is complete.
+func (l *Loop) startNextTask(ctx context.Context) bool {
+	l.task = nil
+	for _, task := range l.tasks {
+		if task.Status != db.TaskCompleted {
+			l.task = task
+			break
+		}
+	}
+	if l.task == nil {
+		return false
+	}
+
+	if l.task.Status != db.TaskInProgress {
+		l.startTaskChange(ctx)
+		if err := l.deps.DB.UpdateTaskStatus(l.task.ID, db.TaskInProgress); err != nil {
+			log.Warn("failed to mark task in progress", "task", l.task.ID, "error", err)
+		}
+		l.task.Status = db.TaskInProgress
+	}
+
+	// Stall detection starts over for each task
+	l.stall = stallDetector{}
+	l.stalled = false
+
+	l.emit(NewEvent(EventTaskStarted, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Task %d/%d: %s", l.task.Sequence, len(l.tasks), l.task.Title)))
+	return true
+}
+
+// startTaskChange moves the task onto a fresh jj change described with its
+// title, reusing the working-copy change if it is still empty. On failure the
+// task shares the current change.
+func (l *Loop) startTaskChange(ctx context.Context) {
+	message := fmt.Sprintf("Task %d: %s", l.task.Sequence, l.task.Title)
+
+	empty, err := l.deps.VCS.IsEmpty(ctx)
+	if err == nil {
+		if empty {
+			err = l.deps.VCS.Describe(ctx, message)
+		} else {
+			err = l.deps.VCS.New(ctx, message)
+		}
+	}
+	if err != nil {
+		log.Warn("failed to start jj change for task", "task", l.task.ID, "error", err)
+		return
+	}
+
+	changeID, err := l.deps.VCS.GetCurrentChangeID(ctx)
+	if err != nil {
+		log.Warn("failed to get change ID for task", "task", l.task.ID, "error", err)
+		return
+	}
+	if err := l.deps.DB.UpdateTaskJJChangeID(l.task.ID, changeID); err != nil {
+		log.Warn("failed to store task change ID", "task", l.task.ID, "error", err)
+	}
+	l.task.JJChangeID = &changeID
+}
+
+// completeTask marks the current task completed.
+func (l *Loop) completeTask() {
+	if err := l.deps.DB.UpdateTaskStatus(l.task.ID, db.TaskCompleted); err != nil {
+		log.Warn("failed to mark task complete", "task", l.task.ID, "error", err)
+	}
+	l.task.Status = db.TaskCompleted
+
+	l.emit(NewEvent(EventTaskCompleted, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("Completed task %d/%d: %s", l.task.Sequence, len(l.tasks), l.task.Title)))
+}
+
+// currentTaskID returns the ID of the current task (empty when the plan is
+// not worked as tasks).
+func (l *Loop) currentTaskID() string {
+	if l.task == nil {
+		return ""
+	}
+	return l.task.ID
+}
+
+// reviewBaseChangeID returns the revision reviewer diffs start from: the
+// parent of the current task's change in task mode, otherwise the plan's
+// base change.
+func (l *Loop) reviewBaseChangeID() string {
+	if l.task != nil && l.task.JJChangeID != nil && *l.task.JJChangeID != "" {
+		return *l.task.JJChangeID + "-"
+	}
+	return l.baseChangeID
+}
+
+// currentTaskPrompt describes the current task for agent prompts
+// (empty when the plan is not worked as tasks).
+func (l *Loop) currentTaskPrompt() string {
+	if l.task == nil {
+		return ""
+	}
+	text := fmt.Sprintf("Task %d of %d: %s", l.task.Sequence, len(l.tasks), l.task.Title)
+	if l.task.Description != "" {
+		text += "\n\n" + l.task.Description
+	}
+	return text
+}
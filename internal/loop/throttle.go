@@ -0,0 +1,122 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// Throttle limits how fast Claude sessions start. The limits are token
+// buckets stored in the database, so concurrent runs share them.
+type Throttle struct {
+	MaxSessionsPerHour int     // Sessions started per hour (0 = unlimited)
+	MaxCostPerHour     float64 // Spend in USD per hour (0 = unlimited)
+}
+
+// throttleTick is how often countdown events are emitted while throttled
+// (variable for tests).
+var throttleTick = 10 * time.Second
+
+// throttleRetries is how many times a throttle that can't be read is checked
+// again, throttleRetryDelay apart, before the session fails (variables for
+// tests).
+var (
+	throttleRetries    = 3
+	throttleRetryDelay = time.Second
+)
+
+// take returns how long until a session may start, taking a session from
+// the budget when it may start now, and the limit that holds it back. The
+// cost budget must be out of debt before a session is taken.
+func (t Throttle) take(database *db.DB) (time.Duration, string, error) {
+	if t.MaxCostPerHour > 0 {
+		wait, err := database.TakeThrottleTokens(db.ThrottleCost, t.MaxCostPerHour, 0)
+		if err != nil || wait > 0 {
+			return wait, fmt.Sprintf("$%.2f/hour", t.MaxCostPerHour), err
+		}
+	}
+	if t.MaxSessionsPerHour > 0 {
+		wait, err := database.TakeThrottleTokens(db.ThrottleSessions, float64(t.MaxSessionsPerHour), 1)
+		return wait, fmt.Sprintf("%d sessions/hour", t.MaxSessionsPerHour), err
+	}
+	return 0, "", nil
+}
+
+// Wait waits until the throttle, stored in database, lets a Claude session
+// start, and takes the session from its budget. While it waits, onWait (if
+// non-nil) is called with when the wait should end and the limit that holds
+// the session back, and once more with a zero time when the wait is over. A
+// throttle that can't be read is retried, then fails the session rather
+// than letting it through. It returns early if ctx is done.
+func (t Throttle) Wait(ctx context.Context, database *db.DB, onWait func(until time.Time, limit string)) error {
+	throttled := false
+	failures := 0
+	for {
+		wait, limit, err := t.take(database)
+		if err != nil {
+			failures++
+			if failures > throttleRetries {
+				return fmt.Errorf("failed to check the Claude throttle: %w", err)
+			}
+			log.Warn("failed to check the Claude throttle, retrying", "error", err, "attempt", failures)
+			if err := sleepContext(ctx, throttleRetryDelay); err != nil {
+				return err
+			}
+			continue
+		}
+		failures = 0
+		if wait <= 0 {
+			break
+		}
+		throttled = true
+
+		// Round up so the countdown never shows 0s while waiting
+		wait = wait.Truncate(time.Second) + time.Second
+		if onWait != nil {
+			onWait(time.Now().Add(wait), limit)
+		}
+		if err := sleepContext(ctx, min(wait, throttleTick)); err != nil {
+			return err
+		}
+	}
+
+	if throttled && onWait != nil {
+		onWait(time.Time{}, "")
+	}
+	return nil
+}
+
+// Spend takes a session's cost from the throttle's cost budget, stored in
+// database.
+func (t Throttle) Spend(database *db.DB, cost float64) {
+	if t.MaxCostPerHour <= 0 || cost <= 0 {
+		return
+	}
+	if err := database.SpendThrottleTokens(db.ThrottleCost, t.MaxCostPerHour, cost); err != nil {
+		log.Warn("failed to record Claude cost with the throttle", "error", err)
+	}
+}
+
+// waitForThrottle waits until the throttle lets a Claude session start,
+// emitting EventThrottled countdown events. It returns early if ctx is
+// done.
+func (l *Loop) waitForThrottle(ctx context.Context) error {
+	return l.cfg.Throttle.Wait(ctx, l.deps.DB, func(until time.Time, limit string) {
+		if until.IsZero() {
+			l.emit(NewEvent(EventThrottled, l.iteration, l.effectiveMaxIter(), "Throttle lifted, resuming"))
+			return
+		}
+		event := NewEvent(EventThrottled, l.iteration, l.effectiveMaxIter(),
+			fmt.Sprintf("Throttled at %s, resuming in %s", limit, time.Until(until).Round(time.Second)))
+		event.Until = until
+		l.emit(event)
+	})
+}
+
+// spendThrottleCost takes a session's cost from the throttle's cost budget.
+func (l *Loop) spendThrottleCost(cost float64) {
+	l.cfg.Throttle.Spend(l.deps.DB, cost)
+}
@@ -0,0 +1,74 @@
+package loop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+// runThrottleLoop runs one iteration under the given throttle and returns
+// the events it emitted.
+func runThrottleLoop(t *testing.T, database *db.DB, plan *db.Plan, throttle Throttle) []Event {
+	t.Helper()
+	oldTick := throttleTick
+	throttleTick = 100 * time.Millisecond
+	t.Cleanup(func() { throttleTick = oldTick })
+
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 1, WorkDir: "/tmp", Throttle: throttle},
+		testDeps(database, mockClaudeCreator("## Progress\nWorking\n\n## Status\nRUNNING RUNNING RUNNING"), mockJJRunnerEmpty()))
+	return runLoop(t, loop)
+}
+
+func TestLoop_WaitsForThrottle(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	// Other runs used up the hour's sessions; one refills every 100ms
+	const perHour = 36000
+	if err := database.SpendThrottleTokens(db.ThrottleSessions, perHour, perHour); err != nil {
+		t.Fatal(err)
+	}
+
+	events := runThrottleLoop(t, database, plan, Throttle{MaxSessionsPerHour: perHour})
+
+	waited, lifted := false, false
+	for _, e := range events {
+		switch e.Type {
+		case EventThrottled:
+			if e.Until.IsZero() {
+				lifted = true
+			} else {
+				waited = true
+			}
+		case EventClaudeStart:
+			if !waited {
+				t.Fatal("expected the wait before the first Claude session")
+			}
+		}
+	}
+	if !waited || !lifted {
+		t.Errorf("expected a throttle countdown and its end, got waited=%v lifted=%v", waited, lifted)
+	}
+}
+
+func TestLoop_ThrottleSpendsCost(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	events := runThrottleLoop(t, database, plan, Throttle{MaxCostPerHour: 1})
+	for _, e := range events {
+		if e.Type == EventThrottled {
+			t.Fatalf("expected no throttling within the budget, got %q", e.Message)
+		}
+	}
+
+	// The sessions' cost was spent, so the full budget isn't available
+	wait, err := database.TakeThrottleTokens(db.ThrottleCost, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait <= 0 {
+		t.Error("expected the sessions' cost to be taken from the budget")
+	}
+}
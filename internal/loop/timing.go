@@ -0,0 +1,233 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// sessionTimer measures the stages of the agent session being run. Sessions
+// run one at a time, so the loop times one session at a time.
+type sessionTimer struct {
+	start   time.Time
+	jjStart time.Duration // The loop's jj time when the session started
+	timings db.SessionTimings
+}
+
+// startSessionTimer starts timing a new session; call it before building
+// the session's prompt.
+func (l *Loop) startSessionTimer() {
+	l.timer = &sessionTimer{start: time.Now(), jjStart: l.jjElapsed()}
+}
+
+// promptBuilt records the time spent building the session's prompt.
+func (l *Loop) promptBuilt() {
+	if l.timer != nil {
+		l.timer.timings.Prompt = time.Since(l.timer.start)
+	}
+}
+
+// sessionElapsed returns the wall time of the session so far.
+func (l *Loop) sessionElapsed() time.Duration {
+	if l.timer == nil {
+		return 0
+	}
+	return time.Since(l.timer.start)
+}
+
+// finishSessionTimer records the session's stage timings, with the time
+// spent parsing its output, and adds them to the iteration's.
+func (l *Loop) finishSessionTimer(sessionID string, parse time.Duration) {
+	timer := l.timer
+	if timer == nil {
+		return
+	}
+	l.timer = nil
+
+	timer.timings.Parse = parse
+	timer.timings.JJ = l.jjElapsed() - timer.jjStart
+	l.iterTimings.Prompt += timer.timings.Prompt
+	l.iterTimings.Claude += timer.timings.Claude
+	l.iterTimings.Parse += timer.timings.Parse
+	if err := l.deps.DB.UpdatePlanSessionTimings(sessionID, timer.timings); err != nil {
+		log.Warn("failed to store session timings", "session", sessionID, "error", err)
+	}
+}
+
+// startIterationTimer starts timing an iteration.
+func (l *Loop) startIterationTimer() {
+	l.iterStart = time.Now()
+	l.iterJJStart = l.jjElapsed()
+	l.iterTimings = db.SessionTimings{}
+}
+
+// newIterationEndEvent creates the EventIterationEnd of the current
+// iteration, with its duration and stage timings.
+func (l *Loop) newIterationEndEvent() Event {
+	event := NewEvent(EventIterationEnd, l.iteration, l.effectiveMaxIter(),
+		fmt.Sprintf("iteration %d complete", l.iteration))
+	event.Duration = time.Since(l.iterStart)
+	timings := l.iterTimings
+	timings.JJ = l.jjElapsed() - l.iterJJStart
+	event.Timings = &timings
+	return event
+}
+
+// jjElapsed returns the total time spent in jj operations.
+func (l *Loop) jjElapsed() time.Duration {
+	return time.Duration(l.jjTime.Load())
+}
+
+// timedVCS wraps a VCS to add the time spent in its operations to elapsed.
+type timedVCS struct {
+	vcs     VCS
+	elapsed *atomic.Int64 // Nanoseconds
+}
+
+// track adds the time since start to the elapsed time.
+func (t timedVCS) track(start time.Time) {
+	t.elapsed.Add(int64(time.Since(start)))
+}
+
+func (t timedVCS) Abandon(ctx context.Context, revision string) error {
+	defer t.track(time.Now())
+	return t.vcs.Abandon(ctx, revision)
+}
+
+func (t timedVCS) AddTrailers(ctx context.Context, revision string, trailers []jj.Trailer) error {
+	defer t.track(time.Now())
+	return t.vcs.AddTrailers(ctx, revision, trailers)
+}
+
+func (t timedVCS) ApplyPatch(ctx context.Context, patch string) error {
+	defer t.track(time.Now())
+	return t.vcs.ApplyPatch(ctx, patch)
+}
+
+func (t timedVCS) ChangeIDs(ctx context.Context, revset string) ([]string, error) {
+	defer t.track(time.Now())
+	return t.vcs.ChangeIDs(ctx, revset)
+}
+
+func (t timedVCS) ChangedFiles(ctx context.Context, from, to string, paths ...string) ([]string, error) {
+	defer t.track(time.Now())
+	return t.vcs.ChangedFiles(ctx, from, to, paths...)
+}
+
+func (t timedVCS) CheckPatch(ctx context.Context, patch string) error {
+	defer t.track(time.Now())
+	return t.vcs.CheckPatch(ctx, patch)
+}
+
+func (t timedVCS) Conflicts(ctx context.Context) ([]string, error) {
+	defer t.track(time.Now())
+	return t.vcs.Conflicts(ctx)
+}
+
+// CurrentOperation returns the wrapped VCS's current operation, or "" if it
+// keeps no operation log.
+func (t timedVCS) CurrentOperation(ctx context.Context) (string, error) {
+	ops, ok := t.vcs.(OperationLog)
+	if !ok {
+		return "", nil
+	}
+	defer t.track(time.Now())
+	return ops.CurrentOperation(ctx)
+}
+
+// SetBookmark sets the bookmark with the wrapped VCS, doing nothing if it
+// has no bookmarks.
+func (t timedVCS) SetBookmark(ctx context.Context, bookmark, revision string) error {
+	bookmarks, ok := t.vcs.(Bookmarks)
+	if !ok {
+		return nil
+	}
+	defer t.track(time.Now())
+	return bookmarks.SetBookmark(ctx, bookmark, revision)
+}
+
+// DeleteBookmark deletes the bookmark with the wrapped VCS, doing nothing if
+// it has no bookmarks.
+func (t timedVCS) DeleteBookmark(ctx context.Context, bookmark string) error {
+	bookmarks, ok := t.vcs.(Bookmarks)
+	if !ok {
+		return nil
+	}
+	defer t.track(time.Now())
+	return bookmarks.DeleteBookmark(ctx, bookmark)
+}
+
+func (t timedVCS) Describe(ctx context.Context, message string) error {
+	defer t.track(time.Now())
+	return t.vcs.Describe(ctx, message)
+}
+
+func (t timedVCS) Diff(ctx context.Context, from, to string, paths ...string) (string, error) {
+	defer t.track(time.Now())
+	return t.vcs.Diff(ctx, from, to, paths...)
+}
+
+func (t timedVCS) GetCurrentChangeID(ctx context.Context) (string, error) {
+	defer t.track(time.Now())
+	return t.vcs.GetCurrentChangeID(ctx)
+}
+
+func (t timedVCS) GetCurrentCommitID(ctx context.Context) (string, error) {
+	defer t.track(time.Now())
+	return t.vcs.GetCurrentCommitID(ctx)
+}
+
+func (t timedVCS) GetDescription(ctx context.Context, revision string) (string, error) {
+	defer t.track(time.Now())
+	return t.vcs.GetDescription(ctx, revision)
+}
+
+func (t timedVCS) GetParentChangeID(ctx context.Context) (string, error) {
+	defer t.track(time.Now())
+	return t.vcs.GetParentChangeID(ctx)
+}
+
+func (t timedVCS) GitDiff(ctx context.Context, from, to string, paths ...string) (string, error) {
+	defer t.track(time.Now())
+	return t.vcs.GitDiff(ctx, from, to, paths...)
+}
+
+func (t timedVCS) IsEmpty(ctx context.Context) (bool, error) {
+	defer t.track(time.Now())
+	return t.vcs.IsEmpty(ctx)
+}
+
+func (t timedVCS) New(ctx context.Context, message string) error {
+	defer t.track(time.Now())
+	return t.vcs.New(ctx, message)
+}
+
+func (t timedVCS) Restore(ctx context.Context, from string, paths ...string) error {
+	defer t.track(time.Now())
+	return t.vcs.Restore(ctx, from, paths...)
+}
+
+func (t timedVCS) Root(ctx context.Context) (string, error) {
+	defer t.track(time.Now())
+	return t.vcs.Root(ctx)
+}
+
+func (t timedVCS) Show(ctx context.Context) (string, error) {
+	defer t.track(time.Now())
+	return t.vcs.Show(ctx)
+}
+
+func (t timedVCS) Squash(ctx context.Context, from, into, message string) error {
+	defer t.track(time.Now())
+	return t.vcs.Squash(ctx, from, into, message)
+}
+
+func (t timedVCS) Status(ctx context.Context) (string, error) {
+	defer t.track(time.Now())
+	return t.vcs.Status(ctx)
+}
@@ -0,0 +1,74 @@
+package loop
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+func TestLoop_RecordsStageTimings(t *testing.T) {
+	database := setupTestDB(t)
+	plan := createTestPlan(t, database, "Test plan content")
+
+	claudeClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	output := createMockClaudeOutput("## Progress\nWorking\n\n## Status\nRUNNING")
+	claudeClient.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "sh", "-c", `sleep 0.02; printf '%s\n' "$0"`, output)
+	})
+	jjClient := jj.NewClient("/tmp")
+	jjClient.SetCommandRunner(func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		time.Sleep(time.Millisecond)
+		return "", "", nil
+	})
+
+	loop := New(Config{PlanID: plan.ID, MaxIterations: 1, WorkDir: "/tmp"},
+		Deps{DB: database, Claude: claudeClient, VCS: jjClient})
+
+	events := runLoop(t, loop)
+
+	var iterationEnd *Event
+	for i, e := range events {
+		if e.Time.IsZero() {
+			t.Errorf("event %s has no time", e.Type)
+		}
+		if i > 0 && e.Time.Before(events[i-1].Time) {
+			t.Errorf("event %s is timed before the event emitted ahead of it", e.Type)
+		}
+		switch e.Type {
+		case EventDeveloperEnd, EventReviewerEnd:
+			if e.Duration < 20*time.Millisecond {
+				t.Errorf("%s duration = %v, want at least the Claude session's", e.Type, e.Duration)
+			}
+		case EventIterationEnd:
+			iterationEnd = &events[i]
+		}
+	}
+	if iterationEnd == nil || iterationEnd.Timings == nil {
+		t.Fatalf("expected an iteration end with timings, got events %+v", events)
+	}
+	if iterationEnd.Timings.Claude < 40*time.Millisecond || iterationEnd.Timings.JJ <= 0 ||
+		iterationEnd.Duration < iterationEnd.Timings.Claude {
+		t.Errorf("unexpected iteration timings %+v over %v", *iterationEnd.Timings, iterationEnd.Duration)
+	}
+
+	sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected developer and reviewer sessions, got %d", len(sessions))
+	}
+	for _, s := range sessions {
+		if s.Timings.Claude < 20*time.Millisecond {
+			t.Errorf("%s session Claude time = %v", s.AgentType, s.Timings.Claude)
+		}
+	}
+	if sessions[1].AgentType != db.LoopAgentReviewer || sessions[1].Timings.JJ <= 0 {
+		t.Errorf("expected the reviewer session's jj time to be recorded, got %+v", sessions[1].Timings)
+	}
+}
@@ -0,0 +1,70 @@
+package loop
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// fileParams are the tool input fields that name the file a tool touches.
+var fileParams = []string{"file_path", "notebook_path", "path"}
+
+// toolUsage aggregates the tool calls of a session or iteration.
+type toolUsage struct {
+	order    []string            // Tool names in first-call order
+	calls    map[string]int      // Calls per tool
+	files    map[string][]string // Unique files per tool, in first-use order
+	commands map[string][]string // Shell commands per tool
+}
+
+// newToolUsage creates an empty aggregator.
+func newToolUsage() *toolUsage {
+	return &toolUsage{
+		calls:    make(map[string]int),
+		files:    make(map[string][]string),
+		commands: make(map[string][]string),
+	}
+}
+
+// add records a tool call with its JSON input.
+func (u *toolUsage) add(name, input string) {
+	if name == "" {
+		return
+	}
+	if u.calls[name] == 0 {
+		u.order = append(u.order, name)
+	}
+	u.calls[name]++
+
+	var params map[string]any
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return
+	}
+	for _, key := range fileParams {
+		if path, ok := params[key].(string); ok && path != "" {
+			if !slices.Contains(u.files[name], path) {
+				u.files[name] = append(u.files[name], path)
+			}
+			break
+		}
+	}
+	if command, ok := params["command"].(string); ok && command != "" && name == "Bash" {
+		u.commands[name] = append(u.commands[name], command)
+	}
+}
+
+// summary formats the calls per tool, most used first, e.g.
+// "Read×10 Edit×4 Bash×2".
+func (u *toolUsage) summary() string {
+	names := slices.Clone(u.order)
+	slices.SortStableFunc(names, func(a, b string) int {
+		return u.calls[b] - u.calls[a]
+	})
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s×%d", name, u.calls[name])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,49 @@
+package loop
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestToolUsage_Add(t *testing.T) {
+	u := newToolUsage()
+	u.add("Read", `{"file_path":"a.go"}`)
+	u.add("Edit", `{"file_path":"a.go","old_string":"x"}`)
+	u.add("Read", `{"file_path":"b.go"}`)
+	u.add("Read", `{"file_path":"a.go"}`)
+	u.add("Bash", `{"command":"go test ./..."}`)
+	u.add("Grep", `{"pattern":"TODO","path":"internal"}`)
+	u.add("Write", `not json`)
+	u.add("", `{}`)
+
+	if u.calls["Read"] != 3 || u.calls["Write"] != 1 {
+		t.Errorf("calls = %v, want Read=3 Write=1", u.calls)
+	}
+	if !slices.Equal(u.files["Read"], []string{"a.go", "b.go"}) {
+		t.Errorf("Read files = %v, want [a.go b.go]", u.files["Read"])
+	}
+	if !slices.Equal(u.files["Grep"], []string{"internal"}) {
+		t.Errorf("Grep files = %v, want [internal]", u.files["Grep"])
+	}
+	if !slices.Equal(u.commands["Bash"], []string{"go test ./..."}) {
+		t.Errorf("Bash commands = %v, want [go test ./...]", u.commands["Bash"])
+	}
+	if _, ok := u.calls[""]; ok {
+		t.Error("expected calls without a tool name to be ignored")
+	}
+}
+
+func TestToolUsage_Summary(t *testing.T) {
+	u := newToolUsage()
+	if got := u.summary(); got != "" {
+		t.Errorf("summary() of no calls = %q, want empty", got)
+	}
+
+	for _, name := range []string{"Bash", "Edit", "Read", "Edit", "Read", "Read", "Bash", "Edit"} {
+		u.add(name, `{}`)
+	}
+	// Ties keep first-call order
+	if got, want := u.summary(), "Edit×3 Read×3 Bash×2"; got != want {
+		t.Errorf("summary() = %q, want %q", got, want)
+	}
+}
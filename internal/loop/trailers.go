@@ -0,0 +1,30 @@
+package loop
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// reviewerTrailerName identifies ralph's reviewer in Reviewed-by trailers.
+const reviewerTrailerName = "ralph-reviewer"
+
+// addReviewTrailers records the approval in the description of the given
+// change, normally the working copy, so downstream tooling can trace
+// ralph's changes. Failures are logged; they never fail the iteration.
+func (l *Loop) addReviewTrailers(ctx context.Context, revision string) {
+	if !l.cfg.CommitTrailers {
+		return
+	}
+
+	trailers := []jj.Trailer{
+		{Key: "Reviewed-by", Value: reviewerTrailerName},
+		{Key: "Iterations", Value: strconv.Itoa(l.iteration)},
+		{Key: "Plan-ID", Value: l.cfg.PlanID},
+	}
+	if err := l.deps.VCS.AddTrailers(ctx, revision, trailers); err != nil {
+		log.Warn("failed to add review trailers", "error", err)
+	}
+}
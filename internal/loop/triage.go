@@ -0,0 +1,51 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/triage"
+)
+
+// trivialChange returns why the developer's change since the snapshot is
+// trivial enough to skip or downgrade its review, or "" if it needs a full
+// review. A claimed done signal always gets a full review.
+func (l *Loop) trivialChange(ctx context.Context, devSnapshot string, devClaimedDone bool) string {
+	if l.cfg.TrivialChanges == nil || devClaimedDone || devSnapshot == "" {
+		return ""
+	}
+	diff, err := l.deps.VCS.GitDiff(ctx, devSnapshot, "@", l.scopePaths()...)
+	if err != nil {
+		log.Warn("failed to get the iteration's diff for triage", "error", err)
+		return ""
+	}
+	result := triage.Classify(diff, *l.cfg.TrivialChanges)
+	if !result.Trivial {
+		log.Debug("change needs a full review", "reason", result.Reason)
+		return ""
+	}
+	return result.Reason
+}
+
+// recordReviewSkip records that the iteration's review was skipped or
+// downgraded, and reports it.
+func (l *Loop) recordReviewSkip(action, reason, sessionID string) {
+	skip := &db.ReviewSkip{
+		PlanID:    l.cfg.PlanID,
+		Iteration: l.iteration,
+		Action:    action,
+		Reason:    reason,
+		SessionID: sessionID,
+	}
+	if err := l.deps.DB.CreateReviewSkip(skip); err != nil {
+		log.Warn("failed to record review skip", "error", err)
+	}
+
+	msg := fmt.Sprintf("Skipped review of a trivial change: %s", reason)
+	if action == db.ReviewSkipActionDowngrade {
+		msg = fmt.Sprintf("Trivial change reviewed by the lightweight reviewer: %s", reason)
+	}
+	l.emit(NewEvent(EventReviewSkipped, l.iteration, l.effectiveMaxIter(), msg))
+}
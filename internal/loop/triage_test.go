@@ -0,0 +1,158 @@
+package loop
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/triage"
+)
+
+const (
+	commentDiff = "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-// Old doc.\n+// New doc.\n"
+	codeDiff    = "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-\treturn 1\n+\treturn 2\n"
+)
+
+// triageRun is the outcome of a one-iteration loop with trivial-change
+// triage enabled.
+type triageRun struct {
+	database      *db.DB
+	plan          *db.Plan
+	events        []Event
+	claudeCalls   int // Sessions run by the developer's and full reviewer's client
+	lightCalls    int // Sessions run by the lightweight reviewer's client
+	reviewSkipped bool
+}
+
+// runTriageLoop runs one iteration in which the developer's change is the
+// given git diff. With light, trivial changes go to a lightweight reviewer.
+func runTriageLoop(t *testing.T, diff, devStatus string, light bool) *triageRun {
+	t.Helper()
+	run := &triageRun{database: setupTestDB(t)}
+	run.plan = createTestPlan(t, run.database, "Test plan content")
+
+	var mu sync.Mutex
+	output := createMockClaudeOutput("## Progress\nWorking\n\n## Status\n" + devStatus)
+	claudeClient := claude.NewClient(claude.ClientConfig{Model: "test", MaxTurns: 1})
+	claudeClient.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		mu.Lock()
+		defer mu.Unlock()
+		run.claudeCalls++
+		return exec.CommandContext(ctx, "echo", output)
+	})
+	deps := Deps{DB: run.database, Claude: claudeClient}
+	if light {
+		lightClient := claude.NewClient(claude.ClientConfig{Model: "light", MaxTurns: 1})
+		lightClient.SetCommandCreator(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+			mu.Lock()
+			defer mu.Unlock()
+			run.lightCalls++
+			return exec.CommandContext(ctx, "echo", output)
+		})
+		deps.TrivialReviewClaude = lightClient
+	}
+
+	jjClient := jj.NewClient("/tmp")
+	jjClient.SetCommandRunner(func(ctx context.Context, dir string, name string, args ...string) (string, string, error) {
+		if len(args) >= 5 && args[0] == "log" && args[4] == "commit_id" {
+			return "commit-1\n", "", nil
+		}
+		if len(args) >= 2 && args[0] == "diff" && args[1] == "--git" {
+			return diff, "", nil
+		}
+		return "", "", nil
+	})
+	deps.VCS = jjClient
+
+	loop := New(Config{
+		PlanID:         run.plan.ID,
+		MaxIterations:  1,
+		WorkDir:        "/tmp",
+		TrivialChanges: &triage.Rules{MaxLines: triage.DefaultMaxLines},
+	}, deps)
+
+	run.events = runLoop(t, loop)
+	for _, event := range run.events {
+		if event.Type == EventReviewSkipped {
+			run.reviewSkipped = true
+		}
+	}
+	return run
+}
+
+// reviewSkips returns the review skips recorded for the run's plan.
+func (r *triageRun) reviewSkips(t *testing.T) []*db.ReviewSkip {
+	t.Helper()
+	skips, err := r.database.GetReviewSkipsByPlan(r.plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return skips
+}
+
+func TestLoop_SkipsReviewOfTrivialChange(t *testing.T) {
+	run := runTriageLoop(t, commentDiff, "RUNNING", false)
+
+	if run.claudeCalls != 1 {
+		t.Errorf("expected only the developer session, got %d sessions", run.claudeCalls)
+	}
+	if !run.reviewSkipped {
+		t.Error("expected EventReviewSkipped")
+	}
+	for _, e := range run.events {
+		if e.Type == EventReviewerStart {
+			t.Error("expected no reviewer to start")
+		}
+	}
+
+	skips := run.reviewSkips(t)
+	if len(skips) != 1 || skips[0].Action != db.ReviewSkipActionSkip || skips[0].Iteration != 1 || skips[0].SessionID != "" {
+		t.Fatalf("review skips = %+v, want one skip in iteration 1", skips)
+	}
+	if skips[0].Reason != "comment-only changes (2 lines in 1 files)" {
+		t.Errorf("skip reason = %q", skips[0].Reason)
+	}
+}
+
+func TestLoop_DowngradesReviewOfTrivialChange(t *testing.T) {
+	run := runTriageLoop(t, commentDiff, "RUNNING", true)
+
+	if run.claudeCalls != 1 || run.lightCalls != 1 {
+		t.Errorf("expected the developer and a lightweight review, got %d and %d sessions", run.claudeCalls, run.lightCalls)
+	}
+
+	skips := run.reviewSkips(t)
+	if len(skips) != 1 || skips[0].Action != db.ReviewSkipActionDowngrade || skips[0].SessionID == "" {
+		t.Fatalf("review skips = %+v, want one downgrade with its session", skips)
+	}
+	session, err := run.database.GetPlanSession(skips[0].SessionID)
+	if err != nil || session.AgentType != db.LoopAgentReviewer {
+		t.Errorf("expected the downgrade to record the reviewer session, got %+v (err %v)", session, err)
+	}
+}
+
+func TestLoop_ReviewsSubstantiveChange(t *testing.T) {
+	run := runTriageLoop(t, codeDiff, "RUNNING", true)
+
+	if run.claudeCalls != 2 || run.lightCalls != 0 {
+		t.Errorf("expected a full review, got %d full and %d lightweight sessions", run.claudeCalls, run.lightCalls)
+	}
+	if run.reviewSkipped || len(run.reviewSkips(t)) != 0 {
+		t.Error("expected no review skip")
+	}
+}
+
+func TestLoop_ReviewsTrivialChangeSignaledDone(t *testing.T) {
+	run := runTriageLoop(t, commentDiff, "DEV_DONE DEV_DONE DEV_DONE!!!", false)
+
+	if run.claudeCalls < 2 {
+		t.Errorf("expected the done signal to get a full review, got %d sessions", run.claudeCalls)
+	}
+	if run.reviewSkipped {
+		t.Error("expected no review skip for a done signal")
+	}
+}
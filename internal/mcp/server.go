@@ -0,0 +1,196 @@
+// Package mcp serves tools to the Claude sessions ralph runs over the Model
+// Context Protocol. The CLI starts `ralph mcp` as a stdio server: each
+// message is a JSON-RPC 2.0 object on a line of its own.
+//
+// Only what tool servers need is implemented: initialize, ping, tools/list,
+// and tools/call. Notifications from the client are accepted and ignored.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// ProtocolVersion is the MCP revision the server speaks when the client
+// doesn't ask for one.
+const ProtocolVersion = "2025-06-18"
+
+// ServerName is the name ralph's server is configured under, so its tools
+// reach sessions as mcp__ralph__<tool>.
+const ServerName = "ralph"
+
+// maxMessageSize is the largest message the server reads.
+const maxMessageSize = 16 << 20
+
+// JSON-RPC 2.0 error codes.
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Tool is a tool the server offers.
+type Tool struct {
+	Name        string
+	Description string
+	InputSchema map[string]any // JSON Schema of the arguments
+
+	// Handler runs a call with its JSON arguments and returns the text
+	// result. An error is reported to the agent as a failed call.
+	Handler func(ctx context.Context, args json.RawMessage) (string, error)
+}
+
+// Server is an MCP server offering a set of tools.
+type Server struct {
+	version string
+	tools   []Tool
+
+	mu sync.Mutex // Guards writes
+	w  io.Writer
+}
+
+// NewServer creates a server reporting the given ralph version.
+func NewServer(version string) *Server {
+	return &Server{version: version}
+}
+
+// AddTool adds a tool to the server.
+func (s *Server) AddTool(tool Tool) {
+	s.tools = append(s.tools, tool)
+}
+
+// request is a JSON-RPC request or notification (without an ID).
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC error object.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve answers requests read from r on w until r ends or ctx is done.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.w = w
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.write(response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: err.Error()}})
+			continue
+		}
+		if len(req.ID) == 0 {
+			// Notifications (initialized, cancelled) need no answer
+			continue
+		}
+		result, rpcErr := s.handle(ctx, req)
+		s.write(response{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr})
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read request: %w", err)
+	}
+	return nil
+}
+
+// handle answers a request.
+func (s *Server) handle(ctx context.Context, req request) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		version := params.ProtocolVersion
+		if version == "" {
+			version = ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": ServerName, "version": s.version},
+		}, nil
+
+	case "ping":
+		return map[string]any{}, nil
+
+	case "tools/list":
+		tools := make([]map[string]any, len(s.tools))
+		for i, t := range s.tools {
+			tools[i] = map[string]any{"name": t.Name, "description": t.Description, "inputSchema": t.InputSchema}
+		}
+		return map[string]any{"tools": tools}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		for _, t := range s.tools {
+			if t.Name == params.Name {
+				return s.call(ctx, t, params.Arguments), nil
+			}
+		}
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool %q", params.Name)}
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+}
+
+// call runs a tool, reporting its error as a failed call.
+func (s *Server) call(ctx context.Context, tool Tool, args json.RawMessage) map[string]any {
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	text, err := tool.Handler(ctx, args)
+	if err != nil {
+		return map[string]any{
+			"content": []map[string]string{{"type": "text", "text": err.Error()}},
+			"isError": true,
+		}
+	}
+	return map[string]any{"content": []map[string]string{{"type": "text", "text": text}}}
+}
+
+// write sends a message on its own line.
+func (s *Server) write(resp response) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		log.Warn("failed to encode MCP response", "error", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(append(data, '\n')); err != nil {
+		log.Warn("failed to write MCP response", "error", err)
+	}
+}
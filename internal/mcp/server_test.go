@@ -0,0 +1,76 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// exchange serves the requests, one per line, and returns the decoded
+// responses.
+func exchange(t *testing.T, s *Server, requests ...string) []map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(strings.Join(requests, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("Serve() error: %v", err)
+	}
+	var responses []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var resp map[string]any
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", line, err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func TestServer_Lifecycle(t *testing.T) {
+	s := NewServer("1.2.3")
+	s.AddTool(StatusTool())
+
+	responses := exchange(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"ralph_status","arguments":{"progress":"Built it","done":true}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"ralph_status","arguments":{"learnings":"x"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"nope"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"resources/list"}`,
+		`not json`,
+	)
+	if len(responses) != 7 {
+		t.Fatalf("expected 7 responses (none for the notification), got %d: %v", len(responses), responses)
+	}
+
+	init := responses[0]["result"].(map[string]any)
+	if init["protocolVersion"] != "2025-03-26" || init["serverInfo"].(map[string]any)["version"] != "1.2.3" {
+		t.Errorf("initialize result = %v", init)
+	}
+
+	tools := responses[1]["result"].(map[string]any)["tools"].([]any)
+	if len(tools) != 1 || tools[0].(map[string]any)["name"] != "ralph_status" {
+		t.Errorf("tools/list result = %v", tools)
+	}
+
+	if call := responses[2]["result"].(map[string]any); call["isError"] != nil ||
+		call["content"].([]any)[0].(map[string]any)["text"] != "Status recorded." {
+		t.Errorf("valid status call result = %v", call)
+	}
+	if call := responses[3]["result"].(map[string]any); call["isError"] != true ||
+		!strings.Contains(call["content"].([]any)[0].(map[string]any)["text"].(string), "progress is required") {
+		t.Errorf("invalid status call result = %v", call)
+	}
+
+	for i, code := range map[int]float64{4: codeInvalidParams, 5: codeMethodNotFound, 6: codeParseError} {
+		rpcErr, _ := responses[i]["error"].(map[string]any)
+		if rpcErr == nil || rpcErr["code"] != code {
+			t.Errorf("response %d = %v, want error %v", i, responses[i], code)
+		}
+	}
+}
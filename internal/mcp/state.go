@@ -0,0 +1,135 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/parser"
+)
+
+// RecordProgressToolName is the tool agents checkpoint their progress with
+// mid-session. Like the status tool's, its calls are recorded by the loop
+// from the session's stream, which knows the session they belong to.
+const RecordProgressToolName = "record_progress"
+
+// IsTool reports whether a tool call, as named by the CLI
+// (mcp__<server>__<tool>), is to the given tool of ralph's server.
+func IsTool(callName, tool string) bool {
+	return callName == "mcp__"+ServerName+"__"+tool
+}
+
+// noArgs is the input schema of tools without arguments.
+var noArgs = map[string]any{"type": "object", "properties": map[string]any{}}
+
+// StateTools returns the tools that let agents query a plan's state on
+// demand: the plan, its progress history, its learnings, and the
+// outstanding reviewer feedback. Each call reads the database afresh, so
+// entries stored while the session runs are included.
+func StateTools(database *db.DB, planID string) []Tool {
+	return []Tool{
+		{
+			Name:        "get_plan",
+			Description: "Get the plan being worked on, including edits merged since the session started.",
+			InputSchema: noArgs,
+			Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+				plan, err := database.GetPlan(planID)
+				if err != nil {
+					return "", fmt.Errorf("failed to get plan: %w", err)
+				}
+				return plan.Content, nil
+			},
+		},
+		{
+			Name:        "get_progress_history",
+			Description: "Get every progress entry recorded for the plan, oldest first. The prompt only includes the latest.",
+			InputSchema: noArgs,
+			Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+				history, err := database.GetProgressHistory(planID)
+				if err != nil {
+					return "", fmt.Errorf("failed to get progress: %w", err)
+				}
+				if len(history) == 0 {
+					return "No progress recorded yet.", nil
+				}
+				var b strings.Builder
+				for i, p := range history {
+					fmt.Fprintf(&b, "## Entry %d (%s)\n\n%s\n\n", i+1, p.CreatedAt.Format("2006-01-02 15:04"), p.Content)
+				}
+				return strings.TrimSpace(b.String()), nil
+			},
+		},
+		{
+			Name:        "get_learnings",
+			Description: "Get every distinct learning recorded during the plan, including ones dropped from the latest learnings.",
+			InputSchema: noArgs,
+			Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+				history, err := database.GetLearningsHistory(planID)
+				if err != nil {
+					return "", fmt.Errorf("failed to get learnings: %w", err)
+				}
+				contents := make([]string, len(history))
+				for i, l := range history {
+					contents[i] = l.Content
+				}
+				items := parser.UniqueLearnings(contents)
+				if len(items) == 0 {
+					return "No learnings recorded yet.", nil
+				}
+				return "- " + strings.Join(items, "\n- "), nil
+			},
+		},
+		{
+			Name:        "get_reviewer_feedback",
+			Description: "Get the reviewer feedback that has not been addressed yet, if any.",
+			InputSchema: noArgs,
+			Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+				feedback, err := database.GetLatestReviewerFeedback(planID)
+				if err != nil {
+					return "", fmt.Errorf("failed to get reviewer feedback: %w", err)
+				}
+				if feedback == nil {
+					return "No outstanding reviewer feedback.", nil
+				}
+				return feedback.Content, nil
+			},
+		},
+		{
+			Name: RecordProgressToolName,
+			Description: "Record your progress so far, so that it is kept even if the session ends unexpectedly. " +
+				"It doesn't replace the progress you report at the end of the session.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"progress": map[string]any{"type": "string", "description": "What you have done so far and the current state"},
+				},
+				"required": []string{"progress"},
+			},
+			Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+				if _, err := ParseRecordProgress(args); err != nil {
+					return "", err
+				}
+				return "Progress recorded.", nil
+			},
+		},
+	}
+}
+
+// ParseRecordProgress returns the progress of a record_progress call's JSON
+// input.
+func ParseRecordProgress(input []byte) (string, error) {
+	var args struct {
+		Progress string `json:"progress"`
+	}
+	if err := json.Unmarshal(input, &args); err != nil {
+		return "", fmt.Errorf("invalid progress: %w", err)
+	}
+	progress := strings.TrimSpace(args.Progress)
+	if progress == "" {
+		return "", errors.New("invalid progress: progress is required")
+	}
+	return progress, nil
+}
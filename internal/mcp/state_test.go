@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+func TestStateTools(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create test db: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close db: %v", err)
+		}
+	})
+	plan := &db.Plan{ID: "plan-1", Content: "Build the API", Status: db.PlanStatusRunning}
+	if err := database.CreatePlan(plan); err != nil {
+		t.Fatal(err)
+	}
+
+	tools := map[string]Tool{}
+	for _, tool := range StateTools(database, plan.ID) {
+		tools[tool.Name] = tool
+	}
+	call := func(name, args string) (string, error) {
+		t.Helper()
+		tool, ok := tools[name]
+		if !ok {
+			t.Fatalf("missing tool %s", name)
+		}
+		return tool.Handler(context.Background(), json.RawMessage(args))
+	}
+
+	for name, want := range map[string]string{
+		"get_plan":              "Build the API",
+		"get_progress_history":  "No progress recorded yet.",
+		"get_learnings":         "No learnings recorded yet.",
+		"get_reviewer_feedback": "No outstanding reviewer feedback.",
+	} {
+		if got, err := call(name, "{}"); err != nil || got != want {
+			t.Errorf("%s = %q (err %v), want %q", name, got, err, want)
+		}
+	}
+
+	session := &db.PlanSession{ID: "s1", PlanID: plan.ID, Iteration: 1, Status: db.PlanSessionCompleted, AgentType: db.LoopAgentReviewer}
+	if err := database.CreatePlanSession(session); err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"Wrote the handler", "Added tests"} {
+		if err := database.CreateProgress(&db.Progress{PlanID: plan.ID, SessionID: "s1", Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, content := range []string{"- Use sqlc", "- Use sqlc\n- Handlers live in api/"} {
+		if err := database.CreateLearnings(&db.Learnings{PlanID: plan.ID, SessionID: "s1", Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.CreateReviewerFeedback(&db.ReviewerFeedback{PlanID: plan.ID, SessionID: "s1", Content: "Handle the nil body"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := call("get_progress_history", "{}"); !strings.Contains(got, "## Entry 1") ||
+		!strings.Contains(got, "Wrote the handler") || !strings.Contains(got, "## Entry 2") {
+		t.Errorf("unexpected progress history: %q", got)
+	}
+	if got, _ := call("get_learnings", "{}"); got != "- Use sqlc\n- Handlers live in api/" {
+		t.Errorf("unexpected learnings: %q", got)
+	}
+	if got, _ := call("get_reviewer_feedback", "{}"); got != "Handle the nil body" {
+		t.Errorf("unexpected reviewer feedback: %q", got)
+	}
+
+	if got, err := call(RecordProgressToolName, `{"progress":"Halfway"}`); err != nil || got != "Progress recorded." {
+		t.Errorf("record_progress = %q (err %v)", got, err)
+	}
+	if _, err := call(RecordProgressToolName, `{"progress":" "}`); err == nil {
+		t.Error("expected an error for empty progress")
+	}
+}
+
+func TestIsTool(t *testing.T) {
+	if !IsTool("mcp__ralph__record_progress", RecordProgressToolName) {
+		t.Error("expected the CLI's name for the tool to match")
+	}
+	if IsTool("record_progress", RecordProgressToolName) || IsTool("mcp__other__record_progress", RecordProgressToolName) {
+		t.Error("expected tools of other servers not to match")
+	}
+}
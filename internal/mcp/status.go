@@ -0,0 +1,90 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gerunddev/ralph/internal/parser"
+)
+
+// StatusTool returns the tool agents report their status with. The call's
+// arguments are read by the loop from the session's stream; the server only
+// checks them, so an agent learns of a malformed report while it can still
+// send another.
+func StatusTool() Tool {
+	return Tool{
+		Name: parser.StatusToolName,
+		Description: "Report your progress, learnings, and status to ralph. Call it once, as the last thing you do " +
+			"in the session, instead of writing the Progress, Learnings, and Status sections.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"progress":  map[string]any{"type": "string", "description": "What you built or reviewed, and the current state"},
+				"learnings": map[string]any{"type": "string", "description": "Insights about the codebase and approaches that didn't work"},
+				"global_learnings": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Durable learnings that apply to the whole repository beyond this plan",
+				},
+				"done":     map[string]any{"type": "boolean", "description": "Developer: all work from the plan is complete (DEV_DONE)"},
+				"rebuttal": map[string]any{"type": "string", "description": "Developer: the review feedback point you dispute and why"},
+				"disputed_items": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "integer"},
+					"description": "Developer: the numbers of the review items your rebuttal disputes",
+				},
+				"fixed_items": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "integer"},
+					"description": "Developer: the numbers of the open review items you fixed",
+				},
+				"sub_plan": map[string]any{"type": "string", "description": "Developer: a plan for a large tangent, run on its own before this plan resumes"},
+				"approved": map[string]any{"type": "boolean", "description": "Reviewer: the work is approved; rebuttal reviewer: the disputed feedback is withdrawn"},
+				"feedback": map[string]any{"type": "string", "description": "Reviewer: the issues to fix when not approved; rebuttal reviewer: your response"},
+				"checklist": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"check":  map[string]any{"type": "string", "description": "The plan check, as listed"},
+							"passed": map[string]any{"type": "boolean", "description": "The work passes the check"},
+							"note":   map[string]any{"type": "string", "description": "Why the check fails"},
+						},
+						"required": []string{"check", "passed"},
+					},
+					"description": "Reviewer: your verdict on each of the plan's own checks, when it lists any",
+				},
+				"criteria": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"check":  map[string]any{"type": "string", "description": "The acceptance criterion, as listed"},
+							"passed": map[string]any{"type": "boolean", "description": "The work satisfies the criterion"},
+							"note":   map[string]any{"type": "string", "description": "What is missing for the criterion"},
+						},
+						"required": []string{"check", "passed"},
+					},
+					"description": "Reviewer: your verdict on each of the plan's acceptance criteria, when it lists any",
+				},
+				"verified_items": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "integer"},
+					"description": "Reviewer: the numbers of the claimed-fixed review items you verified",
+				},
+				"rejected_approaches": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Reviewer: approaches the developer must not try again, and why",
+				},
+			},
+			"required": []string{"progress"},
+		},
+		Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+			if _, err := parser.ParseStatusReport(args); err != nil {
+				return "", err
+			}
+			return "Status recorded.", nil
+		},
+	}
+}
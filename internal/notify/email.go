@@ -0,0 +1,226 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TLS modes for SMTP connections.
+const (
+	EmailTLSStartTLS = "starttls" // Plain connection upgraded with STARTTLS
+	EmailTLSImplicit = "tls"      // TLS from the start (SMTPS, usually port 465)
+	EmailTLSNone     = "none"     // No encryption; only for local relays
+)
+
+// emailTimeout bounds sending one digest.
+const emailTimeout = 30 * time.Second
+
+// ErrMissingSMTPHost is returned when no SMTP host is configured.
+var ErrMissingSMTPHost = errors.New("email SMTP host is empty")
+
+// EmailConfig holds the settings for sending digests.
+type EmailConfig struct {
+	Host     string
+	Port     int // 0 = 587, or 465 with EmailTLSImplicit
+	Username string
+	Password string
+	From     string
+	To       []string
+	TLS      string // EmailTLSStartTLS (default), EmailTLSImplicit, or EmailTLSNone
+
+	// ReportURL is a text/template for a link to the plan's report, given
+	// the Digest (empty = point at the ralph report command)
+	ReportURL string
+
+	// tlsConfig overrides the TLS settings (for testing)
+	tlsConfig *tls.Config
+}
+
+// Digest summarizes a finished run for the email digest.
+type Digest struct {
+	PlanID     string
+	Status     string // Final plan status, e.g. "completed" or "failed"
+	Iterations int
+	CostUSD    float64
+	Progress   string // Final progress summary
+	Error      string // Why the run failed (empty on success)
+}
+
+// Subject returns the digest's email subject.
+func (d Digest) Subject() string {
+	iterations := "iterations"
+	if d.Iterations == 1 {
+		iterations = "iteration"
+	}
+	return fmt.Sprintf("[ralph %.8s] Plan %s after %d %s", d.PlanID, d.Status, d.Iterations, iterations)
+}
+
+// Body returns the digest's plain-text email body, linking the report at
+// reportURL (empty = the ralph report command).
+func (d Digest) Body(reportURL string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan:       %s\n", d.PlanID)
+	fmt.Fprintf(&b, "Status:     %s\n", d.Status)
+	fmt.Fprintf(&b, "Iterations: %d\n", d.Iterations)
+	fmt.Fprintf(&b, "Cost:       $%.2f\n", d.CostUSD)
+	if d.Error != "" {
+		fmt.Fprintf(&b, "Error:      %s\n", d.Error)
+	}
+
+	b.WriteString("\nProgress:\n")
+	if progress := strings.TrimSpace(d.Progress); progress != "" {
+		b.WriteString(progress + "\n")
+	} else {
+		b.WriteString("(none recorded)\n")
+	}
+
+	if reportURL != "" {
+		fmt.Fprintf(&b, "\nReport: %s\n", reportURL)
+	} else {
+		fmt.Fprintf(&b, "\nReport: ralph report %s --format markdown\n", d.PlanID)
+	}
+	return b.String()
+}
+
+// SendDigest emails the digest to the configured recipients.
+func SendDigest(ctx context.Context, cfg EmailConfig, d Digest) error {
+	if cfg.Host == "" {
+		return ErrMissingSMTPHost
+	}
+	if cfg.From == "" || len(cfg.To) == 0 {
+		return errors.New("email needs a sender and at least one recipient")
+	}
+	if cfg.TLS == "" {
+		cfg.TLS = EmailTLSStartTLS
+	}
+	if cfg.TLS != EmailTLSStartTLS && cfg.TLS != EmailTLSImplicit && cfg.TLS != EmailTLSNone {
+		return fmt.Errorf("unsupported email TLS mode: %q", cfg.TLS)
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+		if cfg.TLS == EmailTLSImplicit {
+			cfg.Port = 465
+		}
+	}
+
+	reportURL, err := renderReportURL(cfg.ReportURL, d)
+	if err != nil {
+		return err
+	}
+	msg := digestMessage(cfg.From, cfg.To, d.Subject(), d.Body(reportURL), time.Now())
+
+	ctx, cancel := context.WithTimeout(ctx, emailTimeout)
+	defer cancel()
+	return sendMail(ctx, cfg, msg)
+}
+
+// CheckReportURL reports whether a report URL template parses and renders,
+// so a bad one is caught when the config is loaded rather than when a plan
+// finishes.
+func CheckReportURL(text string) error {
+	_, err := renderReportURL(text, Digest{})
+	return err
+}
+
+// renderReportURL renders the report URL template for a digest.
+func renderReportURL(text string, d Digest) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tmpl, err := template.New("report_url").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid email report URL template: %w", err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, d); err != nil {
+		return "", fmt.Errorf("failed to render email report URL: %w", err)
+	}
+	return out.String(), nil
+}
+
+// digestMessage formats a plain-text email.
+func digestMessage(from string, to []string, subject, body string, date time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// sendMail delivers msg over SMTP, encrypted as cfg.TLS requires.
+func sendMail(ctx context.Context, cfg EmailConfig, msg []byte) error {
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	tlsConfig := cfg.tlsConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: cfg.Host, MinVersion: tls.VersionTLS12}
+	}
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if cfg.TLS == EmailTLSImplicit {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("SMTP handshake with %s failed: %w", addr, err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if cfg.TLS == EmailTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS with %s failed: %w", addr, err)
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(cfg.From); err != nil {
+		return fmt.Errorf("SMTP sender rejected: %w", err)
+	}
+	for _, to := range cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP recipient %s rejected: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP data failed: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return client.Quit()
+}
@@ -0,0 +1,262 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpRecorder is a test SMTP server that records one delivered message.
+type smtpRecorder struct {
+	listener net.Listener
+	tls      *tls.Config // Offered with STARTTLS, unless implicit
+	implicit bool
+
+	done     chan struct{}
+	from     string
+	to       []string
+	auth     string
+	message  string
+	upgraded bool
+}
+
+// newSMTPRecorder starts a recorder on a local port.
+func newSMTPRecorder(t *testing.T, tlsConfig *tls.Config, implicit bool) *smtpRecorder {
+	t.Helper()
+	var listener net.Listener
+	var err error
+	if implicit {
+		listener, err = tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	} else {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	r := &smtpRecorder{listener: listener, tls: tlsConfig, implicit: implicit, done: make(chan struct{})}
+	go r.serve()
+	return r
+}
+
+// port returns the port the recorder listens on.
+func (r *smtpRecorder) port() int {
+	return r.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (r *smtpRecorder) serve() {
+	defer close(r.done)
+	conn, err := r.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 test ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			_ = tp.PrintfLine("250-test")
+			if r.tls != nil && !r.implicit && !r.upgraded {
+				_ = tp.PrintfLine("250-STARTTLS")
+			}
+			_ = tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			_ = tp.PrintfLine("220 ready")
+			conn = tls.Server(conn, r.tls)
+			tp = textproto.NewConn(conn)
+			r.upgraded = true
+		case "AUTH":
+			r.auth = arg
+			_ = tp.PrintfLine("235 ok")
+		case "MAIL":
+			r.from = arg
+			_ = tp.PrintfLine("250 ok")
+		case "RCPT":
+			r.to = append(r.to, arg)
+			_ = tp.PrintfLine("250 ok")
+		case "DATA":
+			_ = tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			r.message = string(data)
+			_ = tp.PrintfLine("250 ok")
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 unsupported")
+		}
+	}
+}
+
+// testTLSConfigs returns server and client TLS configs sharing a
+// self-signed certificate for 127.0.0.1.
+func testTLSConfigs(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	server = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	client = &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"}
+	return server, client
+}
+
+func testDigest() Digest {
+	return Digest{
+		PlanID:     "3f2a9c1e-0000",
+		Status:     "completed",
+		Iterations: 4,
+		CostUSD:    1.5,
+		Progress:   "All endpoints done.\n.\nTests pass.",
+	}
+}
+
+func TestSendDigest_TLSModes(t *testing.T) {
+	serverTLS, clientTLS := testTLSConfigs(t)
+
+	for _, mode := range []string{EmailTLSStartTLS, EmailTLSImplicit} {
+		t.Run(mode, func(t *testing.T) {
+			recorder := newSMTPRecorder(t, serverTLS, mode == EmailTLSImplicit)
+			cfg := EmailConfig{
+				Host:      "127.0.0.1",
+				Port:      recorder.port(),
+				Username:  "ralph",
+				Password:  "secret",
+				From:      "ralph@example.com",
+				To:        []string{"dev@example.com", "lead@example.com"},
+				TLS:       mode,
+				tlsConfig: clientTLS,
+			}
+			if err := SendDigest(context.Background(), cfg, testDigest()); err != nil {
+				t.Fatalf("SendDigest() error: %v", err)
+			}
+			<-recorder.done
+
+			if recorder.auth == "" || len(recorder.to) != 2 || !strings.Contains(recorder.from, "ralph@example.com") {
+				t.Errorf("unexpected envelope: auth=%q from=%q to=%v", recorder.auth, recorder.from, recorder.to)
+			}
+			for _, want := range []string{
+				"Subject: [ralph 3f2a9c1e] Plan completed after 4 iterations\n",
+				"Cost:       $1.50\n",
+				// A lone dot survives dot-stuffing
+				"All endpoints done.\n.\nTests pass.\n",
+				"Report: ralph report 3f2a9c1e-0000 --format markdown\n",
+			} {
+				if !strings.Contains(recorder.message, want) {
+					t.Errorf("expected %q in message:\n%s", want, recorder.message)
+				}
+			}
+		})
+	}
+}
+
+func TestSendDigest_StartTLSRequired(t *testing.T) {
+	recorder := newSMTPRecorder(t, nil, false)
+	cfg := EmailConfig{Host: "127.0.0.1", Port: recorder.port(), From: "a@example.com", To: []string{"b@example.com"}}
+
+	err := SendDigest(context.Background(), cfg, testDigest())
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("expected a STARTTLS error from a server without it, got: %v", err)
+	}
+}
+
+func TestSendDigest_NoTLS(t *testing.T) {
+	recorder := newSMTPRecorder(t, nil, false)
+	cfg := EmailConfig{
+		Host:      "127.0.0.1",
+		Port:      recorder.port(),
+		From:      "a@example.com",
+		To:        []string{"b@example.com"},
+		TLS:       EmailTLSNone,
+		ReportURL: "https://ci.example.com/ralph/{{.PlanID}}",
+	}
+	digest := testDigest()
+	digest.Status, digest.Iterations, digest.Error = "failed", 1, "claude exited"
+
+	if err := SendDigest(context.Background(), cfg, digest); err != nil {
+		t.Fatalf("SendDigest() error: %v", err)
+	}
+	<-recorder.done
+	for _, want := range []string{
+		"after 1 iteration\n",
+		"Error:      claude exited\n",
+		"Report: https://ci.example.com/ralph/3f2a9c1e-0000\n",
+	} {
+		if !strings.Contains(recorder.message, want) {
+			t.Errorf("expected %q in message:\n%s", want, recorder.message)
+		}
+	}
+}
+
+func TestSendDigest_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  EmailConfig
+		want string
+	}{
+		{"no host", EmailConfig{From: "a@x", To: []string{"b@x"}}, "host"},
+		{"no recipients", EmailConfig{Host: "smtp.example.com", From: "a@x"}, "recipient"},
+		{"bad TLS mode", EmailConfig{Host: "smtp.example.com", From: "a@x", To: []string{"b@x"}, TLS: "ssl"}, "TLS mode"},
+		{"bad report URL", EmailConfig{Host: "smtp.example.com", From: "a@x", To: []string{"b@x"}, ReportURL: "{{.Broken"}, "report URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SendDigest(context.Background(), tt.cfg, testDigest())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("SendDigest() error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigestMessage_EncodesSubject(t *testing.T) {
+	msg := string(digestMessage("a@x", []string{"b@x"}, "Plan ✓", "body\n", time.Unix(0, 0).UTC()))
+	if !strings.Contains(msg, "Subject: =?utf-8?q?") || !strings.HasSuffix(msg, "\r\n\r\nbody\r\n") {
+		t.Errorf("unexpected message:\n%q", msg)
+	}
+	reader := textproto.NewReader(bufio.NewReader(strings.NewReader(msg)))
+	header, err := reader.ReadMIMEHeader()
+	if err != nil || header.Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("unexpected header %v, %v", header, err)
+	}
+}
@@ -0,0 +1,261 @@
+// Package notify posts loop milestones to Slack or Discord webhooks, and
+// emails digests of finished runs.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+// Webhook providers, which differ in their payload format.
+const (
+	ProviderSlack   = "slack"
+	ProviderDiscord = "discord"
+)
+
+// DefaultTemplate formats a milestone when no template is configured.
+const DefaultTemplate = `[ralph {{printf "%.8s" .PlanID}}] {{.Event}} (iteration {{.Iteration}}): {{.Message}}`
+
+// DefaultEvents are the milestones posted when no events are configured.
+// EventPaused isn't one: batch mode (--iterations-this-run) pauses the plan
+// at the end of every scheduled run.
+var DefaultEvents = []string{
+	string(loop.EventStarted),
+	string(loop.EventReviewerFeedback),
+	string(loop.EventDone),
+	string(loop.EventMaxIterations),
+	string(loop.EventMaxDuration),
+	string(loop.EventMaxCost),
+	string(loop.EventError),
+	string(loop.EventFailed),
+	string(loop.EventFailureTriage),
+}
+
+// maxDiscordLength is Discord's limit on message content.
+const maxDiscordLength = 2000
+
+// queueSize bounds the messages waiting to be posted; further milestones are
+// dropped until the queue drains.
+const queueSize = 100
+
+// ErrMissingWebhook is returned when no webhook URL is configured.
+var ErrMissingWebhook = errors.New("notify webhook URL is empty")
+
+// Config holds the settings for a Notifier.
+type Config struct {
+	WebhookURL  string
+	Provider    string        // "slack" or "discord" (empty = detect from the URL)
+	Events      []string      // Loop event types to post (empty = DefaultEvents)
+	Template    string        // text/template for each message (empty = DefaultTemplate)
+	MinInterval time.Duration // Minimum time between posts; milestones in between are batched
+	PlanID      string
+}
+
+// Milestone is the data available to message templates.
+type Milestone struct {
+	PlanID    string
+	Event     string
+	Iteration int
+	MaxIter   int // 0 in extreme mode before it triggers
+	Message   string
+}
+
+// Notifier posts selected loop events to a webhook in the background.
+type Notifier struct {
+	cfg        Config
+	tmpl       *template.Template
+	events     map[loop.EventType]bool
+	httpClient *http.Client
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan string
+	done   chan struct{}
+}
+
+// New creates a Notifier and starts its delivery goroutine. Call Close to
+// flush pending messages.
+func New(cfg Config) (*Notifier, error) {
+	if cfg.WebhookURL == "" {
+		return nil, ErrMissingWebhook
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = ProviderSlack
+		if strings.Contains(cfg.WebhookURL, "discord.com/") || strings.Contains(cfg.WebhookURL, "discordapp.com/") {
+			cfg.Provider = ProviderDiscord
+		}
+	}
+	if cfg.Provider != ProviderSlack && cfg.Provider != ProviderDiscord {
+		return nil, fmt.Errorf("unsupported notify provider: %q", cfg.Provider)
+	}
+	if cfg.Template == "" {
+		cfg.Template = DefaultTemplate
+	}
+	tmpl, err := template.New("notify").Parse(cfg.Template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notify template: %w", err)
+	}
+
+	eventTypes := cfg.Events
+	if len(eventTypes) == 0 {
+		eventTypes = DefaultEvents
+	}
+	events := make(map[loop.EventType]bool, len(eventTypes))
+	for _, t := range eventTypes {
+		events[loop.EventType(t)] = true
+	}
+
+	n := &Notifier{
+		cfg:        cfg,
+		tmpl:       tmpl,
+		events:     events,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		queue:      make(chan string, queueSize),
+		done:       make(chan struct{}),
+	}
+	go n.run()
+	return n, nil
+}
+
+// EventTypes returns the loop event types the notifier posts, for use as a
+// subscription filter.
+func (n *Notifier) EventTypes() []loop.EventType {
+	types := make([]loop.EventType, 0, len(n.events))
+	for t := range n.events {
+		types = append(types, t)
+	}
+	return types
+}
+
+// SetHTTPClient allows setting a custom HTTP client (for testing). Call it
+// before the first Notify.
+func (n *Notifier) SetHTTPClient(httpClient *http.Client) {
+	n.httpClient = httpClient
+}
+
+// Notify queues a message for the event if its type is selected. It never
+// blocks; messages are dropped when the queue is full or after Close.
+func (n *Notifier) Notify(event loop.Event) {
+	if !n.events[event.Type] {
+		return
+	}
+
+	var msg bytes.Buffer
+	err := n.tmpl.Execute(&msg, Milestone{
+		PlanID:    n.cfg.PlanID,
+		Event:     string(event.Type),
+		Iteration: event.Iteration,
+		MaxIter:   event.MaxIter,
+		Message:   event.Message,
+	})
+	if err != nil {
+		log.Warn("failed to render notification", "event", event.Type, "error", err)
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	select {
+	case n.queue <- msg.String():
+	default:
+		log.Warn("notification queue full, dropping message", "event", event.Type)
+	}
+}
+
+// Close posts any queued messages, ignoring the rate limit, and waits for
+// delivery to finish.
+func (n *Notifier) Close() {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+	<-n.done
+}
+
+// run delivers queued messages, posting at most once per MinInterval.
+// Messages that arrive while waiting are batched into the next post.
+func (n *Notifier) run() {
+	defer close(n.done)
+
+	var lastPost time.Time
+	for msg := range n.queue {
+		batch := []string{msg}
+
+		if wait := n.cfg.MinInterval - time.Since(lastPost); !lastPost.IsZero() && wait > 0 {
+			timer := time.NewTimer(wait)
+		collect:
+			for {
+				select {
+				case next, ok := <-n.queue:
+					if !ok {
+						// Closed: flush now rather than waiting
+						timer.Stop()
+						break collect
+					}
+					batch = append(batch, next)
+				case <-timer.C:
+					break collect
+				}
+			}
+		}
+
+		if err := n.post(strings.Join(batch, "\n")); err != nil {
+			log.Warn("failed to post notification", "provider", n.cfg.Provider, "error", err)
+		}
+		lastPost = time.Now()
+	}
+}
+
+// post sends one message to the webhook.
+func (n *Notifier) post(text string) error {
+	payload := map[string]string{"text": text}
+	if n.cfg.Provider == ProviderDiscord {
+		if runes := []rune(text); len(runes) > maxDiscordLength {
+			text = string(runes[:maxDiscordLength-3]) + "..."
+		}
+		payload = map[string]string{"content": text}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+// webhookRecorder is a test webhook that records decoded payloads.
+type webhookRecorder struct {
+	mu       sync.Mutex
+	payloads []map[string]string
+}
+
+func (r *webhookRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var payload map[string]string
+	_ = json.NewDecoder(req.Body).Decode(&payload)
+	r.mu.Lock()
+	r.payloads = append(r.payloads, payload)
+	r.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *webhookRecorder) received() []map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]map[string]string(nil), r.payloads...)
+}
+
+func TestNew_Validation(t *testing.T) {
+	if _, err := New(Config{}); !errors.Is(err, ErrMissingWebhook) {
+		t.Errorf("New() error = %v, want ErrMissingWebhook", err)
+	}
+	if _, err := New(Config{WebhookURL: "http://x", Provider: "teams"}); err == nil {
+		t.Error("New() expected unsupported provider error")
+	}
+	if _, err := New(Config{WebhookURL: "http://x", Template: "{{.Broken"}); err == nil {
+		t.Error("New() expected template error")
+	}
+}
+
+func TestNew_DetectsDiscord(t *testing.T) {
+	n, err := New(Config{WebhookURL: "https://discord.com/api/webhooks/1/abc"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer n.Close()
+	if n.cfg.Provider != ProviderDiscord {
+		t.Errorf("provider = %q, want discord", n.cfg.Provider)
+	}
+}
+
+func TestNotifier_PostsSelectedEvents(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	n, err := New(Config{WebhookURL: server.URL, PlanID: "3f2a9c1e-aaaa"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	n.Notify(loop.NewEvent(loop.EventStarted, 1, 15, "Loop started"))
+	n.Notify(loop.NewEvent(loop.EventClaudeStart, 1, 15, "ignored"))
+	n.Notify(loop.NewEvent(loop.EventPaused, 1, 15, "ignored: batch runs pause every time"))
+	n.Close()
+
+	payloads := recorder.received()
+	if len(payloads) != 1 {
+		t.Fatalf("received %d posts, want 1: %v", len(payloads), payloads)
+	}
+	want := "[ralph 3f2a9c1e] started (iteration 1): Loop started"
+	if payloads[0]["text"] != want {
+		t.Errorf("text = %q, want %q", payloads[0]["text"], want)
+	}
+}
+
+func TestNotifier_DiscordTemplate(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	n, err := New(Config{
+		WebhookURL: server.URL,
+		Provider:   ProviderDiscord,
+		Events:     []string{string(loop.EventDone)},
+		Template:   "{{.Event}} after {{.Iteration}}/{{.MaxIter}}",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	n.Notify(loop.NewEvent(loop.EventDone, 4, 15, "Agent completed"))
+	n.Close()
+
+	payloads := recorder.received()
+	if len(payloads) != 1 || payloads[0]["content"] != "done after 4/15" {
+		t.Errorf("payloads = %v, want content \"done after 4/15\"", payloads)
+	}
+}
+
+func TestNotifier_RateLimitBatchesMessages(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	n, err := New(Config{WebhookURL: server.URL, Template: "{{.Message}}", MinInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	n.Notify(loop.NewEvent(loop.EventStarted, 1, 15, "first"))
+	// Wait for the first post so the following messages fall inside the interval
+	deadline := time.Now().Add(5 * time.Second)
+	for len(recorder.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	n.Notify(loop.NewEvent(loop.EventReviewerFeedback, 1, 15, "second"))
+	n.Notify(loop.NewEvent(loop.EventDone, 2, 15, "third"))
+	n.Close()
+
+	payloads := recorder.received()
+	if len(payloads) != 2 {
+		t.Fatalf("received %d posts, want 2: %v", len(payloads), payloads)
+	}
+	if payloads[1]["text"] != "second\nthird" {
+		t.Errorf("batched text = %q, want \"second\\nthird\"", payloads[1]["text"])
+	}
+}
+
+func TestNotifier_NotifyAfterCloseIsIgnored(t *testing.T) {
+	n, err := New(Config{WebhookURL: "http://127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	n.Close()
+	n.Close()
+
+	// Must not panic on the closed queue
+	n.Notify(loop.NewEvent(loop.EventDone, 1, 1, "late"))
+}
+
+func TestPost_TruncatesDiscordMessages(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	n, err := New(Config{WebhookURL: server.URL, Provider: ProviderDiscord})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer n.Close()
+
+	if err := n.post(strings.Repeat("x", 3000)); err != nil {
+		t.Fatalf("post() error = %v", err)
+	}
+	if got := len(recorder.received()[0]["content"]); got != maxDiscordLength {
+		t.Errorf("content length = %d, want %d", got, maxDiscordLength)
+	}
+}
@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// PlanChecklistHeader is the section reviewers report the plan's own
+// checks in.
+const PlanChecklistHeader = "### Plan Checklist"
+
+// AcceptanceCriteriaHeader is the section reviewers report the plan's
+// acceptance criteria in.
+const AcceptanceCriteriaHeader = "### Acceptance Criteria"
+
+// Labels heading failed checks and unsatisfied criteria in feedback.
+const (
+	failedChecksLabel   = "Failed plan checks:"
+	failedCriteriaLabel = "Unsatisfied acceptance criteria:"
+)
+
+// ChecklistResult is a reviewer's verdict on one check of a plan's review
+// checklist.
+type ChecklistResult struct {
+	Check  string `json:"check"`
+	Passed bool   `json:"passed"`
+	Note   string `json:"note,omitempty"` // Why the check failed (empty if it passed or none was given)
+}
+
+// checklistItemPattern matches a reported check: "- [x] check" or
+// "- [ ] check: why".
+var checklistItemPattern = regexp.MustCompile(`^[-*+]\s*\[([ xX])\]\s*(.+)$`)
+
+// parseChecklist returns the checks reported in the section under header
+// (the Plan Checklist or Acceptance Criteria), in order. Lines that aren't
+// task list items are skipped.
+func parseChecklist(output, header string) []ChecklistResult {
+	section, found := extractSection(output, header)
+	if !found {
+		return nil
+	}
+	var results []ChecklistResult
+	for _, line := range strings.Split(section, "\n") {
+		m := checklistItemPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		results = append(results, ChecklistResult{Check: strings.TrimSpace(m[2]), Passed: m[1] != " "})
+	}
+	return results
+}
+
+// MatchChecklist returns the verdict on each of a plan's checks, in the
+// plan's order, from the checks a reviewer reported. A report matches the
+// check it starts with, ignoring case, and the rest of it is the note. When
+// no report starts with a check but the reviewer reported as many checks as
+// the plan has, the report in the same position is taken. Checks the
+// reviewer didn't report fail.
+func MatchChecklist(checks []string, reported []ChecklistResult) []ChecklistResult {
+	byPosition := len(reported) == len(checks)
+	results := make([]ChecklistResult, len(checks))
+	for i, check := range checks {
+		results[i] = ChecklistResult{Check: check, Note: "not reported by the reviewer"}
+		var match *ChecklistResult
+		rest := ""
+		for j := range reported {
+			if text, ok := cutPrefixFold(reported[j].Check, check); ok {
+				match, rest = &reported[j], text
+				break
+			}
+		}
+		if match == nil && byPosition {
+			match = &reported[i]
+		}
+		if match == nil {
+			continue
+		}
+		note := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(rest), ":-–—"))
+		if match.Note != "" {
+			note = strings.TrimSpace(note + " " + match.Note)
+		}
+		results[i] = ChecklistResult{Check: check, Passed: match.Passed, Note: note}
+	}
+	return results
+}
+
+// FailedChecks formats the checks that failed as feedback for the
+// developer, or returns "" if all passed.
+func FailedChecks(results []ChecklistResult) string {
+	return formatFailed(failedChecksLabel, results)
+}
+
+// FailedCriteria formats the acceptance criteria the reviewer didn't mark
+// satisfied as feedback for the developer, or returns "" if all were.
+func FailedCriteria(results []ChecklistResult) string {
+	return formatFailed(failedCriteriaLabel, results)
+}
+
+// formatFailed lists the results that failed under label.
+func formatFailed(label string, results []ChecklistResult) string {
+	var b strings.Builder
+	for _, r := range results {
+		if r.Passed {
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString(label + "\n")
+		}
+		b.WriteString("- " + r.Check)
+		if r.Note != "" {
+			b.WriteString(": " + r.Note)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// cutPrefixFold is strings.CutPrefix ignoring case and surrounding quotes
+// or backticks the reviewer may have added.
+func cutPrefixFold(s, prefix string) (string, bool) {
+	s = strings.TrimLeft(s, "\"'`")
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimLeft(s[len(prefix):], "\"'`"), true
+}
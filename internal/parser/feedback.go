@@ -0,0 +1,70 @@
+package parser
+
+import "strings"
+
+// FeedbackItem is one issue from reviewer feedback.
+type FeedbackItem struct {
+	Severity string // "Critical", "Major", or "Minor"; empty when the reviewer gave none
+	Text     string // The issue, without its list marker; code blocks that follow it are included
+}
+
+// MergeFeedback combines the feedback of several reviewers into the distinct
+// issues they raised, in order of first appearance. Each list item or line is
+// an issue, under the severity of the "Critical Issues:" style label before
+// it. Issues match like learnings do, and the first wording is kept.
+func MergeFeedback(feedbacks []string) []FeedbackItem {
+	var seen learningSet
+	return mergeFeedback(&seen, feedbacks)
+}
+
+// mergeFeedback returns the issues in feedbacks that don't match one in
+// seen, recording them there.
+func mergeFeedback(seen *learningSet, feedbacks []string) []FeedbackItem {
+	var items []FeedbackItem
+	for _, feedback := range feedbacks {
+		severity := ""
+		inFence := false
+		kept := false // Whether the current issue was kept, for its code blocks
+		for _, line := range strings.Split(feedback, "\n") {
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, "```") || inFence {
+				if strings.HasPrefix(trimmed, "```") {
+					inFence = !inFence
+				}
+				if kept {
+					items[len(items)-1].Text += "\n" + line
+				}
+				continue
+			}
+			if isLearningStructure(trimmed) {
+				continue
+			}
+			if label, ok := severityLabel(trimmed); ok {
+				severity = label
+				continue
+			}
+			text := learningText(trimmed)
+			kept = seen.add(text)
+			if kept {
+				items = append(items, FeedbackItem{Severity: severity, Text: text})
+			}
+		}
+	}
+	return items
+}
+
+// severityLabel reports whether a trimmed line is one of the severity labels
+// extractReviewerFeedback writes, returning the severity. Failed plan checks
+// and unsatisfied acceptance criteria (see FailedChecks and FailedCriteria)
+// are major issues.
+func severityLabel(line string) (string, bool) {
+	for _, severity := range []string{"Critical", "Major", "Minor"} {
+		if line == severity+" Issues:" {
+			return severity, true
+		}
+	}
+	if line == failedChecksLabel || line == failedCriteriaLabel {
+		return "Major", true
+	}
+	return "", false
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FixedItemsHeader is the section developers list the open review items
+// they fixed in.
+const FixedItemsHeader = "## Fixed Items"
+
+// VerifiedItemsHeader is the section reviewers list the claimed fixes they
+// verified in.
+const VerifiedItemsHeader = "### Verified Items"
+
+// itemNumberPattern matches a review item reference: "#3".
+var itemNumberPattern = regexp.MustCompile(`#(\d+)\b`)
+
+// parseItemNumbers returns the distinct review item numbers referenced in
+// the section under header, in order of first reference.
+func parseItemNumbers(output, header string) []int {
+	section, found := extractSection(output, header)
+	if !found {
+		return nil
+	}
+	return itemNumbers(section)
+}
+
+// itemNumbers returns the distinct review item numbers referenced in text,
+// in order of first reference.
+func itemNumbers(text string) []int {
+	var numbers []int
+	for _, match := range itemNumberPattern.FindAllStringSubmatch(text, -1) {
+		n, err := strconv.Atoi(match[1])
+		if err != nil || n <= 0 || slices.Contains(numbers, n) {
+			continue
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
+
+// validItemNumbers returns the distinct positive numbers of a status
+// report's item list, in order.
+func validItemNumbers(numbers []int) []int {
+	var valid []int
+	for _, n := range numbers {
+		if n > 0 && !slices.Contains(valid, n) {
+			valid = append(valid, n)
+		}
+	}
+	return valid
+}
+
+// NewFeedback returns the issues in feedbacks, as MergeFeedback does,
+// leaving out those that match one of known: issues already being tracked.
+func NewFeedback(known, feedbacks []string) []FeedbackItem {
+	var seen learningSet
+	for _, text := range known {
+		seen.add(strings.TrimSpace(text))
+	}
+	return mergeFeedback(&seen, feedbacks)
+}
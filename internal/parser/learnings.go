@@ -0,0 +1,163 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+)
+
+// learningSimilarity is the share of distinct words two learnings must have
+// in common (Jaccard index) to count as the same learning.
+const learningSimilarity = 0.8
+
+// minFuzzyWords is the fewest words a learning needs before it is compared
+// fuzzily; shorter learnings must match exactly once normalized.
+const minFuzzyWords = 4
+
+// negationWords turn a learning into its opposite, so learnings that differ
+// in them never match fuzzily however many words they share. "t" is what
+// is left of a contraction such as "don't" once punctuation is dropped.
+var negationWords = map[string]bool{
+	"not": true, "no": true, "never": true, "nor": true, "none": true,
+	"nothing": true, "without": true, "avoid": true, "cannot": true, "t": true,
+}
+
+// DedupeLearnings removes learnings that repeat an earlier one from a
+// learnings section. Each list item or line is a learning; two match when
+// they have the same words ignoring case, punctuation, and markdown, or when
+// they share most of their words and the same negations. The first wording
+// is kept. Headings,
+// horizontal rules, blank lines, and code blocks are left as they are.
+func DedupeLearnings(content string) string {
+	var seen learningSet
+	var out []string
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		if inFence || strings.HasPrefix(trimmed, "```") || isLearningStructure(trimmed) {
+			out = append(out, line)
+			continue
+		}
+		if seen.add(learningText(trimmed)) {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// UniqueLearnings returns the distinct learnings across learnings sections,
+// in order of first appearance, without list markers. Headings, horizontal
+// rules, and code blocks are skipped.
+func UniqueLearnings(contents []string) []string {
+	var seen learningSet
+	var items []string
+	for _, content := range contents {
+		inFence := false
+		for _, line := range strings.Split(content, "\n") {
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, "```") {
+				inFence = !inFence
+				continue
+			}
+			if inFence || isLearningStructure(trimmed) {
+				continue
+			}
+			text := learningText(trimmed)
+			if seen.add(text) {
+				items = append(items, text)
+			}
+		}
+	}
+	return items
+}
+
+// isLearningStructure reports whether a trimmed line is markdown structure
+// rather than a learning.
+func isLearningStructure(line string) bool {
+	return line == "" || line == "---" || strings.HasPrefix(line, "#")
+}
+
+// learningText strips the list marker from a trimmed line.
+func learningText(line string) string {
+	for _, marker := range []string{"- ", "* ", "+ "} {
+		if strings.HasPrefix(line, marker) {
+			return strings.TrimSpace(line[len(marker):])
+		}
+	}
+	if text, ok := numberedItem(line); ok {
+		return text
+	}
+	return line
+}
+
+// learningSet records the learnings seen so far.
+type learningSet struct {
+	exact map[string]bool
+	words []map[string]bool // word sets of learnings long enough to compare fuzzily
+}
+
+// sameNegation reports whether two word sets have the same negation words.
+func sameNegation(a, b map[string]bool) bool {
+	for w := range negationWords {
+		if a[w] != b[w] {
+			return false
+		}
+	}
+	return true
+}
+
+// add records a learning, reporting false if it matches one already seen.
+func (s *learningSet) add(text string) bool {
+	words := learningWords(text)
+	if len(words) == 0 {
+		return true // Only punctuation; nothing to compare
+	}
+	key := strings.Join(words, " ")
+	if s.exact[key] {
+		return false
+	}
+
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	if len(set) >= minFuzzyWords {
+		for _, other := range s.words {
+			if sameNegation(set, other) && jaccard(set, other) >= learningSimilarity {
+				return false
+			}
+		}
+		s.words = append(s.words, set)
+	}
+
+	if s.exact == nil {
+		s.exact = make(map[string]bool)
+	}
+	s.exact[key] = true
+	return true
+}
+
+// learningWords returns the lowercase words of a learning, dropping
+// punctuation and markdown.
+func learningWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// jaccard returns the share of words in a or b that are in both.
+func jaccard(a, b map[string]bool) float64 {
+	shared := 0
+	for w := range a {
+		if b[w] {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
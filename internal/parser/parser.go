@@ -4,6 +4,7 @@ package parser
 import (
 	"strings"
 
+	"github.com/gerunddev/ralph/internal/locale"
 	"github.com/gerunddev/ralph/internal/log"
 )
 
@@ -15,8 +16,14 @@ const (
 	DevDoneMarker          = "DEV_DONE DEV_DONE DEV_DONE!!!"
 	ReviewerApprovedMarker = "REVIEWER_APPROVED REVIEWER_APPROVED!!!"
 	ReviewerFeedbackPrefix = "REVIEWER_FEEDBACK:"
+	SuggestedPatchHeader   = "### Suggested Patch"
+	RebuttalAcceptedMarker = "REBUTTAL_ACCEPTED REBUTTAL_ACCEPTED!!!"
 )
 
+// RejectedApproachesHeader is the section reviewers name the approaches
+// not to try again in.
+const RejectedApproachesHeader = "### Rejected Approaches"
+
 // ParseResult holds the result of parsing agent output.
 type ParseResult struct {
 	IsDone    bool   // True if the agent indicated completion
@@ -32,12 +39,54 @@ type AgentParseResult struct {
 	Learnings string // Extracted learnings content
 	Raw       string // Original output
 
+	// Malformed is true when the output had text but neither a Progress
+	// nor a Learnings section, so all of it was taken as progress.
+	Malformed bool
+
+	// GlobalLearnings are learnings the agent promoted to apply repo-wide,
+	// one per bullet of the "## Global Learnings" section.
+	GlobalLearnings []string
+
 	// Developer-specific
-	DevDone bool // True if developer signaled DEV_DONE
+	DevDone  bool   // True if developer signaled DEV_DONE
+	Rebuttal string // The developer's dispute of the last review feedback (empty if none)
+
+	// DisputedItems are the numbers of the review items the rebuttal
+	// disputes, as it cites them
+	DisputedItems []int
+
+	// FixedItems are the numbers of the open review items the developer
+	// claims to have fixed, from the "## Fixed Items" section
+	FixedItems []int
+
+	// SubPlan is a plan the developer proposed for a large tangent, from
+	// the "## Sub-Plan" section (empty if none)
+	SubPlan string
 
 	// Reviewer-specific
 	ReviewerApproved bool   // True if reviewer approved
 	ReviewerFeedback string // Feedback text if not approved
+	ReviewerPatch    string // Unified diff the reviewer suggested with its feedback (empty if none)
+
+	// Checklist is the reviewer's verdict on each check of the plan's
+	// review checklist it reported, in the order reported
+	Checklist []ChecklistResult
+
+	// Criteria is the reviewer's verdict on each of the plan's acceptance
+	// criteria it reported, in the order reported
+	Criteria []ChecklistResult
+
+	// VerifiedItems are the numbers of the claimed-fixed review items the
+	// reviewer verified, from the "### Verified Items" section
+	VerifiedItems []int
+
+	// RejectedApproaches are the approaches the reviewer rejected outright,
+	// not to be tried again, from the "### Rejected Approaches" section
+	RejectedApproaches []string
+
+	// Rebuttal reviewer-specific
+	RebuttalAccepted bool   // True if the reviewer withdrew the disputed feedback
+	RebuttalResponse string // The reviewer's reasoning, addressed to the developer
 }
 
 // Parse parses agent output to determine completion state or extract progress/learnings.
@@ -85,9 +134,23 @@ func Parse(output string) *ParseResult {
 // extractSection extracts the content of a markdown section.
 // It looks for a header like "## Progress" (case-insensitive) and extracts
 // content until the next "##" header or end of string.
-// Headers inside fenced code blocks are ignored.
+// Headers inside fenced code blocks are ignored, and the header's
+// equivalents in supported locales (e.g. "## 進捗") are accepted.
 // Returns the extracted content (trimmed) and whether the section was found.
 func extractSection(output, header string) (string, bool) {
+	if content, found := findSection(output, header); found {
+		return content, true
+	}
+	for _, alias := range locale.HeaderAliases(header) {
+		if content, found := findSection(output, alias); found {
+			return content, true
+		}
+	}
+	return "", false
+}
+
+// findSection extracts the section under exactly header.
+func findSection(output, header string) (string, bool) {
 	// Mask code blocks to avoid matching headers inside them
 	masked := maskCodeBlocks(output)
 
@@ -215,8 +278,9 @@ func containsMarker(s, marker string) bool {
 	return true
 }
 
-// ParseAgentOutput parses output from a developer or reviewer agent.
-// The agentType should be "developer" or "reviewer".
+// ParseAgentOutput parses output from a developer, reviewer, or rebuttal
+// reviewer agent. The agentType should be "developer", "reviewer", or
+// "rebuttal_reviewer".
 func ParseAgentOutput(output, agentType string) *AgentParseResult {
 	result := &AgentParseResult{
 		Raw: output,
@@ -230,6 +294,10 @@ func ParseAgentOutput(output, agentType string) *AgentParseResult {
 	result.Progress = progress
 	result.Learnings = learnings
 
+	if global, found := extractSection(output, "## Global Learnings"); found {
+		result.GlobalLearnings = splitBullets(global)
+	}
+
 	// If no recognized sections found, treat entire output as progress (malformed case)
 	trimmed := strings.TrimSpace(output)
 	if !foundProgress && !foundLearnings {
@@ -237,6 +305,7 @@ func ParseAgentOutput(output, agentType string) *AgentParseResult {
 			log.Warn("malformed agent output: no sections found, treating as progress",
 				"agent_type", agentType, "output_length", len(output))
 			result.Progress = trimmed
+			result.Malformed = true
 		}
 	}
 
@@ -248,6 +317,13 @@ func ParseAgentOutput(output, agentType string) *AgentParseResult {
 		} else if containsMarker(trimmed, DevDoneMarker) {
 			result.DevDone = true
 		}
+		if rebuttal, found := extractSection(output, "## Rebuttal"); found {
+			// The section may run into the rule before the status
+			result.Rebuttal = strings.TrimSpace(strings.TrimSuffix(rebuttal, "---"))
+			result.DisputedItems = itemNumbers(result.Rebuttal)
+		}
+		result.FixedItems = parseItemNumbers(output, FixedItemsHeader)
+		result.SubPlan = parseSubPlan(output)
 
 	case "reviewer":
 		// Check for reviewer approved marker in status/verdict section
@@ -260,9 +336,26 @@ func ParseAgentOutput(output, agentType string) *AgentParseResult {
 			result.ReviewerApproved = true
 		}
 
-		// Extract reviewer feedback if not approved
+		result.Checklist = parseChecklist(output, PlanChecklistHeader)
+		result.Criteria = parseChecklist(output, AcceptanceCriteriaHeader)
+		result.VerifiedItems = parseItemNumbers(output, VerifiedItemsHeader)
+
+		// Extract reviewer feedback, any suggested patch, and rejected
+		// approaches if not approved
 		if !result.ReviewerApproved {
 			result.ReviewerFeedback = extractReviewerFeedback(output)
+			result.ReviewerPatch = extractSuggestedPatch(output)
+			result.RejectedApproaches = parseListItems(output, RejectedApproachesHeader)
+		}
+
+	case "rebuttal_reviewer":
+		// Without an explicit acceptance the disputed feedback stands
+		verdict, _ := extractSection(output, "### Verdict")
+		result.RebuttalAccepted = containsMarker(verdict, RebuttalAcceptedMarker) || containsMarker(trimmed, RebuttalAcceptedMarker)
+		if response, found := extractSection(output, "## Response"); found && response != "" {
+			result.RebuttalResponse = response
+		} else {
+			result.RebuttalResponse = trimmed
 		}
 	}
 
@@ -291,17 +384,17 @@ func extractReviewerFeedback(output string) string {
 	majorIssues, foundMajor := extractSection(output, "### Major Issues")
 	minorIssues, foundMinor := extractSection(output, "### Minor Issues")
 
-	if foundCritical && criticalIssues != "" && strings.ToLower(criticalIssues) != "none" {
+	if foundCritical && criticalIssues != "" && !locale.IsNone(criticalIssues) {
 		feedback.WriteString("Critical Issues:\n")
 		feedback.WriteString(criticalIssues)
 		feedback.WriteString("\n\n")
 	}
-	if foundMajor && majorIssues != "" && strings.ToLower(majorIssues) != "none" {
+	if foundMajor && majorIssues != "" && !locale.IsNone(majorIssues) {
 		feedback.WriteString("Major Issues:\n")
 		feedback.WriteString(majorIssues)
 		feedback.WriteString("\n\n")
 	}
-	if foundMinor && minorIssues != "" && strings.ToLower(minorIssues) != "none" {
+	if foundMinor && minorIssues != "" && !locale.IsNone(minorIssues) {
 		feedback.WriteString("Minor Issues:\n")
 		feedback.WriteString(minorIssues)
 		feedback.WriteString("\n\n")
@@ -314,3 +407,145 @@ func extractReviewerFeedback(output string) string {
 	// Fallback: use entire output as feedback if no structured sections found
 	return strings.TrimSpace(output)
 }
+
+// parseListItems returns the items of the list in the section under header,
+// without their markers, skipping "None".
+func parseListItems(output, header string) []string {
+	section, found := extractSection(output, header)
+	if !found || locale.IsNone(section) {
+		return nil
+	}
+	var items []string
+	for _, line := range strings.Split(section, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if isLearningStructure(trimmed) {
+			continue
+		}
+		if text := learningText(trimmed); text != "" {
+			items = append(items, text)
+		}
+	}
+	return items
+}
+
+// extractSuggestedPatch returns the unified diff in the "### Suggested Patch"
+// section: the contents of its first fenced code block, or the whole section
+// when it is an unfenced diff. Returns "" if there is no patch.
+func extractSuggestedPatch(output string) string {
+	section, found := extractSection(output, SuggestedPatchHeader)
+	if !found {
+		return ""
+	}
+
+	var patch []string
+	inFence := false
+	for _, line := range strings.Split(section, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if inFence {
+				break
+			}
+			inFence = true
+			continue
+		}
+		if inFence {
+			patch = append(patch, line)
+		}
+	}
+	if !inFence {
+		// Unfenced: accept the section only if it looks like a diff
+		if !strings.HasPrefix(section, "diff ") && !strings.HasPrefix(section, "--- ") {
+			return ""
+		}
+		return section + "\n"
+	}
+
+	// Trim only blank lines: leading spaces are diff context
+	text := strings.Trim(strings.Join(patch, "\n"), "\n")
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	return text + "\n"
+}
+
+// splitBullets splits a markdown list into items, stripping bullet markers
+// and skipping blank lines and horizontal rules.
+func splitBullets(s string) []string {
+	var items []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "---" {
+			continue
+		}
+		for _, marker := range []string{"- ", "* ", "+ "} {
+			if strings.HasPrefix(line, marker) {
+				line = strings.TrimSpace(line[len(marker):])
+				break
+			}
+		}
+		if line != "" {
+			items = append(items, line)
+		}
+	}
+	return items
+}
+
+// PlannedTask is a task produced by the planner agent.
+type PlannedTask struct {
+	Title       string
+	Description string
+}
+
+// ParseTasks extracts the ordered task list from planner output.
+// Tasks are the numbered items of the "## Tasks" section: the text after the
+// number is the title and any following lines up to the next item are the
+// description. Returns nil if the section is missing or has no items.
+func ParseTasks(output string) []PlannedTask {
+	section, found := extractSection(output, "## Tasks")
+	if !found {
+		return nil
+	}
+
+	var tasks []PlannedTask
+	var description []string
+	flush := func() {
+		if len(tasks) > 0 {
+			tasks[len(tasks)-1].Description = strings.TrimSpace(strings.Join(description, "\n"))
+		}
+		description = nil
+	}
+
+	for _, line := range strings.Split(section, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if title, ok := numberedItem(trimmed); ok {
+			flush()
+			tasks = append(tasks, PlannedTask{Title: title})
+			continue
+		}
+		if len(tasks) > 0 && trimmed != "---" {
+			description = append(description, trimmed)
+		}
+	}
+	flush()
+
+	return tasks
+}
+
+// numberedItem returns the text of an ordered list item such as "1. Text"
+// or "2) Text".
+func numberedItem(line string) (string, bool) {
+	digits := 0
+	for digits < len(line) && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 || digits+1 >= len(line) {
+		return "", false
+	}
+	if line[digits] != '.' && line[digits] != ')' {
+		return "", false
+	}
+	if line[digits+1] != ' ' && line[digits+1] != '\t' {
+		return "", false
+	}
+	text := strings.TrimSpace(line[digits+1:])
+	return text, text != ""
+}
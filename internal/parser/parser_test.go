@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"slices"
 	"strings"
 	"testing"
 )
@@ -810,6 +811,96 @@ func TestParseAgentOutput_DevMalformedOutput(t *testing.T) {
 	if result.Progress != input {
 		t.Errorf("Progress should be entire output for malformed, got %q", result.Progress)
 	}
+	if !result.Malformed {
+		t.Error("Malformed should be true without any sections")
+	}
+
+	if ParseAgentOutput("## Progress\nDone.", "developer").Malformed {
+		t.Error("Malformed should be false with a Progress section")
+	}
+	if ParseAgentOutput("  ", "developer").Malformed {
+		t.Error("Malformed should be false for empty output")
+	}
+}
+
+func TestParseAgentOutput_DevRebuttal(t *testing.T) {
+	input := `## Progress
+Fixed the error handling.
+
+## Learnings
+None.
+
+## Rebuttal
+#2: The reviewer asks for a nil check in config.go:12, but New never returns a nil config.
+
+---
+
+## Status
+RUNNING RUNNING RUNNING`
+
+	result := ParseAgentOutput(input, "developer")
+
+	want := "#2: The reviewer asks for a nil check in config.go:12, but New never returns a nil config."
+	if result.Rebuttal != want {
+		t.Errorf("Rebuttal = %q, want %q", result.Rebuttal, want)
+	}
+	if !slices.Equal(result.DisputedItems, []int{2}) {
+		t.Errorf("DisputedItems = %v, want [2]", result.DisputedItems)
+	}
+	if result.Progress != "Fixed the error handling." {
+		t.Errorf("Progress = %q", result.Progress)
+	}
+
+	if result := ParseAgentOutput("## Progress\nDone.", "developer"); result.Rebuttal != "" {
+		t.Errorf("Rebuttal = %q, want empty without the section", result.Rebuttal)
+	}
+}
+
+// =============================================================================
+// Rebuttal Reviewer Output Tests
+// =============================================================================
+
+func TestParseAgentOutput_RebuttalReviewer(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantAccepted bool
+		wantResponse string
+	}{
+		{
+			name:         "accepted",
+			input:        "## Response\nYou are right, New validates the config.\n\n### Verdict\n" + RebuttalAcceptedMarker,
+			wantAccepted: true,
+			wantResponse: "You are right, New validates the config.",
+		},
+		{
+			name:         "upheld",
+			input:        "## Response\nLoad can still return nil.\n\n### Verdict\nFEEDBACK_UPHELD",
+			wantResponse: "Load can still return nil.",
+		},
+		{
+			name:         "extra exclamation",
+			input:        "## Response\nFine.\n\n### Verdict\n" + RebuttalAcceptedMarker + "!",
+			wantResponse: "Fine.",
+		},
+		{
+			name:         "no response section",
+			input:        "The feedback stands.",
+			wantResponse: "The feedback stands.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ParseAgentOutput(tt.input, "rebuttal_reviewer")
+			if result.RebuttalAccepted != tt.wantAccepted {
+				t.Errorf("RebuttalAccepted = %v, want %v", result.RebuttalAccepted, tt.wantAccepted)
+			}
+			if result.RebuttalResponse != tt.wantResponse {
+				t.Errorf("RebuttalResponse = %q, want %q", result.RebuttalResponse, tt.wantResponse)
+			}
+		})
+	}
 }
 
 // =============================================================================
@@ -896,6 +987,40 @@ REVIEWER_FEEDBACK: The login function needs proper error handling for database f
 	}
 }
 
+func TestParseAgentOutput_ReviewerSuggestedPatch(t *testing.T) {
+	input := "## Progress\nFound a bug.\n\n### Critical Issues\nNone\n\n### Major Issues\n- Off by one in loop.go:10\n\n### Minor Issues\nNone\n\n" +
+		"### Verdict\nREVIEWER_FEEDBACK: Fix the off-by-one.\n\n### Suggested Patch\n```diff\n" +
+		"--- a/loop.go\n+++ b/loop.go\n@@ -9,3 +9,3 @@\n func f() {\n-\tfor i := 0; i <= n; i++ {\n+\tfor i := 0; i < n; i++ {\n }\n```\n"
+
+	result := ParseAgentOutput(input, "reviewer")
+
+	if result.ReviewerFeedback != "Fix the off-by-one." {
+		t.Errorf("ReviewerFeedback = %q, expected patch to be excluded", result.ReviewerFeedback)
+	}
+	want := "--- a/loop.go\n+++ b/loop.go\n@@ -9,3 +9,3 @@\n func f() {\n-\tfor i := 0; i <= n; i++ {\n+\tfor i := 0; i < n; i++ {\n }\n"
+	if result.ReviewerPatch != want {
+		t.Errorf("ReviewerPatch = %q, want %q", result.ReviewerPatch, want)
+	}
+}
+
+func TestParseAgentOutput_ReviewerSuggestedPatch_Ignored(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"no section", "### Verdict\nREVIEWER_FEEDBACK: Fix it"},
+		{"not a diff", "### Verdict\nREVIEWER_FEEDBACK: Fix it\n\n### Suggested Patch\nRename the variable."},
+		{"empty fence", "### Verdict\nREVIEWER_FEEDBACK: Fix it\n\n### Suggested Patch\n```diff\n```"},
+		{"approved", "### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!\n\n### Suggested Patch\n```diff\n--- a/x\n+++ b/x\n```"},
+	}
+
+	for _, tt := range tests {
+		if patch := ParseAgentOutput(tt.input, "reviewer").ReviewerPatch; patch != "" {
+			t.Errorf("%s: ReviewerPatch = %q, want empty", tt.name, patch)
+		}
+	}
+}
+
 func TestParseAgentOutput_ReviewerFeedback_ExactTestCase(t *testing.T) {
 	// This is the exact output from TestLoop_ReviewerRejects
 	input := "## Progress\nReviewed code\n\n### Critical Issues\nNone\n\n### Major Issues\n- Missing error handling in auth.go:42\n\n### Minor Issues\nNone\n\n### Verdict\nREVIEWER_FEEDBACK: Fix the error handling issue"
@@ -1058,3 +1183,407 @@ DEV_DONE DEV_DONE DEV_DONE!!!`
 		t.Error("ReviewerApproved should be false for DEV_DONE marker")
 	}
 }
+
+func TestParseAgentOutput_GlobalLearnings(t *testing.T) {
+	output := `## Progress
+Added the endpoint
+
+## Learnings
+The handler package uses table tests
+
+## Global Learnings
+- Run ` + "`make generate`" + ` after editing protos
+* Integration tests need DOCKER_HOST set
+
+---
+
+## Status
+RUNNING RUNNING RUNNING`
+
+	result := ParseAgentOutput(output, "developer")
+
+	if result.Learnings != "The handler package uses table tests" {
+		t.Errorf("Learnings = %q", result.Learnings)
+	}
+	want := []string{"Run `make generate` after editing protos", "Integration tests need DOCKER_HOST set"}
+	if len(result.GlobalLearnings) != len(want) {
+		t.Fatalf("GlobalLearnings = %v, want %v", result.GlobalLearnings, want)
+	}
+	for i := range want {
+		if result.GlobalLearnings[i] != want[i] {
+			t.Errorf("GlobalLearnings[%d] = %q, want %q", i, result.GlobalLearnings[i], want[i])
+		}
+	}
+}
+
+func TestParseAgentOutput_NoGlobalLearnings(t *testing.T) {
+	result := ParseAgentOutput("## Progress\nWorking\n\n## Learnings\nSomething", "developer")
+	if result.GlobalLearnings != nil {
+		t.Errorf("expected no global learnings, got %v", result.GlobalLearnings)
+	}
+}
+
+func TestParseTasks(t *testing.T) {
+	output := `I split the plan into three tasks.
+
+## Tasks
+
+1. Add the config section
+   Add a Webhook struct to config.Config with defaults
+   and validation.
+
+2) Send notifications
+Post JSON on loop completion.
+3. Document the feature
+
+---
+
+## Notes
+Nothing else.`
+
+	tasks := ParseTasks(output)
+
+	want := []PlannedTask{
+		{Title: "Add the config section", Description: "Add a Webhook struct to config.Config with defaults\nand validation."},
+		{Title: "Send notifications", Description: "Post JSON on loop completion."},
+		{Title: "Document the feature", Description: ""},
+	}
+	if len(tasks) != len(want) {
+		t.Fatalf("ParseTasks() returned %d tasks, want %d: %+v", len(tasks), len(want), tasks)
+	}
+	for i := range want {
+		if tasks[i] != want[i] {
+			t.Errorf("tasks[%d] = %+v, want %+v", i, tasks[i], want[i])
+		}
+	}
+}
+
+func TestParseTasks_Missing(t *testing.T) {
+	if tasks := ParseTasks("## Progress\nNo tasks here"); tasks != nil {
+		t.Errorf("expected nil tasks, got %+v", tasks)
+	}
+	if tasks := ParseTasks("## Tasks\n- not numbered"); tasks != nil {
+		t.Errorf("expected nil tasks for unnumbered list, got %+v", tasks)
+	}
+}
+
+func TestDedupeLearnings(t *testing.T) {
+	content := `### Build
+- Run tests with ` + "`go test ./...`" + `
+- run tests with go test ./...
+- The config loader caches files per directory
+1. The config loader caches the files per directory
+- Fixtures live in testdata/
+
+` + "```" + `
+}
+}
+` + "```"
+
+	want := `### Build
+- Run tests with ` + "`go test ./...`" + `
+- The config loader caches files per directory
+- Fixtures live in testdata/
+
+` + "```" + `
+}
+}
+` + "```"
+
+	if got := DedupeLearnings(content); got != want {
+		t.Errorf("DedupeLearnings() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUniqueLearnings(t *testing.T) {
+	contents := []string{
+		"- Use jj, not git\n- The API client retries on 429",
+		"## Learnings\n* use JJ, not git!\n- The API client retries on 429 responses\n- Migrations run at startup",
+	}
+
+	got := UniqueLearnings(contents)
+	want := []string{"Use jj, not git", "The API client retries on 429", "Migrations run at startup"}
+	if len(got) != len(want) {
+		t.Fatalf("UniqueLearnings() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("UniqueLearnings()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestUniqueLearnings_KeepsNegations(t *testing.T) {
+	contents := []string{
+		"- Run the migrations before the integration tests start\n- The cache is shared between the workers in tests",
+		"- Don't run the migrations before the integration tests start\n- The cache is not shared between the workers in tests",
+	}
+
+	if got := UniqueLearnings(contents); len(got) != 4 {
+		t.Errorf("UniqueLearnings() = %q, want learnings that differ in a negation kept apart", got)
+	}
+}
+
+func TestMergeFeedback(t *testing.T) {
+	feedbacks := []string{
+		"Critical Issues:\n- auth.go:10 skips the token check\n\nMajor Issues:\n- No tests for the login handler\n```go\nif token == \"\" {\n```\n",
+		"Critical Issues:\n- auth.go:10 skips the token check!\n\nMinor Issues:\n* Rename `x` to `count`\n",
+		"Handle expired sessions in middleware.go",
+	}
+
+	got := MergeFeedback(feedbacks)
+	want := []FeedbackItem{
+		{Severity: "Critical", Text: "auth.go:10 skips the token check"},
+		{Severity: "Major", Text: "No tests for the login handler\n```go\nif token == \"\" {\n```"},
+		{Severity: "Minor", Text: "Rename `x` to `count`"},
+		{Text: "Handle expired sessions in middleware.go"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("MergeFeedback() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("item %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestNewFeedback(t *testing.T) {
+	known := []string{"auth.go:10 skips the token check"}
+	got := NewFeedback(known, []string{"Critical Issues:\n- auth.go:10 skips the token check!\n- No tests for the login handler\n"})
+	if len(got) != 1 || got[0] != (FeedbackItem{Severity: "Critical", Text: "No tests for the login handler"}) {
+		t.Errorf("NewFeedback() = %+v, want only the untracked issue", got)
+	}
+}
+
+func TestParseAgentOutput_ItemNumbers(t *testing.T) {
+	dev := ParseAgentOutput("## Progress\nFixed\n\n## Fixed Items\n- #3 (nil check)\n- #1, #3\n\n## Status\nRUNNING RUNNING RUNNING", "developer")
+	if !slices.Equal(dev.FixedItems, []int{3, 1}) {
+		t.Errorf("FixedItems = %v, want [3 1]", dev.FixedItems)
+	}
+
+	review := ParseAgentOutput("## Progress\nReviewed\n\n### Verdict\nREVIEWER_FEEDBACK: add the test\n\n### Verified Items\n- #2\n", "reviewer")
+	if !slices.Equal(review.VerifiedItems, []int{2}) {
+		t.Errorf("VerifiedItems = %v, want [2]", review.VerifiedItems)
+	}
+	if strings.Contains(review.ReviewerFeedback, "#2") {
+		t.Errorf("ReviewerFeedback = %q, should not include the verified items", review.ReviewerFeedback)
+	}
+
+	if none := ParseAgentOutput("## Progress\nWorking\n\nSee issue #4", "developer"); none.FixedItems != nil {
+		t.Errorf("FixedItems without the section = %v, want none", none.FixedItems)
+	}
+}
+
+func TestParseAgentOutput_RejectedApproaches(t *testing.T) {
+	output := "## Progress\nReviewed\n\n### Major Issues\n- The cache races\n\n### Verdict\nChanges needed.\n\n" +
+		"### Rejected Approaches\n- Caching in a package-level map: it is shared across requests\n"
+	review := ParseAgentOutput(output, "reviewer")
+	if want := []string{"Caching in a package-level map: it is shared across requests"}; !slices.Equal(review.RejectedApproaches, want) {
+		t.Errorf("RejectedApproaches = %q, want %q", review.RejectedApproaches, want)
+	}
+	if strings.Contains(review.ReviewerFeedback, "package-level map") {
+		t.Errorf("ReviewerFeedback = %q, should not include the rejected approaches", review.ReviewerFeedback)
+	}
+
+	for _, output := range []string{
+		"## Progress\nReviewed\n\n### Verdict\nChanges needed.\n\n### Rejected Approaches\nNone",
+		"## Progress\nReviewed\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!\n\n### Rejected Approaches\n- Global state",
+	} {
+		if got := ParseAgentOutput(output, "reviewer").RejectedApproaches; got != nil {
+			t.Errorf("RejectedApproaches = %q, want none for %q", got, output)
+		}
+	}
+}
+
+func TestParseAgentOutput_SubPlan(t *testing.T) {
+	output := "## Progress\nThe auth module needs rewriting first\n\n" +
+		"## Sub-Plan\n```markdown\n# Rewrite the auth module\n\n## Steps\n- Split tokens from sessions\n```\n\n---\n\n" +
+		"## Status\nRUNNING RUNNING RUNNING"
+	dev := ParseAgentOutput(output, "developer")
+	if want := "# Rewrite the auth module\n\n## Steps\n- Split tokens from sessions"; dev.SubPlan != want {
+		t.Errorf("SubPlan = %q, want %q", dev.SubPlan, want)
+	}
+
+	plain := ParseAgentOutput("## Progress\nBlocked\n\n## Sub-Plan\nMigrate the config loader to JSON\n\n## Status\nRUNNING RUNNING RUNNING", "developer")
+	if plain.SubPlan != "Migrate the config loader to JSON" {
+		t.Errorf("SubPlan without a fence = %q", plain.SubPlan)
+	}
+
+	if none := ParseAgentOutput("## Progress\nWorking", "developer"); none.SubPlan != "" {
+		t.Errorf("SubPlan without the section = %q, want none", none.SubPlan)
+	}
+}
+
+func TestParseAgentOutput_Japanese(t *testing.T) {
+	dev := ParseAgentOutput("## 進捗\nAPIを実装しました\n\n## 学び\nテストは go test で実行する\n\n## 全体の学び\n- ビルドは make\n\n---\n\n## ステータス\nDEV_DONE DEV_DONE DEV_DONE!!!", "developer")
+	if dev.Malformed || dev.Progress != "APIを実装しました" || dev.Learnings != "テストは go test で実行する" {
+		t.Errorf("unexpected sections: %+v", dev)
+	}
+	if !dev.DevDone {
+		t.Error("expected DevDone")
+	}
+	if len(dev.GlobalLearnings) != 1 || dev.GlobalLearnings[0] != "ビルドは make" {
+		t.Errorf("unexpected global learnings: %q", dev.GlobalLearnings)
+	}
+
+	rev := ParseAgentOutput("## 進捗\nレビューしました\n\n## 学び\nなし\n\n### 重大な問題\nなし\n\n### 主要な問題\n- api.go:10 エラーを無視している\n\n### 軽微な問題\nなし\n\n### 判定\n修正が必要です", "reviewer")
+	if rev.ReviewerApproved {
+		t.Error("expected no approval")
+	}
+	if rev.ReviewerFeedback != "Major Issues:\n- api.go:10 エラーを無視している" {
+		t.Errorf("unexpected feedback: %q", rev.ReviewerFeedback)
+	}
+}
+
+func TestIsStatusTool(t *testing.T) {
+	for name, want := range map[string]bool{
+		"ralph_status":              true,
+		"mcp__ralph__ralph_status":  true,
+		"mcp__other__ralph_status":  true,
+		"mcp__ralph__ralph_status2": false,
+		"Bash":                      false,
+	} {
+		if got := IsStatusTool(name); got != want {
+			t.Errorf("IsStatusTool(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestParseStatusReport(t *testing.T) {
+	if _, err := ParseStatusReport([]byte(`{"progress": "  "}`)); err == nil {
+		t.Error("expected a report without progress to be rejected")
+	}
+	if _, err := ParseStatusReport([]byte(`{"progress": 3}`)); err == nil {
+		t.Error("expected invalid JSON to be rejected")
+	}
+
+	report, err := ParseStatusReport([]byte(`{"progress": "Built the parser", "learnings": "Uses RD",
+		"global_learnings": ["Run make test", " "], "done": true, "rebuttal": "The test exists"}`))
+	if err != nil {
+		t.Fatalf("ParseStatusReport() error: %v", err)
+	}
+	dev := report.Result("developer", "text")
+	if !dev.DevDone || dev.Progress != "Built the parser" || dev.Learnings != "Uses RD" || dev.Raw != "text" ||
+		dev.Rebuttal != "The test exists" || len(dev.GlobalLearnings) != 1 || dev.Malformed {
+		t.Errorf("developer result = %+v", dev)
+	}
+
+	review := (&StatusReport{
+		Progress: "Reviewed",
+		Feedback: "Handle nil\n\n### Suggested Patch\n```diff\n--- a/x.go\n+++ b/x.go\n```",
+	}).Result("reviewer", "")
+	if review.ReviewerApproved || !strings.HasPrefix(review.ReviewerFeedback, "Handle nil") || review.ReviewerPatch != "--- a/x.go\n+++ b/x.go\n" {
+		t.Errorf("reviewer result = %+v", review)
+	}
+	if approved := (&StatusReport{Progress: "Reviewed", Approved: true, Feedback: "ignored"}).Result("reviewer", ""); !approved.ReviewerApproved || approved.ReviewerFeedback != "" {
+		t.Errorf("approving reviewer result = %+v", approved)
+	}
+
+	checked := (&StatusReport{Progress: "Reviewed", Approved: true, Checklist: []ChecklistResult{{Check: "no new deps", Note: "adds left-pad"}}}).Result("reviewer", "")
+	if len(checked.Checklist) != 1 || checked.Checklist[0].Note != "adds left-pad" {
+		t.Errorf("reviewer checklist = %+v", checked.Checklist)
+	}
+
+	fixed := (&StatusReport{Progress: "Fixed", FixedItems: []int{2, 0, 2, 5}}).Result("developer", "")
+	if !slices.Equal(fixed.FixedItems, []int{2, 5}) {
+		t.Errorf("developer fixed items = %v, want [2 5]", fixed.FixedItems)
+	}
+	subPlan := (&StatusReport{Progress: "Blocked", SubPlan: "  # Rewrite auth\n"}).Result("developer", "")
+	if subPlan.SubPlan != "# Rewrite auth" {
+		t.Errorf("developer sub-plan = %q", subPlan.SubPlan)
+	}
+	verified := (&StatusReport{Progress: "Reviewed", Feedback: "Add the test", VerifiedItems: []int{2}, RejectedApproaches: []string{" Global state ", ""}}).Result("reviewer", "")
+	if !slices.Equal(verified.VerifiedItems, []int{2}) {
+		t.Errorf("reviewer verified items = %v, want [2]", verified.VerifiedItems)
+	}
+	if !slices.Equal(verified.RejectedApproaches, []string{"Global state"}) {
+		t.Errorf("reviewer rejected approaches = %q, want [Global state]", verified.RejectedApproaches)
+	}
+
+	rebuttal := (&StatusReport{Progress: "Checked", Approved: true, Feedback: "You're right"}).Result("rebuttal_reviewer", "")
+	if !rebuttal.RebuttalAccepted || rebuttal.RebuttalResponse != "You're right" {
+		t.Errorf("rebuttal reviewer result = %+v", rebuttal)
+	}
+}
+
+func TestParseAgentOutput_ReviewerChecklist(t *testing.T) {
+	output := "## Progress\nReviewed\n\n### Plan Checklist\n- [x] no new deps\n* [ ] strings extracted: errors.go:12 is hardcoded\nnot an item\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!"
+	result := ParseAgentOutput(output, "reviewer")
+	want := []ChecklistResult{
+		{Check: "no new deps", Passed: true},
+		{Check: "strings extracted: errors.go:12 is hardcoded"},
+	}
+	if !slices.Equal(result.Checklist, want) {
+		t.Errorf("Checklist = %+v, want %+v", result.Checklist, want)
+	}
+}
+
+func TestParseAgentOutput_ReviewerCriteria(t *testing.T) {
+	output := "## Progress\nReviewed\n\n### Plan Checklist\n- [x] no new deps\n\n### Acceptance Criteria\n- [x] header row written\n- [ ] commas quoted: a,b is split\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!"
+	result := ParseAgentOutput(output, "reviewer")
+	want := []ChecklistResult{
+		{Check: "header row written", Passed: true},
+		{Check: "commas quoted: a,b is split"},
+	}
+	if !slices.Equal(result.Criteria, want) {
+		t.Errorf("Criteria = %+v, want %+v", result.Criteria, want)
+	}
+	if len(result.Checklist) != 1 {
+		t.Errorf("Checklist = %+v, want only the plan check", result.Checklist)
+	}
+
+	failed := FailedCriteria(MatchChecklist([]string{"header row written", "commas quoted"}, result.Criteria))
+	if failed != "Unsatisfied acceptance criteria:\n- commas quoted: a,b is split" {
+		t.Errorf("FailedCriteria() = %q", failed)
+	}
+	if items := MergeFeedback([]string{failed}); len(items) != 1 || items[0].Severity != "Major" {
+		t.Errorf("expected unsatisfied criteria to merge as major issues, got %+v", items)
+	}
+}
+
+func TestMatchChecklist(t *testing.T) {
+	checks := []string{"no new deps", "strings extracted"}
+	tests := []struct {
+		name     string
+		reported []ChecklistResult
+		want     []ChecklistResult
+	}{
+		{"by prefix", []ChecklistResult{
+			{Check: "Strings extracted - errors.go is hardcoded"},
+			{Check: "`no new deps`", Passed: true},
+		}, []ChecklistResult{
+			{Check: "no new deps", Passed: true},
+			{Check: "strings extracted", Note: "errors.go is hardcoded"},
+		}},
+		{"by position", []ChecklistResult{
+			{Check: "No dependencies added", Passed: true},
+			{Check: "i18n", Note: "missing"},
+		}, []ChecklistResult{
+			{Check: "no new deps", Passed: true},
+			{Check: "strings extracted", Note: "missing"},
+		}},
+		{"unreported", []ChecklistResult{{Check: "no new deps", Passed: true}}, []ChecklistResult{
+			{Check: "no new deps", Passed: true},
+			{Check: "strings extracted", Note: "not reported by the reviewer"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchChecklist(checks, tt.reported); !slices.Equal(got, tt.want) {
+				t.Errorf("MatchChecklist() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	failed := FailedChecks(MatchChecklist(checks, nil))
+	if failed != "Failed plan checks:\n- no new deps: not reported by the reviewer\n- strings extracted: not reported by the reviewer" {
+		t.Errorf("FailedChecks() = %q", failed)
+	}
+	if items := MergeFeedback([]string{failed}); len(items) != 2 || items[0].Severity != "Major" {
+		t.Errorf("expected failed checks to merge as major issues, got %+v", items)
+	}
+	if FailedChecks([]ChecklistResult{{Check: "no new deps", Passed: true}}) != "" {
+		t.Error("expected no failed checks")
+	}
+}
@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// StatusToolName is the tool agents report their status with when status
+// reporting by tool call is enabled, in place of the markdown sections and
+// markers.
+const StatusToolName = "ralph_status"
+
+// StatusReport is the input of a status tool call.
+type StatusReport struct {
+	Progress        string   `json:"progress"`
+	Learnings       string   `json:"learnings"`
+	GlobalLearnings []string `json:"global_learnings,omitempty"`
+
+	// Done is the developer's DEV_DONE
+	Done bool `json:"done,omitempty"`
+	// Rebuttal is the developer's dispute of the last review feedback
+	Rebuttal string `json:"rebuttal,omitempty"`
+	// DisputedItems is the numbers of the review items the rebuttal
+	// disputes
+	DisputedItems []int `json:"disputed_items,omitempty"`
+	// FixedItems is the numbers of the open review items the developer
+	// fixed
+	FixedItems []int `json:"fixed_items,omitempty"`
+	// SubPlan is a plan the developer proposes for a large tangent
+	SubPlan string `json:"sub_plan,omitempty"`
+
+	// Approved is the reviewer's REVIEWER_APPROVED, or a rebuttal
+	// reviewer withdrawing the disputed feedback
+	Approved bool `json:"approved,omitempty"`
+	// Feedback is the reviewer's issues when it doesn't approve, or a
+	// rebuttal reviewer's response
+	Feedback string `json:"feedback,omitempty"`
+	// Checklist is the reviewer's verdict on each check of the plan's
+	// review checklist
+	Checklist []ChecklistResult `json:"checklist,omitempty"`
+	// Criteria is the reviewer's verdict on each of the plan's acceptance
+	// criteria
+	Criteria []ChecklistResult `json:"criteria,omitempty"`
+	// VerifiedItems is the numbers of the claimed-fixed review items the
+	// reviewer verified
+	VerifiedItems []int `json:"verified_items,omitempty"`
+	// RejectedApproaches is the approaches the reviewer rejected outright
+	RejectedApproaches []string `json:"rejected_approaches,omitempty"`
+}
+
+// IsStatusTool reports whether a tool call is to the status tool, whichever
+// MCP server the CLI reached it through (it names the tool
+// mcp__<server>__ralph_status).
+func IsStatusTool(name string) bool {
+	return name == StatusToolName || strings.HasSuffix(name, "__"+StatusToolName)
+}
+
+// ParseStatusReport decodes and checks a status tool call's JSON input.
+func ParseStatusReport(input []byte) (*StatusReport, error) {
+	var report StatusReport
+	if err := json.Unmarshal(input, &report); err != nil {
+		return nil, fmt.Errorf("invalid status report: %w", err)
+	}
+	if strings.TrimSpace(report.Progress) == "" {
+		return nil, errors.New("invalid status report: progress is required")
+	}
+	return &report, nil
+}
+
+// Result returns the report as ParseAgentOutput would have parsed the
+// equivalent markdown output of the given agent type, with raw as the
+// session's text output.
+func (r *StatusReport) Result(agentType, raw string) *AgentParseResult {
+	result := &AgentParseResult{
+		Raw:       raw,
+		Progress:  strings.TrimSpace(r.Progress),
+		Learnings: strings.TrimSpace(r.Learnings),
+	}
+	for _, learning := range r.GlobalLearnings {
+		if learning = strings.TrimSpace(learning); learning != "" {
+			result.GlobalLearnings = append(result.GlobalLearnings, learning)
+		}
+	}
+
+	feedback := strings.TrimSpace(r.Feedback)
+	switch agentType {
+	case "developer":
+		result.DevDone = r.Done
+		result.Rebuttal = strings.TrimSpace(r.Rebuttal)
+		if result.Rebuttal != "" {
+			result.DisputedItems = validItemNumbers(r.DisputedItems)
+		}
+		result.FixedItems = validItemNumbers(r.FixedItems)
+		result.SubPlan = strings.TrimSpace(r.SubPlan)
+	case "reviewer":
+		result.ReviewerApproved = r.Approved
+		result.Checklist = r.Checklist
+		result.Criteria = r.Criteria
+		result.VerifiedItems = validItemNumbers(r.VerifiedItems)
+		if !r.Approved {
+			result.ReviewerFeedback = feedback
+			result.ReviewerPatch = extractSuggestedPatch(feedback)
+			for _, approach := range r.RejectedApproaches {
+				if approach = strings.TrimSpace(approach); approach != "" {
+					result.RejectedApproaches = append(result.RejectedApproaches, approach)
+				}
+			}
+		}
+	case "rebuttal_reviewer":
+		result.RebuttalAccepted = r.Approved
+		result.RebuttalResponse = feedback
+	}
+	return result
+}
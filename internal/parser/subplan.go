@@ -0,0 +1,33 @@
+package parser
+
+import "strings"
+
+// SubPlanHeader is the section developers propose a sub-plan in: a large
+// tangent to be planned and run on its own before the plan resumes.
+const SubPlanHeader = "## Sub-Plan"
+
+// parseSubPlan returns the sub-plan proposed in the output, or "" if none.
+// The plan may be wrapped in a fenced block, which keeps its own headings
+// from ending the section.
+func parseSubPlan(output string) string {
+	section, found := extractSection(output, SubPlanHeader)
+	if !found {
+		return ""
+	}
+	// The section may run into the rule before the status
+	return unwrapFence(strings.TrimSpace(strings.TrimSuffix(section, "---")))
+}
+
+// unwrapFence returns the content of text when all of it is one fenced
+// block, and text unchanged otherwise.
+func unwrapFence(text string) string {
+	if !strings.HasPrefix(text, "```") || !strings.HasSuffix(text, "```") {
+		return text
+	}
+	start := strings.Index(text, "\n")
+	end := strings.LastIndex(text, "\n")
+	if start == -1 || end <= start {
+		return text
+	}
+	return strings.TrimSpace(text[start+1 : end])
+}
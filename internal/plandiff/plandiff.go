@@ -0,0 +1,90 @@
+// Package plandiff diffs two versions of a plan line by line, for showing
+// plan edits to users and agents.
+package plandiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxLines caps the size of plans diffed line by line, since the diff takes
+// time and memory proportional to the product of their lengths; larger
+// plans are summarized instead.
+const maxLines = 1000
+
+// contextLines is the number of unchanged lines shown around each change.
+const contextLines = 2
+
+// Diff returns the lines removed ("-") and added ("+") between two
+// versions of a plan, with a little unchanged context (" ") and "@@"
+// between hunks. It returns "" when the texts have the same lines.
+func Diff(before, after string) string {
+	a := strings.Split(strings.TrimRight(before, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(after, "\n"), "\n")
+	if len(a) > maxLines || len(b) > maxLines {
+		if before == after {
+			return ""
+		}
+		return fmt.Sprintf("@@ plan too long to diff: %d lines before, %d lines after @@", len(a), len(b))
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	changed := false
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, " "+a[i])
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "-"+a[i])
+			i++
+			changed = true
+		default:
+			lines = append(lines, "+"+b[j])
+			j++
+			changed = true
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	// Keep changed lines and the context around them
+	keep := make([]bool, len(lines))
+	for k, line := range lines {
+		if line[0] == ' ' {
+			continue
+		}
+		for c := max(0, k-contextLines); c <= min(len(lines)-1, k+contextLines); c++ {
+			keep[c] = true
+		}
+	}
+	var out []string
+	for k, line := range lines {
+		if !keep[k] {
+			continue
+		}
+		if len(out) > 0 && !keep[k-1] {
+			out = append(out, "@@")
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
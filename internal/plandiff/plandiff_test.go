@@ -0,0 +1,38 @@
+package plandiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		want          string
+	}{
+		{"unchanged", "a\nb\n", "a\nb", ""},
+		{"added", "a\nb\nc", "a\nb\nnew\nc", " a\n b\n+new\n c"},
+		{"removed", "a\nb\nc", "a\nc", " a\n-b\n c"},
+		{"replaced", "a\nold\nc", "a\nnew\nc", " a\n-old\n+new\n c"},
+		{"appended", "# Plan\n\na\nb\nc\nd\ne\nf\n", "# Plan\n\na\nb\nC\nd\ne\nf\ng\n", " a\n b\n-c\n+C\n d\n e\n f\n+g"},
+		{"hunks", "1\n2\n3\n4\n5\n6\n7\n8\n9", "x\n2\n3\n4\n5\n6\n7\n8\ny", "-1\n+x\n 2\n 3\n@@\n 7\n 8\n-9\n+y"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.before, tt.after); got != tt.want {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff_TooLong(t *testing.T) {
+	long := strings.Repeat("line\n", maxLines+1)
+	if got := Diff(long, long); got != "" {
+		t.Errorf("Diff() of equal long plans = %q, want none", got)
+	}
+	if got := Diff(long, long+"more\n"); !strings.Contains(got, "plan too long to diff") {
+		t.Errorf("Diff() = %q, want a too long summary", got)
+	}
+}
@@ -0,0 +1,138 @@
+// Package planenv resolves the environment variables a plan declares for
+// the jj and Claude processes that work on it. Plans declare them in an
+// "env:" block of their front matter; values may reference host variables
+// ($NAME or ${NAME}), so secrets stay in the host environment instead of
+// the stored plan.
+package planenv
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/frontmatter"
+)
+
+// namePattern matches valid environment variable names.
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Var is an environment variable declared for a plan. Value is as declared,
+// before host variable references are expanded.
+type Var struct {
+	Name  string
+	Value string
+}
+
+// Parse returns the variables declared in the "env:" block of a plan's
+// front matter: a block at the very start of the plan between "---" lines,
+// holding one indented "NAME: value" line per variable. Other front matter
+// keys are ignored. A plan without front matter declares no variables.
+func Parse(plan string) ([]Var, error) {
+	entries, err := frontmatter.Parse(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	var vars []Var
+	for _, e := range entries {
+		if e.Key != "env" {
+			continue
+		}
+		if e.Value != "" && e.Value != "{}" {
+			return nil, fmt.Errorf("front matter line %d: env must be a block of NAME: value lines", e.Line)
+		}
+		for _, line := range e.Block {
+			name, value, ok := strings.Cut(line.Text, ":")
+			if !ok {
+				return nil, fmt.Errorf("front matter line %d: expected NAME: value, got %q", line.Num, line.Text)
+			}
+			v, err := newVar(strings.TrimSpace(name), frontmatter.Unquote(strings.TrimSpace(value)))
+			if err != nil {
+				return nil, fmt.Errorf("front matter line %d: %w", line.Num, err)
+			}
+			vars = append(vars, v)
+		}
+	}
+	return vars, nil
+}
+
+// ParseAssignment parses a NAME=value assignment, as passed to --env.
+func ParseAssignment(s string) (Var, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return Var{}, fmt.Errorf("expected NAME=value, got %q", s)
+	}
+	return newVar(name, value)
+}
+
+// newVar validates a variable's name.
+func newVar(name, value string) (Var, error) {
+	if !namePattern.MatchString(name) {
+		return Var{}, fmt.Errorf("invalid environment variable name %q", name)
+	}
+	return Var{Name: name, Value: value}, nil
+}
+
+// Resolve returns the environment for a plan as NAME=value pairs: the
+// variables of its front matter, overridden by the --env assignments, with
+// host variable references expanded by lookup (e.g. os.LookupEnv). A
+// reference to an unset host variable is an error, so a missing secret
+// fails the run up front.
+func Resolve(plan string, assignments []string, lookup func(string) (string, bool)) ([]string, error) {
+	vars, err := Parse(plan)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plan front matter: %w", err)
+	}
+	for _, s := range assignments {
+		v, err := ParseAssignment(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --env: %w", err)
+		}
+		vars = append(vars, v)
+	}
+
+	var env []string
+	var names []string
+	for _, v := range vars {
+		value, err := expand(v, lookup)
+		if err != nil {
+			return nil, err
+		}
+		// Later declarations override earlier ones
+		if i := slices.Index(names, v.Name); i >= 0 {
+			env[i] = v.Name + "=" + value
+			continue
+		}
+		names = append(names, v.Name)
+		env = append(env, v.Name+"="+value)
+	}
+	return env, nil
+}
+
+// expand replaces the host variable references in a variable's value.
+func expand(v Var, lookup func(string) (string, bool)) (string, error) {
+	var missing []string
+	value := os.Expand(v.Value, func(ref string) string {
+		value, ok := lookup(ref)
+		if !ok {
+			missing = append(missing, ref)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%s references unset host variable %s", v.Name, strings.Join(missing, ", "))
+	}
+	return value, nil
+}
+
+// Names returns the variable names of NAME=value pairs, for logging the
+// environment without its values.
+func Names(env []string) []string {
+	names := make([]string, len(env))
+	for i, kv := range env {
+		names[i], _, _ = strings.Cut(kv, "=")
+	}
+	return names
+}
@@ -0,0 +1,87 @@
+package planenv
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// lookupIn returns a lookup over the given host variables.
+func lookupIn(host map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := host[name]
+		return value, ok
+	}
+}
+
+func TestParse(t *testing.T) {
+	plan := "---\ntitle: Billing API\nenv:\n  GOPATH: /opt/go\n  # the staging API\n  API_URL: \"https://staging.example.com\"\n\n  API_TOKEN: ${BILLING_TOKEN}\nowner: payments\n---\n# Plan\n\nenv:\n  NOT_FRONT_MATTER: x\n"
+
+	vars, err := Parse(plan)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	want := []Var{
+		{Name: "GOPATH", Value: "/opt/go"},
+		{Name: "API_URL", Value: "https://staging.example.com"},
+		{Name: "API_TOKEN", Value: "${BILLING_TOKEN}"},
+	}
+	if !slices.Equal(vars, want) {
+		t.Errorf("Parse() = %v, want %v", vars, want)
+	}
+}
+
+func TestParse_NoFrontMatter(t *testing.T) {
+	for _, plan := range []string{"", "# Plan\n---\nenv:\n  A: b\n---\n", "---\ntitle: x\n---\n"} {
+		vars, err := Parse(plan)
+		if err != nil || len(vars) != 0 {
+			t.Errorf("Parse(%q) = %v, %v; want no variables", plan, vars, err)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"unclosed":     "---\nenv:\n  A: b\n# Plan\n",
+		"inline env":   "---\nenv: A=b\n---\n",
+		"missing pair": "---\nenv:\n  A\n---\n",
+		"bad name":     "---\nenv:\n  API-URL: x\n---\n",
+	}
+	for name, plan := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse(plan); err == nil {
+				t.Errorf("expected an error for %q", plan)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	plan := "---\nenv:\n  API_TOKEN: ${BILLING_TOKEN}\n  API_URL: https://staging.example.com\n  CACHE: $HOME/.cache/billing\n---\n"
+	host := lookupIn(map[string]string{"BILLING_TOKEN": "s3cret", "HOME": "/home/dev"})
+
+	env, err := Resolve(plan, []string{"API_URL=http://localhost:8080", "DEBUG=1"}, host)
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+	want := []string{"API_TOKEN=s3cret", "API_URL=http://localhost:8080", "CACHE=/home/dev/.cache/billing", "DEBUG=1"}
+	if !slices.Equal(env, want) {
+		t.Errorf("Resolve() = %v, want %v", env, want)
+	}
+	if got := strings.Join(Names(env), ","); got != "API_TOKEN,API_URL,CACHE,DEBUG" {
+		t.Errorf("Names() = %s", got)
+	}
+}
+
+func TestResolve_Errors(t *testing.T) {
+	host := lookupIn(nil)
+	if _, err := Resolve("---\nenv:\n  TOKEN: ${MISSING}\n---\n", nil, host); err == nil || !strings.Contains(err.Error(), "MISSING") {
+		t.Errorf("expected an unset host variable error, got: %v", err)
+	}
+	if _, err := Resolve("", []string{"NOVALUE"}, host); err == nil || !strings.Contains(err.Error(), "--env") {
+		t.Errorf("expected an invalid --env error, got: %v", err)
+	}
+	if _, err := Resolve("---\nenv:\n", nil, host); err == nil || !strings.Contains(err.Error(), "front matter") {
+		t.Errorf("expected a front matter error, got: %v", err)
+	}
+}
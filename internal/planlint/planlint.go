@@ -0,0 +1,204 @@
+// Package planlint checks plans for problems that make agents flounder:
+// empty sections, no way to tell when the work is done, references to files
+// that don't exist, and plans too large to keep in view. Findings are
+// heuristics, reported as warnings; callers decide whether they stop a run.
+package planlint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/frontmatter"
+)
+
+// DefaultMaxBytes is the default size above which a plan is reported as too
+// large.
+const DefaultMaxBytes = 32 * 1024
+
+// Rules findings are reported under.
+const (
+	RuleEmptySection       = "empty-section"
+	RuleAcceptanceCriteria = "acceptance-criteria"
+	RuleMissingFile        = "missing-file"
+	RuleTooLarge           = "too-large"
+)
+
+// Finding is a problem found in a plan.
+type Finding struct {
+	Line    int // 1-based line of the plan (0 = the whole plan)
+	Rule    string
+	Message string
+}
+
+// String formats the finding as "line N: message (rule)".
+func (f Finding) String() string {
+	if f.Line == 0 {
+		return fmt.Sprintf("%s (%s)", f.Message, f.Rule)
+	}
+	return fmt.Sprintf("line %d: %s (%s)", f.Line, f.Message, f.Rule)
+}
+
+// Options configures a lint.
+type Options struct {
+	// RepoDir is the directory file references are resolved against (empty
+	// = don't check file references).
+	RepoDir string
+	// MaxBytes is the size above which the plan is too large (0 = no limit).
+	MaxBytes int
+}
+
+var (
+	// headerPattern matches an ATX markdown header, capturing its #s and text.
+	headerPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+
+	// criteriaPattern matches the names of sections, or lead-ins of lines,
+	// that say how to tell the plan is done.
+	criteriaPattern = regexp.MustCompile(`(?i)\b(acceptance criteria|definition of done|done when|success criteria|exit criteria|completion criteria)\b`)
+
+	// checklistPattern matches a markdown task list item, which also states
+	// what done looks like.
+	checklistPattern = regexp.MustCompile(`^\s*[-*] \[[ xX]\] `)
+
+	// codeSpanPattern and linkPattern capture file references: inline code
+	// and the targets of markdown links.
+	codeSpanPattern = regexp.MustCompile("`([^`\\s]+)`")
+	linkPattern     = regexp.MustCompile(`\]\(([^)\s]+)\)`)
+
+	// lineSuffixPattern matches a ":line" or ":line:column" suffix.
+	lineSuffixPattern = regexp.MustCompile(`(:\d+){1,2}$`)
+
+	// creationPattern matches lines about files the plan will create, whose
+	// references aren't expected to exist yet.
+	creationPattern = regexp.MustCompile(`(?i)\b(create|creates|creating|add|adds|adding|new|generate|write)\b`)
+)
+
+// Lint checks a plan, skipping its front matter. Findings are returned in
+// line order, plan-wide findings last.
+func Lint(plan string, opts Options) []Finding {
+	lines := strings.Split(strings.ReplaceAll(plan, "\r\n", "\n"), "\n")
+	start := frontmatter.Len(plan)
+
+	var findings []Finding
+	hasCriteria := false
+	inFence := false
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		if isFence(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		if criteriaPattern.MatchString(line) || checklistPattern.MatchString(line) {
+			hasCriteria = true
+		}
+		if m := headerPattern.FindStringSubmatch(line); m != nil {
+			if sectionEmpty(lines, i+1, len(m[1])) {
+				findings = append(findings, Finding{
+					Line:    i + 1,
+					Rule:    RuleEmptySection,
+					Message: fmt.Sprintf("section %q is empty", strings.TrimSpace(line)),
+				})
+			}
+			continue
+		}
+		if opts.RepoDir != "" && !creationPattern.MatchString(line) {
+			for _, ref := range fileRefs(line) {
+				if missing(opts.RepoDir, ref) {
+					findings = append(findings, Finding{
+						Line:    i + 1,
+						Rule:    RuleMissingFile,
+						Message: fmt.Sprintf("%s doesn't exist in the repository", ref),
+					})
+				}
+			}
+		}
+	}
+
+	if !hasCriteria {
+		findings = append(findings, Finding{
+			Rule:    RuleAcceptanceCriteria,
+			Message: `no acceptance criteria: add a section such as "## Acceptance Criteria" or a checklist saying how to tell the work is done`,
+		})
+	}
+	if opts.MaxBytes > 0 && len(plan) > opts.MaxBytes {
+		findings = append(findings, Finding{
+			Rule:    RuleTooLarge,
+			Message: fmt.Sprintf("plan is %d bytes, over the %d byte limit: consider splitting it or running it with --decompose", len(plan), opts.MaxBytes),
+		})
+	}
+	return findings
+}
+
+// isFence reports whether a line opens or closes a fenced code block.
+func isFence(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+}
+
+// sectionEmpty reports whether the section of a header of the given level,
+// whose body starts at lines[from], has no content: only blank lines before
+// the next header that isn't one of its subsections, or the end.
+func sectionEmpty(lines []string, from, level int) bool {
+	for _, line := range lines[from:] {
+		if m := headerPattern.FindStringSubmatch(line); m != nil {
+			return len(m[1]) <= level
+		}
+		if strings.TrimSpace(line) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// fileRefs returns the repo-relative file paths a line refers to, in inline
+// code or as link targets. Only references that look like paths are
+// returned: URLs, globs, and absolute paths are skipped.
+func fileRefs(line string) []string {
+	var refs []string
+	for _, pattern := range []*regexp.Regexp{codeSpanPattern, linkPattern} {
+		for _, m := range pattern.FindAllStringSubmatch(line, -1) {
+			if ref, ok := filePath(m[1]); ok {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// missing reports whether a referenced path doesn't exist in the repository.
+// Only paths under a top-level entry that exists are checked, so references
+// such as application/json, which aren't paths at all, aren't reported.
+func missing(repoDir, ref string) bool {
+	top, _, _ := strings.Cut(ref, "/")
+	if _, err := os.Stat(filepath.Join(repoDir, top)); err != nil {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(repoDir, filepath.FromSlash(ref)))
+	return os.IsNotExist(err)
+}
+
+// filePath returns s as a repo-relative path, if it looks like one: a path
+// with a directory, such as internal/db/db.go. Bare file names are left
+// alone, since plans often name files without saying where they are.
+func filePath(s string) (string, bool) {
+	if strings.Contains(s, "://") || strings.ContainsAny(s, "*?[]{}()<>$=,;'\"") ||
+		strings.HasPrefix(s, "#") || strings.HasPrefix(s, "-") || strings.HasPrefix(s, "/") || strings.HasPrefix(s, "~") {
+		return "", false
+	}
+	s, _, _ = strings.Cut(s, "#")
+	s = lineSuffixPattern.ReplaceAllString(s, "")
+	s = strings.TrimPrefix(s, "./")
+	if s == "" || strings.HasPrefix(s, "../") {
+		return "", false
+	}
+	if !strings.Contains(strings.TrimSuffix(s, "/"), "/") {
+		return "", false
+	}
+	return s, true
+}
@@ -0,0 +1,123 @@
+package planlint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// rules returns the rules of findings, after their lines if they have one.
+func rules(findings []Finding) []string {
+	var got []string
+	for _, f := range findings {
+		if f.Line == 0 {
+			got = append(got, f.Rule)
+		} else {
+			got = append(got, fmt.Sprintf("%d %s", f.Line, f.Rule))
+		}
+	}
+	return got
+}
+
+func TestLint_Clean(t *testing.T) {
+	plan := "# Login\n\nAdd a login page.\n\n## Acceptance Criteria\n\n- Users can log in\n"
+	if findings := Lint(plan, Options{MaxBytes: DefaultMaxBytes}); len(findings) != 0 {
+		t.Errorf("Lint() = %v, want no findings", findings)
+	}
+}
+
+func TestLint_EmptySections(t *testing.T) {
+	plan := strings.Join([]string{
+		"---",
+		"env:",
+		"  A: b",
+		"---",
+		"# Plan",          // 5: has subsections, not empty
+		"## Background",   // 6: empty
+		"",                // 7
+		"## Tasks",        // 8
+		"- [ ] Do it",     // 9
+		"## Notes",        // 10: the fenced block is its content
+		"```",             // 11
+		"## Not a header", // 12
+		"```",             // 13
+	}, "\n")
+	plan += "\n## Open Questions\n" // 14: empty at the end
+
+	got := strings.Join(rules(Lint(plan, Options{})), ", ")
+	if got != "6 empty-section, 14 empty-section" {
+		t.Errorf("findings = %q, want empty sections at lines 6 and 14", got)
+	}
+}
+
+func TestLint_AcceptanceCriteria(t *testing.T) {
+	for _, plan := range []string{
+		"Fix the bug.\n\nDone when the tests pass.",
+		"Fix the bug.\n\n## Definition of Done\n\nTests pass.",
+		"Fix the bug.\n\n* [x] Reproduce\n* [ ] Fix",
+	} {
+		if findings := Lint(plan, Options{}); len(findings) != 0 {
+			t.Errorf("Lint(%q) = %v, want no findings", plan, findings)
+		}
+	}
+
+	findings := Lint("Fix the bug.", Options{})
+	if len(findings) != 1 || findings[0].Rule != RuleAcceptanceCriteria || findings[0].Line != 0 {
+		t.Errorf("Lint() = %v, want a plan-wide acceptance-criteria finding", findings)
+	}
+}
+
+func TestLint_MissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "internal", "db"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "internal", "db", "db.go"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan := strings.Join([]string{
+		"## Tasks",
+		"- [ ] Fix `internal/db/db.go:42` and `./internal/db/`",             // 2: exist
+		"- [ ] Update `internal/db/schema.go`",                              // 3: missing
+		"- [ ] Create `internal/db/new.go`",                                 // 4: to be created
+		"- [ ] See [the docs](internal/docs/api.md#auth)",                   // 5: missing
+		"- [ ] Send `application/json` to https://example.com/a/b",          // 6: not paths
+		"- [ ] Match `internal/*.go`, call `fmt.Println`, and `/etc/hosts`", // 7: skipped
+	}, "\n")
+
+	got := strings.Join(rules(Lint(plan, Options{RepoDir: dir})), ", ")
+	if got != "3 missing-file, 5 missing-file" {
+		t.Errorf("findings = %q, want missing files at lines 3 and 5", got)
+	}
+
+	// Without a repository, references aren't checked
+	if findings := Lint(plan, Options{}); len(findings) != 0 {
+		t.Errorf("Lint() without RepoDir = %v, want no findings", findings)
+	}
+}
+
+func TestLint_TooLarge(t *testing.T) {
+	plan := "Done when it works.\n" + strings.Repeat("x", 100)
+	findings := Lint(plan, Options{MaxBytes: 50})
+	if len(findings) != 1 || findings[0].Rule != RuleTooLarge {
+		t.Fatalf("Lint() = %v, want a too-large finding", findings)
+	}
+	if !strings.Contains(findings[0].Message, "over the 50 byte limit") {
+		t.Errorf("message = %q, want the limit", findings[0].Message)
+	}
+	if findings := Lint(plan, Options{}); len(findings) != 0 {
+		t.Errorf("Lint() without a limit = %v, want no findings", findings)
+	}
+}
+
+func TestFinding_String(t *testing.T) {
+	if got := (Finding{Line: 3, Rule: RuleEmptySection, Message: `section "## A" is empty`}).String(); got != `line 3: section "## A" is empty (empty-section)` {
+		t.Errorf("String() = %q", got)
+	}
+	if got := (Finding{Rule: RuleTooLarge, Message: "plan is big"}).String(); got != "plan is big (too-large)" {
+		t.Errorf("String() = %q", got)
+	}
+}
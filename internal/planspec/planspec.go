@@ -0,0 +1,154 @@
+// Package planspec reads structured plans: plan files written as YAML or
+// JSON with a title, goals, acceptance criteria, constraints, and what is
+// out of scope, instead of freeform markdown. A structured plan is rendered
+// to the markdown plan the loop stores and works from, with its acceptance
+// criteria in the front matter so the reviewer verifies each one before a
+// done signal is approved.
+package planspec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Spec is a structured plan.
+type Spec struct {
+	Title              string   `json:"title"`
+	Description        string   `json:"description,omitempty"`
+	Goals              []string `json:"goals,omitempty"`
+	AcceptanceCriteria []string `json:"acceptance_criteria"`
+	Constraints        []string `json:"constraints,omitempty"`
+	OutOfScope         []string `json:"out_of_scope,omitempty"`
+
+	// Front matter keys of markdown plans, passed through
+	Files           []string `json:"files,omitempty"`
+	ReviewChecklist []string `json:"review_checklist,omitempty"`
+}
+
+// IsStructured reports whether the plan file at path is a structured plan,
+// by its extension: .yaml, .yml, or .json.
+func IsStructured(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// Content returns the markdown plan for a plan file's content: a
+// structured plan rendered, or any other file as it is.
+func Content(path string, data []byte) (string, error) {
+	if !IsStructured(path) {
+		return string(data), nil
+	}
+	spec, err := Parse(path, data)
+	if err != nil {
+		return "", err
+	}
+	return spec.Markdown(), nil
+}
+
+// Parse reads a structured plan, as JSON for a .json path and YAML
+// otherwise. A plan needs a title and at least one acceptance criterion.
+func Parse(path string, data []byte) (*Spec, error) {
+	var spec Spec
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&spec); err != nil {
+			return nil, fmt.Errorf("invalid plan %s: %w", path, err)
+		}
+	} else if err := parseYAML(data, &spec); err != nil {
+		return nil, fmt.Errorf("invalid plan %s: %w", path, err)
+	}
+
+	if err := spec.validate(); err != nil {
+		return nil, fmt.Errorf("invalid plan %s: %w", path, err)
+	}
+	return &spec, nil
+}
+
+// validate checks the required fields and that no list has empty items.
+func (s *Spec) validate() error {
+	if strings.TrimSpace(s.Title) == "" {
+		return errors.New("title is required")
+	}
+	if len(s.AcceptanceCriteria) == 0 {
+		return errors.New("at least one acceptance criterion is required")
+	}
+	for _, list := range []struct {
+		key   string
+		items []string
+	}{
+		{"goals", s.Goals},
+		{"acceptance_criteria", s.AcceptanceCriteria},
+		{"constraints", s.Constraints},
+		{"out_of_scope", s.OutOfScope},
+		{"files", s.Files},
+		{"review_checklist", s.ReviewChecklist},
+	} {
+		for i, item := range list.items {
+			if strings.TrimSpace(item) == "" {
+				return fmt.Errorf("%s item %d is empty", list.key, i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// Markdown renders the plan as a markdown plan. The files, review
+// checklist, and acceptance criteria go in the front matter; the rest is
+// the plan's body, one section per list.
+func (s *Spec) Markdown() string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	for _, list := range []struct {
+		key   string
+		items []string
+	}{
+		{"files", s.Files},
+		{"review_checklist", s.ReviewChecklist},
+		{"acceptance_criteria", s.AcceptanceCriteria},
+	} {
+		if len(list.items) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:\n", list.key)
+		for _, item := range list.items {
+			fmt.Fprintf(&b, "  - %s\n", oneLine(item))
+		}
+	}
+	b.WriteString("---\n")
+
+	fmt.Fprintf(&b, "# %s\n", oneLine(s.Title))
+	if description := strings.TrimSpace(s.Description); description != "" {
+		fmt.Fprintf(&b, "\n%s\n", description)
+	}
+	for _, section := range []struct {
+		heading string
+		items   []string
+	}{
+		{"Goals", s.Goals},
+		{"Acceptance Criteria", s.AcceptanceCriteria},
+		{"Constraints", s.Constraints},
+		{"Out of Scope", s.OutOfScope},
+	} {
+		if len(section.items) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", section.heading)
+		for _, item := range section.items {
+			fmt.Fprintf(&b, "- %s\n", strings.ReplaceAll(strings.TrimSpace(item), "\n", "\n  "))
+		}
+	}
+	return b.String()
+}
+
+// oneLine joins the lines of s, for front matter list items.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,123 @@
+package planspec
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const yamlPlan = `# Plan for the export command
+title: Add CSV export
+description: |
+  Users want their reports as CSV.
+
+  Keep the existing JSON export.
+goals:
+  - Export any report as CSV
+acceptance_criteria:
+  - "ralph export --format csv writes a header row"
+  - Fields with commas are quoted,
+    as RFC 4180 requires
+constraints: [no new dependencies, keep the CLI flags stable]
+files:
+  - "internal/export/**"
+`
+
+func TestParse_YAML(t *testing.T) {
+	spec, err := Parse("plan.yaml", []byte(yamlPlan))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if spec.Title != "Add CSV export" {
+		t.Errorf("Title = %q", spec.Title)
+	}
+	if spec.Description != "Users want their reports as CSV.\n\nKeep the existing JSON export." {
+		t.Errorf("Description = %q", spec.Description)
+	}
+	wantCriteria := []string{"ralph export --format csv writes a header row", "Fields with commas are quoted, as RFC 4180 requires"}
+	if !slices.Equal(spec.AcceptanceCriteria, wantCriteria) {
+		t.Errorf("AcceptanceCriteria = %q, want %q", spec.AcceptanceCriteria, wantCriteria)
+	}
+	if !slices.Equal(spec.Constraints, []string{"no new dependencies", "keep the CLI flags stable"}) {
+		t.Errorf("Constraints = %q", spec.Constraints)
+	}
+	if !slices.Equal(spec.Files, []string{"internal/export/**"}) {
+		t.Errorf("Files = %q", spec.Files)
+	}
+}
+
+func TestParse_JSON(t *testing.T) {
+	data := `{"title": "Add CSV export", "goals": ["Export any report"], "acceptance_criteria": ["Header row written"]}`
+	spec, err := Parse("plan.json", []byte(data))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if spec.Title != "Add CSV export" || !slices.Equal(spec.AcceptanceCriteria, []string{"Header row written"}) {
+		t.Errorf("Parse() = %+v", spec)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name, path, data, wantErr string
+	}{
+		{"no title", "p.yaml", "acceptance_criteria:\n  - works\n", "title is required"},
+		{"no criteria", "p.yaml", "title: Export\n", "at least one acceptance criterion"},
+		{"empty criterion", "p.yaml", "title: Export\nacceptance_criteria:\n  - works\n  -\n", "acceptance_criteria item 2 is empty"},
+		{"unknown key", "p.yml", "title: Export\nowner: me\n", `unknown key "owner"`},
+		{"duplicate key", "p.yaml", "title: A\ntitle: B\n", "title is set twice"},
+		{"scalar list", "p.yaml", "title: Export\ngoals: faster\n", "goals must be a list"},
+		{"list title", "p.yaml", "title: [a, b]\n", "title must be a string"},
+		{"json unknown field", "p.json", `{"title": "Export", "owner": "me"}`, "unknown field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.path, []byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestContent(t *testing.T) {
+	markdown := "# Plan\nDo the thing\n"
+	if got, err := Content("plan.md", []byte(markdown)); err != nil || got != markdown {
+		t.Errorf("Content() of a markdown plan = %q, %v; want it unchanged", got, err)
+	}
+
+	got, err := Content("plan.yaml", []byte(yamlPlan))
+	if err != nil {
+		t.Fatalf("Content() error: %v", err)
+	}
+	want := `---
+files:
+  - internal/export/**
+acceptance_criteria:
+  - ralph export --format csv writes a header row
+  - Fields with commas are quoted, as RFC 4180 requires
+---
+# Add CSV export
+
+Users want their reports as CSV.
+
+Keep the existing JSON export.
+
+## Goals
+
+- Export any report as CSV
+
+## Acceptance Criteria
+
+- ralph export --format csv writes a header row
+- Fields with commas are quoted, as RFC 4180 requires
+
+## Constraints
+
+- no new dependencies
+- keep the CLI flags stable
+`
+	if got != want {
+		t.Errorf("Content() =\n%s\nwant\n%s", got, want)
+	}
+}
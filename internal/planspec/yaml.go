@@ -0,0 +1,160 @@
+package planspec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/frontmatter"
+)
+
+// parseYAML reads a structured plan written in the subset of YAML plans
+// need: top-level "key: value" scalars, "|" and ">" block scalars, and
+// lists, either as "- item" lines under their key or inline as "[a, b]".
+// An item continues on the lines indented past its dash. Comments and
+// blank lines are skipped.
+func parseYAML(data []byte, spec *Spec) error {
+	scalars := map[string]*string{
+		"title":       &spec.Title,
+		"description": &spec.Description,
+	}
+	lists := map[string]*[]string{
+		"goals":               &spec.Goals,
+		"acceptance_criteria": &spec.AcceptanceCriteria,
+		"constraints":         &spec.Constraints,
+		"out_of_scope":        &spec.OutOfScope,
+		"files":               &spec.Files,
+		"review_checklist":    &spec.ReviewChecklist,
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	seen := make(map[string]bool)
+	for i := 0; i < len(lines); {
+		lineNum := i + 1
+		line := strings.TrimRight(lines[i], " \t")
+		i++
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if indent(line) > 0 {
+			return fmt.Errorf("line %d: expected a top-level key, got %q", lineNum, trimmed)
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return fmt.Errorf("line %d: expected key: value, got %q", lineNum, trimmed)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if seen[key] {
+			return fmt.Errorf("line %d: %s is set twice", lineNum, key)
+		}
+		seen[key] = true
+
+		if field, ok := scalars[key]; ok {
+			if value == "|" || value == ">" {
+				var block []string
+				block, i = blockLines(lines, i)
+				*field = joinBlock(block, value == ">")
+				continue
+			}
+			if value == "" || strings.HasPrefix(value, "[") {
+				return fmt.Errorf("line %d: %s must be a string", lineNum, key)
+			}
+			*field = frontmatter.Unquote(value)
+			continue
+		}
+
+		field, ok := lists[key]
+		if !ok {
+			return fmt.Errorf("line %d: unknown key %q", lineNum, key)
+		}
+		if value != "" {
+			if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+				return fmt.Errorf("line %d: %s must be a list", lineNum, key)
+			}
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = frontmatter.Unquote(strings.TrimSpace(item)); item != "" {
+					*field = append(*field, item)
+				}
+			}
+			continue
+		}
+
+		// "- item" lines, each continued by the lines indented past its dash
+		for i < len(lines) {
+			itemLine := strings.TrimRight(lines[i], " \t")
+			itemTrimmed := strings.TrimSpace(itemLine)
+			if itemTrimmed == "" || strings.HasPrefix(itemTrimmed, "#") {
+				i++
+				continue
+			}
+			item, ok := strings.CutPrefix(itemTrimmed, "-")
+			if !ok {
+				break
+			}
+			dash := indent(itemLine)
+			i++
+			parts := []string{strings.TrimSpace(item)}
+			for i < len(lines) && strings.TrimSpace(lines[i]) != "" && indent(lines[i]) > dash &&
+				!strings.HasPrefix(strings.TrimSpace(lines[i]), "- ") {
+				parts = append(parts, strings.TrimSpace(lines[i]))
+				i++
+			}
+			*field = append(*field, frontmatter.Unquote(strings.Join(parts, " ")))
+		}
+	}
+	return nil
+}
+
+// blockLines returns the lines of a block scalar starting at lines[i],
+// without their common indentation, and the index of the line after it.
+func blockLines(lines []string, i int) ([]string, int) {
+	var block []string
+	common := -1
+	for ; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		if line == "" {
+			block = append(block, "")
+			continue
+		}
+		n := indent(line)
+		if n == 0 {
+			break
+		}
+		if common < 0 || n < common {
+			common = n
+		}
+		block = append(block, line)
+	}
+	for j, line := range block {
+		if line != "" {
+			block[j] = line[common:]
+		}
+	}
+	return block, i
+}
+
+// joinBlock joins a block scalar's lines: kept as lines, or folded into
+// paragraphs with blank lines between them.
+func joinBlock(block []string, folded bool) string {
+	if !folded {
+		return strings.TrimSpace(strings.Join(block, "\n"))
+	}
+	var paragraphs, current []string
+	for _, line := range append(block, "") {
+		if line != "" {
+			current = append(current, strings.TrimSpace(line))
+			continue
+		}
+		if len(current) > 0 {
+			paragraphs = append(paragraphs, strings.Join(current, " "))
+			current = nil
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// indent returns the number of leading spaces and tabs of line.
+func indent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
@@ -0,0 +1,57 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/gerunddev/ralph/internal/frontmatter"
+)
+
+// PlanFiles returns the globs in the "files:" block of a plan's front
+// matter: the repo-relative paths the plan's developer may touch, matched
+// like AllowedPaths. The block is either an indented list of "- glob" lines
+// or an inline "[a, b]" list. Other front matter keys are ignored. A plan
+// without front matter or a files block allows every path (nil).
+func PlanFiles(plan string) ([]string, error) {
+	return frontmatter.List(plan, "files", "glob")
+}
+
+// ForPlan returns the policy for a plan: base, with the permissions set in
+// the plan's front matter overriding it. The "allowed_paths:" and
+// "forbidden_commands:" lists are written like the files block, and
+// "network:" is true or false. A plan that sets none gets base.
+func ForPlan(base *Policy, plan string) (*Policy, error) {
+	if base == nil {
+		base = New(nil, nil, true)
+	}
+	paths, err := frontmatter.List(plan, "allowed_paths", "path")
+	if err != nil {
+		return nil, err
+	}
+	commands, err := frontmatter.List(plan, "forbidden_commands", "command")
+	if err != nil {
+		return nil, err
+	}
+	network, err := frontmatter.Value(plan, "network")
+	if err != nil {
+		return nil, err
+	}
+	if paths == nil && commands == nil && network == "" {
+		return base, nil
+	}
+
+	p := New(slices.Clone(base.AllowedPaths), slices.Clone(base.ForbiddenCommands), base.Network)
+	if paths != nil {
+		p.AllowedPaths = paths
+	}
+	if commands != nil {
+		p.ForbiddenCommands = commands
+	}
+	if network != "" {
+		if p.Network, err = strconv.ParseBool(network); err != nil {
+			return nil, fmt.Errorf("front matter: network must be true or false, got %q", network)
+		}
+	}
+	return p, nil
+}
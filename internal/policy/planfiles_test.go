@@ -0,0 +1,76 @@
+package policy
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPlanFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		plan string
+		want []string
+	}{
+		{"no front matter", "# Plan\n\nfiles:\n  - a.go\n", nil},
+		{"no files block", "---\nenv:\n  A: b\n---\n# Plan\n", nil},
+		{
+			"block list",
+			"---\nenv:\n  A: b\nfiles:\n  - internal/api/**\n  - \"docs/*.md\"\n# comment\n- go.mod\ntitle: x\n---\n# Plan\n",
+			[]string{"internal/api/**", "docs/*.md", "go.mod"},
+		},
+		{"inline list", "---\nfiles: [cmd/, 'README.md']\n---\n", []string{"cmd/", "README.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PlanFiles(tt.plan)
+			if err != nil {
+				t.Fatalf("PlanFiles() error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("PlanFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanFiles_Invalid(t *testing.T) {
+	for _, plan := range []string{
+		"---\nfiles: a.go\n---\n",
+		"---\nfiles:\n  a.go\n---\n",
+		"---\nfiles:\n  -\n---\n",
+		"---\nfiles:\n  - a.go\n",
+	} {
+		if _, err := PlanFiles(plan); err == nil {
+			t.Errorf("PlanFiles(%q) expected an error", plan)
+		}
+	}
+}
+
+func TestForPlan(t *testing.T) {
+	base := New([]string{"src/"}, []string{"rm"}, true)
+
+	got, err := ForPlan(base, "---\nfiles: [a.go]\n---\n# Plan\n")
+	if err != nil || got != base {
+		t.Errorf("ForPlan() = %+v, %v; want the base policy", got, err)
+	}
+
+	got, err = ForPlan(base, "---\nallowed_paths:\n  - internal/billing/\nnetwork: false\n---\n# Plan\n")
+	if err != nil {
+		t.Fatalf("ForPlan() error: %v", err)
+	}
+	if !slices.Equal(got.AllowedPaths, []string{"internal/billing/"}) || !slices.Equal(got.ForbiddenCommands, []string{"rm"}) || got.Network {
+		t.Errorf("ForPlan() = %+v, want the plan's paths and no network, keeping the base commands", got)
+	}
+	if !slices.Equal(base.AllowedPaths, []string{"src/"}) || !base.Network {
+		t.Errorf("ForPlan() changed the base policy: %+v", base)
+	}
+
+	got, err = ForPlan(nil, "---\nforbidden_commands: [git push]\n---\n")
+	if err != nil || !slices.Equal(got.ForbiddenCommands, []string{"git push"}) || !got.Network || got.HasPathRestrictions() {
+		t.Errorf("ForPlan(nil) = %+v, %v; want only the plan's commands", got, err)
+	}
+
+	if _, err := ForPlan(base, "---\nnetwork: sometimes\n---\n"); err == nil {
+		t.Error("ForPlan() should reject a network value that isn't true or false")
+	}
+}
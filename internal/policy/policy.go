@@ -0,0 +1,116 @@
+// Package policy enforces sandbox restrictions on agent sessions: the
+// permissions config, which a plan's front matter can override for that
+// plan.
+package policy
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// ErrViolation is returned when an agent's changes breach the policy.
+var ErrViolation = errors.New("policy violation")
+
+// networkTools are the Claude tools that reach the network.
+var networkTools = []string{"WebFetch", "WebSearch"}
+
+// Policy describes what an agent is allowed to touch.
+// The zero value allows everything except network access; use New for defaults.
+type Policy struct {
+	// AllowedPaths are repo-relative paths or globs the agent may modify.
+	// A trailing "/" or "/**" matches everything under a directory.
+	// Empty means every path is allowed.
+	AllowedPaths []string
+
+	// ForbiddenCommands are shell command prefixes the agent may not run
+	// through the Bash tool (e.g. "rm", "git push").
+	ForbiddenCommands []string
+
+	// Network controls whether network tools (WebFetch, WebSearch) are available.
+	Network bool
+}
+
+// New creates a policy with network access enabled.
+func New(allowedPaths, forbiddenCommands []string, network bool) *Policy {
+	return &Policy{
+		AllowedPaths:      allowedPaths,
+		ForbiddenCommands: forbiddenCommands,
+		Network:           network,
+	}
+}
+
+// DisallowedTools translates the policy into Claude CLI --disallowedTools rules.
+func (p *Policy) DisallowedTools() []string {
+	if p == nil {
+		return nil
+	}
+
+	var tools []string
+	for _, cmd := range p.ForbiddenCommands {
+		cmd = strings.TrimSpace(cmd)
+		if cmd == "" {
+			continue
+		}
+		tools = append(tools, "Bash("+cmd+":*)")
+	}
+	if !p.Network {
+		tools = append(tools, networkTools...)
+	}
+	return tools
+}
+
+// HasPathRestrictions reports whether the policy limits which paths may change.
+func (p *Policy) HasPathRestrictions() bool {
+	return p != nil && len(p.AllowedPaths) > 0
+}
+
+// CheckPaths returns the files that fall outside AllowedPaths.
+// Returns nil when the policy has no path restrictions.
+func (p *Policy) CheckPaths(files []string) []string {
+	if !p.HasPathRestrictions() {
+		return nil
+	}
+
+	var violations []string
+	for _, file := range files {
+		if !p.pathAllowed(file) {
+			violations = append(violations, file)
+		}
+	}
+	return violations
+}
+
+// pathAllowed reports whether a single file matches any allowed pattern.
+func (p *Policy) pathAllowed(file string) bool {
+	file = filepath.ToSlash(filepath.Clean(file))
+	for _, pattern := range p.AllowedPaths {
+		if MatchPath(pattern, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPath reports whether a repo-relative file path matches a pattern.
+// Patterns ending in "/" or "/**" match a directory and everything under it;
+// other patterns use filepath.Match semantics, and a bare directory name
+// also matches files beneath it.
+func MatchPath(pattern, file string) bool {
+	pattern = filepath.ToSlash(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return false
+	}
+
+	if strings.HasSuffix(pattern, "/**") || strings.HasSuffix(pattern, "/") {
+		dir := strings.TrimSuffix(strings.TrimSuffix(pattern, "**"), "/")
+		return file == dir || strings.HasPrefix(file, dir+"/")
+	}
+
+	if ok, err := filepath.Match(pattern, file); err == nil && ok {
+		return true
+	}
+
+	// Bare directory names match their contents
+	return strings.HasPrefix(file, strings.TrimSuffix(pattern, "/")+"/")
+}
@@ -0,0 +1,82 @@
+package policy
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDisallowedTools(t *testing.T) {
+	p := New(nil, []string{"rm", " git push ", ""}, false)
+
+	got := p.DisallowedTools()
+	want := []string{"Bash(rm:*)", "Bash(git push:*)", "WebFetch", "WebSearch"}
+	if !slices.Equal(got, want) {
+		t.Errorf("DisallowedTools() = %v, want %v", got, want)
+	}
+}
+
+func TestDisallowedTools_NetworkAllowed(t *testing.T) {
+	p := New(nil, nil, true)
+	if got := p.DisallowedTools(); len(got) != 0 {
+		t.Errorf("expected no disallowed tools, got %v", got)
+	}
+}
+
+func TestDisallowedTools_NilPolicy(t *testing.T) {
+	var p *Policy
+	if got := p.DisallowedTools(); got != nil {
+		t.Errorf("expected nil, got %v", got)
+	}
+	if p.HasPathRestrictions() {
+		t.Error("nil policy should not restrict paths")
+	}
+}
+
+func TestCheckPaths(t *testing.T) {
+	p := New([]string{"internal/", "cmd/**", "*.md", "go.mod"}, nil, true)
+
+	files := []string{
+		"internal/loop/loop.go",
+		"cmd/ralph/main.go",
+		"README.md",
+		"go.mod",
+		"go.sum",
+		"secrets/key.pem",
+	}
+
+	got := p.CheckPaths(files)
+	want := []string{"go.sum", "secrets/key.pem"}
+	if !slices.Equal(got, want) {
+		t.Errorf("CheckPaths() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckPaths_NoRestrictions(t *testing.T) {
+	p := New(nil, nil, true)
+	if got := p.CheckPaths([]string{"anything.go"}); got != nil {
+		t.Errorf("expected nil, got %v", got)
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		file    string
+		want    bool
+	}{
+		{"internal/", "internal/a.go", true},
+		{"internal/**", "internal/x/y.go", true},
+		{"internal/**", "internalfoo/a.go", false},
+		{"internal", "internal/a.go", true},
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/main.go", false},
+		{"pkg/*.go", "pkg/main.go", true},
+		{"", "main.go", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchPath(tt.pattern, tt.file); got != tt.want {
+			t.Errorf("MatchPath(%q, %q) = %v, want %v", tt.pattern, tt.file, got, tt.want)
+		}
+	}
+}
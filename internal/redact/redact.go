@@ -0,0 +1,98 @@
+// Package redact masks secrets such as API keys, tokens, and .env values in
+// text before it is stored or displayed.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Mask replaces each secret found.
+const Mask = "[REDACTED]"
+
+// rule is a secret pattern and its replacement. Replacements may keep a
+// leading capture group (e.g. the variable name of an .env assignment).
+type rule struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// boundary matches the start of text, a non-word character, or a JSON
+// escape (\n, \r, \t) before a token, so tokens are found in stored stream
+// events without matching the tail of an ordinary word.
+const boundary = `(^|[^A-Za-z0-9_]|\\[nrt])`
+
+// token returns a rule masking a token that starts at a boundary.
+func token(pattern string) rule {
+	return rule{regexp.MustCompile(boundary + pattern), "${1}" + Mask}
+}
+
+// Value patterns stop at whitespace, quotes, and backslashes so that
+// redacting JSON-encoded text keeps it valid.
+var builtinRules = []rule{
+	// PEM private key blocks
+	{regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`), Mask},
+	// Anthropic and OpenAI API keys
+	token(`sk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`),
+	// GitHub tokens
+	token(`(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`),
+	// GitLab personal access tokens
+	token(`glpat-[A-Za-z0-9_-]{20,}`),
+	// Slack tokens
+	token(`xox[abposr]-[A-Za-z0-9-]{10,}`),
+	// AWS access key IDs
+	token(`(?:AKIA|ASIA)[A-Z0-9]{16}\b`),
+	// Google API keys
+	token(`AIza[A-Za-z0-9_-]{35}`),
+	// Stripe keys
+	token(`[rs]k_(?:live|test)_[A-Za-z0-9]{16,}`),
+	// Bearer tokens in Authorization headers
+	{regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9_\-.=+/]{20,}`), "${1}" + Mask},
+	// .env-style assignments of secret-looking variables: the secret word is
+	// a whole segment of the name, at its end or before _KEY, _ACCESS_KEY,
+	// _ID, or _BASE, so GITHUB_TOKEN and AWS_SECRET_ACCESS_KEY match but
+	// MAX_TOKENS and TOKEN_LIMIT don't
+	{regexp.MustCompile(boundary + `((?:[A-Z0-9]+_)*(?:SECRET|TOKEN|PASSWORD|PASSWD|API_?KEY|ACCESS_KEY|PRIVATE_KEY|CREDENTIALS?)(?:_(?:KEY|ACCESS_KEY|ID|BASE))?=)[^\s"'\\]+`), "${1}${2}" + Mask},
+}
+
+// Redactor masks secrets matching the built-in patterns and any custom
+// patterns. A nil Redactor leaves text unchanged.
+type Redactor struct {
+	rules []rule
+}
+
+// New creates a redactor with the built-in patterns plus the given custom
+// regular expressions, whose whole matches are masked.
+func New(patterns []string) (*Redactor, error) {
+	rules := append([]rule(nil), builtinRules...)
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		rules = append(rules, rule{re: re, repl: Mask})
+	}
+	return &Redactor{rules: rules}, nil
+}
+
+// String returns s with every secret masked.
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, rule := range r.rules {
+		s = rule.re.ReplaceAllString(s, rule.repl)
+	}
+	return s
+}
+
+// Bytes returns b with every secret masked.
+func (r *Redactor) Bytes(b []byte) []byte {
+	if r == nil || len(b) == 0 {
+		return b
+	}
+	for _, rule := range r.rules {
+		b = rule.re.ReplaceAll(b, []byte(rule.repl))
+	}
+	return b
+}
@@ -0,0 +1,155 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/gerunddev/ralph/internal/app"
+	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+// appBackend runs plans headlessly with app.App, one App per plan.
+type appBackend struct {
+	// checkRepo returns an error if the directory isn't a jj repository
+	checkRepo func(ctx context.Context, dir string) error
+}
+
+// NewAppBackend creates the backend ralph serves editors with.
+func NewAppBackend() Backend {
+	return appBackend{checkRepo: checkJJRepository}
+}
+
+// checkJJRepository returns an error if dir isn't inside a jj repository,
+// as the run command checks before starting a plan.
+func checkJJRepository(ctx context.Context, dir string) error {
+	_, err := jj.NewClient(dir).Status(ctx)
+	switch {
+	case errors.Is(err, jj.ErrNotRepo):
+		return fmt.Errorf("not a jj repository: %s (pass a workDir inside one)", dir)
+	case errors.Is(err, jj.ErrCommandNotFound):
+		return errors.New("jj command not found (install jujutsu: https://github.com/martinvonz/jj)")
+	case err != nil:
+		return fmt.Errorf("failed to verify jj repository: %w", err)
+	}
+	return nil
+}
+
+// Start implements Backend. The directory the plan runs in must be a jj
+// repository; it is checked before the plan is created.
+func (b appBackend) Start(ctx context.Context, params StartParams, onEvent func(planID string, event loop.Event)) (Run, error) {
+	dir := params.WorkDir
+	if dir == "" {
+		var err error
+		if dir, err = os.Getwd(); err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+	}
+	if err := b.checkRepo(ctx, dir); err != nil {
+		return nil, err
+	}
+
+	a, err := app.New(app.Config{
+		WorkDir:               params.WorkDir,
+		WorkDirOverride:       params.WorkDir != "",
+		MaxIterationsOverride: params.MaxIterations,
+		ForceNew:              params.ForceNew,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &appRun{app: a, started: make(chan struct{}), done: make(chan struct{})}
+	var once sync.Once
+	a.SetEventHandler(func(event loop.Event) {
+		// The plan exists once the loop emits its first event
+		once.Do(func() {
+			r.planID = a.PlanID()
+			close(r.started)
+		})
+		onEvent(r.planID, event)
+	})
+
+	go func() {
+		defer close(r.done)
+		var result *app.Result
+		var err error
+		switch {
+		case params.Resume != "":
+			result, err = a.ResumeHeadless(ctx, params.Resume)
+		case params.Prompt != "":
+			result, err = a.RunWithPromptHeadless(ctx, params.Prompt)
+		default:
+			result, err = a.RunHeadless(ctx, params.PlanFile)
+		}
+		if err != nil {
+			r.result = RunResult{Err: err}
+			return
+		}
+		r.result = RunResult{Completed: result.Completed, Iterations: result.Iterations, Err: result.Error}
+		r.finalID = result.PlanID
+	}()
+
+	select {
+	case <-r.started:
+		return r, nil
+	case <-r.done:
+		if r.result.Err != nil {
+			return nil, r.result.Err
+		}
+		if r.finalID == "" {
+			return nil, errors.New("plan ended before it started")
+		}
+		return r, nil
+	}
+}
+
+// appRun is a plan an App runs.
+type appRun struct {
+	app     *app.App
+	started chan struct{} // Closed on the loop's first event
+	done    chan struct{} // Closed once the App returns
+	planID  string        // Set before started is closed
+	finalID string        // Set before done is closed
+	result  RunResult     // Set before done is closed
+}
+
+// PlanID implements Run.
+func (r *appRun) PlanID() string {
+	select {
+	case <-r.started:
+		return r.planID
+	default:
+	}
+	<-r.done
+	return r.finalID
+}
+
+// SubmitFeedback implements Run.
+func (r *appRun) SubmitFeedback(feedback string) error {
+	select {
+	case <-r.done:
+		return errors.New("plan is not running")
+	default:
+	}
+	return r.app.SubmitFeedback(feedback)
+}
+
+// Pause implements Run.
+func (r *appRun) Pause() error {
+	select {
+	case <-r.done:
+		return errors.New("plan is not running")
+	default:
+	}
+	return r.app.Pause()
+}
+
+// Wait implements Run.
+func (r *appRun) Wait() RunResult {
+	<-r.done
+	return r.result
+}
@@ -0,0 +1,29 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+func TestAppBackend_StartChecksRepository(t *testing.T) {
+	notRepo := errors.New("not a jj repository")
+	var checked string
+	backend := appBackend{checkRepo: func(ctx context.Context, dir string) error {
+		checked = dir
+		return notRepo
+	}}
+
+	dir := t.TempDir()
+	run, err := backend.Start(context.Background(), StartParams{Prompt: "Build it.", WorkDir: dir}, func(string, loop.Event) {
+		t.Error("expected no events from a plan that didn't start")
+	})
+	if !errors.Is(err, notRepo) || run != nil {
+		t.Errorf("Start() = %v, %v; want the repository error", run, err)
+	}
+	if checked != dir {
+		t.Errorf("checked %q, want %q", checked, dir)
+	}
+}
@@ -0,0 +1,94 @@
+package rpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MaxMessageSize is the largest message body a Conn reads.
+const MaxMessageSize = 16 << 20
+
+// Conn reads and writes Content-Length framed messages. Writes are safe for
+// concurrent use; reads are not.
+type Conn struct {
+	r *bufio.Reader
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewConn creates a connection reading from r and writing to w.
+func NewConn(r io.Reader, w io.Writer) *Conn {
+	return &Conn{r: bufio.NewReader(r), w: w}
+}
+
+// Read returns the body of the next message. It returns io.EOF once r ends
+// between messages.
+func (c *Conn) Read() ([]byte, error) {
+	length := -1
+	for first := true; ; first = false {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				if first && line == "" {
+					return nil, io.EOF
+				}
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("failed to read header: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid header %q", line)
+		}
+		// Other headers, such as Content-Type, are ignored
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || length < 0 {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+		}
+	}
+
+	if length < 0 {
+		return nil, errors.New("message has no Content-Length header")
+	}
+	if length > MaxMessageSize {
+		return nil, fmt.Errorf("message of %d bytes exceeds the limit of %d", length, MaxMessageSize)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, fmt.Errorf("failed to read message body: %w", err)
+	}
+	return body, nil
+}
+
+// Write encodes v as JSON and writes it as one message.
+func (c *Conn) Write(v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if _, err := c.w.Write(body); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package rpc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestConn_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	conn := NewConn(&buf, &buf)
+
+	if err := conn.Write(map[string]int{"a": 1}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := conn.Write(map[string]string{"b": "ü"}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "Content-Length: 7\r\n\r\n{\"a\":1}") {
+		t.Errorf("unexpected framing: %q", buf.String())
+	}
+
+	for _, want := range []string{`{"a":1}`, `{"b":"ü"}`} {
+		body, err := conn.Read()
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if string(body) != want {
+			t.Errorf("Read = %q, want %q", body, want)
+		}
+	}
+	if _, err := conn.Read(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF at the end, got %v", err)
+	}
+}
+
+func TestConn_ReadHeaders(t *testing.T) {
+	input := "Content-Type: application/vscode-jsonrpc; charset=utf-8\r\ncontent-length: 2\r\n\r\n{}"
+	body, err := NewConn(strings.NewReader(input), io.Discard).Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(body) != "{}" {
+		t.Errorf("Read = %q, want {}", body)
+	}
+}
+
+func TestConn_ReadInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"no content length", "Content-Type: x\r\n\r\n{}"},
+		{"bad content length", "Content-Length: abc\r\n\r\n{}"},
+		{"malformed header", "Content-Length 2\r\n\r\n{}"},
+		{"too large", "Content-Length: 999999999\r\n\r\n"},
+		{"truncated body", "Content-Length: 10\r\n\r\n{}"},
+		{"truncated header", "Content-Length: 2\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewConn(strings.NewReader(tt.input), io.Discard).Read()
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if errors.Is(err, io.EOF) {
+				t.Errorf("expected a framing error, got io.EOF")
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+// Package rpc lets editor plugins drive ralph over JSON-RPC 2.0: start plans,
+// receive their events as they stream, and send the developer feedback,
+// without scraping the TUI.
+//
+// Messages are framed as in the Language Server Protocol: a Content-Length
+// header, a blank line, then the JSON body. A client starts with initialize,
+// which negotiates ProtocolVersion; every other request before it fails with
+// CodeNotInitialized.
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+// ProtocolVersion is the version of the protocol the server speaks. It is
+// bumped whenever a method, notification, or field changes incompatibly;
+// additions that clients can ignore keep the version.
+const ProtocolVersion = 1
+
+// Requests a client sends.
+const (
+	// MethodInitialize negotiates the protocol version (InitializeParams,
+	// InitializeResult). It must be the first request.
+	MethodInitialize = "initialize"
+	// MethodShutdown stops every running plan; the client then sends
+	// MethodExit.
+	MethodShutdown = "shutdown"
+	// MethodStart starts running a plan (StartParams, StartResult).
+	MethodStart = "plan/start"
+	// MethodFeedback sends feedback to a running plan's developer
+	// (FeedbackParams).
+	MethodFeedback = "plan/feedback"
+	// MethodPause pauses a running plan once its current iteration
+	// finishes (PlanParams). plan/finished follows.
+	MethodPause = "plan/pause"
+	// MethodStop interrupts a running plan, leaving it paused (PlanParams).
+	MethodStop = "plan/stop"
+)
+
+// Notifications: MethodExit from the client, the rest from the server.
+const (
+	// MethodExit ends the session. Running plans are interrupted first.
+	MethodExit = "exit"
+	// NotifyEvent carries one event of a running plan (EventParams).
+	NotifyEvent = "plan/event"
+	// NotifyFinished is sent once a plan's run ends (FinishedParams).
+	NotifyFinished = "plan/finished"
+)
+
+// Error codes. The JSON-RPC 2.0 codes are joined by the server's own,
+// which use the range JSON-RPC reserves for implementations.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+
+	CodeUnsupportedVersion = -32001 // initialize asked for a protocol version the server doesn't speak
+	CodeNotInitialized     = -32002 // A request came before initialize
+	CodeUnknownPlan        = -32003 // No plan with that ID is running in this session
+	CodeStartFailed        = -32004 // The plan could not be started, e.g. its plan file doesn't exist
+)
+
+// Error is a JSON-RPC error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// newError creates an error object with a formatted message.
+func newError(code int, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// request is an incoming request or, without an ID, notification.
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response answers a request with either a result or an error.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   *Error          `json:"error"`
+}
+
+// notification is a message the server sends without expecting a reply.
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// InitializeParams are the parameters of MethodInitialize.
+type InitializeParams struct {
+	ProtocolVersion int    `json:"protocolVersion"`
+	ClientName      string `json:"clientName,omitempty"`
+}
+
+// InitializeResult describes the server.
+type InitializeResult struct {
+	ProtocolVersion int      `json:"protocolVersion"`
+	ServerName      string   `json:"serverName"`
+	Methods         []string `json:"methods"` // Requests the server accepts
+}
+
+// StartParams are the parameters of MethodStart. Exactly one of PlanFile,
+// Prompt, and Resume is set.
+type StartParams struct {
+	PlanFile      string `json:"planFile,omitempty"` // Path of a plan file to run
+	Prompt        string `json:"prompt,omitempty"`   // Inline plan text
+	Resume        string `json:"resume,omitempty"`   // ID of a plan to resume
+	WorkDir       string `json:"workDir,omitempty"`  // Repository to run in (default: the server's directory)
+	MaxIterations int    `json:"maxIterations,omitempty"`
+
+	// ForceNew, with PlanFile, starts a new plan even if an unfinished
+	// plan was started from the same file. Without it, that plan resumes.
+	ForceNew bool `json:"forceNew,omitempty"`
+}
+
+// StartResult identifies the started plan.
+type StartResult struct {
+	PlanID string `json:"planId"`
+}
+
+// FeedbackParams are the parameters of MethodFeedback. The feedback is
+// added to the developer's next prompt.
+type FeedbackParams struct {
+	PlanID   string `json:"planId"`
+	Feedback string `json:"feedback"`
+}
+
+// PlanParams identify a running plan.
+type PlanParams struct {
+	PlanID string `json:"planId"`
+}
+
+// EventParams are the parameters of NotifyEvent: a loop event of a plan.
+type EventParams struct {
+	PlanID        string       `json:"planId"`
+	Type          string       `json:"type"` // Loop event type, e.g. "iteration_start"
+	Iteration     int          `json:"iteration"`
+	MaxIterations int          `json:"maxIterations"` // 0 in extreme mode until it is triggered
+	Message       string       `json:"message,omitempty"`
+	Prompt        string       `json:"prompt,omitempty"` // Agent prompt, for "prompt_built"
+	Output        string       `json:"output,omitempty"` // Agent output, for "claude_output"
+	Diff          string       `json:"diff,omitempty"`   // Plan file edits, for "plan_changed"
+	Claude        *ClaudeEvent `json:"claude,omitempty"` // Streamed Claude event, for "claude_stream"
+}
+
+// ClaudeEvent is the part of a streamed Claude event editors display.
+type ClaudeEvent struct {
+	Type       string          `json:"type"`                 // e.g. "assistant_text", "tool_use", "result"
+	Text       string          `json:"text,omitempty"`       // Assistant text
+	Tool       string          `json:"tool,omitempty"`       // Tool name, for "tool_use"
+	ToolInput  json.RawMessage `json:"toolInput,omitempty"`  // Tool input, for "tool_use"
+	SubAgentID string          `json:"subAgentId,omitempty"` // Task call of the sub-agent the event came from
+	Error      string          `json:"error,omitempty"`      // Error message, for "error"
+	CostUSD    float64         `json:"costUsd,omitempty"`    // Session cost, for "result"
+}
+
+// FinishedParams are the parameters of NotifyFinished.
+type FinishedParams struct {
+	PlanID     string `json:"planId"`
+	Completed  bool   `json:"completed"`
+	Iterations int    `json:"iterations"`
+	Error      string `json:"error,omitempty"`
+}
+
+// newEventParams converts a loop event for a notification.
+func newEventParams(planID string, event loop.Event) EventParams {
+	params := EventParams{
+		PlanID:        planID,
+		Type:          string(event.Type),
+		Iteration:     event.Iteration,
+		MaxIterations: event.MaxIter,
+		Message:       event.Message,
+		Prompt:        event.Prompt,
+		Output:        event.Output,
+		Diff:          event.Diff,
+	}
+
+	if ce := event.ClaudeEvent; ce != nil {
+		c := &ClaudeEvent{Type: string(ce.Type), SubAgentID: ce.SubAgentID}
+		switch {
+		case ce.AssistantText != nil:
+			c.Text = ce.AssistantText.Text
+		case ce.Message != nil:
+			c.Text = ce.Message.Text
+		}
+		if ce.ToolUse != nil {
+			c.Tool = ce.ToolUse.Name
+			c.ToolInput = ce.ToolUse.Input
+		}
+		if ce.Error != nil {
+			c.Error = ce.Error.Message
+		}
+		if ce.Result != nil {
+			c.CostUSD = ce.Result.CostUSD
+		}
+		params.Claude = c
+	}
+	return params
+}
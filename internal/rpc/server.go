@@ -0,0 +1,329 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+// Backend starts plans for the server.
+type Backend interface {
+	// Start starts running a plan and returns once it runs. The plan stops
+	// when ctx is cancelled. onEvent receives the plan's events, in order,
+	// from a single goroutine.
+	Start(ctx context.Context, params StartParams, onEvent func(planID string, event loop.Event)) (Run, error)
+}
+
+// Run is a running plan.
+type Run interface {
+	PlanID() string
+	// SubmitFeedback adds feedback to the developer's next prompt.
+	SubmitFeedback(feedback string) error
+	// Pause pauses the plan once its current iteration finishes, ending
+	// the run.
+	Pause() error
+	// Wait blocks until the run ends.
+	Wait() RunResult
+}
+
+// RunResult is the outcome of a Run.
+type RunResult struct {
+	Completed  bool
+	Iterations int
+	Err        error
+}
+
+// planRun is a run started by the server.
+type planRun struct {
+	Run
+	cancel context.CancelFunc
+	// ready is closed once the client has the plan/start response, which
+	// the run's notifications wait for
+	ready chan struct{}
+}
+
+// Server serves the protocol on a connection, one session per connection.
+type Server struct {
+	conn    *Conn
+	backend Backend
+
+	initialized bool
+	shutdown    bool
+
+	mu   sync.Mutex
+	runs map[string]*planRun
+	wg   sync.WaitGroup // Running plans
+}
+
+// NewServer creates a server starting plans with backend.
+func NewServer(conn *Conn, backend Backend) *Server {
+	return &Server{conn: conn, backend: backend, runs: make(map[string]*planRun)}
+}
+
+// Serve handles messages until the client sends exit or closes the
+// connection. Running plans are stopped, and waited for, before it returns.
+func (s *Server) Serve(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		s.wg.Wait()
+	}()
+
+	for {
+		body, err := s.conn.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if exit := s.handle(ctx, body); exit {
+			return nil
+		}
+	}
+}
+
+// handle handles one message and reports whether it was exit.
+func (s *Server) handle(ctx context.Context, body []byte) bool {
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		s.reply(nil, nil, newError(CodeParseError, "invalid JSON: %v", err))
+		return false
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		s.reply(req.ID, nil, newError(CodeInvalidRequest, "not a JSON-RPC 2.0 request"))
+		return false
+	}
+
+	// Notifications get no reply; exit is the only one the server knows
+	if len(req.ID) == 0 {
+		return req.Method == MethodExit
+	}
+
+	if req.Method != MethodInitialize && !s.initialized {
+		s.reply(req.ID, nil, newError(CodeNotInitialized, "initialize must be the first request"))
+		return false
+	}
+	if s.shutdown {
+		s.reply(req.ID, nil, newError(CodeInvalidRequest, "server is shutting down"))
+		return false
+	}
+
+	var result any
+	var rpcErr *Error
+	var started *planRun
+	switch req.Method {
+	case MethodInitialize:
+		result, rpcErr = s.initialize(req.Params)
+	case MethodShutdown:
+		s.shutdown = true
+		s.stopAll()
+	case MethodStart:
+		started, rpcErr = s.start(ctx, req.Params)
+		if started != nil {
+			result = StartResult{PlanID: started.PlanID()}
+		}
+	case MethodFeedback:
+		rpcErr = s.feedback(req.Params)
+	case MethodPause:
+		rpcErr = s.pause(req.Params)
+	case MethodStop:
+		rpcErr = s.stop(req.Params)
+	default:
+		rpcErr = newError(CodeMethodNotFound, "unknown method %q", req.Method)
+	}
+
+	s.reply(req.ID, result, rpcErr)
+	if started != nil {
+		close(started.ready)
+	}
+	return false
+}
+
+// initialize negotiates the protocol version.
+func (s *Server) initialize(raw json.RawMessage) (any, *Error) {
+	if s.initialized {
+		return nil, newError(CodeInvalidRequest, "already initialized")
+	}
+	var params InitializeParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if params.ProtocolVersion != ProtocolVersion {
+		err := newError(CodeUnsupportedVersion, "unsupported protocol version %d", params.ProtocolVersion)
+		err.Data = map[string][]int{"supported": {ProtocolVersion}}
+		return nil, err
+	}
+
+	s.initialized = true
+	return InitializeResult{
+		ProtocolVersion: ProtocolVersion,
+		ServerName:      "ralph",
+		Methods:         []string{MethodInitialize, MethodShutdown, MethodStart, MethodFeedback, MethodPause, MethodStop},
+	}, nil
+}
+
+// start starts a plan. Its notifications are held back until the returned
+// run's ready channel is closed.
+func (s *Server) start(ctx context.Context, raw json.RawMessage) (*planRun, *Error) {
+	var params StartParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	sources := 0
+	for _, source := range []string{params.PlanFile, params.Prompt, params.Resume} {
+		if strings.TrimSpace(source) != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, newError(CodeInvalidParams, "exactly one of planFile, prompt, and resume is required")
+	}
+	if params.MaxIterations < 0 {
+		return nil, newError(CodeInvalidParams, "maxIterations must not be negative")
+	}
+	if params.Resume != "" && s.lookup(params.Resume) != nil {
+		return nil, newError(CodeInvalidParams, "plan %s is already running", params.Resume)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	pr := &planRun{cancel: cancel, ready: make(chan struct{})}
+	run, err := s.backend.Start(runCtx, params, func(planID string, event loop.Event) {
+		<-pr.ready
+		s.notify(NotifyEvent, newEventParams(planID, event))
+	})
+	if err != nil {
+		cancel()
+		close(pr.ready)
+		return nil, newError(CodeStartFailed, "failed to start plan: %v", err)
+	}
+	pr.Run = run
+
+	s.mu.Lock()
+	s.runs[run.PlanID()] = pr
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		result := run.Wait()
+		cancel()
+
+		s.mu.Lock()
+		delete(s.runs, run.PlanID())
+		s.mu.Unlock()
+
+		finished := FinishedParams{PlanID: run.PlanID(), Completed: result.Completed, Iterations: result.Iterations}
+		if result.Err != nil {
+			finished.Error = result.Err.Error()
+		}
+		<-pr.ready
+		s.notify(NotifyFinished, finished)
+	}()
+	return pr, nil
+}
+
+// feedback passes feedback to a running plan.
+func (s *Server) feedback(raw json.RawMessage) *Error {
+	var params FeedbackParams
+	if err := decodeParams(raw, &params); err != nil {
+		return err
+	}
+	if strings.TrimSpace(params.Feedback) == "" {
+		return newError(CodeInvalidParams, "feedback is empty")
+	}
+	run := s.lookup(params.PlanID)
+	if run == nil {
+		return newError(CodeUnknownPlan, "plan %q is not running", params.PlanID)
+	}
+	if err := run.SubmitFeedback(params.Feedback); err != nil {
+		return newError(CodeInternalError, "failed to submit feedback: %v", err)
+	}
+	return nil
+}
+
+// pause pauses a running plan once its current iteration finishes.
+// plan/finished follows once it paused.
+func (s *Server) pause(raw json.RawMessage) *Error {
+	var params PlanParams
+	if err := decodeParams(raw, &params); err != nil {
+		return err
+	}
+	run := s.lookup(params.PlanID)
+	if run == nil {
+		return newError(CodeUnknownPlan, "plan %q is not running", params.PlanID)
+	}
+	if err := run.Pause(); err != nil {
+		return newError(CodeInternalError, "failed to pause: %v", err)
+	}
+	return nil
+}
+
+// stop interrupts a running plan. plan/finished follows once it stopped.
+func (s *Server) stop(raw json.RawMessage) *Error {
+	var params PlanParams
+	if err := decodeParams(raw, &params); err != nil {
+		return err
+	}
+	run := s.lookup(params.PlanID)
+	if run == nil {
+		return newError(CodeUnknownPlan, "plan %q is not running", params.PlanID)
+	}
+	run.cancel()
+	return nil
+}
+
+// stopAll interrupts every running plan and waits for them to stop.
+func (s *Server) stopAll() {
+	s.mu.Lock()
+	for _, run := range s.runs {
+		run.cancel()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// lookup returns the running plan with the ID, or nil.
+func (s *Server) lookup(planID string) *planRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.runs[planID]
+}
+
+// reply answers a request.
+func (s *Server) reply(id json.RawMessage, result any, rpcErr *Error) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	var msg any = response{JSONRPC: "2.0", ID: id, Result: result}
+	if rpcErr != nil {
+		msg = errorResponse{JSONRPC: "2.0", ID: id, Error: rpcErr}
+	}
+	if err := s.conn.Write(msg); err != nil {
+		log.Warn("failed to send response", "error", err)
+	}
+}
+
+// notify sends a notification.
+func (s *Server) notify(method string, params any) {
+	if err := s.conn.Write(notification{JSONRPC: "2.0", Method: method, Params: params}); err != nil {
+		log.Warn("failed to send notification", "method", method, "error", err)
+	}
+}
+
+// decodeParams decodes a request's params into v.
+func decodeParams(raw json.RawMessage, v any) *Error {
+	if len(raw) == 0 {
+		return newError(CodeInvalidParams, "missing params")
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return newError(CodeInvalidParams, "invalid params: %v", err)
+	}
+	return nil
+}
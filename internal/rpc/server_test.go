@@ -0,0 +1,415 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+// fakeRun is a plan that runs until stopped or finished.
+type fakeRun struct {
+	id       string
+	mu       sync.Mutex
+	feedback []string
+	finish   chan RunResult
+	done     chan struct{}
+	result   RunResult
+}
+
+func (r *fakeRun) PlanID() string { return r.id }
+
+func (r *fakeRun) SubmitFeedback(feedback string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.feedback = append(r.feedback, feedback)
+	return nil
+}
+
+// Pause finishes the run as paused.
+func (r *fakeRun) Pause() error {
+	select {
+	case r.finish <- RunResult{Iterations: 1}:
+	default:
+	}
+	return nil
+}
+
+func (r *fakeRun) Wait() RunResult {
+	<-r.done
+	return r.result
+}
+
+// fakeBackend starts fakeRuns and emits one event for each.
+type fakeBackend struct {
+	mu   sync.Mutex
+	runs []*fakeRun
+	err  error
+}
+
+func (b *fakeBackend) Start(ctx context.Context, params StartParams, onEvent func(string, loop.Event)) (Run, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	b.mu.Lock()
+	id := params.Resume
+	if id == "" {
+		id = fmt.Sprintf("plan-%d", len(b.runs)+1)
+	}
+	r := &fakeRun{id: id, finish: make(chan RunResult, 1), done: make(chan struct{})}
+	b.runs = append(b.runs, r)
+	b.mu.Unlock()
+
+	go func() {
+		defer close(r.done)
+		onEvent(id, loop.NewEvent(loop.EventIterationStart, 1, 5, "Starting iteration 1"))
+		select {
+		case r.result = <-r.finish:
+		case <-ctx.Done():
+			r.result = RunResult{Iterations: 1, Err: ctx.Err()}
+		}
+	}()
+	return r, nil
+}
+
+func (b *fakeBackend) run(i int) *fakeRun {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.runs[i]
+}
+
+// testClient talks to a server over pipes.
+type testClient struct {
+	t      *testing.T
+	conn   *Conn
+	nextID int
+	queued []message // Notifications read while waiting for a response
+	done   chan error
+	in     io.Closer
+}
+
+func newTestClient(t *testing.T, backend Backend) *testClient {
+	t.Helper()
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &testClient{t: t, conn: NewConn(clientIn, clientOut), done: make(chan error, 1), in: clientOut}
+
+	go func() {
+		err := NewServer(NewConn(serverIn, serverOut), backend).Serve(context.Background())
+		_ = serverOut.Close()
+		c.done <- err
+	}()
+	t.Cleanup(func() { _ = clientOut.Close() })
+	return c
+}
+
+// message is any message the server sends.
+type message struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+func (c *testClient) send(v any) {
+	c.t.Helper()
+	if err := c.conn.Write(v); err != nil {
+		c.t.Fatalf("failed to send: %v", err)
+	}
+}
+
+// read returns the next message, starting with queued notifications.
+func (c *testClient) read() message {
+	c.t.Helper()
+	if len(c.queued) > 0 {
+		msg := c.queued[0]
+		c.queued = c.queued[1:]
+		return msg
+	}
+	body, err := c.conn.Read()
+	if err != nil {
+		c.t.Fatalf("failed to read: %v", err)
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatalf("invalid message %s: %v", body, err)
+	}
+	return msg
+}
+
+// call sends a request and returns its response, queuing notifications
+// that arrive first.
+func (c *testClient) call(method string, params any) message {
+	c.t.Helper()
+	c.nextID++
+	c.send(map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+	return c.response()
+}
+
+// response reads up to the response to the last request.
+func (c *testClient) response() message {
+	c.t.Helper()
+	for {
+		body, err := c.conn.Read()
+		if err != nil {
+			c.t.Fatalf("failed to read: %v", err)
+		}
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			c.t.Fatalf("invalid message %s: %v", body, err)
+		}
+		if msg.ID == nil && msg.Method != "" {
+			c.queued = append(c.queued, msg)
+			continue
+		}
+		if msg.ID == nil || *msg.ID != c.nextID {
+			c.t.Fatalf("expected the response to request %d, got %+v", c.nextID, msg)
+		}
+		return msg
+	}
+}
+
+func (c *testClient) initialize() {
+	c.t.Helper()
+	if msg := c.call(MethodInitialize, InitializeParams{ProtocolVersion: ProtocolVersion}); msg.Error != nil {
+		c.t.Fatalf("initialize failed: %v", msg.Error)
+	}
+}
+
+func (c *testClient) wait() error {
+	c.t.Helper()
+	select {
+	case err := <-c.done:
+		return err
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("server did not stop")
+		return nil
+	}
+}
+
+func TestServer_Initialize(t *testing.T) {
+	c := newTestClient(t, &fakeBackend{})
+
+	msg := c.call(MethodStart, StartParams{Prompt: "p"})
+	if msg.Error == nil || msg.Error.Code != CodeNotInitialized {
+		t.Fatalf("expected CodeNotInitialized before initialize, got %+v", msg)
+	}
+
+	msg = c.call(MethodInitialize, InitializeParams{ProtocolVersion: 99})
+	if msg.Error == nil || msg.Error.Code != CodeUnsupportedVersion {
+		t.Fatalf("expected CodeUnsupportedVersion, got %+v", msg)
+	}
+
+	msg = c.call(MethodInitialize, InitializeParams{ProtocolVersion: ProtocolVersion, ClientName: "test"})
+	if msg.Error != nil {
+		t.Fatalf("initialize failed: %v", msg.Error)
+	}
+	var result InitializeResult
+	if err := json.Unmarshal(msg.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.ProtocolVersion != ProtocolVersion || len(result.Methods) == 0 {
+		t.Errorf("unexpected initialize result: %+v", result)
+	}
+
+	msg = c.call("plan/unknown", nil)
+	if msg.Error == nil || msg.Error.Code != CodeMethodNotFound {
+		t.Errorf("expected CodeMethodNotFound, got %+v", msg)
+	}
+
+	c.send(map[string]any{"jsonrpc": "2.0", "method": MethodExit})
+	if err := c.wait(); err != nil {
+		t.Errorf("Serve returned %v", err)
+	}
+}
+
+func TestServer_StartFeedbackStop(t *testing.T) {
+	backend := &fakeBackend{}
+	c := newTestClient(t, backend)
+	c.initialize()
+
+	msg := c.call(MethodStart, StartParams{PlanFile: "plan.md", Prompt: "p"})
+	if msg.Error == nil || msg.Error.Code != CodeInvalidParams {
+		t.Fatalf("expected CodeInvalidParams for two plan sources, got %+v", msg)
+	}
+
+	// The start response comes before the plan's events
+	msg = c.call(MethodStart, StartParams{Prompt: "Build it"})
+	if msg.Error != nil {
+		t.Fatalf("start failed: %v", msg.Error)
+	}
+	var started StartResult
+	if err := json.Unmarshal(msg.Result, &started); err != nil {
+		t.Fatal(err)
+	}
+	if started.PlanID != "plan-1" {
+		t.Fatalf("planId = %q, want plan-1", started.PlanID)
+	}
+	if len(c.queued) != 0 {
+		t.Errorf("notifications arrived before the start response: %+v", c.queued)
+	}
+
+	event := c.read()
+	if event.Method != NotifyEvent {
+		t.Fatalf("expected %s, got %+v", NotifyEvent, event)
+	}
+	var params EventParams
+	if err := json.Unmarshal(event.Params, &params); err != nil {
+		t.Fatal(err)
+	}
+	if params.PlanID != "plan-1" || params.Type != string(loop.EventIterationStart) || params.Iteration != 1 || params.MaxIterations != 5 {
+		t.Errorf("unexpected event: %+v", params)
+	}
+
+	if msg := c.call(MethodFeedback, FeedbackParams{PlanID: "plan-1", Feedback: "Use tabs"}); msg.Error != nil {
+		t.Fatalf("feedback failed: %v", msg.Error)
+	}
+	if got := backend.run(0).feedback; len(got) != 1 || got[0] != "Use tabs" {
+		t.Errorf("feedback = %v, want [Use tabs]", got)
+	}
+	if msg := c.call(MethodFeedback, FeedbackParams{PlanID: "nope", Feedback: "x"}); msg.Error == nil || msg.Error.Code != CodeUnknownPlan {
+		t.Errorf("expected CodeUnknownPlan, got %+v", msg)
+	}
+
+	if msg := c.call(MethodStop, PlanParams{PlanID: "plan-1"}); msg.Error != nil {
+		t.Fatalf("stop failed: %v", msg.Error)
+	}
+	finished := c.read()
+	if finished.Method != NotifyFinished {
+		t.Fatalf("expected %s, got %+v", NotifyFinished, finished)
+	}
+	var fin FinishedParams
+	if err := json.Unmarshal(finished.Params, &fin); err != nil {
+		t.Fatal(err)
+	}
+	if fin.PlanID != "plan-1" || fin.Completed || fin.Error == "" {
+		t.Errorf("unexpected finish: %+v", fin)
+	}
+
+	// A stopped plan can't take feedback
+	if msg := c.call(MethodFeedback, FeedbackParams{PlanID: "plan-1", Feedback: "x"}); msg.Error == nil || msg.Error.Code != CodeUnknownPlan {
+		t.Errorf("expected CodeUnknownPlan after stop, got %+v", msg)
+	}
+}
+
+func TestServer_Finished(t *testing.T) {
+	backend := &fakeBackend{}
+	c := newTestClient(t, backend)
+	c.initialize()
+
+	if msg := c.call(MethodStart, StartParams{Resume: "abc"}); msg.Error != nil {
+		t.Fatalf("start failed: %v", msg.Error)
+	}
+	if event := c.read(); event.Method != NotifyEvent {
+		t.Fatalf("expected %s, got %+v", NotifyEvent, event)
+	}
+	if msg := c.call(MethodStart, StartParams{Resume: "abc"}); msg.Error == nil || msg.Error.Code != CodeInvalidParams {
+		t.Errorf("expected resuming a running plan to fail, got %+v", msg)
+	}
+
+	backend.run(0).finish <- RunResult{Completed: true, Iterations: 3}
+	finished := c.read()
+	var fin FinishedParams
+	if err := json.Unmarshal(finished.Params, &fin); err != nil {
+		t.Fatal(err)
+	}
+	if finished.Method != NotifyFinished || fin.PlanID != "abc" || !fin.Completed || fin.Iterations != 3 || fin.Error != "" {
+		t.Errorf("unexpected finish: %s %+v", finished.Method, fin)
+	}
+}
+
+func TestServer_Pause(t *testing.T) {
+	backend := &fakeBackend{}
+	c := newTestClient(t, backend)
+	c.initialize()
+
+	if msg := c.call(MethodPause, PlanParams{PlanID: "abc"}); msg.Error == nil || msg.Error.Code != CodeUnknownPlan {
+		t.Errorf("expected CodeUnknownPlan, got %+v", msg)
+	}
+	if msg := c.call(MethodStart, StartParams{Resume: "abc"}); msg.Error != nil {
+		t.Fatalf("start failed: %v", msg.Error)
+	}
+	if event := c.read(); event.Method != NotifyEvent {
+		t.Fatalf("expected %s, got %+v", NotifyEvent, event)
+	}
+
+	if msg := c.call(MethodPause, PlanParams{PlanID: "abc"}); msg.Error != nil {
+		t.Fatalf("pause failed: %v", msg.Error)
+	}
+	finished := c.read()
+	var fin FinishedParams
+	if err := json.Unmarshal(finished.Params, &fin); err != nil {
+		t.Fatal(err)
+	}
+	if finished.Method != NotifyFinished || fin.PlanID != "abc" || fin.Completed || fin.Error != "" {
+		t.Errorf("unexpected finish: %s %+v", finished.Method, fin)
+	}
+}
+
+func TestServer_StartFailed(t *testing.T) {
+	c := newTestClient(t, &fakeBackend{err: errors.New("plan file not found")})
+	c.initialize()
+
+	msg := c.call(MethodStart, StartParams{PlanFile: "missing.md"})
+	if msg.Error == nil || msg.Error.Code != CodeStartFailed {
+		t.Errorf("expected CodeStartFailed, got %+v", msg)
+	}
+}
+
+func TestServer_ShutdownStopsPlans(t *testing.T) {
+	backend := &fakeBackend{}
+	c := newTestClient(t, backend)
+	c.initialize()
+
+	if msg := c.call(MethodStart, StartParams{Prompt: "p"}); msg.Error != nil {
+		t.Fatalf("start failed: %v", msg.Error)
+	}
+
+	// Shutdown answers once the plan stopped, after its notifications
+	if msg := c.call(MethodShutdown, nil); msg.Error != nil {
+		t.Fatalf("shutdown failed: %v", msg.Error)
+	}
+	var methods []string
+	for _, msg := range c.queued {
+		methods = append(methods, msg.Method)
+	}
+	c.queued = nil
+	if len(methods) != 2 || methods[0] != NotifyEvent || methods[1] != NotifyFinished {
+		t.Errorf("notifications before the shutdown response = %v, want event and finished", methods)
+	}
+
+	if msg := c.call(MethodStart, StartParams{Prompt: "p"}); msg.Error == nil || msg.Error.Code != CodeInvalidRequest {
+		t.Errorf("expected requests after shutdown to fail, got %+v", msg)
+	}
+
+	// Closing the connection ends the session
+	_ = c.in.Close()
+	if err := c.wait(); err != nil {
+		t.Errorf("Serve returned %v", err)
+	}
+}
+
+func TestServer_InvalidMessages(t *testing.T) {
+	c := newTestClient(t, &fakeBackend{})
+
+	if _, err := io.WriteString(c.conn.w, "Content-Length: 9\r\n\r\n{not json"); err != nil {
+		t.Fatal(err)
+	}
+	msg := c.read()
+	if msg.Error == nil || msg.Error.Code != CodeParseError {
+		t.Errorf("expected CodeParseError, got %+v", msg)
+	}
+
+	c.send(map[string]any{"id": 1, "method": MethodInitialize})
+	msg = c.read()
+	if msg.Error == nil || msg.Error.Code != CodeInvalidRequest {
+		t.Errorf("expected CodeInvalidRequest without jsonrpc 2.0, got %+v", msg)
+	}
+}
@@ -0,0 +1,122 @@
+// Package scrollback keeps a plain-text copy of a plan's TUI feed on disk,
+// so it can be read after the TUI exits.
+package scrollback
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// DefaultMaxBytes caps a scrollback file when no cap is configured.
+const DefaultMaxBytes = 10 << 20
+
+// Path returns the scrollback file of a plan: feeds/<plan-id>.log in the
+// project-local directory dir (e.g. <repo>/.ralph).
+func Path(dir, planID string) string {
+	return filepath.Join(dir, "feeds", planID+".log")
+}
+
+// Writer appends feed text to a scrollback file, stripped of ANSI escape
+// sequences. When the file grows past its cap, the oldest half is dropped
+// at a line boundary, so the file keeps the most recent output.
+type Writer struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	size     int64
+	maxBytes int64
+	failed   bool // A write failed and was logged; later ones are dropped
+}
+
+// Open opens a plan's scrollback file for appending, creating it and its
+// directory if needed (maxBytes <= 0 = DefaultMaxBytes). The directory
+// ignores its own contents, so jj and git don't track the files.
+func Open(path string, maxBytes int64) (*Writer, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create scrollback directory: %w", err)
+	}
+	ignore := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		if err := os.WriteFile(ignore, []byte("*\n"), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write scrollback ignore file: %w", err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open scrollback file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to stat scrollback file: %w", err)
+	}
+	return &Writer{path: path, file: f, size: info.Size(), maxBytes: maxBytes}, nil
+}
+
+// Write appends p with ANSI escape sequences removed. It always reports p
+// as written: a failing scrollback is logged once and must not disturb the
+// TUI.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failed {
+		return len(p), nil
+	}
+
+	text := ansi.Strip(string(p))
+	n, err := w.file.WriteString(text)
+	w.size += int64(n)
+	if err == nil && w.size > w.maxBytes {
+		err = w.trim()
+	}
+	if err != nil {
+		w.failed = true
+		log.Warn("failed to write feed scrollback, disabling it", "path", w.path, "error", err)
+	}
+	return len(p), nil
+}
+
+// trim rewrites the file with its newest half, starting at a line.
+func (w *Writer) trim() error {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return err
+	}
+	keep := data[len(data)-int(w.maxBytes/2):]
+	if i := bytes.IndexByte(keep, '\n'); i >= 0 {
+		keep = keep[i+1:]
+	}
+
+	tmp := w.path + ".tmp"
+	if err := os.WriteFile(tmp, keep, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, w.path); err != nil {
+		return err
+	}
+	_ = w.file.Close()
+	w.file, err = os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	w.size = int64(len(keep))
+	return nil
+}
+
+// Close closes the file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
@@ -0,0 +1,71 @@
+package scrollback
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriter_StripsANSI(t *testing.T) {
+	path := Path(t.TempDir(), "plan-1")
+	w, err := Open(path, 0)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if _, err := w.Write([]byte("\x1b[1;38;2;169;220;118m✓ Done\x1b[0m\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening appends
+	w, err = Open(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte("again\n"))
+	_ = w.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "✓ Done\nagain\n" {
+		t.Errorf("scrollback = %q", data)
+	}
+	if filepath.Base(filepath.Dir(path)) != "feeds" {
+		t.Errorf("Path() = %s, want a file under feeds/", path)
+	}
+}
+
+func TestWriter_Cap(t *testing.T) {
+	path := Path(t.TempDir(), "plan-1")
+	w, err := Open(path, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = w.Close() }()
+
+	for i := range 30 {
+		_, _ = w.Write([]byte(strings.Repeat("x", 8) + string(rune('a'+i%26)) + "\n"))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) > 100 {
+		t.Errorf("scrollback is %d bytes, want at most 100", len(data))
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	for _, line := range lines {
+		if len(line) != 9 {
+			t.Fatalf("expected whole lines only, got %q", data)
+		}
+	}
+	if lines[len(lines)-1] != "xxxxxxxxd" {
+		t.Errorf("expected the newest line last, got %q", lines[len(lines)-1])
+	}
+}
@@ -0,0 +1,526 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+// changesFile is the change log in the store.
+const changesFile = "changes.json"
+
+// change is an entry of the change log, jj's changes without the graph:
+// each change follows the one before it.
+type change struct {
+	ID          string `json:"id"`
+	Description string `json:"description,omitempty"`
+	// Parent is the snapshot the change started from
+	Parent string `json:"parent"`
+	// Tree is the snapshot the change ended with, empty for the current
+	// change, whose tree is the directory as it is
+	Tree string `json:"tree,omitempty"`
+}
+
+// Client tracks the changes of a directory with snapshots, standing in for
+// the jj client where there is no repository. Its methods mirror the jj
+// client's. Revisions are "@" (the directory as it is now), "@-" (the
+// snapshot the current change started from), change IDs, and snapshot IDs.
+type Client struct {
+	dir   string
+	store store
+	mu    sync.Mutex
+}
+
+// NewClient creates a client for dir that keeps its snapshots and change
+// log in storeDir.
+func NewClient(dir, storeDir string) *Client {
+	return &Client{dir: dir, store: store{dir: dir, path: storeDir}}
+}
+
+// readLog reads the change log, starting it with a change on the current
+// snapshot on first use.
+func (c *Client) readLog() ([]change, error) {
+	data, err := os.ReadFile(filepath.Join(c.store.path, changesFile))
+	if err == nil {
+		var changes []change
+		if err := json.Unmarshal(data, &changes); err != nil {
+			return nil, fmt.Errorf("invalid change log: %w", err)
+		}
+		if len(changes) > 0 {
+			return changes, nil
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read change log: %w", err)
+	}
+
+	snapshot, _, err := c.store.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	id, err := newChangeID()
+	if err != nil {
+		return nil, err
+	}
+	changes := []change{{ID: id, Parent: snapshot}}
+	return changes, c.writeLog(changes)
+}
+
+// writeLog replaces the change log.
+func (c *Client) writeLog(changes []change) error {
+	data, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.store.path, 0o755); err != nil {
+		return fmt.Errorf("failed to write change log: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(c.store.path, changesFile), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write change log: %w", err)
+	}
+	return nil
+}
+
+// resolve returns the snapshot ID of a revision, and its tree.
+func (c *Client) resolve(changes []change, revision string) (string, tree, error) {
+	current := changes[len(changes)-1]
+	id := revision
+	switch revision {
+	case "@", current.ID:
+		return c.store.snapshot()
+	case "@-":
+		id = current.Parent
+	default:
+		for _, ch := range changes[:len(changes)-1] {
+			if ch.ID == revision {
+				id = ch.Tree
+			}
+		}
+	}
+	if !c.store.hasTree(id) {
+		return "", nil, fmt.Errorf("revision %q doesn't exist", revision)
+	}
+	t, err := c.store.tree(id)
+	return id, t, err
+}
+
+// trees resolves the revisions of a diff, defaulting from to "@-" and to
+// to "@".
+func (c *Client) trees(from, to string) (tree, tree, error) {
+	if from == "" {
+		from = "@-"
+	}
+	if to == "" {
+		to = "@"
+	}
+	changes, err := c.readLog()
+	if err != nil {
+		return nil, nil, err
+	}
+	_, a, err := c.resolve(changes, from)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, b, err := c.resolve(changes, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, b, nil
+}
+
+// Show returns the diff of the current change.
+func (c *Client) Show(ctx context.Context) (string, error) {
+	return c.Diff(ctx, "@-", "@")
+}
+
+// Status lists the files the current change modifies.
+func (c *Client) Status(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	a, b, err := c.trees("@-", "@")
+	if err != nil {
+		return "", err
+	}
+	paths := changedPaths(a, b)
+	if len(paths) == 0 {
+		return "The working copy has no changes.\n", nil
+	}
+
+	var out strings.Builder
+	out.WriteString("Working copy changes:\n")
+	for _, path := range paths {
+		status := "M"
+		if _, ok := a[path]; !ok {
+			status = "A"
+		} else if _, ok := b[path]; !ok {
+			status = "D"
+		}
+		fmt.Fprintf(&out, "%s %s\n", status, path)
+	}
+	return out.String(), nil
+}
+
+// Conflicts returns nil: changes follow one another, so they never
+// conflict.
+func (c *Client) Conflicts(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+// IsEmpty returns true if the current change has no file modifications.
+func (c *Client) IsEmpty(ctx context.Context) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changes, err := c.readLog()
+	if err != nil {
+		return false, err
+	}
+	snapshot, _, err := c.store.snapshot()
+	if err != nil {
+		return false, err
+	}
+	return snapshot == changes[len(changes)-1].Parent, nil
+}
+
+// Diff returns the diff between two revisions, in git's format.
+// If from is empty, it diffs from the parent of the current change.
+// If to is empty, it defaults to "@" (current change).
+// With paths, only changes under those paths are included.
+func (c *Client) Diff(ctx context.Context, from, to string, paths ...string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	a, b, err := c.trees(from, to)
+	if err != nil {
+		return "", err
+	}
+	return c.store.diff(a.under(paths), b.under(paths))
+}
+
+// GitDiff returns the diff between two revisions in git's unified format,
+// the same as Diff.
+func (c *Client) GitDiff(ctx context.Context, from, to string, paths ...string) (string, error) {
+	return c.Diff(ctx, from, to, paths...)
+}
+
+// ChangedFiles returns the paths of files modified between two revisions.
+// Arguments follow the same defaults as Diff.
+func (c *Client) ChangedFiles(ctx context.Context, from, to string, paths ...string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	a, b, err := c.trees(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return changedPaths(a.under(paths), b.under(paths)), nil
+}
+
+// New ends the current change at the directory's current snapshot and
+// starts a new empty one with the given description.
+func (c *Client) New(ctx context.Context, message string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changes, err := c.readLog()
+	if err != nil {
+		return err
+	}
+	snapshot, _, err := c.store.snapshot()
+	if err != nil {
+		return err
+	}
+	id, err := newChangeID()
+	if err != nil {
+		return err
+	}
+	changes[len(changes)-1].Tree = snapshot
+	changes = append(changes, change{ID: id, Description: message, Parent: snapshot})
+	return c.writeLog(changes)
+}
+
+// Describe sets the description of the current change.
+func (c *Client) Describe(ctx context.Context, message string) error {
+	return c.describe("@", message)
+}
+
+// describe sets the description of the given change.
+func (c *Client) describe(revision, message string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changes, err := c.readLog()
+	if err != nil {
+		return err
+	}
+	i, err := changeIndex(changes, revision)
+	if err != nil {
+		return err
+	}
+	changes[i].Description = message
+	return c.writeLog(changes)
+}
+
+// GetDescription returns the full description of the given change.
+func (c *Client) GetDescription(ctx context.Context, revision string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changes, err := c.readLog()
+	if err != nil {
+		return "", err
+	}
+	i, err := changeIndex(changes, revision)
+	if err != nil {
+		return "", err
+	}
+	return changes[i].Description, nil
+}
+
+// AddTrailers appends trailers to the description of the given change,
+// replacing existing trailers with the same keys.
+func (c *Client) AddTrailers(ctx context.Context, revision string, trailers []jj.Trailer) error {
+	description, err := c.GetDescription(ctx, revision)
+	if err != nil {
+		return err
+	}
+	return c.describe(revision, jj.AppendTrailers(description, trailers))
+}
+
+// GetCurrentChangeID returns the ID of the current change.
+func (c *Client) GetCurrentChangeID(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changes, err := c.readLog()
+	if err != nil {
+		return "", err
+	}
+	return changes[len(changes)-1].ID, nil
+}
+
+// GetCurrentCommitID snapshots the directory and returns the snapshot's ID,
+// which pins the exact state of the current change, as a jj commit ID does.
+func (c *Client) GetCurrentCommitID(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snapshot, _, err := c.store.snapshot()
+	return snapshot, err
+}
+
+// GetParentChangeID returns the ID of the snapshot the current change
+// started from (@-).
+func (c *Client) GetParentChangeID(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changes, err := c.readLog()
+	if err != nil {
+		return "", err
+	}
+	return changes[len(changes)-1].Parent, nil
+}
+
+// Root returns the directory.
+func (c *Client) Root(ctx context.Context) (string, error) {
+	return filepath.Abs(c.dir)
+}
+
+// CheckPatch reports whether a unified diff applies cleanly to the
+// directory, without changing any files.
+func (c *Client) CheckPatch(ctx context.Context, patch string) error {
+	return c.gitApply(ctx, patch, "--check")
+}
+
+// ApplyPatch applies a unified diff to the directory with `git apply`,
+// which works outside git repositories. Nothing is changed if any hunk
+// fails to apply.
+func (c *Client) ApplyPatch(ctx context.Context, patch string) error {
+	return c.gitApply(ctx, patch)
+}
+
+// gitApply runs `git apply` in the directory on patch, which is passed
+// through a temporary file.
+func (c *Client) gitApply(ctx context.Context, patch string, flags ...string) error {
+	f, err := os.CreateTemp("", "ralph-patch-*.diff")
+	if err != nil {
+		return fmt.Errorf("failed to create patch file: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.WriteString(patch); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write patch file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write patch file: %w", err)
+	}
+
+	args := append([]string{"apply", "--whitespace=nowarn"}, flags...)
+	cmd := exec.CommandContext(ctx, "git", append(args, f.Name())...)
+	cmd.Dir = c.dir
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var execErr *exec.Error
+		if errors.As(err, &execErr) && errors.Is(execErr.Err, exec.ErrNotFound) {
+			return errors.New("git command not found (needed to apply patches)")
+		}
+		return fmt.Errorf("git apply failed: %s: %w", strings.TrimSpace(stderr.String()), err)
+	}
+	return nil
+}
+
+// Restore replaces the directory's contents with those of the given
+// revision, keeping the current change itself. With paths, only those
+// files are restored.
+func (c *Client) Restore(ctx context.Context, from string, paths ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changes, err := c.readLog()
+	if err != nil {
+		return err
+	}
+	_, target, err := c.resolve(changes, from)
+	if err != nil {
+		return err
+	}
+	_, current, err := c.store.snapshot()
+	if err != nil {
+		return err
+	}
+	if len(paths) > 0 {
+		target = current.with(target, paths)
+	}
+	if err := c.store.restore(current, target); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", from, err)
+	}
+	return nil
+}
+
+// ChangeIDs returns the IDs of the changes in a revset, newest first. Only
+// "<revision>..@-" is supported: the changes after revision, up to the
+// current change's parent. A snapshot ID as revision names the last change
+// that started from it.
+func (c *Client) ChangeIDs(ctx context.Context, revset string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changes, err := c.readLog()
+	if err != nil {
+		return nil, err
+	}
+	start, err := rangeStart(changes, revset)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for i := len(changes) - 2; i >= start; i-- {
+		ids = append(ids, changes[i].ID)
+	}
+	return ids, nil
+}
+
+// Squash moves the changes of the revisions in the from revset into the
+// current change and sets its description to message. into must be "@".
+func (c *Client) Squash(ctx context.Context, from, into, message string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changes, err := c.readLog()
+	if err != nil {
+		return err
+	}
+	if into != "@" && into != changes[len(changes)-1].ID {
+		return fmt.Errorf("can only squash into the current change, not %q", into)
+	}
+	start, err := rangeStart(changes, from)
+	if err != nil {
+		return err
+	}
+	start = min(start, len(changes)-1)
+
+	current := changes[len(changes)-1]
+	current.Description = message
+	if start < len(changes)-1 {
+		current.Parent = changes[start].Parent
+	}
+	return c.writeLog(append(changes[:start:start], current))
+}
+
+// Abandon abandons the current change, restoring the snapshot it started
+// from and starting a new empty change there. Only "@" is supported.
+func (c *Client) Abandon(ctx context.Context, revision string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changes, err := c.readLog()
+	if err != nil {
+		return err
+	}
+	current := &changes[len(changes)-1]
+	if revision != "@" && revision != current.ID {
+		return fmt.Errorf("can only abandon the current change, not %q", revision)
+	}
+
+	_, now, err := c.store.snapshot()
+	if err != nil {
+		return err
+	}
+	parent, err := c.store.tree(current.Parent)
+	if err != nil {
+		return err
+	}
+	if err := c.store.restore(now, parent); err != nil {
+		return fmt.Errorf("failed to abandon change: %w", err)
+	}
+
+	id, err := newChangeID()
+	if err != nil {
+		return err
+	}
+	*current = change{ID: id, Parent: current.Parent}
+	return c.writeLog(changes)
+}
+
+// changeIndex returns the index in the log of a change ID or "@".
+func changeIndex(changes []change, revision string) (int, error) {
+	if revision == "@" {
+		return len(changes) - 1, nil
+	}
+	for i, ch := range changes {
+		if ch.ID == revision {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("change %q doesn't exist", revision)
+}
+
+// rangeStart returns the index of the first change of a "<revision>..@-"
+// revset.
+func rangeStart(changes []change, revset string) (int, error) {
+	revision, ok := strings.CutSuffix(revset, "..@-")
+	if !ok {
+		return 0, fmt.Errorf("unsupported revset %q", revset)
+	}
+	for i := len(changes) - 1; i >= 0; i-- {
+		switch revision {
+		case changes[i].ID:
+			return i + 1, nil
+		case changes[i].Parent:
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("revision %q doesn't exist", revision)
+}
@@ -0,0 +1,257 @@
+package snapshot
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newTestClient returns a client for a fresh directory holding the given
+// files.
+func newTestClient(t *testing.T, files map[string]string) (*Client, string) {
+	t.Helper()
+	dir := t.TempDir()
+	for path, content := range files {
+		writeFile(t, dir, path, content)
+	}
+	return NewClient(dir, filepath.Join(t.TempDir(), "store")), dir
+}
+
+func writeFile(t *testing.T, dir, path, content string) {
+	t.Helper()
+	full := filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_DiffAndChangedFiles(t *testing.T) {
+	ctx := context.Background()
+	c, dir := newTestClient(t, map[string]string{"keep.txt": "same\n", "edit.txt": "old\n", "gone.txt": "bye\n"})
+
+	empty, err := c.IsEmpty(ctx)
+	if err != nil || !empty {
+		t.Fatalf("IsEmpty() = %v, %v; want true before any edits", empty, err)
+	}
+	base, err := c.GetParentChangeID(ctx)
+	if err != nil || base == "" {
+		t.Fatalf("GetParentChangeID() = %q, %v", base, err)
+	}
+
+	writeFile(t, dir, "edit.txt", "new\n")
+	writeFile(t, dir, "sub/added.txt", "hello\n")
+	if err := os.Remove(filepath.Join(dir, "gone.txt")); err != nil {
+		t.Fatal(err)
+	}
+	// Version control metadata and ralph's feed scrollback are ignored
+	writeFile(t, dir, ".git/HEAD", "ref\n")
+	writeFile(t, dir, ".ralph/feeds/plan.log", "feed\n")
+
+	files, err := c.ChangedFiles(ctx, base, "@")
+	if err != nil {
+		t.Fatalf("ChangedFiles() error: %v", err)
+	}
+	if want := []string{"edit.txt", "gone.txt", "sub/added.txt"}; !slices.Equal(files, want) {
+		t.Errorf("ChangedFiles() = %v, want %v", files, want)
+	}
+
+	diff, err := c.GitDiff(ctx, base, "@")
+	if err != nil {
+		t.Fatalf("GitDiff() error: %v", err)
+	}
+	for _, want := range []string{
+		"diff --git a/edit.txt b/edit.txt\n--- a/edit.txt\n+++ b/edit.txt\n@@ -1 +1 @@\n-old\n+new\n",
+		"diff --git a/gone.txt b/gone.txt\ndeleted file mode 100644\n--- a/gone.txt\n+++ /dev/null\n",
+		"diff --git a/sub/added.txt b/sub/added.txt\nnew file mode 100644\n--- /dev/null\n+++ b/sub/added.txt\n",
+	} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("expected %q in diff:\n%s", want, diff)
+		}
+	}
+	if strings.Contains(diff, "keep.txt") || strings.Contains(diff, ".git") {
+		t.Errorf("diff includes unchanged or ignored files:\n%s", diff)
+	}
+
+	// With paths, only the changes under them
+	files, err = c.ChangedFiles(ctx, base, "@", "sub/")
+	if err != nil || !slices.Equal(files, []string{"sub/added.txt"}) {
+		t.Errorf("ChangedFiles(sub/) = %v, %v; want [sub/added.txt]", files, err)
+	}
+	diff, err = c.Diff(ctx, base, "@", "sub")
+	if err != nil || strings.Contains(diff, "edit.txt") || !strings.Contains(diff, "sub/added.txt") {
+		t.Errorf("Diff(sub) = %q, %v; want only sub/added.txt", diff, err)
+	}
+
+	status, err := c.Status(ctx)
+	if err != nil || !strings.Contains(status, "A sub/added.txt\n") || !strings.Contains(status, "D gone.txt\n") {
+		t.Errorf("Status() = %q, %v", status, err)
+	}
+}
+
+func TestClient_SnapshotPinsState(t *testing.T) {
+	ctx := context.Background()
+	c, dir := newTestClient(t, map[string]string{"a.txt": "1\n"})
+
+	snapshot, err := c.GetCurrentCommitID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := c.ChangedFiles(ctx, snapshot, "@")
+	if err != nil || len(files) != 0 {
+		t.Fatalf("ChangedFiles() = %v, %v; want none right after the snapshot", files, err)
+	}
+
+	writeFile(t, dir, "a.txt", "2\n")
+	files, err = c.ChangedFiles(ctx, snapshot, "@")
+	if err != nil || !slices.Equal(files, []string{"a.txt"}) {
+		t.Errorf("ChangedFiles() = %v, %v; want [a.txt]", files, err)
+	}
+	if _, err := c.Diff(ctx, "missing", "@"); err == nil {
+		t.Error("expected an error for an unknown revision")
+	}
+}
+
+func TestClient_ChangesSquashAndAbandon(t *testing.T) {
+	ctx := context.Background()
+	c, dir := newTestClient(t, map[string]string{"a.txt": "1\n"})
+
+	base, err := c.GetParentChangeID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "a.txt", "2\n")
+	if err := c.New(ctx, "second"); err != nil {
+		t.Fatal(err)
+	}
+	first, err := c.GetCurrentChangeID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "b.txt", "b\n")
+	if err := c.New(ctx, "third"); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := c.ChangeIDs(ctx, base+"..@-")
+	if err != nil || len(ids) != 2 || ids[0] != first {
+		t.Fatalf("ChangeIDs() = %v, %v; want two changes, newest %s", ids, err, first)
+	}
+
+	// An abandoned change's edits are undone
+	writeFile(t, dir, "c.txt", "c\n")
+	if err := c.Abandon(ctx, "@"); err != nil {
+		t.Fatalf("Abandon() error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "c.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected c.txt removed by Abandon, got: %v", err)
+	}
+
+	if err := c.Squash(ctx, base+"..@-", "@", "Squashed"); err != nil {
+		t.Fatalf("Squash() error: %v", err)
+	}
+	if ids, err := c.ChangeIDs(ctx, base+"..@-"); err != nil || len(ids) != 0 {
+		t.Errorf("ChangeIDs() after Squash = %v, %v; want none", ids, err)
+	}
+	files, err := c.ChangedFiles(ctx, "@-", "@")
+	if err != nil || !slices.Equal(files, []string{"a.txt", "b.txt"}) {
+		t.Errorf("ChangedFiles() after Squash = %v, %v; want every plan edit", files, err)
+	}
+	if description, err := c.GetDescription(ctx, "@"); err != nil || description != "Squashed" {
+		t.Errorf("GetDescription() = %q, %v", description, err)
+	}
+
+	// The log survives a new client
+	reopened := NewClient(dir, c.store.path)
+	if got, err := reopened.GetParentChangeID(ctx); err != nil || got != base {
+		t.Errorf("GetParentChangeID() after reopening = %q, %v; want %s", got, err, base)
+	}
+}
+
+func TestClient_Restore(t *testing.T) {
+	ctx := context.Background()
+	c, dir := newTestClient(t, map[string]string{"a.txt": "1\n"})
+
+	snapshot, err := c.GetCurrentCommitID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "a.txt", "2\n")
+	writeFile(t, dir, "b.txt", "b\n")
+
+	if err := c.Restore(ctx, snapshot); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "a.txt")); err != nil || string(data) != "1\n" {
+		t.Errorf("a.txt = %q, %v; want the snapshot's content", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected b.txt removed by Restore, got: %v", err)
+	}
+}
+
+func TestClient_RestorePaths(t *testing.T) {
+	ctx := context.Background()
+	c, dir := newTestClient(t, map[string]string{"a.txt": "1\n", "c.txt": "c\n"})
+
+	snapshot, err := c.GetCurrentCommitID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "a.txt", "2\n")
+	writeFile(t, dir, "b.txt", "b\n")
+	writeFile(t, dir, "c.txt", "changed\n")
+
+	if err := c.Restore(ctx, snapshot, "a.txt", "b.txt"); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "a.txt")); err != nil || string(data) != "1\n" {
+		t.Errorf("a.txt = %q, %v; want the snapshot's content", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected b.txt removed by Restore, got: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "c.txt")); err != nil || string(data) != "changed\n" {
+		t.Errorf("c.txt = %q, %v; want it left alone", data, err)
+	}
+}
+
+func TestClient_ApplyPatch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	c, dir := newTestClient(t, map[string]string{"a.txt": "one\ntwo\n"})
+
+	base, err := c.GetCurrentCommitID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "a.txt", "one\n2\n")
+	writeFile(t, dir, "new.txt", "new\n")
+	patch, err := c.GitDiff(ctx, base, "@")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Restore(ctx, base); err != nil {
+		t.Fatal(err)
+	}
+
+	// The snapshot diff applies back onto the original tree
+	if err := c.CheckPatch(ctx, patch); err != nil {
+		t.Fatalf("CheckPatch() error: %v\n%s", err, patch)
+	}
+	if err := c.ApplyPatch(ctx, patch); err != nil {
+		t.Fatalf("ApplyPatch() error: %v", err)
+	}
+	files, err := c.ChangedFiles(ctx, base, "@")
+	if err != nil || !slices.Equal(files, []string{"a.txt", "new.txt"}) {
+		t.Errorf("ChangedFiles() after ApplyPatch = %v, %v", files, err)
+	}
+}
@@ -0,0 +1,228 @@
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines around each hunk.
+const contextLines = 3
+
+// binarySniffLen is how much of a file is checked for NUL bytes to decide
+// it is binary, as git does.
+const binarySniffLen = 8000
+
+// opKind is an edit in a line diff.
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// op is one line of a line diff.
+type op struct {
+	kind opKind
+	line string
+}
+
+// splitLines splits content into lines, each keeping its newline. The last
+// line lacks one if the content doesn't end in a newline.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// isBinary reports whether content looks binary.
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), binarySniffLen)], 0) >= 0
+}
+
+// diffLines returns the edits turning a into b.
+func diffLines(a, b []string) []op {
+	// Only the lines between the common prefix and suffix need diffing
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []op
+	for _, line := range a[:prefix] {
+		ops = append(ops, op{opEqual, line})
+	}
+	ops = append(ops, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, op{opEqual, line})
+	}
+	return ops
+}
+
+// maxEdits bounds the edit distance myers searches, and with it the time
+// and memory a diff takes; beyond it the lines are replaced wholesale.
+const maxEdits = 2000
+
+// myers returns the edits turning a into b, with Myers' algorithm.
+func myers(a, b []string) []op {
+	n, m := len(a), len(b)
+	limit := min(n+m, maxEdits)
+	offset := n + m + 1
+	v := make([]int, 2*(n+m)+3)
+	// The part of v each step read, for backtracking
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+
+	var ops []op
+	for _, line := range a {
+		ops = append(ops, op{opDelete, line})
+	}
+	for _, line := range b {
+		ops = append(ops, op{opInsert, line})
+	}
+	return ops
+}
+
+// backtrack walks the Myers trace back from the end to build the edits.
+// Step d of the trace holds v for diagonals -d-1 through d+1.
+func backtrack(trace [][]int, a, b []string) []op {
+	var ops []op
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := func(k int) int { return trace[d][k+d+1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v(k-1) < v(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{opEqual, a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				ops = append(ops, op{opInsert, b[y]})
+			} else {
+				x--
+				ops = append(ops, op{opDelete, a[x]})
+			}
+		}
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// unifiedHunks formats the edits turning a into b as unified diff hunks.
+func unifiedHunks(a, b []string) string {
+	ops := diffLines(a, b)
+
+	var out strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change
+		for start < len(ops) && ops[start].kind == opEqual {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// Extend the hunk while changes are within two contexts of each other
+		first := max(start-contextLines, 0)
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != opEqual {
+				end = i + 1
+			} else if i-end >= 2*contextLines {
+				break
+			}
+		}
+		last := min(end+contextLines, len(ops))
+
+		// Line numbers of the hunk in a and b
+		oldStart, newStart := 1, 1
+		for _, o := range ops[:first] {
+			if o.kind != opInsert {
+				oldStart++
+			}
+			if o.kind != opDelete {
+				newStart++
+			}
+		}
+		oldLines, newLines := 0, 0
+		for _, o := range ops[first:last] {
+			if o.kind != opInsert {
+				oldLines++
+			}
+			if o.kind != opDelete {
+				newLines++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldLines), hunkRange(newStart, newLines))
+
+		for _, o := range ops[first:last] {
+			prefix := " "
+			switch o.kind {
+			case opDelete:
+				prefix = "-"
+			case opInsert:
+				prefix = "+"
+			}
+			out.WriteString(prefix + o.line)
+			if !strings.HasSuffix(o.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = last
+	}
+	return out.String()
+}
+
+// hunkRange formats a hunk's start and length as in a "@@" line.
+func hunkRange(start, lines int) string {
+	if lines == 0 {
+		// An empty range names the line before it
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if lines == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
@@ -0,0 +1,96 @@
+package snapshot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedHunks(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "identical",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name: "replace and append without newline",
+			a:    "a\nb\nc\n",
+			b:    "a\nB\nc\nd",
+			want: "@@ -1,3 +1,4 @@\n a\n-b\n+B\n c\n+d\n\\ No newline at end of file\n",
+		},
+		{
+			name: "new file",
+			a:    "",
+			b:    "x\ny\n",
+			want: "@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+		{
+			name: "deleted file",
+			a:    "x\n",
+			b:    "",
+			want: "@@ -1 +0,0 @@\n-x\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unifiedHunks(splitLines([]byte(tt.a)), splitLines([]byte(tt.b)))
+			if got != tt.want {
+				t.Errorf("unifiedHunks() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedHunks_SeparateHunks(t *testing.T) {
+	var a, b []string
+	for i := 1; i <= 20; i++ {
+		a = append(a, fmt.Sprintf("line %d\n", i))
+		b = append(b, fmt.Sprintf("line %d\n", i))
+	}
+	b[1] = "changed 2\n"
+	b[17] = "changed 18\n"
+
+	got := unifiedHunks(a, b)
+	if strings.Count(got, "@@ -") != 2 {
+		t.Fatalf("expected two hunks, got:\n%s", got)
+	}
+	for _, want := range []string{"@@ -1,5 +1,5 @@\n", "@@ -15,6 +15,6 @@\n", "-line 18\n+changed 18\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestDiffLines_RoundTrip(t *testing.T) {
+	a := splitLines([]byte("one\ntwo\nthree\nfour\nfive\nsix\n"))
+	b := splitLines([]byte("zero\none\nthree\n4\nfive\nsix\nseven\n"))
+
+	var gotA, gotB []string
+	for _, o := range diffLines(a, b) {
+		if o.kind != opInsert {
+			gotA = append(gotA, o.line)
+		}
+		if o.kind != opDelete {
+			gotB = append(gotB, o.line)
+		}
+	}
+	if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+		t.Errorf("edits don't rebuild the inputs: %q, %q", gotA, gotB)
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	if isBinary([]byte("plain text\n")) {
+		t.Error("expected text not to be binary")
+	}
+	if !isBinary([]byte{'P', 'N', 'G', 0, 1}) {
+		t.Error("expected content with NUL bytes to be binary")
+	}
+}
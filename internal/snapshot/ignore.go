@@ -0,0 +1,149 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreFile holds a directory's ignore rules, in the format jj reads them
+// from as well as git.
+const ignoreFile = ".gitignore"
+
+// ignoreRule is a pattern of an ignore file.
+type ignoreRule struct {
+	base    string // Directory of the ignore file, relative and slash-separated ("" = the root)
+	pattern *regexp.Regexp
+	negate  bool // A "!pattern", which re-includes what it matches
+	dirOnly bool // A "pattern/", which matches only directories
+}
+
+// ignorer matches paths against the rules of the ignore files found so
+// far. As with git and jj, the last matching rule wins, so a nested ignore
+// file's rules override those of the directories above it.
+type ignorer struct {
+	rules []ignoreRule
+}
+
+// load adds the rules of the ignore file in dir, whose path relative to the
+// snapshotted directory is rel ("." for the root). A missing or unreadable
+// file has none.
+func (ig *ignorer) load(dir, rel string) {
+	data, err := os.ReadFile(filepath.Join(dir, ignoreFile))
+	if err != nil {
+		return
+	}
+	base := filepath.ToSlash(rel)
+	if base == "." {
+		base = ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(base, scanner.Text()); ok {
+			ig.rules = append(ig.rules, rule)
+		}
+	}
+}
+
+// ignored reports whether the path, relative and slash-separated, is
+// ignored.
+func (ig *ignorer) ignored(path string, isDir bool) bool {
+	ignored := false
+	for _, rule := range ig.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		rel := path
+		if rule.base != "" {
+			var ok bool
+			if rel, ok = strings.CutPrefix(path, rule.base+"/"); !ok {
+				continue
+			}
+		}
+		if rule.pattern.MatchString(rel) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// parseIgnoreRule parses a line of an ignore file. Blank lines and comments
+// aren't rules.
+func parseIgnoreRule(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(strings.TrimSuffix(line, "\r"), " ")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if rest, ok := strings.CutPrefix(line, "!"); ok {
+		rule.negate, line = true, rest
+	} else if strings.HasPrefix(line, `\`) {
+		// An escaped leading "#" or "!"
+		line = line[1:]
+	}
+	if rest, ok := strings.CutSuffix(line, "/"); ok {
+		rule.dirOnly, line = true, rest
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	// A pattern with a slash other than a trailing one is relative to the
+	// ignore file's directory; one without matches a name at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	prefix := "^(?:.*/)?"
+	if anchored {
+		prefix = "^"
+	}
+	pattern, err := regexp.Compile(prefix + globRegexp(line) + "$")
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.pattern = pattern
+	return rule, true
+}
+
+// globRegexp translates an ignore pattern to a regular expression: "*" and
+// "?" match within a path component, "[...]" matches a character class, and
+// "**" matches across components.
+func globRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if rest, ok := strings.CutPrefix(class, "!"); ok {
+				class = "^" + rest
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package snapshot
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestIgnorer(t *testing.T) {
+	var ig ignorer
+	for _, line := range []string{"# build output", "*.log", "!keep.log", "/bin/", "docs/**/*.tmp", "cache/", `\#notes`} {
+		if rule, ok := parseIgnoreRule("", line); ok {
+			ig.rules = append(ig.rules, rule)
+		}
+	}
+	if rule, ok := parseIgnoreRule("sub", "local"); ok {
+		ig.rules = append(ig.rules, rule)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"deep/nested/app.log", false, true},
+		{"keep.log", false, false},
+		{"bin", true, true},
+		{"bin", false, false},
+		{"src/bin", true, false},
+		{"docs/a/b/x.tmp", false, true},
+		{"docs/x.tmp", false, true},
+		{"x.tmp", false, false},
+		{"src/cache", true, true},
+		{"#notes", false, true},
+		{"sub/local", false, true},
+		{"local", false, false},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := ig.ignored(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestClient_SnapshotSkipsIgnored(t *testing.T) {
+	ctx := context.Background()
+	c, dir := newTestClient(t, map[string]string{
+		".gitignore":     "node_modules/\n*.log\n",
+		"sub/.gitignore": "!important.log\n",
+		"main.go":        "package main\n",
+	})
+	base, err := c.GetParentChangeID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, dir, "node_modules/dep/index.js", "x\n")
+	writeFile(t, dir, "debug.log", "x\n")
+	writeFile(t, dir, "sub/important.log", "x\n")
+	writeFile(t, dir, "main.go", "package main\n\nfunc main() {}\n")
+
+	files, err := c.ChangedFiles(ctx, base, "@")
+	if err != nil {
+		t.Fatalf("ChangedFiles() error: %v", err)
+	}
+	if want := []string{"main.go", "sub/important.log"}; !slices.Equal(files, want) {
+		t.Errorf("ChangedFiles() = %v, want %v", files, want)
+	}
+}
@@ -0,0 +1,373 @@
+// Package snapshot tracks the changes of a plain directory, for running
+// plans in workspaces without jj or git. It stores content-addressed
+// snapshots of the tree and a log of changes, and answers what the loop
+// otherwise asks jj: what changed since a snapshot, the diff, and whether
+// the current change is empty.
+package snapshot
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Entry modes in a tree, as git names them in diffs.
+const (
+	modeFile = "100644"
+	modeExec = "100755"
+	modeLink = "120000"
+)
+
+// skippedDirs are never snapshotted: version control metadata, should the
+// directory gain some.
+var skippedDirs = map[string]bool{".git": true, ".jj": true}
+
+// skippedPaths are directories, relative to the snapshotted one, holding
+// ralph's own output, which is written while the plan runs.
+var skippedPaths = map[string]bool{".ralph/feeds": true}
+
+// entry is a file of a tree.
+type entry struct {
+	mode string
+	hash string // Object holding the content (a symlink's target)
+}
+
+// tree maps slash-separated paths, relative to the directory, to entries.
+type tree map[string]entry
+
+// store holds a directory's snapshots: file contents in objects/, and trees
+// in trees/, both named by the SHA-256 of their content.
+type store struct {
+	dir  string // Directory snapshotted
+	path string // Directory holding the store
+}
+
+// snapshot stores the directory's current tree and returns its ID. Files
+// and directories ignored by .gitignore files are left out, as jj leaves
+// them untracked.
+func (s *store) snapshot() (string, tree, error) {
+	t := tree{}
+	storePath, _ := filepath.Abs(s.path)
+	var ig ignorer
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if abs, _ := filepath.Abs(path); skippedDirs[d.Name()] || skippedPaths[filepath.ToSlash(rel)] || abs == storePath {
+				return filepath.SkipDir
+			}
+			if rel != "." && ig.ignored(filepath.ToSlash(rel), true) {
+				return filepath.SkipDir
+			}
+			ig.load(path, rel)
+			return nil
+		}
+		if ig.ignored(filepath.ToSlash(rel), false) {
+			return nil
+		}
+
+		var content []byte
+		var mode string
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			content, mode = []byte(target), modeLink
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if content, err = os.ReadFile(path); err != nil {
+				return err
+			}
+			mode = modeFile
+			if info.Mode()&0111 != 0 {
+				mode = modeExec
+			}
+		default:
+			return nil
+		}
+
+		hash, err := s.writeObject("objects", content)
+		if err != nil {
+			return err
+		}
+		t[filepath.ToSlash(rel)] = entry{mode: mode, hash: hash}
+		return nil
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to snapshot %s: %w", s.dir, err)
+	}
+
+	id, err := s.writeObject("trees", t.manifest())
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to store snapshot: %w", err)
+	}
+	return id, t, nil
+}
+
+// manifest serializes the tree, one "mode hash path" line per file in path
+// order.
+func (t tree) manifest() []byte {
+	var b strings.Builder
+	for _, path := range t.paths() {
+		fmt.Fprintf(&b, "%s %s %s\n", t[path].mode, t[path].hash, path)
+	}
+	return []byte(b.String())
+}
+
+// paths returns the tree's paths in order.
+func (t tree) paths() []string {
+	paths := make([]string, 0, len(t))
+	for path := range t {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// under returns the part of the tree under the given paths: the files they
+// name and the files in the directories they name. With no paths, it
+// returns the tree itself.
+func (t tree) under(paths []string) tree {
+	if len(paths) == 0 {
+		return t
+	}
+	sub := tree{}
+	for path, e := range t {
+		for _, p := range paths {
+			p = strings.TrimSuffix(filepath.ToSlash(p), "/")
+			if path == p || strings.HasPrefix(path, p+"/") {
+				sub[path] = e
+				break
+			}
+		}
+	}
+	return sub
+}
+
+// with returns a copy of the tree with the given paths taken from other:
+// replaced where other has them, and removed where it doesn't.
+func (t tree) with(other tree, paths []string) tree {
+	merged := make(tree, len(t))
+	for path, e := range t {
+		merged[path] = e
+	}
+	for _, path := range paths {
+		if e, ok := other[path]; ok {
+			merged[path] = e
+		} else {
+			delete(merged, path)
+		}
+	}
+	return merged
+}
+
+// hasTree reports whether id names a stored tree.
+func (s *store) hasTree(id string) bool {
+	if len(id) != sha256.Size*2 {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(s.path, "trees", id))
+	return err == nil
+}
+
+// tree loads a stored tree.
+func (s *store) tree(id string) (tree, error) {
+	data, err := s.readObject("trees", id)
+	if err != nil {
+		return nil, err
+	}
+	t := tree{}
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("corrupt snapshot %s", id)
+		}
+		t[fields[2]] = entry{mode: fields[0], hash: fields[1]}
+	}
+	return t, nil
+}
+
+// content returns the stored content of an entry, or nil for a missing one.
+func (s *store) content(e entry, ok bool) ([]byte, error) {
+	if !ok {
+		return nil, nil
+	}
+	return s.readObject("objects", e.hash)
+}
+
+// writeObject stores data in kind (objects or trees) under its hash,
+// unless it is already there, and returns the hash.
+func (s *store) writeObject(kind string, data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	path := filepath.Join(s.path, kind, hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+
+	// Write then rename, so an interrupted write leaves no partial object
+	f, err := os.CreateTemp(filepath.Dir(path), hash+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return hash, nil
+}
+
+// readObject reads a stored object.
+func (s *store) readObject(kind, hash string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.path, kind, hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("snapshot object %s not found", hash)
+	}
+	return data, err
+}
+
+// restore makes the directory's tree match target: files missing from it
+// are removed, and changed or missing ones written.
+func (s *store) restore(current, target tree) error {
+	for _, path := range current.paths() {
+		if _, ok := target[path]; !ok {
+			if err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(path))); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	for _, path := range target.paths() {
+		e := target[path]
+		if old, ok := current[path]; ok && old == e {
+			continue
+		}
+		content, err := s.readObject("objects", e.hash)
+		if err != nil {
+			return err
+		}
+		full := filepath.Join(s.dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			return err
+		}
+		if err := os.Remove(full); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		switch e.mode {
+		case modeLink:
+			err = os.Symlink(string(content), full)
+		case modeExec:
+			err = os.WriteFile(full, content, 0o755)
+		default:
+			err = os.WriteFile(full, content, 0o644)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diff returns the git-format diff turning tree a into tree b.
+func (s *store) diff(a, b tree) (string, error) {
+	var out strings.Builder
+	for _, path := range changedPaths(a, b) {
+		oldEntry, inOld := a[path]
+		newEntry, inNew := b[path]
+
+		fmt.Fprintf(&out, "diff --git a/%s b/%s\n", path, path)
+		switch {
+		case !inOld:
+			fmt.Fprintf(&out, "new file mode %s\n", newEntry.mode)
+		case !inNew:
+			fmt.Fprintf(&out, "deleted file mode %s\n", oldEntry.mode)
+		case oldEntry.mode != newEntry.mode:
+			fmt.Fprintf(&out, "old mode %s\nnew mode %s\n", oldEntry.mode, newEntry.mode)
+		}
+		if inOld && inNew && oldEntry.hash == newEntry.hash {
+			continue
+		}
+
+		oldContent, err := s.content(oldEntry, inOld)
+		if err != nil {
+			return "", err
+		}
+		newContent, err := s.content(newEntry, inNew)
+		if err != nil {
+			return "", err
+		}
+		oldName, newName := "a/"+path, "b/"+path
+		if !inOld {
+			oldName = "/dev/null"
+		}
+		if !inNew {
+			newName = "/dev/null"
+		}
+		if isBinary(oldContent) || isBinary(newContent) {
+			fmt.Fprintf(&out, "Binary files %s and %s differ\n", oldName, newName)
+			continue
+		}
+		fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+		out.WriteString(unifiedHunks(splitLines(oldContent), splitLines(newContent)))
+	}
+	return out.String(), nil
+}
+
+// changedPaths returns the paths whose entries differ between two trees,
+// in order.
+func changedPaths(a, b tree) []string {
+	var paths []string
+	for _, path := range a.paths() {
+		if e, ok := b[path]; !ok || e != a[path] {
+			paths = append(paths, path)
+		}
+	}
+	for _, path := range b.paths() {
+		if _, ok := a[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// newChangeID returns a random change ID, shorter than snapshot IDs so the
+// two never collide.
+func newChangeID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,223 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/frontmatter"
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+// Default templates, used for the templates left empty.
+const (
+	DefaultTitleTemplate   = `ralph: {{.Title}}`
+	DefaultBodyTemplate    = "Tracking issue for ralph plan `{{.PlanID}}`.\n\n{{.Plan}}"
+	DefaultCommentTemplate = "**Iteration {{.Iteration}}** finished in {{.Duration}}.\n\n{{.Progress}}"
+	DefaultCloseTemplate   = "Plan completed after {{.Iteration}} iteration(s)."
+)
+
+// maxTitleLength bounds a plan's title as derived from its first line.
+const maxTitleLength = 100
+
+// Templates are the text/templates issue titles, bodies, and comments are
+// rendered from (empty = the default).
+type Templates struct {
+	Title   string // Title of the tracking issue
+	Body    string // Description of the tracking issue
+	Comment string // Comment summarizing an iteration
+	Close   string // Comment posted when the plan completes, before closing
+}
+
+// Update is the data available to templates.
+type Update struct {
+	PlanID    string
+	Title     string // The plan's first header or line
+	Plan      string // The plan's content
+	Iteration int
+	Duration  time.Duration // How long the iteration took (comments only)
+	Progress  string        // The developer's latest progress
+	Message   string        // The loop event's message
+}
+
+// Issues is the tracker the syncer keeps an issue in. *Client implements it.
+type Issues interface {
+	Provider() string
+	CreateIssue(ctx context.Context, title, body string) (Issue, error)
+	Comment(ctx context.Context, key, body string) error
+	Close(ctx context.Context, key string) error
+}
+
+// Store records the issue that follows a plan and provides the plan's
+// progress. *db.DB implements it.
+type Store interface {
+	GetPlanIssue(planID string) (*db.PlanIssue, error)
+	CreatePlanIssue(issue *db.PlanIssue) error
+	GetLatestProgress(planID string) (*db.Progress, error)
+}
+
+// Syncer keeps a plan's tracking issue in step with the loop's events.
+type Syncer struct {
+	issues Issues
+	store  Store
+	plan   *db.Plan
+
+	title, body, comment, closing *template.Template
+}
+
+// NewSyncer creates a syncer for a plan, parsing its templates.
+func NewSyncer(issues Issues, store Store, plan *db.Plan, templates Templates) (*Syncer, error) {
+	s := &Syncer{issues: issues, store: store, plan: plan}
+	for _, t := range []struct {
+		name string
+		text string
+		def  string
+		dst  **template.Template
+	}{
+		{"title", templates.Title, DefaultTitleTemplate, &s.title},
+		{"body", templates.Body, DefaultBodyTemplate, &s.body},
+		{"comment", templates.Comment, DefaultCommentTemplate, &s.comment},
+		{"close", templates.Close, DefaultCloseTemplate, &s.closing},
+	} {
+		text := t.text
+		if text == "" {
+			text = t.def
+		}
+		tmpl, err := template.New(t.name).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tracker %s template: %w", t.name, err)
+		}
+		*t.dst = tmpl
+	}
+	return s, nil
+}
+
+// EventTypes returns the loop event types the syncer acts on, for use as a
+// subscription filter.
+func (s *Syncer) EventTypes() []loop.EventType {
+	return []loop.EventType{loop.EventStarted, loop.EventIterationEnd, loop.EventDone}
+}
+
+// Sync acts on a loop event: the plan starting opens its tracking issue
+// (unless an earlier run already did), an iteration ending is summarized in
+// a comment, and the plan completing closes the issue.
+func (s *Syncer) Sync(ctx context.Context, event loop.Event) error {
+	issue, err := s.store.GetPlanIssue(s.plan.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get plan issue: %w", err)
+	}
+
+	if event.Type == loop.EventStarted {
+		if issue != nil {
+			return nil
+		}
+		return s.open(ctx, event)
+	}
+	if issue == nil {
+		return nil
+	}
+
+	update, err := s.update(event)
+	if err != nil {
+		return err
+	}
+	switch event.Type {
+	case loop.EventIterationEnd:
+		return s.post(ctx, issue.Key, s.comment, update)
+	case loop.EventDone:
+		if err := s.post(ctx, issue.Key, s.closing, update); err != nil {
+			return err
+		}
+		return s.issues.Close(ctx, issue.Key)
+	}
+	return nil
+}
+
+// open creates the plan's tracking issue and records it.
+func (s *Syncer) open(ctx context.Context, event loop.Event) error {
+	update, err := s.update(event)
+	if err != nil {
+		return err
+	}
+	title, err := render(s.title, update)
+	if err != nil {
+		return err
+	}
+	body, err := render(s.body, update)
+	if err != nil {
+		return err
+	}
+
+	created, err := s.issues.CreateIssue(ctx, strings.TrimSpace(title), body)
+	if err != nil {
+		return err
+	}
+	return s.store.CreatePlanIssue(&db.PlanIssue{
+		PlanID:   s.plan.ID,
+		Provider: s.issues.Provider(),
+		Key:      created.Key,
+		URL:      created.URL,
+	})
+}
+
+// post renders a comment and adds it to the issue, skipping empty ones.
+func (s *Syncer) post(ctx context.Context, key string, tmpl *template.Template, update Update) error {
+	text, err := render(tmpl, update)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	return s.issues.Comment(ctx, key, strings.TrimSpace(text))
+}
+
+// update builds the template data for an event.
+func (s *Syncer) update(event loop.Event) (Update, error) {
+	update := Update{
+		PlanID:    s.plan.ID,
+		Title:     planTitle(s.plan.Content),
+		Plan:      s.plan.Content,
+		Iteration: event.Iteration,
+		Duration:  event.Duration.Round(time.Second),
+		Message:   event.Message,
+	}
+	progress, err := s.store.GetLatestProgress(s.plan.ID)
+	if err != nil {
+		return update, fmt.Errorf("failed to get progress: %w", err)
+	}
+	if progress != nil {
+		update.Progress = progress.Content
+	}
+	return update, nil
+}
+
+// render executes a template.
+func render(tmpl *template.Template, update Update) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, update); err != nil {
+		return "", fmt.Errorf("failed to render tracker %s template: %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+// planTitle returns a plan's first non-empty line, after any front matter,
+// without its header #s.
+func planTitle(content string) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for _, line := range lines[frontmatter.Len(content):] {
+		title := strings.TrimSpace(strings.TrimLeft(line, "#"))
+		if title == "" {
+			continue
+		}
+		if runes := []rune(title); len(runes) > maxTitleLength {
+			title = string(runes[:maxTitleLength-3]) + "..."
+		}
+		return title
+	}
+	return "Untitled plan"
+}
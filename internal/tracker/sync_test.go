@@ -0,0 +1,148 @@
+package tracker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+// fakeIssues records what the syncer does to issues.
+type fakeIssues struct {
+	created  []string // Titles
+	bodies   []string
+	comments []string
+	closed   []string
+}
+
+func (f *fakeIssues) Provider() string { return ProviderGitHub }
+
+func (f *fakeIssues) CreateIssue(ctx context.Context, title, body string) (Issue, error) {
+	f.created = append(f.created, title)
+	f.bodies = append(f.bodies, body)
+	return Issue{Key: "42", URL: "https://github.com/acme/app/issues/42"}, nil
+}
+
+func (f *fakeIssues) Comment(ctx context.Context, key, body string) error {
+	f.comments = append(f.comments, key+": "+body)
+	return nil
+}
+
+func (f *fakeIssues) Close(ctx context.Context, key string) error {
+	f.closed = append(f.closed, key)
+	return nil
+}
+
+// fakeStore keeps a plan's issue and progress in memory.
+type fakeStore struct {
+	issue    *db.PlanIssue
+	progress string
+}
+
+func (f *fakeStore) GetPlanIssue(planID string) (*db.PlanIssue, error) { return f.issue, nil }
+
+func (f *fakeStore) CreatePlanIssue(issue *db.PlanIssue) error {
+	f.issue = issue
+	return nil
+}
+
+func (f *fakeStore) GetLatestProgress(planID string) (*db.Progress, error) {
+	if f.progress == "" {
+		return nil, nil
+	}
+	return &db.Progress{Content: f.progress}, nil
+}
+
+func TestSyncer_Lifecycle(t *testing.T) {
+	issues := &fakeIssues{}
+	store := &fakeStore{}
+	plan := &db.Plan{ID: "plan-1", Content: "---\nenv:\n  A: b\n---\n# Add a parser\n\nDetails."}
+	syncer, err := NewSyncer(issues, store, plan, Templates{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	sync := func(event loop.Event) {
+		t.Helper()
+		if err := syncer.Sync(ctx, event); err != nil {
+			t.Fatalf("Sync(%s) error: %v", event.Type, err)
+		}
+	}
+
+	sync(loop.NewEvent(loop.EventStarted, 0, 10, "Loop started"))
+	if len(issues.created) != 1 || issues.created[0] != "ralph: Add a parser" || !strings.Contains(issues.bodies[0], "`plan-1`") {
+		t.Fatalf("created = %q, bodies = %q", issues.created, issues.bodies)
+	}
+	if store.issue == nil || store.issue.Key != "42" || store.issue.Provider != ProviderGitHub {
+		t.Fatalf("recorded issue = %+v", store.issue)
+	}
+
+	store.progress = "Wrote the lexer"
+	end := loop.NewEvent(loop.EventIterationEnd, 1, 10, "iteration 1 complete")
+	end.Duration = 90 * time.Second
+	sync(end)
+	if len(issues.comments) != 1 || issues.comments[0] != "42: **Iteration 1** finished in 1m30s.\n\nWrote the lexer" {
+		t.Errorf("comments = %q", issues.comments)
+	}
+
+	// A resumed plan keeps its issue
+	sync(loop.NewEvent(loop.EventStarted, 1, 10, "Loop started"))
+	if len(issues.created) != 1 {
+		t.Errorf("expected no second issue, created %q", issues.created)
+	}
+
+	sync(loop.NewEvent(loop.EventDone, 2, 10, "Plan completed"))
+	if len(issues.comments) != 2 || issues.comments[1] != "42: Plan completed after 2 iteration(s)." {
+		t.Errorf("comments = %q", issues.comments)
+	}
+	if len(issues.closed) != 1 || issues.closed[0] != "42" {
+		t.Errorf("closed = %q", issues.closed)
+	}
+}
+
+func TestSyncer_Templates(t *testing.T) {
+	issues := &fakeIssues{}
+	store := &fakeStore{issue: &db.PlanIssue{PlanID: "plan-1", Key: "7"}}
+	syncer, err := NewSyncer(issues, store, &db.Plan{ID: "plan-1", Content: "Fix it"}, Templates{
+		Comment: "{{if .Progress}}{{.Progress}}{{end}}",
+		Close:   "Done: {{.Message}}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	// Empty comments aren't posted
+	if err := syncer.Sync(ctx, loop.NewEvent(loop.EventIterationEnd, 1, 10, "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := syncer.Sync(ctx, loop.NewEvent(loop.EventDone, 1, 10, "Plan completed")); err != nil {
+		t.Fatal(err)
+	}
+	if len(issues.comments) != 1 || issues.comments[0] != "7: Done: Plan completed" {
+		t.Errorf("comments = %q", issues.comments)
+	}
+
+	if _, err := NewSyncer(issues, store, &db.Plan{}, Templates{Title: "{{"}); err == nil || !strings.Contains(err.Error(), "title template") {
+		t.Errorf("expected a template error, got %v", err)
+	}
+}
+
+func TestPlanTitle(t *testing.T) {
+	tests := map[string]string{
+		"# Add a parser\nbody":        "Add a parser",
+		"\n\nFix the login bug\n":     "Fix the login bug",
+		"":                            "Untitled plan",
+		strings.Repeat("x", 150):      strings.Repeat("x", 97) + "...",
+		"---\nfiles: [a]\n---\n## Do": "Do",
+	}
+	for content, want := range tests {
+		if got := planTitle(content); got != want {
+			t.Errorf("planTitle(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
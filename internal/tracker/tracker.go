@@ -0,0 +1,227 @@
+// Package tracker keeps an issue in an issue tracker (GitHub Issues, Jira) in
+// step with a plan: it opens a tracking issue when the plan starts, comments
+// a summary of each iteration, and closes the issue when the plan completes.
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Issue trackers.
+const (
+	ProviderGitHub = "github"
+	ProviderJira   = "jira"
+)
+
+// DefaultGitHubAPIURL is the GitHub API used when no API URL is configured.
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// DefaultJiraIssueType is the type of the Jira issues created when none is
+// configured.
+const DefaultJiraIssueType = "Task"
+
+// Error types for tracker operations.
+var (
+	ErrUnsupportedProvider = errors.New("unsupported tracker provider")
+	ErrMissingToken        = errors.New("tracker token is empty")
+	ErrMissingRepo         = errors.New("tracker repository is empty")
+	ErrMissingProject      = errors.New("tracker project is empty")
+	ErrMissingAPIURL       = errors.New("tracker API URL is empty")
+)
+
+// Config holds the settings for a tracker client.
+type Config struct {
+	Provider  string // "github" or "jira"
+	Repo      string // GitHub "owner/name"
+	Project   string // Jira project key
+	IssueType string // Jira issue type (empty = DefaultJiraIssueType)
+	APIURL    string // API base URL; the site URL on Jira (empty = api.github.com on GitHub)
+	User      string // Jira account email for basic auth (empty = bearer token)
+	Token     string // API token sent with every request
+}
+
+// Issue is an issue opened in a tracker.
+type Issue struct {
+	Key string // "42" on GitHub, "PROJ-42" on Jira
+	URL string // Web URL of the issue
+}
+
+// Client opens, comments on, and closes issues through a tracker's REST API.
+type Client struct {
+	cfg        Config
+	httpClient *http.Client
+}
+
+// NewClient creates a tracker client, validating the configuration.
+func NewClient(cfg Config) (*Client, error) {
+	switch cfg.Provider {
+	case ProviderGitHub:
+		if cfg.APIURL == "" {
+			cfg.APIURL = DefaultGitHubAPIURL
+		}
+		if cfg.Repo == "" {
+			return nil, ErrMissingRepo
+		}
+	case ProviderJira:
+		if cfg.APIURL == "" {
+			return nil, ErrMissingAPIURL
+		}
+		if cfg.Project == "" {
+			return nil, ErrMissingProject
+		}
+		if cfg.IssueType == "" {
+			cfg.IssueType = DefaultJiraIssueType
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedProvider, cfg.Provider)
+	}
+	if cfg.Token == "" {
+		return nil, ErrMissingToken
+	}
+	cfg.APIURL = strings.TrimRight(cfg.APIURL, "/")
+
+	return &Client{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// SetHTTPClient allows setting a custom HTTP client (for testing).
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.httpClient = httpClient
+}
+
+// Provider returns the tracker the client talks to.
+func (c *Client) Provider() string {
+	return c.cfg.Provider
+}
+
+// CreateIssue opens an issue and returns it.
+func (c *Client) CreateIssue(ctx context.Context, title, body string) (Issue, error) {
+	if c.cfg.Provider == ProviderGitHub {
+		var created struct {
+			Number  int    `json:"number"`
+			HTMLURL string `json:"html_url"`
+		}
+		err := c.do(ctx, http.MethodPost, "/repos/"+c.cfg.Repo+"/issues",
+			map[string]any{"title": title, "body": body}, &created)
+		if err != nil {
+			return Issue{}, err
+		}
+		return Issue{Key: strconv.Itoa(created.Number), URL: created.HTMLURL}, nil
+	}
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	err := c.do(ctx, http.MethodPost, "/rest/api/2/issue", map[string]any{
+		"fields": map[string]any{
+			"project":     map[string]string{"key": c.cfg.Project},
+			"issuetype":   map[string]string{"name": c.cfg.IssueType},
+			"summary":     title,
+			"description": body,
+		},
+	}, &created)
+	if err != nil {
+		return Issue{}, err
+	}
+	return Issue{Key: created.Key, URL: c.cfg.APIURL + "/browse/" + created.Key}, nil
+}
+
+// Comment adds a comment to an issue.
+func (c *Client) Comment(ctx context.Context, key, body string) error {
+	if c.cfg.Provider == ProviderGitHub {
+		return c.do(ctx, http.MethodPost, "/repos/"+c.cfg.Repo+"/issues/"+key+"/comments",
+			map[string]any{"body": body}, nil)
+	}
+	return c.do(ctx, http.MethodPost, "/rest/api/2/issue/"+key+"/comment",
+		map[string]any{"body": body}, nil)
+}
+
+// Close closes an issue as completed. On Jira, the issue is moved with the
+// first transition to a status in the "done" category.
+func (c *Client) Close(ctx context.Context, key string) error {
+	if c.cfg.Provider == ProviderGitHub {
+		return c.do(ctx, http.MethodPatch, "/repos/"+c.cfg.Repo+"/issues/"+key,
+			map[string]any{"state": "closed", "state_reason": "completed"}, nil)
+	}
+
+	var available struct {
+		Transitions []struct {
+			ID string `json:"id"`
+			To struct {
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/rest/api/2/issue/"+key+"/transitions", nil, &available); err != nil {
+		return err
+	}
+	for _, t := range available.Transitions {
+		if t.To.StatusCategory.Key == "done" {
+			return c.do(ctx, http.MethodPost, "/rest/api/2/issue/"+key+"/transitions",
+				map[string]any{"transition": map[string]string{"id": t.ID}}, nil)
+		}
+	}
+	return fmt.Errorf("jira issue %s has no transition to a done status", key)
+}
+
+// do sends a request to the tracker's API, decoding the response into out
+// unless it is nil.
+func (c *Client) do(ctx context.Context, method, path string, payload any, out any) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.cfg.APIURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	switch {
+	case c.cfg.Provider == ProviderGitHub:
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	case c.cfg.User != "":
+		req.SetBasicAuth(c.cfg.User, c.cfg.Token)
+	default:
+		req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", c.cfg.Provider, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", c.cfg.Provider, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s: %s", c.cfg.Provider, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", c.cfg.Provider, err)
+	}
+	return nil
+}
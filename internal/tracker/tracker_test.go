@@ -0,0 +1,145 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewClient_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr error
+	}{
+		{"unknown provider", Config{Provider: "linear", Token: "t"}, ErrUnsupportedProvider},
+		{"missing token", Config{Provider: ProviderGitHub, Repo: "a/b"}, ErrMissingToken},
+		{"missing repo", Config{Provider: ProviderGitHub, Token: "t"}, ErrMissingRepo},
+		{"missing jira URL", Config{Provider: ProviderJira, Project: "P", Token: "t"}, ErrMissingAPIURL},
+		{"missing jira project", Config{Provider: ProviderJira, APIURL: "https://x.atlassian.net", Token: "t"}, ErrMissingProject},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewClient(tt.cfg); !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewClient() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// recordedRequest is a request a fake tracker received.
+type recordedRequest struct {
+	Method string
+	Path   string
+	Auth   string
+	Body   map[string]any
+}
+
+// newFakeTracker serves canned responses by "METHOD path", recording the
+// requests it receives.
+func newFakeTracker(t *testing.T, responses map[string]string) (*httptest.Server, *[]recordedRequest) {
+	t.Helper()
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := recordedRequest{Method: r.Method, Path: r.URL.Path, Auth: r.Header.Get("Authorization")}
+		_ = json.NewDecoder(r.Body).Decode(&req.Body)
+		requests = append(requests, req)
+
+		response, ok := responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestClient_GitHub(t *testing.T) {
+	server, requests := newFakeTracker(t, map[string]string{
+		"POST /repos/acme/app/issues":             `{"number":42,"html_url":"https://github.com/acme/app/issues/42"}`,
+		"POST /repos/acme/app/issues/42/comments": `{}`,
+		"PATCH /repos/acme/app/issues/42":         `{}`,
+	})
+	client, err := NewClient(Config{Provider: ProviderGitHub, Repo: "acme/app", Token: "secret", APIURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	issue, err := client.CreateIssue(ctx, "Add parser", "Body")
+	if err != nil {
+		t.Fatalf("CreateIssue() error = %v", err)
+	}
+	if issue.Key != "42" || issue.URL != "https://github.com/acme/app/issues/42" {
+		t.Errorf("CreateIssue() = %+v", issue)
+	}
+	if err := client.Comment(ctx, "42", "Iteration 1"); err != nil {
+		t.Fatalf("Comment() error = %v", err)
+	}
+	if err := client.Close(ctx, "42"); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	got := *requests
+	if len(got) != 3 || got[0].Auth != "Bearer secret" || got[0].Body["title"] != "Add parser" ||
+		got[1].Body["body"] != "Iteration 1" || got[2].Body["state"] != "closed" {
+		t.Errorf("unexpected requests: %+v", got)
+	}
+}
+
+func TestClient_Jira(t *testing.T) {
+	server, requests := newFakeTracker(t, map[string]string{
+		"POST /rest/api/2/issue":                    `{"key":"PROJ-7"}`,
+		"POST /rest/api/2/issue/PROJ-7/comment":     `{}`,
+		"GET /rest/api/2/issue/PROJ-7/transitions":  `{"transitions":[{"id":"11","to":{"statusCategory":{"key":"indeterminate"}}},{"id":"31","to":{"statusCategory":{"key":"done"}}}]}`,
+		"POST /rest/api/2/issue/PROJ-7/transitions": `{}`,
+	})
+	client, err := NewClient(Config{Provider: ProviderJira, Project: "PROJ", APIURL: server.URL + "/", User: "me@example.com", Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	issue, err := client.CreateIssue(ctx, "Add parser", "Body")
+	if err != nil {
+		t.Fatalf("CreateIssue() error = %v", err)
+	}
+	if issue.Key != "PROJ-7" || issue.URL != server.URL+"/browse/PROJ-7" {
+		t.Errorf("CreateIssue() = %+v", issue)
+	}
+	if err := client.Comment(ctx, "PROJ-7", "Iteration 1"); err != nil {
+		t.Fatalf("Comment() error = %v", err)
+	}
+	if err := client.Close(ctx, "PROJ-7"); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	got := *requests
+	if len(got) != 4 || !strings.HasPrefix(got[0].Auth, "Basic ") {
+		t.Fatalf("unexpected requests: %+v", got)
+	}
+	fields, _ := got[0].Body["fields"].(map[string]any)
+	if fields["summary"] != "Add parser" || fields["issuetype"].(map[string]any)["name"] != DefaultJiraIssueType {
+		t.Errorf("unexpected issue fields: %+v", fields)
+	}
+	if transition, _ := got[3].Body["transition"].(map[string]any); transition["id"] != "31" {
+		t.Errorf("expected the done transition, got %+v", got[3].Body)
+	}
+}
+
+func TestClient_Error(t *testing.T) {
+	server, _ := newFakeTracker(t, nil)
+	client, err := NewClient(Config{Provider: ProviderGitHub, Repo: "acme/app", Token: "secret", APIURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Comment(context.Background(), "1", "x"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Comment() error = %v, want a 404", err)
+	}
+}
@@ -0,0 +1,248 @@
+// Package triage classifies the change an iteration made, so the review of
+// a trivial one (whitespace, comments, files that don't need review) can be
+// skipped or done by a cheaper reviewer.
+package triage
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/gerunddev/ralph/internal/policy"
+)
+
+// DefaultMaxLines is the default size above which a change is never
+// trivial.
+const DefaultMaxLines = 20
+
+// Rules decide which changes are trivial. A change is trivial when it is
+// within MaxLines and every file it touches is trivial: the file matches a
+// TrivialPaths glob, or its edits only change whitespace or comments.
+type Rules struct {
+	MaxLines     int      // Changed lines above which a change is never trivial (0 = no limit)
+	TrivialPaths []string // Repo-relative globs of files whose changes are always trivial, e.g. "*.md"
+}
+
+// Result is the classification of a change.
+type Result struct {
+	Trivial bool
+	Reason  string // Why the change is, or is not, trivial
+	Lines   int    // Added and removed lines
+	Files   int    // Files touched
+}
+
+// Kinds of trivial file changes, in the order they are reported.
+var kinds = []string{"whitespace-only", "comment-only", "trivial-path"}
+
+// commentPrefixes maps file extensions to the prefixes of comment lines.
+var commentPrefixes = func() map[string][]string {
+	prefixes := map[string][]string{}
+	for _, ext := range []string{".go", ".c", ".h", ".cc", ".cpp", ".hpp", ".java", ".js", ".jsx", ".ts", ".tsx",
+		".rs", ".swift", ".kt", ".cs", ".scala", ".proto", ".dart", ".php"} {
+		prefixes[ext] = []string{"//", "/*", "*/", "* "}
+	}
+	for _, ext := range []string{".py", ".sh", ".bash", ".rb", ".pl", ".r", ".yaml", ".yml", ".toml", ".mk"} {
+		prefixes[ext] = []string{"#"}
+	}
+	for _, ext := range []string{".sql", ".lua", ".hs"} {
+		prefixes[ext] = []string{"--"}
+	}
+	for _, ext := range []string{".html", ".xml", ".svg"} {
+		prefixes[ext] = []string{"<!--", "-->"}
+	}
+	return prefixes
+}()
+
+// directivePrefixes maps file extensions to the prefixes of lines that look
+// like comments but are read by the toolchain, so changing them is a code
+// change.
+var directivePrefixes = map[string][]string{
+	".go": {"//go:", "// +build"},
+}
+
+// significantWhitespace reports whether whitespace changes the meaning of
+// the file: indentation in Python and YAML, tabs in Makefiles.
+func significantWhitespace(file string) bool {
+	switch strings.ToLower(path.Ext(file)) {
+	case ".py", ".yaml", ".yml", ".mk":
+		return true
+	}
+	switch path.Base(file) {
+	case "Makefile", "makefile", "GNUmakefile":
+		return true
+	}
+	return false
+}
+
+// fileChange is one file's part of a git-format diff.
+type fileChange struct {
+	path           string
+	structural     bool // Added, deleted, renamed, binary, or a mode change
+	added, removed []string
+}
+
+// Classify classifies a git-format diff by the rules. An empty diff is not
+// trivial: there is nothing to skip a review for.
+func Classify(diff string, rules Rules) Result {
+	files := parse(diff)
+	result := Result{Files: len(files)}
+	for _, f := range files {
+		result.Lines += len(f.added) + len(f.removed)
+	}
+
+	switch {
+	case len(files) == 0:
+		result.Reason = "no changes"
+		return result
+	case rules.MaxLines > 0 && result.Lines > rules.MaxLines:
+		result.Reason = fmt.Sprintf("%d changed lines exceed the limit of %d", result.Lines, rules.MaxLines)
+		return result
+	}
+
+	found := map[string]bool{}
+	for _, f := range files {
+		kind := f.kind(rules)
+		if kind == "" {
+			result.Reason = fmt.Sprintf("%s has substantive changes", f.path)
+			return result
+		}
+		found[kind] = true
+	}
+
+	var names []string
+	for _, kind := range kinds {
+		if found[kind] {
+			names = append(names, kind)
+		}
+	}
+	result.Trivial = true
+	result.Reason = fmt.Sprintf("%s changes (%d lines in %d files)", strings.Join(names, ", "), result.Lines, result.Files)
+	return result
+}
+
+// kind returns the kind of trivial change made to the file, or "" if the
+// change is not trivial.
+func (f *fileChange) kind(rules Rules) string {
+	for _, pattern := range rules.TrivialPaths {
+		if matchPath(pattern, f.path) {
+			return "trivial-path"
+		}
+	}
+	if f.structural || len(f.added)+len(f.removed) == 0 {
+		return ""
+	}
+	if !significantWhitespace(f.path) && stripSpace(f.removed) == stripSpace(f.added) {
+		return "whitespace-only"
+	}
+	if f.commentsOnly() {
+		return "comment-only"
+	}
+	return ""
+}
+
+// commentsOnly reports whether every non-blank changed line is a comment.
+func (f *fileChange) commentsOnly() bool {
+	ext := strings.ToLower(path.Ext(f.path))
+	prefixes := commentPrefixes[ext]
+	if len(prefixes) == 0 {
+		return false
+	}
+	for _, lines := range [][]string{f.added, f.removed} {
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if line != "" && !isComment(line, prefixes, directivePrefixes[ext]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isComment reports whether a trimmed line is a comment line, and not one
+// of the directives.
+func isComment(line string, prefixes, directives []string) bool {
+	for _, directive := range directives {
+		if strings.HasPrefix(line, directive) {
+			return false
+		}
+	}
+	if line == "*" {
+		// Blank line of a block comment
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(line, prefix) || (prefix == "*/" && strings.HasSuffix(line, prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath reports whether file matches a glob; globs without a "/" also
+// match the file's base name.
+func matchPath(pattern, file string) bool {
+	if policy.MatchPath(pattern, file) {
+		return true
+	}
+	return !strings.Contains(pattern, "/") && policy.MatchPath(pattern, path.Base(file))
+}
+
+// stripSpace joins lines with all whitespace removed.
+func stripSpace(lines []string) string {
+	var b strings.Builder
+	for _, line := range lines {
+		for _, r := range line {
+			if !unicode.IsSpace(r) {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// parse splits a git-format diff into its files' changes.
+func parse(diff string) []*fileChange {
+	var files []*fileChange
+	var current *fileChange
+	inHunk := false
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			current = &fileChange{path: diffPath(line)}
+			files = append(files, current)
+			inHunk = false
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if strings.HasPrefix(line, "@@") {
+			inHunk = true
+			continue
+		}
+		if !inHunk {
+			for _, header := range []string{"new file mode", "deleted file mode", "rename from", "old mode", "Binary files"} {
+				if strings.HasPrefix(line, header) {
+					current.structural = true
+				}
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "+"):
+			current.added = append(current.added, line[1:])
+		case strings.HasPrefix(line, "-"):
+			current.removed = append(current.removed, line[1:])
+		}
+	}
+	return files
+}
+
+// diffPath returns the new path from a "diff --git a/old b/new" line.
+func diffPath(line string) string {
+	rest := strings.TrimPrefix(line, "diff --git ")
+	if i := strings.LastIndex(rest, " b/"); i >= 0 {
+		return rest[i+len(" b/"):]
+	}
+	return rest
+}
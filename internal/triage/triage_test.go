@@ -0,0 +1,72 @@
+package triage
+
+import (
+	"strings"
+	"testing"
+)
+
+// fileDiff returns a git-format diff of one file with the given hunk lines.
+func fileDiff(path string, lines ...string) string {
+	return "diff --git a/" + path + " b/" + path + "\n" +
+		"index 1111111..2222222 100644\n" +
+		"--- a/" + path + "\n" +
+		"+++ b/" + path + "\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" package main\n" +
+		strings.Join(lines, "\n") + "\n"
+}
+
+func TestClassify(t *testing.T) {
+	rules := Rules{MaxLines: 6, TrivialPaths: []string{"*.md", "testdata/"}}
+
+	tests := []struct {
+		name    string
+		diff    string
+		trivial bool
+		reason  string
+	}{
+		{"empty", "", false, "no changes"},
+		{"whitespace", fileDiff("main.go", "-func main()  {", "+func main() {", "+"), true, "whitespace-only changes (3 lines in 1 files)"},
+		{"comments", fileDiff("main.go", "-// Old doc.", "+// New doc.", "+/*", "+ * More.", "+ */"), true, "comment-only"},
+		{"shell comments", fileDiff("run.sh", "+# Explain the flag"), true, "comment-only"},
+		{"code", fileDiff("main.go", "-\treturn 1", "+\treturn 2"), false, "main.go has substantive changes"},
+		{"trailing comment", fileDiff("main.go", "+\tx := 1 // one"), false, "substantive"},
+		{"go directive", fileDiff("main.go", "+//go:build linux"), false, "substantive"},
+		{"build constraint", fileDiff("main.go", "-// +build linux", "+// +build darwin"), false, "substantive"},
+		{"go generate", fileDiff("main.go", "+//go:generate stringer -type=Kind"), false, "substantive"},
+		{"python indentation", fileDiff("app.py", "-    return x", "+return x"), false, "app.py has substantive changes"},
+		{"yaml indentation", fileDiff("ci.yml", "-  - run: make", "+    - run: make"), false, "substantive"},
+		{"makefile tab", fileDiff("Makefile", "-\tgo build", "+    go build"), false, "Makefile has substantive changes"},
+		{"python comment", fileDiff("app.py", "+# Explain the branch"), true, "comment-only"},
+		{"unknown language comment", fileDiff("notes.txt", "+# heading"), false, "substantive"},
+		{"trivial path", fileDiff("docs/guide.md", "+Rewritten paragraph."), true, "trivial-path changes"},
+		{"trivial dir", fileDiff("testdata/out.json", "+{}"), true, "trivial-path"},
+		{"too large", fileDiff("README.md", "+1", "+2", "+3", "+4", "+5", "+6", "+7"), false, "7 changed lines exceed the limit of 6"},
+		{"mixed", fileDiff("a.go", "+// Doc.") + fileDiff("b.go", "-\tx := 1", "+\tx  :=  1"), true, "whitespace-only, comment-only changes (3 lines in 2 files)"},
+		{"one substantive file", fileDiff("a.go", "+// Doc.") + fileDiff("b.go", "+\tx++"), false, "b.go"},
+		{"new file", "diff --git a/c.go b/c.go\nnew file mode 100644\n--- /dev/null\n+++ b/c.go\n@@ -0,0 +1 @@\n+// Package c.\n", false, "c.go"},
+		{"binary", "diff --git a/logo.png b/logo.png\nBinary files a/logo.png and b/logo.png differ\n", false, "logo.png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Classify(tt.diff, rules)
+			if got.Trivial != tt.trivial {
+				t.Errorf("Classify() trivial = %v, want %v (%s)", got.Trivial, tt.trivial, got.Reason)
+			}
+			if !strings.Contains(got.Reason, tt.reason) {
+				t.Errorf("Classify() reason = %q, want it to contain %q", got.Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestClassify_NoLimit(t *testing.T) {
+	lines := make([]string, 100)
+	for i := range lines {
+		lines[i] = "+// line"
+	}
+	if got := Classify(fileDiff("main.go", lines...), Rules{}); !got.Trivial || got.Lines != 100 {
+		t.Errorf("Classify() = %+v, want a trivial 100-line change", got)
+	}
+}
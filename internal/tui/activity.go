@@ -0,0 +1,77 @@
+package tui
+
+import "github.com/charmbracelet/lipgloss"
+
+// ActivityBar displays a one-line summary of the current iteration's tool
+// calls, e.g. "Read×10 Edit×4 Bash×2".
+//
+// Like the header, it is rendered when it changes rather than every frame.
+// A toast, a short-lived warning, takes the summary's place while it shows.
+type ActivityBar struct {
+	Summary string
+	Toast   string
+	width   int
+	view    string // rendered bar, refreshed by the setters
+}
+
+// NewActivityBar creates a new activity bar component.
+func NewActivityBar() ActivityBar {
+	a := ActivityBar{}
+	a.view = a.render()
+	return a
+}
+
+// SetSummary sets the tool usage summary. An empty summary clears the bar.
+func (a *ActivityBar) SetSummary(summary string) {
+	if a.Summary == summary {
+		return
+	}
+	a.Summary = summary
+	a.view = a.render()
+}
+
+// SetToast shows a warning in place of the summary. An empty toast
+// brings the summary back.
+func (a *ActivityBar) SetToast(toast string) {
+	if a.Toast == toast {
+		return
+	}
+	a.Toast = toast
+	a.view = a.render()
+}
+
+// SetWidth sets the component width.
+func (a *ActivityBar) SetWidth(w int) {
+	if a.width == w {
+		return
+	}
+	a.width = w
+	a.view = a.render()
+}
+
+// View returns the activity bar as rendered when it last changed. It is
+// always exactly one line so the layout doesn't shift when tools start
+// being called.
+func (a ActivityBar) View() string {
+	if a.view == "" {
+		return a.render()
+	}
+	return a.view
+}
+
+// render renders the activity bar from its current state.
+func (a ActivityBar) render() string {
+	summary := helpDescStyle.Render("no tool calls yet")
+	if a.Summary != "" {
+		summary = headerValueStyle.Render(a.Summary)
+	}
+	line := " " + headerLabelStyle.Render("Tools: ") + summary
+	if a.Toast != "" {
+		line = " " + statusFailedStyle.Render(glyph.warning+" "+a.Toast)
+	}
+
+	if a.width > 0 {
+		line = lipgloss.NewStyle().MaxWidth(a.width).Render(line)
+	}
+	return line
+}
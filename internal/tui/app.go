@@ -4,6 +4,7 @@ package tui
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
 	"time"
 
@@ -19,6 +20,7 @@ import (
 // Model is the main Bubble Tea model for the Ralph TUI.
 type Model struct {
 	header         Header
+	activityBar    ActivityBar
 	feedPanel      *ScrollablePanel
 	floatingWindow FloatingWindow
 
@@ -36,6 +38,14 @@ type Model struct {
 	quitting    bool
 	initialized bool
 
+	// Status to restore once a rate-limit wait is over ("" = not waiting)
+	statusBeforeWait string
+
+	// Toasts shown in the activity bar, counted so only the latest one's
+	// timer clears it
+	toastSeq      int
+	budgetTripped bool // Whether the cost budget's toast has been shown
+
 	// Event tracking
 	eventSeq      int
 	startTime     time.Time
@@ -45,6 +55,15 @@ type Model struct {
 	lastProgress  string
 	lastLearnings string
 
+	// Sub-agents spawned through the Task tool, by the ID of that call
+	subAgents          map[string]*subAgentBlock
+	subAgentsCollapsed bool
+
+	// History search overlay
+	search      SearchFunc
+	searching   bool
+	searchQuery string
+
 	width  int
 	height int
 }
@@ -52,13 +71,15 @@ type Model struct {
 // NewModel creates a new TUI model.
 func NewModel() Model {
 	feedPanel := NewScrollablePanel("Feed", true)
-	floatingWindow := NewFloatingWindow("✓ Completed")
+	floatingWindow := NewFloatingWindow(glyph.done + " Completed")
 	return Model{
 		header:         NewHeader(),
+		activityBar:    NewActivityBar(),
 		feedPanel:      &feedPanel,
 		floatingWindow: floatingWindow,
 		keys:           DefaultKeyMap(),
 		startTime:      time.Now(),
+		subAgents:      make(map[string]*subAgentBlock),
 	}
 }
 
@@ -98,6 +119,15 @@ type LoopEventMsg struct {
 // EventsClosedMsg signals that the event channel has closed.
 type EventsClosedMsg struct{}
 
+// toastDuration is how long a toast shows in the activity bar.
+const toastDuration = 10 * time.Second
+
+// toastExpiredMsg clears the toast it was scheduled for, unless a newer
+// one replaced it.
+type toastExpiredMsg struct {
+	seq int
+}
+
 // SetIterationMsg sets the iteration information.
 type SetIterationMsg struct {
 	Current int
@@ -137,6 +167,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
+		// While the search prompt is open, keys edit the query
+		if m.searching {
+			return m.handleSearchInput(msg)
+		}
+
 		// Handle quit first
 		if key.Matches(msg, m.keys.Quit) {
 			m.quitting = true
@@ -153,20 +188,43 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handleFloatingScroll(msg)
 		}
 
+		if m.isSearchKey(msg) {
+			m.openSearch()
+			return m, nil
+		}
+
+		if key.Matches(msg, m.keys.SubAgents) {
+			m.toggleSubAgents()
+			return m, nil
+		}
+
 		// Handle scrolling
 		return m.handleScroll(msg)
 
 	case LoopEventMsg:
+		toastSeq := m.toastSeq
 		m.handleLoopEvent(msg.Event)
+		if m.toastSeq != toastSeq {
+			seq := m.toastSeq
+			cmds = append(cmds, tea.Tick(toastDuration, func(time.Time) tea.Msg {
+				return toastExpiredMsg{seq: seq}
+			}))
+		}
 		cmds = append(cmds, m.listenForEvents())
 
+	case toastExpiredMsg:
+		if msg.seq == m.toastSeq {
+			m.activityBar.SetToast("")
+		}
+		return m, nil
+
 	case EventsClosedMsg:
 		// Event channel closed
 		if !m.completed && m.err == nil {
 			m.completed = true
 			m.status = "Completed"
 			m.header.SetStatus("Completed")
-			finishMsg := sectionDividerStyle.Render("─── Execution finished ───")
+			finishMsg := sectionDividerStyle.Render(divider("Execution finished"))
 			m.feedPanel.AppendLine(fmt.Sprintf("\n%s", finishMsg))
 		}
 		return m, nil
@@ -177,10 +235,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.header.SetIteration(msg.Current, msg.Max)
 
 	case SetPromptMsg:
-		promptHeader := sectionDividerStyle.Render("─── Prompt ───")
+		promptHeader := sectionDividerStyle.Render(divider("Prompt"))
 		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", promptHeader))
 		m.feedPanel.AppendContent(msg.Prompt)
-		outputHeader := sectionDividerStyle.Render("─── Output ───")
+		outputHeader := sectionDividerStyle.Render(divider("Output"))
 		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", outputHeader))
 
 	case AppendOutputMsg:
@@ -192,7 +250,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case SetErrorMsg:
 		m.err = fmt.Errorf("%s", msg.Error)
-		errorMsg := errorStyle.Render(fmt.Sprintf("✗ ERROR: %s", msg.Error))
+		errorMsg := errorStyle.Render(fmt.Sprintf("%s ERROR: %s", glyph.failed, msg.Error))
 		m.feedPanel.AppendLine(errorMsg)
 	}
 
@@ -240,10 +298,11 @@ func (m *Model) handleLoopEvent(event loop.Event) {
 
 	case loop.EventIterationStart:
 		m.streamedBytes = 0 // Reset streaming tracker for new iteration
+		m.activityBar.SetSummary("")
 		m.status = "Running"
 		m.header.SetStatus("Running")
 		// Build marker with current phase and panel width
-		panelWidth := m.feedPanel.viewport.Width
+		panelWidth := m.feedPanel.ContentWidth()
 		if panelWidth < 40 {
 			panelWidth = 40
 		}
@@ -251,10 +310,10 @@ func (m *Model) handleLoopEvent(event loop.Event) {
 		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", iterMarker))
 
 	case loop.EventPromptBuilt:
-		promptHeader := sectionDividerStyle.Render("─── Prompt ───")
+		promptHeader := sectionDividerStyle.Render(divider("Prompt"))
 		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", promptHeader))
 		m.feedPanel.AppendContent(event.Prompt)
-		outputHeader := sectionDividerStyle.Render("─── Output ───")
+		outputHeader := sectionDividerStyle.Render(divider("Output"))
 		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", outputHeader))
 
 	case loop.EventClaudeStart:
@@ -285,7 +344,12 @@ func (m *Model) handleLoopEvent(event loop.Event) {
 		// No-op
 
 	case loop.EventIterationEnd:
-		m.feedPanel.AppendLine(systemMessageStyle.Render("Iteration complete"))
+		if event.Duration <= 0 {
+			m.feedPanel.AppendLine(systemMessageStyle.Render("Iteration complete"))
+			break
+		}
+		m.header.SetLastIterationDuration(event.Duration)
+		m.feedPanel.AppendLine(systemMessageStyle.Render("Iteration complete in " + formatIterationTimings(event)))
 
 	case loop.EventDeveloperStart:
 		if event.TeamMode {
@@ -310,40 +374,243 @@ func (m *Model) handleLoopEvent(event loop.Event) {
 		m.completed = true
 		m.status = "Completed"
 		m.header.SetStatus("Completed")
-		doneMsg := doneMarkerStyle.Render("✓ DONE DONE DONE!!!")
+		doneMsg := doneMarkerStyle.Render(glyph.done + " DONE DONE DONE!!!")
 		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", doneMsg))
 		// Show completion floating window with summary
-		m.showSummaryWindow("✓ Completed", colorGreen, "Completed")
+		m.showSummaryWindow(glyph.done+" Completed", colorGreen, "Completed", "")
 
 	case loop.EventMaxIterations:
 		m.completed = true
 		m.status = "Stopped"
 		m.header.SetStatus("Stopped")
-		maxIterMsg := statusStoppedStyle.Render(fmt.Sprintf("■ %s", event.Message))
+		maxIterMsg := statusStoppedStyle.Render(fmt.Sprintf("%s %s", glyph.stopped, event.Message))
 		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", maxIterMsg))
 		// Show summary floating window
-		m.showSummaryWindow("■ Stopped - Iteration Limit", colorYellow, "Stopped")
+		m.showSummaryWindow(glyph.stopped+" Stopped - Iteration Limit", colorYellow, "Stopped", event.Message)
+
+	case loop.EventMaxDuration:
+		m.completed = true
+		m.status = "Paused"
+		m.header.SetStatus("Paused")
+		maxDurationMsg := statusStoppedStyle.Render(fmt.Sprintf("%s %s", glyph.stopped, event.Message))
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", maxDurationMsg))
+		m.showSummaryWindow(glyph.stopped+" Paused - Time Limit", colorYellow, "Paused", event.Message)
+
+	case loop.EventMaxCost:
+		m.completed = true
+		m.status = "Paused"
+		m.header.SetStatus("Paused")
+		maxCostMsg := statusFailedStyle.Render(fmt.Sprintf("%s %s", glyph.stopped, event.Message))
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", maxCostMsg))
+		m.showSummaryWindow(glyph.stopped+" Paused - Cost Limit", colorRed, "Paused", event.Message)
+
+	case loop.EventCost:
+		if event.Usage == nil {
+			break
+		}
+		m.header.SetUsage(*event.Usage)
+		if event.Usage.OverBudget() && !m.budgetTripped {
+			m.budgetTripped = true
+			warning := fmt.Sprintf("Cost budget spent: $%.2f of $%.2f; pausing after this iteration",
+				event.Usage.CostUSD, event.Usage.MaxCostUSD)
+			m.feedPanel.AppendLine(fmt.Sprintf("\n%s", statusFailedStyle.Render(glyph.warning+" "+warning)))
+			m.showToast(warning)
+		}
+
+	case loop.EventPaused:
+		m.completed = true
+		m.status = "Paused"
+		m.header.SetStatus("Paused")
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", statusStoppedStyle.Render(fmt.Sprintf("%s %s", glyph.stopped, event.Message))))
+		m.showSummaryWindow(glyph.stopped+" Paused", colorYellow, "Paused", event.Message)
+
+	case loop.EventDoneRejected:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", statusStoppedStyle.Render(glyph.warning+" "+event.Message)))
+
+	case loop.EventReviewQuorum:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", systemMessageStyle.Render(glyph.review+" Review panel: "+event.Message)))
+
+	case loop.EventReviewPatchApplied:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", systemMessageStyle.Render(event.Message)))
+
+	case loop.EventReviewerTests:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", systemMessageStyle.Render(glyph.review+" "+event.Message)))
+
+	case loop.EventUserFeedback:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", systemMessageStyle.Render(glyph.message+" "+event.Message)))
+
+	case loop.EventAnalyzerFindings, loop.EventChecksFailed:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", systemMessageStyle.Render(glyph.findings+" "+event.Message)))
+
+	case loop.EventSubPlanCreated, loop.EventSubPlanFinished, loop.EventSessionResubmitted:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", systemMessageStyle.Render(glyph.resumed+" "+event.Message)))
+
+	case loop.EventHookFailed:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", statusStoppedStyle.Render(glyph.warning+" "+event.Message)))
+
+	case loop.EventReviewSkipped:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", systemMessageStyle.Render(glyph.skipped+" "+event.Message)))
+
+	case loop.EventPlanChanged:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", systemMessageStyle.Render(glyph.edited+" "+event.Message)))
+		if event.Diff != "" {
+			m.feedPanel.AppendLine(formatPlanDiff(event.Diff))
+		}
 
 	case loop.EventExtremeModeTriggered:
-		extremeMsg := systemMessageStyle.Render(fmt.Sprintf("Extreme mode: %s", event.Message))
+		label := "Extreme mode"
+		if event.Rounds > 1 {
+			label = fmt.Sprintf("Extreme mode round %d/%d", event.Round, event.Rounds)
+		}
+		extremeMsg := systemMessageStyle.Render(fmt.Sprintf("%s: %s", label, event.Message))
 		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", extremeMsg))
 
+	case loop.EventClaudeRetry:
+		retryMsg := statusStoppedStyle.Render(fmt.Sprintf("%s %s", glyph.retry, event.Message))
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", retryMsg))
+
+	case loop.EventRateLimitWait, loop.EventThrottled, loop.EventQuietHours:
+		if event.Until.IsZero() {
+			if m.statusBeforeWait != "" {
+				m.status = m.statusBeforeWait
+				m.header.SetStatus(m.status)
+				m.statusBeforeWait = ""
+			}
+			m.feedPanel.AppendLine(fmt.Sprintf("\n%s", systemMessageStyle.Render(glyph.resumed+" "+event.Message)))
+			break
+		}
+		if m.statusBeforeWait == "" {
+			m.statusBeforeWait = m.status
+			m.feedPanel.AppendLine(fmt.Sprintf("\n%s", statusStoppedStyle.Render(glyph.waiting+" "+event.Message)))
+		}
+		label := "Rate limited"
+		switch event.Type {
+		case loop.EventThrottled:
+			label = "Throttled"
+		case loop.EventQuietHours:
+			label = "Quiet hours"
+		}
+		m.status = fmt.Sprintf("%s (%s)", label, formatDuration(time.Until(event.Until).Round(time.Second)))
+		m.header.SetStatus(m.status)
+
+	case loop.EventClaudeStalled, loop.EventContextPruned:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", statusStoppedStyle.Render(glyph.warning+" "+event.Message)))
+
+	case loop.EventConflictDetected:
+		m.status = "Conflicted"
+		m.header.SetStatus("Conflicted")
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", statusStoppedStyle.Render(glyph.warning+" "+event.Message)))
+
+	case loop.EventConflictResolverStart:
+		m.status = "Resolving conflicts"
+		m.header.SetStatus("Resolving conflicts")
+		m.feedPanel.AppendLine(systemMessageStyle.Render(event.Message))
+
+	case loop.EventConflictResolved:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", systemMessageStyle.Render(glyph.done+" "+event.Message)))
+
+	case loop.EventRebuttalStart:
+		m.status = "Re-evaluating"
+		m.header.SetStatus("Re-evaluating")
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", systemMessageStyle.Render(glyph.review+" "+event.Message)))
+
+	case loop.EventRebuttalAccepted, loop.EventRebuttalRejected:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", systemMessageStyle.Render(glyph.review+" "+event.Message)))
+
+	case loop.EventReformatStart, loop.EventReformatted:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", systemMessageStyle.Render(glyph.reformat+" "+event.Message)))
+
+	case loop.EventReformatFailed:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", statusStoppedStyle.Render(glyph.warning+" "+event.Message)))
+
+	case loop.EventDiffSummaryStart, loop.EventDiffSummarized:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", systemMessageStyle.Render(glyph.review+" "+event.Message)))
+
+	case loop.EventFailureTriageStart:
+		m.status = "Triaging"
+		m.header.SetStatus("Triaging")
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", statusStoppedStyle.Render(glyph.warning+" "+event.Message)))
+
+	case loop.EventFailureTriage:
+		triageMsg := statusStoppedStyle.Render(fmt.Sprintf("%s Failure triage: %s", glyph.warning, event.Message))
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", triageMsg))
+		if event.Output != "" {
+			m.feedPanel.AppendLine(event.Output)
+		}
+
+	case loop.EventStallDetected:
+		stallMsg := statusStoppedStyle.Render(fmt.Sprintf("%s Stall detected: %s", glyph.warning, event.Message))
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", stallMsg))
+
+	case loop.EventStallAborted:
+		m.completed = true
+		m.status = "Stopped"
+		m.header.SetStatus("Stopped")
+		stallMsg := statusStoppedStyle.Render(fmt.Sprintf("%s %s", glyph.stopped, event.Message))
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", stallMsg))
+		m.showSummaryWindow(glyph.stopped+" Stopped - No Progress", colorYellow, "Stopped", event.Message)
+
+	case loop.EventPlanningStart:
+		m.status = "Planning"
+		m.header.SetStatus("Planning")
+		m.feedPanel.AppendLine(systemMessageStyle.Render(event.Message))
+
+	case loop.EventTasksPlanned:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", systemMessageStyle.Render(event.Message)))
+
+	case loop.EventTaskStarted:
+		taskMsg := sectionDividerStyle.Render(divider(event.Message))
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", taskMsg))
+
+	case loop.EventTaskCompleted:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", doneMarkerStyle.Render(fmt.Sprintf("%s %s", glyph.done, event.Message))))
+
+	case loop.EventToolActivity:
+		m.activityBar.SetSummary(event.Message)
+
+	case loop.EventFilesOutOfScope:
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", statusStoppedStyle.Render(glyph.warning+" "+event.Message)))
+
+	case loop.EventPolicyViolation:
+		violationMsg := errorStyle.Render(fmt.Sprintf("%s POLICY VIOLATION: %s", glyph.failed, event.Message))
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", violationMsg))
+
 	case loop.EventError:
-		errorMsg := errorStyle.Render(fmt.Sprintf("✗ ERROR: %s", event.Message))
+		errorMsg := errorStyle.Render(fmt.Sprintf("%s ERROR: %s", glyph.failed, event.Message))
 		m.feedPanel.AppendLine(errorMsg)
+
+	case loop.EventFailed:
+		m.completed = true
+		m.status = "Failed"
+		m.header.SetStatus("Failed")
+		failedMsg := errorStyle.Render(fmt.Sprintf("%s FAILED: %s", glyph.failed, event.Message))
+		m.feedPanel.AppendLine(fmt.Sprintf("\n%s", failedMsg))
+		m.showSummaryWindow(glyph.failed+" Failed", colorRed, "Failed", event.Message)
 	}
 }
 
+// showToast shows a warning in the activity bar for toastDuration.
+func (m *Model) showToast(text string) {
+	m.toastSeq++
+	m.activityBar.SetToast(text)
+}
+
 // handleClaudeEvent processes a Claude stream event.
 // Only assistant text is displayed to the screen - all events are still stored in the database.
 func (m *Model) handleClaudeEvent(event *claude.StreamEvent) {
 	m.eventSeq++
 
+	// Sub-agents' activity goes in their own blocks
+	if event.SubAgentID != "" {
+		m.handleSubAgentEvent(event)
+		return
+	}
+
 	switch event.Type {
 	case claude.EventAssistantText:
-		// Streaming text - display inline and track
+		// Streaming text - render as markdown inline and track
 		if event.AssistantText != nil && event.AssistantText.Text != "" {
-			m.feedPanel.AppendContent(event.AssistantText.Text)
+			m.feedPanel.AppendMarkdown(event.AssistantText.Text)
 			m.streamedBytes += len(event.AssistantText.Text)
 		}
 
@@ -352,7 +619,7 @@ func (m *Model) handleClaudeEvent(event *claude.StreamEvent) {
 		if event.Message != nil && event.Message.Text != "" {
 			if m.streamedBytes == 0 {
 				// Streaming didn't work, show the complete message
-				m.feedPanel.AppendContent(event.Message.Text)
+				m.feedPanel.AppendMarkdown(event.Message.Text)
 			}
 			// If streaming worked, this is duplicate - skip
 		}
@@ -360,18 +627,27 @@ func (m *Model) handleClaudeEvent(event *claude.StreamEvent) {
 	case claude.EventToolUse:
 		// Show any text that preceded the tool call (often not streamed!)
 		if event.Message != nil && event.Message.Text != "" {
-			m.feedPanel.AppendContent(event.Message.Text)
+			m.feedPanel.AppendMarkdown(event.Message.Text)
 		}
 		// Tool call - show condensed format
 		if event.ToolUse != nil {
 			toolLine := formatToolUse(event.ToolUse)
 			m.feedPanel.AppendLine(toolLine)
+			if claude.IsSubAgentSpawn(event.ToolUse) {
+				m.subAgent(event.ToolUse.ID)
+			}
+		}
+
+	case claude.EventToolResult:
+		// A Task call returning ends its sub-agent's block
+		if event.ToolResult != nil {
+			m.finishSubAgent(event.ToolResult)
 		}
 
 	case claude.EventError:
 		// Always show errors with styled formatting
 		if event.Error != nil {
-			errorMsg := errorStyle.Render(fmt.Sprintf("✗ [%s]: %s", event.Error.Code, event.Error.Message))
+			errorMsg := errorStyle.Render(fmt.Sprintf("%s [%s]: %s", glyph.failed, event.Error.Code, event.Error.Message))
 			m.feedPanel.AppendLine(fmt.Sprintf("\n%s", errorMsg))
 		}
 	}
@@ -390,9 +666,9 @@ func formatToolUse(tool *claude.ToolUseContent) string {
 	nameStyle, paramStyle := GetToolStyles(category)
 
 	// Build styled components
-	icon := toolIconStyle.Render("▸")
+	icon := toolIconStyle.Render(glyph.tool)
 	name := nameStyle.Render(tool.Name)
-	chevron := toolChevronStyle.Render("›")
+	chevron := toolChevronStyle.Render(glyph.chevron)
 
 	if param != "" {
 		styledParam := paramStyle.Render(param)
@@ -401,6 +677,36 @@ func formatToolUse(tool *claude.ToolUseContent) string {
 	return fmt.Sprintf("\n%s %s", icon, name)
 }
 
+// maxPlanDiffLines is the number of plan diff lines shown in the feed.
+const maxPlanDiffLines = 20
+
+// formatPlanDiff colors a plan diff for the feed, added lines green and
+// removed lines red, showing at most maxPlanDiffLines lines.
+func formatPlanDiff(diff string) string {
+	lines := strings.Split(diff, "\n")
+	more := 0
+	if len(lines) > maxPlanDiffLines {
+		more = len(lines) - maxPlanDiffLines
+		lines = lines[:maxPlanDiffLines]
+	}
+
+	styled := make([]string, 0, len(lines)+1)
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			styled = append(styled, statusCompletedStyle.Render(line))
+		case strings.HasPrefix(line, "-"):
+			styled = append(styled, statusFailedStyle.Render(line))
+		default:
+			styled = append(styled, helpDescStyle.Render(line))
+		}
+	}
+	if more > 0 {
+		styled = append(styled, helpDescStyle.Render(fmt.Sprintf("… %d more lines", more)))
+	}
+	return strings.Join(styled, "\n")
+}
+
 // buildIterationMarker creates a centered iteration marker with phase.
 // Format: ──── Iteration 1/3 • Running ────
 func buildIterationMarker(iteration, maxIter int, phase string, width int) string {
@@ -412,10 +718,13 @@ func buildIterationMarker(iteration, maxIter int, phase string, width int) strin
 		iterLabel = fmt.Sprintf("Iteration %d/X", iteration)
 	}
 	iterText := iterationTextStyle.Render(iterLabel)
-	bullet := iterationBulletStyle.Render(" • ")
+	bullet := iterationBulletStyle.Render(glyph.bullet)
 	phaseText := GetPhaseStyle(phase).Render(phase)
 
 	content := iterText + bullet + phaseText
+	if glyph.rule == "" {
+		return content
+	}
 	contentWidth := lipgloss.Width(content)
 
 	// Calculate padding for centering
@@ -428,8 +737,8 @@ func buildIterationMarker(iteration, maxIter int, phase string, width int) strin
 	leftDashes := totalDashes / 2
 	rightDashes := totalDashes - leftDashes
 
-	left := iterationDashStyle.Render(strings.Repeat("─", leftDashes))
-	right := iterationDashStyle.Render(strings.Repeat("─", rightDashes))
+	left := iterationDashStyle.Render(strings.Repeat(glyph.rule, leftDashes))
+	right := iterationDashStyle.Render(strings.Repeat(glyph.rule, rightDashes))
 
 	return fmt.Sprintf("%s %s %s", left, content, right)
 }
@@ -444,7 +753,7 @@ func extractMainParam(input json.RawMessage) string {
 		return ""
 	}
 	// Common param names to look for
-	for _, key := range []string{"path", "file_path", "command", "query", "pattern", "url", "content"} {
+	for _, key := range []string{"path", "file_path", "command", "query", "pattern", "url", "content", "description"} {
 		if v, ok := params[key]; ok {
 			if s, ok := v.(string); ok {
 				// Truncate long values
@@ -461,13 +770,14 @@ func extractMainParam(input json.RawMessage) string {
 // updateLayout updates component sizes based on window size.
 func (m *Model) updateLayout() {
 	m.header.SetWidth(m.width)
+	m.activityBar.SetWidth(m.width)
 
-	// Measure actual header height after rendering
-	headerView := m.header.View()
-	headerHeight := lipgloss.Height(headerView)
+	// Measure actual header and activity bar heights after rendering
+	headerHeight := lipgloss.Height(m.header.View())
+	activityHeight := lipgloss.Height(m.activityBar.View())
 
-	// Feed panel gets remaining height (minus header and newline)
-	availableHeight := m.height - headerHeight - 1
+	// Feed panel gets remaining height (minus header, activity bar, and newlines)
+	availableHeight := m.height - headerHeight - activityHeight - 2
 	if availableHeight < 10 {
 		availableHeight = 10
 	}
@@ -481,8 +791,9 @@ func (m *Model) updateLayout() {
 }
 
 // showSummaryWindow displays the floating window with a summary.
-// verb is the action word (e.g. "Completed", "Stopped").
-func (m *Model) showSummaryWindow(title string, borderColor lipgloss.Color, verb string) {
+// verb is the action word (e.g. "Completed", "Stopped"); reason, when set,
+// says why the plan stopped or failed.
+func (m *Model) showSummaryWindow(title string, borderColor lipgloss.TerminalColor, verb, reason string) {
 	m.floatingWindow.SetTitle(title)
 	m.floatingWindow.SetBorderColor(borderColor)
 
@@ -493,6 +804,9 @@ func (m *Model) showSummaryWindow(title string, borderColor lipgloss.Color, verb
 	durationStr := formatDuration(duration)
 
 	summary.WriteString(fmt.Sprintf("%s after %d iteration(s) (%s)\n\n", verb, m.iteration, durationStr))
+	if reason != "" {
+		summary.WriteString(fmt.Sprintf("Reason: %s\n\n", reason))
+	}
 
 	if m.lastProgress != "" {
 		summary.WriteString("## Summary\n")
@@ -506,6 +820,16 @@ func (m *Model) showSummaryWindow(title string, borderColor lipgloss.Color, verb
 	m.floatingWindow.Show(summary.String())
 }
 
+// formatIterationTimings formats an iteration's duration with its Claude
+// and jj time, e.g. "2m 5s (Claude 1m 50s, jj 3s)".
+func formatIterationTimings(event loop.Event) string {
+	text := formatDuration(event.Duration)
+	if event.Timings != nil {
+		text += fmt.Sprintf(" (Claude %s, jj %s)", formatDuration(event.Timings.Claude), formatDuration(event.Timings.JJ))
+	}
+	return text
+}
+
 // formatDuration formats a duration in a human-readable way.
 func formatDuration(d time.Duration) string {
 	if d < time.Minute {
@@ -540,6 +864,10 @@ func (m Model) View() string {
 	s.WriteString(m.header.View())
 	s.WriteString("\n")
 
+	// Live tool activity for the current iteration
+	s.WriteString(m.activityBar.View())
+	s.WriteString("\n")
+
 	// Feed panel (single panel - ALL content)
 	s.WriteString(m.feedPanel.View())
 
@@ -602,6 +930,12 @@ func (m *Model) SetEvents(events <-chan loop.Event) {
 	m.events = events
 }
 
+// SetScrollback copies the feed to w as it is written, so it outlives the
+// TUI. Call it before SetPrompt to keep the prompt too.
+func (m *Model) SetScrollback(w io.Writer) {
+	m.feedPanel.SetTee(w)
+}
+
 // SetPlanID sets the plan ID in the header.
 func (m *Model) SetPlanID(id string) {
 	m.header.SetPlanID(id)
@@ -609,10 +943,10 @@ func (m *Model) SetPlanID(id string) {
 
 // SetPrompt sets the prompt content.
 func (m *Model) SetPrompt(prompt string) {
-	promptHeader := sectionDividerStyle.Render("─── Prompt ───")
+	promptHeader := sectionDividerStyle.Render(divider("Prompt"))
 	m.feedPanel.AppendLine(fmt.Sprintf("\n%s", promptHeader))
 	m.feedPanel.AppendContent(prompt)
-	outputHeader := sectionDividerStyle.Render("─── Output ───")
+	outputHeader := sectionDividerStyle.Render(divider("Output"))
 	m.feedPanel.AppendLine(fmt.Sprintf("\n%s", outputHeader))
 }
 
@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 
 	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
 	"github.com/gerunddev/ralph/internal/loop"
 )
 
@@ -128,6 +131,39 @@ func TestModel_HandleLoopEvent_Started(t *testing.T) {
 	close(events)
 }
 
+func TestModel_HandleLoopEvent_ToolActivity(t *testing.T) {
+	m := NewModel()
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	m.handleLoopEvent(loop.NewEvent(loop.EventToolActivity, 1, 10, "Read×10 Edit×4 Bash×2"))
+	if !strings.Contains(m.View(), "Read×10 Edit×4 Bash×2") {
+		t.Error("expected the view to show the tool activity summary")
+	}
+
+	// A new iteration starts with a clean summary
+	m.handleLoopEvent(loop.NewEvent(loop.EventIterationStart, 2, 10, "Starting iteration 2"))
+	if m.activityBar.Summary != "" {
+		t.Errorf("expected activity to be cleared on iteration start, got %q", m.activityBar.Summary)
+	}
+	if !strings.Contains(m.View(), "no tool calls yet") {
+		t.Error("expected the empty activity placeholder")
+	}
+}
+
+func TestActivityBar_View_SingleLine(t *testing.T) {
+	a := NewActivityBar()
+	a.SetWidth(30)
+	a.SetSummary("Read×10 Edit×4 Bash×2 Grep×7 Write×1 Glob×3")
+
+	view := a.View()
+	if strings.Contains(view, "\n") {
+		t.Errorf("expected a single line, got %q", view)
+	}
+	if w := lipgloss.Width(view); w > 30 {
+		t.Errorf("expected width <= 30, got %d", w)
+	}
+}
+
 func TestModel_HandleLoopEvent_Done(t *testing.T) {
 	events := make(chan loop.Event, 10)
 	m := NewModelWithEvents(events)
@@ -248,6 +284,234 @@ func TestModel_HandleLoopEvent_MaxIterations_StoppedTitle(t *testing.T) {
 	close(events)
 }
 
+func TestModel_HandleLoopEvent_Failed(t *testing.T) {
+	events := make(chan loop.Event, 10)
+	m := NewModelWithEvents(events)
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	m.handleLoopEvent(loop.Event{
+		Type:      loop.EventFailed,
+		Iteration: 2,
+		MaxIter:   10,
+		Message:   "failed to load tasks",
+	})
+
+	if !m.completed || m.status != "Failed" {
+		t.Errorf("expected completed with status 'Failed', got completed=%v status=%q", m.completed, m.status)
+	}
+	if !strings.Contains(m.floatingWindow.Title, "Failed") {
+		t.Errorf("expected floating window title to contain 'Failed', got '%s'", m.floatingWindow.Title)
+	}
+	if view := m.floatingWindow.View(); !strings.Contains(view, "Reason: failed to load tasks") {
+		t.Errorf("expected floating window to show the failure reason, got '%s'", view)
+	}
+
+	close(events)
+}
+
+func TestModel_HandleLoopEvent_MaxDuration(t *testing.T) {
+	events := make(chan loop.Event, 10)
+	m := NewModelWithEvents(events)
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	m.handleLoopEvent(loop.Event{
+		Type:      loop.EventMaxDuration,
+		Iteration: 4,
+		MaxIter:   10,
+		Message:   "Reached max duration (2h0m0s); resume with: ralph --resume abc123",
+	})
+
+	if !m.completed || m.status != "Paused" {
+		t.Errorf("expected completed with status 'Paused', got completed=%v status=%q", m.completed, m.status)
+	}
+	if !strings.Contains(m.floatingWindow.Title, "Time Limit") {
+		t.Errorf("expected floating window title to contain 'Time Limit', got '%s'", m.floatingWindow.Title)
+	}
+	if view := m.floatingWindow.View(); !strings.Contains(view, "ralph --resume abc123") {
+		t.Errorf("expected floating window to show resume instructions, got '%s'", view)
+	}
+
+	close(events)
+}
+
+func TestModel_HandleLoopEvent_Paused(t *testing.T) {
+	events := make(chan loop.Event, 10)
+	m := NewModelWithEvents(events)
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	m.handleLoopEvent(loop.Event{
+		Type:      loop.EventPaused,
+		Iteration: 2,
+		MaxIter:   10,
+		Message:   "Paused by request; resume with: ralph --resume abc123",
+	})
+
+	if !m.completed || m.status != "Paused" {
+		t.Errorf("expected completed with status 'Paused', got completed=%v status=%q", m.completed, m.status)
+	}
+	if view := m.floatingWindow.View(); !strings.Contains(view, "Paused by request") {
+		t.Errorf("expected floating window to show the reason, got '%s'", view)
+	}
+
+	close(events)
+}
+
+func TestModel_HandleLoopEvent_RateLimitWait(t *testing.T) {
+	events := make(chan loop.Event, 10)
+	m := NewModelWithEvents(events)
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+	m.status = "Developing"
+
+	m.handleLoopEvent(loop.Event{
+		Type:    loop.EventRateLimitWait,
+		Message: "Rate limited, resuming in 5m0s",
+		Until:   time.Now().Add(5 * time.Minute),
+	})
+	if !strings.HasPrefix(m.status, "Rate limited (") {
+		t.Errorf("expected a rate-limit countdown status, got %q", m.status)
+	}
+
+	m.handleLoopEvent(loop.Event{Type: loop.EventRateLimitWait, Message: "Rate limit reset, resuming"})
+	if m.status != "Developing" {
+		t.Errorf("expected the status restored after the wait, got %q", m.status)
+	}
+
+	close(events)
+}
+
+func TestModel_HandleLoopEvent_Throttled(t *testing.T) {
+	events := make(chan loop.Event, 10)
+	m := NewModelWithEvents(events)
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+	m.status = "Reviewing"
+
+	m.handleLoopEvent(loop.Event{
+		Type:    loop.EventThrottled,
+		Message: "Throttled at 10 sessions/hour, resuming in 6m0s",
+		Until:   time.Now().Add(6 * time.Minute),
+	})
+	if !strings.HasPrefix(m.status, "Throttled (") {
+		t.Errorf("expected a throttle countdown status, got %q", m.status)
+	}
+
+	m.handleLoopEvent(loop.Event{Type: loop.EventThrottled, Message: "Throttle lifted, resuming"})
+	if m.status != "Reviewing" {
+		t.Errorf("expected the status restored after the wait, got %q", m.status)
+	}
+
+	close(events)
+}
+
+func TestModel_HandleLoopEvent_ClaudeStalled(t *testing.T) {
+	events := make(chan loop.Event, 10)
+	m := NewModelWithEvents(events)
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	m.handleLoopEvent(loop.Event{Type: loop.EventClaudeHeartbeat, Message: "Waiting on Claude (silent for 1m0s)"})
+	if strings.Contains(m.feedPanel.Content(), "Waiting on Claude") {
+		t.Error("expected heartbeats to stay out of the feed")
+	}
+
+	m.handleLoopEvent(loop.Event{Type: loop.EventClaudeStalled, Message: "Claude has produced no output for 5m0s"})
+	if !strings.Contains(m.feedPanel.Content(), "no output for 5m0s") {
+		t.Errorf("expected the stall in the feed, got: %q", m.feedPanel.Content())
+	}
+
+	close(events)
+}
+
+func TestModel_HandleLoopEvent_ReviewSkipped(t *testing.T) {
+	events := make(chan loop.Event, 10)
+	m := NewModelWithEvents(events)
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	m.handleLoopEvent(loop.Event{Type: loop.EventReviewSkipped, Message: "Skipped review of a trivial change: comment-only changes (2 lines in 1 files)"})
+	if !strings.Contains(m.feedPanel.Content(), "comment-only changes") {
+		t.Errorf("expected the skipped review in the feed, got: %q", m.feedPanel.Content())
+	}
+
+	close(events)
+}
+
+func TestModel_HandleLoopEvent_Conflicts(t *testing.T) {
+	events := make(chan loop.Event, 10)
+	m := NewModelWithEvents(events)
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	m.handleLoopEvent(loop.Event{Type: loop.EventConflictDetected, Message: "jj reports conflicts in api/handler.go"})
+	if m.status != "Conflicted" {
+		t.Errorf("status = %q, want Conflicted", m.status)
+	}
+	m.handleLoopEvent(loop.Event{Type: loop.EventConflictResolverStart, Message: "Starting conflict resolver agent"})
+	if m.status != "Resolving conflicts" {
+		t.Errorf("status = %q, want Resolving conflicts", m.status)
+	}
+	m.handleLoopEvent(loop.Event{Type: loop.EventConflictResolved, Message: "Conflicts resolved, continuing"})
+
+	content := m.feedPanel.Content()
+	for _, want := range []string{"conflicts in api/handler.go", "Starting conflict resolver agent", "Conflicts resolved"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("feed missing %q: %q", want, content)
+		}
+	}
+
+	close(events)
+}
+
+func TestModel_HandleLoopEvent_Rebuttal(t *testing.T) {
+	events := make(chan loop.Event, 10)
+	m := NewModelWithEvents(events)
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	m.handleLoopEvent(loop.Event{Type: loop.EventRebuttalStart, Message: "Developer disputed the review feedback, starting re-evaluation"})
+	if m.status != "Re-evaluating" {
+		t.Errorf("status = %q, want Re-evaluating", m.status)
+	}
+	m.handleLoopEvent(loop.Event{Type: loop.EventRebuttalRejected, Message: "Reviewer upheld its feedback: config can be nil"})
+
+	content := m.feedPanel.Content()
+	for _, want := range []string{"starting re-evaluation", "upheld its feedback"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("feed missing %q: %q", want, content)
+		}
+	}
+
+	close(events)
+}
+
+func TestModel_HandleLoopEvent_PlanChanged(t *testing.T) {
+	events := make(chan loop.Event, 10)
+	m := NewModelWithEvents(events)
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	m.handleLoopEvent(loop.Event{
+		Type:      loop.EventPlanChanged,
+		Iteration: 2,
+		MaxIter:   10,
+		Message:   "Plan file /tmp/plan.md was edited; merged into the plan",
+		Diff:      " # Plan\n+Add logout",
+	})
+
+	content := m.feedPanel.Content()
+	if !strings.Contains(content, "merged into the plan") || !strings.Contains(content, "+Add logout") {
+		t.Errorf("expected the plan change and its diff in the feed, got: %q", content)
+	}
+
+	close(events)
+}
+
+func TestFormatPlanDiff_Truncates(t *testing.T) {
+	lines := make([]string, maxPlanDiffLines+5)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("+line %d", i)
+	}
+
+	got := formatPlanDiff(strings.Join(lines, "\n"))
+	if strings.Contains(got, fmt.Sprintf("line %d", maxPlanDiffLines)) || !strings.Contains(got, "… 5 more lines") {
+		t.Errorf("expected the diff cut at %d lines with a count of the rest, got: %q", maxPlanDiffLines, got)
+	}
+}
+
 func TestFloatingWindow_SetTitle(t *testing.T) {
 	fw := NewFloatingWindow("Original Title")
 	fw.SetSize(100, 40)
@@ -815,6 +1079,161 @@ func TestScrollablePanel_DeferredSync(t *testing.T) {
 	}
 }
 
+func TestLineBuffer_EvictsOldestLines(t *testing.T) {
+	b := newLineBuffer(3)
+	if evicted := b.Write("one\ntwo\nthr"); evicted != 0 {
+		t.Errorf("expected no evictions, got %d", evicted)
+	}
+	if evicted := b.Write("ee\nfour\nfive\npartial"); evicted != 2 {
+		t.Errorf("expected 2 evictions, got %d", evicted)
+	}
+
+	if got := b.String(); got != "three\nfour\nfive\npartial" {
+		t.Errorf("unexpected content %q", got)
+	}
+	if b.Len() != 4 {
+		t.Errorf("expected 4 lines including the partial one, got %d", b.Len())
+	}
+	if got := b.Slice(1, 10); strings.Join(got, ",") != "four,five,partial" {
+		t.Errorf("unexpected slice %q", got)
+	}
+
+	b.Reset()
+	if b.String() != "" || b.Len() != 1 {
+		t.Errorf("expected empty buffer after Reset, got %q", b.String())
+	}
+}
+
+func TestScrollablePanel_Folded(t *testing.T) {
+	p := NewScrollablePanel("Test", true)
+	p.SetSize(80, 10)
+	p.SetMaxLines(3)
+	p.AppendLine("one")
+	p.AppendFoldableLine("two")
+	p.AppendLine("three")
+
+	p.SetFolded(true)
+	if got := strings.Join(p.shownSlice(0, 10), ","); got != "one,three," {
+		t.Errorf("expected the foldable line hidden, got %q", got)
+	}
+
+	// Evicting the foldable line leaves nothing to fold
+	p.AppendLine("four")
+	if got := strings.Join(p.shownSlice(0, 10), ","); got != "three,four," || p.shownLen() != 3 {
+		t.Errorf("expected the evicted line forgotten, got %q (%d lines)", got, p.shownLen())
+	}
+
+	p.AppendFoldableLine("five")
+	p.SetFolded(false)
+	if got := strings.Join(p.shownSlice(0, 10), ","); got != "three,four,five," {
+		t.Errorf("expected unfolding to show every line, got %q", got)
+	}
+}
+
+func TestScrollablePanel_MaxLines(t *testing.T) {
+	p := NewScrollablePanel("Test", true)
+	p.SetSize(80, 10)
+	p.SetMaxLines(50)
+
+	for i := 0; i < 200; i++ {
+		p.AppendLine(fmt.Sprintf("line %d", i))
+	}
+
+	content := p.Content()
+	if strings.Contains(content, "line 149\n") {
+		t.Error("expected old lines to be dropped")
+	}
+	if !strings.HasPrefix(content, "line 150\n") || !strings.HasSuffix(content, "line 199\n") {
+		t.Errorf("expected the last 50 lines to be retained, got %q", content)
+	}
+
+	view := p.View()
+	if !strings.Contains(view, "line 199") {
+		t.Error("expected auto-scrolled view to show the newest line")
+	}
+}
+
+func TestScrollablePanel_Tee(t *testing.T) {
+	p := NewScrollablePanel("Test", true)
+	p.SetMaxLines(2)
+	var tee strings.Builder
+	p.SetTee(&tee)
+
+	p.AppendLine("one")
+	p.AppendContent("two ")
+	p.AppendLine("three")
+	p.AppendLine("four")
+
+	if tee.String() != "one\ntwo three\nfour\n" {
+		t.Errorf("tee = %q, want every line, including dropped ones", tee.String())
+	}
+}
+
+func TestScrollablePanel_ScrolledViewSurvivesEviction(t *testing.T) {
+	p := NewScrollablePanel("Test", true)
+	p.SetSize(80, 10)
+	p.SetMaxLines(20)
+	for i := 0; i < 20; i++ {
+		p.AppendLine(fmt.Sprintf("line %d", i))
+	}
+
+	p.ScrollUp(5)
+	before := p.View()
+	if p.AutoScroll {
+		t.Fatal("expected scrolling up to turn off auto-scroll")
+	}
+
+	// Dropping old lines must not move the lines being read
+	p.AppendLine("line 20")
+	p.AppendLine("line 21")
+	if after := p.View(); after != before {
+		t.Errorf("expected view to stay put, got:\n%s\nwant:\n%s", after, before)
+	}
+
+	p.GotoBottom()
+	if !strings.Contains(p.View(), "line 21") {
+		t.Error("expected bottom of the feed after GotoBottom")
+	}
+}
+
+func TestHeader_ViewUpdatesOnChange(t *testing.T) {
+	h := NewHeader()
+	h.SetWidth(80)
+	h.SetStatus("Running")
+	first := h.View()
+
+	h.SetStatus("Running")
+	if h.View() != first {
+		t.Error("expected unchanged status to keep the rendered header")
+	}
+
+	h.SetStatus("Reviewing")
+	if !strings.Contains(h.View(), "Reviewing") {
+		t.Error("expected header to re-render after a status change")
+	}
+}
+
+func TestModel_HandleLoopEvent_IterationTimings(t *testing.T) {
+	events := make(chan loop.Event, 10)
+	m := NewModelWithEvents(events)
+	m = updateModel(m, tea.WindowSizeMsg{Width: 160, Height: 40})
+
+	m.handleLoopEvent(loop.Event{
+		Type:     loop.EventIterationEnd,
+		Duration: 125 * time.Second,
+		Timings:  &db.SessionTimings{Claude: 110 * time.Second, JJ: 3 * time.Second},
+	})
+
+	if !strings.Contains(m.feedPanel.Content(), "Iteration complete in 2m 5s (Claude 1m 50s, jj 3s)") {
+		t.Errorf("feed missing iteration timings: %q", m.feedPanel.Content())
+	}
+	if !strings.Contains(m.header.View(), "last: 2m 5s") {
+		t.Errorf("header missing last iteration duration: %q", m.header.View())
+	}
+
+	close(events)
+}
+
 func TestKeyMap_ShortHelp(t *testing.T) {
 	km := DefaultKeyMap()
 	help := km.ShortHelp()
@@ -823,9 +1242,9 @@ func TestKeyMap_ShortHelp(t *testing.T) {
 		t.Error("expected non-empty short help")
 	}
 
-	// Should have 2 bindings: scroll and quit
-	if len(help) != 2 {
-		t.Errorf("expected 2 short help bindings, got %d", len(help))
+	// Should have 3 bindings: scroll, search, and quit
+	if len(help) != 3 {
+		t.Errorf("expected 3 short help bindings, got %d", len(help))
 	}
 }
 
@@ -837,12 +1256,12 @@ func TestKeyMap_FullHelp(t *testing.T) {
 		t.Error("expected non-empty full help")
 	}
 
-	// Should have one group with 3 bindings: up, down, quit
+	// Should have one group with 5 bindings: up, down, search, sub-agents, quit
 	if len(help) != 1 {
 		t.Errorf("expected 1 help group, got %d", len(help))
 	}
-	if len(help[0]) != 3 {
-		t.Errorf("expected 3 bindings in help group, got %d", len(help[0]))
+	if len(help[0]) != 5 {
+		t.Errorf("expected 5 bindings in help group, got %d", len(help[0]))
 	}
 }
 
@@ -1049,6 +1468,89 @@ func TestModel_HandleClaudeEvent_EventToolUse(t *testing.T) {
 	close(events)
 }
 
+func TestModel_HandleClaudeEvent_SubAgentBlock(t *testing.T) {
+	m := NewModel()
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	send := func(e *claude.StreamEvent) {
+		m.handleLoopEvent(loop.Event{Type: loop.EventClaudeStream, Iteration: 1, MaxIter: 5, ClaudeEvent: e})
+	}
+	send(&claude.StreamEvent{
+		Type:    claude.EventToolUse,
+		ToolUse: &claude.ToolUseContent{ID: "task_1", Name: "Task", Input: []byte(`{"description":"Find callers"}`)},
+	})
+	send(&claude.StreamEvent{
+		Type:       claude.EventToolUse,
+		SubAgentID: "task_1",
+		ToolUse:    &claude.ToolUseContent{ID: "tool_2", Name: "Grep", Input: []byte(`{"pattern":"Run("}`)},
+	})
+	send(&claude.StreamEvent{
+		Type:       claude.EventMessage,
+		SubAgentID: "task_1",
+		Message:    &claude.MessageContent{Text: "Found 3 callers"},
+	})
+	send(&claude.StreamEvent{
+		Type:       claude.EventToolResult,
+		ToolResult: &claude.ToolResultContent{ToolUseID: "task_1", Content: "3 callers"},
+	})
+
+	content := m.feedPanel.Content()
+	for _, want := range []string{"Find callers", glyph.gutter, "Grep", "Found 3 callers", "sub-agent finished · 1 tool call(s)"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in the feed, got: %q", want, content)
+		}
+	}
+	if m.streamedBytes != 0 {
+		t.Errorf("expected sub-agent text not to count as streamed output, got %d bytes", m.streamedBytes)
+	}
+	if len(m.subAgents) != 0 {
+		t.Errorf("expected the finished sub-agent to be forgotten, got %d", len(m.subAgents))
+	}
+}
+
+func TestModel_SubAgentsCollapse(t *testing.T) {
+	m := NewModel()
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+	if !m.subAgentsCollapsed {
+		t.Fatal("expected s to collapse sub-agent activity")
+	}
+
+	send := func(e *claude.StreamEvent) {
+		m.handleLoopEvent(loop.Event{Type: loop.EventClaudeStream, Iteration: 1, MaxIter: 5, ClaudeEvent: e})
+	}
+	send(&claude.StreamEvent{
+		Type:    claude.EventToolUse,
+		ToolUse: &claude.ToolUseContent{ID: "task_1", Name: "Task"},
+	})
+	send(&claude.StreamEvent{
+		Type:       claude.EventMessage,
+		SubAgentID: "task_1",
+		Message:    &claude.MessageContent{Text: "hidden notes"},
+	})
+	send(&claude.StreamEvent{
+		Type:       claude.EventToolResult,
+		ToolResult: &claude.ToolResultContent{ToolUseID: "task_1"},
+	})
+
+	content := m.feedPanel.Content()
+	if !strings.Contains(content, "hidden notes") {
+		t.Error("expected collapsed sub-agent text to be kept in the feed")
+	}
+	if strings.Contains(m.feedPanel.View(), "hidden notes") {
+		t.Error("expected collapsed sub-agent text not to be shown")
+	}
+	if !strings.Contains(content, "1 line(s) collapsed") {
+		t.Errorf("expected the block summary to count collapsed lines, got: %q", content)
+	}
+
+	// Expanding shows the lines collapsed earlier
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+	if !strings.Contains(m.feedPanel.View(), "hidden notes") {
+		t.Error("expected expanding to show the collapsed sub-agent text")
+	}
+}
+
 func TestModel_HandleClaudeEvent_EventToolUse_WithPrecedingText(t *testing.T) {
 	events := make(chan loop.Event, 10)
 	m := NewModelWithEvents(events)
@@ -1731,3 +2233,181 @@ func TestModel_HandleLoopEvent_ExtremeModeTriggered(t *testing.T) {
 
 	close(events)
 }
+
+func TestModel_HandleLoopEvent_ExtremeModeRounds(t *testing.T) {
+	events := make(chan loop.Event, 10)
+	m := NewModelWithEvents(events)
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	m.handleLoopEvent(loop.Event{
+		Type:      loop.EventExtremeModeTriggered,
+		Iteration: 7,
+		MaxIter:   12,
+		Message:   "+5 iterations (max now 12)",
+		Round:     2,
+		Rounds:    3,
+	})
+
+	output := m.feedPanel.Content()
+	if !strings.Contains(output, "Extreme mode round 2/3: +5 iterations") {
+		t.Errorf("expected the round in the feed, got '%s'", output)
+	}
+
+	close(events)
+}
+
+func TestModel_SearchOverlay(t *testing.T) {
+	var gotQuery string
+	m := NewModel()
+	m.SetSearch(func(query string, limit int) ([]*db.SearchResult, error) {
+		gotQuery = query
+		return []*db.SearchResult{
+			{Kind: "learnings", PlanID: "plan-1", Iteration: 4, Snippet: "needs a [mutex]"},
+		}, nil
+	})
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	if !m.searching || !m.floatingWindow.IsVisible() {
+		t.Fatal("expected search prompt to open on /")
+	}
+
+	// "q" is part of the query, not quit
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("quux")})
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyBackspace})
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeySpace})
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("mutex")})
+	if m.quitting {
+		t.Fatal("typing q in the search prompt should not quit")
+	}
+
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if gotQuery != "quu mutex" {
+		t.Errorf("search query = %q, want %q", gotQuery, "quu mutex")
+	}
+	if m.searching {
+		t.Error("expected prompt to close after Enter")
+	}
+	if !strings.Contains(m.floatingWindow.Content, "plan-1 · iteration 4 · learnings") ||
+		!strings.Contains(m.floatingWindow.Content, "needs a [mutex]") {
+		t.Errorf("unexpected results content: %q", m.floatingWindow.Content)
+	}
+
+	// Results dismiss like any floating window
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyEscape})
+	if m.floatingWindow.IsVisible() {
+		t.Error("expected results to be dismissed")
+	}
+}
+
+func TestModel_SearchOverlay_Cancel(t *testing.T) {
+	m := NewModel()
+	m.SetSearch(func(query string, limit int) ([]*db.SearchResult, error) {
+		t.Error("search should not run when cancelled")
+		return nil, nil
+	})
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("abc")})
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyEscape})
+
+	if m.searching || m.floatingWindow.IsVisible() {
+		t.Error("expected Esc to close the search prompt")
+	}
+}
+
+func TestModel_SearchKey_DisabledWithoutSearch(t *testing.T) {
+	m := NewModel()
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	if m.searching || m.floatingWindow.IsVisible() {
+		t.Error("expected / to be ignored when search is not configured")
+	}
+}
+
+func TestFormatSearchResults_Empty(t *testing.T) {
+	if got := formatSearchResults(nil); got != "No matches" {
+		t.Errorf("formatSearchResults(nil) = %q, want %q", got, "No matches")
+	}
+}
+
+func TestModel_HandleLoopEvent_Tasks(t *testing.T) {
+	m := NewModel()
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	m.handleLoopEvent(loop.Event{Type: loop.EventPlanningStart, Message: "Breaking plan into tasks"})
+	if m.status != "Planning" {
+		t.Errorf("status = %q, want Planning", m.status)
+	}
+
+	m.handleLoopEvent(loop.Event{Type: loop.EventTasksPlanned, Message: "Planned 2 tasks"})
+	m.handleLoopEvent(loop.Event{Type: loop.EventTaskStarted, Message: "Task 1/2: Add config"})
+	m.handleLoopEvent(loop.Event{Type: loop.EventTaskCompleted, Message: "Completed task 1/2: Add config"})
+
+	output := m.feedPanel.Content()
+	for _, want := range []string{"Planned 2 tasks", "Task 1/2: Add config", "✓ Completed task 1/2: Add config"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected feed to contain %q, got '%s'", want, output)
+		}
+	}
+}
+
+func TestHeader_View_Usage(t *testing.T) {
+	h := NewHeader()
+	h.SetIteration(3, 20)
+	h.SetWidth(120)
+	if strings.Contains(h.View(), "$") {
+		t.Error("expected no cost before usage is reported")
+	}
+
+	h.SetUsage(loop.PlanUsage{CostUSD: 1.234, Tokens: 45600})
+	view := h.View()
+	if !strings.Contains(view, "$1.23") || !strings.Contains(view, "45.6k tok") {
+		t.Errorf("expected cost and tokens in view, got '%s'", view)
+	}
+
+	h.SetUsage(loop.PlanUsage{CostUSD: 8.5, Tokens: 1_200_000, MaxCostUSD: 10})
+	view = h.View()
+	if !strings.Contains(view, "$8.50/$10.00") || !strings.Contains(view, "1.2M tok") {
+		t.Errorf("expected cost against the budget in view, got '%s'", view)
+	}
+	if !strings.Contains(view, glyph.warning) {
+		t.Errorf("expected a warning past 80%% of the budget, got '%s'", view)
+	}
+}
+
+func TestModel_HandleLoopEvent_CostBudget(t *testing.T) {
+	events := make(chan loop.Event, 10)
+	m := NewModelWithEvents(events)
+	m = updateModel(m, tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	under := loop.PlanUsage{CostUSD: 4, MaxCostUSD: 5}
+	m = updateModel(m, LoopEventMsg{Event: loop.Event{Type: loop.EventCost, Usage: &under}})
+	if m.activityBar.Toast != "" {
+		t.Errorf("expected no toast under budget, got %q", m.activityBar.Toast)
+	}
+
+	over := loop.PlanUsage{CostUSD: 5.2, MaxCostUSD: 5}
+	updated, cmd := m.Update(LoopEventMsg{Event: loop.Event{Type: loop.EventCost, Usage: &over}})
+	m = updated.(Model)
+	if !strings.Contains(m.activityBar.Toast, "Cost budget spent: $5.20 of $5.00") {
+		t.Errorf("expected budget toast, got %q", m.activityBar.Toast)
+	}
+	if cmd == nil {
+		t.Fatal("expected a command to expire the toast")
+	}
+
+	// Only the first report past the budget toasts
+	m = updateModel(m, toastExpiredMsg{seq: m.toastSeq})
+	m = updateModel(m, LoopEventMsg{Event: loop.Event{Type: loop.EventCost, Usage: &over}})
+	if m.activityBar.Toast != "" {
+		t.Errorf("expected toast to expire and not return, got %q", m.activityBar.Toast)
+	}
+
+	m = updateModel(m, LoopEventMsg{Event: loop.Event{Type: loop.EventMaxCost, Message: "Reached max cost ($5.20 spent of $5.00)"}})
+	if !m.completed || m.status != "Paused" {
+		t.Errorf("expected paused after EventMaxCost, got status %q", m.status)
+	}
+}
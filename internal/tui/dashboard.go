@@ -0,0 +1,434 @@
+// Package tui provides the Bubble Tea TUI for Ralph.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+// DefaultDashboardInterval is how often the dashboard refreshes its plans
+// when no interval is set.
+const DefaultDashboardInterval = 2 * time.Second
+
+// DashboardPlan is one plan as listed on the dashboard.
+type DashboardPlan struct {
+	ID        string
+	Status    string
+	Iteration int    // Latest iteration (0 = not started)
+	Agent     string // Agent of the latest session
+	LastEvent string // What the plan's agents did last
+	CostUSD   float64
+	UpdatedAt time.Time
+}
+
+// ListPlansFunc returns the plans the dashboard lists, most relevant first.
+type ListPlansFunc func() ([]DashboardPlan, error)
+
+// AttachFunc streams a plan's events, starting from its history, until ctx
+// is cancelled. The channel must be closed once ctx is cancelled.
+type AttachFunc func(ctx context.Context, planID string) <-chan loop.Event
+
+// DashboardKeyMap defines the key bindings of the plan list.
+type DashboardKeyMap struct {
+	Up      key.Binding
+	Down    key.Binding
+	Attach  key.Binding
+	Detach  key.Binding
+	Refresh key.Binding
+	Quit    key.Binding
+}
+
+// DefaultDashboardKeyMap returns the default dashboard key bindings.
+func DefaultDashboardKeyMap() DashboardKeyMap {
+	return DashboardKeyMap{
+		Up: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("↑↓", "select"),
+		),
+		Down: key.NewBinding(
+			key.WithKeys("down", "j"),
+		),
+		Attach: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "attach"),
+		),
+		Detach: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "back to plans"),
+		),
+		Refresh: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "refresh"),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("q", "ctrl+c"),
+			key.WithHelp("q", "quit"),
+		),
+	}
+}
+
+// DashboardModel is the Bubble Tea model for the multi-plan dashboard. It
+// lists plans with their live status and, once one is selected, shows that
+// plan's event stream in the regular feed until the user goes back.
+type DashboardModel struct {
+	list     ListPlansFunc
+	attach   AttachFunc
+	interval time.Duration
+	keys     DashboardKeyMap
+
+	plans  []DashboardPlan
+	cursor int
+	err    error
+
+	// The feed of the plan attached to, if any. generation tells messages
+	// from an earlier attachment apart so they can be dropped.
+	attached       *Model
+	attachedEvents <-chan loop.Event
+	cancelAttach   context.CancelFunc
+	generation     int
+
+	quitting bool
+	width    int
+	height   int
+}
+
+// NewDashboardModel creates a dashboard that lists plans with list every
+// interval and attaches to them with attach.
+func NewDashboardModel(list ListPlansFunc, attach AttachFunc, interval time.Duration) DashboardModel {
+	if interval <= 0 {
+		interval = DefaultDashboardInterval
+	}
+	return DashboardModel{
+		list:     list,
+		attach:   attach,
+		interval: interval,
+		keys:     DefaultDashboardKeyMap(),
+	}
+}
+
+// dashboardPlansMsg carries the result of listing plans.
+type dashboardPlansMsg struct {
+	plans []DashboardPlan
+	err   error
+}
+
+// dashboardTickMsg triggers a refresh of the plan list.
+type dashboardTickMsg struct{}
+
+// attachedMsg wraps a message for the attached feed, tagged with the
+// attachment it belongs to.
+type attachedMsg struct {
+	generation int
+	msg        tea.Msg
+}
+
+// Init implements tea.Model.
+func (m DashboardModel) Init() tea.Cmd {
+	return tea.Batch(m.refresh(), m.tick())
+}
+
+// refresh returns a command that lists the plans.
+func (m DashboardModel) refresh() tea.Cmd {
+	list := m.list
+	return func() tea.Msg {
+		plans, err := list()
+		return dashboardPlansMsg{plans: plans, err: err}
+	}
+}
+
+// tick schedules the next refresh.
+func (m DashboardModel) tick() tea.Cmd {
+	return tea.Tick(m.interval, func(time.Time) tea.Msg {
+		return dashboardTickMsg{}
+	})
+}
+
+// Update implements tea.Model.
+func (m DashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		if m.attached != nil {
+			return m, m.updateAttached(m.feedSize())
+		}
+		return m, nil
+
+	case dashboardPlansMsg:
+		m.err = msg.err
+		if msg.err == nil {
+			m.setPlans(msg.plans)
+		}
+		return m, nil
+
+	case dashboardTickMsg:
+		return m, tea.Batch(m.refresh(), m.tick())
+
+	case attachedMsg:
+		if msg.generation != m.generation || m.attached == nil {
+			return m, nil // From an earlier attachment
+		}
+		switch inner := msg.msg.(type) {
+		case tea.QuitMsg:
+			return m.quit()
+		case tea.BatchMsg:
+			cmds := make([]tea.Cmd, 0, len(inner))
+			for _, cmd := range inner {
+				cmds = append(cmds, m.wrapAttached(cmd))
+			}
+			return m, tea.Batch(cmds...)
+		}
+		return m, m.updateAttached(msg.msg)
+
+	case tea.KeyMsg:
+		if m.attached != nil {
+			return m.handleAttachedKey(msg)
+		}
+		return m.handleListKey(msg)
+	}
+
+	return m, nil
+}
+
+// handleListKey handles keys while the plan list is shown.
+func (m DashboardModel) handleListKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	case key.Matches(msg, m.keys.Up):
+		m.cursor = max(m.cursor-1, 0)
+	case key.Matches(msg, m.keys.Down):
+		m.cursor = min(m.cursor+1, max(len(m.plans)-1, 0))
+	case key.Matches(msg, m.keys.Refresh):
+		return m, m.refresh()
+	case key.Matches(msg, m.keys.Attach):
+		if m.cursor < len(m.plans) {
+			return m.attachTo(m.plans[m.cursor].ID)
+		}
+	}
+	return m, nil
+}
+
+// handleAttachedKey handles keys while a plan's feed is shown. Keys go to the
+// feed unless they leave it; while the feed has a window or search prompt
+// open, they all go to the feed.
+func (m DashboardModel) handleAttachedKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if !m.attached.searching && !m.attached.floatingWindow.IsVisible() {
+		switch {
+		case key.Matches(msg, m.keys.Quit):
+			return m.quit()
+		case key.Matches(msg, m.keys.Detach):
+			m.detach()
+			return m, m.refresh()
+		}
+	}
+	return m, m.updateAttached(msg)
+}
+
+// setPlans replaces the listed plans, keeping the selected plan selected.
+func (m *DashboardModel) setPlans(plans []DashboardPlan) {
+	selected := ""
+	if m.cursor < len(m.plans) {
+		selected = m.plans[m.cursor].ID
+	}
+	m.plans = plans
+	m.cursor = min(m.cursor, max(len(plans)-1, 0))
+	for i, plan := range plans {
+		if plan.ID == selected {
+			m.cursor = i
+			break
+		}
+	}
+}
+
+// attachTo shows the feed of a plan, streaming its events.
+func (m DashboardModel) attachTo(planID string) (tea.Model, tea.Cmd) {
+	m.detach()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := m.attach(ctx, planID)
+	feed := NewModelWithEvents(events)
+	feed.SetPlanID(planID)
+
+	m.generation++
+	m.attached = &feed
+	m.attachedEvents = events
+	m.cancelAttach = cancel
+
+	cmds := []tea.Cmd{m.wrapAttached(feed.Init())}
+	if m.width > 0 {
+		cmds = append(cmds, m.updateAttached(m.feedSize()))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// detach stops streaming the attached plan's events. The channel is drained
+// until it closes so its publisher never blocks on a reader that is gone.
+func (m *DashboardModel) detach() {
+	if m.cancelAttach != nil {
+		m.cancelAttach()
+	}
+	if events := m.attachedEvents; events != nil {
+		go func() {
+			for range events {
+			}
+		}()
+	}
+	m.attached = nil
+	m.attachedEvents = nil
+	m.cancelAttach = nil
+}
+
+// quit stops any attachment and exits.
+func (m DashboardModel) quit() (tea.Model, tea.Cmd) {
+	m.detach()
+	m.quitting = true
+	return m, tea.Quit
+}
+
+// updateAttached passes a message to the attached feed.
+func (m *DashboardModel) updateAttached(msg tea.Msg) tea.Cmd {
+	updated, cmd := m.attached.Update(msg)
+	feed := updated.(Model)
+	m.attached = &feed
+	return m.wrapAttached(cmd)
+}
+
+// wrapAttached tags the messages of a feed command with the current
+// attachment.
+func (m DashboardModel) wrapAttached(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	generation := m.generation
+	return func() tea.Msg {
+		return attachedMsg{generation: generation, msg: cmd()}
+	}
+}
+
+// feedSize is the size given to the attached feed, leaving a line for the
+// dashboard's hints.
+func (m DashboardModel) feedSize() tea.WindowSizeMsg {
+	return tea.WindowSizeMsg{Width: m.width, Height: max(m.height-1, 1)}
+}
+
+// View implements tea.Model.
+func (m DashboardModel) View() string {
+	if m.quitting {
+		return "Goodbye!\n"
+	}
+	if m.attached != nil {
+		return m.attached.View() + "\n" + renderHints(m.keys.Detach, m.keys.Quit)
+	}
+
+	var s strings.Builder
+	s.WriteString(headerStyle.Render(headerValueStyle.Render("Ralph Dashboard") +
+		headerLabelStyle.Render(fmt.Sprintf("  |  %d plans", len(m.plans)))))
+	s.WriteString("\n\n")
+
+	switch {
+	case m.err != nil:
+		s.WriteString(errorStyle.Render(fmt.Sprintf("%s ERROR: %v", glyph.failed, m.err)))
+		s.WriteString("\n")
+	case len(m.plans) == 0:
+		s.WriteString(helpDescStyle.Render("No plans."))
+		s.WriteString("\n")
+	default:
+		columns := fmt.Sprintf("  %-10s%-10s"+dashboardDetailColumns, "PLAN", "STATUS", "ITER", "COST", "UPDATED", "LAST EVENT")
+		s.WriteString(headerLabelStyle.Render(columns))
+		s.WriteString("\n")
+		for i, plan := range m.plans {
+			s.WriteString(m.renderPlan(i, plan))
+			s.WriteString("\n")
+		}
+	}
+
+	s.WriteString("\n")
+	s.WriteString(renderHints(m.keys.Up, m.keys.Attach, m.keys.Refresh, m.keys.Quit))
+
+	if m.width > 0 {
+		return lipgloss.NewStyle().MaxWidth(m.width).Render(s.String())
+	}
+	return s.String()
+}
+
+// renderPlan renders one row of the plan list.
+func (m DashboardModel) renderPlan(i int, plan DashboardPlan) string {
+	cursor := "  "
+	if i == m.cursor {
+		cursor = "> "
+	}
+
+	shortID := plan.ID
+	if len(shortID) > 8 {
+		shortID = shortID[:8]
+	}
+	iteration := "-"
+	if plan.Iteration > 0 {
+		iteration = fmt.Sprintf("%d", plan.Iteration)
+		if plan.Agent != "" {
+			iteration += " " + plan.Agent
+		}
+	}
+	cost := "-"
+	if plan.CostUSD > 0 {
+		cost = fmt.Sprintf("$%.2f", plan.CostUSD)
+	}
+	updated := "-"
+	if !plan.UpdatedAt.IsZero() {
+		updated = formatDuration(time.Since(plan.UpdatedAt)) + " ago"
+	}
+
+	id := fmt.Sprintf("%s%-10s", cursor, shortID)
+	if i == m.cursor {
+		id = helpKeyStyle.Render(id)
+	}
+	status := renderPlanStatus(fmt.Sprintf("%-10s", plan.Status), plan.Status)
+	return id + status + fmt.Sprintf(dashboardDetailColumns, iteration, cost, updated, plan.LastEvent)
+}
+
+// dashboardDetailColumns lays out the plan list's columns after the plan
+// ID and status, which are styled separately.
+const dashboardDetailColumns = "%-16s%-9s%-12s%s"
+
+// renderPlanStatus styles text for a stored plan status.
+func renderPlanStatus(text, status string) string {
+	switch status {
+	case "running":
+		return statusRunningStyle.Render(text)
+	case "completed":
+		return statusCompletedStyle.Render(text)
+	case "failed", "abandoned":
+		return statusFailedStyle.Render(text)
+	case "paused", "stopped", "cancelled":
+		return statusStoppedStyle.Render(text)
+	default:
+		return statusPendingStyle.Render(text)
+	}
+}
+
+// renderHints renders key bindings as "key:desc" hints.
+func renderHints(bindings ...key.Binding) string {
+	parts := make([]string, 0, len(bindings))
+	for _, b := range bindings {
+		help := b.Help()
+		parts = append(parts, helpKeyStyle.Render(help.Key)+helpDescStyle.Render(":"+help.Desc))
+	}
+	return strings.Join(parts, helpSeparatorStyle.Render("  "))
+}
+
+// RunDashboard starts the dashboard TUI.
+func RunDashboard(list ListPlansFunc, attach AttachFunc, interval time.Duration) error {
+	m := NewDashboardModel(list, attach, interval)
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	_, err := p.Run()
+	return err
+}
@@ -0,0 +1,121 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+// updateDashboard updates the dashboard and casts it back.
+func updateDashboard(m DashboardModel, msg tea.Msg) (DashboardModel, tea.Cmd) {
+	updated, cmd := m.Update(msg)
+	return updated.(DashboardModel), cmd
+}
+
+func testDashboardPlans() []DashboardPlan {
+	return []DashboardPlan{
+		{ID: "3f2a9c1e-aaaa", Status: "running", Iteration: 2, Agent: "developer", LastEvent: "tool: Edit", CostUSD: 1.5},
+		{ID: "7b1d0e42-bbbb", Status: "completed"},
+	}
+}
+
+func TestDashboard_ListsPlans(t *testing.T) {
+	m := NewDashboardModel(func() ([]DashboardPlan, error) { return testDashboardPlans(), nil }, nil, 0)
+	if m.interval != DefaultDashboardInterval {
+		t.Errorf("interval = %s, want default %s", m.interval, DefaultDashboardInterval)
+	}
+
+	m, _ = updateDashboard(m, tea.WindowSizeMsg{Width: 120, Height: 30})
+	m, _ = updateDashboard(m, m.refresh()())
+
+	view := m.View()
+	for _, want := range []string{"Ralph Dashboard", "3f2a9c1e", "running", "2 developer", "$1.50", "tool: Edit", "7b1d0e42", "completed"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q:\n%s", want, view)
+		}
+	}
+	if strings.Contains(view, "3f2a9c1e-aaaa") {
+		t.Error("expected plan IDs to be shortened")
+	}
+}
+
+func TestDashboard_CursorFollowsSelectedPlan(t *testing.T) {
+	m := NewDashboardModel(nil, nil, time.Second)
+	m, _ = updateDashboard(m, dashboardPlansMsg{plans: testDashboardPlans()})
+
+	m, _ = updateDashboard(m, tea.KeyMsg{Type: tea.KeyDown})
+	m, _ = updateDashboard(m, tea.KeyMsg{Type: tea.KeyDown})
+	if m.cursor != 1 {
+		t.Fatalf("cursor = %d, want 1 (clamped to the last plan)", m.cursor)
+	}
+
+	// A refresh that reorders the plans keeps the same plan selected
+	plans := testDashboardPlans()
+	plans[0], plans[1] = plans[1], plans[0]
+	m, _ = updateDashboard(m, dashboardPlansMsg{plans: plans})
+	if m.cursor != 0 {
+		t.Errorf("cursor = %d after reorder, want 0", m.cursor)
+	}
+}
+
+func TestDashboard_AttachAndDetach(t *testing.T) {
+	var attachedTo []string
+	var contexts []context.Context
+	channels := []chan loop.Event{make(chan loop.Event, 1), make(chan loop.Event, 1)}
+	attach := func(ctx context.Context, planID string) <-chan loop.Event {
+		attachedTo = append(attachedTo, planID)
+		contexts = append(contexts, ctx)
+		return channels[len(attachedTo)-1]
+	}
+
+	m := NewDashboardModel(nil, attach, time.Second)
+	m, _ = updateDashboard(m, tea.WindowSizeMsg{Width: 100, Height: 30})
+	m, _ = updateDashboard(m, dashboardPlansMsg{plans: testDashboardPlans()})
+
+	m, cmd := updateDashboard(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.attached == nil || len(attachedTo) != 1 || attachedTo[0] != "3f2a9c1e-aaaa" {
+		t.Fatalf("expected to attach to the selected plan, attached to %v", attachedTo)
+	}
+	if cmd == nil {
+		t.Fatal("expected a command listening for the plan's events")
+	}
+	firstGeneration := m.generation
+
+	channels[0] <- loop.NewEvent(loop.EventReviewerStart, 3, 0, "")
+	m, _ = updateDashboard(m, attachedMsg{generation: firstGeneration, msg: m.attached.listenForEvents()()})
+	if m.attached.status != "Reviewing" {
+		t.Errorf("attached feed status = %q, want Reviewing", m.attached.status)
+	}
+	if !strings.Contains(m.View(), "back to plans") {
+		t.Error("expected the attached view to show how to go back")
+	}
+
+	m, _ = updateDashboard(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.attached != nil {
+		t.Fatal("expected Esc to detach")
+	}
+	if contexts[0].Err() == nil {
+		t.Error("expected detaching to cancel the plan's stream")
+	}
+	close(channels[0])
+
+	// Messages from the earlier attachment are dropped
+	m, _ = updateDashboard(m, tea.KeyMsg{Type: tea.KeyEnter})
+	m, _ = updateDashboard(m, attachedMsg{generation: firstGeneration, msg: EventsClosedMsg{}})
+	if m.attached.completed {
+		t.Error("expected a message from the earlier attachment to be ignored")
+	}
+
+	m, cmd = updateDashboard(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if !m.quitting || cmd == nil {
+		t.Error("expected q to quit while attached")
+	}
+	if contexts[1].Err() == nil {
+		t.Error("expected quitting to cancel the plan's stream")
+	}
+}
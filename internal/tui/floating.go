@@ -16,7 +16,7 @@ type FloatingWindow struct {
 	visible     bool
 	width       int
 	height      int
-	borderColor lipgloss.Color
+	borderColor lipgloss.TerminalColor
 }
 
 // NewFloatingWindow creates a new floating window.
@@ -34,7 +34,7 @@ func (f *FloatingWindow) SetTitle(title string) {
 }
 
 // SetBorderColor sets the floating window border and title color.
-func (f *FloatingWindow) SetBorderColor(color lipgloss.Color) {
+func (f *FloatingWindow) SetBorderColor(color lipgloss.TerminalColor) {
 	f.borderColor = color
 }
 
@@ -126,7 +126,7 @@ func (f FloatingWindow) View() string {
 	// Build styles (use custom border color if set)
 	winStyle := floatingWindowStyle
 	titleStyle := floatingTitleStyle
-	if f.borderColor != "" {
+	if f.borderColor != nil {
 		winStyle = winStyle.BorderForeground(f.borderColor)
 		titleStyle = titleStyle.Foreground(f.borderColor)
 	}
@@ -4,49 +4,105 @@ package tui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
+
+	"github.com/gerunddev/ralph/internal/loop"
 )
 
-// Header displays iteration status and key hints.
+// Header displays iteration status and key hints. It is rendered when its
+// state changes rather than on every frame, since frames are redrawn for
+// each streamed chunk of output.
 type Header struct {
 	Iteration int
 	MaxIter   int
 	Status    string
 	PlanID    string
+	LastIter  time.Duration // How long the last completed iteration took (0 = none yet)
 	width     int
+	view      string // rendered header, refreshed by the setters
+
+	// Usage is the plan's cost and tokens so far, shown once reported
+	Usage    loop.PlanUsage
+	hasUsage bool
 }
 
 // NewHeader creates a new header component.
 func NewHeader() Header {
-	return Header{
+	h := Header{
 		Status: "Pending",
 	}
+	h.view = h.render()
+	return h
 }
 
 // SetIteration sets the current iteration and max.
 func (h *Header) SetIteration(current, max int) {
+	if h.Iteration == current && h.MaxIter == max {
+		return
+	}
 	h.Iteration = current
 	h.MaxIter = max
+	h.view = h.render()
+}
+
+// SetLastIterationDuration sets how long the last completed iteration took.
+func (h *Header) SetLastIterationDuration(d time.Duration) {
+	if h.LastIter == d {
+		return
+	}
+	h.LastIter = d
+	h.view = h.render()
+}
+
+// SetUsage sets the plan's cost and tokens so far.
+func (h *Header) SetUsage(usage loop.PlanUsage) {
+	if h.hasUsage && h.Usage == usage {
+		return
+	}
+	h.Usage = usage
+	h.hasUsage = true
+	h.view = h.render()
 }
 
 // SetStatus sets the status text.
 func (h *Header) SetStatus(status string) {
+	if h.Status == status {
+		return
+	}
 	h.Status = status
+	h.view = h.render()
 }
 
 // SetWidth sets the component width.
 func (h *Header) SetWidth(w int) {
+	if h.width == w {
+		return
+	}
 	h.width = w
+	h.view = h.render()
 }
 
 // SetPlanID sets the plan ID.
 func (h *Header) SetPlanID(id string) {
+	if h.PlanID == id {
+		return
+	}
 	h.PlanID = id
+	h.view = h.render()
 }
 
-// View renders the header.
+// View returns the header as rendered when it last changed.
 func (h Header) View() string {
+	if h.view == "" {
+		return h.render()
+	}
+	return h.view
+}
+
+// render renders the header from its current state.
+func (h Header) render() string {
 	// Get border size (Width() sets width including padding but excluding border)
 	borderH := headerStyle.GetHorizontalBorderSize()
 
@@ -56,7 +112,7 @@ func (h Header) View() string {
 		styleWidth = 40
 	}
 
-	// Build content: Iteration | Status: <status> | ↑↓:scroll  q:quit
+	// Build content: Iteration [last: <duration>] | <cost> | Status: <status> | ↑↓:scroll  /:search  q:quit
 	iterStr := "---"
 	if h.MaxIter > 0 {
 		iterStr = fmt.Sprintf("%d/%d", h.Iteration, h.MaxIter)
@@ -66,6 +122,9 @@ func (h Header) View() string {
 	}
 
 	iterSection := headerValueStyle.Render(iterStr)
+	if h.LastIter > 0 {
+		iterSection += headerLabelStyle.Render(" last: ") + headerValueStyle.Render(formatDuration(h.LastIter))
+	}
 
 	statusSection := lipgloss.JoinHorizontal(lipgloss.Center,
 		headerLabelStyle.Render("Status: "),
@@ -77,16 +136,23 @@ func (h Header) View() string {
 	// Key hints inline after status
 	hints := h.renderKeyHints()
 
-	content := iterSection + separator + statusSection + separator + hints
+	content := iterSection
+	if h.hasUsage {
+		content += separator + h.renderUsage()
+	}
+	content += separator + statusSection + separator + hints
 
-	// Add plan ID after key hints if set
+	// Add plan ID after key hints if set and there is room for it
 	if h.PlanID != "" {
 		// Truncate UUID to first 8 chars for display
 		shortID := h.PlanID
 		if len(shortID) > 8 {
 			shortID = shortID[:8]
 		}
-		content += separator + helpDescStyle.Render(shortID)
+		withID := content + separator + helpDescStyle.Render(shortID)
+		if lipgloss.Width(withID) <= styleWidth-headerStyle.GetHorizontalPadding() {
+			content = withID
+		}
 	}
 
 	// Apply style with explicit width and safety cap
@@ -102,7 +168,7 @@ func (h Header) renderStatus() string {
 	}
 
 	switch strings.ToLower(status) {
-	case "running", "in progress":
+	case "running", "in progress", "planning":
 		return statusRunningStyle.Render(status)
 	case "developing", "developing (team)":
 		return statusDevelopingStyle.Render(status)
@@ -114,15 +180,48 @@ func (h Header) renderStatus() string {
 		return statusStoppedStyle.Render(status)
 	case "failed", "error":
 		return statusFailedStyle.Render(status)
+	}
+	if strings.HasPrefix(strings.ToLower(status), "rate limited") {
+		return statusStoppedStyle.Render(status)
+	}
+	return statusPendingStyle.Render(status)
+}
+
+// renderUsage renders the plan's cost, against --max-cost when set, and
+// its tokens. The cost turns yellow past 80% of the budget and red once it
+// is spent.
+func (h Header) renderUsage() string {
+	cost := fmt.Sprintf("$%.2f", h.Usage.CostUSD)
+	if h.Usage.MaxCostUSD > 0 {
+		cost += fmt.Sprintf("/$%.2f", h.Usage.MaxCostUSD)
+	}
+	switch {
+	case h.Usage.OverBudget():
+		cost = statusFailedStyle.Render(glyph.warning + " " + cost)
+	case h.Usage.NearBudget():
+		cost = statusStoppedStyle.Render(glyph.warning + " " + cost)
 	default:
-		return statusPendingStyle.Render(status)
+		cost = headerValueStyle.Render(cost)
+	}
+	return cost + headerLabelStyle.Render(" "+formatTokens(h.Usage.Tokens)+" tok")
+}
+
+// formatTokens formats a token count compactly, e.g. 950, 45.6k, or 1.2M.
+func formatTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
 	}
+	return fmt.Sprintf("%d", n)
 }
 
 // renderKeyHints renders the key binding hints.
 func (h Header) renderKeyHints() string {
 	parts := []string{
 		h.renderHint("↑↓", "scroll"),
+		h.renderHint("/", "search"),
 		h.renderHint("q", "quit"),
 	}
 	return strings.Join(parts, helpSeparatorStyle.Render("  "))
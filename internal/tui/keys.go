@@ -10,8 +10,10 @@ type KeyMap struct {
 	Down key.Binding
 
 	// Actions
-	Quit    key.Binding
-	Dismiss key.Binding
+	Quit      key.Binding
+	Dismiss   key.Binding
+	Search    key.Binding
+	SubAgents key.Binding
 }
 
 // DefaultKeyMap returns the default key bindings.
@@ -32,15 +34,23 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("enter", "esc"),
 			key.WithHelp("Enter/Esc", "close"),
 		),
+		Search: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "search"),
+		),
+		SubAgents: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "expand/collapse sub-agents"),
+		),
 	}
 }
 
 // ShortHelp returns the key bindings for the short help view.
 func (k KeyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Up, k.Quit}
+	return []key.Binding{k.Up, k.Search, k.Quit}
 }
 
 // FullHelp returns the key bindings for the full help view.
 func (k KeyMap) FullHelp() [][]key.Binding {
-	return [][]key.Binding{{k.Up, k.Down, k.Quit}}
+	return [][]key.Binding{{k.Up, k.Down, k.Search, k.SubAgents, k.Quit}}
 }
@@ -0,0 +1,139 @@
+// Package tui provides the Bubble Tea TUI for Ralph.
+package tui
+
+import "strings"
+
+// lineBuffer holds a panel's styled lines in a ring buffer. Appending is
+// O(1) however long the session runs and, once maxLines complete lines are
+// held, each new line evicts the oldest, so memory stays bounded.
+//
+// The last line is always the unterminated text written since the final
+// newline (possibly empty), matching how a viewport splits its content.
+type lineBuffer struct {
+	lines    []string // ring storage, grown up to maxLines
+	start    int      // index of the oldest line in lines
+	count    int      // complete lines held
+	maxLines int
+	partial  strings.Builder // text after the last newline
+
+	// foldable marks, parallel to lines, the lines a panel can hide; lines
+	// completed while fold is set are foldable
+	foldable  []bool
+	nFoldable int
+	fold      bool
+}
+
+// newLineBuffer creates a buffer retaining at most maxLines complete lines.
+func newLineBuffer(maxLines int) lineBuffer {
+	if maxLines < 1 {
+		maxLines = 1
+	}
+	return lineBuffer{maxLines: maxLines}
+}
+
+// Write appends text, which may contain newlines. It returns the number of
+// lines evicted to make room.
+func (b *lineBuffer) Write(text string) int {
+	evicted := 0
+	for {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			b.partial.WriteString(text)
+			return evicted
+		}
+		b.partial.WriteString(text[:i])
+		if b.push(b.partial.String()) {
+			evicted++
+		}
+		b.partial.Reset()
+		text = text[i+1:]
+	}
+}
+
+// push adds a complete line, reporting whether the oldest was evicted.
+func (b *lineBuffer) push(line string) bool {
+	if b.fold {
+		b.nFoldable++
+	}
+	if b.count < b.maxLines {
+		b.lines = append(b.lines, line)
+		b.foldable = append(b.foldable, b.fold)
+		b.count++
+		return false
+	}
+	if b.foldable[b.start] {
+		b.nFoldable--
+	}
+	b.lines[b.start] = line
+	b.foldable[b.start] = b.fold
+	b.start = (b.start + 1) % b.maxLines
+	return true
+}
+
+// Partial returns the unterminated last line.
+func (b *lineBuffer) Partial() string {
+	return b.partial.String()
+}
+
+// SetPartial replaces the unterminated last line.
+func (b *lineBuffer) SetPartial(text string) {
+	b.partial.Reset()
+	b.partial.WriteString(text)
+}
+
+// Len returns the number of lines, counting the unterminated last line.
+func (b *lineBuffer) Len() int {
+	return b.count + 1
+}
+
+// Line returns line i, where 0 is the oldest retained line.
+func (b *lineBuffer) Line(i int) string {
+	if i == b.count {
+		return b.partial.String()
+	}
+	return b.lines[(b.start+i)%len(b.lines)]
+}
+
+// Foldable reports whether line i was written while fold was set. The
+// unterminated last line never is.
+func (b *lineBuffer) Foldable(i int) bool {
+	if i == b.count {
+		return false
+	}
+	return b.foldable[(b.start+i)%len(b.lines)]
+}
+
+// Slice returns lines [from, to), clamped to the lines held.
+func (b *lineBuffer) Slice(from, to int) []string {
+	from = max(from, 0)
+	to = min(to, b.Len())
+	if from >= to {
+		return nil
+	}
+	out := make([]string, 0, to-from)
+	for i := from; i < to; i++ {
+		out = append(out, b.Line(i))
+	}
+	return out
+}
+
+// Reset removes all lines.
+func (b *lineBuffer) Reset() {
+	b.lines = nil
+	b.foldable = nil
+	b.nFoldable = 0
+	b.start = 0
+	b.count = 0
+	b.partial.Reset()
+}
+
+// String returns the retained lines joined by newlines.
+func (b *lineBuffer) String() string {
+	var s strings.Builder
+	for i := 0; i < b.count; i++ {
+		s.WriteString(b.Line(i))
+		s.WriteByte('\n')
+	}
+	s.WriteString(b.partial.String())
+	return s.String()
+}
@@ -0,0 +1,367 @@
+package tui
+
+import (
+	"regexp"
+	"strings"
+)
+
+// markdownStream renders markdown streamed into a panel a line at a time.
+// Complete lines are rendered once and kept; the line still being streamed
+// is re-rendered as chunks arrive, styling inline markup only once it is
+// closed, so a partial "**bo" shows as typed rather than flickering.
+type markdownStream struct {
+	pending string // text of the line being streamed
+	prefix  string // text on the panel's last line before the markdown began
+	active  bool   // the pending line is shown on the panel
+	fence   string // marker of the open code block ("```", "~~~~"); empty outside one
+	lang    string // language of the open code block
+}
+
+// renderLine renders a complete line, opening or closing code blocks.
+func (s *markdownStream) renderLine(line string) string {
+	if s.fence != "" {
+		if isClosingFence(line, s.fence) {
+			s.fence, s.lang = "", ""
+			return mdFenceStyle.Render(line)
+		}
+		return highlightCode(line, s.lang)
+	}
+	if fence, lang, ok := openingFence(line); ok {
+		s.fence, s.lang = fence, lang
+		return mdFenceStyle.Render(line)
+	}
+	return renderMarkdownBlock(line)
+}
+
+// renderPartial renders the line being streamed, leaving the block state
+// to renderLine.
+func (s *markdownStream) renderPartial(line string) string {
+	if line == "" {
+		return ""
+	}
+	if s.fence != "" {
+		if strings.HasPrefix(s.fence, strings.TrimSpace(line)) {
+			return mdFenceStyle.Render(line)
+		}
+		return highlightCode(line, s.lang)
+	}
+	if strings.HasPrefix(strings.TrimLeft(line, " "), "```") || strings.HasPrefix(strings.TrimLeft(line, " "), "~~~") {
+		return mdFenceStyle.Render(line)
+	}
+	return renderMarkdownBlock(line)
+}
+
+// openingFence reports whether a line opens a fenced code block, returning
+// its marker and language.
+func openingFence(line string) (fence, lang string, ok bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return "", "", false
+	}
+	n := len(trimmed) - len(strings.TrimLeft(trimmed, trimmed[:1]))
+	if n < 3 {
+		return "", "", false
+	}
+	info := strings.TrimSpace(trimmed[n:])
+	if trimmed[0] == '`' && strings.Contains(info, "`") {
+		return "", "", false
+	}
+	if fields := strings.Fields(info); len(fields) > 0 {
+		lang = strings.ToLower(fields[0])
+	}
+	return trimmed[:n], lang, true
+}
+
+// isClosingFence reports whether a line closes the code block opened by
+// fence: a run of the same character at least as long, and nothing else.
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	return len(trimmed) >= len(fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
+
+var (
+	mdHeadingPattern = regexp.MustCompile(`^ {0,3}(#{1,6})(?: +(.*?))?\s*$`)
+	mdRulePattern    = regexp.MustCompile(`^ {0,3}(?:(?:\* *){3,}|(?:- *){3,}|(?:_ *){3,})$`)
+	mdQuotePattern   = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	mdItemPattern    = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)]) +(.*)$`)
+)
+
+// renderMarkdownBlock renders a line outside code blocks: headings, rules,
+// quotes, and list items, with their inline markup.
+func renderMarkdownBlock(line string) string {
+	if m := mdHeadingPattern.FindStringSubmatch(line); m != nil {
+		return mdHeadingMarkerStyle.Render(m[1]+" ") + mdHeadingStyle.Render(m[2])
+	}
+	if mdRulePattern.MatchString(line) {
+		return mdFenceStyle.Render(line)
+	}
+	if m := mdQuotePattern.FindStringSubmatch(line); m != nil {
+		return mdQuoteStyle.Render(glyph.quote) + renderInline(m[1])
+	}
+	if m := mdItemPattern.FindStringSubmatch(line); m != nil {
+		marker := m[2]
+		if marker == "-" || marker == "*" || marker == "+" {
+			marker = glyph.item
+		}
+		return m[1] + mdItemStyle.Render(marker) + " " + renderInline(m[3])
+	}
+	return renderInline(line)
+}
+
+// renderInline styles code spans, bold and italic text, and links. Markup
+// that isn't closed on the line is left as written.
+func renderInline(text string) string {
+	var out strings.Builder
+	plainStart := 0
+	flush := func(end int) {
+		out.WriteString(text[plainStart:end])
+	}
+
+	for i := 0; i < len(text); {
+		var styled string
+		next := -1
+		switch c := text[i]; {
+		case c == '`':
+			ticks := len(text[i:]) - len(strings.TrimLeft(text[i:], "`"))
+			if end := strings.Index(text[i+ticks:], text[i:i+ticks]); end >= 0 {
+				code := text[i+ticks : i+ticks+end]
+				styled, next = mdCodeStyle.Render(code), i+ticks+end+ticks
+			} else {
+				i += ticks
+				continue
+			}
+		case (c == '*' || c == '_') && strings.HasPrefix(text[i:], strings.Repeat(string(c), 2)):
+			marker := text[i : i+2]
+			if end := strings.Index(text[i+2:], marker); end > 0 && emphasisBoundary(text, i, i+2+end+2, c) {
+				styled, next = mdBoldStyle.Render(text[i+2:i+2+end]), i+2+end+2
+			}
+		case c == '*' || c == '_':
+			if i+1 < len(text) && text[i+1] != ' ' {
+				if end := strings.IndexByte(text[i+1:], c); end > 0 && text[i+end] != ' ' && emphasisBoundary(text, i, i+1+end+1, c) {
+					styled, next = mdItalicStyle.Render(text[i+1:i+1+end]), i+1+end+1
+				}
+			}
+		case c == '[':
+			if close := strings.IndexByte(text[i:], ']'); close > 1 && strings.HasPrefix(text[i+close:], "](") {
+				if end := strings.IndexByte(text[i+close+2:], ')'); end >= 0 {
+					label := text[i+1 : i+close]
+					url := text[i+close+2 : i+close+2+end]
+					styled = mdLinkStyle.Render(label) + mdLinkURLStyle.Render(" ("+url+")")
+					next = i + close + 2 + end + 1
+				}
+			}
+		}
+		if next < 0 {
+			i++
+			continue
+		}
+		flush(i)
+		out.WriteString(styled)
+		i, plainStart = next, next
+	}
+	flush(len(text))
+	return out.String()
+}
+
+// emphasisBoundary reports whether emphasis spanning text[start:end] stands
+// apart from the words around it. Underscores inside words, as in
+// snake_case names, aren't emphasis.
+func emphasisBoundary(text string, start, end int, marker byte) bool {
+	if marker != '_' {
+		return true
+	}
+	before := start == 0 || !isWordByte(text[start-1])
+	after := end >= len(text) || !isWordByte(text[end])
+	return before && after
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= 0x80
+}
+
+// =============================================================================
+// Code highlighting
+// =============================================================================
+
+// codeTokenKind is what a token of highlighted code is.
+type codeTokenKind int
+
+const (
+	codePlain codeTokenKind = iota
+	codeKeyword
+	codeString
+	codeNumber
+	codeComment
+	codeFunction
+	codeKey // Keys of JSON and YAML mappings
+	codeAdded
+	codeRemoved
+	codeHunk
+)
+
+// codeToken is a run of code of one kind.
+type codeToken struct {
+	kind codeTokenKind
+	text string
+}
+
+// codeLanguage is what the highlighter knows of a language.
+type codeLanguage struct {
+	keywords map[string]bool
+	comment  string // Line comment marker
+	quotes   string // String delimiters
+}
+
+// words returns the set of space-separated words in s.
+func words(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		set[w] = true
+	}
+	return set
+}
+
+var (
+	cLikeKeywords = "break case const continue default do else for if return static struct switch typedef void while " +
+		"int char long short float double unsigned signed sizeof enum union extern goto true false null"
+
+	codeLanguages = map[string]codeLanguage{
+		"go": {words("break case chan const continue default defer else fallthrough for func go goto if import " +
+			"interface map package range return select struct switch type var true false nil iota"), "//", "\"'`"},
+		"python": {words("and as assert async await break class continue def del elif else except finally for from " +
+			"global if import in is lambda nonlocal not or pass raise return try while with yield True False None self"), "#", "\"'"},
+		"javascript": {words("async await break case catch class const continue default delete do else export extends " +
+			"finally for function if import in instanceof let new of return static super switch this throw try typeof " +
+			"var void while yield true false null undefined interface type enum implements readonly"), "//", "\"'`"},
+		"rust": {words("as async await break const continue crate dyn else enum extern fn for if impl in let loop match " +
+			"mod move mut pub ref return self Self static struct super trait type unsafe use where while true false"), "//", "\""},
+		"shell": {words("if then else elif fi for while until do done case esac in function return local export " +
+			"set unset shift exit echo cd source"), "#", "\"'"},
+		"sql": {words("select from where and or not insert into values update set delete create table index drop " +
+			"alter add primary key foreign references join left right inner outer on as group by order having " +
+			"limit offset distinct union all null is in exists like between case when then else end begin commit " +
+			"SELECT FROM WHERE AND OR NOT INSERT INTO VALUES UPDATE SET DELETE CREATE TABLE INDEX DROP ALTER ADD " +
+			"PRIMARY KEY FOREIGN REFERENCES JOIN LEFT RIGHT INNER OUTER ON AS GROUP BY ORDER HAVING LIMIT OFFSET " +
+			"DISTINCT UNION ALL NULL IS IN EXISTS LIKE BETWEEN CASE WHEN THEN ELSE END BEGIN COMMIT"), "--", "'\""},
+		"c":    {words(cLikeKeywords + " class public private protected new this virtual template namespace using"), "//", "\"'"},
+		"json": {words("true false null"), "", "\""},
+		"yaml": {words("true false null yes no on off"), "#", "\"'"},
+	}
+
+	// codeLanguageAliases maps the names fenced code blocks use to a language
+	codeLanguageAliases = map[string]string{
+		"golang": "go", "py": "python", "python3": "python",
+		"js": "javascript", "jsx": "javascript", "ts": "javascript", "tsx": "javascript", "typescript": "javascript",
+		"rs": "rust", "sh": "shell", "bash": "shell", "zsh": "shell", "console": "shell",
+		"cpp": "c", "c++": "c", "h": "c", "java": "c", "csharp": "c", "cs": "c", "kotlin": "c",
+		"yml": "yaml", "jsonc": "json", "postgres": "sql", "sqlite": "sql", "patch": "diff",
+	}
+)
+
+// highlightCode renders a line of a code block in the given language.
+// Languages it doesn't know are shown in the code color, with no tokens
+// picked out.
+func highlightCode(line, lang string) string {
+	var out strings.Builder
+	for _, tok := range tokenizeCode(line, lang) {
+		out.WriteString(codeTokenStyle(tok.kind).Render(tok.text))
+	}
+	return out.String()
+}
+
+// tokenizeCode splits a line of code into tokens. Each line is tokenized
+// on its own, so strings and comments spanning lines aren't recognized past
+// their first.
+func tokenizeCode(line, lang string) []codeToken {
+	if alias, ok := codeLanguageAliases[lang]; ok {
+		lang = alias
+	}
+	if lang == "diff" {
+		return []codeToken{{diffLineKind(line), line}}
+	}
+	spec, ok := codeLanguages[lang]
+	if !ok || line == "" {
+		return []codeToken{{codePlain, line}}
+	}
+
+	var tokens []codeToken
+	add := func(kind codeTokenKind, text string) {
+		if n := len(tokens); n > 0 && tokens[n-1].kind == kind {
+			tokens[n-1].text += text
+			return
+		}
+		tokens = append(tokens, codeToken{kind, text})
+	}
+
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case spec.comment != "" && strings.HasPrefix(line[i:], spec.comment):
+			add(codeComment, line[i:])
+			return tokens
+		case strings.IndexByte(spec.quotes, c) >= 0:
+			end := i + 1
+			for end < len(line) && line[end] != c {
+				if line[end] == '\\' && c != '`' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(line))
+			kind := codeString
+			if (lang == "json" || lang == "yaml") && strings.HasPrefix(strings.TrimLeft(line[end:], " "), ":") {
+				kind = codeKey
+			}
+			add(kind, line[i:end])
+			i = end
+		case c >= '0' && c <= '9':
+			end := i + 1
+			for end < len(line) && (isWordByte(line[end]) || line[end] == '.') {
+				end++
+			}
+			add(codeNumber, line[i:end])
+			i = end
+		case isWordByte(c):
+			end := i + 1
+			for end < len(line) && (isWordByte(line[end]) || lang == "yaml" && line[end] == '-') {
+				end++
+			}
+			word := line[i:end]
+			rest := line[end:]
+			switch {
+			case spec.keywords[word]:
+				add(codeKeyword, word)
+			case lang == "yaml" && strings.HasPrefix(rest, ":"):
+				add(codeKey, word)
+			case strings.HasPrefix(rest, "(") && lang != "json" && lang != "yaml":
+				add(codeFunction, word)
+			default:
+				add(codePlain, word)
+			}
+			i = end
+		default:
+			end := i + 1
+			for end < len(line) && !isWordByte(line[end]) && strings.IndexByte(spec.quotes, line[end]) < 0 &&
+				!(spec.comment != "" && strings.HasPrefix(line[end:], spec.comment)) {
+				end++
+			}
+			add(codePlain, line[i:end])
+			i = end
+		}
+	}
+	return tokens
+}
+
+// diffLineKind classifies a line of a diff.
+func diffLineKind(line string) codeTokenKind {
+	switch {
+	case strings.HasPrefix(line, "@@"):
+		return codeHunk
+	case strings.HasPrefix(line, "+"):
+		return codeAdded
+	case strings.HasPrefix(line, "-"):
+		return codeRemoved
+	default:
+		return codePlain
+	}
+}
@@ -0,0 +1,101 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdownBlock(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"plain text", "plain text"},
+		{"## Progress", "## Progress"},
+		{"**Done** with `loop.go` and *more*", "Done with loop.go and more"},
+		{"call ``a `b` c`` here", "call a `b` c here"},
+		{"see [the docs](https://example.com/docs)", "see the docs (https://example.com/docs)"},
+		{"- first item", "• first item"},
+		{"  * nested **bold**", "  • nested bold"},
+		{"3. third", "3. third"},
+		{"> quoted _text_", "┃ quoted text"},
+		{"snake_case_name and 2 * 3 * 4", "snake_case_name and 2 * 3 * 4"},
+		{"**unclosed and `open", "**unclosed and `open"},
+	}
+	for _, tt := range tests {
+		if got := renderMarkdownBlock(tt.line); got != tt.want {
+			t.Errorf("renderMarkdownBlock(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestTokenizeCode(t *testing.T) {
+	tests := []struct {
+		line, lang string
+		want       []codeToken
+	}{
+		{`x := fmt.Sprintf("%d", 42) // note`, "go", []codeToken{
+			{codePlain, "x := fmt."}, {codeFunction, "Sprintf"}, {codePlain, "("}, {codeString, `"%d"`},
+			{codePlain, ", "}, {codeNumber, "42"}, {codePlain, ") "}, {codeComment, "// note"},
+		}},
+		{`return "unterminated`, "golang", []codeToken{
+			{codeKeyword, "return"}, {codePlain, " "}, {codeString, `"unterminated`},
+		}},
+		{`  "name": true,`, "json", []codeToken{
+			{codePlain, "  "}, {codeKey, `"name"`}, {codePlain, ": "}, {codeKeyword, "true"}, {codePlain, ","},
+		}},
+		{"-removed line", "diff", []codeToken{{codeRemoved, "-removed line"}}},
+		{"anything goes", "brainfuck", []codeToken{{codePlain, "anything goes"}}},
+	}
+	for _, tt := range tests {
+		got := tokenizeCode(tt.line, tt.lang)
+		if len(got) != len(tt.want) {
+			t.Errorf("tokenizeCode(%q, %q) = %+v, want %+v", tt.line, tt.lang, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("tokenizeCode(%q, %q)[%d] = %+v, want %+v", tt.line, tt.lang, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestScrollablePanel_AppendMarkdown(t *testing.T) {
+	p := NewScrollablePanel("Feed", true)
+	var tee strings.Builder
+	p.SetTee(&tee)
+
+	p.AppendContent("before: ")
+	p.AppendMarkdown("Some **bo")
+	if got := p.Content(); got != "before: Some **bo" {
+		t.Errorf("partial line = %q, want the markup as typed", got)
+	}
+	p.AppendMarkdown("ld** text\n```go\nx := \"**")
+	p.AppendMarkdown("raw**\"\n```\n- item")
+	want := "before: Some bold text\n```go\nx := \"**raw**\"\n```\n• item"
+	if got := p.Content(); got != want {
+		t.Errorf("Content() = %q, want %q", got, want)
+	}
+	if got := tee.String(); got != "before: Some bold text\n```go\nx := \"**raw**\"\n```\n" {
+		t.Errorf("tee got %q, want only complete lines", got)
+	}
+
+	// Other content ends the markdown, writing the line being streamed
+	p.AppendMarkdown(" and `code`")
+	p.AppendLine("\n▸ Read")
+	if got := p.Content(); !strings.HasSuffix(got, "• item and code\n▸ Read\n") {
+		t.Errorf("Content() = %q, want the streamed line ended before the tool call", got)
+	}
+	if !strings.HasSuffix(tee.String(), "• item and code\n▸ Read\n") {
+		t.Errorf("tee got %q, want the ended line", tee.String())
+	}
+
+	// A code block left open doesn't carry over to later markdown
+	p.AppendMarkdown("```\n**bold**")
+	p.AppendLine("")
+	p.AppendMarkdown("**bold**\n")
+	if got := p.Content(); !strings.HasSuffix(got, "```\n**bold**\nbold\n") {
+		t.Errorf("Content() = %q, want the code block closed by the interruption", got)
+	}
+}
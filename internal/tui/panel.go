@@ -2,35 +2,71 @@
 package tui
 
 import (
+	"io"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
-// ScrollablePanel is a generic scrollable text panel.
+// defaultMaxPanelLines is the number of lines a panel retains before the
+// oldest are dropped.
+const defaultMaxPanelLines = 10000
+
+// ScrollablePanel is a generic scrollable text panel. Its content is kept
+// as a bounded ring buffer of lines, and rendering only touches the lines
+// in view, so streaming into a long session stays cheap.
 type ScrollablePanel struct {
 	Title      string
-	viewport   viewport.Model
-	content    strings.Builder
+	lines      lineBuffer
+	keys       viewport.KeyMap
+	yOffset    int // index of the first line in view
 	AutoScroll bool
 	Focused    bool
 	width      int
 	height     int
-	dirty      bool // content changed since last viewport sync
+	viewWidth  int // width of the content area
+	viewHeight int // height of the content area
+
+	// tee receives everything appended to the panel (nil = none)
+	tee io.Writer
+
+	// folded hides the lines appended with AppendFoldableLine; they are
+	// kept, and shown again when it's cleared
+	folded bool
+
+	// md renders markdown appended with AppendMarkdown
+	md markdownStream
 }
 
 // NewScrollablePanel creates a new scrollable panel.
 func NewScrollablePanel(title string, autoScroll bool) ScrollablePanel {
-	vp := viewport.New(80, 10)
 	return ScrollablePanel{
 		Title:      title,
-		viewport:   vp,
+		lines:      newLineBuffer(defaultMaxPanelLines),
+		keys:       viewport.DefaultKeyMap(),
 		AutoScroll: autoScroll,
+		viewWidth:  80,
+		viewHeight: 10,
 	}
 }
 
+// SetTee copies everything appended to the panel from now on to w, e.g. a
+// scrollback file, including lines the panel later drops.
+func (p *ScrollablePanel) SetTee(w io.Writer) {
+	p.tee = w
+}
+
+// SetMaxLines sets how many lines the panel retains. Existing content is
+// cleared.
+func (p *ScrollablePanel) SetMaxLines(n int) {
+	p.lines = newLineBuffer(n)
+	p.md = markdownStream{}
+	p.yOffset = 0
+}
+
 // SetSize sets the panel dimensions.
 func (p *ScrollablePanel) SetSize(width, height int) {
 	p.width = width
@@ -46,57 +82,133 @@ func (p *ScrollablePanel) SetSize(width, height int) {
 	// Title line takes 1 row + newline separator
 	titleHeight := 1
 
-	// Viewport gets remaining space
-	viewportWidth := width - frameH
-	viewportHeight := height - frameV - titleHeight
+	// Content area gets remaining space
+	viewWidth := width - frameH
+	viewHeight := height - frameV - titleHeight
 
 	// Clamp to minimums
-	if viewportWidth < 10 {
-		viewportWidth = 10
+	if viewWidth < 10 {
+		viewWidth = 10
 	}
-	if viewportHeight < 3 {
-		viewportHeight = 3
+	if viewHeight < 3 {
+		viewHeight = 3
 	}
 
-	p.viewport.Width = viewportWidth
-	p.viewport.Height = viewportHeight
+	p.viewWidth = viewWidth
+	p.viewHeight = viewHeight
+	p.yOffset = min(p.yOffset, p.maxYOffset())
+}
+
+// ContentWidth returns the width available to the panel's lines.
+func (p *ScrollablePanel) ContentWidth() int {
+	return p.viewWidth
 }
 
 // SetContent replaces the entire content.
 func (p *ScrollablePanel) SetContent(content string) {
-	p.content.Reset()
-	p.content.WriteString(content)
-	p.viewport.SetContent(content)
+	p.md = markdownStream{}
+	p.lines.Reset()
+	p.lines.Write(content)
+	p.yOffset = min(p.yOffset, p.maxYOffset())
 	if p.AutoScroll {
-		p.viewport.GotoBottom()
+		p.yOffset = p.maxYOffset()
 	}
 }
 
-// AppendContent adds content to the end.
-// This is O(1) - viewport sync is deferred until View() is called.
+// AppendContent adds content to the end. This is O(len(content)):
+// existing lines are never re-split or re-rendered.
 func (p *ScrollablePanel) AppendContent(content string) {
-	p.content.WriteString(content)
-	p.dirty = true
+	p.write(content)
 }
 
 // AppendLine adds a line of content with a newline.
-// This is O(1) - viewport sync is deferred until View() is called.
 func (p *ScrollablePanel) AppendLine(line string) {
-	p.content.WriteString(line)
-	p.content.WriteString("\n")
-	p.dirty = true
+	p.write(line)
+	p.write("\n")
+}
+
+// AppendFoldableLine adds a line that is hidden while the panel is folded.
+func (p *ScrollablePanel) AppendFoldableLine(line string) {
+	p.endMarkdown()
+	p.output(line)
+	p.lines.fold = true
+	p.output("\n")
+	p.lines.fold = false
+}
+
+// SetFolded hides or shows the lines appended with AppendFoldableLine,
+// including those already in the panel.
+func (p *ScrollablePanel) SetFolded(folded bool) {
+	p.folded = folded
+	p.yOffset = min(p.yOffset, p.maxYOffset())
+	if p.AutoScroll {
+		p.yOffset = p.maxYOffset()
+	}
+}
+
+// AppendMarkdown adds streamed markdown to the end, rendering each line as
+// it completes. The line still being streamed is shown as rendered so far;
+// the next append of any other content ends the markdown.
+func (p *ScrollablePanel) AppendMarkdown(text string) {
+	if !p.md.active {
+		p.md.active = true
+		p.md.prefix = p.lines.Partial()
+	}
+	p.md.pending += text
+	for {
+		i := strings.IndexByte(p.md.pending, '\n')
+		if i < 0 {
+			break
+		}
+		line := p.md.renderLine(p.md.pending[:i])
+		p.md.pending = p.md.pending[i+1:]
+		p.lines.SetPartial(p.md.prefix)
+		p.md.prefix = ""
+		p.output(line + "\n")
+	}
+	p.lines.SetPartial(p.md.prefix + p.md.renderPartial(p.md.pending))
+}
+
+// endMarkdown ends streamed markdown, writing the line being streamed as
+// it stands and closing any open code block.
+func (p *ScrollablePanel) endMarkdown() {
+	if !p.md.active {
+		return
+	}
+	p.lines.SetPartial(p.md.prefix)
+	if p.md.pending != "" {
+		p.output(p.md.renderLine(p.md.pending))
+	}
+	p.md = markdownStream{}
+}
+
+// write appends text after ending any streamed markdown.
+func (p *ScrollablePanel) write(text string) {
+	p.endMarkdown()
+	p.output(text)
+}
+
+// output appends text, keeping a scrolled-back view on the same lines when
+// old lines are dropped.
+func (p *ScrollablePanel) output(text string) {
+	if p.tee != nil {
+		_, _ = io.WriteString(p.tee, text)
+	}
+	if evicted := p.lines.Write(text); evicted > 0 && !p.AutoScroll {
+		p.yOffset = max(0, p.yOffset-evicted)
+	}
 }
 
 // Clear clears all content.
 func (p *ScrollablePanel) Clear() {
-	p.content.Reset()
-	p.viewport.SetContent("")
-	p.dirty = false
+	p.md = markdownStream{}
+	p.lines.Reset()
+	p.yOffset = 0
 }
 
-// Content returns the current content.
+// Content returns the retained content.
 func (p *ScrollablePanel) Content() string {
-	return p.content.String()
+	return p.lines.String()
 }
 
 // SetFocused sets the focus state.
@@ -110,79 +222,120 @@ func (p *ScrollablePanel) Update(msg tea.Msg) tea.Cmd {
 		return nil
 	}
 
-	var cmd tea.Cmd
-	p.viewport, cmd = p.viewport.Update(msg)
-
-	// Track if user scrolled away from bottom
-	if _, ok := msg.(tea.KeyMsg); ok {
-		if !p.viewport.AtBottom() {
-			p.AutoScroll = false
-		} else {
-			p.AutoScroll = true
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, p.keys.PageDown):
+			p.PageDown()
+		case key.Matches(msg, p.keys.PageUp):
+			p.PageUp()
+		case key.Matches(msg, p.keys.HalfPageDown):
+			p.ScrollDown(p.viewHeight / 2)
+		case key.Matches(msg, p.keys.HalfPageUp):
+			p.ScrollUp(p.viewHeight / 2)
+		case key.Matches(msg, p.keys.Down):
+			p.ScrollDown(1)
+		case key.Matches(msg, p.keys.Up):
+			p.ScrollUp(1)
+		}
+	case tea.MouseMsg:
+		if msg.Action != tea.MouseActionPress {
+			break
+		}
+		switch msg.Button {
+		case tea.MouseButtonWheelDown:
+			p.ScrollDown(3)
+		case tea.MouseButtonWheelUp:
+			p.ScrollUp(3)
 		}
 	}
 
-	return cmd
+	return nil
 }
 
 // ScrollUp scrolls up by n lines.
 func (p *ScrollablePanel) ScrollUp(n int) {
-	p.viewport.LineUp(n)
+	p.follow()
+	p.yOffset = max(0, p.yOffset-n)
 	p.AutoScroll = false
 }
 
 // ScrollDown scrolls down by n lines.
 func (p *ScrollablePanel) ScrollDown(n int) {
-	p.viewport.LineDown(n)
-	if p.viewport.AtBottom() {
+	p.follow()
+	p.yOffset = min(p.maxYOffset(), p.yOffset+n)
+	if p.AtBottom() {
 		p.AutoScroll = true
 	}
 }
 
 // PageUp scrolls up by one page.
 func (p *ScrollablePanel) PageUp() {
-	p.viewport.ViewUp()
-	p.AutoScroll = false
+	p.ScrollUp(p.viewHeight)
 }
 
 // PageDown scrolls down by one page.
 func (p *ScrollablePanel) PageDown() {
-	p.viewport.ViewDown()
-	if p.viewport.AtBottom() {
-		p.AutoScroll = true
-	}
+	p.ScrollDown(p.viewHeight)
 }
 
 // GotoTop scrolls to the top.
 func (p *ScrollablePanel) GotoTop() {
-	p.viewport.GotoTop()
+	p.yOffset = 0
 	p.AutoScroll = false
 }
 
 // GotoBottom scrolls to the bottom.
 func (p *ScrollablePanel) GotoBottom() {
-	p.viewport.GotoBottom()
+	p.yOffset = p.maxYOffset()
 	p.AutoScroll = true
 }
 
-// syncViewport updates the viewport with accumulated content changes.
-// This batches multiple AppendContent/AppendLine calls into a single
-// viewport update, converting O(n²) streaming behavior to O(n).
-func (p *ScrollablePanel) syncViewport() {
-	if !p.dirty {
-		return
+// maxYOffset returns the offset that shows the last page of lines.
+func (p *ScrollablePanel) maxYOffset() int {
+	return max(0, p.shownLen()-p.viewHeight)
+}
+
+// shownLen returns the number of lines shown, leaving out folded ones.
+func (p *ScrollablePanel) shownLen() int {
+	if !p.folded {
+		return p.lines.Len()
+	}
+	return p.lines.Len() - p.lines.nFoldable
+}
+
+// shownSlice returns shown lines [from, to). Only while lines are folded
+// does it walk the buffer to skip them.
+func (p *ScrollablePanel) shownSlice(from, to int) []string {
+	if !p.folded || p.lines.nFoldable == 0 {
+		return p.lines.Slice(from, to)
+	}
+	var out []string
+	shown := 0
+	for i := 0; i < p.lines.Len() && shown < to; i++ {
+		if p.lines.Foldable(i) {
+			continue
+		}
+		if shown >= from {
+			out = append(out, p.lines.Line(i))
+		}
+		shown++
 	}
-	p.viewport.SetContent(p.content.String())
+	return out
+}
+
+// follow moves the view to the bottom if auto-scrolling. Appends don't
+// move the view themselves; it catches up here, once per render.
+func (p *ScrollablePanel) follow() {
 	if p.AutoScroll {
-		p.viewport.GotoBottom()
+		p.yOffset = p.maxYOffset()
 	}
-	p.dirty = false
 }
 
 // View renders the panel.
 func (p *ScrollablePanel) View() string {
-	// Sync any pending content changes before rendering
-	p.syncViewport()
+	// Catch up with lines appended since the last render
+	p.follow()
 
 	// Get frame size dynamically
 	style := panelStyle
@@ -216,11 +369,15 @@ func (p *ScrollablePanel) View() string {
 	}
 	titleLine := title + strings.Repeat(" ", spacing) + scrollIndicator
 
-	// Viewport content
-	viewportContent := p.viewport.View()
+	// Only the lines in view are rendered, clipped to the content area
+	visible := p.shownSlice(p.yOffset, p.yOffset+p.viewHeight)
+	body := lipgloss.NewStyle().
+		Width(p.viewWidth).Height(p.viewHeight).
+		MaxWidth(p.viewWidth).MaxHeight(p.viewHeight).
+		Render(strings.Join(visible, "\n"))
 
 	// Combine
-	content := titleLine + "\n" + viewportContent
+	content := titleLine + "\n" + body
 
 	// Apply style with MaxHeight as safety cap to prevent overflow
 	if p.Focused {
@@ -232,12 +389,12 @@ func (p *ScrollablePanel) View() string {
 	return style.Render(content)
 }
 
-// AtBottom returns whether the viewport is at the bottom.
+// AtBottom returns whether the last line is in view.
 func (p *ScrollablePanel) AtBottom() bool {
-	return p.viewport.AtBottom()
+	return p.AutoScroll || p.yOffset >= p.maxYOffset()
 }
 
-// AtTop returns whether the viewport is at the top.
+// AtTop returns whether the first retained line is in view.
 func (p *ScrollablePanel) AtTop() bool {
-	return p.viewport.AtTop()
+	return p.yOffset <= 0 && (!p.AutoScroll || p.maxYOffset() == 0)
 }
@@ -0,0 +1,112 @@
+// Package tui provides the Bubble Tea TUI for Ralph.
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+// searchResultLimit caps the number of results shown in the search overlay.
+const searchResultLimit = 20
+
+// SearchFunc searches progress, learnings, and reviewer feedback history.
+type SearchFunc func(query string, limit int) ([]*db.SearchResult, error)
+
+// SetSearch enables the history search overlay, opened with "/".
+func (m *Model) SetSearch(search SearchFunc) {
+	m.search = search
+}
+
+// openSearch shows the search prompt in the floating window.
+func (m *Model) openSearch() {
+	m.searching = true
+	m.searchQuery = ""
+	m.floatingWindow.SetTitle("Search History")
+	m.floatingWindow.SetBorderColor(colorMagenta)
+	m.renderSearchPrompt()
+}
+
+// renderSearchPrompt redraws the query being typed.
+func (m *Model) renderSearchPrompt() {
+	m.floatingWindow.Show(fmt.Sprintf("/ %s%s\n\n%s", m.searchQuery, glyph.cursor,
+		helpDescStyle.Render("Enter to search · Esc to cancel")))
+}
+
+// handleSearchInput edits the search query while the prompt is open.
+func (m Model) handleSearchInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		m.quitting = true
+		return m, tea.Quit
+
+	case tea.KeyEsc:
+		m.searching = false
+		m.floatingWindow.Hide()
+
+	case tea.KeyEnter:
+		m.searching = false
+		m.showSearchResults()
+
+	case tea.KeyBackspace:
+		if runes := []rune(m.searchQuery); len(runes) > 0 {
+			m.searchQuery = string(runes[:len(runes)-1])
+		}
+		m.renderSearchPrompt()
+
+	case tea.KeySpace:
+		m.searchQuery += " "
+		m.renderSearchPrompt()
+
+	case tea.KeyRunes:
+		m.searchQuery += string(msg.Runes)
+		m.renderSearchPrompt()
+	}
+
+	return m, nil
+}
+
+// showSearchResults runs the query and shows matches in the floating window.
+func (m *Model) showSearchResults() {
+	query := strings.TrimSpace(m.searchQuery)
+	if query == "" {
+		m.floatingWindow.Hide()
+		return
+	}
+
+	m.floatingWindow.SetTitle(fmt.Sprintf("Search: %s", query))
+	results, err := m.search(query, searchResultLimit)
+	if err != nil {
+		m.floatingWindow.SetBorderColor(colorRed)
+		m.floatingWindow.Show(fmt.Sprintf("Search failed: %v", err))
+		return
+	}
+	m.floatingWindow.Show(formatSearchResults(results))
+}
+
+// formatSearchResults renders matches with their plan and iteration.
+func formatSearchResults(results []*db.SearchResult) string {
+	if len(results) == 0 {
+		return "No matches"
+	}
+
+	var b strings.Builder
+	for i, r := range results {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(helpKeyStyle.Render(fmt.Sprintf("%s · iteration %d · %s", r.PlanID, r.Iteration, r.Kind)))
+		b.WriteString("\n")
+		b.WriteString(r.Snippet)
+	}
+	return b.String()
+}
+
+// isSearchKey reports whether msg opens the search overlay.
+func (m Model) isSearchKey(msg tea.KeyMsg) bool {
+	return m.search != nil && key.Matches(msg, m.keys.Search)
+}
@@ -8,47 +8,38 @@ import (
 )
 
 // =============================================================================
-// COLOR PALETTE - Monokai Pro with depth-through-intensity
+// COLOR PALETTE - set from the theme by SetTheme; the dark theme is Monokai
+// Pro with depth-through-intensity
 // =============================================================================
 
 var (
 	// Base colors
-	colorForeground = lipgloss.Color("#fcfcfa")
-	colorBackground = lipgloss.Color("#2d2a2e") // For future use
+	colorForeground lipgloss.TerminalColor
+	colorBackground lipgloss.TerminalColor // For future use
 
 	// Cyan family (Claude output, Read tools)
-	colorCyan      = lipgloss.Color("#78dce8") // Primary
-	colorCyanLight = lipgloss.Color("#a1eaf8") // Light
-	colorCyanDim   = lipgloss.Color("#4b8a94") // Dim
+	colorCyan, colorCyanLight, colorCyanDim lipgloss.TerminalColor
 
 	// Green family (Success, Bash commands, commits)
-	colorGreen      = lipgloss.Color("#a9dc76") // Primary
-	colorGreenLight = lipgloss.Color("#c4e8a4") // Light
-	colorGreenDim   = lipgloss.Color("#6a8a4a") // Dim
+	colorGreen, colorGreenLight, colorGreenDim lipgloss.TerminalColor
 
 	// Yellow family (Write/Edit tools, focus, attention)
-	colorYellow      = lipgloss.Color("#ffd866") // Primary
-	colorYellowLight = lipgloss.Color("#ffe9a0") // Light
-	colorYellowDim   = lipgloss.Color("#9a8340") // Dim
+	colorYellow, colorYellowLight, colorYellowDim lipgloss.TerminalColor
 
 	// Orange family (Running status, misc tools)
-	colorOrange      = lipgloss.Color("#fc9867") // Primary
-	colorOrangeLight = lipgloss.Color("#fdb899") // Light
-	colorOrangeDim   = lipgloss.Color("#9a5e3f") // Dim
+	colorOrange, colorOrangeLight, colorOrangeDim lipgloss.TerminalColor
 
 	// Red family (Errors only)
-	colorRed      = lipgloss.Color("#ff6188") // Primary
-	colorRedLight = lipgloss.Color("#ff97ab") // Light
-	colorRedDim   = lipgloss.Color("#993a52") // Dim
+	colorRed, colorRedLight, colorRedDim lipgloss.TerminalColor
 
 	// Magenta family (Structure, Reviewing, Search tools)
-	colorMagenta      = lipgloss.Color("#ab9df2") // Primary
-	colorMagentaLight = lipgloss.Color("#c9bff7") // Light
-	colorMagentaDim   = lipgloss.Color("#6e6494") // Dim
+	colorMagenta, colorMagentaLight, colorMagentaDim lipgloss.TerminalColor
 
 	// Neutral family
-	colorGray    = lipgloss.Color("#727072")
-	colorDimGray = lipgloss.Color("#5b595c")
+	colorGray, colorDimGray lipgloss.TerminalColor
+
+	// Borders of panels and of floating windows
+	panelBorder, floatingBorder lipgloss.Border
 )
 
 // =============================================================================
@@ -88,63 +79,69 @@ func GetToolCategory(toolName string) ToolCategory {
 
 // Panel styles
 var (
+	headerStyle, headerLabelStyle, headerValueStyle, progressBarStyle,
+	progressFillStyle, progressEmptyStyle, panelStyle, panelTitleStyle,
+	panelFocusedStyle, statusBarStyle, scrollIndicatorStyle lipgloss.Style
+)
+
+func buildPanelStyles() {
 	// headerStyle is used for the header panel border
 	headerStyle = lipgloss.NewStyle().
-			BorderStyle(lipgloss.RoundedBorder()).
-			BorderForeground(colorDimGray).
-			Padding(0, 1)
+		BorderStyle(panelBorder).
+		BorderForeground(colorDimGray).
+		Padding(0, 1)
 
 	// headerLabelStyle is used for labels in the header
 	headerLabelStyle = lipgloss.NewStyle().
-				Foreground(colorGray)
+		Foreground(colorGray)
 
 	// headerValueStyle is used for values in the header
 	headerValueStyle = lipgloss.NewStyle().
-				Foreground(colorForeground).
-				Bold(true)
+		Foreground(colorForeground).
+		Bold(true)
 
 	// progressBarStyle is the outer border for the progress bar section
 	progressBarStyle = lipgloss.NewStyle().
-				BorderStyle(lipgloss.RoundedBorder()).
-				BorderForeground(colorDimGray).
-				Padding(0, 1)
+		BorderStyle(panelBorder).
+		BorderForeground(colorDimGray).
+		Padding(0, 1)
 
 	// progressFillStyle is the filled portion of the progress bar
 	progressFillStyle = lipgloss.NewStyle().
-				Foreground(colorGreen)
+		Foreground(colorGreen)
 
 	// progressEmptyStyle is the empty portion of the progress bar
 	progressEmptyStyle = lipgloss.NewStyle().
-				Foreground(colorDimGray)
+		Foreground(colorDimGray)
 
 	// panelStyle is used for scrollable panels (prompt and output)
 	panelStyle = lipgloss.NewStyle().
-			BorderStyle(lipgloss.RoundedBorder()).
-			BorderForeground(colorDimGray).
-			Padding(0, 1)
+		BorderStyle(panelBorder).
+		BorderForeground(colorDimGray).
+		Padding(0, 1)
 
 	// panelTitleStyle is used for panel titles
 	panelTitleStyle = lipgloss.NewStyle().
-			Foreground(colorMagenta).
-			Bold(true)
+		Foreground(colorMagenta).
+		Bold(true)
 
 	// panelFocusedStyle is used for focused panel border
 	panelFocusedStyle = lipgloss.NewStyle().
-				BorderStyle(lipgloss.RoundedBorder()).
-				BorderForeground(colorYellow).
-				Padding(0, 1)
+		BorderStyle(panelBorder).
+		BorderForeground(colorYellow).
+		Padding(0, 1)
 
 	// statusBarStyle is used for the status bar
 	statusBarStyle = lipgloss.NewStyle().
-			BorderStyle(lipgloss.RoundedBorder()).
-			BorderForeground(colorDimGray).
-			Padding(0, 1)
+		BorderStyle(panelBorder).
+		BorderForeground(colorDimGray).
+		Padding(0, 1)
 
 	// scrollIndicatorStyle is for scroll indicators
 	scrollIndicatorStyle = lipgloss.NewStyle().
-				Foreground(colorDimGray).
-				Italic(true)
-)
+		Foreground(colorDimGray).
+		Italic(true)
+}
 
 // =============================================================================
 // STATUS STYLES
@@ -152,79 +149,91 @@ var (
 
 // Status indicator styles
 var (
+	statusRunningStyle, statusDevelopingStyle, statusReviewingStyle,
+	statusCompletedStyle, statusFailedStyle, statusStoppedStyle,
+	statusPendingStyle lipgloss.Style
+)
+
+func buildStatusStyles() {
 	statusRunningStyle = lipgloss.NewStyle().
-				Foreground(colorOrange).
-				Bold(true)
+		Foreground(colorOrange).
+		Bold(true)
 
 	statusDevelopingStyle = lipgloss.NewStyle().
-				Foreground(colorCyan).
-				Bold(true)
+		Foreground(colorCyan).
+		Bold(true)
 
 	statusReviewingStyle = lipgloss.NewStyle().
-				Foreground(colorMagenta).
-				Bold(true)
+		Foreground(colorMagenta).
+		Bold(true)
 
 	statusCompletedStyle = lipgloss.NewStyle().
-				Foreground(colorGreen).
-				Bold(true)
+		Foreground(colorGreen).
+		Bold(true)
 
 	statusFailedStyle = lipgloss.NewStyle().
-				Foreground(colorRed).
-				Bold(true)
+		Foreground(colorRed).
+		Bold(true)
 
 	statusStoppedStyle = lipgloss.NewStyle().
-				Foreground(colorYellow).
-				Bold(true)
+		Foreground(colorYellow).
+		Bold(true)
 
 	statusPendingStyle = lipgloss.NewStyle().
-				Foreground(colorGray)
-)
+		Foreground(colorGray)
+}
 
 // =============================================================================
 // HELP STYLES
 // =============================================================================
 
 // Help text styles
-var (
+var helpKeyStyle, helpDescStyle, helpSeparatorStyle lipgloss.Style
+
+func buildHelpStyles() {
 	helpKeyStyle = lipgloss.NewStyle().
-			Foreground(colorYellow)
+		Foreground(colorYellow)
 
 	helpDescStyle = lipgloss.NewStyle().
-			Foreground(colorGray)
+		Foreground(colorGray)
 
 	helpSeparatorStyle = lipgloss.NewStyle().
-				Foreground(colorDimGray)
-)
+		Foreground(colorDimGray)
+}
 
 // =============================================================================
 // ERROR STYLES
 // =============================================================================
 
 // Error styles
-var (
+var errorStyle, errorMessageStyle lipgloss.Style
+
+func buildErrorStyles() {
 	errorStyle = lipgloss.NewStyle().
-			Foreground(colorRed).
-			Bold(true)
+		Foreground(colorRed).
+		Bold(true)
 
 	errorMessageStyle = lipgloss.NewStyle().
-				Foreground(colorRedLight)
-)
+		Foreground(colorRedLight)
+}
 
 // =============================================================================
 // FLOATING WINDOW STYLES
 // =============================================================================
 
 // Floating window styles
-var (
+var floatingWindowStyle, floatingTitleStyle lipgloss.Style
+
+func buildFloatingWindowStyles() {
 	floatingWindowStyle = lipgloss.NewStyle().
-				BorderStyle(lipgloss.DoubleBorder()).
-				BorderForeground(colorGreen).
-				Padding(0, 1)
+		BorderStyle(floatingBorder).
+		BorderForeground(colorGreen).
+		Padding(0, 1)
 
 	floatingTitleStyle = lipgloss.NewStyle().
-				Foreground(colorGreen).
-				Bold(true)
-)
+		Foreground(colorGreen).
+		Bold(true)
+}
 
 // =============================================================================
 // TOOL CALL STYLES (by category)
@@ -232,47 +241,54 @@ var (
 
 // Tool styles by category
 var (
+	toolReadStyle, toolReadParamStyle, toolWriteStyle, toolWriteParamStyle,
+	toolBashStyle, toolBashParamStyle, toolSearchStyle,
+	toolSearchParamStyle, toolOtherStyle, toolOtherParamStyle,
+	toolChevronStyle, toolIconStyle lipgloss.Style
+)
+
+func buildToolStyles() {
 	// Read operations - Cyan family
 	toolReadStyle = lipgloss.NewStyle().
-			Foreground(colorCyan).
-			Bold(true)
+		Foreground(colorCyan).
+		Bold(true)
 	toolReadParamStyle = lipgloss.NewStyle().
-				Foreground(colorCyanLight)
+		Foreground(colorCyanLight)
 
 	// Write/Edit operations - Yellow family
 	toolWriteStyle = lipgloss.NewStyle().
-			Foreground(colorYellow).
-			Bold(true)
+		Foreground(colorYellow).
+		Bold(true)
 	toolWriteParamStyle = lipgloss.NewStyle().
-				Foreground(colorYellowLight)
+		Foreground(colorYellowLight)
 
 	// Bash/Command operations - Green family
 	toolBashStyle = lipgloss.NewStyle().
-			Foreground(colorGreen).
-			Bold(true)
+		Foreground(colorGreen).
+		Bold(true)
 	toolBashParamStyle = lipgloss.NewStyle().
-				Foreground(colorGreenLight)
+		Foreground(colorGreenLight)
 
 	// Search operations - Magenta family
 	toolSearchStyle = lipgloss.NewStyle().
-			Foreground(colorMagenta).
-			Bold(true)
+		Foreground(colorMagenta).
+		Bold(true)
 	toolSearchParamStyle = lipgloss.NewStyle().
-				Foreground(colorMagentaLight)
+		Foreground(colorMagentaLight)
 
 	// Other/misc tools - Orange family
 	toolOtherStyle = lipgloss.NewStyle().
-			Foreground(colorOrange).
-			Bold(true)
+		Foreground(colorOrange).
+		Bold(true)
 	toolOtherParamStyle = lipgloss.NewStyle().
-				Foreground(colorOrangeLight)
+		Foreground(colorOrangeLight)
 
 	// Chevron separator and icon for tool calls
 	toolChevronStyle = lipgloss.NewStyle().
-				Foreground(colorDimGray)
+		Foreground(colorDimGray)
 	toolIconStyle = lipgloss.NewStyle().
-			Foreground(colorGray)
-)
+		Foreground(colorGray)
+}
 
 // GetToolStyles returns the name and param styles for a tool category.
 func GetToolStyles(category ToolCategory) (nameStyle, paramStyle lipgloss.Style) {
@@ -296,38 +312,44 @@ func GetToolStyles(category ToolCategory) (nameStyle, paramStyle lipgloss.Style)
 
 // Phase-colored text styles for iteration markers
 var (
+	iterationTextStyle, iterationDashStyle, iterationBulletStyle,
+	phaseRunningStyle, phaseDevelopingStyle, phaseReviewingStyle,
+	phaseCompletedStyle, phaseFailedStyle, phaseStoppedStyle lipgloss.Style
+)
+
+func buildPhaseStyles() {
 	iterationTextStyle = lipgloss.NewStyle().
-				Foreground(colorForeground)
+		Foreground(colorForeground)
 
 	iterationDashStyle = lipgloss.NewStyle().
-				Foreground(colorDimGray)
+		Foreground(colorDimGray)
 
 	iterationBulletStyle = lipgloss.NewStyle().
-				Foreground(colorDimGray)
+		Foreground(colorDimGray)
 
 	phaseRunningStyle = lipgloss.NewStyle().
-				Foreground(colorOrange)
+		Foreground(colorOrange)
 
 	phaseDevelopingStyle = lipgloss.NewStyle().
-				Foreground(colorCyan)
+		Foreground(colorCyan)
 
 	phaseReviewingStyle = lipgloss.NewStyle().
-				Foreground(colorMagenta)
+		Foreground(colorMagenta)
 
 	phaseCompletedStyle = lipgloss.NewStyle().
-				Foreground(colorGreen)
+		Foreground(colorGreen)
 
 	phaseFailedStyle = lipgloss.NewStyle().
-				Foreground(colorRed)
+		Foreground(colorRed)
 
 	phaseStoppedStyle = lipgloss.NewStyle().
-				Foreground(colorYellow)
-)
+		Foreground(colorYellow)
+}
 
 // GetPhaseStyle returns the appropriate style for a phase name.
 func GetPhaseStyle(phase string) lipgloss.Style {
 	switch strings.ToLower(phase) {
-	case "running", "in progress":
+	case "running", "in progress", "planning":
 		return phaseRunningStyle
 	case "developing":
 		return phaseDevelopingStyle
@@ -350,16 +372,118 @@ func GetPhaseStyle(phase string) lipgloss.Style {
 
 // Message content styles - for aesthetic formatting of Claude session output
 var (
+	sectionDividerStyle, doneMarkerStyle, systemMessageStyle,
+	subAgentGutterStyle, subAgentTextStyle lipgloss.Style
+)
+
+func buildMessageStyles() {
 	// Section dividers and markers
 	sectionDividerStyle = lipgloss.NewStyle().
-				Foreground(colorDimGray)
+		Foreground(colorDimGray)
 
 	doneMarkerStyle = lipgloss.NewStyle().
-			Foreground(colorGreen).
-			Bold(true)
+		Foreground(colorGreen).
+		Bold(true)
 
 	// System messages (jj operations, commits, etc.)
 	systemMessageStyle = lipgloss.NewStyle().
-				Foreground(colorGray).
-				Italic(true)
+		Foreground(colorGray).
+		Italic(true)
+
+	// Gutter and text of sub-agent activity, indented under its Task call
+	subAgentGutterStyle = lipgloss.NewStyle().
+		Foreground(colorDimGray)
+	subAgentTextStyle = lipgloss.NewStyle().
+		Foreground(colorGray)
+}
+
+// =============================================================================
+// MARKDOWN STYLES
+// =============================================================================
+
+// Styles of agent output rendered as markdown
+var (
+	mdHeadingStyle, mdHeadingMarkerStyle, mdBoldStyle, mdItalicStyle,
+	mdCodeStyle, mdLinkStyle, mdLinkURLStyle, mdQuoteStyle, mdItemStyle,
+	mdFenceStyle lipgloss.Style
+)
+
+// Styles of highlighted code, by token kind
+var (
+	codePlainStyle, codeKeywordStyle, codeStringStyle, codeNumberStyle,
+	codeCommentStyle, codeFunctionStyle, codeKeyStyle lipgloss.Style
 )
+
+func buildMarkdownStyles() {
+	// Headings - Magenta family, like panel titles
+	mdHeadingStyle = lipgloss.NewStyle().
+		Foreground(colorMagenta).
+		Bold(true)
+	mdHeadingMarkerStyle = lipgloss.NewStyle().
+		Foreground(colorMagentaDim)
+
+	// Inline markup
+	mdBoldStyle = lipgloss.NewStyle().
+		Bold(true)
+	mdItalicStyle = lipgloss.NewStyle().
+		Italic(true)
+	mdCodeStyle = lipgloss.NewStyle().
+		Foreground(colorCyanLight)
+	mdLinkStyle = lipgloss.NewStyle().
+		Foreground(colorCyan).
+		Underline(true)
+	mdLinkURLStyle = lipgloss.NewStyle().
+		Foreground(colorDimGray)
+
+	// Block markers, rules, and code fences
+	mdQuoteStyle = lipgloss.NewStyle().
+		Foreground(colorDimGray)
+	mdItemStyle = lipgloss.NewStyle().
+		Foreground(colorOrange)
+	mdFenceStyle = lipgloss.NewStyle().
+		Foreground(colorDimGray)
+
+	// Code - Monokai-style token colors
+	codePlainStyle = lipgloss.NewStyle().
+		Foreground(colorForeground)
+	codeKeywordStyle = lipgloss.NewStyle().
+		Foreground(colorMagenta)
+	codeStringStyle = lipgloss.NewStyle().
+		Foreground(colorYellow)
+	codeNumberStyle = lipgloss.NewStyle().
+		Foreground(colorOrange)
+	codeCommentStyle = lipgloss.NewStyle().
+		Foreground(colorGray).
+		Italic(true)
+	codeFunctionStyle = lipgloss.NewStyle().
+		Foreground(colorGreen)
+	codeKeyStyle = lipgloss.NewStyle().
+		Foreground(colorCyan)
+}
+
+// codeTokenStyle returns the style of a kind of code token. Diff lines
+// share the colors of plan diffs.
+func codeTokenStyle(kind codeTokenKind) lipgloss.Style {
+	switch kind {
+	case codeKeyword:
+		return codeKeywordStyle
+	case codeString:
+		return codeStringStyle
+	case codeNumber:
+		return codeNumberStyle
+	case codeComment:
+		return codeCommentStyle
+	case codeFunction:
+		return codeFunctionStyle
+	case codeKey:
+		return codeKeyStyle
+	case codeAdded:
+		return statusCompletedStyle
+	case codeRemoved:
+		return statusFailedStyle
+	case codeHunk:
+		return codeKeyStyle
+	default:
+		return codePlainStyle
+	}
+}
@@ -0,0 +1,103 @@
+// Package tui provides the Bubble Tea TUI for Ralph.
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/claude"
+)
+
+// subAgentBlock tracks the activity of one sub-agent.
+type subAgentBlock struct {
+	toolCalls int
+	lines     int // lines appended to the feed
+}
+
+// subAgent returns the block of the sub-agent spawned by the Task call with
+// the given ID, starting one on first use.
+func (m *Model) subAgent(id string) *subAgentBlock {
+	block, ok := m.subAgents[id]
+	if !ok {
+		if m.subAgents == nil {
+			m.subAgents = make(map[string]*subAgentBlock)
+		}
+		block = &subAgentBlock{}
+		m.subAgents[id] = block
+	}
+	return block
+}
+
+// handleSubAgentEvent appends a sub-agent's tool calls and messages to the
+// feed, indented under its Task call, as lines the feed hides while
+// sub-agents are collapsed. Streaming text deltas are skipped; the complete
+// message follows them.
+func (m *Model) handleSubAgentEvent(event *claude.StreamEvent) {
+	block := m.subAgent(event.SubAgentID)
+
+	var lines []string
+	switch event.Type {
+	case claude.EventToolUse:
+		if event.Message != nil && event.Message.Text != "" {
+			lines = append(lines, subAgentTextLines(event.Message.Text)...)
+		}
+		if event.ToolUse != nil {
+			block.toolCalls++
+			lines = append(lines, strings.TrimPrefix(formatToolUse(event.ToolUse), "\n"))
+		}
+	case claude.EventMessage:
+		if event.Message != nil && event.Message.Text != "" {
+			lines = append(lines, subAgentTextLines(event.Message.Text)...)
+		}
+	case claude.EventError:
+		if event.Error != nil {
+			lines = append(lines, errorStyle.Render(fmt.Sprintf("%s [%s]: %s", glyph.failed, event.Error.Code, event.Error.Message)))
+		}
+	}
+
+	block.lines += len(lines)
+	for _, line := range lines {
+		m.feedPanel.AppendFoldableLine(subAgentGutterStyle.Render(glyph.gutter) + line)
+	}
+}
+
+// finishSubAgent closes a sub-agent's block when its Task call returns.
+func (m *Model) finishSubAgent(result *claude.ToolResultContent) {
+	block, ok := m.subAgents[result.ToolUseID]
+	if !ok {
+		return
+	}
+	delete(m.subAgents, result.ToolUseID)
+
+	summary := fmt.Sprintf("sub-agent finished · %d tool call(s)", block.toolCalls)
+	if result.IsError {
+		summary = fmt.Sprintf("sub-agent failed · %d tool call(s)", block.toolCalls)
+	}
+	if m.subAgentsCollapsed && block.lines > 0 {
+		summary += fmt.Sprintf(" · %d line(s) collapsed, press s to expand", block.lines)
+	}
+	m.feedPanel.AppendLine(subAgentGutterStyle.Render(glyph.gutterEnd) + systemMessageStyle.Render(summary))
+}
+
+// toggleSubAgents switches between showing and collapsing sub-agent
+// activity, in the whole feed. Collapsed lines are kept, so expanding shows
+// them again.
+func (m *Model) toggleSubAgents() {
+	m.subAgentsCollapsed = !m.subAgentsCollapsed
+	m.feedPanel.SetFolded(m.subAgentsCollapsed)
+	msg := "Sub-agent activity expanded"
+	if m.subAgentsCollapsed {
+		msg = "Sub-agent activity collapsed"
+	}
+	m.feedPanel.AppendLine(systemMessageStyle.Render(msg))
+}
+
+// subAgentTextLines styles a sub-agent's message text, one entry per line.
+func subAgentTextLines(text string) []string {
+	parts := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	lines := make([]string, len(parts))
+	for i, part := range parts {
+		lines[i] = subAgentTextStyle.Render(part)
+	}
+	return lines
+}
@@ -0,0 +1,183 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme names, as set with tui.theme or --theme.
+const (
+	ThemeDark         = "dark"          // Monokai Pro, for dark terminals (default)
+	ThemeLight        = "light"         // Deeper tones, for light terminals
+	ThemeHighContrast = "high-contrast" // Bright ANSI colors, without dim shades
+	ThemeNoColor      = "no-color"      // Bold and italic only
+)
+
+// palette holds the colors a theme renders with: a primary, light, and dim
+// shade of each color family.
+type palette struct {
+	foreground, background            lipgloss.TerminalColor
+	cyan, cyanLight, cyanDim          lipgloss.TerminalColor
+	green, greenLight, greenDim       lipgloss.TerminalColor
+	yellow, yellowLight, yellowDim    lipgloss.TerminalColor
+	orange, orangeLight, orangeDim    lipgloss.TerminalColor
+	red, redLight, redDim             lipgloss.TerminalColor
+	magenta, magentaLight, magentaDim lipgloss.TerminalColor
+	gray, dimGray                     lipgloss.TerminalColor
+}
+
+// themes is the registry of palettes by theme name.
+var themes = map[string]palette{
+	ThemeDark: {
+		foreground: lipgloss.Color("#fcfcfa"), background: lipgloss.Color("#2d2a2e"),
+		cyan: lipgloss.Color("#78dce8"), cyanLight: lipgloss.Color("#a1eaf8"), cyanDim: lipgloss.Color("#4b8a94"),
+		green: lipgloss.Color("#a9dc76"), greenLight: lipgloss.Color("#c4e8a4"), greenDim: lipgloss.Color("#6a8a4a"),
+		yellow: lipgloss.Color("#ffd866"), yellowLight: lipgloss.Color("#ffe9a0"), yellowDim: lipgloss.Color("#9a8340"),
+		orange: lipgloss.Color("#fc9867"), orangeLight: lipgloss.Color("#fdb899"), orangeDim: lipgloss.Color("#9a5e3f"),
+		red: lipgloss.Color("#ff6188"), redLight: lipgloss.Color("#ff97ab"), redDim: lipgloss.Color("#993a52"),
+		magenta: lipgloss.Color("#ab9df2"), magentaLight: lipgloss.Color("#c9bff7"), magentaDim: lipgloss.Color("#6e6494"),
+		gray: lipgloss.Color("#727072"), dimGray: lipgloss.Color("#5b595c"),
+	},
+	ThemeLight: {
+		foreground: lipgloss.Color("#2c2a2e"), background: lipgloss.Color("#faf8f5"),
+		cyan: lipgloss.Color("#0e7490"), cyanLight: lipgloss.Color("#1590ad"), cyanDim: lipgloss.Color("#6aa8b8"),
+		green: lipgloss.Color("#3f7d20"), greenLight: lipgloss.Color("#55952f"), greenDim: lipgloss.Color("#8fb07a"),
+		yellow: lipgloss.Color("#946200"), yellowLight: lipgloss.Color("#ad7a0c"), yellowDim: lipgloss.Color("#c2a65a"),
+		orange: lipgloss.Color("#c2410c"), orangeLight: lipgloss.Color("#d65d27"), orangeDim: lipgloss.Color("#d9a17f"),
+		red: lipgloss.Color("#be123c"), redLight: lipgloss.Color("#d9304f"), redDim: lipgloss.Color("#e69aac"),
+		magenta: lipgloss.Color("#6d28d9"), magentaLight: lipgloss.Color("#8149e6"), magentaDim: lipgloss.Color("#a996d6"),
+		gray: lipgloss.Color("#6b696c"), dimGray: lipgloss.Color("#a3a1a4"),
+	},
+	ThemeHighContrast: {
+		foreground: lipgloss.Color("15"), background: lipgloss.Color("0"),
+		cyan: lipgloss.Color("14"), cyanLight: lipgloss.Color("14"), cyanDim: lipgloss.Color("14"),
+		green: lipgloss.Color("10"), greenLight: lipgloss.Color("10"), greenDim: lipgloss.Color("10"),
+		yellow: lipgloss.Color("11"), yellowLight: lipgloss.Color("11"), yellowDim: lipgloss.Color("11"),
+		orange: lipgloss.Color("208"), orangeLight: lipgloss.Color("208"), orangeDim: lipgloss.Color("208"),
+		red: lipgloss.Color("9"), redLight: lipgloss.Color("9"), redDim: lipgloss.Color("9"),
+		magenta: lipgloss.Color("13"), magentaLight: lipgloss.Color("13"), magentaDim: lipgloss.Color("13"),
+		gray: lipgloss.Color("15"), dimGray: lipgloss.Color("7"),
+	},
+	ThemeNoColor: {
+		foreground: lipgloss.NoColor{}, background: lipgloss.NoColor{},
+		cyan: lipgloss.NoColor{}, cyanLight: lipgloss.NoColor{}, cyanDim: lipgloss.NoColor{},
+		green: lipgloss.NoColor{}, greenLight: lipgloss.NoColor{}, greenDim: lipgloss.NoColor{},
+		yellow: lipgloss.NoColor{}, yellowLight: lipgloss.NoColor{}, yellowDim: lipgloss.NoColor{},
+		orange: lipgloss.NoColor{}, orangeLight: lipgloss.NoColor{}, orangeDim: lipgloss.NoColor{},
+		red: lipgloss.NoColor{}, redLight: lipgloss.NoColor{}, redDim: lipgloss.NoColor{},
+		magenta: lipgloss.NoColor{}, magentaLight: lipgloss.NoColor{}, magentaDim: lipgloss.NoColor{},
+		gray: lipgloss.NoColor{}, dimGray: lipgloss.NoColor{},
+	},
+}
+
+// glyphSet holds the symbols feed lines, dividers, and gutters are drawn
+// with.
+type glyphSet struct {
+	done, failed, warning, stopped string // Outcomes
+	review, message, findings      string // Review panels and rebuttals, user feedback, analyzer findings
+	skipped, edited, reformat      string // Skipped reviews, plan edits, reformatted output
+	retry, resumed, waiting        string // Claude retries and rate-limit waits
+	tool, chevron, bullet          string // Tool calls ("▸ Read › file") and iteration markers
+	rule, gutter, gutterEnd        string // Divider lines, sub-agent gutters
+	item, quote                    string // Markdown list items and block quotes
+	cursor                         string // Search prompt cursor
+}
+
+// symbolGlyphs are the default glyphs.
+var symbolGlyphs = glyphSet{
+	done: "✓", failed: "✗", warning: "⚠", stopped: "■",
+	review: "⚖", message: "✉", findings: "⚑",
+	skipped: "⏭", edited: "✎", reformat: "↺",
+	retry: "↻", resumed: "▶", waiting: "⏸",
+	tool: "▸", chevron: "›", bullet: " • ",
+	rule: "─", gutter: "  │ ", gutterEnd: "  └ ",
+	item: "•", quote: "┃ ",
+	cursor: "▏",
+}
+
+// plainGlyphs are the accessible glyphs: words a screen reader reads
+// naturally instead of symbols, and no box-drawing characters.
+var plainGlyphs = glyphSet{
+	done: "[done]", failed: "[error]", warning: "[warning]", stopped: "[stopped]",
+	review: "[review]", message: "[message]", findings: "[findings]",
+	skipped: "[skipped]", edited: "[plan edited]", reformat: "[reformat]",
+	retry: "[retry]", resumed: "[resumed]", waiting: "[waiting]",
+	tool: "[tool]", chevron: "-", bullet: ", ",
+	rule: "", gutter: "    ", gutterEnd: "    ",
+	item: "-", quote: "> ",
+	cursor: "_",
+}
+
+// glyph holds the glyphs of the current theme.
+var glyph glyphSet
+
+func init() {
+	if err := SetTheme(ThemeDark, false); err != nil {
+		panic(err)
+	}
+}
+
+// ThemeNames returns the names of the registered themes, sorted.
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SetTheme switches the TUI to a registered theme (empty = dark). With
+// plain, it also uses the accessible mode: ASCII borders and word prefixes
+// such as "[done]" instead of symbols and box-drawing characters, for
+// screen readers. Call it before creating a model.
+func SetTheme(name string, plain bool) error {
+	if name == "" {
+		name = ThemeDark
+	}
+	p, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(ThemeNames(), ", "))
+	}
+
+	colorForeground, colorBackground = p.foreground, p.background
+	colorCyan, colorCyanLight, colorCyanDim = p.cyan, p.cyanLight, p.cyanDim
+	colorGreen, colorGreenLight, colorGreenDim = p.green, p.greenLight, p.greenDim
+	colorYellow, colorYellowLight, colorYellowDim = p.yellow, p.yellowLight, p.yellowDim
+	colorOrange, colorOrangeLight, colorOrangeDim = p.orange, p.orangeLight, p.orangeDim
+	colorRed, colorRedLight, colorRedDim = p.red, p.redLight, p.redDim
+	colorMagenta, colorMagentaLight, colorMagentaDim = p.magenta, p.magentaLight, p.magentaDim
+	colorGray, colorDimGray = p.gray, p.dimGray
+
+	if plain {
+		glyph = plainGlyphs
+		panelBorder, floatingBorder = lipgloss.ASCIIBorder(), lipgloss.ASCIIBorder()
+	} else {
+		glyph = symbolGlyphs
+		panelBorder, floatingBorder = lipgloss.RoundedBorder(), lipgloss.DoubleBorder()
+	}
+
+	buildPanelStyles()
+	buildStatusStyles()
+	buildHelpStyles()
+	buildErrorStyles()
+	buildFloatingWindowStyles()
+	buildToolStyles()
+	buildPhaseStyles()
+	buildMessageStyles()
+	buildMarkdownStyles()
+	return nil
+}
+
+// divider returns a section divider with a title, e.g. "─── Prompt ───";
+// in the accessible mode, just the title.
+func divider(title string) string {
+	if glyph.rule == "" {
+		return title
+	}
+	rule := strings.Repeat(glyph.rule, 3)
+	return rule + " " + title + " " + rule
+}
@@ -0,0 +1,51 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSetTheme(t *testing.T) {
+	t.Cleanup(func() { _ = SetTheme(ThemeDark, false) })
+
+	for _, name := range []string{ThemeDark, ThemeLight, ThemeHighContrast, ThemeNoColor, ""} {
+		if err := SetTheme(name, false); err != nil {
+			t.Errorf("SetTheme(%q) error: %v", name, err)
+		}
+	}
+	if err := SetTheme("solarized", false); err == nil || !strings.Contains(err.Error(), "high-contrast") {
+		t.Errorf("expected an unknown theme error listing the themes, got: %v", err)
+	}
+	if !slices.Equal(ThemeNames(), []string{"dark", "high-contrast", "light", "no-color"}) {
+		t.Errorf("ThemeNames() = %v", ThemeNames())
+	}
+}
+
+func TestSetTheme_Accessible(t *testing.T) {
+	t.Cleanup(func() { _ = SetTheme(ThemeDark, false) })
+	if err := SetTheme(ThemeNoColor, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := divider("Prompt"); got != "Prompt" {
+		t.Errorf("divider() = %q, want the plain title", got)
+	}
+	marker := buildIterationMarker(2, 5, "Reviewing", 80)
+	if marker != "Iteration 2/5, Reviewing" {
+		t.Errorf("buildIterationMarker() = %q", marker)
+	}
+
+	m := updateModel(NewModel(), tea.WindowSizeMsg{Width: 100, Height: 30})
+	m.feedPanel.AppendLine(glyph.done + " Conflicts resolved")
+	for _, r := range m.View() {
+		if r >= 0x2500 && r <= 0x259f || strings.ContainsRune("✓✗⚠■", r) {
+			t.Fatalf("accessible view contains %q:\n%s", r, m.View())
+		}
+	}
+	if !strings.Contains(m.View(), "[done] Conflicts resolved") {
+		t.Errorf("expected a plain prefix in the feed:\n%s", m.View())
+	}
+}
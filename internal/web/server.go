@@ -0,0 +1,278 @@
+// Package web serves a read-only browser view of plans: the plan list, each
+// plan's iteration history and diffs, and its output streamed live with
+// server-sent events.
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+// ErrNotFound is returned by a Backend for a plan that doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// Backend reads the plans the server shows.
+type Backend interface {
+	// Plans returns the plans to list, most relevant first.
+	Plans() ([]PlanSummary, error)
+	// Plan returns a plan with its iteration history.
+	Plan(id string) (*PlanDetail, error)
+	// Diff returns the changes a plan made, or one iteration of them when
+	// iteration is positive.
+	Diff(ctx context.Context, planID string, iteration int) (string, error)
+	// Follow streams a plan's events from its first, until ctx is done.
+	Follow(ctx context.Context, planID string) <-chan loop.Event
+}
+
+// PlanSummary is a plan as listed.
+type PlanSummary struct {
+	ID        string
+	Status    string
+	Iteration int
+	Agent     string // Agent of the latest session
+	LastEvent string
+	CostUSD   float64
+	UpdatedAt time.Time
+}
+
+// PlanDetail is a plan with its iteration history.
+type PlanDetail struct {
+	PlanSummary
+	OriginPath    string
+	FailureReason string
+	Content       string // The plan text
+	Progress      string // Latest progress
+	Learnings     string // Latest learnings
+	CreatedAt     time.Time
+	Iterations    []Iteration
+}
+
+// Iteration is the sessions one iteration ran.
+type Iteration struct {
+	Number   int
+	Sessions []Session
+}
+
+// Session is one agent session of an iteration.
+type Session struct {
+	Agent    string
+	Status   string
+	Started  time.Time
+	Duration time.Duration // Zero while running
+}
+
+// Server serves the web UI for a Backend.
+type Server struct {
+	backend Backend
+}
+
+// NewServer creates a server reading plans from backend.
+func NewServer(backend Backend) *Server {
+	return &Server{backend: backend}
+}
+
+// Handler returns the server's routes. Everything is read-only: only GET
+// requests are served.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handlePlans)
+	mux.HandleFunc("GET /plans/{id}", s.handlePlan)
+	mux.HandleFunc("GET /plans/{id}/diff", s.handleDiff)
+	mux.HandleFunc("GET /plans/{id}/events", s.handleEvents)
+	return mux
+}
+
+func (s *Server) handlePlans(w http.ResponseWriter, r *http.Request) {
+	plans, err := s.backend.Plans()
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	s.render(w, "plans", plans)
+}
+
+func (s *Server) handlePlan(w http.ResponseWriter, r *http.Request) {
+	plan, err := s.backend.Plan(r.PathValue("id"))
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	s.render(w, "plan", plan)
+}
+
+// diffPage is the data of the diff page.
+type diffPage struct {
+	PlanID    string
+	Iteration int
+	Lines     []diffLine
+}
+
+// diffLine is a line of a diff, with the CSS class it is shown with.
+type diffLine struct {
+	Class string
+	Text  string
+}
+
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	iteration := 0
+	if v := r.URL.Query().Get("iteration"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "iteration must be a positive number", http.StatusBadRequest)
+			return
+		}
+		iteration = n
+	}
+
+	planID := r.PathValue("id")
+	diff, err := s.backend.Diff(r.Context(), planID, iteration)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	s.render(w, "diff", diffPage{PlanID: planID, Iteration: iteration, Lines: diffLines(diff)})
+}
+
+// diffLines splits a diff into lines classed for highlighting.
+func diffLines(diff string) []diffLine {
+	if strings.TrimSpace(diff) == "" {
+		return []diffLine{{Text: "No changes."}}
+	}
+	var lines []diffLine
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		class := ""
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"), strings.HasPrefix(line, "diff "):
+			class = "file"
+		case strings.HasPrefix(line, "@@"):
+			class = "hunk"
+		case strings.HasPrefix(line, "+"):
+			class = "add"
+		case strings.HasPrefix(line, "-"):
+			class = "del"
+		}
+		lines = append(lines, diffLine{Class: class, Text: line})
+	}
+	return lines
+}
+
+// streamLine is a line of a plan's live output, sent as the data of a
+// server-sent event.
+type streamLine struct {
+	Kind string `json:"kind"` // "text", "tool", or "status"
+	Text string `json:"text"`
+}
+
+// handleEvents streams a plan's output as server-sent events until the
+// client goes away.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	planID := r.PathValue("id")
+	if _, err := s.backend.Plan(planID); err != nil {
+		s.fail(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for event := range s.backend.Follow(r.Context(), planID) {
+		line, ok := describe(event)
+		if !ok {
+			continue
+		}
+		data, err := json.Marshal(line)
+		if err != nil {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// describe turns a loop event into a line of live output, if it is worth
+// showing. Claude's complete messages are shown rather than their streamed
+// deltas.
+func describe(event loop.Event) (streamLine, bool) {
+	if event.Type != loop.EventClaudeStream {
+		if event.Message == "" {
+			return streamLine{}, false
+		}
+		return streamLine{Kind: "status", Text: event.Message}, true
+	}
+
+	ce := event.ClaudeEvent
+	if ce == nil || ce.SubAgentID != "" {
+		return streamLine{}, false
+	}
+	switch {
+	case ce.Type == claude.EventMessage && ce.Message != nil && strings.TrimSpace(ce.Message.Text) != "":
+		return streamLine{Kind: "text", Text: ce.Message.Text}, true
+	case ce.Type == claude.EventToolUse && ce.ToolUse != nil:
+		return streamLine{Kind: "tool", Text: ce.ToolUse.Name}, true
+	case ce.Type == claude.EventError && ce.Error != nil:
+		return streamLine{Kind: "status", Text: "Error: " + ce.Error.Message}, true
+	}
+	return streamLine{}, false
+}
+
+// render executes a page template, reporting failures to the client.
+func (s *Server) render(w http.ResponseWriter, name string, data any) {
+	var buf strings.Builder
+	if err := pages.ExecuteTemplate(&buf, name, data); err != nil {
+		log.Warn("failed to render page", "page", name, "error", err)
+		http.Error(w, "failed to render page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(buf.String()))
+}
+
+// fail reports a backend error, as 404 for a missing plan.
+func (s *Server) fail(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "plan not found", http.StatusNotFound)
+		return
+	}
+	log.Warn("web request failed", "error", err)
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// pages are the parsed page templates.
+var pages = template.Must(template.New("pages").Funcs(template.FuncMap{
+	"ago":      ago,
+	"duration": func(d time.Duration) string { return d.Round(time.Second).String() },
+}).Parse(pageTemplates))
+
+// ago formats how long ago t was, coarsely.
+func ago(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+}
@@ -0,0 +1,205 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/loop"
+)
+
+// fakeBackend serves one plan and its canned events.
+type fakeBackend struct {
+	plan       PlanDetail
+	diffs      map[int]string
+	events     []loop.Event
+	iterations []int // Iterations Diff was asked for
+}
+
+func (b *fakeBackend) Plans() ([]PlanSummary, error) {
+	return []PlanSummary{b.plan.PlanSummary}, nil
+}
+
+func (b *fakeBackend) Plan(id string) (*PlanDetail, error) {
+	if id != b.plan.ID {
+		return nil, ErrNotFound
+	}
+	return &b.plan, nil
+}
+
+func (b *fakeBackend) Diff(ctx context.Context, planID string, iteration int) (string, error) {
+	if planID != b.plan.ID {
+		return "", ErrNotFound
+	}
+	b.iterations = append(b.iterations, iteration)
+	return b.diffs[iteration], nil
+}
+
+func (b *fakeBackend) Follow(ctx context.Context, planID string) <-chan loop.Event {
+	ch := make(chan loop.Event, len(b.events))
+	for _, e := range b.events {
+		ch <- e
+	}
+	close(ch)
+	return ch
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, *fakeBackend) {
+	t.Helper()
+	backend := &fakeBackend{
+		plan: PlanDetail{
+			PlanSummary: PlanSummary{
+				ID:        "plan-1",
+				Status:    "running",
+				Iteration: 2,
+				Agent:     "developer",
+				LastEvent: "tool: Edit",
+				CostUSD:   1.5,
+				UpdatedAt: time.Now(),
+			},
+			Content:  "Add <login> page",
+			Progress: "Wrote the form",
+			Iterations: []Iteration{
+				{Number: 1, Sessions: []Session{
+					{Agent: "developer", Status: "completed", Started: time.Now(), Duration: 90 * time.Second},
+					{Agent: "reviewer", Status: "completed", Started: time.Now(), Duration: time.Minute},
+				}},
+			},
+		},
+		diffs: map[int]string{
+			0: "--- a/login.go\n+++ b/login.go\n@@ -1 +1 @@\n-old\n+new\n",
+		},
+		events: []loop.Event{
+			loop.NewEvent(loop.EventIterationStart, 1, 0, "Starting iteration 1"),
+			loop.NewClaudeStreamEvent(1, 0, &claude.StreamEvent{Type: claude.EventMessage, Message: &claude.MessageContent{Text: "Writing the form"}}),
+			loop.NewClaudeStreamEvent(1, 0, &claude.StreamEvent{Type: claude.EventToolUse, ToolUse: &claude.ToolUseContent{Name: "Edit"}}),
+			loop.NewClaudeStreamEvent(1, 0, &claude.StreamEvent{Type: claude.EventAssistantText, AssistantText: &claude.AssistantTextContent{Text: "Writ"}}),
+		},
+	}
+	srv := httptest.NewServer(NewServer(backend).Handler())
+	t.Cleanup(srv.Close)
+	return srv, backend
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestServer_Plans(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	status, body := get(t, srv.URL+"/")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	for _, want := range []string{`href="/plans/plan-1"`, "running", "tool: Edit", "$1.50"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("plan list missing %q", want)
+		}
+	}
+}
+
+func TestServer_Plan(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	status, body := get(t, srv.URL+"/plans/plan-1")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	for _, want := range []string{"Add &lt;login&gt; page", "Wrote the form", "1m30s", `href="/plans/plan-1/diff?iteration=1"`, "EventSource"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("plan page missing %q", want)
+		}
+	}
+
+	if status, _ := get(t, srv.URL+"/plans/missing"); status != http.StatusNotFound {
+		t.Errorf("missing plan status = %d, want 404", status)
+	}
+}
+
+func TestServer_Diff(t *testing.T) {
+	srv, backend := newTestServer(t)
+
+	status, body := get(t, srv.URL+"/plans/plan-1/diff")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	for _, want := range []string{`<div class="add">&#43;new</div>`, `<div class="del">-old</div>`, `<div class="hunk">`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("diff page missing %q", want)
+		}
+	}
+
+	if _, body := get(t, srv.URL+"/plans/plan-1/diff?iteration=3"); !strings.Contains(body, "No changes.") {
+		t.Error("expected an empty iteration diff to say so")
+	}
+	if len(backend.iterations) != 2 || backend.iterations[1] != 3 {
+		t.Errorf("Diff iterations = %v, want [0 3]", backend.iterations)
+	}
+
+	if status, _ := get(t, srv.URL+"/plans/plan-1/diff?iteration=0"); status != http.StatusBadRequest {
+		t.Errorf("invalid iteration status = %d, want 400", status)
+	}
+}
+
+func TestServer_Events(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/plans/plan-1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	var data []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			data = append(data, line)
+		}
+	}
+	want := []string{
+		`{"kind":"status","text":"Starting iteration 1"}`,
+		`{"kind":"text","text":"Writing the form"}`,
+		`{"kind":"tool","text":"Edit"}`,
+	}
+	if strings.Join(data, "\n") != strings.Join(want, "\n") {
+		t.Errorf("events = %q, want %q", data, want)
+	}
+
+	if status, _ := get(t, srv.URL+"/plans/missing/events"); status != http.StatusNotFound {
+		t.Errorf("missing plan status = %d, want 404", status)
+	}
+}
+
+func TestServer_ReadOnly(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	resp, err := http.Post(srv.URL+"/plans/plan-1", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", resp.StatusCode)
+	}
+}
@@ -0,0 +1,131 @@
+package web
+
+// pageTemplates are the pages of the web UI. They are kept in one file with
+// a shared layout and no external assets, so the server is a single binary
+// with nothing to deploy alongside it.
+const pageTemplates = `
+{{define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.}} · ralph</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem auto; max-width: 72rem; padding: 0 1rem; color: #222; }
+a { color: #0b5cad; text-decoration: none; }
+a:hover { text-decoration: underline; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: .35rem .6rem; border-bottom: 1px solid #ddd; vertical-align: top; }
+th { font-weight: 600; color: #555; }
+code, pre { font-family: ui-monospace, monospace; font-size: .85rem; }
+pre { background: #f6f8fa; padding: .75rem; overflow-x: auto; white-space: pre-wrap; }
+.status { font-weight: 600; }
+.status-running { color: #1a7f37; }
+.status-failed, .status-abandoned, .status-cancelled { color: #cf222e; }
+.status-paused, .status-stopped { color: #9a6700; }
+.muted { color: #777; }
+#feed { max-height: 40rem; overflow-y: auto; }
+#feed .tool { color: #8250df; }
+#feed .status { color: #555; font-style: italic; }
+.diff .add { background: #e6ffec; }
+.diff .del { background: #ffebe9; }
+.diff .hunk { color: #8250df; }
+.diff .file { font-weight: 600; }
+</style>
+</head>
+<body>
+{{end}}
+
+{{define "foot"}}</body>
+</html>
+{{end}}
+
+{{define "plans"}}{{template "head" "Plans"}}
+<h1>Plans</h1>
+{{if .}}
+<table>
+<tr><th>Plan</th><th>Status</th><th>Iteration</th><th>Agent</th><th>Last event</th><th>Cost</th><th>Updated</th></tr>
+{{range .}}
+<tr>
+<td><a href="/plans/{{.ID}}"><code>{{.ID}}</code></a></td>
+<td class="status status-{{.Status}}">{{.Status}}</td>
+<td>{{if .Iteration}}{{.Iteration}}{{end}}</td>
+<td>{{.Agent}}</td>
+<td>{{.LastEvent}}</td>
+<td>{{printf "$%.2f" .CostUSD}}</td>
+<td class="muted">{{ago .UpdatedAt}}</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p class="muted">No plans yet.</p>
+{{end}}
+<script>setTimeout(function () { location.reload(); }, 10000);</script>
+{{template "foot"}}{{end}}
+
+{{define "plan"}}{{template "head" .ID}}
+<p><a href="/">&larr; Plans</a></p>
+<h1>Plan <code>{{.ID}}</code></h1>
+<p>
+<span class="status status-{{.Status}}">{{.Status}}</span>
+{{if .Iteration}}· iteration {{.Iteration}}{{end}}
+· {{printf "$%.2f" .CostUSD}}
+· <a href="/plans/{{.ID}}/diff">diff</a>
+{{if .OriginPath}}· <code>{{.OriginPath}}</code>{{end}}
+</p>
+{{if .FailureReason}}<p class="muted">{{.FailureReason}}</p>{{end}}
+
+<h2>Live output</h2>
+<pre id="feed"></pre>
+
+<h2>Iterations</h2>
+{{if .Iterations}}
+<table>
+<tr><th>Iteration</th><th>Agent</th><th>Status</th><th>Started</th><th>Duration</th><th></th></tr>
+{{range $it := .Iterations}}{{range $i, $s := $it.Sessions}}
+<tr>
+<td>{{if eq $i 0}}{{$it.Number}}{{end}}</td>
+<td>{{$s.Agent}}</td>
+<td>{{$s.Status}}</td>
+<td class="muted">{{ago $s.Started}}</td>
+<td>{{if $s.Duration}}{{duration $s.Duration}}{{end}}</td>
+<td>{{if and (eq $i 0) $it.Number}}<a href="/plans/{{$.ID}}/diff?iteration={{$it.Number}}">diff</a>{{end}}</td>
+</tr>
+{{end}}{{end}}
+</table>
+{{else}}
+<p class="muted">No sessions yet.</p>
+{{end}}
+
+{{if .Progress}}<h2>Progress</h2>
+<pre>{{.Progress}}</pre>{{end}}
+{{if .Learnings}}<h2>Learnings</h2>
+<pre>{{.Learnings}}</pre>{{end}}
+<details>
+<summary>Plan</summary>
+<pre>{{.Content}}</pre>
+</details>
+
+<script>
+(function () {
+  var feed = document.getElementById("feed");
+  var source = new EventSource("/plans/{{.ID}}/events");
+  source.onmessage = function (e) {
+    var line = JSON.parse(e.data);
+    var follow = feed.scrollTop + feed.clientHeight >= feed.scrollHeight - 8;
+    var div = document.createElement("div");
+    div.className = line.kind;
+    div.textContent = line.kind === "tool" ? "→ " + line.text : line.text;
+    feed.appendChild(div);
+    if (follow) { feed.scrollTop = feed.scrollHeight; }
+  };
+})();
+</script>
+{{template "foot"}}{{end}}
+
+{{define "diff"}}{{template "head" "Diff"}}
+<p><a href="/plans/{{.PlanID}}">&larr; Plan <code>{{.PlanID}}</code></a></p>
+<h1>{{if .Iteration}}Iteration {{.Iteration}}{{else}}All changes{{end}}</h1>
+<pre class="diff">{{range .Lines}}<div class="{{.Class}}">{{.Text}}</div>{{end}}</pre>
+{{template "foot"}}{{end}}
+`
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/parser"
+	"github.com/spf13/cobra"
+)
+
+func learningsCmd() *cobra.Command {
+	var asOfIteration int
+
+	cmd := &cobra.Command{
+		Use:   "learnings <plan-id>",
+		Short: "List what the agents learned during a plan",
+		Long: `List every learning the agents recorded for a plan, once each. Learnings
+are restated from iteration to iteration, so entries with the same words
+(ignoring case, punctuation, and markdown) or nearly the same words are
+shown only in their first wording. With --as-of-iteration, only learnings
+recorded by the end of that iteration are listed.
+
+Examples:
+  ralph learnings 3f2a9c1e
+  ralph learnings 3f2a9c1e --as-of-iteration 4`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("as-of-iteration") && asOfIteration < 1 {
+				return errors.New("--as-of-iteration must be at least 1")
+			}
+
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			return runLearnings(cmd.OutOrStdout(), database, args[0], asOfIteration)
+		},
+	}
+
+	cmd.Flags().IntVar(&asOfIteration, "as-of-iteration", 0, "List the learnings recorded by the end of this iteration")
+
+	return cmd
+}
+
+// runLearnings prints the deduplicated learnings of a plan, as of the end of
+// the given iteration (0 = all of them).
+func runLearnings(out io.Writer, database *db.DB, planID string, asOfIteration int) error {
+	if _, err := database.GetPlan(planID); errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("plan not found: %s", planID)
+	} else if err != nil {
+		return fmt.Errorf("failed to get plan: %w", err)
+	}
+
+	var history []*db.Learnings
+	var err error
+	if asOfIteration > 0 {
+		if err := database.CheckIteration(planID, asOfIteration); err != nil {
+			return err
+		}
+		history, err = database.GetLearningsHistoryAsOf(planID, asOfIteration)
+	} else {
+		history, err = database.GetLearningsHistory(planID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get learnings: %w", err)
+	}
+	contents := make([]string, len(history))
+	for i, l := range history {
+		contents[i] = l.Content
+	}
+
+	items := parser.UniqueLearnings(contents)
+	if len(items) == 0 {
+		fmt.Fprintln(out, "No learnings recorded.")
+		return nil
+	}
+	for _, item := range items {
+		fmt.Fprintf(out, "- %s\n", item)
+	}
+	return nil
+}
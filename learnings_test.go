@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+func TestRunLearnings(t *testing.T) {
+	database := newPlansTestDB(t)
+	if err := database.CreatePlan(&db.Plan{ID: "plan-1", OriginPath: "plan.md", Content: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreatePlanSession(&db.PlanSession{ID: "s1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runLearnings(&out, database, "plan-1", 0); err != nil {
+		t.Fatalf("runLearnings() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "No learnings recorded.") {
+		t.Errorf("output = %q, want no learnings message", out.String())
+	}
+
+	for _, content := range []string{
+		"- Tests need the fake clock\n- Handlers live in api/",
+		"- tests need the fake clock.\n- Handlers live in api/\n- Lint with golangci-lint",
+	} {
+		if err := database.CreateLearnings(&db.Learnings{PlanID: "plan-1", SessionID: "s1", Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out.Reset()
+	if err := runLearnings(&out, database, "plan-1", 0); err != nil {
+		t.Fatalf("runLearnings() error: %v", err)
+	}
+	want := "- Tests need the fake clock\n- Handlers live in api/\n- Lint with golangci-lint\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	// Learnings of later iterations are left out as of an earlier one
+	if err := database.CreatePlanSession(&db.PlanSession{ID: "s2", PlanID: "plan-1", Iteration: 2, InputPrompt: "p"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreateLearnings(&db.Learnings{PlanID: "plan-1", SessionID: "s2", Content: "- Cache the config"}); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := runLearnings(&out, database, "plan-1", 1); err != nil {
+		t.Fatalf("runLearnings() error: %v", err)
+	}
+	if out.String() != want {
+		t.Errorf("output as of iteration 1 = %q, want %q", out.String(), want)
+	}
+	if err := runLearnings(&out, database, "plan-1", 3); err == nil || !strings.Contains(err.Error(), "no iteration 3") {
+		t.Errorf("runLearnings() as of a future iteration = %v, want no iteration error", err)
+	}
+
+	if err := runLearnings(&out, database, "missing", 0); err == nil || !strings.Contains(err.Error(), "plan not found") {
+		t.Errorf("runLearnings() for a missing plan = %v, want plan not found", err)
+	}
+}
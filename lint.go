@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/planlint"
+	"github.com/gerunddev/ralph/internal/planspec"
+	"github.com/spf13/cobra"
+)
+
+func lintCmd() *cobra.Command {
+	var strict bool
+	var workDirFlag string
+
+	cmd := &cobra.Command{
+		Use:   "lint <plan-file | ->",
+		Short: "Check a plan for problems before running it",
+		Long: `Check a plan for problems that make agents flounder: sections with nothing
+in them, no acceptance criteria saying how to tell the work is done,
+references to repository files that don't exist, and plans over lint.max_bytes.
+
+Findings are warnings; with --strict (or lint.strict in config) the command
+fails if there are any. The same checks run before every plan file starts.
+
+Examples:
+  ralph lint plan.md
+  ralph lint plan.md --strict
+  gen-plan | ralph lint -`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workDir := workDirFlag
+			if workDir == "" {
+				var err error
+				if workDir, err = os.Getwd(); err != nil {
+					return fmt.Errorf("failed to get working directory: %w", err)
+				}
+			}
+			cfg, err := config.LoadForDir(workDir)
+			if err != nil {
+				return err
+			}
+
+			var content []byte
+			if args[0] == "-" {
+				content, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				content, err = os.ReadFile(args[0])
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read plan: %w", err)
+			}
+
+			plan, err := planspec.Content(args[0], content)
+			if err != nil {
+				return err
+			}
+
+			findings := lintPlan(cmd.OutOrStdout(), args[0], plan, workDir, cfg.Lint)
+			if len(findings) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No problems found.")
+				return nil
+			}
+			if strict || cfg.Lint.Strict {
+				return lintError(findings)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail if there are any findings")
+	cmd.Flags().StringVar(&workDirFlag, "workdir", "", "Repository file references are resolved against (default: current directory)")
+
+	return cmd
+}
+
+// lintPlan lints a plan, printing each finding to out prefixed with the
+// plan's name.
+func lintPlan(out io.Writer, name, content, workDir string, cfg config.LintConfig) []planlint.Finding {
+	findings := planlint.Lint(content, planlint.Options{RepoDir: workDir, MaxBytes: cfg.MaxBytes})
+	for _, f := range findings {
+		fmt.Fprintf(out, "%s: %s\n", name, f)
+	}
+	return findings
+}
+
+// lintError is the error for a plan with findings in strict mode.
+func lintError(findings []planlint.Finding) error {
+	return fmt.Errorf("plan has %d lint finding(s); fix them or run without --strict", len(findings))
+}
+
+// lintBeforeRun lints a plan about to start, warning about findings on
+// stderr. In strict mode, findings stop the plan from starting.
+func lintBeforeRun(name, content string, opts runOptions) error {
+	cfg, err := config.LoadForDir(opts.workDir)
+	if err != nil {
+		return err
+	}
+	findings := lintPlan(os.Stderr, name, content, opts.workDir, cfg.Lint)
+	if len(findings) > 0 && (opts.strictLint || cfg.Lint.Strict) {
+		return lintError(findings)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/app"
+)
+
+// writeLintPlan writes a plan to a temporary directory, returning its path.
+func writeLintPlan(t *testing.T, content string) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "plan.md")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLintCmd(t *testing.T) {
+	path := writeLintPlan(t, "# Plan\n\n## Background\n\n## Tasks\n\nDo it.\n")
+
+	var out bytes.Buffer
+	cmd := lintCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{path, "--workdir", t.TempDir()})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("lint error: %v", err)
+	}
+	got := out.String()
+	if !strings.Contains(got, path+`: line 3: section "## Background" is empty (empty-section)`) ||
+		!strings.Contains(got, "(acceptance-criteria)") {
+		t.Errorf("unexpected output:\n%s", got)
+	}
+
+	cmd = lintCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{path, "--strict"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "2 lint finding(s)") {
+		t.Errorf("expected a strict lint error, got: %v", err)
+	}
+}
+
+func TestLintCmd_Clean(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var out bytes.Buffer
+	cmd := lintCmd()
+	cmd.SetOut(&out)
+	cmd.SetIn(strings.NewReader("Fix the bug.\n\nDone when the tests pass.\n"))
+	cmd.SetArgs([]string{"-", "--strict"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("lint error: %v", err)
+	}
+	if got := out.String(); got != "No problems found.\n" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestRunNew_StrictLint(t *testing.T) {
+	originalFactory := appFactory
+	defer func() { appFactory = originalFactory }()
+
+	started := false
+	appFactory = func(cfg app.Config) (App, error) {
+		return &mockAppImpl{runFunc: func(ctx context.Context, planPath string) error {
+			started = true
+			return nil
+		}}, nil
+	}
+
+	path := writeLintPlan(t, "# Test Plan")
+	err := runNew(context.Background(), path, runOptions{workDir: t.TempDir(), strictLint: true}, false)
+	if err == nil || !strings.Contains(err.Error(), "lint finding") {
+		t.Errorf("expected a lint error, got: %v", err)
+	}
+	if started {
+		t.Error("expected the plan not to start")
+	}
+
+	// Without --strict the findings are only warnings
+	if err := runNew(context.Background(), path, runOptions{workDir: t.TempDir()}, false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !started {
+		t.Error("expected the plan to start")
+	}
+}
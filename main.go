@@ -5,11 +5,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/gerunddev/ralph/internal/app"
+	"github.com/gerunddev/ralph/internal/config"
 	"github.com/gerunddev/ralph/internal/db"
 	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/planenv"
+	"github.com/gerunddev/ralph/internal/planspec"
 	"github.com/spf13/cobra"
 )
 
@@ -17,6 +23,10 @@ import (
 // It can be replaced in tests to mock jj validation.
 var jjValidator = defaultJJValidator
 
+// planInput is where --stdin and "-" read the plan from.
+// It can be replaced in tests.
+var planInput io.Reader = os.Stdin
+
 // appFactory is the function used to create a new app.App.
 // It can be replaced in tests to mock app creation.
 var appFactory = defaultAppFactory
@@ -41,21 +51,57 @@ type App interface {
 }
 
 func main() {
-	if err := run(); err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+	err := run()
+	if err == nil {
+		return
 	}
+
+	// A plan that ended without completing exits with its outcome's code;
+	// the outcome line already said why
+	var exitErr *app.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.Err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", exitErr.Err)
+		}
+		os.Exit(exitErr.ExitCode())
+	}
+	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	os.Exit(1)
 }
 
 func run() error {
 	var resumeID string
+	var fromIteration int
+	var restoreWorkingCopy bool
 	var maxIterations int
+	var maxDuration time.Duration
+	var maxCost float64
+	var iterationsThisRun int
 	var promptStr string
 	var extremeMode bool
+	var extremeBonus int
 	var teamMode bool
+	var decompose bool
+	var createPR bool
+	var autoApplyPatches bool
+	var planRefresh string
+	var edit bool
+	var forceNew bool
+	var fromStdin bool
+	var workDirFlag string
+	var recordFixtures string
+	var replayFixtures string
+	var envVars []string
+	var noVCS bool
+	var noWorkspace bool
+	var scopeFlag string
+	var theme string
+	var modelFlag string
+	var accessible bool
+	var strictLint bool
 
 	rootCmd := &cobra.Command{
-		Use:   "ralph [plan-file]",
+		Use:   "ralph [plan-file | -]",
 		Short: "Iterative AI development with a single agent loop",
 		Long: `Ralph runs an AI agent iteratively against a plan file.
 The agent works until it declares completion or hits max iterations.
@@ -63,9 +109,25 @@ The agent works until it declares completion or hits max iterations.
 Examples:
   ralph plan.md                    # Start new execution from plan file
   ralph plan.md --max-iterations 30  # Start with custom iteration limit
+  ralph plan.md --max-duration 2h  # Pause after two hours (resume with -r)
+  ralph plan.md --max-cost 20      # Pause once the plan has cost $20
+  ralph -r abc123 --iterations-this-run 1  # Batch mode: one iteration, then exit (for cron)
   ralph -r abc123                  # Resume existing plan by ID
   ralph --resume abc123            # Resume existing plan by ID
-  ralph -p "Fix the login bug"     # Start execution with inline prompt`,
+  ralph -r abc123 --from-iteration 3  # Discard iterations after 3, then resume
+  ralph -p "Fix the login bug"     # Start execution with inline prompt
+  ralph plan.md --decompose        # Break the plan into tasks, then work them in order
+  ralph plan.md --create-pr        # Push the result and open a pull request when done
+  ralph plan.md --edit             # Tweak the plan in $EDITOR before starting
+  ralph plan.md --force-new        # Start over even if plan.md's last plan is unfinished
+  ralph plan.md --strict           # Refuse to start if the plan linter finds problems
+  ralph plan.md --plan-refresh merge  # Apply edits to plan.md made while it runs
+  ralph plan.md --model opus,sonnet  # Fall back to sonnet while opus is overloaded
+  gen-plan | ralph -               # Read the plan from stdin (same as --stdin)
+  ralph --workdir ~/src/api plan.md  # Run the plan in another repository
+  ralph plan.md --no-vcs           # Run in a plain directory, tracking changes with snapshots
+  ralph plan.md --record-fixtures fx  # Save Claude's raw output, replay with --replay-fixtures fx
+  ralph plan.md --env API_URL=http://localhost:8080  # Set a variable for jj and claude`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
@@ -74,62 +136,300 @@ Examples:
 			if maxIterations < 0 {
 				return fmt.Errorf("--max-iterations cannot be negative")
 			}
+			if maxDuration < 0 {
+				return fmt.Errorf("--max-duration cannot be negative")
+			}
+			if maxCost < 0 {
+				return fmt.Errorf("--max-cost cannot be negative")
+			}
+			if iterationsThisRun < 0 {
+				return fmt.Errorf("--iterations-this-run cannot be negative")
+			}
+			if extremeBonus < 0 {
+				return fmt.Errorf("--extreme-iterations cannot be negative")
+			}
+			if err := config.ValidatePlanRefresh(planRefresh); err != nil {
+				return fmt.Errorf("--plan-refresh %w", err)
+			}
+			var models []string
+			if cmd.Flags().Changed("model") {
+				var err error
+				if models, err = config.ParseModelChain(modelFlag); err != nil {
+					return fmt.Errorf("--model: %w", err)
+				}
+			}
+			if err := config.ValidateTheme(theme); err != nil {
+				return fmt.Errorf("--theme %w", err)
+			}
+			if cmd.Flags().Changed("from-iteration") {
+				if resumeID == "" {
+					return errors.New("--from-iteration requires --resume")
+				}
+				if fromIteration < 1 {
+					return errors.New("--from-iteration must be at least 1")
+				}
+			}
+			if restoreWorkingCopy && fromIteration == 0 {
+				return errors.New("--restore-working-copy requires --from-iteration")
+			}
+			if strictLint && (resumeID != "" || promptStr != "") {
+				return errors.New("--strict requires a plan file or --stdin")
+			}
+			if noVCS && createPR {
+				return errors.New("cannot combine --no-vcs and --create-pr")
+			}
+			if recordFixtures != "" && replayFixtures != "" {
+				return errors.New("cannot combine --record-fixtures and --replay-fixtures")
+			}
+			for _, assignment := range envVars {
+				if _, err := planenv.ParseAssignment(assignment); err != nil {
+					return fmt.Errorf("--env %w", err)
+				}
+			}
 
-			// Validate working directory is a jj repository
-			if err := validateJJRepository(ctx); err != nil {
+			// Resolve and validate the directory the plan runs in
+			workDir, err := resolveWorkDir(workDirFlag, resumeID)
+			if err != nil {
 				return err
 			}
+			if !noVCS {
+				if err := validateJJRepository(ctx, workDir); err != nil {
+					return err
+				}
+			}
+			scope, err := resolveScope(scopeFlag)
+			if err != nil {
+				return err
+			}
+
+			opts := runOptions{
+				maxIterations:      maxIterations,
+				maxDuration:        maxDuration,
+				maxCost:            maxCost,
+				iterationsThisRun:  iterationsThisRun,
+				extremeMode:        extremeMode || cmd.Flags().Changed("extreme-iterations"),
+				extremeBonus:       extremeBonus,
+				teamMode:           teamMode,
+				decompose:          decompose,
+				createPR:           createPR,
+				autoApplyPatches:   autoApplyPatches,
+				planRefresh:        planRefresh,
+				workDir:            workDir,
+				workDirOverride:    workDirFlag != "",
+				fromIteration:      fromIteration,
+				restoreWorkingCopy: restoreWorkingCopy,
+				recordFixtures:     recordFixtures,
+				replayFixtures:     replayFixtures,
+				env:                envVars,
+				noVCS:              noVCS,
+				noWorkspace:        noWorkspace,
+				scope:              scope,
+				theme:              theme,
+				models:             models,
+				accessible:         accessible,
+				strictLint:         strictLint,
+				forceNew:           forceNew,
+			}
+
+			// "-" as the plan file reads the plan from stdin
+			if len(args) == 1 && args[0] == "-" {
+				fromStdin = true
+				args = nil
+			}
+
+			if edit && (resumeID != "" || promptStr != "" || fromStdin) {
+				return fmt.Errorf("--edit requires a plan file")
+			}
+			if forceNew && (resumeID != "" || promptStr != "" || fromStdin) {
+				return fmt.Errorf("--force-new requires a plan file")
+			}
+
+			if fromStdin {
+				if resumeID != "" || promptStr != "" || len(args) > 0 {
+					return fmt.Errorf("cannot combine --stdin with a plan file, --prompt, or --resume")
+				}
+				return runNewFromStdin(ctx, planInput, opts)
+			}
 
 			// Determine mode
 			if resumeID != "" {
 				if len(args) > 0 || promptStr != "" {
 					return fmt.Errorf("cannot specify both --resume and plan file or --prompt")
 				}
-				return runResume(ctx, resumeID, maxIterations, extremeMode, teamMode)
+				return runResume(ctx, resumeID, opts)
 			}
 
 			if promptStr != "" {
 				if len(args) > 0 {
 					return fmt.Errorf("cannot specify both plan file and --prompt")
 				}
-				return runNewWithPrompt(ctx, promptStr, maxIterations, extremeMode, teamMode)
+				return runNewWithPrompt(ctx, promptStr, opts)
 			}
 
 			if len(args) == 0 {
-				return fmt.Errorf("plan file required (or use --resume or --prompt)")
+				return fmt.Errorf("plan file required (or use --resume, --prompt, or --stdin)")
 			}
 
-			return runNew(ctx, args[0], maxIterations, extremeMode, teamMode)
+			return runNew(ctx, args[0], opts, edit)
 		},
 	}
 
 	rootCmd.Flags().StringVarP(&resumeID, "resume", "r", "",
 		"Resume execution of an existing plan by ID")
+	rootCmd.Flags().IntVar(&fromIteration, "from-iteration", 0,
+		"With --resume, discard the progress, learnings, and feedback of later iterations and resume after this one")
+	rootCmd.Flags().BoolVar(&restoreWorkingCopy, "restore-working-copy", false,
+		"With --from-iteration, also restore the working copy to that iteration's snapshot")
 	rootCmd.Flags().StringVarP(&promptStr, "prompt", "p", "",
 		"Use inline prompt as the plan instead of a file")
+	rootCmd.Flags().BoolVar(&fromStdin, "stdin", false,
+		"Read the plan from standard input (same as passing - as the plan file)")
 	rootCmd.Flags().IntVar(&maxIterations, "max-iterations", 0,
 		"Override max iterations from config")
+	rootCmd.Flags().DurationVar(&maxDuration, "max-duration", 0,
+		"Pause the plan after this much wall-clock time, once the current iteration finishes (e.g. 2h)")
+	rootCmd.Flags().Float64Var(&maxCost, "max-cost", 0,
+		"Pause the plan once its Claude sessions have cost this many USD across all runs, after the current iteration finishes")
+	rootCmd.Flags().IntVar(&iterationsThisRun, "iterations-this-run", 0,
+		"Batch mode: run this many iterations without the TUI, leave the plan paused, print a summary, and exit")
 	rootCmd.Flags().BoolVarP(&extremeMode, "extreme", "x", false,
-		"Extreme mode: run +3 iterations after robots think they're done")
+		"Extreme mode: run more iterations after robots think they're done")
+	rootCmd.Flags().IntVar(&extremeBonus, "extreme-iterations", 0,
+		"Bonus iterations per extreme mode round, implying --extreme (default extreme_bonus_iterations from config)")
 	rootCmd.Flags().BoolVarP(&teamMode, "team", "t", false,
 		"Enable agent teams for parallel development")
+	rootCmd.Flags().BoolVar(&decompose, "decompose", false,
+		"Break the plan into tasks with a planner agent and work them one at a time")
+	rootCmd.Flags().BoolVar(&createPR, "create-pr", false,
+		"Push a bookmark and open a pull request once the plan completes (see forge config)")
+	rootCmd.Flags().BoolVar(&autoApplyPatches, "auto-apply-review-patches", false,
+		"Apply patches the reviewer suggests as separate jj changes instead of only passing them to the developer")
+	rootCmd.Flags().StringVar(&planRefresh, "plan-refresh", "",
+		"What to do when the plan file is edited during the run: off, detect, or merge (default: plan_refresh from config)")
+	rootCmd.Flags().BoolVar(&edit, "edit", false,
+		"Edit the plan in $EDITOR before starting; the edited plan is stored, the file is left untouched")
+	rootCmd.Flags().BoolVar(&forceNew, "force-new", false,
+		"Start a new plan even if one started from the same, unchanged plan file is unfinished, instead of asking to resume it")
+	rootCmd.Flags().StringVar(&workDirFlag, "workdir", "",
+		"Repository to run the plan in (default: current directory, or the plan's own directory with --resume)")
+	rootCmd.Flags().StringVar(&recordFixtures, "record-fixtures", "",
+		"Record the raw stream of every Claude session to numbered files in this directory")
+	rootCmd.Flags().StringVar(&replayFixtures, "replay-fixtures", "",
+		"Replay Claude sessions from fixtures recorded with --record-fixtures instead of running claude")
+	rootCmd.Flags().StringArrayVar(&envVars, "env", nil,
+		"Set NAME=value for the jj and claude processes, overriding the plan's front matter env (repeatable; not stored, pass again with --resume)")
+	rootCmd.Flags().BoolVar(&noVCS, "no-vcs", false,
+		"Run in a directory that isn't a jj repository, diffing snapshots of it taken around each iteration (pass again with --resume)")
+	rootCmd.Flags().BoolVar(&noWorkspace, "no-workspace", false,
+		"Run the plan in the current jj workspace instead of its own (see jj.workspaces)")
+	rootCmd.Flags().StringVar(&scopeFlag, "scope", "",
+		"Limit the plan to this directory of a mono-repo, relative to the repository root: diffs, context, and allowed edits (pass again with --resume)")
+	rootCmd.Flags().StringVar(&modelFlag, "model", "",
+		"Model to run sessions on, optionally followed by fallbacks tried in order when it is overloaded or out of quota (e.g. opus,sonnet,haiku; default: claude.model from config)")
+	rootCmd.Flags().StringVar(&theme, "theme", "",
+		"TUI color theme: dark, light, high-contrast, or no-color (default: tui.theme from config)")
+	rootCmd.Flags().BoolVar(&accessible, "accessible", false,
+		"Screen-reader-friendly TUI: plain word prefixes instead of symbols and no box-drawing characters")
+	rootCmd.Flags().BoolVar(&strictLint, "strict", false,
+		"Refuse to start if the plan linter finds problems instead of warning (see ralph lint)")
 
 	// Add subcommands
 	rootCmd.AddCommand(taskCmd())
+	rootCmd.AddCommand(initCmd())
+	rootCmd.AddCommand(plansCmd())
+	rootCmd.AddCommand(searchCmd())
+	rootCmd.AddCommand(transcriptCmd())
+	rootCmd.AddCommand(learningsCmd())
+	rootCmd.AddCommand(reportCmd())
+	rootCmd.AddCommand(auditCmd())
+	rootCmd.AddCommand(reviewCmd())
+	rootCmd.AddCommand(onceCmd())
+	rootCmd.AddCommand(diffCmd())
+	rootCmd.AddCommand(undoIterationCmd())
+	rootCmd.AddCommand(forkCmd())
+	rootCmd.AddCommand(exportStateCmd())
+	rootCmd.AddCommand(importStateCmd())
+	rootCmd.AddCommand(compareCmd())
+	rootCmd.AddCommand(doctorCmd())
+	rootCmd.AddCommand(statusCmd())
+	rootCmd.AddCommand(dashboardCmd())
+	rootCmd.AddCommand(feedCmd())
+	rootCmd.AddCommand(rpcCmd())
+	rootCmd.AddCommand(dbCmd())
+	rootCmd.AddCommand(webCmd())
+	rootCmd.AddCommand(lintCmd())
+	rootCmd.AddCommand(mcpCmd())
 
 	return rootCmd.Execute()
 }
 
-// validateJJRepository checks that we're inside a jj repository.
-func validateJJRepository(ctx context.Context) error {
-	workDir, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get working directory: %w", err)
+// runOptions holds the flags shared by every way of starting or resuming a
+// plan.
+type runOptions struct {
+	maxIterations      int
+	maxDuration        time.Duration
+	maxCost            float64
+	iterationsThisRun  int // Batch mode: iterations to run before exiting (0 = run with the TUI)
+	extremeMode        bool
+	extremeBonus       int // Bonus iterations per extreme mode round (0 = use config)
+	teamMode           bool
+	decompose          bool
+	createPR           bool
+	autoApplyPatches   bool
+	planRefresh        string   // Overrides plan_refresh from config (empty = use config)
+	workDir            string   // Directory the plan runs in (empty = current directory)
+	workDirOverride    bool     // workDir was set explicitly with --workdir
+	fromIteration      int      // Iteration to rewind a resumed plan to (0 = don't rewind)
+	restoreWorkingCopy bool     // Restore the working copy when rewinding
+	recordFixtures     string   // Directory to record Claude session streams to
+	replayFixtures     string   // Directory to replay Claude session streams from
+	env                []string // NAME=value assignments for the jj and claude processes
+	noVCS              bool     // Track changes with snapshots instead of jj
+	noWorkspace        bool     // Run in the current jj workspace instead of the plan's own
+	scope              string   // Mono-repo directory the plan is limited to (empty = the whole repository)
+	theme              string   // Overrides tui.theme from config (empty = use config)
+	models             []string // Overrides claude.model and its fallbacks from config (empty = use config)
+	accessible         bool     // Screen-reader-friendly TUI
+	strictLint         bool     // Refuse to start plans with lint findings
+	forceNew           bool     // Start a new plan even if the plan file's last plan is unfinished
+}
+
+// appConfig returns the app configuration for the options.
+func (o runOptions) appConfig() app.Config {
+	return app.Config{
+		WorkDir:                o.workDir,
+		WorkDirOverride:        o.workDirOverride,
+		MaxIterationsOverride:  o.maxIterations,
+		MaxDuration:            o.maxDuration,
+		MaxCost:                o.maxCost,
+		IterationsThisRun:      o.iterationsThisRun,
+		ExtremeMode:            o.extremeMode,
+		ExtremeBonus:           o.extremeBonus,
+		TeamMode:               o.teamMode,
+		Decompose:              o.decompose,
+		CreatePR:               o.createPR,
+		AutoApplyReviewPatches: o.autoApplyPatches,
+		PlanRefresh:            o.planRefresh,
+		FromIteration:          o.fromIteration,
+		RestoreWorkingCopy:     o.restoreWorkingCopy,
+		RecordFixtures:         o.recordFixtures,
+		ReplayFixtures:         o.replayFixtures,
+		Env:                    o.env,
+		NoVCS:                  o.noVCS,
+		NoWorkspace:            o.noWorkspace,
+		Scope:                  o.scope,
+		Theme:                  o.theme,
+		Models:                 o.models,
+		Accessible:             o.accessible,
+		ForceNew:               o.forceNew,
 	}
+}
 
-	err = jjValidator(ctx, workDir)
+// validateJJRepository checks that workDir is inside a jj repository.
+func validateJJRepository(ctx context.Context, workDir string) error {
+	err := jjValidator(ctx, workDir)
 	if errors.Is(err, jj.ErrNotRepo) {
-		return fmt.Errorf("not a jj repository (run from within a jj repo)")
+		return fmt.Errorf("not a jj repository: %s (run from within a jj repo, pass --workdir, or pass --no-vcs)", workDir)
 	}
 	if errors.Is(err, jj.ErrCommandNotFound) {
 		return fmt.Errorf("jj command not found (install jujutsu: https://github.com/martinvonz/jj)")
@@ -141,18 +441,52 @@ func validateJJRepository(ctx context.Context) error {
 }
 
 // runNew starts execution with a new plan from the given file path.
-func runNew(ctx context.Context, planPath string, maxIterations int, extremeMode, teamMode bool) error {
+func runNew(ctx context.Context, planPath string, opts runOptions, edit bool) error {
 	// Validate plan file exists
 	if _, err := os.Stat(planPath); os.IsNotExist(err) {
 		return fmt.Errorf("plan file not found: %s", planPath)
 	}
 
+	// Let the user adjust the plan before it is stored
+	var planContent string
+	if edit {
+		var err error
+		planContent, err = editPlan(os.Stdout, planPath)
+		if errors.Is(err, errEditCancelled) {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Check the plan as it will be stored
+	content := planContent
+	if !edit {
+		fileContent, err := os.ReadFile(planPath)
+		if err != nil {
+			return fmt.Errorf("failed to read plan file: %w", err)
+		}
+		content = string(fileContent)
+	}
+	content, err := planspec.Content(planPath, []byte(content))
+	if err != nil {
+		return err
+	}
+	if err := lintBeforeRun(planPath, content, opts); err != nil {
+		return err
+	}
+
 	// Create app
-	app, err := appFactory(app.Config{
-		MaxIterationsOverride: maxIterations,
-		ExtremeMode:           extremeMode,
-		TeamMode:              teamMode,
-	})
+	cfg := opts.appConfig()
+	cfg.PlanContent = planContent
+	cfg.ConfirmResume = func(plan *db.Plan) bool {
+		fmt.Printf("Plan %s was started from this file on %s and is %s.\n",
+			plan.ID, plan.CreatedAt.Format("2006-01-02 15:04"), plan.Status)
+		return confirmYes(os.Stdout, "Resume it instead of starting a new plan? (--force-new skips this)")
+	}
+	app, err := appFactory(cfg)
 	if err != nil {
 		return err
 	}
@@ -162,13 +496,9 @@ func runNew(ctx context.Context, planPath string, maxIterations int, extremeMode
 }
 
 // runNewWithPrompt starts execution with a plan from an inline prompt string.
-func runNewWithPrompt(ctx context.Context, prompt string, maxIterations int, extremeMode, teamMode bool) error {
+func runNewWithPrompt(ctx context.Context, prompt string, opts runOptions) error {
 	// Create app
-	app, err := appFactory(app.Config{
-		MaxIterationsOverride: maxIterations,
-		ExtremeMode:           extremeMode,
-		TeamMode:              teamMode,
-	})
+	app, err := appFactory(opts.appConfig())
 	if err != nil {
 		return err
 	}
@@ -177,14 +507,42 @@ func runNewWithPrompt(ctx context.Context, prompt string, maxIterations int, ext
 	return app.RunWithPrompt(ctx, prompt)
 }
 
+// runNewFromStdin starts execution with a plan read from input, typically a
+// plan generated by another tool and piped in.
+func runNewFromStdin(ctx context.Context, input io.Reader, opts runOptions) error {
+	if f, ok := input.(*os.File); ok {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			return fmt.Errorf("--stdin expects the plan to be piped in")
+		}
+	}
+
+	content, err := io.ReadAll(input)
+	if err != nil {
+		return fmt.Errorf("failed to read plan from stdin: %w", err)
+	}
+	if strings.TrimSpace(string(content)) == "" {
+		return fmt.Errorf("plan from stdin is empty")
+	}
+	if err := lintBeforeRun("stdin", string(content), opts); err != nil {
+		return err
+	}
+
+	// Create app
+	cfg := opts.appConfig()
+	cfg.PromptFromStdin = true
+	app, err := appFactory(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Run with the piped plan
+	return app.RunWithPrompt(ctx, string(content))
+}
+
 // runResume continues execution of an existing plan.
-func runResume(ctx context.Context, planID string, maxIterations int, extremeMode, teamMode bool) error {
+func runResume(ctx context.Context, planID string, opts runOptions) error {
 	// Create app first to access database
-	app, err := appFactory(app.Config{
-		MaxIterationsOverride: maxIterations,
-		ExtremeMode:           extremeMode,
-		TeamMode:              teamMode,
-	})
+	app, err := appFactory(opts.appConfig())
 	if err != nil {
 		return err
 	}
@@ -33,7 +33,11 @@ type testFlags struct {
 	resumeID      string
 	maxIterations int
 	extremeMode   bool
+	extremeBonus  int
 	teamMode      bool
+	fromStdin     bool
+	fromIteration int
+	restoreWC     bool
 }
 
 // createTestCommand creates a test version of the root command
@@ -59,9 +63,38 @@ Examples:
 			if flags.maxIterations < 0 {
 				return errors.New("--max-iterations cannot be negative")
 			}
+			if flags.extremeBonus < 0 {
+				return errors.New("--extreme-iterations cannot be negative")
+			}
+			flags.extremeMode = flags.extremeMode || cmd.Flags().Changed("extreme-iterations")
+			if cmd.Flags().Changed("from-iteration") {
+				if flags.resumeID == "" {
+					return errors.New("--from-iteration requires --resume")
+				}
+				if flags.fromIteration < 1 {
+					return errors.New("--from-iteration must be at least 1")
+				}
+			}
+			if flags.restoreWC && flags.fromIteration == 0 {
+				return errors.New("--restore-working-copy requires --from-iteration")
+			}
 
 			// Skip jj validation in test command
 
+			// "-" as the plan file reads the plan from stdin (same as main.go)
+			if len(args) == 1 && args[0] == "-" {
+				flags.fromStdin = true
+				args = nil
+			}
+
+			if flags.fromStdin {
+				if flags.resumeID != "" || flags.promptStr != "" || len(args) > 0 {
+					return errors.New("cannot combine --stdin with a plan file, --prompt, or --resume")
+				}
+				// Stdin mode - valid
+				return nil
+			}
+
 			// Validate mode selection (same as main.go)
 			if flags.resumeID != "" {
 				if len(args) > 0 || flags.promptStr != "" {
@@ -80,7 +113,7 @@ Examples:
 			}
 
 			if len(args) == 0 {
-				return errors.New("plan file required (or use --resume or --prompt)")
+				return errors.New("plan file required (or use --resume, --prompt, or --stdin)")
 			}
 
 			// Capture the plan path argument
@@ -91,12 +124,20 @@ Examples:
 
 	rootCmd.Flags().StringVarP(&flags.resumeID, "resume", "r", "",
 		"Resume execution of an existing plan by ID")
+	rootCmd.Flags().IntVar(&flags.fromIteration, "from-iteration", 0,
+		"With --resume, discard the progress, learnings, and feedback of later iterations and resume after this one")
+	rootCmd.Flags().BoolVar(&flags.restoreWC, "restore-working-copy", false,
+		"With --from-iteration, also restore the working copy to that iteration's snapshot")
 	rootCmd.Flags().StringVarP(&flags.promptStr, "prompt", "p", "",
 		"Use inline prompt as the plan instead of a file")
+	rootCmd.Flags().BoolVar(&flags.fromStdin, "stdin", false,
+		"Read the plan from standard input (same as passing - as the plan file)")
 	rootCmd.Flags().IntVar(&flags.maxIterations, "max-iterations", 0,
 		"Override max iterations from config")
 	rootCmd.Flags().BoolVarP(&flags.extremeMode, "extreme", "x", false,
-		"Extreme mode: run +3 iterations after robots think they're done")
+		"Extreme mode: run more iterations after robots think they're done")
+	rootCmd.Flags().IntVar(&flags.extremeBonus, "extreme-iterations", 0,
+		"Bonus iterations per extreme mode round, implying --extreme (default extreme_bonus_iterations from config)")
 	rootCmd.Flags().BoolVarP(&flags.teamMode, "team", "t", false,
 		"Enable agent teams for parallel development")
 
@@ -450,6 +491,39 @@ func TestValidation_ResumeOnlyWorks(t *testing.T) {
 	}
 }
 
+func TestValidation_FromIterationWithResume(t *testing.T) {
+	cmd, flags := createTestCommand()
+
+	_, err := executeCommand(cmd, "-r", "abc123", "--from-iteration", "3", "--restore-working-copy")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if flags.fromIteration != 3 || !flags.restoreWC {
+		t.Errorf("Expected fromIteration 3 with restore, got %d, %v", flags.fromIteration, flags.restoreWC)
+	}
+}
+
+func TestValidation_FromIterationInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"without resume", []string{"plan.md", "--from-iteration", "2"}, "requires --resume"},
+		{"zero", []string{"-r", "abc123", "--from-iteration", "0"}, "must be at least 1"},
+		{"restore without iteration", []string{"-r", "abc123", "--restore-working-copy"}, "requires --from-iteration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, _ := createTestCommand()
+			_, err := executeCommand(cmd, tt.args...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
 func TestValidation_PlanFileOnlyWorks(t *testing.T) {
 	cmd, _ := createTestCommand()
 
@@ -461,6 +535,39 @@ func TestValidation_PlanFileOnlyWorks(t *testing.T) {
 
 // Tests for validateJJRepository function
 
+func TestValidation_DashReadsStdin(t *testing.T) {
+	cmd, flags := createTestCommand()
+
+	_, err := executeCommand(cmd, "-")
+	if err != nil {
+		t.Errorf("Unexpected error with -: %v", err)
+	}
+	if !flags.fromStdin || flags.planPath != "" {
+		t.Errorf("Expected stdin mode without a plan path, got fromStdin=%v planPath=%q", flags.fromStdin, flags.planPath)
+	}
+}
+
+func TestValidation_StdinFlagWorks(t *testing.T) {
+	cmd, flags := createTestCommand()
+
+	_, err := executeCommand(cmd, "--stdin")
+	if err != nil {
+		t.Errorf("Unexpected error with --stdin: %v", err)
+	}
+	if !flags.fromStdin {
+		t.Error("Expected fromStdin to be set")
+	}
+}
+
+func TestValidation_StdinWithPlanFile(t *testing.T) {
+	cmd, _ := createTestCommand()
+
+	_, err := executeCommand(cmd, "--stdin", "plan.md")
+	if err == nil || !strings.Contains(err.Error(), "cannot combine --stdin") {
+		t.Errorf("Expected 'cannot combine --stdin' error, got: %v", err)
+	}
+}
+
 func TestValidateJJRepository_Success(t *testing.T) {
 	// Save original and restore after test
 	originalValidator := jjValidator
@@ -471,7 +578,7 @@ func TestValidateJJRepository_Success(t *testing.T) {
 		return nil
 	}
 
-	err := validateJJRepository(context.Background())
+	err := validateJJRepository(context.Background(), t.TempDir())
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -487,7 +594,7 @@ func TestValidateJJRepository_NotRepo(t *testing.T) {
 		return jj.ErrNotRepo
 	}
 
-	err := validateJJRepository(context.Background())
+	err := validateJJRepository(context.Background(), t.TempDir())
 	if err == nil {
 		t.Error("Expected error for not a jj repository")
 	}
@@ -506,7 +613,7 @@ func TestValidateJJRepository_CommandNotFound(t *testing.T) {
 		return jj.ErrCommandNotFound
 	}
 
-	err := validateJJRepository(context.Background())
+	err := validateJJRepository(context.Background(), t.TempDir())
 	if err == nil {
 		t.Error("Expected error for jj command not found")
 	}
@@ -525,7 +632,7 @@ func TestValidateJJRepository_OtherError(t *testing.T) {
 		return errors.New("some other jj error")
 	}
 
-	err := validateJJRepository(context.Background())
+	err := validateJJRepository(context.Background(), t.TempDir())
 	if err == nil {
 		t.Error("Expected error for generic jj failure")
 	}
@@ -550,7 +657,7 @@ func TestRunNew_PlanFileNotFound(t *testing.T) {
 	tempDir := t.TempDir()
 	nonExistentPath := filepath.Join(tempDir, "nonexistent.md")
 
-	err := runNew(context.Background(), nonExistentPath, 0, false, false)
+	err := runNew(context.Background(), nonExistentPath, runOptions{}, false)
 	if err == nil {
 		t.Error("Expected error for non-existent plan file")
 	}
@@ -577,7 +684,7 @@ func TestRunNew_PlanFileExists_AppFactoryError(t *testing.T) {
 		t.Fatalf("Failed to create test plan file: %v", err)
 	}
 
-	err = runNew(context.Background(), planPath, 0, false, false)
+	err = runNew(context.Background(), planPath, runOptions{}, false)
 	if err == nil {
 		t.Error("Expected error from app factory")
 	}
@@ -615,7 +722,7 @@ func TestRunNew_Success(t *testing.T) {
 		t.Fatalf("Failed to create test plan file: %v", err)
 	}
 
-	err = runNew(context.Background(), planPath, 25, false, false)
+	err = runNew(context.Background(), planPath, runOptions{maxIterations: 25}, false)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -651,7 +758,7 @@ func TestRunNew_AppRunError(t *testing.T) {
 		t.Fatalf("Failed to create test plan file: %v", err)
 	}
 
-	err = runNew(context.Background(), planPath, 0, false, false)
+	err = runNew(context.Background(), planPath, runOptions{}, false)
 	if err == nil {
 		t.Error("Expected error from app.Run")
 	}
@@ -672,7 +779,7 @@ func TestRunNewWithPrompt_AppFactoryError(t *testing.T) {
 		return nil, errors.New("failed to create app")
 	}
 
-	err := runNewWithPrompt(context.Background(), "Fix the bug", 0, false, false)
+	err := runNewWithPrompt(context.Background(), "Fix the bug", runOptions{})
 	if err == nil {
 		t.Error("Expected error from app factory")
 	}
@@ -702,7 +809,7 @@ func TestRunNewWithPrompt_Success(t *testing.T) {
 		return mockApp, nil
 	}
 
-	err := runNewWithPrompt(context.Background(), "Fix the login bug", 20, false, false)
+	err := runNewWithPrompt(context.Background(), "Fix the login bug", runOptions{maxIterations: 20})
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -730,7 +837,7 @@ func TestRunNewWithPrompt_AppRunError(t *testing.T) {
 		return mockApp, nil
 	}
 
-	err := runNewWithPrompt(context.Background(), "Fix bug", 0, false, false)
+	err := runNewWithPrompt(context.Background(), "Fix bug", runOptions{})
 	if err == nil {
 		t.Error("Expected error from app.RunWithPrompt")
 	}
@@ -741,6 +848,50 @@ func TestRunNewWithPrompt_AppRunError(t *testing.T) {
 
 // Tests for runResume function
 
+func TestRunNewFromStdin_Success(t *testing.T) {
+	originalFactory := appFactory
+	defer func() { appFactory = originalFactory }()
+
+	var capturedPrompt string
+	var capturedFromStdin bool
+	appFactory = func(cfg app.Config) (App, error) {
+		capturedFromStdin = cfg.PromptFromStdin
+		return &mockAppImpl{
+			runWithPromptFunc: func(ctx context.Context, prompt string) error {
+				capturedPrompt = prompt
+				return nil
+			},
+		}, nil
+	}
+
+	input := strings.NewReader("# Generated plan\n\nDo the thing\n")
+	err := runNewFromStdin(context.Background(), input, runOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if capturedPrompt != "# Generated plan\n\nDo the thing\n" {
+		t.Errorf("Expected piped plan to be passed to RunWithPrompt, got %q", capturedPrompt)
+	}
+	if !capturedFromStdin {
+		t.Error("Expected PromptFromStdin=true to be passed to app.Config")
+	}
+}
+
+func TestRunNewFromStdin_Empty(t *testing.T) {
+	originalFactory := appFactory
+	defer func() { appFactory = originalFactory }()
+
+	appFactory = func(cfg app.Config) (App, error) {
+		t.Error("appFactory should not be called for an empty plan")
+		return nil, nil
+	}
+
+	err := runNewFromStdin(context.Background(), strings.NewReader("  \n"), runOptions{})
+	if err == nil || !strings.Contains(err.Error(), "empty") {
+		t.Errorf("Expected empty plan error, got: %v", err)
+	}
+}
+
 func TestRunResume_AppFactoryError(t *testing.T) {
 	// Save original and restore after test
 	originalFactory := appFactory
@@ -751,7 +902,7 @@ func TestRunResume_AppFactoryError(t *testing.T) {
 		return nil, errors.New("failed to create app")
 	}
 
-	err := runResume(context.Background(), "plan-123", 0, false, false)
+	err := runResume(context.Background(), "plan-123", runOptions{})
 	if err == nil {
 		t.Error("Expected error from app factory")
 	}
@@ -781,7 +932,7 @@ func TestRunResume_Success(t *testing.T) {
 		return mockApp, nil
 	}
 
-	err := runResume(context.Background(), "plan-xyz", 42, false, false)
+	err := runResume(context.Background(), "plan-xyz", runOptions{maxIterations: 42})
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -809,7 +960,7 @@ func TestRunResume_PlanNotFound(t *testing.T) {
 		return mockApp, nil
 	}
 
-	err := runResume(context.Background(), "nonexistent-plan", 0, false, false)
+	err := runResume(context.Background(), "nonexistent-plan", runOptions{})
 	if err == nil {
 		t.Error("Expected error for plan not found")
 	}
@@ -833,7 +984,7 @@ func TestRunResume_OtherError(t *testing.T) {
 		return mockApp, nil
 	}
 
-	err := runResume(context.Background(), "plan-123", 0, false, false)
+	err := runResume(context.Background(), "plan-123", runOptions{})
 	if err == nil {
 		t.Error("Expected error from resume")
 	}
@@ -864,6 +1015,32 @@ func TestCLI_ExtremeModeFlagLong(t *testing.T) {
 	}
 }
 
+func TestCLI_ExtremeModeBonus(t *testing.T) {
+	for _, args := range [][]string{
+		{"plan.md", "--extreme-iterations", "5"},
+		{"plan.md", "-x", "--extreme-iterations=5"},
+	} {
+		cmd, flags := createTestCommand()
+		if _, err := executeCommand(cmd, args...); err != nil {
+			t.Errorf("%v: unexpected error: %v", args, err)
+		}
+		if !flags.extremeMode || flags.extremeBonus != 5 {
+			t.Errorf("%v: expected extreme mode with 5 bonus iterations, got %v, %d", args, flags.extremeMode, flags.extremeBonus)
+		}
+	}
+
+	// -x combines with other short flags rather than taking a value
+	cmd, flags := createTestCommand()
+	if _, err := executeCommand(cmd, "plan.md", "-xt"); err != nil || !flags.extremeMode || !flags.teamMode {
+		t.Errorf("Expected -xt to enable extreme and team mode, got %v, %v (err %v)", flags.extremeMode, flags.teamMode, err)
+	}
+
+	cmd, _ = createTestCommand()
+	if _, err := executeCommand(cmd, "plan.md", "--extreme-iterations=-1"); err == nil || !strings.Contains(err.Error(), "--extreme-iterations cannot be negative") {
+		t.Errorf("Expected negative --extreme-iterations error, got: %v", err)
+	}
+}
+
 func TestCLI_ExtremeModeDefaultFalse(t *testing.T) {
 	cmd, flags := createTestCommand()
 	_, err := executeCommand(cmd, "plan.md")
@@ -927,7 +1104,7 @@ func TestRunNew_TeamModePassedToApp(t *testing.T) {
 	planPath := filepath.Join(tempDir, "plan.md")
 	os.WriteFile(planPath, []byte("# Test Plan"), 0644)
 
-	err := runNew(context.Background(), planPath, 0, false, true)
+	err := runNew(context.Background(), planPath, runOptions{teamMode: true}, false)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -936,11 +1113,36 @@ func TestRunNew_TeamModePassedToApp(t *testing.T) {
 	}
 }
 
+func TestRunNew_ForceNewPassedToApp(t *testing.T) {
+	originalFactory := appFactory
+	defer func() { appFactory = originalFactory }()
+
+	var captured app.Config
+	appFactory = func(cfg app.Config) (App, error) {
+		captured = cfg
+		return &mockAppImpl{runFunc: func(ctx context.Context, planPath string) error { return nil }}, nil
+	}
+
+	planPath := filepath.Join(t.TempDir(), "plan.md")
+	os.WriteFile(planPath, []byte("# Test Plan"), 0644)
+
+	if err := runNew(context.Background(), planPath, runOptions{forceNew: true}, false); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !captured.ForceNew {
+		t.Error("Expected ForceNew=true to be passed to app.Config")
+	}
+	if captured.ConfirmResume == nil {
+		t.Error("Expected ConfirmResume to be set for a plan file")
+	}
+}
+
 func TestRunNew_ExtremeModePassedToApp(t *testing.T) {
 	originalFactory := appFactory
 	defer func() { appFactory = originalFactory }()
 
 	var capturedExtremeMode bool
+	var capturedExtremeBonus int
 	mockApp := &mockAppImpl{
 		runFunc: func(ctx context.Context, planPath string) error {
 			return nil
@@ -948,6 +1150,7 @@ func TestRunNew_ExtremeModePassedToApp(t *testing.T) {
 	}
 	appFactory = func(cfg app.Config) (App, error) {
 		capturedExtremeMode = cfg.ExtremeMode
+		capturedExtremeBonus = cfg.ExtremeBonus
 		return mockApp, nil
 	}
 
@@ -955,13 +1158,92 @@ func TestRunNew_ExtremeModePassedToApp(t *testing.T) {
 	planPath := filepath.Join(tempDir, "plan.md")
 	os.WriteFile(planPath, []byte("# Test Plan"), 0644)
 
-	err := runNew(context.Background(), planPath, 0, true, false)
+	err := runNew(context.Background(), planPath, runOptions{extremeMode: true, extremeBonus: 5}, false)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
 	if !capturedExtremeMode {
 		t.Error("Expected ExtremeMode=true to be passed to app.Config")
 	}
+	if capturedExtremeBonus != 5 {
+		t.Errorf("Expected ExtremeBonus=5 to be passed to app.Config, got %d", capturedExtremeBonus)
+	}
+}
+
+func TestRunNew_DecomposePassedToApp(t *testing.T) {
+	originalFactory := appFactory
+	defer func() { appFactory = originalFactory }()
+
+	var capturedDecompose bool
+	mockApp := &mockAppImpl{
+		runFunc: func(ctx context.Context, planPath string) error {
+			return nil
+		},
+	}
+	appFactory = func(cfg app.Config) (App, error) {
+		capturedDecompose = cfg.Decompose
+		return mockApp, nil
+	}
+
+	tempDir := t.TempDir()
+	planPath := filepath.Join(tempDir, "plan.md")
+	os.WriteFile(planPath, []byte("# Test Plan"), 0644)
+
+	err := runNew(context.Background(), planPath, runOptions{decompose: true}, false)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !capturedDecompose {
+		t.Error("Expected Decompose=true to be passed to app.Config")
+	}
+}
+
+func TestRunResume_CreatePRPassedToApp(t *testing.T) {
+	originalFactory := appFactory
+	defer func() { appFactory = originalFactory }()
+
+	var capturedCreatePR bool
+	mockApp := &mockAppImpl{
+		resumeFunc: func(ctx context.Context, planID string) error {
+			return nil
+		},
+	}
+	appFactory = func(cfg app.Config) (App, error) {
+		capturedCreatePR = cfg.CreatePR
+		return mockApp, nil
+	}
+
+	err := runResume(context.Background(), "plan-123", runOptions{createPR: true})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !capturedCreatePR {
+		t.Error("Expected CreatePR=true to be passed to app.Config")
+	}
+}
+
+func TestRunResume_PassesWorkDir(t *testing.T) {
+	originalFactory := appFactory
+	defer func() { appFactory = originalFactory }()
+
+	var captured app.Config
+	mockApp := &mockAppImpl{
+		resumeFunc: func(ctx context.Context, planID string) error {
+			return nil
+		},
+	}
+	appFactory = func(cfg app.Config) (App, error) {
+		captured = cfg
+		return mockApp, nil
+	}
+
+	err := runResume(context.Background(), "plan-123", runOptions{workDir: "/src/api", workDirOverride: true})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if captured.WorkDir != "/src/api" || !captured.WorkDirOverride {
+		t.Errorf("Expected WorkDir=/src/api with override, got %q (override %v)", captured.WorkDir, captured.WorkDirOverride)
+	}
 }
 
 // mockAppImpl is a mock implementation of the App interface for testing
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/mcp"
+	"github.com/spf13/cobra"
+)
+
+func mcpCmd() *cobra.Command {
+	var planID string
+
+	cmd := &cobra.Command{
+		Use:   "mcp",
+		Short: "Serve ralph's tools to Claude sessions over MCP",
+		Long: `Speak the Model Context Protocol on stdin and stdout, offering the
+ralph_status tool agents report their progress, learnings, and status with.
+With --plan, it also offers tools to query the plan's state on demand
+(get_plan, get_progress_history, get_learnings, get_reviewer_feedback) and to
+checkpoint progress mid-session (record_progress).
+
+ralph starts this server for its own sessions when claude.status_reporting is
+"tool" or claude.state_tools is set; it isn't meant to be run by hand. Logs go
+to stderr.
+
+Examples:
+  ralph mcp
+  ralph mcp --plan 3f2a9c1e`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version := "devel"
+			if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+				version = info.Main.Version
+			}
+			server := mcp.NewServer(version)
+			server.AddTool(mcp.StatusTool())
+
+			if planID != "" {
+				database, _, err := openPlansDB("")
+				if err != nil {
+					return err
+				}
+				defer func() {
+					if closeErr := database.Close(); closeErr != nil {
+						log.Warn("failed to close database", "error", closeErr)
+					}
+				}()
+				if _, err := database.GetPlan(planID); errors.Is(err, db.ErrNotFound) {
+					return fmt.Errorf("plan not found: %s", planID)
+				} else if err != nil {
+					return fmt.Errorf("failed to get plan: %w", err)
+				}
+				for _, tool := range mcp.StateTools(database, planID) {
+					server.AddTool(tool)
+				}
+			}
+
+			return server.Serve(cmd.Context(), cmd.InOrStdin(), cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVar(&planID, "plan", "", "Offer tools querying this plan's state")
+	return cmd
+}
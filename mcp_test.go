@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMCPCmd_Stdio(t *testing.T) {
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"ralph_status","arguments":{"progress":"done"}}}`,
+	}, "\n")
+
+	var output bytes.Buffer
+	cmd := mcpCmd()
+	cmd.SetArgs(nil)
+	cmd.SetIn(strings.NewReader(input))
+	cmd.SetOut(&output)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("mcp failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 responses, got %q", output.String())
+	}
+	if !strings.Contains(lines[0], `"serverInfo":{"name":"ralph"`) {
+		t.Errorf("unexpected initialize response: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "Status recorded.") {
+		t.Errorf("unexpected tools/call response: %s", lines[1])
+	}
+}
+
+func TestMCPCmd_UnknownPlan(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cmd := mcpCmd()
+	cmd.SetArgs([]string{"--plan", "missing"})
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "plan not found") {
+		t.Fatalf("expected a plan not found error, got %v", err)
+	}
+}
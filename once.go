@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/loop"
+	"github.com/gerunddev/ralph/internal/parser"
+	"github.com/gerunddev/ralph/internal/redact"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// onceRunner runs a developer session in workDir, calling onEvent with each
+// event as it streams, and returns its output. It can be replaced in tests.
+var onceRunner = defaultOnceRunner
+
+// defaultOnceRunner runs prompt in a Claude session configured like the
+// loop's developer, under the throttle in database, and returns the text
+// of its messages.
+func defaultOnceRunner(ctx context.Context, cfg *config.Config, database *db.DB, workDir, prompt string, onEvent func(claude.StreamEvent)) (string, error) {
+	return runClaudeSession(ctx, cfg, database, cfg.Claude.Developer, workDir, prompt, onEvent)
+}
+
+func onceCmd() *cobra.Command {
+	var instruction string
+
+	cmd := &cobra.Command{
+		Use:   "once",
+		Short: "Run a single developer session, with no review loop",
+		Long: `Run exactly one developer session on an instruction and exit. The prompt
+includes the repository's convention files and the repo-wide learnings of
+earlier plans, like the loop's. No reviewer runs.
+
+The session is stored as a plan of its own, with its events, transcript,
+progress, and learnings. A session that ends without signaling done leaves
+the plan stopped, so the full loop can pick it up with ralph -r.
+
+Examples:
+  ralph once -p "Rename Fetcher.Get to Fetch"
+  ralph once -p "Add a --verbose flag to the sync command"`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if strings.TrimSpace(instruction) == "" {
+				return fmt.Errorf("--prompt is required")
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			workDir, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("failed to get working directory: %w", err)
+			}
+
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			return runOnce(cmd.Context(), cmd.OutOrStdout(), cfg, database, jj.NewClient(workDir), workDir, instruction)
+		},
+	}
+
+	cmd.Flags().StringVarP(&instruction, "prompt", "p", "", "Instruction for the developer")
+
+	return cmd
+}
+
+// runOnce runs one developer session on instruction, stores it as a plan
+// with the session's records, and prints what it did.
+func runOnce(ctx context.Context, out io.Writer, cfg *config.Config, database *db.DB, jjClient *jj.Client, workDir, instruction string) error {
+	root, err := jjClient.Root(ctx)
+	if err != nil || root == "" {
+		root = workDir
+	}
+
+	customPrompt, err := cfg.GetAgentPrompt("developer")
+	if err != nil {
+		return fmt.Errorf("failed to build developer prompt: %w", err)
+	}
+	devCtx := agent.DeveloperContext{
+		PlanContent: instruction,
+		Conventions: repoConventions(ctx, cfg, jjClient, workDir),
+		Locale:      cfg.Locale,
+		Template:    customPrompt,
+	}
+	if cfg.GlobalLearningsLimit > 0 {
+		learnings, err := database.GetGlobalLearnings(root)
+		if err != nil {
+			log.Warn("failed to load global learnings", "error", err)
+		}
+		devCtx.GlobalLearnings = loop.FormatGlobalLearnings(instruction, learnings, cfg.GlobalLearningsLimit)
+	}
+	prompt, omitted, err := agent.FitPrompt(loop.PromptBudget(cfg.Claude.Model), agent.PrunableSections{Learnings: &devCtx.GlobalLearnings},
+		func() (string, error) { return agent.BuildDeveloperPrompt(devCtx) })
+	if err != nil {
+		return fmt.Errorf("failed to build developer prompt: %w", err)
+	}
+	for _, o := range omitted {
+		log.Warn("learnings exceed the context budget, pruned them", "lines", o.Lines, "tokens", o.Tokens)
+	}
+
+	// The base lets ralph -r review everything since the session started
+	baseChangeID, err := jjClient.GetParentChangeID(ctx)
+	if err != nil {
+		log.Warn("failed to get parent change ID", "error", err)
+	}
+	plan := &db.Plan{
+		ID:           uuid.New().String(),
+		Content:      instruction,
+		Status:       db.PlanStatusRunning,
+		BaseChangeID: baseChangeID,
+		WorkDir:      workDir,
+	}
+	if err := database.CreatePlan(plan); err != nil {
+		return fmt.Errorf("failed to store plan: %w", err)
+	}
+	session := &db.PlanSession{
+		ID:          uuid.New().String(),
+		PlanID:      plan.ID,
+		Iteration:   1,
+		InputPrompt: prompt,
+		Status:      db.PlanSessionRunning,
+		AgentType:   db.LoopAgentDeveloper,
+	}
+	if err := database.CreatePlanSession(session); err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+
+	records := db.NewBatchWriter(database, db.DefaultBatchInterval)
+	output, err := onceRunner(ctx, cfg, database, workDir, prompt, func(event claude.StreamEvent) {
+		loop.RecordStreamEvent(records, session.ID, event)
+	})
+	if closeErr := records.Close(); closeErr != nil {
+		log.Warn("failed to store events", "error", closeErr)
+	}
+	if err != nil {
+		if completeErr := database.CompletePlanSession(session.ID, db.PlanSessionFailed, output); completeErr != nil {
+			log.Warn("failed to mark session as failed", "error", completeErr)
+		}
+		if statusErr := database.UpdatePlanStatusWithReason(plan.ID, db.PlanStatusFailed, err.Error()); statusErr != nil {
+			log.Warn("failed to mark plan as failed", "error", statusErr)
+		}
+		return fmt.Errorf("developer session failed: %w", err)
+	}
+
+	result := parser.ParseAgentOutput(output, "developer")
+	if err := storeOnce(database, plan.ID, session.ID, root, output, result, cfg.GlobalLearningsLimit > 0); err != nil {
+		return err
+	}
+	redactor, err := cfg.Redaction.Redactor()
+	if err != nil {
+		return err
+	}
+	writeOnceText(out, plan.ID, session.ID, result, redactor)
+	return nil
+}
+
+// storeOnce completes a one-shot session and its plan, storing its progress
+// and learnings, and the learnings it promoted repo-wide when promote is
+// set. The plan is completed if the developer signaled done, and stopped
+// otherwise.
+func storeOnce(database *db.DB, planID, sessionID, repoRoot, output string, result *parser.AgentParseResult, promote bool) error {
+	if err := database.CompletePlanSession(sessionID, db.PlanSessionCompleted, output); err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+	if progress := stripDoneMarkers(result.Progress); progress != "" {
+		if err := database.CreateProgress(&db.Progress{PlanID: planID, SessionID: sessionID, Content: progress}); err != nil {
+			return fmt.Errorf("failed to store progress: %w", err)
+		}
+	}
+	if learnings := stripDoneMarkers(result.Learnings); learnings != "" {
+		if err := database.CreateLearnings(&db.Learnings{PlanID: planID, SessionID: sessionID, Content: learnings}); err != nil {
+			return fmt.Errorf("failed to store learnings: %w", err)
+		}
+	}
+	if promote {
+		for _, content := range result.GlobalLearnings {
+			if content = stripDoneMarkers(content); content == "" {
+				continue
+			}
+			if _, err := database.CreateGlobalLearning(&db.GlobalLearning{RepoRoot: repoRoot, PlanID: planID, Content: content}); err != nil {
+				log.Warn("failed to store global learning", "error", err)
+			}
+		}
+	}
+
+	if result.DevDone {
+		return database.UpdatePlanStatus(planID, db.PlanStatusCompleted)
+	}
+	return database.UpdatePlanStatusWithReason(planID, db.PlanStatusStopped, "one-shot session ended before the developer signaled done")
+}
+
+// stripDoneMarkers removes the completion markers from text a session
+// stored, so a later prompt that includes it doesn't read as done.
+func stripDoneMarkers(s string) string {
+	s = strings.ReplaceAll(s, parser.DevDoneMarker, "")
+	return strings.TrimSpace(strings.ReplaceAll(s, parser.DoneMarker, ""))
+}
+
+// writeOnceText prints what a one-shot session reported, with secrets
+// masked by redactor.
+func writeOnceText(out io.Writer, planID, sessionID string, result *parser.AgentParseResult, redactor *redact.Redactor) {
+	if result.DevDone {
+		fmt.Fprintln(out, "Developer signaled done")
+	} else {
+		fmt.Fprintln(out, "Developer did not signal done")
+	}
+	if progress := stripDoneMarkers(result.Progress); progress != "" {
+		fmt.Fprintf(out, "\nProgress:\n%s\n", redactor.String(progress))
+	}
+	fmt.Fprintf(out, "\nStored as plan %s (ralph transcript %s)\n", planID, sessionID)
+	if !result.DevDone {
+		fmt.Fprintf(out, "Continue in the full loop with: ralph -r %s\n", planID)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+// newOnceJJ returns a jj client for a repository rooted at /repo whose
+// working copy's parent is change "base".
+func newOnceJJ() *jj.Client {
+	jjClient := jj.NewClient("/repo")
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		if args[0] == "root" {
+			return "/repo\n", "", nil
+		}
+		return "base\n", "", nil
+	})
+	return jjClient
+}
+
+func TestRunOnce(t *testing.T) {
+	originalRunner := onceRunner
+	t.Cleanup(func() { onceRunner = originalRunner })
+
+	tests := []struct {
+		name       string
+		output     string
+		wantStatus db.PlanStatus
+		wantText   []string
+	}{
+		{
+			"done",
+			"## Progress\nRenamed Get to Fetch.\n\n## Learnings\nCallers live in cmd/.\n\nDEV_DONE DEV_DONE DEV_DONE!!!",
+			db.PlanStatusCompleted,
+			[]string{"Developer signaled done\n", "\nProgress:\nRenamed Get to Fetch.\n"},
+		},
+		{
+			"not done",
+			"## Progress\nRenamed half the callers.\n\n## Learnings\nCallers live in cmd/.",
+			db.PlanStatusStopped,
+			[]string{"Developer did not signal done\n", "Continue in the full loop with: ralph -r "},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := newPlansTestDB(t)
+			if _, err := database.CreateGlobalLearning(&db.GlobalLearning{RepoRoot: "/repo", PlanID: "earlier", Content: "Run go generate after renames"}); err != nil {
+				t.Fatal(err)
+			}
+
+			var prompt string
+			onceRunner = func(ctx context.Context, cfg *config.Config, database *db.DB, workDir, p string, onEvent func(claude.StreamEvent)) (string, error) {
+				prompt = p
+				onEvent(claude.StreamEvent{Type: claude.EventHeartbeat})
+				onEvent(claude.StreamEvent{
+					Type:    claude.EventMessage,
+					Raw:     []byte(`{"type":"assistant"}`),
+					Message: &claude.MessageContent{Text: tt.output},
+				})
+				return tt.output, nil
+			}
+
+			var out bytes.Buffer
+			if err := runOnce(context.Background(), &out, config.DefaultConfig(), database, newOnceJJ(), "/repo", "Rename Fetcher.Get to Fetch"); err != nil {
+				t.Fatalf("runOnce() error: %v", err)
+			}
+			if !strings.Contains(prompt, "Rename Fetcher.Get to Fetch") || !strings.Contains(prompt, "Run go generate after renames") {
+				t.Error("prompt is missing the instruction or the repo-wide learnings")
+			}
+			for _, want := range tt.wantText {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+
+			plans, err := database.ListPlans(10)
+			if err != nil || len(plans) != 1 {
+				t.Fatalf("ListPlans() = %d plans, %v; want the one-shot plan", len(plans), err)
+			}
+			plan := plans[0]
+			if plan.Status != tt.wantStatus || plan.BaseChangeID != "base" {
+				t.Errorf("plan status = %s, base = %q; want %s, %q", plan.Status, plan.BaseChangeID, tt.wantStatus, "base")
+			}
+			sessions, err := database.GetPlanSessionsByPlan(plan.ID)
+			if err != nil || len(sessions) != 1 || sessions[0].Status != db.PlanSessionCompleted {
+				t.Fatalf("GetPlanSessionsByPlan() = %+v, %v; want one completed session", sessions, err)
+			}
+			events, err := database.GetEventsBySession(sessions[0].ID)
+			if err != nil || len(events) != 1 {
+				t.Errorf("GetEventsBySession() = %d events, %v; want the message without the heartbeat", len(events), err)
+			}
+			learnings, err := database.GetLatestLearnings(plan.ID)
+			if err != nil || learnings == nil || learnings.Content != "Callers live in cmd/." {
+				t.Errorf("GetLatestLearnings() = %+v, %v; want the session's learnings", learnings, err)
+			}
+		})
+	}
+}
+
+func TestRunOnce_SessionFails(t *testing.T) {
+	originalRunner := onceRunner
+	t.Cleanup(func() { onceRunner = originalRunner })
+	onceRunner = func(ctx context.Context, cfg *config.Config, database *db.DB, workDir, p string, onEvent func(claude.StreamEvent)) (string, error) {
+		return "", errors.New("claude not found")
+	}
+
+	database := newPlansTestDB(t)
+	err := runOnce(context.Background(), &bytes.Buffer{}, config.DefaultConfig(), database, newOnceJJ(), "/repo", "Fix it")
+	if err == nil || !strings.Contains(err.Error(), "claude not found") {
+		t.Fatalf("runOnce() error = %v, want the session's error", err)
+	}
+	plans, err := database.ListPlans(10)
+	if err != nil || len(plans) != 1 || plans[0].Status != db.PlanStatusFailed {
+		t.Errorf("ListPlans() = %+v, %v; want the plan marked failed", plans, err)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"github.com/gerunddev/ralph/internal/app"
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/spf13/cobra"
+)
+
+// plansCmd creates the plans subcommand group.
+func plansCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plans",
+		Short: "Plan management commands",
+		Long: `Plan management commands for archiving, pruning, cancelling, and deleting plans.
+
+Archived plans are moved, with their sessions, events, transcripts, progress,
+learnings, and reviewer feedback, into a separate SQLite archive database.`,
+	}
+
+	cmd.AddCommand(plansArchiveCmd())
+	cmd.AddCommand(plansPruneCmd())
+	cmd.AddCommand(plansEncryptCmd())
+	cmd.AddCommand(plansCancelCmd())
+	cmd.AddCommand(plansDeleteCmd())
+
+	return cmd
+}
+
+// openPlansDB loads config and opens the centralized plans database.
+// Returns the database and the archive path to use (archivePath if set,
+// otherwise the default next to the plans database).
+func openPlansDB(archivePath string) (*db.DB, string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, "", err
+	}
+
+	database, err := app.OpenPlansDB(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if archivePath == "" {
+		archivePath = db.ArchiveDBPath(cfg.GetProjectsDir())
+	}
+	return database, archivePath, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/spf13/cobra"
+)
+
+func plansArchiveCmd() *cobra.Command {
+	var archivePath string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "archive <plan-id>...",
+		Short: "Move plans to the archive database",
+		Long: `Move one or more plans and all their history to the archive database
+and delete them from the live database in a single transaction.
+
+Only completed plans are archived unless --force is given. Running plans
+cannot be archived.
+
+Examples:
+  ralph plans archive abc123
+  ralph plans archive abc123 --force
+  ralph plans archive abc123 def456 --archive ~/ralph-archive.db`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, path, err := openPlansDB(archivePath)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			return archivePlans(cmd.OutOrStdout(), database, path, args, force)
+		},
+	}
+
+	cmd.Flags().StringVar(&archivePath, "archive", "", "Archive database path (default: archive.db in the projects dir)")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Archive plans that aren't completed")
+
+	return cmd
+}
+
+// archivePlans verifies each plan can be archived, then moves them all.
+// Plans that aren't completed are refused unless force is set; running
+// plans are always refused.
+func archivePlans(out io.Writer, database *db.DB, archivePath string, planIDs []string, force bool) error {
+	for _, id := range planIDs {
+		plan, err := database.GetPlan(id)
+		if errors.Is(err, db.ErrNotFound) {
+			return fmt.Errorf("plan not found: %s", id)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load plan %s: %w", id, err)
+		}
+		if plan.Status == db.PlanStatusRunning {
+			return fmt.Errorf("plan %s is running and cannot be archived", id)
+		}
+		if plan.Status != db.PlanStatusCompleted && !force {
+			return fmt.Errorf("plan %s is %s, not completed (pass --force to archive it anyway)", id, plan.Status)
+		}
+	}
+
+	if err := database.ArchivePlans(archivePath, planIDs); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Archived %d plan(s) to %s\n", len(planIDs), archivePath)
+	return nil
+}
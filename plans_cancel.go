@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/spf13/cobra"
+)
+
+func plansCancelCmd() *cobra.Command {
+	var reason string
+
+	cmd := &cobra.Command{
+		Use:   "cancel <plan-id>",
+		Short: "Give up on a plan",
+		Long: `Mark a plan that won't be resumed as cancelled, with an optional reason.
+
+A plan still marked running (because its ralph process was killed or the
+machine crashed) is marked abandoned instead. Cancelled and abandoned plans
+can still be resumed with ralph --resume.
+
+Examples:
+  ralph plans cancel 3f2a9c1e
+  ralph plans cancel 3f2a9c1e --reason "superseded by the v2 plan"`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			return cancelPlan(cmd.OutOrStdout(), database, args[0], reason)
+		},
+	}
+
+	cmd.Flags().StringVar(&reason, "reason", "", "Why the plan is being cancelled")
+
+	return cmd
+}
+
+// cancelPlan marks a plan cancelled, or abandoned if it is still marked
+// running.
+func cancelPlan(out io.Writer, database *db.DB, planID, reason string) error {
+	plan, err := database.GetPlan(planID)
+	if errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("plan not found: %s", planID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get plan: %w", err)
+	}
+
+	status := db.PlanStatusCancelled
+	if plan.Status == db.PlanStatusRunning {
+		status = db.PlanStatusAbandoned
+	}
+	if reason == "" {
+		reason = "Cancelled by user"
+	}
+
+	err = database.UpdatePlanStatusWithReason(planID, status, reason)
+	if errors.Is(err, db.ErrInvalidTransition) {
+		return fmt.Errorf("plan %s is %s and cannot be cancelled", planID, plan.Status)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update plan: %w", err)
+	}
+
+	fmt.Fprintf(out, "Plan %s marked %s\n", planID, status)
+	return nil
+}
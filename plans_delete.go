@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/spf13/cobra"
+)
+
+func plansDeleteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete <plan-id>...",
+		Short: "Delete plans and all their history",
+		Long: `Delete one or more plans with their sessions, events, transcripts,
+progress, learnings, reviewer feedback, and tasks in a single transaction.
+Unlike archive, nothing is kept.
+
+Running plans cannot be deleted.
+
+Examples:
+  ralph plans delete abc123
+  ralph plans delete abc123 def456`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			return deletePlans(cmd.OutOrStdout(), database, args)
+		},
+	}
+
+	return cmd
+}
+
+// deletePlans verifies each plan can be deleted, then deletes them all.
+func deletePlans(out io.Writer, database *db.DB, planIDs []string) error {
+	for _, id := range planIDs {
+		plan, err := database.GetPlan(id)
+		if errors.Is(err, db.ErrNotFound) {
+			return fmt.Errorf("plan not found: %s", id)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load plan %s: %w", id, err)
+		}
+		if plan.Status == db.PlanStatusRunning {
+			return fmt.Errorf("plan %s is running and cannot be deleted", id)
+		}
+	}
+
+	if err := database.DeletePlans(planIDs); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Deleted %d plan(s)\n", len(planIDs))
+	return nil
+}
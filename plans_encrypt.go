@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/spf13/cobra"
+)
+
+func plansEncryptCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "encrypt",
+		Short: "Encrypt prompts and outputs stored before encryption was enabled",
+		Long: `Encrypt plan content, prompts, Claude output, raw events, and transcripts
+that are still stored in plaintext. New data is encrypted as it is written once
+encryption is enabled; this converts what was stored before.
+
+Requires "encryption": {"enabled": true} in the config and the key in
+RALPH_ENCRYPTION_KEY (or the configured key_env or keychain service).
+
+Examples:
+  ralph plans encrypt`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			if !cfg.Encryption.Enabled {
+				return errors.New(`encryption is not enabled: set "encryption": {"enabled": true} in the config first`)
+			}
+
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			return encryptPlans(cmd.Context(), cmd.OutOrStdout(), database)
+		},
+	}
+}
+
+// encryptPlans encrypts the plaintext sensitive values in the database.
+func encryptPlans(ctx context.Context, out io.Writer, database *db.DB) error {
+	n, err := database.EncryptExisting(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Encrypted %d value(s)\n", n)
+	return nil
+}
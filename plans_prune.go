@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/spf13/cobra"
+)
+
+func plansPruneCmd() *cobra.Command {
+	var olderThan string
+	var archivePath string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Archive completed plans older than a given age",
+		Long: `Move completed plans not updated within the given age to the archive
+database and delete them from the live database in a single transaction.
+
+Ages accept Go durations plus d (days) and w (weeks) suffixes.
+
+Examples:
+  ralph plans prune --older-than 30d
+  ralph plans prune --older-than 2w --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			age, err := parseAge(olderThan)
+			if err != nil {
+				return err
+			}
+
+			database, path, err := openPlansDB(archivePath)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			return prunePlans(cmd.OutOrStdout(), database, path, time.Now().Add(-age), dryRun)
+		},
+	}
+
+	cmd.Flags().StringVar(&olderThan, "older-than", "", "Archive completed plans older than this age (e.g. 30d)")
+	cmd.Flags().StringVar(&archivePath, "archive", "", "Archive database path (default: archive.db in the projects dir)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List plans that would be archived without moving them")
+	_ = cmd.MarkFlagRequired("older-than")
+
+	return cmd
+}
+
+// prunePlans archives completed plans last updated before cutoff.
+func prunePlans(out io.Writer, database *db.DB, archivePath string, cutoff time.Time, dryRun bool) error {
+	plans, err := database.ListCompletedPlansBefore(cutoff)
+	if err != nil {
+		return fmt.Errorf("failed to list plans: %w", err)
+	}
+
+	if len(plans) == 0 {
+		fmt.Fprintln(out, "No plans to prune")
+		return nil
+	}
+
+	ids := make([]string, len(plans))
+	for i, plan := range plans {
+		ids[i] = plan.ID
+		fmt.Fprintf(out, "  %s  %s  %s\n", plan.ID, plan.UpdatedAt.Format("2006-01-02"), plan.OriginPath)
+	}
+
+	if dryRun {
+		fmt.Fprintf(out, "\n%d plan(s) would be archived to %s\n", len(plans), archivePath)
+		return nil
+	}
+
+	if err := database.ArchivePlans(archivePath, ids); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\nArchived %d plan(s) to %s\n", len(plans), archivePath)
+	return nil
+}
+
+// parseAge parses a duration that may use d (days) or w (weeks) suffixes.
+func parseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+
+	var age time.Duration
+	if unit != 0 {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q: expected e.g. 30d, 2w, or 12h", s)
+		}
+		age = time.Duration(n) * unit
+	} else {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q: expected e.g. 30d, 2w, or 12h", s)
+		}
+		age = d
+	}
+
+	if age <= 0 {
+		return 0, fmt.Errorf("age must be positive: %s", s)
+	}
+	return age, nil
+}
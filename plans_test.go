@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+// newPlansTestDB creates a file-backed plans database for command tests.
+func newPlansTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	database, err := db.New(filepath.Join(t.TempDir(), "ralph.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	return database
+}
+
+func TestPlansCmd_SubcommandGroup(t *testing.T) {
+	cmd := plansCmd()
+
+	if cmd.Use != "plans" {
+		t.Errorf("plansCmd().Use = %q, want %q", cmd.Use, "plans")
+	}
+
+	subNames := make(map[string]bool)
+	for _, sub := range cmd.Commands() {
+		subNames[sub.Name()] = true
+	}
+	for _, e := range []string{"archive", "prune", "encrypt", "cancel", "delete"} {
+		if !subNames[e] {
+			t.Errorf("plansCmd() missing subcommand %q", e)
+		}
+	}
+}
+
+func TestPlansPruneCmd_RequiresOlderThan(t *testing.T) {
+	cmd := plansPruneCmd()
+	cmd.SetArgs([]string{})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "older-than") {
+		t.Errorf("expected missing --older-than error, got: %v", err)
+	}
+}
+
+func TestArchivePlans_RefusesRunning(t *testing.T) {
+	database := newPlansTestDB(t)
+	if err := database.CreatePlan(&db.Plan{ID: "running", Content: "c", Status: db.PlanStatusRunning}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := archivePlans(&bytes.Buffer{}, database, filepath.Join(t.TempDir(), "archive.db"), []string{"running"}, true)
+	if err == nil || !strings.Contains(err.Error(), "running") {
+		t.Errorf("expected running plan error, got: %v", err)
+	}
+}
+
+func TestArchivePlans_RefusesUncompleted(t *testing.T) {
+	database := newPlansTestDB(t)
+	if err := database.CreatePlan(&db.Plan{ID: "paused", Content: "c", Status: db.PlanStatusPaused}); err != nil {
+		t.Fatal(err)
+	}
+	archivePath := filepath.Join(t.TempDir(), "archive.db")
+
+	err := archivePlans(&bytes.Buffer{}, database, archivePath, []string{"paused"}, false)
+	if err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("expected not completed error, got: %v", err)
+	}
+	if _, err := database.GetPlan("paused"); err != nil {
+		t.Errorf("expected plan to stay in the live database, got err: %v", err)
+	}
+
+	if err := archivePlans(&bytes.Buffer{}, database, archivePath, []string{"paused"}, true); err != nil {
+		t.Fatalf("archivePlans(force) error: %v", err)
+	}
+	if _, err := database.GetPlan("paused"); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected plan to be archived, got err: %v", err)
+	}
+}
+
+func TestArchivePlans_NotFound(t *testing.T) {
+	database := newPlansTestDB(t)
+
+	err := archivePlans(&bytes.Buffer{}, database, filepath.Join(t.TempDir(), "archive.db"), []string{"missing"}, false)
+	if err == nil || !strings.Contains(err.Error(), "plan not found") {
+		t.Errorf("expected not found error, got: %v", err)
+	}
+}
+
+func TestPrunePlans(t *testing.T) {
+	database := newPlansTestDB(t)
+	if err := database.CreatePlan(&db.Plan{ID: "done", OriginPath: "plan.md", Content: "c", Status: db.PlanStatusCompleted}); err != nil {
+		t.Fatal(err)
+	}
+	archivePath := filepath.Join(t.TempDir(), "archive.db")
+
+	// Dry run lists but keeps the plan
+	var out bytes.Buffer
+	if err := prunePlans(&out, database, archivePath, time.Now().Add(time.Hour), true); err != nil {
+		t.Fatalf("prunePlans() dry run error: %v", err)
+	}
+	if !strings.Contains(out.String(), "would be archived") {
+		t.Errorf("expected dry run output, got: %s", out.String())
+	}
+	if _, err := database.GetPlan("done"); err != nil {
+		t.Errorf("dry run should keep plan, got err: %v", err)
+	}
+
+	out.Reset()
+	if err := prunePlans(&out, database, archivePath, time.Now().Add(time.Hour), false); err != nil {
+		t.Fatalf("prunePlans() error: %v", err)
+	}
+	if _, err := database.GetPlan("done"); err != db.ErrNotFound {
+		t.Errorf("expected plan to be archived, got err: %v", err)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"", 0, true},
+		{"xd", 0, true},
+		{"0d", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseAge(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAge(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseAge(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestCancelPlan(t *testing.T) {
+	database := newPlansTestDB(t)
+	for _, p := range []*db.Plan{
+		{ID: "paused", Content: "c", Status: db.PlanStatusPaused},
+		{ID: "running", Content: "c", Status: db.PlanStatusRunning},
+		{ID: "done", Content: "c", Status: db.PlanStatusCompleted},
+	} {
+		if err := database.CreatePlan(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := cancelPlan(&out, database, "paused", "superseded"); err != nil {
+		t.Fatalf("cancelPlan() error: %v", err)
+	}
+	plan, err := database.GetPlan("paused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Status != db.PlanStatusCancelled || plan.FailureReason != "superseded" {
+		t.Errorf("got status %q reason %q, want cancelled/superseded", plan.Status, plan.FailureReason)
+	}
+
+	// A plan left running by a dead process is abandoned
+	if err := cancelPlan(&out, database, "running", ""); err != nil {
+		t.Fatalf("cancelPlan() error: %v", err)
+	}
+	plan, err = database.GetPlan("running")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Status != db.PlanStatusAbandoned || plan.FailureReason == "" {
+		t.Errorf("got status %q reason %q, want abandoned with a default reason", plan.Status, plan.FailureReason)
+	}
+
+	if err := cancelPlan(&out, database, "done", ""); err == nil || !strings.Contains(err.Error(), "cannot be cancelled") {
+		t.Errorf("expected completed plan to be rejected, got: %v", err)
+	}
+	if err := cancelPlan(&out, database, "missing", ""); err == nil || !strings.Contains(err.Error(), "plan not found") {
+		t.Errorf("expected not found error, got: %v", err)
+	}
+}
+
+func TestDeletePlans(t *testing.T) {
+	database := newPlansTestDB(t)
+	for _, p := range []*db.Plan{
+		{ID: "done", Content: "c", Status: db.PlanStatusCompleted},
+		{ID: "running", Content: "c", Status: db.PlanStatusRunning},
+	} {
+		if err := database.CreatePlan(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := deletePlans(&out, database, []string{"running"}); err == nil || !strings.Contains(err.Error(), "cannot be deleted") {
+		t.Errorf("expected running plan error, got: %v", err)
+	}
+	if err := deletePlans(&out, database, []string{"missing"}); err == nil || !strings.Contains(err.Error(), "plan not found") {
+		t.Errorf("expected not found error, got: %v", err)
+	}
+
+	if err := deletePlans(&out, database, []string{"done"}); err != nil {
+		t.Fatalf("deletePlans() error: %v", err)
+	}
+	if _, err := database.GetPlan("done"); err != db.ErrNotFound {
+		t.Errorf("expected the plan to be deleted, got: %v", err)
+	}
+	if !strings.Contains(out.String(), "Deleted 1 plan(s)") {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
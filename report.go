@@ -0,0 +1,451 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/spf13/cobra"
+)
+
+// Report output formats.
+const (
+	reportFormatTable    = "table"
+	reportFormatMarkdown = "markdown"
+)
+
+func reportCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "report <plan-id>",
+		Short: "Summarize a plan's iterations, time, cost, and diff churn",
+		Long: `Aggregate a plan's stored sessions into a report: iterations, wall-clock
+time per stage, Claude cost and tokens, and lines added/removed per iteration.
+
+Diff churn is read from jj in the plan's repository (the current directory
+for plans that didn't record one); if jj can't resolve it, churn is left
+blank. Use --format markdown to paste the report into a pull request.
+
+Examples:
+  ralph report 3f2a9c1e
+  ralph report 3f2a9c1e --format markdown`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != reportFormatTable && format != reportFormatMarkdown {
+				return fmt.Errorf("--format must be %q or %q", reportFormatTable, reportFormatMarkdown)
+			}
+
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			workDir, err := planWorkDir(database, args[0])
+			if err != nil {
+				return err
+			}
+
+			return runReport(cmd.Context(), cmd.OutOrStdout(), database, jj.NewClient(workDir), args[0], format)
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", reportFormatTable, "Output format: table or markdown")
+
+	return cmd
+}
+
+// usageStats is the time, cost, and token usage of one or more sessions.
+type usageStats struct {
+	Sessions     int
+	Duration     time.Duration
+	CostUSD      float64
+	InputTokens  int // Including cache reads and writes
+	OutputTokens int
+}
+
+// add accumulates other into s.
+func (s *usageStats) add(other usageStats) {
+	s.Sessions += other.Sessions
+	s.Duration += other.Duration
+	s.CostUSD += other.CostUSD
+	s.InputTokens += other.InputTokens
+	s.OutputTokens += other.OutputTokens
+}
+
+// iterationStats summarizes one loop iteration.
+type iterationStats struct {
+	Iteration int
+	Developer time.Duration
+	Reviewer  time.Duration
+	Usage     usageStats
+	HasChurn  bool // Whether Added/Removed could be read from jj
+	Added     int
+	Removed   int
+}
+
+// stageStats summarizes every session of one agent type.
+type stageStats struct {
+	Stage db.LoopAgentType
+	Usage usageStats
+}
+
+// environmentStats lists the iterations one agent type ran in the same
+// environment.
+type environmentStats struct {
+	Stage         db.LoopAgentType
+	Iterations    []int
+	ClaudeVersion string
+	Model         string
+	GoVersion     string
+}
+
+// planReport aggregates a plan's stored sessions.
+type planReport struct {
+	Plan         *db.Plan
+	Stages       []*stageStats
+	Iterations   []*iterationStats
+	Environments []*environmentStats
+	Total        usageStats
+}
+
+// runReport builds and prints the report for a plan.
+func runReport(ctx context.Context, out io.Writer, database *db.DB, jjClient *jj.Client, planID, format string) error {
+	report, err := buildReport(ctx, database, jjClient, planID)
+	if err != nil {
+		return err
+	}
+
+	if format == reportFormatMarkdown {
+		writeReportMarkdown(out, report)
+		return nil
+	}
+	return writeReportTable(out, report)
+}
+
+// buildReport aggregates the sessions of a plan. Churn is measured between the
+// working-copy commits recorded after each developer session; it is skipped
+// when jjClient is nil or jj cannot resolve the commits.
+func buildReport(ctx context.Context, database *db.DB, jjClient *jj.Client, planID string) (*planReport, error) {
+	plan, err := database.GetPlan(planID)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, fmt.Errorf("plan not found: %s", planID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plan: %w", err)
+	}
+
+	sessions, err := database.GetPlanSessionsByPlan(planID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	envs, err := database.GetSessionEnvironmentsByPlan(planID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session environments: %w", err)
+	}
+	envBySession := make(map[string]*db.SessionEnvironment, len(envs))
+	for _, env := range envs {
+		envBySession[env.SessionID] = env
+	}
+
+	report := &planReport{Plan: plan}
+	stages := make(map[db.LoopAgentType]*stageStats)
+	iterations := make(map[int]*iterationStats)
+	prevCommit := plan.BaseChangeID
+
+	for _, session := range sessions {
+		usage, err := sessionUsage(database, session)
+		if err != nil {
+			return nil, err
+		}
+		report.Total.add(usage)
+
+		stage := stages[session.AgentType]
+		if stage == nil {
+			stage = &stageStats{Stage: session.AgentType}
+			stages[session.AgentType] = stage
+		}
+		stage.Usage.add(usage)
+
+		if env := envBySession[session.ID]; env != nil {
+			report.addEnvironment(session, env)
+		}
+
+		// Planner sessions run before the first iteration
+		if session.AgentType == db.LoopAgentPlanner {
+			continue
+		}
+
+		iter := iterations[session.Iteration]
+		if iter == nil {
+			iter = &iterationStats{Iteration: session.Iteration}
+			iterations[session.Iteration] = iter
+			report.Iterations = append(report.Iterations, iter)
+		}
+		iter.Usage.add(usage)
+
+		switch session.AgentType {
+		case db.LoopAgentReviewer, db.LoopAgentRebuttalReviewer:
+			iter.Reviewer += usage.Duration
+		default:
+			iter.Developer += usage.Duration
+			if session.CommitID == "" {
+				continue
+			}
+			if jjClient != nil && prevCommit != "" {
+				added, removed, err := jjClient.DiffStat(ctx, prevCommit, session.CommitID)
+				if err != nil {
+					log.Debug("failed to measure iteration churn", "iteration", session.Iteration, "error", err)
+				} else {
+					iter.HasChurn = true
+					iter.Added += added
+					iter.Removed += removed
+				}
+			}
+			prevCommit = session.CommitID
+		}
+	}
+
+	for _, agentType := range []db.LoopAgentType{db.LoopAgentPlanner, db.LoopAgentConflictResolver, db.LoopAgentDeveloper, db.LoopAgentReviewer, db.LoopAgentRebuttalReviewer, db.LoopAgentReformatter, db.LoopAgentFailureTriager, db.LoopAgentDiffSummarizer} {
+		if stage := stages[agentType]; stage != nil {
+			report.Stages = append(report.Stages, stage)
+		}
+	}
+
+	return report, nil
+}
+
+// addEnvironment adds a session's environment to the report, grouping the
+// sessions of an agent type that ran in the same environment.
+func (r *planReport) addEnvironment(session *db.PlanSession, env *db.SessionEnvironment) {
+	var group *environmentStats
+	for _, g := range r.Environments {
+		if g.Stage == session.AgentType && g.ClaudeVersion == env.ClaudeVersion &&
+			g.Model == env.Model && g.GoVersion == env.GoVersion {
+			group = g
+			break
+		}
+	}
+	if group == nil {
+		group = &environmentStats{
+			Stage:         session.AgentType,
+			ClaudeVersion: env.ClaudeVersion,
+			Model:         env.Model,
+			GoVersion:     env.GoVersion,
+		}
+		r.Environments = append(r.Environments, group)
+	}
+	if n := len(group.Iterations); n == 0 || group.Iterations[n-1] != session.Iteration {
+		group.Iterations = append(group.Iterations, session.Iteration)
+	}
+}
+
+// sessionUsage returns a session's wall-clock time and the cost and tokens
+// from the result events of each of its attempts.
+func sessionUsage(database *db.DB, session *db.PlanSession) (usageStats, error) {
+	usage := usageStats{Sessions: 1}
+	if session.CompletedAt != nil {
+		usage.Duration = session.CompletedAt.Sub(session.CreatedAt)
+	}
+
+	events, err := database.GetEventsBySession(session.ID)
+	if err != nil {
+		return usage, fmt.Errorf("failed to get events for session %s: %w", session.ID, err)
+	}
+	for _, e := range events {
+		if e.EventType != string(claude.EventResult) {
+			continue
+		}
+		event, err := claude.NewParser(strings.NewReader(e.RawJSON)).Next()
+		if err != nil || event.Result == nil {
+			continue
+		}
+		tokens := event.Result.TotalUsage
+		usage.CostUSD += event.Result.CostUSD
+		usage.InputTokens += tokens.InputTokens + tokens.CacheRead + tokens.CacheCreate
+		usage.OutputTokens += tokens.OutputTokens
+	}
+	return usage, nil
+}
+
+// totalChurn sums the churn of iterations where it is known.
+func (r *planReport) totalChurn() (added, removed int, known bool) {
+	for _, iter := range r.Iterations {
+		if iter.HasChurn {
+			added += iter.Added
+			removed += iter.Removed
+			known = true
+		}
+	}
+	return added, removed, known
+}
+
+// summaryLines returns the report's headline figures.
+func (r *planReport) summaryLines() []string {
+	churn := "n/a"
+	if added, removed, ok := r.totalChurn(); ok {
+		churn = fmt.Sprintf("+%d -%d", added, removed)
+	}
+	return []string{
+		fmt.Sprintf("Iterations: %d", len(r.Iterations)),
+		fmt.Sprintf("Wall-clock time: %s", formatReportDuration(r.Total.Duration)),
+		fmt.Sprintf("Claude cost: %s", formatReportCost(r.Total.CostUSD)),
+		fmt.Sprintf("Tokens: %d in / %d out", r.Total.InputTokens, r.Total.OutputTokens),
+		fmt.Sprintf("Diff churn: %s", churn),
+	}
+}
+
+// stageRows returns the per-stage table, header first.
+func (r *planReport) stageRows() [][]string {
+	rows := [][]string{{"Stage", "Sessions", "Time", "Cost", "Tokens in", "Tokens out"}}
+	for _, stage := range r.Stages {
+		rows = append(rows, []string{
+			string(stage.Stage),
+			strconv.Itoa(stage.Usage.Sessions),
+			formatReportDuration(stage.Usage.Duration),
+			formatReportCost(stage.Usage.CostUSD),
+			strconv.Itoa(stage.Usage.InputTokens),
+			strconv.Itoa(stage.Usage.OutputTokens),
+		})
+	}
+	return rows
+}
+
+// iterationRows returns the per-iteration table, header first.
+func (r *planReport) iterationRows() [][]string {
+	rows := [][]string{{"Iteration", "Developer", "Reviewer", "Cost", "Tokens in", "Tokens out", "Added", "Removed"}}
+	for _, iter := range r.Iterations {
+		added, removed := "-", "-"
+		if iter.HasChurn {
+			added, removed = fmt.Sprintf("+%d", iter.Added), fmt.Sprintf("-%d", iter.Removed)
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(iter.Iteration),
+			formatReportDuration(iter.Developer),
+			formatReportDuration(iter.Reviewer),
+			formatReportCost(iter.Usage.CostUSD),
+			strconv.Itoa(iter.Usage.InputTokens),
+			strconv.Itoa(iter.Usage.OutputTokens),
+			added,
+			removed,
+		})
+	}
+	return rows
+}
+
+// environmentRows returns the environment table, header first. Each row is
+// an agent type's environment and the iterations it ran in, so changes of
+// the claude CLI, model, or Go version stand out.
+func (r *planReport) environmentRows() [][]string {
+	rows := [][]string{{"Stage", "Iterations", "Claude CLI", "Model", "Go"}}
+	for _, env := range r.Environments {
+		iterations := "-"
+		if env.Stage != db.LoopAgentPlanner {
+			iterations = formatIterationRanges(env.Iterations)
+		}
+		rows = append(rows, []string{
+			string(env.Stage),
+			iterations,
+			orDash(env.ClaudeVersion),
+			orDash(env.Model),
+			orDash(env.GoVersion),
+		})
+	}
+	return rows
+}
+
+// writeReportTable prints the report as aligned plain-text tables.
+func writeReportTable(out io.Writer, r *planReport) error {
+	fmt.Fprintf(out, "Plan %s (%s)\n", r.Plan.ID, r.Plan.Status)
+	for _, line := range r.summaryLines() {
+		fmt.Fprintf(out, "  %s\n", line)
+	}
+
+	for _, rows := range [][][]string{r.stageRows(), r.iterationRows(), r.environmentRows()} {
+		if len(rows) == 1 {
+			continue
+		}
+		fmt.Fprintln(out)
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, row := range rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeReportMarkdown prints the report as markdown for pull requests.
+func writeReportMarkdown(out io.Writer, r *planReport) {
+	fmt.Fprintf(out, "### Ralph report: plan `%s` (%s)\n\n", r.Plan.ID, r.Plan.Status)
+	for _, line := range r.summaryLines() {
+		fmt.Fprintf(out, "- %s\n", line)
+	}
+
+	for _, rows := range [][][]string{r.stageRows(), r.iterationRows(), r.environmentRows()} {
+		if len(rows) == 1 {
+			continue
+		}
+		fmt.Fprintln(out)
+		for i, row := range rows {
+			fmt.Fprintf(out, "| %s |\n", strings.Join(row, " | "))
+			if i == 0 {
+				fmt.Fprintf(out, "|%s\n", strings.Repeat("---|", len(row)))
+			}
+		}
+	}
+}
+
+// formatReportDuration formats a duration rounded to the second.
+func formatReportDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
+
+// formatReportCost formats a cost in US dollars.
+func formatReportCost(usd float64) string {
+	return fmt.Sprintf("$%.2f", usd)
+}
+
+// formatIterationRanges formats sorted iteration numbers as ranges, e.g.
+// "1-3, 5".
+func formatIterationRanges(iterations []int) string {
+	var parts []string
+	for i := 0; i < len(iterations); {
+		j := i
+		for j+1 < len(iterations) && iterations[j+1] == iterations[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(iterations[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", iterations[i], iterations[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+// orDash returns s, or "-" if it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
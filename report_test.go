@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+// createReportSession stores a completed session with a result event.
+func createReportSession(t *testing.T, database *db.DB, session *db.PlanSession, resultJSON string) {
+	t.Helper()
+	if err := database.CreatePlanSession(session); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreateEvent(&db.Event{SessionID: session.ID, EventType: "result", RawJSON: resultJSON}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CompletePlanSession(session.ID, db.PlanSessionCompleted, "out"); err != nil {
+		t.Fatal(err)
+	}
+	if session.CommitID != "" {
+		if err := database.UpdatePlanSessionCommitID(session.ID, session.CommitID); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRunReport(t *testing.T) {
+	database := newPlansTestDB(t)
+	if err := database.CreatePlan(&db.Plan{ID: "plan-1", OriginPath: "plan.md", Content: "c", BaseChangeID: "base"}); err != nil {
+		t.Fatal(err)
+	}
+	result := `{"type":"result","total_cost_usd":0.5,"usage":{"input_tokens":100,"output_tokens":20,"cache_read_input_tokens":900}}`
+	createReportSession(t, database, &db.PlanSession{ID: "d1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p", CommitID: "c1"}, result)
+	createReportSession(t, database, &db.PlanSession{ID: "r1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p", AgentType: db.LoopAgentReviewer}, result)
+	createReportSession(t, database, &db.PlanSession{ID: "d2", PlanID: "plan-1", Iteration: 2, InputPrompt: "p", CommitID: "c2"}, result)
+
+	var diffs []string
+	jjClient := jj.NewClient("/repo")
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		diffs = append(diffs, args[3]+".."+args[5])
+		if args[3] == "base" {
+			return "+a\n+b\n-c\n", "", nil
+		}
+		return "+d\n", "", nil
+	})
+
+	var out bytes.Buffer
+	if err := runReport(context.Background(), &out, database, jjClient, "plan-1", reportFormatTable); err != nil {
+		t.Fatalf("runReport() error: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"Iterations: 2",
+		"Claude cost: $1.50",
+		"Tokens: 3000 in / 60 out",
+		"Diff churn: +3 -1",
+		"developer  2",
+		"reviewer   1",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("table output missing %q:\n%s", want, got)
+		}
+	}
+	if len(diffs) != 2 || diffs[0] != "base..c1" || diffs[1] != "c1..c2" {
+		t.Errorf("churn diffs = %v, want [base..c1 c1..c2]", diffs)
+	}
+
+	out.Reset()
+	if err := runReport(context.Background(), &out, database, nil, "plan-1", reportFormatMarkdown); err != nil {
+		t.Fatalf("runReport() markdown error: %v", err)
+	}
+	got = out.String()
+	for _, want := range []string{
+		"### Ralph report: plan `plan-1`",
+		"- Diff churn: n/a",
+		"| Iteration | Developer | Reviewer |",
+		"|---|---|---|",
+		"| 2 | ",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("markdown output missing %q:\n%s", want, got)
+		}
+	}
+
+	if err := runReport(context.Background(), &out, database, nil, "missing", reportFormatTable); err == nil || !strings.Contains(err.Error(), "plan not found") {
+		t.Errorf("runReport() for unknown plan error = %v, want plan not found", err)
+	}
+}
+
+func TestRunReport_Environments(t *testing.T) {
+	database := newPlansTestDB(t)
+	if err := database.CreatePlan(&db.Plan{ID: "plan-1", OriginPath: "plan.md", Content: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	result := `{"type":"result","total_cost_usd":0.5}`
+	for _, s := range []struct {
+		id        string
+		iteration int
+		claude    string
+	}{{"d1", 1, "2.0.1"}, {"d2", 2, "2.0.1"}, {"d3", 3, "2.1.0"}, {"d4", 4, "2.0.1"}} {
+		createReportSession(t, database, &db.PlanSession{ID: s.id, PlanID: "plan-1", Iteration: s.iteration, InputPrompt: "p"}, result)
+		if err := database.UpdatePlanSessionModel(s.id, "claude-opus-4"); err != nil {
+			t.Fatal(err)
+		}
+		env := &db.SessionEnvironment{SessionID: s.id, PlanID: "plan-1", ClaudeVersion: s.claude, GoVersion: "1.22"}
+		if err := database.CreateSessionEnvironment(env); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := runReport(context.Background(), &out, database, nil, "plan-1", reportFormatMarkdown); err != nil {
+		t.Fatalf("runReport() error: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"| Stage | Iterations | Claude CLI | Model | Go |",
+		"| developer | 1-2, 4 | 2.0.1 | claude-opus-4 | 1.22 |",
+		"| developer | 3 | 2.1.0 | claude-opus-4 | 1.22 |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("markdown output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestFormatIterationRanges(t *testing.T) {
+	tests := []struct {
+		iterations []int
+		want       string
+	}{
+		{nil, ""},
+		{[]int{3}, "3"},
+		{[]int{1, 2, 3}, "1-3"},
+		{[]int{1, 2, 4, 6, 7}, "1-2, 4, 6-7"},
+	}
+	for _, tt := range tests {
+		if got := formatIterationRanges(tt.iterations); got != tt.want {
+			t.Errorf("formatIterationRanges(%v) = %q, want %q", tt.iterations, got, tt.want)
+		}
+	}
+}
+
+func TestFormatReportDuration(t *testing.T) {
+	if got := formatReportDuration(90*time.Second + 400*time.Millisecond); got != "1m30s" {
+		t.Errorf("formatReportDuration() = %q, want %q", got, "1m30s")
+	}
+}
+
+func TestReportCmd_RejectsUnknownFormat(t *testing.T) {
+	cmd := reportCmd()
+	cmd.SetArgs([]string{"plan-1", "--format", "html"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "--format") {
+		t.Errorf("expected --format error, got: %v", err)
+	}
+}
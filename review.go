@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/conventions"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/loop"
+	"github.com/gerunddev/ralph/internal/parser"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// Review output formats.
+const (
+	reviewFormatText = "text"
+	reviewFormatJSON = "json"
+)
+
+// reviewRunner runs a reviewer session in workDir, calling onEvent with
+// each event as it streams, and returns its output. It can be replaced in
+// tests.
+var reviewRunner = defaultReviewRunner
+
+// defaultReviewRunner runs prompt in a Claude session configured like the
+// loop's reviewer, under the throttle in database, and returns the text of
+// its messages.
+func defaultReviewRunner(ctx context.Context, cfg *config.Config, database *db.DB, workDir, prompt string, onEvent func(claude.StreamEvent)) (string, error) {
+	return runClaudeSession(ctx, cfg, database, cfg.Claude.Reviewer, workDir, prompt, onEvent)
+}
+
+func reviewCmd() *cobra.Command {
+	var rev, format string
+
+	cmd := &cobra.Command{
+		Use:   "review",
+		Short: "Run the reviewer on a jj revision made outside ralph",
+		Long: `Run one reviewer session on the changes of a jj revision, or a range of
+revisions (FROM..TO), with no developer loop. The revisions' descriptions
+stand in for the plan. The verdict and the issues raised, by severity, are
+printed and stored as a plan of their own, so the review can be found later
+with ralph transcript or ralph search.
+
+Examples:
+  ralph review                       # The working-copy change
+  ralph review --rev @-              # Its parent
+  ralph review --rev main..@         # Everything since main
+  ralph review --rev @- --format json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != reviewFormatText && format != reviewFormatJSON {
+				return fmt.Errorf("--format must be %q or %q", reviewFormatText, reviewFormatJSON)
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			workDir, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("failed to get working directory: %w", err)
+			}
+
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			return runReview(cmd.Context(), cmd.OutOrStdout(), cfg, database, jj.NewClient(workDir), workDir, rev, format)
+		},
+	}
+
+	cmd.Flags().StringVarP(&rev, "rev", "r", "@", "Revision, or FROM..TO range, to review")
+	cmd.Flags().StringVarP(&format, "format", "f", reviewFormatText, "Output format: text or json")
+
+	return cmd
+}
+
+// reviewResult is a standalone review's structured outcome.
+type reviewResult struct {
+	PlanID    string        `json:"plan_id"`
+	SessionID string        `json:"session_id"`
+	Revision  string        `json:"revision"`
+	Approved  bool          `json:"approved"`
+	Issues    []reviewIssue `json:"issues,omitempty"`
+}
+
+// reviewIssue is one issue the reviewer raised.
+type reviewIssue struct {
+	Severity string `json:"severity,omitempty"`
+	Text     string `json:"text"`
+}
+
+// reviewRange splits a FROM..TO range into the revisions to diff between;
+// a single revision is diffed against its parent.
+func reviewRange(rev string) (from, to string) {
+	if from, to, ok := strings.Cut(rev, ".."); ok {
+		if to == "" {
+			to = "@"
+		}
+		return from, to
+	}
+	return "", rev
+}
+
+// reviewPlanContent stands in for the plan of a revision made outside
+// ralph: what was asked is whatever its description says.
+func reviewPlanContent(rev, description string) string {
+	if description = strings.TrimSpace(description); description == "" {
+		description = "(no description)"
+	}
+	return fmt.Sprintf("# Review of %s\n\nThese changes were made outside ralph. Review them as finished work, "+
+		"against what their description says they do.\n\n## Description\n\n%s\n", rev, description)
+}
+
+// runReview reviews rev with one reviewer session, stores the review as a
+// plan with its session and any feedback, and prints the result.
+func runReview(ctx context.Context, out io.Writer, cfg *config.Config, database *db.DB, jjClient *jj.Client, workDir, rev, format string) error {
+	from, to := reviewRange(rev)
+	diff, err := jjClient.GitDiff(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to diff %s: %w", rev, err)
+	}
+	if strings.TrimSpace(diff) == "" {
+		return fmt.Errorf("%s has no changes to review", rev)
+	}
+	description, err := jjClient.GetDescription(ctx, rev)
+	if err != nil {
+		return fmt.Errorf("failed to get the description of %s: %w", rev, err)
+	}
+
+	customPrompt, err := cfg.GetAgentPrompt("reviewer")
+	if err != nil {
+		return fmt.Errorf("failed to build reviewer prompt: %w", err)
+	}
+	reviewCtx := agent.ReviewerContext{
+		PlanContent:     reviewPlanContent(rev, description),
+		DiffOutput:      diff,
+		DevSignaledDone: true,
+		Conventions:     repoConventions(ctx, cfg, jjClient, workDir),
+		Locale:          cfg.Locale,
+		Template:        customPrompt,
+	}
+	prompt, omitted, err := agent.FitPrompt(loop.PromptBudget(cfg.Claude.Model), agent.PrunableSections{Diff: &reviewCtx.DiffOutput},
+		func() (string, error) { return agent.BuildReviewerPrompt(reviewCtx) })
+	if err != nil {
+		return fmt.Errorf("failed to build reviewer prompt: %w", err)
+	}
+	for _, o := range omitted {
+		log.Warn("diff exceeds the context budget, truncated it", "lines", o.Lines, "tokens", o.Tokens)
+	}
+
+	plan := &db.Plan{
+		ID:           uuid.New().String(),
+		Content:      reviewCtx.PlanContent,
+		Status:       db.PlanStatusRunning,
+		BaseChangeID: from,
+		WorkDir:      workDir,
+	}
+	if err := database.CreatePlan(plan); err != nil {
+		return fmt.Errorf("failed to store review: %w", err)
+	}
+	session := &db.PlanSession{
+		ID:          uuid.New().String(),
+		PlanID:      plan.ID,
+		Iteration:   1,
+		InputPrompt: prompt,
+		Status:      db.PlanSessionRunning,
+		AgentType:   db.LoopAgentReviewer,
+	}
+	if err := database.CreatePlanSession(session); err != nil {
+		return fmt.Errorf("failed to store review session: %w", err)
+	}
+
+	records := db.NewBatchWriter(database, db.DefaultBatchInterval)
+	output, err := reviewRunner(ctx, cfg, database, workDir, prompt, func(event claude.StreamEvent) {
+		loop.RecordStreamEvent(records, session.ID, event)
+	})
+	if closeErr := records.Close(); closeErr != nil {
+		log.Warn("failed to store events", "error", closeErr)
+	}
+	if err != nil {
+		if completeErr := database.CompletePlanSession(session.ID, db.PlanSessionFailed, ""); completeErr != nil {
+			log.Warn("failed to mark review session as failed", "error", completeErr)
+		}
+		if statusErr := database.UpdatePlanStatusWithReason(plan.ID, db.PlanStatusFailed, err.Error()); statusErr != nil {
+			log.Warn("failed to mark review as failed", "error", statusErr)
+		}
+		return fmt.Errorf("reviewer session failed: %w", err)
+	}
+
+	review := parser.ParseAgentOutput(output, "reviewer")
+	if err := storeReview(database, plan.ID, session.ID, output, review); err != nil {
+		return err
+	}
+
+	redactor, err := cfg.Redaction.Redactor()
+	if err != nil {
+		return err
+	}
+	result := &reviewResult{
+		PlanID:    plan.ID,
+		SessionID: session.ID,
+		Revision:  rev,
+		Approved:  review.ReviewerApproved,
+	}
+	if review.ReviewerFeedback != "" {
+		for _, item := range parser.MergeFeedback([]string{review.ReviewerFeedback}) {
+			result.Issues = append(result.Issues, reviewIssue{Severity: item.Severity, Text: redactor.String(item.Text)})
+		}
+	}
+
+	if format == reviewFormatJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+	writeReviewText(out, result)
+	return nil
+}
+
+// storeReview completes a review's session and plan, storing the reviewer's
+// feedback when it asked for changes. An approved review's plan is
+// completed; one that asked for changes is stopped.
+func storeReview(database *db.DB, planID, sessionID, output string, review *parser.AgentParseResult) error {
+	if err := database.CompletePlanSession(sessionID, db.PlanSessionCompleted, output); err != nil {
+		return fmt.Errorf("failed to store review session: %w", err)
+	}
+	if review.ReviewerApproved {
+		return database.UpdatePlanStatus(planID, db.PlanStatusCompleted)
+	}
+	if review.ReviewerFeedback != "" {
+		if err := database.CreateReviewerFeedback(&db.ReviewerFeedback{
+			PlanID:    planID,
+			SessionID: sessionID,
+			Content:   review.ReviewerFeedback,
+		}); err != nil {
+			return fmt.Errorf("failed to store review feedback: %w", err)
+		}
+	}
+	return database.UpdatePlanStatusWithReason(planID, db.PlanStatusStopped, "reviewer requested changes")
+}
+
+// repoConventions returns the repository's convention files formatted for
+// a prompt, or "" when none are configured or they can't be read.
+func repoConventions(ctx context.Context, cfg *config.Config, jjClient *jj.Client, workDir string) string {
+	if len(cfg.Conventions.Include) == 0 {
+		return ""
+	}
+	root, err := jjClient.Root(ctx)
+	if err != nil || root == "" {
+		root = workDir
+	}
+	files, err := conventions.NewProvider(cfg.Conventions.Include, cfg.Conventions.Exclude, cfg.Conventions.MaxBytes).Load(root)
+	if err != nil {
+		log.Warn("failed to load convention files", "error", err)
+		return ""
+	}
+	return conventions.Format(files)
+}
+
+// writeReviewText prints a review's verdict and issues by severity.
+func writeReviewText(out io.Writer, r *reviewResult) {
+	verdict := "Changes requested"
+	if r.Approved {
+		verdict = "Approved"
+	}
+	fmt.Fprintf(out, "Review of %s: %s\n", r.Revision, verdict)
+
+	for _, severity := range []string{"Critical", "Major", "Minor", ""} {
+		var issues []string
+		for _, issue := range r.Issues {
+			if issue.Severity == severity {
+				issues = append(issues, issue.Text)
+			}
+		}
+		if len(issues) == 0 {
+			continue
+		}
+		heading := severity + " issues"
+		if severity == "" {
+			heading = "Other issues"
+		}
+		fmt.Fprintf(out, "\n%s:\n", heading)
+		for _, issue := range issues {
+			fmt.Fprintf(out, "  - %s\n", strings.ReplaceAll(issue, "\n", "\n    "))
+		}
+	}
+
+	fmt.Fprintf(out, "\nStored as plan %s (ralph transcript %s)\n", r.PlanID, r.SessionID)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+// newReviewJJ returns a jj client that reports diff for any revision and
+// records the commands it was asked to run.
+func newReviewJJ(diff string, calls *[][]string) *jj.Client {
+	jjClient := jj.NewClient("/repo")
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		*calls = append(*calls, args)
+		if args[0] == "log" {
+			return "Add retries to the fetcher\n", "", nil
+		}
+		return diff, "", nil
+	})
+	return jjClient
+}
+
+func TestReviewRange(t *testing.T) {
+	tests := []struct {
+		rev, from, to string
+	}{
+		{"@", "", "@"},
+		{"abc", "", "abc"},
+		{"main..@", "main", "@"},
+		{"main..", "main", "@"},
+	}
+	for _, tt := range tests {
+		if from, to := reviewRange(tt.rev); from != tt.from || to != tt.to {
+			t.Errorf("reviewRange(%q) = %q, %q; want %q, %q", tt.rev, from, to, tt.from, tt.to)
+		}
+	}
+}
+
+func TestRunReview(t *testing.T) {
+	originalRunner := reviewRunner
+	t.Cleanup(func() { reviewRunner = originalRunner })
+
+	tests := []struct {
+		name       string
+		output     string
+		wantStatus db.PlanStatus
+		wantText   []string
+	}{
+		{
+			"approved",
+			"## Progress\nReviewed.\n\n### Verdict\nREVIEWER_APPROVED REVIEWER_APPROVED!!!",
+			db.PlanStatusCompleted,
+			[]string{"Review of main..@: Approved\n"},
+		},
+		{
+			"changes requested",
+			"## Progress\nReviewed.\n\n### Critical Issues\n- Retries never stop\n\n### Minor Issues\n- Typo in a comment\n\n### Verdict\nChanges needed.",
+			db.PlanStatusStopped,
+			[]string{"Review of main..@: Changes requested\n", "\nCritical issues:\n  - Retries never stop\n", "\nMinor issues:\n  - Typo in a comment\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := newPlansTestDB(t)
+			var calls [][]string
+			jjClient := newReviewJJ("diff --git a/fetch.go b/fetch.go\n+retry()\n", &calls)
+
+			var prompt string
+			reviewRunner = func(ctx context.Context, cfg *config.Config, database *db.DB, workDir, p string, onEvent func(claude.StreamEvent)) (string, error) {
+				prompt = p
+				onEvent(claude.StreamEvent{
+					Type:    claude.EventMessage,
+					Raw:     []byte(`{"type":"assistant"}`),
+					Message: &claude.MessageContent{Text: tt.output},
+				})
+				return tt.output, nil
+			}
+
+			var out bytes.Buffer
+			if err := runReview(context.Background(), &out, config.DefaultConfig(), database, jjClient, "/repo", "main..@", reviewFormatText); err != nil {
+				t.Fatalf("runReview() error: %v", err)
+			}
+			if !slices.Equal(calls[0], []string{"diff", "--git", "--from", "main", "--to", "@"}) {
+				t.Errorf("jj diff args = %v, want the range", calls[0])
+			}
+			if !strings.Contains(prompt, "+retry()") || !strings.Contains(prompt, "Add retries to the fetcher") {
+				t.Error("prompt is missing the diff or the description")
+			}
+			for _, want := range tt.wantText {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+
+			plans, err := database.ListPlans(10)
+			if err != nil || len(plans) != 1 {
+				t.Fatalf("ListPlans() = %d plans, %v; want the stored review", len(plans), err)
+			}
+			if plans[0].Status != tt.wantStatus {
+				t.Errorf("plan status = %s, want %s", plans[0].Status, tt.wantStatus)
+			}
+			feedback, err := database.GetLatestReviewerFeedback(plans[0].ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (feedback != nil) != (tt.wantStatus == db.PlanStatusStopped) {
+				t.Errorf("stored feedback = %+v, want it only when changes were requested", feedback)
+			}
+			sessions, err := database.GetPlanSessionsByPlan(plans[0].ID)
+			if err != nil || len(sessions) != 1 {
+				t.Fatalf("GetPlanSessionsByPlan() = %d sessions, %v; want the reviewer's", len(sessions), err)
+			}
+			events, err := database.GetEventsBySession(sessions[0].ID)
+			if err != nil || len(events) != 1 {
+				t.Errorf("GetEventsBySession() = %d events, %v; want the reviewer's message", len(events), err)
+			}
+		})
+	}
+}
+
+func TestRunReview_JSON(t *testing.T) {
+	originalRunner := reviewRunner
+	t.Cleanup(func() { reviewRunner = originalRunner })
+	reviewRunner = func(ctx context.Context, cfg *config.Config, database *db.DB, workDir, p string, onEvent func(claude.StreamEvent)) (string, error) {
+		return "## Progress\nReviewed.\n\n### Major Issues\n- No test\n\n### Minor Issues\n- Logs sk-ant-REDACTED\n\n### Verdict\nChanges needed.", nil
+	}
+
+	database := newPlansTestDB(t)
+	var calls [][]string
+	var out bytes.Buffer
+	if err := runReview(context.Background(), &out, config.DefaultConfig(), database, newReviewJJ("+x\n", &calls), "/repo", "@", reviewFormatJSON); err != nil {
+		t.Fatalf("runReview() error: %v", err)
+	}
+	var result reviewResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if result.Approved || len(result.Issues) != 2 || result.Issues[0] != (reviewIssue{Severity: "Major", Text: "No test"}) || result.PlanID == "" {
+		t.Errorf("result = %+v, want a major and a minor issue", result)
+	}
+	if strings.Contains(out.String(), "sk-ant-api03") {
+		t.Errorf("output is not redacted:\n%s", out.String())
+	}
+}
+
+func TestRunReview_Errors(t *testing.T) {
+	originalRunner := reviewRunner
+	t.Cleanup(func() { reviewRunner = originalRunner })
+	database := newPlansTestDB(t)
+	var calls [][]string
+
+	err := runReview(context.Background(), &bytes.Buffer{}, config.DefaultConfig(), database, newReviewJJ("", &calls), "/repo", "@", reviewFormatText)
+	if err == nil || !strings.Contains(err.Error(), "no changes to review") {
+		t.Errorf("runReview() on an empty change error = %v", err)
+	}
+
+	reviewRunner = func(ctx context.Context, cfg *config.Config, database *db.DB, workDir, p string, onEvent func(claude.StreamEvent)) (string, error) {
+		return "", errors.New("claude not found")
+	}
+	err = runReview(context.Background(), &bytes.Buffer{}, config.DefaultConfig(), database, newReviewJJ("+x\n", &calls), "/repo", "@", reviewFormatText)
+	if err == nil || !strings.Contains(err.Error(), "claude not found") {
+		t.Fatalf("runReview() with a failing session error = %v", err)
+	}
+	plans, err := database.ListPlans(10)
+	if err != nil || len(plans) != 1 || plans[0].Status != db.PlanStatusFailed {
+		t.Errorf("ListPlans() = %+v, %v; want the review marked failed", plans, err)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/gerunddev/ralph/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+// rpcBackend starts the plans rpc serves. It can be replaced in tests.
+var rpcBackend = rpc.NewAppBackend
+
+func rpcCmd() *cobra.Command {
+	var stdio bool
+
+	cmd := &cobra.Command{
+		Use:     "rpc --stdio",
+		Aliases: []string{"lsp-ish"},
+		Short:   "Serve editor integrations over JSON-RPC",
+		Long: `Speak JSON-RPC 2.0 on stdin and stdout so editor plugins can start plans,
+receive their events as they stream, and send the developer feedback. Messages
+are framed with Content-Length headers as in the Language Server Protocol; logs
+go to stderr.
+
+The client sends initialize with the protocol version it speaks first, then
+plan/start, plan/feedback, plan/pause, and plan/stop requests, and receives plan/event and
+plan/finished notifications. Running plans are interrupted when the client
+sends exit or closes stdin, leaving them paused.
+
+Examples:
+  ralph rpc --stdio
+  ralph lsp-ish --stdio`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !stdio {
+				return errors.New("--stdio is required (it is the only transport)")
+			}
+			server := rpc.NewServer(rpc.NewConn(cmd.InOrStdin(), cmd.OutOrStdout()), rpcBackend())
+			return server.Serve(cmd.Context())
+		},
+	}
+
+	cmd.Flags().BoolVar(&stdio, "stdio", false, "Serve on stdin and stdout")
+	return cmd
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/rpc"
+)
+
+func TestRPCCmd_RequiresStdio(t *testing.T) {
+	cmd := rpcCmd()
+	cmd.SetArgs(nil)
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "--stdio") {
+		t.Fatalf("expected an error about --stdio, got %v", err)
+	}
+}
+
+func TestRPCCmd_Stdio(t *testing.T) {
+	var input bytes.Buffer
+	for _, msg := range []string{
+		fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":%d}}`, rpc.ProtocolVersion),
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	} {
+		fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+
+	var output bytes.Buffer
+	cmd := rpcCmd()
+	cmd.SetArgs([]string{"--stdio"})
+	cmd.SetIn(&input)
+	cmd.SetOut(&output)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("rpc --stdio failed: %v", err)
+	}
+
+	body, err := rpc.NewConn(&output, nil).Read()
+	if err != nil {
+		t.Fatalf("failed to read the response: %v", err)
+	}
+	var resp struct {
+		Result rpc.InitializeResult `json:"result"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Result.ServerName != "ralph" || resp.Result.ProtocolVersion != rpc.ProtocolVersion {
+		t.Errorf("unexpected initialize result: %s", body)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/spf13/cobra"
+)
+
+func searchCmd() *cobra.Command {
+	var planID string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search progress, learnings, and reviewer feedback history",
+		Long: `Full-text search over every plan's progress, learnings, and reviewer
+feedback. All terms must match. Results are ranked by relevance and show the
+plan and iteration each entry came from.
+
+Examples:
+  ralph search retry queue
+  ralph search --plan 3f2a9c1e mutex`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if limit < 1 {
+				return fmt.Errorf("--limit must be at least 1")
+			}
+
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			return runSearch(cmd.OutOrStdout(), database, strings.Join(args, " "), planID, limit)
+		},
+	}
+
+	cmd.Flags().StringVar(&planID, "plan", "", "Only search entries from this plan")
+	cmd.Flags().IntVarP(&limit, "limit", "n", 20, "Maximum number of results")
+
+	return cmd
+}
+
+// runSearch prints history entries matching query.
+func runSearch(out io.Writer, database *db.DB, query, planID string, limit int) error {
+	results, err := database.Search(query, planID, limit)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintln(out, "No matches")
+		return nil
+	}
+
+	for _, r := range results {
+		fmt.Fprintf(out, "%s  iteration %d  %s\n", r.PlanID, r.Iteration, r.Kind)
+		fmt.Fprintf(out, "  %s\n", r.Snippet)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+func TestRunSearch(t *testing.T) {
+	database := newPlansTestDB(t)
+	if err := database.CreatePlan(&db.Plan{ID: "plan-1", OriginPath: "plan.md", Content: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreatePlanSession(&db.PlanSession{ID: "s1", PlanID: "plan-1", Iteration: 2, InputPrompt: "p"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreateLearnings(&db.Learnings{PlanID: "plan-1", SessionID: "s1", Content: "jj needs a clean working copy"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runSearch(&out, database, "working copy", "", 10); err != nil {
+		t.Fatalf("runSearch() error: %v", err)
+	}
+	for _, want := range []string{"plan-1  iteration 2  learnings", "[working] [copy]"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := runSearch(&out, database, "nothing", "", 10); err != nil {
+		t.Fatalf("runSearch() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "No matches") {
+		t.Errorf("expected no matches output, got: %s", out.String())
+	}
+}
+
+func TestSearchCmd_RequiresQuery(t *testing.T) {
+	cmd := searchCmd()
+	cmd.SetArgs([]string{})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected error without a query")
+	}
+}
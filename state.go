@@ -0,0 +1,291 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/spf13/cobra"
+)
+
+// stateFileName is the name of the plan state in a state bundle.
+const stateFileName = "state.json"
+
+// stateFilePermissions keeps bundles private: they hold plan content and
+// prompts decrypted.
+const stateFilePermissions = 0600
+
+func exportStateCmd() *cobra.Command {
+	var outputFile string
+	var asOfIteration int
+
+	cmd := &cobra.Command{
+		Use:   "export-state <plan-id>",
+		Short: "Bundle a plan's state so it can be resumed on another machine",
+		Long: `Bundle a plan with its sessions, progress, learnings, reviewer feedback,
+tasks, and jj change references into a gzip-compressed tar file, so a
+colleague can import it with 'ralph import-state' and resume the plan on
+their clone of the repository.
+
+Content encrypted at rest is bundled decrypted; keep the bundle private.
+The plan's jj changes are not bundled: push them so the importing clone can
+fetch them.
+
+With --as-of-iteration, the bundle holds the plan as it stood at the end of
+that iteration, as --from-iteration would rewind it: later iterations are
+marked superseded and the plan imports paused, to resume from there. The
+plan in the local database is left as it is. Plans decomposed into tasks
+can't be exported as of an earlier iteration.
+
+Running plans cannot be exported.
+
+Examples:
+  ralph export-state abc123                  # Writes abc123.tar.gz
+  ralph export-state abc123 -o state.tar.gz
+  ralph export-state abc123 --as-of-iteration 4`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("as-of-iteration") && asOfIteration < 1 {
+				return errors.New("--as-of-iteration must be at least 1")
+			}
+
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			path := outputFile
+			if path == "" {
+				path = args[0] + ".tar.gz"
+			}
+			return exportState(cmd.OutOrStdout(), database, args[0], path, asOfIteration)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Bundle file (default: <plan-id>.tar.gz)")
+	cmd.Flags().IntVar(&asOfIteration, "as-of-iteration", 0, "Bundle the plan as it stood at the end of this iteration")
+
+	return cmd
+}
+
+func importStateCmd() *cobra.Command {
+	var workDirFlag string
+
+	cmd := &cobra.Command{
+		Use:   "import-state <bundle>",
+		Short: "Import a plan's state bundled by 'ralph export-state'",
+		Long: `Import a plan bundled by 'ralph export-state' into the local database,
+to run in the current directory (or --workdir). Resume it afterwards with
+ralph --resume.
+
+The plan's jj changes must be fetched into this clone before resuming;
+changes that can't be found are listed.
+
+Examples:
+  ralph import-state abc123.tar.gz
+  ralph import-state abc123.tar.gz --workdir ~/src/project`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workDir, err := resolveWorkDir(workDirFlag, "")
+			if err != nil {
+				return err
+			}
+
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			return importState(cmd.Context(), cmd.OutOrStdout(), database, jj.NewClient(workDir), args[0], workDir)
+		},
+	}
+
+	cmd.Flags().StringVar(&workDirFlag, "workdir", "", "Directory the imported plan runs in (default: current directory)")
+
+	return cmd
+}
+
+// exportState writes a bundle of a plan's state to path, as of the end of
+// the given iteration (0 = as it is).
+func exportState(out io.Writer, database *db.DB, planID, path string, asOfIteration int) error {
+	plan, err := database.GetPlan(planID)
+	if errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("plan not found: %s", planID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get plan: %w", err)
+	}
+	if plan.Status == db.PlanStatusRunning {
+		return fmt.Errorf("plan %s is running; stop it before exporting", planID)
+	}
+
+	var state *db.PlanState
+	if asOfIteration > 0 {
+		state, err = database.ExportPlanStateAsOf(planID, asOfIteration)
+	} else {
+		state, err = database.ExportPlanState(planID)
+	}
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, stateFilePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	if err := writeStateBundle(f, state); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	if asOfIteration > 0 {
+		fmt.Fprintf(out, "Exported plan %s as of iteration %d to %s\n", planID, asOfIteration, path)
+	} else {
+		fmt.Fprintf(out, "Exported plan %s to %s\n", planID, path)
+	}
+	if refs := changeRefsAsOf(database, plan, asOfIteration); len(refs) > 0 {
+		fmt.Fprintf(out, "Push its jj changes so they can be fetched on import: %v\n", refs)
+	}
+	return nil
+}
+
+// importState imports a plan from the bundle at path to run in workDir, and
+// reports the plan's jj changes missing from the repository.
+func importState(ctx context.Context, out io.Writer, database *db.DB, jjClient *jj.Client, path, workDir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil {
+			log.Warn("failed to close bundle", "error", closeErr)
+		}
+	}()
+
+	state, err := readStateBundle(f)
+	if err != nil {
+		return fmt.Errorf("failed to read bundle %s: %w", path, err)
+	}
+	if err := database.ImportPlanState(state, workDir); err != nil {
+		return err
+	}
+
+	plan, err := database.GetPlan(state.PlanID)
+	if err != nil {
+		return fmt.Errorf("failed to get plan: %w", err)
+	}
+	fmt.Fprintf(out, "Imported plan %s (%s) to run in %s\n", plan.ID, plan.Status, workDir)
+
+	var missing []string
+	for _, ref := range changeRefs(database, plan) {
+		if _, err := jjClient.Log(ctx, ref, "change_id"); err != nil {
+			missing = append(missing, ref)
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(out, "jj changes not found in this repository: %v\n", missing)
+		fmt.Fprintln(out, "Fetch them (e.g. jj git fetch) before resuming.")
+	}
+	fmt.Fprintf(out, "Resume it with: ralph --resume %s\n", plan.ID)
+	return nil
+}
+
+// changeRefs returns the jj revisions a plan builds on: its base change and
+// the latest snapshot of its work.
+func changeRefs(database *db.DB, plan *db.Plan) []string {
+	return changeRefsAsOf(database, plan, 0)
+}
+
+// changeRefsAsOf returns the jj revisions a plan built on by the end of the
+// given iteration (0 = the latest iteration).
+func changeRefsAsOf(database *db.DB, plan *db.Plan, iteration int) []string {
+	var refs []string
+	if plan.BaseChangeID != "" {
+		refs = append(refs, plan.BaseChangeID)
+	}
+	head, err := planHeadAsOf(database, plan, iteration)
+	if err != nil {
+		log.Warn("failed to find the plan's latest change", "plan", plan.ID, "error", err)
+	}
+	if head != "" && head != plan.BaseChangeID {
+		refs = append(refs, head)
+	}
+	return refs
+}
+
+// writeStateBundle writes plan state as a gzip-compressed tar holding
+// stateFileName.
+func writeStateBundle(w io.Writer, state *db.PlanState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	header := &tar.Header{
+		Name:    stateFileName,
+		Mode:    stateFilePermissions,
+		Size:    int64(len(data)),
+		ModTime: state.ExportedAt,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// readStateBundle reads the plan state of a bundle written by
+// writeStateBundle.
+func readStateBundle(r io.Reader) (*db.PlanState, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("no %s in bundle", stateFileName)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Name != stateFileName {
+			continue
+		}
+
+		var state db.PlanState
+		if err := json.NewDecoder(tr).Decode(&state); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", stateFileName, err)
+		}
+		return &state, nil
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+func TestExportImportState(t *testing.T) {
+	source := newDiffTestDB(t, "base")
+	if err := source.CreateLearnings(&db.Learnings{PlanID: "plan-1", SessionID: "d2", Content: "Use table tests"}); err != nil {
+		t.Fatal(err)
+	}
+	bundle := filepath.Join(t.TempDir(), "state.tar.gz")
+
+	var out bytes.Buffer
+	if err := exportState(&out, source, "plan-1", bundle, 0); err != nil {
+		t.Fatalf("exportState() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "[base c2]") {
+		t.Errorf("expected the plan's jj changes, got:\n%s", out.String())
+	}
+
+	// The base change was fetched, the latest snapshot wasn't
+	jjClient := jj.NewClient("/colleague/repo")
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		if args[0] == "log" && args[2] == "c2" {
+			return "", "Error: Revision `c2` doesn't exist", errors.New("exit status 1")
+		}
+		return "base\n", "", nil
+	})
+
+	target := newPlansTestDB(t)
+	out.Reset()
+	if err := importState(context.Background(), &out, target, jjClient, bundle, "/colleague/repo"); err != nil {
+		t.Fatalf("importState() error: %v", err)
+	}
+	for _, want := range []string{"Imported plan plan-1", "not found in this repository: [c2]", "ralph --resume plan-1"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in output, got:\n%s", want, out.String())
+		}
+	}
+
+	plan, err := target.GetPlan("plan-1")
+	if err != nil {
+		t.Fatalf("GetPlan() error: %v", err)
+	}
+	if plan.WorkDir != "/colleague/repo" || plan.BaseChangeID != "base" {
+		t.Errorf("unexpected imported plan: %+v", plan)
+	}
+	if head, err := planHead(target, plan); err != nil || head != "c2" {
+		t.Errorf("planHead() = %q, %v; want c2", head, err)
+	}
+	learnings, err := target.GetLatestLearnings("plan-1")
+	if err != nil || learnings == nil || learnings.Content != "Use table tests" {
+		t.Errorf("GetLatestLearnings() = %+v, %v; want the exported learnings", learnings, err)
+	}
+}
+
+func TestExportState_Errors(t *testing.T) {
+	database := newDiffTestDB(t, "base")
+	bundle := filepath.Join(t.TempDir(), "state.tar.gz")
+
+	var out bytes.Buffer
+	if err := exportState(&out, database, "missing", bundle, 0); err == nil || !strings.Contains(err.Error(), "plan not found") {
+		t.Errorf("expected plan not found error, got: %v", err)
+	}
+	if err := database.UpdatePlanStatus("plan-1", db.PlanStatusRunning); err != nil {
+		t.Fatal(err)
+	}
+	if err := exportState(&out, database, "plan-1", bundle, 0); err == nil || !strings.Contains(err.Error(), "running") {
+		t.Errorf("expected running plan error, got: %v", err)
+	}
+}
+
+func TestReadStateBundle_Invalid(t *testing.T) {
+	if _, err := readStateBundle(strings.NewReader("not a bundle")); err == nil {
+		t.Error("expected an error for a file that isn't gzip")
+	}
+
+	var buf bytes.Buffer
+	if err := writeStateBundle(&buf, &db.PlanState{PlanID: "plan-1"}); err != nil {
+		t.Fatal(err)
+	}
+	state, err := readStateBundle(&buf)
+	if err != nil || state.PlanID != "plan-1" {
+		t.Errorf("readStateBundle() = %+v, %v; want the written state", state, err)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/spf13/cobra"
+)
+
+func statusCmd() *cobra.Command {
+	var limit int
+	var asOfIteration int
+
+	cmd := &cobra.Command{
+		Use:   "status [plan-id]",
+		Short: "Show the status of plans",
+		Long: `List the most recently updated plans with their status and, for plans that
+were paused, failed, stopped, cancelled, or abandoned, the reason why. With a
+plan ID, show that plan's details and its latest iteration. With
+--as-of-iteration, also show the progress, learnings, and reviewer feedback
+as they stood at the end of that iteration: what the next iteration's
+prompts were built from.
+
+Plan statuses:
+  pending    created but not started
+  running    a ralph process is working on it
+  paused     interrupted, or out of --max-duration time or --max-cost budget;
+             resume with ralph --resume
+  completed  the developer and reviewer both signed off
+  failed     stopped by an error
+  stopped    hit the iteration limit or stalled
+  cancelled  given up on with ralph plans cancel
+  abandoned  its ralph process died while running
+
+Examples:
+  ralph status
+  ralph status --limit 50
+  ralph status 3f2a9c1e
+  ralph status 3f2a9c1e --as-of-iteration 4`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if limit < 1 {
+				return errors.New("--limit must be at least 1")
+			}
+			if cmd.Flags().Changed("as-of-iteration") {
+				if len(args) == 0 {
+					return errors.New("--as-of-iteration needs a plan ID")
+				}
+				if asOfIteration < 1 {
+					return errors.New("--as-of-iteration must be at least 1")
+				}
+			}
+
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			if len(args) == 1 {
+				if err := showPlanStatus(cmd.OutOrStdout(), database, args[0]); err != nil {
+					return err
+				}
+				if asOfIteration > 0 {
+					return showStateAsOf(cmd.OutOrStdout(), database, args[0], asOfIteration)
+				}
+				return nil
+			}
+			return listPlanStatuses(cmd.OutOrStdout(), database, limit)
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "n", 20, "Maximum number of plans to list")
+	cmd.Flags().IntVar(&asOfIteration, "as-of-iteration", 0, "Also show the plan's state at the end of this iteration")
+
+	return cmd
+}
+
+// listPlanStatuses prints a table of the most recently updated plans.
+func listPlanStatuses(out io.Writer, database *db.DB, limit int) error {
+	plans, err := database.ListPlans(limit)
+	if err != nil {
+		return fmt.Errorf("failed to list plans: %w", err)
+	}
+	if len(plans) == 0 {
+		fmt.Fprintln(out, "No plans.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tUPDATED\tREASON")
+	for _, plan := range plans {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			plan.ID, plan.Status, plan.UpdatedAt.Local().Format(time.DateTime), plan.FailureReason)
+	}
+	return w.Flush()
+}
+
+// showPlanStatus prints one plan's status, reason, and latest iteration.
+func showPlanStatus(out io.Writer, database *db.DB, planID string) error {
+	plan, err := database.GetPlan(planID)
+	if errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("plan not found: %s", planID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get plan: %w", err)
+	}
+
+	session, err := database.GetLatestPlanSession(planID)
+	if err != nil {
+		return fmt.Errorf("failed to get latest session: %w", err)
+	}
+
+	skips, err := database.GetReviewSkipsByPlan(planID)
+	if err != nil {
+		return fmt.Errorf("failed to get review skips: %w", err)
+	}
+
+	items, err := database.GetFeedbackItemsByPlan(planID)
+	if err != nil {
+		return fmt.Errorf("failed to get review items: %w", err)
+	}
+	var open []*db.FeedbackItem
+	for _, item := range items {
+		if item.Open() {
+			open = append(open, item)
+		}
+	}
+
+	subPlans, err := database.GetSubPlans(planID)
+	if err != nil {
+		return fmt.Errorf("failed to get sub-plans: %w", err)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Plan:\t%s\n", plan.ID)
+	if plan.OriginPath != "" {
+		fmt.Fprintf(w, "Origin:\t%s\n", plan.OriginPath)
+	}
+	fmt.Fprintf(w, "Status:\t%s\n", plan.Status)
+	if plan.FailureReason != "" {
+		fmt.Fprintf(w, "Reason:\t%s\n", plan.FailureReason)
+	}
+	if plan.WorkDir != "" {
+		fmt.Fprintf(w, "Work dir:\t%s\n", plan.WorkDir)
+	}
+	if plan.ForkedFrom != "" {
+		fmt.Fprintf(w, "Forked from:\t%s\n", plan.ForkedFrom)
+	}
+	if plan.ParentPlanID != "" {
+		fmt.Fprintf(w, "Sub-plan of:\t%s\n", plan.ParentPlanID)
+	}
+	fmt.Fprintf(w, "Created:\t%s\n", plan.CreatedAt.Local().Format(time.DateTime))
+	fmt.Fprintf(w, "Updated:\t%s\n", plan.UpdatedAt.Local().Format(time.DateTime))
+	if session != nil {
+		fmt.Fprintf(w, "Iteration:\t%d (%s, %s)\n", session.Iteration, session.AgentType, session.Status)
+	} else {
+		fmt.Fprintln(w, "Iteration:\tnone yet")
+	}
+	if len(skips) > 0 {
+		skipped := 0
+		for _, skip := range skips {
+			if skip.Action == db.ReviewSkipActionSkip {
+				skipped++
+			}
+		}
+		fmt.Fprintf(w, "Trivial reviews:\t%d skipped, %d downgraded\n", skipped, len(skips)-skipped)
+	}
+	if len(items) > 0 {
+		fmt.Fprintf(w, "Review items:\t%d open, %d closed\n", len(open), len(items)-len(open))
+	}
+	for _, subPlan := range subPlans {
+		fmt.Fprintf(w, "Sub-plan:\t%s (%s)\n", subPlan.ID, subPlan.Status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(open) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out, "\nOpen review items:")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, item := range open {
+		severity := item.Severity
+		if severity == "" {
+			severity = "-"
+		}
+		fmt.Fprintf(w, "  #%d\t%s\t%s\t%s\n", item.Number, severity, item.State(), firstLine(item.Content, ""))
+	}
+	return w.Flush()
+}
+
+// showStateAsOf prints a plan's progress, learnings, and reviewer feedback
+// as they stood at the end of an iteration.
+func showStateAsOf(out io.Writer, database *db.DB, planID string, iteration int) error {
+	state, err := database.GetStateAsOf(planID, iteration)
+	if err != nil {
+		return err
+	}
+
+	var progress, learnings, feedback string
+	if state.Progress != nil {
+		progress = state.Progress.Content
+	}
+	if state.Learnings != nil {
+		learnings = state.Learnings.Content
+	}
+	if state.Feedback != nil {
+		feedback = state.Feedback.Content
+	}
+
+	fmt.Fprintf(out, "\nAs of iteration %d:\n", state.Iteration)
+	for _, section := range []struct{ title, content string }{
+		{"Progress", progress},
+		{"Learnings", learnings},
+		{"Reviewer Feedback", feedback},
+	} {
+		content := strings.TrimSpace(section.content)
+		if content == "" {
+			content = "(none)"
+		}
+		fmt.Fprintf(out, "\n## %s\n\n%s\n", section.title, content)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+func TestListPlanStatuses(t *testing.T) {
+	database := newPlansTestDB(t)
+
+	var out bytes.Buffer
+	if err := listPlanStatuses(&out, database, 20); err != nil {
+		t.Fatalf("listPlanStatuses() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "No plans.") {
+		t.Errorf("expected empty message, got: %s", out.String())
+	}
+
+	if err := database.CreatePlan(&db.Plan{ID: "plan-1", Content: "c", Status: db.PlanStatusRunning}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.UpdatePlanStatusWithReason("plan-1", db.PlanStatusFailed, "claude exited with status 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	if err := listPlanStatuses(&out, database, 20); err != nil {
+		t.Fatalf("listPlanStatuses() error: %v", err)
+	}
+	for _, want := range []string{"STATUS", "plan-1", "failed", "claude exited with status 1"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestShowPlanStatus(t *testing.T) {
+	database := newPlansTestDB(t)
+	if err := database.CreatePlan(&db.Plan{ID: "plan-1", OriginPath: "plan.md", Content: "c", Status: db.PlanStatusPaused, WorkDir: "/src/api"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreatePlanSession(&db.PlanSession{
+		ID: "s1", PlanID: "plan-1", Iteration: 3, Status: db.PlanSessionCompleted, AgentType: db.LoopAgentDeveloper,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range []string{db.ReviewSkipActionSkip, db.ReviewSkipActionSkip, db.ReviewSkipActionDowngrade} {
+		if err := database.CreateReviewSkip(&db.ReviewSkip{PlanID: "plan-1", Iteration: 2, Action: action, Reason: "comment-only changes"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, content := range []string{"Handle the nil config", "Add a test for empty input\n```go\nparse(\"\")\n```"} {
+		if err := database.CreateFeedbackItem(&db.FeedbackItem{PlanID: "plan-1", SessionID: "s1", Severity: "Major", Content: content, IssuedIteration: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.ClaimFeedbackItems("plan-1", []int{1, 2}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.VerifyFeedbackItems("plan-1", []int{1}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.ClaimFeedbackItems("plan-1", []int{2}, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreatePlan(&db.Plan{ID: "sub-1", Content: "Rewrite auth", Status: db.PlanStatusCompleted, ParentPlanID: "plan-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := showPlanStatus(&out, database, "plan-1"); err != nil {
+		t.Fatalf("showPlanStatus() error: %v", err)
+	}
+	for _, want := range []string{"plan.md", "paused", "/src/api", "3 (developer, completed)", "2 skipped, 1 downgraded",
+		"1 open, 1 closed", "sub-1 (completed)", "#2  Major  claimed-fixed  Add a test for empty input\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := showPlanStatus(&out, database, "sub-1"); err != nil || !strings.Contains(out.String(), "Sub-plan of:  plan-1") {
+		t.Errorf("sub-plan status should name its parent, got %v:\n%s", err, out.String())
+	}
+
+	if err := showPlanStatus(&out, database, "missing"); err == nil || !strings.Contains(err.Error(), "plan not found") {
+		t.Errorf("expected not found error, got: %v", err)
+	}
+}
+
+func TestShowStateAsOf(t *testing.T) {
+	database := newPlansTestDB(t)
+	if err := database.CreatePlan(&db.Plan{ID: "plan-1", Content: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range []string{"dev-1", "rev-1", "dev-2"} {
+		agentType := db.LoopAgentDeveloper
+		if id == "rev-1" {
+			agentType = db.LoopAgentReviewer
+		}
+		if err := database.CreatePlanSession(&db.PlanSession{ID: id, PlanID: "plan-1", Iteration: 1 + i/2, InputPrompt: "p", AgentType: agentType}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.CreateProgress(&db.Progress{PlanID: "plan-1", SessionID: "dev-1", Content: "Added the handlers"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreateReviewerFeedback(&db.ReviewerFeedback{PlanID: "plan-1", SessionID: "rev-1", Content: "Handle empty input"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreateProgress(&db.Progress{PlanID: "plan-1", SessionID: "dev-2", Content: "Handled empty input"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := showStateAsOf(&out, database, "plan-1", 1); err != nil {
+		t.Fatalf("showStateAsOf() error: %v", err)
+	}
+	want := "\nAs of iteration 1:\n\n## Progress\n\nAdded the handlers\n\n## Learnings\n\n(none)\n\n## Reviewer Feedback\n\nHandle empty input\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	if err := showStateAsOf(&out, database, "plan-1", 5); err == nil || !strings.Contains(err.Error(), "no iteration 5") {
+		t.Errorf("expected no iteration error, got: %v", err)
+	}
+}
@@ -7,7 +7,7 @@ func taskCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "task",
 		Short: "Task management commands",
-		Long: `Task management commands for viewing, exporting, and importing task content.
+		Long: `Task management commands for viewing, exporting, importing, and restructuring tasks.
 
 These commands allow you to modify task plans on the fly during Ralph execution.`,
 	}
@@ -15,6 +15,9 @@ These commands allow you to modify task plans on the fly during Ralph execution.
 	cmd.AddCommand(taskListCmd())
 	cmd.AddCommand(taskExportCmd())
 	cmd.AddCommand(taskImportCmd())
+	cmd.AddCommand(taskReorderCmd())
+	cmd.AddCommand(taskSplitCmd())
+	cmd.AddCommand(taskMergeCmd())
 
 	return cmd
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/spf13/cobra"
+)
+
+func taskMergeCmd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "merge <project-id> <task-sequence> <task-sequence>",
+		Short: "Merge two tasks into one",
+		Long: `Merge the second task into the first. The first task keeps its title and
+place in the order, and its description gets the second task's title and
+description appended. The second task is removed and the tasks after it are
+renumbered. Tasks in progress or completed can't be merged.
+
+Examples:
+  ralph task merge abc123 2 3      # Fold task 3 into task 2
+  ralph task merge abc123 5 2 -f   # Fold task 2 into task 5 without asking`,
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sequences, err := parseTaskSequences(args[1:])
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			database, err := db.OpenProjectDB(cfg.GetProjectsDir(), args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open project %s: %w", args[0], err)
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			return runTaskMerge(cmd.OutOrStdout(), database, args[0], sequences[0], sequences[1], force)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Skip confirmation prompt")
+
+	return cmd
+}
+
+// runTaskMerge merges task second into task first after confirmation.
+func runTaskMerge(out io.Writer, database *db.DB, projectID string, first, second int, force bool) error {
+	into, err := database.GetTaskBySequence(projectID, first)
+	if err != nil {
+		return fmt.Errorf("task %d not found in project %s: %w", first, projectID, err)
+	}
+	from, err := database.GetTaskBySequence(projectID, second)
+	if err != nil {
+		return fmt.Errorf("task %d not found in project %s: %w", second, projectID, err)
+	}
+
+	if !force {
+		fmt.Fprintf(out, "Merge task %d (%s) into task %d (%s)?\n", second, from.Title, first, into.Title)
+		fmt.Fprint(out, "Proceed? [y/N]: ")
+		response, _ := bufio.NewReader(confirmInput).ReadString('\n')
+		if response = strings.TrimSpace(response); response != "y" && response != "Y" {
+			fmt.Fprintln(out, "Merge cancelled.")
+			return nil
+		}
+	}
+
+	if err := database.MergeTasks(projectID, first, second); err != nil {
+		return fmt.Errorf("failed to merge tasks: %w", err)
+	}
+	return printTasks(out, database, projectID)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/spf13/cobra"
+)
+
+func taskReorderCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reorder <project-id> <task-sequence>...",
+		Short: "Change the order tasks are worked in",
+		Long: `Move tasks to the front of a project's order. The listed tasks come first,
+in the order given, followed by the rest in their current order; all tasks are
+then renumbered from 1. A task in progress can't be moved.
+
+Examples:
+  ralph task reorder abc123 4        # Work task 4 next
+  ralph task reorder abc123 3 1 2    # Work tasks 3, 1, and 2 first, in that order`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sequences, err := parseTaskSequences(args[1:])
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			database, err := db.OpenProjectDB(cfg.GetProjectsDir(), args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open project %s: %w", args[0], err)
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			return runTaskReorder(cmd.OutOrStdout(), database, args[0], sequences)
+		},
+	}
+}
+
+// runTaskReorder moves the tasks with the given sequence numbers to the
+// front of the project's order and prints the new order.
+func runTaskReorder(out io.Writer, database *db.DB, projectID string, sequences []int) error {
+	tasks, err := database.GetTasksByProject(projectID)
+	if err != nil {
+		return err
+	}
+	bySequence := make(map[int]*db.Task, len(tasks))
+	for _, task := range tasks {
+		bySequence[task.Sequence] = task
+	}
+
+	var ids []string
+	moved := make(map[int]bool, len(sequences))
+	for _, seq := range sequences {
+		if bySequence[seq] == nil {
+			return fmt.Errorf("task %d not found in project %s", seq, projectID)
+		}
+		if moved[seq] {
+			return fmt.Errorf("task %d is listed twice", seq)
+		}
+		moved[seq] = true
+		ids = append(ids, bySequence[seq].ID)
+	}
+	for _, task := range tasks {
+		if !moved[task.Sequence] {
+			ids = append(ids, task.ID)
+		}
+	}
+
+	if err := database.ReorderTasks(projectID, ids); err != nil {
+		return fmt.Errorf("failed to reorder tasks: %w", err)
+	}
+	return printTasks(out, database, projectID)
+}
+
+// parseTaskSequences parses task sequence number arguments.
+func parseTaskSequences(args []string) ([]int, error) {
+	sequences := make([]int, len(args))
+	for i, arg := range args {
+		seq, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid task sequence: %s", arg)
+		}
+		sequences[i] = seq
+	}
+	return sequences, nil
+}
+
+// printTasks prints a project's tasks in order after they were changed.
+func printTasks(out io.Writer, database *db.DB, projectID string) error {
+	tasks, err := database.GetTasksByProject(projectID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Tasks in project %s:\n\n", projectID)
+	for _, task := range tasks {
+		fmt.Fprintf(out, "  %s %d. %s\n", statusIcon(task.Status), task.Sequence, task.Title)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/agent"
+	"github.com/gerunddev/ralph/internal/app"
+	"github.com/gerunddev/ralph/internal/claude"
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/parser"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// plannerRunner runs a planner agent session and returns its output.
+// It can be replaced in tests.
+var plannerRunner = defaultPlannerRunner
+
+// defaultPlannerRunner runs prompt in a Claude session configured like the
+// reviewer's, since the planner only reads, and returns the text of its
+// messages.
+func defaultPlannerRunner(ctx context.Context, cfg *config.Config, prompt string) (string, error) {
+	return runClaudePrompt(ctx, cfg, cfg.Claude.Reviewer, "", prompt)
+}
+
+// runClaudePrompt runs prompt in a Claude session with role's CLI options,
+// in workDir ("" = the current directory), and returns the text of its
+// messages.
+func runClaudePrompt(ctx context.Context, cfg *config.Config, role config.ClaudeRoleConfig, workDir, prompt string) (string, error) {
+	return runClaudeSession(ctx, cfg, nil, role, workDir, prompt, nil)
+}
+
+// runClaudeSession is runClaudePrompt, calling onEvent (if non-nil) with
+// each event of the session as it streams. The client is built like the
+// loop's, under the permissions config's policy. With a plans database,
+// the session also waits for the configured throttle, and its cost is
+// charged to it, as the loop's sessions are.
+func runClaudeSession(ctx context.Context, cfg *config.Config, database *db.DB, role config.ClaudeRoleConfig, workDir, prompt string, onEvent func(claude.StreamEvent)) (string, error) {
+	client, err := app.NewRoleClient(cfg, role, workDir)
+	if err != nil {
+		return "", err
+	}
+	throttle := app.SessionThrottle(cfg)
+	if database != nil {
+		if err := throttle.Wait(ctx, database, func(until time.Time, limit string) {
+			if !until.IsZero() {
+				log.Info("throttled", "limit", limit, "until", until.Format(time.TimeOnly))
+			}
+		}); err != nil {
+			return "", err
+		}
+	}
+	session, err := client.Run(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+
+	// Complete messages supersede the text streamed before them
+	var messages, streamed strings.Builder
+	for event := range session.Events() {
+		if onEvent != nil {
+			onEvent(event)
+		}
+		if event.SubAgentID != "" {
+			continue
+		}
+		if database != nil && event.Type == claude.EventResult && event.Result != nil {
+			throttle.Spend(database, event.Result.CostUSD)
+		}
+		switch {
+		case event.Type == claude.EventMessage && event.Message != nil:
+			messages.WriteString(event.Message.Text)
+		case event.Type == claude.EventAssistantText && event.AssistantText != nil:
+			streamed.WriteString(event.AssistantText.Text)
+		}
+	}
+	if err := session.Wait(); err != nil {
+		return "", err
+	}
+	if messages.Len() > 0 {
+		return messages.String(), nil
+	}
+	return streamed.String(), nil
+}
+
+func taskSplitCmd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "split <project-id> <task-sequence>",
+		Short: "Split a task into subtasks with a planner agent",
+		Long: `Run a planner agent on a task's title and description to break it into
+smaller subtasks, which replace the task in its place in the order. The tasks
+after it are renumbered. Tasks in progress or completed can't be split.
+
+The first subtask keeps the task's ID and history. The proposed subtasks are
+shown for confirmation before anything is changed.
+
+Examples:
+  ralph task split abc123 3
+  ralph task split abc123 3 -f   # Don't ask for confirmation`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sequences, err := parseTaskSequences(args[1:])
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			database, err := db.OpenProjectDB(cfg.GetProjectsDir(), args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open project %s: %w", args[0], err)
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			return runTaskSplit(cmd.Context(), cmd.OutOrStdout(), cfg, database, args[0], sequences[0], force)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Skip confirmation prompt")
+
+	return cmd
+}
+
+// runTaskSplit has a planner agent break a task into subtasks and, after
+// confirmation, replaces the task with them.
+func runTaskSplit(ctx context.Context, out io.Writer, cfg *config.Config, database *db.DB, projectID string, sequence int, force bool) error {
+	task, err := database.GetTaskBySequence(projectID, sequence)
+	if err != nil {
+		return fmt.Errorf("task %d not found in project %s: %w", sequence, projectID, err)
+	}
+	// Checked again when the task is split; this saves a planner session
+	switch task.Status {
+	case db.TaskInProgress:
+		return fmt.Errorf("cannot split task %d: %w", sequence, db.ErrTaskInProgress)
+	case db.TaskCompleted:
+		return fmt.Errorf("cannot split completed task %d", sequence)
+	}
+
+	customPrompt, err := cfg.GetAgentPrompt("planner")
+	if err != nil {
+		return fmt.Errorf("failed to build planner prompt: %w", err)
+	}
+	prompt, err := agent.BuildPlannerPrompt(agent.PlannerContext{
+		PlanContent: fmt.Sprintf("# %s\n\n%s", task.Title, task.Description),
+		Locale:      cfg.Locale,
+		Template:    customPrompt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build planner prompt: %w", err)
+	}
+
+	fmt.Fprintf(out, "Splitting task %d (%s)...\n", sequence, task.Title)
+	output, err := plannerRunner(ctx, cfg, prompt)
+	if err != nil {
+		return fmt.Errorf("planner agent failed: %w", err)
+	}
+	planned := parser.ParseTasks(output)
+	if len(planned) < 2 {
+		return fmt.Errorf("planner did not split task %d: it produced %d tasks", sequence, len(planned))
+	}
+
+	fmt.Fprintf(out, "\nProposed subtasks:\n\n")
+	for i, p := range planned {
+		fmt.Fprintf(out, "  %d. %s\n", sequence+i, p.Title)
+	}
+	if !force {
+		fmt.Fprintf(out, "\nReplace task %d with these %d subtasks? [y/N]: ", sequence, len(planned))
+		response, _ := bufio.NewReader(confirmInput).ReadString('\n')
+		if response = strings.TrimSpace(response); response != "y" && response != "Y" {
+			fmt.Fprintln(out, "Split cancelled.")
+			return nil
+		}
+	}
+
+	subtasks := make([]*db.Task, len(planned))
+	for i, p := range planned {
+		subtasks[i] = &db.Task{
+			ID:          uuid.New().String(),
+			Title:       p.Title,
+			Description: p.Description,
+			Status:      db.TaskPending,
+		}
+	}
+	if err := database.SplitTask(task.ID, subtasks); err != nil {
+		return fmt.Errorf("failed to split task: %w", err)
+	}
+	fmt.Fprintln(out)
+	return printTasks(out, database, projectID)
+}
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/gerunddev/ralph/internal/config"
+	"github.com/gerunddev/ralph/internal/db"
 )
 
 func TestTaskCmd_SubcommandGroup(t *testing.T) {
@@ -14,8 +20,8 @@ func TestTaskCmd_SubcommandGroup(t *testing.T) {
 
 	// Verify subcommands exist
 	subcommands := cmd.Commands()
-	if len(subcommands) != 3 {
-		t.Errorf("taskCmd() has %d subcommands, want 3", len(subcommands))
+	if len(subcommands) != 6 {
+		t.Errorf("taskCmd() has %d subcommands, want 6", len(subcommands))
 	}
 
 	subNames := make(map[string]bool)
@@ -23,7 +29,11 @@ func TestTaskCmd_SubcommandGroup(t *testing.T) {
 		subNames[sub.Use] = true
 	}
 
-	expected := []string{"list <project-id>", "export <project-id> <task-sequence>", "import <project-id> <task-sequence> <file>"}
+	expected := []string{
+		"list <project-id>", "export <project-id> <task-sequence>", "import <project-id> <task-sequence> <file>",
+		"reorder <project-id> <task-sequence>...", "split <project-id> <task-sequence>",
+		"merge <project-id> <task-sequence> <task-sequence>",
+	}
 	for _, e := range expected {
 		if !subNames[e] {
 			t.Errorf("taskCmd() missing subcommand %q", e)
@@ -237,3 +247,104 @@ func TestStatusIcon(t *testing.T) {
 		})
 	}
 }
+
+// newTaskTestDB returns a database with a project of tasks titled "T1"
+// through "Tn".
+func newTaskTestDB(t *testing.T, n int) *db.DB {
+	t.Helper()
+	database := newPlansTestDB(t)
+	if err := database.CreateProject(&db.Project{ID: "proj-1", Name: "Project", PlanText: "Plan"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= n; i++ {
+		task := &db.Task{ID: fmt.Sprintf("task-%d", i), ProjectID: "proj-1", Sequence: i, Title: fmt.Sprintf("T%d", i), Description: "Desc"}
+		if err := database.CreateTask(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return database
+}
+
+func TestRunTaskReorder(t *testing.T) {
+	database := newTaskTestDB(t, 4)
+
+	var out bytes.Buffer
+	if err := runTaskReorder(&out, database, "proj-1", []int{3, 1}); err != nil {
+		t.Fatalf("runTaskReorder() error: %v", err)
+	}
+	want := "  [ ] 1. T3\n  [ ] 2. T1\n  [ ] 3. T2\n  [ ] 4. T4\n"
+	if !strings.HasSuffix(out.String(), want) {
+		t.Errorf("output = %q, want suffix %q", out.String(), want)
+	}
+
+	if err := runTaskReorder(&out, database, "proj-1", []int{2, 2}); err == nil || !strings.Contains(err.Error(), "listed twice") {
+		t.Errorf("expected a listed twice error, got %v", err)
+	}
+	if err := runTaskReorder(&out, database, "proj-1", []int{7}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestRunTaskMerge(t *testing.T) {
+	database := newTaskTestDB(t, 3)
+	originalInput := confirmInput
+	t.Cleanup(func() { confirmInput = originalInput })
+
+	confirmInput = strings.NewReader("n\n")
+	var out bytes.Buffer
+	if err := runTaskMerge(&out, database, "proj-1", 1, 2, false); err != nil {
+		t.Fatalf("runTaskMerge() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Merge cancelled.") {
+		t.Errorf("expected the merge to be cancelled, got %q", out.String())
+	}
+
+	confirmInput = strings.NewReader("y\n")
+	out.Reset()
+	if err := runTaskMerge(&out, database, "proj-1", 1, 2, false); err != nil {
+		t.Fatalf("runTaskMerge() error: %v", err)
+	}
+	if !strings.HasSuffix(out.String(), "  [ ] 1. T1\n  [ ] 2. T3\n") {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestRunTaskSplit(t *testing.T) {
+	database := newTaskTestDB(t, 2)
+	originalRunner, originalInput := plannerRunner, confirmInput
+	t.Cleanup(func() { plannerRunner, confirmInput = originalRunner, originalInput })
+
+	var prompt string
+	plannerRunner = func(ctx context.Context, cfg *config.Config, p string) (string, error) {
+		prompt = p
+		return "## Tasks\n\n1. Parse input\nRead the file.\n2. Write output\nPrint it.\n", nil
+	}
+	confirmInput = strings.NewReader("y\n")
+
+	var out bytes.Buffer
+	if err := runTaskSplit(context.Background(), &out, config.DefaultConfig(), database, "proj-1", 1, false); err != nil {
+		t.Fatalf("runTaskSplit() error: %v", err)
+	}
+	if !strings.Contains(prompt, "# T1\n\nDesc") {
+		t.Errorf("expected the task in the planner prompt, got:\n%s", prompt)
+	}
+	if !strings.HasSuffix(out.String(), "  [ ] 1. Parse input\n  [ ] 2. Write output\n  [ ] 3. T2\n") {
+		t.Errorf("output = %q", out.String())
+	}
+
+	if err := database.UpdateTaskStatus("task-2", db.TaskInProgress); err != nil {
+		t.Fatal(err)
+	}
+	prompt = ""
+	err := runTaskSplit(context.Background(), &out, config.DefaultConfig(), database, "proj-1", 3, true)
+	if err == nil || !strings.Contains(err.Error(), "in progress") || prompt != "" {
+		t.Errorf("expected an in-progress task to be refused before running the planner, got %v", err)
+	}
+
+	plannerRunner = func(ctx context.Context, cfg *config.Config, p string) (string, error) {
+		return "## Tasks\n\n1. Only one\n", nil
+	}
+	if err := runTaskSplit(context.Background(), &out, config.DefaultConfig(), database, "proj-1", 1, true); err == nil || !strings.Contains(err.Error(), "did not split") {
+		t.Errorf("expected a single task to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/spf13/cobra"
+)
+
+func transcriptCmd() *cobra.Command {
+	var raw bool
+
+	cmd := &cobra.Command{
+		Use:   "transcript <session-id>",
+		Short: "Print the full conversation of an agent session",
+		Long: `Print the prompt and complete ordered transcript of an agent session:
+assistant text, every tool call with its input, and every tool result.
+
+With --raw, print the stored Claude stream-json events instead, one per line.
+
+Examples:
+  ralph transcript 7bed4f67-63ba-469b-9f74-6eee94fdcb53
+  ralph transcript --raw 7bed4f67-63ba-469b-9f74-6eee94fdcb53 | jq .`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			return runTranscript(cmd.OutOrStdout(), database, args[0], raw)
+		},
+	}
+
+	cmd.Flags().BoolVar(&raw, "raw", false, "Print the raw stream-json events")
+
+	return cmd
+}
+
+// runTranscript prints the transcript of a session.
+func runTranscript(out io.Writer, database *db.DB, sessionID string, raw bool) error {
+	session, err := database.GetPlanSession(sessionID)
+	if errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if raw {
+		events, err := database.GetEventsBySession(sessionID)
+		if err != nil {
+			return fmt.Errorf("failed to get events: %w", err)
+		}
+		for _, e := range events {
+			fmt.Fprintln(out, e.RawJSON)
+		}
+		return nil
+	}
+
+	messages, err := database.GetTranscriptBySession(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get transcript: %w", err)
+	}
+
+	fmt.Fprintf(out, "Session %s  plan %s  iteration %d  %s  %s\n\n",
+		session.ID, session.PlanID, session.Iteration, session.AgentType, session.Status)
+	fmt.Fprintln(out, "=== prompt ===")
+	fmt.Fprintln(out, session.InputPrompt)
+
+	for _, m := range messages {
+		// Sub-agent messages are labeled with the Task call that spawned them
+		agent := ""
+		if m.SubAgentID != "" {
+			agent = fmt.Sprintf("sub-agent %s ", m.SubAgentID)
+		}
+		switch m.Kind {
+		case "tool_use":
+			fmt.Fprintf(out, "\n=== %stool_use %s (%s) ===\n", agent, m.ToolName, m.ToolUseID)
+		case "tool_result":
+			status := ""
+			if m.IsError {
+				status = " error"
+			}
+			fmt.Fprintf(out, "\n=== %stool_result (%s)%s ===\n", agent, m.ToolUseID, status)
+		default:
+			fmt.Fprintf(out, "\n=== %s%s ===\n", agent, m.Role)
+		}
+		fmt.Fprintln(out, m.Content)
+	}
+
+	if len(messages) == 0 {
+		fmt.Fprintln(out, "\n(no transcript recorded for this session)")
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+)
+
+func TestRunTranscript(t *testing.T) {
+	database := newPlansTestDB(t)
+	if err := database.CreatePlan(&db.Plan{ID: "plan-1", OriginPath: "plan.md", Content: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreatePlanSession(&db.PlanSession{ID: "s1", PlanID: "plan-1", Iteration: 3, InputPrompt: "Do the thing", AgentType: db.LoopAgentDeveloper}); err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range []*db.TranscriptMessage{
+		{Role: "assistant", Kind: "text", Content: "Reading the file"},
+		{Role: "assistant", Kind: "tool_use", ToolName: "Read", ToolUseID: "t1", Content: `{"path":"a.go"}`},
+		{Role: "user", Kind: "tool_result", ToolUseID: "t1", Content: "no such file", IsError: true},
+		{Role: "assistant", Kind: "tool_use", ToolName: "Grep", ToolUseID: "t3", Content: `{"pattern":"a"}`, SubAgentID: "t2"},
+	} {
+		m.SessionID = "s1"
+		m.Sequence = i
+		if err := database.CreateTranscriptMessage(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.CreateEvent(&db.Event{SessionID: "s1", EventType: "init", RawJSON: `{"type":"init"}`}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runTranscript(&out, database, "s1", false); err != nil {
+		t.Fatalf("runTranscript() error: %v", err)
+	}
+	got := out.String()
+	wantOrder := []string{
+		"iteration 3  developer",
+		"=== prompt ===\nDo the thing",
+		"=== assistant ===\nReading the file",
+		"=== tool_use Read (t1) ===\n{\"path\":\"a.go\"}",
+		"=== tool_result (t1) error ===\nno such file",
+		"=== sub-agent t2 tool_use Grep (t3) ===",
+	}
+	pos := 0
+	for _, want := range wantOrder {
+		i := strings.Index(got[pos:], want)
+		if i < 0 {
+			t.Fatalf("output missing %q after offset %d:\n%s", want, pos, got)
+		}
+		pos += i + len(want)
+	}
+
+	out.Reset()
+	if err := runTranscript(&out, database, "s1", true); err != nil {
+		t.Fatalf("runTranscript() raw error: %v", err)
+	}
+	if out.String() != "{\"type\":\"init\"}\n" {
+		t.Errorf("raw output = %q, want the stored event JSON", out.String())
+	}
+
+	if err := runTranscript(&out, database, "missing", false); err == nil || !strings.Contains(err.Error(), "session not found") {
+		t.Errorf("runTranscript() for unknown session error = %v, want session not found", err)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/spf13/cobra"
+)
+
+func undoIterationCmd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "undo-iteration <plan-id>",
+		Short: "Undo a plan's latest iteration",
+		Long: `Undo the latest iteration of a plan that isn't running. The repository is
+restored with jj op restore to the operation recorded when the iteration
+started, which reverts everything jj did since: the developer's edits, the
+iteration's change, descriptions, and bookmarks. The iteration's sessions,
+progress, learnings, and feedback are marked superseded, as resuming with
+--from-iteration does, so resuming the plan runs the iteration again.
+
+Anything else done in the repository since the iteration started is reverted
+too, in every workspace, so it refuses to run while any plan in the
+repository is running, and asks for confirmation first. The restore is
+itself a jj operation: find the one before it with jj op log to bring the
+undone work back.
+
+Examples:
+  ralph undo-iteration 3f2a9c1e
+  ralph undo-iteration 3f2a9c1e -f  # Don't ask for confirmation
+  ralph -r 3f2a9c1e  # Run the iteration again`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			workDir, err := planWorkDir(database, args[0])
+			if err != nil {
+				return err
+			}
+
+			return runUndoIteration(cmd.Context(), cmd.OutOrStdout(), database, jj.NewClient(workDir), args[0], force)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Skip confirmation prompt")
+	return cmd
+}
+
+// runUndoIteration restores the repository to the jj operation recorded at
+// the start of a plan's latest iteration and supersedes the iteration's
+// records, after confirmation unless force is set.
+func runUndoIteration(ctx context.Context, out io.Writer, database *db.DB, jjClient *jj.Client, planID string, force bool) error {
+	plan, err := database.GetPlan(planID)
+	if errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("plan not found: %s", planID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get plan: %w", err)
+	}
+	if plan.Status == db.PlanStatusRunning {
+		return fmt.Errorf("plan %s is running; stop it before undoing an iteration", planID)
+	}
+
+	// Task statuses aren't versioned, so an undo would leave them ahead
+	tasks, err := database.GetTasksByProject(planID)
+	if err != nil {
+		return fmt.Errorf("failed to get plan tasks: %w", err)
+	}
+	if len(tasks) > 0 {
+		return fmt.Errorf("cannot undo an iteration of plan %s: it was decomposed into tasks", planID)
+	}
+
+	iteration, err := database.LatestIteration(planID)
+	if err != nil {
+		return fmt.Errorf("failed to get latest iteration: %w", err)
+	}
+	if iteration == 0 {
+		return fmt.Errorf("plan %s has no iterations to undo", planID)
+	}
+	operationID, err := database.GetIterationOperation(planID, iteration)
+	if errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("no jj operation was recorded when iteration %d of plan %s started", iteration, planID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get iteration operation: %w", err)
+	}
+
+	// The restore reverts the whole repository, other plans' work included
+	root, err := jjClient.Root(ctx)
+	if err != nil || root == "" {
+		root = plan.WorkDir
+	}
+	running, err := database.GetPlansByStatus(db.PlanStatusRunning)
+	if err != nil {
+		return fmt.Errorf("failed to get running plans: %w", err)
+	}
+	for _, other := range running {
+		if root != "" && inDir(other.WorkDir, root) {
+			return fmt.Errorf("plan %s is running in this repository; stop it before undoing an iteration, as the restore would revert its work too", other.ID)
+		}
+	}
+
+	if !force {
+		fmt.Fprintf(out, "Undo iteration %d of plan %s by restoring the repository to jj operation %s?\n", iteration, planID, operationID)
+		fmt.Fprintln(out, "Everything done in the repository since, in every workspace, is reverted.")
+		fmt.Fprint(out, "Proceed? [y/N]: ")
+		response, _ := bufio.NewReader(confirmInput).ReadString('\n')
+		if response = strings.TrimSpace(response); response != "y" && response != "Y" {
+			fmt.Fprintln(out, "Undo cancelled.")
+			return nil
+		}
+	}
+
+	// Restore the repository first, so a failure leaves the plan as it was
+	if err := jjClient.RestoreOperation(ctx, operationID); err != nil {
+		return fmt.Errorf("failed to restore jj operation %s: %w", operationID, err)
+	}
+	if _, err := database.UndoIteration(planID); err != nil {
+		return fmt.Errorf("restored jj operation %s, but failed to undo iteration %d: %w", operationID, iteration, err)
+	}
+
+	fmt.Fprintf(out, "Undid iteration %d of plan %s (restored jj operation %s)\n", iteration, planID, operationID)
+	fmt.Fprintf(out, "Run it again with: ralph --resume %s\n", planID)
+	return nil
+}
+
+// inDir reports whether path is dir or inside it.
+func inDir(path, dir string) bool {
+	if path == "" {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
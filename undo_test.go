@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+)
+
+func TestRunUndoIteration(t *testing.T) {
+	database := newDiffTestDB(t, "base")
+	if err := database.RecordIterationOperation("plan-1", 1, "op-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.RecordIterationOperation("plan-1", 2, "op-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls [][]string
+	jjClient := jj.NewClient("/repo")
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		if args[0] == "root" {
+			return "/repo\n", "", nil
+		}
+		calls = append(calls, args)
+		return "", "", nil
+	})
+
+	var out bytes.Buffer
+	if err := runUndoIteration(context.Background(), &out, database, jjClient, "plan-1", true); err != nil {
+		t.Fatalf("runUndoIteration() error: %v", err)
+	}
+	if len(calls) != 1 || !slices.Equal(calls[0], []string{"op", "restore", "op-2"}) {
+		t.Errorf("jj calls = %v, want [op restore op-2]", calls)
+	}
+	if !strings.Contains(out.String(), "Undid iteration 2 of plan plan-1") {
+		t.Errorf("output = %q", out.String())
+	}
+	if latest, err := database.LatestIteration("plan-1"); err != nil || latest != 1 {
+		t.Errorf("LatestIteration() = %d, %v; want 1", latest, err)
+	}
+
+	// The next undo reaches back to the first iteration
+	calls = nil
+	if err := runUndoIteration(context.Background(), &out, database, jjClient, "plan-1", true); err != nil {
+		t.Fatalf("runUndoIteration() error: %v", err)
+	}
+	if len(calls) != 1 || !slices.Equal(calls[0], []string{"op", "restore", "op-1"}) {
+		t.Errorf("jj calls = %v, want [op restore op-1]", calls)
+	}
+	if err := runUndoIteration(context.Background(), &out, database, jjClient, "plan-1", true); err == nil ||
+		!strings.Contains(err.Error(), "no iterations to undo") {
+		t.Errorf("expected no iterations error, got: %v", err)
+	}
+}
+
+func TestRunUndoIteration_Errors(t *testing.T) {
+	jjClient := jj.NewClient("/repo")
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		t.Errorf("unexpected jj call: %v", args)
+		return "", "", nil
+	})
+
+	database := newDiffTestDB(t, "base")
+	var out bytes.Buffer
+	if err := runUndoIteration(context.Background(), &out, database, jjClient, "missing", true); err == nil || !strings.Contains(err.Error(), "plan not found") {
+		t.Errorf("expected plan not found error, got: %v", err)
+	}
+	if err := runUndoIteration(context.Background(), &out, database, jjClient, "plan-1", true); err == nil ||
+		!strings.Contains(err.Error(), "no jj operation was recorded when iteration 2") {
+		t.Errorf("expected missing operation error, got: %v", err)
+	}
+
+	if err := database.UpdatePlanStatus("plan-1", db.PlanStatusRunning); err != nil {
+		t.Fatal(err)
+	}
+	if err := runUndoIteration(context.Background(), &out, database, jjClient, "plan-1", true); err == nil || !strings.Contains(err.Error(), "is running") {
+		t.Errorf("expected running plan error, got: %v", err)
+	}
+}
+
+func TestRunUndoIteration_Guards(t *testing.T) {
+	originalInput := confirmInput
+	defer func() { confirmInput = originalInput }()
+
+	database := newDiffTestDB(t, "base")
+	if err := database.RecordIterationOperation("plan-1", 2, "op-2"); err != nil {
+		t.Fatal(err)
+	}
+	jjClient := jj.NewClient("/repo")
+	jjClient.SetCommandRunner(func(ctx context.Context, dir, name string, args ...string) (string, string, error) {
+		if args[0] == "root" {
+			return "/repo\n", "", nil
+		}
+		t.Errorf("unexpected jj call: %v", args)
+		return "", "", nil
+	})
+
+	// Declining leaves everything as it was
+	confirmInput = strings.NewReader("n\n")
+	var out bytes.Buffer
+	if err := runUndoIteration(context.Background(), &out, database, jjClient, "plan-1", false); err != nil {
+		t.Fatalf("runUndoIteration() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "every workspace") || !strings.Contains(out.String(), "Undo cancelled.") {
+		t.Errorf("output = %q, want the warning and a cancellation", out.String())
+	}
+	if latest, err := database.LatestIteration("plan-1"); err != nil || latest != 2 {
+		t.Errorf("LatestIteration() = %d, %v; want 2", latest, err)
+	}
+
+	// Another plan running in the repository blocks the restore, even forced
+	if err := database.CreatePlan(&db.Plan{ID: "other", Content: "c", WorkDir: "/repo/services/api"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.UpdatePlanStatus("other", db.PlanStatusRunning); err != nil {
+		t.Fatal(err)
+	}
+	if err := runUndoIteration(context.Background(), &out, database, jjClient, "plan-1", true); err == nil ||
+		!strings.Contains(err.Error(), "plan other is running in this repository") {
+		t.Errorf("expected running plan error, got: %v", err)
+	}
+}
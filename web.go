@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/jj"
+	"github.com/gerunddev/ralph/internal/log"
+	"github.com/gerunddev/ralph/internal/loop"
+	"github.com/gerunddev/ralph/internal/tui"
+	"github.com/gerunddev/ralph/internal/web"
+	"github.com/spf13/cobra"
+)
+
+func webCmd() *cobra.Command {
+	var addr string
+	var limit int
+	var interval time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "web",
+		Short: "Serve a read-only web view of plans",
+		Long: `Serve a read-only web UI so people without a terminal can follow runs from a
+browser. It lists the most recently updated plans, running plans first, and
+shows each plan's live output, iteration history, progress, and diffs.
+
+Like the dashboard, the web UI reads the plans database, so it shows plans run
+by any ralph process. Nothing can be changed from it. It listens on localhost
+by default; pass --addr to expose it on other interfaces.
+
+Examples:
+  ralph web
+  ralph web --addr :8080 --limit 50`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if limit < 1 {
+				return errors.New("--limit must be at least 1")
+			}
+			if interval <= 0 {
+				return errors.New("--interval must be positive")
+			}
+
+			database, _, err := openPlansDB("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := database.Close(); closeErr != nil {
+					log.Warn("failed to close database", "error", closeErr)
+				}
+			}()
+
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("failed to listen on %s: %w", addr, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Serving plans at http://%s\n", listener.Addr())
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			return serveWeb(ctx, listener, newWebBackend(database, limit, interval))
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:8080", "Address to listen on")
+	cmd.Flags().IntVarP(&limit, "limit", "n", 20, "Maximum number of plans to list")
+	cmd.Flags().DurationVar(&interval, "interval", tui.DefaultDashboardInterval, "How often live output polls for new events")
+
+	return cmd
+}
+
+// serveWeb serves the web UI on listener until ctx is cancelled, then shuts
+// the server down, ending open event streams.
+func serveWeb(ctx context.Context, listener net.Listener, backend web.Backend) error {
+	server := &http.Server{
+		Handler:           web.NewServer(backend).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		// Event streams end with the request, so they stop on shutdown
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	errs := make(chan error, 1)
+	go func() { errs <- server.Serve(listener) }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down web server: %w", err)
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// webBackend reads the web UI's plans from the plans database.
+type webBackend struct {
+	db       *db.DB
+	interval time.Duration
+
+	// The lister caches what it has read of each plan's events; requests
+	// are served concurrently, so it is only used under mu.
+	mu     sync.Mutex
+	lister *planLister
+}
+
+// newWebBackend creates a backend listing the most recently updated limit
+// plans, following live output every interval.
+func newWebBackend(database *db.DB, limit int, interval time.Duration) *webBackend {
+	return &webBackend{db: database, interval: interval, lister: newPlanLister(database, limit)}
+}
+
+func (b *webBackend) Plans() ([]web.PlanSummary, error) {
+	b.mu.Lock()
+	plans, err := b.lister.list()
+	b.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]web.PlanSummary, len(plans))
+	for i, plan := range plans {
+		summaries[i] = web.PlanSummary(plan)
+	}
+	return summaries, nil
+}
+
+func (b *webBackend) Plan(id string) (*web.PlanDetail, error) {
+	plan, err := b.getPlan(id)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	summary, err := b.lister.summarize(plan)
+	b.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &web.PlanDetail{
+		PlanSummary:   web.PlanSummary(summary),
+		OriginPath:    plan.OriginPath,
+		FailureReason: plan.FailureReason,
+		Content:       plan.Content,
+		CreatedAt:     plan.CreatedAt,
+	}
+
+	progress, err := b.db.GetLatestProgress(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get progress: %w", err)
+	}
+	if progress != nil {
+		detail.Progress = progress.Content
+	}
+	learnings, err := b.db.GetLatestLearnings(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get learnings: %w", err)
+	}
+	if learnings != nil {
+		detail.Learnings = learnings.Content
+	}
+
+	sessions, err := b.db.GetPlanSessionsByPlan(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	for _, session := range sessions {
+		if session.Superseded {
+			continue
+		}
+		if n := len(detail.Iterations); n == 0 || detail.Iterations[n-1].Number != session.Iteration {
+			detail.Iterations = append(detail.Iterations, web.Iteration{Number: session.Iteration})
+		}
+		entry := web.Session{
+			Agent:   string(session.AgentType),
+			Status:  string(session.Status),
+			Started: session.CreatedAt,
+		}
+		if session.CompletedAt != nil {
+			entry.Duration = session.CompletedAt.Sub(session.CreatedAt)
+		}
+		last := &detail.Iterations[len(detail.Iterations)-1]
+		last.Sessions = append(last.Sessions, entry)
+	}
+	return detail, nil
+}
+
+func (b *webBackend) Diff(ctx context.Context, planID string, iteration int) (string, error) {
+	if _, err := b.getPlan(planID); err != nil {
+		return "", err
+	}
+	workDir, err := planWorkDir(b.db, planID)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	if err := runDiff(ctx, &out, b.db, jj.NewClient(workDir), planID, iteration, false); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func (b *webBackend) Follow(ctx context.Context, planID string) <-chan loop.Event {
+	return followPlan(ctx, b.db, planID, b.interval)
+}
+
+// getPlan returns a plan, reporting a missing one as web.ErrNotFound.
+func (b *webBackend) getPlan(id string) (*db.Plan, error) {
+	plan, err := b.db.GetPlan(id)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, web.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plan: %w", err)
+	}
+	return plan, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/web"
+)
+
+func TestWebBackend_Plan(t *testing.T) {
+	database := newPlansTestDB(t)
+	if err := database.CreatePlan(&db.Plan{ID: "plan-1", Content: "Build it"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []*db.PlanSession{
+		{ID: "d1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p"},
+		{ID: "r1", PlanID: "plan-1", Iteration: 1, InputPrompt: "p", AgentType: db.LoopAgentReviewer},
+		{ID: "d2", PlanID: "plan-1", Iteration: 2, InputPrompt: "p"},
+	} {
+		if err := database.CreatePlanSession(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.CompletePlanSession("d1", db.PlanSessionCompleted, "out"); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreateProgress(&db.Progress{PlanID: "plan-1", SessionID: "d1", Content: "Half done"}); err != nil {
+		t.Fatal(err)
+	}
+	createDashboardEvent(t, database, "d2", "result", dashboardResult)
+
+	backend := newWebBackend(database, 20, time.Second)
+	plan, err := backend.Plan("plan-1")
+	if err != nil {
+		t.Fatalf("Plan() error: %v", err)
+	}
+	if plan.Content != "Build it" || plan.Progress != "Half done" || plan.CostUSD != 0.25 || plan.Iteration != 2 {
+		t.Errorf("Plan() = %+v, want content, progress, cost 0.25, and iteration 2", plan)
+	}
+	if len(plan.Iterations) != 2 || len(plan.Iterations[0].Sessions) != 2 || len(plan.Iterations[1].Sessions) != 1 {
+		t.Fatalf("iterations = %+v, want two sessions in iteration 1 and one in 2", plan.Iterations)
+	}
+	if s := plan.Iterations[0].Sessions[1]; s.Agent != "reviewer" || s.Duration != 0 {
+		t.Errorf("reviewer session = %+v, want a running reviewer", s)
+	}
+
+	plans, err := backend.Plans()
+	if err != nil || len(plans) != 1 || plans[0].ID != "plan-1" {
+		t.Errorf("Plans() = %+v, %v; want plan-1", plans, err)
+	}
+
+	if _, err := backend.Plan("missing"); !errors.Is(err, web.ErrNotFound) {
+		t.Errorf("Plan(missing) error = %v, want web.ErrNotFound", err)
+	}
+	if _, err := backend.Diff(context.Background(), "missing", 0); !errors.Is(err, web.ErrNotFound) {
+		t.Errorf("Diff(missing) error = %v, want web.ErrNotFound", err)
+	}
+}
+
+func TestServeWeb_ShutsDown(t *testing.T) {
+	database := newPlansTestDB(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() { errs <- serveWeb(ctx, listener, newWebBackend(database, 20, time.Second)) }()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Errorf("serveWeb() error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveWeb() did not return after cancel")
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// resolveWorkDir returns the absolute directory a plan runs in: the --workdir
+// flag if set, the directory a resumed plan was created in, or the current
+// directory.
+func resolveWorkDir(flag, resumeID string) (string, error) {
+	if flag != "" {
+		workDir, err := filepath.Abs(flag)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve --workdir: %w", err)
+		}
+		if info, err := os.Stat(workDir); err != nil || !info.IsDir() {
+			return "", fmt.Errorf("--workdir is not a directory: %s", flag)
+		}
+		return workDir, nil
+	}
+
+	if resumeID != "" {
+		workDir, err := storedWorkDir(resumeID)
+		if err != nil {
+			return "", err
+		}
+		if workDir != "" {
+			if info, err := os.Stat(workDir); err != nil || !info.IsDir() {
+				return "", fmt.Errorf("plan %s ran in %s, which no longer exists (pass --workdir to run it elsewhere)", resumeID, workDir)
+			}
+			return workDir, nil
+		}
+	}
+
+	workDir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	return workDir, nil
+}
+
+// storedWorkDir returns the directory a plan was created in, or "" if the
+// plan doesn't exist or predates stored directories. A missing plan is
+// reported later, when resuming it.
+func storedWorkDir(planID string) (string, error) {
+	database, _, err := openPlansDB("")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if closeErr := database.Close(); closeErr != nil {
+			log.Warn("failed to close database", "error", closeErr)
+		}
+	}()
+
+	plan, err := database.GetPlan(planID)
+	if errors.Is(err, db.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get plan: %w", err)
+	}
+	return plan.WorkDir, nil
+}
+
+// planWorkDir returns the directory jj commands about a plan should run in:
+// the plan's own directory when stored, otherwise the current directory.
+func planWorkDir(database *db.DB, planID string) (string, error) {
+	if plan, err := database.GetPlan(planID); err == nil && plan.WorkDir != "" {
+		return plan.WorkDir, nil
+	}
+	workDir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	return workDir, nil
+}
+
+// resolveScope returns the --scope flag as a slash-separated directory
+// relative to the repository root, without a trailing slash ("" = the whole
+// repository). The directory doesn't have to exist yet: the plan may create
+// it.
+func resolveScope(flag string) (string, error) {
+	scope := strings.TrimSpace(filepath.ToSlash(flag))
+	if scope == "" {
+		return "", nil
+	}
+	scope = path.Clean(scope)
+	if !filepath.IsLocal(filepath.FromSlash(scope)) {
+		return "", fmt.Errorf("--scope must be a directory inside the repository, relative to its root: %s", flag)
+	}
+	if scope == "." {
+		return "", nil
+	}
+	return scope, nil
+}
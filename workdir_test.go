@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gerunddev/ralph/internal/db"
+	"github.com/gerunddev/ralph/internal/log"
+)
+
+// createStoredPlan stores a plan in the default plans database, which lives
+// under a temporary HOME.
+func createStoredPlan(t *testing.T, plan *db.Plan) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	database, _, err := openPlansDB("")
+	if err != nil {
+		t.Fatalf("openPlansDB() error: %v", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			log.Warn("failed to close database", "error", err)
+		}
+	}()
+	if err := database.CreatePlan(plan); err != nil {
+		t.Fatalf("CreatePlan() error: %v", err)
+	}
+}
+
+func TestResolveWorkDir(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := t.TempDir()
+	createStoredPlan(t, &db.Plan{ID: "plan-1", Content: "c", WorkDir: repo})
+
+	tests := []struct {
+		name     string
+		flag     string
+		resumeID string
+		want     string
+	}{
+		{"current directory", "", "", cwd},
+		{"flag", repo, "", repo},
+		{"relative flag", ".", "", cwd},
+		{"resumed plan's directory", "", "plan-1", repo},
+		{"flag overrides resumed plan", cwd, "plan-1", cwd},
+		{"unknown plan falls back to current directory", "", "missing", cwd},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveWorkDir(tt.flag, tt.resumeID)
+			if err != nil {
+				t.Fatalf("resolveWorkDir() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveWorkDir(%q, %q) = %q, want %q", tt.flag, tt.resumeID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveWorkDir_Errors(t *testing.T) {
+	gone := filepath.Join(t.TempDir(), "gone")
+	createStoredPlan(t, &db.Plan{ID: "plan-1", Content: "c", WorkDir: gone})
+
+	if _, err := resolveWorkDir(gone, ""); err == nil || !strings.Contains(err.Error(), "--workdir") {
+		t.Errorf("expected --workdir error for a missing directory, got: %v", err)
+	}
+	if _, err := resolveWorkDir("", "plan-1"); err == nil || !strings.Contains(err.Error(), "no longer exists") {
+		t.Errorf("expected error for a plan whose directory is gone, got: %v", err)
+	}
+}
+
+func TestResolveScope(t *testing.T) {
+	tests := []struct {
+		flag    string
+		want    string
+		wantErr bool
+	}{
+		{flag: "", want: ""},
+		{flag: "services/api", want: "services/api"},
+		{flag: "services/api/", want: "services/api"},
+		{flag: "./services//api", want: "services/api"},
+		{flag: ".", want: ""},
+		{flag: "../other", wantErr: true},
+		{flag: "services/../../other", wantErr: true},
+		{flag: "/abs/path", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := resolveScope(tt.flag)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("resolveScope(%q) error = %v, wantErr %v", tt.flag, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("resolveScope(%q) = %q, want %q", tt.flag, got, tt.want)
+		}
+	}
+}